@@ -1,5 +1,10 @@
 module.exports = {
-  require: ['ts-node/register/transpile-only', 'abort-controller/polyfill', __dirname + '/client/shared/dev/fetch'],
+  require: [
+    'ts-node/register/transpile-only',
+    'abort-controller/polyfill',
+    __dirname + '/client/shared/dev/fetch',
+    __dirname + '/client/shared/dev/mochaTimings',
+  ],
   extension: ['js', 'ts'],
   // 1 minute test timeout. This must be greater than the default Puppeteer
   // command timeout of 30s in order to get the stack trace to point to the
@@ -0,0 +1,34 @@
+// @ts-check
+
+// Mocha root hook plugin that records the duration of the tests of each test file to the file
+// TEST_TIMINGS_FILE. CI uses the recorded durations to balance the test files across steps.
+
+const fs = require('fs')
+const path = require('path')
+
+/** @type {Map<string, number>} */
+const durations = new Map()
+
+const mochaHooks = {
+  /** @this {import('mocha').Context} */
+  afterEach() {
+    const test = this.currentTest
+    if (test && test.file && typeof test.duration === 'number') {
+      durations.set(test.file, (durations.get(test.file) || 0) + test.duration)
+    }
+  },
+  afterAll() {
+    const timingsFile = process.env.TEST_TIMINGS_FILE
+    if (!timingsFile || durations.size === 0) {
+      return
+    }
+    const lines = [...durations].map(
+      ([file, duration]) => `web-integration\t${path.relative(process.cwd(), file)}\t${duration / 1000}\n`
+    )
+    durations.clear()
+    fs.mkdirSync(path.dirname(timingsFile), { recursive: true })
+    fs.appendFileSync(timingsFile, lines.join(''))
+  },
+}
+
+module.exports = { mochaHooks }
@@ -23,6 +23,12 @@ interface Props {
     >
 }
 
+/**
+ * The response header of a sign-in whose password was correct, but that also requires the code of
+ * the user's authenticator app.
+ */
+const TWO_FACTOR_HEADER = 'X-Sourcegraph-Two-Factor'
+
 /**
  * The form for signing in with a username and password.
  */
@@ -34,6 +40,8 @@ export const UsernamePasswordSignInForm: React.FunctionComponent<Props> = ({
 }) => {
     const [usernameOrEmail, setUsernameOrEmail] = useState('')
     const [password, setPassword] = useState('')
+    const [totpRequired, setTotpRequired] = useState(false)
+    const [totpCode, setTotpCode] = useState('')
     const [loading, setLoading] = useState(false)
 
     const onUsernameOrEmailFieldChange = useCallback((event: React.ChangeEvent<HTMLInputElement>): void => {
@@ -44,6 +52,10 @@ export const UsernamePasswordSignInForm: React.FunctionComponent<Props> = ({
         setPassword(event.target.value)
     }, [])
 
+    const onTotpCodeFieldChange = useCallback((event: React.ChangeEvent<HTMLInputElement>): void => {
+        setTotpCode(event.target.value)
+    }, [])
+
     const handleSubmit = useCallback(
         (event: React.FormEvent<HTMLFormElement>): void => {
             event.preventDefault()
@@ -64,6 +76,7 @@ export const UsernamePasswordSignInForm: React.FunctionComponent<Props> = ({
                 body: JSON.stringify({
                     email: usernameOrEmail,
                     password,
+                    totpCode,
                 }),
             })
                 .then(response => {
@@ -74,8 +87,17 @@ export const UsernamePasswordSignInForm: React.FunctionComponent<Props> = ({
                             const returnTo = getReturnTo(location)
                             window.location.replace(returnTo)
                         }
+                    } else if (response.status === 401 && response.headers.get(TWO_FACTOR_HEADER) === 'required') {
+                        // The password was correct, ask for the code of the authenticator app.
+                        setTotpRequired(true)
+                        setLoading(false)
+                        onAuthError(null)
                     } else if (response.status === 401) {
-                        throw new Error('User or password was incorrect')
+                        throw new Error(
+                            totpRequired
+                                ? 'User, password or authentication code was incorrect'
+                                : 'User or password was incorrect'
+                        )
                     } else {
                         throw new Error('Unknown Error')
                     }
@@ -86,7 +108,7 @@ export const UsernamePasswordSignInForm: React.FunctionComponent<Props> = ({
                     onAuthError(asError(error))
                 })
         },
-        [usernameOrEmail, loading, location, password, onAuthError, context]
+        [usernameOrEmail, loading, location, password, totpCode, totpRequired, onAuthError, context]
     )
 
     return (
@@ -130,6 +152,27 @@ export const UsernamePasswordSignInForm: React.FunctionComponent<Props> = ({
                         placeholder=" "
                     />
                 </div>
+                {totpRequired && (
+                    <div className="form-group d-flex flex-column align-content-start">
+                        <label htmlFor="totp-code" className="align-self-start">
+                            Authentication code
+                        </label>
+                        <input
+                            id="totp-code"
+                            className="form-control"
+                            type="text"
+                            onChange={onTotpCodeFieldChange}
+                            required={true}
+                            value={totpCode}
+                            disabled={loading}
+                            autoComplete="one-time-code"
+                            autoFocus={true}
+                        />
+                        <small className="form-text text-muted">
+                            Enter the code from your authenticator app, or one of your recovery codes.
+                        </small>
+                    </div>
+                )}
                 <div
                     className={classNames('form-group', {
                         'mb-0': noThirdPartyProviders,
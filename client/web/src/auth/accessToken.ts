@@ -4,4 +4,7 @@
 export enum AccessTokenScopes {
     UserAll = 'user:all',
     SiteAdminSudo = 'site-admin:sudo',
+    ReadSearch = 'read:search',
+    WriteBatches = 'write:batches',
+    Admin = 'admin',
 }
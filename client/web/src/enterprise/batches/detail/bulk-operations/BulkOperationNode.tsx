@@ -50,6 +50,11 @@ const OPERATION_TITLES: Record<BulkOperationType, JSX.Element> = {
             <UploadIcon className="icon-inline text-muted" /> Publish changesets
         </>
     ),
+    MERGE_WHEN_CHECKS_PASS: (
+        <>
+            <SourceBranchIcon className="icon-inline text-muted" /> Merge changesets when checks pass
+        </>
+    ),
 }
 
 export interface BulkOperationNodeProps {
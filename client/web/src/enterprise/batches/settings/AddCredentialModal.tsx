@@ -56,9 +56,25 @@ const helpTexts: Record<ExternalServiceKind, JSX.Element> = {
             with <code>write</code> permissions on the project and repository level.
         </>
     ),
+    [ExternalServiceKind.GERRIT]: (
+        <>
+            <a href={HELP_TEXT_LINK_URL} rel="noreferrer noopener" target="_blank">
+                Generate a new HTTP password
+            </a>{' '}
+            in the <b>HTTP Credentials</b> section of your Gerrit settings.
+        </>
+    ),
+    [ExternalServiceKind.BITBUCKETCLOUD]: (
+        <>
+            <a href={HELP_TEXT_LINK_URL} rel="noreferrer noopener" target="_blank">
+                Create a new app password
+            </a>{' '}
+            with <code>account:read</code>, <code>repository:write</code>, and <code>pullrequest:write</code>{' '}
+            permissions.
+        </>
+    ),
 
     // These are just for type completeness and serve as placeholders for a bright future.
-    [ExternalServiceKind.BITBUCKETCLOUD]: <span>Unsupported</span>,
     [ExternalServiceKind.GITOLITE]: <span>Unsupported</span>,
     [ExternalServiceKind.JVMPACKAGES]: <span>Unsupported</span>,
     [ExternalServiceKind.PERFORCE]: <span>Unsupported</span>,
@@ -67,6 +83,13 @@ const helpTexts: Record<ExternalServiceKind, JSX.Element> = {
     [ExternalServiceKind.OTHER]: <span>Unsupported</span>,
 }
 
+// Code hosts whose credentials are passwords that can only be used together
+// with the username of their user, and the names of those passwords.
+const passwordLabels: Partial<Record<ExternalServiceKind, string>> = {
+    [ExternalServiceKind.GERRIT]: 'HTTP password',
+    [ExternalServiceKind.BITBUCKETCLOUD]: 'App password',
+}
+
 type Step = 'add-token' | 'get-ssh-key'
 
 export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps> = ({
@@ -82,6 +105,7 @@ export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps
     const labelId = 'addCredential'
     const [isLoading, setIsLoading] = useState<boolean | Error>(false)
     const [credential, setCredential] = useState<string>('')
+    const [username, setUsername] = useState<string>('')
     const [sshPublicKey, setSSHPublicKey] = useState<string>()
     const [step, setStep] = useState<Step>(initialStep)
 
@@ -89,6 +113,12 @@ export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps
         setCredential(event.target.value)
     }, [])
 
+    const passwordLabel = passwordLabels[externalServiceKind]
+    const requiresUsername = passwordLabel !== undefined
+    const onChangeUsername = useCallback<React.ChangeEventHandler<HTMLInputElement>>(event => {
+        setUsername(event.target.value)
+    }, [])
+
     const onSubmit = useCallback<React.FormEventHandler>(
         async event => {
             event.preventDefault()
@@ -97,6 +127,7 @@ export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps
                 const createdCredential = await createBatchChangesCredential({
                     user: userID,
                     credential,
+                    username: requiresUsername ? username : null,
                     externalServiceKind,
                     externalServiceURL,
                 })
@@ -114,6 +145,8 @@ export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps
             afterCreate,
             userID,
             credential,
+            requiresUsername,
+            username,
             externalServiceKind,
             externalServiceURL,
             requiresSSH,
@@ -164,8 +197,25 @@ export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps
                     <>
                         {isErrorLike(isLoading) && <ErrorAlert error={isLoading} />}
                         <Form onSubmit={onSubmit}>
+                            {requiresUsername && (
+                                <div className="form-group">
+                                    <label htmlFor="username">Username</label>
+                                    <input
+                                        id="username"
+                                        name="username"
+                                        type="text"
+                                        autoComplete="off"
+                                        className="form-control test-add-credential-modal-username"
+                                        required={true}
+                                        spellCheck="false"
+                                        minLength={1}
+                                        value={username}
+                                        onChange={onChangeUsername}
+                                    />
+                                </div>
+                            )}
                             <div className="form-group">
-                                <label htmlFor="token">Personal access token</label>
+                                <label htmlFor="token">{passwordLabel ?? 'Personal access token'}</label>
                                 <input
                                     id="token"
                                     name="token"
@@ -191,7 +241,11 @@ export const AddCredentialModal: React.FunctionComponent<AddCredentialModalProps
                                 </button>
                                 <button
                                     type="submit"
-                                    disabled={isLoading === true || credential.length === 0}
+                                    disabled={
+                                        isLoading === true ||
+                                        credential.length === 0 ||
+                                        (requiresUsername && username.length === 0)
+                                    }
                                     className="btn btn-primary test-add-credential-modal-submit"
                                 >
                                     {isLoading === true && <LoadingSpinner className="icon-inline" />}
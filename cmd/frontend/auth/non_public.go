@@ -69,7 +69,12 @@ var (
 		router.SignOut:            {},
 		router.ResetPasswordInit:  {},
 		router.ResetPasswordCode:  {},
+		router.RecoverAccountInit: {},
 		router.CheckUsernameTaken: {},
+
+		// Reminders can be unsubscribed from without signing in. The
+		// handler checks the token included in the link.
+		router.UnsubscribeVerificationReminders: {},
 	}
 	anonymousAccessibleUIRoutes = map[string]struct{}{
 		uirouter.RouteSignIn:             {},
@@ -124,6 +129,8 @@ func AllowAnonymousRequest(req *http.Request) bool {
 		"/.api/github-webhooks",
 		"/.api/gitlab-webhooks",
 		"/.api/bitbucket-server-webhooks",
+		"/.api/bitbucket-cloud-webhooks",
+		"/.api/email-webhooks",
 	} {
 		if strings.HasPrefix(req.URL.Path, prefix) {
 			return true
@@ -135,6 +142,11 @@ func AllowAnonymousRequest(req *http.Request) bool {
 		return true
 	}
 
+	// Permission is checked by the SCIM bearer token in the SCIM handler itself.
+	if strings.HasPrefix(req.URL.Path, "/.api/scim/") {
+		return true
+	}
+
 	apiRouteName := matchedRouteName(req, router.Router())
 	if apiRouteName == router.UI {
 		// Test against UI router. (Some of its handlers inject private data into the title or meta tags.)
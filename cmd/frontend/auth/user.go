@@ -7,6 +7,7 @@ import (
 
 	"github.com/inconshreveable/log15"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/authz"
 	"github.com/sourcegraph/sourcegraph/internal/database"
@@ -104,6 +105,14 @@ func GetAndSaveUser(ctx context.Context, db dbutil.DB, op GetAndSaveUserOp) (use
 			return 0, false, false, "It looks like this is your first time signing in with this external identity. Sourcegraph couldn't link it to an existing user, because no verified email was provided. Ask your site admin to configure the auth provider to include the user's verified email on sign-in.", lookupByExternalErr
 		}
 
+		// Role and shared mailbox addresses are rejected on sign-up with any auth provider.
+		if err := backend.CheckRoleEmailAddress(op.UserProps.Email); err != nil {
+			if backend.IsRoleEmailAddressError(err) {
+				return 0, false, false, err.Error(), err
+			}
+			return 0, false, false, "Unable to check the email address of the new user account due to an unexpected error. Ask a site admin for help.", err
+		}
+
 		// If CreateIfNotExist is true, create the new user, regardless of whether the email was verified or not.
 		userID, err := extacc.CreateUserAndSave(ctx, op.UserProps, op.ExternalAccount, op.ExternalAccountData)
 		switch {
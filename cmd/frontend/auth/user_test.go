@@ -10,12 +10,15 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/sergi/go-diff/diffmatchpatch"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func init() {
@@ -426,6 +429,34 @@ func TestGetAndSaveUser(t *testing.T) {
 	}
 }
 
+func TestGetAndSaveUser_RoleEmailAddress(t *testing.T) {
+	db := dbtest.NewDB(t, "")
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		EmailRoleAddresses: &schema.EmailRoleAddresses{Reject: true},
+	}})
+	defer conf.Mock(nil)
+
+	m := newMocks(t, mockParams{})
+	m.apply()
+	defer m.reset()
+
+	userID, safeErr, err := GetAndSaveUser(context.Background(), db, GetAndSaveUserOp{
+		ExternalAccount:  ext("st1", "s1", "c1", "s1/admin"),
+		UserProps:        userProps("admin", "admin@example.com", true),
+		CreateIfNotExist: true,
+	})
+	if !backend.IsRoleEmailAddressError(err) {
+		t.Fatalf("want role email address error, have %v", err)
+	}
+	if userID != 0 || safeErr != err.Error() {
+		t.Fatalf("unexpected result: userID=%d, safeErr=%q", userID, safeErr)
+	}
+	if len(m.createdUsers) != 0 {
+		t.Fatalf("user created: %v", m.createdUsers)
+	}
+}
+
 type userInfo struct {
 	user     types.User
 	extAccts []extsvc.AccountSpec
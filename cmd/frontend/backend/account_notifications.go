@@ -0,0 +1,234 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// accountChange describes a security-relevant change to a user's account, such as a changed
+// password, that the user is notified about.
+type accountChange struct {
+	UserID   int32
+	Username string
+	Change   string // e.g. "updated the password"
+	Host     string // the host of the Sourcegraph instance
+}
+
+// accountChangeNotifier notifies users about changes to their account on a single channel.
+type accountChangeNotifier interface {
+	// Notify notifies the user about the change. It returns errUserUnreachable if the user
+	// can't be notified on this channel (e.g. because they have no Slack identity).
+	Notify(ctx context.Context, change *accountChange) error
+}
+
+var errUserUnreachable = errors.New("user cannot be notified on this channel")
+
+// newAccountChangeNotifier returns the notifier for the given channel of the
+// notifications.accountChanges site configuration.
+func newAccountChangeNotifier(channel string, cfg *schema.AccountChangeNotifications) (accountChangeNotifier, error) {
+	switch channel {
+	case "email":
+		return emailAccountChangeNotifier{}, nil
+	case "slack":
+		if cfg == nil || cfg.Slack == nil || cfg.Slack.BotToken == "" {
+			return nil, errors.New("slack account change notifications are not configured")
+		}
+		return &slackAccountChangeNotifier{
+			doer:     httpcli.ExternalDoer,
+			apiURL:   slackPostMessageURL,
+			botToken: cfg.Slack.BotToken,
+		}, nil
+	case "webhook":
+		if cfg == nil || cfg.Webhook == nil || cfg.Webhook.Url == "" {
+			return nil, errors.New("webhook account change notifications are not configured")
+		}
+		return &webhookAccountChangeNotifier{
+			doer:   httpcli.ExternalDoer,
+			url:    cfg.Webhook.Url,
+			secret: cfg.Webhook.Secret,
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown account change notification channel %q", channel)
+	}
+}
+
+// emailAccountChangeNotifier notifies users by sending an email to their contact email address.
+type emailAccountChangeNotifier struct{}
+
+func (emailAccountChangeNotifier) Notify(ctx context.Context, change *accountChange) error {
+	email, _, err := UserEmails.ContactEmail(ctx, dbconn.Global, change.UserID)
+	if err != nil {
+		return errors.Wrap(err, "getting contact email")
+	}
+
+	return sendTrackedEmail(ctx, change.UserID, EmailDeliveryKindNotification, txemail.Message{
+		To:       []string{email},
+		Template: updateAccountEmailTemplate,
+		Data: struct {
+			Email    string
+			Change   string
+			Username string
+			Host     string
+		}{
+			Email:    email,
+			Change:   change.Change,
+			Username: change.Username,
+			Host:     change.Host,
+		},
+	})
+}
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// slackIssuerHost is the host of the OpenID Connect issuer of "Sign in with Slack". Users who
+// signed in that way have an external account whose account ID is their Slack user ID.
+const slackIssuerHost = "slack.com"
+
+// slackAccountChangeNotifier notifies users with a direct message from a Slack bot. Only users
+// with a linked Slack identity can be notified.
+type slackAccountChangeNotifier struct {
+	doer     httpcli.Doer
+	apiURL   string
+	botToken string
+}
+
+func (n *slackAccountChangeNotifier) Notify(ctx context.Context, change *accountChange) error {
+	slackUserID, err := linkedSlackUserID(ctx, change.UserID)
+	if err != nil {
+		return err
+	}
+	if slackUserID == "" {
+		return errUserUnreachable
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"channel": slackUserID,
+		"text": fmt.Sprintf(
+			"Somebody (likely you) *%s* for the user *%s* on Sourcegraph (%s).\n\n*If this was not you please change your password immediately.*",
+			change.Change, change.Username, change.Host,
+		),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+n.botToken)
+
+	resp, err := n.doer.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "slack: http request")
+	}
+	defer resp.Body.Close()
+
+	// The Slack Web API responds with 200 OK even if the request failed, and reports errors in
+	// the body instead.
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return errors.Wrapf(err, "slack: decoding response with status %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return errors.Errorf("slack: posting message failed: %s", result.Error)
+	}
+	return nil
+}
+
+// linkedSlackUserID returns the Slack user ID of the user's linked Slack identity, or "" if the
+// user has none.
+func linkedSlackUserID(ctx context.Context, userID int32) (string, error) {
+	accounts, err := database.ExternalAccounts(dbconn.Global).List(ctx, database.ExternalAccountsListOptions{
+		UserID:      userID,
+		ServiceType: "openidconnect",
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "listing external accounts")
+	}
+	for _, account := range accounts {
+		if u, err := url.Parse(account.ServiceID); err == nil && u.Host == slackIssuerHost {
+			return account.AccountID, nil
+		}
+	}
+	return "", nil
+}
+
+// webhookAccountChangeNotifier notifies an external service, which is responsible for
+// delivering the notification to the user.
+type webhookAccountChangeNotifier struct {
+	doer   httpcli.Doer
+	url    string
+	secret string
+}
+
+// accountChangeWebhookPayload is the JSON payload sent by webhookAccountChangeNotifier.
+type accountChangeWebhookPayload struct {
+	UserID    int32     `json:"userID"`
+	Username  string    `json:"username"`
+	Change    string    `json:"change"`
+	Host      string    `json:"host"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (n *webhookAccountChangeNotifier) Notify(ctx context.Context, change *accountChange) error {
+	body, err := json.Marshal(accountChangeWebhookPayload{
+		UserID:    change.UserID,
+		Username:  change.Username,
+		Change:    change.Change,
+		Host:      change.Host,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set("X-Sourcegraph-Signature", signWebhookPayload(n.secret, body))
+	}
+
+	resp, err := n.doer.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "webhook: http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("webhook: request failed with %d %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// signWebhookPayload returns the hex-encoded HMAC-SHA256 of the payload, keyed with the secret.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
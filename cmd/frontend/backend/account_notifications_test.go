@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+)
+
+func TestSlackAccountChangeNotifier(t *testing.T) {
+	var gotAuth string
+	var gotBody map[string]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer ts.Close()
+
+	notifier := &slackAccountChangeNotifier{doer: http.DefaultClient, apiURL: ts.URL, botToken: "xoxb-123"}
+	change := &accountChange{UserID: 1, Username: "alice", Change: "updated the password", Host: "example.com"}
+
+	t.Run("no linked Slack identity", func(t *testing.T) {
+		database.Mocks.ExternalAccounts.List = func(database.ExternalAccountsListOptions) ([]*extsvc.Account, error) {
+			return []*extsvc.Account{{AccountSpec: extsvc.AccountSpec{
+				ServiceType: "openidconnect",
+				ServiceID:   "https://accounts.google.com",
+				AccountID:   "1234",
+			}}}, nil
+		}
+		defer func() { database.Mocks.ExternalAccounts.List = nil }()
+
+		if err := notifier.Notify(context.Background(), change); !errors.Is(err, errUserUnreachable) {
+			t.Fatalf("got error %v, want errUserUnreachable", err)
+		}
+		if gotBody != nil {
+			t.Fatal("want no message to be posted")
+		}
+	})
+
+	t.Run("linked Slack identity", func(t *testing.T) {
+		database.Mocks.ExternalAccounts.List = func(database.ExternalAccountsListOptions) ([]*extsvc.Account, error) {
+			return []*extsvc.Account{{AccountSpec: extsvc.AccountSpec{
+				ServiceType: "openidconnect",
+				ServiceID:   "https://slack.com",
+				AccountID:   "U0R7JM",
+			}}}, nil
+		}
+		defer func() { database.Mocks.ExternalAccounts.List = nil }()
+
+		if err := notifier.Notify(context.Background(), change); err != nil {
+			t.Fatal(err)
+		}
+		if want := "Bearer xoxb-123"; gotAuth != want {
+			t.Errorf("got Authorization header %q, want %q", gotAuth, want)
+		}
+		if want := "U0R7JM"; gotBody["channel"] != want {
+			t.Errorf("got channel %q, want %q", gotBody["channel"], want)
+		}
+	})
+}
+
+func TestWebhookAccountChangeNotifier(t *testing.T) {
+	var gotSignature string
+	var gotBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Sourcegraph-Signature")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	notifier := &webhookAccountChangeNotifier{doer: http.DefaultClient, url: ts.URL, secret: "s3cr3t"}
+	change := &accountChange{UserID: 1, Username: "alice", Change: "created an access token", Host: "example.com"}
+	if err := notifier.Notify(context.Background(), change); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload accountChangeWebhookPayload
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != 1 || payload.Username != "alice" || payload.Change != "created an access token" || payload.Host != "example.com" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if want := signWebhookPayload("s3cr3t", gotBody); gotSignature != want {
+		t.Errorf("got signature %q, want %q", gotSignature, want)
+	}
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/rcache"
+)
+
+// contactEmailCache caches the effective contact email of users. Entries are deleted by the
+// database.AfterUserEmailsChange hook whenever the email addresses of a user change; the TTL only
+// bounds how long a missed invalidation (e.g., a failed Redis call) goes unnoticed.
+var contactEmailCache = rcache.NewWithTTL("user-contact-email", 600) // 10 minutes
+
+func init() {
+	database.AfterUserEmailsChange = UserEmails.InvalidateContactEmail
+}
+
+type contactEmail struct {
+	Email    string `json:"email"`
+	Verified bool   `json:"verified"`
+}
+
+// noContactEmailError is returned by ContactEmail if the user has no email address.
+type noContactEmailError struct {
+	userID int32
+}
+
+func (e noContactEmailError) Error() string {
+	return fmt.Sprintf("user %d has no email address", e.userID)
+}
+
+func (e noContactEmailError) NotFound() bool { return true }
+
+// ContactEmail returns the email address that notifications for the given user must be sent to,
+// and whether it is verified. It is
+//
+// - the primary email address, if it is verified,
+// - otherwise the oldest verified email address,
+// - otherwise the unverified primary email address.
+//
+// Callers that must not send to unverified addresses have to check verified. If the user has no
+// email address, an error satisfying errcode.IsNotFound is returned.
+func (userEmails) ContactEmail(ctx context.Context, db dbutil.DB, userID int32) (email string, verified bool, err error) {
+	key := strconv.Itoa(int(userID))
+	if b, ok := contactEmailCache.Get(key); ok {
+		var c contactEmail
+		if err := json.Unmarshal(b, &c); err == nil {
+			return c.Email, c.Verified, nil
+		}
+		contactEmailCache.Delete(key) // remove unexpectedly invalid cache value
+	}
+
+	emails, err := database.UserEmails(db).ListByUser(ctx, database.UserEmailsListOptions{UserID: userID})
+	if err != nil {
+		return "", false, err
+	}
+	c, ok := effectiveContactEmail(emails)
+	if !ok {
+		return "", false, noContactEmailError{userID: userID}
+	}
+
+	if b, err := json.Marshal(c); err == nil {
+		contactEmailCache.Set(key, b)
+	}
+	return c.Email, c.Verified, nil
+}
+
+// InvalidateContactEmail drops the cached contact email of the given user. It is called by the
+// database.AfterUserEmailsChange hook, so callers of the database.UserEmailsStore need not call it.
+func (userEmails) InvalidateContactEmail(userID int32) {
+	contactEmailCache.Delete(strconv.Itoa(int(userID)))
+}
+
+// effectiveContactEmail picks the contact email from the given email addresses of a user, which
+// must be ordered by creation time. It returns false if there is none.
+func effectiveContactEmail(emails []*database.UserEmail) (contactEmail, bool) {
+	var primary, oldestVerified *database.UserEmail
+	for _, e := range emails {
+		if e.Primary {
+			primary = e
+		}
+		if e.VerifiedAt != nil && oldestVerified == nil {
+			oldestVerified = e
+		}
+	}
+
+	switch {
+	case primary != nil && primary.VerifiedAt != nil:
+		return contactEmail{Email: primary.Email, Verified: true}, true
+	case oldestVerified != nil:
+		return contactEmail{Email: oldestVerified.Email, Verified: true}, true
+	case primary != nil:
+		return contactEmail{Email: primary.Email}, true
+	default:
+		return contactEmail{}, false
+	}
+}
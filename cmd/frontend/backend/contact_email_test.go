@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+func TestEffectiveContactEmail(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		emails []*database.UserEmail
+		want   contactEmail
+		wantOK bool
+	}{
+		{
+			name: "no emails",
+		},
+		{
+			name: "verified primary",
+			emails: []*database.UserEmail{
+				{Email: "a@example.com", VerifiedAt: &now},
+				{Email: "b@example.com", VerifiedAt: &now, Primary: true},
+			},
+			want:   contactEmail{Email: "b@example.com", Verified: true},
+			wantOK: true,
+		},
+		{
+			name: "unverified primary with verified email",
+			emails: []*database.UserEmail{
+				{Email: "a@example.com", Primary: true},
+				{Email: "b@example.com", VerifiedAt: &now},
+				{Email: "c@example.com", VerifiedAt: &now},
+			},
+			want:   contactEmail{Email: "b@example.com", Verified: true},
+			wantOK: true,
+		},
+		{
+			name: "unverified primary",
+			emails: []*database.UserEmail{
+				{Email: "a@example.com"},
+				{Email: "b@example.com", Primary: true},
+			},
+			want:   contactEmail{Email: "b@example.com"},
+			wantOK: true,
+		},
+		{
+			name: "no primary and none verified",
+			emails: []*database.UserEmail{
+				{Email: "a@example.com"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := effectiveContactEmail(test.emails)
+			if ok != test.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("unexpected contact email (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
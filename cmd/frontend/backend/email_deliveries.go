@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+)
+
+// The kinds of emails whose delivery is tracked.
+const (
+	EmailDeliveryKindVerification = "verification"
+	EmailDeliveryKindNotification = "notification"
+)
+
+// sendTrackedEmail sends the message to the user with a new Message-ID, and records the delivery
+// to each recipient so that the bounces and complaints that the email provider reports for it can
+// be matched to the user's email address.
+func sendTrackedEmail(ctx context.Context, userID int32, kind string, message txemail.Message) error {
+	messageID := txemail.NewMessageID()
+	message.MessageID = &messageID
+	if err := txemail.Send(ctx, message); err != nil {
+		return err
+	}
+
+	for _, to := range message.To {
+		err := database.EmailDeliveries(dbconn.Global).Create(ctx, &database.EmailDelivery{
+			MessageID: messageID,
+			UserID:    userID,
+			Email:     to,
+			Kind:      kind,
+		})
+		if err != nil {
+			// The email was sent, so don't fail the caller.
+			log15.Warn("Failed to record email delivery.", "userID", userID, "kind", kind, "error", err)
+		}
+	}
+	return nil
+}
+
+// EmailDeliveryEvent is a report of the email provider about an email that was sent.
+type EmailDeliveryEvent struct {
+	MessageID string // the Message-ID header of the email, if the provider reports it
+	Email     string // the recipient
+	Status    string // one of the database.EmailDelivery* statuses
+	Reason    string // e.g. the SMTP response of a bounce
+}
+
+// HandleEmailDeliveryEvent records the event reported by the email provider. If emails to the
+// recipient bounce permanently or were marked as spam, the address is marked as bouncing for
+// every user that has it.
+func HandleEmailDeliveryEvent(ctx context.Context, db dbutil.DB, event EmailDeliveryEvent) error {
+	if _, err := database.EmailDeliveries(db).UpdateStatus(ctx, event.MessageID, event.Email, event.Status, event.Reason); err != nil {
+		return err
+	}
+
+	switch event.Status {
+	case database.EmailDeliveryBounced, database.EmailDeliveryComplained:
+		reason := event.Reason
+		if reason == "" {
+			reason = event.Status
+		}
+		if _, err := database.UserEmails(db).MarkBounced(ctx, event.Email, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// EmailDomainPolicyError is returned when signing up with or adding an email address that the
+// email domain policy rejects.
+type EmailDomainPolicyError struct {
+	Email  string
+	Domain string
+}
+
+func (e EmailDomainPolicyError) Error() string {
+	return fmt.Sprintf("email addresses of the domain %s can't be used on this site", e.Domain)
+}
+
+func (EmailDomainPolicyError) BadRequest() bool { return true }
+
+// IsEmailDomainPolicyError reports whether err or one of its causes is an EmailDomainPolicyError.
+func IsEmailDomainPolicyError(err error) bool {
+	var e EmailDomainPolicyError
+	return errors.As(err, &e)
+}
+
+// EmailDomainPolicyDecision is the result of evaluating the email domain policy for an email
+// address.
+type EmailDomainPolicyDecision struct {
+	Email   string
+	Domain  string
+	Allowed bool
+	// Reason explains which rule decided, for site admins inspecting the policy.
+	Reason string
+}
+
+// EvaluateEmailDomainPolicy decides whether the email domain policy allows the given email
+// address. Overrides stored in the database take precedence over the email.domainPolicy site
+// configuration, and the most specific override wins. In the site configuration, deny takes
+// precedence over allow.
+func EvaluateEmailDomainPolicy(ctx context.Context, db dbutil.DB, email string) (*EmailDomainPolicyDecision, error) {
+	d := &EmailDomainPolicyDecision{Email: email, Allowed: true}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		// Not an email address, which is reported elsewhere.
+		d.Reason = "not an email address"
+		return d, nil
+	}
+	d.Domain = strings.ToLower(email[at+1:])
+
+	overrides, err := database.EmailDomainPolicyOverrides(db).List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing email domain policy overrides")
+	}
+	var override *database.EmailDomainPolicyOverride
+	for _, o := range overrides {
+		if emailDomainMatches(d.Domain, o.Domain) && (override == nil || len(o.Domain) > len(override.Domain)) {
+			override = o
+		}
+	}
+	if override != nil {
+		d.Allowed = override.Allow
+		if override.Allow {
+			d.Reason = fmt.Sprintf("allowed by the override for %s", override.Domain)
+		} else {
+			d.Reason = fmt.Sprintf("denied by the override for %s", override.Domain)
+		}
+		return d, nil
+	}
+
+	policy := conf.Get().EmailDomainPolicy
+	if policy == nil {
+		d.Reason = "no policy is configured"
+		return d, nil
+	}
+	for _, rule := range policy.Deny {
+		if emailDomainMatches(d.Domain, rule) {
+			d.Allowed = false
+			d.Reason = fmt.Sprintf("denied by %s in email.domainPolicy.deny", rule)
+			return d, nil
+		}
+	}
+	if len(policy.Allow) == 0 {
+		d.Reason = "not denied by email.domainPolicy"
+		return d, nil
+	}
+	for _, rule := range policy.Allow {
+		if emailDomainMatches(d.Domain, rule) {
+			d.Reason = fmt.Sprintf("allowed by %s in email.domainPolicy.allow", rule)
+			return d, nil
+		}
+	}
+	d.Allowed = false
+	d.Reason = "not in email.domainPolicy.allow"
+	return d, nil
+}
+
+// CheckEmailDomainPolicy returns an EmailDomainPolicyError if the email domain policy rejects the
+// given email address.
+func CheckEmailDomainPolicy(ctx context.Context, db dbutil.DB, email string) error {
+	d, err := EvaluateEmailDomainPolicy(ctx, db, email)
+	if err != nil {
+		return err
+	}
+	if !d.Allowed {
+		return EmailDomainPolicyError{Email: email, Domain: d.Domain}
+	}
+	return nil
+}
+
+// NormalizeEmailDomain returns the canonical form of a domain in the email domain policy, which
+// is lowercase without a leading @.
+func NormalizeEmailDomain(domain string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+}
+
+// emailDomainMatches reports whether domain is the domain of the rule or one of its subdomains.
+func emailDomainMatches(domain, rule string) bool {
+	rule = NormalizeEmailDomain(rule)
+	return rule != "" && (domain == rule || strings.HasSuffix(domain, "."+rule))
+}
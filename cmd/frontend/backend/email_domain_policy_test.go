@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestEvaluateEmailDomainPolicy(t *testing.T) {
+	defer func() { database.Mocks.EmailDomainPolicyOverrides = database.MockEmailDomainPolicyOverrides{} }()
+
+	tests := []struct {
+		name      string
+		policy    *schema.EmailDomainPolicy
+		overrides []*database.EmailDomainPolicyOverride
+		email     string
+		allowed   bool
+	}{
+		{name: "no policy", email: "alice@mailinator.com", allowed: true},
+		{name: "not an email address", policy: &schema.EmailDomainPolicy{Allow: []string{"example.com"}}, email: "alice", allowed: true},
+		{name: "denied", policy: &schema.EmailDomainPolicy{Deny: []string{"mailinator.com"}}, email: "alice@mailinator.com"},
+		{name: "denied case-insensitively", policy: &schema.EmailDomainPolicy{Deny: []string{"Mailinator.com"}}, email: "alice@MAILINATOR.COM"},
+		{name: "denied subdomain", policy: &schema.EmailDomainPolicy{Deny: []string{"mailinator.com"}}, email: "alice@eu.mailinator.com"},
+		{name: "not a subdomain", policy: &schema.EmailDomainPolicy{Deny: []string{"mailinator.com"}}, email: "alice@notmailinator.com", allowed: true},
+		{name: "allowed", policy: &schema.EmailDomainPolicy{Allow: []string{"example.com"}}, email: "alice@example.com", allowed: true},
+		{name: "not allowed", policy: &schema.EmailDomainPolicy{Allow: []string{"example.com"}}, email: "alice@example.org"},
+		{
+			name:   "deny takes precedence over allow",
+			policy: &schema.EmailDomainPolicy{Allow: []string{"example.com"}, Deny: []string{"contractors.example.com"}},
+			email:  "alice@contractors.example.com",
+		},
+		{
+			name:      "override allows",
+			policy:    &schema.EmailDomainPolicy{Allow: []string{"example.com"}},
+			overrides: []*database.EmailDomainPolicyOverride{{Domain: "partner.org", Allow: true}},
+			email:     "alice@partner.org",
+			allowed:   true,
+		},
+		{
+			name:      "override denies",
+			overrides: []*database.EmailDomainPolicyOverride{{Domain: "mailinator.com"}},
+			email:     "alice@mailinator.com",
+		},
+		{
+			name: "most specific override wins",
+			overrides: []*database.EmailDomainPolicyOverride{
+				{Domain: "example.com"},
+				{Domain: "eng.example.com", Allow: true},
+			},
+			email:   "alice@eng.example.com",
+			allowed: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{EmailDomainPolicy: test.policy}})
+			defer conf.Mock(nil)
+			database.Mocks.EmailDomainPolicyOverrides.List = func(context.Context) ([]*database.EmailDomainPolicyOverride, error) {
+				return test.overrides, nil
+			}
+
+			d, err := EvaluateEmailDomainPolicy(context.Background(), nil, test.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Allowed != test.allowed {
+				t.Fatalf("want allowed=%t, have %+v", test.allowed, d)
+			}
+			if d.Reason == "" {
+				t.Fatal("want a reason")
+			}
+
+			err = CheckEmailDomainPolicy(context.Background(), nil, test.email)
+			if rejected := IsEmailDomainPolicyError(err); rejected == test.allowed {
+				t.Fatalf("want rejected=%t, have err=%v", !test.allowed, err)
+			}
+			if !test.allowed && !errcode.IsBadRequest(errors.Wrap(err, "adding email")) {
+				t.Fatal("want a bad request error")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/redigostore"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/redispool"
+)
+
+// Verification emails are rate limited per user and per client IP, so that they can't be used to
+// spam or annoy people, or to enumerate the email addresses of users.
+var (
+	emailRateQuotaPerUser = throttled.RateQuota{MaxRate: throttled.PerHour(10), MaxBurst: 4}
+	emailRateQuotaPerIP   = throttled.RateQuota{MaxRate: throttled.PerHour(30), MaxBurst: 9}
+)
+
+// EmailRateLimitError is returned when too many verification emails were requested. Clients can
+// retry after RetryAfter.
+type EmailRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *EmailRateLimitError) Error() string {
+	return fmt.Sprintf("too many verification emails requested, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *EmailRateLimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       "ErrEmailRateLimited",
+		"retryAfter": int(math.Ceil(e.RetryAfter.Seconds())),
+	}
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context that carries the IP address of the client that made the request,
+// which is used to rate limit requests that send emails.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+var (
+	emailRateLimitersOnce sync.Once
+	emailRateLimiters     struct {
+		perUser, perIP *throttled.GCRARateLimiter
+	}
+
+	// mockEmailRateLimitStore is used instead of Redis in tests.
+	mockEmailRateLimitStore throttled.GCRAStore
+)
+
+func initEmailRateLimiters() {
+	var store throttled.GCRAStore = mockEmailRateLimitStore
+	if store == nil {
+		var err error
+		store, err = redigostore.New(redispool.Cache, "email:rl:", 0)
+		if err != nil {
+			log15.Error("Failed to create email rate limit store, email rate limits are disabled", "error", err)
+			return
+		}
+	}
+
+	perUser, err := throttled.NewGCRARateLimiter(store, emailRateQuotaPerUser)
+	if err != nil {
+		log15.Error("Failed to create email rate limiter, email rate limits are disabled", "error", err)
+		return
+	}
+	perIP, err := throttled.NewGCRARateLimiter(store, emailRateQuotaPerIP)
+	if err != nil {
+		log15.Error("Failed to create email rate limiter, email rate limits are disabled", "error", err)
+		return
+	}
+	emailRateLimiters.perUser, emailRateLimiters.perIP = perUser, perIP
+}
+
+// CheckEmailRateLimit returns an *EmailRateLimitError if the given user, or the client the request
+// comes from, requested too many verification emails recently. Otherwise it counts the request
+// against both limits. Site admins are not limited.
+//
+// If the limits can't be checked, for example because Redis is unavailable, the request is
+// allowed, so that users can still verify their email addresses.
+func CheckEmailRateLimit(ctx context.Context, db dbutil.DB, userID int32) error {
+	if CheckCurrentUserIsSiteAdmin(ctx, db) == nil {
+		return nil
+	}
+
+	emailRateLimitersOnce.Do(initEmailRateLimiters)
+	if emailRateLimiters.perUser == nil {
+		return nil
+	}
+
+	limiters := map[string]*throttled.GCRARateLimiter{
+		"user:" + strconv.Itoa(int(userID)): emailRateLimiters.perUser,
+	}
+	if ip := clientIPFromContext(ctx); ip != "" {
+		limiters["ip:"+ip] = emailRateLimiters.perIP
+	}
+
+	for key, limiter := range limiters {
+		limited, result, err := limiter.RateLimit(key, 1)
+		if err != nil {
+			log15.Error("Failed to check email rate limit", "key", key, "error", err)
+			continue
+		}
+		if limited {
+			return &EmailRateLimitError{RetryAfter: result.RetryAfter}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/throttled/throttled/v2/store/memstore"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestCheckEmailRateLimit(t *testing.T) {
+	store, err := memstore.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockEmailRateLimitStore = store
+	emailRateLimitersOnce = sync.Once{}
+	defer func() {
+		mockEmailRateLimitStore = nil
+		emailRateLimitersOnce = sync.Once{}
+		emailRateLimiters.perUser, emailRateLimiters.perIP = nil, nil
+	}()
+
+	database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		uid := actor.FromContext(ctx).UID
+		return &types.User{ID: uid, SiteAdmin: uid == 99}, nil
+	}
+	defer func() { database.Mocks.Users.GetByCurrentAuthUser = nil }()
+
+	// exhaust calls CheckEmailRateLimit until the first error, which it returns.
+	exhaust := func(ctx context.Context, userID int32) (int, error) {
+		for i := 1; i <= 100; i++ {
+			if err := CheckEmailRateLimit(ctx, nil, userID); err != nil {
+				return i, err
+			}
+		}
+		return 0, nil
+	}
+
+	t.Run("per user", func(t *testing.T) {
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		n, err := exhaust(ctx, 1)
+		var rateLimitErr *EmailRateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("got err %v, want *EmailRateLimitError", err)
+		}
+		if want := emailRateQuotaPerUser.MaxBurst + 2; n != want {
+			t.Errorf("limited after %d requests, want %d", n, want)
+		}
+		if retryAfter, _ := rateLimitErr.Extensions()["retryAfter"].(int); retryAfter <= 0 {
+			t.Errorf("got retryAfter %d, want > 0", retryAfter)
+		}
+
+		// Other users are limited separately.
+		ctx = actor.WithActor(context.Background(), &actor.Actor{UID: 2})
+		if err := CheckEmailRateLimit(ctx, nil, 2); err != nil {
+			t.Fatalf("unexpected error for other user: %v", err)
+		}
+	})
+
+	t.Run("per IP", func(t *testing.T) {
+		var n int
+		for uid := int32(10); uid < 20; uid++ {
+			ctx := WithClientIP(actor.WithActor(context.Background(), &actor.Actor{UID: uid}), "10.0.0.1")
+			for i := 0; i < emailRateQuotaPerUser.MaxBurst+1; i++ {
+				n++
+				if err := CheckEmailRateLimit(ctx, nil, uid); err != nil {
+					if want := emailRateQuotaPerIP.MaxBurst + 2; n != want {
+						t.Errorf("limited after %d requests, want %d", n, want)
+					}
+					return
+				}
+			}
+		}
+		t.Fatal("requests from the same IP were not limited")
+	})
+
+	t.Run("site admins are not limited", func(t *testing.T) {
+		ctx := WithClientIP(actor.WithActor(context.Background(), &actor.Actor{UID: 99}), "10.0.0.1")
+		if n, err := exhaust(ctx, 1); err != nil {
+			t.Fatalf("site admin limited after %d requests: %v", n, err)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// Identity changes are recorded as backend events in the event_logs table, so that site admins can
+// review recent changes to the email addresses and two-factor authentication of users (e.g. when
+// investigating an account takeover).
+const (
+	IdentityChangeUserEmailAdded           = "UserEmailAdded"
+	IdentityChangeUserEmailRemoved         = "UserEmailRemoved"
+	IdentityChangeUserEmailPrimaryChanged  = "UserEmailPrimaryChanged"
+	IdentityChangeUserEmailVerifiedChanged = "UserEmailVerifiedChanged"
+	IdentityChangeTwoFactorChanged         = "TwoFactorChanged"
+)
+
+// IdentityChangeEventNames are the names of all identity change events.
+var IdentityChangeEventNames = []string{
+	IdentityChangeUserEmailAdded,
+	IdentityChangeUserEmailRemoved,
+	IdentityChangeUserEmailPrimaryChanged,
+	IdentityChangeUserEmailVerifiedChanged,
+	IdentityChangeTwoFactorChanged,
+}
+
+// IdentityChange describes a change to the identity of a user. It is stored as the argument of the
+// event.
+type IdentityChange struct {
+	// Actor is the ID of the user who made the change, or 0 if it was made by Sourcegraph itself.
+	Actor int32 `json:"actor"`
+	// IP is the IP address of the client that made the change, if known.
+	IP       string `json:"ip,omitempty"`
+	Email    string `json:"email,omitempty"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+}
+
+// LogIdentityChange records a change to the identity of the given user. Failures are logged
+// instead of returned, because the change itself has already been made.
+func LogIdentityChange(ctx context.Context, db dbutil.DB, name string, userID int32, change IdentityChange) {
+	change.Actor = actor.FromContext(ctx).UID
+	change.IP = clientIPFromContext(ctx)
+
+	args, err := json.Marshal(change)
+	if err != nil {
+		log15.Error("LogIdentityChange: failed to marshal JSON", "error", err)
+		return
+	}
+
+	// 🚨 SECURITY: The event is inserted directly instead of going through usagestats, so that it is
+	// recorded even if event logging is disabled and the email addresses are never exported to
+	// analytics.
+	if err := database.EventLogs(db).Insert(ctx, &database.Event{
+		Name:      name,
+		UserID:    uint32(userID),
+		Argument:  args,
+		Source:    "BACKEND",
+		Timestamp: time.Now(),
+	}); err != nil {
+		log15.Error("LogIdentityChange: failed to insert event", "name", name, "userID", userID, "error", err)
+	}
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// Organizations prove control of a domain by publishing a DNS TXT record with the domain's
+// verification token. Once a domain is verified, users who verify an email address of the domain
+// join the organization. The address itself is still verified like any other one, because a
+// verified domain doesn't prove that the user receives its mail.
+
+const orgDomainVerificationRecordPrefix = "sourcegraph-domain-verification="
+
+// OrgDomainCheckPeriod is how long after a domain is added its DNS TXT records are checked in the
+// background. After that, the domain can still be checked on demand.
+const OrgDomainCheckPeriod = 7 * 24 * time.Hour
+
+// orgDomainCheckInterval is how often the DNS TXT records of a pending domain are checked in the
+// background.
+const orgDomainCheckInterval = 10 * time.Minute
+
+// lookupTXT is mocked in tests.
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// OrgDomainVerificationRecord returns the DNS TXT record that proves control of the domain.
+func OrgDomainVerificationRecord(d *database.OrgDomain) string {
+	return orgDomainVerificationRecordPrefix + d.VerificationToken
+}
+
+// MakeOrgDomainVerificationToken returns a random token for a domain's verification record.
+func MakeOrgDomainVerificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CheckOrgDomain looks up the DNS TXT records of the domain and marks the domain as verified if
+// one of them is its verification record. It returns whether the domain is verified.
+func CheckOrgDomain(ctx context.Context, db dbutil.DB, d *database.OrgDomain) (bool, error) {
+	if d.VerifiedAt != nil {
+		return true, nil
+	}
+
+	records, err := lookupTXT(ctx, d.Domain)
+	if err != nil {
+		// A domain without TXT records just isn't verified yet.
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return false, errors.Wrapf(err, "looking up DNS TXT records of %s", d.Domain)
+		}
+	}
+	verified := false
+	for _, r := range records {
+		if strings.TrimSpace(r) == OrgDomainVerificationRecord(d) {
+			verified = true
+			break
+		}
+	}
+
+	if err := database.OrgDomains(db).MarkChecked(ctx, d.ID, verified); err != nil {
+		return false, err
+	}
+	return verified, nil
+}
+
+// CheckPendingOrgDomains checks the DNS TXT records of the domains that were added in the last
+// OrgDomainCheckPeriod and are not verified yet.
+func CheckPendingOrgDomains(ctx context.Context, db dbutil.DB) error {
+	now := time.Now()
+	domains, err := database.OrgDomains(db).ListDueForCheck(ctx, now.Add(-OrgDomainCheckPeriod), now.Add(-orgDomainCheckInterval), 100)
+	if err != nil {
+		return err
+	}
+	for _, d := range domains {
+		if verified, err := CheckOrgDomain(ctx, db, d); err != nil {
+			log15.Warn("Failed to check organization domain", "orgID", d.OrgID, "domain", d.Domain, "error", err)
+		} else if verified {
+			log15.Info("Verified organization domain", "orgID", d.OrgID, "domain", d.Domain)
+		}
+	}
+	return nil
+}
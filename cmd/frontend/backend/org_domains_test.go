@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+func TestCheckOrgDomain(t *testing.T) {
+	defer func() {
+		lookupTXT = net.DefaultResolver.LookupTXT
+		database.Mocks.OrgDomains = database.MockOrgDomains{}
+	}()
+
+	d := &database.OrgDomain{ID: 1, OrgID: 2, Domain: "example.com", VerificationToken: "abc"}
+	tests := []struct {
+		name         string
+		records      []string
+		lookupErr    error
+		wantVerified bool
+		wantErr      bool
+	}{
+		{name: "verified", records: []string{"v=spf1 -all", " sourcegraph-domain-verification=abc "}, wantVerified: true},
+		{name: "wrong token", records: []string{"sourcegraph-domain-verification=abd"}},
+		{name: "no records", lookupErr: &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}},
+		{name: "lookup failure", lookupErr: errors.New("timeout"), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lookupTXT = func(_ context.Context, name string) ([]string, error) {
+				if name != d.Domain {
+					t.Fatalf("looked up %q", name)
+				}
+				return test.records, test.lookupErr
+			}
+			checked := false
+			database.Mocks.OrgDomains.MarkChecked = func(_ context.Context, id int32, verified bool) error {
+				checked = true
+				if id != d.ID || verified != test.wantVerified {
+					t.Fatalf("got MarkChecked(%d, %t)", id, verified)
+				}
+				return nil
+			}
+
+			verified, err := CheckOrgDomain(context.Background(), nil, d)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got err %v", err)
+			}
+			if verified != test.wantVerified {
+				t.Fatalf("got verified %t, want %t", verified, test.wantVerified)
+			}
+			if checked == test.wantErr {
+				t.Fatalf("got checked %t", checked)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// defaultRoleEmailAddressPatterns is used if email.roleAddresses doesn't set patterns. It must be
+// kept in sync with the default in the site configuration schema.
+var defaultRoleEmailAddressPatterns = []string{
+	"admin",
+	"administrator",
+	"hostmaster",
+	"info",
+	"no-?reply",
+	"postmaster",
+	"root",
+	"sales",
+	"security",
+	"support",
+	"webmaster",
+}
+
+// RoleEmailAddressError is returned when adding an email address that the email.roleAddresses
+// policy rejects as a role or shared mailbox address.
+type RoleEmailAddressError struct {
+	Email string
+}
+
+func (e RoleEmailAddressError) Error() string {
+	return fmt.Sprintf("%s is a role or shared mailbox address, which can't be added to a user account", e.Email)
+}
+
+func (RoleEmailAddressError) BadRequest() bool { return true }
+
+// IsRoleEmailAddressError reports whether err or one of its causes is a RoleEmailAddressError.
+func IsRoleEmailAddressError(err error) bool {
+	var e RoleEmailAddressError
+	return errors.As(err, &e)
+}
+
+// CheckRoleEmailAddress returns a RoleEmailAddressError if the email.roleAddresses policy is
+// enabled and rejects the given email address.
+func CheckRoleEmailAddress(email string) error {
+	cfg := conf.Get().EmailRoleAddresses
+	if cfg == nil || !cfg.Reject {
+		return nil
+	}
+
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultRoleEmailAddressPatterns
+	}
+	re, err := roleEmailAddressRegexps.get(patterns)
+	if err != nil {
+		return err
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		// Not an email address, which is reported elsewhere.
+		return nil
+	}
+	local := email[:at]
+	// Subaddresses (support+sourcegraph@) are delivered to the same mailbox.
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+
+	if re.MatchString(local) {
+		return RoleEmailAddressError{Email: email}
+	}
+	return nil
+}
+
+// roleEmailAddressRegexps caches the regexp compiled from the most recently used patterns, so that
+// they are only compiled again when the site configuration changes.
+var roleEmailAddressRegexps roleEmailAddressRegexpCache
+
+type roleEmailAddressRegexpCache struct {
+	mu  sync.Mutex
+	key string
+	re  *regexp.Regexp
+}
+
+// get returns a regexp matching a local part that matches any of the patterns as a whole,
+// case-insensitively.
+func (c *roleEmailAddressRegexpCache) get(patterns []string) (*regexp.Regexp, error) {
+	key := strings.Join(patterns, "\x00")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.re != nil && c.key == key {
+		return c.re, nil
+	}
+
+	alternatives := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		// Compile each pattern on its own to report which one is invalid.
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q in email.roleAddresses", pattern)
+		}
+		alternatives = append(alternatives, "(?:"+pattern+")")
+	}
+	re, err := regexp.Compile("^(?i:" + strings.Join(alternatives, "|") + ")$")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid patterns in email.roleAddresses")
+	}
+	c.key, c.re = key, re
+	return re, nil
+}
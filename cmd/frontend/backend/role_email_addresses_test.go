@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestCheckRoleEmailAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *schema.EmailRoleAddresses
+		email    string
+		rejected bool
+	}{
+		{name: "no policy", email: "admin@example.com"},
+		{name: "policy disabled", policy: &schema.EmailRoleAddresses{}, email: "admin@example.com"},
+		{name: "default patterns", policy: &schema.EmailRoleAddresses{Reject: true}, email: "admin@example.com", rejected: true},
+		{name: "case-insensitive", policy: &schema.EmailRoleAddresses{Reject: true}, email: "No-Reply@example.com", rejected: true},
+		{name: "subaddress", policy: &schema.EmailRoleAddresses{Reject: true}, email: "support+sourcegraph@example.com", rejected: true},
+		{name: "whole local part", policy: &schema.EmailRoleAddresses{Reject: true}, email: "adminton@example.com"},
+		{name: "personal address", policy: &schema.EmailRoleAddresses{Reject: true}, email: "alice@example.com"},
+		{
+			name:     "custom patterns",
+			policy:   &schema.EmailRoleAddresses{Reject: true, Patterns: []string{"team-.*"}},
+			email:    "team-frontend@example.com",
+			rejected: true,
+		},
+		{
+			name:   "custom patterns replace defaults",
+			policy: &schema.EmailRoleAddresses{Reject: true, Patterns: []string{"team-.*"}},
+			email:  "admin@example.com",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{EmailRoleAddresses: test.policy}})
+			defer conf.Mock(nil)
+
+			err := CheckRoleEmailAddress(test.email)
+			if rejected := IsRoleEmailAddressError(err); rejected != test.rejected {
+				t.Fatalf("want rejected=%t, have err=%v", test.rejected, err)
+			}
+			if test.rejected && !errcode.IsBadRequest(errors.Wrap(err, "adding email")) {
+				t.Fatal("want a bad request error")
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{EmailRoleAddresses: &schema.EmailRoleAddresses{
+			Reject:   true,
+			Patterns: []string{"("},
+		}}})
+		defer conf.Mock(nil)
+
+		if err := CheckRoleEmailAddress("admin@example.com"); err == nil || IsRoleEmailAddressError(err) {
+			t.Fatalf("want pattern error, have %v", err)
+		}
+	})
+}
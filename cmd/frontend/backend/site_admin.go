@@ -7,6 +7,7 @@ import (
 	"github.com/cockroachdb/errors"
 
 	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -15,7 +16,13 @@ import (
 
 var ErrMustBeSiteAdmin = errors.New("must be site admin")
 
-// CheckCurrentUserIsSiteAdmin returns an error if the current user is NOT a site admin.
+// ErrAccessTokenAdminScopeRequired occurs when a site admin uses an access token with
+// fine-grained scopes that don't include the admin scope to perform a site admin action.
+var ErrAccessTokenAdminScopeRequired = errors.Errorf("access token must have scope %q to perform site admin actions", authz.ScopeAdmin)
+
+// CheckCurrentUserIsSiteAdmin returns an error if the current user is NOT a site admin, is a site
+// admin who must enable two-factor authentication first, or uses an access token whose scopes
+// don't allow site admin actions.
 func CheckCurrentUserIsSiteAdmin(ctx context.Context, db dbutil.DB) error {
 	if actor.FromContext(ctx).IsInternal() {
 		return nil
@@ -30,6 +37,12 @@ func CheckCurrentUserIsSiteAdmin(ctx context.Context, db dbutil.DB) error {
 	if !user.SiteAdmin {
 		return ErrMustBeSiteAdmin
 	}
+	if isTwoFactorEnrollmentPending(ctx) {
+		return ErrTwoFactorEnrollmentRequired
+	}
+	if scopes, restricted := authz.AccessTokenScopesFromContext(ctx); restricted && !authz.HasScope(scopes, authz.ScopeAdmin) {
+		return ErrAccessTokenAdminScopeRequired
+	}
 	return nil
 }
 
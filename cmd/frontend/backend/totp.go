@@ -0,0 +1,276 @@
+package backend
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// Two-factor authentication uses time-based one-time passwords (RFC 6238) with the parameters that
+// all common authenticator apps support.
+const (
+	totpPeriod = 30 // seconds
+	totpDigits = 6
+	totpModulo = 1000000 // 10^totpDigits
+
+	// totpSkew is the number of time steps before and after the current one whose codes are
+	// accepted, to allow for clock drift and for the time it takes to enter a code.
+	totpSkew = 1
+
+	totpRecoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var (
+	ErrInvalidTOTPCode          = errors.New("invalid two-factor authentication code")
+	ErrTOTPNotEnabled           = errors.New("two-factor authentication is not enabled")
+	ErrTOTPEnrollmentNotStarted = errors.New("two-factor authentication enrollment was not started")
+
+	// ErrTwoFactorEnrollmentRequired occurs when a site admin who is required to enable two-factor
+	// authentication (see auth.twoFactor) performs a site admin action without having enabled it.
+	ErrTwoFactorEnrollmentRequired = errors.New("two-factor authentication must be enabled to perform site admin actions (see auth.twoFactor)")
+)
+
+// UserTOTP contains backend methods related to two-factor authentication with TOTP.
+var UserTOTP = &userTOTP{}
+
+type userTOTP struct{}
+
+// TOTPEnrollment is a TOTP secret that the user adds to their authenticator app, either by
+// entering the secret or by scanning a QR code of the key URI.
+type TOTPEnrollment struct {
+	Secret string
+	KeyURI string
+}
+
+// Enroll starts the enrollment of the user in two-factor authentication with a new secret. The
+// enrollment must be confirmed with ConfirmEnrollment.
+func (userTOTP) Enroll(ctx context.Context, db dbutil.DB, userID int32) (*TOTPEnrollment, error) {
+	user, err := database.Users(db).GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 20) // 160 bits, as recommended by RFC 4226
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	secret := totpEncoding.EncodeToString(b)
+	if err := database.UserTOTPs(db).CreatePending(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, KeyURI: totpKeyURI(globals.ExternalURL().Host, user.Username, secret)}, nil
+}
+
+// totpKeyURI returns the otpauth:// URI of the secret, as understood by authenticator apps.
+func totpKeyURI(host, username, secret string) string {
+	issuer := "Sourcegraph"
+	if host != "" {
+		issuer += " (" + host + ")"
+	}
+	q := make(url.Values)
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + username,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// ConfirmEnrollment enables two-factor authentication for the user if the code is valid for the
+// secret of the pending enrollment. It returns the user's new recovery codes, which are only
+// stored hashed and can't be shown again.
+func (userTOTP) ConfirmEnrollment(ctx context.Context, db dbutil.DB, userID int32, code string) ([]string, error) {
+	store := database.UserTOTPs(db)
+	t, err := store.GetByUserID(ctx, userID)
+	if errcode.IsNotFound(err) {
+		return nil, ErrTOTPEnrollmentNotStarted
+	} else if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, database.ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := validateTOTPCode(t.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+	codes, hashes, err := makeTOTPRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Enabled reports whether the user enabled two-factor authentication.
+func (userTOTP) Enabled(ctx context.Context, db dbutil.DB, userID int32) (bool, error) {
+	t, err := database.UserTOTPs(db).GetByUserID(ctx, userID)
+	if errcode.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return t.EnabledAt != nil, nil
+}
+
+// Verify reports whether the code is a valid TOTP code or an unused recovery code of the user.
+// Each code is accepted only once. After too many failed attempts, it returns a
+// *TOTPRateLimitError without checking the code.
+func (userTOTP) Verify(ctx context.Context, db dbutil.DB, userID int32, code string) (bool, error) {
+	store := database.UserTOTPs(db)
+	t, err := store.GetByUserID(ctx, userID)
+	if errcode.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if t.EnabledAt == nil {
+		return false, nil
+	}
+	if err := checkTOTPRateLimit(userID); err != nil {
+		return false, err
+	}
+
+	code = strings.Join(strings.Fields(code), "")
+	var ok bool
+	if step, valid := validateTOTPCode(t.Secret, code, time.Now()); valid {
+		ok, err = store.UseStep(ctx, userID, step)
+	} else {
+		ok, err = store.UseRecoveryCode(ctx, userID, hashTOTPRecoveryCode(code))
+	}
+	if err != nil || ok {
+		return ok, err
+	}
+	recordTOTPFailure(userID)
+	return false, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, who must have enabled
+// two-factor authentication, and returns the new ones.
+func (u userTOTP) RegenerateRecoveryCodes(ctx context.Context, db dbutil.DB, userID int32) ([]string, error) {
+	if enabled, err := u.Enabled(ctx, db, userID); err != nil {
+		return nil, err
+	} else if !enabled {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	codes, hashes, err := makeTOTPRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := database.UserTOTPs(db).ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable disables two-factor authentication for the user. It returns false if the user had not
+// enabled or started enrolling in it.
+func (userTOTP) Disable(ctx context.Context, db dbutil.DB, userID int32) (bool, error) {
+	return database.UserTOTPs(db).Delete(ctx, userID)
+}
+
+// validateTOTPCode returns the time step of the code if it is valid for the base32-encoded secret
+// at the given time.
+func validateTOTPCode(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		// 🚨 SECURITY: Use constant-time comparisons to avoid leaking the code via timing attack.
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode returns the code of the time step (RFC 6238 section 4, using the HOTP algorithm of RFC
+// 4226 section 5.3).
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo)
+}
+
+// makeTOTPRecoveryCodes returns new recovery codes (formatted like "0123a-4567b") and their
+// hashes.
+func makeTOTPRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < totpRecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashTOTPRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashTOTPRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// TwoFactorRequired reports whether the user must enable two-factor authentication, according to
+// the auth.twoFactor site configuration.
+func TwoFactorRequired(user *types.User) bool {
+	cfg := conf.Get().AuthTwoFactor
+	return cfg != nil && cfg.RequireForSiteAdmins && user.SiteAdmin
+}
+
+type twoFactorEnrollmentPendingKey struct{}
+
+// WithTwoFactorEnrollmentPending returns a context for a request of a user who is required to
+// enable two-factor authentication but hasn't. Site admin checks fail with
+// ErrTwoFactorEnrollmentRequired for such requests.
+func WithTwoFactorEnrollmentPending(ctx context.Context) context.Context {
+	return context.WithValue(ctx, twoFactorEnrollmentPendingKey{}, true)
+}
+
+func isTwoFactorEnrollmentPending(ctx context.Context) bool {
+	pending, _ := ctx.Value(twoFactorEnrollmentPendingKey{}).(bool)
+	return pending
+}
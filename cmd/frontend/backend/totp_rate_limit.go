@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/redigostore"
+
+	"github.com/sourcegraph/sourcegraph/internal/redispool"
+)
+
+// Failed two-factor authentication attempts are rate limited per user, so that the codes can't be
+// guessed by brute force once the password (or a session) is known. Only failed attempts count:
+// after MaxBurst+1 of them, the user can make another attempt every totpFailureInterval.
+const totpFailureInterval = 5 * time.Minute
+
+var totpRateQuota = throttled.RateQuota{MaxRate: throttled.PerDuration(1, totpFailureInterval), MaxBurst: 5}
+
+// TOTPRateLimitError is returned when a user made too many failed two-factor authentication
+// attempts. Clients can retry after RetryAfter.
+type TOTPRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TOTPRateLimitError) Error() string {
+	return fmt.Sprintf("too many failed two-factor authentication attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *TOTPRateLimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       "ErrTOTPRateLimited",
+		"retryAfter": int(math.Ceil(e.RetryAfter.Seconds())),
+	}
+}
+
+var (
+	totpRateLimiterOnce sync.Once
+	totpRateLimiter     *throttled.GCRARateLimiter
+
+	// mockTOTPRateLimitStore is used instead of Redis in tests.
+	mockTOTPRateLimitStore throttled.GCRAStore
+)
+
+func initTOTPRateLimiter() {
+	var store throttled.GCRAStore = mockTOTPRateLimitStore
+	if store == nil {
+		var err error
+		store, err = redigostore.New(redispool.Cache, "totp:rl:", 0)
+		if err != nil {
+			log15.Error("Failed to create two-factor authentication rate limit store, rate limits are disabled", "error", err)
+			return
+		}
+	}
+
+	limiter, err := throttled.NewGCRARateLimiter(store, totpRateQuota)
+	if err != nil {
+		log15.Error("Failed to create two-factor authentication rate limiter, rate limits are disabled", "error", err)
+		return
+	}
+	totpRateLimiter = limiter
+}
+
+func totpRateLimitKey(userID int32) string {
+	return "user:" + strconv.Itoa(int(userID))
+}
+
+// checkTOTPRateLimit returns a *TOTPRateLimitError if the user used up their failed attempts,
+// without counting an attempt.
+//
+// If the limit can't be checked, for example because Redis is unavailable, the attempt is allowed,
+// so that users can still sign in.
+func checkTOTPRateLimit(userID int32) error {
+	totpRateLimiterOnce.Do(initTOTPRateLimiter)
+	if totpRateLimiter == nil {
+		return nil
+	}
+
+	// A quantity of 0 only reads the state of the limiter. It never reports the key as limited,
+	// so check whether another failed attempt would be allowed instead.
+	key := totpRateLimitKey(userID)
+	_, result, err := totpRateLimiter.RateLimit(key, 0)
+	if err != nil {
+		log15.Error("Failed to check two-factor authentication rate limit", "key", key, "error", err)
+		return nil
+	}
+	if result.Remaining > 0 {
+		return nil
+	}
+	// The next attempt is allowed once the oldest of the last MaxBurst+1 failures expired.
+	retryAfter := result.ResetAfter - time.Duration(totpRateQuota.MaxBurst)*totpFailureInterval
+	if retryAfter <= 0 {
+		return nil
+	}
+	return &TOTPRateLimitError{RetryAfter: retryAfter}
+}
+
+// recordTOTPFailure counts a failed attempt of the user against the limit.
+func recordTOTPFailure(userID int32) {
+	totpRateLimiterOnce.Do(initTOTPRateLimiter)
+	if totpRateLimiter == nil {
+		return
+	}
+
+	key := totpRateLimitKey(userID)
+	if _, _, err := totpRateLimiter.RateLimit(key, 1); err != nil {
+		log15.Error("Failed to record failed two-factor authentication attempt", "key", key, "error", err)
+	}
+}
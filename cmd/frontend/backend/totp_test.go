@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"context"
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/throttled/throttled/v2/store/memstore"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238 Appendix B (SHA1), truncated to 6 digits.
+	key := []byte("12345678901234567890")
+	for _, test := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		if got := totpCode(key, test.unix/totpPeriod); got != test.want {
+			t.Errorf("at %d: got %q, want %q", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+
+	if step, ok := validateTOTPCode(secret, "050471", now); !ok || step != 1111111111/totpPeriod {
+		t.Errorf("got %d, %v, want the current code to be valid", step, ok)
+	}
+	// The code of the previous step is still accepted to allow for clock drift.
+	if _, ok := validateTOTPCode(secret, "081804", now); !ok {
+		t.Error("want the code of the previous step to be valid")
+	}
+	if _, ok := validateTOTPCode(secret, "081804", now.Add(2*totpPeriod*time.Second)); ok {
+		t.Error("want an old code to be invalid")
+	}
+	for _, code := range []string{"", "123456", "05047", "0504710"} {
+		if _, ok := validateTOTPCode(secret, code, now); ok {
+			t.Errorf("want %q to be invalid", code)
+		}
+	}
+	if _, ok := validateTOTPCode("not base32!", "050471", now); ok {
+		t.Error("want an invalid secret to be rejected")
+	}
+}
+
+func TestTOTPKeyURI(t *testing.T) {
+	u, err := url.Parse(totpKeyURI("sourcegraph.example.com", "alice", "SECRET"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Sourcegraph (sourcegraph.example.com):alice" {
+		t.Errorf("unexpected URI %s", u)
+	}
+	if q := u.Query(); q.Get("secret") != "SECRET" || q.Get("issuer") != "Sourcegraph (sourcegraph.example.com)" || q.Get("digits") != "6" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestUserTOTP_Verify(t *testing.T) {
+	defer func() { database.Mocks.UserTOTPs = database.MockUserTOTPs{} }()
+	ctx := context.Background()
+
+	key := []byte("12345678901234567890")
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	enabledAt := time.Now()
+	database.Mocks.UserTOTPs.GetByUserID = func(context.Context, int32) (*database.UserTOTP, error) {
+		return &database.UserTOTP{UserID: 1, Secret: secret, EnabledAt: &enabledAt}, nil
+	}
+	var usedStep int64
+	database.Mocks.UserTOTPs.UseStep = func(_ context.Context, _ int32, step int64) (bool, error) {
+		usedStep = step
+		return true, nil
+	}
+	var usedHash string
+	database.Mocks.UserTOTPs.UseRecoveryCode = func(_ context.Context, _ int32, hash string) (bool, error) {
+		usedHash = hash
+		return hash == hashTOTPRecoveryCode("0123a-4567b"), nil
+	}
+
+	step := time.Now().Unix() / totpPeriod
+	if ok, err := UserTOTP.Verify(ctx, nil, 1, totpCode(key, step)); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the current code to be accepted", ok, err)
+	}
+	if usedStep != step {
+		t.Fatalf("got used step %d, want %d", usedStep, step)
+	}
+
+	// Recovery codes are accepted regardless of case and dashes.
+	if ok, err := UserTOTP.Verify(ctx, nil, 1, strings.ToUpper("0123a4567b")); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the recovery code to be accepted", ok, err)
+	}
+	if ok, err := UserTOTP.Verify(ctx, nil, 1, "nope"); err != nil || ok {
+		t.Fatalf("got %v, %v, want an invalid code to be rejected", ok, err)
+	}
+	if usedHash != hashTOTPRecoveryCode("nope") {
+		t.Fatalf("want the invalid code to be checked as a recovery code")
+	}
+
+	// Codes are rejected while the enrollment is pending.
+	database.Mocks.UserTOTPs.GetByUserID = func(context.Context, int32) (*database.UserTOTP, error) {
+		return &database.UserTOTP{UserID: 1, Secret: secret}, nil
+	}
+	if ok, err := UserTOTP.Verify(ctx, nil, 1, totpCode(key, step)); err != nil || ok {
+		t.Fatalf("got %v, %v, want codes to be rejected while the enrollment is pending", ok, err)
+	}
+}
+
+func TestUserTOTP_VerifyRateLimit(t *testing.T) {
+	store, err := memstore.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockTOTPRateLimitStore = store
+	totpRateLimiterOnce = sync.Once{}
+	defer func() {
+		mockTOTPRateLimitStore = nil
+		totpRateLimiterOnce = sync.Once{}
+		totpRateLimiter = nil
+		database.Mocks.UserTOTPs = database.MockUserTOTPs{}
+	}()
+	ctx := context.Background()
+
+	key := []byte("12345678901234567890")
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	enabledAt := time.Now()
+	database.Mocks.UserTOTPs.GetByUserID = func(_ context.Context, userID int32) (*database.UserTOTP, error) {
+		return &database.UserTOTP{UserID: userID, Secret: secret, EnabledAt: &enabledAt}, nil
+	}
+	database.Mocks.UserTOTPs.UseStep = func(context.Context, int32, int64) (bool, error) { return true, nil }
+	database.Mocks.UserTOTPs.UseRecoveryCode = func(context.Context, int32, string) (bool, error) { return false, nil }
+
+	// Valid codes don't count against the limit.
+	step := time.Now().Unix() / totpPeriod
+	for i := 0; i < 20; i++ {
+		if ok, err := UserTOTP.Verify(ctx, nil, 1, totpCode(key, step)); err != nil || !ok {
+			t.Fatalf("got %v, %v, want the current code to be accepted", ok, err)
+		}
+	}
+
+	failures := 0
+	for ; failures < 100; failures++ {
+		ok, err := UserTOTP.Verify(ctx, nil, 1, "000000")
+		var rateLimitErr *TOTPRateLimitError
+		if errors.As(err, &rateLimitErr) {
+			if rateLimitErr.RetryAfter <= 0 {
+				t.Errorf("got retry after %s, want > 0", rateLimitErr.RetryAfter)
+			}
+			break
+		}
+		if err != nil || ok {
+			t.Fatalf("got %v, %v, want an invalid code to be rejected", ok, err)
+		}
+	}
+	if want := totpRateQuota.MaxBurst + 1; failures != want {
+		t.Errorf("limited after %d failed attempts, want %d", failures, want)
+	}
+
+	// Once limited, even valid codes are refused.
+	if _, err := UserTOTP.Verify(ctx, nil, 1, totpCode(key, step)); err == nil {
+		t.Fatal("want valid code to be refused while limited")
+	}
+
+	// Other users are limited separately.
+	if ok, err := UserTOTP.Verify(ctx, nil, 2, totpCode(key, step)); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the code of another user to be accepted", ok, err)
+	}
+}
+
+func TestMakeTOTPRecoveryCodes(t *testing.T) {
+	codes, hashes, err := makeTOTPRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != totpRecoveryCodeCount || len(hashes) != totpRecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), totpRecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %q", code)
+		}
+		if hashes[i] != hashTOTPRecoveryCode(code) {
+			t.Errorf("hash of %q doesn't match", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/router"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+)
+
+// emailChangeRequestTTL is how long the link to confirm an email change is valid.
+const emailChangeRequestTTL = 24 * time.Hour
+
+// ErrInvalidEmailChangeToken is returned when an email change can't be confirmed, because the
+// token is malformed, expired, was issued for another user or the change was already confirmed.
+var ErrInvalidEmailChangeToken = errors.New("the link to confirm the email change is invalid or expired")
+
+// emailChangeClaims is the signed part of the token that confirms an email change.
+type emailChangeClaims struct {
+	UserID int32  `json:"u"`
+	Email  string `json:"e"`
+}
+
+// signEmailChangeToken returns the token that confirms the email change, signed with the secret
+// of the change request.
+func signEmailChangeToken(secret string, claims emailChangeClaims) (string, error) {
+	return signToken(secret, claims)
+}
+
+// parseEmailChangeToken returns the claims of the token, without verifying its signature, and a
+// function that does.
+func parseEmailChangeToken(token string) (claims emailChangeClaims, verify func(secret string) bool, err error) {
+	verify, err = parseSignedToken(token, &claims)
+	if err != nil {
+		return claims, nil, ErrInvalidEmailChangeToken
+	}
+	return claims, verify, nil
+}
+
+// RequestChange starts changing the primary email address of the user to the given address. The
+// change only takes effect once it is confirmed with ConfirmChange, using the token that is sent
+// to the new address. Until then the current primary address stays in place, so that an address
+// the user doesn't control never becomes primary.
+//
+// A user has at most one pending change; requesting another one invalidates the earlier link.
+func (userEmails) RequestChange(ctx context.Context, db dbutil.DB, userID int32, email string) error {
+	// 🚨 SECURITY: Only the user and site admins can change the email address of a user, and only
+	// site admins can change the one of a service account.
+	if err := CheckCanEditUserEmails(ctx, db, userID); err != nil {
+		return err
+	}
+
+	usr, err := database.Users(db).GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if IsServiceAccount(usr) {
+		// Nobody reads the inboxes of service accounts, so the change could never be confirmed.
+		return errors.New("service accounts can't confirm email changes, add a verified email address and set it as primary instead")
+	}
+	if err := CheckRoleEmailAddress(email); err != nil {
+		return err
+	}
+	if err := CheckEmailDomainPolicy(ctx, db, email); err != nil {
+		return err
+	}
+
+	if _, verified, err := database.UserEmails(db).Get(ctx, userID, email); err == nil && verified {
+		return errors.New("the email address is already verified, set it as primary instead")
+	}
+
+	// Prevent abuse (users sending confirmation emails to people whom they want to annoy).
+	if isSiteAdmin := CheckCurrentUserIsSiteAdmin(ctx, db) == nil; !isSiteAdmin {
+		abused, reason, err := checkEmailAbuse(ctx, userID)
+		if err != nil {
+			return err
+		} else if abused {
+			return errors.Errorf("refusing to change email address because %s", reason)
+		}
+	}
+
+	// Another user may have already verified this email address. If so, the change could never be
+	// completed, so don't send a confirmation email. As in Add, do not tell the user, to avoid
+	// leaking the existence of emails.
+	if _, err := database.Users(db).GetByVerifiedEmail(ctx, email); err != nil && !errcode.IsNotFound(err) {
+		return err
+	} else if err == nil {
+		return nil
+	}
+
+	secret, err := MakeEmailVerificationCode()
+	if err != nil {
+		return err
+	}
+	req := &database.UserEmailChangeRequest{
+		UserID:    userID,
+		Email:     email,
+		Secret:    secret,
+		ExpiresAt: time.Now().Add(emailChangeRequestTTL),
+	}
+	if err := database.UserEmails(db).CreateChangeRequest(ctx, req); err != nil {
+		return err
+	}
+
+	token, err := signEmailChangeToken(secret, emailChangeClaims{UserID: userID, Email: email})
+	if err != nil {
+		return err
+	}
+	return sendEmailChangeConfirmationEmail(ctx, usr.Username, email, token)
+}
+
+// ConfirmChange completes the pending email change of the user, given the token that was sent to
+// the new address: the address is added to the user as verified and becomes their primary
+// address. It returns the new primary address.
+func (userEmails) ConfirmChange(ctx context.Context, db dbutil.DB, userID int32, token string) (string, error) {
+	// 🚨 SECURITY: Only the user and site admins can change the email address of a user. The token
+	// proves that they control the new address.
+	if err := CheckCanEditUserEmails(ctx, db, userID); err != nil {
+		return "", err
+	}
+
+	claims, verify, err := parseEmailChangeToken(token)
+	if err != nil {
+		return "", err
+	}
+	if claims.UserID != userID {
+		return "", ErrInvalidEmailChangeToken
+	}
+
+	req, err := database.UserEmails(db).GetChangeRequest(ctx, userID)
+	if err != nil {
+		if errcode.IsNotFound(err) {
+			return "", ErrInvalidEmailChangeToken
+		}
+		return "", err
+	}
+	// The secret is replaced by every request, so only the latest link is valid.
+	if !verify(req.Secret) || !strings.EqualFold(claims.Email, req.Email) || time.Now().After(req.ExpiresAt) {
+		return "", ErrInvalidEmailChangeToken
+	}
+
+	if err := database.UserEmails(db).CompleteChangeRequest(ctx, userID, req.Email); err != nil {
+		if errcode.IsNotFound(err) {
+			return "", ErrInvalidEmailChangeToken
+		}
+		return "", err
+	}
+
+	return req.Email, nil
+}
+
+// sendEmailChangeConfirmationEmail sends the link that confirms an email change to the new address.
+func sendEmailChangeConfirmationEmail(ctx context.Context, username, email, token string) error {
+	q := make(url.Values)
+	q.Set("token", token)
+	confirmPath, _ := router.Router().Get(router.ConfirmEmailChange).URLPath()
+	return txemail.Send(ctx, txemail.Message{
+		To:       []string{email},
+		Template: confirmEmailChangeTemplates,
+		Data: struct {
+			Username string
+			URL      string
+			Host     string
+		}{
+			Username: username,
+			URL: globals.ExternalURL().ResolveReference(&url.URL{
+				Path:     confirmPath.Path,
+				RawQuery: q.Encode(),
+			}).String(),
+			Host: globals.ExternalURL().Host,
+		},
+	})
+}
+
+var confirmEmailChangeTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `Confirm your new email on Sourcegraph ({{.Host}})`,
+	Text: `Hi {{.Username}},
+
+Please confirm that you want to use this email address for your account on Sourcegraph ({{.Host}}) by clicking this link:
+
+{{.URL}}
+
+The link expires in 24 hours. If you didn't request this change, you can ignore this email.
+`,
+	HTML: `<p>Hi {{.Username}},</p>
+
+<p>Please confirm that you want to use this email address for your account on Sourcegraph ({{.Host}}) by clicking this link:</p>
+
+<p><strong><a href="{{.URL}}">Confirm email address</a></strong></p>
+
+<p>The link expires in 24 hours. If you didn't request this change, you can ignore this email.</p>
+`,
+})
+
+// RevokeSessionsOnPrimaryEmailChange logs that the primary email of the user changed and revokes
+// all of the user's sessions and access tokens if the auth.primaryEmailChangeSessionRevocation
+// site policy asks for it.
+func (userEmails) RevokeSessionsOnPrimaryEmailChange(ctx context.Context, db dbutil.DB, userID int32) error {
+	// A change is recognized if the user made it themselves from a signed-in browser session.
+	// Changes made with an access token or by a site admin are not.
+	a := actor.FromContext(ctx)
+	recognized := a.UID == userID && a.FromSessionCookie
+
+	logPrimaryEmailSecurityEvent(ctx, db, database.SecurityEventNamePrimaryEmailChanged, userID, a.UID, recognized)
+
+	switch conf.AuthPrimaryEmailChangeSessionRevocation() {
+	case "always":
+	case "unrecognized":
+		if recognized {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if err := database.Users(db).InvalidateSessionsByID(ctx, userID); err != nil {
+		return errors.Wrap(err, "invalidating sessions")
+	}
+	if err := database.AccessTokens(db).DeleteBySubjectUser(ctx, userID); err != nil {
+		return errors.Wrap(err, "deleting access tokens")
+	}
+
+	logPrimaryEmailSecurityEvent(ctx, db, database.SecurityEventNameSessionsRevoked, userID, a.UID, recognized)
+	return nil
+}
+
+func logPrimaryEmailSecurityEvent(ctx context.Context, db dbutil.DB, name database.SecurityEventName, userID, by int32, recognized bool) {
+	args, err := json.Marshal(struct {
+		By         int32  `json:"by"`
+		Recognized bool   `json:"recognized"`
+		Reason     string `json:"reason"`
+	}{
+		By:         by,
+		Recognized: recognized,
+		Reason:     "primary email changed",
+	})
+	if err != nil {
+		log15.Error("logPrimaryEmailSecurityEvent: failed to marshal JSON", "error", err)
+	}
+
+	database.SecurityEventLogs(db).LogEvent(ctx, &database.SecurityEvent{
+		Name:      name,
+		UserID:    uint32(userID),
+		Argument:  args,
+		Source:    "BACKEND",
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestEmailChangeToken(t *testing.T) {
+	claims := emailChangeClaims{UserID: 1, Email: "new@example.com"}
+	token, err := signEmailChangeToken("secret", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have, verify, err := parseEmailChangeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have != claims {
+		t.Fatalf("got claims %+v, want %+v", have, claims)
+	}
+	if !verify("secret") {
+		t.Fatal("token not verified with the secret it was signed with")
+	}
+	if verify("other secret") {
+		t.Fatal("token verified with another secret")
+	}
+
+	// Changing the claims invalidates the signature.
+	forged, err := signEmailChangeToken("other secret", emailChangeClaims{UserID: 2, Email: "new@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged = strings.SplitN(forged, ".", 2)[0] + token[strings.Index(token, "."):]
+	if _, verify, err := parseEmailChangeToken(forged); err != nil {
+		t.Fatal(err)
+	} else if verify("secret") {
+		t.Fatal("forged token verified")
+	}
+
+	for _, malformed := range []string{"", "abc", "!!!.abc", "abc.!!!"} {
+		if _, _, err := parseEmailChangeToken(malformed); err != ErrInvalidEmailChangeToken {
+			t.Errorf("token %q: got err %v, want %v", malformed, err, ErrInvalidEmailChangeToken)
+		}
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	const userID = 1
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id}, nil
+	}
+	database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: actor.FromContext(ctx).UID}, nil
+	}
+	defer func() {
+		database.Mocks.Users.GetByID = nil
+		database.Mocks.Users.GetByCurrentAuthUser = nil
+		database.Mocks.UserEmails = database.MockUserEmails{}
+	}()
+
+	req := &database.UserEmailChangeRequest{UserID: userID, Email: "new@example.com", Secret: "secret"}
+	database.Mocks.UserEmails.GetChangeRequest = func(ctx context.Context, id int32) (*database.UserEmailChangeRequest, error) {
+		return req, nil
+	}
+	var completed string
+	database.Mocks.UserEmails.CompleteChangeRequest = func(ctx context.Context, id int32, email string) error {
+		completed = email
+		return nil
+	}
+
+	sign := func(secret string, claims emailChangeClaims) string {
+		t.Helper()
+		token, err := signEmailChangeToken(secret, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := sign("secret", emailChangeClaims{UserID: userID, Email: "new@example.com"})
+
+	for _, test := range []struct {
+		name        string
+		actorID     int32
+		token       string
+		expiresIn   time.Duration
+		wantErr     error
+		wantAuthErr bool
+	}{
+		{name: "valid", actorID: userID, token: valid, expiresIn: time.Hour},
+		{name: "other user", actorID: 2, token: valid, expiresIn: time.Hour, wantAuthErr: true},
+		{name: "token of other user", actorID: userID, token: sign("secret", emailChangeClaims{UserID: 2, Email: "new@example.com"}), expiresIn: time.Hour, wantErr: ErrInvalidEmailChangeToken},
+		{name: "wrong secret", actorID: userID, token: sign("earlier secret", emailChangeClaims{UserID: userID, Email: "new@example.com"}), expiresIn: time.Hour, wantErr: ErrInvalidEmailChangeToken},
+		{name: "other email", actorID: userID, token: sign("secret", emailChangeClaims{UserID: userID, Email: "other@example.com"}), expiresIn: time.Hour, wantErr: ErrInvalidEmailChangeToken},
+		{name: "expired", actorID: userID, token: valid, expiresIn: -time.Minute, wantErr: ErrInvalidEmailChangeToken},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			completed = ""
+			req.ExpiresAt = time.Now().Add(test.expiresIn)
+
+			ctx := actor.WithActor(context.Background(), &actor.Actor{UID: test.actorID})
+			email, err := UserEmails.ConfirmChange(ctx, nil, userID, test.token)
+			if test.wantErr != nil || test.wantAuthErr {
+				var authErr *InsufficientAuthorizationError
+				if test.wantAuthErr && !errors.As(err, &authErr) {
+					t.Fatalf("got err %v, want authorization error", err)
+				} else if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+					t.Fatalf("got err %v, want %v", err, test.wantErr)
+				}
+				if completed != "" {
+					t.Fatal("change completed despite error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if email != "new@example.com" || completed != "new@example.com" {
+				t.Fatalf("got email %q, completed %q; want %q", email, completed, "new@example.com")
+			}
+		})
+	}
+}
@@ -5,19 +5,23 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"net/url"
+	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
 	"github.com/inconshreveable/log15"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/router"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/txemail"
 	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+	"github.com/sourcegraph/sourcegraph/internal/types"
 )
 
 // UserEmails contains backend methods related to user email addresses.
@@ -25,6 +29,39 @@ var UserEmails = &userEmails{}
 
 type userEmails struct{}
 
+// IsServiceAccount reports whether the user is a service account (bot user), i.e. has the
+// database.TagServiceAccount tag.
+func IsServiceAccount(user *types.User) bool {
+	for _, tag := range user.Tags {
+		if tag == database.TagServiceAccount {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCanEditUserEmails returns an error if the current user may not add, remove or change the
+// email addresses of the given user. Only the user and site admins can edit a user's email
+// addresses, and only site admins can edit those of service accounts, which often belong to shared
+// inboxes.
+func CheckCanEditUserEmails(ctx context.Context, db dbutil.DB, userID int32) error {
+	if err := CheckSiteAdminOrSameUser(ctx, db, userID); err != nil {
+		return err
+	}
+	if actor.FromContext(ctx).IsInternal() {
+		return nil
+	}
+
+	usr, err := database.Users(db).GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if IsServiceAccount(usr) {
+		return CheckCurrentUserIsSiteAdmin(ctx, db)
+	}
+	return nil
+}
+
 // checkEmailAbuse performs abuse prevention checks to prevent email abuse, i.e. users using emails
 // of other people whom they want to annoy.
 func checkEmailAbuse(ctx context.Context, userID int32) (abused bool, reason string, err error) {
@@ -83,13 +120,31 @@ func checkEmailAbuse(ctx context.Context, userID int32) (abused bool, reason str
 }
 
 // Add adds an email address to a user. If email verification is required, it sends an email
-// verification email.
+// verification email. Email addresses of service accounts are marked as verified right away.
 func (userEmails) Add(ctx context.Context, db dbutil.DB, userID int32, email string) error {
-	// 🚨 SECURITY: Only the user and site admins can add an email address to a user.
-	if err := CheckSiteAdminOrSameUser(ctx, db, userID); err != nil {
+	// 🚨 SECURITY: Only the user and site admins can add an email address to a user, and only site
+	// admins can add one to a service account.
+	if err := CheckCanEditUserEmails(ctx, db, userID); err != nil {
 		return err
 	}
 
+	usr, err := database.GlobalUsers.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	serviceAccount := IsServiceAccount(usr)
+
+	// Service accounts often use shared inboxes, so they are exempt from the role address and
+	// domain policies.
+	if !serviceAccount {
+		if err := CheckRoleEmailAddress(email); err != nil {
+			return err
+		}
+		if err := CheckEmailDomainPolicy(ctx, db, email); err != nil {
+			return err
+		}
+	}
+
 	// Prevent abuse (users adding emails of other people whom they want to annoy) with the
 	// following abuse prevention checks.
 	if isSiteAdmin := CheckCurrentUserIsSiteAdmin(ctx, db) == nil; !isSiteAdmin {
@@ -100,9 +155,12 @@ func (userEmails) Add(ctx context.Context, db dbutil.DB, userID int32, email str
 			return errors.Errorf("refusing to add email address because %s", reason)
 		}
 	}
+	if err := CheckEmailRateLimit(ctx, db, userID); err != nil {
+		return err
+	}
 
 	var code *string
-	if conf.EmailVerificationRequired() {
+	if conf.EmailVerificationRequired() && !serviceAccount {
 		tmp, err := MakeEmailVerificationCode()
 		if err != nil {
 			return err
@@ -125,14 +183,15 @@ func (userEmails) Add(ctx context.Context, db dbutil.DB, userID int32, email str
 		return err
 	}
 
-	if conf.EmailVerificationRequired() && !emailAlreadyExistsAndIsVerified {
-		usr, err := database.GlobalUsers.GetByID(ctx, userID)
-		if err != nil {
-			return err
-		}
+	if serviceAccount {
+		// Service accounts often use shared inboxes, so there is nobody to click a verification
+		// link. Only site admins can add their email addresses, so trust them instead.
+		return database.GlobalUserEmails.SetVerified(ctx, userID, email, true)
+	}
 
+	if conf.EmailVerificationRequired() && !emailAlreadyExistsAndIsVerified {
 		// Send email verification email.
-		if err := SendUserEmailVerificationEmail(ctx, usr.Username, email, *code); err != nil {
+		if err := SendUserEmailVerificationEmail(ctx, userID, usr.Username, email, *code); err != nil {
 			return errors.Wrap(err, "SendUserEmailVerificationEmail")
 		} else if err = database.GlobalUserEmails.SetLastVerification(ctx, userID, email, *code); err != nil {
 			return errors.Wrap(err, "SetLastVerificationSentAt")
@@ -153,12 +212,8 @@ func MakeEmailVerificationCode() (string, error) {
 
 // SendUserEmailVerificationEmail sends an email to the user to verify the email address. The code
 // is the verification code that the user must provide to verify their access to the email address.
-func SendUserEmailVerificationEmail(ctx context.Context, username, email, code string) error {
-	q := make(url.Values)
-	q.Set("code", code)
-	q.Set("email", email)
-	verifyEmailPath, _ := router.Router().Get(router.VerifyEmail).URLPath()
-	return txemail.Send(ctx, txemail.Message{
+func SendUserEmailVerificationEmail(ctx context.Context, userID int32, username, email, code string) error {
+	return sendTrackedEmail(ctx, userID, EmailDeliveryKindVerification, txemail.Message{
 		To:       []string{email},
 		Template: verifyEmailTemplates,
 		Data: struct {
@@ -167,15 +222,33 @@ func SendUserEmailVerificationEmail(ctx context.Context, username, email, code s
 			Host     string
 		}{
 			Username: username,
-			URL: globals.ExternalURL().ResolveReference(&url.URL{
-				Path:     verifyEmailPath.Path,
-				RawQuery: q.Encode(),
-			}).String(),
-			Host: globals.ExternalURL().Host,
+			URL:      verifyEmailURL(userID, email, code),
+			Host:     globals.ExternalURL().Host,
 		},
 	})
 }
 
+// verifyEmailURL returns the absolute URL that verifies the email address with the code. If
+// email.verificationLinkSignIn is enabled, the URL also signs the user in.
+func verifyEmailURL(userID int32, email, code string) string {
+	if conf.Get().EmailVerificationLinkSignIn {
+		u, err := verifyEmailSignInURL(userID, email, code, time.Now())
+		if err == nil {
+			return u
+		}
+		log15.Error("Failed to create verification sign-in link, falling back to the verification link", "error", err)
+	}
+
+	q := make(url.Values)
+	q.Set("code", code)
+	q.Set("email", email)
+	verifyEmailPath, _ := router.Router().Get(router.VerifyEmail).URLPath()
+	return globals.ExternalURL().ResolveReference(&url.URL{
+		Path:     verifyEmailPath.Path,
+		RawQuery: q.Encode(),
+	}).String()
+}
+
 var verifyEmailTemplates = txemail.MustValidate(txtypes.Templates{
 	Subject: `Verify your email on Sourcegraph ({{.Host}})`,
 	Text: `Hi {{.Username}},
@@ -192,35 +265,44 @@ Please verify your email address on Sourcegraph ({{.Host}}) by clicking this lin
 `,
 })
 
-// SendUserEmailOnFieldUpdate sends the user an email that important account information has changed.
-// The change is the information we want to provide the user about the change
-func (userEmails) SendUserEmailOnFieldUpdate(ctx context.Context, id int32, change string) error {
-	email, _, err := database.GlobalUserEmails.GetPrimaryEmail(ctx, id)
-	if err != nil {
-		log15.Warn("Failed to get user email", "error", err)
-		return err
-	}
+// NotifyUserOnFieldUpdate notifies the user that important account information has changed, on
+// every channel configured in notifications.accountChanges that the user can be reached on. The
+// change is the information we want to provide the user about the change.
+func (userEmails) NotifyUserOnFieldUpdate(ctx context.Context, id int32, change string) error {
 	usr, err := database.GlobalUsers.GetByID(ctx, id)
 	if err != nil {
 		log15.Warn("Failed to get user from database", "error", err)
 		return err
 	}
+	if IsServiceAccount(usr) {
+		// Nobody reads the inboxes of service accounts.
+		return nil
+	}
 
-	return txemail.Send(ctx, txemail.Message{
-		To:       []string{email},
-		Template: updateAccountEmailTemplate,
-		Data: struct {
-			Email    string
-			Change   string
-			Username string
-			Host     string
-		}{
-			Email:    email,
-			Change:   change,
-			Username: usr.Username,
-			Host:     globals.ExternalURL().Host,
-		},
-	})
+	c := &accountChange{
+		UserID:   usr.ID,
+		Username: usr.Username,
+		Change:   change,
+		Host:     globals.ExternalURL().Host,
+	}
+	cfg := conf.Get().NotificationsAccountChanges
+
+	var errs error
+	for _, channel := range conf.AccountChangeNotificationChannels() {
+		notifier, err := newAccountChangeNotifier(channel, cfg)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		if err := notifier.Notify(ctx, c); err != nil {
+			if errors.Is(err, errUserUnreachable) {
+				log15.Debug("User cannot be notified of account change", "userID", id, "channel", channel)
+				continue
+			}
+			errs = multierror.Append(errs, errors.Wrapf(err, "notifying user on %s", channel))
+		}
+	}
+	return errs
 }
 
 var updateAccountEmailTemplate = txemail.MustValidate(txtypes.Templates{
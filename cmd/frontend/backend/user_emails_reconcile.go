@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+)
+
+// UserEmailDiscrepancyKind is how a stored email address of a user differs from the directory of an
+// auth provider the user has an external account with.
+type UserEmailDiscrepancyKind string
+
+const (
+	// UserEmailMissing is a verified address in the directory that isn't stored for the user.
+	UserEmailMissing UserEmailDiscrepancyKind = "MISSING"
+	// UserEmailExtra is an address stored for the user that isn't in the directory.
+	UserEmailExtra UserEmailDiscrepancyKind = "EXTRA"
+	// UserEmailWrongVerification is an address whose verification state differs from the
+	// directory.
+	UserEmailWrongVerification UserEmailDiscrepancyKind = "WRONG_VERIFICATION"
+)
+
+// UserEmailDiscrepancy is a difference between the stored email addresses of a user and the
+// directory.
+type UserEmailDiscrepancy struct {
+	UserID int32
+	Email  string
+	Kind   UserEmailDiscrepancyKind
+	// Fixed is whether the stored address was changed to match the directory.
+	Fixed bool
+	// FixError is why fixing the discrepancy failed, if it did.
+	FixError string
+}
+
+// UserEmailReconciliation is the result of comparing the stored email addresses of users with the
+// directories of auth providers.
+type UserEmailReconciliation struct {
+	Discrepancies []*UserEmailDiscrepancy
+	// Errors are why the addresses of some external accounts could not be compared.
+	Errors []string
+}
+
+// DirectoryEmail is an email address of a user in the directory of an auth provider.
+type DirectoryEmail struct {
+	Email    string
+	Verified bool
+}
+
+// userEmailDirectories lists the email addresses of the user of an external account in the
+// directory of its auth provider, by the service type of the account. Providers that don't expose
+// the addresses of their users, like OpenID Connect and SAML, are not reconciled.
+var userEmailDirectories = map[string]func(ctx context.Context, acct *extsvc.Account) ([]DirectoryEmail, error){
+	extsvc.TypeGitHub: listGitHubDirectoryEmails,
+}
+
+// listGitHubDirectoryEmails lists the email addresses of the GitHub user with the OAuth token of the
+// account.
+func listGitHubDirectoryEmails(ctx context.Context, acct *extsvc.Account) ([]DirectoryEmail, error) {
+	_, tok, err := github.GetExternalAccountData(&acct.AccountData)
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil || tok.AccessToken == "" {
+		return nil, errors.New("no OAuth token")
+	}
+	baseURL, err := url.Parse(acct.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	apiURL, _ := github.APIRoot(baseURL)
+
+	emails, err := github.NewV3Client(apiURL, &auth.OAuthBearerToken{Token: tok.AccessToken}, nil).GetAuthenticatedUserEmails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DirectoryEmail, 0, len(emails))
+	for _, e := range emails {
+		result = append(result, DirectoryEmail{Email: e.Email, Verified: e.Verified})
+	}
+	return result, nil
+}
+
+// Reconcile compares the stored email addresses of the users who have an external account with an
+// auth provider that exposes a directory of email addresses with that directory.
+//
+// Unless dryRun is true, discrepancies for which the directory is authoritative are fixed: verified
+// addresses that are missing are added as verified, and unverified addresses that the directory
+// verified are marked as verified. Extra addresses and addresses that the directory didn't verify
+// are only reported, because users may have added and verified them on Sourcegraph.
+func (userEmails) Reconcile(ctx context.Context, db dbutil.DB, dryRun bool) (*UserEmailReconciliation, error) {
+	// Only the users with directory accounts are compared, and all of their directory addresses
+	// are compared at once, so that an address of one account isn't extra for another.
+	directoryEmails := map[int32]map[string]DirectoryEmail{}
+	result := &UserEmailReconciliation{Discrepancies: []*UserEmailDiscrepancy{}, Errors: []string{}}
+	for serviceType, list := range userEmailDirectories {
+		accts, err := database.ExternalAccounts(db).List(ctx, database.ExternalAccountsListOptions{ServiceType: serviceType, ExcludeExpired: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, acct := range accts {
+			emails, err := list(ctx, acct)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("external account %d of user %d: %s", acct.ID, acct.UserID, err))
+				// Without all the addresses of the user, extra addresses can't be told apart.
+				directoryEmails[acct.UserID] = nil
+				continue
+			}
+			byEmail, ok := directoryEmails[acct.UserID]
+			if !ok {
+				byEmail = map[string]DirectoryEmail{}
+				directoryEmails[acct.UserID] = byEmail
+			} else if byEmail == nil {
+				continue
+			}
+			for _, e := range emails {
+				// An address verified by any account is verified.
+				key := strings.ToLower(e.Email)
+				e.Verified = e.Verified || byEmail[key].Verified
+				byEmail[key] = e
+			}
+		}
+	}
+
+	userIDs := make([]int32, 0, len(directoryEmails))
+	for userID, byEmail := range directoryEmails {
+		if byEmail != nil {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	for _, userID := range userIDs {
+		stored, err := database.UserEmails(db).ListByUser(ctx, database.UserEmailsListOptions{UserID: userID})
+		if err != nil {
+			return nil, err
+		}
+		discrepancies := compareUserEmails(userID, stored, directoryEmails[userID])
+		if !dryRun {
+			for _, d := range discrepancies {
+				fixUserEmailDiscrepancy(ctx, db, d, directoryEmails[userID][strings.ToLower(d.Email)])
+			}
+		}
+		result.Discrepancies = append(result.Discrepancies, discrepancies...)
+	}
+	return result, nil
+}
+
+// compareUserEmails returns the discrepancies between the stored addresses of the user and the
+// addresses in the directory, keyed by their lowercase address, sorted by address.
+func compareUserEmails(userID int32, stored []*database.UserEmail, directory map[string]DirectoryEmail) []*UserEmailDiscrepancy {
+	var discrepancies []*UserEmailDiscrepancy
+	seen := make(map[string]struct{}, len(stored))
+	for _, s := range stored {
+		key := strings.ToLower(s.Email)
+		seen[key] = struct{}{}
+		d, ok := directory[key]
+		switch {
+		case !ok:
+			discrepancies = append(discrepancies, &UserEmailDiscrepancy{UserID: userID, Email: s.Email, Kind: UserEmailExtra})
+		case d.Verified != (s.VerifiedAt != nil):
+			discrepancies = append(discrepancies, &UserEmailDiscrepancy{UserID: userID, Email: s.Email, Kind: UserEmailWrongVerification})
+		}
+	}
+	for key, d := range directory {
+		if _, ok := seen[key]; !ok && d.Verified {
+			discrepancies = append(discrepancies, &UserEmailDiscrepancy{UserID: userID, Email: d.Email, Kind: UserEmailMissing})
+		}
+	}
+	sort.Slice(discrepancies, func(i, j int) bool { return discrepancies[i].Email < discrepancies[j].Email })
+	return discrepancies
+}
+
+// fixUserEmailDiscrepancy changes the stored address to match the directory, if the directory is
+// authoritative for the discrepancy.
+func fixUserEmailDiscrepancy(ctx context.Context, db dbutil.DB, d *UserEmailDiscrepancy, directory DirectoryEmail) {
+	var err error
+	switch {
+	case d.Kind == UserEmailMissing:
+		err = database.UserEmails(db).Add(ctx, d.UserID, d.Email, nil)
+		if err == nil {
+			err = database.UserEmails(db).SetVerified(ctx, d.UserID, d.Email, true)
+		}
+		if err == nil {
+			LogIdentityChange(ctx, db, IdentityChangeUserEmailAdded, d.UserID, IdentityChange{Email: d.Email})
+		}
+	case d.Kind == UserEmailWrongVerification && directory.Verified:
+		err = database.UserEmails(db).SetVerified(ctx, d.UserID, d.Email, true)
+		if err == nil {
+			LogIdentityChange(ctx, db, IdentityChangeUserEmailVerifiedChanged, d.UserID, IdentityChange{Email: d.Email, NewValue: "true"})
+		}
+	default:
+		return
+	}
+	if err != nil {
+		d.FixError = err.Error()
+		return
+	}
+	d.Fixed = true
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+)
+
+func TestUserEmailsReconcile(t *testing.T) {
+	defer func(directories map[string]func(context.Context, *extsvc.Account) ([]DirectoryEmail, error)) {
+		userEmailDirectories = directories
+	}(userEmailDirectories)
+	defer func() {
+		database.Mocks.ExternalAccounts = database.MockExternalAccounts{}
+		database.Mocks.UserEmails = database.MockUserEmails{}
+		database.Mocks.EventLogs = database.MockEventLogs{}
+	}()
+
+	// User 1 has two accounts whose addresses are compared together, and the account of user 2
+	// can't be listed.
+	userEmailDirectories = map[string]func(context.Context, *extsvc.Account) ([]DirectoryEmail, error){
+		extsvc.TypeGitHub: func(_ context.Context, acct *extsvc.Account) ([]DirectoryEmail, error) {
+			switch acct.ID {
+			case 1:
+				return []DirectoryEmail{{Email: "a@example.com", Verified: true}, {Email: "new@example.com", Verified: true}}, nil
+			case 2:
+				return []DirectoryEmail{{Email: "B@example.com", Verified: true}, {Email: "unverified@example.com"}}, nil
+			default:
+				return nil, errors.New("bad credentials")
+			}
+		},
+	}
+	database.Mocks.ExternalAccounts.List = func(opt database.ExternalAccountsListOptions) ([]*extsvc.Account, error) {
+		if opt.ServiceType != extsvc.TypeGitHub {
+			return nil, nil
+		}
+		return []*extsvc.Account{{ID: 1, UserID: 1}, {ID: 2, UserID: 1}, {ID: 3, UserID: 2}}, nil
+	}
+	now := time.Now()
+	database.Mocks.UserEmails.ListByUser = func(_ context.Context, opt database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		if opt.UserID != 1 {
+			t.Fatalf("listed addresses of user %d", opt.UserID)
+		}
+		return []*database.UserEmail{
+			{UserID: 1, Email: "a@example.com", VerifiedAt: &now},
+			{UserID: 1, Email: "b@example.com"},
+			{UserID: 1, Email: "extra@example.com", VerifiedAt: &now},
+		}, nil
+	}
+	var added, verified []string
+	database.Mocks.UserEmails.Add = func(_ context.Context, _ int32, email string, _ *string) error {
+		added = append(added, email)
+		return nil
+	}
+	database.Mocks.UserEmails.SetVerified = func(_ context.Context, _ int32, email string, _ bool) error {
+		verified = append(verified, email)
+		return nil
+	}
+	database.Mocks.EventLogs.Insert = func(context.Context, *database.Event) error { return nil }
+
+	wantErrors := []string{"external account 3 of user 2: bad credentials"}
+
+	t.Run("dry run", func(t *testing.T) {
+		added, verified = nil, nil
+		have, err := UserEmails.Reconcile(testContext(), nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &UserEmailReconciliation{
+			Discrepancies: []*UserEmailDiscrepancy{
+				{UserID: 1, Email: "b@example.com", Kind: UserEmailWrongVerification},
+				{UserID: 1, Email: "extra@example.com", Kind: UserEmailExtra},
+				{UserID: 1, Email: "new@example.com", Kind: UserEmailMissing},
+			},
+			Errors: wantErrors,
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("unexpected reconciliation (-want +have):\n%s", diff)
+		}
+		if len(added) > 0 || len(verified) > 0 {
+			t.Fatalf("dry run changed addresses: added %v, verified %v", added, verified)
+		}
+	})
+
+	t.Run("fix", func(t *testing.T) {
+		added, verified = nil, nil
+		have, err := UserEmails.Reconcile(testContext(), nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &UserEmailReconciliation{
+			Discrepancies: []*UserEmailDiscrepancy{
+				{UserID: 1, Email: "b@example.com", Kind: UserEmailWrongVerification, Fixed: true},
+				{UserID: 1, Email: "extra@example.com", Kind: UserEmailExtra},
+				{UserID: 1, Email: "new@example.com", Kind: UserEmailMissing, Fixed: true},
+			},
+			Errors: wantErrors,
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("unexpected reconciliation (-want +have):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"new@example.com"}, added); diff != "" {
+			t.Errorf("unexpected added addresses (-want +have):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"b@example.com", "new@example.com"}, verified); diff != "" {
+			t.Errorf("unexpected verified addresses (-want +have):\n%s", diff)
+		}
+	})
+
+	t.Run("fix error", func(t *testing.T) {
+		database.Mocks.UserEmails.Add = func(context.Context, int32, string, *string) error {
+			return errors.New("address taken")
+		}
+		have, err := UserEmails.Reconcile(testContext(), nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		missing := have.Discrepancies[len(have.Discrepancies)-1]
+		if missing.Fixed || missing.FixError != "address taken" {
+			t.Fatalf("got fixed %v and error %q, want unfixed with error", missing.Fixed, missing.FixError)
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/txemail"
@@ -132,18 +133,33 @@ func TestSendUserEmailVerificationEmail(t *testing.T) {
 		sent = &message
 		return nil
 	}
-	defer func() { txemail.MockSend = nil }()
+	var recorded *database.EmailDelivery
+	database.Mocks.EmailDeliveries.Create = func(_ context.Context, d *database.EmailDelivery) error {
+		recorded = d
+		return nil
+	}
+	defer func() {
+		txemail.MockSend = nil
+		database.Mocks.EmailDeliveries.Create = nil
+	}()
 
-	if err := SendUserEmailVerificationEmail(context.Background(), "Alan Johnson", "a@example.com", "c"); err != nil {
+	if err := SendUserEmailVerificationEmail(context.Background(), 1, "Alan Johnson", "a@example.com", "c"); err != nil {
 		t.Fatal(err)
 	}
 	if sent == nil {
 		t.Fatal("want sent != nil")
 	}
+	if sent.MessageID == nil {
+		t.Fatal("want sent.MessageID != nil")
+	}
+	if want := (database.EmailDelivery{MessageID: *sent.MessageID, UserID: 1, Email: "a@example.com", Kind: EmailDeliveryKindVerification}); recorded == nil || *recorded != want {
+		t.Errorf("got recorded delivery %+v, want %+v", recorded, want)
+	}
 	if want := (txemail.Message{
-		FromName: "",
-		To:       []string{"a@example.com"},
-		Template: verifyEmailTemplates,
+		FromName:  "",
+		To:        []string{"a@example.com"},
+		MessageID: sent.MessageID,
+		Template:  verifyEmailTemplates,
 		Data: struct {
 			Username string
 			URL      string
@@ -158,34 +174,40 @@ func TestSendUserEmailVerificationEmail(t *testing.T) {
 	}
 }
 
-func TestSendUserEmailOnFieldUpdate(t *testing.T) {
+func TestNotifyUserOnFieldUpdate(t *testing.T) {
 	var sent *txemail.Message
 	txemail.MockSend = func(ctx context.Context, message txemail.Message) error {
 		sent = &message
 		return nil
 	}
-	database.Mocks.UserEmails.GetPrimaryEmail = func(ctx context.Context, id int32) (emailCanonicalCase string, verified bool, err error) {
-		return "a@example.com", true, nil
+	database.Mocks.UserEmails.ListByUser = func(context.Context, database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		now := time.Now()
+		return []*database.UserEmail{{Email: "a@example.com", VerifiedAt: &now, Primary: true}}, nil
 	}
 	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
 		return &types.User{Username: "Foo"}, nil
 	}
+	database.Mocks.EmailDeliveries.Create = func(context.Context, *database.EmailDelivery) error {
+		return nil
+	}
 	defer func() {
 		txemail.MockSend = nil
-		database.Mocks.UserEmails.GetPrimaryEmail = nil
+		database.Mocks.UserEmails.ListByUser = nil
 		database.Mocks.Users.GetByID = nil
+		database.Mocks.EmailDeliveries.Create = nil
 	}()
 
-	if err := UserEmails.SendUserEmailOnFieldUpdate(context.Background(), 123, "updated password"); err != nil {
+	if err := UserEmails.NotifyUserOnFieldUpdate(context.Background(), 123, "updated password"); err != nil {
 		t.Fatal(err)
 	}
 	if sent == nil {
 		t.Fatal("want sent != nil")
 	}
 	if want := (txemail.Message{
-		FromName: "",
-		To:       []string{"a@example.com"},
-		Template: updateAccountEmailTemplate,
+		FromName:  "",
+		To:        []string{"a@example.com"},
+		MessageID: sent.MessageID,
+		Template:  updateAccountEmailTemplate,
 		Data: struct {
 			Email    string
 			Change   string
@@ -201,3 +223,60 @@ func TestSendUserEmailOnFieldUpdate(t *testing.T) {
 		t.Errorf("got %+v, want %+v", *sent, want)
 	}
 }
+
+func TestCheckCanEditUserEmails(t *testing.T) {
+	users := map[int32]*types.User{
+		1: {ID: 1, SiteAdmin: true},
+		2: {ID: 2, Tags: []string{database.TagServiceAccount}},
+		3: {ID: 3},
+	}
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return users[id], nil
+	}
+	database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return users[actor.FromContext(ctx).UID], nil
+	}
+	defer func() {
+		database.Mocks.Users.GetByID = nil
+		database.Mocks.Users.GetByCurrentAuthUser = nil
+	}()
+
+	for _, test := range []struct {
+		name    string
+		actorID int32
+		userID  int32
+		wantErr bool
+	}{
+		{name: "user edits own emails", actorID: 3, userID: 3},
+		{name: "site admin edits emails of user", actorID: 1, userID: 3},
+		{name: "site admin edits emails of service account", actorID: 1, userID: 2},
+		{name: "service account edits own emails", actorID: 2, userID: 2, wantErr: true},
+		{name: "user edits emails of service account", actorID: 3, userID: 2, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := actor.WithActor(context.Background(), &actor.Actor{UID: test.actorID})
+			err := CheckCanEditUserEmails(ctx, nil, test.userID)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotifyUserOnFieldUpdate_ServiceAccount(t *testing.T) {
+	txemail.MockSend = func(ctx context.Context, message txemail.Message) error {
+		t.Fatal("unexpected email to service account")
+		return nil
+	}
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id, Username: "bot", Tags: []string{database.TagServiceAccount}}, nil
+	}
+	defer func() {
+		txemail.MockSend = nil
+		database.Mocks.Users.GetByID = nil
+	}()
+
+	if err := UserEmails.NotifyUserOnFieldUpdate(context.Background(), 123, "updated password"); err != nil {
+		t.Fatal(err)
+	}
+}
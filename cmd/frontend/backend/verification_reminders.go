@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/router"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+)
+
+const (
+	// defaultVerificationReminderInterval is used if email.verificationReminders doesn't set
+	// intervalHours.
+	defaultVerificationReminderInterval = 72 * time.Hour
+
+	// verificationRemindersBatchSize caps the number of reminders sent per call of
+	// SendVerificationReminders, so that a large backlog is worked off gradually instead of
+	// flooding the SMTP server.
+	verificationRemindersBatchSize = 100
+)
+
+// verificationReminderOptions returns which email addresses are due for a verification reminder
+// according to the site configuration, and false if no reminders are to be sent.
+func verificationReminderOptions(now time.Time) (database.VerificationReminderOptions, bool) {
+	cfg := conf.Get().EmailVerificationReminders
+	if !conf.EmailVerificationRequired() || cfg == nil || cfg.MaxReminders <= 0 {
+		return database.VerificationReminderOptions{}, false
+	}
+
+	interval := defaultVerificationReminderInterval
+	if cfg.IntervalHours > 0 {
+		interval = time.Duration(cfg.IntervalHours) * time.Hour
+	}
+	return database.VerificationReminderOptions{
+		MaxReminders: cfg.MaxReminders,
+		SentBefore:   now.Add(-interval),
+	}, true
+}
+
+// SendVerificationReminders sends a reminder to verify their email address to the owners of the
+// unverified email addresses that are due for one, as configured in email.verificationReminders.
+// Each reminder is recorded before it is sent, so that concurrent callers never send the same
+// reminder twice; a reminder that fails to send is not retried.
+func (userEmails) SendVerificationReminders(ctx context.Context, db dbutil.DB) error {
+	opts, ok := verificationReminderOptions(time.Now())
+	if !ok {
+		return nil
+	}
+
+	store := database.UserEmails(db)
+	emails, err := store.ListDueForVerificationReminder(ctx, opts, verificationRemindersBatchSize)
+	if err != nil {
+		return errors.Wrap(err, "listing email addresses due for a verification reminder")
+	}
+
+	for _, email := range emails {
+		if email.VerificationCode == nil {
+			continue
+		}
+
+		usr, err := database.Users(db).GetByID(ctx, email.UserID)
+		if err != nil {
+			return errors.Wrap(err, "getting user")
+		}
+
+		newToken, err := MakeEmailVerificationCode()
+		if err != nil {
+			return err
+		}
+		token, claimed, err := store.ClaimVerificationReminder(ctx, email.UserID, email.Email, newToken, opts)
+		if err != nil {
+			return errors.Wrap(err, "claiming verification reminder")
+		}
+		if !claimed {
+			// Another sender got there first, or the email address was verified in the
+			// meantime.
+			continue
+		}
+
+		if err := sendVerificationReminder(ctx, usr.Username, email, token); err != nil {
+			log15.Warn("Failed to send email verification reminder", "userID", email.UserID, "error", err)
+		}
+	}
+	return nil
+}
+
+func sendVerificationReminder(ctx context.Context, username string, email *database.UserEmail, token string) error {
+	q := make(url.Values)
+	q.Set("user", strconv.Itoa(int(email.UserID)))
+	q.Set("email", email.Email)
+	q.Set("token", token)
+	unsubscribePath, _ := router.Router().Get(router.UnsubscribeVerificationReminders).URLPath()
+
+	return txemail.Send(ctx, txemail.Message{
+		To:       []string{email.Email},
+		Template: verificationReminderEmailTemplates,
+		Data: struct {
+			Username       string
+			URL            string
+			UnsubscribeURL string
+			Host           string
+		}{
+			Username: username,
+			URL:      verifyEmailURL(email.UserID, email.Email, *email.VerificationCode),
+			UnsubscribeURL: globals.ExternalURL().ResolveReference(&url.URL{
+				Path:     unsubscribePath.Path,
+				RawQuery: q.Encode(),
+			}).String(),
+			Host: globals.ExternalURL().Host,
+		},
+	})
+}
+
+var verificationReminderEmailTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `Reminder: verify your email on Sourcegraph ({{.Host}})`,
+	Text: `Hi {{.Username}},
+
+Your email address on Sourcegraph ({{.Host}}) is not verified yet. Please verify it by clicking this link:
+
+{{.URL}}
+
+To stop receiving these reminders, click this link:
+
+{{.UnsubscribeURL}}
+`,
+	HTML: `<p>Hi {{.Username}},</p>
+
+<p>Your email address on Sourcegraph ({{.Host}}) is not verified yet. Please verify it by clicking this link:</p>
+
+<p><strong><a href="{{.URL}}">Verify email address</a></strong></p>
+
+<p><small><a href="{{.UnsubscribeURL}}">Stop receiving these reminders</a></small></p>
+`,
+})
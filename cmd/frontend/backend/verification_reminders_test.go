@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestVerificationReminderOptions(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		smtp      *schema.SMTPServerConfig
+		reminders *schema.EmailVerificationReminders
+		want      database.VerificationReminderOptions
+		wantOK    bool
+	}{
+		{
+			name:      "no SMTP server",
+			reminders: &schema.EmailVerificationReminders{MaxReminders: 3},
+		},
+		{
+			name: "not configured",
+			smtp: &schema.SMTPServerConfig{},
+		},
+		{
+			name:      "disabled",
+			smtp:      &schema.SMTPServerConfig{},
+			reminders: &schema.EmailVerificationReminders{MaxReminders: 0, IntervalHours: 24},
+		},
+		{
+			name:      "default interval",
+			smtp:      &schema.SMTPServerConfig{},
+			reminders: &schema.EmailVerificationReminders{MaxReminders: 3},
+			want:      database.VerificationReminderOptions{MaxReminders: 3, SentBefore: now.Add(-72 * time.Hour)},
+			wantOK:    true,
+		},
+		{
+			name:      "custom interval",
+			smtp:      &schema.SMTPServerConfig{},
+			reminders: &schema.EmailVerificationReminders{MaxReminders: 1, IntervalHours: 24},
+			want:      database.VerificationReminderOptions{MaxReminders: 1, SentBefore: now.Add(-24 * time.Hour)},
+			wantOK:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+				EmailSmtp:                  test.smtp,
+				EmailVerificationReminders: test.reminders,
+			}})
+			defer conf.Mock(nil)
+
+			got, ok := verificationReminderOptions(now)
+			if ok != test.wantOK {
+				t.Fatalf("got ok %t, want %t", ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("unexpected options (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
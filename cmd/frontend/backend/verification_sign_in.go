@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/router"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// verificationSignInTTL is how long the link in a verification email signs the user in. The link
+// verifies the email address for as long as the verification code is valid.
+const verificationSignInTTL = 24 * time.Hour
+
+// ErrInvalidVerificationToken is returned when an email address can't be verified with a token,
+// because the token is malformed, was already used or doesn't belong to the email address.
+var ErrInvalidVerificationToken = errors.New("the verification link is invalid or was already used")
+
+// verificationClaims is the signed part of the token in the link of a verification email.
+type verificationClaims struct {
+	UserID    int32  `json:"u"`
+	Email     string `json:"e"`
+	ExpiresAt int64  `json:"x"`
+}
+
+// verifyEmailSignInURL returns the absolute URL that verifies the email address and signs the user
+// in. The token in the URL is signed with the verification code, so it can only be used once: the
+// code is cleared when the email address is verified.
+func verifyEmailSignInURL(userID int32, email, code string, now time.Time) (string, error) {
+	token, err := signToken(code, verificationClaims{
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: now.Add(verificationSignInTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	q := make(url.Values)
+	q.Set("token", token)
+	signInPath, _ := router.Router().Get(router.VerifyEmailSignIn).URLPath()
+	return globals.ExternalURL().ResolveReference(&url.URL{
+		Path:     signInPath.Path,
+		RawQuery: q.Encode(),
+	}).String(), nil
+}
+
+// VerifyWithToken verifies the email address that the token from a verification email was issued
+// for. It returns the owner of the email address, the verified email address and whether the token
+// is still recent enough to sign the user in.
+//
+// If a user is signed in, the token must have been issued for one of their email addresses.
+func (userEmails) VerifyWithToken(ctx context.Context, db dbutil.DB, token string) (userID int32, email string, canSignIn bool, err error) {
+	var claims verificationClaims
+	verify, err := parseSignedToken(token, &claims)
+	if err != nil {
+		return 0, "", false, ErrInvalidVerificationToken
+	}
+
+	// 🚨 SECURITY: Don't verify the email addresses of other users while signed in, which would
+	// be confusing at best.
+	if a := actor.FromContext(ctx); a.IsAuthenticated() && a.UID != claims.UserID {
+		return 0, "", false, errors.New("the verification link was sent to another user, sign out to use it")
+	}
+
+	emails, err := database.UserEmails(db).ListByUser(ctx, database.UserEmailsListOptions{UserID: claims.UserID})
+	if err != nil {
+		return 0, "", false, err
+	}
+	var userEmail *database.UserEmail
+	for _, e := range emails {
+		if strings.EqualFold(e.Email, claims.Email) {
+			userEmail = e
+			break
+		}
+	}
+	// 🚨 SECURITY: The token is signed with the verification code, which proves that it was sent
+	// to the email address. Verified email addresses have no code, so tokens can't be reused.
+	if userEmail == nil || userEmail.VerificationCode == nil || !verify(*userEmail.VerificationCode) {
+		return 0, "", false, ErrInvalidVerificationToken
+	}
+
+	verified, err := database.UserEmails(db).Verify(ctx, claims.UserID, userEmail.Email, *userEmail.VerificationCode)
+	if err != nil {
+		return 0, "", false, err
+	}
+	if !verified {
+		// Another request used the token concurrently.
+		return 0, "", false, ErrInvalidVerificationToken
+	}
+
+	return claims.UserID, userEmail.Email, time.Now().Before(time.Unix(claims.ExpiresAt, 0)), nil
+}
@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+func TestVerifyWithToken(t *testing.T) {
+	code := "c"
+	database.Mocks.UserEmails.ListByUser = func(ctx context.Context, opt database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		if opt.UserID != 1 {
+			return nil, nil
+		}
+		email := &database.UserEmail{UserID: 1, Email: "alice@example.com"}
+		if code != "" {
+			email.VerificationCode = &code
+		}
+		return []*database.UserEmail{email}, nil
+	}
+	database.Mocks.UserEmails.Verify = func(ctx context.Context, userID int32, email, c string) (bool, error) {
+		if c != code {
+			return false, nil
+		}
+		code = ""
+		return true, nil
+	}
+	defer func() { database.Mocks.UserEmails = database.MockUserEmails{} }()
+
+	token := func(t *testing.T, userID int32, email, code string, now time.Time) string {
+		t.Helper()
+		u, err := verifyEmailSignInURL(userID, email, code, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := url.Parse(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.Query().Get("token")
+	}
+
+	for _, test := range []struct {
+		name          string
+		actorID       int32
+		token         func(t *testing.T) string
+		wantErr       bool
+		wantCanSignIn bool
+	}{
+		{
+			name:          "valid",
+			token:         func(t *testing.T) string { return token(t, 1, "Alice@example.com", "c", time.Now()) },
+			wantCanSignIn: true,
+		},
+		{
+			name: "expired for signing in",
+			token: func(t *testing.T) string {
+				return token(t, 1, "alice@example.com", "c", time.Now().Add(-2*verificationSignInTTL))
+			},
+		},
+		{
+			name:          "signed in as the same user",
+			actorID:       1,
+			token:         func(t *testing.T) string { return token(t, 1, "alice@example.com", "c", time.Now()) },
+			wantCanSignIn: true,
+		},
+		{
+			name:    "signed in as another user",
+			actorID: 2,
+			token:   func(t *testing.T) string { return token(t, 1, "alice@example.com", "c", time.Now()) },
+			wantErr: true,
+		},
+		{
+			name:    "wrong code",
+			token:   func(t *testing.T) string { return token(t, 1, "alice@example.com", "other", time.Now()) },
+			wantErr: true,
+		},
+		{
+			name:    "other user",
+			token:   func(t *testing.T) string { return token(t, 2, "alice@example.com", "c", time.Now()) },
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			token:   func(t *testing.T) string { return "abc" },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			code = "c"
+			ctx := context.Background()
+			if test.actorID != 0 {
+				ctx = actor.WithActor(ctx, &actor.Actor{UID: test.actorID})
+			}
+
+			tok := test.token(t)
+			userID, email, canSignIn, err := UserEmails.VerifyWithToken(ctx, nil, tok)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				if code == "" {
+					t.Fatal("email verified despite error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if userID != 1 || email != "alice@example.com" || canSignIn != test.wantCanSignIn {
+				t.Fatalf("got (%d, %q, %t), want (1, %q, %t)", userID, email, canSignIn, "alice@example.com", test.wantCanSignIn)
+			}
+
+			// The token can only be used once.
+			if _, _, _, err := UserEmails.VerifyWithToken(ctx, nil, tok); !errors.Is(err, ErrInvalidVerificationToken) {
+				t.Fatalf("got err %v on reuse, want %v", err, ErrInvalidVerificationToken)
+			}
+		})
+	}
+}
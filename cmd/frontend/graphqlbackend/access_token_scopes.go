@@ -0,0 +1,142 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+)
+
+// CheckAccessTokenScopes returns an error if the request is authenticated with an access token
+// whose fine-grained scopes (see authz.WithAccessTokenScopes) don't allow the GraphQL operation.
+// Each root field of the operation must be allowed by one of the scopes: authz.ScopeReadSearch
+// allows Query.search, authz.ScopeWriteBatches allows the queries and mutations of the batch
+// changes API, and authz.ScopeAdmin allows the queries and mutations that are documented as being
+// only for site admins. (Site admin checks also fail for tokens without authz.ScopeAdmin, see
+// backend.CheckCurrentUserIsSiteAdmin.)
+//
+// 🚨 SECURITY: Only the root fields of the operation are checked. Scoped tokens still only grant
+// the privileges of their subject user, so nested fields (such as the namespace of a batch change)
+// don't give access to anything the user couldn't otherwise see.
+func CheckAccessTokenScopes(ctx context.Context, query, operationName string) error {
+	scopes, restricted := authz.AccessTokenScopesFromContext(ctx)
+	if !restricted {
+		return nil
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return errors.Wrap(err, "parsing query")
+	}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		d, ok := def.(*ast.OperationDefinition)
+		if !ok || (operationName != "" && (d.Name == nil || d.Name.Value != operationName)) {
+			continue
+		}
+		if op != nil {
+			return errors.New("the operation to execute is ambiguous")
+		}
+		op = d
+	}
+	if op == nil || op.SelectionSet == nil {
+		return errors.Errorf("unknown operation %q", operationName)
+	}
+
+	for _, sel := range op.SelectionSet.Selections {
+		// Fragments on the root type aren't used by our clients, so we don't bother resolving them.
+		f, ok := sel.(*ast.Field)
+		if !ok {
+			return errors.New("access tokens with fine-grained scopes can't use fragments on the root type")
+		}
+		name := f.Name.Value
+		if name == "__typename" {
+			continue
+		}
+		if scope := accessTokenScopeOfRootField(op.Operation, name); scope == "" || !authz.HasScope(scopes, scope) {
+			return errors.Errorf("the scopes %q of the access token don't allow the %s field %q", scopes, op.Operation, name)
+		}
+	}
+	return nil
+}
+
+var (
+	rootFieldScopesOnce sync.Once
+	rootFieldScopes     map[string]map[string]string // operation type -> field name -> scope
+)
+
+// accessTokenScopeOfRootField returns the fine-grained scope that allows the root field of the
+// operation type ("query", "mutation" or "subscription"), or "" if only tokens with authz.ScopeUserAll may use it.
+func accessTokenScopeOfRootField(operation, field string) string {
+	rootFieldScopesOnce.Do(func() {
+		rootFieldScopes = map[string]map[string]string{
+			ast.OperationTypeQuery:        {"search": authz.ScopeReadSearch},
+			ast.OperationTypeMutation:     {},
+			ast.OperationTypeSubscription: {},
+		}
+		addRootFieldScopes(rootFieldScopes, batchesSchema, func(string) string {
+			return authz.ScopeWriteBatches
+		})
+		for _, schema := range []string{mainSchema, codeIntelSchema, dotcomSchema, licenseSchema, codeMonitorsSchema, insightsSchema, authzSchema, computeSchema, searchContextsSchema} {
+			addRootFieldScopes(rootFieldScopes, schema, func(description string) string {
+				if strings.Contains(description, "Only site admins") {
+					return authz.ScopeAdmin
+				}
+				return ""
+			})
+		}
+	})
+	return rootFieldScopes[operation][field]
+}
+
+// addRootFieldScopes adds the scopes of the fields that the schema defines on the Query, Mutation
+// and Subscription types, as determined by scopeOf from the field's description. Fields that already have
+// a scope are skipped.
+func addRootFieldScopes(scopes map[string]map[string]string, schema string, scopeOf func(description string) string) {
+	doc, err := parser.Parse(parser.ParseParams{Source: schema})
+	if err != nil {
+		log15.Error("Unable to parse GraphQL schema for access token scopes.", "err", err)
+		return
+	}
+	for _, def := range doc.Definitions {
+		var obj *ast.ObjectDefinition
+		switch d := def.(type) {
+		case *ast.ObjectDefinition:
+			obj = d
+		case *ast.TypeExtensionDefinition:
+			obj = d.Definition
+		}
+		if obj == nil || obj.Name == nil {
+			continue
+		}
+		var operation string
+		switch obj.Name.Value {
+		case "Query":
+			operation = ast.OperationTypeQuery
+		case "Mutation":
+			operation = ast.OperationTypeMutation
+		case "Subscription":
+			operation = ast.OperationTypeSubscription
+		default:
+			continue
+		}
+		for _, f := range obj.Fields {
+			if _, ok := scopes[operation][f.Name.Value]; ok {
+				continue
+			}
+			var description string
+			if f.Description != nil {
+				description = f.Description.Value
+			}
+			if scope := scopeOf(description); scope != "" {
+				scopes[operation][f.Name.Value] = scope
+			}
+		}
+	}
+}
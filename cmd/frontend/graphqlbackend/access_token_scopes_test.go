@@ -0,0 +1,61 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+)
+
+// 🚨 SECURITY: This tests that access tokens with fine-grained scopes can only run the operations
+// of their APIs.
+func TestCheckAccessTokenScopes(t *testing.T) {
+	const (
+		searchQuery      = `query Search($q: String!) { __typename s: search(query: $q) { results { matchCount } } }`
+		batchesQuery     = `{ batchChanges(first: 10) { totalCount } }`
+		batchesMutation  = `mutation ($spec: ID!) { applyBatchChange(batchSpec: $spec) { id } }`
+		batchesSub       = `subscription ($spec: ID!) { batchSpecExecution(batchSpec: $spec) { state } }`
+		adminMutation    = `mutation { createUser(username: "alice") { user { id } } }`
+		currentUserQuery = `{ currentUser { username } }`
+		mixedQuery       = `{ search(query: "x") { __typename } currentUser { username } }`
+		fragmentQuery    = `{ ...F } fragment F on Query { search(query: "x") { __typename } }`
+		twoOperations    = `query A { search(query: "x") { __typename } } query B { currentUser { username } }`
+	)
+	tests := []struct {
+		name          string
+		scopes        []string
+		query         string
+		operationName string
+		wantErr       bool
+	}{
+		{name: "not restricted", query: currentUserQuery},
+		{name: "search", scopes: []string{authz.ScopeReadSearch}, query: searchQuery},
+		{name: "search without scope", scopes: []string{authz.ScopeWriteBatches}, query: searchQuery, wantErr: true},
+		{name: "batch changes query", scopes: []string{authz.ScopeWriteBatches}, query: batchesQuery},
+		{name: "batch changes mutation", scopes: []string{authz.ScopeReadSearch, authz.ScopeWriteBatches}, query: batchesMutation},
+		{name: "batch changes without scope", scopes: []string{authz.ScopeReadSearch}, query: batchesMutation, wantErr: true},
+		{name: "batch changes subscription", scopes: []string{authz.ScopeWriteBatches}, query: batchesSub},
+		{name: "batch changes subscription without scope", scopes: []string{authz.ScopeReadSearch}, query: batchesSub, wantErr: true},
+		{name: "admin", scopes: []string{authz.ScopeAdmin}, query: adminMutation},
+		{name: "admin without scope", scopes: []string{authz.ScopeReadSearch, authz.ScopeWriteBatches}, query: adminMutation, wantErr: true},
+		{name: "field of no scope", scopes: []string{authz.ScopeReadSearch, authz.ScopeWriteBatches, authz.ScopeAdmin}, query: currentUserQuery, wantErr: true},
+		{name: "mixed fields", scopes: []string{authz.ScopeReadSearch}, query: mixedQuery, wantErr: true},
+		{name: "fragment on root type", scopes: []string{authz.ScopeReadSearch}, query: fragmentQuery, wantErr: true},
+		{name: "selected operation", scopes: []string{authz.ScopeReadSearch}, query: twoOperations, operationName: "A"},
+		{name: "other selected operation", scopes: []string{authz.ScopeReadSearch}, query: twoOperations, operationName: "B", wantErr: true},
+		{name: "ambiguous operation", scopes: []string{authz.ScopeReadSearch}, query: twoOperations, wantErr: true},
+		{name: "invalid query", scopes: []string{authz.ScopeReadSearch}, query: `{ search(`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.scopes != nil {
+				ctx = authz.WithAccessTokenScopes(ctx, test.scopes)
+			}
+			err := CheckAccessTokenScopes(ctx, test.query, test.operationName)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("got err %v, want error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
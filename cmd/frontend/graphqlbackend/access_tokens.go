@@ -4,6 +4,7 @@ import (
 	"context"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/graph-gophers/graphql-go"
@@ -20,9 +21,10 @@ import (
 )
 
 type createAccessTokenInput struct {
-	User   graphql.ID
-	Scopes []string
-	Note   string
+	User      graphql.ID
+	Scopes    []string
+	Note      string
+	ExpiresAt *DateTime
 }
 
 func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAccessTokenInput) (*createAccessTokenResult, error) {
@@ -31,64 +33,144 @@ func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAcce
 		return nil, err
 	}
 
+	if err := r.checkCanCreateAccessToken(ctx, userID); err != nil {
+		return nil, err
+	}
+	sort.Strings(args.Scopes)
+	if err := r.validateAccessTokenScopes(ctx, args.Scopes); err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if args.ExpiresAt != nil {
+		if !args.ExpiresAt.After(time.Now()) {
+			return nil, errors.New("access token expiration must be in the future")
+		}
+		expiresAt = &args.ExpiresAt.Time
+	}
+
+	id, token, err := database.AccessTokens(r.db).Create(ctx, userID, args.Scopes, args.Note, actor.FromContext(ctx).UID, expiresAt)
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "created an access token"); err != nil {
+			log15.Warn("Failed to notify user of access token creation", "error", err)
+		}
+	}
+
+	return &createAccessTokenResult{id: marshalAccessTokenID(id), token: token}, err
+}
+
+// checkCanCreateAccessToken returns an error if the current user may not create access tokens for
+// the given user, which would let them use the API as that user.
+func (r *schemaResolver) checkCanCreateAccessToken(ctx context.Context, userID int32) error {
 	switch conf.AccessTokensAllow() {
 	case conf.AccessTokensAll:
 		// 🚨 SECURITY: Only the current logged in user should be able to create a token
 		// for themselves. A site admin should NOT be allowed to do this since they could
 		// then use the token to impersonate a user and gain access to their private
-		// code.
+		// code. Service accounts can't sign in, so site admins create their tokens.
 		if err := backend.CheckSameUser(ctx, userID); err != nil {
-			return nil, err
+			if !r.canManageServiceAccountTokens(ctx, userID) {
+				return err
+			}
 		}
 	case conf.AccessTokensAdmin:
 		// 🚨 SECURITY: The site has opted in to only allow site admins to create access
 		// tokens. In this case, they can create a token for any user.
 		if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
-			return nil, errors.New("Access token creation has been restricted to admin users. Contact an admin user to create a new access token.")
+			return errors.New("Access token creation has been restricted to admin users. Contact an admin user to create a new access token.")
 		}
 	case conf.AccessTokensNone:
 		fallthrough
 	default:
-		return nil, errors.New("Access token creation is disabled. Contact an admin user to enable.")
+		return errors.New("Access token creation is disabled. Contact an admin user to enable.")
 	}
+	return nil
+}
 
-	// Validate scopes.
-	var hasUserAllScope bool
+// validateAccessTokenScopes returns an error if the sorted scopes are invalid or the current user
+// may not create tokens with them.
+func (r *schemaResolver) validateAccessTokenScopes(ctx context.Context, scopes []string) error {
+	var hasUserAllScope, hasFineGrainedScope bool
 	seenScope := map[string]struct{}{}
-	sort.Strings(args.Scopes)
-	for _, scope := range args.Scopes {
+	for _, scope := range scopes {
 		switch scope {
 		case authz.ScopeUserAll:
 			hasUserAllScope = true
+		case authz.ScopeReadSearch, authz.ScopeWriteBatches:
+			hasFineGrainedScope = true
+		case authz.ScopeAdmin:
+			// 🚨 SECURITY: Only site admins may create a token with the "admin" scope.
+			if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+				return err
+			}
+			hasFineGrainedScope = true
 		case authz.ScopeSiteAdminSudo:
 			// 🚨 SECURITY: Only site admins may create a token with the "site-admin:sudo" scope.
 			if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
-				return nil, err
+				return err
 			} else if envvar.SourcegraphDotComMode() {
-				return nil, errors.New("creation of access tokens with sudo scope is disabled")
+				return errors.New("creation of access tokens with sudo scope is disabled")
 			}
 		default:
-			return nil, errors.Errorf("unknown access token scope %q (valid scopes: %q)", scope, authz.AllScopes)
+			return errors.Errorf("unknown access token scope %q (valid scopes: %q)", scope, authz.AllScopes)
 		}
 
 		if _, seen := seenScope[scope]; seen {
-			return nil, errors.Errorf("access token scope %q may not be specified multiple times", scope)
+			return errors.Errorf("access token scope %q may not be specified multiple times", scope)
 		}
 		seenScope[scope] = struct{}{}
 	}
-	if !hasUserAllScope {
-		return nil, errors.Errorf("all access tokens must have scope %q", authz.ScopeUserAll)
+	if !hasUserAllScope && !hasFineGrainedScope {
+		return errors.Errorf("access tokens must have scope %q or at least one of the scopes %q", authz.ScopeUserAll, []string{authz.ScopeReadSearch, authz.ScopeWriteBatches, authz.ScopeAdmin})
 	}
+	return nil
+}
 
-	id, token, err := database.AccessTokens(r.db).Create(ctx, userID, args.Scopes, args.Note, actor.FromContext(ctx).UID)
+// canManageServiceAccountTokens reports whether the given user is a service account and the current
+// user is a site admin, who manage the access tokens of service accounts.
+func (r *schemaResolver) canManageServiceAccountTokens(ctx context.Context, userID int32) bool {
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return false
+	}
+	user, err := database.Users(r.db).GetByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return backend.IsServiceAccount(user)
+}
+
+func (r *schemaResolver) RotateAccessToken(ctx context.Context, args *struct{ ID graphql.ID }) (*createAccessTokenResult, error) {
+	accessTokenID, err := unmarshalAccessTokenID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := database.AccessTokens(r.db).GetByID(ctx, accessTokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Rotating a token reveals a new secret value for it, so the current user must be
+	// allowed to create the token.
+	if err := r.checkCanCreateAccessToken(ctx, token.SubjectUserID); err != nil {
+		return nil, err
+	}
+	if err := r.validateAccessTokenScopes(ctx, token.Scopes); err != nil {
+		return nil, err
+	}
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, userID, "created an access token"); err != nil {
-			log15.Warn("Failed to send email to inform user of access token creation", "error", err)
+	id, newToken, err := database.AccessTokens(r.db).Rotate(ctx, accessTokenID, actor.FromContext(ctx).UID)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, token.SubjectUserID, "rotated an access token"); err != nil {
+			log15.Warn("Failed to notify user of access token rotation", "error", err)
 		}
 	}
 
-	return &createAccessTokenResult{id: marshalAccessTokenID(id), token: token}, err
+	return &createAccessTokenResult{id: marshalAccessTokenID(id), token: newToken}, nil
 }
 
 type createAccessTokenResult struct {
@@ -148,9 +230,9 @@ func (r *schemaResolver) DeleteAccessToken(ctx context.Context, args *deleteAcce
 
 	}
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, subjectUserID, "deleted an access token"); err != nil {
-			log15.Warn("Failed to send email to inform user of access token deletion", "error", err)
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, subjectUserID, "deleted an access token"); err != nil {
+			log15.Warn("Failed to notify user of access token deletion", "error", err)
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/graph-gophers/graphql-go"
@@ -26,7 +27,7 @@ func TestMutation_CreateAccessToken(t *testing.T) {
 	db := new(dbtesting.MockDB)
 
 	mockAccessTokensCreate := func(t *testing.T, wantCreatorUserID int32, wantScopes []string) {
-		database.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorUserID int32) (int64, string, error) {
+		database.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (int64, string, error) {
 			if want := int32(1); subjectUserID != want {
 				t.Errorf("got %v, want %v", subjectUserID, want)
 			}
@@ -148,6 +149,10 @@ func TestMutation_CreateAccessToken(t *testing.T) {
 			return &types.User{ID: differentSiteAdminUID, SiteAdmin: true}, nil
 		}
 		defer func() { database.Mocks.Users.GetByCurrentAuthUser = nil }()
+		database.Mocks.Users.GetByID = func(_ context.Context, userID int32) (*types.User, error) {
+			return &types.User{ID: userID}, nil
+		}
+		defer func() { database.Mocks.Users.GetByID = nil }()
 
 		RunTests(t, []*Test{
 			{
@@ -173,6 +178,114 @@ func TestMutation_CreateAccessToken(t *testing.T) {
 		})
 	})
 
+	t.Run("authenticated as site admin, creating API token for service account", func(t *testing.T) {
+		resetMocks()
+		const siteAdminUID = 234
+		mockAccessTokensCreate(t, siteAdminUID, []string{authz.ScopeReadSearch})
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: siteAdminUID, SiteAdmin: true}, nil
+		}
+		database.Mocks.Users.GetByID = func(_ context.Context, userID int32) (*types.User, error) {
+			return &types.User{ID: userID, Tags: []string{database.TagServiceAccount}}, nil
+		}
+		defer func() { database.Mocks = database.MockStores{} }()
+
+		RunTests(t, []*Test{
+			{
+				Context: actor.WithActor(context.Background(), &actor.Actor{UID: siteAdminUID}),
+				Schema:  mustParseGraphQLSchema(t),
+				Query: `
+				mutation {
+					createAccessToken(user: "` + uid1GQLID + `", scopes: ["read:search"], note: "n") {
+						id
+					}
+				}
+			`,
+				ExpectedResult: `
+				{
+					"createAccessToken": {
+						"id": "QWNjZXNzVG9rZW46MQ=="
+					}
+				}
+			`,
+			},
+		})
+	})
+
+	t.Run("authenticated as user, creating API token", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokensCreate(t, 1, []string{authz.ScopeReadSearch})
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1}, nil
+		}
+		database.Mocks.Users.GetByID = func(_ context.Context, userID int32) (*types.User, error) {
+			return &types.User{ID: userID}, nil
+		}
+		defer func() { database.Mocks = database.MockStores{} }()
+
+		RunTests(t, []*Test{
+			{
+				Context: actor.WithActor(context.Background(), &actor.Actor{UID: 1}),
+				Schema:  mustParseGraphQLSchema(t),
+				Query: `
+				mutation {
+					createAccessToken(user: "` + uid1GQLID + `", scopes: ["read:search"], note: "n") {
+						id
+					}
+				}
+			`,
+				ExpectedResult: `
+				{
+					"createAccessToken": {
+						"id": "QWNjZXNzVG9rZW46MQ=="
+					}
+				}
+			`,
+			},
+		})
+	})
+
+	t.Run("authenticated as user, using admin scope", func(t *testing.T) {
+		resetMocks()
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: false}, nil
+		}
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&schemaResolver{db: db}).CreateAccessToken(ctx, &createAccessTokenInput{
+			User:   uid1GQLID,
+			Scopes: []string{authz.ScopeAdmin},
+			Note:   "n",
+		})
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Errorf("got err %v, want %v", err, want)
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+
+	t.Run("authenticated as user, expiring in the past", func(t *testing.T) {
+		resetMocks()
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: false}, nil
+		}
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&schemaResolver{db: db}).CreateAccessToken(ctx, &createAccessTokenInput{
+			User:      uid1GQLID,
+			Scopes:    []string{authz.ScopeUserAll},
+			Note:      "n",
+			ExpiresAt: &DateTime{Time: time.Now().Add(-time.Hour)},
+		})
+		if err == nil {
+			t.Error("Expected error, but there was none")
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+
 	t.Run("authenticated as different user who is a site-admin. Admin allowed", func(t *testing.T) {
 		resetMocks()
 		const differentSiteAdminUID = 234
@@ -397,3 +510,93 @@ func TestMutation_DeleteAccessToken(t *testing.T) {
 		}
 	})
 }
+
+func TestMutation_RotateAccessToken(t *testing.T) {
+	db := new(dbtesting.MockDB)
+
+	mockAccessTokens := func(t *testing.T, scopes []string) {
+		database.Mocks.AccessTokens.GetByID = func(id int64) (*database.AccessToken, error) {
+			return &database.AccessToken{ID: 1, SubjectUserID: 1, Scopes: scopes}, nil
+		}
+		database.Mocks.AccessTokens.Rotate = func(id int64, creatorUserID int32) (int64, string, error) {
+			if want := int64(1); id != want {
+				t.Errorf("got %d, want %d", id, want)
+			}
+			return 2, "t2", nil
+		}
+	}
+
+	token1GQLID := graphql.ID("QWNjZXNzVG9rZW46MQ==")
+
+	t.Run("authenticated as user", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokens(t, []string{authz.ScopeUserAll})
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1}, nil
+		}
+		defer func() { database.Mocks = database.MockStores{} }()
+
+		RunTests(t, []*Test{
+			{
+				Context: actor.WithActor(context.Background(), &actor.Actor{UID: 1}),
+				Schema:  mustParseGraphQLSchema(t),
+				Query: `
+				mutation {
+					rotateAccessToken(id: "` + string(token1GQLID) + `") {
+						id
+						token
+					}
+				}
+			`,
+				ExpectedResult: `
+				{
+					"rotateAccessToken": {
+						"id": "QWNjZXNzVG9rZW46Mg==",
+						"token": "t2"
+					}
+				}
+			`,
+			},
+		})
+	})
+
+	t.Run("authenticated as different user who is a site-admin", func(t *testing.T) {
+		resetMocks()
+		const differentSiteAdminUID = 234
+		mockAccessTokens(t, []string{authz.ScopeUserAll})
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: differentSiteAdminUID, SiteAdmin: true}, nil
+		}
+		database.Mocks.Users.GetByID = func(_ context.Context, userID int32) (*types.User, error) {
+			return &types.User{ID: userID}, nil
+		}
+		defer func() { database.Mocks = database.MockStores{} }()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: differentSiteAdminUID})
+		result, err := (&schemaResolver{db: db}).RotateAccessToken(ctx, &struct{ ID graphql.ID }{ID: token1GQLID})
+		if err == nil {
+			t.Error("Expected error, but there was none")
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+
+	t.Run("authenticated as user, rotating sudo token after losing site admin", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokens(t, []string{authz.ScopeSiteAdminSudo, authz.ScopeUserAll})
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: false}, nil
+		}
+		defer func() { database.Mocks = database.MockStores{} }()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&schemaResolver{db: db}).RotateAccessToken(ctx, &struct{ ID graphql.ID }{ID: token1GQLID})
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Errorf("got err %v, want %v", err, want)
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+}
@@ -130,6 +130,33 @@ type CloseBatchChangeArgs struct {
 	CloseChangesets bool
 }
 
+type RollbackBatchChangeArgs struct {
+	BatchChange graphql.ID
+}
+
+type SetBatchChangeAutoRebaseArgs struct {
+	BatchChange graphql.ID
+	AutoRebase  bool
+}
+
+type ScheduleBatchSpecExecutionArgs struct {
+	BatchChange     graphql.ID
+	IntervalSeconds int32
+	Enabled         bool
+}
+
+type DeleteBatchSpecExecutionScheduleArgs struct {
+	BatchChange graphql.ID
+}
+
+type InvalidateBatchSpecExecutionCacheArgs struct {
+	BatchSpec graphql.ID
+}
+
+type InvalidateUserBatchSpecExecutionCacheArgs struct {
+	User graphql.ID
+}
+
 type MoveBatchChangeArgs struct {
 	BatchChange  graphql.ID
 	NewName      *string
@@ -168,6 +195,11 @@ type CreateBatchSpecFromRawArgs struct {
 	Namespace        *graphql.ID
 }
 
+type ImportBatchSpecArgs struct {
+	Archive   string
+	Namespace graphql.ID
+}
+
 type ReplaceBatchSpecInputArgs struct {
 	PreviousSpec     graphql.ID
 	BatchSpec        string
@@ -243,6 +275,7 @@ type CreateBatchChangesCredentialArgs struct {
 	ExternalServiceURL  string
 	User                *graphql.ID
 	Credential          string
+	Username            *string
 }
 
 type DeleteBatchChangesCredentialArgs struct {
@@ -290,6 +323,11 @@ type MergeChangesetsArgs struct {
 	Squash bool
 }
 
+type MergeChangesetsWhenChecksPassArgs struct {
+	BulkOperationBaseArgs
+	Squash bool
+}
+
 type CloseChangesetsArgs struct {
 	BulkOperationBaseArgs
 }
@@ -328,6 +366,7 @@ type BatchChangesResolver interface {
 	CreateBatchChange(ctx context.Context, args *CreateBatchChangeArgs) (BatchChangeResolver, error)
 	CreateBatchSpec(ctx context.Context, args *CreateBatchSpecArgs) (BatchSpecResolver, error)
 	CreateBatchSpecFromRaw(ctx context.Context, args *CreateBatchSpecFromRawArgs) (BatchSpecResolver, error)
+	ImportBatchSpec(ctx context.Context, args *ImportBatchSpecArgs) (BatchSpecResolver, error)
 	ReplaceBatchSpecInput(ctx context.Context, args *ReplaceBatchSpecInputArgs) (BatchSpecResolver, error)
 	DeleteBatchSpec(ctx context.Context, args *DeleteBatchSpecArgs) (*EmptyResponse, error)
 	ExecuteBatchSpec(ctx context.Context, args *ExecuteBatchSpecArgs) (BatchSpecResolver, error)
@@ -340,6 +379,12 @@ type BatchChangesResolver interface {
 
 	ApplyBatchChange(ctx context.Context, args *ApplyBatchChangeArgs) (BatchChangeResolver, error)
 	CloseBatchChange(ctx context.Context, args *CloseBatchChangeArgs) (BatchChangeResolver, error)
+	RollbackBatchChange(ctx context.Context, args *RollbackBatchChangeArgs) (BatchChangeRollbackJobResolver, error)
+	SetBatchChangeAutoRebase(ctx context.Context, args *SetBatchChangeAutoRebaseArgs) (BatchChangeResolver, error)
+	ScheduleBatchSpecExecution(ctx context.Context, args *ScheduleBatchSpecExecutionArgs) (BatchSpecExecutionScheduleResolver, error)
+	DeleteBatchSpecExecutionSchedule(ctx context.Context, args *DeleteBatchSpecExecutionScheduleArgs) (*EmptyResponse, error)
+	InvalidateBatchSpecExecutionCache(ctx context.Context, args *InvalidateBatchSpecExecutionCacheArgs) (*EmptyResponse, error)
+	InvalidateUserBatchSpecExecutionCache(ctx context.Context, args *InvalidateUserBatchSpecExecutionCacheArgs) (*EmptyResponse, error)
 	MoveBatchChange(ctx context.Context, args *MoveBatchChangeArgs) (BatchChangeResolver, error)
 	DeleteBatchChange(ctx context.Context, args *DeleteBatchChangeArgs) (*EmptyResponse, error)
 	CreateBatchChangesCredential(ctx context.Context, args *CreateBatchChangesCredentialArgs) (BatchChangesCredentialResolver, error)
@@ -352,6 +397,7 @@ type BatchChangesResolver interface {
 	CreateChangesetComments(ctx context.Context, args *CreateChangesetCommentsArgs) (BulkOperationResolver, error)
 	ReenqueueChangesets(ctx context.Context, args *ReenqueueChangesetsArgs) (BulkOperationResolver, error)
 	MergeChangesets(ctx context.Context, args *MergeChangesetsArgs) (BulkOperationResolver, error)
+	MergeChangesetsWhenChecksPass(ctx context.Context, args *MergeChangesetsWhenChecksPassArgs) (BulkOperationResolver, error)
 	CloseChangesets(ctx context.Context, args *CloseChangesetsArgs) (BulkOperationResolver, error)
 	PublishChangesets(ctx context.Context, args *PublishChangesetsArgs) (BulkOperationResolver, error)
 
@@ -370,10 +416,53 @@ type BatchChangesResolver interface {
 	RepoDiffStat(ctx context.Context, repo *graphql.ID) (*DiffStat, error)
 
 	BatchSpecs(cx context.Context, args *ListBatchSpecArgs) (BatchSpecConnectionResolver, error)
+	BatchSpecResolutionJobs(ctx context.Context, args *ListBatchSpecResolutionJobsArgs) (BatchSpecWorkspaceResolutionConnectionResolver, error)
+	BatchSpecResolutionQuota(ctx context.Context, args *BatchSpecResolutionQuotaArgs) (BatchSpecResolutionQuotaResolver, error)
+	BatchChangesAuditEvents(ctx context.Context, args *ListBatchChangesAuditEventsArgs) (BatchChangesAuditEventConnectionResolver, error)
+
+	// Subscriptions
+	BatchSpecExecution(ctx context.Context, args *BatchSpecExecutionArgs) (<-chan BatchSpecResolver, error)
 
 	NodeResolvers() map[string]NodeByIDFunc
 }
 
+type BatchChangesAuditEventConnectionResolver interface {
+	TotalCount(ctx context.Context) (int32, error)
+	PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error)
+	Nodes(ctx context.Context) ([]BatchChangesAuditEventResolver, error)
+}
+
+type BatchChangesAuditEventResolver interface {
+	Actor(ctx context.Context) (*UserResolver, error)
+	Action() string
+	ResourceType() string
+	ResourceID() string
+	Metadata() JSONValue
+	CreatedAt() DateTime
+}
+
+type BatchChangeRollbackJobResolver interface {
+	BatchChange(ctx context.Context) (BatchChangeResolver, error)
+	State() string
+	FailureMessage() *string
+	Initiator(ctx context.Context) (*UserResolver, error)
+	CreatedAt() DateTime
+	StartedAt() *DateTime
+	FinishedAt() *DateTime
+}
+
+type BatchSpecExecutionScheduleResolver interface {
+	BatchChange(ctx context.Context) (BatchChangeResolver, error)
+	Creator(ctx context.Context) (*UserResolver, error)
+	IntervalSeconds() int32
+	Enabled() bool
+	NextRunAt() DateTime
+	LastRunAt() *DateTime
+	PendingBatchSpec(ctx context.Context) (BatchSpecResolver, error)
+	CreatedAt() DateTime
+	UpdatedAt() DateTime
+}
+
 type BulkOperationConnectionResolver interface {
 	TotalCount(ctx context.Context) (int32, error)
 	PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error)
@@ -441,6 +530,8 @@ type BatchSpecResolver interface {
 	FinishedAt(ctx context.Context) (*DateTime, error)
 	FailureMessage(ctx context.Context) (*string, error)
 	WorkspaceResolution(ctx context.Context) (BatchSpecWorkspaceResolutionResolver, error)
+	ResolutionJobs(ctx context.Context, args *ListResolutionJobsArgs) (BatchSpecWorkspaceResolutionConnectionResolver, error)
+	Archive(ctx context.Context) (string, error)
 	ImportingChangesets(ctx context.Context, args *ListImportingChangesetsArgs) (ChangesetSpecConnectionResolver, error)
 }
 
@@ -661,6 +752,41 @@ type ListBatchSpecArgs struct {
 	After *string
 }
 
+type ListBatchSpecResolutionJobsArgs struct {
+	First         int32
+	After         *string
+	State         *string
+	CreatedAfter  *DateTime
+	CreatedBefore *DateTime
+
+	Namespace graphql.ID
+}
+
+type BatchSpecResolutionQuotaArgs struct {
+	Namespace graphql.ID
+}
+
+type BatchSpecExecutionArgs struct {
+	BatchSpec graphql.ID
+}
+
+type ListBatchChangesAuditEventsArgs struct {
+	First int32
+	After *string
+
+	Actor *graphql.ID
+	// Action is a value of type btypes.AuditEventAction.
+	Action *string
+	// ResourceType is a value of type btypes.AuditEventResourceType.
+	ResourceType *string
+}
+
+type ListResolutionJobsArgs struct {
+	First int32
+	After *string
+	State *string
+}
+
 type ListWorkspacesArgs struct {
 	First   int32
 	After   *string
@@ -668,6 +794,11 @@ type ListWorkspacesArgs struct {
 	Search  *string
 }
 
+type BatchSpecWorkspaceResolutionExecutionLogsArgs struct {
+	Offset      int32
+	WaitSeconds int32
+}
+
 type ListRecentlyCompletedWorkspacesArgs struct {
 	First int32
 	After *string
@@ -700,10 +831,12 @@ type BatchChangeResolver interface {
 	Changesets(ctx context.Context, args *ListChangesetsArgs) (ChangesetsConnectionResolver, error)
 	ChangesetCountsOverTime(ctx context.Context, args *ChangesetCountsArgs) ([]ChangesetCountsResolver, error)
 	ClosedAt() *DateTime
+	AutoRebase() bool
 	DiffStat(ctx context.Context) (*DiffStat, error)
 	CurrentSpec(ctx context.Context) (BatchSpecResolver, error)
 	BulkOperations(ctx context.Context, args *ListBatchChangeBulkOperationArgs) (BulkOperationConnectionResolver, error)
 	BatchSpecs(ctx context.Context, args *ListBatchSpecArgs) (BatchSpecConnectionResolver, error)
+	ExecutionSchedule(ctx context.Context) (BatchSpecExecutionScheduleResolver, error)
 
 	// TODO(campaigns-deprecation): This should be removed once we remove batches.
 	// It's here so that in the NodeResolver we can have the same resolver,
@@ -816,6 +949,7 @@ type ExternalChangesetResolver interface {
 
 	Error() *string
 	SyncerError() *string
+	RebaseConflict() *string
 	ScheduleEstimateAt(ctx context.Context) (*DateTime, error)
 
 	CurrentSpec(ctx context.Context) (VisibleChangesetSpecResolver, error)
@@ -845,11 +979,34 @@ type ChangesetCountsResolver interface {
 	OpenPending() int32
 }
 
+type BatchSpecResolutionQuotaResolver interface {
+	MaxConcurrent() *int32
+	MaxPerDay() *int32
+	Concurrent() int32
+	CreatedLastDay() int32
+	RemainingConcurrent() *int32
+	RemainingPerDay() *int32
+}
+
+type BatchSpecWorkspaceResolutionConnectionResolver interface {
+	Nodes(ctx context.Context) ([]BatchSpecWorkspaceResolutionResolver, error)
+	TotalCount(ctx context.Context) (int32, error)
+	PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error)
+}
+
 type BatchSpecWorkspaceResolutionResolver interface {
+	BatchSpec(ctx context.Context) (BatchSpecResolver, error)
+	CreatedAt() DateTime
 	State() string
 	StartedAt() *DateTime
 	FinishedAt() *DateTime
 	FailureMessage() *string
+	EstimatedSecondsRemaining(ctx context.Context) (*int32, error)
+	SearchQueries() []string
+	Progress() BatchSpecWorkspaceResolutionProgressResolver
+	CredentialWarnings() []BatchSpecWorkspaceResolutionCredentialWarningResolver
+	RepositoryErrors(ctx context.Context) ([]BatchSpecWorkspaceResolutionRepositoryErrorResolver, error)
+	ExecutionLogs(ctx context.Context, args *BatchSpecWorkspaceResolutionExecutionLogsArgs) (BatchSpecWorkspaceResolutionExecutionLogsResolver, error)
 
 	AllowIgnored() bool
 	AllowUnsupported() bool
@@ -861,6 +1018,29 @@ type BatchSpecWorkspaceResolutionResolver interface {
 	RecentlyErrored(ctx context.Context, args *ListRecentlyErroredWorkspacesArgs) BatchSpecWorkspaceConnectionResolver
 }
 
+type BatchSpecWorkspaceResolutionProgressResolver interface {
+	RepositoriesDiscovered() int32
+	WorkspacesResolved() int32
+	PercentComplete() int32
+}
+
+type BatchSpecWorkspaceResolutionCredentialWarningResolver interface {
+	CodeHost() BatchChangesCodeHostResolver
+	RepositoryCount() int32
+}
+
+type BatchSpecWorkspaceResolutionRepositoryErrorResolver interface {
+	Repository() *RepositoryResolver
+	RepositoryName() string
+	Message() string
+}
+
+type BatchSpecWorkspaceResolutionExecutionLogsResolver interface {
+	Entries() []ExecutionLogEntryResolver
+	NextOffset() int32
+	Finished() bool
+}
+
 type BatchSpecWorkspaceConnectionResolver interface {
 	Nodes(ctx context.Context) ([]BatchSpecWorkspaceResolver, error)
 	TotalCount(ctx context.Context) (int32, error)
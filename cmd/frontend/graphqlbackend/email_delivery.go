@@ -0,0 +1,80 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+)
+
+// userEmailMutationResult is returned by mutations that may send an email to
+// the user, so that clients can tell the user when it will not arrive.
+type userEmailMutationResult struct {
+	db dbutil.DB
+}
+
+func (r *userEmailMutationResult) AlwaysNil() *string { return nil }
+
+func (r *userEmailMutationResult) EmailDelivery() *emailDeliveryStatusResolver {
+	return &emailDeliveryStatusResolver{db: r.db, err: txemail.CheckDeliverable()}
+}
+
+type emailDeliveryStatusResolver struct {
+	db  dbutil.DB
+	err error
+}
+
+func (r *emailDeliveryStatusResolver) Deliverable() bool { return r.err == nil }
+
+const emailNotDeliverableMessage = "Emails cannot currently be delivered. Contact your site admin."
+
+func (r *emailDeliveryStatusResolver) Reason(ctx context.Context) *string {
+	if r.err == nil {
+		return nil
+	}
+
+	// 🚨 SECURITY: The underlying error may contain details about the email
+	// provider, so only site admins may see it.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return strptr(emailNotDeliverableMessage)
+	}
+	return strptr(r.err.Error())
+}
+
+func (r *siteResolver) EmailDelivery() *emailDeliveryStatusResolver {
+	return &emailDeliveryStatusResolver{db: r.db, err: txemail.CheckDeliverable()}
+}
+
+func (r *schemaResolver) SendTestEmail(ctx context.Context, args *struct {
+	To string
+}) (*emailDeliveryStatusResolver, error) {
+	// 🚨 SECURITY: Only site admins can send test emails, since the result reveals the errors of
+	// the email provider.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	err := txemail.Send(ctx, txemail.Message{
+		To:       []string{args.To},
+		Template: testEmailTemplates,
+		Data: struct {
+			Provider string
+		}{
+			Provider: conf.EmailProviderType(),
+		},
+	})
+	return &emailDeliveryStatusResolver{db: r.db, err: err}, nil
+}
+
+var testEmailTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `Test email from Sourcegraph`,
+	Text: `
+This is a test email sent by a site admin with the {{.Provider}} email provider. Emails from Sourcegraph can be delivered.
+`,
+	HTML: `
+<p>This is a test email sent by a site admin with the <strong>{{.Provider}}</strong> email provider. Emails from Sourcegraph can be delivered.</p>
+`,
+})
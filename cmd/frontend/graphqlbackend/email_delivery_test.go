@@ -0,0 +1,69 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestSendTestEmail(t *testing.T) {
+	resetMocks()
+	var sent [][]string
+	sendErr := errors.New("SendGrid API responded with status 401: unauthorized")
+	txemail.MockSend = func(_ context.Context, msg txemail.Message) error {
+		sent = append(sent, msg.To)
+		return sendErr
+	}
+	defer func() { txemail.MockSend = nil }()
+
+	t.Run("non site admin", func(t *testing.T) {
+		database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return &types.User{ID: 1}, nil
+		}
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		_, err := newSchemaResolver(new(dbtesting.MockDB)).SendTestEmail(ctx, &struct{ To string }{To: "alice@example.com"})
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Fatalf("got err %v, want %v", err, want)
+		}
+		if len(sent) != 0 {
+			t.Fatalf("unexpected emails sent to %v", sent)
+		}
+	})
+
+	t.Run("site admin", func(t *testing.T) {
+		database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: true}, nil
+		}
+		RunTest(t, &Test{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				mutation {
+					sendTestEmail(to: "alice@example.com") {
+						deliverable
+						reason
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"sendTestEmail": {
+						"deliverable": false,
+						"reason": "SendGrid API responded with status 401: unauthorized"
+					}
+				}
+			`,
+		})
+		if diff := cmp.Diff([][]string{{"alice@example.com"}}, sent); diff != "" {
+			t.Fatalf("unexpected recipients (-want +got):\n%s", diff)
+		}
+	})
+}
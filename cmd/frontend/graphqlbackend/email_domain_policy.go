@@ -0,0 +1,120 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+func (r *schemaResolver) EmailDomainPolicy(ctx context.Context) (*emailDomainPolicyResolver, error) {
+	// 🚨 SECURITY: Only site admins may view the email domain policy.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+	return &emailDomainPolicyResolver{db: r.db}, nil
+}
+
+func (r *schemaResolver) SetEmailDomainPolicyOverride(ctx context.Context, args *struct {
+	Domain string
+	Allow  bool
+}) (*emailDomainPolicyOverrideResolver, error) {
+	// 🚨 SECURITY: Only site admins may change the email domain policy.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	domain := backend.NormalizeEmailDomain(args.Domain)
+	if domain == "" || strings.ContainsAny(domain, "@ ") {
+		return nil, errors.Errorf("invalid domain %q", args.Domain)
+	}
+	override, err := database.EmailDomainPolicyOverrides(r.db).Upsert(ctx, domain, args.Allow)
+	if err != nil {
+		return nil, err
+	}
+	return &emailDomainPolicyOverrideResolver{override: override}, nil
+}
+
+func (r *schemaResolver) DeleteEmailDomainPolicyOverride(ctx context.Context, args *struct {
+	Domain string
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may change the email domain policy.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	if err := database.EmailDomainPolicyOverrides(r.db).Delete(ctx, backend.NormalizeEmailDomain(args.Domain)); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+type emailDomainPolicyResolver struct {
+	db dbutil.DB
+}
+
+func (r *emailDomainPolicyResolver) Allow() []string {
+	if policy := conf.Get().EmailDomainPolicy; policy != nil && policy.Allow != nil {
+		return policy.Allow
+	}
+	return []string{}
+}
+
+func (r *emailDomainPolicyResolver) Deny() []string {
+	if policy := conf.Get().EmailDomainPolicy; policy != nil && policy.Deny != nil {
+		return policy.Deny
+	}
+	return []string{}
+}
+
+func (r *emailDomainPolicyResolver) Overrides(ctx context.Context) ([]*emailDomainPolicyOverrideResolver, error) {
+	overrides, err := database.EmailDomainPolicyOverrides(r.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*emailDomainPolicyOverrideResolver, 0, len(overrides))
+	for _, o := range overrides {
+		resolvers = append(resolvers, &emailDomainPolicyOverrideResolver{override: o})
+	}
+	return resolvers, nil
+}
+
+func (r *emailDomainPolicyResolver) Evaluate(ctx context.Context, args *struct{ Email string }) (*emailDomainPolicyDecisionResolver, error) {
+	decision, err := backend.EvaluateEmailDomainPolicy(ctx, r.db, args.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &emailDomainPolicyDecisionResolver{decision: decision}, nil
+}
+
+type emailDomainPolicyOverrideResolver struct {
+	override *database.EmailDomainPolicyOverride
+}
+
+func (r *emailDomainPolicyOverrideResolver) Domain() string { return r.override.Domain }
+func (r *emailDomainPolicyOverrideResolver) Allow() bool    { return r.override.Allow }
+func (r *emailDomainPolicyOverrideResolver) CreatedAt() DateTime {
+	return DateTime{Time: r.override.CreatedAt}
+}
+func (r *emailDomainPolicyOverrideResolver) UpdatedAt() DateTime {
+	return DateTime{Time: r.override.UpdatedAt}
+}
+
+type emailDomainPolicyDecisionResolver struct {
+	decision *backend.EmailDomainPolicyDecision
+}
+
+func (r *emailDomainPolicyDecisionResolver) Email() string { return r.decision.Email }
+func (r *emailDomainPolicyDecisionResolver) Domain() *string {
+	if r.decision.Domain == "" {
+		return nil
+	}
+	return &r.decision.Domain
+}
+func (r *emailDomainPolicyDecisionResolver) Allowed() bool  { return r.decision.Allowed }
+func (r *emailDomainPolicyDecisionResolver) Reason() string { return r.decision.Reason }
@@ -3,6 +3,8 @@ package graphqlbackend
 import (
 	"context"
 
+	"github.com/graph-gophers/graphql-go"
+
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
 	"github.com/sourcegraph/sourcegraph/internal/database"
@@ -24,6 +26,26 @@ func (r *UserResolver) EventLogs(ctx context.Context, args *struct {
 	return &userEventLogsConnectionResolver{db: r.db, opt: opt}, nil
 }
 
+func (r *schemaResolver) IdentityChanges(ctx context.Context, args *struct {
+	graphqlutil.ConnectionArgs
+	User *graphql.ID
+}) (*userEventLogsConnectionResolver, error) {
+	// 🚨 SECURITY: Identity changes of all users can only be viewed by site admins.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+	opt := database.EventLogsListOptions{EventNames: backend.IdentityChangeEventNames}
+	args.ConnectionArgs.Set(&opt.LimitOffset)
+	if args.User != nil {
+		userID, err := UnmarshalUserID(*args.User)
+		if err != nil {
+			return nil, err
+		}
+		opt.UserID = userID
+	}
+	return &userEventLogsConnectionResolver{db: r.db, opt: opt}, nil
+}
+
 type userEventLogsConnectionResolver struct {
 	db  dbutil.DB
 	opt database.EventLogsListOptions
@@ -43,29 +65,24 @@ func (r *userEventLogsConnectionResolver) Nodes(ctx context.Context) ([]*userEve
 	return eventLogs, nil
 }
 
-func (r *userEventLogsConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
-	var count int
-	var err error
-
-	if r.opt.EventName != nil {
-		count, err = database.EventLogs(r.db).CountByUserIDAndEventName(ctx, r.opt.UserID, *r.opt.EventName)
-	} else {
-		count, err = database.EventLogs(r.db).CountByUserID(ctx, r.opt.UserID)
+func (r *userEventLogsConnectionResolver) count(ctx context.Context) (int, error) {
+	switch {
+	case r.opt.EventNames != nil:
+		return database.EventLogs(r.db).CountAll(ctx, r.opt)
+	case r.opt.EventName != nil:
+		return database.EventLogs(r.db).CountByUserIDAndEventName(ctx, r.opt.UserID, *r.opt.EventName)
+	default:
+		return database.EventLogs(r.db).CountByUserID(ctx, r.opt.UserID)
 	}
+}
 
+func (r *userEventLogsConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := r.count(ctx)
 	return int32(count), err
 }
 
 func (r *userEventLogsConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
-	var count int
-	var err error
-
-	if r.opt.EventName != nil {
-		count, err = database.EventLogs(r.db).CountByUserIDAndEventName(ctx, r.opt.UserID, *r.opt.EventName)
-	} else {
-		count, err = database.EventLogs(r.db).CountByUserID(ctx, r.opt.UserID)
-	}
-
+	count, err := r.count(ctx)
 	if err != nil {
 		return nil, err
 	}
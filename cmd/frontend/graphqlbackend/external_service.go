@@ -125,6 +125,10 @@ func (r *externalServiceResolver) WebhookURL() (*string, error) {
 			if len(c.Webhooks) > 0 {
 				r.webhookURL = u
 			}
+		case *schema.BitbucketCloudConnection:
+			if len(c.Webhooks) > 0 {
+				r.webhookURL = u
+			}
 		}
 	})
 	if r.webhookURL == "" {
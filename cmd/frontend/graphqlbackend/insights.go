@@ -15,15 +15,117 @@ import (
 // InsightsResolver is the root resolver.
 type InsightsResolver interface {
 	Insights(ctx context.Context, args *InsightsArgs) (InsightConnectionResolver, error)
+	InsightsDashboards(ctx context.Context, args *InsightsDashboardsArgs) (InsightsDashboardConnectionResolver, error)
+
+	// Mutations
+	PauseInsightSeries(ctx context.Context, args *PauseInsightSeriesArgs) (*EmptyResponse, error)
+	ResumeInsightSeries(ctx context.Context, args *ResumeInsightSeriesArgs) (*EmptyResponse, error)
+	SetInsightsTeamRepositories(ctx context.Context, args *SetInsightsTeamRepositoriesArgs) (*EmptyResponse, error)
+	CreateInsightSeriesAlert(ctx context.Context, args *CreateInsightSeriesAlertArgs) (InsightSeriesAlertResolver, error)
+	UpdateInsightSeriesAlert(ctx context.Context, args *UpdateInsightSeriesAlertArgs) (InsightSeriesAlertResolver, error)
+	DeleteInsightSeriesAlert(ctx context.Context, args *DeleteInsightSeriesAlertArgs) (*EmptyResponse, error)
+	CreateInsightsDashboard(ctx context.Context, args *CreateInsightsDashboardArgs) (InsightsDashboardResolver, error)
+	ShareInsightsDashboard(ctx context.Context, args *ShareInsightsDashboardArgs) (*EmptyResponse, error)
+	UnshareInsightsDashboard(ctx context.Context, args *ShareInsightsDashboardArgs) (*EmptyResponse, error)
+
+	// Subscriptions
+	InsightBackfillProgress(ctx context.Context, args *InsightBackfillProgressArgs) (<-chan InsightResolver, error)
 }
 
 type InsightsArgs struct {
 	Ids *[]graphql.ID
 }
 
+type InsightsDashboardsArgs struct {
+	Ids *[]graphql.ID
+}
+
+type PauseInsightSeriesArgs struct {
+	SeriesID string
+	Reason   *string
+}
+
+type ResumeInsightSeriesArgs struct {
+	SeriesID string
+}
+
+type SetInsightsTeamRepositoriesArgs struct {
+	Team         string
+	Repositories []string
+}
+
+type CreateInsightSeriesAlertArgs struct {
+	SeriesID        string
+	Label           *string
+	Condition       string
+	Threshold       float64
+	NotifyEmail     bool
+	WebhookURL      *string
+	SlackWebhookURL *string
+}
+
+type UpdateInsightSeriesAlertArgs struct {
+	ID              graphql.ID
+	Label           *string
+	Condition       string
+	Threshold       float64
+	NotifyEmail     bool
+	WebhookURL      *string
+	SlackWebhookURL *string
+}
+
+type DeleteInsightSeriesAlertArgs struct {
+	ID graphql.ID
+}
+
+type CreateInsightsDashboardArgs struct {
+	Title      string
+	InsightIds *[]string
+	Grants     *InsightsPermissionGrantsInput
+}
+
+type ShareInsightsDashboardArgs struct {
+	ID     graphql.ID
+	Grants InsightsPermissionGrantsInput
+}
+
+type InsightsPermissionGrantsInput struct {
+	Users         *[]graphql.ID
+	Organizations *[]graphql.ID
+	Global        *bool
+}
+
+type InsightBackfillProgressArgs struct {
+	ID string
+}
+
 type InsightsDataPointResolver interface {
 	DateTime() DateTime
 	Value() float64
+	Capture() *string
+	IncompleteReasons() []string
+	Samples(ctx context.Context, args *InsightDataPointSamplesArgs) ([]InsightDataPointSampleResolver, error)
+	Repositories(ctx context.Context, args *InsightDataPointRepositoriesArgs) ([]InsightRepositoryDataPointResolver, error)
+}
+
+type InsightDataPointSamplesArgs struct {
+	First int32
+}
+
+type InsightDataPointSampleResolver interface {
+	RepositoryName() string
+	Path() string
+	LineNumber() *int32
+	Preview() *string
+}
+
+type InsightDataPointRepositoriesArgs struct {
+	First int32
+}
+
+type InsightRepositoryDataPointResolver interface {
+	RepositoryName() string
+	Value() float64
 }
 
 type InsightStatusResolver interface {
@@ -32,6 +134,15 @@ type InsightStatusResolver interface {
 	CompletedJobs() int32
 	FailedJobs() int32
 	BackfillQueuedAt() *DateTime
+	BackfillProgress() InsightBackfillProgressResolver
+}
+
+type InsightBackfillProgressResolver interface {
+	TotalRepositories() int32
+	CompletedRepositories() int32
+	FailedRepositories() int32
+	TotalFrames() int32
+	CompletedFrames() int32
 }
 
 type InsightsPointsArgs struct {
@@ -42,10 +153,28 @@ type InsightsPointsArgs struct {
 }
 
 type InsightSeriesResolver interface {
+	SeriesID() string
 	Label() string
 	Points(ctx context.Context, args *InsightsPointsArgs) ([]InsightsDataPointResolver, error)
 	Status(ctx context.Context) (InsightStatusResolver, error)
 	DirtyMetadata(ctx context.Context) ([]InsightDirtyQueryResolver, error)
+	PausedAt() *DateTime
+	PauseReason() *string
+	Alerts(ctx context.Context) ([]InsightSeriesAlertResolver, error)
+}
+
+type InsightSeriesAlertResolver interface {
+	ID() graphql.ID
+	SeriesID() string
+	Label() string
+	Condition() string
+	Threshold() float64
+	NotifyEmail() bool
+	WebhookURL() *string
+	SlackWebhookURL() *string
+	Triggered() bool
+	LastTriggeredAt() *DateTime
+	CreatedAt() DateTime
 }
 
 type InsightResolver interface {
@@ -53,6 +182,11 @@ type InsightResolver interface {
 	Description() string
 	Series() []InsightSeriesResolver
 	ID() string
+	ExportData(ctx context.Context, args *InsightExportDataArgs) (string, error)
+}
+
+type InsightExportDataArgs struct {
+	Format string
 }
 
 type InsightConnectionResolver interface {
@@ -66,3 +200,22 @@ type InsightDirtyQueryResolver interface {
 	Time(ctx context.Context) DateTime
 	Count(ctx context.Context) int32
 }
+
+type InsightsDashboardConnectionResolver interface {
+	Nodes(ctx context.Context) ([]InsightsDashboardResolver, error)
+	TotalCount(ctx context.Context) (int32, error)
+	PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error)
+}
+
+type InsightsDashboardResolver interface {
+	ID() graphql.ID
+	Title() string
+	Views() InsightConnectionResolver
+	Grants(ctx context.Context) (InsightsPermissionGrantsResolver, error)
+}
+
+type InsightsPermissionGrantsResolver interface {
+	Users() []graphql.ID
+	Organizations() []graphql.ID
+	Global() bool
+}
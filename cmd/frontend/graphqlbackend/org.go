@@ -187,6 +187,15 @@ func (o *OrgResolver) BatchChanges(ctx context.Context, args *ListBatchChangesAr
 	return EnterpriseResolvers.batchChangesResolver.BatchChanges(ctx, args)
 }
 
+func (o *OrgResolver) BatchSpecResolutionJobs(ctx context.Context, args *ListBatchSpecResolutionJobsArgs) (BatchSpecWorkspaceResolutionConnectionResolver, error) {
+	args.Namespace = o.ID()
+	return EnterpriseResolvers.batchChangesResolver.BatchSpecResolutionJobs(ctx, args)
+}
+
+func (o *OrgResolver) BatchSpecResolutionQuota(ctx context.Context) (BatchSpecResolutionQuotaResolver, error) {
+	return EnterpriseResolvers.batchChangesResolver.BatchSpecResolutionQuota(ctx, &BatchSpecResolutionQuotaArgs{Namespace: o.ID()})
+}
+
 func (r *schemaResolver) CreateOrganization(ctx context.Context, args *struct {
 	Name        string
 	DisplayName *string
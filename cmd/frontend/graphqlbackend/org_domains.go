@@ -0,0 +1,127 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+func (o *OrgResolver) Domains(ctx context.Context) ([]*orgDomainResolver, error) {
+	// 🚨 SECURITY: Only organization members and site admins may view the domains, because they
+	// include the verification tokens.
+	if err := backend.CheckOrgAccessOrSiteAdmin(ctx, o.db, o.org.ID); err != nil {
+		return nil, err
+	}
+
+	domains, err := database.OrgDomains(o.db).ListByOrg(ctx, o.org.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*orgDomainResolver, 0, len(domains))
+	for _, d := range domains {
+		resolvers = append(resolvers, &orgDomainResolver{domain: d})
+	}
+	return resolvers, nil
+}
+
+type orgDomainArgs struct {
+	Organization graphql.ID
+	Domain       string
+}
+
+func (r *schemaResolver) AddOrganizationDomain(ctx context.Context, args *orgDomainArgs) (*orgDomainResolver, error) {
+	// 🚨 SECURITY: Only site admins may add domains to organizations, because users of verified
+	// domains join the organization without being invited.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	orgID, err := UnmarshalOrgID(args.Organization)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := database.Orgs(r.db).GetByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	domain := backend.NormalizeEmailDomain(args.Domain)
+	if domain == "" || !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@ ") {
+		return nil, errors.Errorf("invalid domain %q", args.Domain)
+	}
+	token, err := backend.MakeOrgDomainVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+	d, err := database.OrgDomains(r.db).Create(ctx, orgID, domain, token)
+	if err != nil {
+		return nil, err
+	}
+	return &orgDomainResolver{domain: d}, nil
+}
+
+func (r *schemaResolver) VerifyOrganizationDomain(ctx context.Context, args *orgDomainArgs) (*orgDomainResolver, error) {
+	orgID, err := UnmarshalOrgID(args.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only organization members and site admins may check the domains.
+	if err := backend.CheckOrgAccessOrSiteAdmin(ctx, r.db, orgID); err != nil {
+		return nil, err
+	}
+
+	store := database.OrgDomains(r.db)
+	d, err := store.Get(ctx, orgID, backend.NormalizeEmailDomain(args.Domain))
+	if err != nil {
+		return nil, err
+	}
+	if verified, err := backend.CheckOrgDomain(ctx, r.db, d); err != nil {
+		return nil, err
+	} else if !verified {
+		return nil, errors.Errorf("no DNS TXT record of %s is %q", d.Domain, backend.OrgDomainVerificationRecord(d))
+	}
+
+	d, err = store.Get(ctx, orgID, d.Domain)
+	if err != nil {
+		return nil, err
+	}
+	return &orgDomainResolver{domain: d}, nil
+}
+
+func (r *schemaResolver) RemoveOrganizationDomain(ctx context.Context, args *orgDomainArgs) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may remove domains from organizations.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	orgID, err := UnmarshalOrgID(args.Organization)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.OrgDomains(r.db).Delete(ctx, orgID, backend.NormalizeEmailDomain(args.Domain)); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+type orgDomainResolver struct {
+	domain *database.OrgDomain
+}
+
+func (r *orgDomainResolver) Domain() string { return r.domain.Domain }
+func (r *orgDomainResolver) VerificationRecord() string {
+	return backend.OrgDomainVerificationRecord(r.domain)
+}
+func (r *orgDomainResolver) Verified() bool { return r.domain.VerifiedAt != nil }
+func (r *orgDomainResolver) VerifiedAt() *DateTime {
+	return DateTimeOrNil(r.domain.VerifiedAt)
+}
+func (r *orgDomainResolver) LastCheckedAt() *DateTime {
+	return DateTimeOrNil(r.domain.LastCheckedAt)
+}
+func (r *orgDomainResolver) CreatedAt() DateTime { return DateTime{Time: r.domain.CreatedAt} }
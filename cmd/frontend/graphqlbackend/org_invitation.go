@@ -52,8 +52,19 @@ func (r *organizationInvitationResolver) Sender(ctx context.Context) (*UserResol
 }
 
 func (r *organizationInvitationResolver) Recipient(ctx context.Context) (*UserResolver, error) {
+	if r.v.RecipientUserID == 0 {
+		return nil, nil
+	}
 	return UserByIDInt32(ctx, r.db, r.v.RecipientUserID)
 }
+
+func (r *organizationInvitationResolver) RecipientEmail() *string {
+	if r.v.RecipientEmail == "" {
+		return nil
+	}
+	return &r.v.RecipientEmail
+}
+
 func (r *organizationInvitationResolver) CreatedAt() DateTime { return DateTime{Time: r.v.CreatedAt} }
 func (r *organizationInvitationResolver) NotifiedAt() *DateTime {
 	return DateTimeOrNil(r.v.NotifiedAt)
@@ -3,6 +3,7 @@ package graphqlbackend
 import (
 	"context"
 	"fmt"
+	"net/mail"
 	"net/url"
 
 	"github.com/cockroachdb/errors"
@@ -30,9 +31,9 @@ func getUserToInviteToOrganization(ctx context.Context, db dbutil.DB, username s
 
 	if conf.CanSendEmail() {
 		// Look up user's email address so we can send them an email (if needed).
-		email, verified, err := database.UserEmails(db).GetPrimaryEmail(ctx, userToInvite.ID)
+		email, verified, err := backend.UserEmails.ContactEmail(ctx, db, userToInvite.ID)
 		if err != nil && !errcode.IsNotFound(err) {
-			return nil, "", errors.WithMessage(err, "looking up invited user's primary email address")
+			return nil, "", errors.WithMessage(err, "looking up invited user's contact email address")
 		}
 		if verified {
 			// Completely discard unverified emails.
@@ -93,7 +94,63 @@ func (r *schemaResolver) InviteUserToOrganization(ctx context.Context, args *str
 	// Send a notification to the recipient. If disabled, the frontend will still show the
 	// invitation link.
 	if conf.CanSendEmail() && recipientEmail != "" {
-		if err := sendOrgInvitationNotification(ctx, r.db, org, sender, recipientEmail); err != nil {
+		if err := sendOrgInvitationNotification(ctx, r.db, org, sender, recipientEmail, orgInvitationURL(org), emailTemplates); err != nil {
+			return nil, errors.WithMessage(err, "sending notification to invitation recipient")
+		}
+		result.sentInvitationEmail = true
+	}
+
+	return result, nil
+}
+
+func (r *schemaResolver) InviteEmailToOrganization(ctx context.Context, args *struct {
+	Organization graphql.ID
+	Email        string
+}) (*inviteUserToOrganizationResult, error) {
+	var orgID int32
+	if err := relay.UnmarshalSpec(args.Organization, &orgID); err != nil {
+		return nil, err
+	}
+	// 🚨 SECURITY: Check that the current user is a member of the org that the email address is
+	// being invited to.
+	if err := backend.CheckOrgAccessOrSiteAdmin(ctx, r.db, orgID); err != nil {
+		return nil, err
+	}
+
+	if _, err := mail.ParseAddress(args.Email); err != nil {
+		return nil, errors.Errorf("invalid email address %q", args.Email)
+	}
+
+	org, err := database.Orgs(r.db).GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := database.Users(r.db).GetByCurrentAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 🚨 SECURITY: The invitation is recorded, and the response built, the same way whether or not
+	// a user has already verified the address, so that this mutation can't be used to find out
+	// which email addresses have accounts. Invitations to the address of an existing user are
+	// accepted or rejected by that user (see OrgInvitationStore.Respond), instead of being accepted
+	// on their behalf when the address is verified.
+	if _, err := database.OrgInvitations(r.db).CreateForEmail(ctx, orgID, sender.ID, args.Email); err != nil {
+		return nil, err
+	}
+	result := &inviteUserToOrganizationResult{
+		invitationURL: globals.ExternalURL().ResolveReference(orgEmailInvitationURL(org)).String(),
+	}
+
+	// Send a notification to the recipient. If disabled, the frontend will still show the
+	// invitation link.
+	if conf.CanSendEmail() {
+		invitationURL, templates := orgEmailInvitationURL(org), emailInvitationTemplates
+		if existing, err := database.UserEmails(r.db).GetVerifiedEmails(ctx, args.Email); err != nil {
+			return nil, err
+		} else if len(existing) > 0 {
+			invitationURL, templates = orgInvitationURL(org), emailTemplates
+		}
+		if err := sendOrgInvitationNotification(ctx, r.db, org, sender, args.Email, invitationURL, templates); err != nil {
 			return nil, errors.WithMessage(err, "sending notification to invitation recipient")
 		}
 		result.sentInvitationEmail = true
@@ -177,14 +234,21 @@ func (r *schemaResolver) ResendOrganizationInvitationNotification(ctx context.Co
 	if err != nil {
 		return nil, err
 	}
-	recipientEmail, recipientEmailVerified, err := database.UserEmails(r.db).GetPrimaryEmail(ctx, orgInvitation.v.RecipientUserID)
+	if orgInvitation.v.RecipientUserID == 0 {
+		// The invitation was sent to an email address that has no user yet.
+		if err := sendOrgInvitationNotification(ctx, r.db, org, sender, orgInvitation.v.RecipientEmail, orgEmailInvitationURL(org), emailInvitationTemplates); err != nil {
+			return nil, err
+		}
+		return &EmptyResponse{}, nil
+	}
+	recipientEmail, recipientEmailVerified, err := backend.UserEmails.ContactEmail(ctx, r.db, orgInvitation.v.RecipientUserID)
 	if err != nil {
 		return nil, err
 	}
 	if !recipientEmailVerified {
 		return nil, errors.New("refusing to send notification because recipient has no verified email address")
 	}
-	if err := sendOrgInvitationNotification(ctx, r.db, org, sender, recipientEmail); err != nil {
+	if err := sendOrgInvitationNotification(ctx, r.db, org, sender, recipientEmail, orgInvitationURL(org), emailTemplates); err != nil {
 		return nil, err
 	}
 	return &EmptyResponse{}, nil
@@ -213,10 +277,19 @@ func orgInvitationURL(org *types.Org) *url.URL {
 	return &url.URL{Path: fmt.Sprintf("/organizations/%s/invitation", org.Name)}
 }
 
+// orgEmailInvitationURL is the URL sent to an email address that was invited to an org. The
+// recipient has no account yet, so the URL leads to the sign-up page and then on to the org once
+// the recipient's email address is verified (which accepts the invitation).
+func orgEmailInvitationURL(org *types.Org) *url.URL {
+	q := make(url.Values)
+	q.Set("returnTo", fmt.Sprintf("/organizations/%s", org.Name))
+	return &url.URL{Path: "/sign-up", RawQuery: q.Encode()}
+}
+
 // sendOrgInvitationNotification sends an email to the recipient of an org invitation with a link to
 // respond to the invitation. Callers should check conf.CanSendEmail() if they want to return a nice
 // error if sending email is not enabled.
-func sendOrgInvitationNotification(ctx context.Context, db dbutil.DB, org *types.Org, sender *types.User, recipientEmail string) error {
+func sendOrgInvitationNotification(ctx context.Context, db dbutil.DB, org *types.Org, sender *types.User, recipientEmail string, invitationURL *url.URL, templates txtypes.Templates) error {
 	if envvar.SourcegraphDotComMode() {
 		// Basic abuse prevention for Sourcegraph.com.
 
@@ -247,7 +320,7 @@ func sendOrgInvitationNotification(ctx context.Context, db dbutil.DB, org *types
 
 	return txemail.Send(ctx, txemail.Message{
 		To:       []string{recipientEmail},
-		Template: emailTemplates,
+		Template: templates,
 		Data: struct {
 			FromName string
 			OrgName  string
@@ -255,7 +328,7 @@ func sendOrgInvitationNotification(ctx context.Context, db dbutil.DB, org *types
 		}{
 			FromName: fromName,
 			OrgName:  org.Name,
-			URL:      globals.ExternalURL().ResolveReference(orgInvitationURL(org)).String(),
+			URL:      globals.ExternalURL().ResolveReference(invitationURL).String(),
 		},
 	})
 }
@@ -278,3 +351,24 @@ To accept the invitation, follow this link:
 <p><strong><a href="{{.URL}}">Join {{.OrgName}}</a></strong></p>
 `,
 })
+
+var emailInvitationTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `{{.FromName}} invited you to join {{.OrgName}} on Sourcegraph`,
+	Text: `
+{{.FromName}} invited you to join the {{.OrgName}} organization on Sourcegraph.
+
+To accept the invitation, sign up with this email address and verify it:
+
+  {{.URL}}
+`,
+	HTML: `
+<p>
+  <strong>{{.FromName}}</strong> invited you to join the
+  <strong>{{.OrgName}}</strong> organization on Sourcegraph.
+</p>
+
+<p>To accept the invitation, sign up with this email address and verify it.</p>
+
+<p><strong><a href="{{.URL}}">Sign up and join {{.OrgName}}</a></strong></p>
+`,
+})
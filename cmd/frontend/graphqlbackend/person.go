@@ -34,20 +34,34 @@ func NewPersonResolver(db dbutil.DB, name, email string, includeUserInfo bool) *
 	}
 }
 
-// resolveUser resolves the person to a user (using the email address). Not all persons can be
-// resolved to a user.
+// resolveUser resolves the person to a user (using the email address, or the email address it is
+// attributed to). Not all persons can be resolved to a user.
 func (r *PersonResolver) resolveUser(ctx context.Context) (*types.User, error) {
 	r.once.Do(func() {
 		if r.includeUserInfo && r.email != "" {
 			r.user, r.err = database.Users(r.db).GetByVerifiedEmail(ctx, r.email)
 			if errcode.IsNotFound(r.err) {
-				r.err = nil
+				r.user, r.err = r.resolveAttributedUser(ctx)
 			}
 		}
 	})
 	return r.user, r.err
 }
 
+// resolveAttributedUser resolves the person to the user that the email address was attributed to,
+// if any.
+func (r *PersonResolver) resolveAttributedUser(ctx context.Context) (*types.User, error) {
+	attributions, err := database.UserEmails(r.db).GetAttributions(ctx, r.email)
+	if err != nil || len(attributions) == 0 {
+		return nil, err
+	}
+	user, err := database.Users(r.db).GetByID(ctx, attributions[0].UserID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
 func (r *PersonResolver) Name(ctx context.Context) (string, error) {
 	user, err := r.resolveUser(ctx)
 	if err != nil {
@@ -1,6 +1,7 @@
 package graphqlbackend
 
 import (
+	"fmt"
 	"strconv"
 	"sync/atomic"
 
@@ -361,6 +362,75 @@ func shouldCheckParam(p visitor.VisitFuncParams) bool {
 	return true
 }
 
+// QueryTooExpensiveError occurs when the estimated cost or depth of a query exceeds the maximum of
+// the api.ratelimit site configuration, or when a maximum is configured but the cost of the query
+// could not be estimated.
+type QueryTooExpensiveError struct {
+	Cost     *QueryCost // nil if the cost could not be estimated
+	MaxCost  int        // 0 if there is no maximum
+	MaxDepth int        // 0 if there is no maximum
+}
+
+func (e *QueryTooExpensiveError) Error() string {
+	if e.Cost == nil {
+		return "query is too complex: its cost could not be estimated"
+	}
+	if e.MaxDepth > 0 && e.Cost.MaxDepth > e.MaxDepth {
+		return fmt.Sprintf("query is too deep: depth %d exceeds the maximum of %d", e.Cost.MaxDepth, e.MaxDepth)
+	}
+	return fmt.Sprintf("query is too expensive: estimated cost %d exceeds the maximum of %d", e.Cost.FieldCount, e.MaxCost)
+}
+
+// Reason returns the limit that the query exceeded, "max_depth" or "max_cost", or "no_estimate"
+// if its cost could not be estimated.
+func (e *QueryTooExpensiveError) Reason() string {
+	if e.Cost == nil {
+		return "no_estimate"
+	}
+	if e.MaxDepth > 0 && e.Cost.MaxDepth > e.MaxDepth {
+		return "max_depth"
+	}
+	return "max_cost"
+}
+
+// Extensions returns the details of the error that are included in the GraphQL response, so that
+// clients can tell how much to reduce the query.
+func (e *QueryTooExpensiveError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": "QUERY_TOO_EXPENSIVE"}
+	if e.Cost != nil {
+		ext["estimatedCost"] = e.Cost.FieldCount
+		ext["estimatedDepth"] = e.Cost.MaxDepth
+	}
+	if e.MaxCost > 0 {
+		ext["maxCost"] = e.MaxCost
+	}
+	if e.MaxDepth > 0 {
+		ext["maxDepth"] = e.MaxDepth
+	}
+	return ext
+}
+
+// CheckQueryCost returns a *QueryTooExpensiveError if the estimated cost or depth of a query
+// exceeds the maximum of the api.ratelimit site configuration. If a maximum is configured, cost
+// must not be nil: queries whose cost could not be estimated are rejected, so that they can't
+// bypass the maximum.
+func CheckQueryCost(cost *QueryCost) error {
+	return checkQueryCost(cost, conf.Get().ApiRatelimit)
+}
+
+func checkQueryCost(cost *QueryCost, rlc *schema.ApiRatelimit) error {
+	if rlc == nil || (rlc.MaxCost <= 0 && rlc.MaxDepth <= 0) {
+		return nil
+	}
+	if cost == nil {
+		return &QueryTooExpensiveError{MaxCost: rlc.MaxCost, MaxDepth: rlc.MaxDepth}
+	}
+	if (rlc.MaxCost > 0 && cost.FieldCount > rlc.MaxCost) || (rlc.MaxDepth > 0 && cost.MaxDepth > rlc.MaxDepth) {
+		return &QueryTooExpensiveError{Cost: cost, MaxCost: rlc.MaxCost, MaxDepth: rlc.MaxDepth}
+	}
+	return nil
+}
+
 type LimiterArgs struct {
 	IsIP          bool
 	Anonymous     bool
@@ -584,6 +584,91 @@ func TestRatelimitFromConfig(t *testing.T) {
 	}
 }
 
+func TestCheckQueryCost(t *testing.T) {
+	cost := &QueryCost{FieldCount: 100, MaxDepth: 5}
+	for _, tc := range []struct {
+		name       string
+		config     *schema.ApiRatelimit
+		noEstimate bool
+		wantReason string
+		wantExt    map[string]interface{}
+	}{
+		{
+			name: "no config",
+		},
+		{
+			name:   "no maximum",
+			config: &schema.ApiRatelimit{Enabled: true, PerUser: 10, PerIP: 10},
+		},
+		{
+			name:   "within limits",
+			config: &schema.ApiRatelimit{MaxCost: 100, MaxDepth: 5},
+		},
+		{
+			name:       "too expensive",
+			config:     &schema.ApiRatelimit{MaxCost: 99},
+			wantReason: "max_cost",
+			wantExt: map[string]interface{}{
+				"code":           "QUERY_TOO_EXPENSIVE",
+				"estimatedCost":  100,
+				"estimatedDepth": 5,
+				"maxCost":        99,
+			},
+		},
+		{
+			name:       "no estimate without maximum",
+			config:     &schema.ApiRatelimit{Enabled: true, PerUser: 10, PerIP: 10},
+			noEstimate: true,
+		},
+		{
+			name:       "no estimate",
+			config:     &schema.ApiRatelimit{MaxDepth: 4},
+			noEstimate: true,
+			wantReason: "no_estimate",
+			wantExt: map[string]interface{}{
+				"code":     "QUERY_TOO_EXPENSIVE",
+				"maxDepth": 4,
+			},
+		},
+		{
+			name:       "too deep",
+			config:     &schema.ApiRatelimit{MaxCost: 1000, MaxDepth: 4},
+			wantReason: "max_depth",
+			wantExt: map[string]interface{}{
+				"code":           "QUERY_TOO_EXPENSIVE",
+				"estimatedCost":  100,
+				"estimatedDepth": 5,
+				"maxCost":        1000,
+				"maxDepth":       4,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := cost
+			if tc.noEstimate {
+				c = nil
+			}
+			err := checkQueryCost(c, tc.config)
+			if tc.wantReason == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			e, ok := err.(*QueryTooExpensiveError)
+			if !ok {
+				t.Fatalf("got error %v, want *QueryTooExpensiveError", err)
+			}
+			if e.Reason() != tc.wantReason {
+				t.Fatalf("got reason %q, want %q", e.Reason(), tc.wantReason)
+			}
+			if diff := cmp.Diff(tc.wantExt, e.Extensions()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
 func TestBasicLimiterEnabled(t *testing.T) {
 	tests := []struct {
 		limit       int
@@ -0,0 +1,102 @@
+package graphqlbackend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+// SubscriptionPollInterval is how often subscriptions check whether the state they subscribe to
+// changed.
+var SubscriptionPollInterval = 2 * time.Second
+
+// PollSubscription implements a subscription to state that is only stored in the database, which
+// doesn't notify about changes. It calls poll right away and then every SubscriptionPollInterval,
+// and calls send whenever the fingerprint of the state that poll returns changed, including the
+// first time. It returns when ctx is done, poll fails or send returns false.
+//
+// Subscription resolvers call it in a goroutine that closes their result channel when it returns.
+func PollSubscription(ctx context.Context, poll func(ctx context.Context) (fingerprint string, err error), send func() bool) {
+	limit, _ := ctx.Value(subscriptionPollLimiterKey{}).(func() error)
+
+	var last string
+	for first := true; ; first = false {
+		if !first && limit != nil {
+			if err := limit(); err != nil {
+				return
+			}
+		}
+		fingerprint, err := poll(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log15.Warn("polling GraphQL subscription", "error", err)
+			}
+			return
+		}
+		if first || fingerprint != last {
+			if !send() {
+				return
+			}
+			last = fingerprint
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(SubscriptionPollInterval):
+		}
+	}
+}
+
+type subscriptionPollLimiterKey struct{}
+
+// WithSubscriptionPollLimiter returns a context in which PollSubscription calls limit before every
+// poll but the first, which is charged when the subscription starts. The subscription ends when
+// limit returns an error.
+func WithSubscriptionPollLimiter(ctx context.Context, limit func() error) context.Context {
+	return context.WithValue(ctx, subscriptionPollLimiterKey{}, limit)
+}
+
+// RepositorySyncState sends the repository when the subscription starts and whenever its clone or
+// fetch state changes.
+func (r *schemaResolver) RepositorySyncState(ctx context.Context, args *struct{ Repository graphql.ID }) (<-chan *RepositoryResolver, error) {
+	repoID, err := UnmarshalRepositoryID(args.Repository)
+	if err != nil {
+		return nil, err
+	}
+	// 🚨 SECURITY: Getting the repository checks that the current user may see it.
+	repo, err := database.Repos(r.db).Get(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan *RepositoryResolver)
+	go func() {
+		defer close(c)
+		PollSubscription(ctx, func(ctx context.Context) (string, error) {
+			gr, err := database.GitserverRepos(r.db).GetByID(ctx, repo.ID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// The repository wasn't cloned yet.
+				return "", nil
+			} else if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %q %d %d", gr.CloneStatus, gr.LastError, gr.LastFetched.UnixNano(), gr.UpdatedAt.UnixNano()), nil
+		}, func() bool {
+			select {
+			case c <- NewRepositoryResolver(r.db, repo):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return c, nil
+}
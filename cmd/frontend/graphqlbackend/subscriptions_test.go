@@ -0,0 +1,66 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPollSubscription(t *testing.T) {
+	defer func(d time.Duration) { SubscriptionPollInterval = d }(SubscriptionPollInterval)
+	SubscriptionPollInterval = time.Millisecond
+
+	t.Run("sends on changes", func(t *testing.T) {
+		fingerprints := []string{"a", "a", "b", "b", "b", "a", "c"}
+		var polls int
+		var sent []string
+		PollSubscription(context.Background(), func(context.Context) (string, error) {
+			if polls == len(fingerprints) {
+				return "", errors.New("done")
+			}
+			polls++
+			return fingerprints[polls-1], nil
+		}, func() bool {
+			sent = append(sent, fingerprints[polls-1])
+			return true
+		})
+		if diff := cmp.Diff([]string{"a", "b", "a", "c"}, sent); diff != "" {
+			t.Errorf("unexpected sends (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("stops when send fails", func(t *testing.T) {
+		var polls, sends int
+		PollSubscription(context.Background(), func(context.Context) (string, error) {
+			polls++
+			return fmt.Sprint(polls), nil
+		}, func() bool {
+			sends++
+			return sends < 2
+		})
+		if polls != 2 || sends != 2 {
+			t.Fatalf("got %d polls and %d sends, want 2 each", polls, sends)
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			PollSubscription(ctx, func(context.Context) (string, error) {
+				return "a", nil
+			}, func() bool { return true })
+		}()
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("PollSubscription did not return after the context was done")
+		}
+	})
+}
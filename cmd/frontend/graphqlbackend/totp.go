@@ -0,0 +1,133 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+func (r *UserResolver) TwoFactorEnabled(ctx context.Context) (bool, error) {
+	// 🚨 SECURITY: Only the user and site admins can see whether the user enabled two-factor
+	// authentication.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, r.db, r.user.ID); err != nil {
+		return false, err
+	}
+	return backend.UserTOTP.Enabled(ctx, r.db, r.user.ID)
+}
+
+type totpEnrollmentResolver struct {
+	enrollment *backend.TOTPEnrollment
+}
+
+func (r *totpEnrollmentResolver) Secret() string { return r.enrollment.Secret }
+func (r *totpEnrollmentResolver) KeyURI() string { return r.enrollment.KeyURI }
+
+type totpRecoveryCodesResolver struct {
+	codes []string
+}
+
+func (r *totpRecoveryCodesResolver) Codes() []string { return r.codes }
+
+func (r *schemaResolver) EnrollTOTP(ctx context.Context) (*totpEnrollmentResolver, error) {
+	// 🚨 SECURITY: Users can only enroll themselves, because the secret must stay with the user.
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, backend.ErrNotAuthenticated
+	}
+	enrollment, err := backend.UserTOTP.Enroll(ctx, r.db, a.UID)
+	if err != nil {
+		return nil, err
+	}
+	return &totpEnrollmentResolver{enrollment: enrollment}, nil
+}
+
+func (r *schemaResolver) ConfirmTOTPEnrollment(ctx context.Context, args *struct{ Code string }) (*totpRecoveryCodesResolver, error) {
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, backend.ErrNotAuthenticated
+	}
+	codes, err := backend.UserTOTP.ConfirmEnrollment(ctx, r.db, a.UID, args.Code)
+	if err != nil {
+		return nil, err
+	}
+	r.logTwoFactorChange(ctx, a.UID, "enabled")
+	return &totpRecoveryCodesResolver{codes: codes}, nil
+}
+
+func (r *schemaResolver) RegenerateTOTPRecoveryCodes(ctx context.Context, args *struct{ Code string }) (*totpRecoveryCodesResolver, error) {
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, backend.ErrNotAuthenticated
+	}
+
+	// 🚨 SECURITY: Like for disabling two-factor authentication, users must prove that they still
+	// have their authenticator app or a recovery code, so that a stolen session can't be used to get
+	// recovery codes and disable two-factor authentication with them.
+	if args.Code == "" {
+		return nil, errors.New("a two-factor authentication code is required")
+	}
+	ok, err := backend.UserTOTP.Verify(ctx, r.db, a.UID, args.Code)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, backend.ErrInvalidTOTPCode
+	}
+
+	codes, err := backend.UserTOTP.RegenerateRecoveryCodes(ctx, r.db, a.UID)
+	if err != nil {
+		return nil, err
+	}
+	return &totpRecoveryCodesResolver{codes: codes}, nil
+}
+
+func (r *schemaResolver) DisableTOTP(ctx context.Context, args *struct {
+	User graphql.ID
+	Code *string
+}) (*EmptyResponse, error) {
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Users must prove that they still have their authenticator app or a recovery code,
+	// so that a stolen session can't be used to disable two-factor authentication. Site admins can
+	// disable it for other users who lost both.
+	if backend.CheckSameUser(ctx, userID) == nil {
+		if args.Code == nil || *args.Code == "" {
+			return nil, errors.New("a two-factor authentication code is required")
+		}
+		ok, err := backend.UserTOTP.Verify(ctx, r.db, userID, *args.Code)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, backend.ErrInvalidTOTPCode
+		}
+	} else if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	disabled, err := backend.UserTOTP.Disable(ctx, r.db, userID)
+	if err != nil {
+		return nil, err
+	} else if !disabled {
+		return nil, backend.ErrTOTPNotEnabled
+	}
+	r.logTwoFactorChange(ctx, userID, "disabled")
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) logTwoFactorChange(ctx context.Context, userID int32, newValue string) {
+	backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeTwoFactorChanged, userID, backend.IdentityChange{NewValue: newValue})
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, newValue+" two-factor authentication"); err != nil {
+			log15.Warn("Failed to notify user of two-factor authentication change", "error", err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// 🚨 SECURITY: This tests that users need a code to disable their own two-factor authentication,
+// and that only site admins can disable it for other users.
+func TestMutation_DisableTOTP(t *testing.T) {
+	enabledAt := time.Now()
+	tests := []struct {
+		name        string
+		currentUser *types.User
+		code        string
+		wantErr     string
+	}{
+		{
+			name:        "same user without code",
+			currentUser: &types.User{ID: 1},
+			wantErr:     "a two-factor authentication code is required",
+		},
+		{
+			name:        "same user with invalid code",
+			currentUser: &types.User{ID: 1},
+			code:        `"123456"`,
+			wantErr:     backend.ErrInvalidTOTPCode.Error(),
+		},
+		{
+			name:        "other user",
+			currentUser: &types.User{ID: 2},
+			wantErr:     "must be site admin",
+		},
+		{
+			name:        "site admin",
+			currentUser: &types.User{ID: 2, SiteAdmin: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetMocks()
+			database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+				return test.currentUser, nil
+			}
+			database.Mocks.UserTOTPs.GetByUserID = func(context.Context, int32) (*database.UserTOTP, error) {
+				return &database.UserTOTP{UserID: 1, Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", EnabledAt: &enabledAt}, nil
+			}
+			database.Mocks.UserTOTPs.UseStep = func(context.Context, int32, int64) (bool, error) { return false, nil }
+			database.Mocks.UserTOTPs.UseRecoveryCode = func(context.Context, int32, string) (bool, error) { return false, nil }
+			deleted := false
+			database.Mocks.UserTOTPs.Delete = func(context.Context, int32) (bool, error) {
+				deleted = true
+				return true, nil
+			}
+			var events []*database.Event
+			database.Mocks.EventLogs.Insert = func(_ context.Context, e *database.Event) error {
+				events = append(events, e)
+				return nil
+			}
+
+			code := "null"
+			if test.code != "" {
+				code = test.code
+			}
+			var wantErrors []*gqlerrors.QueryError
+			expectedResult := `{"disableTOTP": {"alwaysNil": null}}`
+			if test.wantErr != "" {
+				wantErrors = []*gqlerrors.QueryError{{Path: []interface{}{"disableTOTP"}, Message: test.wantErr}}
+				expectedResult = "null"
+			}
+			RunTest(t, &Test{
+				Context: actor.WithActor(context.Background(), &actor.Actor{UID: test.currentUser.ID}),
+				Schema:  mustParseGraphQLSchema(t),
+				Query: `
+				mutation {
+					disableTOTP(user: "VXNlcjox", code: ` + code + `) {
+						alwaysNil
+					}
+				}
+			`,
+				ExpectedResult: expectedResult,
+				ExpectedErrors: wantErrors,
+			})
+
+			if deleted != (test.wantErr == "") {
+				t.Fatalf("got deleted %v, want %v", deleted, test.wantErr == "")
+			}
+			if deleted && (len(events) != 1 || events[0].Name != backend.IdentityChangeTwoFactorChanged || events[0].UserID != 1) {
+				t.Fatalf("unexpected events %+v", events)
+			}
+		})
+	}
+}
+
+// 🚨 SECURITY: This tests that users need a code to regenerate their recovery codes, which could
+// otherwise be used to disable two-factor authentication with a stolen session.
+func TestMutation_RegenerateTOTPRecoveryCodes(t *testing.T) {
+	enabledAt := time.Now()
+	tests := []struct {
+		name         string
+		code         string
+		recoveryCode bool
+		wantErr      string
+	}{
+		{
+			name:    "without code",
+			wantErr: "a two-factor authentication code is required",
+		},
+		{
+			name:    "invalid code",
+			code:    "123456",
+			wantErr: backend.ErrInvalidTOTPCode.Error(),
+		},
+		{
+			name:         "recovery code",
+			code:         "abcde-fghij",
+			recoveryCode: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetMocks()
+			database.Mocks.UserTOTPs.GetByUserID = func(context.Context, int32) (*database.UserTOTP, error) {
+				return &database.UserTOTP{UserID: 1, Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", EnabledAt: &enabledAt}, nil
+			}
+			database.Mocks.UserTOTPs.UseStep = func(context.Context, int32, int64) (bool, error) { return false, nil }
+			database.Mocks.UserTOTPs.UseRecoveryCode = func(context.Context, int32, string) (bool, error) { return test.recoveryCode, nil }
+			replaced := false
+			database.Mocks.UserTOTPs.ReplaceRecoveryCodes = func(context.Context, int32, []string) error {
+				replaced = true
+				return nil
+			}
+
+			var wantErrors []*gqlerrors.QueryError
+			expectedResult := `{"regenerateTOTPRecoveryCodes": {"__typename": "TOTPRecoveryCodes"}}`
+			if test.wantErr != "" {
+				wantErrors = []*gqlerrors.QueryError{{Path: []interface{}{"regenerateTOTPRecoveryCodes"}, Message: test.wantErr}}
+				expectedResult = "null"
+			}
+			RunTest(t, &Test{
+				Context: actor.WithActor(context.Background(), &actor.Actor{UID: 1}),
+				Schema:  mustParseGraphQLSchema(t),
+				Query: `
+				mutation {
+					regenerateTOTPRecoveryCodes(code: "` + test.code + `") {
+						__typename
+					}
+				}
+			`,
+				ExpectedResult: expectedResult,
+				ExpectedErrors: wantErrors,
+			})
+
+			if replaced != (test.wantErr == "") {
+				t.Fatalf("got replaced %v, want %v", replaced, test.wantErr == "")
+			}
+		})
+	}
+}
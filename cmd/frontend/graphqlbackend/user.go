@@ -130,6 +130,10 @@ func (r *UserResolver) BuiltinAuth() bool {
 	return r.user.BuiltinAuth && providers.BuiltinAuthEnabled()
 }
 
+func (r *UserResolver) ServiceAccount() bool {
+	return backend.IsServiceAccount(r.user)
+}
+
 func (r *UserResolver) AvatarURL() *string {
 	if r.user.AvatarURL == "" {
 		return nil
@@ -313,9 +317,9 @@ func (r *schemaResolver) UpdatePassword(ctx context.Context, args *struct {
 		return nil, err
 	}
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, user.ID, "updated the password"); err != nil {
-			log15.Warn("Failed to send email to inform user of password update", "error", err)
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, user.ID, "updated the password"); err != nil {
+			log15.Warn("Failed to notify user of password update", "error", err)
 		}
 	}
 	return &EmptyResponse{}, nil
@@ -336,9 +340,9 @@ func (r *schemaResolver) CreatePassword(ctx context.Context, args *struct {
 		return nil, err
 	}
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, user.ID, "created a password"); err != nil {
-			log15.Warn("Failed to send email to inform user of password creation", "error", err)
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, user.ID, "created a password"); err != nil {
+			log15.Warn("Failed to notify user of password creation", "error", err)
 		}
 	}
 	return &EmptyResponse{}, nil
@@ -362,6 +366,15 @@ func (r *UserResolver) BatchChanges(ctx context.Context, args *ListBatchChangesA
 	return EnterpriseResolvers.batchChangesResolver.BatchChanges(ctx, args)
 }
 
+func (r *UserResolver) BatchSpecResolutionJobs(ctx context.Context, args *ListBatchSpecResolutionJobsArgs) (BatchSpecWorkspaceResolutionConnectionResolver, error) {
+	args.Namespace = r.ID()
+	return EnterpriseResolvers.batchChangesResolver.BatchSpecResolutionJobs(ctx, args)
+}
+
+func (r *UserResolver) BatchSpecResolutionQuota(ctx context.Context) (BatchSpecResolutionQuotaResolver, error) {
+	return EnterpriseResolvers.batchChangesResolver.BatchSpecResolutionQuota(ctx, &BatchSpecResolutionQuotaArgs{Namespace: r.ID()})
+}
+
 type ListUserRepositoriesArgs struct {
 	First             *int32
 	Query             *string
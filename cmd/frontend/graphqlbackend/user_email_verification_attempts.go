@@ -0,0 +1,88 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func (r *schemaResolver) UserEmailVerificationAttempts(ctx context.Context, args *struct {
+	User  *graphql.ID
+	Email *string
+	First int32
+}) (*userEmailVerificationAttemptConnectionResolver, error) {
+	// 🚨 SECURITY: Only site admins may audit verification emails.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	var opts database.VerificationAttemptsListOptions
+	if args.User != nil {
+		userID, err := UnmarshalUserID(*args.User)
+		if err != nil {
+			return nil, err
+		}
+		opts.UserID = userID
+	}
+	if args.Email != nil {
+		opts.Email = *args.Email
+	}
+	return &userEmailVerificationAttemptConnectionResolver{db: r.db, opts: opts, first: int(args.First)}, nil
+}
+
+type userEmailVerificationAttemptConnectionResolver struct {
+	db    dbutil.DB
+	opts  database.VerificationAttemptsListOptions
+	first int
+}
+
+func (r *userEmailVerificationAttemptConnectionResolver) Nodes(ctx context.Context) ([]*userEmailVerificationAttemptResolver, error) {
+	opts := r.opts
+	opts.LimitOffset = &database.LimitOffset{Limit: r.first}
+	attempts, err := database.UserEmails(r.db).ListVerificationAttempts(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*userEmailVerificationAttemptResolver, 0, len(attempts))
+	for _, a := range attempts {
+		resolvers = append(resolvers, &userEmailVerificationAttemptResolver{db: r.db, attempt: a})
+	}
+	return resolvers, nil
+}
+
+func (r *userEmailVerificationAttemptConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := database.UserEmails(r.db).CountVerificationAttempts(ctx, r.opts)
+	return int32(count), err
+}
+
+type userEmailVerificationAttemptResolver struct {
+	db      dbutil.DB
+	attempt *database.UserEmailVerificationAttempt
+}
+
+func (r *userEmailVerificationAttemptResolver) User(ctx context.Context) (*UserResolver, error) {
+	return UserByIDInt32(ctx, r.db, r.attempt.UserID)
+}
+
+func (r *userEmailVerificationAttemptResolver) Email() string { return r.attempt.Email }
+
+func (r *userEmailVerificationAttemptResolver) Actor(ctx context.Context) (*UserResolver, error) {
+	if r.attempt.ActorUserID == 0 {
+		return nil, nil
+	}
+	user, err := UserByIDInt32(ctx, r.db, r.attempt.ActorUserID)
+	if errcode.IsNotFound(err) {
+		// The user was soft-deleted.
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *userEmailVerificationAttemptResolver) CreatedAt() DateTime {
+	return DateTime{Time: r.attempt.CreatedAt}
+}
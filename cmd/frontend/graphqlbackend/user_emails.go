@@ -57,12 +57,28 @@ func (r *userEmailResolver) IsPrimary(ctx context.Context) (bool, error) {
 	return email == r.userEmail.Email, nil
 }
 
+func (r *userEmailResolver) IsRecovery() bool { return r.userEmail.Recovery }
+
 func (r *userEmailResolver) Verified() bool { return r.userEmail.VerifiedAt != nil }
 func (r *userEmailResolver) VerificationPending() bool {
-	return !r.Verified() && conf.EmailVerificationRequired()
+	return !r.Verified() && conf.EmailVerificationRequired() && !backend.IsServiceAccount(r.user.user)
 }
 func (r *userEmailResolver) User() *UserResolver { return r.user }
 
+func (r *userEmailResolver) AddedAt() DateTime { return DateTime{Time: r.userEmail.CreatedAt} }
+func (r *userEmailResolver) VerifiedAt() *DateTime {
+	return DateTimeOrNil(r.userEmail.VerifiedAt)
+}
+func (r *userEmailResolver) VerificationSentAt() *DateTime {
+	return DateTimeOrNil(r.userEmail.LastVerificationSentAt)
+}
+
+func (r *userEmailResolver) Bouncing() bool { return r.userEmail.BouncedAt != nil }
+func (r *userEmailResolver) BouncedAt() *DateTime {
+	return DateTimeOrNil(r.userEmail.BouncedAt)
+}
+func (r *userEmailResolver) BounceReason() *string { return r.userEmail.BounceReason }
+
 func (r *userEmailResolver) ViewerCanManuallyVerify(ctx context.Context) (bool, error) {
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err == backend.ErrNotAuthenticated || err == backend.ErrMustBeSiteAdmin {
 		return false, nil
@@ -75,7 +91,7 @@ func (r *userEmailResolver) ViewerCanManuallyVerify(ctx context.Context) (bool,
 func (r *schemaResolver) AddUserEmail(ctx context.Context, args *struct {
 	User  graphql.ID
 	Email string
-}) (*EmptyResponse, error) {
+}) (*userEmailMutationResult, error) {
 	userID, err := UnmarshalUserID(args.User)
 	if err != nil {
 		return nil, err
@@ -84,14 +100,15 @@ func (r *schemaResolver) AddUserEmail(ctx context.Context, args *struct {
 	if err := backend.UserEmails.Add(ctx, r.db, userID, args.Email); err != nil {
 		return nil, err
 	}
+	backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeUserEmailAdded, userID, backend.IdentityChange{Email: args.Email})
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, userID, "added an email"); err != nil {
-			log15.Warn("Failed to send email to inform user of email addition", "error", err)
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "added an email"); err != nil {
+			log15.Warn("Failed to notify user of email addition", "error", err)
 		}
 	}
 
-	return &EmptyResponse{}, nil
+	return &userEmailMutationResult{db: r.db}, nil
 }
 
 func (r *schemaResolver) RemoveUserEmail(ctx context.Context, args *struct {
@@ -103,23 +120,91 @@ func (r *schemaResolver) RemoveUserEmail(ctx context.Context, args *struct {
 		return nil, err
 	}
 
-	// 🚨 SECURITY: Only the user and site admins can remove an email address from a user.
-	if err := backend.CheckSiteAdminOrSameUser(ctx, r.db, userID); err != nil {
+	// 🚨 SECURITY: Only the user and site admins can remove an email address from a user, and only
+	// site admins can remove one from a service account.
+	if err := backend.CheckCanEditUserEmails(ctx, r.db, userID); err != nil {
 		return nil, err
 	}
 
 	if err := database.UserEmails(r.db).Remove(ctx, userID, args.Email); err != nil {
 		return nil, err
 	}
+	backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeUserEmailRemoved, userID, backend.IdentityChange{Email: args.Email})
 
 	// 🚨 SECURITY: If an email is removed, invalidate any existing password reset tokens that may have been sent to that email.
 	if err := database.Users(r.db).DeletePasswordResetCode(ctx, userID); err != nil {
 		return nil, err
 	}
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, userID, "removed an email"); err != nil {
-			log15.Warn("Failed to send email to inform user of email removal", "error", err)
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "removed an email"); err != nil {
+			log15.Warn("Failed to notify user of email removal", "error", err)
+		}
+	}
+
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) RestoreUserEmail(ctx context.Context, args *struct {
+	User  graphql.ID
+	Email string
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins can restore a removed email address, since the user may have
+	// removed it because they lost access to it.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.UserEmails(r.db).Restore(ctx, userID, args.Email); err != nil {
+		return nil, err
+	}
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "restored an email"); err != nil {
+			log15.Warn("Failed to notify user of email restoration", "error", err)
+		}
+	}
+
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) TransferUserEmailAttribution(ctx context.Context, args *struct {
+	User graphql.ID
+	From string
+	To   string
+}) (*EmptyResponse, error) {
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only the user and site admins can transfer the attribution of a user's email
+	// address, and only site admins can transfer the one of a service account.
+	if err := backend.CheckCanEditUserEmails(ctx, r.db, userID); err != nil {
+		return nil, err
+	}
+
+	if err := database.UserEmails(r.db).TransferAttribution(ctx, userID, args.From, args.To); err != nil {
+		return nil, err
+	}
+
+	// The user may now be granted pending permissions bound to the attributed email address.
+	if err := database.GlobalAuthz.GrantPendingPermissions(ctx, &database.GrantPendingPermissionsArgs{
+		UserID: userID,
+		Perm:   authz.Read,
+		Type:   authz.PermRepos,
+	}); err != nil {
+		log15.Error("Failed to grant user pending permissions", "userID", userID, "error", err)
+	}
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "transferred the attribution of an email"); err != nil {
+			log15.Warn("Failed to notify user of email attribution transfer", "error", err)
 		}
 	}
 
@@ -135,24 +220,171 @@ func (r *schemaResolver) SetUserEmailPrimary(ctx context.Context, args *struct {
 		return nil, err
 	}
 
-	// 🚨 SECURITY: Only the user and site admins can set the primary email address from a user.
-	if err := backend.CheckSiteAdminOrSameUser(ctx, r.db, userID); err != nil {
+	// 🚨 SECURITY: Only the user and site admins can set the primary email address from a user, and
+	// only site admins can set the one of a service account.
+	if err := backend.CheckCanEditUserEmails(ctx, r.db, userID); err != nil {
 		return nil, err
 	}
 
+	// The previous primary email address is only recorded in the identity change, so failing to
+	// look it up is not fatal.
+	oldPrimary, _, _ := database.UserEmails(r.db).GetPrimaryEmail(ctx, userID)
+
 	if err := database.UserEmails(r.db).SetPrimaryEmail(ctx, userID, args.Email); err != nil {
 		return nil, err
 	}
+	backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeUserEmailPrimaryChanged, userID, backend.IdentityChange{
+		Email:    args.Email,
+		OldValue: oldPrimary,
+		NewValue: args.Email,
+	})
+
+	// 🚨 SECURITY: Depending on the site policy, sign the user out everywhere. Otherwise someone
+	// who got hold of a session or access token could swap the primary email and then reset the
+	// password to take over the account, without the user's existing sessions noticing.
+	if err := revokeSessionsOnPrimaryEmailChange(ctx, r.db, userID); err != nil {
+		return nil, err
+	}
 
-	if conf.CanSendEmail() {
-		if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, userID, "changed primary email"); err != nil {
-			log15.Warn("Failed to send email to inform user of primary address change", "error", err)
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "changed primary email"); err != nil {
+			log15.Warn("Failed to notify user of primary address change", "error", err)
 		}
 	}
 
 	return &EmptyResponse{}, nil
 }
 
+func (r *schemaResolver) SetUserRecoveryEmail(ctx context.Context, args *struct {
+	User  graphql.ID
+	Email string
+}) (*EmptyResponse, error) {
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only the user and site admins can set the recovery email address of a user, and
+	// only site admins can set the one of a service account.
+	if err := backend.CheckCanEditUserEmails(ctx, r.db, userID); err != nil {
+		return nil, err
+	}
+
+	if err := database.UserEmails(r.db).SetRecoveryEmail(ctx, userID, args.Email); err != nil {
+		return nil, err
+	}
+	if err := onRecoveryEmailChanged(ctx, r.db, userID, "set a recovery email"); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) RemoveUserRecoveryEmail(ctx context.Context, args *struct {
+	User graphql.ID
+}) (*EmptyResponse, error) {
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only the user and site admins can unset the recovery email address of a user,
+	// and only site admins can unset the one of a service account.
+	if err := backend.CheckCanEditUserEmails(ctx, r.db, userID); err != nil {
+		return nil, err
+	}
+
+	cleared, err := database.UserEmails(r.db).ClearRecoveryEmail(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cleared {
+		if err := onRecoveryEmailChanged(ctx, r.db, userID, "removed the recovery email"); err != nil {
+			return nil, err
+		}
+	}
+	return &EmptyResponse{}, nil
+}
+
+// onRecoveryEmailChanged invalidates the password reset code of the user, logs the change as a
+// security event and notifies the user about it.
+func onRecoveryEmailChanged(ctx context.Context, db dbutil.DB, userID int32, change string) error {
+	// 🚨 SECURITY: Invalidate any existing password reset code, which may have been sent to the
+	// previous recovery email address.
+	if err := database.Users(db).DeletePasswordResetCode(ctx, userID); err != nil {
+		return err
+	}
+	database.LogPasswordEvent(ctx, db, nil, database.SecurityEventNameRecoveryEmailChanged, userID)
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, change); err != nil {
+			log15.Warn("Failed to notify user of recovery email change", "error", err)
+		}
+	}
+	return nil
+}
+
+func (r *schemaResolver) RequestUserEmailChange(ctx context.Context, args *struct {
+	User  graphql.ID
+	Email string
+}) (*userEmailMutationResult, error) {
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := backend.UserEmails.RequestChange(ctx, r.db, userID, args.Email); err != nil {
+		return nil, err
+	}
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "requested to change the primary email"); err != nil {
+			log15.Warn("Failed to notify user of email change request", "error", err)
+		}
+	}
+
+	return &userEmailMutationResult{db: r.db}, nil
+}
+
+func (r *schemaResolver) ConfirmUserEmailChange(ctx context.Context, args *struct {
+	User  graphql.ID
+	Token string
+}) (*EmptyResponse, error) {
+	userID, err := UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPrimary, _, _ := database.UserEmails(r.db).GetPrimaryEmail(ctx, userID)
+
+	email, err := backend.UserEmails.ConfirmChange(ctx, r.db, userID, args.Token)
+	if err != nil {
+		return nil, err
+	}
+	backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeUserEmailPrimaryChanged, userID, backend.IdentityChange{
+		Email:    email,
+		OldValue: oldPrimary,
+		NewValue: email,
+	})
+
+	// 🚨 SECURITY: Depending on the site policy, sign the user out everywhere, as for any other
+	// change of the primary email address.
+	if err := revokeSessionsOnPrimaryEmailChange(ctx, r.db, userID); err != nil {
+		return nil, err
+	}
+
+	if conf.CanNotifyOfAccountChanges() {
+		if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, userID, "changed primary email"); err != nil {
+			log15.Warn("Failed to notify user of primary address change", "error", err)
+		}
+	}
+
+	return &EmptyResponse{}, nil
+}
+
+// revokeSessionsOnPrimaryEmailChange applies the auth.primaryEmailChangeSessionRevocation site
+// policy after the primary email of a user changed.
+var revokeSessionsOnPrimaryEmailChange = backend.UserEmails.RevokeSessionsOnPrimaryEmailChange
+
 func (r *schemaResolver) SetUserEmailVerified(ctx context.Context, args *struct {
 	User     graphql.ID
 	Email    string
@@ -168,9 +400,18 @@ func (r *schemaResolver) SetUserEmailVerified(ctx context.Context, args *struct
 	if err != nil {
 		return nil, err
 	}
+	_, wasVerified, err := database.UserEmails(r.db).Get(ctx, userID, args.Email)
+	if err != nil {
+		return nil, err
+	}
 	if err := database.UserEmails(r.db).SetVerified(ctx, userID, args.Email, args.Verified); err != nil {
 		return nil, err
 	}
+	backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeUserEmailVerifiedChanged, userID, backend.IdentityChange{
+		Email:    args.Email,
+		OldValue: verificationStatus(wasVerified),
+		NewValue: verificationStatus(args.Verified),
+	})
 
 	// Avoid unnecessary calls if the email is set to unverified.
 	if args.Verified {
@@ -186,10 +427,65 @@ func (r *schemaResolver) SetUserEmailVerified(ctx context.Context, args *struct
 	return &EmptyResponse{}, nil
 }
 
+func (r *schemaResolver) SetUserEmailsVerified(ctx context.Context, args *struct {
+	Users    []graphql.ID
+	Verified bool
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins (NOT users themselves) can manually set email verification
+	// status.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int32, 0, len(args.Users))
+	for _, id := range args.Users {
+		userID, err := UnmarshalUserID(id)
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if _, err := database.UserEmails(r.db).SetVerifiedBulk(ctx, userIDs, args.Verified); err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		// All email addresses of the user were changed, so no single address or previous status
+		// is recorded.
+		backend.LogIdentityChange(ctx, r.db, backend.IdentityChangeUserEmailVerifiedChanged, userID, backend.IdentityChange{
+			NewValue: verificationStatus(args.Verified),
+		})
+	}
+
+	// Avoid unnecessary calls if the emails are set to unverified.
+	if args.Verified {
+		for _, userID := range userIDs {
+			if err := database.GlobalAuthz.GrantPendingPermissions(ctx, &database.GrantPendingPermissionsArgs{
+				UserID: userID,
+				Perm:   authz.Read,
+				Type:   authz.PermRepos,
+			}); err != nil {
+				log15.Error("Failed to grant user pending permissions", "userID", userID, "error", err)
+			}
+		}
+	}
+
+	return &EmptyResponse{}, nil
+}
+
+// verificationStatus returns the value recorded in identity changes for the verification status of
+// an email address.
+func verificationStatus(verified bool) string {
+	if verified {
+		return "verified"
+	}
+	return "unverified"
+}
+
 func (r *schemaResolver) ResendVerificationEmail(ctx context.Context, args *struct {
 	User  graphql.ID
 	Email string
-}) (*EmptyResponse, error) {
+}) (*userEmailMutationResult, error) {
 	userID, err := UnmarshalUserID(args.User)
 	if err != nil {
 		return nil, err
@@ -203,24 +499,46 @@ func (r *schemaResolver) ResendVerificationEmail(ctx context.Context, args *stru
 	if err != nil {
 		return nil, err
 	}
+	if backend.IsServiceAccount(user) {
+		return nil, errors.New("service accounts are exempt from email verification")
+	}
+
+	// 🚨 SECURITY: Look up the email of the user before anything else, so that the errors below
+	// don't reveal whether the address belongs to another user.
+	email, verified, err := database.UserEmails(r.db).Get(ctx, userID, args.Email)
+	if err != nil {
+		return nil, err
+	}
+	if verified {
+		return &userEmailMutationResult{db: r.db}, nil
+	}
 
-	lastSent, err := database.UserEmails(r.db).GetLatestVerificationSentEmail(ctx, args.Email)
+	lastSent, err := database.UserEmails(r.db).GetLatestVerificationSentEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 	if lastSent != nil &&
 		lastSent.LastVerificationSentAt != nil &&
-		timeNow().Sub(*lastSent.LastVerificationSentAt) < 1*time.Minute {
+		timeNow().Sub(*lastSent.LastVerificationSentAt) < conf.EmailVerificationResendCooldown() {
 		return nil, errors.New("Last verification email sent too recently")
 	}
+	if max := conf.EmailVerificationResendMaxPerDay(); max > 0 {
+		sent, err := database.UserEmails(r.db).CountVerificationAttempts(ctx, database.VerificationAttemptsListOptions{
+			UserID: userID,
+			Email:  email,
+			Since:  timeNow().Add(-24 * time.Hour),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if sent >= max {
+			return nil, errors.Errorf("At most %d verification emails can be sent per day, try again later", max)
+		}
+	}
 
-	email, verified, err := database.UserEmails(r.db).Get(ctx, userID, args.Email)
-	if err != nil {
+	if err := backend.CheckEmailRateLimit(ctx, r.db, userID); err != nil {
 		return nil, err
 	}
-	if verified {
-		return &EmptyResponse{}, nil
-	}
 
 	code, err := backend.MakeEmailVerificationCode()
 	if err != nil {
@@ -232,10 +550,10 @@ func (r *schemaResolver) ResendVerificationEmail(ctx context.Context, args *stru
 		return nil, err
 	}
 
-	err = backend.SendUserEmailVerificationEmail(ctx, user.Username, email, code)
+	err = backend.SendUserEmailVerificationEmail(ctx, userID, user.Username, email, code)
 	if err != nil {
 		return nil, err
 	}
 
-	return &EmptyResponse{}, nil
+	return &userEmailMutationResult{db: r.db}, nil
 }
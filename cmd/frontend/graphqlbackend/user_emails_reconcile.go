@@ -0,0 +1,60 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+func (r *schemaResolver) ReconcileUserEmails(ctx context.Context, args *struct {
+	DryRun bool
+}) (*userEmailReconciliationResolver, error) {
+	// 🚨 SECURITY: Only site admins can compare and change the email addresses of all users.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	reconciliation, err := backend.UserEmails.Reconcile(ctx, r.db, args.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &userEmailReconciliationResolver{db: r.db, reconciliation: reconciliation}, nil
+}
+
+type userEmailReconciliationResolver struct {
+	db             dbutil.DB
+	reconciliation *backend.UserEmailReconciliation
+}
+
+func (r *userEmailReconciliationResolver) Discrepancies() []*userEmailDiscrepancyResolver {
+	resolvers := make([]*userEmailDiscrepancyResolver, 0, len(r.reconciliation.Discrepancies))
+	for _, d := range r.reconciliation.Discrepancies {
+		resolvers = append(resolvers, &userEmailDiscrepancyResolver{db: r.db, discrepancy: d})
+	}
+	return resolvers
+}
+
+func (r *userEmailReconciliationResolver) Errors() []string { return r.reconciliation.Errors }
+
+type userEmailDiscrepancyResolver struct {
+	db          dbutil.DB
+	discrepancy *backend.UserEmailDiscrepancy
+}
+
+func (r *userEmailDiscrepancyResolver) User(ctx context.Context) (*UserResolver, error) {
+	return UserByIDInt32(ctx, r.db, r.discrepancy.UserID)
+}
+
+func (r *userEmailDiscrepancyResolver) Email() string { return r.discrepancy.Email }
+
+func (r *userEmailDiscrepancyResolver) Kind() string { return string(r.discrepancy.Kind) }
+
+func (r *userEmailDiscrepancyResolver) Fixed() bool { return r.discrepancy.Fixed }
+
+func (r *userEmailDiscrepancyResolver) FixError() *string {
+	if r.discrepancy.FixError == "" {
+		return nil
+	}
+	return &r.discrepancy.FixError
+}
@@ -2,15 +2,25 @@ package graphqlbackend
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"github.com/graph-gophers/graphql-go"
 	gqlerrors "github.com/graph-gophers/graphql-go/errors"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
 	"github.com/sourcegraph/sourcegraph/internal/txemail"
 	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestSetUserEmailVerified(t *testing.T) {
@@ -18,6 +28,9 @@ func TestSetUserEmailVerified(t *testing.T) {
 	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
 		return &types.User{SiteAdmin: true}, nil
 	}
+	database.Mocks.UserEmails.Get = func(int32, string) (string, bool, error) {
+		return "alice@example.com", false, nil
+	}
 	database.Mocks.UserEmails.SetVerified = func(context.Context, int32, string, bool) error {
 		return nil
 	}
@@ -26,6 +39,7 @@ func TestSetUserEmailVerified(t *testing.T) {
 		name                                string
 		gqlTests                            []*Test
 		expectCalledGrantPendingPermissions bool
+		wantChange                          backend.IdentityChange
 	}{
 		{
 			name: "set an email to be verified",
@@ -49,6 +63,7 @@ func TestSetUserEmailVerified(t *testing.T) {
 				},
 			},
 			expectCalledGrantPendingPermissions: true,
+			wantChange:                          backend.IdentityChange{Email: "alice@example.com", OldValue: "unverified", NewValue: "verified"},
 		},
 		{
 			name: "set an email to be unverified",
@@ -72,6 +87,7 @@ func TestSetUserEmailVerified(t *testing.T) {
 				},
 			},
 			expectCalledGrantPendingPermissions: false,
+			wantChange:                          backend.IdentityChange{Email: "alice@example.com", OldValue: "unverified", NewValue: "unverified"},
 		},
 	}
 	for _, test := range tests {
@@ -81,12 +97,320 @@ func TestSetUserEmailVerified(t *testing.T) {
 				calledGrantPendingPermissions = true
 				return nil
 			}
+			var events []*database.Event
+			database.Mocks.EventLogs.Insert = func(_ context.Context, e *database.Event) error {
+				events = append(events, e)
+				return nil
+			}
 
 			RunTests(t, test.gqlTests)
 
 			if test.expectCalledGrantPendingPermissions != calledGrantPendingPermissions {
 				t.Fatalf("calledGrantPendingPermissions: want %v but got %v", test.expectCalledGrantPendingPermissions, calledGrantPendingPermissions)
 			}
+
+			if len(events) != 1 || events[0].Name != backend.IdentityChangeUserEmailVerifiedChanged || events[0].UserID != 1 {
+				t.Fatalf("unexpected events %+v", events)
+			}
+			var change backend.IdentityChange
+			if err := json.Unmarshal(events[0].Argument, &change); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantChange, change); diff != "" {
+				t.Fatalf("unexpected identity change (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUserEmailTimestamps(t *testing.T) {
+	resetMocks()
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id}, nil
+	}
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1}, nil
+	}
+	added := time.Date(2021, 9, 1, 10, 0, 0, 0, time.UTC)
+	sent := added.Add(time.Minute)
+	verified := added.Add(time.Hour)
+	database.Mocks.UserEmails.ListByUser = func(context.Context, database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		return []*database.UserEmail{
+			{UserID: 1, Email: "alice@example.com", CreatedAt: added, LastVerificationSentAt: &sent, VerifiedAt: &verified},
+			{UserID: 1, Email: "alice@example.org", CreatedAt: added},
+		}, nil
+	}
+
+	RunTest(t, &Test{
+		Schema: mustParseGraphQLSchema(t),
+		Query: `
+			{
+				node(id: "VXNlcjox") {
+					... on User {
+						emails {
+							email
+							addedAt
+							verifiedAt
+							verificationSentAt
+						}
+					}
+				}
+			}
+		`,
+		ExpectedResult: `
+			{
+				"node": {
+					"emails": [
+						{
+							"email": "alice@example.com",
+							"addedAt": "2021-09-01T10:00:00Z",
+							"verifiedAt": "2021-09-01T11:00:00Z",
+							"verificationSentAt": "2021-09-01T10:01:00Z"
+						},
+						{
+							"email": "alice@example.org",
+							"addedAt": "2021-09-01T10:00:00Z",
+							"verifiedAt": null,
+							"verificationSentAt": null
+						}
+					]
+				}
+			}
+		`,
+	})
+}
+
+func TestSetUserEmailsVerified(t *testing.T) {
+	resetMocks()
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	var gotUserIDs []int32
+	database.Mocks.UserEmails.SetVerifiedBulk = func(_ context.Context, userIDs []int32, _ bool) (int, error) {
+		gotUserIDs = userIDs
+		return len(userIDs), nil
+	}
+	var grantedUserIDs []int32
+	database.Mocks.Authz.GrantPendingPermissions = func(_ context.Context, args *database.GrantPendingPermissionsArgs) error {
+		grantedUserIDs = append(grantedUserIDs, args.UserID)
+		return nil
+	}
+	var loggedUserIDs []int32
+	database.Mocks.EventLogs.Insert = func(_ context.Context, e *database.Event) error {
+		loggedUserIDs = append(loggedUserIDs, int32(e.UserID))
+		return nil
+	}
+
+	for _, verified := range []bool{true, false} {
+		t.Run(fmt.Sprintf("verified %t", verified), func(t *testing.T) {
+			gotUserIDs, grantedUserIDs, loggedUserIDs = nil, nil, nil
+
+			RunTest(t, &Test{
+				Schema: mustParseGraphQLSchema(t),
+				Query: fmt.Sprintf(`
+				mutation {
+					setUserEmailsVerified(users: ["VXNlcjox", "VXNlcjoy"], verified: %t) {
+						alwaysNil
+					}
+				}
+			`, verified),
+				ExpectedResult: `
+				{
+					"setUserEmailsVerified": {
+						"alwaysNil": null
+					}
+				}
+			`,
+			})
+
+			if diff := cmp.Diff([]int32{1, 2}, gotUserIDs); diff != "" {
+				t.Fatalf("unexpected user IDs (-want +got):\n%s", diff)
+			}
+			var wantGranted []int32
+			if verified {
+				wantGranted = []int32{1, 2}
+			}
+			if diff := cmp.Diff(wantGranted, grantedUserIDs); diff != "" {
+				t.Fatalf("unexpected pending permission grants (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]int32{1, 2}, loggedUserIDs); diff != "" {
+				t.Fatalf("unexpected identity changes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIdentityChanges(t *testing.T) {
+	resetMocks()
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	database.Mocks.Users.GetByID = func(_ context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id, Username: "alice"}, nil
+	}
+	var gotOpt database.EventLogsListOptions
+	database.Mocks.EventLogs.ListAll = func(_ context.Context, opt database.EventLogsListOptions) ([]*types.Event, error) {
+		gotOpt = opt
+		userID := int32(1)
+		return []*types.Event{{
+			Name:     backend.IdentityChangeUserEmailRemoved,
+			UserID:   &userID,
+			Argument: `{"actor":1,"email":"alice@example.com"}`,
+		}}, nil
+	}
+	database.Mocks.EventLogs.CountAll = func(context.Context, database.EventLogsListOptions) (int, error) {
+		return 1, nil
+	}
+
+	RunTest(t, &Test{
+		Schema: mustParseGraphQLSchema(t),
+		Query: `
+			{
+				identityChanges(user: "VXNlcjox") {
+					nodes {
+						name
+						user { username }
+						argument
+					}
+					totalCount
+				}
+			}
+		`,
+		ExpectedResult: `
+			{
+				"identityChanges": {
+					"nodes": [{
+						"name": "UserEmailRemoved",
+						"user": { "username": "alice" },
+						"argument": "{\"actor\":1,\"email\":\"alice@example.com\"}"
+					}],
+					"totalCount": 1
+				}
+			}
+		`,
+	})
+
+	want := database.EventLogsListOptions{
+		UserID:      1,
+		LimitOffset: &database.LimitOffset{Limit: 50},
+		EventNames:  backend.IdentityChangeEventNames,
+	}
+	if diff := cmp.Diff(want, gotOpt); diff != "" {
+		t.Fatalf("unexpected options (-want +got):\n%s", diff)
+	}
+
+	// Only site admins may review identity changes.
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 2}, nil
+	}
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 2})
+	if _, err := newSchemaResolver(new(dbtesting.MockDB)).IdentityChanges(ctx, &struct {
+		graphqlutil.ConnectionArgs
+		User *graphql.ID
+	}{}); err != backend.ErrMustBeSiteAdmin {
+		t.Fatalf("got error %v, want %v", err, backend.ErrMustBeSiteAdmin)
+	}
+}
+
+func TestRestoreUserEmail(t *testing.T) {
+	resetMocks()
+	var restored []string
+	database.Mocks.UserEmails.Restore = func(_ context.Context, userID int32, email string) error {
+		restored = append(restored, fmt.Sprintf("%d:%s", userID, email))
+		return nil
+	}
+
+	t.Run("non site admin", func(t *testing.T) {
+		database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return &types.User{ID: 1}, nil
+		}
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		_, err := newSchemaResolver(new(dbtesting.MockDB)).RestoreUserEmail(ctx, &struct {
+			User  graphql.ID
+			Email string
+		}{User: MarshalUserID(1), Email: "alice@example.com"})
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Fatalf("got err %v, want %v", err, want)
+		}
+		if len(restored) != 0 {
+			t.Fatalf("unexpected restored emails %v", restored)
+		}
+	})
+
+	t.Run("site admin", func(t *testing.T) {
+		database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+			return &types.User{ID: 2, SiteAdmin: true}, nil
+		}
+		RunTest(t, &Test{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				mutation {
+					restoreUserEmail(user: "VXNlcjox", email: "alice@example.com") {
+						alwaysNil
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"restoreUserEmail": {
+						"alwaysNil": null
+					}
+				}
+			`,
+		})
+		if diff := cmp.Diff([]string{"1:alice@example.com"}, restored); diff != "" {
+			t.Fatalf("unexpected restored emails (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRevokeSessionsOnPrimaryEmailChange(t *testing.T) {
+	const userID = 1
+
+	tests := []struct {
+		policy      string
+		actor       *actor.Actor
+		wantRevoked bool
+	}{
+		{policy: "", actor: &actor.Actor{UID: 2}, wantRevoked: false},
+		{policy: "never", actor: &actor.Actor{UID: 2}, wantRevoked: false},
+		{policy: "unrecognized", actor: &actor.Actor{UID: userID, FromSessionCookie: true}, wantRevoked: false},
+		{policy: "unrecognized", actor: &actor.Actor{UID: userID}, wantRevoked: true},
+		{policy: "unrecognized", actor: &actor.Actor{UID: 2, FromSessionCookie: true}, wantRevoked: true},
+		{policy: "always", actor: &actor.Actor{UID: userID, FromSessionCookie: true}, wantRevoked: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("policy %q, actor %+v", test.policy, *test.actor), func(t *testing.T) {
+			resetMocks()
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+				AuthPrimaryEmailChangeSessionRevocation: test.policy,
+			}})
+			defer conf.Mock(nil)
+
+			var invalidatedSessions, deletedTokens bool
+			database.Mocks.Users.InvalidateSessionsByID = func(_ context.Context, id int32) error {
+				invalidatedSessions = id == userID
+				return nil
+			}
+			database.Mocks.AccessTokens.DeleteBySubjectUser = func(subjectUserID int32) error {
+				deletedTokens = subjectUserID == userID
+				return nil
+			}
+			defer func() {
+				database.Mocks.Users.InvalidateSessionsByID = nil
+				database.Mocks.AccessTokens.DeleteBySubjectUser = nil
+			}()
+
+			ctx := actor.WithActor(context.Background(), test.actor)
+			if err := revokeSessionsOnPrimaryEmailChange(ctx, nil, userID); err != nil {
+				t.Fatal(err)
+			}
+			if invalidatedSessions != test.wantRevoked {
+				t.Errorf("got sessions invalidated %v, want %v", invalidatedSessions, test.wantRevoked)
+			}
+			if deletedTokens != test.wantRevoked {
+				t.Errorf("got access tokens deleted %v, want %v", deletedTokens, test.wantRevoked)
+			}
 		})
 	}
 }
@@ -238,6 +562,9 @@ func TestResendUserEmailVerification(t *testing.T) {
 				emailSent = true
 				return nil
 			}
+			database.Mocks.EmailDeliveries.Create = func(context.Context, *database.EmailDelivery) error {
+				return nil
+			}
 			database.Mocks.UserEmails.Get = func(id int32, email string) (string, bool, error) {
 				if email != test.email.Email {
 					return "", false, errors.New("oh no!")
@@ -256,3 +583,125 @@ func TestResendUserEmailVerification(t *testing.T) {
 		})
 	}
 }
+
+func TestResendUserEmailVerification_MaxPerDay(t *testing.T) {
+	resetMocks()
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id}, nil
+	}
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: true}, nil
+	}
+	database.Mocks.UserEmails.Get = func(id int32, email string) (string, bool, error) {
+		return email, false, nil
+	}
+	database.Mocks.UserEmails.GetLatestVerificationSentEmail = func(context.Context, string) (*database.UserEmail, error) {
+		return nil, nil
+	}
+	database.Mocks.UserEmails.SetLastVerification = func(context.Context, int32, string, string) error {
+		return nil
+	}
+	var sent int
+	database.Mocks.UserEmails.CountVerificationAttempts = func(_ context.Context, opts database.VerificationAttemptsListOptions) (int, error) {
+		if opts.UserID != 1 || opts.Email != "alice@example.com" || opts.Since.IsZero() {
+			t.Errorf("unexpected options %+v", opts)
+		}
+		return sent, nil
+	}
+	txemail.MockSend = func(ctx context.Context, msg txemail.Message) error {
+		return nil
+	}
+	database.Mocks.EmailDeliveries.Create = func(context.Context, *database.EmailDelivery) error {
+		return nil
+	}
+	defer func() { txemail.MockSend = nil }()
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		EmailVerificationResend: &schema.EmailVerificationResend{MaxPerDay: 3},
+	}})
+	defer conf.Mock(nil)
+
+	const query = `
+		mutation {
+			resendVerificationEmail(user: "VXNlcjox", email: "alice@example.com") {
+				alwaysNil
+			}
+		}
+	`
+	sent = 2
+	RunTest(t, &Test{
+		Schema:         mustParseGraphQLSchema(t),
+		Query:          query,
+		ExpectedResult: `{"resendVerificationEmail": {"alwaysNil": null}}`,
+	})
+
+	sent = 3
+	RunTest(t, &Test{
+		Schema:         mustParseGraphQLSchema(t),
+		Query:          query,
+		ExpectedResult: "null",
+		ExpectedErrors: []*gqlerrors.QueryError{
+			{
+				Message:       "At most 3 verification emails can be sent per day, try again later",
+				Path:          []interface{}{"resendVerificationEmail"},
+				ResolverError: errors.New("At most 3 verification emails can be sent per day, try again later"),
+			},
+		},
+	})
+}
+
+func TestResendUserEmailVerificationEmailDelivery(t *testing.T) {
+	resetMocks()
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id, SiteAdmin: true}, nil
+	}
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: true}, nil
+	}
+	database.Mocks.UserEmails.GetLatestVerificationSentEmail = func(context.Context, string) (*database.UserEmail, error) {
+		return nil, nil
+	}
+	database.Mocks.UserEmails.Get = func(id int32, email string) (string, bool, error) {
+		return email, false, nil
+	}
+	database.Mocks.UserEmails.SetLastVerification = func(context.Context, int32, string, string) error {
+		return nil
+	}
+	txemail.MockSend = func(ctx context.Context, msg txemail.Message) error {
+		return nil
+	}
+	database.Mocks.EmailDeliveries.Create = func(context.Context, *database.EmailDelivery) error {
+		return nil
+	}
+	defer func() { txemail.MockSend = nil }()
+
+	// No email provider is configured, so the verification email cannot be delivered.
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+
+	RunTests(t, []*Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				mutation {
+					resendVerificationEmail(user: "VXNlcjox", email: "alice@example.com") {
+						emailDelivery {
+							deliverable
+							reason
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"resendVerificationEmail": {
+						"emailDelivery": {
+							"deliverable": false,
+							"reason": "no email provider configured (in email.smtp or email.provider)"
+						}
+					}
+				}
+			`,
+		},
+	})
+}
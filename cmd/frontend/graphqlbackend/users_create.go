@@ -51,6 +51,33 @@ func (r *schemaResolver) CreateUser(ctx context.Context, args *struct {
 	return &createUserResult{db: r.db, user: user}, nil
 }
 
+func (r *schemaResolver) CreateServiceAccount(ctx context.Context, args *struct {
+	Username    string
+	DisplayName *string
+}) (*UserResolver, error) {
+	// 🚨 SECURITY: Only site admins can create service accounts.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	var displayName string
+	if args.DisplayName != nil {
+		displayName = *args.DisplayName
+	}
+
+	// Service accounts have no password, so nobody can sign in as them. They use the API with
+	// access tokens that site admins create.
+	user, err := database.Users(r.db).Create(ctx, database.NewUser{
+		Username:    args.Username,
+		DisplayName: displayName,
+		Tags:        []string{database.TagServiceAccount},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewUserResolver(r.db, user), nil
+}
+
 // createUserResult is the result of Mutation.createUser.
 //
 // 🚨 SECURITY: Only site admins should be able to instantiate this value.
@@ -50,3 +50,40 @@ func TestCreateUser(t *testing.T) {
 		t.Fatal("!calledGrantPendingPermissions")
 	}
 }
+
+func TestCreateServiceAccount(t *testing.T) {
+	resetMocks()
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	var created database.NewUser
+	database.Mocks.Users.Create = func(_ context.Context, info database.NewUser) (*types.User, error) {
+		created = info
+		return &types.User{ID: 1, Username: info.Username, Tags: info.Tags}, nil
+	}
+
+	RunTests(t, []*Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				mutation {
+					createServiceAccount(username: "ci-bot") {
+						id
+						serviceAccount
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"createServiceAccount": {
+						"id": "VXNlcjox",
+						"serviceAccount": true
+					}
+				}
+			`,
+		},
+	})
+	if created.Password != "" || created.Email != "" {
+		t.Fatalf("want service account without password or email, got %+v", created)
+	}
+}
@@ -61,7 +61,11 @@ func NewHandler(db dbutil.DB) http.Handler {
 	r.Get(router.SignOut).Handler(trace.Route(http.HandlerFunc(serveSignOutHandler(db))))
 	r.Get(router.ResetPasswordInit).Handler(trace.Route(http.HandlerFunc(userpasswd.HandleResetPasswordInit(db))))
 	r.Get(router.ResetPasswordCode).Handler(trace.Route(http.HandlerFunc(userpasswd.HandleResetPasswordCode(db))))
+	r.Get(router.RecoverAccountInit).Handler(trace.Route(http.HandlerFunc(userpasswd.HandleRecoverAccountInit(db))))
 	r.Get(router.VerifyEmail).Handler(trace.Route(http.HandlerFunc(serveVerifyEmail(db))))
+	r.Get(router.UnsubscribeVerificationReminders).Handler(trace.Route(http.HandlerFunc(serveUnsubscribeVerificationReminders(db))))
+	r.Get(router.ConfirmEmailChange).Handler(trace.Route(http.HandlerFunc(serveConfirmEmailChange(db))))
+	r.Get(router.VerifyEmailSignIn).Handler(trace.Route(http.HandlerFunc(serveVerifyEmailSignIn(db))))
 
 	r.Get(router.CheckUsernameTaken).Handler(trace.Route(http.HandlerFunc(userpasswd.HandleCheckUsernameTaken(db))))
 
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// serveConfirmEmailChange handles the link in the email that is sent to the new address when a
+// user requests to change their primary email address.
+func serveConfirmEmailChange(db dbutil.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !actor.FromContext(ctx).IsAuthenticated() {
+			q := make(url.Values)
+			q.Set("returnTo", r.URL.String())
+			http.Redirect(w, r, "/sign-in?"+q.Encode(), http.StatusFound)
+			return
+		}
+		// 🚨 SECURITY: The change must be confirmed by the user who requested it.
+		usr, err := database.Users(db).GetByCurrentAuthUser(ctx)
+		if err != nil {
+			httpLogAndError(w, "Could not get current user", http.StatusUnauthorized)
+			return
+		}
+
+		email, err := backend.UserEmails.ConfirmChange(ctx, db, usr.ID, r.URL.Query().Get("token"))
+		if err != nil {
+			if errors.Is(err, backend.ErrInvalidEmailChangeToken) {
+				http.Error(w, "Could not change email address. "+err.Error()+".", http.StatusUnauthorized)
+				return
+			}
+			httpLogAndError(w, "Could not change email address", http.StatusInternalServerError, "userID", usr.ID, "error", err)
+			return
+		}
+
+		if err := backend.UserEmails.RevokeSessionsOnPrimaryEmailChange(ctx, db, usr.ID); err != nil {
+			httpLogAndError(w, "Could not revoke sessions", http.StatusInternalServerError, "userID", usr.ID, "error", err)
+			return
+		}
+
+		if conf.CanNotifyOfAccountChanges() {
+			if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, usr.ID, "changed primary email"); err != nil {
+				log15.Warn("Failed to notify user of primary address change", "error", err)
+			}
+		}
+
+		if err := database.GlobalAuthz.GrantPendingPermissions(ctx, &database.GrantPendingPermissionsArgs{
+			UserID: usr.ID,
+			Perm:   authz.Read,
+			Type:   authz.PermRepos,
+		}); err != nil {
+			log15.Error("Failed to grant user pending permissions", "userID", usr.ID, "email", email, "error", err)
+		}
+
+		http.Redirect(w, r, "/user/settings/emails", http.StatusFound)
+	}
+}
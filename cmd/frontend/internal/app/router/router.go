@@ -30,8 +30,13 @@ const (
 	VerifyEmail        = "verify-email"
 	ResetPasswordInit  = "reset-password.init"
 	ResetPasswordCode  = "reset-password.code"
+	RecoverAccountInit = "recover-account.init"
 	CheckUsernameTaken = "check-username-taken"
 
+	UnsubscribeVerificationReminders = "unsubscribe-verification-reminders"
+	ConfirmEmailChange               = "confirm-email-change"
+	VerifyEmailSignIn                = "verify-email-sign-in"
+
 	RegistryExtensionBundle = "registry.extension.bundle"
 
 	UsageStatsDownload = "usage-stats.download"
@@ -75,10 +80,14 @@ func newRouter() *mux.Router {
 	base.Path("/-/welcome").Methods("GET").Name(Welcome)
 	base.Path("/-/site-init").Methods("POST").Name(SiteInit)
 	base.Path("/-/verify-email").Methods("GET").Name(VerifyEmail)
+	base.Path("/-/unsubscribe-verification-reminders").Methods("GET").Name(UnsubscribeVerificationReminders)
+	base.Path("/-/confirm-email-change").Methods("GET").Name(ConfirmEmailChange)
+	base.Path("/-/verify-email-sign-in").Methods("GET").Name(VerifyEmailSignIn)
 	base.Path("/-/sign-in").Methods("POST").Name(SignIn)
 	base.Path("/-/sign-out").Methods("GET").Name(SignOut)
 	base.Path("/-/reset-password-init").Methods("POST").Name(ResetPasswordInit)
 	base.Path("/-/reset-password-code").Methods("POST").Name(ResetPasswordCode)
+	base.Path("/-/recover-account-init").Methods("POST").Name(RecoverAccountInit)
 
 	base.Path("/-/check-username-taken/{username}").Methods("GET").Name(CheckUsernameTaken)
 
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// serveUnsubscribeVerificationReminders handles the link in email verification reminders that
+// opts the email address out of further reminders. It doesn't require the user to be signed in,
+// so it must only act on a correct token.
+func serveUnsubscribeVerificationReminders(db dbutil.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		userID, err := strconv.ParseInt(q.Get("user"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid user ID.", http.StatusBadRequest)
+			return
+		}
+		email := q.Get("email")
+
+		// 🚨 SECURITY: The token is the only proof that the request comes from the owner of the
+		// email address.
+		ok, err := database.UserEmails(db).OptOutOfVerificationReminders(r.Context(), int32(userID), email, q.Get("token"))
+		if err != nil {
+			httpLogAndError(w, "Could not unsubscribe from email verification reminders.", http.StatusInternalServerError, "userID", userID, "error", err)
+			return
+		}
+		if !ok {
+			http.Error(w, "Could not unsubscribe from email verification reminders. The link is invalid.", http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprintf(w, "You will no longer receive reminders to verify %s.\n", email)
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
+)
+
+func TestServeUnsubscribeVerificationReminders(t *testing.T) {
+	db := new(dbtesting.MockDB)
+
+	database.Mocks.UserEmails.OptOutOfVerificationReminders = func(ctx context.Context, userID int32, email, token string) (bool, error) {
+		return userID == 1 && email == "alice@example.com" && token == "t0k3n", nil
+	}
+	defer func() { database.Mocks.UserEmails = database.MockUserEmails{} }()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{
+			name:       "valid token",
+			query:      "user=1&email=alice%40example.com&token=t0k3n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong token",
+			query:      "user=1&email=alice%40example.com&token=guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong user",
+			query:      "user=2&email=alice%40example.com&token=t0k3n",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid user",
+			query:      "user=alice&email=alice%40example.com&token=t0k3n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/-/unsubscribe-verification-reminders?"+test.query, nil)
+			resp := httptest.NewRecorder()
+
+			serveUnsubscribeVerificationReminders(db)(resp, req)
+
+			if resp.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d: %s", resp.Code, test.wantStatus, resp.Body.String())
+			}
+		})
+	}
+}
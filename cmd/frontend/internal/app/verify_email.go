@@ -2,15 +2,20 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/inconshreveable/log15"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/session"
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/cookie"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
@@ -53,27 +58,93 @@ func serveVerifyEmail(db dbutil.DB) func(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Could not verify user email. Email verification code did not match.", http.StatusUnauthorized)
 			return
 		}
-		// Set the verified email as primary if user has no primary email
-		_, _, err = database.UserEmails(db).GetPrimaryEmail(ctx, usr.ID)
+
+		if err := afterEmailVerified(ctx, db, r, usr.ID, email); err != nil {
+			httpLogAndError(w, "Could not set primary email.", http.StatusInternalServerError, "userID", usr.ID, "email", email, "error", err)
+			return
+		}
+
+		http.Redirect(w, r, "/user/settings/emails", http.StatusFound)
+	}
+}
+
+// serveVerifyEmailSignIn handles the link in verification emails if email.verificationLinkSignIn
+// is enabled: it verifies the email address and signs the user in, unless they are a site admin,
+// enabled two-factor authentication or the link is too old.
+func serveVerifyEmailSignIn(db dbutil.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email, canSignIn, err := backend.UserEmails.VerifyWithToken(ctx, db, r.URL.Query().Get("token"))
 		if err != nil {
-			if err := database.UserEmails(db).SetPrimaryEmail(ctx, usr.ID, email); err != nil {
-				httpLogAndError(w, "Could not set primary email.", http.StatusInternalServerError, "userID", usr.ID, "email", email, "error", err)
+			if errors.Is(err, backend.ErrInvalidVerificationToken) {
+				http.Error(w, "Could not verify user email. "+err.Error()+".", http.StatusUnauthorized)
 				return
 			}
+			httpLogAndError(w, "Could not verify user email", http.StatusInternalServerError, "error", err)
+			return
 		}
 
-		logEmailVerified(ctx, db, r, actr.UID)
+		if err := afterEmailVerified(ctx, db, r, userID, email); err != nil {
+			httpLogAndError(w, "Could not set primary email.", http.StatusInternalServerError, "userID", userID, "email", email, "error", err)
+			return
+		}
 
-		if err = database.GlobalAuthz.GrantPendingPermissions(ctx, &database.GrantPendingPermissionsArgs{
-			UserID: usr.ID,
-			Perm:   authz.Read,
-			Type:   authz.PermRepos,
-		}); err != nil {
-			log15.Error("Failed to grant user pending permissions", "userID", usr.ID, "error", err)
+		const returnTo = "/user/settings/emails"
+		if actor.FromContext(ctx).IsAuthenticated() {
+			http.Redirect(w, r, returnTo, http.StatusFound)
+			return
 		}
 
-		http.Redirect(w, r, "/user/settings/emails", http.StatusFound)
+		usr, err := database.Users(db).GetByID(ctx, userID)
+		if err != nil {
+			httpLogAndError(w, "Could not get user", http.StatusInternalServerError, "userID", userID, "error", err)
+			return
+		}
+		// 🚨 SECURITY: The link is a weaker credential than a password or SSO, so it never signs in
+		// site admins, and only signs in users if the site allows it. It never signs in users who
+		// enabled two-factor authentication either, since that would skip the second factor. Fail
+		// closed if we can't determine whether they did.
+		twoFactor, err := backend.UserTOTP.Enabled(ctx, db, usr.ID)
+		if err != nil {
+			log15.Error("Unable to check two-factor authentication.", "userID", usr.ID, "err", err)
+			twoFactor = true
+		}
+		if !canSignIn || usr.SiteAdmin || twoFactor || !conf.Get().EmailVerificationLinkSignIn {
+			q := make(url.Values)
+			q.Set("returnTo", returnTo)
+			http.Redirect(w, r, "/sign-in?"+q.Encode(), http.StatusFound)
+			return
+		}
+
+		if err := session.SetActor(w, r, &actor.Actor{UID: usr.ID}, 0, usr.CreatedAt); err != nil {
+			httpLogAndError(w, "Could not create new user session", http.StatusInternalServerError, "userID", usr.ID, "error", err)
+			return
+		}
+		logSignedInWithVerificationLink(ctx, db, r, usr.ID)
+
+		http.Redirect(w, r, returnTo, http.StatusFound)
+	}
+}
+
+// afterEmailVerified sets the verified email as primary if the user has no primary email, logs
+// the verification and grants the user pending permissions.
+func afterEmailVerified(ctx context.Context, db dbutil.DB, r *http.Request, userID int32, email string) error {
+	if _, _, err := database.UserEmails(db).GetPrimaryEmail(ctx, userID); err != nil {
+		if err := database.UserEmails(db).SetPrimaryEmail(ctx, userID, email); err != nil {
+			return err
+		}
+	}
+
+	logEmailVerified(ctx, db, r, userID)
+
+	if err := database.GlobalAuthz.GrantPendingPermissions(ctx, &database.GrantPendingPermissionsArgs{
+		UserID: userID,
+		Perm:   authz.Read,
+		Type:   authz.PermRepos,
+	}); err != nil {
+		log15.Error("Failed to grant user pending permissions", "userID", userID, "error", err)
 	}
+	return nil
 }
 
 func logEmailVerified(ctx context.Context, db dbutil.DB, r *http.Request, userID int32) {
@@ -90,6 +161,20 @@ func logEmailVerified(ctx context.Context, db dbutil.DB, r *http.Request, userID
 	database.SecurityEventLogs(db).LogEvent(ctx, event)
 }
 
+func logSignedInWithVerificationLink(ctx context.Context, db dbutil.DB, r *http.Request, userID int32) {
+	event := &database.SecurityEvent{
+		Name:      database.SecurityEventNameSignInSucceeded,
+		URL:       r.URL.Path,
+		UserID:    uint32(userID),
+		Argument:  json.RawMessage(`{"method":"verification-link"}`),
+		Source:    "BACKEND",
+		Timestamp: time.Now(),
+	}
+	event.AnonymousUserID, _ = cookie.AnonymousUID(r)
+
+	database.SecurityEventLogs(db).LogEvent(ctx, event)
+}
+
 func httpLogAndError(w http.ResponseWriter, msg string, code int, errArgs ...interface{}) {
 	log15.Error(msg, errArgs...)
 	http.Error(w, msg, code)
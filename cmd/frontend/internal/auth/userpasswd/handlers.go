@@ -22,6 +22,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/cookie"
 	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbconn"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/featureflag"
@@ -35,6 +36,7 @@ type credentials struct {
 	Password        string `json:"password"`
 	AnonymousUserID string `json:"anonymousUserId"`
 	FirstSourceURL  string `json:"firstSourceUrl"`
+	TOTPCode        string `json:"totpCode"` // only for sign-in
 }
 
 // HandleSignUp handles submission of the user signup form.
@@ -102,6 +104,29 @@ func handleSignUp(w http.ResponseWriter, r *http.Request, failIfNewUserIsNotInit
 		return
 	}
 
+	// The initial site admin is exempt from the email role address and domain policies, so that a
+	// misconfigured policy can't prevent initializing the site.
+	if !failIfNewUserIsNotInitialSiteAdmin {
+		if err := backend.CheckRoleEmailAddress(creds.Email); err != nil {
+			if backend.IsRoleEmailAddressError(err) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log15.Error("Error checking email role address policy", "email", creds.Email, "error", err)
+			http.Error(w, defaultErrorMessage, http.StatusInternalServerError)
+			return
+		}
+		if err := backend.CheckEmailDomainPolicy(r.Context(), dbconn.Global, creds.Email); err != nil {
+			if backend.IsEmailDomainPolicyError(err) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log15.Error("Error checking email domain policy", "email", creds.Email, "error", err)
+			http.Error(w, defaultErrorMessage, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Create the user.
 	//
 	// We don't need to check the builtin auth provider's allowSignup because we assume the caller
@@ -183,7 +208,7 @@ func handleSignUp(w http.ResponseWriter, r *http.Request, failIfNewUserIsNotInit
 	}
 
 	if conf.EmailVerificationRequired() && !newUserData.EmailIsVerified {
-		if err := backend.SendUserEmailVerificationEmail(r.Context(), usr.Username, creds.Email, newUserData.EmailVerificationCode); err != nil {
+		if err := backend.SendUserEmailVerificationEmail(r.Context(), usr.ID, usr.Username, creds.Email, newUserData.EmailVerificationCode); err != nil {
 			log15.Error("failed to send email verification (continuing, user's email will be unverified)", "email", creds.Email, "err", err)
 		} else if err = database.GlobalUserEmails.SetLastVerification(r.Context(), usr.ID, creds.Email, newUserData.EmailVerificationCode); err != nil {
 			log15.Error("failed to set email last verification sent at (user's email is verified)", "email", creds.Email, "err", err)
@@ -264,6 +289,12 @@ func HandleSignIn(db dbutil.DB) func(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// 🚨 SECURITY: Users who enabled two-factor authentication must also provide a code from
+		// their authenticator app or a recovery code.
+		if ok := checkTwoFactor(w, r, db, usr.ID, creds.TOTPCode); !ok {
+			return
+		}
+
 		actor.UID = usr.ID
 
 		// Write the session cookie
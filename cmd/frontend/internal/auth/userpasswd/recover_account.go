@@ -0,0 +1,130 @@
+package userpasswd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// HandleRecoverAccountInit initiates the builtin-auth account recovery flow for users who lost
+// access to their primary email address, by sending a password-reset email to the recovery email
+// address of the user.
+func HandleRecoverAccountInit(db dbutil.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if handleEnabledCheck(w) {
+			return
+		}
+		if handleNotAuthenticatedCheck(w, r) {
+			return
+		}
+		if !conf.CanSendEmail() {
+			httpLogAndError(w, "Unable to recover account because email sending is not configured on this site", http.StatusNotFound)
+			return
+		}
+
+		ctx := r.Context()
+		var formData struct {
+			Username string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&formData); err != nil {
+			httpLogAndError(w, "Could not decode account recovery request body", http.StatusBadRequest, "err", err)
+			return
+		}
+
+		if formData.Username == "" {
+			httpLogAndError(w, "No username specified in account recovery request", http.StatusBadRequest)
+			return
+		}
+
+		usr, err := sendAccountRecoveryEmail(ctx, db, formData.Username)
+		if err == database.ErrPasswordResetRateLimit {
+			httpLogAndError(w, "Too many password reset requests. Try again in a few minutes.", http.StatusTooManyRequests, "err", err)
+			return
+		} else if err != nil {
+			httpLogAndError(w, "Could not recover account", http.StatusInternalServerError, "err", err)
+			return
+		}
+		if usr != nil {
+			database.LogPasswordEvent(ctx, db, r, database.SecurityEventNameAccountRecoveryRequested, usr.ID)
+		}
+	}
+}
+
+// sendAccountRecoveryEmail sends a password-reset email to the recovery email address of the user
+// with the given username. It returns the user if an email was sent.
+//
+// 🚨 SECURITY: The password reset code is only ever sent to the recovery email address, never to
+// the primary one, because recovery is for users who lost access to their primary email address.
+// Nothing is sent and no error is returned if the user doesn't exist or has no recovery email
+// address, so as to not leak either.
+func sendAccountRecoveryEmail(ctx context.Context, db dbutil.DB, username string) (*types.User, error) {
+	usr, err := database.Users(db).GetByUsername(ctx, username)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	email, err := database.UserEmails(db).GetRecoveryEmail(ctx, usr.ID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	resetURL, err := backend.MakePasswordResetURL(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := txemail.Send(ctx, txemail.Message{
+		To:       []string{email},
+		Template: recoverAccountEmailTemplates,
+		Data: struct {
+			Username string
+			URL      string
+			Host     string
+		}{
+			Username: usr.Username,
+			URL:      globals.ExternalURL().ResolveReference(resetURL).String(),
+			Host:     globals.ExternalURL().Host,
+		},
+	}); err != nil {
+		return nil, err
+	}
+	return usr, nil
+}
+
+var recoverAccountEmailTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `Recover your Sourcegraph account ({{.Host}})`,
+	Text: `
+Somebody (likely you) requested to recover the account {{.Username}} on Sourcegraph ({{.Host}}). You are receiving this email because it is the recovery email address of the account.
+
+To reset the password for {{.Username}} on Sourcegraph, follow this link:
+
+  {{.URL}}
+
+If you didn't request this, you can ignore this email.
+`,
+	HTML: `
+<p>
+  Somebody (likely you) requested to recover the account <strong>{{.Username}}</strong>
+  on Sourcegraph ({{.Host}}). You are receiving this email because it is the recovery
+  email address of the account.
+</p>
+
+<p><strong><a href="{{.URL}}">Reset password for {{.Username}}</a></strong></p>
+
+<p>If you didn't request this, you can ignore this email.</p>
+`,
+})
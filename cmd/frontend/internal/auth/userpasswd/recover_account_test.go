@@ -0,0 +1,87 @@
+package userpasswd
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestSendAccountRecoveryEmail(t *testing.T) {
+	var sent []txemail.Message
+	txemail.MockSend = func(ctx context.Context, message txemail.Message) error {
+		sent = append(sent, message)
+		return nil
+	}
+	backend.MockMakePasswordResetURL = func(context.Context, int32) (*url.URL, error) {
+		return &url.URL{Path: "/password-reset", RawQuery: "code=foo&userID=1"}, nil
+	}
+	database.Mocks.Users.GetByUsername = func(ctx context.Context, username string) (*types.User, error) {
+		switch username {
+		case "alice":
+			return &types.User{ID: 1, Username: "alice"}, nil
+		case "bob":
+			return &types.User{ID: 2, Username: "bob"}, nil
+		}
+		return nil, database.MockUserNotFoundErr
+	}
+	database.Mocks.UserEmails.GetRecoveryEmail = func(ctx context.Context, userID int32) (string, error) {
+		if userID == 1 {
+			return "alice@recovery.example.com", nil
+		}
+		return "", database.MockUserEmailNotFoundErr
+	}
+	defer func() {
+		txemail.MockSend = nil
+		backend.MockMakePasswordResetURL = nil
+		database.Mocks.Users = database.MockUsers{}
+		database.Mocks.UserEmails = database.MockUserEmails{}
+	}()
+
+	for _, test := range []struct {
+		username string
+		wantSent bool
+	}{
+		{username: "alice", wantSent: true},
+		// Nothing is sent, and no error returned, for users without a recovery email and for
+		// unknown users.
+		{username: "bob"},
+		{username: "unknown"},
+	} {
+		t.Run(test.username, func(t *testing.T) {
+			sent = nil
+			usr, err := sendAccountRecoveryEmail(context.Background(), nil, test.username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !test.wantSent {
+				if usr != nil || len(sent) != 0 {
+					t.Fatalf("got user %v and %d emails, want none", usr, len(sent))
+				}
+				return
+			}
+
+			if usr == nil || usr.Username != test.username {
+				t.Fatalf("got user %v, want %q", usr, test.username)
+			}
+			if len(sent) != 1 {
+				t.Fatalf("got %d emails, want 1", len(sent))
+			}
+			if to := sent[0].To; len(to) != 1 || to[0] != "alice@recovery.example.com" {
+				t.Fatalf("got recipients %v, want only the recovery email", to)
+			}
+			data := sent[0].Data.(struct {
+				Username string
+				URL      string
+				Host     string
+			})
+			if want := "http://example.com/password-reset?code=foo&userID=1"; data.URL != want {
+				t.Fatalf("got URL %q, want %q", data.URL, want)
+			}
+		})
+	}
+}
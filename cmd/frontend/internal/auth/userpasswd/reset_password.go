@@ -107,9 +107,9 @@ To reset the password for {{.Username}} on Sourcegraph, follow this link:
 
 // HandleSetPasswordEmail sends the password reset email directly to the user for users created by site admins.
 func HandleSetPasswordEmail(ctx context.Context, db dbutil.DB, id int32) (string, error) {
-	e, _, err := database.UserEmails(db).GetPrimaryEmail(ctx, id)
+	e, _, err := backend.UserEmails.ContactEmail(ctx, db, id)
 	if err != nil {
-		return "", errors.Wrap(err, "get user primary email")
+		return "", errors.Wrap(err, "get user contact email")
 	}
 
 	usr, err := database.Users(db).GetByID(ctx, id)
@@ -201,9 +201,9 @@ func HandleResetPasswordCode(db dbutil.DB) func(w http.ResponseWriter, r *http.R
 
 		database.LogPasswordEvent(ctx, db, r, database.SecurityEventNamePasswordChanged, params.UserID)
 
-		if conf.CanSendEmail() {
-			if err := backend.UserEmails.SendUserEmailOnFieldUpdate(ctx, params.UserID, "reset the password"); err != nil {
-				log15.Warn("Failed to send email to inform user of password reset", "error", err)
+		if conf.CanNotifyOfAccountChanges() {
+			if err := backend.UserEmails.NotifyUserOnFieldUpdate(ctx, params.UserID, "reset the password"); err != nil {
+				log15.Warn("Failed to notify user of password reset", "error", err)
 			}
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -25,7 +26,7 @@ func TestHandleSetPasswordEmail(t *testing.T) {
 	}
 	defer func() { txemail.MockSend = nil }()
 	defer func() { backend.MockMakePasswordResetURL = nil }()
-	defer func() { database.Mocks.UserEmails.GetPrimaryEmail = nil }()
+	defer func() { database.Mocks.UserEmails.ListByUser = nil }()
 	defer func() { database.Mocks.Users.GetByID = nil }()
 
 	backend.MockMakePasswordResetURL = func(context.Context, int32) (*url.URL, error) {
@@ -35,8 +36,9 @@ func TestHandleSetPasswordEmail(t *testing.T) {
 		return &url.URL{Path: "/password-reset", RawQuery: query.Encode()}, nil
 	}
 
-	database.Mocks.UserEmails.GetPrimaryEmail = func(context.Context, int32) (string, bool, error) {
-		return "a@example.com", true, nil
+	database.Mocks.UserEmails.ListByUser = func(context.Context, database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		now := time.Now()
+		return []*database.UserEmail{{Email: "a@example.com", VerifiedAt: &now, Primary: true}}, nil
 	}
 
 	database.Mocks.Users.GetByID = func(context.Context, int32) (*types.User, error) {
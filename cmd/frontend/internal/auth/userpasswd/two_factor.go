@@ -0,0 +1,93 @@
+package userpasswd
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// twoFactorHeader is set on sign-in responses when the user must also provide a two-factor
+// authentication code.
+const twoFactorHeader = "X-Sourcegraph-Two-Factor"
+
+// checkTwoFactor reports whether the user may sign in given the two-factor authentication code
+// (which is empty if the client didn't send one). If not, it writes the error response.
+func checkTwoFactor(w http.ResponseWriter, r *http.Request, db dbutil.DB, userID int32, code string) bool {
+	ctx := r.Context()
+	enabled, err := backend.UserTOTP.Enabled(ctx, db, userID)
+	if err != nil {
+		httpLogAndError(w, "Could not check two-factor authentication", http.StatusInternalServerError, "err", err)
+		return false
+	}
+	if !enabled {
+		return true
+	}
+
+	if code == "" {
+		w.Header().Set(twoFactorHeader, "required")
+		http.Error(w, "Two-factor authentication code required", http.StatusUnauthorized)
+		return false
+	}
+	ok, err := backend.UserTOTP.Verify(ctx, db, userID, code)
+	var rateLimitErr *backend.TOTPRateLimitError
+	if errors.As(err, &rateLimitErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		http.Error(w, "Too many failed two-factor authentication attempts. Try again later.", http.StatusTooManyRequests)
+		return false
+	}
+	if err != nil {
+		httpLogAndError(w, "Could not verify two-factor authentication code", http.StatusInternalServerError, "err", err)
+		return false
+	}
+	if !ok {
+		w.Header().Set(twoFactorHeader, "required")
+		httpLogAndError(w, "Authentication failed", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// TwoFactorMiddleware marks requests of site admins who are required to enable two-factor
+// authentication (see auth.twoFactor) but haven't, so that site admin actions are denied until
+// they do. It must run after the auth middlewares.
+func TwoFactorMiddleware(db dbutil.DB, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		cfg := conf.Get().AuthTwoFactor
+		a := actor.FromContext(ctx)
+		if cfg == nil || !cfg.RequireForSiteAdmins || !a.IsAuthenticated() || a.Internal {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := database.Users(db).GetByID(ctx, a.UID)
+		if err != nil {
+			log15.Error("Unable to get user for two-factor authentication check.", "userID", a.UID, "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !backend.TwoFactorRequired(user) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// 🚨 SECURITY: Fail closed if we can't determine whether the user enabled two-factor
+		// authentication.
+		if enabled, err := backend.UserTOTP.Enabled(ctx, db, user.ID); err != nil || !enabled {
+			if err != nil {
+				log15.Error("Unable to check two-factor authentication.", "userID", user.ID, "err", err)
+			}
+			r = r.WithContext(backend.WithTwoFactorEnrollmentPending(ctx))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,22 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// CheckPendingOrgDomains periodically checks whether organizations published the DNS TXT records
+// that verify their pending domains.
+func CheckPendingOrgDomains(ctx context.Context, db dbutil.DB) {
+	for {
+		if err := backend.CheckPendingOrgDomains(ctx, db); err != nil {
+			log15.Error("checking pending organization domains", "error", err)
+		}
+		time.Sleep(10 * time.Minute)
+	}
+}
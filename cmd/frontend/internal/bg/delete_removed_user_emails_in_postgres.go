@@ -0,0 +1,22 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// DeleteRemovedUserEmailsInPostgres deletes the user emails that were removed longer ago than they
+// can be restored.
+func DeleteRemovedUserEmailsInPostgres(ctx context.Context, db dbutil.DB) {
+	for {
+		if _, err := database.UserEmails(db).PurgeRemoved(ctx); err != nil {
+			log15.Error("deleting removed rows from user_emails table", "error", err)
+		}
+		time.Sleep(time.Hour)
+	}
+}
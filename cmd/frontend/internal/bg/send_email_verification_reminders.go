@@ -0,0 +1,23 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// SendEmailVerificationReminders periodically reminds users to verify their email addresses, as
+// configured in email.verificationReminders. Reminders are claimed in the database before they
+// are sent, so multiple frontends never send the same reminder.
+func SendEmailVerificationReminders(ctx context.Context, db dbutil.DB) {
+	for {
+		if err := backend.UserEmails.SendVerificationReminders(ctx, db); err != nil {
+			log15.Error("sending email verification reminders", "error", err)
+		}
+		time.Sleep(10 * time.Minute)
+	}
+}
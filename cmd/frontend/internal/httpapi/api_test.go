@@ -28,6 +28,7 @@ func newTest() *httptestutil.Client {
 		enterpriseServices.GitHubWebhook,
 		enterpriseServices.GitLabWebhook,
 		enterpriseServices.BitbucketServerWebhook,
+		enterpriseServices.BitbucketCloudWebhook,
 		enterpriseServices.NewCodeIntelUploadHandler,
 		rateLimiter,
 	))
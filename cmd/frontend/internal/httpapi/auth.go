@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/inconshreveable/log15"
 
@@ -20,6 +21,13 @@ func AccessTokenAuthMiddleware(db dbutil.DB, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
 
+		// SCIM requests are authenticated with their own bearer token by the SCIM handler, which
+		// must not be logged as an unrecognized Authorization header below.
+		if strings.HasPrefix(r.URL.Path, "/.api/scim/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		var sudoUser string
 		token := r.URL.Query().Get("token")
 
@@ -70,11 +78,27 @@ func AccessTokenAuthMiddleware(db dbutil.DB, next http.Handler) http.Handler {
 				requiredScope = authz.ScopeSiteAdminSudo
 			}
 			subjectUserID, err := database.AccessTokens(db).Lookup(r.Context(), token, requiredScope)
+			var restrictedScopes []string
+			if err == database.ErrAccessTokenNotFound && sudoUser == "" {
+				// Tokens with fine-grained scopes instead of authz.ScopeUserAll are restricted to
+				// the APIs of their scopes.
+				var scopes []string
+				subjectUserID, scopes, err = database.AccessTokens(db).LookupWithScopes(r.Context(), token)
+				if err == nil {
+					if restrictedScopes = fineGrainedScopes(scopes); len(restrictedScopes) == 0 {
+						err = database.ErrAccessTokenNotFound
+					}
+				}
+			}
 			if err != nil {
 				log15.Error("Invalid access token.", "token", token, "err", err)
 				http.Error(w, "Invalid access token.", http.StatusUnauthorized)
 				return
 			}
+			if restrictedScopes != nil && !accessTokenScopesAllowRequest(r, restrictedScopes) {
+				http.Error(w, "The scopes of the access token don't allow this request.", http.StatusForbidden)
+				return
+			}
 
 			// Determine the actor's user ID.
 			var actorUserID int32
@@ -107,9 +131,40 @@ func AccessTokenAuthMiddleware(db dbutil.DB, next http.Handler) http.Handler {
 				log15.Debug("HTTP request used sudo token.", "requestURI", r.URL.RequestURI(), "tokenSubjectUserID", subjectUserID, "actorUserID", actorUserID, "actorUsername", user.Username)
 			}
 
-			r = r.WithContext(actor.WithActor(r.Context(), &actor.Actor{UID: actorUserID}))
+			ctx := actor.WithActor(r.Context(), &actor.Actor{UID: actorUserID})
+			if restrictedScopes != nil {
+				// 🚨 SECURITY: The GraphQL API and site admin checks enforce the scopes.
+				ctx = authz.WithAccessTokenScopes(ctx, restrictedScopes)
+			}
+			r = r.WithContext(ctx)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// fineGrainedScopes returns the fine-grained scopes (such as authz.ScopeReadSearch) among the
+// scopes of an access token.
+func fineGrainedScopes(scopes []string) []string {
+	var fineGrained []string
+	for _, scope := range scopes {
+		switch scope {
+		case authz.ScopeReadSearch, authz.ScopeWriteBatches, authz.ScopeAdmin:
+			fineGrained = append(fineGrained, scope)
+		}
+	}
+	return fineGrained
+}
+
+// accessTokenScopesAllowRequest reports whether an access token with the fine-grained scopes may
+// be used for the request. GraphQL requests and subscriptions are allowed here, because the
+// GraphQL handlers check the scopes of each operation.
+func accessTokenScopesAllowRequest(r *http.Request, scopes []string) bool {
+	switch r.URL.Path {
+	case "/.api/graphql", "/.api/graphql/ws":
+		return true
+	case "/.api/search/stream":
+		return authz.HasScope(scopes, authz.ScopeReadSearch)
+	}
+	return false
+}
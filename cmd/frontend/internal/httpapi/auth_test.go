@@ -283,3 +283,83 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 		}
 	})
 }
+
+// 🚨 SECURITY: This tests that tokens with fine-grained scopes can only be used for the APIs of
+// their scopes.
+func TestAccessTokenAuthMiddleware_FineGrainedScopes(t *testing.T) {
+	handler := AccessTokenAuthMiddleware(new(dbtesting.MockDB), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes, restricted := authz.AccessTokenScopesFromContext(r.Context())
+		fmt.Fprintf(w, "user %v, scopes %q (restricted %v)", actor.FromContext(r.Context()).UID, scopes, restricted)
+	}))
+	database.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		return 0, database.ErrAccessTokenNotFound
+	}
+	database.Mocks.AccessTokens.LookupWithScopes = func(tokenHexEncoded string) (subjectUserID int32, scopes []string, err error) {
+		return 123, []string{authz.ScopeReadSearch}, nil
+	}
+	defer func() { database.Mocks = database.MockStores{} }()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "stream search",
+			method:         "GET",
+			path:           "/.api/search/stream?q=x",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `user 123, scopes ["read:search"] (restricted true)`,
+		},
+		{
+			// The GraphQL handler checks the scopes of each operation.
+			name:           "GraphQL",
+			method:         "POST",
+			path:           "/.api/graphql",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `user 123, scopes ["read:search"] (restricted true)`,
+		},
+		{
+			// The WebSocket handler checks the scopes of each subscription.
+			name:           "GraphQL subscriptions",
+			method:         "GET",
+			path:           "/.api/graphql/ws",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `user 123, scopes ["read:search"] (restricted true)`,
+		},
+		{
+			name:           "other endpoint",
+			method:         "GET",
+			path:           "/.api/repos/github.com/foo/bar",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "The scopes of the access token don't allow this request.\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(test.method, test.path, nil)
+			req.Header.Set("Authorization", "token abcdef")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != test.wantStatusCode {
+				t.Errorf("got response status %d, want %d", rr.Code, test.wantStatusCode)
+			}
+			if got := rr.Body.String(); got != test.wantBody {
+				t.Errorf("got response body %q, want %q", got, test.wantBody)
+			}
+		})
+	}
+
+	t.Run("sudo", func(t *testing.T) {
+		// Fine-grained scopes never allow sudo.
+		req, _ := http.NewRequest("GET", "/.api/search/stream?q=x", nil)
+		req.Header.Set("Authorization", `token-sudo token="abcdef",user="alice"`)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if want := http.StatusUnauthorized; rr.Code != want {
+			t.Errorf("got response status %d, want %d", rr.Code, want)
+		}
+	})
+}
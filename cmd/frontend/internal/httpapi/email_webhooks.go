@@ -0,0 +1,263 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/mux"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+)
+
+// maxEmailWebhookBodySize is the maximum size of a request to the email webhooks endpoint.
+// SendGrid batches events, but a batch is far smaller than this.
+const maxEmailWebhookBodySize = 4 << 20
+
+// serveEmailWebhook receives the delivery, bounce and complaint notifications of the email
+// provider. The request is authenticated with the email.provider.webhookSecret site
+// configuration property, which must be passed in the secret query parameter.
+func serveEmailWebhook(db dbutil.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := conf.Get().EmailProvider
+		if cfg == nil || cfg.WebhookSecret == "" {
+			http.Error(w, "email webhooks are not enabled", http.StatusNotFound)
+			return
+		}
+
+		// 🚨 SECURITY: Use a constant-time comparison to avoid leaking the secret via timing attack.
+		if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(cfg.WebhookSecret)) != 1 {
+			http.Error(w, "invalid email webhook secret", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxEmailWebhookBodySize))
+		if err != nil {
+			http.Error(w, "reading request body", http.StatusBadRequest)
+			return
+		}
+
+		var events []backend.EmailDeliveryEvent
+		switch provider := mux.Vars(r)["provider"]; provider {
+		case "ses":
+			events, err = parseSNSNotification(r.Context(), body)
+		case "sendgrid":
+			events, err = parseSendGridEvents(body)
+		default:
+			http.Error(w, "unknown email provider "+provider, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, event := range events {
+			if err := backend.HandleEmailDeliveryEvent(r.Context(), db, event); err != nil {
+				// Fail the request so that the provider retries it later.
+				log15.Error("Failed to handle email delivery event", "status", event.Status, "error", err)
+				http.Error(w, "handling email delivery event", http.StatusInternalServerError)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// snsMessage is a message of Amazon SNS, which Amazon SES publishes its notifications to.
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// sesNotification is a bounce, complaint or delivery notification of Amazon SES. Notifications
+// set notificationType, and event publishing (with a configuration set) sets eventType instead.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Bounce           struct {
+		BounceType        string `json:"bounceType"`
+		BounceSubType     string `json:"bounceSubType"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplaintFeedbackType string `json:"complaintFeedbackType"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Delivery struct {
+		Recipients []string `json:"recipients"`
+	} `json:"delivery"`
+	Mail struct {
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		CommonHeaders struct {
+			MessageID string `json:"messageId"`
+		} `json:"commonHeaders"`
+	} `json:"mail"`
+}
+
+// parseSNSNotification parses an Amazon SNS message that contains an Amazon SES notification. SNS
+// subscription confirmations are confirmed. If raw message delivery is enabled for the
+// subscription, the body is the SES notification itself.
+func parseSNSNotification(ctx context.Context, body []byte) ([]backend.EmailDeliveryEvent, error) {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, errors.Wrap(err, "invalid SNS message")
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		return nil, confirmSNSSubscription(ctx, msg.SubscribeURL)
+	case "Notification":
+		body = []byte(msg.Message)
+	case "":
+		// Raw message delivery.
+	default:
+		return nil, nil
+	}
+
+	var n sesNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, errors.Wrap(err, "invalid SES notification")
+	}
+	return n.events(), nil
+}
+
+// confirmSNSSubscription confirms the subscription of the endpoint to an SNS topic.
+func confirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	// 🚨 SECURITY: Only request SNS URLs, so that the endpoint can't be used to make requests to
+	// arbitrary hosts.
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return errors.Errorf("invalid SNS subscribe URL %q", subscribeURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpcli.ExternalDoer.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "confirming SNS subscription")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("confirming SNS subscription: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// messageID returns the Message-ID header that we set on the email. SES may replace it in the
+// common headers with its own, so the original headers are preferred.
+func (n *sesNotification) messageID() string {
+	for _, h := range n.Mail.Headers {
+		if strings.EqualFold(h.Name, "Message-ID") {
+			return h.Value
+		}
+	}
+	return n.Mail.CommonHeaders.MessageID
+}
+
+func (n *sesNotification) events() []backend.EmailDeliveryEvent {
+	typ := n.NotificationType
+	if typ == "" {
+		typ = n.EventType
+	}
+	messageID := n.messageID()
+
+	var events []backend.EmailDeliveryEvent
+	switch typ {
+	case "Bounce":
+		status := database.EmailDeliverySoftBounced
+		if n.Bounce.BounceType == "Permanent" {
+			status = database.EmailDeliveryBounced
+		}
+		for _, r := range n.Bounce.BouncedRecipients {
+			reason := r.DiagnosticCode
+			if reason == "" {
+				reason = n.Bounce.BounceType + " bounce (" + n.Bounce.BounceSubType + ")"
+			}
+			events = append(events, backend.EmailDeliveryEvent{MessageID: messageID, Email: r.EmailAddress, Status: status, Reason: reason})
+		}
+	case "Complaint":
+		for _, r := range n.Complaint.ComplainedRecipients {
+			events = append(events, backend.EmailDeliveryEvent{MessageID: messageID, Email: r.EmailAddress, Status: database.EmailDeliveryComplained, Reason: n.Complaint.ComplaintFeedbackType})
+		}
+	case "Delivery":
+		for _, email := range n.Delivery.Recipients {
+			events = append(events, backend.EmailDeliveryEvent{MessageID: messageID, Email: email, Status: database.EmailDeliveryDelivered})
+		}
+	}
+	return events
+}
+
+// sendGridEvent is an event of the SendGrid Event Webhook.
+type sendGridEvent struct {
+	Email     string `json:"email"`
+	Event     string `json:"event"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Response  string `json:"response"`
+	MessageID string `json:"smtp-id"`
+}
+
+// parseSendGridEvents parses a batch of events of the SendGrid Event Webhook. Events that aren't
+// about the delivery of an email (such as opens and clicks) are ignored.
+func parseSendGridEvents(body []byte) ([]backend.EmailDeliveryEvent, error) {
+	var sgEvents []sendGridEvent
+	if err := json.Unmarshal(body, &sgEvents); err != nil {
+		return nil, errors.Wrap(err, "invalid SendGrid events")
+	}
+
+	var events []backend.EmailDeliveryEvent
+	for _, e := range sgEvents {
+		event := backend.EmailDeliveryEvent{MessageID: e.MessageID, Email: e.Email, Reason: e.Reason}
+		switch e.Event {
+		case "delivered":
+			event.Status = database.EmailDeliveryDelivered
+			event.Reason = ""
+		case "bounce":
+			// Blocked emails were rejected for a reason other than an invalid address, such as
+			// the content or the reputation of the sender.
+			event.Status = database.EmailDeliveryBounced
+			if e.Type == "blocked" {
+				event.Status = database.EmailDeliverySoftBounced
+			}
+		case "dropped":
+			// Only emails dropped because of the address itself mean that it is bouncing.
+			event.Status = database.EmailDeliverySoftBounced
+			switch e.Reason {
+			case "Bounced Address", "Spam Reporting Address", "Invalid":
+				event.Status = database.EmailDeliveryBounced
+			}
+		case "deferred":
+			event.Status = database.EmailDeliverySoftBounced
+			event.Reason = e.Response
+		case "spamreport":
+			event.Status = database.EmailDeliveryComplained
+			event.Reason = "spam report"
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
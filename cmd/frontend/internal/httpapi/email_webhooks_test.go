@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestEmailWebhook(t *testing.T) {
+	c := newTest()
+	type update struct{ messageID, email, status, reason string }
+	var updates []update
+	var bounced []string
+	database.Mocks.EmailDeliveries.UpdateStatus = func(_ context.Context, messageID, email, status, reason string) (bool, error) {
+		updates = append(updates, update{messageID, email, status, reason})
+		return true, nil
+	}
+	database.Mocks.UserEmails.MarkBounced = func(_ context.Context, email, reason string) (int, error) {
+		bounced = append(bounced, email)
+		return 1, nil
+	}
+	defer func() {
+		database.Mocks.EmailDeliveries = database.MockEmailDeliveries{}
+		database.Mocks.UserEmails = database.MockUserEmails{}
+	}()
+
+	const body = `[{"email":"alice@example.com","event":"bounce","type":"bounce","reason":"550 no such user","smtp-id":"<1@example.com>"}]`
+	for _, test := range []struct {
+		name       string
+		secret     string
+		path       string
+		wantStatus int
+	}{
+		{name: "not enabled", path: "/email-webhooks/sendgrid?secret=s3cr3t", wantStatus: http.StatusNotFound},
+		{name: "no secret", secret: "s3cr3t", path: "/email-webhooks/sendgrid", wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", secret: "s3cr3t", path: "/email-webhooks/sendgrid?secret=nope", wantStatus: http.StatusUnauthorized},
+		{name: "unknown provider", secret: "s3cr3t", path: "/email-webhooks/mailgun?secret=s3cr3t", wantStatus: http.StatusNotFound},
+		{name: "valid secret", secret: "s3cr3t", path: "/email-webhooks/sendgrid?secret=s3cr3t", wantStatus: http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			updates, bounced = nil, nil
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+				EmailProvider: &schema.EmailProvider{Type: "sendgrid", WebhookSecret: test.secret},
+			}})
+			defer conf.Mock(nil)
+
+			req, _ := http.NewRequest("POST", test.path, strings.NewReader(body))
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if resp.StatusCode != http.StatusOK {
+				if len(updates) != 0 || len(bounced) != 0 {
+					t.Fatalf("unexpected updates %v and bounces %v", updates, bounced)
+				}
+				return
+			}
+			if diff := cmp.Diff([]update{{"<1@example.com>", "alice@example.com", database.EmailDeliveryBounced, "550 no such user"}}, updates, cmp.AllowUnexported(update{})); diff != "" {
+				t.Fatalf("unexpected updates (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"alice@example.com"}, bounced); diff != "" {
+				t.Fatalf("unexpected bounces (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSNSNotification(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []backend.EmailDeliveryEvent
+	}{
+		{
+			name: "permanent bounce",
+			body: `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"bounce\":{\"bounceType\":\"Permanent\",\"bounceSubType\":\"General\",\"bouncedRecipients\":[{\"emailAddress\":\"alice@example.com\",\"diagnosticCode\":\"smtp; 550 no such user\"}]},\"mail\":{\"headers\":[{\"name\":\"Message-ID\",\"value\":\"<1@example.com>\"}],\"commonHeaders\":{\"messageId\":\"<ses@amazonses.com>\"}}}"}`,
+			want: []backend.EmailDeliveryEvent{{MessageID: "<1@example.com>", Email: "alice@example.com", Status: database.EmailDeliveryBounced, Reason: "smtp; 550 no such user"}},
+		},
+		{
+			name: "transient bounce",
+			body: `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"bounce\":{\"bounceType\":\"Transient\",\"bounceSubType\":\"MailboxFull\",\"bouncedRecipients\":[{\"emailAddress\":\"alice@example.com\"}]},\"mail\":{\"commonHeaders\":{\"messageId\":\"<1@example.com>\"}}}"}`,
+			want: []backend.EmailDeliveryEvent{{MessageID: "<1@example.com>", Email: "alice@example.com", Status: database.EmailDeliverySoftBounced, Reason: "Transient bounce (MailboxFull)"}},
+		},
+		{
+			name: "raw complaint event",
+			body: `{"eventType":"Complaint","complaint":{"complaintFeedbackType":"abuse","complainedRecipients":[{"emailAddress":"alice@example.com"}]},"mail":{}}`,
+			want: []backend.EmailDeliveryEvent{{Email: "alice@example.com", Status: database.EmailDeliveryComplained, Reason: "abuse"}},
+		},
+		{
+			name: "unsubscribe confirmation",
+			body: `{"Type":"UnsubscribeConfirmation"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := parseSNSNotification(context.Background(), []byte(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, events); diff != "" {
+				t.Fatalf("unexpected events (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Subscriptions are only confirmed with SNS.
+	body := `{"Type":"SubscriptionConfirmation","SubscribeURL":"http://169.254.169.254/latest"}`
+	if _, err := parseSNSNotification(context.Background(), []byte(body)); err == nil {
+		t.Fatal("want error for a subscribe URL that isn't SNS")
+	}
+}
+
+func TestParseSendGridEvents(t *testing.T) {
+	events, err := parseSendGridEvents([]byte(`[
+		{"email":"a@example.com","event":"delivered","response":"250 OK","smtp-id":"<1@example.com>"},
+		{"email":"b@example.com","event":"bounce","type":"blocked","reason":"blocked by policy"},
+		{"email":"c@example.com","event":"dropped","reason":"Bounced Address"},
+		{"email":"d@example.com","event":"dropped","reason":"Spam Content"},
+		{"email":"e@example.com","event":"spamreport"},
+		{"email":"f@example.com","event":"open"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []backend.EmailDeliveryEvent{
+		{MessageID: "<1@example.com>", Email: "a@example.com", Status: database.EmailDeliveryDelivered},
+		{Email: "b@example.com", Status: database.EmailDeliverySoftBounced, Reason: "blocked by policy"},
+		{Email: "c@example.com", Status: database.EmailDeliveryBounced, Reason: "Bounced Address"},
+		{Email: "d@example.com", Status: database.EmailDeliverySoftBounced, Reason: "Spam Content"},
+		{Email: "e@example.com", Status: database.EmailDeliveryComplained, Reason: "spam report"},
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Fatalf("unexpected events (-want +got):\n%s", diff)
+	}
+}
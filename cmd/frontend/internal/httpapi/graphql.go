@@ -3,26 +3,32 @@ package httpapi
 import (
 	"compress/gzip"
 	"encoding/json"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/graph-gophers/graphql-go"
 	gqlerrors "github.com/graph-gophers/graphql-go/errors"
 	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/throttled/throttled/v2"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/cookie"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/honey"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 )
 
-func serveGraphQL(schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInternal bool) func(w http.ResponseWriter, r *http.Request) (err error) {
+func serveGraphQL(db dbutil.DB, schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInternal bool) func(w http.ResponseWriter, r *http.Request) (err error) {
 	return func(w http.ResponseWriter, r *http.Request) (err error) {
 		if r.Method != "POST" {
 			// The URL router should not have routed to this handler if method is not POST, but just in
@@ -42,6 +48,9 @@ func serveGraphQL(schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInt
 		r = r.WithContext(trace.WithGraphQLRequestName(r.Context(), requestName))
 		r = r.WithContext(trace.WithRequestSource(r.Context(), requestSource))
 
+		// Used to rate limit mutations that send emails
+		r = r.WithContext(backend.WithClientIP(r.Context(), clientIP(r)))
+
 		if r.Header.Get("Content-Encoding") == "gzip" {
 			gzipReader, err := gzip.NewReader(r.Body)
 			if err != nil {
@@ -58,6 +67,15 @@ func serveGraphQL(schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInt
 			return err
 		}
 
+		registerPersistedQueryHash, err := resolvePersistedQuery(r.Context(), db, &params, isInternal)
+		if err != nil {
+			var e *persistedQueryError
+			if errors.As(err, &e) {
+				return writeGraphQLError(w, http.StatusOK, e.message, map[string]interface{}{"code": e.code})
+			}
+			return err
+		}
+
 		traceData := traceData{
 			queryParams:   params,
 			isInternal:    isInternal,
@@ -88,6 +106,12 @@ func serveGraphQL(schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInt
 			traceData.costError = costErr
 			traceData.cost = cost
 
+			if err, ok := graphqlbackend.CheckQueryCost(cost).(*graphqlbackend.QueryTooExpensiveError); ok {
+				graphqlThrottledCounter.WithLabelValues(err.Reason(), string(requestSource)).Inc()
+				traceData.tooExpensive = true
+				return writeGraphQLError(w, http.StatusOK, err.Error(), err.Extensions())
+			}
+
 			if rl, enabled := rlw.Get(); enabled && cost != nil {
 				limited, result, err := rl.RateLimit(uid, cost.FieldCount, graphqlbackend.LimiterArgs{
 					IsIP:          isIP,
@@ -102,16 +126,34 @@ func serveGraphQL(schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInt
 					traceData.limited = limited
 					traceData.limitResult = result
 					if limited {
-						w.Header().Set("Retry-After", strconv.Itoa(int(result.RetryAfter.Seconds())))
-						w.WriteHeader(http.StatusTooManyRequests)
-						return nil
+						graphqlThrottledCounter.WithLabelValues("rate_limit", string(requestSource)).Inc()
+						retryAfter := int(result.RetryAfter.Seconds())
+						w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+						return writeGraphQLError(w, http.StatusTooManyRequests, "rate limit exceeded", map[string]interface{}{
+							"code":          "RATE_LIMITED",
+							"estimatedCost": cost.FieldCount,
+							"retryAfter":    retryAfter,
+						})
 					}
 				}
 			}
 		}
 
+		if registerPersistedQueryHash != "" && len(validationErrs) == 0 {
+			if err := registerPersistedQuery(r.Context(), db, registerPersistedQueryHash, params.Query); err != nil {
+				log15.Error("registering persisted GraphQL query", "error", err)
+			}
+		}
+
 		traceData.execStart = time.Now()
-		response := schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+		var response *graphql.Response
+		// 🚨 SECURITY: Access tokens with fine-grained scopes may only run the operations of their
+		// APIs.
+		if err := graphqlbackend.CheckAccessTokenScopes(r.Context(), params.Query, params.OperationName); err != nil {
+			response = &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}}
+		} else {
+			response = schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+		}
 		traceData.queryErrors = response.Errors
 		responseJSON, err := json.Marshal(response)
 		if err != nil {
@@ -125,10 +167,32 @@ func serveGraphQL(schema *graphql.Schema, rlw graphqlbackend.LimitWatcher, isInt
 	}
 }
 
+// writeGraphQLError writes a GraphQL response with a single error for a request that was rejected
+// before it was executed.
+func writeGraphQLError(w http.ResponseWriter, statusCode int, message string, extensions map[string]interface{}) error {
+	responseJSON, err := json.Marshal(&graphql.Response{Errors: []*gqlerrors.QueryError{{
+		Message:    message,
+		Extensions: extensions,
+	}}})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(responseJSON)
+	return nil
+}
+
+var graphqlThrottledCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "src_graphql_throttled_requests_total",
+	Help: "Total number of GraphQL requests that were rejected by the rate limiter or for exceeding the maximum query cost.",
+}, []string{"reason", "source"})
+
 type graphQLQueryParams struct {
-	Query         string                 `json:"query"`
-	OperationName string                 `json:"operationName"`
-	Variables     map[string]interface{} `json:"variables"`
+	Query         string                    `json:"query"`
+	OperationName string                    `json:"operationName"`
+	Variables     map[string]interface{}    `json:"variables"`
+	Extensions    *graphQLRequestExtensions `json:"extensions"`
 }
 
 type traceData struct {
@@ -141,8 +205,9 @@ type traceData struct {
 	requestSource string
 	queryErrors   []*gqlerrors.QueryError
 
-	cost      *graphqlbackend.QueryCost
-	costError error
+	cost         *graphqlbackend.QueryCost
+	costError    error
+	tooExpensive bool
 
 	limited     bool
 	limitError  error
@@ -183,6 +248,7 @@ func traceGraphQL(data traceData) {
 		ev.AddField("depth", data.cost.MaxDepth)
 		ev.AddField("costVersion", data.cost.Version)
 	}
+	ev.AddField("tooExpensive", data.tooExpensive)
 
 	ev.AddField("rateLimited", data.limited)
 	if data.limitError != nil {
@@ -215,3 +281,29 @@ func getUID(r *http.Request) (uid string, ip bool, anonymous bool) {
 	}
 	return "unknown", false, anonymous
 }
+
+// clientIP returns the IP address of the client that made the request.
+//
+// The frontend usually runs behind a proxy in the same network, so if the request comes from a
+// loopback or private address, the last address in X-Forwarded-For is used. That is the one the
+// proxy appended; any address before it was sent by the client and can't be trusted.
+func clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || !(ip.IsLoopback() || ip.IsPrivate()) {
+		return remote
+	}
+
+	v := r.Header.Values("X-Forwarded-For")
+	if len(v) == 0 {
+		return remote
+	}
+	hops := strings.Split(v[len(v)-1], ",")
+	if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+		return last
+	}
+	return remote
+}
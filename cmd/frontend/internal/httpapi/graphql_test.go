@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "no proxy", remoteAddr: "203.0.113.1:1234", want: "203.0.113.1"},
+		{
+			name:         "spoofed header without proxy",
+			remoteAddr:   "203.0.113.1:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "proxy",
+			remoteAddr:   "10.0.0.2:1234",
+			forwardedFor: []string{"203.0.113.1"},
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "spoofed header behind proxy",
+			remoteAddr:   "10.0.0.2:1234",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.1"},
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "spoofed header line behind proxy",
+			remoteAddr:   "127.0.0.1:1234",
+			forwardedFor: []string{"198.51.100.1", "203.0.113.1"},
+			want:         "203.0.113.1",
+		},
+		{name: "proxy without header", remoteAddr: "10.0.0.2:1234", want: "10.0.0.2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/.api/graphql", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, v := range tc.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if have := clientIP(r); have != tc.want {
+				t.Errorf("have %q, want %q", have, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// GraphQL subscriptions are served over WebSocket using the graphql-transport-ws protocol
+// (https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md), which is what the graphql-ws
+// client library implements.
+const graphqlTransportWSProtocol = "graphql-transport-ws"
+
+// Message types of the graphql-transport-ws protocol.
+const (
+	graphqlWSConnectionInit = "connection_init"
+	graphqlWSConnectionAck  = "connection_ack"
+	graphqlWSPing           = "ping"
+	graphqlWSPong           = "pong"
+	graphqlWSSubscribe      = "subscribe"
+	graphqlWSNext           = "next"
+	graphqlWSError          = "error"
+	graphqlWSComplete       = "complete"
+)
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	graphqlWSCloseInvalidMessage         = 4400
+	graphqlWSCloseUnauthorized           = 4401
+	graphqlWSCloseInitTimeout            = 4408
+	graphqlWSCloseSubscriberExists       = 4409
+	graphqlWSCloseTooManyInitialisations = 4429
+)
+
+const (
+	// graphqlWSInitTimeout is how long a client has to initialize the connection after opening it.
+	graphqlWSInitTimeout = 10 * time.Second
+
+	// graphqlWSWriteTimeout is how long writing a message to the client may take before the
+	// connection is closed.
+	graphqlWSWriteTimeout = 10 * time.Second
+
+	// graphqlWSMaxMessageSize is the maximum size of a message from the client.
+	graphqlWSMaxMessageSize = 1 << 20
+
+	// graphqlWSMaxSubscriptions is the maximum number of concurrent subscriptions of a connection,
+	// since each of them polls the database.
+	graphqlWSMaxSubscriptions = 20
+)
+
+var graphqlWSUpgrader = websocket.Upgrader{
+	Subprotocols: []string{graphqlTransportWSProtocol},
+	// 🚨 SECURITY: The default CheckOrigin rejects cross-origin requests, which would otherwise be
+	// able to use the session cookie of the user.
+}
+
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// serveGraphQLWebSocket serves GraphQL subscriptions over WebSocket.
+func serveGraphQLWebSocket(db dbutil.DB, schema *graphql.Schema, rlw graphqlbackend.LimitWatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := graphqlWSUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade already responded with an error.
+			return
+		}
+		defer conn.Close()
+
+		if conn.Subprotocol() != graphqlTransportWSProtocol {
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseProtocolError, "unsupported subprotocol, must be "+graphqlTransportWSProtocol), time.Now().Add(graphqlWSWriteTimeout))
+			return
+		}
+
+		requestName := "unknown"
+		if r.URL.RawQuery != "" {
+			requestName = r.URL.RawQuery
+		}
+		requestSource := search.GuessSource(r)
+		ctx := trace.WithGraphQLRequestName(r.Context(), requestName)
+		ctx = trace.WithRequestSource(ctx, requestSource)
+		ctx = backend.WithClientIP(ctx, clientIP(r))
+
+		uid, isIP, anonymous := getUID(r)
+		c := &graphqlWSConn{
+			db:     db,
+			schema: schema,
+			conn:   conn,
+			rlw:    rlw,
+			uid:    uid,
+			limiterArgs: graphqlbackend.LimiterArgs{
+				IsIP:          isIP,
+				Anonymous:     anonymous,
+				RequestName:   requestName,
+				RequestSource: requestSource,
+			},
+			subscriptions: map[string]*graphqlWSSubscription{},
+		}
+		c.serve(ctx)
+	})
+}
+
+// graphqlWSConn is a WebSocket connection of a client of GraphQL subscriptions.
+type graphqlWSConn struct {
+	db     dbutil.DB
+	schema *graphql.Schema
+	conn   *websocket.Conn
+
+	// The subscriptions of the client are rate limited like its queries and mutations.
+	rlw         graphqlbackend.LimitWatcher
+	uid         string
+	limiterArgs graphqlbackend.LimiterArgs
+
+	// writeMu serializes writes to the connection, since the subscriptions send messages
+	// concurrently.
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	subscriptions map[string]*graphqlWSSubscription
+}
+
+type graphqlWSSubscription struct {
+	cancel context.CancelFunc
+}
+
+// serve reads the messages of the client until the connection is closed. All subscriptions are
+// canceled when it returns.
+func (c *graphqlWSConn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.conn.SetReadLimit(graphqlWSMaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(graphqlWSInitTimeout))
+
+	initialized := false
+	for {
+		var msg graphqlWSMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && !initialized {
+				c.close(graphqlWSCloseInitTimeout, "Connection initialisation timeout")
+			} else if _, ok := err.(*json.SyntaxError); ok {
+				c.close(graphqlWSCloseInvalidMessage, "Invalid message")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case graphqlWSConnectionInit:
+			if initialized {
+				c.close(graphqlWSCloseTooManyInitialisations, "Too many initialisation requests")
+				return
+			}
+			// The client was already authenticated by the upgrade request, so there is nothing to
+			// check here.
+			initialized = true
+			_ = c.conn.SetReadDeadline(time.Time{})
+			c.send(graphqlWSConnectionAck, "", nil)
+
+		case graphqlWSPing:
+			c.send(graphqlWSPong, "", nil)
+
+		case graphqlWSPong:
+
+		case graphqlWSSubscribe:
+			if !initialized {
+				c.close(graphqlWSCloseUnauthorized, "Unauthorized")
+				return
+			}
+			if !c.subscribe(ctx, msg.ID, msg.Payload) {
+				return
+			}
+
+		case graphqlWSComplete:
+			c.mu.Lock()
+			if s, ok := c.subscriptions[msg.ID]; ok {
+				s.cancel()
+				delete(c.subscriptions, msg.ID)
+			}
+			c.mu.Unlock()
+
+		default:
+			c.close(graphqlWSCloseInvalidMessage, fmt.Sprintf("Unknown message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// subscribe starts the subscription with the given ID. It returns false if the client violated the
+// protocol and the connection was closed.
+func (c *graphqlWSConn) subscribe(ctx context.Context, id string, payload json.RawMessage) bool {
+	var params graphQLQueryParams
+	if id == "" || json.Unmarshal(payload, &params) != nil {
+		c.close(graphqlWSCloseInvalidMessage, "Invalid subscribe message")
+		return false
+	}
+
+	c.mu.Lock()
+	if _, ok := c.subscriptions[id]; ok {
+		c.mu.Unlock()
+		c.close(graphqlWSCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", id))
+		return false
+	}
+	if len(c.subscriptions) >= graphqlWSMaxSubscriptions {
+		c.mu.Unlock()
+		c.sendErrors(id, gqlerrors.Errorf("too many subscriptions on this connection (at most %d)", graphqlWSMaxSubscriptions))
+		return true
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &graphqlWSSubscription{cancel: cancel}
+	c.subscriptions[id] = s
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			if c.subscriptions[id] == s {
+				delete(c.subscriptions, id)
+			}
+			c.mu.Unlock()
+			cancel()
+		}()
+		c.run(ctx, id, params)
+	}()
+	return true
+}
+
+// run runs the subscription and sends its results until it ends or the client completes it.
+func (c *graphqlWSConn) run(ctx context.Context, id string, params graphQLQueryParams) {
+	if err := c.checkSubscription(ctx, &params); err != nil {
+		c.sendErrors(id, toSubscriptionError(err))
+		return
+	}
+
+	// Each poll of the subscription is charged to the rate limit of the client. If it is exceeded,
+	// the resolver ends the subscription and the client gets the rate limit error.
+	var (
+		limitMu  sync.Mutex
+		limitErr error
+	)
+	ctx = graphqlbackend.WithSubscriptionPollLimiter(ctx, func() error {
+		err := c.rateLimit(1)
+		if err != nil {
+			limitMu.Lock()
+			limitErr = err
+			limitMu.Unlock()
+		}
+		return err
+	})
+
+	responses, err := c.schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables)
+	if err != nil {
+		c.sendErrors(id, gqlerrors.Errorf("%s", err))
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			// The client completed the subscription or closed the connection.
+			return
+
+		case v, ok := <-responses:
+			if !ok {
+				limitMu.Lock()
+				err := limitErr
+				limitMu.Unlock()
+				if err != nil {
+					c.sendErrors(id, toSubscriptionError(err))
+				} else {
+					c.send(graphqlWSComplete, id, nil)
+				}
+				return
+			}
+			response, ok := v.(*graphql.Response)
+			if !ok {
+				continue
+			}
+			if len(response.Data) == 0 && len(response.Errors) > 0 {
+				// The subscription failed before it was executed, e.g. because the query is
+				// invalid.
+				c.sendErrors(id, response.Errors...)
+				return
+			}
+			c.send(graphqlWSNext, id, response)
+		}
+	}
+}
+
+// checkSubscription applies the checks to the subscription that serveGraphQL applies to queries
+// and mutations. Starting the subscription is charged its estimated cost, like a query.
+func (c *graphqlWSConn) checkSubscription(ctx context.Context, params *graphQLQueryParams) error {
+	registerPersistedQueryHash, err := resolvePersistedQuery(ctx, c.db, params, false)
+	if err != nil {
+		return err
+	}
+
+	if errs := c.schema.ValidateWithVariables(params.Query, params.Variables); len(errs) > 0 {
+		// Subscribe reports the validation errors.
+		return nil
+	}
+	cost, err := graphqlbackend.EstimateQueryCost(params.Query, params.Variables)
+	if err != nil {
+		log15.Debug("estimating GraphQL cost", "error", err)
+	}
+	if err, ok := graphqlbackend.CheckQueryCost(cost).(*graphqlbackend.QueryTooExpensiveError); ok {
+		graphqlThrottledCounter.WithLabelValues(err.Reason(), string(c.limiterArgs.RequestSource)).Inc()
+		return err
+	}
+	if cost != nil {
+		if err := c.rateLimit(cost.FieldCount); err != nil {
+			return err
+		}
+	}
+
+	// 🚨 SECURITY: Access tokens with fine-grained scopes may only run the operations of their
+	// APIs.
+	if err := graphqlbackend.CheckAccessTokenScopes(ctx, params.Query, params.OperationName); err != nil {
+		return err
+	}
+
+	if registerPersistedQueryHash != "" {
+		if err := registerPersistedQuery(ctx, c.db, registerPersistedQueryHash, params.Query); err != nil {
+			log15.Error("registering persisted GraphQL query", "error", err)
+		}
+	}
+	return nil
+}
+
+// graphqlWSRateLimitError is returned when starting or polling a subscription exceeds the rate
+// limit of the client.
+type graphqlWSRateLimitError struct {
+	retryAfter time.Duration
+}
+
+func (e *graphqlWSRateLimitError) Error() string { return "rate limit exceeded" }
+
+func (e *graphqlWSRateLimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       "RATE_LIMITED",
+		"retryAfter": int(e.retryAfter.Seconds()),
+	}
+}
+
+// rateLimit charges quantity to the rate limit of the client. It returns a
+// *graphqlWSRateLimitError if the limit is exceeded.
+func (c *graphqlWSConn) rateLimit(quantity int) error {
+	if c.rlw == nil {
+		return nil
+	}
+	rl, enabled := c.rlw.Get()
+	if !enabled {
+		return nil
+	}
+	limited, result, err := rl.RateLimit(c.uid, quantity, c.limiterArgs)
+	if err != nil {
+		log15.Error("checking GraphQL rate limit", "error", err)
+		return nil
+	}
+	if limited {
+		graphqlThrottledCounter.WithLabelValues("rate_limit", string(c.limiterArgs.RequestSource)).Inc()
+		return &graphqlWSRateLimitError{retryAfter: result.RetryAfter}
+	}
+	return nil
+}
+
+// toSubscriptionError converts an error that prevented or ended a subscription to a GraphQL error,
+// with the same extensions that serveGraphQL reports for such errors.
+func toSubscriptionError(err error) *gqlerrors.QueryError {
+	queryErr := gqlerrors.Errorf("%s", err)
+	var persistedErr *persistedQueryError
+	var costErr *graphqlbackend.QueryTooExpensiveError
+	var rateLimitErr *graphqlWSRateLimitError
+	if errors.As(err, &persistedErr) {
+		queryErr.Extensions = map[string]interface{}{"code": persistedErr.code}
+	} else if errors.As(err, &costErr) {
+		queryErr.Extensions = costErr.Extensions()
+	} else if errors.As(err, &rateLimitErr) {
+		queryErr.Extensions = rateLimitErr.Extensions()
+	}
+	return queryErr
+}
+
+func (c *graphqlWSConn) sendErrors(id string, errs ...*gqlerrors.QueryError) {
+	c.send(graphqlWSError, id, errs)
+}
+
+// send sends a message to the client. If that fails, it closes the connection, which ends serve.
+func (c *graphqlWSConn) send(typ, id string, payload interface{}) {
+	msg := graphqlWSMessage{ID: id, Type: typ}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			log15.Error("marshaling GraphQL WebSocket message", "type", typ, "error", err)
+			return
+		}
+		msg.Payload = b
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(graphqlWSWriteTimeout))
+	if err := c.conn.WriteJSON(&msg); err != nil {
+		c.conn.Close()
+	}
+}
+
+// close closes the connection with the given close code and reason.
+func (c *graphqlWSConn) close(code int, reason string) {
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(graphqlWSWriteTimeout))
+}
@@ -0,0 +1,259 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/throttled/throttled/v2"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
+)
+
+type testSubscriptionResolver struct{}
+
+func (testSubscriptionResolver) Hello() string { return "hello" }
+
+func (testSubscriptionResolver) Count(ctx context.Context, args *struct{ To int32 }) (<-chan int32, error) {
+	c := make(chan int32)
+	go func() {
+		defer close(c)
+		for i := int32(1); i <= args.To; i++ {
+			select {
+			case c <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+// Ticks polls a counter that changes on every poll.
+func (testSubscriptionResolver) Ticks(ctx context.Context) (<-chan int32, error) {
+	c := make(chan int32)
+	go func() {
+		defer close(c)
+		var n int32
+		graphqlbackend.PollSubscription(ctx, func(context.Context) (string, error) {
+			n++
+			return strconv.Itoa(int(n)), nil
+		}, func() bool {
+			select {
+			case c <- n:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return c, nil
+}
+
+// testLimiter allows the first calls and limits all others.
+type testLimiter struct {
+	mu      sync.Mutex
+	allowed int
+}
+
+func (l *testLimiter) RateLimit(string, int, graphqlbackend.LimiterArgs) (bool, throttled.RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.allowed == 0 {
+		return true, throttled.RateLimitResult{RetryAfter: time.Minute}, nil
+	}
+	l.allowed--
+	return false, throttled.RateLimitResult{}, nil
+}
+
+type testLimitWatcher struct {
+	limiter graphqlbackend.Limiter
+}
+
+func (w testLimitWatcher) Get() (graphqlbackend.Limiter, bool) { return w.limiter, w.limiter != nil }
+
+func TestServeGraphQLWebSocket(t *testing.T) {
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+
+	pollInterval := graphqlbackend.SubscriptionPollInterval
+	graphqlbackend.SubscriptionPollInterval = 10 * time.Millisecond
+	defer func() { graphqlbackend.SubscriptionPollInterval = pollInterval }()
+
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			subscription: Subscription
+		}
+		type Query {
+			hello: String!
+		}
+		type Subscription {
+			count(to: Int!): Int!
+			ticks: Int!
+		}
+	`, testSubscriptionResolver{})
+
+	limiter := &testLimiter{}
+	handler := serveGraphQLWebSocket(new(dbtesting.MockDB), schema, testLimitWatcher{limiter: limiter})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	// scopedServer serves requests as if they were authenticated with an access token that only
+	// has fine-grained scopes.
+	scopedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(authz.WithAccessTokenScopes(r.Context(), []string{authz.ScopeReadSearch})))
+	}))
+	defer scopedServer.Close()
+
+	// setAllowed sets how many more requests the rate limiter allows.
+	setAllowed := func(n int) {
+		limiter.mu.Lock()
+		limiter.allowed = n
+		limiter.mu.Unlock()
+	}
+
+	dialServer := func(t *testing.T, server *httptest.Server) *websocket.Conn {
+		t.Helper()
+		dialer := websocket.Dialer{Subprotocols: []string{graphqlTransportWSProtocol}}
+		conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		return conn
+	}
+	dial := func(t *testing.T) *websocket.Conn {
+		t.Helper()
+		return dialServer(t, server)
+	}
+	write := func(t *testing.T, conn *websocket.Conn, msg string) {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(t *testing.T, conn *websocket.Conn) graphqlWSMessage {
+		t.Helper()
+		var msg graphqlWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	initConn := func(t *testing.T, conn *websocket.Conn) {
+		t.Helper()
+		write(t, conn, `{"type":"connection_init"}`)
+		if msg := read(t, conn); msg.Type != graphqlWSConnectionAck {
+			t.Fatalf("got message %+v, want %s", msg, graphqlWSConnectionAck)
+		}
+	}
+
+	t.Run("subscription", func(t *testing.T) {
+		setAllowed(100)
+		conn := dial(t)
+		initConn(t, conn)
+
+		write(t, conn, `{"type":"ping"}`)
+		if msg := read(t, conn); msg.Type != graphqlWSPong {
+			t.Fatalf("got message %+v, want %s", msg, graphqlWSPong)
+		}
+
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { count(to: 3) }"}}`)
+		for i := 1; i <= 3; i++ {
+			msg := read(t, conn)
+			if msg.Type != graphqlWSNext || msg.ID != "1" {
+				t.Fatalf("got message %+v, want %s", msg, graphqlWSNext)
+			}
+			var response struct {
+				Data struct{ Count int }
+			}
+			if err := json.Unmarshal(msg.Payload, &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Data.Count != i {
+				t.Fatalf("got count %d, want %d", response.Data.Count, i)
+			}
+		}
+		if msg := read(t, conn); msg.Type != graphqlWSComplete || msg.ID != "1" {
+			t.Fatalf("got message %+v, want %s", msg, graphqlWSComplete)
+		}
+	})
+
+	t.Run("access token scopes", func(t *testing.T) {
+		setAllowed(100)
+		conn := dialServer(t, scopedServer)
+		initConn(t, conn)
+
+		// The scopes of the token don't allow the subscription.
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { count(to: 3) }"}}`)
+		msg := read(t, conn)
+		if msg.Type != graphqlWSError || msg.ID != "1" {
+			t.Fatalf("got message %+v, want %s", msg, graphqlWSError)
+		}
+		if !strings.Contains(string(msg.Payload), "scopes") {
+			t.Fatalf("got error %s, want an access token scopes error", msg.Payload)
+		}
+	})
+
+	t.Run("rate limited subscription", func(t *testing.T) {
+		setAllowed(0)
+		conn := dial(t)
+		initConn(t, conn)
+
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { count(to: 3) }"}}`)
+		msg := read(t, conn)
+		if msg.Type != graphqlWSError || !strings.Contains(string(msg.Payload), "RATE_LIMITED") {
+			t.Fatalf("got message %+v, want a rate limit error", msg)
+		}
+	})
+
+	t.Run("rate limited polls", func(t *testing.T) {
+		// Starting the subscription and two more polls are allowed.
+		setAllowed(3)
+		conn := dial(t)
+		initConn(t, conn)
+
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks }"}}`)
+		for i := 1; i <= 3; i++ {
+			if msg := read(t, conn); msg.Type != graphqlWSNext || msg.ID != "1" {
+				t.Fatalf("got message %+v, want %s", msg, graphqlWSNext)
+			}
+		}
+		msg := read(t, conn)
+		if msg.Type != graphqlWSError || msg.ID != "1" || !strings.Contains(string(msg.Payload), "RATE_LIMITED") {
+			t.Fatalf("got message %+v, want a rate limit error", msg)
+		}
+	})
+
+	t.Run("invalid subscription", func(t *testing.T) {
+		conn := dial(t)
+		initConn(t, conn)
+
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { unknown }"}}`)
+		if msg := read(t, conn); msg.Type != graphqlWSError || msg.ID != "1" {
+			t.Fatalf("got message %+v, want %s", msg, graphqlWSError)
+		}
+	})
+
+	t.Run("subscribe before connection_init", func(t *testing.T) {
+		conn := dial(t)
+
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { count(to: 3) }"}}`)
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, graphqlWSCloseUnauthorized) {
+			t.Fatalf("got err %v, want close code %d", err, graphqlWSCloseUnauthorized)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/rcache"
+)
+
+// Persisted queries follow the protocol of Apollo's automatic persisted queries
+// (https://www.apollographql.com/docs/apollo-server/performance/apq/): a client first sends only
+// the hash of a query, and if the query isn't registered yet, sends the query along with its hash
+// to register it.
+
+// graphQLRequestExtensions are the extensions of a GraphQL request.
+type graphQLRequestExtensions struct {
+	PersistedQuery *persistedQueryExtension `json:"persistedQuery,omitempty"`
+}
+
+type persistedQueryExtension struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// Error codes of requests that use persisted queries wrongly, or that run a query which isn't
+// registered in allowlist-only mode. Clients retry with the full query on
+// PERSISTED_QUERY_NOT_FOUND.
+const (
+	persistedQueryNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	persistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	persistedQueryNotAllowed   = "PERSISTED_QUERY_NOT_ALLOWED"
+	persistedQueryInvalid      = "PERSISTED_QUERY_INVALID"
+)
+
+type persistedQueryError struct {
+	code    string
+	message string
+}
+
+func (e *persistedQueryError) Error() string { return e.message }
+
+// Persisted queries never change, so they are cached in Redis to avoid a database query for each
+// request.
+var persistedQueryCache = rcache.NewWithTTL("graphql_persisted_query", 60*60)
+
+// resolvePersistedQuery fills in the query of a request that only sends the hash of a persisted
+// query, and in allowlist-only mode checks that the query of the request is registered. It returns
+// the hash of the query if the request registers it, which the caller must do with
+// registerPersistedQuery once the query passed validation. Only authenticated users and internal
+// requests register queries. It returns a *persistedQueryError if the request must be rejected.
+func resolvePersistedQuery(ctx context.Context, db dbutil.DB, params *graphQLQueryParams, isInternal bool) (registerHash string, err error) {
+	mode := conf.PersistedQueries()
+	var ext *persistedQueryExtension
+	if params.Extensions != nil {
+		ext = params.Extensions.PersistedQuery
+	}
+
+	// 🚨 SECURITY: In allowlist-only mode, users other than site admins may only run registered
+	// queries. Internal requests come from other services and are trusted.
+	allowlisted := mode == conf.PersistedQueriesAllowlistOnly && !isInternal && backend.CheckCurrentUserIsSiteAdmin(ctx, db) != nil
+
+	if ext == nil {
+		if allowlisted {
+			if _, err := getPersistedQuery(ctx, db, hashPersistedQuery(params.Query)); errcode.IsNotFound(err) {
+				return "", &persistedQueryError{code: persistedQueryNotAllowed, message: "only registered persisted queries may be run"}
+			} else if err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	}
+
+	if mode == conf.PersistedQueriesDisabled {
+		return "", &persistedQueryError{code: persistedQueryNotSupported, message: "persisted queries are disabled"}
+	}
+	if ext.Version != 1 {
+		return "", &persistedQueryError{code: persistedQueryNotSupported, message: "unsupported persisted query version"}
+	}
+	hash := strings.ToLower(ext.SHA256Hash)
+
+	if params.Query == "" {
+		query, err := getPersistedQuery(ctx, db, hash)
+		if errcode.IsNotFound(err) {
+			return "", &persistedQueryError{code: persistedQueryNotFound, message: "persisted query not found"}
+		} else if err != nil {
+			return "", err
+		}
+		params.Query = query
+		return "", nil
+	}
+
+	if hashPersistedQuery(params.Query) != hash {
+		return "", &persistedQueryError{code: persistedQueryInvalid, message: "the hash of the persisted query doesn't match the query"}
+	}
+	if allowlisted {
+		// Only site admins may register queries, but others may run registered ones.
+		if _, err := getPersistedQuery(ctx, db, hash); errcode.IsNotFound(err) {
+			return "", &persistedQueryError{code: persistedQueryNotAllowed, message: "only site admins may register persisted queries"}
+		} else if err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+	// 🚨 SECURITY: Anonymous users may run the query, but not register it, so that they can't fill
+	// the table with arbitrary queries.
+	if !isInternal && !actor.FromContext(ctx).IsAuthenticated() {
+		return "", nil
+	}
+	return hash, nil
+}
+
+// registerPersistedQuery registers the query with its hash for the current user.
+func registerPersistedQuery(ctx context.Context, db dbutil.DB, hash, query string) error {
+	var creatorUserID *int32
+	if a := actor.FromContext(ctx); a.IsAuthenticated() {
+		creatorUserID = &a.UID
+	}
+	if err := database.GraphQLPersistedQueries(db).Create(ctx, hash, query, creatorUserID); err != nil {
+		return err
+	}
+	persistedQueryCache.Set(hash, []byte(query))
+	return nil
+}
+
+func getPersistedQuery(ctx context.Context, db dbutil.DB, hash string) (string, error) {
+	if query, ok := persistedQueryCache.Get(hash); ok {
+		return string(query), nil
+	}
+	q, err := database.GraphQLPersistedQueries(db).GetByHash(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	persistedQueryCache.Set(hash, []byte(q.Query))
+	return q.Query, nil
+}
+
+// hashPersistedQuery returns the hex-encoded SHA-256 hash of the query, which identifies it as a
+// persisted query.
+func hashPersistedQuery(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
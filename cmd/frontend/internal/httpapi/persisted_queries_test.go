@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/rcache"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// 🚨 SECURITY: This tests that only registered queries may be run in allowlist-only mode.
+func TestResolvePersistedQuery(t *testing.T) {
+	rcache.SetupForTest(t)
+	db := new(dbtesting.MockDB)
+
+	const registered = "query { currentUser { username } }"
+	const unregistered = "query { site { id } }"
+	database.Mocks.GraphQLPersistedQueries.GetByHash = func(_ context.Context, hash string) (*database.GraphQLPersistedQuery, error) {
+		if hash == hashPersistedQuery(registered) {
+			return &database.GraphQLPersistedQuery{Hash: hash, Query: registered}, nil
+		}
+		return nil, &errcode.Mock{IsNotFound: true}
+	}
+	siteAdmin := false
+	database.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: siteAdmin}, nil
+	}
+	defer func() { database.Mocks = database.MockStores{} }()
+
+	withHash := func(query, hash string) *graphQLQueryParams {
+		return &graphQLQueryParams{
+			Query:      query,
+			Extensions: &graphQLRequestExtensions{PersistedQuery: &persistedQueryExtension{Version: 1, SHA256Hash: hash}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		mode      string
+		siteAdmin bool
+		anonymous bool
+		params    *graphQLQueryParams
+
+		wantQuery    string
+		wantRegister bool
+		wantCode     string
+	}{
+		{
+			name:      "full query",
+			params:    &graphQLQueryParams{Query: unregistered},
+			wantQuery: unregistered,
+		},
+		{
+			name:      "registered hash",
+			params:    withHash("", hashPersistedQuery(registered)),
+			wantQuery: registered,
+		},
+		{
+			name:     "unknown hash",
+			params:   withHash("", hashPersistedQuery(unregistered)),
+			wantCode: persistedQueryNotFound,
+		},
+		{
+			name:         "register",
+			params:       withHash(unregistered, hashPersistedQuery(unregistered)),
+			wantQuery:    unregistered,
+			wantRegister: true,
+		},
+		{
+			name:      "register anonymously",
+			anonymous: true,
+			params:    withHash(unregistered, hashPersistedQuery(unregistered)),
+			wantQuery: unregistered,
+		},
+		{
+			name:     "hash mismatch",
+			params:   withHash(unregistered, hashPersistedQuery(registered)),
+			wantCode: persistedQueryInvalid,
+		},
+		{
+			name:     "disabled",
+			mode:     "disabled",
+			params:   withHash("", hashPersistedQuery(registered)),
+			wantCode: persistedQueryNotSupported,
+		},
+		{
+			name:      "allowlist-only, registered full query",
+			mode:      "allowlist-only",
+			params:    &graphQLQueryParams{Query: registered},
+			wantQuery: registered,
+		},
+		{
+			name:      "allowlist-only, registered hash",
+			mode:      "allowlist-only",
+			params:    withHash("", hashPersistedQuery(registered)),
+			wantQuery: registered,
+		},
+		{
+			name:     "allowlist-only, unregistered full query",
+			mode:     "allowlist-only",
+			params:   &graphQLQueryParams{Query: unregistered},
+			wantCode: persistedQueryNotAllowed,
+		},
+		{
+			name:     "allowlist-only, register",
+			mode:     "allowlist-only",
+			params:   withHash(unregistered, hashPersistedQuery(unregistered)),
+			wantCode: persistedQueryNotAllowed,
+		},
+		{
+			name:      "allowlist-only, unregistered full query as site admin",
+			mode:      "allowlist-only",
+			siteAdmin: true,
+			params:    &graphQLQueryParams{Query: unregistered},
+			wantQuery: unregistered,
+		},
+		{
+			name:         "allowlist-only, register as site admin",
+			mode:         "allowlist-only",
+			siteAdmin:    true,
+			params:       withHash(unregistered, hashPersistedQuery(unregistered)),
+			wantQuery:    unregistered,
+			wantRegister: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{ApiPersistedQueries: test.mode}})
+			defer conf.Mock(nil)
+			siteAdmin = test.siteAdmin
+
+			ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+			if test.anonymous {
+				ctx = context.Background()
+			}
+			hash, err := resolvePersistedQuery(ctx, db, test.params, false)
+			if test.wantCode != "" {
+				if e, ok := err.(*persistedQueryError); !ok || e.code != test.wantCode {
+					t.Fatalf("got err %v, want code %s", err, test.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.params.Query != test.wantQuery {
+				t.Errorf("got query %q, want %q", test.params.Query, test.wantQuery)
+			}
+			if register := hash != ""; register != test.wantRegister {
+				t.Errorf("got register %v, want %v", register, test.wantRegister)
+			}
+		})
+	}
+}
@@ -8,8 +8,9 @@ import (
 )
 
 const (
-	LSIFUpload = "lsif.upload"
-	GraphQL    = "graphql"
+	LSIFUpload       = "lsif.upload"
+	GraphQL          = "graphql"
+	GraphQLWebSocket = "graphql.ws"
 
 	SearchStream = "search.stream"
 
@@ -18,6 +19,11 @@ const (
 
 	Registry = "registry"
 
+	SCIMUsers = "scim.users"
+	SCIMUser  = "scim.user"
+
+	InsightExport = "insights.export"
+
 	RepoShield  = "repo.shield"
 	RepoRefresh = "repo.refresh"
 	Telemetry   = "telemetry"
@@ -25,6 +31,8 @@ const (
 	GitHubWebhooks          = "github.webhooks"
 	GitLabWebhooks          = "gitlab.webhooks"
 	BitbucketServerWebhooks = "bitbucketServer.webhooks"
+	BitbucketCloudWebhooks  = "bitbucketCloud.webhooks"
+	EmailWebhooks           = "email.webhooks"
 
 	SavedQueriesListAll    = "internal.saved-queries.list-all"
 	SavedQueriesGetInfo    = "internal.saved-queries.get-info"
@@ -56,6 +64,8 @@ const (
 	ExternalServiceConfigs = "internal.external-services.configs"
 	ExternalServicesList   = "internal.external-services.list"
 	StreamingSearch        = "internal.stream-search"
+
+	BatchSpecResolutionJobSummary = "internal.batches.resolution-jobs.summary"
 )
 
 // New creates a new API router with route URL pattern definitions but
@@ -69,13 +79,19 @@ func New(base *mux.Router) *mux.Router {
 
 	addRegistryRoute(base)
 	addGraphQLRoute(base)
+	base.Path("/graphql/ws").Methods("GET").Name(GraphQLWebSocket)
 	base.Path("/github-webhooks").Methods("POST").Name(GitHubWebhooks)
 	base.Path("/gitlab-webhooks").Methods("POST").Name(GitLabWebhooks)
 	base.Path("/bitbucket-server-webhooks").Methods("POST").Name(BitbucketServerWebhooks)
+	base.Path("/bitbucket-cloud-webhooks").Methods("POST").Name(BitbucketCloudWebhooks)
+	base.Path("/email-webhooks/{provider}").Methods("POST").Name(EmailWebhooks)
 	base.Path("/lsif/upload").Methods("POST").Name(LSIFUpload)
 	base.Path("/search/stream").Methods("GET").Name(SearchStream)
 	base.Path("/src-cli/version").Methods("GET").Name(SrcCliVersion)
 	base.Path("/src-cli/{rest:.*}").Methods("GET").Name(SrcCliDownload)
+	base.Path("/scim/v2/Users").Methods("GET", "POST").Name(SCIMUsers)
+	base.Path("/scim/v2/Users/{id}").Methods("GET", "PUT", "PATCH", "DELETE").Name(SCIMUser)
+	base.Path("/insights/export/{id}").Methods("GET").Name(InsightExport)
 
 	// repo contains routes that are NOT specific to a revision. In these routes, the URL may not contain a revspec after the repo (that is, no "github.com/foo/bar@myrevspec").
 	repoPath := `/repos/` + routevar.Repo
@@ -129,6 +145,7 @@ func NewInternal(base *mux.Router) *mux.Router {
 	base.Path("/telemetry").Methods("POST").Name(Telemetry)
 	base.Path("/lsif/upload").Methods("POST").Name(LSIFUpload)
 	base.Path("/search/stream").Methods("GET").Name(StreamingSearch)
+	base.Path("/batches/resolution-jobs/{ID:[0-9]+}/summary").Methods("GET").Name(BatchSpecResolutionJobSummary)
 	addRegistryRoute(base)
 	addGraphQLRoute(base)
 
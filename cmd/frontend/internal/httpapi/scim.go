@@ -0,0 +1,115 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/lib/pq"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+// The SCIM 2.0 API (RFC 7643 and RFC 7644) lets identity providers such as Okta and Azure AD
+// provision and deprovision users and keep their email addresses in sync. Only the Users resource
+// is supported.
+
+const (
+	scimUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// scimError is an error that is reported to the identity provider as a SCIM error response (RFC
+// 7644 section 3.12).
+type scimError struct {
+	Status   int
+	ScimType string // optional, e.g. "uniqueness" or "invalidFilter"
+	Detail   string
+}
+
+func (e *scimError) Error() string { return e.Detail }
+
+func scimErrorf(status int, scimType, format string, args ...interface{}) error {
+	return &scimError{Status: status, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+// scimHandler authenticates SCIM requests with the bearer token from the scim.authToken site
+// configuration property and reports the errors returned by h as SCIM error responses.
+func scimHandler(h func(http.ResponseWriter, *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authToken := conf.Get().ScimAuthToken
+		if authToken == "" {
+			writeSCIMError(w, &scimError{Status: http.StatusNotFound, Detail: "SCIM provisioning is not enabled"})
+			return
+		}
+
+		// 🚨 SECURITY: Use a constant-time comparison to avoid leaking the token via timing attack.
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == r.Header.Get("Authorization") || subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) != 1 {
+			writeSCIMError(w, &scimError{Status: http.StatusUnauthorized, Detail: "invalid SCIM bearer token"})
+			return
+		}
+
+		// The identity provider manages users on behalf of the site, not of any user.
+		r = r.WithContext(actor.WithInternalActor(r.Context()))
+
+		err := h(w, r)
+		if err == nil {
+			return
+		}
+		var e *scimError
+		if !errors.As(err, &e) {
+			e = toSCIMError(err)
+		}
+		if e.Status >= 500 {
+			log15.Error("SCIM request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		}
+		writeSCIMError(w, e)
+	})
+}
+
+// toSCIMError converts errors from the database to SCIM errors.
+func toSCIMError(err error) *scimError {
+	if errcode.IsNotFound(err) {
+		return &scimError{Status: http.StatusNotFound, Detail: "user not found"}
+	}
+	if database.IsUsernameExists(err) {
+		return &scimError{Status: http.StatusConflict, ScimType: "uniqueness", Detail: "the username is already taken"}
+	}
+	// Unique and exclusion constraint violations, e.g. when an email address is already verified
+	// by another user.
+	var pqErr *pq.Error
+	if database.IsEmailExists(err) || (errors.As(err, &pqErr) && (pqErr.Code == "23505" || pqErr.Code == "23P01")) {
+		return &scimError{Status: http.StatusConflict, ScimType: "uniqueness", Detail: "an email address is already used by another user"}
+	}
+	return &scimError{Status: http.StatusInternalServerError, Detail: "internal error"}
+}
+
+func writeSCIMError(w http.ResponseWriter, e *scimError) {
+	_ = writeSCIM(w, e.Status, struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail"`
+	}{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(e.Status),
+		ScimType: e.ScimType,
+		Detail:   e.Detail,
+	})
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,256 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+const testSCIMToken = "0123456789abcdef0123456789abcdef"
+
+func TestSCIMAuth(t *testing.T) {
+	c := newTest()
+	database.Mocks.Users.Count = func(context.Context, *database.UsersListOptions) (int, error) { return 0, nil }
+	database.Mocks.Users.List = func(context.Context, *database.UsersListOptions) ([]*types.User, error) { return nil, nil }
+	defer func() { database.Mocks.Users = database.MockUsers{} }()
+
+	for _, test := range []struct {
+		name       string
+		authToken  string
+		header     string
+		wantStatus int
+	}{
+		{name: "not enabled", header: "Bearer " + testSCIMToken, wantStatus: http.StatusNotFound},
+		{name: "no token", authToken: testSCIMToken, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", authToken: testSCIMToken, header: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", authToken: testSCIMToken, header: testSCIMToken, wantStatus: http.StatusUnauthorized},
+		{name: "valid token", authToken: testSCIMToken, header: "Bearer " + testSCIMToken, wantStatus: http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{ScimAuthToken: test.authToken}})
+			defer conf.Mock(nil)
+
+			req, _ := http.NewRequest("GET", "/scim/v2/Users", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/scim+json" {
+				t.Fatalf("got content type %q", ct)
+			}
+		})
+	}
+}
+
+func TestSCIMListUsersFilter(t *testing.T) {
+	c := newTest()
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{ScimAuthToken: testSCIMToken}})
+	defer conf.Mock(nil)
+
+	alice := &types.User{ID: 1, Username: "alice"}
+	database.Mocks.Users.GetByUsername = func(ctx context.Context, username string) (*types.User, error) {
+		if username == alice.Username {
+			return alice, nil
+		}
+		return nil, database.MockUserNotFoundErr
+	}
+	database.Mocks.Users.GetByVerifiedEmail = func(ctx context.Context, email string) (*types.User, error) {
+		if email == "alice@example.com" {
+			return alice, nil
+		}
+		return nil, database.MockUserNotFoundErr
+	}
+	database.Mocks.UserEmails.ListByUser = func(ctx context.Context, opt database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		return []*database.UserEmail{{UserID: 1, Email: "alice@example.com", Primary: true}}, nil
+	}
+	defer func() {
+		database.Mocks.Users = database.MockUsers{}
+		database.Mocks.UserEmails = database.MockUserEmails{}
+	}()
+
+	for _, test := range []struct {
+		filter     string
+		wantStatus int
+		wantTotal  int
+	}{
+		// Identity providers often use email addresses as usernames.
+		{filter: `userName eq "alice@example.com"`, wantStatus: http.StatusOK, wantTotal: 1},
+		{filter: `userName eq "bob"`, wantStatus: http.StatusOK},
+		{filter: `emails.value eq "alice@example.com"`, wantStatus: http.StatusOK, wantTotal: 1},
+		{filter: `emails EQ "bob@example.com"`, wantStatus: http.StatusOK},
+		{filter: `displayName eq "Alice"`, wantStatus: http.StatusBadRequest},
+		{filter: `userName sw "a"`, wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/scim/v2/Users", nil)
+			q := req.URL.Query()
+			q.Set("filter", test.filter)
+			req.URL.RawQuery = q.Encode()
+			req.Header.Set("Authorization", "Bearer "+testSCIMToken)
+
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			var list struct {
+				TotalResults int         `json:"totalResults"`
+				Resources    []*scimUser `json:"Resources"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+			if list.TotalResults != test.wantTotal || len(list.Resources) != test.wantTotal {
+				t.Fatalf("got %d results and %d resources, want %d", list.TotalResults, len(list.Resources), test.wantTotal)
+			}
+			if test.wantTotal > 0 {
+				u := list.Resources[0]
+				if u.ID != "1" || u.UserName != "alice" || len(u.Emails) != 1 || !u.Emails[0].Primary {
+					t.Fatalf("unexpected user %+v", u)
+				}
+			}
+		})
+	}
+}
+
+func TestSCIMUserWriteRestrictions(t *testing.T) {
+	c := newTest()
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{ScimAuthToken: testSCIMToken}})
+	defer conf.Mock(nil)
+
+	users := map[int32]*types.User{
+		1: {ID: 1, Username: "admin", SiteAdmin: true},
+		2: {ID: 2, Username: "provisioned"},
+		3: {ID: 3, Username: "local"},
+	}
+	database.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		if u, ok := users[id]; ok {
+			return u, nil
+		}
+		return nil, database.MockUserNotFoundErr
+	}
+	database.Mocks.ExternalAccounts.Count = func(opt database.ExternalAccountsListOptions) (int, error) {
+		if opt.ServiceType == scimServiceType && (opt.UserID == 1 || opt.UserID == 2) {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	database.Mocks.UserEmails.ListByUser = func(ctx context.Context, opt database.UserEmailsListOptions) ([]*database.UserEmail, error) {
+		return nil, nil
+	}
+	defer func() {
+		database.Mocks.Users = database.MockUsers{}
+		database.Mocks.ExternalAccounts = database.MockExternalAccounts{}
+		database.Mocks.UserEmails = database.MockUserEmails{}
+	}()
+
+	for _, test := range []struct {
+		name       string
+		method     string
+		id         string
+		wantStatus int
+	}{
+		{name: "delete site admin", method: "DELETE", id: "1", wantStatus: http.StatusForbidden},
+		{name: "update site admin", method: "PUT", id: "1", wantStatus: http.StatusForbidden},
+		{name: "delete user not provisioned by SCIM", method: "DELETE", id: "3", wantStatus: http.StatusForbidden},
+		{name: "patch user not provisioned by SCIM", method: "PATCH", id: "3", wantStatus: http.StatusForbidden},
+		{name: "get user not provisioned by SCIM", method: "GET", id: "3", wantStatus: http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(test.method, "/scim/v2/Users/"+test.id, nil)
+			req.Header.Set("Authorization", "Bearer "+testSCIMToken)
+
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestNormalizeSCIMEmails(t *testing.T) {
+	emails, err := normalizeSCIMEmails([]scimEmail{
+		{Value: "a@example.com"},
+		{Value: " b@example.com", Primary: true},
+		{Value: "A@example.com"},
+		{Value: "c@example.com", Primary: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []scimEmail{
+		{Value: "b@example.com", Primary: true},
+		{Value: "a@example.com"},
+		{Value: "c@example.com"},
+	}
+	if diff := cmp.Diff(want, emails); diff != "" {
+		t.Fatalf("unexpected emails (-want +got):\n%s", diff)
+	}
+
+	if _, err := normalizeSCIMEmails([]scimEmail{{Value: "not an email"}}); err == nil {
+		t.Fatal("want error for invalid email address")
+	}
+}
+
+func TestSCIMUserApply(t *testing.T) {
+	active := true
+	u := &scimUser{
+		UserName: "alice",
+		Active:   &active,
+		Emails:   []scimEmail{{Value: "a@example.com", Primary: true}},
+	}
+
+	ops := []scimPatchOperation{
+		{Op: "replace", Path: "displayName", Value: json.RawMessage(`"Alice"`)},
+		{Op: "Add", Path: "emails", Value: json.RawMessage(`[{"value":"b@example.com","primary":true}]`)},
+		// Attributes that aren't stored are ignored.
+		{Op: "replace", Path: "name.givenName", Value: json.RawMessage(`"Alice"`)},
+		{Op: "replace", Value: json.RawMessage(`{"active":false,"title":"Engineer"}`)},
+	}
+	for _, op := range ops {
+		if err := u.apply(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inactive := false
+	want := &scimUser{
+		UserName:    "alice",
+		DisplayName: "Alice",
+		Active:      &inactive,
+		Emails: []scimEmail{
+			{Value: "a@example.com"},
+			{Value: "b@example.com", Primary: true},
+		},
+	}
+	if diff := cmp.Diff(want, u); diff != "" {
+		t.Fatalf("unexpected user (-want +got):\n%s", diff)
+	}
+
+	if err := u.apply(scimPatchOperation{Op: "remove", Path: "emails"}); err == nil {
+		t.Fatal("want error for unsupported operation")
+	}
+}
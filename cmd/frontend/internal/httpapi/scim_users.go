@@ -0,0 +1,666 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/mux"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// scimUser is the SCIM representation of a user (RFC 7643 section 4.1). Attributes that
+// Sourcegraph doesn't store, such as name or title, are ignored.
+type scimUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Emails      []scimEmail `json:"emails,omitempty"`
+	Meta        *scimMeta   `json:"meta,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// scimPatch is a SCIM PATCH request (RFC 7644 section 3.5.2).
+type scimPatch struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []scimPatchOperation `json:"Operations"`
+}
+
+type scimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// scimMaxCount is the maximum number of users returned per page when listing users.
+const scimMaxCount = 1000
+
+// Users provisioned by SCIM have an external account with this service type and ID, and their user
+// ID as account ID. Only those users can be updated or deprovisioned by the identity provider.
+const (
+	scimServiceType = "scim"
+	scimServiceID   = "scim"
+)
+
+func scimAccountSpec(userID int32) extsvc.AccountSpec {
+	return extsvc.AccountSpec{
+		ServiceType: scimServiceType,
+		ServiceID:   scimServiceID,
+		AccountID:   strconv.Itoa(int(userID)),
+	}
+}
+
+func serveSCIMUsers(db dbutil.DB) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == "POST" {
+			return serveSCIMCreateUser(db, w, r)
+		}
+		return serveSCIMListUsers(db, w, r)
+	}
+}
+
+func serveSCIMUser(db dbutil.DB) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+		if err != nil {
+			return scimErrorf(http.StatusNotFound, "", "user not found")
+		}
+		user, err := database.Users(db).GetByID(ctx, int32(id))
+		if err != nil {
+			return err
+		}
+
+		switch r.Method {
+		case "PUT", "PATCH", "DELETE":
+			if err := checkSCIMUserWritable(ctx, db, user); err != nil {
+				return err
+			}
+		}
+
+		switch r.Method {
+		case "PUT":
+			var in scimUser
+			if err := decodeSCIM(r, &in); err != nil {
+				return err
+			}
+			return serveSCIMUpdateUser(ctx, db, w, user, &in)
+
+		case "PATCH":
+			var patch scimPatch
+			if err := decodeSCIM(r, &patch); err != nil {
+				return err
+			}
+			in, err := toSCIMUser(ctx, db, user)
+			if err != nil {
+				return err
+			}
+			for _, op := range patch.Operations {
+				if err := in.apply(op); err != nil {
+					return err
+				}
+			}
+			return serveSCIMUpdateUser(ctx, db, w, user, in)
+
+		case "DELETE":
+			if err := deprovisionSCIMUser(ctx, db, user); err != nil {
+				return err
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+
+		u, err := toSCIMUser(ctx, db, user)
+		if err != nil {
+			return err
+		}
+		return writeSCIM(w, http.StatusOK, u)
+	}
+}
+
+func serveSCIMListUsers(db dbutil.DB, w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	// startIndex is 1-based.
+	startIndex, count := 1, 100
+	if n, err := strconv.Atoi(q.Get("startIndex")); err == nil && n > 1 {
+		startIndex = n
+	}
+	if n, err := strconv.Atoi(q.Get("count")); err == nil && n >= 0 {
+		count = n
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+
+	var (
+		users []*types.User
+		total int
+	)
+	if filter := q.Get("filter"); filter != "" {
+		user, err := findSCIMUser(ctx, db, filter)
+		if err != nil {
+			return err
+		}
+		if user != nil {
+			total = 1
+			if startIndex == 1 && count > 0 {
+				users = []*types.User{user}
+			}
+		}
+	} else {
+		var err error
+		total, err = database.Users(db).Count(ctx, &database.UsersListOptions{})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			users, err = database.Users(db).List(ctx, &database.UsersListOptions{
+				LimitOffset: &database.LimitOffset{Limit: count, Offset: startIndex - 1},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	resources := make([]*scimUser, 0, len(users))
+	for _, user := range users {
+		u, err := toSCIMUser(ctx, db, user)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, u)
+	}
+	return writeSCIM(w, http.StatusOK, struct {
+		Schemas      []string    `json:"schemas"`
+		TotalResults int         `json:"totalResults"`
+		StartIndex   int         `json:"startIndex"`
+		ItemsPerPage int         `json:"itemsPerPage"`
+		Resources    []*scimUser `json:"Resources"`
+	}{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// scimFilterPattern matches the filters that identity providers use to look up a user before
+// provisioning it, which are equality filters on the username or an email address. Other
+// filters are not supported.
+var scimFilterPattern = lazyregexp.New(`^(?i:(userName|emails|emails\.value)\s+eq\s+("(?:[^"\\]|\\.)*"))$`)
+
+// findSCIMUser returns the user matching the filter, or nil if there is none.
+func findSCIMUser(ctx context.Context, db dbutil.DB, filter string) (*types.User, error) {
+	m := scimFilterPattern.FindStringSubmatch(strings.TrimSpace(filter))
+	if m == nil {
+		return nil, scimErrorf(http.StatusBadRequest, "invalidFilter", "unsupported filter %q: only userName and emails eq filters are supported", filter)
+	}
+	var value string
+	if err := json.Unmarshal([]byte(m[2]), &value); err != nil {
+		return nil, scimErrorf(http.StatusBadRequest, "invalidFilter", "invalid filter value %s", m[2])
+	}
+
+	var (
+		user *types.User
+		err  error
+	)
+	if strings.EqualFold(m[1], "userName") {
+		username, normErr := auth.NormalizeUsername(value)
+		if normErr != nil {
+			// No user can have this username.
+			return nil, nil
+		}
+		user, err = database.Users(db).GetByUsername(ctx, username)
+	} else {
+		user, err = database.Users(db).GetByVerifiedEmail(ctx, value)
+	}
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func serveSCIMCreateUser(db dbutil.DB, w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var in scimUser
+	if err := decodeSCIM(r, &in); err != nil {
+		return err
+	}
+	if in.Active != nil && !*in.Active {
+		return scimErrorf(http.StatusBadRequest, "invalidValue", "inactive users can't be provisioned")
+	}
+
+	user, err := createSCIMUser(ctx, db, &in)
+	if err != nil {
+		return err
+	}
+	grantSCIMUserPendingPermissions(ctx, user.ID)
+
+	u, err := toSCIMUser(ctx, db, user)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", u.Meta.Location)
+	return writeSCIM(w, http.StatusCreated, u)
+}
+
+// createSCIMUser creates the user with its email addresses in a single transaction, so that the
+// identity provider can retry if any email address is rejected.
+func createSCIMUser(ctx context.Context, db dbutil.DB, in *scimUser) (_ *types.User, err error) {
+	username, err := normalizeSCIMUsername(in.UserName)
+	if err != nil {
+		return nil, err
+	}
+	emails, err := normalizeSCIMEmails(in.Emails)
+	if err != nil {
+		return nil, err
+	}
+	var primaryEmail string
+	if len(emails) > 0 {
+		primaryEmail = emails[0].Value
+	}
+
+	tx, err := database.Users(db).Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	// 🚨 SECURITY: The identity provider is trusted to have verified the email addresses, like
+	// the external auth providers that the user signs in with. The user has no password.
+	user, err := tx.Create(ctx, database.NewUser{
+		Username:        username,
+		DisplayName:     in.DisplayName,
+		Email:           primaryEmail,
+		EmailIsVerified: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := database.ExternalAccountsWith(tx).AssociateUserAndSave(ctx, user.ID, scimAccountSpec(user.ID), extsvc.AccountData{}); err != nil {
+		return nil, err
+	}
+	if _, _, err := syncSCIMUserEmails(ctx, database.UserEmailsWith(tx), user.ID, emails); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkSCIMUserWritable returns an error if the identity provider must not update or deprovision
+// the user.
+//
+// 🚨 SECURITY: The identity provider may only change the users it provisioned. Otherwise a SCIM
+// token could be used to take over any account by replacing its email addresses, or to lock out
+// the site admins.
+func checkSCIMUserWritable(ctx context.Context, db dbutil.DB, user *types.User) error {
+	if user.SiteAdmin {
+		return scimErrorf(http.StatusForbidden, "", "site admins can't be managed by SCIM")
+	}
+	spec := scimAccountSpec(user.ID)
+	n, err := database.ExternalAccounts(db).Count(ctx, database.ExternalAccountsListOptions{
+		UserID:      user.ID,
+		ServiceType: spec.ServiceType,
+		ServiceID:   spec.ServiceID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return scimErrorf(http.StatusForbidden, "", "the user was not provisioned by SCIM")
+	}
+	return nil
+}
+
+// grantSCIMUserPendingPermissions grants the user the repository permissions that are pending for
+// its verified email addresses and username.
+func grantSCIMUserPendingPermissions(ctx context.Context, userID int32) {
+	if err := database.GlobalAuthz.GrantPendingPermissions(ctx, &database.GrantPendingPermissionsArgs{
+		UserID: userID,
+		Perm:   authz.Read,
+		Type:   authz.PermRepos,
+	}); err != nil {
+		log15.Error("Failed to grant user pending permissions", "userID", userID, "error", err)
+	}
+}
+
+// serveSCIMUpdateUser updates the user to match the SCIM representation from a PUT or PATCH
+// request. Inactive users are deprovisioned.
+func serveSCIMUpdateUser(ctx context.Context, db dbutil.DB, w http.ResponseWriter, user *types.User, in *scimUser) error {
+	if in.Active != nil && !*in.Active {
+		u, err := toSCIMUser(ctx, db, user)
+		if err != nil {
+			return err
+		}
+		if err := deprovisionSCIMUser(ctx, db, user); err != nil {
+			return err
+		}
+		u.Active = in.Active
+		return writeSCIM(w, http.StatusOK, u)
+	}
+
+	verified, err := updateSCIMUser(ctx, db, user, in)
+	if err != nil {
+		return err
+	}
+	if verified {
+		grantSCIMUserPendingPermissions(ctx, user.ID)
+	}
+	user, err = database.Users(db).GetByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	u, err := toSCIMUser(ctx, db, user)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, u)
+}
+
+// updateSCIMUser returns whether any email address of the user was verified.
+func updateSCIMUser(ctx context.Context, db dbutil.DB, user *types.User, in *scimUser) (verified bool, err error) {
+	username, err := normalizeSCIMUsername(in.UserName)
+	if err != nil {
+		return false, err
+	}
+	emails, err := normalizeSCIMEmails(in.Emails)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := database.Users(db).Transact(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if username != user.Username || in.DisplayName != user.DisplayName {
+		update := database.UserUpdate{DisplayName: &in.DisplayName}
+		if username != user.Username {
+			update.Username = username
+		}
+		if err := tx.Update(ctx, user.ID, update); err != nil {
+			return false, err
+		}
+	}
+
+	verified, removed, err := syncSCIMUserEmails(ctx, database.UserEmailsWith(tx), user.ID, emails)
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		// 🚨 SECURITY: Invalidate any existing password reset code, which may have been sent to a
+		// removed email address.
+		if err := tx.DeletePasswordResetCode(ctx, user.ID); err != nil {
+			return false, err
+		}
+	}
+	return verified, nil
+}
+
+// normalizeSCIMUsername converts the userName from the identity provider, which is often an email
+// address, to a valid username.
+func normalizeSCIMUsername(name string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", scimErrorf(http.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	username, err := auth.NormalizeUsername(name)
+	if err != nil {
+		return "", scimErrorf(http.StatusBadRequest, "invalidValue", "invalid userName: %v", err)
+	}
+	return username, nil
+}
+
+// normalizeSCIMEmails returns the email addresses without duplicates, with the primary address
+// first. The first address is primary if none is marked as primary.
+func normalizeSCIMEmails(emails []scimEmail) ([]scimEmail, error) {
+	var normalized []scimEmail
+	seen := make(map[string]bool, len(emails))
+	primary := -1
+	for _, e := range emails {
+		e.Value = strings.TrimSpace(e.Value)
+		if !strings.Contains(e.Value, "@") {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "invalid email address %q", e.Value)
+		}
+		key := strings.ToLower(e.Value)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if e.Primary && primary == -1 {
+			primary = len(normalized)
+		}
+		normalized = append(normalized, e)
+	}
+	if primary > 0 {
+		p := normalized[primary]
+		copy(normalized[1:primary+1], normalized[:primary])
+		normalized[0] = p
+	}
+	for i := range normalized {
+		normalized[i].Primary = i == 0
+	}
+	return normalized, nil
+}
+
+// syncSCIMUserEmails makes the email addresses of the user match the ones from the identity
+// provider, the first of which is the primary address. The identity provider is trusted to have
+// verified the addresses. The email addresses are left alone if the identity provider sent none.
+//
+// It returns whether any email address was verified and whether any was removed.
+func syncSCIMUserEmails(ctx context.Context, store *database.UserEmailsStore, userID int32, emails []scimEmail) (verified, removed bool, err error) {
+	if len(emails) == 0 {
+		return false, false, nil
+	}
+
+	current, err := store.ListByUser(ctx, database.UserEmailsListOptions{UserID: userID})
+	if err != nil {
+		return false, false, err
+	}
+	have := make(map[string]*database.UserEmail, len(current))
+	for _, e := range current {
+		have[strings.ToLower(e.Email)] = e
+	}
+
+	want := make(map[string]bool, len(emails))
+	for _, e := range emails {
+		key := strings.ToLower(e.Value)
+		want[key] = true
+		existing, ok := have[key]
+		if !ok {
+			if err := store.Add(ctx, userID, e.Value, nil); err != nil {
+				return false, false, err
+			}
+		}
+		if !ok || existing.VerifiedAt == nil {
+			if err := store.SetVerified(ctx, userID, e.Value, true); err != nil {
+				return false, false, err
+			}
+			verified = true
+		}
+	}
+
+	if primary, ok := have[strings.ToLower(emails[0].Value)]; !ok || !primary.Primary {
+		if err := store.SetPrimaryEmail(ctx, userID, emails[0].Value); err != nil {
+			return false, false, err
+		}
+	}
+
+	for _, e := range current {
+		if want[strings.ToLower(e.Email)] {
+			continue
+		}
+		if err := store.Remove(ctx, userID, e.Email); err != nil {
+			return false, false, err
+		}
+		removed = true
+	}
+	return verified, removed, nil
+}
+
+// deprovisionSCIMUser soft-deletes the user, which signs them out and prevents them from signing
+// in again, and revokes their repository permissions.
+func deprovisionSCIMUser(ctx context.Context, db dbutil.DB, user *types.User) error {
+	// Collect the accounts of the user before deleting it, to revoke their permissions.
+	extAccounts, err := database.ExternalAccounts(db).List(ctx, database.ExternalAccountsListOptions{UserID: user.ID})
+	if err != nil {
+		return errors.Wrap(err, "list external accounts")
+	}
+	accounts := make([]*extsvc.Accounts, 0, len(extAccounts)+1)
+	for _, acct := range extAccounts {
+		accounts = append(accounts, &extsvc.Accounts{
+			ServiceType: acct.ServiceType,
+			ServiceID:   acct.ServiceID,
+			AccountIDs:  []string{acct.AccountID},
+		})
+	}
+	verifiedEmails, err := database.UserEmails(db).ListByUser(ctx, database.UserEmailsListOptions{
+		UserID:       user.ID,
+		OnlyVerified: true,
+	})
+	if err != nil {
+		return err
+	}
+	accountIDs := []string{user.Username}
+	for _, e := range verifiedEmails {
+		accountIDs = append(accountIDs, e.Email)
+	}
+	accounts = append(accounts, &extsvc.Accounts{
+		ServiceType: authz.SourcegraphServiceType,
+		ServiceID:   authz.SourcegraphServiceID,
+		AccountIDs:  accountIDs,
+	})
+
+	if err := database.Users(db).Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	return database.GlobalAuthz.RevokeUserPermissions(ctx, &database.RevokeUserPermissionsArgs{
+		UserID:   user.ID,
+		Accounts: accounts,
+	})
+}
+
+func toSCIMUser(ctx context.Context, db dbutil.DB, user *types.User) (*scimUser, error) {
+	emails, err := database.UserEmails(db).ListByUser(ctx, database.UserEmailsListOptions{UserID: user.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	id := strconv.Itoa(int(user.ID))
+	active := true
+	u := &scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          id,
+		UserName:    user.Username,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     globals.ExternalURL().ResolveReference(&url.URL{Path: "/.api/scim/v2/Users/" + id}).String(),
+		},
+	}
+	for _, e := range emails {
+		u.Emails = append(u.Emails, scimEmail{Value: e.Email, Primary: e.Primary})
+	}
+	return u, nil
+}
+
+// apply applies a PATCH operation to the user. Only the add and replace operations are supported.
+// Attributes that Sourcegraph doesn't store, and paths with value filters, are ignored.
+func (u *scimUser) apply(op scimPatchOperation) error {
+	add := strings.EqualFold(op.Op, "add")
+	if !add && !strings.EqualFold(op.Op, "replace") {
+		return scimErrorf(http.StatusBadRequest, "invalidSyntax", "unsupported patch operation %q", op.Op)
+	}
+	if op.Path != "" {
+		return u.set(op.Path, op.Value, add)
+	}
+
+	// Without a path, the value holds the attributes to set.
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &attrs); err != nil {
+		return scimErrorf(http.StatusBadRequest, "invalidValue", "invalid patch value: %v", err)
+	}
+	for name, value := range attrs {
+		if err := u.set(name, value, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *scimUser) set(name string, value json.RawMessage, add bool) error {
+	var err error
+	switch strings.ToLower(name) {
+	case "username":
+		err = json.Unmarshal(value, &u.UserName)
+	case "displayname":
+		err = json.Unmarshal(value, &u.DisplayName)
+	case "active":
+		err = json.Unmarshal(value, &u.Active)
+	case "emails":
+		var emails []scimEmail
+		if err = json.Unmarshal(value, &emails); err != nil {
+			break
+		}
+		if !add {
+			u.Emails = emails
+			break
+		}
+		// An added primary email address replaces the current primary one.
+		for _, e := range emails {
+			if e.Primary {
+				for i := range u.Emails {
+					u.Emails[i].Primary = false
+				}
+				break
+			}
+		}
+		u.Emails = append(u.Emails, emails...)
+	}
+	if err != nil {
+		return scimErrorf(http.StatusBadRequest, "invalidValue", "invalid value for %s: %v", name, err)
+	}
+	return nil
+}
+
+func decodeSCIM(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return scimErrorf(http.StatusBadRequest, "invalidSyntax", "invalid request body: %v", err)
+	}
+	return nil
+}
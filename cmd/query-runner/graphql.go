@@ -1,26 +1,17 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"log"
-	"net/http"
-	"net/url"
 	"runtime"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
-	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/gqlclient"
 
 	"github.com/cockroachdb/errors"
 )
 
-type graphQLQuery struct {
-	Query     string      `json:"query"`
-	Variables interface{} `json:"variables"`
-}
-
 const gqlSearchQuery = `query Search(
 	$query: String!,
 ) {
@@ -117,56 +108,24 @@ type gqlSearchResponse struct {
 			}
 		}
 	}
-	Errors []interface{}
 }
 
 func search(ctx context.Context, query string) (*gqlSearchResponse, error) {
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(graphQLQuery{
-		Query:     gqlSearchQuery,
-		Variables: gqlSearchVars{Query: query},
-	})
-	if err != nil {
-		return nil, errors.Wrap(err, "Encode")
-	}
-
-	url, err := gqlURL("QueryRunnerSearch")
-	if err != nil {
-		return nil, errors.Wrap(err, "constructing frontend URL")
-	}
-
-	req, err := http.NewRequest("POST", url, &buf)
+	client, err := gqlclient.NewInternal("sourcegraph/query-runner")
 	if err != nil {
-		return nil, errors.Wrap(err, "Post")
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "sourcegraph/query-runner")
-
-	resp, err := httpcli.InternalDoer.Do(req.WithContext(ctx))
-	if err != nil {
-		return nil, errors.Wrap(err, "Post")
-	}
-	defer resp.Body.Close()
-
-	var res *gqlSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return nil, errors.Wrap(err, "Decode")
-	}
-	if len(res.Errors) > 0 {
-		return res, errors.Errorf("graphql: errors: %v", res.Errors)
-	}
-	return res, nil
-}
-
-func gqlURL(queryName string) (string, error) {
-	u, err := url.Parse(api.InternalClient.URL)
+	var res gqlSearchResponse
+	err = client.Do(ctx, gqlclient.Request{
+		Name:      "QueryRunnerSearch",
+		Query:     gqlSearchQuery,
+		Variables: gqlSearchVars{Query: query},
+	}, &res.Data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	u.Path = "/.internal/graphql"
-	u.RawQuery = queryName
-	return u.String(), nil
+	return &res, nil
 }
 
 // extractTime extracts the time from the given search result.
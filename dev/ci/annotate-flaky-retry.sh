@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+
+# Annotates the build when a step running a test suite known to be flaky is retried, so
+# that flaky retries are visible without failing the build. The first argument is the URL
+# of the issue tracking the flakiness. See enterprise/dev/ci/internal/ci/flakes/flakes.yaml.
+
+set -u
+
+if [ "${BUILDKITE_RETRY_COUNT:-0}" = "0" ]; then
+  exit 0
+fi
+
+issue="$1"
+job_url="${BUILDKITE_BUILD_URL}#${BUILDKITE_JOB_ID}"
+
+# Annotations must not fail the step.
+printf -- '- [%s](%s) is a known flaky test suite and was retried (attempt %s), see %s\n' \
+  "$BUILDKITE_LABEL" "$job_url" "$((BUILDKITE_RETRY_COUNT + 1))" "$issue" |
+  buildkite-agent annotate --style warning --context flaky-retries --append || true
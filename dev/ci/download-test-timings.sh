@@ -0,0 +1,35 @@
+#!/usr/bin/env bash
+
+# Downloads the test timings recorded by the last passed build of main into the given
+# directory, to balance the test shards of the pipeline generated with
+# TEST_TIMINGS_DIR set to that directory. Test shards are not balanced if no timings are
+# available, so failures to download them don't fail the build.
+
+set -uo pipefail
+
+dir="${1:-test-timings-history}"
+mkdir -p "$dir"
+
+if [ -z "${BUILDKITE_API_TOKEN:-}" ]; then
+  echo "BUILDKITE_API_TOKEN is not set, not downloading test timings"
+  exit 0
+fi
+
+build_id=$(curl -sSf -H "Authorization: Bearer $BUILDKITE_API_TOKEN" \
+  "https://api.buildkite.com/v2/organizations/${BUILDKITE_ORGANIZATION_SLUG:-sourcegraph}/pipelines/${BUILDKITE_PIPELINE_SLUG:-sourcegraph}/builds?branch=main&state=passed&per_page=1" |
+  jq -r '.[0].id // empty')
+if [ -z "$build_id" ]; then
+  echo "No passed build of main found, not downloading test timings"
+  exit 0
+fi
+
+echo "Downloading test timings of build $build_id"
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+# Artifacts are downloaded with their path, i.e. into $tmp/test-timings.
+if buildkite-agent artifact download 'test-timings/*.tsv' "$tmp" --build "$build_id"; then
+  mv "$tmp"/test-timings/*.tsv "$dir"
+else
+  echo "Failed to download test timings"
+fi
+exit 0
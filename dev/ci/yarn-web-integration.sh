@@ -14,6 +14,8 @@ echo "--- Download Puppeteer browser"
 yarn --cwd client/shared run download-puppeteer-browser
 
 echo "--- Run integration test suite"
+# Record the durations of the test files, to balance the chunks of later builds.
+export TEST_TIMINGS_FILE="test-timings/web-integration-${BUILDKITE_JOB_ID:-local}.tsv"
 # Word splittinng is intentional here. $1 contains a string with test files separated by a space.
 # shellcheck disable=SC2086
 yarn percy exec --parallel yarn cover-integration:base $1
@@ -54,6 +54,7 @@ var (
 			doctorCommand,
 			liveCommand,
 			migrationCommand,
+			ciCommand,
 			rfcCommand,
 			funkLogoCommand,
 		},
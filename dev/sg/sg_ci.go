@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/exec"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+
+	"github.com/sourcegraph/sourcegraph/dev/sg/root"
+	"github.com/sourcegraph/sourcegraph/lib/output"
+)
+
+var (
+	ciPreviewFlagSet  = flag.NewFlagSet("sg ci preview", flag.ExitOnError)
+	ciPreviewBaseFlag = ciPreviewFlagSet.String("base", "origin/main", "The branch the changes would be merged into.")
+	ciPreviewYAMLFlag = ciPreviewFlagSet.Bool("yaml", false, "Print the generated Buildkite pipeline as YAML instead of a summary.")
+	ciPreviewCommand  = &ffcli.Command{
+		Name:       "preview",
+		ShortUsage: "sg ci preview [-base=origin/main] [-yaml]",
+		ShortHelp:  "Preview the CI pipeline of the local changes",
+		LongHelp: `Runs the CI pipeline generator against the local changes, including uncommitted and untracked files,
+and prints the changed files, the Go packages to test and the steps a pull request with these changes would run.
+
+Use it to check how changes to the CI targeting rules affect builds before pushing them.`,
+		FlagSet: ciPreviewFlagSet,
+		Exec:    ciPreviewExec,
+	}
+
+	ciFlagSet = flag.NewFlagSet("sg ci", flag.ExitOnError)
+	ciCommand = &ffcli.Command{
+		Name:       "ci",
+		ShortUsage: "sg ci <command>",
+		ShortHelp:  "Interacts with the CI pipelines",
+		FlagSet:    ciFlagSet,
+		Exec: func(ctx context.Context, args []string) error {
+			return flag.ErrHelp
+		},
+		Subcommands: []*ffcli.Command{
+			ciPreviewCommand,
+		},
+	}
+)
+
+func ciPreviewExec(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		out.WriteLine(output.Linef("", output.StyleWarning, "ERROR: too many arguments"))
+		return flag.ErrHelp
+	}
+
+	repoRoot, err := root.RepositoryRoot()
+	if err != nil {
+		return err
+	}
+
+	// Keep the YAML output valid, e.g. to pipe it to other tools.
+	if !*ciPreviewYAMLFlag {
+		out.WriteLine(output.Linef("", output.StylePending, "Generating the pipeline of the changes since %s...", *ciPreviewBaseFlag))
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "run", "./enterprise/dev/ci/gen-pipeline.go")
+	cmd.Dir = repoRoot
+	cmd.Env = append(os.Environ(), "PIPELINE_PREVIEW="+*ciPreviewBaseFlag)
+	if *ciPreviewYAMLFlag {
+		cmd.Env = append(cmd.Env, "PIPELINE_PREVIEW_FORMAT=yaml")
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
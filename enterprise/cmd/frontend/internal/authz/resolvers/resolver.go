@@ -118,6 +118,20 @@ func (r *Resolver) SetRepositoryPermissionsForUsers(ctx context.Context, args *g
 			delete(bindIDSet, emails[i].Email)
 		}
 
+		// Email addresses that users no longer have may be attributed to their other ones.
+		attributed := make([]string, 0, len(bindIDSet))
+		for id := range bindIDSet {
+			attributed = append(attributed, id)
+		}
+		attributions, err := database.GlobalUserEmails.GetAttributions(ctx, attributed...)
+		if err != nil {
+			return nil, err
+		}
+		for i := range attributions {
+			p.UserIDs.Add(uint32(attributions[i].UserID))
+			delete(bindIDSet, attributions[i].Email)
+		}
+
 	case "username":
 		users, err := database.GlobalUsers.GetByUsernames(ctx, bindIDs...)
 		if err != nil {
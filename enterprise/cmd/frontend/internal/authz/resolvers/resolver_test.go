@@ -184,6 +184,9 @@ func TestResolver_SetRepositoryPermissionsForUsers(t *testing.T) {
 			database.Mocks.UserEmails.GetVerifiedEmails = func(context.Context, ...string) ([]*database.UserEmail, error) {
 				return test.mockVerifiedEmails, nil
 			}
+			database.Mocks.UserEmails.GetAttributions = func(context.Context, ...string) ([]*database.UserEmailAttribution, error) {
+				return nil, nil
+			}
 			database.Mocks.Repos.Get = func(_ context.Context, id api.RepoID) (*types.Repo, error) {
 				return &types.Repo{ID: id}, nil
 			}
@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+)
+
+// NewResolutionJobSummaryHandler returns a handler that serves the summary of
+// a finished batch spec resolution job as JSON. It responds with 404 if the
+// job doesn't exist and with 409 if it hasn't finished yet.
+func NewResolutionJobSummaryHandler(s *store.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["ID"], 10, 64)
+		if err != nil {
+			http.Error(w, "invalid resolution job ID", http.StatusBadRequest)
+			return
+		}
+
+		job, err := s.GetBatchSpecResolutionJob(r.Context(), store.GetBatchSpecResolutionJobOpts{ID: id})
+		if err != nil {
+			if err == store.ErrNoResults {
+				http.Error(w, "resolution job not found", http.StatusNotFound)
+				return
+			}
+			log15.Error("failed to load batch spec resolution job", "id", id, "err", err)
+			http.Error(w, "failed to load resolution job", http.StatusInternalServerError)
+			return
+		}
+
+		if job.Summary == nil {
+			http.Error(w, "resolution job has not finished", http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(job.Summary); err != nil {
+			log15.Error("failed to write batch spec resolution job summary", "id", id, "err", err)
+		}
+	})
+}
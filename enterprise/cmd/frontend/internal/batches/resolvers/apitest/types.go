@@ -84,6 +84,7 @@ type BatchChange struct {
 	CreatedAt               string
 	UpdatedAt               string
 	ClosedAt                string
+	AutoRebase              bool
 	URL                     string
 	ChangesetsStats         ChangesetsStats
 	Changesets              ChangesetConnection
@@ -0,0 +1,49 @@
+package resolvers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+type auditEventResolver struct {
+	store *store.Store
+	event *btypes.AuditEvent
+}
+
+var _ graphqlbackend.BatchChangesAuditEventResolver = &auditEventResolver{}
+
+func (r *auditEventResolver) Actor(ctx context.Context) (*graphqlbackend.UserResolver, error) {
+	if r.event.ActorUserID == 0 {
+		return nil, nil
+	}
+	user, err := graphqlbackend.UserByIDInt32(ctx, r.store.DB(), r.event.ActorUserID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *auditEventResolver) Action() string {
+	return string(r.event.Action)
+}
+
+func (r *auditEventResolver) ResourceType() string {
+	return string(r.event.ResourceType)
+}
+
+func (r *auditEventResolver) ResourceID() string {
+	return strconv.FormatInt(r.event.ResourceID, 10)
+}
+
+func (r *auditEventResolver) Metadata() graphqlbackend.JSONValue {
+	return graphqlbackend.JSONValue{Value: r.event.Metadata}
+}
+
+func (r *auditEventResolver) CreatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.event.CreatedAt}
+}
@@ -0,0 +1,65 @@
+package resolvers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+type auditEventConnectionResolver struct {
+	store *store.Store
+	opts  store.ListAuditEventsOpts
+
+	// Cache results because they are used by multiple fields.
+	once   sync.Once
+	events []*btypes.AuditEvent
+	next   int64
+	err    error
+}
+
+var _ graphqlbackend.BatchChangesAuditEventConnectionResolver = &auditEventConnectionResolver{}
+
+func (r *auditEventConnectionResolver) Nodes(ctx context.Context) ([]graphqlbackend.BatchChangesAuditEventResolver, error) {
+	nodes, _, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.BatchChangesAuditEventResolver, 0, len(nodes))
+	for _, e := range nodes {
+		resolvers = append(resolvers, &auditEventResolver{store: r.store, event: e})
+	}
+	return resolvers, nil
+}
+
+func (r *auditEventConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := r.store.CountAuditEvents(ctx, store.CountAuditEventsOpts{
+		ActorUserID:  r.opts.ActorUserID,
+		Action:       r.opts.Action,
+		ResourceType: r.opts.ResourceType,
+		ResourceID:   r.opts.ResourceID,
+	})
+	return int32(count), err
+}
+
+func (r *auditEventConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	_, next, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if next != 0 {
+		return graphqlutil.NextPageCursor(strconv.Itoa(int(next))), nil
+	}
+	return graphqlutil.HasNextPage(false), nil
+}
+
+func (r *auditEventConnectionResolver) compute(ctx context.Context) ([]*btypes.AuditEvent, int64, error) {
+	r.once.Do(func() {
+		r.events, r.next, r.err = r.store.ListAuditEvents(ctx, r.opts)
+	})
+	return r.events, r.next, r.err
+}
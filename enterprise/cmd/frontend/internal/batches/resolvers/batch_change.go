@@ -154,6 +154,10 @@ func (r *batchChangeResolver) ClosedAt() *graphqlbackend.DateTime {
 	return &graphqlbackend.DateTime{Time: r.batchChange.ClosedAt}
 }
 
+func (r *batchChangeResolver) AutoRebase() bool {
+	return r.batchChange.AutoRebase
+}
+
 func (r *batchChangeResolver) ChangesetsStats(ctx context.Context) (graphqlbackend.ChangesetsStatsResolver, error) {
 	stats, err := r.store.GetChangesetsStats(ctx, r.batchChange.ID)
 	if err != nil {
@@ -316,3 +320,19 @@ func (r *batchChangeResolver) BatchSpecs(
 
 	return &batchSpecConnectionResolver{store: r.store, opts: opts}, nil
 }
+
+func (r *batchChangeResolver) ExecutionSchedule(ctx context.Context) (graphqlbackend.BatchSpecExecutionScheduleResolver, error) {
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	schedule, err := r.store.GetBatchSpecExecutionSchedule(ctx, store.GetBatchSpecExecutionScheduleOpts{BatchChangeID: r.batchChange.ID})
+	if err != nil {
+		if err == store.ErrNoResults {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batchSpecExecutionScheduleResolver{store: r.store, schedule: schedule}, nil
+}
@@ -0,0 +1,62 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+type batchChangeRollbackJobResolver struct {
+	store *store.Store
+	job   *btypes.BatchChangeRollbackJob
+}
+
+var _ graphqlbackend.BatchChangeRollbackJobResolver = &batchChangeRollbackJobResolver{}
+
+func (r *batchChangeRollbackJobResolver) BatchChange(ctx context.Context) (graphqlbackend.BatchChangeResolver, error) {
+	batchChange, err := r.store.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: r.job.BatchChangeID})
+	if err != nil {
+		return nil, err
+	}
+	return &batchChangeResolver{store: r.store, batchChange: batchChange}, nil
+}
+
+func (r *batchChangeRollbackJobResolver) State() string {
+	return string(r.job.State)
+}
+
+func (r *batchChangeRollbackJobResolver) FailureMessage() *string {
+	return r.job.FailureMessage
+}
+
+func (r *batchChangeRollbackJobResolver) Initiator(ctx context.Context) (*graphqlbackend.UserResolver, error) {
+	if r.job.UserID == 0 {
+		return nil, nil
+	}
+	user, err := graphqlbackend.UserByIDInt32(ctx, r.store.DB(), r.job.UserID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *batchChangeRollbackJobResolver) CreatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.job.CreatedAt}
+}
+
+func (r *batchChangeRollbackJobResolver) StartedAt() *graphqlbackend.DateTime {
+	if r.job.StartedAt.IsZero() {
+		return nil
+	}
+	return &graphqlbackend.DateTime{Time: r.job.StartedAt}
+}
+
+func (r *batchChangeRollbackJobResolver) FinishedAt() *graphqlbackend.DateTime {
+	if r.job.FinishedAt.IsZero() {
+		return nil
+	}
+	return &graphqlbackend.DateTime{Time: r.job.FinishedAt}
+}
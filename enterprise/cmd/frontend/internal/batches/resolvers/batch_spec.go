@@ -3,6 +3,7 @@ package resolvers
 import (
 	"context"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/cockroachdb/errors"
@@ -453,6 +454,43 @@ func (r *batchSpecResolver) WorkspaceResolution(ctx context.Context) (graphqlbac
 	return &batchSpecWorkspaceResolutionResolver{store: r.store, resolution: resolution}, nil
 }
 
+func (r *batchSpecResolver) ResolutionJobs(ctx context.Context, args *graphqlbackend.ListResolutionJobsArgs) (graphqlbackend.BatchSpecWorkspaceResolutionConnectionResolver, error) {
+	if err := validateFirstParamDefaults(args.First); err != nil {
+		return nil, err
+	}
+	opts := store.ListNamespaceBatchSpecResolutionJobsOpts{
+		LimitOpts: store.LimitOpts{
+			Limit: int(args.First),
+		},
+		BatchSpecID: r.batchSpec.ID,
+	}
+	if args.After != nil {
+		id, err := strconv.Atoi(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = int64(id)
+	}
+	if args.State != nil {
+		state := btypes.BatchSpecResolutionJobState(strings.ToLower(*args.State))
+		if !state.Valid() {
+			return nil, errors.Errorf("unknown state %q", *args.State)
+		}
+		opts.State = state
+	}
+
+	return &batchSpecWorkspaceResolutionConnectionResolver{store: r.store, opts: opts}, nil
+}
+
+func (r *batchSpecResolver) Archive(ctx context.Context) (string, error) {
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return "", err
+	}
+
+	return service.New(r.store).ExportBatchSpec(ctx, r.batchSpec.ID)
+}
+
 func (r *batchSpecResolver) computeNamespace(ctx context.Context) (*graphqlbackend.NamespaceResolver, error) {
 	r.namespaceOnce.Do(func() {
 		if r.preloadedNamespace != nil {
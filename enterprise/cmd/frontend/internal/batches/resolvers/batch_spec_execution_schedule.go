@@ -0,0 +1,74 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+type batchSpecExecutionScheduleResolver struct {
+	store    *store.Store
+	schedule *btypes.BatchSpecExecutionSchedule
+}
+
+var _ graphqlbackend.BatchSpecExecutionScheduleResolver = &batchSpecExecutionScheduleResolver{}
+
+func (r *batchSpecExecutionScheduleResolver) BatchChange(ctx context.Context) (graphqlbackend.BatchChangeResolver, error) {
+	batchChange, err := r.store.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: r.schedule.BatchChangeID})
+	if err != nil {
+		return nil, err
+	}
+	return &batchChangeResolver{store: r.store, batchChange: batchChange}, nil
+}
+
+func (r *batchSpecExecutionScheduleResolver) Creator(ctx context.Context) (*graphqlbackend.UserResolver, error) {
+	user, err := graphqlbackend.UserByIDInt32(ctx, r.store.DB(), r.schedule.UserID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *batchSpecExecutionScheduleResolver) IntervalSeconds() int32 {
+	return int32(r.schedule.Interval.Seconds())
+}
+
+func (r *batchSpecExecutionScheduleResolver) Enabled() bool {
+	return r.schedule.Enabled
+}
+
+func (r *batchSpecExecutionScheduleResolver) NextRunAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.schedule.NextRunAt}
+}
+
+func (r *batchSpecExecutionScheduleResolver) LastRunAt() *graphqlbackend.DateTime {
+	if r.schedule.LastRunAt.IsZero() {
+		return nil
+	}
+	return &graphqlbackend.DateTime{Time: r.schedule.LastRunAt}
+}
+
+func (r *batchSpecExecutionScheduleResolver) PendingBatchSpec(ctx context.Context) (graphqlbackend.BatchSpecResolver, error) {
+	if r.schedule.PendingBatchSpecID == 0 {
+		return nil, nil
+	}
+	batchSpec, err := r.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: r.schedule.PendingBatchSpecID})
+	if err != nil {
+		if err == store.ErrNoResults {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batchSpecResolver{store: r.store, batchSpec: batchSpec}, nil
+}
+
+func (r *batchSpecExecutionScheduleResolver) CreatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.schedule.CreatedAt}
+}
+
+func (r *batchSpecExecutionScheduleResolver) UpdatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.schedule.UpdatedAt}
+}
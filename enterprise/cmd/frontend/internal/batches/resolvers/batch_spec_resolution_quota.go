@@ -0,0 +1,59 @@
+package resolvers
+
+import (
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+type batchSpecResolutionQuotaResolver struct {
+	quota btypes.BatchSpecResolutionQuota
+	usage btypes.BatchSpecResolutionQuotaUsage
+}
+
+var _ graphqlbackend.BatchSpecResolutionQuotaResolver = &batchSpecResolutionQuotaResolver{}
+
+func (r *batchSpecResolutionQuotaResolver) MaxConcurrent() *int32 {
+	return quotaLimit(r.quota.MaxConcurrent)
+}
+
+func (r *batchSpecResolutionQuotaResolver) MaxPerDay() *int32 {
+	return quotaLimit(r.quota.MaxPerDay)
+}
+
+func (r *batchSpecResolutionQuotaResolver) Concurrent() int32 {
+	return int32(r.usage.Concurrent)
+}
+
+func (r *batchSpecResolutionQuotaResolver) CreatedLastDay() int32 {
+	return int32(r.usage.LastDay)
+}
+
+func (r *batchSpecResolutionQuotaResolver) RemainingConcurrent() *int32 {
+	return quotaRemaining(r.quota.MaxConcurrent, r.usage.Concurrent)
+}
+
+func (r *batchSpecResolutionQuotaResolver) RemainingPerDay() *int32 {
+	return quotaRemaining(r.quota.MaxPerDay, r.usage.LastDay)
+}
+
+// quotaLimit returns nil for unlimited quotas.
+func quotaLimit(max int) *int32 {
+	if max <= 0 {
+		return nil
+	}
+	l := int32(max)
+	return &l
+}
+
+// quotaRemaining returns nil for unlimited quotas. Since the quota can be
+// lowered while jobs are running, used can exceed max.
+func quotaRemaining(max, used int) *int32 {
+	if max <= 0 {
+		return nil
+	}
+	var remaining int32
+	if used < max {
+		remaining = int32(max - used)
+	}
+	return &remaining
+}
@@ -2,11 +2,18 @@ package resolvers
 
 import (
 	"context"
+	"math"
 	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/types"
 )
 
 type batchSpecWorkspaceResolutionResolver struct {
@@ -16,6 +23,18 @@ type batchSpecWorkspaceResolutionResolver struct {
 
 var _ graphqlbackend.BatchSpecWorkspaceResolutionResolver = &batchSpecWorkspaceResolutionResolver{}
 
+func (r *batchSpecWorkspaceResolutionResolver) BatchSpec(ctx context.Context) (graphqlbackend.BatchSpecResolver, error) {
+	batchSpec, err := r.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: r.resolution.BatchSpecID})
+	if err != nil {
+		return nil, err
+	}
+	return &batchSpecResolver{store: r.store, batchSpec: batchSpec}, nil
+}
+
+func (r *batchSpecWorkspaceResolutionResolver) CreatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.resolution.CreatedAt}
+}
+
 func (r *batchSpecWorkspaceResolutionResolver) State() string {
 	return r.resolution.State.ToGraphQL()
 }
@@ -38,6 +57,205 @@ func (r *batchSpecWorkspaceResolutionResolver) FailureMessage() *string {
 	return r.resolution.FailureMessage
 }
 
+func (r *batchSpecWorkspaceResolutionResolver) EstimatedSecondsRemaining(ctx context.Context) (*int32, error) {
+	if r.resolution.State != btypes.BatchSpecResolutionJobStateProcessing || r.resolution.StartedAt.IsZero() {
+		return nil, nil
+	}
+
+	spec, err := r.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: r.resolution.BatchSpecID})
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := resolutionJobDurationStats.get(ctx, r.store)
+	if err != nil {
+		return nil, err
+	}
+
+	elapsed := r.store.Clock()().Sub(r.resolution.StartedAt)
+	remaining, ok := btypes.EstimateTimeRemaining(stats, knownRepoCount(spec), elapsed, r.resolution.Progress.PercentComplete)
+	if !ok {
+		return nil, nil
+	}
+
+	seconds := int32(math.Ceil(remaining.Seconds()))
+	return &seconds, nil
+}
+
+// resolutionJobDurationStatsTTL is how long the duration stats of resolution
+// jobs are cached. They change slowly, but computing them is expensive and
+// clients poll the estimate of running jobs.
+const resolutionJobDurationStatsTTL = 5 * time.Minute
+
+var resolutionJobDurationStats = &durationStatsCache{}
+
+// durationStatsCache caches the result of
+// store.ListBatchSpecResolutionJobDurationStats for
+// resolutionJobDurationStatsTTL.
+type durationStatsCache struct {
+	mu        sync.Mutex
+	stats     []*btypes.BatchSpecResolutionJobDurationStats
+	fetchedAt time.Time
+}
+
+func (c *durationStatsCache) get(ctx context.Context, s *store.Store) ([]*btypes.BatchSpecResolutionJobDurationStats, error) {
+	// The lock is held while loading the stats, so that concurrent requests
+	// don't all compute them when the cache expires.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := s.Clock()()
+	if !c.fetchedAt.IsZero() && now.Sub(c.fetchedAt) < resolutionJobDurationStatsTTL {
+		return c.stats, nil
+	}
+
+	stats, err := s.ListBatchSpecResolutionJobDurationStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.stats, c.fetchedAt = stats, now
+	return stats, nil
+}
+
+// knownRepoCount returns the number of repositories the batch spec will
+// resolve to, if that's known before resolving it. That is only the case if
+// the spec lists repositories explicitly, without any search queries.
+// Otherwise it returns -1.
+func knownRepoCount(spec *btypes.BatchSpec) int {
+	if spec.Spec == nil || len(spec.Spec.On) == 0 {
+		return -1
+	}
+
+	repos := make(map[string]struct{}, len(spec.Spec.On))
+	for _, on := range spec.Spec.On {
+		if on.RepositoriesMatchingQuery != "" {
+			return -1
+		}
+		repos[on.Repository] = struct{}{}
+	}
+	return len(repos)
+}
+
+func (r *batchSpecWorkspaceResolutionResolver) SearchQueries() []string {
+	if r.resolution.SearchQueries == nil {
+		return []string{}
+	}
+	return r.resolution.SearchQueries
+}
+
+func (r *batchSpecWorkspaceResolutionResolver) Progress() graphqlbackend.BatchSpecWorkspaceResolutionProgressResolver {
+	return &batchSpecWorkspaceResolutionProgressResolver{progress: r.resolution.Progress}
+}
+
+func (r *batchSpecWorkspaceResolutionResolver) CredentialWarnings() []graphqlbackend.BatchSpecWorkspaceResolutionCredentialWarningResolver {
+	resolvers := make([]graphqlbackend.BatchSpecWorkspaceResolutionCredentialWarningResolver, 0, len(r.resolution.CredentialWarnings))
+	for _, w := range r.resolution.CredentialWarnings {
+		resolvers = append(resolvers, &batchSpecWorkspaceResolutionCredentialWarningResolver{warning: w})
+	}
+	return resolvers
+}
+
+func (r *batchSpecWorkspaceResolutionResolver) RepositoryErrors(ctx context.Context) ([]graphqlbackend.BatchSpecWorkspaceResolutionRepositoryErrorResolver, error) {
+	var repoIDs []api.RepoID
+	for _, e := range r.resolution.RepositoryErrors {
+		if e.RepoID != 0 {
+			repoIDs = append(repoIDs, e.RepoID)
+		}
+	}
+
+	// 🚨 SECURITY: The repositories are loaded as the viewer, so that errors
+	// of repositories the viewer has no access to, which would leak their
+	// names, are omitted.
+	var accessibleRepos map[api.RepoID]*types.Repo
+	if len(repoIDs) > 0 {
+		var err error
+		accessibleRepos, err = r.store.Repos().GetReposSetByIDs(ctx, repoIDs...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resolvers := make([]graphqlbackend.BatchSpecWorkspaceResolutionRepositoryErrorResolver, 0, len(r.resolution.RepositoryErrors))
+	for _, e := range r.resolution.RepositoryErrors {
+		// Errors without a repository are about names given in the batch
+		// spec that couldn't be found, which the viewer can see in the spec.
+		var repo *types.Repo
+		if e.RepoID != 0 {
+			var ok bool
+			if repo, ok = accessibleRepos[e.RepoID]; !ok {
+				continue
+			}
+		}
+		resolvers = append(resolvers, &batchSpecWorkspaceResolutionRepositoryErrorResolver{store: r.store, repoErr: e, repo: repo})
+	}
+	return resolvers, nil
+}
+
+const (
+	// resolutionLogsPollInterval is how often the logs of a resolution are
+	// checked for new entries while a request waits for them.
+	resolutionLogsPollInterval = time.Second
+	// resolutionLogsMaxWait caps how long a request waits for new entries.
+	resolutionLogsMaxWait = 30 * time.Second
+)
+
+func (r *batchSpecWorkspaceResolutionResolver) ExecutionLogs(ctx context.Context, args *graphqlbackend.BatchSpecWorkspaceResolutionExecutionLogsArgs) (graphqlbackend.BatchSpecWorkspaceResolutionExecutionLogsResolver, error) {
+	if args.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	wait := time.Duration(args.WaitSeconds) * time.Second
+	if wait > resolutionLogsMaxWait {
+		wait = resolutionLogsMaxWait
+	}
+	deadline := time.Now().Add(wait)
+
+	for {
+		logs, err := r.store.GetBatchSpecResolutionJobLogs(ctx, r.resolution.ID, int(args.Offset))
+		if err != nil {
+			return nil, err
+		}
+
+		if hasFinishedEntries(logs) || resolutionFinished(logs.State) || !time.Now().Before(deadline) {
+			return &batchSpecWorkspaceResolutionExecutionLogsResolver{store: r.store, logs: logs}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(resolutionLogsPollInterval):
+		}
+	}
+}
+
+// hasFinishedEntries returns whether the logs contain an entry that the
+// client won't need to fetch again. A single unfinished entry is the one the
+// client already tails, so waiting for it to change avoids a busy loop.
+func hasFinishedEntries(logs *btypes.BatchSpecResolutionJobLogs) bool {
+	switch len(logs.Entries) {
+	case 0:
+		return false
+	case 1:
+		return logs.Entries[0].ExitCode != nil
+	default:
+		return true
+	}
+}
+
+// resolutionFinished returns whether a resolution job in the given state won't
+// be processed anymore. Errored jobs are never retried, so they're finished
+// too.
+func resolutionFinished(state btypes.BatchSpecResolutionJobState) bool {
+	switch state {
+	case btypes.BatchSpecResolutionJobStateCompleted,
+		btypes.BatchSpecResolutionJobStateFailed,
+		btypes.BatchSpecResolutionJobStateErrored:
+		return true
+	default:
+		return false
+	}
+}
+
 func (r *batchSpecWorkspaceResolutionResolver) AllowIgnored() bool {
 	return r.resolution.AllowIgnored
 }
@@ -79,3 +297,90 @@ func (r *batchSpecWorkspaceResolutionResolver) RecentlyErrored(ctx context.Conte
 	// TODO(ssbc): not implemented
 	return nil
 }
+
+type batchSpecWorkspaceResolutionProgressResolver struct {
+	progress btypes.BatchSpecResolutionJobProgress
+}
+
+var _ graphqlbackend.BatchSpecWorkspaceResolutionProgressResolver = &batchSpecWorkspaceResolutionProgressResolver{}
+
+func (r *batchSpecWorkspaceResolutionProgressResolver) RepositoriesDiscovered() int32 {
+	return int32(r.progress.ReposDiscovered)
+}
+
+func (r *batchSpecWorkspaceResolutionProgressResolver) WorkspacesResolved() int32 {
+	return int32(r.progress.WorkspacesResolved)
+}
+
+func (r *batchSpecWorkspaceResolutionProgressResolver) PercentComplete() int32 {
+	return int32(r.progress.PercentComplete)
+}
+
+type batchSpecWorkspaceResolutionCredentialWarningResolver struct {
+	warning *btypes.BatchSpecResolutionCredentialWarning
+}
+
+var _ graphqlbackend.BatchSpecWorkspaceResolutionCredentialWarningResolver = &batchSpecWorkspaceResolutionCredentialWarningResolver{}
+
+func (r *batchSpecWorkspaceResolutionCredentialWarningResolver) CodeHost() graphqlbackend.BatchChangesCodeHostResolver {
+	return &batchChangesCodeHostResolver{codeHost: &btypes.CodeHost{
+		ExternalServiceType: r.warning.ExternalServiceType,
+		ExternalServiceID:   r.warning.ExternalServiceID,
+	}}
+}
+
+func (r *batchSpecWorkspaceResolutionCredentialWarningResolver) RepositoryCount() int32 {
+	return int32(r.warning.RepoCount)
+}
+
+type batchSpecWorkspaceResolutionRepositoryErrorResolver struct {
+	store   *store.Store
+	repoErr *btypes.BatchSpecResolutionRepositoryError
+	repo    *types.Repo
+}
+
+var _ graphqlbackend.BatchSpecWorkspaceResolutionRepositoryErrorResolver = &batchSpecWorkspaceResolutionRepositoryErrorResolver{}
+
+func (r *batchSpecWorkspaceResolutionRepositoryErrorResolver) Repository() *graphqlbackend.RepositoryResolver {
+	if r.repo == nil {
+		return nil
+	}
+	return graphqlbackend.NewRepositoryResolver(r.store.DB(), r.repo)
+}
+
+func (r *batchSpecWorkspaceResolutionRepositoryErrorResolver) RepositoryName() string {
+	return r.repoErr.RepoName
+}
+
+func (r *batchSpecWorkspaceResolutionRepositoryErrorResolver) Message() string {
+	return r.repoErr.Message
+}
+
+type batchSpecWorkspaceResolutionExecutionLogsResolver struct {
+	store *store.Store
+	logs  *btypes.BatchSpecResolutionJobLogs
+}
+
+var _ graphqlbackend.BatchSpecWorkspaceResolutionExecutionLogsResolver = &batchSpecWorkspaceResolutionExecutionLogsResolver{}
+
+func (r *batchSpecWorkspaceResolutionExecutionLogsResolver) Entries() []graphqlbackend.ExecutionLogEntryResolver {
+	resolvers := make([]graphqlbackend.ExecutionLogEntryResolver, 0, len(r.logs.Entries))
+	for _, entry := range r.logs.Entries {
+		resolvers = append(resolvers, graphqlbackend.NewExecutionLogEntryResolver(r.store.DB(), entry))
+	}
+	return resolvers
+}
+
+func (r *batchSpecWorkspaceResolutionExecutionLogsResolver) NextOffset() int32 {
+	next := r.logs.Offset + len(r.logs.Entries)
+	// The output of an entry that hasn't finished yet may still grow, so
+	// it needs to be fetched again.
+	if n := len(r.logs.Entries); n > 0 && r.logs.Entries[n-1].ExitCode == nil {
+		next--
+	}
+	return int32(next)
+}
+
+func (r *batchSpecWorkspaceResolutionExecutionLogsResolver) Finished() bool {
+	return resolutionFinished(r.logs.State)
+}
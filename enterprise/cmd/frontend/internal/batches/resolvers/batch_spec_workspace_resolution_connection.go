@@ -0,0 +1,60 @@
+package resolvers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+type batchSpecWorkspaceResolutionConnectionResolver struct {
+	store *store.Store
+	opts  store.ListNamespaceBatchSpecResolutionJobsOpts
+
+	// Cache results because they are used by multiple fields.
+	once sync.Once
+	jobs []*btypes.BatchSpecResolutionJob
+	next int64
+	err  error
+}
+
+var _ graphqlbackend.BatchSpecWorkspaceResolutionConnectionResolver = &batchSpecWorkspaceResolutionConnectionResolver{}
+
+func (r *batchSpecWorkspaceResolutionConnectionResolver) Nodes(ctx context.Context) ([]graphqlbackend.BatchSpecWorkspaceResolutionResolver, error) {
+	nodes, _, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.BatchSpecWorkspaceResolutionResolver, 0, len(nodes))
+	for _, j := range nodes {
+		resolvers = append(resolvers, &batchSpecWorkspaceResolutionResolver{store: r.store, resolution: j})
+	}
+	return resolvers, nil
+}
+
+func (r *batchSpecWorkspaceResolutionConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := r.store.CountNamespaceBatchSpecResolutionJobs(ctx, r.opts)
+	return int32(count), err
+}
+
+func (r *batchSpecWorkspaceResolutionConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	_, next, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if next != 0 {
+		return graphqlutil.NextPageCursor(strconv.Itoa(int(next))), nil
+	}
+	return graphqlutil.HasNextPage(false), nil
+}
+
+func (r *batchSpecWorkspaceResolutionConnectionResolver) compute(ctx context.Context) ([]*btypes.BatchSpecResolutionJob, int64, error) {
+	r.once.Do(func() {
+		r.jobs, r.next, r.err = r.store.ListNamespaceBatchSpecResolutionJobs(ctx, r.opts)
+	})
+	return r.jobs, r.next, r.err
+}
@@ -702,6 +702,12 @@ func TestPermissionLevels(t *testing.T) {
 					return fmt.Sprintf(`mutation { closeBatchChange(batchChange: %q, closeChangesets: false) { id } }`, batchChangeID)
 				},
 			},
+			{
+				name: "setBatchChangeAutoRebase",
+				mutationFunc: func(batchChangeID, changesetID, batchSpecID string) string {
+					return fmt.Sprintf(`mutation { setBatchChangeAutoRebase(batchChange: %q, autoRebase: true) { id } }`, batchChangeID)
+				},
+			},
 			{
 				name: "deleteBatchChange",
 				mutationFunc: func(batchChangeID, changesetID, batchSpecID string) string {
@@ -750,6 +756,12 @@ func TestPermissionLevels(t *testing.T) {
 					return fmt.Sprintf(`mutation { mergeChangesets(batchChange: %q, changesets: [%q]) { id } }`, batchChangeID, changesetID)
 				},
 			},
+			{
+				name: "mergeChangesetsWhenChecksPass",
+				mutationFunc: func(batchChangeID, changesetID, batchSpecID string) string {
+					return fmt.Sprintf(`mutation { mergeChangesetsWhenChecksPass(batchChange: %q, changesets: [%q]) { id } }`, batchChangeID, changesetID)
+				},
+			},
 			{
 				name: "closeChangesets",
 				mutationFunc: func(batchChangeID, changesetID, batchSpecID string) string {
@@ -1184,6 +1196,38 @@ func TestRepositoryPermissions(t *testing.T) {
 			}
 		}
 	})
+	t.Run("BatchSpecWorkspaceResolution repositoryErrors", func(t *testing.T) {
+		resolution := &btypes.BatchSpecResolutionJob{
+			RepositoryErrors: []*btypes.BatchSpecResolutionRepositoryError{
+				{RepoID: repos[0].ID, RepoName: string(repos[0].Name), Message: "filtered"},
+				{RepoID: repos[1].ID, RepoName: string(repos[1].Name), Message: "accessible"},
+				{RepoName: "github.com/sourcegraph/not-found", Message: "not found"},
+			},
+		}
+		r := &batchSpecWorkspaceResolutionResolver{store: cstore, resolution: resolution}
+
+		// Only the repository of the second error is accessible.
+		ct.MockRepoPermissions(t, db, userID, repos[1].ID)
+		userCtx := actor.WithActor(ctx, actor.FromUser(userID))
+
+		errs, err := r.RepositoryErrors(userCtx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, e := range errs {
+			have = append(have, e.Message())
+		}
+		if diff := cmp.Diff([]string{"accessible", "not found"}, have); diff != "" {
+			t.Fatalf("wrong repository errors (-want +got):\n%s", diff)
+		}
+		if errs[0].Repository() == nil {
+			t.Fatal("accessible repository not returned")
+		}
+		if errs[1].Repository() != nil {
+			t.Fatal("repository returned for error without repository")
+		}
+	})
 }
 
 type wantBatchChangeResponse struct {
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/graph-gophers/graphql-go"
@@ -944,6 +946,207 @@ func (r *Resolver) CloseBatchChange(ctx context.Context, args *graphqlbackend.Cl
 	return &batchChangeResolver{store: r.store, batchChange: batchChange}, nil
 }
 
+func (r *Resolver) SetBatchChangeAutoRebase(ctx context.Context, args *graphqlbackend.SetBatchChangeAutoRebaseArgs) (_ graphqlbackend.BatchChangeResolver, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.SetBatchChangeAutoRebase", fmt.Sprintf("BatchChange: %q, AutoRebase: %t", args.BatchChange, args.AutoRebase))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchChangeID, err := unmarshalBatchChangeID(args.BatchChange)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling batch change id")
+	}
+
+	if batchChangeID == 0 {
+		return nil, ErrIDIsZero{}
+	}
+
+	svc := service.New(r.store)
+	// 🚨 SECURITY: SetBatchChangeAutoRebase checks whether current user is authorized.
+	batchChange, err := svc.SetBatchChangeAutoRebase(ctx, batchChangeID, args.AutoRebase)
+	if err != nil {
+		return nil, errors.Wrap(err, "setting auto-rebase of batch change")
+	}
+
+	return &batchChangeResolver{store: r.store, batchChange: batchChange}, nil
+}
+
+func (r *Resolver) RollbackBatchChange(ctx context.Context, args *graphqlbackend.RollbackBatchChangeArgs) (_ graphqlbackend.BatchChangeRollbackJobResolver, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.RollbackBatchChange", fmt.Sprintf("BatchChange: %q", args.BatchChange))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchChangeID, err := unmarshalBatchChangeID(args.BatchChange)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling batch change id")
+	}
+
+	if batchChangeID == 0 {
+		return nil, ErrIDIsZero{}
+	}
+
+	svc := service.New(r.store)
+	// 🚨 SECURITY: RollbackBatchChange checks whether current user is authorized.
+	job, err := svc.RollbackBatchChange(ctx, batchChangeID)
+	if err != nil {
+		return nil, errors.Wrap(err, "rolling back batch change")
+	}
+
+	return &batchChangeRollbackJobResolver{store: r.store, job: job}, nil
+}
+
+func (r *Resolver) ScheduleBatchSpecExecution(ctx context.Context, args *graphqlbackend.ScheduleBatchSpecExecutionArgs) (_ graphqlbackend.BatchSpecExecutionScheduleResolver, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.ScheduleBatchSpecExecution", fmt.Sprintf("BatchChange: %q, IntervalSeconds: %d", args.BatchChange, args.IntervalSeconds))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchChangeID, err := unmarshalBatchChangeID(args.BatchChange)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling batch change id")
+	}
+
+	if batchChangeID == 0 {
+		return nil, ErrIDIsZero{}
+	}
+
+	svc := service.New(r.store)
+	// 🚨 SECURITY: ScheduleBatchSpecExecution checks whether current user is authorized.
+	schedule, err := svc.ScheduleBatchSpecExecution(ctx, service.ScheduleBatchSpecExecutionOpts{
+		BatchChangeID: batchChangeID,
+		Interval:      time.Duration(args.IntervalSeconds) * time.Second,
+		Enabled:       args.Enabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchSpecExecutionScheduleResolver{store: r.store, schedule: schedule}, nil
+}
+
+func (r *Resolver) DeleteBatchSpecExecutionSchedule(ctx context.Context, args *graphqlbackend.DeleteBatchSpecExecutionScheduleArgs) (_ *graphqlbackend.EmptyResponse, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.DeleteBatchSpecExecutionSchedule", fmt.Sprintf("BatchChange: %q", args.BatchChange))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchChangeID, err := unmarshalBatchChangeID(args.BatchChange)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling batch change id")
+	}
+
+	if batchChangeID == 0 {
+		return nil, ErrIDIsZero{}
+	}
+
+	svc := service.New(r.store)
+	// 🚨 SECURITY: DeleteBatchSpecExecutionSchedule checks whether current user is authorized.
+	if err := svc.DeleteBatchSpecExecutionSchedule(ctx, batchChangeID); err != nil {
+		return nil, err
+	}
+
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+func (r *Resolver) InvalidateBatchSpecExecutionCache(ctx context.Context, args *graphqlbackend.InvalidateBatchSpecExecutionCacheArgs) (_ *graphqlbackend.EmptyResponse, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.InvalidateBatchSpecExecutionCache", fmt.Sprintf("BatchSpec: %q", args.BatchSpec))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchSpecRandID, err := unmarshalBatchSpecID(args.BatchSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling batch spec id")
+	}
+
+	if batchSpecRandID == "" {
+		return nil, ErrIDIsZero{}
+	}
+
+	svc := service.New(r.store)
+	// 🚨 SECURITY: InvalidateBatchSpecExecutionCache checks whether current user is authorized.
+	if err := svc.InvalidateBatchSpecExecutionCache(ctx, batchSpecRandID); err != nil {
+		return nil, err
+	}
+
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+func (r *Resolver) InvalidateUserBatchSpecExecutionCache(ctx context.Context, args *graphqlbackend.InvalidateUserBatchSpecExecutionCacheArgs) (_ *graphqlbackend.EmptyResponse, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.InvalidateUserBatchSpecExecutionCache", fmt.Sprintf("User: %q", args.User))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	userID, err := graphqlbackend.UnmarshalUserID(args.User)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling user id")
+	}
+
+	if userID == 0 {
+		return nil, ErrIDIsZero{}
+	}
+
+	svc := service.New(r.store)
+	// 🚨 SECURITY: InvalidateUserExecutionCache checks whether current user is authorized.
+	if err := svc.InvalidateUserExecutionCache(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
 func (r *Resolver) SyncChangeset(ctx context.Context, args *graphqlbackend.SyncChangesetArgs) (_ *graphqlbackend.EmptyResponse, err error) {
 	tr, ctx := trace.New(ctx, "Resolver.SyncChangeset", fmt.Sprintf("Changeset: %q", args.Changeset))
 	defer func() {
@@ -1033,14 +1236,27 @@ func (r *Resolver) CreateBatchChangesCredential(ctx context.Context, args *graph
 		return nil, errors.New("empty credential not allowed")
 	}
 
+	var username string
+	if args.Username != nil {
+		username = *args.Username
+	}
+	if username == "" {
+		switch kind {
+		case extsvc.KindGerrit:
+			return nil, errors.New("username required for Gerrit credentials")
+		case extsvc.KindBitbucketCloud:
+			return nil, errors.New("username required for Bitbucket Cloud credentials")
+		}
+	}
+
 	if userID != 0 {
-		return r.createBatchChangesUserCredential(ctx, args.ExternalServiceURL, extsvc.KindToType(kind), userID, args.Credential)
+		return r.createBatchChangesUserCredential(ctx, args.ExternalServiceURL, extsvc.KindToType(kind), userID, username, args.Credential)
 	}
 
-	return r.createBatchChangesSiteCredential(ctx, args.ExternalServiceURL, extsvc.KindToType(kind), args.Credential)
+	return r.createBatchChangesSiteCredential(ctx, args.ExternalServiceURL, extsvc.KindToType(kind), username, args.Credential)
 }
 
-func (r *Resolver) createBatchChangesUserCredential(ctx context.Context, externalServiceURL, externalServiceType string, userID int32, credential string) (graphqlbackend.BatchChangesCredentialResolver, error) {
+func (r *Resolver) createBatchChangesUserCredential(ctx context.Context, externalServiceURL, externalServiceType string, userID int32, username, credential string) (graphqlbackend.BatchChangesCredentialResolver, error) {
 	// 🚨 SECURITY: Check that the requesting user can create the credential.
 	if err := backend.CheckSiteAdminOrSameUser(ctx, r.store.DB(), userID); err != nil {
 		return nil, err
@@ -1061,7 +1277,7 @@ func (r *Resolver) createBatchChangesUserCredential(ctx context.Context, externa
 		return nil, ErrDuplicateCredential{}
 	}
 
-	a, err := r.generateAuthenticatorForCredential(ctx, externalServiceType, externalServiceURL, credential)
+	a, err := r.generateAuthenticatorForCredential(ctx, externalServiceType, externalServiceURL, username, credential)
 	if err != nil {
 		return nil, err
 	}
@@ -1073,7 +1289,7 @@ func (r *Resolver) createBatchChangesUserCredential(ctx context.Context, externa
 	return &batchChangesUserCredentialResolver{credential: cred}, nil
 }
 
-func (r *Resolver) createBatchChangesSiteCredential(ctx context.Context, externalServiceURL, externalServiceType string, credential string) (graphqlbackend.BatchChangesCredentialResolver, error) {
+func (r *Resolver) createBatchChangesSiteCredential(ctx context.Context, externalServiceURL, externalServiceType, username, credential string) (graphqlbackend.BatchChangesCredentialResolver, error) {
 	// 🚨 SECURITY: Check that a site credential can only be created
 	// by a site-admin.
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
@@ -1092,7 +1308,7 @@ func (r *Resolver) createBatchChangesSiteCredential(ctx context.Context, externa
 		return nil, ErrDuplicateCredential{}
 	}
 
-	a, err := r.generateAuthenticatorForCredential(ctx, externalServiceType, externalServiceURL, credential)
+	a, err := r.generateAuthenticatorForCredential(ctx, externalServiceType, externalServiceURL, username, credential)
 	if err != nil {
 		return nil, err
 	}
@@ -1107,7 +1323,7 @@ func (r *Resolver) createBatchChangesSiteCredential(ctx context.Context, externa
 	return &batchChangesSiteCredentialResolver{credential: cred}, nil
 }
 
-func (r *Resolver) generateAuthenticatorForCredential(ctx context.Context, externalServiceType, externalServiceURL, credential string) (auth.Authenticator, error) {
+func (r *Resolver) generateAuthenticatorForCredential(ctx context.Context, externalServiceType, externalServiceURL, username, credential string) (auth.Authenticator, error) {
 	svc := service.New(r.store)
 
 	var a auth.Authenticator
@@ -1115,7 +1331,8 @@ func (r *Resolver) generateAuthenticatorForCredential(ctx context.Context, exter
 	if err != nil {
 		return nil, err
 	}
-	if externalServiceType == extsvc.TypeBitbucketServer {
+	switch externalServiceType {
+	case extsvc.TypeBitbucketServer:
 		// We need to fetch the username for the token, as just an OAuth token isn't enough for some reason..
 		username, err := svc.FetchUsernameForBitbucketServerToken(ctx, externalServiceURL, externalServiceType, credential)
 		if err != nil {
@@ -1130,7 +1347,17 @@ func (r *Resolver) generateAuthenticatorForCredential(ctx context.Context, exter
 			PublicKey:  keypair.PublicKey,
 			Passphrase: keypair.Passphrase,
 		}
-	} else {
+	case extsvc.TypeGerrit, extsvc.TypeBitbucketCloud:
+		// Gerrit HTTP passwords and Bitbucket Cloud app passwords can only be
+		// used together with the username of their user, which can't be
+		// looked up with the password alone.
+		a = &auth.BasicAuthWithSSH{
+			BasicAuth:  auth.BasicAuth{Username: username, Password: credential},
+			PrivateKey: keypair.PrivateKey,
+			PublicKey:  keypair.PublicKey,
+			Passphrase: keypair.Passphrase,
+		}
+	default:
 		a = &auth.OAuthBearerTokenWithSSH{
 			OAuthBearerToken: auth.OAuthBearerToken{Token: credential},
 			PrivateKey:       keypair.PrivateKey,
@@ -1358,6 +1585,44 @@ func (r *Resolver) MergeChangesets(ctx context.Context, args *graphqlbackend.Mer
 	return r.bulkOperationByIDString(ctx, bulkGroupID)
 }
 
+func (r *Resolver) MergeChangesetsWhenChecksPass(ctx context.Context, args *graphqlbackend.MergeChangesetsWhenChecksPassArgs) (_ graphqlbackend.BulkOperationResolver, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.MergeChangesetsWhenChecksPass", fmt.Sprintf("BatchChange: %q, len(Changesets): %d", args.BatchChange, len(args.Changesets)))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchChangeID, changesetIDs, err := unmarshalBulkOperationBaseArgs(args.BulkOperationBaseArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: CreateChangesetJobs checks whether current user is authorized.
+	svc := service.New(r.store)
+	published := btypes.ChangesetPublicationStatePublished
+	openState := btypes.ChangesetExternalStateOpen
+	bulkGroupID, err := svc.CreateChangesetJobs(
+		ctx,
+		batchChangeID,
+		changesetIDs,
+		btypes.ChangesetJobTypeMergeWhenChecksPass,
+		&btypes.ChangesetJobMergeWhenChecksPassPayload{Squash: args.Squash},
+		store.ListChangesetsOpts{
+			PublicationState: &published,
+			ReconcilerStates: []btypes.ReconcilerState{btypes.ReconcilerStateCompleted},
+			ExternalStates:   []btypes.ChangesetExternalState{openState},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.bulkOperationByIDString(ctx, bulkGroupID)
+}
+
 func (r *Resolver) CloseChangesets(ctx context.Context, args *graphqlbackend.CloseChangesetsArgs) (_ graphqlbackend.BulkOperationResolver, err error) {
 	tr, ctx := trace.New(ctx, "Resolver.CloseChangesets", fmt.Sprintf("BatchChange: %q, len(Changesets): %d", args.BatchChange, len(args.Changesets)))
 	defer func() {
@@ -1453,6 +1718,207 @@ func (r *Resolver) BatchSpecs(ctx context.Context, args *graphqlbackend.ListBatc
 	return &batchSpecConnectionResolver{store: r.store, opts: opts}, nil
 }
 
+func (r *Resolver) BatchChangesAuditEvents(ctx context.Context, args *graphqlbackend.ListBatchChangesAuditEventsArgs) (graphqlbackend.BatchChangesAuditEventConnectionResolver, error) {
+	// 🚨 SECURITY: Only site admins can see the audit log.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	if err := validateFirstParamDefaults(args.First); err != nil {
+		return nil, err
+	}
+	opts := store.ListAuditEventsOpts{
+		LimitOpts: store.LimitOpts{
+			Limit: int(args.First),
+		},
+	}
+	if args.After != nil {
+		id, err := strconv.Atoi(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = int64(id)
+	}
+	if args.Actor != nil {
+		userID, err := graphqlbackend.UnmarshalUserID(*args.Actor)
+		if err != nil {
+			return nil, err
+		}
+		opts.ActorUserID = userID
+	}
+	if args.Action != nil {
+		action := btypes.AuditEventAction(*args.Action)
+		if !action.Valid() {
+			return nil, errors.Errorf("unknown action %q", *args.Action)
+		}
+		opts.Action = action
+	}
+	if args.ResourceType != nil {
+		resourceType := btypes.AuditEventResourceType(*args.ResourceType)
+		if !resourceType.Valid() {
+			return nil, errors.Errorf("unknown resource type %q", *args.ResourceType)
+		}
+		opts.ResourceType = resourceType
+	}
+
+	return &auditEventConnectionResolver{store: r.store, opts: opts}, nil
+}
+
+// BatchSpecExecution sends the batch spec when the subscription starts and whenever the state of
+// its workspace resolution or of the execution of one of its workspaces changes.
+func (r *Resolver) BatchSpecExecution(ctx context.Context, args *graphqlbackend.BatchSpecExecutionArgs) (<-chan graphqlbackend.BatchSpecResolver, error) {
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	batchSpecRandID, err := unmarshalBatchSpecID(args.BatchSpec)
+	if err != nil {
+		return nil, err
+	}
+	batchSpec, err := r.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{RandID: batchSpecRandID})
+	if err != nil {
+		if err == store.ErrNoResults {
+			return nil, errors.Errorf("batch spec not found: %q", args.BatchSpec)
+		}
+		return nil, err
+	}
+
+	c := make(chan graphqlbackend.BatchSpecResolver)
+	go func() {
+		defer close(c)
+		graphqlbackend.PollSubscription(ctx, func(ctx context.Context) (string, error) {
+			return r.batchSpecExecutionFingerprint(ctx, batchSpec.ID)
+		}, func() bool {
+			// Send a new resolver each time, since the resolver caches the state of the batch spec.
+			select {
+			case c <- &batchSpecResolver{store: r.store, batchSpec: batchSpec}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return c, nil
+}
+
+// batchSpecExecutionFingerprint returns a string that changes whenever the state of the workspace
+// resolution or of a workspace execution of the batch spec changes.
+func (r *Resolver) batchSpecExecutionFingerprint(ctx context.Context, batchSpecID int64) (string, error) {
+	var b strings.Builder
+
+	job, err := r.store.GetBatchSpecResolutionJob(ctx, store.GetBatchSpecResolutionJobOpts{BatchSpecID: batchSpecID})
+	if err != nil && err != store.ErrNoResults {
+		return "", err
+	}
+	if job != nil {
+		fmt.Fprintf(&b, "resolution:%s:%d ", job.State, job.UpdatedAt.UnixNano())
+	}
+
+	workspaces, _, err := r.store.ListBatchSpecWorkspaces(ctx, store.ListBatchSpecWorkspacesOpts{BatchSpecID: batchSpecID})
+	if err != nil {
+		return "", err
+	}
+	if len(workspaces) == 0 {
+		return b.String(), nil
+	}
+	ids := make([]int64, 0, len(workspaces))
+	for _, w := range workspaces {
+		ids = append(ids, w.ID)
+	}
+	jobs, err := r.store.ListBatchSpecWorkspaceExecutionJobs(ctx, store.ListBatchSpecWorkspaceExecutionJobsOpts{BatchSpecWorkspaceIDs: ids})
+	if err != nil {
+		return "", err
+	}
+	for _, j := range jobs {
+		fmt.Fprintf(&b, "%d:%s:%d ", j.ID, j.State, j.UpdatedAt.UnixNano())
+	}
+	return b.String(), nil
+}
+
+func (r *Resolver) BatchSpecResolutionJobs(ctx context.Context, args *graphqlbackend.ListBatchSpecResolutionJobsArgs) (graphqlbackend.BatchSpecWorkspaceResolutionConnectionResolver, error) {
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	if err := validateFirstParamDefaults(args.First); err != nil {
+		return nil, err
+	}
+	opts := store.ListNamespaceBatchSpecResolutionJobsOpts{
+		LimitOpts: store.LimitOpts{
+			Limit: int(args.First),
+		},
+	}
+	if args.After != nil {
+		id, err := strconv.Atoi(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = int64(id)
+	}
+
+	if err := graphqlbackend.UnmarshalNamespaceID(args.Namespace, &opts.NamespaceUserID, &opts.NamespaceOrgID); err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only site admins, the user themselves or members of the
+	// org can see the resolution jobs in a namespace.
+	if opts.NamespaceUserID != 0 {
+		if err := backend.CheckSiteAdminOrSameUser(ctx, r.store.DB(), opts.NamespaceUserID); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := backend.CheckOrgAccessOrSiteAdmin(ctx, r.store.DB(), opts.NamespaceOrgID); err != nil {
+			return nil, err
+		}
+	}
+
+	if args.State != nil {
+		state := btypes.BatchSpecResolutionJobState(strings.ToLower(*args.State))
+		if !state.Valid() {
+			return nil, errors.Errorf("unknown state %q", *args.State)
+		}
+		opts.State = state
+	}
+	if args.CreatedAfter != nil {
+		opts.CreatedAfter = args.CreatedAfter.Time
+	}
+	if args.CreatedBefore != nil {
+		opts.CreatedBefore = args.CreatedBefore.Time
+	}
+
+	return &batchSpecWorkspaceResolutionConnectionResolver{store: r.store, opts: opts}, nil
+}
+
+func (r *Resolver) BatchSpecResolutionQuota(ctx context.Context, args *graphqlbackend.BatchSpecResolutionQuotaArgs) (graphqlbackend.BatchSpecResolutionQuotaResolver, error) {
+	if err := enterprise.BatchChangesEnabledForUser(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	var opts store.GetBatchSpecResolutionQuotaUsageOpts
+	if err := graphqlbackend.UnmarshalNamespaceID(args.Namespace, &opts.NamespaceUserID, &opts.NamespaceOrgID); err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only site admins, the user themselves or members of the
+	// org can see the quota usage of a namespace.
+	if opts.NamespaceUserID != 0 {
+		if err := backend.CheckSiteAdminOrSameUser(ctx, r.store.DB(), opts.NamespaceUserID); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := backend.CheckOrgAccessOrSiteAdmin(ctx, r.store.DB(), opts.NamespaceOrgID); err != nil {
+			return nil, err
+		}
+	}
+
+	usage, err := r.store.GetBatchSpecResolutionQuotaUsage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchSpecResolutionQuotaResolver{quota: btypes.CurrentBatchSpecResolutionQuota(), usage: usage}, nil
+}
+
 func (r *Resolver) CreateBatchSpecFromRaw(ctx context.Context, args *graphqlbackend.CreateBatchSpecFromRawArgs) (graphqlbackend.BatchSpecResolver, error) {
 	// TODO(ssbc): currently admin only.
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
@@ -1465,6 +1931,9 @@ func (r *Resolver) CreateBatchSpecFromRaw(ctx context.Context, args *graphqlback
 		RawSpec:          args.BatchSpec,
 		AllowIgnored:     args.AllowIgnored,
 		AllowUnsupported: args.AllowUnsupported,
+		// Raw batch specs are created from the batch spec editor, where the
+		// user waits for the workspaces to be resolved.
+		Priority: btypes.BatchSpecResolutionJobPriorityInteractive,
 	})
 	if err != nil {
 		return nil, err
@@ -1473,6 +1942,26 @@ func (r *Resolver) CreateBatchSpecFromRaw(ctx context.Context, args *graphqlback
 	return &batchSpecResolver{store: r.store, batchSpec: batchSpec}, nil
 }
 
+func (r *Resolver) ImportBatchSpec(ctx context.Context, args *graphqlbackend.ImportBatchSpecArgs) (graphqlbackend.BatchSpecResolver, error) {
+	// TODO(ssbc): currently admin only.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
+		return nil, err
+	}
+
+	opts := service.ImportBatchSpecOpts{Archive: args.Archive}
+	if err := graphqlbackend.UnmarshalNamespaceID(args.Namespace, &opts.NamespaceUserID, &opts.NamespaceOrgID); err != nil {
+		return nil, err
+	}
+
+	svc := service.New(r.store)
+	batchSpec, err := svc.ImportBatchSpec(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchSpecResolver{store: r.store, batchSpec: batchSpec}, nil
+}
+
 func (r *Resolver) DeleteBatchSpec(ctx context.Context, args *graphqlbackend.DeleteBatchSpecArgs) (*graphqlbackend.EmptyResponse, error) {
 	// TODO(ssbc): currently admin only.
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.store.DB()); err != nil {
@@ -1499,7 +1988,8 @@ func (r *Resolver) ExecuteBatchSpec(ctx context.Context, args *graphqlbackend.Ex
 	svc := service.New(r.store)
 	batchSpec, err := svc.ExecuteBatchSpec(ctx, service.ExecuteBatchSpecOpts{
 		BatchSpecRandID: batchSpecRandID,
-		// TODO: args not yet implemented: NoCache, AutoApply
+		NoCache:         args.NoCache,
+		// TODO: args not yet implemented: AutoApply
 	})
 	if err != nil {
 		return nil, err
@@ -1583,6 +2073,7 @@ func (r *Resolver) ReplaceBatchSpecInput(ctx context.Context, args *graphqlbacke
 		RawSpec:          args.BatchSpec,
 		AllowIgnored:     args.AllowIgnored,
 		AllowUnsupported: args.AllowUnsupported,
+		Priority:         btypes.BatchSpecResolutionJobPriorityInteractive,
 	})
 	if err != nil {
 		return nil, err
@@ -72,6 +72,7 @@ func TestNullIDResilience(t *testing.T) {
 
 	mutations := []string{
 		fmt.Sprintf(`mutation { closeBatchChange(batchChange: %q) { id } }`, marshalBatchChangeID(0)),
+		fmt.Sprintf(`mutation { setBatchChangeAutoRebase(batchChange: %q, autoRebase: true) { id } }`, marshalBatchChangeID(0)),
 		fmt.Sprintf(`mutation { deleteBatchChange(batchChange: %q) { alwaysNil } }`, marshalBatchChangeID(0)),
 		fmt.Sprintf(`mutation { syncChangeset(changeset: %q) { alwaysNil } }`, marshalChangesetID(0)),
 		fmt.Sprintf(`mutation { reenqueueChangeset(changeset: %q) { id } }`, marshalChangesetID(0)),
@@ -87,6 +88,8 @@ func TestNullIDResilience(t *testing.T) {
 		fmt.Sprintf(`mutation { reenqueueChangesets(batchChange: %q, changesets: [%q]) { id } }`, marshalBatchChangeID(1), marshalChangesetID(0)),
 		fmt.Sprintf(`mutation { mergeChangesets(batchChange: %q, changesets: []) { id } }`, marshalBatchChangeID(0)),
 		fmt.Sprintf(`mutation { mergeChangesets(batchChange: %q, changesets: [%q]) { id } }`, marshalBatchChangeID(1), marshalChangesetID(0)),
+		fmt.Sprintf(`mutation { mergeChangesetsWhenChecksPass(batchChange: %q, changesets: []) { id } }`, marshalBatchChangeID(0)),
+		fmt.Sprintf(`mutation { mergeChangesetsWhenChecksPass(batchChange: %q, changesets: [%q]) { id } }`, marshalBatchChangeID(1), marshalChangesetID(0)),
 		fmt.Sprintf(`mutation { closeChangesets(batchChange: %q, changesets: []) { id } }`, marshalBatchChangeID(0)),
 		fmt.Sprintf(`mutation { closeChangesets(batchChange: %q, changesets: [%q]) { id } }`, marshalBatchChangeID(1), marshalChangesetID(0)),
 		fmt.Sprintf(`mutation { publishChangesets(batchChange: %q, changesets: []) { id } }`, marshalBatchChangeID(0)),
@@ -1592,6 +1595,79 @@ mutation($batchChange: ID!, $changesets: [ID!]!, $squash: Boolean = false) {
 }
 `
 
+func TestMergeChangesetsWhenChecksPass(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+	cstore := store.New(db, &observation.TestContext, nil)
+
+	userID := ct.CreateTestUser(t, db, true).ID
+	batchSpec := ct.CreateBatchSpec(t, ctx, cstore, "test-merge-checks", userID)
+	batchChange := ct.CreateBatchChange(t, ctx, cstore, "test-merge-checks", userID, batchSpec.ID)
+	repo, _ := ct.CreateTestRepo(t, ctx, db)
+	changeset := ct.CreateChangeset(t, ctx, cstore, ct.TestChangesetOpts{
+		Repo:             repo.ID,
+		BatchChange:      batchChange.ID,
+		PublicationState: btypes.ChangesetPublicationStatePublished,
+		ReconcilerState:  btypes.ReconcilerStateCompleted,
+		ExternalState:    btypes.ChangesetExternalStateOpen,
+	})
+	mergedChangeset := ct.CreateChangeset(t, ctx, cstore, ct.TestChangesetOpts{
+		Repo:             repo.ID,
+		BatchChange:      batchChange.ID,
+		PublicationState: btypes.ChangesetPublicationStatePublished,
+		ReconcilerState:  btypes.ReconcilerStateCompleted,
+		ExternalState:    btypes.ChangesetExternalStateMerged,
+	})
+
+	r := &Resolver{store: cstore}
+	s, err := graphqlbackend.NewSchema(db, r, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response struct {
+		MergeChangesetsWhenChecksPass apitest.BulkOperation
+	}
+	actorCtx := actor.WithActor(ctx, actor.FromUser(userID))
+
+	t.Run("merged changeset fails", func(t *testing.T) {
+		input := map[string]interface{}{
+			"batchChange": marshalBatchChangeID(batchChange.ID),
+			"changesets":  []string{string(marshalChangesetID(mergedChangeset.ID))},
+		}
+		errs := apitest.Exec(actorCtx, t, s, input, &response, mutationMergeChangesetsWhenChecksPass)
+
+		if len(errs) != 1 {
+			t.Fatalf("expected single errors, but got none")
+		}
+		if have, want := errs[0].Message, "some changesets could not be found"; have != want {
+			t.Fatalf("wrong error. want=%q, have=%q", want, have)
+		}
+	})
+
+	t.Run("runs successfully", func(t *testing.T) {
+		input := map[string]interface{}{
+			"batchChange": marshalBatchChangeID(batchChange.ID),
+			"changesets":  []string{string(marshalChangesetID(changeset.ID))},
+		}
+		apitest.MustExec(actorCtx, t, s, input, &response, mutationMergeChangesetsWhenChecksPass)
+
+		if have, want := response.MergeChangesetsWhenChecksPass.Type, "MERGE_WHEN_CHECKS_PASS"; have != want {
+			t.Fatalf("wrong bulk operation type. want=%q, have=%q", want, have)
+		}
+	})
+}
+
+const mutationMergeChangesetsWhenChecksPass = `
+mutation($batchChange: ID!, $changesets: [ID!]!, $squash: Boolean = false) {
+    mergeChangesetsWhenChecksPass(batchChange: $batchChange, changesets: $changesets, squash: $squash) { id type }
+}
+`
+
 func TestCloseChangesets(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -1820,3 +1896,44 @@ mutation($batchChange: ID!, $changesets: [ID!]!, $draft: Boolean!) {
 `
 
 func stringPtr(s string) *string { return &s }
+
+func TestSetBatchChangeAutoRebase(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+	cstore := store.New(db, &observation.TestContext, nil)
+
+	userID := ct.CreateTestUser(t, db, true).ID
+	batchSpec := ct.CreateBatchSpec(t, ctx, cstore, "test-auto-rebase", userID)
+	batchChange := ct.CreateBatchChange(t, ctx, cstore, "test-auto-rebase", userID, batchSpec.ID)
+
+	r := &Resolver{store: cstore}
+	s, err := graphqlbackend.NewSchema(db, r, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actorCtx := actor.WithActor(ctx, actor.FromUser(userID))
+
+	for _, autoRebase := range []bool{true, false} {
+		var response struct{ SetBatchChangeAutoRebase apitest.BatchChange }
+		input := map[string]interface{}{
+			"batchChange": marshalBatchChangeID(batchChange.ID),
+			"autoRebase":  autoRebase,
+		}
+		apitest.MustExec(actorCtx, t, s, input, &response, mutationSetBatchChangeAutoRebase)
+
+		if have, want := response.SetBatchChangeAutoRebase.AutoRebase, autoRebase; have != want {
+			t.Fatalf("wrong autoRebase. want=%t, have=%t", want, have)
+		}
+	}
+}
+
+const mutationSetBatchChangeAutoRebase = `
+mutation($batchChange: ID!, $autoRebase: Boolean!) {
+    setBatchChangeAutoRebase(batchChange: $batchChange, autoRebase: $autoRebase) { id autoRebase }
+}
+`
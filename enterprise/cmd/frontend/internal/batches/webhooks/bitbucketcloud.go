@@ -0,0 +1,225 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type BitbucketCloudWebhook struct {
+	*Webhook
+}
+
+func NewBitbucketCloudWebhook(store *store.Store) *BitbucketCloudWebhook {
+	return &BitbucketCloudWebhook{
+		Webhook: &Webhook{store, extsvc.TypeBitbucketCloud},
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *BitbucketCloudWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	extSvc, err := h.getExternalServiceFromRawID(r.Context(), r.FormValue(extsvc.IDParam))
+	if err == errExternalServiceNotFound {
+		respond(w, http.StatusUnauthorized, err)
+		return
+	} else if err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "getting external service"))
+		return
+	}
+
+	// 🚨 SECURITY: Bitbucket Cloud doesn't sign webhook payloads, so the
+	// shared secret is passed as a query parameter of the webhook URL. If
+	// there isn't a webhook defined in the service with this secret, or the
+	// parameter is empty, then we return a 401 to the client.
+	if ok, err := validateBitbucketCloudSecret(extSvc, r.URL.Query().Get("secret")); err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "validating the shared secret"))
+		return
+	} else if !ok {
+		respond(w, http.StatusUnauthorized, "shared secret is incorrect")
+		return
+	}
+
+	if r.Body == nil {
+		respond(w, http.StatusBadRequest, "missing request body")
+		return
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "reading payload"))
+		return
+	}
+
+	e, err := bitbucketcloud.ParseWebhookEvent(bitbucketcloud.WebhookEventType(r), payload)
+	if err != nil {
+		// Webhooks can be configured to send events we don't handle. We don't
+		// want Bitbucket Cloud to retry them, so we return 204.
+		log15.Debug("cannot handle Bitbucket Cloud webhook event", "err", err)
+		respond(w, http.StatusNoContent, nil)
+		return
+	}
+
+	externalServiceID, err := extractExternalServiceID(extSvc)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	pr, ev, err := h.convertEvent(r.Context(), externalServiceID, e)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, err)
+		return
+	}
+	if pr == (PR{}) {
+		log15.Debug("Dropping Bitbucket Cloud webhook event", "type", fmt.Sprintf("%T", e))
+		respond(w, http.StatusNoContent, nil)
+		return
+	}
+
+	if err := h.upsertChangesetEvent(r.Context(), externalServiceID, pr, ev); err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "upserting changeset event"))
+		return
+	}
+	respond(w, http.StatusNoContent, nil)
+}
+
+// getExternalServiceFromRawID retrieves the Bitbucket Cloud external service
+// matching the given raw ID, or errExternalServiceNotFound.
+func (h *BitbucketCloudWebhook) getExternalServiceFromRawID(ctx context.Context, raw string) (*types.ExternalService, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the raw external service ID")
+	}
+
+	es, err := h.Store.ExternalServices().List(ctx, database.ExternalServicesListOptions{
+		IDs:   []int64{id},
+		Kinds: []string{extsvc.KindBitbucketCloud},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing external services")
+	}
+
+	if len(es) == 0 {
+		return nil, errExternalServiceNotFound
+	} else if len(es) > 1 {
+		return nil, errors.New("too many external services found")
+	}
+
+	return es[0], nil
+}
+
+// convertEvent returns the pull request the given event belongs to and the
+// changeset event to upsert for it. An empty PR is returned if the event
+// can't be matched to a pull request.
+func (h *BitbucketCloudWebhook) convertEvent(ctx context.Context, externalServiceID string, theirs interface{}) (PR, keyer, error) {
+	log15.Debug("Bitbucket Cloud webhook received", "type", fmt.Sprintf("%T", theirs))
+
+	switch e := theirs.(type) {
+	case *bitbucketcloud.PullRequestApprovedEvent:
+		return bitbucketCloudToPR(&e.PullRequestEvent), e, nil
+	case *bitbucketcloud.PullRequestUnapprovedEvent:
+		return bitbucketCloudToPR(&e.PullRequestEvent), e, nil
+	case *bitbucketcloud.PullRequestChangesRequestCreatedEvent:
+		return bitbucketCloudToPR(&e.PullRequestEvent), e, nil
+	case *bitbucketcloud.PullRequestChangesRequestRemovedEvent:
+		return bitbucketCloudToPR(&e.PullRequestEvent), e, nil
+	case *bitbucketcloud.PullRequestFulfilledEvent:
+		return bitbucketCloudToPR(&e.PullRequestEvent), e, nil
+	case *bitbucketcloud.PullRequestRejectedEvent:
+		return bitbucketCloudToPR(&e.PullRequestEvent), e, nil
+	case *bitbucketcloud.RepoCommitStatusEvent:
+		pr, err := h.getPRForCommitStatus(ctx, externalServiceID, e)
+		if err != nil {
+			return PR{}, nil, err
+		}
+		return pr, &bitbucketcloud.CommitStatus{
+			Commit: e.CommitStatus.CommitHash(),
+			Status: e.CommitStatus,
+		}, nil
+	}
+
+	return PR{}, nil, nil
+}
+
+// getPRForCommitStatus matches the given commit status event to a pull
+// request. Commit status events don't reference pull requests, so we look for
+// a changeset on the branch the status was reported for. Statuses without a
+// branch are picked up by the next sync instead.
+func (h *BitbucketCloudWebhook) getPRForCommitStatus(ctx context.Context, externalServiceID string, e *bitbucketcloud.RepoCommitStatusEvent) (PR, error) {
+	if e.CommitStatus.RefName == "" {
+		return PR{}, nil
+	}
+
+	pr := PR{RepoExternalID: e.Repository.UUID}
+	repo, err := h.getRepoForPR(ctx, h.Store, pr, externalServiceID)
+	if err != nil {
+		log15.Debug("Webhook event could not be matched to repo", "err", err)
+		return PR{}, nil
+	}
+
+	cs, err := h.Store.GetChangeset(ctx, store.GetChangesetOpts{
+		RepoID:              repo.ID,
+		ExternalBranch:      git.EnsureRefPrefix(e.CommitStatus.RefName),
+		ExternalServiceType: h.ServiceType,
+	})
+	if err != nil {
+		if err == store.ErrNoResults {
+			return PR{}, nil
+		}
+		return PR{}, errors.Wrap(err, "getting changeset")
+	}
+
+	pr.ID, err = strconv.ParseInt(cs.ExternalID, 10, 64)
+	if err != nil {
+		return PR{}, errors.Wrapf(err, "parsing changeset external ID %s", cs.ExternalID)
+	}
+	return pr, nil
+}
+
+// bitbucketCloudToPR instantiates a new PR instance from the fields that are
+// available in all Bitbucket Cloud pull request webhook payloads.
+func bitbucketCloudToPR(e *bitbucketcloud.PullRequestEvent) PR {
+	return PR{
+		ID:             e.PullRequest.ID,
+		RepoExternalID: e.Repository.UUID,
+	}
+}
+
+// validateBitbucketCloudSecret validates that the given secret matches one of
+// the webhooks in the external service.
+func validateBitbucketCloudSecret(extSvc *types.ExternalService, secret string) (bool, error) {
+	// An empty secret never succeeds.
+	if secret == "" {
+		return false, nil
+	}
+
+	c, err := extSvc.Configuration()
+	if err != nil {
+		return false, errors.Wrap(err, "getting external service configuration")
+	}
+
+	config, ok := c.(*schema.BitbucketCloudConnection)
+	if !ok {
+		return false, errExternalServiceWrongKind
+	}
+
+	for _, webhook := range config.Webhooks {
+		if subtle.ConstantTimeCompare([]byte(webhook.Secret), []byte(secret)) == 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
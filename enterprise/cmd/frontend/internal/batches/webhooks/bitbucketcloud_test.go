@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestValidateBitbucketCloudSecret(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty secret", func(t *testing.T) {
+		ok, err := validateBitbucketCloudSecret(nil, "")
+		if ok {
+			t.Errorf("unexpected ok: %v", ok)
+		}
+		if err != nil {
+			t.Errorf("unexpected non-nil error: %+v", err)
+		}
+	})
+
+	t.Run("not a Bitbucket Cloud connection", func(t *testing.T) {
+		es := &types.ExternalService{Kind: extsvc.KindGitHub}
+		ok, err := validateBitbucketCloudSecret(es, "secret")
+		if ok {
+			t.Errorf("unexpected ok: %v", ok)
+		}
+		if err != errExternalServiceWrongKind {
+			t.Errorf("unexpected error: have %+v; want %+v", err, errExternalServiceWrongKind)
+		}
+	})
+
+	t.Run("valid webhooks", func(t *testing.T) {
+		for secret, want := range map[string]bool{
+			"not secret": false,
+			"secret":     true,
+			"super":      true,
+		} {
+			t.Run(secret, func(t *testing.T) {
+				es := &types.ExternalService{
+					Kind: extsvc.KindBitbucketCloud,
+					Config: ct.MarshalJSON(t, &schema.BitbucketCloudConnection{
+						Webhooks: []*schema.BitbucketCloudWebhook{
+							{Secret: "super"},
+							{Secret: "secret"},
+						},
+					}),
+				}
+
+				ok, err := validateBitbucketCloudSecret(es, secret)
+				if ok != want {
+					t.Errorf("unexpected ok: have %v; want %v", ok, want)
+				}
+				if err != nil {
+					t.Errorf("unexpected non-nil error: %+v", err)
+				}
+			})
+		}
+	})
+}
+
+func TestBitbucketCloudWebhookConvertEvent(t *testing.T) {
+	h := NewBitbucketCloudWebhook(nil)
+
+	e := &bitbucketcloud.PullRequestApprovedEvent{}
+	e.Repository.UUID = "{e1e75436-05e6-4c38-8543-9c36ec26fad1}"
+	e.PullRequest.ID = 7
+
+	pr, ev, err := h.convertEvent(context.Background(), "https://bitbucket.org/", e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PR{ID: 7, RepoExternalID: "{e1e75436-05e6-4c38-8543-9c36ec26fad1}"}); pr != want {
+		t.Errorf("unexpected PR: have %+v; want %+v", pr, want)
+	}
+	if ev != e {
+		t.Errorf("unexpected event: have %+v; want %+v", ev, e)
+	}
+
+	t.Run("commit status without branch", func(t *testing.T) {
+		pr, _, err := h.convertEvent(context.Background(), "https://bitbucket.org/", &bitbucketcloud.RepoCommitStatusEvent{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pr != (PR{}) {
+			t.Errorf("unexpected PR: %+v", pr)
+		}
+	})
+}
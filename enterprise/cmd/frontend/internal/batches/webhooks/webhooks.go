@@ -74,6 +74,8 @@ func extractExternalServiceID(extSvc *types.ExternalService) (string, error) {
 		serviceID = c.Url
 	case *schema.GitLabConnection:
 		serviceID = c.Url
+	case *schema.BitbucketCloudConnection:
+		serviceID = c.Url
 	}
 	if serviceID == "" {
 		return "", errors.New("could not determine service id")
@@ -174,7 +176,9 @@ func (h Webhook) upsertChangesetEvent(
 		return err
 	}
 
-	return nil
+	// Jobs waiting for the checks of the changeset to pass before merging it
+	// can look at the updated state right away.
+	return tx.ExpediteChangesetJobs(ctx, cs.ID, btypes.ChangesetJobTypeMergeWhenChecksPass)
 }
 
 type httpError struct {
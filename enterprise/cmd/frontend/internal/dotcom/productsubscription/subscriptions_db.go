@@ -26,7 +26,7 @@ type dbSubscription struct {
 var emailQueries = sqlf.Sprintf(`all_primary_emails AS (
 	SELECT user_id, FIRST_VALUE(email) over (PARTITION BY user_id ORDER BY created_at ASC) AS primary_email
 	FROM user_emails
-	WHERE verified_at IS NOT NULL),
+	WHERE verified_at IS NOT NULL AND deleted_at IS NULL),
 primary_emails AS (
 	SELECT user_id, primary_email FROM all_primary_emails GROUP BY 1, 2)`)
 
@@ -7,6 +7,10 @@ import (
 	"net/url"
 
 	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
@@ -18,13 +22,24 @@ import (
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 )
 
+var (
+	executionCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_batches_execution_cache_hits_total",
+		Help: "Number of steps of batch spec workspace executions skipped because their result was cached.",
+	})
+	executionCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_batches_execution_cache_misses_total",
+		Help: "Number of steps of batch spec workspace executions without a cached result.",
+	})
+)
+
 const (
 	accessTokenNote  = "batch-spec-execution"
 	accessTokenScope = "user:all"
 )
 
 func createAccessToken(ctx context.Context, db dbutil.DB, userID int32) (string, error) {
-	_, token, err := database.AccessTokens(db).Create(ctx, userID, []string{accessTokenScope}, accessTokenNote, userID)
+	_, token, err := database.AccessTokens(db).Create(ctx, userID, []string{accessTokenScope}, accessTokenNote, userID, nil)
 	if err != nil {
 		return "", err
 	}
@@ -44,6 +59,8 @@ func makeURL(base, username, password string) (string, error) {
 type batchesStore interface {
 	GetBatchSpecWorkspace(context.Context, store.GetBatchSpecWorkspaceOpts) (*btypes.BatchSpecWorkspace, error)
 	GetBatchSpec(context.Context, store.GetBatchSpecOpts) (*btypes.BatchSpec, error)
+	ListBatchSpecExecutionCacheEntries(context.Context, store.ListBatchSpecExecutionCacheEntriesOpts) ([]*btypes.BatchSpecExecutionCacheEntry, error)
+	MarkUsedBatchSpecExecutionCacheEntries(context.Context, []int64) error
 
 	DB() dbutil.DB
 }
@@ -92,6 +109,26 @@ func transformBatchSpecWorkspaceExecutionJobRecord(ctx context.Context, s batche
 		},
 	}
 
+	// Executions of batch specs with noCache set still cache their results,
+	// they just don't start from cached ones.
+	if !batchSpec.NoCache {
+		entry, err := findCachedStepResult(ctx, s, batchSpec, workspace, string(repo.Name))
+		if err != nil {
+			// The workspace can still be executed from scratch, so we don't
+			// fail the job.
+			log15.Warn("failed to look up cached step results", "workspace", workspace.ID, "err", err)
+		} else if entry != nil {
+			executionInput.Workspaces[0].CachedStepResultFound = true
+			executionInput.Workspaces[0].CachedStepResult = entry.Result
+		}
+	}
+	if ws := executionInput.Workspaces[0]; ws.CachedStepResultFound {
+		executionCacheHits.Add(float64(ws.CachedStepResult.StepIndex + 1))
+		executionCacheMisses.Add(float64(len(ws.Steps) - ws.CachedStepResult.StepIndex - 1))
+	} else {
+		executionCacheMisses.Add(float64(len(workspace.Steps)))
+	}
+
 	// TODO: createAccessToken is a bit of technical debt until we figure out a
 	// better solution. The problem is that src-cli needs to make requests to
 	// the Sourcegraph instance *on behalf of the user*.
@@ -168,3 +205,51 @@ func transformBatchSpecWorkspaceExecutionJobRecord(ctx context.Context, s batche
 		},
 	}, nil
 }
+
+// findCachedStepResult returns the cache entry of the last step of the given
+// workspace with a cached result, or nil if no step has one. src-cli resumes
+// the execution after that step.
+func findCachedStepResult(ctx context.Context, s batchesStore, batchSpec *btypes.BatchSpec, workspace *btypes.BatchSpecWorkspace, repoName string) (*btypes.BatchSpecExecutionCacheEntry, error) {
+	if len(workspace.Steps) == 0 {
+		return nil, nil
+	}
+
+	workspaceKey, err := btypes.ExecutionCacheWorkspaceKey(batchSpec, workspace, repoName)
+	if err != nil {
+		return nil, errors.Wrap(err, "computing workspace key")
+	}
+	stepKeys, err := btypes.ExecutionCacheStepKeys(workspace.Steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "computing step keys")
+	}
+
+	entries, err := s.ListBatchSpecExecutionCacheEntries(ctx, store.ListBatchSpecExecutionCacheEntriesOpts{
+		UserID:       batchSpec.UserID,
+		WorkspaceKey: workspaceKey,
+		StepKeys:     stepKeys,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing cache entries")
+	}
+
+	var latest *btypes.BatchSpecExecutionCacheEntry
+	for _, e := range entries {
+		// The step key covers all steps up to the cached one, so an entry
+		// stored under a different index can't be resumed from.
+		idx := e.Result.StepIndex
+		if idx < 0 || idx >= len(stepKeys) || stepKeys[idx] != e.StepKey {
+			continue
+		}
+		if latest == nil || idx > latest.Result.StepIndex {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	if err := s.MarkUsedBatchSpecExecutionCacheEntries(ctx, []int64{latest.ID}); err != nil {
+		return nil, errors.Wrap(err, "marking cache entry as used")
+	}
+	return latest, nil
+}
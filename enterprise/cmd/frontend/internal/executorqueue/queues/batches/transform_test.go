@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -24,7 +25,7 @@ import (
 
 func TestTransformBatchSpecWorkspaceExecutionJobRecord(t *testing.T) {
 	accessToken := "thisissecret-dont-tell-anyone"
-	database.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorID int32) (int64, string, error) {
+	database.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorID int32, expiresAt *time.Time) (int64, string, error) {
 		return 1234, accessToken, nil
 	}
 	t.Cleanup(func() { database.Mocks.AccessTokens.Create = nil })
@@ -123,12 +124,66 @@ func TestTransformBatchSpecWorkspaceExecutionJobRecord(t *testing.T) {
 	if diff := cmp.Diff(expected, job); diff != "" {
 		t.Errorf("unexpected job (-want +got):\n%s", diff)
 	}
+
+	t.Run("cached step result", func(t *testing.T) {
+		stepKeys, err := btypes.ExecutionCacheStepKeys(workspace.Steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := &btypes.BatchSpecExecutionCacheEntry{
+			ID:      7,
+			UserID:  batchSpec.UserID,
+			StepKey: stepKeys[0],
+			Result: batcheslib.AfterStepResult{
+				StepIndex: 0,
+				Diff:      "diff --git a/readme.md b/readme.md",
+				Outputs:   map[string]interface{}{},
+			},
+		}
+		store.cacheEntries = []*btypes.BatchSpecExecutionCacheEntry{entry}
+		t.Cleanup(func() { store.cacheEntries = nil })
+
+		for name, noCache := range map[string]bool{"used": false, "noCache": true} {
+			t.Run(name, func(t *testing.T) {
+				batchSpec.NoCache = noCache
+				store.usedCacheEntries = nil
+				t.Cleanup(func() { batchSpec.NoCache = false })
+
+				job, err := transformBatchSpecWorkspaceExecutionJobRecord(context.Background(), store, workspaceExecutionJob, config)
+				if err != nil {
+					t.Fatalf("unexpected error transforming record: %s", err)
+				}
+
+				var input batcheslib.WorkspacesExecutionInput
+				if err := json.Unmarshal([]byte(job.VirtualMachineFiles["input.json"]), &input); err != nil {
+					t.Fatal(err)
+				}
+				if have, want := input.Workspaces[0].CachedStepResultFound, !noCache; have != want {
+					t.Fatalf("wrong CachedStepResultFound. want=%t, have=%t", want, have)
+				}
+				if noCache {
+					if len(store.usedCacheEntries) != 0 {
+						t.Fatalf("cache entries marked as used: %v", store.usedCacheEntries)
+					}
+					return
+				}
+				if diff := cmp.Diff(entry.Result, input.Workspaces[0].CachedStepResult); diff != "" {
+					t.Errorf("unexpected cached step result (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff([]int64{entry.ID}, store.usedCacheEntries); diff != "" {
+					t.Errorf("unexpected used cache entries (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
 }
 
 type dummyBatchesStore struct {
 	dbHandle           dbutil.DB
 	batchSpec          *btypes.BatchSpec
 	batchSpecWorkspace *btypes.BatchSpecWorkspace
+	cacheEntries       []*btypes.BatchSpecExecutionCacheEntry
+	usedCacheEntries   []int64
 }
 
 func (db *dummyBatchesStore) GetBatchSpecWorkspace(context.Context, store.GetBatchSpecWorkspaceOpts) (*btypes.BatchSpecWorkspace, error) {
@@ -137,4 +192,11 @@ func (db *dummyBatchesStore) GetBatchSpecWorkspace(context.Context, store.GetBat
 func (db *dummyBatchesStore) GetBatchSpec(context.Context, store.GetBatchSpecOpts) (*btypes.BatchSpec, error) {
 	return db.batchSpec, nil
 }
+func (db *dummyBatchesStore) ListBatchSpecExecutionCacheEntries(context.Context, store.ListBatchSpecExecutionCacheEntriesOpts) ([]*btypes.BatchSpecExecutionCacheEntry, error) {
+	return db.cacheEntries, nil
+}
+func (db *dummyBatchesStore) MarkUsedBatchSpecExecutionCacheEntries(_ context.Context, ids []int64) error {
+	db.usedCacheEntries = append(db.usedCacheEntries, ids...)
+	return nil
+}
 func (db *dummyBatchesStore) DB() dbutil.DB { return db.dbHandle }
@@ -0,0 +1,98 @@
+// Command batches-query-plans explains the queries the batches store uses to
+// get, list and dequeue batch spec resolution jobs, against a database seeded
+// with a realistic number of jobs. It reports the plans and flags sequential
+// scans, which usually point at a missing index.
+//
+// All changes to the database are made in a transaction that is rolled back.
+// The database is configured through the usual PG* environment variables:
+//
+//	go run ./enterprise/dev/batches-query-plans -rows 100000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/keegancsmith/sqlf"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+var (
+	rows    = flag.Int("rows", 100000, "number of synthetic batch spec resolution jobs to seed before explaining")
+	verbose = flag.Bool("v", false, "print the full query plans, not only the findings")
+	strict  = flag.Bool("strict", false, "exit with a non-zero status if any query scans a table sequentially")
+)
+
+// errRollback is used to roll back the transaction once we're done.
+var errRollback = errors.New("rollback")
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+
+	findings, err := run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if findings > 0 && *strict {
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) (findings int, err error) {
+	db, err := dbconn.New(dbconn.Opts{DBName: "frontend", AppName: "batches-query-plans"})
+	if err != nil {
+		return 0, errors.Wrap(err, "connecting to database")
+	}
+
+	observationContext := &observation.Context{
+		Logger:     log15.Root(),
+		Registerer: prometheus.NewRegistry(),
+	}
+	tx, err := store.New(db, observationContext, nil).Transact(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if doneErr := tx.Done(errRollback); doneErr != nil && !errors.Is(doneErr, errRollback) {
+			err = doneErr
+		}
+	}()
+
+	// The seeded jobs don't reference existing batch specs.
+	if err := tx.Exec(ctx, sqlf.Sprintf("SET CONSTRAINTS ALL DEFERRED")); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Seeding %d batch spec resolution jobs...", *rows)
+	if err := tx.SeedBatchSpecResolutionJobs(ctx, *rows); err != nil {
+		return 0, errors.Wrap(err, "seeding batch spec resolution jobs")
+	}
+
+	plans, err := tx.ExplainBatchSpecResolutionJobQueries(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "explaining queries")
+	}
+
+	for _, plan := range plans {
+		status := "OK"
+		if len(plan.SeqScans) > 0 {
+			status = fmt.Sprintf("SEQ SCAN on %v, consider adding an index", plan.SeqScans)
+			findings++
+		}
+		fmt.Printf("%s: %s\n", plan.Name, status)
+		if *verbose {
+			fmt.Printf("%s\n\n", plan.Plan)
+		}
+	}
+	return findings, nil
+}
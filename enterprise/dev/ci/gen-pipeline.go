@@ -3,15 +3,47 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/cockroachdb/errors"
+
+	bk "github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/buildkite"
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci"
+	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/changed"
 )
 
 func main() {
+	// Instead of a pipeline, emit how the CI impact of two refs differs, if
+	// requested.
+	if refs := os.Getenv("PIPELINE_IMPACT_DIFF"); refs != "" {
+		if err := writePipelineImpactDiff(refs); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	// Instead of a pipeline, emit a preview of the steps a pull request with the
+	// local changes would run, if requested.
+	if base := os.Getenv("PIPELINE_PREVIEW"); base != "" {
+		if err := writePipelinePreview(base, os.Getenv("PIPELINE_PREVIEW_FORMAT")); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	config := ci.NewConfig(time.Now())
 
+	// Emit the classification of the changed files for the flake tracking
+	// tooling, if requested.
+	if reportPath := os.Getenv("CHANGED_FILES_REPORT"); reportPath != "" {
+		if err := writeChangedFilesReport(config, reportPath); err != nil {
+			panic(err)
+		}
+	}
+
 	pipeline, err := ci.GeneratePipeline(config)
 	if err != nil {
 		panic(err)
@@ -22,3 +54,70 @@ func main() {
 		panic(err)
 	}
 }
+
+func writeChangedFilesReport(config ci.Config, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return config.ChangedFiles.WriteReport(f, ".")
+}
+
+func writePipelineImpactDiff(refs string) error {
+	parts := strings.Split(refs, "...")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.Errorf("PIPELINE_IMPACT_DIFF must be of the form FROM...TO, got %q", refs)
+	}
+	return changed.WriteImpact(os.Stdout, parts[0], parts[1], ci.PullRequestSteps)
+}
+
+func writePipelinePreview(base, format string) error {
+	files, err := changed.Local(base)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Printf("No changes since %s.\n", base)
+		return nil
+	}
+
+	config := ci.NewPreviewConfig(time.Now(), files)
+	pipeline, err := ci.GeneratePipeline(config)
+	if err != nil {
+		return err
+	}
+	if format == "yaml" {
+		_, err := pipeline.WriteTo(os.Stdout)
+		return err
+	}
+
+	fmt.Printf("Changed files since %s (%d):\n", base, len(files))
+	for _, f := range files {
+		fmt.Printf("  %s\n", f)
+	}
+	fmt.Printf("\nCategories: %s\n", strings.Join(files.Categories(), ", "))
+	if config.GoTestPackages != nil {
+		fmt.Printf("Go packages to test: %d\n", len(config.GoTestPackages))
+		for _, p := range config.GoTestPackages {
+			fmt.Printf("  %s\n", p)
+		}
+	} else if files.AffectsGo() {
+		fmt.Println("Go packages to test: all")
+	}
+
+	fmt.Println("\nSteps:")
+	for _, s := range pipeline.Steps {
+		step, ok := s.(*bk.Step)
+		if !ok {
+			continue
+		}
+		if step.Trigger != "" {
+			fmt.Printf("  %s (triggers %s)\n", step.Label, step.Trigger)
+			continue
+		}
+		fmt.Printf("  %s\n", step.Label)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package changed
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// GoPackage is a package of the Go import graph of the repository.
+type GoPackage struct {
+	// ImportPath is the import path of the package.
+	ImportPath string
+	// Dir is the directory of the package, relative to the root of the
+	// repository.
+	Dir string
+	// Main is whether the package belongs to the main module, i.e. is tested
+	// by the Go test step of the root of the repository.
+	Main bool
+	// Deps are the transitive dependencies of the package.
+	Deps []string
+	// TestImports are the direct dependencies of the tests of the package.
+	TestImports []string
+}
+
+// goListFormat prints one line per package: its import path, directory,
+// whether it is in the main module, its transitive dependencies and the
+// imports of its tests.
+const goListFormat = "{{if not .Standard}}{{.ImportPath}}\t{{.Dir}}\t{{with .Module}}{{.Main}}{{end}}\t" +
+	`{{join .Deps " "}}\t{{join .TestImports " "}} {{join .XTestImports " "}}{{end}}`
+
+// LoadGoPackages returns the packages of the main module of the repository
+// checked out at root, along with all the packages of the repository they
+// depend on, as listed by `go list -deps`.
+func LoadGoPackages(root string) ([]GoPackage, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("go", "list", "-deps", "-e", "-f", goListFormat, "./...")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "go list -deps ./...")
+	}
+
+	var packages []GoPackage
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 {
+			continue
+		}
+
+		// Dependencies from outside the repository, e.g. in the module cache,
+		// can't be affected by the changes.
+		dir, err := filepath.Rel(absRoot, fields[1])
+		if err != nil || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		packages = append(packages, GoPackage{
+			ImportPath:  fields[0],
+			Dir:         filepath.ToSlash(dir),
+			Main:        fields[2] == "true",
+			Deps:        strings.Fields(fields[3]),
+			TestImports: strings.Fields(fields[4]),
+		})
+	}
+	return packages, scanner.Err()
+}
+
+// AffectedGoPackages returns the sorted import paths of the packages of the
+// main module whose tests may be affected by the changes, i.e. the packages
+// that contain changed files, that depend on such packages, or whose tests
+// import such packages. root is the repository checked out at the changes,
+// used to recognize files of other Go modules than the main module.
+//
+// A nil slice is returned if the changes can't be mapped to packages, e.g.
+// because the go.mod of the main module or of a module it depends on, or a
+// package that no longer exists changed, in which case all the packages should
+// be tested.
+func (f Files) AffectedGoPackages(root string, packages []GoPackage) []string {
+	byDir := make(map[string]GoPackage, len(packages))
+	for _, pkg := range packages {
+		byDir[pkg.Dir] = pkg
+	}
+
+	changed := map[string]struct{}{}
+	for _, p := range f {
+		if base := path.Base(p); base == "go.mod" || base == "go.sum" {
+			// The dependencies of the packages of the module changed, which
+			// the import graph doesn't capture.
+			if moduleHasPackages(path.Dir(p), packages) {
+				return nil
+			}
+			continue
+		}
+
+		pkg, ok, otherModule := enclosingGoPackage(root, byDir, p)
+		switch {
+		case ok:
+			changed[pkg.ImportPath] = struct{}{}
+		case otherModule:
+			// Changes to other modules that the main module doesn't depend
+			// on are tested by the Go test step of these modules.
+		case strings.HasSuffix(p, ".go"):
+			// A Go file outside of any package we know of, e.g. of a
+			// deleted package or excluded by build constraints.
+			return nil
+		}
+	}
+
+	// Deps are transitive, so a single pass finds all the packages that
+	// depend on a changed package.
+	affected := map[string]struct{}{}
+	for _, pkg := range packages {
+		if _, ok := changed[pkg.ImportPath]; ok {
+			affected[pkg.ImportPath] = struct{}{}
+			continue
+		}
+		for _, dep := range pkg.Deps {
+			if _, ok := changed[dep]; ok {
+				affected[pkg.ImportPath] = struct{}{}
+				break
+			}
+		}
+	}
+
+	// Test imports are not transitive, and not part of Deps, so the tests
+	// importing affected packages are affected too.
+	tested := []string{}
+	for _, pkg := range packages {
+		if !pkg.Main {
+			continue
+		}
+		if _, ok := affected[pkg.ImportPath]; ok {
+			tested = append(tested, pkg.ImportPath)
+			continue
+		}
+		for _, imp := range pkg.TestImports {
+			if _, ok := affected[imp]; ok {
+				tested = append(tested, pkg.ImportPath)
+				break
+			}
+		}
+	}
+	sort.Strings(tested)
+	return tested
+}
+
+// enclosingGoPackage returns the package of the closest directory containing
+// the file at p, which includes e.g. the testdata and embedded files of the
+// package. otherModule is true if the file belongs to a Go module nested in
+// the repository that is not a dependency of the main module.
+func enclosingGoPackage(root string, byDir map[string]GoPackage, p string) (pkg GoPackage, ok, otherModule bool) {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if pkg, ok := byDir[dir]; ok {
+			return pkg, true, false
+		}
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir), "go.mod")); err == nil {
+			return GoPackage{}, false, true
+		}
+	}
+	if path.Dir(p) == "." {
+		pkg, ok = byDir["."]
+	}
+	return pkg, ok, false
+}
+
+// moduleHasPackages returns whether any of the packages is in the module at
+// dir, i.e. whether the main module depends on the module.
+func moduleHasPackages(dir string, packages []GoPackage) bool {
+	for _, pkg := range packages {
+		if dir == "." || pkg.Dir == dir || strings.HasPrefix(pkg.Dir, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
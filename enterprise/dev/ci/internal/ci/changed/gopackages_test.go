@@ -0,0 +1,102 @@
+package changed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAffectedGoPackages(t *testing.T) {
+	// The repository has a main module with a nested module it depends on,
+	// lib, and a nested module it doesn't depend on, dev/ci.
+	root := t.TempDir()
+	for _, name := range []string{"go.mod", "lib/go.mod", "dev/ci/go.mod"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("module test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	packages := []GoPackage{
+		{ImportPath: "lib/errors", Dir: "lib/errors"},
+		{ImportPath: "example.com/internal/conf", Dir: "internal/conf", Main: true, Deps: []string{"lib/errors"}},
+		{ImportPath: "example.com/internal/db", Dir: "internal/db", Main: true, Deps: []string{"example.com/internal/conf", "lib/errors"}},
+		{ImportPath: "example.com/internal/dbtest", Dir: "internal/dbtest", Main: true},
+		{ImportPath: "example.com/cmd/app", Dir: "cmd/app", Main: true, TestImports: []string{"example.com/internal/dbtest"}},
+		{ImportPath: "example.com/cmd/worker", Dir: "cmd/worker", Main: true},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		files Files
+		want  []string
+	}{
+		{
+			name:  "no Go changes",
+			files: Files{"doc/index.md"},
+			want:  []string{},
+		},
+		{
+			name:  "leaf package",
+			files: Files{"cmd/worker/main.go"},
+			want:  []string{"example.com/cmd/worker"},
+		},
+		{
+			name:  "dependents",
+			files: Files{"internal/conf/conf.go"},
+			want:  []string{"example.com/internal/conf", "example.com/internal/db"},
+		},
+		{
+			name:  "testdata",
+			files: Files{"internal/conf/testdata/site.json"},
+			want:  []string{"example.com/internal/conf", "example.com/internal/db"},
+		},
+		{
+			name:  "test-only import",
+			files: Files{"internal/dbtest/dbtest.go"},
+			want:  []string{"example.com/cmd/app", "example.com/internal/dbtest"},
+		},
+		{
+			name:  "go.mod",
+			files: Files{"cmd/worker/main.go", "go.mod"},
+			want:  nil,
+		},
+		{
+			name:  "go.sum",
+			files: Files{"go.sum"},
+			want:  nil,
+		},
+		{
+			name:  "deleted package",
+			files: Files{"internal/removed/removed.go"},
+			want:  nil,
+		},
+		{
+			name:  "nested module dependency",
+			files: Files{"lib/errors/errors.go"},
+			want:  []string{"example.com/internal/conf", "example.com/internal/db"},
+		},
+		{
+			name:  "nested module dependency go.mod",
+			files: Files{"lib/go.mod"},
+			want:  nil,
+		},
+		{
+			name:  "other nested module",
+			files: Files{"dev/ci/main.go", "dev/ci/go.mod", "dev/ci/go.sum"},
+			want:  []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.files.AffectedGoPackages(root, packages)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected affected packages (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
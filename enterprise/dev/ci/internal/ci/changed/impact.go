@@ -0,0 +1,159 @@
+package changed
+
+import (
+	"encoding/json"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Between returns the files changed on to since it diverged from from, i.e. the
+// equivalent of `git diff --name-only from...to`.
+func Between(from, to string) (Files, error) {
+	output, err := exec.Command("git", "diff", "--name-only", from+"..."+to).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git diff %s...%s", from, to)
+	}
+
+	files := Files{}
+	for _, p := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	return files, nil
+}
+
+// Local returns the files changed in the working tree since it diverged from
+// base, including uncommitted and untracked files, i.e. the changes a pull
+// request against base would have once everything is committed and pushed.
+func Local(base string) (Files, error) {
+	mergeBase, err := exec.Command("git", "merge-base", base, "HEAD").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git merge-base %s HEAD", base)
+	}
+	diff, err := exec.Command("git", "diff", "--name-only", strings.TrimSpace(string(mergeBase))).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git diff %s", strings.TrimSpace(string(mergeBase)))
+	}
+	untracked, err := exec.Command("git", "ls-files", "--others", "--exclude-standard").Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "git ls-files --others")
+	}
+
+	files := Files{}
+	for _, p := range strings.Split(strings.TrimSpace(string(diff))+"\n"+strings.TrimSpace(string(untracked)), "\n") {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	return files, nil
+}
+
+// ImpactSide describes what the changes on one side of an ImpactDiff trigger
+// in CI.
+type ImpactSide struct {
+	// Ref is the Git ref of this side.
+	Ref string `json:"ref"`
+	// Files is the list of files changed on this side since the merge-base.
+	Files []string `json:"files"`
+	// Categories is the list of categories the changes fall into.
+	Categories []string `json:"categories"`
+	// Steps is the list of labels of the pipeline steps the changes trigger.
+	Steps []string `json:"steps"`
+	// OnlyCategories is the list of categories only this side falls into.
+	OnlyCategories []string `json:"onlyCategories"`
+	// OnlySteps is the list of pipeline steps only this side triggers.
+	OnlySteps []string `json:"onlySteps"`
+}
+
+// ImpactDiff compares the CI impact of the changes on two refs since their
+// merge-base. Release engineering uses it to understand which validation a
+// backport branch needs in addition to what already ran on main.
+type ImpactDiff struct {
+	From ImpactSide `json:"from"`
+	To   ImpactSide `json:"to"`
+}
+
+// StepsFunc returns the labels of the pipeline steps triggered by the given
+// changed files. It is never called with an empty Files, which pipeline
+// generation treats as "run everything".
+type StepsFunc func(Files) []string
+
+// Impact computes the ImpactDiff between the refs from and to. steps is used to
+// determine the pipeline steps triggered by the changes of either side.
+func Impact(from, to string, steps StepsFunc) (*ImpactDiff, error) {
+	fromFiles, err := Between(to, from)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := Between(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &ImpactDiff{
+		From: newImpactSide(from, fromFiles, steps),
+		To:   newImpactSide(to, toFiles, steps),
+	}
+	d.From.OnlyCategories = difference(d.From.Categories, d.To.Categories)
+	d.To.OnlyCategories = difference(d.To.Categories, d.From.Categories)
+	d.From.OnlySteps = difference(d.From.Steps, d.To.Steps)
+	d.To.OnlySteps = difference(d.To.Steps, d.From.Steps)
+	return d, nil
+}
+
+// WriteImpact writes the ImpactDiff between the refs from and to as JSON to w.
+func WriteImpact(w io.Writer, from, to string, steps StepsFunc) error {
+	d, err := Impact(from, to, steps)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func newImpactSide(ref string, files Files, steps StepsFunc) ImpactSide {
+	side := ImpactSide{
+		Ref:        ref,
+		Files:      files,
+		Categories: files.Categories(),
+		Steps:      []string{},
+	}
+	if len(files) > 0 {
+		side.Steps = dedupe(steps(files))
+	}
+	return side
+}
+
+// difference returns the elements of a that are not in b, in the order of a.
+func difference(a, b []string) []string {
+	set := map[string]struct{}{}
+	for _, s := range b {
+		set[s] = struct{}{}
+	}
+	diff := []string{}
+	for _, s := range a {
+		if _, ok := set[s]; !ok {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
+
+// dedupe returns the given strings without duplicates, keeping the first
+// occurrence of each.
+func dedupe(ss []string) []string {
+	seen := map[string]struct{}{}
+	deduped := []string{}
+	for _, s := range ss {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			deduped = append(deduped, s)
+		}
+	}
+	return deduped
+}
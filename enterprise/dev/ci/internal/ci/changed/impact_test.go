@@ -0,0 +1,158 @@
+package changed
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImpact(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	// The commits are created in a temporary repository, which becomes the
+	// working directory the git commands of Impact run in.
+	root := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	commit := func(files map[string]string) {
+		t.Helper()
+		for name, contents := range files {
+			path := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "change")
+	}
+
+	git("init", "-q")
+	git("checkout", "-q", "-b", "main")
+	commit(map[string]string{"doc/index.md": "base", "cmd/shared/shared.go": "package shared"})
+
+	git("checkout", "-q", "-b", "backport")
+	commit(map[string]string{"doc/backport.md": "backport", "cmd/shared/shared.go": "package shared // backport"})
+
+	git("checkout", "-q", "main")
+	commit(map[string]string{"cmd/shared/shared.go": "package shared // main", "cmd/app/main.go": "package main"})
+
+	// steps fakes pipeline generation: every change runs the linters, Go
+	// changes run the Go tests and doc changes run the docs checks.
+	steps := func(files Files) []string {
+		if len(files) == 0 {
+			t.Fatal("steps called without changed files")
+		}
+		labels := []string{"lint"}
+		for _, f := range files {
+			switch {
+			case strings.HasSuffix(f, ".go"):
+				labels = append(labels, "go test")
+			case strings.HasSuffix(f, ".md"):
+				labels = append(labels, "docs")
+			}
+		}
+		return labels
+	}
+
+	t.Run("diverged refs", func(t *testing.T) {
+		d, err := Impact("backport", "main", steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &ImpactDiff{
+			From: ImpactSide{
+				Ref:            "backport",
+				Files:          []string{"cmd/shared/shared.go", "doc/backport.md"},
+				Categories:     []string{CategoryDocs, CategoryGo},
+				Steps:          []string{"lint", "go test", "docs"},
+				OnlyCategories: []string{CategoryDocs},
+				OnlySteps:      []string{"docs"},
+			},
+			To: ImpactSide{
+				Ref:            "main",
+				Files:          []string{"cmd/app/main.go", "cmd/shared/shared.go"},
+				Categories:     []string{CategoryGo},
+				Steps:          []string{"lint", "go test"},
+				OnlyCategories: []string{},
+				OnlySteps:      []string{},
+			},
+		}
+		if diff := cmp.Diff(want, d); diff != "" {
+			t.Errorf("unexpected impact (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ancestor", func(t *testing.T) {
+		// main~1 is the merge-base, so it has no changes of its own and its
+		// steps must not be generated, which would run everything.
+		d, err := Impact("main~1", "main", steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.From.Files) != 0 || len(d.From.Steps) != 0 {
+			t.Errorf("unexpected changes on the ancestor: %+v", d.From)
+		}
+		if diff := cmp.Diff([]string{"lint", "go test"}, d.To.OnlySteps); diff != "" {
+			t.Errorf("unexpected steps only triggered by main (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		if _, err := Impact("does-not-exist", "main", steps); err == nil {
+			t.Fatal("want error for unknown ref")
+		}
+	})
+}
+
+func TestDifference(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{"empty", nil, nil, []string{}},
+		{"disjoint", []string{"b", "a"}, []string{"c"}, []string{"b", "a"}},
+		{"overlapping", []string{"a", "b", "c"}, []string{"b"}, []string{"a", "c"}},
+		{"subset", []string{"a"}, []string{"a", "b"}, []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, difference(tc.a, tc.b)); diff != "" {
+				t.Errorf("unexpected difference (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	got := dedupe([]string{"lint", "go test", "lint", "docs", "go test"})
+	if diff := cmp.Diff([]string{"lint", "go test", "docs"}, got); diff != "" {
+		t.Errorf("unexpected deduped steps (-want +got):\n%s", diff)
+	}
+}
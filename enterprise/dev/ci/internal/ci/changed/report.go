@@ -0,0 +1,223 @@
+package changed
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Categories of changes, as reported in a Report.
+const (
+	CategoryDocs        = "docs"
+	CategorySg          = "sg"
+	CategoryGo          = "go"
+	CategoryDockerfiles = "dockerfiles"
+	CategoryGraphQL     = "graphql"
+	CategoryClient      = "client"
+)
+
+// Report is the classification of a set of changed files. It is consumed by
+// the flake tracking tooling to correlate newly introduced test flakes with the
+// areas of the codebase touched by a build.
+type Report struct {
+	// Files is the list of changed files.
+	Files []string `json:"files"`
+	// Packages is the list of Go package directories and client packages
+	// containing changed files.
+	Packages []string `json:"packages"`
+	// Owners is the list of owners of the changed files, as listed in
+	// CODENOTIFY files.
+	Owners []string `json:"owners"`
+	// Categories is the list of categories the changes fall into.
+	Categories []string `json:"categories"`
+}
+
+// categories are the categories of changes, in the order they are reported.
+var categories = []string{
+	CategoryDocs,
+	CategorySg,
+	CategoryGo,
+	CategoryDockerfiles,
+	CategoryGraphQL,
+	CategoryClient,
+}
+
+// Categories returns the categories the changes fall into, in the same terms as
+// the `AffectsXYZ` helpers.
+func (f Files) Categories() []string {
+	result := []string{}
+	for _, c := range categories {
+		if defaultRules.Affects(c, f) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Packages returns the sorted list of Go package directories and client
+// packages (e.g. "client/web") containing changed files.
+func (f Files) Packages() []string {
+	set := map[string]struct{}{}
+	for _, p := range f {
+		switch {
+		case strings.HasSuffix(p, ".go"):
+			set[path.Dir(p)] = struct{}{}
+		case strings.HasPrefix(p, "client/"):
+			if parts := strings.SplitN(p, "/", 3); len(parts) == 3 {
+				set[path.Join(parts[0], parts[1])] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(set)
+}
+
+// Owners returns the sorted list of owners of the changed files, based on the
+// CODENOTIFY files in the repository checked out at root. See
+// https://github.com/sourcegraph/codenotify for the file format.
+func (f Files) Owners(root string) ([]string, error) {
+	rulesByDir := map[string][]codenotifyRule{}
+	set := map[string]struct{}{}
+	for _, p := range f {
+		// CODENOTIFY files apply to their own directory and all
+		// subdirectories, so we need to check every ancestor.
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			rules, ok := rulesByDir[dir]
+			if !ok {
+				var err error
+				rules, err = readCodenotify(filepath.Join(root, filepath.FromSlash(dir), "CODENOTIFY"))
+				if err != nil {
+					return nil, err
+				}
+				rulesByDir[dir] = rules
+			}
+
+			rel := p
+			if dir != "." {
+				rel = strings.TrimPrefix(p, dir+"/")
+			}
+			for _, r := range rules {
+				if r.pattern.MatchString(rel) {
+					for _, o := range r.owners {
+						set[o] = struct{}{}
+					}
+				}
+			}
+
+			if dir == "." {
+				break
+			}
+		}
+	}
+	return sortedKeys(set), nil
+}
+
+// Report builds the Report for the changed files, reading ownership
+// information from the repository checked out at root.
+func (f Files) Report(root string) (*Report, error) {
+	// Empty paths, e.g. from a trailing newline of git diff, aren't files and
+	// must not match any rule.
+	files := Files{}
+	for _, p := range f {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+
+	owners, err := files.Owners(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading owners")
+	}
+
+	return &Report{
+		Files:      files,
+		Packages:   files.Packages(),
+		Owners:     owners,
+		Categories: files.Categories(),
+	}, nil
+}
+
+// WriteReport writes the Report for the changed files as JSON to w.
+func (f Files) WriteReport(w io.Writer, root string) error {
+	report, err := f.Report(root)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+type codenotifyRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// readCodenotify parses the CODENOTIFY file at the given path. A missing file
+// yields no rules.
+func readCodenotify(filename string) ([]codenotifyRule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []codenotifyRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pattern, err := regexp.Compile(globToRegexp(fields[0]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: invalid pattern %q", filename, fields[0])
+		}
+		rules = append(rules, codenotifyRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules, scanner.Err()
+}
+
+// globToRegexp converts a CODENOTIFY glob pattern into a regular expression:
+// "**" matches any number of directories, "*" and "?" never match "/".
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
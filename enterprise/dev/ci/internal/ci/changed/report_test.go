@@ -0,0 +1,129 @@
+package changed
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPackages(t *testing.T) {
+	files := Files{
+		"cmd/frontend/main.go",
+		"cmd/frontend/README.md",
+		"internal/database/repos.go",
+		"internal/database/repos_test.go",
+		"client/web/src/index.tsx",
+		"client/web/package.json",
+		"client/README.md",
+		"go.mod",
+	}
+	want := []string{"client/web", "cmd/frontend", "internal/database"}
+	if diff := cmp.Diff(want, files.Packages()); diff != "" {
+		t.Errorf("unexpected packages (-want +got):\n%s", diff)
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"*.go", "main.go.orig", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "doc/admin/index.md", true},
+		{"**/*.md", "doc/admin/index.mdx", false},
+		{"dev/**", "dev/sg/main.go", true},
+		{"dev/**", "enterprise/dev/ci/main.go", false},
+		{"repo?.go", "repos.go", true},
+		{"repo?.go", "repo/.go", false},
+		{"schema.graphql", "schemaXgraphql", false},
+	} {
+		pattern := regexp.MustCompile(globToRegexp(tc.glob))
+		if match := pattern.MatchString(tc.path); match != tc.match {
+			t.Errorf("glob %q (%s) matching %q: want %t, have %t", tc.glob, pattern, tc.path, tc.match, match)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	root := t.TempDir()
+	for name, contents := range map[string]string{
+		"CODENOTIFY": `# Comments and lines without owners are ignored.
+**/*.md @alice
+dev/**  @bob @dave
+CHANGELOG.md
+`,
+		// Rules apply relative to the directory of the CODENOTIFY file.
+		"internal/database/CODENOTIFY": "*.go @carol\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("owners", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			files  Files
+			owners []string
+		}{
+			{"nested rules", Files{"internal/database/repos.go"}, []string{"@carol"}},
+			{"root rules", Files{"dev/sg/main.go", "doc/admin/index.md"}, []string{"@alice", "@bob", "@dave"}},
+			{"rules of all ancestors", Files{"internal/database/README.md"}, []string{"@alice"}},
+			{"pattern without owners", Files{"internal/database/migrations/up.go", "CHANGELOG.md"}, []string{"@alice"}},
+			{"unowned", Files{"internal/database/migrations/up.go"}, []string{}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				owners, err := tc.files.Owners(root)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.owners, owners); diff != "" {
+					t.Errorf("unexpected owners (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		files := Files{"internal/database/repos.go", "doc/admin/index.md", ""}
+		want := &Report{
+			Files:      []string{"internal/database/repos.go", "doc/admin/index.md"},
+			Packages:   []string{"internal/database"},
+			Owners:     []string{"@alice", "@carol"},
+			Categories: []string{CategoryDocs, CategoryGo},
+		}
+
+		report, err := files.Report(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, report); diff != "" {
+			t.Errorf("unexpected report (-want +got):\n%s", diff)
+		}
+
+		var buf bytes.Buffer
+		if err := files.WriteReport(&buf, root); err != nil {
+			t.Fatal(err)
+		}
+		var written Report
+		if err := json.Unmarshal(buf.Bytes(), &written); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, &written); diff != "" {
+			t.Errorf("unexpected written report (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package changed
+
+import (
+	_ "embed"
+	"regexp"
+
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// rulesYAML is the rules file, see rules.yaml.
+//
+//go:embed rules.yaml
+var rulesYAML []byte
+
+// defaultRules are the rules the `AffectsXYZ` helpers evaluate. An invalid rules file
+// fails the generation of the pipeline.
+var defaultRules = mustParseRules(rulesYAML)
+
+// Rules is a set of rules that classify changed files into categories.
+type Rules struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule classifies the changed files that match one of its include patterns and none of
+// its exclude patterns into the category Name.
+type Rule struct {
+	Name    string   `yaml:"name"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// ParseRules parses and validates a rules file. Every category must have exactly one
+// rule.
+func ParseRules(data []byte) (*Rules, error) {
+	var rules Rules
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, errors.Wrap(err, "parsing rules")
+	}
+
+	known := map[string]bool{}
+	for _, c := range categories {
+		known[c] = true
+	}
+	seen := map[string]bool{}
+	for i, r := range rules.Rules {
+		if r == nil || r.Name == "" {
+			return nil, errors.Errorf("rule %d: missing name", i)
+		}
+		if !known[r.Name] {
+			return nil, errors.Errorf("rule %q: unknown category", r.Name)
+		}
+		if seen[r.Name] {
+			return nil, errors.Errorf("rule %q: duplicate rule for category", r.Name)
+		}
+		seen[r.Name] = true
+
+		if len(r.Include) == 0 {
+			return nil, errors.Errorf("rule %q: no include patterns", r.Name)
+		}
+		var err error
+		if r.include, err = compilePatterns(r.Include); err != nil {
+			return nil, errors.Wrapf(err, "rule %q", r.Name)
+		}
+		if r.exclude, err = compilePatterns(r.Exclude); err != nil {
+			return nil, errors.Wrapf(err, "rule %q", r.Name)
+		}
+	}
+	for _, c := range categories {
+		if !seen[c] {
+			return nil, errors.Errorf("missing rule for category %q", c)
+		}
+	}
+	return &rules, nil
+}
+
+func mustParseRules(data []byte) *Rules {
+	rules, err := ParseRules(data)
+	if err != nil {
+		panic(errors.Wrap(err, "invalid changed files rules"))
+	}
+	return rules
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		if p == "" {
+			return nil, errors.New("empty pattern")
+		}
+		re, err := regexp.Compile(globToRegexp(p))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", p)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Matches returns whether the file at path p falls into the category of the rule.
+func (r *Rule) Matches(p string) bool {
+	return matchesAny(r.include, p) && !matchesAny(r.exclude, p)
+}
+
+func matchesAny(patterns []*regexp.Regexp, p string) bool {
+	for _, re := range patterns {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Affects returns whether any of the files falls into the given category.
+func (r *Rules) Affects(category string, files Files) bool {
+	for _, rule := range r.Rules {
+		if rule.Name != category {
+			continue
+		}
+		for _, p := range files {
+			if rule.Matches(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package changed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDefaultRules(t *testing.T) {
+	for _, tc := range []struct {
+		file       string
+		categories []string
+	}{
+		{"doc/admin/index.md", []string{CategoryDocs}},
+		{"CHANGELOG.md", []string{}},
+		{"dev/sg/main.go", []string{CategorySg, CategoryGo}},
+		{"internal/database/repos.go", []string{CategoryGo}},
+		{"go.sum", []string{CategoryGo}},
+		{"migrations/frontend/1528395937_foo.up.sql", []string{CategoryGo}},
+		{"Dockerfile", []string{CategoryDockerfiles, CategoryClient}},
+		{"cmd/server/Dockerfile", []string{CategoryDockerfiles}},
+		{"cmd/frontend/graphqlbackend/schema.graphql", []string{CategoryGraphQL}},
+		{"client/web/src/index.tsx", []string{CategoryClient}},
+		{"client/web/README.md", []string{}},
+		{"package.json", []string{CategoryClient}},
+		{"renovate.json", []string{}},
+		{".eslintrc.js", []string{}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			if diff := cmp.Diff(tc.categories, Files{tc.file}.Categories()); diff != "" {
+				t.Errorf("unexpected categories (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - name: docs
+    include: [doc/**]
+  - name: sg
+    include: [dev/sg/**]
+  - name: go
+    include: ["**/*.go"]
+    exclude: ["**/testdata/**"]
+  - name: dockerfiles
+    include: ["**/Dockerfile"]
+  - name: graphql
+    include: ["**/*.graphql"]
+  - name: client
+    include: [client/**]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		files Files
+		want  bool
+	}{
+		{Files{"main.go"}, true},
+		{Files{"internal/database/repos.go"}, true},
+		{Files{"internal/database/testdata/repos.go"}, false},
+		{Files{"internal/database/repos.go.orig"}, false},
+		{Files{"README.md", "internal/database/testdata/repos.go", "cmd/frontend/main.go"}, true},
+		{Files{}, false},
+	} {
+		if got := rules.Affects(CategoryGo, tc.files); got != tc.want {
+			t.Errorf("Affects(%q, %q) = %v, want %v", CategoryGo, tc.files, got, tc.want)
+		}
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	valid := `
+  - name: docs
+    include: [doc/**]
+  - name: sg
+    include: [dev/sg/**]
+  - name: go
+    include: ["**/*.go"]
+  - name: dockerfiles
+    include: ["**/Dockerfile"]
+  - name: graphql
+    include: ["**/*.graphql"]
+`
+	for _, tc := range []struct {
+		name  string
+		rules string
+		err   string
+	}{
+		{
+			name:  "missing category",
+			rules: "rules:" + valid,
+			err:   `missing rule for category "client"`,
+		},
+		{
+			name:  "unknown category",
+			rules: "rules:" + valid + "  - name: client\n    include: [client/**]\n  - name: storybook\n    include: [client/**]\n",
+			err:   `rule "storybook": unknown category`,
+		},
+		{
+			name:  "duplicate category",
+			rules: "rules:" + valid + "  - name: client\n    include: [client/**]\n  - name: docs\n    include: [doc/**]\n",
+			err:   `rule "docs": duplicate rule for category`,
+		},
+		{
+			name:  "no include patterns",
+			rules: "rules:" + valid + "  - name: client\n    exclude: [client/**]\n",
+			err:   `rule "client": no include patterns`,
+		},
+		{
+			name:  "empty pattern",
+			rules: "rules:" + valid + "  - name: client\n    include: [\"\"]\n",
+			err:   `rule "client": empty pattern`,
+		},
+		{
+			name:  "missing name",
+			rules: "rules:\n  - include: [doc/**]\n",
+			err:   "rule 0: missing name",
+		},
+		{
+			name:  "unknown field",
+			rules: "rules:\n  - name: docs\n    includes: [doc/**]\n",
+			err:   "field includes not found",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRules([]byte(tc.rules))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("unexpected error %q, want it to contain %q", err, tc.err)
+			}
+		})
+	}
+}
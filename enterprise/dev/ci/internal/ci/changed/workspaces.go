@@ -0,0 +1,267 @@
+package changed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+)
+
+// Workspace is a yarn or pnpm workspace of the client code, e.g. client/web.
+type Workspace struct {
+	// Name is the package name of the workspace, e.g. @sourcegraph/web.
+	Name string
+	// Dir is the directory of the workspace, relative to the root of the
+	// repository.
+	Dir string
+	// Dependencies are the directories of the workspaces this workspace
+	// depends on, either as a package dependency or as a TypeScript project
+	// reference.
+	Dependencies []string
+}
+
+// Workspaces is the workspace dependency graph of the client code.
+type Workspaces []*Workspace
+
+// LoadWorkspaces returns the workspaces of the repository checked out at root,
+// as declared by the "workspaces" field of its package.json or by its
+// pnpm-workspace.yaml.
+func LoadWorkspaces(root string) (Workspaces, error) {
+	patterns, err := workspacePatterns(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspaces Workspaces
+	byName := map[string]*Workspace{}
+	dependencyNames := map[*Workspace][]string{}
+	references := map[*Workspace][]string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid workspace pattern %q", pattern)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			var pkg packageJSON
+			if err := readJSON(filepath.Join(match, "package.json"), &pkg); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			var tsconfig struct {
+				References []struct {
+					Path string `json:"path"`
+				} `json:"references"`
+			}
+			if err := readJSON(filepath.Join(match, "tsconfig.json"), &tsconfig); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+
+			dir, err := filepath.Rel(root, match)
+			if err != nil {
+				return nil, err
+			}
+			w := &Workspace{Name: pkg.Name, Dir: filepath.ToSlash(dir)}
+			workspaces = append(workspaces, w)
+			if w.Name != "" {
+				byName[w.Name] = w
+			}
+			for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies, pkg.OptionalDependencies} {
+				for name := range deps {
+					dependencyNames[w] = append(dependencyNames[w], name)
+				}
+			}
+			for _, r := range tsconfig.References {
+				references[w] = append(references[w], path.Join(w.Dir, filepath.ToSlash(r.Path)))
+			}
+		}
+	}
+
+	// Only keep the dependencies on other workspaces, e.g. not on packages
+	// from the registry or on TypeScript projects outside of workspaces.
+	byDir := map[string]*Workspace{}
+	for _, w := range workspaces {
+		byDir[w.Dir] = w
+	}
+	for _, w := range workspaces {
+		deps := map[string]struct{}{}
+		for _, name := range dependencyNames[w] {
+			if dep, ok := byName[name]; ok && dep != w {
+				deps[dep.Dir] = struct{}{}
+			}
+		}
+		for _, dir := range references[w] {
+			if dep, ok := byDir[dir]; ok && dep != w {
+				deps[dep.Dir] = struct{}{}
+			}
+		}
+		w.Dependencies = sortedKeys(deps)
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Dir < workspaces[j].Dir })
+	return workspaces, nil
+}
+
+type packageJSON struct {
+	Name                 string            `json:"name"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// workspacePatterns returns the glob patterns of the workspace directories of
+// the repository checked out at root.
+func workspacePatterns(root string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml"))
+	if err == nil {
+		var config struct {
+			Packages []string `yaml:"packages"`
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, errors.Wrap(err, "parsing pnpm-workspace.yaml")
+		}
+		return config.Packages, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// Yarn accepts either a list of patterns or an object with the list of
+	// patterns in its "packages" field.
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := readJSON(filepath.Join(root, "package.json"), &pkg); err != nil {
+		return nil, err
+	}
+	if len(pkg.Workspaces) == 0 {
+		return nil, nil
+	}
+	var patterns []string
+	if err := json.Unmarshal(pkg.Workspaces, &patterns); err == nil {
+		return patterns, nil
+	}
+	var workspaces struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(pkg.Workspaces, &workspaces); err != nil {
+		return nil, errors.Wrap(err, "parsing workspaces of package.json")
+	}
+	return workspaces.Packages, nil
+}
+
+// readJSON reads the JSON file at filename, which may contain comments and
+// trailing commas like tsconfig.json files, into v.
+func readJSON(filename string, v interface{}) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", filename)
+	}
+	if err := jsonc.Unmarshal(string(data), v); err != nil {
+		return errors.Wrapf(err, "parsing %s", filename)
+	}
+	return nil
+}
+
+// AffectedWorkspaces returns the sorted directories of the workspaces affected
+// by the changes, i.e. the workspaces that contain changed files affecting client
+// code and the workspaces that depend on them, directly or not.
+//
+// A nil slice is returned if changes outside of the workspaces affect client
+// code, e.g. to the root package.json or yarn.lock, in which case all the
+// workspaces are affected.
+func (f Files) AffectedWorkspaces(workspaces Workspaces) []string {
+	dependents := map[string][]string{}
+	for _, w := range workspaces {
+		for _, dep := range w.Dependencies {
+			dependents[dep] = append(dependents[dep], w.Dir)
+		}
+	}
+
+	affected := map[string]struct{}{}
+	var queue []string
+	for _, p := range f {
+		if !(Files{p}).AffectsClient() {
+			continue
+		}
+		w := enclosingWorkspace(workspaces, p)
+		if w == nil {
+			return nil
+		}
+		queue = append(queue, w.Dir)
+	}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		if _, ok := affected[dir]; ok {
+			continue
+		}
+		affected[dir] = struct{}{}
+		queue = append(queue, dependents[dir]...)
+	}
+	return sortedKeys(affected)
+}
+
+// enclosingWorkspace returns the workspace containing the file at p, if any.
+// The most nested workspace wins.
+func enclosingWorkspace(workspaces Workspaces, p string) *Workspace {
+	var enclosing *Workspace
+	for _, w := range workspaces {
+		if strings.HasPrefix(p, w.Dir+"/") && (enclosing == nil || len(w.Dir) > len(enclosing.Dir)) {
+			enclosing = w
+		}
+	}
+	return enclosing
+}
+
+var (
+	defaultWorkspacesOnce sync.Once
+	defaultWorkspaces     Workspaces
+	defaultWorkspacesErr  error
+)
+
+// AffectsClientWorkspaces returns whether the changes affect client code in any
+// of the workspaces with the given directories, e.g. client/web, including
+// through the workspaces they depend on. The workspaces are loaded from the
+// repository checked out in the working directory, and all workspaces are
+// considered affected if they can't be loaded.
+func (f Files) AffectsClientWorkspaces(dirs ...string) bool {
+	if !f.AffectsClient() {
+		return false
+	}
+
+	defaultWorkspacesOnce.Do(func() {
+		defaultWorkspaces, defaultWorkspacesErr = LoadWorkspaces(".")
+		if defaultWorkspacesErr != nil {
+			fmt.Fprintf(os.Stderr, "failed to load the client workspaces, running all client steps: %s\n", defaultWorkspacesErr)
+		}
+	})
+	if defaultWorkspacesErr != nil {
+		return true
+	}
+
+	affected := f.AffectedWorkspaces(defaultWorkspaces)
+	if affected == nil {
+		return true
+	}
+	for _, dir := range dirs {
+		i := sort.SearchStrings(affected, dir)
+		if i < len(affected) && affected[i] == dir {
+			return true
+		}
+	}
+	return false
+}
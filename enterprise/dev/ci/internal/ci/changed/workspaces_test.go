@@ -0,0 +1,94 @@
+package changed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	for name, contents := range map[string]string{
+		"package.json":                      `{"workspaces": {"packages": ["client/*"]}}`,
+		"client/shared/package.json":        `{"name": "@sourcegraph/shared", "dependencies": {"react": "^17.0.2"}}`,
+		"client/extension-api/package.json": `{"name": "sourcegraph"}`,
+		"client/web/package.json":           `{"name": "@sourcegraph/web", "devDependencies": {"sourcegraph": "*"}}`,
+		// tsconfig.json files may have trailing commas and comments.
+		"client/web/tsconfig.json": `{
+			// The schema is not a workspace.
+			"references": [{ "path": "../shared" }, { "path": "../../schema" },],
+		}`,
+		"client/README.md": "Not a workspace.",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	workspaces, err := LoadWorkspaces(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Workspaces{
+		{Name: "sourcegraph", Dir: "client/extension-api", Dependencies: []string{}},
+		{Name: "@sourcegraph/shared", Dir: "client/shared", Dependencies: []string{}},
+		{Name: "@sourcegraph/web", Dir: "client/web", Dependencies: []string{"client/extension-api", "client/shared"}},
+	}
+	if diff := cmp.Diff(want, workspaces); diff != "" {
+		t.Errorf("unexpected workspaces (-want +got):\n%s", diff)
+	}
+}
+
+func TestAffectedWorkspaces(t *testing.T) {
+	workspaces := Workspaces{
+		{Dir: "client/shared"},
+		{Dir: "client/branded", Dependencies: []string{"client/shared"}},
+		{Dir: "client/storybook", Dependencies: []string{"client/shared"}},
+		{Dir: "client/web", Dependencies: []string{"client/branded", "client/storybook"}},
+		{Dir: "client/browser", Dependencies: []string{"client/branded"}},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		files Files
+		want  []string
+	}{
+		{
+			name:  "leaf workspace",
+			files: Files{"client/web/src/index.tsx"},
+			want:  []string{"client/web"},
+		},
+		{
+			name:  "dependents",
+			files: Files{"client/branded/src/Button.tsx"},
+			want:  []string{"client/branded", "client/browser", "client/web"},
+		},
+		{
+			name:  "transitive dependents",
+			files: Files{"client/shared/src/api.ts"},
+			want:  []string{"client/branded", "client/browser", "client/shared", "client/storybook", "client/web"},
+		},
+		{
+			name:  "documentation",
+			files: Files{"client/shared/README.md", "internal/database/repos.go"},
+			want:  []string{},
+		},
+		{
+			name:  "root files",
+			files: Files{"client/web/src/index.tsx", "package.json"},
+			want:  nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.files.AffectedWorkspaces(workspaces)); diff != "" {
+				t.Errorf("unexpected affected workspaces (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/images"
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/changed"
+	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/shards"
 )
 
 // Config is the set of configuration parameters that determine the structure of the CI build. These
@@ -33,6 +34,15 @@ type Config struct {
 	// merge-base with origin/main.
 	ChangedFiles changed.Files
 
+	// GoTestPackages, if non-nil, is the list of packages of the main Go module
+	// whose tests may be affected by ChangedFiles. It is only computed for pull
+	// requests.
+	GoTestPackages []string
+
+	// TestTimings are the durations of tests in previous builds, read from the directory
+	// TEST_TIMINGS_DIR, used to balance test shards.
+	TestTimings shards.Timings
+
 	// ProfilingEnabled, if true, tells buildkite to print timing and resource utilization information
 	// for each command
 	ProfilingEnabled bool
@@ -93,6 +103,12 @@ func NewConfig(now time.Time) Config {
 		tag = tag + "_patch"
 	}
 
+	// only test the Go packages that depend on the changes in pull requests
+	var goTestPackages []string
+	if runType.Is(PullRequest) && changed.Files(changedFiles).AffectsGo() {
+		goTestPackages = affectedGoPackages(changedFiles)
+	}
+
 	return Config{
 		RunType: runType,
 
@@ -102,6 +118,8 @@ func NewConfig(now time.Time) Config {
 		Commit:            commit,
 		MustIncludeCommit: mustIncludeCommits,
 		ChangedFiles:      changedFiles,
+		GoTestPackages:    goTestPackages,
+		TestTimings:       loadTestTimings(),
 		BuildNumber:       buildNumber,
 
 		ProfilingEnabled: strings.Contains(branch, "buildkite-enable-profiling"),
@@ -109,6 +127,62 @@ func NewConfig(now time.Time) Config {
 
 }
 
+// NewPreviewConfig computes the configuration of a pull request build with the given changed
+// files, e.g. to preview the pipeline of local changes before pushing them.
+func NewPreviewConfig(now time.Time, changedFiles changed.Files) Config {
+	var commit, branch string
+	if output, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+		commit = strings.TrimSpace(string(output))
+	}
+	if output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+		branch = strings.TrimSpace(string(output))
+	}
+
+	var goTestPackages []string
+	if changedFiles.AffectsGo() {
+		goTestPackages = affectedGoPackages(changedFiles)
+	}
+
+	return Config{
+		RunType: PullRequest,
+
+		Time:           now,
+		Branch:         branch,
+		Version:        fmt.Sprintf("%05d_%s_%.7s", 0, now.Format("2006-01-02"), commit),
+		Commit:         commit,
+		ChangedFiles:   changedFiles,
+		GoTestPackages: goTestPackages,
+		TestTimings:    loadTestTimings(),
+	}
+}
+
+// loadTestTimings loads the test timings of previous builds from the directory
+// TEST_TIMINGS_DIR, if set.
+func loadTestTimings() shards.Timings {
+	dir := os.Getenv("TEST_TIMINGS_DIR")
+	if dir == "" {
+		return shards.Timings{}
+	}
+	timings, err := shards.LoadTimings(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load test timings, not balancing test shards: %s\n", err)
+		return shards.Timings{}
+	}
+	return timings
+}
+
+// affectedGoPackages returns the packages of the main Go module whose tests may be
+// affected by the changed files, based on the import graph of the repository. It returns
+// nil if all packages should be tested, including when the import graph can't be loaded.
+func affectedGoPackages(changedFiles changed.Files) []string {
+	packages, err := changed.LoadGoPackages(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load the Go import graph, testing all packages: %s\n", err)
+		return nil
+	}
+	return changedFiles.AffectedGoPackages(".", packages)
+}
+
 func (c Config) shortCommit() string {
 	// http://git-scm.com/book/en/v2/Git-Tools-Revision-Selection#Short-SHA-1
 	if len(c.Commit) < 12 {
@@ -0,0 +1,78 @@
+// Package flakes lists the test suites known to be flaky, whose steps CI retries
+// automatically. See flakes.yaml.
+package flakes
+
+import (
+	_ "embed"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed flakes.yaml
+var flakesYAML []byte
+
+// defaultFlakes are the flaky test suites Lookup looks up. An invalid flakes file fails the
+// generation of the pipeline.
+var defaultFlakes = mustParse(flakesYAML)
+
+// Flake is a test suite known to be flaky.
+type Flake struct {
+	// Label is the prefix of the labels of the steps running the test suite.
+	Label string `yaml:"label"`
+	// Issue is the URL of the issue tracking the flakiness of the test suite.
+	Issue string `yaml:"issue"`
+}
+
+// Flakes is a list of test suites known to be flaky.
+type Flakes struct {
+	Flakes []Flake `yaml:"flakes"`
+}
+
+// Parse parses and validates a flakes file.
+func Parse(data []byte) (*Flakes, error) {
+	var flakes Flakes
+	if err := yaml.UnmarshalStrict(data, &flakes); err != nil {
+		return nil, errors.Wrap(err, "parsing flakes")
+	}
+
+	for i, f := range flakes.Flakes {
+		if strings.TrimSpace(f.Label) == "" {
+			return nil, errors.Errorf("flake %d: missing label", i)
+		}
+		u, err := url.Parse(f.Issue)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, errors.Errorf("flake %q: issue must be the URL of the issue tracking the flakiness, got %q", f.Label, f.Issue)
+		}
+		if strings.ContainsAny(f.Issue, "'\" \t\n") {
+			return nil, errors.Errorf("flake %q: invalid issue URL %q", f.Label, f.Issue)
+		}
+	}
+	return &flakes, nil
+}
+
+func mustParse(data []byte) *Flakes {
+	flakes, err := Parse(data)
+	if err != nil {
+		panic(errors.Wrap(err, "invalid flakes"))
+	}
+	return flakes
+}
+
+// Match returns the flaky test suite run by the step with the given label, if any.
+func (f *Flakes) Match(label string) (Flake, bool) {
+	for _, flake := range f.Flakes {
+		if strings.HasPrefix(label, flake.Label) {
+			return flake, true
+		}
+	}
+	return Flake{}, false
+}
+
+// Lookup returns the flaky test suite listed in flakes.yaml run by the step with the given
+// label, if any.
+func Lookup(label string) (Flake, bool) {
+	return defaultFlakes.Match(label)
+}
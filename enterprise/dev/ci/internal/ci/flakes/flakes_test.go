@@ -0,0 +1,69 @@
+package flakes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	flakes, err := Parse([]byte(`
+flakes:
+  - label: ":puppeteer::electric_plug: Puppeteer tests chunk"
+    issue: https://github.com/sourcegraph/sourcegraph/issues/123
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flake, ok := flakes.Match(":puppeteer::electric_plug: Puppeteer tests chunk #2")
+	if !ok || flake.Issue != "https://github.com/sourcegraph/sourcegraph/issues/123" {
+		t.Errorf("expected the chunk to match the flake, got %+v (%v)", flake, ok)
+	}
+	if _, ok := flakes.Match(":go: Test"); ok {
+		t.Error("expected the Go tests not to match")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		flakes string
+		err    string
+	}{
+		{
+			name:   "missing label",
+			flakes: "flakes:\n  - issue: https://github.com/sourcegraph/sourcegraph/issues/123\n",
+			err:    "flake 0: missing label",
+		},
+		{
+			name:   "missing issue",
+			flakes: "flakes:\n  - label: \":go: Test\"\n",
+			err:    "issue must be the URL",
+		},
+		{
+			name:   "invalid issue",
+			flakes: "flakes:\n  - label: \":go: Test\"\n    issue: \"#123\"\n",
+			err:    "issue must be the URL",
+		},
+		{
+			name:   "quoted issue",
+			flakes: "flakes:\n  - label: \":go: Test\"\n    issue: \"https://example.com/'$(true)'\"\n",
+			err:    "invalid issue URL",
+		},
+		{
+			name:   "unknown field",
+			flakes: "flakes:\n  - label: \":go: Test\"\n    retries: 3\n",
+			err:    "field retries not found",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.flakes))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("unexpected error %q, want it to contain %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package ci
+
+import (
+	"fmt"
+
+	bk "github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/buildkite"
+	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/flakes"
+)
+
+// retryFlakySteps retries the command steps running test suites known to be flaky once,
+// and makes their retries annotate the build with a link to the issue tracking the
+// flakiness. See flakes/flakes.yaml.
+func retryFlakySteps(s *bk.Step) {
+	if len(s.Command) == 0 {
+		return
+	}
+	flake, ok := flakes.Lookup(s.Label)
+	if !ok {
+		return
+	}
+
+	if s.Retry == nil {
+		s.Retry = &bk.RetryOptions{}
+	}
+	if s.Retry.Automatic == nil || s.Retry.Automatic.Limit < 1 {
+		s.Retry.Automatic = &bk.AutomaticRetryOptions{Limit: 1}
+	}
+	s.Command = append([]string{fmt.Sprintf("./dev/ci/annotate-flaky-retry.sh '%s'", flake.Issue)}, s.Command...)
+}
@@ -3,14 +3,17 @@ package ci
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/images"
 	bk "github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/buildkite"
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/changed"
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/operations"
+	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/shards"
 )
 
 // CoreTestOperationsOptions should be used ONLY to adjust the behaviour of specific steps,
@@ -18,6 +21,11 @@ import (
 type CoreTestOperationsOptions struct {
 	// for clientChromaticTests
 	ChromaticShouldAutoAccept bool
+	// for addGoTests: if non-nil, only these packages of the main module are tested
+	GoTestPackages []string
+	// for addGoTests and clientIntegrationTests: timings of previous builds to balance
+	// test shards with
+	TestTimings shards.Timings
 }
 
 // CoreTestOperations is a core set of tests that should be run in most CI cases. More
@@ -44,20 +52,36 @@ func CoreTestOperations(changedFiles changed.Files, opts CoreTestOperationsOptio
 	})
 
 	if runAll || changedFiles.AffectsClient() {
-		ops.Append(
-			clientIntegrationTests,
-			clientChromaticTests(opts.ChromaticShouldAutoAccept),
-			frontendTests,   // ~4.5m
-			addWebApp,       // ~3m
-			addBrowserExt,   // ~2m
-			addBrandedTests, // ~1.5m
-			addTsLint,
-		)
+		// Only run the client steps of the workspaces affected by the changes,
+		// directly or through the workspaces they depend on.
+		affectsWorkspaces := func(dirs ...string) bool {
+			return runAll || changedFiles.AffectsClientWorkspaces(dirs...)
+		}
+		if affectsWorkspaces("client/web") {
+			ops.Append(clientIntegrationTests(opts.TestTimings))
+		}
+		// Storybook collects the stories of these workspaces.
+		if affectsWorkspaces("client/storybook", "client/branded", "client/browser", "client/shared", "client/web", "client/wildcard") {
+			ops.Append(clientChromaticTests(opts.ChromaticShouldAutoAccept))
+		}
+		if affectsWorkspaces("client/shared", "client/wildcard") {
+			ops.Append(frontendTests) // ~4.5m
+		}
+		if affectsWorkspaces("client/web") {
+			ops.Append(addWebApp) // ~3m
+		}
+		if affectsWorkspaces("client/browser") {
+			ops.Append(addBrowserExt) // ~2m
+		}
+		if affectsWorkspaces("client/branded") {
+			ops.Append(addBrandedTests) // ~1.5m
+		}
+		ops.Append(addTsLint)
 	}
 
 	if runAll || changedFiles.AffectsGo() {
 		ops.Append(
-			addGoTests,
+			addGoTests(opts.GoTestPackages, opts.TestTimings),
 		)
 
 		// If the changes are only in ./dev/sg then we skip the build
@@ -172,53 +196,54 @@ func addBrowserExt(pipeline *bk.Pipeline) {
 		bk.Cmd("dev/ci/codecov.sh -c -F typescript -F unit"))
 }
 
-func clientIntegrationTests(pipeline *bk.Pipeline) {
-	chunkSize := 3
-	prepStepKey := "puppeteer:prep"
-	skipGitCloneStep := bk.Plugin("uber-workflow/run-without-clone", "")
-
-	// Build web application used for integration tests to share it between multiple parallel steps.
-	pipeline.AddStep(":puppeteer::electric_plug: Puppeteer tests prep",
-		bk.Key(prepStepKey),
-		bk.Env("ENTERPRISE", "1"),
-		bk.Cmd("COVERAGE_INSTRUMENT=true dev/ci/yarn-build.sh client/web"),
-		bk.Cmd("dev/ci/create-client-artifact.sh"))
-
-	// Chunk web integration tests to save time via parallel execution.
-	chunkedTestFiles := getChunkedWebIntegrationFileNames(chunkSize)
-	// Percy finalize step should be executed after all integration tests.
-	puppeteerFinalizeDependencies := make([]bk.StepOpt, len(chunkedTestFiles))
-
-	// Add pipeline step for each chunk of web integrations files.
-	for i, chunkTestFiles := range chunkedTestFiles {
-		stepLabel := fmt.Sprintf(":puppeteer::electric_plug: Puppeteer tests chunk #%s", fmt.Sprint(i+1))
-
-		stepKey := fmt.Sprintf("puppeteer:chunk:%s", fmt.Sprint(i+1))
-		puppeteerFinalizeDependencies[i] = bk.DependsOn(stepKey)
-
-		pipeline.AddStep(stepLabel,
-			bk.Key(stepKey),
-			bk.DependsOn(prepStepKey),
-			bk.DisableManualRetry("The Percy build is finalized even if one of the concurrent agents fails. To retry correctly, restart the entire pipeline."),
-			percyBrowserExecutableEnv,
-			bk.Env("PERCY_ON", "true"),
-			bk.Cmd(fmt.Sprintf(`dev/ci/yarn-web-integration.sh "%s"`, chunkTestFiles)),
-			bk.ArtifactPaths("./puppeteer/*.png"))
-	}
+func clientIntegrationTests(timings shards.Timings) operations.Operation {
+	return func(pipeline *bk.Pipeline) {
+		prepStepKey := "puppeteer:prep"
+		skipGitCloneStep := bk.Plugin("uber-workflow/run-without-clone", "")
+
+		// Build web application used for integration tests to share it between multiple parallel steps.
+		pipeline.AddStep(":puppeteer::electric_plug: Puppeteer tests prep",
+			bk.Key(prepStepKey),
+			bk.Env("ENTERPRISE", "1"),
+			bk.Cmd("COVERAGE_INSTRUMENT=true dev/ci/yarn-build.sh client/web"),
+			bk.Cmd("dev/ci/create-client-artifact.sh"))
+
+		// Chunk web integration tests to save time via parallel execution.
+		chunkedTestFiles := getChunkedWebIntegrationFileNames(timings)
+		// Percy finalize step should be executed after all integration tests.
+		puppeteerFinalizeDependencies := make([]bk.StepOpt, len(chunkedTestFiles))
+
+		// Add pipeline step for each chunk of web integrations files.
+		for i, chunkTestFiles := range chunkedTestFiles {
+			stepLabel := fmt.Sprintf(":puppeteer::electric_plug: Puppeteer tests chunk #%s", fmt.Sprint(i+1))
+
+			stepKey := fmt.Sprintf("puppeteer:chunk:%s", fmt.Sprint(i+1))
+			puppeteerFinalizeDependencies[i] = bk.DependsOn(stepKey)
+
+			pipeline.AddStep(stepLabel,
+				bk.Key(stepKey),
+				bk.DependsOn(prepStepKey),
+				bk.DisableManualRetry("The Percy build is finalized even if one of the concurrent agents fails. To retry correctly, restart the entire pipeline."),
+				percyBrowserExecutableEnv,
+				bk.Env("PERCY_ON", "true"),
+				bk.Cmd(fmt.Sprintf(`dev/ci/yarn-web-integration.sh "%s"`, chunkTestFiles)),
+				bk.ArtifactPaths("./puppeteer/*.png;test-timings/*"))
+		}
 
-	finalizeSteps := []bk.StepOpt{
-		// Allow to teardown the Percy build even if there was a failure in the earlier Percy steps.
-		bk.AllowDependencyFailure(),
-		// Percy service often fails for obscure reasons. The step is pretty fast, so we
-		// just retry a few times.
-		bk.AutomaticRetry(3),
-		// Finalize just uses a remote package.
-		skipGitCloneStep,
-		bk.Cmd("npx @percy/cli build:finalize"),
-	}
+		finalizeSteps := []bk.StepOpt{
+			// Allow to teardown the Percy build even if there was a failure in the earlier Percy steps.
+			bk.AllowDependencyFailure(),
+			// Percy service often fails for obscure reasons. The step is pretty fast, so we
+			// just retry a few times.
+			bk.AutomaticRetry(3),
+			// Finalize just uses a remote package.
+			skipGitCloneStep,
+			bk.Cmd("npx @percy/cli build:finalize"),
+		}
 
-	pipeline.AddStep(":puppeteer::electric_plug: Puppeteer tests finalize",
-		append(finalizeSteps, puppeteerFinalizeDependencies...)...)
+		pipeline.AddStep(":puppeteer::electric_plug: Puppeteer tests finalize",
+			append(finalizeSteps, puppeteerFinalizeDependencies...)...)
+	}
 }
 
 func clientChromaticTests(autoAcceptChanges bool) operations.Operation {
@@ -258,12 +283,62 @@ func addBrandedTests(pipeline *bk.Pipeline) {
 		bk.Cmd("dev/ci/codecov.sh -c -F typescript -F unit"))
 }
 
-// Adds the Go test step.
-func addGoTests(pipeline *bk.Pipeline) {
-	pipeline.AddStep(":go: Test",
-		bk.Cmd("./dev/ci/go-test.sh"),
-		bk.Cmd("dev/ci/codecov.sh -c -F go"),
-		bk.ArtifactPaths("$HOME/.sourcegraph-dev/logs/**/*"))
+// goTestShardOptions balance the Go tests of the main module across steps of about 10
+// minutes.
+var goTestShardOptions = shards.Options{
+	Target:    10 * time.Minute,
+	MaxShards: 6,
+}
+
+// Adds the Go test step. If packages is non-nil, only these packages of the main module
+// are tested, and the other modules are tested entirely. If timings of the Go tests are
+// available, the tests of the main module are split across several steps.
+func addGoTests(packages []string, timings shards.Timings) operations.Operation {
+	return func(pipeline *bk.Pipeline) {
+		stepOpts := func(packages []string) []bk.StepOpt {
+			opts := []bk.StepOpt{
+				bk.Cmd("./dev/ci/go-test.sh"),
+				bk.Cmd("dev/ci/codecov.sh -c -F go"),
+				bk.ArtifactPaths("$HOME/.sourcegraph-dev/logs/**/*;test-timings/*"),
+			}
+			if packages != nil {
+				opts = append(opts, bk.Env("GO_TEST_PACKAGES", strings.Join(packages, " ")))
+			}
+			return opts
+		}
+
+		goTestShards := shardGoTests(packages, timings)
+		if len(goTestShards) <= 1 {
+			pipeline.AddStep(":go: Test", stepOpts(packages)...)
+			return
+		}
+		for i, shard := range goTestShards {
+			opts := stepOpts(shard)
+			// The other modules are tested by the first shard only.
+			if i > 0 {
+				opts = append(opts, bk.Env("GO_TEST_SKIP_OTHER_MODULES", "true"))
+			}
+			pipeline.AddStep(fmt.Sprintf(":go: Test (%d/%d)", i+1, len(goTestShards)), opts...)
+		}
+	}
+}
+
+// shardGoTests splits the given packages of the main module, or all its packages if nil,
+// into shards based on the timings of the Go tests. It returns nil if there are no timings
+// of the Go tests to balance the shards with.
+func shardGoTests(packages []string, timings shards.Timings) [][]string {
+	if len(timings[shards.SuiteGo]) == 0 {
+		return nil
+	}
+	if packages == nil {
+		output, err := exec.Command("go", "list", "./...").Output()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list Go packages, not sharding Go tests: %s\n", err)
+			return nil
+		}
+		packages = strings.Fields(string(output))
+	}
+	return timings.Split(shards.SuiteGo, packages, goTestShardOptions)
 }
 
 // Builds the OSS and Enterprise Go commands.
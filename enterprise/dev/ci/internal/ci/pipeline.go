@@ -10,6 +10,7 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/images"
 	bk "github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/buildkite"
+	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/changed"
 	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/operations"
 )
 
@@ -71,6 +72,9 @@ func GeneratePipeline(c Config) (*bk.Pipeline, error) {
 		}
 	})
 
+	// Retry the steps of known flaky test suites, and report their retries
+	bk.AfterEveryStepOpts = append(bk.AfterEveryStepOpts, retryFlakySteps)
+
 	// Toggle profiling of each step
 	if c.ProfilingEnabled {
 		bk.AfterEveryStepOpts = append(bk.AfterEveryStepOpts, func(s *bk.Step) {
@@ -91,13 +95,10 @@ func GeneratePipeline(c Config) (*bk.Pipeline, error) {
 	// PERF: Try to order steps such that slower steps are first.
 	switch c.RunType {
 	case PullRequest:
-		if c.ChangedFiles.AffectsClient() {
-			// triggers a slow pipeline, currently only affects web. It's optional so we
-			// set it up separately from CoreTestOperations
-			ops.Append(triggerAsync(buildOptions))
-		}
-
-		ops.Merge(CoreTestOperations(c.ChangedFiles, CoreTestOperationsOptions{}))
+		ops.Merge(pullRequestOperations(c.ChangedFiles, CoreTestOperationsOptions{
+			GoTestPackages: c.GoTestPackages,
+			TestTimings:    c.TestTimings,
+		}, buildOptions))
 
 	case BextReleaseBranch:
 		// If this is a browser extension release branch, run the browser-extension tests and
@@ -174,6 +175,7 @@ func GeneratePipeline(c Config) (*bk.Pipeline, error) {
 		// Core tests
 		ops.Merge(CoreTestOperations(nil, CoreTestOperationsOptions{
 			ChromaticShouldAutoAccept: c.RunType.Is(MainBranch),
+			TestTimings:               c.TestTimings,
 		}))
 
 		// Trigger e2e late so that it can leverage candidate images
@@ -210,3 +212,32 @@ func GeneratePipeline(c Config) (*bk.Pipeline, error) {
 	ops.Apply(pipeline)
 	return pipeline, nil
 }
+
+// pullRequestOperations returns the operations of a pull request build with the
+// given changed files.
+func pullRequestOperations(changedFiles changed.Files, opts CoreTestOperationsOptions, buildOptions bk.BuildOptions) *operations.Set {
+	var ops operations.Set
+	if changedFiles.AffectsClient() {
+		// triggers a slow pipeline, currently only affects web. It's optional so we
+		// set it up separately from CoreTestOperations
+		ops.Append(triggerAsync(buildOptions))
+	}
+
+	ops.Merge(CoreTestOperations(changedFiles, opts))
+	return &ops
+}
+
+// PullRequestSteps returns the labels of the steps a pull request build with
+// the given changed files runs. It implements changed.StepsFunc.
+func PullRequestSteps(changedFiles changed.Files) []string {
+	pipeline := &bk.Pipeline{}
+	pullRequestOperations(changedFiles, CoreTestOperationsOptions{}, bk.BuildOptions{}).Apply(pipeline)
+
+	var labels []string
+	for _, s := range pipeline.Steps {
+		if step, ok := s.(*bk.Step); ok {
+			labels = append(labels, step.Label)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,161 @@
+// Package shards splits test suites into shards of similar durations that run in parallel
+// steps, based on the durations of the tests recorded by previous builds.
+//
+// Test steps record timings as tab-separated lines of the form "<suite>\t<unit>\t<seconds>",
+// e.g. "go\tgithub.com/sourcegraph/sourcegraph/internal/database\t42.5", in files uploaded
+// as test-timings/*.tsv artifacts.
+package shards
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Suites of tests that are sharded. Units of the Go suite are the import paths of packages,
+// units of the web integration suite are test files.
+const (
+	SuiteGo             = "go"
+	SuiteWebIntegration = "web-integration"
+)
+
+// Timings are the durations of the units of each suite of tests.
+type Timings map[string]map[string]time.Duration
+
+// LoadTimings reads the timings of all the *.tsv files in dir. If a unit is listed more
+// than once, its longest duration is kept. A missing dir yields no timings.
+func LoadTimings(dir string) (Timings, error) {
+	timings := Timings{}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tsv"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = timings.Read(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", name)
+		}
+	}
+	return timings, nil
+}
+
+// Read adds the timings read from r.
+func (t Timings) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			return errors.Errorf("line %d: expected 3 tab-separated fields, got %d", line, len(fields))
+		}
+		seconds, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || seconds < 0 {
+			return errors.Errorf("line %d: invalid duration %q", line, fields[2])
+		}
+
+		suite, unit, duration := fields[0], fields[1], time.Duration(seconds*float64(time.Second))
+		if t[suite] == nil {
+			t[suite] = map[string]time.Duration{}
+		}
+		if duration > t[suite][unit] {
+			t[suite][unit] = duration
+		}
+	}
+	return scanner.Err()
+}
+
+// Options configure how a suite is split into shards.
+type Options struct {
+	// Target is the duration each shard should take.
+	Target time.Duration
+	// MaxShards is the maximum number of shards.
+	MaxShards int
+	// Default is the duration of units without timings, used if the suite has no timings at
+	// all. Otherwise, the average duration of the units with timings is used.
+	Default time.Duration
+}
+
+// Split splits the units of the given suite into shards that take about the same time, using
+// as few shards as possible for each to take about opts.Target. Units are assigned longest
+// first to the shard that would end the soonest, and the units of each shard are sorted.
+func (t Timings) Split(suite string, units []string, opts Options) [][]string {
+	if len(units) == 0 {
+		return nil
+	}
+
+	estimate := opts.Default
+	if known := t[suite]; len(known) > 0 {
+		var total time.Duration
+		for _, d := range known {
+			total += d
+		}
+		estimate = total / time.Duration(len(known))
+	}
+
+	type weightedUnit struct {
+		name     string
+		duration time.Duration
+	}
+	weighted := make([]weightedUnit, 0, len(units))
+	var total time.Duration
+	for _, u := range units {
+		d, ok := t[suite][u]
+		if !ok {
+			d = estimate
+		}
+		weighted = append(weighted, weightedUnit{name: u, duration: d})
+		total += d
+	}
+	sort.Slice(weighted, func(i, j int) bool {
+		if weighted[i].duration != weighted[j].duration {
+			return weighted[i].duration > weighted[j].duration
+		}
+		return weighted[i].name < weighted[j].name
+	})
+
+	n := 1
+	if opts.Target > 0 {
+		n = int(math.Ceil(float64(total) / float64(opts.Target)))
+	}
+	if opts.MaxShards > 0 && n > opts.MaxShards {
+		n = opts.MaxShards
+	}
+	if n > len(units) {
+		n = len(units)
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	shards := make([][]string, n)
+	durations := make([]time.Duration, n)
+	for _, u := range weighted {
+		shortest := 0
+		for i := range durations {
+			if durations[i] < durations[shortest] {
+				shortest = i
+			}
+		}
+		shards[shortest] = append(shards[shortest], u.name)
+		durations[shortest] += u.duration
+	}
+	for _, shard := range shards {
+		sort.Strings(shard)
+	}
+	return shards
+}
@@ -0,0 +1,97 @@
+package shards
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTimingsRead(t *testing.T) {
+	timings := Timings{}
+	err := timings.Read(strings.NewReader("go\ta\t1.5\n\ngo\ta\t0.5\nweb-integration\tclient/web/src/integration/search.test.ts\t60\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Timings{
+		SuiteGo:             {"a": 1500 * time.Millisecond},
+		SuiteWebIntegration: {"client/web/src/integration/search.test.ts": time.Minute},
+	}
+	if diff := cmp.Diff(want, timings); diff != "" {
+		t.Errorf("unexpected timings (-want +got):\n%s", diff)
+	}
+
+	for _, invalid := range []string{"go\ta\n", "go\ta\tfast\n", "go\ta\t-1\n"} {
+		if err := (Timings{}).Read(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	timings := Timings{SuiteGo: {
+		"a": 8 * time.Minute,
+		"b": 5 * time.Minute,
+		"c": 4 * time.Minute,
+		"d": 3 * time.Minute,
+		"e": 2 * time.Minute,
+	}}
+
+	for _, tc := range []struct {
+		name  string
+		units []string
+		opts  Options
+		want  [][]string
+	}{
+		{
+			name:  "balanced",
+			units: []string{"a", "b", "c", "d", "e"},
+			opts:  Options{Target: 11 * time.Minute},
+			want:  [][]string{{"a", "d"}, {"b", "c", "e"}},
+		},
+		{
+			name:  "max shards",
+			units: []string{"a", "b", "c", "d", "e"},
+			opts:  Options{Target: time.Minute, MaxShards: 3},
+			want:  [][]string{{"a"}, {"b", "e"}, {"c", "d"}},
+		},
+		{
+			name:  "fewer units than shards",
+			units: []string{"a", "b"},
+			opts:  Options{Target: time.Minute},
+			want:  [][]string{{"a"}, {"b"}},
+		},
+		{
+			// Units without timings take the average duration of the suite, 4.4m.
+			name:  "unknown units",
+			units: []string{"a", "new1", "new2"},
+			opts:  Options{Target: 9 * time.Minute},
+			want:  [][]string{{"a"}, {"new1", "new2"}},
+		},
+		{
+			name:  "no target",
+			units: []string{"c", "a"},
+			opts:  Options{},
+			want:  [][]string{{"a", "c"}},
+		},
+		{
+			name:  "no units",
+			units: nil,
+			opts:  Options{Target: time.Minute},
+			want:  nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, timings.Split(SuiteGo, tc.units, tc.opts)); diff != "" {
+				t.Errorf("unexpected shards (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Without timings, the default duration is used.
+	got := Timings{}.Split(SuiteWebIntegration, []string{"x", "y", "z"}, Options{Target: 2 * time.Minute, Default: time.Minute})
+	if diff := cmp.Diff([][]string{{"x", "z"}, {"y"}}, got); diff != "" {
+		t.Errorf("unexpected shards (-want +got):\n%s", diff)
+	}
+}
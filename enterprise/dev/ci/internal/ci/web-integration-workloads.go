@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/dev/ci/internal/ci/shards"
 )
 
 // Code in this file is used to split web integration tests workloads.
@@ -39,32 +42,19 @@ func getWebIntegrationFileNames() []string {
 	return fileNames
 }
 
-func chunkItems(items []string, size int) [][]string {
-	lenItems := len(items)
-	lenChunks := lenItems/size + 1
-	chunks := make([][]string, lenChunks)
-
-	for i := 0; i < lenChunks; i++ {
-		start := i * size
-		end := min(start+size, lenItems)
-		chunks[i] = items[start:end]
-	}
-
-	return chunks
-}
-
-func min(x int, y int) int {
-	if x < y {
-		return x
-	}
-
-	return y
+// webIntegrationShardOptions balance the web integration tests across steps of about 3
+// minutes, assuming test files without timings take a minute.
+var webIntegrationShardOptions = shards.Options{
+	Target:    3 * time.Minute,
+	MaxShards: 8,
+	Default:   time.Minute,
 }
 
-// getChunkedWebIntegrationFileNames gets web integration test filenames and splits them in chunks for parallelizing client integration tests.
-func getChunkedWebIntegrationFileNames(chunkSize int) []string {
+// getChunkedWebIntegrationFileNames gets web integration test filenames and splits them in chunks
+// of similar durations, based on the given timings, for parallelizing client integration tests.
+func getChunkedWebIntegrationFileNames(timings shards.Timings) []string {
 	testFiles := getWebIntegrationFileNames()
-	chunkedTestFiles := chunkItems(testFiles, chunkSize)
+	chunkedTestFiles := timings.Split(shards.SuiteWebIntegration, testFiles, webIntegrationShardOptions)
 	chunkedTestFileStrings := make([]string, len(chunkedTestFiles))
 
 	for i, v := range chunkedTestFiles {
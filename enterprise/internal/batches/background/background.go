@@ -15,18 +15,24 @@ import (
 func Routines(ctx context.Context, batchesStore *store.Store, cf *httpcli.Factory, observationContext *observation.Context) []goroutine.BackgroundRoutine {
 	sourcer := sources.NewSourcer(cf)
 	metrics := newMetrics(observationContext)
+	observationContext.Registerer.MustRegister(newBatchSpecResolutionQueueCollector(batchesStore))
 
 	reconcilerWorkerStore := NewReconcilerDBWorkerStore(batchesStore.Handle(), observationContext)
 	bulkProcessorWorkerStore := NewBulkOperationDBWorkerStore(batchesStore.Handle(), observationContext)
 
 	batchSpecWorkspaceExecutionWorkerStore := NewBatchSpecWorkspaceExecutionWorkerStore(batchesStore.Handle(), observationContext)
 	batchSpecResolutionWorkerStore := newBatchSpecResolutionWorkerStore(batchesStore.Handle(), observationContext)
+	batchChangeRollbackWorkerStore := newBatchChangeRollbackWorkerStore(batchesStore.Handle(), observationContext)
+	outboundWebhookWorkerStore := newOutboundWebhookWorkerStore(batchesStore.Handle(), observationContext)
 
 	routines := []goroutine.BackgroundRoutine{
 		newReconcilerWorker(ctx, batchesStore, reconcilerWorkerStore, gitserver.DefaultClient, sourcer, metrics),
 		newReconcilerWorkerResetter(reconcilerWorkerStore, metrics),
 
 		newSpecExpireJob(ctx, batchesStore),
+		newChangesetRebaser(ctx, batchesStore),
+		newBatchSpecResolutionJanitor(ctx, batchesStore),
+		newBatchSpecExecutionScheduler(ctx, batchesStore),
 
 		scheduler.NewScheduler(ctx, batchesStore),
 
@@ -37,6 +43,13 @@ func Routines(ctx context.Context, batchesStore *store.Store, cf *httpcli.Factor
 		newBatchSpecResolutionWorkerResetter(batchSpecResolutionWorkerStore, metrics),
 
 		newBatchSpecWorkspaceExecutionWorkerResetter(batchSpecWorkspaceExecutionWorkerStore, metrics),
+
+		newBatchChangeRollbackWorker(ctx, batchesStore, batchChangeRollbackWorkerStore, metrics),
+		newBatchChangeRollbackWorkerResetter(batchChangeRollbackWorkerStore, metrics),
+
+		newOutboundWebhookEnqueuer(ctx, batchesStore),
+		newOutboundWebhookWorker(ctx, batchesStore, outboundWebhookWorkerStore, cf, metrics),
+		newOutboundWebhookWorkerResetter(outboundWebhookWorkerStore, metrics),
 	}
 	return routines
 }
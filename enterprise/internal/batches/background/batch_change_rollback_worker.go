@@ -0,0 +1,143 @@
+package background
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/service"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+// batchChangeRollbackMaxNumRetries is the maximum number of attempts the
+// rollback worker makes to roll back a batch change when it fails.
+const batchChangeRollbackMaxNumRetries = 5
+
+// batchChangeRollbackMaxNumResets is the maximum number of attempts the
+// rollback worker makes to roll back a batch change when it stalls (process
+// crashes, etc.).
+const batchChangeRollbackMaxNumResets = 60
+
+// newBatchChangeRollbackWorker creates a dbworker.Worker that fetches enqueued
+// batch_change_rollback_jobs from the database and rolls back their batch
+// changes.
+func newBatchChangeRollbackWorker(
+	ctx context.Context,
+	s *store.Store,
+	workerStore dbworkerstore.Store,
+	metrics batchChangesMetrics,
+) *workerutil.Worker {
+	r := &batchChangeRollbackWorker{store: s}
+
+	options := workerutil.WorkerOptions{
+		Name:              "batches_batch_change_rollback_worker",
+		NumHandlers:       1,
+		HeartbeatInterval: 15 * time.Second,
+		Interval:          5 * time.Second,
+		Metrics:           metrics.batchChangeRollbackWorkerMetrics,
+	}
+
+	worker := dbworker.NewWorker(ctx, workerStore, r.HandlerFunc(), options)
+	return worker
+}
+
+// newBatchChangeRollbackWorkerResetter creates a dbworker.Resetter that
+// reenqueues lost rollback jobs for processing.
+func newBatchChangeRollbackWorkerResetter(workerStore dbworkerstore.Store, metrics batchChangesMetrics) *dbworker.Resetter {
+	options := dbworker.ResetterOptions{
+		Name:     "batches_batch_change_rollback_worker_resetter",
+		Interval: 1 * time.Minute,
+		Metrics:  metrics.batchChangeRollbackWorkerResetterMetrics,
+	}
+
+	resetter := dbworker.NewResetter(workerStore, options)
+	return resetter
+}
+
+func newBatchChangeRollbackWorkerStore(handle *basestore.TransactableHandle, observationContext *observation.Context) dbworkerstore.Store {
+	options := dbworkerstore.Options{
+		Name:              "batches_batch_change_rollback_worker_store",
+		TableName:         "batch_change_rollback_jobs",
+		ColumnExpressions: store.BatchChangeRollbackJobColumns.ToSqlf(),
+		Scan:              scanFirstBatchChangeRollbackJobRecord,
+
+		OrderByExpression: sqlf.Sprintf("batch_change_rollback_jobs.state = 'errored', batch_change_rollback_jobs.updated_at DESC"),
+
+		// Rolling back waits for changesets that are currently being
+		// processed by the reconciler, which can take up to two minutes per
+		// step.
+		StalledMaxAge: 5 * time.Minute,
+		MaxNumResets:  batchChangeRollbackMaxNumResets,
+
+		RetryAfter:    30 * time.Second,
+		MaxNumRetries: batchChangeRollbackMaxNumRetries,
+	}
+
+	return dbworkerstore.NewWithMetrics(handle, options, observationContext)
+}
+
+// scanFirstBatchChangeRollbackJobRecord wraps
+// store.ScanFirstBatchChangeRollbackJob to return a generic workerutil.Record.
+func scanFirstBatchChangeRollbackJobRecord(rows *sql.Rows, err error) (workerutil.Record, bool, error) {
+	return store.ScanFirstBatchChangeRollbackJob(rows, err)
+}
+
+// batchChangeRollbackWorker is a wrapper for the workerutil handlerfunc to
+// roll back batch changes with a store.
+type batchChangeRollbackWorker struct {
+	store *store.Store
+}
+
+func (b *batchChangeRollbackWorker) HandlerFunc() workerutil.HandlerFunc {
+	return func(ctx context.Context, record workerutil.Record) error {
+		job := record.(*btypes.BatchChangeRollbackJob)
+		return rollbackBatchChange(ctx, service.New(b.store), b.store, job)
+	}
+}
+
+// rollbackBatchChange undoes the effects of applying the batch change of the
+// given job on the code hosts: changesets that haven't been published yet are
+// no longer published, published changesets are closed, and the batch change
+// itself is closed so that it can't be applied to again by accident.
+//
+// The steps wait for changesets that the reconciler is processing, so they
+// don't share a transaction that would hold on to the changesets for minutes.
+// Every step can be repeated, so a retried job picks up where a failed one
+// stopped.
+func rollbackBatchChange(ctx context.Context, svc *service.Service, s *store.Store, job *btypes.BatchChangeRollbackJob) error {
+	// The batch change is rolled back on behalf of the user that requested
+	// it, so that their permissions are checked and the audit log records
+	// them.
+	ctx = actor.WithActor(ctx, actor.FromUser(job.UserID))
+
+	batchChange, err := s.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: job.BatchChangeID})
+	if err != nil {
+		return errors.Wrap(err, "getting batch change")
+	}
+
+	if err := s.CancelQueuedBatchChangeChangesets(ctx, batchChange.ID); err != nil {
+		return err
+	}
+
+	if !batchChange.Closed() {
+		_, err := svc.CloseBatchChange(ctx, batchChange.ID, true)
+		return err
+	}
+
+	// The batch change was closed before, possibly without closing its
+	// changesets.
+	if err := s.EnqueueChangesetsToClose(ctx, batchChange.ID); err != nil {
+		return errors.Wrap(err, "enqueueing changesets to close")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package background
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/service"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/timeutil"
+)
+
+func TestRollbackBatchChange(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+
+	now := timeutil.Now()
+	clock := func() time.Time { return now }
+	s := store.NewWithClock(db, &observation.TestContext, nil, clock)
+	svc := service.New(s)
+
+	user := ct.CreateTestUser(t, db, false)
+	repo, _ := ct.CreateTestRepo(t, ctx, db)
+
+	createBatchChange := func(t *testing.T, name string) (*btypes.BatchChange, *btypes.Changeset, *btypes.Changeset) {
+		t.Helper()
+
+		spec := ct.CreateBatchSpec(t, ctx, s, name, user.ID)
+		batchChange := ct.CreateBatchChange(t, ctx, s, name, user.ID, spec.ID)
+
+		published := ct.CreateChangeset(t, ctx, s, ct.TestChangesetOpts{
+			Repo:               repo.ID,
+			BatchChange:        batchChange.ID,
+			OwnedByBatchChange: batchChange.ID,
+			PublicationState:   btypes.ChangesetPublicationStatePublished,
+			ExternalState:      btypes.ChangesetExternalStateOpen,
+			ReconcilerState:    btypes.ReconcilerStateCompleted,
+		})
+		unpublished := ct.CreateChangeset(t, ctx, s, ct.TestChangesetOpts{
+			Repo:               repo.ID,
+			BatchChange:        batchChange.ID,
+			OwnedByBatchChange: batchChange.ID,
+			PublicationState:   btypes.ChangesetPublicationStateUnpublished,
+			ReconcilerState:    btypes.ReconcilerStateQueued,
+		})
+		return batchChange, published, unpublished
+	}
+
+	assertRolledBack := func(t *testing.T, batchChange *btypes.BatchChange, published, unpublished *btypes.Changeset) {
+		t.Helper()
+
+		have, err := s.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: batchChange.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !have.Closed() {
+			t.Fatal("batch change not closed")
+		}
+
+		c, err := s.GetChangeset(ctx, store.GetChangesetOpts{ID: published.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.Closing || c.ReconcilerState != btypes.ReconcilerStateQueued {
+			t.Fatalf("published changeset not enqueued to close: closing=%t, state=%s", c.Closing, c.ReconcilerState)
+		}
+
+		c, err = s.GetChangeset(ctx, store.GetChangesetOpts{ID: unpublished.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.ReconcilerState != btypes.ReconcilerStateFailed {
+			t.Fatalf("unpublished changeset not canceled: state=%s", c.ReconcilerState)
+		}
+	}
+
+	t.Run("open batch change", func(t *testing.T) {
+		batchChange, published, unpublished := createBatchChange(t, "open")
+
+		job := &btypes.BatchChangeRollbackJob{BatchChangeID: batchChange.ID, UserID: user.ID}
+		if err := rollbackBatchChange(ctx, svc, s, job); err != nil {
+			t.Fatal(err)
+		}
+		assertRolledBack(t, batchChange, published, unpublished)
+
+		events, _, err := s.ListAuditEvents(ctx, store.ListAuditEventsOpts{
+			ResourceType: btypes.AuditEventResourceTypeBatchChange,
+			ResourceID:   batchChange.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Action != btypes.AuditEventActionClosed || events[0].ActorUserID != user.ID {
+			t.Fatalf("wrong audit events for rolled back batch change: %+v", events)
+		}
+	})
+
+	t.Run("batch change closed without its changesets", func(t *testing.T) {
+		batchChange, published, unpublished := createBatchChange(t, "closed")
+		batchChange.ClosedAt = now
+		if err := s.UpdateBatchChange(ctx, batchChange); err != nil {
+			t.Fatal(err)
+		}
+
+		job := &btypes.BatchChangeRollbackJob{BatchChangeID: batchChange.ID, UserID: user.ID}
+		if err := rollbackBatchChange(ctx, svc, s, job); err != nil {
+			t.Fatal(err)
+		}
+		assertRolledBack(t, batchChange, published, unpublished)
+	})
+}
@@ -0,0 +1,59 @@
+package background
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/service"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+)
+
+const batchSpecExecutionSchedulerInterval = 1 * time.Minute
+
+// newBatchSpecExecutionScheduler periodically starts the runs of due batch
+// spec execution schedules, and executes the batch specs of previous runs
+// whose workspaces have been resolved in the meantime.
+func newBatchSpecExecutionScheduler(ctx context.Context, s *store.Store) goroutine.BackgroundRoutine {
+	return goroutine.NewPeriodicGoroutine(
+		ctx,
+		batchSpecExecutionSchedulerInterval,
+		goroutine.NewHandlerWithErrorMessage("run scheduled batch spec executions", func(ctx context.Context) error {
+			return runBatchSpecExecutionSchedules(ctx, service.New(s), s)
+		}),
+	)
+}
+
+func runBatchSpecExecutionSchedules(ctx context.Context, svc *service.Service, s *store.Store) error {
+	var errs *multierror.Error
+
+	// Execute pending batch specs first, so that a run whose resolution
+	// finished doesn't cause the next one to be skipped.
+	pending, _, err := s.ListBatchSpecExecutionSchedules(ctx, store.ListBatchSpecExecutionSchedulesOpts{OnlyPending: true})
+	if err != nil {
+		return err
+	}
+	for _, sched := range pending {
+		if err := svc.ExecutePendingScheduledBatchSpec(ctx, sched); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	due, _, err := s.ListBatchSpecExecutionSchedules(ctx, store.ListBatchSpecExecutionSchedulesOpts{DueAt: s.Clock()()})
+	if err != nil {
+		return err
+	}
+	for _, sched := range due {
+		// Scheduled runs create batch specs on behalf of the user of the
+		// schedule, with their permissions.
+		userCtx := actor.WithActor(ctx, actor.FromUser(sched.UserID))
+		if err := svc.RunBatchSpecExecutionSchedule(userCtx, sched); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,62 @@
+package background
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+)
+
+const batchSpecResolutionJanitorInterval = 1 * time.Hour
+
+// newBatchSpecResolutionJanitor periodically cleans up batch spec resolution
+// jobs that finished longer ago than the configured retention period, so that
+// the table and the execution logs in it don't grow unbounded.
+func newBatchSpecResolutionJanitor(ctx context.Context, s *store.Store) goroutine.BackgroundRoutine {
+	return goroutine.NewPeriodicGoroutine(
+		ctx,
+		batchSpecResolutionJanitorInterval,
+		goroutine.NewHandlerWithErrorMessage("clean up batch spec resolution jobs", func(ctx context.Context) error {
+			finishedBefore := s.Clock()().Add(-conf.BatchChangesResolutionJobRetention())
+
+			if err := deleteFinishedDryRunResolutionJobs(ctx, s, finishedBefore); err != nil {
+				return err
+			}
+			if err := s.PurgeBatchSpecResolutionJobLogs(ctx, finishedBefore); err != nil {
+				return errors.Wrap(err, "PurgeBatchSpecResolutionJobLogs")
+			}
+			return nil
+		}),
+	)
+}
+
+// deleteFinishedDryRunResolutionJobs deletes the dry-run resolution jobs that
+// finished before the given time and records their deletion in the audit log.
+func deleteFinishedDryRunResolutionJobs(ctx context.Context, s *store.Store, finishedBefore time.Time) (err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	dryRun := true
+	deletedIDs, err := tx.DeleteBatchSpecResolutionJobs(ctx, store.DeleteBatchSpecResolutionJobsOpts{
+		DryRun: &dryRun,
+		// Errored jobs are never retried, so they count as finished.
+		States: []btypes.BatchSpecResolutionJobState{
+			btypes.BatchSpecResolutionJobStateCompleted,
+			btypes.BatchSpecResolutionJobStateFailed,
+			btypes.BatchSpecResolutionJobStateErrored,
+		},
+		FinishedBefore: finishedBefore,
+	})
+	if err != nil {
+		return errors.Wrap(err, "DeleteBatchSpecResolutionJobs")
+	}
+	return recordExpiryAuditEvents(ctx, tx, btypes.AuditEventResourceTypeBatchSpecResolutionJob, deletedIDs)
+}
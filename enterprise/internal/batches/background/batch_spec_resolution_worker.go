@@ -2,12 +2,11 @@ package background
 
 import (
 	"context"
-	"database/sql"
+	"sync"
 	"time"
 
-	"github.com/keegancsmith/sqlf"
-
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil"
@@ -15,27 +14,36 @@ import (
 	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
 )
 
-// batchSpecResolutionMaxNumRetries sets the number of retries for batch spec
-// resolutions to 0. We don't want to retry automatically and instead wait for
-// user input
-const batchSpecResolutionMaxNumRetries = 0
 const batchSpecResolutionMaxNumResets = 60
 
+// Resolution jobs are usually done within seconds and users wait for them in
+// the UI, so a job whose worker crashed is picked up again quickly instead of
+// after the default dbworker timeouts. Workers heartbeat every
+// batchSpecResolutionHeartbeatInterval, and a job that hasn't seen a heartbeat
+// for batchSpecResolutionStalledMaxAge, which tolerates a few missed ones, is
+// put back into the queue by the resetter within
+// batchSpecResolutionResetInterval.
+const (
+	batchSpecResolutionHeartbeatInterval = 5 * time.Second
+	batchSpecResolutionStalledMaxAge     = 20 * time.Second
+	batchSpecResolutionResetInterval     = 5 * time.Second
+)
+
 // newBatchSpecResolutionWorker creates a dbworker.newWorker that fetches BatchSpecResolutionJobs
 // specs and passes them to the batchSpecWorkspaceCreator.
 func newBatchSpecResolutionWorker(
 	ctx context.Context,
 	s *store.Store,
-	workerStore dbworkerstore.Store,
+	workerStore *batchSpecResolutionWorkerStore,
 	metrics batchChangesMetrics,
 ) *workerutil.Worker {
-	e := &batchSpecWorkspaceCreator{store: s}
+	e := &batchSpecWorkspaceCreator{store: s, workerStore: workerStore, summaries: workerStore.summaries}
 
 	options := workerutil.WorkerOptions{
 		Name:              "batch_changes_batch_spec_resolution_worker",
 		NumHandlers:       5,
 		Interval:          5 * time.Second,
-		HeartbeatInterval: 15 * time.Second,
+		HeartbeatInterval: batchSpecResolutionHeartbeatInterval,
 		Metrics:           metrics.batchSpecResolutionWorkerMetrics,
 	}
 
@@ -46,7 +54,7 @@ func newBatchSpecResolutionWorker(
 func newBatchSpecResolutionWorkerResetter(workerStore dbworkerstore.Store, metrics batchChangesMetrics) *dbworker.Resetter {
 	options := dbworker.ResetterOptions{
 		Name:     "batch_changes_batch_spec_resolution_worker_resetter",
-		Interval: 1 * time.Minute,
+		Interval: batchSpecResolutionResetInterval,
 		Metrics:  metrics.batchSpecResolutionWorkerResetterMetrics,
 	}
 
@@ -54,25 +62,99 @@ func newBatchSpecResolutionWorkerResetter(workerStore dbworkerstore.Store, metri
 	return resetter
 }
 
-func scanFirstBatchSpecResolutionJobRecord(rows *sql.Rows, err error) (workerutil.Record, bool, error) {
-	return store.ScanFirstBatchSpecResolutionJob(rows, err)
+func newBatchSpecResolutionWorkerStore(handle *basestore.TransactableHandle, observationContext *observation.Context) *batchSpecResolutionWorkerStore {
+	options := store.BatchSpecResolutionWorkerStoreOptions
+	options.StalledMaxAge = batchSpecResolutionStalledMaxAge
+	options.MaxNumResets = batchSpecResolutionMaxNumResets
+
+	return &batchSpecResolutionWorkerStore{
+		Store:              dbworkerstore.NewWithMetrics(handle, options, observationContext),
+		options:            options,
+		observationContext: observationContext,
+		summaries:          &resolutionSummaries{},
+	}
+}
+
+var _ dbworkerstore.Store = &batchSpecResolutionWorkerStore{}
+
+// batchSpecResolutionWorkerStore is a thin wrapper around dbworkerstore.Store
+// that writes the summary of a resolution job in the same transaction that
+// moves the job into its final state, so that a finished job always has a
+// summary.
+type batchSpecResolutionWorkerStore struct {
+	dbworkerstore.Store
+
+	options            dbworkerstore.Options
+	observationContext *observation.Context
+	summaries          *resolutionSummaries
+}
+
+func (s *batchSpecResolutionWorkerStore) MarkComplete(ctx context.Context, id int, options dbworkerstore.MarkFinalOptions) (bool, error) {
+	return s.markFinal(ctx, id, func(ws dbworkerstore.Store) (bool, error) {
+		return ws.MarkComplete(ctx, id, options)
+	})
+}
+
+func (s *batchSpecResolutionWorkerStore) MarkErrored(ctx context.Context, id int, failureMessage string, options dbworkerstore.MarkFinalOptions) (bool, error) {
+	return s.markFinal(ctx, id, func(ws dbworkerstore.Store) (bool, error) {
+		return ws.MarkErrored(ctx, id, failureMessage, options)
+	})
+}
+
+func (s *batchSpecResolutionWorkerStore) MarkFailed(ctx context.Context, id int, failureMessage string, options dbworkerstore.MarkFinalOptions) (bool, error) {
+	return s.markFinal(ctx, id, func(ws dbworkerstore.Store) (bool, error) {
+		return ws.MarkFailed(ctx, id, failureMessage, options)
+	})
 }
 
-func newBatchSpecResolutionWorkerStore(handle *basestore.TransactableHandle, observationContext *observation.Context) dbworkerstore.Store {
-	options := dbworkerstore.Options{
-		Name:              "batch_changes_batch_spec_resolution_worker_store",
-		TableName:         "batch_spec_resolution_jobs",
-		ColumnExpressions: store.BatchSpecResolutionJobColums.ToSqlf(),
-		Scan:              scanFirstBatchSpecResolutionJobRecord,
+// markFinal calls mark with a worker store that shares a transaction with the
+// write of the job's summary, if the handler left one. The summary is only
+// written if mark updated the job.
+func (s *batchSpecResolutionWorkerStore) markFinal(ctx context.Context, id int, mark func(dbworkerstore.Store) (bool, error)) (_ bool, err error) {
+	summary := s.summaries.take(int64(id))
+	if summary == nil {
+		return mark(s.Store)
+	}
+
+	tx, err := store.New(s.Store.Handle().DB(), s.observationContext, nil).Transact(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = tx.Done(err) }()
 
-		OrderByExpression: sqlf.Sprintf("batch_spec_resolution_jobs.state = 'errored', batch_spec_resolution_jobs.updated_at DESC"),
+	ok, err := mark(dbworkerstore.New(tx.Handle(), s.options))
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, tx.SetBatchSpecResolutionJobSummary(ctx, int64(id), summary)
+}
 
-		StalledMaxAge: 60 * time.Second,
-		MaxNumResets:  batchSpecResolutionMaxNumResets,
+// resolutionSummaries hands the summaries of finished resolution jobs from the
+// handler to the worker store. A nil *resolutionSummaries drops them.
+type resolutionSummaries struct {
+	mu sync.Mutex
+	m  map[int64]*btypes.BatchSpecResolutionJobSummary
+}
 
-		RetryAfter:    5 * time.Second,
-		MaxNumRetries: batchSpecResolutionMaxNumRetries,
+func (r *resolutionSummaries) put(id int64, summary *btypes.BatchSpecResolutionJobSummary) {
+	if r == nil {
+		return
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.m == nil {
+		r.m = make(map[int64]*btypes.BatchSpecResolutionJobSummary)
+	}
+	r.m[id] = summary
+}
 
-	return dbworkerstore.NewWithMetrics(handle, options, observationContext)
+func (r *resolutionSummaries) take(id int64) *btypes.BatchSpecResolutionJobSummary {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	summary := r.m[id]
+	delete(r.m, id)
+	return summary
 }
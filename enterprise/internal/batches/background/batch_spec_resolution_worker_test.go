@@ -0,0 +1,194 @@
+package background
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+func TestBatchSpecResolutionWorkerStore_DequeuePriority(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+	user := ct.CreateTestUser(t, db, true)
+
+	s := store.New(db, &observation.TestContext, nil)
+	workStore := newBatchSpecResolutionWorkerStore(s.Handle(), &observation.TestContext)
+
+	var jobs []*btypes.BatchSpecResolutionJob
+	for _, priority := range []btypes.BatchSpecResolutionJobPriority{
+		btypes.BatchSpecResolutionJobPriorityBulk,
+		btypes.BatchSpecResolutionJobPriorityInteractive,
+		btypes.BatchSpecResolutionJobPriorityBulk,
+	} {
+		batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID}
+		if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+			t.Fatal(err)
+		}
+
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpec.ID, Priority: priority}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if job.Priority != priority {
+			t.Fatalf("job created with wrong priority. want=%d, have=%d", priority, job.Priority)
+		}
+		jobs = append(jobs, job)
+	}
+
+	record, ok, err := workStore.Dequeue(ctx, "worker-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("no job dequeued")
+	}
+	if have, want := record.RecordID(), int(jobs[1].ID); have != want {
+		t.Fatalf("wrong job dequeued. want=%d, have=%d", want, have)
+	}
+}
+
+func TestBatchSpecResolutionWorkerStore_ResetStalled(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+	user := ct.CreateTestUser(t, db, true)
+
+	s := store.New(db, &observation.TestContext, nil)
+	workStore := newBatchSpecResolutionWorkerStore(s.Handle(), &observation.TestContext)
+
+	lastHeartbeats := []time.Duration{
+		// Still heartbeating.
+		batchSpecResolutionHeartbeatInterval,
+		// Worker stopped heartbeating.
+		batchSpecResolutionStalledMaxAge + time.Second,
+	}
+	var jobs []*btypes.BatchSpecResolutionJob
+	for _, lastHeartbeat := range lastHeartbeats {
+		batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID}
+		if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+			t.Fatal(err)
+		}
+
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpec.ID}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Exec(ctx, sqlf.Sprintf(
+			`UPDATE batch_spec_resolution_jobs SET state = 'processing', started_at = NOW(), last_heartbeat_at = NOW() - (%s * '1 second'::interval) WHERE id = %s`,
+			int(lastHeartbeat/time.Second),
+			job.ID,
+		)); err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	reset, failed, err := workStore.ResetStalled(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 0 {
+		t.Fatalf("unexpected failed jobs: %v", failed)
+	}
+	if len(reset) != 1 {
+		t.Fatalf("wrong number of jobs reset. want=1, have=%d", len(reset))
+	}
+	if _, ok := reset[int(jobs[1].ID)]; !ok {
+		t.Fatalf("stalled job %d not reset: %v", jobs[1].ID, reset)
+	}
+}
+
+func TestBatchSpecResolutionWorkerStore_MarkWritesSummary(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+	user := ct.CreateTestUser(t, db, true)
+
+	s := store.New(db, &observation.TestContext, nil)
+	workStore := newBatchSpecResolutionWorkerStore(s.Handle(), &observation.TestContext)
+
+	createProcessingJob := func(t *testing.T) *btypes.BatchSpecResolutionJob {
+		t.Helper()
+		batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID}
+		if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+			t.Fatal(err)
+		}
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpec.ID}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Exec(ctx, sqlf.Sprintf(`UPDATE batch_spec_resolution_jobs SET state = 'processing', started_at = NOW() WHERE id = %s`, job.ID)); err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+
+	assertJob := func(t *testing.T, id int64, wantState btypes.BatchSpecResolutionJobState, wantSummary bool) {
+		t.Helper()
+		job, err := s.GetBatchSpecResolutionJob(ctx, store.GetBatchSpecResolutionJobOpts{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State != wantState {
+			t.Fatalf("wrong state. want=%s, have=%s", wantState, job.State)
+		}
+		if have := job.Summary != nil; have != wantSummary {
+			t.Fatalf("wrong summary presence. want=%t, have=%t", wantSummary, have)
+		}
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		job := createProcessingJob(t)
+		workStore.summaries.put(job.ID, &btypes.BatchSpecResolutionJobSummary{JobID: job.ID, State: btypes.BatchSpecResolutionJobStateCompleted})
+
+		ok, err := workStore.MarkComplete(ctx, int(job.ID), dbworkerstore.MarkFinalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("job not marked as completed")
+		}
+		assertJob(t, job.ID, btypes.BatchSpecResolutionJobStateCompleted, true)
+	})
+
+	t.Run("errored", func(t *testing.T) {
+		job := createProcessingJob(t)
+		workStore.summaries.put(job.ID, &btypes.BatchSpecResolutionJobSummary{JobID: job.ID, State: btypes.BatchSpecResolutionJobStateErrored})
+
+		ok, err := workStore.MarkErrored(ctx, int(job.ID), "boom", dbworkerstore.MarkFinalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("job not marked as errored")
+		}
+		assertJob(t, job.ID, btypes.BatchSpecResolutionJobStateErrored, true)
+	})
+
+	t.Run("not processing", func(t *testing.T) {
+		job := createProcessingJob(t)
+		if err := s.Exec(ctx, sqlf.Sprintf(`UPDATE batch_spec_resolution_jobs SET state = 'queued' WHERE id = %s`, job.ID)); err != nil {
+			t.Fatal(err)
+		}
+		workStore.summaries.put(job.ID, &btypes.BatchSpecResolutionJobSummary{JobID: job.ID, State: btypes.BatchSpecResolutionJobStateCompleted})
+
+		ok, err := workStore.MarkComplete(ctx, int(job.ID), dbworkerstore.MarkFinalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Fatal("queued job marked as completed")
+		}
+		assertJob(t, job.ID, btypes.BatchSpecResolutionJobStateQueued, false)
+		if summary := workStore.summaries.take(job.ID); summary != nil {
+			t.Fatal("summary of unmarked job kept")
+		}
+	})
+}
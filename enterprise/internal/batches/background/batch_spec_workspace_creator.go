@@ -2,13 +2,24 @@ package background
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/inconshreveable/log15"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/service"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/types"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 )
 
@@ -16,31 +27,104 @@ import (
 // RepoWorkspaces and then persists those as pending BatchSpecWorkspaces.
 type batchSpecWorkspaceCreator struct {
 	store *store.Store
+
+	// workerStore, if set, is used to write the execution logs of the jobs.
+	workerStore dbworkerstore.Store
+
+	// summaries, if set, receives the summaries of finished jobs, which the
+	// worker store writes when it moves the jobs into their final state.
+	summaries *resolutionSummaries
 }
 
 // HandlerFunc returns a workeruitl.HandlerFunc that can be passed to a
 // workerutil.Worker to process queued changesets.
 func (e *batchSpecWorkspaceCreator) HandlerFunc() workerutil.HandlerFunc {
 	return func(ctx context.Context, record workerutil.Record) (err error) {
-		tx, err := e.store.Transact(ctx)
-		if err != nil {
-			return err
+		job := record.(*btypes.BatchSpecResolutionJob)
+
+		// Progress is recorded outside of the transaction, so that it's
+		// visible while the job is still processing.
+		progress := &resolutionProgressRecorder{
+			store:       e.store,
+			jobID:       job.ID,
+			minInterval: resolutionProgressInterval,
+		}
+
+		// The logs are written outside of the transaction too, so that they
+		// can be tailed.
+		var logger *resolutionLogger
+		if e.workerStore != nil {
+			logger = newResolutionLogger(ctx, e.workerStore, job.ID, resolutionProgressInterval)
+		}
+
+		var repoErrs []*btypes.BatchSpecResolutionRepositoryError
+		searchQueries, err := e.processInTransaction(ctx, job, progress.record, func(repoErr *btypes.BatchSpecResolutionRepositoryError) {
+			repoErrs = append(repoErrs, repoErr)
+			logger.logf(ctx, "Failed to resolve %s: %s", repoErr.RepoName, repoErr.Message)
+		}, logger)
+		if err == nil {
+			progress.finish(ctx)
 		}
-		defer func() { err = tx.Done(err) }()
+		logger.finish(ctx, err)
+
+		// The search queries are recorded outside of the transaction, so
+		// that they're kept even if resolving the workspaces failed, which
+		// is when they're most useful for debugging.
+		if len(searchQueries) > 0 {
+			if setErr := e.store.SetBatchSpecResolutionJobSearchQueries(ctx, job.ID, searchQueries); setErr != nil {
+				log15.Error("failed to record search queries of batch spec resolution job", "job", job.ID, "err", setErr)
+			}
+		}
+
+		// The same goes for the repository errors. They're also written if
+		// there are none, to clear those of a previous attempt.
+		if len(repoErrs) > 0 || len(job.RepositoryErrors) > 0 {
+			if setErr := e.store.SetBatchSpecResolutionJobRepositoryErrors(ctx, job.ID, repoErrs); setErr != nil {
+				log15.Error("failed to record repository errors of batch spec resolution job", "job", job.ID, "err", setErr)
+			}
+		}
+
+		if state, finished := resolutionFinalState(job.NumFailures, err); finished {
+			e.summaries.put(job.ID, newResolutionJobSummary(job, state, e.store.Clock()(), len(searchQueries), progress.last, err))
+		}
+
+		return err
+	}
+}
 
-		return e.process(ctx, tx, service.NewWorkspaceResolver, record.(*btypes.BatchSpecResolutionJob))
+func (e *batchSpecWorkspaceCreator) processInTransaction(
+	ctx context.Context,
+	job *btypes.BatchSpecResolutionJob,
+	onProgress func(context.Context, btypes.BatchSpecResolutionJobProgress),
+	onRepositoryError func(*btypes.BatchSpecResolutionRepositoryError),
+	logger *resolutionLogger,
+) (searchQueries []string, err error) {
+	tx, err := e.store.Transact(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer func() { err = tx.Done(err) }()
+
+	return e.process(ctx, tx, service.NewWorkspaceResolver, job, onProgress, onRepositoryError, logger)
 }
 
+// process resolves the workspaces of the job's batch spec and persists them.
+// It returns the repository search queries that were executed, even if it
+// returns an error. Repositories that failed to resolve are reported to
+// onRepositoryError, if set. What the resolution does is written to logger,
+// which may be nil.
 func (r *batchSpecWorkspaceCreator) process(
 	ctx context.Context,
 	tx *store.Store,
 	newResolver service.WorkspaceResolverBuilder,
 	job *btypes.BatchSpecResolutionJob,
-) error {
+	onProgress func(context.Context, btypes.BatchSpecResolutionJobProgress),
+	onRepositoryError func(*btypes.BatchSpecResolutionRepositoryError),
+	logger *resolutionLogger,
+) (searchQueries []string, err error) {
 	spec, err := tx.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: job.BatchSpecID})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	evaluatableSpec, err := batcheslib.ParseBatchSpec([]byte(spec.RawSpec), batcheslib.ParseBatchSpecOptions{
@@ -49,19 +133,47 @@ func (r *batchSpecWorkspaceCreator) process(
 		AllowConditionalExec:   true,
 	})
 	if err != nil {
-		return err
+		return nil, &resolutionError{category: btypes.BatchSpecResolutionFailureInvalidSpec, err: err}
 	}
 
 	resolver := newResolver(tx)
 	workspaces, unsupported, ignored, err := resolver.ResolveWorkspacesForBatchSpec(ctx, evaluatableSpec, service.ResolveWorkspacesForBatchSpecOpts{
 		AllowUnsupported: job.AllowUnsupported,
 		AllowIgnored:     job.AllowIgnored,
+		OnRepositorySearch: func(query string) {
+			searchQueries = append(searchQueries, query)
+			logger.logf(ctx, "Searching for repositories: %s", query)
+		},
+		OnProgress: func(progress btypes.BatchSpecResolutionJobProgress) {
+			if onProgress != nil {
+				onProgress(ctx, progress)
+			}
+		},
+		OnRepositoryError: onRepositoryError,
 	})
 	if err != nil {
-		return err
+		return searchQueries, &resolutionError{category: btypes.BatchSpecResolutionFailureRepositorySearch, err: err}
 	}
 
 	log15.Info("resolved workspaces for batch spec", "job", job.ID, "spec", spec.ID, "workspaces", len(workspaces), "unsupported", len(unsupported), "ignored", len(ignored))
+	logger.logf(ctx, "Resolved %d workspaces (%d repositories on unsupported code hosts, %d repositories with a .batchignore file).", len(workspaces), len(unsupported), len(ignored))
+
+	// Check the credentials now, so users find out about missing ones before
+	// executing the batch spec instead of when publishing changesets.
+	warnings, err := credentialWarnings(ctx, tx, spec.UserID, workspaces)
+	if err != nil {
+		return searchQueries, err
+	}
+	if err := tx.SetBatchSpecResolutionJobCredentialWarnings(ctx, job.ID, warnings); err != nil {
+		return searchQueries, err
+	}
+	for _, w := range warnings {
+		logger.logf(ctx, "No credential configured for %s %s, which hosts %d of the repositories.", w.ExternalServiceType, w.ExternalServiceID, w.RepoCount)
+	}
+
+	if job.DryRun {
+		return searchQueries, tx.SetBatchSpecResolutionJobDryRunResult(ctx, job.ID, newDryRunResult(workspaces, unsupported, ignored))
+	}
 
 	var ws []*btypes.BatchSpecWorkspace
 	for _, w := range workspaces {
@@ -79,5 +191,297 @@ func (r *batchSpecWorkspaceCreator) process(
 		})
 	}
 
-	return tx.CreateBatchSpecWorkspace(ctx, ws...)
+	return searchQueries, tx.CreateBatchSpecWorkspace(ctx, ws...)
+}
+
+// resolutionError attaches the failure category reported in the summary of a
+// resolution job to an error.
+type resolutionError struct {
+	category btypes.BatchSpecResolutionFailureCategory
+	err      error
+}
+
+func (e *resolutionError) Error() string { return e.err.Error() }
+func (e *resolutionError) Unwrap() error { return e.err }
+
+// failureCategory classifies the error a resolution job failed with.
+func failureCategory(err error) btypes.BatchSpecResolutionFailureCategory {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return btypes.BatchSpecResolutionFailureTimeout
+	}
+	var re *resolutionError
+	if errors.As(err, &re) {
+		return re.category
+	}
+	return btypes.BatchSpecResolutionFailureInternal
+}
+
+// resolutionFinalState returns the state a resolution job ends up in after an
+// attempt that returned err, and false if the job will be retried. This
+// mirrors how the dbworker store marks records as errored or failed.
+func resolutionFinalState(numFailures int64, err error) (btypes.BatchSpecResolutionJobState, bool) {
+	if err == nil {
+		return btypes.BatchSpecResolutionJobStateCompleted, true
+	}
+	switch failures := numFailures + 1; {
+	case failures == store.BatchSpecResolutionMaxNumRetries:
+		return btypes.BatchSpecResolutionJobStateFailed, true
+	case failures > store.BatchSpecResolutionMaxNumRetries:
+		// The job stays errored, but it won't be dequeued again.
+		return btypes.BatchSpecResolutionJobStateErrored, true
+	default:
+		return "", false
+	}
+}
+
+// newResolutionJobSummary builds the summary of a resolution job that finished
+// at finishedAt in the given state.
+func newResolutionJobSummary(
+	job *btypes.BatchSpecResolutionJob,
+	state btypes.BatchSpecResolutionJobState,
+	finishedAt time.Time,
+	searchQueries int,
+	progress btypes.BatchSpecResolutionJobProgress,
+	err error,
+) *btypes.BatchSpecResolutionJobSummary {
+	summary := &btypes.BatchSpecResolutionJobSummary{
+		Version:     btypes.BatchSpecResolutionJobSummaryVersion,
+		JobID:       job.ID,
+		BatchSpecID: job.BatchSpecID,
+		DryRun:      job.DryRun,
+		State:       state,
+		Counts: btypes.BatchSpecResolutionJobSummaryCounts{
+			SearchQueries: searchQueries,
+			Repositories:  progress.ReposDiscovered,
+			Workspaces:    progress.WorkspacesResolved,
+		},
+		Timings: btypes.BatchSpecResolutionJobSummaryTimings{
+			QueuedAt:     job.CreatedAt,
+			StartedAt:    job.StartedAt,
+			FinishedAt:   finishedAt,
+			QueuedMs:     job.StartedAt.Sub(job.CreatedAt).Milliseconds(),
+			ProcessingMs: finishedAt.Sub(job.StartedAt).Milliseconds(),
+		},
+	}
+	if err != nil {
+		summary.Failure = &btypes.BatchSpecResolutionJobSummaryFailure{
+			Category: failureCategory(err),
+			Message:  err.Error(),
+		}
+	}
+	return summary
+}
+
+// credentialWarnings returns a warning for each code host of the given
+// workspaces for which neither the user nor the site has a credential
+// configured. Those are the credentials used to publish changesets.
+func credentialWarnings(ctx context.Context, tx *store.Store, userID int32, workspaces []*service.RepoWorkspace) ([]*btypes.BatchSpecResolutionCredentialWarning, error) {
+	type codeHost struct {
+		externalServiceType string
+		externalServiceID   string
+	}
+	repos := make(map[codeHost]map[api.RepoID]struct{})
+	var codeHosts []codeHost
+	for _, w := range workspaces {
+		// Changesets can't be published on unsupported code hosts
+		// regardless of credentials.
+		if !btypes.IsRepoSupported(&w.Repo.ExternalRepo) {
+			continue
+		}
+		ch := codeHost{
+			externalServiceType: w.Repo.ExternalRepo.ServiceType,
+			externalServiceID:   w.Repo.ExternalRepo.ServiceID,
+		}
+		if _, ok := repos[ch]; !ok {
+			repos[ch] = make(map[api.RepoID]struct{})
+			codeHosts = append(codeHosts, ch)
+		}
+		repos[ch][w.Repo.ID] = struct{}{}
+	}
+
+	var warnings []*btypes.BatchSpecResolutionCredentialWarning
+	for _, ch := range codeHosts {
+		_, err := tx.UserCredentials().GetByScope(ctx, database.UserCredentialScope{
+			Domain:              database.UserCredentialDomainBatches,
+			UserID:              userID,
+			ExternalServiceType: ch.externalServiceType,
+			ExternalServiceID:   ch.externalServiceID,
+		})
+		if err == nil {
+			continue
+		}
+		if !errcode.IsNotFound(err) {
+			return nil, err
+		}
+
+		_, err = tx.GetSiteCredential(ctx, store.GetSiteCredentialOpts{
+			ExternalServiceType: ch.externalServiceType,
+			ExternalServiceID:   ch.externalServiceID,
+		})
+		if err == nil {
+			continue
+		}
+		if err != store.ErrNoResults {
+			return nil, err
+		}
+
+		warnings = append(warnings, &btypes.BatchSpecResolutionCredentialWarning{
+			ExternalServiceType: ch.externalServiceType,
+			ExternalServiceID:   ch.externalServiceID,
+			RepoCount:           len(repos[ch]),
+		})
+	}
+	return warnings, nil
+}
+
+// newDryRunResult builds the result of a dry run resolution job from the
+// resolved workspaces.
+func newDryRunResult(workspaces []*service.RepoWorkspace, unsupported, ignored map[*types.Repo]struct{}) *btypes.BatchSpecResolutionDryRunResult {
+	result := &btypes.BatchSpecResolutionDryRunResult{
+		Workspaces:         make([]*btypes.BatchSpecResolutionDryRunWorkspace, 0, len(workspaces)),
+		UnsupportedRepoIDs: sortedRepoIDs(unsupported),
+		IgnoredRepoIDs:     sortedRepoIDs(ignored),
+	}
+	for _, w := range workspaces {
+		result.Workspaces = append(result.Workspaces, &btypes.BatchSpecResolutionDryRunWorkspace{
+			RepoID:             w.Repo.ID,
+			Branch:             w.Branch,
+			Commit:             string(w.Commit),
+			Path:               w.Path,
+			FileMatches:        w.FileMatches,
+			OnlyFetchWorkspace: w.OnlyFetchWorkspace,
+		})
+	}
+	return result
+}
+
+func sortedRepoIDs(repos map[*types.Repo]struct{}) []api.RepoID {
+	ids := make([]api.RepoID, 0, len(repos))
+	for r := range repos {
+		ids = append(ids, r.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// resolutionProgressInterval is the minimum interval between two progress
+// updates of a resolution job in the database.
+const resolutionProgressInterval = 2 * time.Second
+
+// resolutionProgressRecorder records the progress of a resolution job in the
+// database, at most once per minInterval.
+type resolutionProgressRecorder struct {
+	store       *store.Store
+	jobID       int64
+	minInterval time.Duration
+
+	last         btypes.BatchSpecResolutionJobProgress
+	lastRecorded time.Time
+}
+
+func (r *resolutionProgressRecorder) record(ctx context.Context, progress btypes.BatchSpecResolutionJobProgress) {
+	r.last = progress
+	if time.Since(r.lastRecorded) < r.minInterval {
+		return
+	}
+	r.write(ctx)
+}
+
+// finish records that the resolution is complete, regardless of when
+// progress was recorded last.
+func (r *resolutionProgressRecorder) finish(ctx context.Context) {
+	r.last.PercentComplete = 100
+	r.write(ctx)
+}
+
+func (r *resolutionProgressRecorder) write(ctx context.Context) {
+	r.lastRecorded = time.Now()
+	if err := r.store.SetBatchSpecResolutionJobProgress(ctx, r.jobID, r.last); err != nil {
+		log15.Error("failed to record progress of batch spec resolution job", "job", r.jobID, "err", err)
+	}
+}
+
+// resolutionLogKey is the key of the execution log entry of a resolution job.
+const resolutionLogKey = "resolve.workspaces"
+
+// resolutionLogger writes the output of a resolution job to a single entry in
+// the execution logs of the job, at most once per minInterval, so that it can
+// be tailed while the job is processing. All methods are safe to call on a nil
+// logger, which discards the output.
+type resolutionLogger struct {
+	store       dbworkerstore.Store
+	jobID       int
+	minInterval time.Duration
+
+	mu          sync.Mutex
+	entryID     int
+	entry       workerutil.ExecutionLogEntry
+	out         strings.Builder
+	lastWritten time.Time
+}
+
+// newResolutionLogger adds the log entry of the given job. It returns nil if
+// the entry can't be added, since the logs aren't worth failing the job for.
+func newResolutionLogger(ctx context.Context, s dbworkerstore.Store, jobID int64, minInterval time.Duration) *resolutionLogger {
+	l := &resolutionLogger{
+		store:       s,
+		jobID:       int(jobID),
+		minInterval: minInterval,
+		entry: workerutil.ExecutionLogEntry{
+			Key:       resolutionLogKey,
+			Command:   []string{},
+			StartTime: time.Now(),
+		},
+	}
+
+	entryID, err := s.AddExecutionLogEntry(ctx, l.jobID, l.entry, dbworkerstore.ExecutionLogEntryOptions{})
+	if err != nil {
+		log15.Error("failed to add execution log entry of batch spec resolution job", "job", jobID, "err", err)
+		return nil
+	}
+	l.entryID = entryID
+	l.lastWritten = time.Now()
+	return l
+}
+
+func (l *resolutionLogger) logf(ctx context.Context, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(&l.out, format+"\n", args...)
+	if time.Since(l.lastWritten) < l.minInterval {
+		return
+	}
+	l.write(ctx)
+}
+
+// finish marks the log entry as finished, with a non-zero exit code if the
+// job failed with err.
+func (l *resolutionLogger) finish(ctx context.Context, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	exitCode := 0
+	if err != nil {
+		exitCode = 1
+		fmt.Fprintf(&l.out, "Resolution failed: %s\n", err)
+	}
+	durationMs := int(time.Since(l.entry.StartTime) / time.Millisecond)
+	l.entry.ExitCode = &exitCode
+	l.entry.DurationMs = &durationMs
+	l.write(ctx)
+}
+
+func (l *resolutionLogger) write(ctx context.Context) {
+	l.lastWritten = time.Now()
+	l.entry.Out = l.out.String()
+	if err := l.store.UpdateExecutionLogEntry(ctx, l.jobID, l.entryID, l.entry, dbworkerstore.ExecutionLogEntryOptions{}); err != nil {
+		log15.Error("failed to update execution log entry of batch spec resolution job", "job", l.jobID, "err", err)
+	}
 }
@@ -3,7 +3,9 @@ package background
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
@@ -11,9 +13,13 @@ import (
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
 	"github.com/sourcegraph/sourcegraph/internal/types"
+	workerstoremocks "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store/mocks"
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 )
 
@@ -69,12 +75,17 @@ func TestBatchSpecWorkspaceCreatorProcess(t *testing.T) {
 				OnlyFetchWorkspace: true,
 			},
 		},
+		searchQueries: []string{"file:go.mod count:all"},
 	}
 
 	creator := &batchSpecWorkspaceCreator{store: s}
-	if err := creator.process(context.Background(), s, resolver.DummyBuilder, job); err != nil {
+	searchQueries, err := creator.process(context.Background(), s, resolver.DummyBuilder, job, nil, nil, nil)
+	if err != nil {
 		t.Fatalf("proces failed: %s", err)
 	}
+	if diff := cmp.Diff(resolver.searchQueries, searchQueries); diff != "" {
+		t.Fatalf("wrong search queries returned. (-want +got):\n%s", diff)
+	}
 
 	have, _, err := s.ListBatchSpecWorkspaces(context.Background(), store.ListBatchSpecWorkspacesOpts{BatchSpecID: batchSpec.ID})
 	if err != nil {
@@ -125,11 +136,282 @@ func TestBatchSpecWorkspaceCreatorProcess(t *testing.T) {
 	}
 }
 
+func TestBatchSpecWorkspaceCreatorProcess_DryRun(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+
+	repos, _ := ct.CreateTestRepos(t, ctx, db, 3)
+
+	user := ct.CreateTestUser(t, db, true)
+
+	s := store.New(db, &observation.TestContext, nil)
+
+	batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID, RawSpec: ct.TestRawBatchSpecYAML}
+	if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpec.ID, DryRun: true}
+	if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := &dummyWorkspaceResolver{
+		workspaces: []*service.RepoWorkspace{
+			{
+				RepoRevision: &service.RepoRevision{
+					Repo:        repos[0],
+					Branch:      "refs/heads/main",
+					Commit:      "d34db33f",
+					FileMatches: []string{"a/b/c.go"},
+				},
+				Path:               "a/b",
+				Steps:              []batcheslib.Step{},
+				OnlyFetchWorkspace: true,
+			},
+		},
+		unsupported: map[*types.Repo]struct{}{repos[2]: {}},
+		ignored:     map[*types.Repo]struct{}{repos[1]: {}},
+	}
+
+	creator := &batchSpecWorkspaceCreator{store: s}
+	if _, err := creator.process(ctx, s, resolver.DummyBuilder, job, nil, nil, nil); err != nil {
+		t.Fatalf("proces failed: %s", err)
+	}
+
+	workspaces, _, err := s.ListBatchSpecWorkspaces(ctx, store.ListBatchSpecWorkspacesOpts{BatchSpecID: batchSpec.ID})
+	if err != nil {
+		t.Fatalf("listing workspaces failed: %s", err)
+	}
+	if len(workspaces) != 0 {
+		t.Fatalf("dry run created %d workspaces", len(workspaces))
+	}
+
+	have, err := s.GetBatchSpecResolutionJob(ctx, store.GetBatchSpecResolutionJobOpts{BatchSpecID: batchSpec.ID, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &btypes.BatchSpecResolutionDryRunResult{
+		Workspaces: []*btypes.BatchSpecResolutionDryRunWorkspace{
+			{
+				RepoID:             repos[0].ID,
+				Branch:             "refs/heads/main",
+				Commit:             "d34db33f",
+				FileMatches:        []string{"a/b/c.go"},
+				Path:               "a/b",
+				OnlyFetchWorkspace: true,
+			},
+		},
+		UnsupportedRepoIDs: []api.RepoID{repos[2].ID},
+		IgnoredRepoIDs:     []api.RepoID{repos[1].ID},
+	}
+	if diff := cmp.Diff(want, have.DryRunResult); diff != "" {
+		t.Fatalf("wrong dry run result (-want +got):\n%s", diff)
+	}
+}
+
+func TestBatchSpecWorkspaceCreatorProcess_CredentialWarnings(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+
+	repos, _ := ct.CreateTestRepos(t, ctx, db, 2)
+
+	user := ct.CreateTestUser(t, db, false)
+
+	s := store.New(db, &observation.TestContext, nil)
+
+	workspace := func(repo *types.Repo, path string) *service.RepoWorkspace {
+		return &service.RepoWorkspace{
+			RepoRevision: &service.RepoRevision{Repo: repo, Branch: "refs/heads/main", Commit: "d34db33f"},
+			Path:         path,
+			Steps:        []batcheslib.Step{},
+		}
+	}
+	resolver := &dummyWorkspaceResolver{
+		workspaces: []*service.RepoWorkspace{
+			workspace(repos[0], "a"),
+			workspace(repos[0], "b"),
+			workspace(repos[1], ""),
+		},
+	}
+
+	process := func(t *testing.T) *btypes.BatchSpecResolutionJob {
+		t.Helper()
+
+		batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID, RawSpec: ct.TestRawBatchSpecYAML}
+		if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+			t.Fatal(err)
+		}
+
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpec.ID, DryRun: true}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+
+		creator := &batchSpecWorkspaceCreator{store: s}
+		if _, err := creator.process(ctx, s, resolver.DummyBuilder, job, nil, nil, nil); err != nil {
+			t.Fatalf("proces failed: %s", err)
+		}
+
+		have, err := s.GetBatchSpecResolutionJob(ctx, store.GetBatchSpecResolutionJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return have
+	}
+
+	t.Run("without credentials", func(t *testing.T) {
+		want := []*btypes.BatchSpecResolutionCredentialWarning{{
+			ExternalServiceType: repos[0].ExternalRepo.ServiceType,
+			ExternalServiceID:   repos[0].ExternalRepo.ServiceID,
+			RepoCount:           2,
+		}}
+		if diff := cmp.Diff(want, process(t).CredentialWarnings); diff != "" {
+			t.Fatalf("wrong credential warnings (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("with user credential", func(t *testing.T) {
+		if _, err := s.UserCredentials().Create(ctx, database.UserCredentialScope{
+			Domain:              database.UserCredentialDomainBatches,
+			UserID:              user.ID,
+			ExternalServiceType: repos[0].ExternalRepo.ServiceType,
+			ExternalServiceID:   repos[0].ExternalRepo.ServiceID,
+		}, &auth.OAuthBearerToken{Token: "abcdef"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if warnings := process(t).CredentialWarnings; len(warnings) != 0 {
+			t.Fatalf("expected no credential warnings, got %d", len(warnings))
+		}
+	})
+}
+
+func TestBatchSpecWorkspaceCreatorProcess_RepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+
+	user := ct.CreateTestUser(t, db, true)
+
+	s := store.New(db, &observation.TestContext, nil)
+
+	batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID, RawSpec: ct.TestRawBatchSpecYAML}
+	if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpec.ID}
+
+	resolver := &dummyWorkspaceResolver{
+		repoErrs: []*btypes.BatchSpecResolutionRepositoryError{
+			{RepoName: "github.com/sourcegraph/missing", Message: "repo not found"},
+			{RepoID: 3, RepoName: "github.com/sourcegraph/broken", Message: "checking for .batchignore file: boom"},
+		},
+		err: errors.New("resolution failed"),
+	}
+
+	var have []*btypes.BatchSpecResolutionRepositoryError
+	creator := &batchSpecWorkspaceCreator{store: s}
+	_, err := creator.process(ctx, s, resolver.DummyBuilder, job, nil, func(repoErr *btypes.BatchSpecResolutionRepositoryError) {
+		have = append(have, repoErr)
+	}, nil)
+	if err == nil {
+		t.Fatal("process did not fail")
+	}
+	if diff := cmp.Diff(resolver.repoErrs, have); diff != "" {
+		t.Fatalf("wrong repository errors reported (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolutionLogger(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("buffers output until finished", func(t *testing.T) {
+		workerStore := workerstoremocks.NewMockStore()
+		workerStore.AddExecutionLogEntryFunc.SetDefaultReturn(3, nil)
+
+		logger := newResolutionLogger(ctx, workerStore, 42, time.Hour)
+		if logger == nil {
+			t.Fatal("no logger returned")
+		}
+		logger.logf(ctx, "Searching for repositories: %s", "repo:a")
+		logger.logf(ctx, "Failed to resolve %s: %s", "github.com/sourcegraph/missing", "repo not found")
+		if calls := len(workerStore.UpdateExecutionLogEntryFunc.History()); calls != 0 {
+			t.Fatalf("log entry updated %d times before the interval passed", calls)
+		}
+		logger.finish(ctx, errors.New("boom"))
+
+		adds := workerStore.AddExecutionLogEntryFunc.History()
+		if len(adds) != 1 || adds[0].Arg1 != 42 || adds[0].Arg2.Key != resolutionLogKey {
+			t.Fatalf("unexpected log entries added: %+v", adds)
+		}
+
+		updates := workerStore.UpdateExecutionLogEntryFunc.History()
+		if len(updates) != 1 {
+			t.Fatalf("wrong number of updates. want=%d, have=%d", 1, len(updates))
+		}
+		if updates[0].Arg1 != 42 || updates[0].Arg2 != 3 {
+			t.Fatalf("wrong log entry updated: job=%d, entry=%d", updates[0].Arg1, updates[0].Arg2)
+		}
+		entry := updates[0].Arg3
+		wantOut := "Searching for repositories: repo:a\nFailed to resolve github.com/sourcegraph/missing: repo not found\nResolution failed: boom\n"
+		if diff := cmp.Diff(wantOut, entry.Out); diff != "" {
+			t.Fatalf("wrong output (-want +got):\n%s", diff)
+		}
+		if entry.ExitCode == nil || *entry.ExitCode != 1 {
+			t.Fatalf("wrong exit code: %v", entry.ExitCode)
+		}
+		if entry.DurationMs == nil {
+			t.Fatal("no duration set")
+		}
+	})
+
+	t.Run("writes every line without interval", func(t *testing.T) {
+		workerStore := workerstoremocks.NewMockStore()
+
+		logger := newResolutionLogger(ctx, workerStore, 42, 0)
+		logger.logf(ctx, "one")
+		logger.logf(ctx, "two")
+		logger.finish(ctx, nil)
+
+		updates := workerStore.UpdateExecutionLogEntryFunc.History()
+		if len(updates) != 3 {
+			t.Fatalf("wrong number of updates. want=%d, have=%d", 3, len(updates))
+		}
+		if have := updates[0].Arg3; have.Out != "one\n" || have.ExitCode != nil {
+			t.Fatalf("unexpected first update: %+v", have)
+		}
+		if have := updates[2].Arg3; have.Out != "one\ntwo\n" || have.ExitCode == nil || *have.ExitCode != 0 {
+			t.Fatalf("unexpected last update: %+v", have)
+		}
+	})
+
+	t.Run("discards output if the entry can't be added", func(t *testing.T) {
+		workerStore := workerstoremocks.NewMockStore()
+		workerStore.AddExecutionLogEntryFunc.SetDefaultReturn(0, errors.New("boom"))
+
+		logger := newResolutionLogger(ctx, workerStore, 42, 0)
+		if logger != nil {
+			t.Fatal("expected no logger")
+		}
+		// A nil logger discards the output.
+		logger.logf(ctx, "one")
+		logger.finish(ctx, nil)
+
+		if calls := len(workerStore.UpdateExecutionLogEntryFunc.History()); calls != 0 {
+			t.Fatalf("log entry updated %d times", calls)
+		}
+	})
+}
+
 type dummyWorkspaceResolver struct {
-	workspaces  []*service.RepoWorkspace
-	unsupported map[*types.Repo]struct{}
-	ignored     map[*types.Repo]struct{}
-	err         error
+	workspaces    []*service.RepoWorkspace
+	unsupported   map[*types.Repo]struct{}
+	ignored       map[*types.Repo]struct{}
+	searchQueries []string
+	repoErrs      []*btypes.BatchSpecResolutionRepositoryError
+	err           error
 }
 
 // DummyBuilder is a simple implementation of the service.WorkspaceResolverBuilder
@@ -137,6 +419,88 @@ func (d *dummyWorkspaceResolver) DummyBuilder(s *store.Store) service.WorkspaceR
 	return d
 }
 
-func (d *dummyWorkspaceResolver) ResolveWorkspacesForBatchSpec(context.Context, *batcheslib.BatchSpec, service.ResolveWorkspacesForBatchSpecOpts) ([]*service.RepoWorkspace, map[*types.Repo]struct{}, map[*types.Repo]struct{}, error) {
+func (d *dummyWorkspaceResolver) ResolveWorkspacesForBatchSpec(_ context.Context, _ *batcheslib.BatchSpec, opts service.ResolveWorkspacesForBatchSpecOpts) ([]*service.RepoWorkspace, map[*types.Repo]struct{}, map[*types.Repo]struct{}, error) {
+	if opts.OnRepositorySearch != nil {
+		for _, q := range d.searchQueries {
+			opts.OnRepositorySearch(q)
+		}
+	}
+	if opts.OnRepositoryError != nil {
+		for _, e := range d.repoErrs {
+			opts.OnRepositoryError(e)
+		}
+	}
 	return d.workspaces, d.unsupported, d.ignored, d.err
 }
+
+func TestNewResolutionJobSummary(t *testing.T) {
+	createdAt := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	job := &btypes.BatchSpecResolutionJob{
+		ID:          12,
+		BatchSpecID: 34,
+		CreatedAt:   createdAt,
+		StartedAt:   createdAt.Add(2 * time.Second),
+	}
+	finishedAt := createdAt.Add(5 * time.Second)
+	progress := btypes.BatchSpecResolutionJobProgress{ReposDiscovered: 3, WorkspacesResolved: 4}
+
+	t.Run("completed", func(t *testing.T) {
+		have := newResolutionJobSummary(job, btypes.BatchSpecResolutionJobStateCompleted, finishedAt, 1, progress, nil)
+		want := &btypes.BatchSpecResolutionJobSummary{
+			Version:     btypes.BatchSpecResolutionJobSummaryVersion,
+			JobID:       12,
+			BatchSpecID: 34,
+			State:       btypes.BatchSpecResolutionJobStateCompleted,
+			Counts: btypes.BatchSpecResolutionJobSummaryCounts{
+				SearchQueries: 1,
+				Repositories:  3,
+				Workspaces:    4,
+			},
+			Timings: btypes.BatchSpecResolutionJobSummaryTimings{
+				QueuedAt:     createdAt,
+				StartedAt:    job.StartedAt,
+				FinishedAt:   finishedAt,
+				QueuedMs:     2000,
+				ProcessingMs: 3000,
+			},
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("wrong summary (-want +have):\n%s", diff)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		for _, tc := range []struct {
+			err  error
+			want btypes.BatchSpecResolutionFailureCategory
+		}{
+			{
+				err:  &resolutionError{category: btypes.BatchSpecResolutionFailureInvalidSpec, err: errors.New("invalid")},
+				want: btypes.BatchSpecResolutionFailureInvalidSpec,
+			},
+			{
+				err:  errors.Wrap(&resolutionError{category: btypes.BatchSpecResolutionFailureRepositorySearch, err: errors.New("search")}, "resolving"),
+				want: btypes.BatchSpecResolutionFailureRepositorySearch,
+			},
+			{
+				err:  &resolutionError{category: btypes.BatchSpecResolutionFailureRepositorySearch, err: context.DeadlineExceeded},
+				want: btypes.BatchSpecResolutionFailureTimeout,
+			},
+			{
+				err:  errors.New("database is gone"),
+				want: btypes.BatchSpecResolutionFailureInternal,
+			},
+		} {
+			have := newResolutionJobSummary(job, btypes.BatchSpecResolutionJobStateFailed, finishedAt, 0, progress, tc.err)
+			if have.Failure == nil {
+				t.Fatalf("no failure in summary for %q", tc.err)
+			}
+			if have.Failure.Category != tc.want {
+				t.Errorf("wrong category for %q: want=%s have=%s", tc.err, tc.want, have.Failure.Category)
+			}
+			if have.Failure.Message != tc.err.Error() {
+				t.Errorf("wrong message: want=%q have=%q", tc.err.Error(), have.Failure.Message)
+			}
+		}
+	})
+}
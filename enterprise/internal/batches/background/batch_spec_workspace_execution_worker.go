@@ -5,16 +5,19 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/inconshreveable/log15"
 	"github.com/keegancsmith/sqlf"
 	"github.com/lib/pq"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil"
@@ -111,9 +114,13 @@ func (s *batchSpecWorkspaceExecutionWorkerStore) FetchCanceled(ctx context.Conte
 	return ids, nil
 }
 
-func (s *batchSpecWorkspaceExecutionWorkerStore) MarkComplete(ctx context.Context, id int, options dbworkerstore.MarkFinalOptions) (_ bool, err error) {
+func (s *batchSpecWorkspaceExecutionWorkerStore) MarkComplete(ctx context.Context, id int, options dbworkerstore.MarkFinalOptions) (ok bool, err error) {
 	batchesStore := store.New(s.Store.Handle().DB(), s.observationContext, nil)
 
+	// Deferred before the transaction is done, so that this runs after it
+	// has been committed.
+	defer func() { cacheStepResults(ctx, batchesStore, id, ok, err) }()
+
 	tx, err := batchesStore.Transact(ctx)
 	if err != nil {
 		return false, err
@@ -129,6 +136,34 @@ func (s *batchSpecWorkspaceExecutionWorkerStore) MarkComplete(ctx context.Contex
 	return markBatchSpecWorkspaceExecutionJobComplete(ctx, tx, job, changesetSpecIDs, options.WorkerHostname)
 }
 
+// MarkFailed caches the results of the steps that succeeded before the job
+// failed, so that they don't have to be executed again on retry.
+func (s *batchSpecWorkspaceExecutionWorkerStore) MarkFailed(ctx context.Context, id int, failureMessage string, options dbworkerstore.MarkFinalOptions) (bool, error) {
+	ok, err := s.Store.MarkFailed(ctx, id, failureMessage, options)
+	cacheStepResults(ctx, store.New(s.Store.Handle().DB(), s.observationContext, nil), id, ok, err)
+	return ok, err
+}
+
+// MarkErrored caches the results of the steps that succeeded before the job
+// errored, like MarkFailed.
+func (s *batchSpecWorkspaceExecutionWorkerStore) MarkErrored(ctx context.Context, id int, failureMessage string, options dbworkerstore.MarkFinalOptions) (bool, error) {
+	ok, err := s.Store.MarkErrored(ctx, id, failureMessage, options)
+	cacheStepResults(ctx, store.New(s.Store.Handle().DB(), s.observationContext, nil), id, ok, err)
+	return ok, err
+}
+
+// cacheStepResults caches the step results of the job once it has been marked
+// as finished, i.e. if marked is true. The cache is only an optimization, so
+// failing to fill it doesn't fail the job.
+func cacheStepResults(ctx context.Context, s *store.Store, id int, marked bool, err error) {
+	if !marked || err != nil {
+		return
+	}
+	if err := storeCachedStepResults(ctx, s, int64(id)); err != nil {
+		log15.Error("failed to cache step results of batch spec workspace execution job", "job", id, "err", err)
+	}
+}
+
 // markBatchSpecWorkspaceExecutionJobCompleteQuery is taken from internal/workerutil/dbworker/store/store.go
 //
 // If that one changes we need to update this one here too.
@@ -250,3 +285,87 @@ func extractChangesetSpecRandIDs(logs []workerutil.ExecutionLogEntry) ([]string,
 
 	return nil, ErrNoChangesetSpecIDs
 }
+
+// storeCachedStepResults stores the results of the steps the given job
+// executed successfully in the execution cache of the user that executed it.
+// Nothing is stored for canceled jobs.
+func storeCachedStepResults(ctx context.Context, s *store.Store, id int64) error {
+	job, err := s.GetBatchSpecWorkspaceExecutionJob(ctx, store.GetBatchSpecWorkspaceExecutionJobOpts{ID: id})
+	if err != nil {
+		return errors.Wrap(err, "loading job")
+	}
+	if job.Cancel {
+		return nil
+	}
+
+	results := extractStepResults(job.ExecutionLogs)
+	if len(results) == 0 {
+		return nil
+	}
+
+	workspace, err := s.GetBatchSpecWorkspace(ctx, store.GetBatchSpecWorkspaceOpts{ID: job.BatchSpecWorkspaceID})
+	if err != nil {
+		return errors.Wrap(err, "loading workspace")
+	}
+	batchSpec, err := s.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: workspace.BatchSpecID})
+	if err != nil {
+		return errors.Wrap(err, "loading batch spec")
+	}
+	repo, err := s.Repos().Get(actor.WithInternalActor(ctx), workspace.RepoID)
+	if err != nil {
+		return errors.Wrap(err, "loading repo")
+	}
+
+	workspaceKey, err := btypes.ExecutionCacheWorkspaceKey(batchSpec, workspace, string(repo.Name))
+	if err != nil {
+		return errors.Wrap(err, "computing workspace key")
+	}
+	stepKeys, err := btypes.ExecutionCacheStepKeys(workspace.Steps)
+	if err != nil {
+		return errors.Wrap(err, "computing step keys")
+	}
+
+	for _, result := range results {
+		if result.StepIndex >= len(stepKeys) {
+			continue
+		}
+		if err := s.UpsertBatchSpecExecutionCacheEntry(ctx, &btypes.BatchSpecExecutionCacheEntry{
+			UserID:       batchSpec.UserID,
+			WorkspaceKey: workspaceKey,
+			StepKey:      stepKeys[result.StepIndex],
+			Result:       result,
+		}); err != nil {
+			return errors.Wrap(err, "upserting cache entry")
+		}
+	}
+	return nil
+}
+
+// extractStepResults returns the results of the steps that were executed
+// successfully, as reported in the JSON logs of src-cli. Steps that were
+// skipped, either because of their condition or because their result was
+// cached, aren't included.
+func extractStepResults(logs []workerutil.ExecutionLogEntry) []batcheslib.AfterStepResult {
+	var lines []*batcheslib.LogEvent
+	for _, e := range logs {
+		if e.Key == "step.src.0" {
+			lines = btypes.ParseJSONLogsFromOutput(e.Out)
+			break
+		}
+	}
+
+	infos := btypes.ParseLogLines(lines)
+	results := make([]batcheslib.AfterStepResult, 0, len(infos))
+	for step, info := range infos {
+		if info.Skipped || info.Diff == nil || step < 1 {
+			continue
+		}
+		results = append(results, batcheslib.AfterStepResult{
+			StepIndex: step - 1,
+			Diff:      *info.Diff,
+			Outputs:   info.OutputVariables,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].StepIndex < results[j].StepIndex })
+	return results
+}
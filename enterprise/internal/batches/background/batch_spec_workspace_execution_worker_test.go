@@ -251,4 +251,152 @@ stdout: {"operation":"UPLOADING_CHANGESET_SPECS","timestamp":"2021-09-09T13:20:3
 	}
 }
 
+func TestExtractStepResults(t *testing.T) {
+	entries := []workerutil.ExecutionLogEntry{
+		{Key: "setup.firecracker.start"},
+		{
+			Key: "step.src.0",
+			Out: `stdout: {"operation":"TASK_SKIPPING_STEPS","timestamp":"2021-09-09T13:20:32.942Z","status":"PROGRESS","metadata":{"startStep":2}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:33.942Z","status":"STARTED","metadata":{"step":2}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:34.942Z","status":"SUCCESS","metadata":{"step":2,"diff":"diff --git a/README.md b/README.md","outputs":{"count":1}}}
+stdout: {"operation":"TASK_STEP_SKIPPED","timestamp":"2021-09-09T13:20:34.942Z","status":"PROGRESS","metadata":{"step":3}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:35.942Z","status":"STARTED","metadata":{"step":4}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:36.942Z","status":"SUCCESS","metadata":{"step":4,"diff":"diff --git a/main.go b/main.go"}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:37.942Z","status":"STARTED","metadata":{"step":5}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:38.942Z","status":"FAILURE","metadata":{"step":5,"exitCode":1}}
+`,
+		},
+	}
+
+	want := []batcheslib.AfterStepResult{
+		{StepIndex: 1, Diff: "diff --git a/README.md b/README.md", Outputs: map[string]interface{}{"count": float64(1)}},
+		{StepIndex: 3, Diff: "diff --git a/main.go b/main.go", Outputs: map[string]interface{}{}},
+	}
+	if diff := cmp.Diff(want, extractStepResults(entries)); diff != "" {
+		t.Fatalf("wrong step results extracted: %s", diff)
+	}
+
+	if have := extractStepResults(nil); len(have) != 0 {
+		t.Fatalf("unexpected step results without logs: %+v", have)
+	}
+}
+
+func TestBatchSpecWorkspaceExecutionWorkerStore_CachesStepResults(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewDB(t, "")
+	repo, _ := ct.CreateTestRepo(t, ctx, db)
+
+	s := store.New(db, &observation.TestContext, nil)
+	workStore := dbworkerstore.NewWithMetrics(s.Handle(), batchSpecWorkspaceExecutionWorkerStoreOptions, &observation.TestContext)
+	executionStore := &batchSpecWorkspaceExecutionWorkerStore{Store: workStore, observationContext: &observation.TestContext}
+
+	steps := []batcheslib.Step{{Run: "echo 1", Container: "alpine"}, {Run: "echo 2", Container: "alpine"}}
+	stepKeys, err := btypes.ExecutionCacheStepKeys(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// createJob creates a processing job of a new user, whose first step
+	// succeeded before the job finished.
+	createJob := func(t *testing.T, state btypes.BatchSpecWorkspaceExecutionJobState, cancel bool) (*btypes.BatchSpecWorkspaceExecutionJob, store.ListBatchSpecExecutionCacheEntriesOpts) {
+		t.Helper()
+
+		user := ct.CreateTestUser(t, db, false)
+		batchSpec := &btypes.BatchSpec{UserID: user.ID, NamespaceUserID: user.ID, RawSpec: "horse"}
+		if err := s.CreateBatchSpec(ctx, batchSpec); err != nil {
+			t.Fatal(err)
+		}
+		workspace := &btypes.BatchSpecWorkspace{BatchSpecID: batchSpec.ID, RepoID: repo.ID, Commit: "deadbeef", Steps: steps}
+		if err := s.CreateBatchSpecWorkspace(ctx, workspace); err != nil {
+			t.Fatal(err)
+		}
+		job := &btypes.BatchSpecWorkspaceExecutionJob{BatchSpecWorkspaceID: workspace.ID}
+		if err := s.CreateBatchSpecWorkspaceExecutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		job.WorkerHostname = "worker-1"
+		if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_workspace_execution_jobs SET worker_hostname = %s, state = %s, cancel = %s WHERE id = %s", job.WorkerHostname, state, cancel, job.ID)); err != nil {
+			t.Fatal(err)
+		}
+
+		entry := workerutil.ExecutionLogEntry{
+			Key: "step.src.0",
+			Out: `stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:33.942Z","status":"STARTED","metadata":{"step":1}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:34.942Z","status":"SUCCESS","metadata":{"step":1,"diff":"diff --git a/README.md b/README.md"}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:35.942Z","status":"STARTED","metadata":{"step":2}}
+stdout: {"operation":"TASK_STEP","timestamp":"2021-09-09T13:20:36.942Z","status":"FAILURE","metadata":{"step":2,"exitCode":1}}
+`,
+		}
+		if _, err := workStore.AddExecutionLogEntry(ctx, int(job.ID), entry, dbworkerstore.ExecutionLogEntryOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		workspaceKey, err := btypes.ExecutionCacheWorkspaceKey(batchSpec, workspace, string(repo.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job, store.ListBatchSpecExecutionCacheEntriesOpts{UserID: user.ID, WorkspaceKey: workspaceKey, StepKeys: stepKeys}
+	}
+
+	opts := dbworkerstore.MarkFinalOptions{WorkerHostname: "worker-1"}
+	for _, tc := range []struct {
+		name       string
+		state      btypes.BatchSpecWorkspaceExecutionJobState
+		cancel     bool
+		mark       func(id int) (bool, error)
+		wantMarked bool
+		wantCached bool
+	}{
+		{
+			name:       "failed",
+			state:      btypes.BatchSpecWorkspaceExecutionJobStateProcessing,
+			mark:       func(id int) (bool, error) { return executionStore.MarkFailed(ctx, id, "boom", opts) },
+			wantMarked: true,
+			wantCached: true,
+		},
+		{
+			name:       "errored",
+			state:      btypes.BatchSpecWorkspaceExecutionJobStateProcessing,
+			mark:       func(id int) (bool, error) { return executionStore.MarkErrored(ctx, id, "boom", opts) },
+			wantMarked: true,
+			wantCached: true,
+		},
+		{
+			name:       "canceled",
+			state:      btypes.BatchSpecWorkspaceExecutionJobStateProcessing,
+			cancel:     true,
+			mark:       func(id int) (bool, error) { return executionStore.MarkFailed(ctx, id, "canceled", opts) },
+			wantMarked: true,
+			wantCached: false,
+		},
+		{
+			name:       "not processing",
+			state:      btypes.BatchSpecWorkspaceExecutionJobStateFailed,
+			mark:       func(id int) (bool, error) { return executionStore.MarkErrored(ctx, id, "boom", opts) },
+			wantMarked: false,
+			wantCached: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			job, listOpts := createJob(t, tc.state, tc.cancel)
+
+			ok, err := tc.mark(int(job.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.wantMarked {
+				t.Fatalf("wrong marked. want=%t, have=%t", tc.wantMarked, ok)
+			}
+
+			entries, err := s.ListBatchSpecExecutionCacheEntries(ctx, listOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := len(entries) == 1; have != tc.wantCached {
+				t.Fatalf("wrong cache entries. want cached=%t, have=%+v", tc.wantCached, entries)
+			}
+		})
+	}
+}
+
 func intptr(i int) *int { return &i }
@@ -35,7 +35,7 @@ func newBulkOperationWorker(
 	sourcer sources.Sourcer,
 	metrics batchChangesMetrics,
 ) *workerutil.Worker {
-	r := &bulkProcessorWorker{sourcer: sourcer, store: s}
+	r := &bulkProcessorWorker{sourcer: sourcer, store: s, workerStore: workerStore}
 
 	options := workerutil.WorkerOptions{
 		Name:              "batches_bulk_processor",
@@ -90,22 +90,33 @@ func scanFirstChangesetJobRecord(rows *sql.Rows, err error) (workerutil.Record,
 // bulkProcessorWorker is a wrapper for the workerutil handlerfunc to create a
 // bulkProcessor with a source and store.
 type bulkProcessorWorker struct {
-	store   *store.Store
-	sourcer sources.Sourcer
+	store       *store.Store
+	workerStore dbworkerstore.Store
+	sourcer     sources.Sourcer
 }
 
 func (b *bulkProcessorWorker) HandlerFunc() workerutil.HandlerFunc {
-	return func(ctx context.Context, record workerutil.Record) (err error) {
+	return func(ctx context.Context, record workerutil.Record) error {
 		job := record.(*btypes.ChangesetJob)
 
-		tx, err := b.store.Transact(ctx)
-		if err != nil {
-			return err
+		err := b.process(ctx, job)
+		if after, ok := processor.RequeueAfter(err); ok {
+			// Requeueing the job moves it out of the processing state, so the
+			// worker doesn't mark it as completed once we return.
+			return b.workerStore.Requeue(ctx, job.RecordID(), b.store.Clock()().Add(after))
 		}
-		defer func() { err = tx.Done(err) }()
-
-		p := processor.New(tx, b.sourcer)
+		return err
+	}
+}
 
-		return p.Process(ctx, job)
+func (b *bulkProcessorWorker) process(ctx context.Context, job *btypes.ChangesetJob) (err error) {
+	tx, err := b.store.Transact(ctx)
+	if err != nil {
+		return err
 	}
+	defer func() { err = tx.Done(err) }()
+
+	p := processor.New(tx, b.sourcer)
+
+	return p.Process(ctx, job)
 }
@@ -0,0 +1,70 @@
+package background
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/global"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/reconciler"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+)
+
+// changesetRebaserInterval is how often the base branches of the changesets of
+// batch changes rebasing changesets automatically are checked.
+const changesetRebaserInterval = 5 * time.Minute
+
+// newChangesetRebaser periodically enqueues the changesets of batch changes
+// rebasing changesets automatically whose base branch moved, so that the
+// reconciler rebases them.
+func newChangesetRebaser(ctx context.Context, s *store.Store) goroutine.BackgroundRoutine {
+	return goroutine.NewPeriodicGoroutine(
+		ctx,
+		changesetRebaserInterval,
+		goroutine.NewHandlerWithErrorMessage("enqueue changesets to rebase", func(ctx context.Context) error {
+			return enqueueChangesetsToRebase(ctx, s)
+		}),
+	)
+}
+
+func enqueueChangesetsToRebase(ctx context.Context, s *store.Store) error {
+	changesets, err := s.ListAutoRebaseChangesets(ctx)
+	if err != nil {
+		return errors.Wrap(err, "ListAutoRebaseChangesets")
+	}
+
+	for _, ch := range changesets {
+		spec, err := s.GetChangesetSpecByID(ctx, ch.CurrentSpecID)
+		if err != nil {
+			return errors.Wrapf(err, "getting changeset spec for changeset %d", ch.ID)
+		}
+		if !reconciler.Rebaseable(ch, spec) {
+			continue
+		}
+
+		repo, err := s.Repos().Get(ctx, ch.RepoID)
+		if err != nil {
+			return errors.Wrapf(err, "getting repository of changeset %d", ch.ID)
+		}
+
+		_, behind, err := reconciler.RebaseTarget(ctx, repo.Name, ch, spec)
+		if err != nil {
+			// A base branch that can't be resolved shouldn't prevent other
+			// changesets from being rebased.
+			log15.Warn("Checking whether changeset needs a rebase", "changeset", ch.ID, "err", err)
+			continue
+		}
+		if !behind {
+			continue
+		}
+
+		if err := s.EnqueueChangeset(ctx, ch, global.DefaultReconcilerEnqueueState(), ""); err != nil {
+			return errors.Wrapf(err, "enqueueing changeset %d", ch.ID)
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,15 @@
 package background
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/inconshreveable/log15"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker"
@@ -20,6 +25,12 @@ type batchChangesMetrics struct {
 	batchSpecResolutionWorkerResetterMetrics dbworker.ResetterMetrics
 
 	batchSpecWorkspaceExecutionWorkerResetterMetrics dbworker.ResetterMetrics
+
+	batchChangeRollbackWorkerMetrics         workerutil.WorkerMetrics
+	batchChangeRollbackWorkerResetterMetrics dbworker.ResetterMetrics
+
+	outboundWebhookWorkerMetrics         workerutil.WorkerMetrics
+	outboundWebhookWorkerResetterMetrics dbworker.ResetterMetrics
 }
 
 func newMetrics(observationContext *observation.Context) batchChangesMetrics {
@@ -33,6 +44,12 @@ func newMetrics(observationContext *observation.Context) batchChangesMetrics {
 		batchSpecResolutionWorkerResetterMetrics: makeResetterMetrics(observationContext, "batch_changes_batch_spec_resolution_worker_resetter"),
 
 		batchSpecWorkspaceExecutionWorkerResetterMetrics: makeResetterMetrics(observationContext, "batch_spec_workspace_execution_worker_resetter"),
+
+		batchChangeRollbackWorkerMetrics:         workerutil.NewMetrics(observationContext, "batch_changes_batch_change_rollback_worker", nil),
+		batchChangeRollbackWorkerResetterMetrics: makeResetterMetrics(observationContext, "batch_changes_batch_change_rollback_worker_resetter"),
+
+		outboundWebhookWorkerMetrics:         workerutil.NewMetrics(observationContext, "batch_changes_outbound_webhook_worker", nil),
+		outboundWebhookWorkerResetterMetrics: makeResetterMetrics(observationContext, "batch_changes_outbound_webhook_worker_resetter"),
 	}
 }
 
@@ -60,3 +77,92 @@ func makeResetterMetrics(observationContext *observation.Context, workerName str
 		Errors:              errors,
 	}
 }
+
+// batchSpecResolutionQueueCollectTimeout bounds how long collecting the
+// resolution queue metrics may take per scrape.
+const batchSpecResolutionQueueCollectTimeout = 5 * time.Second
+
+// batchSpecResolutionQueueCollector exports the state of the batch spec
+// resolution job queue, so that admins can alert on a backed-up queue.
+type batchSpecResolutionQueueCollector struct {
+	store *store.Store
+
+	jobsDesc                  *prometheus.Desc
+	oldestQueuedAgeDesc       *prometheus.Desc
+	averageProcessingTimeDesc *prometheus.Desc
+}
+
+var _ prometheus.Collector = &batchSpecResolutionQueueCollector{}
+
+func newBatchSpecResolutionQueueCollector(s *store.Store) *batchSpecResolutionQueueCollector {
+	return &batchSpecResolutionQueueCollector{
+		store: s,
+		jobsDesc: prometheus.NewDesc(
+			"src_batch_changes_batch_spec_resolution_jobs",
+			"The number of batch spec resolution jobs per state.",
+			[]string{"state"},
+			nil,
+		),
+		oldestQueuedAgeDesc: prometheus.NewDesc(
+			"src_batch_changes_batch_spec_resolution_oldest_queued_age_seconds",
+			"The time since the oldest queued batch spec resolution job was created.",
+			nil,
+			nil,
+		),
+		averageProcessingTimeDesc: prometheus.NewDesc(
+			"src_batch_changes_batch_spec_resolution_average_processing_seconds",
+			"The average processing time of recently completed batch spec resolution jobs.",
+			nil,
+			nil,
+		),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *batchSpecResolutionQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.jobsDesc
+	ch <- c.oldestQueuedAgeDesc
+	ch <- c.averageProcessingTimeDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *batchSpecResolutionQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchSpecResolutionQueueCollectTimeout)
+	defer cancel()
+
+	stats, err := c.store.GetBatchSpecResolutionJobStats(ctx)
+	if err != nil {
+		log15.Error("failed to collect batch spec resolution queue metrics", "err", err)
+		return
+	}
+
+	for _, state := range []btypes.BatchSpecResolutionJobState{
+		btypes.BatchSpecResolutionJobStateQueued,
+		btypes.BatchSpecResolutionJobStateProcessing,
+		btypes.BatchSpecResolutionJobStateErrored,
+		btypes.BatchSpecResolutionJobStateFailed,
+		btypes.BatchSpecResolutionJobStateCompleted,
+	} {
+		ch <- prometheus.MustNewConstMetric(
+			c.jobsDesc,
+			prometheus.GaugeValue,
+			float64(stats.Counts[state]),
+			string(state),
+		)
+	}
+
+	var oldestQueuedAge time.Duration
+	if !stats.OldestQueuedAt.IsZero() {
+		oldestQueuedAge = c.store.Clock()().Sub(stats.OldestQueuedAt)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.oldestQueuedAgeDesc,
+		prometheus.GaugeValue,
+		oldestQueuedAge.Seconds(),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.averageProcessingTimeDesc,
+		prometheus.GaugeValue,
+		stats.AverageProcessingTime.Seconds(),
+	)
+}
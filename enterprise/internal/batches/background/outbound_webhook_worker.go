@@ -0,0 +1,213 @@
+package background
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+// Finished resolutions and executions are looked for every
+// outboundWebhookEnqueueInterval. Each time, everything that finished within
+// outboundWebhookEventLookback is considered, so that events aren't lost if
+// enqueueing fails for a while. Events are only delivered once either way.
+const (
+	outboundWebhookEnqueueInterval = 10 * time.Second
+	outboundWebhookEventLookback   = 1 * time.Hour
+)
+
+// outboundWebhookMaxNumRetries is the maximum number of attempts made to
+// deliver an event to a webhook that doesn't respond successfully.
+const outboundWebhookMaxNumRetries = 5
+
+// outboundWebhookMaxNumResets is the maximum number of attempts made to
+// deliver an event when the delivery stalls (process crashes, etc.).
+const outboundWebhookMaxNumResets = 60
+
+// outboundWebhookSignatureHeader carries the hex encoded HMAC-SHA256 of the
+// request body, keyed with the secret of the webhook, prefixed with "sha256=".
+const outboundWebhookSignatureHeader = "X-Sourcegraph-Signature"
+
+// outboundWebhookEventHeader carries the event that is delivered.
+const outboundWebhookEventHeader = "X-Sourcegraph-Event"
+
+// newOutboundWebhookEnqueuer periodically enqueues deliveries of the batch
+// spec resolutions and executions that finished to the outbound webhooks.
+func newOutboundWebhookEnqueuer(ctx context.Context, s *store.Store) goroutine.BackgroundRoutine {
+	return goroutine.NewPeriodicGoroutine(
+		ctx,
+		outboundWebhookEnqueueInterval,
+		goroutine.NewHandlerWithErrorMessage("enqueue outbound webhook deliveries", func(ctx context.Context) error {
+			return s.EnqueueOutboundWebhookJobs(ctx, s.Clock()().Add(-outboundWebhookEventLookback))
+		}),
+	)
+}
+
+// newOutboundWebhookWorker creates a dbworker.Worker that fetches enqueued
+// batch_changes_outbound_webhook_jobs from the database and delivers them.
+func newOutboundWebhookWorker(
+	ctx context.Context,
+	s *store.Store,
+	workerStore dbworkerstore.Store,
+	cf *httpcli.Factory,
+	metrics batchChangesMetrics,
+) *workerutil.Worker {
+	w := &outboundWebhookWorker{store: s, httpFactory: cf}
+
+	options := workerutil.WorkerOptions{
+		Name:              "batches_outbound_webhook_worker",
+		NumHandlers:       5,
+		HeartbeatInterval: 15 * time.Second,
+		Interval:          5 * time.Second,
+		Metrics:           metrics.outboundWebhookWorkerMetrics,
+	}
+
+	return dbworker.NewWorker(ctx, workerStore, w.HandlerFunc(), options)
+}
+
+// newOutboundWebhookWorkerResetter creates a dbworker.Resetter that
+// reenqueues lost outbound webhook deliveries.
+func newOutboundWebhookWorkerResetter(workerStore dbworkerstore.Store, metrics batchChangesMetrics) *dbworker.Resetter {
+	options := dbworker.ResetterOptions{
+		Name:     "batches_outbound_webhook_worker_resetter",
+		Interval: 1 * time.Minute,
+		Metrics:  metrics.outboundWebhookWorkerResetterMetrics,
+	}
+
+	return dbworker.NewResetter(workerStore, options)
+}
+
+func newOutboundWebhookWorkerStore(handle *basestore.TransactableHandle, observationContext *observation.Context) dbworkerstore.Store {
+	options := dbworkerstore.Options{
+		Name:              "batches_outbound_webhook_worker_store",
+		TableName:         "batch_changes_outbound_webhook_jobs",
+		ColumnExpressions: store.OutboundWebhookJobColumns.ToSqlf(),
+		Scan:              scanFirstOutboundWebhookJobRecord,
+
+		// Events are delivered in the order they occurred.
+		OrderByExpression: sqlf.Sprintf("batch_changes_outbound_webhook_jobs.occurred_at, batch_changes_outbound_webhook_jobs.id"),
+
+		StalledMaxAge: 1 * time.Minute,
+		MaxNumResets:  outboundWebhookMaxNumResets,
+
+		RetryAfter:    1 * time.Minute,
+		MaxNumRetries: outboundWebhookMaxNumRetries,
+	}
+
+	return dbworkerstore.NewWithMetrics(handle, options, observationContext)
+}
+
+// scanFirstOutboundWebhookJobRecord wraps store.ScanFirstOutboundWebhookJob
+// to return a generic workerutil.Record.
+func scanFirstOutboundWebhookJobRecord(rows *sql.Rows, err error) (workerutil.Record, bool, error) {
+	return store.ScanFirstOutboundWebhookJob(rows, err)
+}
+
+// outboundWebhookPayload is the JSON body of outbound webhook deliveries.
+type outboundWebhookPayload struct {
+	Event     btypes.OutboundWebhookEvent `json:"event"`
+	BatchSpec struct {
+		// ID is the GraphQL ID of the batch spec.
+		ID graphql.ID `json:"id"`
+	} `json:"batchSpec"`
+	// Message is the failure message of failed resolutions.
+	Message    string    `json:"message,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// outboundWebhookWorker is a wrapper for the workerutil handlerfunc to
+// deliver events to outbound webhooks.
+type outboundWebhookWorker struct {
+	store       *store.Store
+	httpFactory *httpcli.Factory
+}
+
+func (w *outboundWebhookWorker) HandlerFunc() workerutil.HandlerFunc {
+	return func(ctx context.Context, record workerutil.Record) error {
+		job := record.(*btypes.OutboundWebhookJob)
+
+		webhook, err := w.store.GetOutboundWebhook(ctx, store.GetOutboundWebhookOpts{ID: job.WebhookID})
+		if err != nil {
+			return errors.Wrap(err, "getting webhook")
+		}
+
+		batchSpec, err := w.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: job.BatchSpecID})
+		if err != nil {
+			return errors.Wrap(err, "getting batch spec")
+		}
+
+		payload := outboundWebhookPayload{
+			Event:      job.Event,
+			Message:    job.EventMessage,
+			OccurredAt: job.OccurredAt,
+		}
+		payload.BatchSpec.ID = relay.MarshalID("BatchSpec", batchSpec.RandID)
+
+		cli, err := w.httpFactory.Doer()
+		if err != nil {
+			return errors.Wrap(err, "creating HTTP client")
+		}
+
+		return deliverOutboundWebhook(ctx, cli, webhook, payload)
+	}
+}
+
+// deliverOutboundWebhook POSTs the payload to the webhook, signed with its
+// secret. Responses other than 2xx are errors, so that the delivery is
+// retried.
+func deliverOutboundWebhook(ctx context.Context, cli httpcli.Doer, webhook *btypes.OutboundWebhook, payload outboundWebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	secret, err := webhook.Secret(ctx)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(outboundWebhookEventHeader, string(payload.Event))
+	req.Header.Set(outboundWebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Include the start of the response in the failure message, it
+		// usually explains what went wrong.
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("webhook responded with status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package background
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+func TestDeliverOutboundWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	payload := outboundWebhookPayload{
+		Event:      btypes.OutboundWebhookEventResolutionFailed,
+		Message:    "repository not found",
+		OccurredAt: time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+	}
+	payload.BatchSpec.ID = "QmF0Y2hTcGVjOiJhYmMi"
+
+	webhook := &btypes.OutboundWebhook{}
+	if err := webhook.SetSecret(ctx, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var have outboundWebhookPayload
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write(body)
+			if have, want := r.Header.Get(outboundWebhookSignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); have != want {
+				t.Errorf("wrong signature. want=%q, have=%q", want, have)
+			}
+			if have, want := r.Header.Get(outboundWebhookEventHeader), string(payload.Event); have != want {
+				t.Errorf("wrong event. want=%q, have=%q", want, have)
+			}
+			if err := json.Unmarshal(body, &have); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		webhook.URL = srv.URL
+		if err := deliverOutboundWebhook(ctx, srv.Client(), webhook, payload); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(payload, have); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		webhook.URL = srv.URL
+		if err := deliverOutboundWebhook(ctx, srv.Client(), webhook, payload); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
@@ -7,11 +7,16 @@ import (
 	"github.com/cockroachdb/errors"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/goroutine"
 )
 
 const specExpireInteral = 2 * time.Minute
 
+// executionCacheEntryTTL is how long cached step results are kept after they
+// were last used.
+const executionCacheEntryTTL = 7 * 24 * time.Hour
+
 func newSpecExpireJob(ctx context.Context, cstore *store.Store) goroutine.BackgroundRoutine {
 	return goroutine.NewPeriodicGoroutine(
 		ctx,
@@ -23,10 +28,46 @@ func newSpecExpireJob(ctx context.Context, cstore *store.Store) goroutine.Backgr
 			}
 			// ... and then the BatchSpecs, due to the batch_spec_id
 			// foreign key on changeset_specs.
-			if err := cstore.DeleteExpiredBatchSpecs(ctx); err != nil {
-				return errors.Wrap(err, "DeleteExpiredBatchSpecs")
+			if err := deleteExpiredBatchSpecs(ctx, cstore); err != nil {
+				return err
+			}
+			if err := cstore.DeleteBatchSpecExecutionCacheEntries(ctx, store.DeleteBatchSpecExecutionCacheEntriesOpts{
+				LastUsedBefore: cstore.Clock()().Add(-executionCacheEntryTTL),
+			}); err != nil {
+				return errors.Wrap(err, "DeleteBatchSpecExecutionCacheEntries")
 			}
 			return nil
 		}),
 	)
 }
+
+// deleteExpiredBatchSpecs deletes the expired BatchSpecs and records their
+// deletion in the audit log.
+func deleteExpiredBatchSpecs(ctx context.Context, cstore *store.Store) (err error) {
+	tx, err := cstore.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	deletedIDs, err := tx.DeleteExpiredBatchSpecs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "DeleteExpiredBatchSpecs")
+	}
+	return recordExpiryAuditEvents(ctx, tx, btypes.AuditEventResourceTypeBatchSpec, deletedIDs)
+}
+
+// recordExpiryAuditEvents records in the audit log that Sourcegraph deleted
+// the resources with the given IDs because they expired.
+func recordExpiryAuditEvents(ctx context.Context, tx *store.Store, resourceType btypes.AuditEventResourceType, ids []int64) error {
+	events := make([]*btypes.AuditEvent, 0, len(ids))
+	for _, id := range ids {
+		events = append(events, &btypes.AuditEvent{
+			Action:       btypes.AuditEventActionDeleted,
+			ResourceType: resourceType,
+			ResourceID:   id,
+			Metadata:     map[string]string{"expired": "true"},
+		})
+	}
+	return errors.Wrap(tx.CreateAuditEvents(ctx, events...), "CreateAuditEvents")
+}
@@ -0,0 +1,32 @@
+package codehostlimit
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// Like the rollout window configuration, this is a singleton, because the
+// limits have to be shared by everything that talks to code hosts on behalf
+// of batch changes in a process.
+var (
+	defaultLimiter *Limiter
+	defaultOnce    sync.Once
+)
+
+// Default returns the Limiter configured by the
+// batchChanges.codeHostRequestLimits site configuration. It's kept up to date
+// with changes to the site configuration, and its utilization is exported as
+// metrics.
+func Default() *Limiter {
+	defaultOnce.Do(func() {
+		defaultLimiter = NewLimiter(nil)
+		conf.Watch(func() {
+			defaultLimiter.Configure(conf.Get().BatchChangesCodeHostRequestLimits)
+		})
+		prometheus.MustRegister(newUtilizationCollector(defaultLimiter))
+	})
+	return defaultLimiter
+}
@@ -0,0 +1,204 @@
+// Package codehostlimit limits the requests that batch changes make to the API
+// of a code host, as configured in batchChanges.codeHostRequestLimits.
+package codehostlimit
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// Limiter limits the number of concurrent requests, and the rate of requests,
+// per code host. Code hosts are identified by their normalized base URL, which
+// is the ServiceID of the external repositories hosted on them.
+//
+// A nil *Limiter doesn't limit anything.
+type Limiter struct {
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+type host struct {
+	concurrency       int
+	requestsPerSecond float64
+	rateLimiter       *rate.Limiter
+
+	inUse   int
+	waiting int
+
+	// changed is closed, and replaced, whenever a request finishes or the
+	// limits of the code host change, to wake up waiting requests.
+	changed chan struct{}
+}
+
+func (h *host) notify() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// NewLimiter returns a Limiter enforcing the given limits.
+func NewLimiter(limits []*schema.BatchChangeCodeHostRequestLimit) *Limiter {
+	l := &Limiter{hosts: map[string]*host{}}
+	l.Configure(limits)
+	return l
+}
+
+// Configure replaces the limits of the Limiter. Requests that are already
+// running are counted against the new limits.
+func (l *Limiter) Configure(limits []*schema.BatchChangeCodeHostRequestLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	configured := make(map[string]struct{}, len(limits))
+	for _, limit := range limits {
+		key, err := normalizeURL(limit.Url)
+		if err != nil {
+			log15.Warn("ignoring batch changes request limit of invalid code host URL", "url", limit.Url, "err", err)
+			continue
+		}
+		configured[key] = struct{}{}
+
+		h, ok := l.hosts[key]
+		if !ok {
+			h = &host{changed: make(chan struct{})}
+			l.hosts[key] = h
+		}
+		h.concurrency = limit.Concurrency
+		if h.requestsPerSecond != limit.RequestsPerSecond {
+			h.requestsPerSecond = limit.RequestsPerSecond
+			h.rateLimiter = nil
+			if limit.RequestsPerSecond > 0 {
+				h.rateLimiter = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), 1)
+			}
+		}
+		h.notify()
+	}
+
+	// Requests for code hosts that are no longer limited are let through.
+	// Requests that are still running keep a reference to their host, so
+	// they can be released safely.
+	for key, h := range l.hosts {
+		if _, ok := configured[key]; !ok {
+			h.concurrency = 0
+			h.rateLimiter = nil
+			h.notify()
+			delete(l.hosts, key)
+		}
+	}
+}
+
+// Acquire blocks until a request to the code host with the given base URL is
+// allowed by the limits, or ctx is canceled. The returned
+// function must be called when the request is done.
+func (l *Limiter) Acquire(ctx context.Context, codeHostURL string) (release func(), err error) {
+	noop := func() {}
+	if l == nil {
+		return noop, nil
+	}
+
+	key, err := normalizeURL(codeHostURL)
+	if err != nil {
+		return noop, nil
+	}
+
+	l.mu.Lock()
+	h, ok := l.hosts[key]
+	if !ok {
+		l.mu.Unlock()
+		return noop, nil
+	}
+
+	h.waiting++
+	for h.concurrency > 0 && h.inUse >= h.concurrency {
+		changed := h.changed
+		l.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			l.mu.Lock()
+			h.waiting--
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	h.waiting--
+	h.inUse++
+	rateLimiter := h.rateLimiter
+	l.mu.Unlock()
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			h.inUse--
+			h.notify()
+		})
+	}
+
+	if rateLimiter != nil {
+		if err := rateLimiter.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// Utilization describes the limits of a code host and how much of them is
+// currently used.
+type Utilization struct {
+	// CodeHostURL is the normalized base URL of the code host.
+	CodeHostURL       string
+	Concurrency       int
+	RequestsPerSecond float64
+
+	// InUse is the number of requests currently running.
+	InUse int
+	// Waiting is the number of requests waiting for a free slot.
+	Waiting int
+}
+
+// Utilization returns the current utilization of all limited code hosts,
+// ordered by URL.
+func (l *Limiter) Utilization() []Utilization {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	us := make([]Utilization, 0, len(l.hosts))
+	for key, h := range l.hosts {
+		us = append(us, Utilization{
+			CodeHostURL:       key,
+			Concurrency:       h.concurrency,
+			RequestsPerSecond: h.requestsPerSecond,
+			InUse:             h.inUse,
+			Waiting:           h.waiting,
+		})
+	}
+	sort.Slice(us, func(i, j int) bool { return us[i].CodeHostURL < us[j].CodeHostURL })
+	return us
+}
+
+// normalizeURL normalizes the given code host URL the same way as the
+// ServiceID of external repositories.
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	return extsvc.NormalizeBaseURL(u).String(), nil
+}
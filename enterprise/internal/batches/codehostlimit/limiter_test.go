@@ -0,0 +1,168 @@
+package codehostlimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil limiter", func(t *testing.T) {
+		var l *Limiter
+		release, err := l.Acquire(ctx, "https://github.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		if have := l.Utilization(); have != nil {
+			t.Fatalf("unexpected utilization: %+v", have)
+		}
+	})
+
+	t.Run("unlimited code host", func(t *testing.T) {
+		l := NewLimiter([]*schema.BatchChangeCodeHostRequestLimit{
+			{Url: "https://github.example.com", Concurrency: 1},
+		})
+		for i := 0; i < 3; i++ {
+			if _, err := l.Acquire(ctx, "https://gitlab.example.com/"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		l := NewLimiter([]*schema.BatchChangeCodeHostRequestLimit{
+			{Url: "https://GitHub.example.com", Concurrency: 1},
+		})
+
+		release, err := l.Acquire(ctx, "https://github.example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		acquired := make(chan struct{})
+		go func() {
+			release, err := l.Acquire(ctx, "https://github.example.com/")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			release()
+			close(acquired)
+		}()
+
+		waitForUtilization(t, l, []Utilization{
+			{CodeHostURL: "https://github.example.com/", Concurrency: 1, InUse: 1, Waiting: 1},
+		})
+
+		release()
+		// Releasing twice must not free another slot.
+		release()
+
+		select {
+		case <-acquired:
+		case <-time.After(5 * time.Second):
+			t.Fatal("second request was not let through")
+		}
+
+		waitForUtilization(t, l, []Utilization{
+			{CodeHostURL: "https://github.example.com/", Concurrency: 1},
+		})
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		l := NewLimiter([]*schema.BatchChangeCodeHostRequestLimit{
+			{Url: "https://github.example.com/", Concurrency: 1},
+		})
+
+		if _, err := l.Acquire(ctx, "https://github.example.com/"); err != nil {
+			t.Fatal(err)
+		}
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := l.Acquire(cancelCtx, "https://github.example.com/"); err != context.DeadlineExceeded {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		waitForUtilization(t, l, []Utilization{
+			{CodeHostURL: "https://github.example.com/", Concurrency: 1, InUse: 1},
+		})
+	})
+
+	t.Run("reconfigured", func(t *testing.T) {
+		l := NewLimiter([]*schema.BatchChangeCodeHostRequestLimit{
+			{Url: "https://github.example.com/", Concurrency: 1},
+		})
+
+		release, err := l.Acquire(ctx, "https://github.example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		acquired := make(chan struct{})
+		go func() {
+			if _, err := l.Acquire(ctx, "https://github.example.com/"); err != nil {
+				t.Error(err)
+			}
+			close(acquired)
+		}()
+
+		waitForUtilization(t, l, []Utilization{
+			{CodeHostURL: "https://github.example.com/", Concurrency: 1, InUse: 1, Waiting: 1},
+		})
+
+		// Removing the limit lets waiting requests through.
+		l.Configure(nil)
+
+		select {
+		case <-acquired:
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiting request was not let through")
+		}
+
+		waitForUtilization(t, l, []Utilization{})
+		release()
+	})
+
+	t.Run("requests per second", func(t *testing.T) {
+		l := NewLimiter([]*schema.BatchChangeCodeHostRequestLimit{
+			{Url: "https://github.example.com/", RequestsPerSecond: 0.001},
+		})
+
+		release, err := l.Acquire(ctx, "https://github.example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := l.Acquire(cancelCtx, "https://github.example.com/"); err == nil {
+			t.Fatal("expected error, request was let through")
+		}
+
+		waitForUtilization(t, l, []Utilization{
+			{CodeHostURL: "https://github.example.com/", RequestsPerSecond: 0.001},
+		})
+	})
+}
+
+func waitForUtilization(t *testing.T, l *Limiter, want []Utilization) {
+	t.Helper()
+
+	var have []Utilization
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		have = l.Utilization()
+		if cmp.Equal(want, have) {
+			return
+		}
+	}
+	t.Fatalf("unexpected utilization (-want +have):\n%s", cmp.Diff(want, have))
+}
@@ -0,0 +1,48 @@
+package codehostlimit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// utilizationCollector exports the utilization of a Limiter. Limits are kept
+// in memory, so every process reports the requests it makes itself.
+type utilizationCollector struct {
+	limiter *Limiter
+
+	inUseDesc   *prometheus.Desc
+	waitingDesc *prometheus.Desc
+}
+
+var _ prometheus.Collector = &utilizationCollector{}
+
+func newUtilizationCollector(l *Limiter) *utilizationCollector {
+	return &utilizationCollector{
+		limiter: l,
+		inUseDesc: prometheus.NewDesc(
+			"src_batch_changes_code_host_requests_in_use",
+			"The number of running batch changes requests to a limited code host.",
+			[]string{"code_host"},
+			nil,
+		),
+		waitingDesc: prometheus.NewDesc(
+			"src_batch_changes_code_host_requests_waiting",
+			"The number of batch changes requests waiting for the limits of a code host.",
+			[]string{"code_host"},
+			nil,
+		),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *utilizationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inUseDesc
+	ch <- c.waitingDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *utilizationCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, u := range c.limiter.Utilization() {
+		ch <- prometheus.MustNewConstMetric(c.inUseDesc, prometheus.GaugeValue, float64(u.InUse), u.CodeHostURL)
+		ch <- prometheus.MustNewConstMetric(c.waitingDesc, prometheus.GaugeValue, float64(u.Waiting), u.CodeHostURL)
+	}
+}
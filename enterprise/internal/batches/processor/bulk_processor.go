@@ -3,6 +3,8 @@ package processor
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/inconshreveable/log15"
@@ -34,6 +36,37 @@ func (e unknownJobTypeErr) NonRetryable() bool {
 
 var changesetIsProcessingErr = errors.New("cannot update a changeset that is currently being processed; will retry")
 
+// requeueErr is returned when a ChangesetJob cannot be processed yet and should
+// be processed again after a delay, without counting as a failed attempt.
+type requeueErr struct {
+	reason string
+	after  time.Duration
+}
+
+func (e requeueErr) Error() string {
+	return e.reason
+}
+
+// RequeueAfter returns the delay after which the job should be processed again,
+// if the given error returned by Process asks for the job to be requeued.
+func RequeueAfter(err error) (time.Duration, bool) {
+	var r requeueErr
+	if errors.As(err, &r) {
+		return r.after, true
+	}
+	return 0, false
+}
+
+// mergeWhenChecksPassInterval is the delay after which a job waiting for the
+// checks of its changeset to pass looks at them again. Webhooks updating the
+// changeset expedite waiting jobs, so this only matters for code hosts without
+// webhooks configured, whose changesets are updated by the syncer.
+const mergeWhenChecksPassInterval = 2 * time.Minute
+
+// mergeWhenChecksPassTimeout is how long a job waits for the checks of its
+// changeset to pass before giving up.
+const mergeWhenChecksPassTimeout = 24 * time.Hour
+
 func New(tx *store.Store, sourcer sources.Sourcer) BulkProcessor {
 	return &bulkProcessor{
 		tx:      tx,
@@ -101,6 +134,8 @@ func (b *bulkProcessor) Process(ctx context.Context, job *btypes.ChangesetJob) (
 		return b.closeChangeset(ctx, job)
 	case btypes.ChangesetJobTypePublish:
 		return b.publishChangeset(ctx, job)
+	case btypes.ChangesetJobTypeMergeWhenChecksPass:
+		return b.mergeChangesetWhenChecksPass(ctx, job)
 
 	default:
 		return &unknownJobTypeErr{jobType: string(job.JobType)}
@@ -161,11 +196,48 @@ func (b *bulkProcessor) mergeChangeset(ctx context.Context, job *btypes.Changese
 		return errors.Errorf("invalid payload type for changeset_job, want=%T have=%T", &btypes.ChangesetJobMergePayload{}, job.Payload)
 	}
 
+	return b.merge(ctx, typedPayload.Squash)
+}
+
+func (b *bulkProcessor) mergeChangesetWhenChecksPass(ctx context.Context, job *btypes.ChangesetJob) (err error) {
+	typedPayload, ok := job.Payload.(*btypes.ChangesetJobMergeWhenChecksPassPayload)
+	if !ok {
+		return errors.Errorf("invalid payload type for changeset_job, want=%T have=%T", &btypes.ChangesetJobMergeWhenChecksPassPayload{}, job.Payload)
+	}
+
+	// The changeset may have been merged or closed on the code host while the
+	// job was waiting.
+	switch b.ch.ExternalState {
+	case btypes.ChangesetExternalStateMerged:
+		return nil
+	case btypes.ChangesetExternalStateOpen:
+	default:
+		return errcode.MakeNonRetryable(errors.Newf("cannot merge a changeset in state %s", b.ch.ExternalState))
+	}
+
+	switch b.ch.ExternalCheckState {
+	case btypes.ChangesetCheckStatePassed:
+		return b.merge(ctx, typedPayload.Squash)
+	case btypes.ChangesetCheckStateFailed:
+		return errcode.MakeNonRetryable(errors.New("checks failed on the code host; not merging"))
+	}
+
+	// The checks are still pending, or the code host hasn't reported any yet.
+	if b.tx.Clock()().Sub(job.CreatedAt) > mergeWhenChecksPassTimeout {
+		return errcode.MakeNonRetryable(errors.Newf("checks did not pass within %s; not merging", mergeWhenChecksPassTimeout))
+	}
+	return requeueErr{
+		reason: fmt.Sprintf("checks are %s; will retry", strings.ToLower(string(b.ch.ExternalCheckState))),
+		after:  mergeWhenChecksPassInterval,
+	}
+}
+
+func (b *bulkProcessor) merge(ctx context.Context, squash bool) (err error) {
 	cs := &sources.Changeset{
 		Changeset: b.ch,
 		Repo:      b.repo,
 	}
-	if err := b.css.MergeChangeset(ctx, cs, typedPayload.Squash); err != nil {
+	if err := b.css.MergeChangeset(ctx, cs, squash); err != nil {
 		return err
 	}
 
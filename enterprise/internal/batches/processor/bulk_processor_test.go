@@ -3,6 +3,7 @@ package processor
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/global"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/sources"
@@ -211,6 +212,97 @@ func TestBulkProcessor(t *testing.T) {
 		}
 	})
 
+	t.Run("Merge when checks pass job", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			externalState btypes.ChangesetExternalState
+			checkState    btypes.ChangesetCheckState
+			createdAt     time.Time
+			wantMerge     bool
+			wantRequeue   bool
+			wantErr       bool
+		}{
+			"checks passed": {
+				externalState: btypes.ChangesetExternalStateOpen,
+				checkState:    btypes.ChangesetCheckStatePassed,
+				createdAt:     time.Now(),
+				wantMerge:     true,
+			},
+			"checks pending": {
+				externalState: btypes.ChangesetExternalStateOpen,
+				checkState:    btypes.ChangesetCheckStatePending,
+				createdAt:     time.Now(),
+				wantRequeue:   true,
+			},
+			"checks unknown": {
+				externalState: btypes.ChangesetExternalStateOpen,
+				checkState:    btypes.ChangesetCheckStateUnknown,
+				createdAt:     time.Now(),
+				wantRequeue:   true,
+			},
+			"checks failed": {
+				externalState: btypes.ChangesetExternalStateOpen,
+				checkState:    btypes.ChangesetCheckStateFailed,
+				createdAt:     time.Now(),
+				wantErr:       true,
+			},
+			"checks pending for too long": {
+				externalState: btypes.ChangesetExternalStateOpen,
+				checkState:    btypes.ChangesetCheckStatePending,
+				createdAt:     time.Now().Add(-mergeWhenChecksPassTimeout - time.Hour),
+				wantErr:       true,
+			},
+			"already merged": {
+				externalState: btypes.ChangesetExternalStateMerged,
+				checkState:    btypes.ChangesetCheckStatePending,
+				createdAt:     time.Now(),
+			},
+			"closed": {
+				externalState: btypes.ChangesetExternalStateClosed,
+				checkState:    btypes.ChangesetCheckStatePassed,
+				createdAt:     time.Now(),
+				wantErr:       true,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				fake := &sources.FakeChangesetSource{}
+				bp := &bulkProcessor{
+					tx:      bstore,
+					sourcer: sources.NewFakeSourcer(nil, fake),
+				}
+				changeset := ct.CreateChangeset(t, ctx, bstore, ct.TestChangesetOpts{
+					Repo:                repo.ID,
+					BatchChanges:        []types.BatchChangeAssoc{{BatchChangeID: batchChange.ID}},
+					Metadata:            &github.PullRequest{},
+					ExternalServiceType: extsvc.TypeGitHub,
+					ExternalState:       tc.externalState,
+					ExternalCheckState:  tc.checkState,
+					CurrentSpec:         changesetSpec.ID,
+				})
+				job := &types.ChangesetJob{
+					JobType:     types.ChangesetJobTypeMergeWhenChecksPass,
+					ChangesetID: changeset.ID,
+					UserID:      user.ID,
+					Payload:     &btypes.ChangesetJobMergeWhenChecksPassPayload{},
+					CreatedAt:   tc.createdAt,
+				}
+
+				err := bp.Process(ctx, job)
+				if _, requeue := RequeueAfter(err); requeue != tc.wantRequeue {
+					t.Errorf("unexpected requeue. want=%t, have=%t (err=%v)", tc.wantRequeue, requeue, err)
+				}
+				if tc.wantErr && !errcode.IsNonRetryable(err) {
+					t.Errorf("expected a non-retryable error, got %v", err)
+				}
+				if !tc.wantErr && !tc.wantRequeue && err != nil {
+					t.Fatal(err)
+				}
+				if fake.MergeChangesetCalled != tc.wantMerge {
+					t.Errorf("unexpected MergeChangeset call. want=%t, have=%t", tc.wantMerge, fake.MergeChangesetCalled)
+				}
+			})
+		}
+	})
+
 	t.Run("Publish job", func(t *testing.T) {
 		fake := &sources.FakeChangesetSource{FakeMetadata: &github.PullRequest{}}
 		bp := &bulkProcessor{
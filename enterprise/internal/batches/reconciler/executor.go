@@ -75,6 +75,9 @@ func (e *executor) Run(ctx context.Context, plan *Plan) (err error) {
 		case btypes.ReconcilerOperationPush:
 			err = e.pushChangesetPatch(ctx)
 
+		case btypes.ReconcilerOperationRebase:
+			err = e.rebaseChangeset(ctx, plan.RebaseOnto)
+
 		case btypes.ReconcilerOperationPublish:
 			err = e.publishChangeset(ctx, false)
 
@@ -143,18 +146,78 @@ func (e *executor) pushChangesetPatch(ctx context.Context) (err error) {
 	}
 
 	// Create a commit and push it
+	opts, err := e.commitOpts(ctx)
+	if err != nil {
+		return err
+	}
+	if err := e.pushCommit(ctx, opts); err != nil {
+		return err
+	}
+
+	// The new commit is based on the base revision of the spec again.
+	e.ch.RebaseBaseRev = ""
+	e.ch.RebaseConflict = nil
+	return nil
+}
+
+// rebaseChangeset recreates the commit of the changeset on top of the given
+// commit of its base branch and pushes it, overwriting the changeset branch. If
+// the diff of the changeset doesn't apply anymore, the conflict is reported on
+// the changeset instead of failing the reconciliation. Either way, the changeset
+// isn't rebased again until its base branch moves again.
+func (e *executor) rebaseChangeset(ctx context.Context, baseRev string) (err error) {
+	opts, err := e.commitOpts(ctx)
+	if err != nil {
+		return err
+	}
+	opts.BaseCommit = api.CommitID(baseRev)
+
+	e.ch.RebaseBaseRev = baseRev
+	e.ch.RebaseConflict = nil
+
+	_, err = e.gitserverClient.CreateCommitFromPatch(ctx, opts)
+	var patchErr *protocol.CreateCommitFromPatchError
+	if errors.As(err, &patchErr) && strings.HasPrefix(patchErr.Command, "git apply") {
+		conflict := fmt.Sprintf(
+			"The changes don't apply on commit %s of %s anymore:\n"+
+				"```\n"+
+				"%s\n"+
+				"```",
+			baseRev, e.spec.Spec.BaseRef, strings.TrimSpace(patchErr.CombinedOutput))
+		e.ch.RebaseConflict = &conflict
+		return nil
+	}
+	return createCommitFromPatchError(err)
+}
+
+// commitOpts returns the request to create the commit of the changeset from
+// its spec and push it to the code host.
+func (e *executor) commitOpts(ctx context.Context) (protocol.CreateCommitFromPatchRequest, error) {
 	// Figure out which authenticator we should use to modify the changeset.
 	// au is nil if we want to use the global credentials stored in the external
 	// service configuration.
 	pushConf, err := e.css.GitserverPushConfig(ctx, e.tx.ExternalServices(), e.repo)
 	if err != nil {
-		return err
+		return protocol.CreateCommitFromPatchRequest{}, err
 	}
 	opts, err := buildCommitOpts(e.repo, e.spec, pushConf)
 	if err != nil {
-		return err
+		return opts, err
+	}
+
+	// Some code hosts need to adjust the commit or where it's pushed to.
+	if pcss, ok := e.css.(sources.PushPreparingChangesetSource); ok {
+		cs := &sources.Changeset{
+			BaseRef:   e.spec.Spec.BaseRef,
+			HeadRef:   e.spec.Spec.HeadRef,
+			Repo:      e.repo,
+			Changeset: e.ch,
+		}
+		if err := pcss.PreparePush(ctx, cs, &opts); err != nil {
+			return opts, errors.Wrap(err, "preparing push")
+		}
 	}
-	return e.pushCommit(ctx, opts)
+	return opts, nil
 }
 
 // publishChangeset creates the given changeset on its code host.
@@ -390,21 +453,27 @@ func loadChangesetSource(ctx context.Context, s *store.Store, sourcer sources.So
 
 func (e *executor) pushCommit(ctx context.Context, opts protocol.CreateCommitFromPatchRequest) error {
 	_, err := e.gitserverClient.CreateCommitFromPatch(ctx, opts)
-	if err != nil {
-		var e *protocol.CreateCommitFromPatchError
-		if errors.As(err, &e) {
-			return errors.Errorf(
-				"creating commit from patch for repository %q: %s\n"+
-					"```\n"+
-					"$ %s\n"+
-					"%s\n"+
-					"```",
-				e.RepositoryName, e.InternalError, e.Command, strings.TrimSpace(e.CombinedOutput))
-		}
-		return err
+	return createCommitFromPatchError(err)
+}
+
+// createCommitFromPatchError adds the details of the failed git command to
+// errors returned by gitserver when creating a commit from a patch.
+func createCommitFromPatchError(err error) error {
+	if err == nil {
+		return nil
 	}
 
-	return nil
+	var e *protocol.CreateCommitFromPatchError
+	if errors.As(err, &e) {
+		return errors.Errorf(
+			"creating commit from patch for repository %q: %s\n"+
+				"```\n"+
+				"$ %s\n"+
+				"%s\n"+
+				"```",
+			e.RepositoryName, e.InternalError, e.Command, strings.TrimSpace(e.CombinedOutput))
+	}
+	return err
 }
 
 func buildCommitOpts(repo *types.Repo, spec *btypes.ChangesetSpec, pushOpts *protocol.PushConfig) (opts protocol.CreateCommitFromPatchRequest, err error) {
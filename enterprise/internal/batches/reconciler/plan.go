@@ -12,6 +12,7 @@ import (
 
 var operationPrecedence = map[btypes.ReconcilerOperation]int{
 	btypes.ReconcilerOperationPush:         0,
+	btypes.ReconcilerOperationRebase:       0,
 	btypes.ReconcilerOperationDetach:       0,
 	btypes.ReconcilerOperationArchive:      0,
 	btypes.ReconcilerOperationImport:       1,
@@ -97,6 +98,10 @@ type Plan struct {
 	// The Delta between a possible previous ChangesetSpec and the current
 	// ChangesetSpec.
 	Delta *ChangesetSpecDelta
+
+	// RebaseOnto is the commit of the base branch the changeset is rebased
+	// onto, if the plan includes a rebase.
+	RebaseOnto string
 }
 
 func (p *Plan) AddOp(op btypes.ReconcilerOperation) { p.Ops = append(p.Ops, op) }
@@ -0,0 +1,93 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// Rebaseable returns whether the given changeset can be rebased automatically:
+// it's a published and open changeset created from the given changeset spec.
+// Whether its batch change rebases changesets automatically is up to the caller
+// to check.
+func Rebaseable(ch *btypes.Changeset, spec *btypes.ChangesetSpec) bool {
+	if spec == nil || spec.Spec.IsImportingExisting() || ch.OwnedByBatchChangeID == 0 {
+		return false
+	}
+	if !ch.Published() || ch.Closing {
+		return false
+	}
+	return ch.ExternalState == btypes.ChangesetExternalStateOpen || ch.ExternalState == btypes.ChangesetExternalStateDraft
+}
+
+// RebaseTarget returns the current commit of the base branch of the given
+// changeset, if the base branch moved since the commit of the changeset was
+// last created, either by pushing its changeset spec or by rebasing it.
+func RebaseTarget(ctx context.Context, repo api.RepoName, ch *btypes.Changeset, spec *btypes.ChangesetSpec) (rev string, behind bool, err error) {
+	tip, err := git.ResolveRevision(ctx, repo, spec.Spec.BaseRef, git.ResolveRevisionOptions{})
+	if err != nil {
+		return "", false, errors.Wrapf(err, "resolving base branch %q", spec.Spec.BaseRef)
+	}
+
+	base := ch.RebaseBaseRev
+	if base == "" {
+		base = spec.Spec.BaseRev
+	}
+	if string(tip) == base {
+		return "", false, nil
+	}
+	return string(tip), true, nil
+}
+
+// planRebase adds a rebase of the changeset to the plan if its batch change
+// rebases changesets automatically and its base branch moved. Changesets that
+// are getting a new commit pushed anyway, or are being closed, detached or
+// archived aren't rebased.
+func planRebase(ctx context.Context, tx *store.Store, plan *Plan) error {
+	ch := plan.Changeset
+	if !Rebaseable(ch, plan.ChangesetSpec) {
+		return nil
+	}
+	for _, op := range plan.Ops {
+		switch op {
+		case btypes.ReconcilerOperationPush,
+			btypes.ReconcilerOperationPublish,
+			btypes.ReconcilerOperationPublishDraft,
+			btypes.ReconcilerOperationClose,
+			btypes.ReconcilerOperationDetach,
+			btypes.ReconcilerOperationArchive:
+			return nil
+		}
+	}
+
+	batchChange, err := loadBatchChange(ctx, tx, ch.OwnedByBatchChangeID)
+	if err != nil {
+		return err
+	}
+	if !batchChange.AutoRebase {
+		return nil
+	}
+
+	repo, err := tx.Repos().Get(ctx, ch.RepoID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load repository")
+	}
+
+	rev, behind, err := RebaseTarget(ctx, repo.Name, ch, plan.ChangesetSpec)
+	if err != nil || !behind {
+		return err
+	}
+
+	plan.RebaseOnto = rev
+	plan.AddOp(btypes.ReconcilerOperationRebase)
+	// Like after pushing a new commit, give the code host some time to update
+	// the changeset before syncing it.
+	plan.AddOp(btypes.ReconcilerOperationSleep)
+	plan.AddOp(btypes.ReconcilerOperationSync)
+	return nil
+}
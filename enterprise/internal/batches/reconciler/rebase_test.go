@@ -0,0 +1,91 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/lib/batches"
+)
+
+func TestRebaseable(t *testing.T) {
+	branchSpec := &btypes.ChangesetSpec{Spec: &batches.ChangesetSpec{BaseRef: "refs/heads/main", HeadRef: "refs/heads/my-branch"}}
+	importSpec := &btypes.ChangesetSpec{Spec: &batches.ChangesetSpec{ExternalID: "123"}}
+
+	openChangeset := func() *btypes.Changeset {
+		return &btypes.Changeset{
+			OwnedByBatchChangeID: 1,
+			PublicationState:     btypes.ChangesetPublicationStatePublished,
+			ExternalState:        btypes.ChangesetExternalStateOpen,
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		changeset func(*btypes.Changeset)
+		spec      *btypes.ChangesetSpec
+		want      bool
+	}{
+		"open":     {spec: branchSpec, want: true},
+		"draft":    {changeset: func(c *btypes.Changeset) { c.ExternalState = btypes.ChangesetExternalStateDraft }, spec: branchSpec, want: true},
+		"merged":   {changeset: func(c *btypes.Changeset) { c.ExternalState = btypes.ChangesetExternalStateMerged }, spec: branchSpec},
+		"closing":  {changeset: func(c *btypes.Changeset) { c.Closing = true }, spec: branchSpec},
+		"imported": {changeset: func(c *btypes.Changeset) { c.OwnedByBatchChangeID = 0 }, spec: importSpec},
+		"no spec":  {},
+		"unpublished": {
+			changeset: func(c *btypes.Changeset) { c.PublicationState = btypes.ChangesetPublicationStateUnpublished },
+			spec:      branchSpec,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ch := openChangeset()
+			if tc.changeset != nil {
+				tc.changeset(ch)
+			}
+			if have := Rebaseable(ch, tc.spec); have != tc.want {
+				t.Fatalf("wrong Rebaseable. want=%t, have=%t", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestRebaseTarget(t *testing.T) {
+	git.Mocks.ResolveRevision = func(spec string, opt git.ResolveRevisionOptions) (api.CommitID, error) {
+		if spec != "refs/heads/main" {
+			t.Fatalf("resolved unexpected revision %q", spec)
+		}
+		return "tip", nil
+	}
+	t.Cleanup(git.ResetMocks)
+
+	spec := &btypes.ChangesetSpec{Spec: &batches.ChangesetSpec{BaseRef: "refs/heads/main", BaseRev: "base"}}
+
+	for name, tc := range map[string]struct {
+		rebaseBaseRev string
+		baseRev       string
+		wantBehind    bool
+	}{
+		"base branch moved since push":           {baseRev: "base", wantBehind: true},
+		"base branch didn't move since push":     {baseRev: "tip"},
+		"base branch moved since last rebase":    {rebaseBaseRev: "previous", wantBehind: true},
+		"base branch didn't move since rebase":   {rebaseBaseRev: "tip"},
+		"rebase base takes precedence over spec": {rebaseBaseRev: "tip", baseRev: "base"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec.Spec.BaseRev = tc.baseRev
+			ch := &btypes.Changeset{RebaseBaseRev: tc.rebaseBaseRev}
+
+			rev, behind, err := RebaseTarget(context.Background(), "github.com/sourcegraph/sourcegraph", ch, spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if behind != tc.wantBehind {
+				t.Fatalf("wrong behind. want=%t, have=%t", tc.wantBehind, behind)
+			}
+			if behind && rev != "tip" {
+				t.Fatalf("wrong rev. want=%q, have=%q", "tip", rev)
+			}
+		})
+	}
+}
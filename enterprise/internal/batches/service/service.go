@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -66,6 +67,14 @@ type operations struct {
 	getNewestBatchSpec                   *observation.Operation
 	moveBatchChange                      *observation.Operation
 	closeBatchChange                     *observation.Operation
+	rollbackBatchChange                  *observation.Operation
+	setBatchChangeAutoRebase             *observation.Operation
+	scheduleBatchSpecExecution           *observation.Operation
+	deleteBatchSpecExecutionSchedule     *observation.Operation
+	runBatchSpecExecutionSchedule        *observation.Operation
+	executePendingScheduledBatchSpec     *observation.Operation
+	invalidateBatchSpecExecutionCache    *observation.Operation
+	invalidateUserExecutionCache         *observation.Operation
 	deleteBatchChange                    *observation.Operation
 	enqueueChangesetSync                 *observation.Operation
 	reenqueueChangeset                   *observation.Operation
@@ -76,6 +85,8 @@ type operations struct {
 	applyBatchChange                     *observation.Operation
 	reconcileBatchChange                 *observation.Operation
 	validateChangesetSpecs               *observation.Operation
+	exportBatchSpec                      *observation.Operation
+	importBatchSpec                      *observation.Operation
 }
 
 var (
@@ -113,6 +124,14 @@ func newOperations(observationContext *observation.Context) *operations {
 			getNewestBatchSpec:                   op("GetNewestBatchSpec"),
 			moveBatchChange:                      op("MoveBatchChange"),
 			closeBatchChange:                     op("CloseBatchChange"),
+			rollbackBatchChange:                  op("RollbackBatchChange"),
+			setBatchChangeAutoRebase:             op("SetBatchChangeAutoRebase"),
+			scheduleBatchSpecExecution:           op("ScheduleBatchSpecExecution"),
+			deleteBatchSpecExecutionSchedule:     op("DeleteBatchSpecExecutionSchedule"),
+			runBatchSpecExecutionSchedule:        op("RunBatchSpecExecutionSchedule"),
+			executePendingScheduledBatchSpec:     op("ExecutePendingScheduledBatchSpec"),
+			invalidateBatchSpecExecutionCache:    op("InvalidateBatchSpecExecutionCache"),
+			invalidateUserExecutionCache:         op("InvalidateUserExecutionCache"),
 			deleteBatchChange:                    op("DeleteBatchChange"),
 			enqueueChangesetSync:                 op("EnqueueChangesetSync"),
 			reenqueueChangeset:                   op("ReenqueueChangeset"),
@@ -123,6 +142,8 @@ func newOperations(observationContext *observation.Context) *operations {
 			applyBatchChange:                     op("ApplyBatchChange"),
 			reconcileBatchChange:                 op("ReconcileBatchChange"),
 			validateChangesetSpecs:               op("ValidateChangesetSpecs"),
+			exportBatchSpec:                      op("ExportBatchSpec"),
+			importBatchSpec:                      op("ImportBatchSpec"),
 		}
 	})
 
@@ -166,37 +187,36 @@ func (s *Service) CreateBatchSpec(ctx context.Context, opts CreateBatchSpecOpts)
 	actor := actor.FromContext(ctx)
 	spec.UserID = actor.UID
 
-	if len(opts.ChangesetSpecRandIDs) == 0 {
-		return spec, s.store.CreateBatchSpec(ctx, spec)
-	}
-
-	listOpts := store.ListChangesetSpecsOpts{RandIDs: opts.ChangesetSpecRandIDs}
-	cs, _, err := s.store.ListChangesetSpecs(ctx, listOpts)
-	if err != nil {
-		return nil, err
-	}
+	var cs btypes.ChangesetSpecs
+	if len(opts.ChangesetSpecRandIDs) != 0 {
+		listOpts := store.ListChangesetSpecsOpts{RandIDs: opts.ChangesetSpecRandIDs}
+		cs, _, err = s.store.ListChangesetSpecs(ctx, listOpts)
+		if err != nil {
+			return nil, err
+		}
 
-	// 🚨 SECURITY: database.Repos.GetRepoIDsSet uses the authzFilter under the hood and
-	// filters out repositories that the user doesn't have access to.
-	accessibleReposByID, err := s.store.Repos().GetReposSetByIDs(ctx, cs.RepoIDs()...)
-	if err != nil {
-		return nil, err
-	}
+		// 🚨 SECURITY: database.Repos.GetRepoIDsSet uses the authzFilter under the hood and
+		// filters out repositories that the user doesn't have access to.
+		accessibleReposByID, err := s.store.Repos().GetReposSetByIDs(ctx, cs.RepoIDs()...)
+		if err != nil {
+			return nil, err
+		}
 
-	byRandID := make(map[string]*btypes.ChangesetSpec, len(cs))
-	for _, changesetSpec := range cs {
-		// 🚨 SECURITY: We return an error if the user doesn't have access to one
-		// of the repositories associated with a ChangesetSpec.
-		if _, ok := accessibleReposByID[changesetSpec.RepoID]; !ok {
-			return nil, &database.RepoNotFoundErr{ID: changesetSpec.RepoID}
+		byRandID := make(map[string]*btypes.ChangesetSpec, len(cs))
+		for _, changesetSpec := range cs {
+			// 🚨 SECURITY: We return an error if the user doesn't have access to one
+			// of the repositories associated with a ChangesetSpec.
+			if _, ok := accessibleReposByID[changesetSpec.RepoID]; !ok {
+				return nil, &database.RepoNotFoundErr{ID: changesetSpec.RepoID}
+			}
+			byRandID[changesetSpec.RandID] = changesetSpec
 		}
-		byRandID[changesetSpec.RandID] = changesetSpec
-	}
 
-	// Check if a changesetSpec was not found
-	for _, randID := range opts.ChangesetSpecRandIDs {
-		if _, ok := byRandID[randID]; !ok {
-			return nil, &changesetSpecNotFoundErr{RandID: randID}
+		// Check if a changesetSpec was not found
+		for _, randID := range opts.ChangesetSpecRandIDs {
+			if _, ok := byRandID[randID]; !ok {
+				return nil, &changesetSpecNotFoundErr{RandID: randID}
+			}
 		}
 	}
 
@@ -218,7 +238,7 @@ func (s *Service) CreateBatchSpec(ctx context.Context, opts CreateBatchSpecOpts)
 		}
 	}
 
-	return spec, nil
+	return spec, recordAuditEvents(ctx, tx, btypes.AuditEventActionCreated, btypes.AuditEventResourceTypeBatchSpec, nil, spec.ID)
 }
 
 type CreateBatchSpecFromRawOpts struct {
@@ -229,6 +249,13 @@ type CreateBatchSpecFromRawOpts struct {
 
 	AllowIgnored     bool
 	AllowUnsupported bool
+
+	// Labels are attached to the resolution job created for the batch spec.
+	Labels map[string]string
+
+	// Priority is the priority of the resolution job created for the batch
+	// spec.
+	Priority btypes.BatchSpecResolutionJobPriority
 }
 
 // CreateBatchSpecFromRaw creates the BatchSpec.
@@ -264,6 +291,8 @@ func (s *Service) CreateBatchSpecFromRaw(ctx context.Context, opts CreateBatchSp
 		spec:             spec,
 		allowIgnored:     opts.AllowIgnored,
 		allowUnsupported: opts.AllowUnsupported,
+		labels:           opts.Labels,
+		priority:         opts.Priority,
 	})
 }
 
@@ -271,6 +300,8 @@ type createBatchSpecForExecutionOpts struct {
 	spec             *btypes.BatchSpec
 	allowIgnored     bool
 	allowUnsupported bool
+	labels           map[string]string
+	priority         btypes.BatchSpecResolutionJobPriority
 }
 
 // createBatchSpecForExecution persists the given BatchSpec in the given
@@ -335,12 +366,22 @@ func (s *Service) createBatchSpecForExecution(ctx context.Context, tx *store.Sto
 	}
 
 	// Return spec and enqueue resolution
-	return tx.CreateBatchSpecResolutionJob(ctx, &btypes.BatchSpecResolutionJob{
+	job := &btypes.BatchSpecResolutionJob{
 		State:            btypes.BatchSpecResolutionJobStateQueued,
 		BatchSpecID:      opts.spec.ID,
 		AllowIgnored:     opts.allowIgnored,
 		AllowUnsupported: opts.allowUnsupported,
-	})
+		Labels:           opts.labels,
+		Priority:         opts.priority,
+	}
+	if err := tx.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+		return err
+	}
+
+	if err := recordAuditEvents(ctx, tx, btypes.AuditEventActionCreated, btypes.AuditEventResourceTypeBatchSpec, nil, opts.spec.ID); err != nil {
+		return err
+	}
+	return recordAuditEvents(ctx, tx, btypes.AuditEventActionCreated, btypes.AuditEventResourceTypeBatchSpecResolutionJob, batchSpecAuditMetadata(opts.spec.ID), job.ID)
 }
 
 type EnqueueBatchSpecResolutionOpts struct {
@@ -348,6 +389,15 @@ type EnqueueBatchSpecResolutionOpts struct {
 
 	AllowIgnored     bool
 	AllowUnsupported bool
+
+	Labels map[string]string
+
+	// DryRun, if true, only records which workspaces the batch spec would
+	// target, without creating workspaces that can be executed.
+	DryRun bool
+
+	// Priority is the priority of the created resolution job.
+	Priority btypes.BatchSpecResolutionJobPriority
 }
 
 // EnqueueBatchSpecResolution creates a pending BatchSpec that will be picked up by a worker in the background.
@@ -360,6 +410,9 @@ func (s *Service) EnqueueBatchSpecResolution(ctx context.Context, opts EnqueueBa
 		BatchSpecID:      opts.BatchSpecID,
 		AllowIgnored:     opts.AllowIgnored,
 		AllowUnsupported: opts.AllowUnsupported,
+		Labels:           opts.Labels,
+		DryRun:           opts.DryRun,
+		Priority:         opts.Priority,
 	})
 }
 
@@ -378,6 +431,9 @@ var ErrBatchSpecResolutionIncomplete = errors.New("cannot execute batch spec, wo
 
 type ExecuteBatchSpecOpts struct {
 	BatchSpecRandID string
+	// NoCache, if set, executes all steps of the batch spec instead of
+	// resuming from cached step results.
+	NoCache bool
 }
 
 // ExecuteBatchSpec creates BatchSpecWorkspaceExecutionJobs for every created
@@ -421,6 +477,12 @@ func (s *Service) ExecuteBatchSpec(ctx context.Context, opts ExecuteBatchSpecOpt
 		return nil, ErrBatchSpecResolutionErrored{resolutionJob.FailureMessage}
 
 	case btypes.BatchSpecResolutionJobStateCompleted:
+		if batchSpec.NoCache != opts.NoCache {
+			batchSpec.NoCache = opts.NoCache
+			if err := tx.UpdateBatchSpec(ctx, batchSpec); err != nil {
+				return nil, err
+			}
+		}
 		return batchSpec, tx.CreateBatchSpecWorkspaceExecutionJobs(ctx, batchSpec.ID)
 
 	default:
@@ -433,6 +495,10 @@ type ReplaceBatchSpecInputOpts struct {
 	RawSpec          string
 	AllowIgnored     bool
 	AllowUnsupported bool
+
+	// Priority is the priority of the resolution job created for the new
+	// batch spec.
+	Priority btypes.BatchSpecResolutionJobPriority
 }
 
 // ReplaceBatchSpecInput creates BatchSpecWorkspaceExecutionJobs for every created
@@ -469,13 +535,27 @@ func (s *Service) ReplaceBatchSpecInput(ctx context.Context, opts ReplaceBatchSp
 	}
 	defer func() { err = tx.Done(err) }()
 
-	// Delete the previous batch spec, which should delete
-	// - batch_spec_resolution_jobs
-	// - batch_spec_workspaces
-	// associated with it
+	// The foreign key would cascade the deletion of the batch spec below to
+	// its resolution jobs, but we delete them explicitly first to learn the
+	// IDs of exactly the jobs that are deleted, for the audit events.
+	jobIDs, err := tx.DeleteBatchSpecResolutionJobs(ctx, store.DeleteBatchSpecResolutionJobsOpts{
+		BatchSpecIDs: []int64{batchSpec.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAuditEvents(ctx, tx, btypes.AuditEventActionDeleted, btypes.AuditEventResourceTypeBatchSpecResolutionJob, batchSpecAuditMetadata(batchSpec.ID), jobIDs...); err != nil {
+		return nil, err
+	}
+
+	// Delete the previous batch spec, which should delete the
+	// batch_spec_workspaces associated with it.
 	if err := tx.DeleteBatchSpec(ctx, batchSpec.ID); err != nil {
 		return nil, err
 	}
+	if err := recordAuditEvents(ctx, tx, btypes.AuditEventActionDeleted, btypes.AuditEventResourceTypeBatchSpec, nil, batchSpec.ID); err != nil {
+		return nil, err
+	}
 
 	// We keep the RandID so the user-visible GraphQL ID is stable
 	newSpec.RandID = batchSpec.RandID
@@ -488,6 +568,7 @@ func (s *Service) ReplaceBatchSpecInput(ctx context.Context, opts ReplaceBatchSp
 		spec:             newSpec,
 		allowIgnored:     opts.AllowIgnored,
 		allowUnsupported: opts.AllowUnsupported,
+		priority:         opts.Priority,
 	})
 }
 
@@ -671,6 +752,11 @@ func (s *Service) CloseBatchChange(ctx context.Context, id int64, closeChangeset
 		return nil, err
 	}
 
+	metadata := map[string]string{"close_changesets": strconv.FormatBool(closeChangesets)}
+	if err := recordAuditEvents(ctx, tx, btypes.AuditEventActionClosed, btypes.AuditEventResourceTypeBatchChange, metadata, batchChange.ID); err != nil {
+		return nil, err
+	}
+
 	if !closeChangesets {
 		return batchChange, nil
 	}
@@ -687,6 +773,71 @@ func (s *Service) CloseBatchChange(ctx context.Context, id int64, closeChangeset
 	return batchChange, nil
 }
 
+// SetBatchChangeAutoRebase sets whether the changesets of the batch change with
+// the given ID are rebased automatically when their base branch moves. The
+// changesets are rebased the next time their base branches are checked.
+func (s *Service) SetBatchChangeAutoRebase(ctx context.Context, id int64, autoRebase bool) (batchChange *btypes.BatchChange, err error) {
+	ctx, endObservation := s.operations.setBatchChangeAutoRebase.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	batchChange, err = s.store.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: id})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting batch change")
+	}
+
+	if err := backend.CheckSiteAdminOrSameUser(ctx, s.store.DB(), batchChange.InitialApplierID); err != nil {
+		return nil, err
+	}
+
+	if batchChange.AutoRebase == autoRebase {
+		return batchChange, nil
+	}
+
+	batchChange.AutoRebase = autoRebase
+	if err := s.store.UpdateBatchChange(ctx, batchChange); err != nil {
+		return nil, err
+	}
+
+	return batchChange, nil
+}
+
+// RollbackBatchChange enqueues a job that rolls back the BatchChange with the
+// given ID: changesets that haven't been published yet won't be published,
+// published changesets are closed and the batch change itself is closed.
+//
+// If the batch change is already being rolled back, the active job is
+// returned.
+func (s *Service) RollbackBatchChange(ctx context.Context, id int64) (job *btypes.BatchChangeRollbackJob, err error) {
+	ctx, endObservation := s.operations.rollbackBatchChange.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	batchChange, err := s.store.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: id})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting batch change")
+	}
+
+	if err := backend.CheckSiteAdminOrSameUser(ctx, s.store.DB(), batchChange.InitialApplierID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	job = &btypes.BatchChangeRollbackJob{
+		BatchChangeID: batchChange.ID,
+		UserID:        actor.FromContext(ctx).UID,
+	}
+	if err := tx.CreateBatchChangeRollbackJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{"rollback_job_id": strconv.FormatInt(job.ID, 10)}
+	return job, recordAuditEvents(ctx, tx, btypes.AuditEventActionRolledBack, btypes.AuditEventResourceTypeBatchChange, metadata, batchChange.ID)
+}
+
 // DeleteBatchChange deletes the BatchChange with the given ID if it hasn't been
 // deleted yet.
 func (s *Service) DeleteBatchChange(ctx context.Context, id int64) (err error) {
@@ -702,7 +853,16 @@ func (s *Service) DeleteBatchChange(ctx context.Context, id int64) (err error) {
 		return err
 	}
 
-	return s.store.DeleteBatchChange(ctx, id)
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.DeleteBatchChange(ctx, id); err != nil {
+		return err
+	}
+	return recordAuditEvents(ctx, tx, btypes.AuditEventActionDeleted, btypes.AuditEventResourceTypeBatchChange, nil, id)
 }
 
 // EnqueueChangesetSync loads the given changeset from the database, checks
@@ -800,11 +960,17 @@ func (s *Service) ReenqueueChangeset(ctx context.Context, id int64) (changeset *
 		return nil, nil, authErr
 	}
 
-	if err := s.store.EnqueueChangeset(ctx, changeset, global.DefaultReconcilerEnqueueState(), btypes.ReconcilerStateFailed); err != nil {
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.EnqueueChangeset(ctx, changeset, global.DefaultReconcilerEnqueueState(), btypes.ReconcilerStateFailed); err != nil {
 		return nil, nil, err
 	}
 
-	return changeset, repo, nil
+	return changeset, repo, recordAuditEvents(ctx, tx, btypes.AuditEventActionRetried, btypes.AuditEventResourceTypeChangeset, nil, changeset.ID)
 }
 
 // CheckNamespaceAccess checks whether the current user in the ctx has access
@@ -1052,3 +1218,31 @@ func formatChangesetSpecHeadRefConflicts(es []error) string {
 		"%d errors when validating changeset specs:\n%s\n",
 		len(es), strings.Join(points, "\n"))
 }
+
+// recordAuditEvents records in the audit log that the actor in ctx performed
+// the given action on the resources with the given IDs.
+func recordAuditEvents(ctx context.Context, tx *store.Store, action btypes.AuditEventAction, resourceType btypes.AuditEventResourceType, metadata map[string]string, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	actorUserID := actor.FromContext(ctx).UID
+	events := make([]*btypes.AuditEvent, 0, len(ids))
+	for _, id := range ids {
+		events = append(events, &btypes.AuditEvent{
+			ActorUserID:  actorUserID,
+			Action:       action,
+			ResourceType: resourceType,
+			ResourceID:   id,
+			Metadata:     metadata,
+		})
+	}
+	return tx.CreateAuditEvents(ctx, events...)
+}
+
+// batchSpecAuditMetadata returns the audit event metadata of resources that
+// belong to the given batch spec, so that they can be found once the batch
+// spec is gone.
+func batchSpecAuditMetadata(batchSpecID int64) map[string]string {
+	return map[string]string{"batch_spec_id": strconv.FormatInt(batchSpecID, 10)}
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+)
+
+// BatchSpecArchiveVersion is the version of the batch spec archives created by
+// ExportBatchSpec. It must be incremented on incompatible changes to
+// batchSpecArchive, since archives are exchanged between instances that may
+// run different versions of Sourcegraph.
+const BatchSpecArchiveVersion = 2
+
+// ErrBatchSpecArchivesDisabled is returned by ExportBatchSpec and
+// ImportBatchSpec when no archive signing key is configured.
+var ErrBatchSpecArchivesDisabled = errors.New("batch spec archives are disabled: batchChanges.archiveSigningKey is not set in the site configuration")
+
+// ErrInvalidBatchSpecArchiveSignature is returned by ImportBatchSpec when the
+// archive wasn't signed with the configured key, or was modified after it was
+// signed.
+var ErrInvalidBatchSpecArchiveSignature = errors.New("invalid batch spec archive signature")
+
+// batchSpecArchive is the payload of a batch spec archive. Repositories are
+// referenced by name instead of by ID, since IDs differ between instances.
+type batchSpecArchive struct {
+	RawSpec        string                          `json:"rawSpec"`
+	Workspaces     []batchSpecArchiveWorkspace     `json:"workspaces"`
+	ChangesetSpecs []batchSpecArchiveChangesetSpec `json:"changesetSpecs"`
+}
+
+type batchSpecArchiveWorkspace struct {
+	Repository         api.RepoName      `json:"repository"`
+	Branch             string            `json:"branch"`
+	Commit             string            `json:"commit"`
+	Path               string            `json:"path"`
+	Steps              []batcheslib.Step `json:"steps"`
+	FileMatches        []string          `json:"fileMatches"`
+	OnlyFetchWorkspace bool              `json:"onlyFetchWorkspace"`
+	// ChangesetSpecs are the indexes of the changeset specs created by the
+	// workspace in batchSpecArchive.ChangesetSpecs.
+	ChangesetSpecs []int `json:"changesetSpecs"`
+}
+
+type batchSpecArchiveChangesetSpec struct {
+	Repository api.RepoName `json:"repository"`
+	// Spec is the changeset spec without its base repository, which is
+	// replaced by the repository on the importing instance.
+	Spec *batcheslib.ChangesetSpec `json:"spec"`
+}
+
+// ExportBatchSpec creates an archive of the given batch spec, its resolved
+// workspaces and its changeset specs, signed with the configured archive
+// signing key. The archive can be imported on another instance with
+// ImportBatchSpec.
+func (s *Service) ExportBatchSpec(ctx context.Context, batchSpecID int64) (archive string, err error) {
+	ctx, endObservation := s.operations.exportBatchSpec.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchSpecID", int(batchSpecID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	key := conf.Get().BatchChangesArchiveSigningKey
+	if key == "" {
+		return "", ErrBatchSpecArchivesDisabled
+	}
+
+	spec, err := s.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: batchSpecID})
+	if err != nil {
+		return "", errors.Wrap(err, "getting batch spec")
+	}
+
+	workspaces, _, err := s.store.ListBatchSpecWorkspaces(ctx, store.ListBatchSpecWorkspacesOpts{BatchSpecID: spec.ID})
+	if err != nil {
+		return "", errors.Wrap(err, "listing workspaces")
+	}
+
+	changesetSpecs, _, err := s.store.ListChangesetSpecs(ctx, store.ListChangesetSpecsOpts{BatchSpecID: spec.ID})
+	if err != nil {
+		return "", errors.Wrap(err, "listing changeset specs")
+	}
+
+	repoIDs := make([]api.RepoID, 0, len(workspaces)+len(changesetSpecs))
+	for _, w := range workspaces {
+		repoIDs = append(repoIDs, w.RepoID)
+	}
+	repoIDs = append(repoIDs, changesetSpecs.RepoIDs()...)
+
+	// 🚨 SECURITY: database.Repos.GetReposSetByIDs uses the authzFilter under
+	// the hood and filters out repositories that the user doesn't have access
+	// to. We refuse to export batch specs that touch any of them.
+	repos, err := s.store.Repos().GetReposSetByIDs(ctx, repoIDs...)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range repoIDs {
+		if _, ok := repos[id]; !ok {
+			return "", &database.RepoNotFoundErr{ID: id}
+		}
+	}
+
+	a := batchSpecArchive{
+		RawSpec:        spec.RawSpec,
+		Workspaces:     make([]batchSpecArchiveWorkspace, 0, len(workspaces)),
+		ChangesetSpecs: make([]batchSpecArchiveChangesetSpec, 0, len(changesetSpecs)),
+	}
+
+	changesetSpecIndexes := make(map[int64]int, len(changesetSpecs))
+	for i, cs := range changesetSpecs {
+		changesetSpecIndexes[cs.ID] = i
+
+		desc := *cs.Spec
+		desc.BaseRepository = ""
+		a.ChangesetSpecs = append(a.ChangesetSpecs, batchSpecArchiveChangesetSpec{
+			Repository: repos[cs.RepoID].Name,
+			Spec:       &desc,
+		})
+	}
+
+	for _, w := range workspaces {
+		aw := batchSpecArchiveWorkspace{
+			Repository:         repos[w.RepoID].Name,
+			Branch:             w.Branch,
+			Commit:             w.Commit,
+			Path:               w.Path,
+			Steps:              w.Steps,
+			FileMatches:        w.FileMatches,
+			OnlyFetchWorkspace: w.OnlyFetchWorkspace,
+			ChangesetSpecs:     make([]int, 0, len(w.ChangesetSpecIDs)),
+		}
+		for _, id := range w.ChangesetSpecIDs {
+			// Changeset specs can expire, so the workspace may reference
+			// some that no longer exist.
+			if i, ok := changesetSpecIndexes[id]; ok {
+				aw.ChangesetSpecs = append(aw.ChangesetSpecs, i)
+			}
+		}
+		a.Workspaces = append(a.Workspaces, aw)
+	}
+
+	return encodeBatchSpecArchive(key, &a)
+}
+
+type ImportBatchSpecOpts struct {
+	Archive string
+
+	NamespaceUserID int32
+	NamespaceOrgID  int32
+}
+
+// ImportBatchSpec verifies the signature of the given archive created by
+// ExportBatchSpec and recreates the batch spec, its workspaces and its
+// changeset specs in the given namespace. Repositories are matched by name,
+// and all of them must exist on this instance.
+func (s *Service) ImportBatchSpec(ctx context.Context, opts ImportBatchSpecOpts) (spec *btypes.BatchSpec, err error) {
+	ctx, endObservation := s.operations.importBatchSpec.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	key := conf.Get().BatchChangesArchiveSigningKey
+	if key == "" {
+		return nil, ErrBatchSpecArchivesDisabled
+	}
+
+	a, err := decodeBatchSpecArchive(key, opts.Archive)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err = btypes.NewBatchSpecFromRaw(a.RawSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check whether the current user has access to either one of the namespaces.
+	if err := s.CheckNamespaceAccess(ctx, opts.NamespaceUserID, opts.NamespaceOrgID); err != nil {
+		return nil, err
+	}
+	spec.NamespaceOrgID = opts.NamespaceOrgID
+	spec.NamespaceUserID = opts.NamespaceUserID
+	spec.UserID = actor.FromContext(ctx).UID
+
+	names := make([]string, 0, len(a.Workspaces)+len(a.ChangesetSpecs))
+	for _, w := range a.Workspaces {
+		names = append(names, string(w.Repository))
+	}
+	for _, cs := range a.ChangesetSpecs {
+		names = append(names, string(cs.Repository))
+	}
+
+	// 🚨 SECURITY: database.Repos.ListRepoNames uses the authzFilter under the
+	// hood and filters out repositories that the user doesn't have access to.
+	repos, err := s.store.Repos().ListRepoNames(ctx, database.ReposListOptions{Names: names})
+	if err != nil {
+		return nil, err
+	}
+	repoIDs := make(map[api.RepoName]api.RepoID, len(repos))
+	for _, r := range repos {
+		repoIDs[r.Name] = r.ID
+	}
+	for _, name := range names {
+		if _, ok := repoIDs[api.RepoName(name)]; !ok {
+			return nil, &database.RepoNotFoundErr{Name: api.RepoName(name)}
+		}
+	}
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.CreateBatchSpec(ctx, spec); err != nil {
+		return nil, err
+	}
+
+	changesetSpecIDs := make([]int64, 0, len(a.ChangesetSpecs))
+	for _, acs := range a.ChangesetSpecs {
+		if acs.Spec == nil {
+			return nil, errors.New("archive contains an empty changeset spec")
+		}
+
+		repoID := repoIDs[acs.Repository]
+		acs.Spec.BaseRepository = string(graphqlbackend.MarshalRepositoryID(repoID))
+		rawSpec, err := json.Marshal(acs.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		cs, err := btypes.NewChangesetSpecFromRaw(string(rawSpec))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid changeset spec in archive")
+		}
+		cs.BatchSpecID = spec.ID
+		cs.RepoID = repoID
+		cs.UserID = spec.UserID
+		if err := tx.CreateChangesetSpec(ctx, cs); err != nil {
+			return nil, err
+		}
+		changesetSpecIDs = append(changesetSpecIDs, cs.ID)
+	}
+
+	workspaces := make([]*btypes.BatchSpecWorkspace, 0, len(a.Workspaces))
+	for _, aw := range a.Workspaces {
+		w := &btypes.BatchSpecWorkspace{
+			BatchSpecID:        spec.ID,
+			ChangesetSpecIDs:   make([]int64, 0, len(aw.ChangesetSpecs)),
+			RepoID:             repoIDs[aw.Repository],
+			Branch:             aw.Branch,
+			Commit:             aw.Commit,
+			Path:               aw.Path,
+			Steps:              aw.Steps,
+			FileMatches:        aw.FileMatches,
+			OnlyFetchWorkspace: aw.OnlyFetchWorkspace,
+		}
+		for _, i := range aw.ChangesetSpecs {
+			if i < 0 || i >= len(changesetSpecIDs) {
+				return nil, errors.Errorf("archive references unknown changeset spec %d", i)
+			}
+			w.ChangesetSpecIDs = append(w.ChangesetSpecIDs, changesetSpecIDs[i])
+		}
+		workspaces = append(workspaces, w)
+	}
+	if len(workspaces) > 0 {
+		if err := tx.CreateBatchSpecWorkspace(ctx, workspaces...); err != nil {
+			return nil, err
+		}
+	}
+
+	return spec, recordAuditEvents(ctx, tx, btypes.AuditEventActionCreated, btypes.AuditEventResourceTypeBatchSpec, map[string]string{"imported": "true"}, spec.ID)
+}
+
+// maxBatchSpecArchivePayloadSize bounds the size of the decompressed payload
+// of an imported archive, so that a small archive can't expand to exhaust the
+// memory of the importing instance.
+const maxBatchSpecArchivePayloadSize = 64 << 20
+
+// encodeBatchSpecArchive encodes the given archive as gzipped JSON and signs
+// it with key. The result has the form "<version>.<signature>.<payload>",
+// where the signature is the hex encoded HMAC-SHA256 of the version and the
+// compressed payload, and the payload is base64 encoded.
+func encodeBatchSpecArchive(key string, a *batchSpecArchive) (string, error) {
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	compressed := buf.Bytes()
+
+	return fmt.Sprintf(
+		"%d.%s.%s",
+		BatchSpecArchiveVersion,
+		signBatchSpecArchive(key, BatchSpecArchiveVersion, compressed),
+		base64.StdEncoding.EncodeToString(compressed),
+	), nil
+}
+
+// decodeBatchSpecArchive verifies that an archive created by
+// encodeBatchSpecArchive was signed with key and decodes it. The signature is
+// verified before the payload is decompressed.
+func decodeBatchSpecArchive(key, archive string) (*batchSpecArchive, error) {
+	parts := strings.SplitN(archive, ".", 3)
+	if len(parts) != 3 {
+		return nil, errors.New("malformed batch spec archive")
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding batch spec archive version")
+	}
+	if version != BatchSpecArchiveVersion {
+		return nil, errors.Errorf("unsupported batch spec archive version %d, expected %d", version, BatchSpecArchiveVersion)
+	}
+	compressed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding batch spec archive")
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(signBatchSpecArchive(key, version, compressed))) {
+		return nil, ErrInvalidBatchSpecArchiveSignature
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing batch spec archive")
+	}
+	payload, err := io.ReadAll(io.LimitReader(zr, maxBatchSpecArchivePayloadSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing batch spec archive")
+	}
+	if len(payload) > maxBatchSpecArchivePayloadSize {
+		return nil, errors.Errorf("batch spec archive exceeds the maximum size of %d bytes", maxBatchSpecArchivePayloadSize)
+	}
+
+	var a batchSpecArchive
+	if err := json.Unmarshal(payload, &a); err != nil {
+		return nil, errors.Wrap(err, "decoding batch spec archive payload")
+	}
+	return &a, nil
+}
+
+func signBatchSpecArchive(key string, version int, compressed []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%d.", version)
+	mac.Write(compressed)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeBatchSpecArchive(t *testing.T) {
+	const key = "the-archive-signing-key-used-in-tests"
+
+	// signed returns an archive with the given compressed payload, signed
+	// with key.
+	signed := func(compressed []byte) string {
+		return fmt.Sprintf(
+			"%d.%s.%s",
+			BatchSpecArchiveVersion,
+			signBatchSpecArchive(key, BatchSpecArchiveVersion, compressed),
+			base64.StdEncoding.EncodeToString(compressed),
+		)
+	}
+	gzipped := func(t *testing.T, payload []byte) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("roundtrip", func(t *testing.T) {
+		want := &batchSpecArchive{RawSpec: "name: test"}
+		archive, err := encodeBatchSpecArchive(key, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, err := decodeBatchSpecArchive(key, archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("wrong archive decoded (-want +have):\n%s", diff)
+		}
+	})
+
+	t.Run("not decompressed before verification", func(t *testing.T) {
+		// The payload isn't valid gzip, so decompressing it would fail with a
+		// different error.
+		compressed := []byte("not gzipped")
+		archive := fmt.Sprintf(
+			"%d.%s.%s",
+			BatchSpecArchiveVersion,
+			signBatchSpecArchive("another-key", BatchSpecArchiveVersion, compressed),
+			base64.StdEncoding.EncodeToString(compressed),
+		)
+		if _, err := decodeBatchSpecArchive(key, archive); err != ErrInvalidBatchSpecArchiveSignature {
+			t.Fatalf("wrong error. want=%v, have=%v", ErrInvalidBatchSpecArchiveSignature, err)
+		}
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		archive := signed(gzipped(t, []byte(`{"rawSpec":"name: test"}`)))
+		archive = "1" + strings.TrimPrefix(archive, fmt.Sprint(BatchSpecArchiveVersion))
+		if _, err := decodeBatchSpecArchive(key, archive); err == nil {
+			t.Fatal("archive with unsupported version decoded")
+		}
+	})
+
+	t.Run("payload too large", func(t *testing.T) {
+		payload := bytes.Repeat([]byte(" "), maxBatchSpecArchivePayloadSize+1)
+		_, err := decodeBatchSpecArchive(key, signed(gzipped(t, payload)))
+		if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+			t.Fatalf("wrong error for payload exceeding the maximum size: %v", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := decodeBatchSpecArchive(key, "not an archive"); err == nil {
+			t.Fatal("malformed archive decoded")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// InvalidateBatchSpecExecutionCache deletes the cached step results of the
+// workspaces of the given batch spec, so that its next execution runs all
+// steps again.
+func (s *Service) InvalidateBatchSpecExecutionCache(ctx context.Context, batchSpecRandID string) (err error) {
+	ctx, endObservation := s.operations.invalidateBatchSpecExecutionCache.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("batchSpecRandID", batchSpecRandID),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	batchSpec, err := s.store.GetBatchSpec(ctx, store.GetBatchSpecOpts{RandID: batchSpecRandID})
+	if err != nil {
+		return err
+	}
+
+	// Check whether the current user has access to either one of the namespaces.
+	if err := s.CheckNamespaceAccess(ctx, batchSpec.NamespaceUserID, batchSpec.NamespaceOrgID); err != nil {
+		return err
+	}
+
+	workspaces, _, err := s.store.ListBatchSpecWorkspaces(ctx, store.ListBatchSpecWorkspacesOpts{BatchSpecID: batchSpec.ID})
+	if err != nil {
+		return errors.Wrap(err, "listing workspaces")
+	}
+	if len(workspaces) == 0 {
+		return nil
+	}
+
+	repoIDs := make([]api.RepoID, 0, len(workspaces))
+	for _, ws := range workspaces {
+		repoIDs = append(repoIDs, ws.RepoID)
+	}
+	repos, err := s.store.Repos().GetByIDs(ctx, repoIDs...)
+	if err != nil {
+		return errors.Wrap(err, "loading repos")
+	}
+	repoNames := make(map[api.RepoID]string, len(repos))
+	for _, repo := range repos {
+		repoNames[repo.ID] = string(repo.Name)
+	}
+
+	workspaceKeys := make([]string, 0, len(workspaces))
+	for _, ws := range workspaces {
+		// Workspaces in repositories the current user can't access are left
+		// alone.
+		name, ok := repoNames[ws.RepoID]
+		if !ok {
+			continue
+		}
+		key, err := btypes.ExecutionCacheWorkspaceKey(batchSpec, ws, name)
+		if err != nil {
+			return errors.Wrap(err, "computing workspace key")
+		}
+		workspaceKeys = append(workspaceKeys, key)
+	}
+
+	return s.store.DeleteBatchSpecExecutionCacheEntries(ctx, store.DeleteBatchSpecExecutionCacheEntriesOpts{
+		UserID:        batchSpec.UserID,
+		WorkspaceKeys: workspaceKeys,
+	})
+}
+
+// InvalidateUserExecutionCache deletes all cached step results of the given
+// user.
+func (s *Service) InvalidateUserExecutionCache(ctx context.Context, userID int32) (err error) {
+	ctx, endObservation := s.operations.invalidateUserExecutionCache.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("userID", int(userID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if userID == 0 {
+		return errors.New("no user given")
+	}
+
+	// 🚨 SECURITY: Only the user themselves or site admins may invalidate the
+	// cache of a user.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, s.store.DB(), userID); err != nil {
+		return err
+	}
+
+	return s.store.DeleteBatchSpecExecutionCacheEntries(ctx, store.DeleteBatchSpecExecutionCacheEntriesOpts{UserID: userID})
+}
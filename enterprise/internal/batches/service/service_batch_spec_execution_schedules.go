@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// MinBatchSpecExecutionScheduleInterval is the shortest interval at which a
+// batch spec can be scheduled to be re-executed.
+const MinBatchSpecExecutionScheduleInterval = time.Hour
+
+// ErrBatchSpecExecutionScheduleIntervalTooShort is returned by
+// ScheduleBatchSpecExecution when the interval is shorter than
+// MinBatchSpecExecutionScheduleInterval.
+var ErrBatchSpecExecutionScheduleIntervalTooShort = errors.Newf("batch spec executions can be scheduled at most every %s", MinBatchSpecExecutionScheduleInterval)
+
+// ErrScheduleClosedBatchChange is returned by ScheduleBatchSpecExecution when
+// the batch change is closed.
+var ErrScheduleClosedBatchChange = errors.New("cannot schedule batch spec executions of a closed batch change")
+
+// batchSpecExecutionScheduleLabel is the label set on the resolution jobs
+// created by scheduled runs, with the ID of the schedule as value.
+const batchSpecExecutionScheduleLabel = "batch_spec_execution_schedule_id"
+
+type ScheduleBatchSpecExecutionOpts struct {
+	BatchChangeID int64
+	Interval      time.Duration
+	Enabled       bool
+}
+
+// ScheduleBatchSpecExecution creates or updates the schedule that re-executes
+// the batch spec of the given batch change every opts.Interval. The scheduled
+// runs use the permissions of the current user.
+func (s *Service) ScheduleBatchSpecExecution(ctx context.Context, opts ScheduleBatchSpecExecutionOpts) (sched *btypes.BatchSpecExecutionSchedule, err error) {
+	ctx, endObservation := s.operations.scheduleBatchSpecExecution.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchChangeID", int(opts.BatchChangeID)),
+		log.String("interval", opts.Interval.String()),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if opts.Interval < MinBatchSpecExecutionScheduleInterval {
+		return nil, ErrBatchSpecExecutionScheduleIntervalTooShort
+	}
+
+	batchChange, err := s.store.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: opts.BatchChangeID})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting batch change")
+	}
+
+	if batchChange.Closed() {
+		return nil, ErrScheduleClosedBatchChange
+	}
+
+	if err := backend.CheckSiteAdminOrSameUser(ctx, s.store.DB(), batchChange.InitialApplierID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	now := s.clock()
+	sched, err = tx.GetBatchSpecExecutionSchedule(ctx, store.GetBatchSpecExecutionScheduleOpts{BatchChangeID: batchChange.ID})
+	if err != nil && err != store.ErrNoResults {
+		return nil, err
+	}
+	if sched == nil {
+		sched = &btypes.BatchSpecExecutionSchedule{
+			BatchChangeID: batchChange.ID,
+			UserID:        actor.FromContext(ctx).UID,
+			Interval:      opts.Interval,
+			Enabled:       opts.Enabled,
+			NextRunAt:     now.Add(opts.Interval),
+		}
+		return sched, tx.CreateBatchSpecExecutionSchedule(ctx, sched)
+	}
+
+	if sched.Interval != opts.Interval || (opts.Enabled && !sched.Enabled) {
+		sched.NextRunAt = now.Add(opts.Interval)
+	}
+	sched.UserID = actor.FromContext(ctx).UID
+	sched.Interval = opts.Interval
+	sched.Enabled = opts.Enabled
+	return sched, tx.UpdateBatchSpecExecutionSchedule(ctx, sched)
+}
+
+// DeleteBatchSpecExecutionSchedule deletes the schedule of the given batch
+// change, if it has one.
+func (s *Service) DeleteBatchSpecExecutionSchedule(ctx context.Context, batchChangeID int64) (err error) {
+	ctx, endObservation := s.operations.deleteBatchSpecExecutionSchedule.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchChangeID", int(batchChangeID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	batchChange, err := s.store.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: batchChangeID})
+	if err != nil {
+		return errors.Wrap(err, "getting batch change")
+	}
+
+	if err := backend.CheckSiteAdminOrSameUser(ctx, s.store.DB(), batchChange.InitialApplierID); err != nil {
+		return err
+	}
+
+	sched, err := s.store.GetBatchSpecExecutionSchedule(ctx, store.GetBatchSpecExecutionScheduleOpts{BatchChangeID: batchChange.ID})
+	if err != nil {
+		if err == store.ErrNoResults {
+			return nil
+		}
+		return err
+	}
+	return s.store.DeleteBatchSpecExecutionSchedule(ctx, sched.ID)
+}
+
+// RunBatchSpecExecutionSchedule starts a scheduled run: it creates a new batch
+// spec from the raw spec the batch change was last applied with and enqueues
+// the resolution of its workspaces. ExecutePendingScheduledBatchSpec executes
+// the batch spec once its workspaces are resolved.
+//
+// If the batch spec of the previous run is still pending, the run is skipped.
+// If the batch change has been closed in the meantime, the schedule is
+// disabled.
+//
+// ctx must carry the user of the schedule as actor.
+func (s *Service) RunBatchSpecExecutionSchedule(ctx context.Context, sched *btypes.BatchSpecExecutionSchedule) (err error) {
+	ctx, endObservation := s.operations.runBatchSpecExecutionSchedule.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("scheduleID", int(sched.ID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	now := s.clock()
+	sched.NextRunAt = now.Add(sched.Interval)
+	if sched.PendingBatchSpecID != 0 {
+		return tx.UpdateBatchSpecExecutionSchedule(ctx, sched)
+	}
+
+	batchChange, err := tx.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: sched.BatchChangeID})
+	if err != nil {
+		return errors.Wrap(err, "getting batch change")
+	}
+	if batchChange.Closed() {
+		sched.Enabled = false
+		return tx.UpdateBatchSpecExecutionSchedule(ctx, sched)
+	}
+
+	appliedSpec, err := tx.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: batchChange.BatchSpecID})
+	if err != nil {
+		return errors.Wrap(err, "getting applied batch spec")
+	}
+
+	spec, err := btypes.NewBatchSpecFromRaw(appliedSpec.RawSpec)
+	if err != nil {
+		return err
+	}
+	spec.NamespaceUserID = batchChange.NamespaceUserID
+	spec.NamespaceOrgID = batchChange.NamespaceOrgID
+	spec.UserID = sched.UserID
+
+	// 🚨 SECURITY: The user of the schedule must still have access to the
+	// namespace of the batch change. If they lost it, the schedule stays
+	// disabled until someone with access enables it again.
+	if err := s.CheckNamespaceAccess(ctx, spec.NamespaceUserID, spec.NamespaceOrgID); err != nil {
+		if !errcode.IsUnauthorized(err) && err != backend.ErrNotAnOrgMember {
+			return err
+		}
+		sched.Enabled = false
+		return tx.UpdateBatchSpecExecutionSchedule(ctx, sched)
+	}
+
+	if err := s.createBatchSpecForExecution(ctx, tx, createBatchSpecForExecutionOpts{
+		spec:     spec,
+		labels:   map[string]string{batchSpecExecutionScheduleLabel: strconv.FormatInt(sched.ID, 10)},
+		priority: btypes.BatchSpecResolutionJobPriorityBulk,
+	}); err != nil {
+		return err
+	}
+
+	sched.LastRunAt = now
+	sched.PendingBatchSpecID = spec.ID
+	return tx.UpdateBatchSpecExecutionSchedule(ctx, sched)
+}
+
+// ExecutePendingScheduledBatchSpec executes the batch spec created by the last
+// run of the given schedule once its workspaces have been resolved. If the
+// resolution failed or errored, or the batch spec or its resolution job have
+// been deleted in the meantime, the batch spec is dropped and the schedule waits
+// for its next run.
+func (s *Service) ExecutePendingScheduledBatchSpec(ctx context.Context, sched *btypes.BatchSpecExecutionSchedule) (err error) {
+	ctx, endObservation := s.operations.executePendingScheduledBatchSpec.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("scheduleID", int(sched.ID)),
+		log.Int("batchSpecID", int(sched.PendingBatchSpecID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	tx, err := s.store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	resolutionJob, err := tx.GetBatchSpecResolutionJob(ctx, store.GetBatchSpecResolutionJobOpts{BatchSpecID: sched.PendingBatchSpecID})
+	switch {
+	case err == store.ErrNoResults:
+		// The batch spec or its resolution job are gone, so there's nothing
+		// left to execute and the next run starts over.
+
+	case err != nil:
+		return err
+
+	case resolutionJob.State == btypes.BatchSpecResolutionJobStateCompleted:
+		if err := tx.CreateBatchSpecWorkspaceExecutionJobs(ctx, sched.PendingBatchSpecID); err != nil {
+			return err
+		}
+
+	case resolutionJob.State == btypes.BatchSpecResolutionJobStateFailed,
+		resolutionJob.State == btypes.BatchSpecResolutionJobStateErrored:
+		// Nothing to execute, the next run starts over. Resolution jobs are
+		// never retried, so errored ones are final, too.
+
+	default:
+		// Resolution is still in progress.
+		return nil
+	}
+
+	sched.PendingBatchSpecID = 0
+	return tx.UpdateBatchSpecExecutionSchedule(ctx, sched)
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
+	"github.com/keegancsmith/sqlf"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
@@ -20,6 +22,7 @@ import (
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -30,6 +33,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
 	"github.com/sourcegraph/sourcegraph/internal/timeutil"
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestServicePermissionLevels(t *testing.T) {
@@ -146,6 +150,16 @@ func TestServicePermissionLevels(t *testing.T) {
 				tc.assertFunc(t, err)
 			})
 
+			t.Run("RollbackBatchChange", func(t *testing.T) {
+				_, err := svc.RollbackBatchChange(currentUserCtx, batchChange.ID)
+				tc.assertFunc(t, err)
+			})
+
+			t.Run("SetBatchChangeAutoRebase", func(t *testing.T) {
+				_, err := svc.SetBatchChangeAutoRebase(currentUserCtx, batchChange.ID, true)
+				tc.assertFunc(t, err)
+			})
+
 			t.Run("DeleteBatchChange", func(t *testing.T) {
 				err := svc.DeleteBatchChange(currentUserCtx, batchChange.ID)
 				tc.assertFunc(t, err)
@@ -240,6 +254,155 @@ func TestService(t *testing.T) {
 		if err != nil && err != store.ErrNoResults {
 			t.Fatalf("want batch change to be deleted, but was not: %e", err)
 		}
+
+		events, _, err := s.ListAuditEvents(ctx, store.ListAuditEventsOpts{
+			ResourceType: btypes.AuditEventResourceTypeBatchChange,
+			ResourceID:   batchChange.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Action != btypes.AuditEventActionDeleted {
+			t.Fatalf("wrong audit events for deleted batch change: %+v", events)
+		}
+	})
+
+	t.Run("BatchSpecExecutionSchedules", func(t *testing.T) {
+		spec := testBatchSpec(user.ID)
+		spec.RawSpec = ct.TestRawBatchSpecYAML
+		if err := s.CreateBatchSpec(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+		batchChange := testBatchChange(user.ID, spec)
+		batchChange.Name = "scheduled-batch-change"
+		if err := s.CreateBatchChange(ctx, batchChange); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run("interval too short", func(t *testing.T) {
+			_, err := svc.ScheduleBatchSpecExecution(userCtx, ScheduleBatchSpecExecutionOpts{
+				BatchChangeID: batchChange.ID,
+				Interval:      time.Minute,
+				Enabled:       true,
+			})
+			if err != ErrBatchSpecExecutionScheduleIntervalTooShort {
+				t.Fatalf("have err %v, want %v", err, ErrBatchSpecExecutionScheduleIntervalTooShort)
+			}
+		})
+
+		sched, err := svc.ScheduleBatchSpecExecution(userCtx, ScheduleBatchSpecExecutionOpts{
+			BatchChangeID: batchChange.ID,
+			Interval:      2 * time.Hour,
+			Enabled:       true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := sched.NextRunAt, now.Add(2*time.Hour); !have.Equal(want) {
+			t.Fatalf("wrong next run. want=%s, have=%s", want, have)
+		}
+		if sched.UserID != user.ID {
+			t.Fatalf("wrong user. want=%d, have=%d", user.ID, sched.UserID)
+		}
+
+		t.Run("Run", func(t *testing.T) {
+			if err := svc.RunBatchSpecExecutionSchedule(userCtx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID == 0 {
+				t.Fatal("run created no batch spec")
+			}
+			if !sched.LastRunAt.Equal(now) {
+				t.Fatalf("wrong last run. want=%s, have=%s", now, sched.LastRunAt)
+			}
+
+			created, err := s.GetBatchSpec(ctx, store.GetBatchSpecOpts{ID: sched.PendingBatchSpecID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.RawSpec != spec.RawSpec || created.NamespaceUserID != user.ID || created.UserID != user.ID {
+				t.Fatalf("batch spec created with wrong attributes: %+v", created)
+			}
+
+			job, err := s.GetBatchSpecResolutionJob(ctx, store.GetBatchSpecResolutionJobOpts{BatchSpecID: created.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have, want := job.Labels[batchSpecExecutionScheduleLabel], strconv.FormatInt(sched.ID, 10); have != want {
+				t.Fatalf("wrong schedule label. want=%q, have=%q", want, have)
+			}
+
+			// The resolution is still queued, so there's nothing to execute yet.
+			if err := svc.ExecutePendingScheduledBatchSpec(ctx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID != created.ID {
+				t.Fatal("pending batch spec dropped before resolution finished")
+			}
+
+			// A run while the previous one is pending is skipped.
+			if err := svc.RunBatchSpecExecutionSchedule(userCtx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID != created.ID {
+				t.Fatal("overlapping run started")
+			}
+
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = %s WHERE id = %s", btypes.BatchSpecResolutionJobStateFailed, job.ID)); err != nil {
+				t.Fatal(err)
+			}
+			if err := svc.ExecutePendingScheduledBatchSpec(ctx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID != 0 {
+				t.Fatal("batch spec with failed resolution still pending")
+			}
+		})
+
+		t.Run("Run with errored resolution", func(t *testing.T) {
+			if err := svc.RunBatchSpecExecutionSchedule(userCtx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID == 0 {
+				t.Fatal("run created no batch spec")
+			}
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = %s WHERE batch_spec_id = %s", btypes.BatchSpecResolutionJobStateErrored.ToDB(), sched.PendingBatchSpecID)); err != nil {
+				t.Fatal(err)
+			}
+			if err := svc.ExecutePendingScheduledBatchSpec(ctx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID != 0 {
+				t.Fatal("batch spec with errored resolution still pending")
+			}
+		})
+
+		t.Run("Run with deleted resolution job", func(t *testing.T) {
+			if err := svc.RunBatchSpecExecutionSchedule(userCtx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID == 0 {
+				t.Fatal("run created no batch spec")
+			}
+			if err := s.Exec(ctx, sqlf.Sprintf("DELETE FROM batch_spec_resolution_jobs WHERE batch_spec_id = %s", sched.PendingBatchSpecID)); err != nil {
+				t.Fatal(err)
+			}
+			if err := svc.ExecutePendingScheduledBatchSpec(ctx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.PendingBatchSpecID != 0 {
+				t.Fatal("batch spec without resolution job still pending")
+			}
+		})
+
+		t.Run("Delete", func(t *testing.T) {
+			if err := svc.DeleteBatchSpecExecutionSchedule(userCtx, batchChange.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.GetBatchSpecExecutionSchedule(ctx, store.GetBatchSpecExecutionScheduleOpts{ID: sched.ID}); err != store.ErrNoResults {
+				t.Fatalf("have err %v, want %v", err, store.ErrNoResults)
+			}
+		})
 	})
 
 	t.Run("CloseBatchChange", func(t *testing.T) {
@@ -269,6 +432,17 @@ func TestService(t *testing.T) {
 				t.Fatalf("batch change ClosedAt is zero")
 			}
 
+			events, _, err := s.ListAuditEvents(ctx, store.ListAuditEventsOpts{
+				ResourceType: btypes.AuditEventResourceTypeBatchChange,
+				ResourceID:   c.ID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || events[0].Action != btypes.AuditEventActionClosed || events[0].ActorUserID != admin.ID {
+				t.Fatalf("wrong audit events for closed batch change: %+v", events)
+			}
+
 			if !closeChangesets {
 				return
 			}
@@ -314,6 +488,36 @@ func TestService(t *testing.T) {
 		})
 	})
 
+	t.Run("SetBatchChangeAutoRebase", func(t *testing.T) {
+		spec := testBatchSpec(admin.ID)
+		if err := s.CreateBatchSpec(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+
+		batchChange := testBatchChange(admin.ID, spec)
+		if err := s.CreateBatchChange(ctx, batchChange); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, autoRebase := range []bool{true, false} {
+			updated, err := svc.SetBatchChangeAutoRebase(adminCtx, batchChange.ID, autoRebase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have, want := updated.AutoRebase, autoRebase; have != want {
+				t.Fatalf("wrong AutoRebase. want=%t, have=%t", want, have)
+			}
+
+			reloaded, err := s.GetBatchChange(ctx, store.GetBatchChangeOpts{ID: batchChange.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have, want := reloaded.AutoRebase, autoRebase; have != want {
+				t.Fatalf("AutoRebase not persisted. want=%t, have=%t", want, have)
+			}
+		}
+	})
+
 	t.Run("EnqueueChangesetSync", func(t *testing.T) {
 		spec := testBatchSpec(user.ID)
 		if err := s.CreateBatchSpec(ctx, spec); err != nil {
@@ -392,6 +596,17 @@ func TestService(t *testing.T) {
 			FailureMessage:  nil,
 		})
 
+		events, _, err := s.ListAuditEvents(ctx, store.ListAuditEventsOpts{
+			ResourceType: btypes.AuditEventResourceTypeChangeset,
+			ResourceID:   changeset.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Action != btypes.AuditEventActionRetried || events[0].ActorUserID != user.ID {
+			t.Fatalf("wrong audit events for retried changeset: %+v", events)
+		}
+
 		// rs[0] is filtered out
 		ct.MockRepoPermissions(t, db, user.ID, rs[1].ID, rs[2].ID, rs[3].ID)
 
@@ -1349,6 +1564,22 @@ func TestService(t *testing.T) {
 			if want, have := btypes.BatchSpecResolutionJobStateQueued, resolutionJob.State; have != want {
 				t.Fatalf("resolution job has wrong state. want=%s, have=%s", want, have)
 			}
+
+			for resourceType, resourceID := range map[btypes.AuditEventResourceType]int64{
+				btypes.AuditEventResourceTypeBatchSpec:              newSpec.ID,
+				btypes.AuditEventResourceTypeBatchSpecResolutionJob: resolutionJob.ID,
+			} {
+				events, _, err := s.ListAuditEvents(ctx, store.ListAuditEventsOpts{
+					ResourceType: resourceType,
+					ResourceID:   resourceID,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(events) != 1 || events[0].Action != btypes.AuditEventActionCreated {
+					t.Fatalf("wrong audit events for %s %d: %+v", resourceType, resourceID, events)
+				}
+			}
 		})
 
 		t.Run("success with importChangesets", func(t *testing.T) {
@@ -1427,6 +1658,124 @@ func TestService(t *testing.T) {
 		})
 	})
 
+	t.Run("BatchSpecArchives", func(t *testing.T) {
+		spec := testBatchSpec(admin.ID)
+		spec.RawSpec = ct.TestRawBatchSpecYAML
+		if err := s.CreateBatchSpec(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+
+		rawChangesetSpec := ct.NewRawChangesetSpecGitBranch(graphqlbackend.MarshalRepositoryID(rs[0].ID), "d34db33f")
+		changesetSpec, err := svc.CreateChangesetSpec(ctx, rawChangesetSpec, admin.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changesetSpec.BatchSpecID = spec.ID
+		if err := s.UpdateChangesetSpec(ctx, changesetSpec); err != nil {
+			t.Fatal(err)
+		}
+
+		workspaces := []*btypes.BatchSpecWorkspace{
+			{BatchSpecID: spec.ID, RepoID: rs[0].ID, Branch: "refs/heads/main", Commit: "d34db33f", ChangesetSpecIDs: []int64{changesetSpec.ID}},
+			{BatchSpecID: spec.ID, RepoID: rs[1].ID, Branch: "refs/heads/main", Commit: "c0ffee", Path: "sub/dir"},
+		}
+		if err := s.CreateBatchSpecWorkspace(ctx, workspaces...); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run("disabled", func(t *testing.T) {
+			if _, err := svc.ExportBatchSpec(adminCtx, spec.ID); err != ErrBatchSpecArchivesDisabled {
+				t.Fatalf("wrong error. want=%v, have=%v", ErrBatchSpecArchivesDisabled, err)
+			}
+		})
+
+		mockArchiveSigningKey := func(t *testing.T, key string) {
+			conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{BatchChangesArchiveSigningKey: key}})
+			t.Cleanup(func() { conf.Mock(nil) })
+		}
+
+		t.Run("success", func(t *testing.T) {
+			mockArchiveSigningKey(t, "the-archive-signing-key-used-in-tests")
+
+			archive, err := svc.ExportBatchSpec(adminCtx, spec.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			imported, err := svc.ImportBatchSpec(userCtx, ImportBatchSpecOpts{Archive: archive, NamespaceUserID: user.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imported.ID == spec.ID || imported.RandID == spec.RandID {
+				t.Fatal("batch spec was not recreated")
+			}
+			if have, want := imported.UserID, user.ID; have != want {
+				t.Fatalf("UserID is %d, want %d", have, want)
+			}
+			if have, want := imported.RawSpec, spec.RawSpec; have != want {
+				t.Fatalf("wrong raw spec. want=%q, have=%q", want, have)
+			}
+
+			importedChangesetSpecs, _, err := s.ListChangesetSpecs(ctx, store.ListChangesetSpecsOpts{BatchSpecID: imported.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(importedChangesetSpecs) != 1 {
+				t.Fatalf("wrong number of changeset specs: %d", len(importedChangesetSpecs))
+			}
+			ics := importedChangesetSpecs[0]
+			if ics.RepoID != rs[0].ID || ics.UserID != user.ID {
+				t.Fatalf("changeset spec has wrong repo or user: %+v", ics)
+			}
+			if diff := cmp.Diff(changesetSpec.Spec, ics.Spec); diff != "" {
+				t.Fatalf("wrong changeset spec (-want +have):\n%s", diff)
+			}
+
+			importedWorkspaces, _, err := s.ListBatchSpecWorkspaces(ctx, store.ListBatchSpecWorkspacesOpts{BatchSpecID: imported.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(importedWorkspaces) != len(workspaces) {
+				t.Fatalf("wrong number of workspaces: %d", len(importedWorkspaces))
+			}
+			for i, w := range importedWorkspaces {
+				if w.RepoID != workspaces[i].RepoID || w.Commit != workspaces[i].Commit || w.Path != workspaces[i].Path {
+					t.Fatalf("workspace %d was not recreated: %+v", i, w)
+				}
+			}
+			if diff := cmp.Diff([]int64{ics.ID}, importedWorkspaces[0].ChangesetSpecIDs); diff != "" {
+				t.Fatalf("wrong changeset specs of workspace (-want +have):\n%s", diff)
+			}
+		})
+
+		t.Run("invalid signature", func(t *testing.T) {
+			mockArchiveSigningKey(t, "the-archive-signing-key-used-in-tests")
+			archive, err := svc.ExportBatchSpec(adminCtx, spec.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockArchiveSigningKey(t, "another-archive-signing-key-used-in-tests")
+			_, err = svc.ImportBatchSpec(userCtx, ImportBatchSpecOpts{Archive: archive, NamespaceUserID: user.ID})
+			if err != ErrInvalidBatchSpecArchiveSignature {
+				t.Fatalf("wrong error. want=%v, have=%v", ErrInvalidBatchSpecArchiveSignature, err)
+			}
+		})
+
+		t.Run("namespace not accessible", func(t *testing.T) {
+			mockArchiveSigningKey(t, "the-archive-signing-key-used-in-tests")
+			archive, err := svc.ExportBatchSpec(adminCtx, spec.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = svc.ImportBatchSpec(userCtx, ImportBatchSpecOpts{Archive: archive, NamespaceUserID: admin.ID})
+			if !errors.HasType(err, &backend.InsufficientAuthorizationError{}) {
+				t.Fatalf("expected auth error, got %v", err)
+			}
+		})
+	})
+
 	t.Run("ValidateChangesetSpecs", func(t *testing.T) {
 		batchSpec := ct.CreateBatchSpec(t, ctx, s, "matching-batch-spec", admin.ID)
 		conflictingRef := "refs/heads/conflicting-head-ref"
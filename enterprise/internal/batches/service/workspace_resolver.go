@@ -52,8 +52,31 @@ type RepoWorkspace struct {
 type ResolveWorkspacesForBatchSpecOpts struct {
 	AllowIgnored     bool
 	AllowUnsupported bool
+
+	// OnRepositorySearch, if set, is called with every search query that is
+	// executed to discover repositories, exactly as it is sent to the search
+	// API.
+	OnRepositorySearch func(query string)
+
+	// OnProgress, if set, is called whenever the resolution makes progress.
+	// It is never called concurrently.
+	OnProgress func(progress btypes.BatchSpecResolutionJobProgress)
+
+	// OnRepositoryError, if set, is called for every repository that could
+	// not be resolved or inspected. It is never called concurrently. The
+	// resolution still returns an error in that case.
+	OnRepositoryError func(repoErr *btypes.BatchSpecResolutionRepositoryError)
 }
 
+// Estimates of how much of a resolution is done after each of its phases, in
+// percent. Resolving the "on" definitions is by far the slowest phase, so it
+// takes up most of the range.
+const (
+	progressReposDetermined = 70
+	progressIgnoredFound    = 80
+	progressWorkspacesFound = 95
+)
+
 type WorkspaceResolver interface {
 	ResolveWorkspacesForBatchSpec(
 		ctx context.Context,
@@ -97,16 +120,32 @@ func (wr *workspaceResolver) ResolveWorkspacesForBatchSpec(
 	// First, find all repositories that match the batch spec on definitions.
 	// This list is filtered by permissions using database.Repos.List.
 	// This also returns the list of repos that aren't supported.
-	seen, unsupported, err := wr.determineRepositories(ctx, batchSpec)
+	var progress btypes.BatchSpecResolutionJobProgress
+	reportProgress := func() {
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	seen, unsupported, err := wr.determineRepositories(ctx, batchSpec, opts.OnRepositorySearch, opts.OnRepositoryError, func(resolvedOns, reposDiscovered int) {
+		progress.ReposDiscovered = reposDiscovered
+		progress.PercentComplete = progressReposDetermined * resolvedOns / len(batchSpec.On)
+		reportProgress()
+	})
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	progress.ReposDiscovered = len(seen)
+	progress.PercentComplete = progressReposDetermined
+	reportProgress()
 
 	// Next, find the repos that are ignored through a .batchignore file.
-	ignored, err = findIgnoredRepositories(ctx, seen, opts.AllowIgnored, unsupported)
+	ignored, err = findIgnoredRepositories(ctx, seen, opts.AllowIgnored, unsupported, opts.OnRepositoryError)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	progress.PercentComplete = progressIgnoredFound
+	reportProgress()
 
 	// Now build the list of repoRevs we want to consider for workspaces.
 	repoRevs := make([]*RepoRevision, 0, len(seen))
@@ -125,6 +164,9 @@ func (wr *workspaceResolver) ResolveWorkspacesForBatchSpec(
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	progress.WorkspacesResolved = len(final)
+	progress.PercentComplete = progressWorkspacesFound
+	reportProgress()
 
 	return final, unsupported, ignored, nil
 }
@@ -132,6 +174,9 @@ func (wr *workspaceResolver) ResolveWorkspacesForBatchSpec(
 func (wr *workspaceResolver) determineRepositories(
 	ctx context.Context,
 	batchSpec *batcheslib.BatchSpec,
+	onSearch func(query string),
+	onRepositoryError func(repoErr *btypes.BatchSpecResolutionRepositoryError),
+	onResolved func(resolvedOns, reposDiscovered int),
 ) (
 	map[api.RepoID]*RepoRevision,
 	map[*types.Repo]struct{},
@@ -140,16 +185,40 @@ func (wr *workspaceResolver) determineRepositories(
 	seen := map[api.RepoID]*RepoRevision{}
 	unsupported := make(map[*types.Repo]struct{})
 
+	var resolvedOns, reposDiscovered int
+	results := resolveRepositoriesOnConcurrently(ctx, batchSpec.On, wr.resolveRepositoriesOn, func(result resolveOnResult) {
+		resolvedOns++
+		reposDiscovered += len(result.repos)
+		if onResolved != nil {
+			onResolved(resolvedOns, reposDiscovered)
+		}
+	})
+
+	// Results are merged in the order of the on definitions, so that later
+	// definitions consistently win over earlier ones, regardless of which
+	// finished resolving first.
 	var errs error
-	// TODO: this could be trivially parallelised in the future.
-	for _, on := range batchSpec.On {
-		repos, err := wr.resolveRepositoriesOn(ctx, &on)
-		if err != nil {
-			errs = multierror.Append(errs, errors.Wrapf(err, "resolving %q", on.String()))
+	for i, result := range results {
+		if onSearch != nil {
+			for _, query := range result.searchQueries {
+				onSearch(query)
+			}
+		}
+
+		if result.err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(result.err, "resolving %q", batchSpec.On[i].String()))
+			// Failures of definitions naming a single repository can be
+			// attributed to it, unlike failed repository searches.
+			if repo := batchSpec.On[i].Repository; repo != "" && onRepositoryError != nil {
+				onRepositoryError(&btypes.BatchSpecResolutionRepositoryError{
+					RepoName: repo,
+					Message:  result.err.Error(),
+				})
+			}
 			continue
 		}
 
-		for _, repo := range repos {
+		for _, repo := range result.repos {
 			// Skip repos where no branch exists.
 			if !repo.HasBranch() {
 				continue
@@ -173,11 +242,69 @@ func (wr *workspaceResolver) determineRepositories(
 	return seen, unsupported, errs
 }
 
+// resolveOnConcurrency is the maximum number of on definitions of a batch spec
+// that are resolved concurrently.
+const resolveOnConcurrency = 5
+
+type resolveOnResult struct {
+	repos         []*RepoRevision
+	searchQueries []string
+	err           error
+}
+
+// resolveRepositoriesOnConcurrently resolves the given on definitions using a
+// bounded number of workers. The returned results are in the same order as
+// ons, so callers can process them deterministically. If onDone is set, it is
+// called with each result as soon as it is available, but never concurrently.
+func resolveRepositoriesOnConcurrently(
+	ctx context.Context,
+	ons []batcheslib.OnQueryOrRepository,
+	resolve func(ctx context.Context, on *batcheslib.OnQueryOrRepository, onSearch func(query string)) ([]*RepoRevision, error),
+	onDone func(result resolveOnResult),
+) []resolveOnResult {
+	var (
+		results = make([]resolveOnResult, len(ons))
+		input   = make(chan int, len(ons))
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+	)
+
+	for i := 0; i < resolveOnConcurrency && i < len(ons); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range input {
+				// Each worker only ever writes to the result at its own index,
+				// so no locking is required.
+				result := &results[idx]
+				result.repos, result.err = resolve(ctx, &ons[idx], func(query string) {
+					result.searchQueries = append(result.searchQueries, query)
+				})
+
+				if onDone != nil {
+					mu.Lock()
+					onDone(*result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range ons {
+		input <- i
+	}
+	close(input)
+	wg.Wait()
+
+	return results
+}
+
 func findIgnoredRepositories(
 	ctx context.Context,
 	repos map[api.RepoID]*RepoRevision,
 	allowIgnored bool,
 	unsupported map[*types.Repo]struct{},
+	onRepositoryError func(repoErr *btypes.BatchSpecResolutionRepositoryError),
 ) (map[*types.Repo]struct{}, error) {
 	type result struct {
 		repo           *RepoRevision
@@ -219,6 +346,13 @@ func findIgnoredRepositories(
 	for result := range results {
 		if result.err != nil {
 			errs = multierror.Append(errs, result.err)
+			if onRepositoryError != nil {
+				onRepositoryError(&btypes.BatchSpecResolutionRepositoryError{
+					RepoID:   result.repo.Repo.ID,
+					RepoName: string(result.repo.Repo.Name),
+					Message:  errors.Wrap(result.err, "checking for .batchignore file").Error(),
+				})
+			}
 			continue
 		}
 
@@ -232,7 +366,7 @@ func findIgnoredRepositories(
 
 var ErrMalformedOnQueryOrRepository = batcheslib.NewValidationError(errors.New("malformed 'on' field; missing either a repository name or a query"))
 
-func (wr *workspaceResolver) resolveRepositoriesOn(ctx context.Context, on *batcheslib.OnQueryOrRepository) (_ []*RepoRevision, err error) {
+func (wr *workspaceResolver) resolveRepositoriesOn(ctx context.Context, on *batcheslib.OnQueryOrRepository, onSearch func(query string)) (_ []*RepoRevision, err error) {
 	tr, ctx := trace.New(ctx, "workspaceResolver.resolveRepositoriesOn", "")
 	defer func() {
 		tr.SetError(err)
@@ -240,7 +374,7 @@ func (wr *workspaceResolver) resolveRepositoriesOn(ctx context.Context, on *batc
 	}()
 
 	if on.RepositoriesMatchingQuery != "" {
-		return wr.resolveRepositoriesMatchingQuery(ctx, on.RepositoriesMatchingQuery)
+		return wr.resolveRepositoriesMatchingQuery(ctx, on.RepositoriesMatchingQuery, onSearch)
 	}
 
 	if on.Repository != "" && on.Branch != "" {
@@ -312,7 +446,7 @@ func (wr *workspaceResolver) resolveRepositoryNameAndBranch(ctx context.Context,
 	}, nil
 }
 
-func (wr *workspaceResolver) resolveRepositoriesMatchingQuery(ctx context.Context, query string) (_ []*RepoRevision, err error) {
+func (wr *workspaceResolver) resolveRepositoriesMatchingQuery(ctx context.Context, query string, onSearch func(query string)) (_ []*RepoRevision, err error) {
 	tr, ctx := trace.New(ctx, "workspaceResolver.resolveRepositorySearch", "")
 	defer func() {
 		tr.SetError(err)
@@ -320,6 +454,9 @@ func (wr *workspaceResolver) resolveRepositoriesMatchingQuery(ctx context.Contex
 	}()
 
 	query = setDefaultQueryCount(query)
+	if onSearch != nil {
+		onSearch(query)
+	}
 
 	repoIDs := []api.RepoID{}
 	repoFileMatches := make(map[api.RepoID]map[string]bool)
@@ -358,20 +495,67 @@ func (wr *workspaceResolver) resolveRepositoriesMatchingQuery(ctx context.Contex
 		return nil, err
 	}
 
-	revs := make([]*RepoRevision, 0, len(accessibleRepos))
-	for _, repo := range accessibleRepos {
-		fileMatches := make([]string, 0, len(repoFileMatches[repo.ID]))
+	fileMatches := make([][]string, len(accessibleRepos))
+	for i, repo := range accessibleRepos {
+		fileMatches[i] = make([]string, 0, len(repoFileMatches[repo.ID]))
 		for path := range repoFileMatches[repo.ID] {
-			fileMatches = append(fileMatches, path)
+			fileMatches[i] = append(fileMatches[i], path)
 		}
-		sort.Strings(fileMatches)
-		rev, err := repoToRepoRevisionWithDefaultBranch(ctx, repo, fileMatches)
+		sort.Strings(fileMatches[i])
+	}
+
+	return resolveDefaultBranchesConcurrently(ctx, accessibleRepos, fileMatches, repoToRepoRevisionWithDefaultBranch)
+}
+
+// resolveDefaultBranchConcurrency is the maximum number of repositories whose
+// default branch is resolved concurrently for a single on definition.
+const resolveDefaultBranchConcurrency = 10
+
+// resolveDefaultBranchesConcurrently resolves the default branch of the given
+// repositories, whose file matches are at the same index of fileMatches,
+// using a bounded number of workers. The returned revisions are in the same
+// order as repos. If resolving any repository fails, the errors of all
+// repositories are returned.
+func resolveDefaultBranchesConcurrently(
+	ctx context.Context,
+	repos []*types.Repo,
+	fileMatches [][]string,
+	resolve func(ctx context.Context, repo *types.Repo, fileMatches []string) (*RepoRevision, error),
+) ([]*RepoRevision, error) {
+	var (
+		revs  = make([]*RepoRevision, len(repos))
+		errs  = make([]error, len(repos))
+		input = make(chan int, len(repos))
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < resolveDefaultBranchConcurrency && i < len(repos); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range input {
+				// Each worker only ever writes to its own index, so no
+				// locking is required.
+				revs[idx], errs[idx] = resolve(ctx, repos[idx], fileMatches[idx])
+			}
+		}()
+	}
+
+	for i := range repos {
+		input <- i
+	}
+	close(input)
+	wg.Wait()
+
+	var merr *multierror.Error
+	for i, err := range errs {
 		if err != nil {
-			return nil, err
+			merr = multierror.Append(merr, errors.Wrapf(err, "resolving default branch of %s", repos[i].Name))
 		}
-		revs = append(revs, rev)
 	}
-
+	if err := merr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
 	return revs, nil
 }
 
@@ -517,14 +701,14 @@ func (wr *workspaceResolver) FindDirectoriesInRepos(ctx context.Context, fileNam
 			}()
 
 			result, err := findForRepoRev(repoRev)
+
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
 				errs = multierror.Append(errs, err)
 				return
 			}
-
-			mu.Lock()
 			results[repoRev.Key()] = result
-			mu.Unlock()
 		}(repoRev)
 	}
 
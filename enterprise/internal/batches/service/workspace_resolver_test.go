@@ -9,12 +9,15 @@ import (
 	"os"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
@@ -46,6 +49,103 @@ func TestSetDefaultQueryCount(t *testing.T) {
 	}
 }
 
+func TestResolveRepositoriesOnConcurrently(t *testing.T) {
+	var ons []batcheslib.OnQueryOrRepository
+	for i := 0; i < 3*resolveOnConcurrency; i++ {
+		ons = append(ons, batcheslib.OnQueryOrRepository{Repository: fmt.Sprintf("repo-%d", i)})
+	}
+
+	var done int
+	results := resolveRepositoriesOnConcurrently(context.Background(), ons, func(ctx context.Context, on *batcheslib.OnQueryOrRepository, onSearch func(query string)) ([]*RepoRevision, error) {
+		// Make earlier definitions finish last, so results would be out of
+		// order if they were collected in completion order.
+		var idx int
+		fmt.Sscanf(on.Repository, "repo-%d", &idx)
+		time.Sleep(time.Duration(len(ons)-idx) * time.Millisecond)
+
+		onSearch("query " + on.Repository)
+		if idx%2 == 1 {
+			return nil, errors.New("failed " + on.Repository)
+		}
+		return []*RepoRevision{{Repo: &types.Repo{Name: api.RepoName(on.Repository)}}}, nil
+	}, func(result resolveOnResult) {
+		done++
+	})
+	if done != len(ons) {
+		t.Fatalf("got %d results reported as done, want %d", done, len(ons))
+	}
+
+	if len(results) != len(ons) {
+		t.Fatalf("got %d results, want %d", len(results), len(ons))
+	}
+	for i, result := range results {
+		name := fmt.Sprintf("repo-%d", i)
+		if want := []string{"query " + name}; !cmp.Equal(result.searchQueries, want) {
+			t.Errorf("result %d: got search queries %v, want %v", i, result.searchQueries, want)
+		}
+		if i%2 == 1 {
+			if result.err == nil || result.err.Error() != "failed "+name {
+				t.Errorf("result %d: got error %v, want failure for %s", i, result.err, name)
+			}
+			continue
+		}
+		if result.err != nil {
+			t.Errorf("result %d: unexpected error %v", i, result.err)
+		} else if len(result.repos) != 1 || string(result.repos[0].Repo.Name) != name {
+			t.Errorf("result %d: got repos %+v, want %s", i, result.repos, name)
+		}
+	}
+}
+
+func TestResolveDefaultBranchesConcurrently(t *testing.T) {
+	var (
+		repos       []*types.Repo
+		fileMatches [][]string
+	)
+	for i := 0; i < 3*resolveDefaultBranchConcurrency; i++ {
+		repos = append(repos, &types.Repo{ID: api.RepoID(i), Name: api.RepoName(fmt.Sprintf("repo-%d", i))})
+		fileMatches = append(fileMatches, []string{fmt.Sprintf("file-%d", i)})
+	}
+
+	resolve := func(failing map[api.RepoID]bool) func(context.Context, *types.Repo, []string) (*RepoRevision, error) {
+		return func(ctx context.Context, repo *types.Repo, fileMatches []string) (*RepoRevision, error) {
+			// Make earlier repositories finish last, so revisions would be
+			// out of order if they were collected in completion order.
+			time.Sleep(time.Duration(len(repos)-int(repo.ID)) * time.Millisecond)
+			if failing[repo.ID] {
+				return nil, errors.New("failed")
+			}
+			return &RepoRevision{Repo: repo, Branch: "main", FileMatches: fileMatches}, nil
+		}
+	}
+
+	t.Run("ordered results", func(t *testing.T) {
+		revs, err := resolveDefaultBranchesConcurrently(context.Background(), repos, fileMatches, resolve(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revs) != len(repos) {
+			t.Fatalf("got %d revisions, want %d", len(revs), len(repos))
+		}
+		for i, rev := range revs {
+			if rev.Repo != repos[i] || !cmp.Equal(rev.FileMatches, fileMatches[i]) {
+				t.Errorf("revision %d: got %+v, want repo %s", i, rev, repos[i].Name)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		revs, err := resolveDefaultBranchesConcurrently(context.Background(), repos, fileMatches, resolve(map[api.RepoID]bool{1: true, 4: true}))
+		if revs != nil {
+			t.Errorf("got revisions %+v despite errors", revs)
+		}
+		want := "2 errors occurred:\n\t* resolving default branch of repo-1: failed\n\t* resolving default branch of repo-4: failed\n\n"
+		if err == nil || err.Error() != want {
+			t.Errorf("got error %q, want %q", err, want)
+		}
+	})
+}
+
 func TestService_ResolveWorkspacesForBatchSpec(t *testing.T) {
 	ctx := context.Background()
 
@@ -141,6 +241,83 @@ func TestService_ResolveWorkspacesForBatchSpec(t *testing.T) {
 		resolveWorkspacesAndCompare(t, s, defaultOpts, searchMatches, batchSpec, want, wantIgnored, wantUnsupported)
 	})
 
+	t.Run("repositoriesMatchingQuery reports search queries", func(t *testing.T) {
+		batchSpec := &batcheslib.BatchSpec{
+			On: []batcheslib.OnQueryOrRepository{
+				{RepositoriesMatchingQuery: "repohasfile:horse.txt"},
+				{RepositoriesMatchingQuery: "repohasfile:horse.txt count:10"},
+				{Repository: string(rs[0].Name)},
+			},
+			Steps: steps,
+		}
+
+		mockBatchIgnores(t, map[api.CommitID]bool{
+			defaultBranches[rs[0].Name].commit: false,
+		})
+
+		searchMatches := []streamhttp.EventMatch{
+			&streamhttp.EventRepoMatch{
+				Type:         streamhttp.RepoMatchType,
+				RepositoryID: int32(rs[0].ID),
+			},
+		}
+
+		var have []string
+		opts := defaultOpts
+		opts.OnRepositorySearch = func(query string) { have = append(have, query) }
+
+		wr := &workspaceResolver{
+			store:               s,
+			frontendInternalURL: newStreamSearchTestServer(t, searchMatches),
+		}
+		if _, _, _, err := wr.ResolveWorkspacesForBatchSpec(context.Background(), batchSpec, opts); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		want := []string{"repohasfile:horse.txt count:all", "repohasfile:horse.txt count:10"}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("wrong search queries reported. (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("reports progress", func(t *testing.T) {
+		batchSpec := &batcheslib.BatchSpec{
+			On: []batcheslib.OnQueryOrRepository{
+				{Repository: string(rs[0].Name)},
+				{Repository: string(rs[1].Name)},
+			},
+			Steps: steps,
+		}
+
+		mockBatchIgnores(t, map[api.CommitID]bool{
+			defaultBranches[rs[0].Name].commit: false,
+			defaultBranches[rs[1].Name].commit: false,
+		})
+
+		var have []btypes.BatchSpecResolutionJobProgress
+		opts := defaultOpts
+		opts.OnProgress = func(progress btypes.BatchSpecResolutionJobProgress) { have = append(have, progress) }
+
+		wr := &workspaceResolver{
+			store:               s,
+			frontendInternalURL: newStreamSearchTestServer(t, nil),
+		}
+		if _, _, _, err := wr.ResolveWorkspacesForBatchSpec(context.Background(), batchSpec, opts); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		want := []btypes.BatchSpecResolutionJobProgress{
+			{ReposDiscovered: 1, PercentComplete: 35},
+			{ReposDiscovered: 2, PercentComplete: 70},
+			{ReposDiscovered: 2, PercentComplete: 70},
+			{ReposDiscovered: 2, PercentComplete: 80},
+			{ReposDiscovered: 2, WorkspacesResolved: 2, PercentComplete: 95},
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("wrong progress reported. (-want +got):\n%s", diff)
+		}
+	})
+
 	t.Run("repositories", func(t *testing.T) {
 		batchSpec := &batcheslib.BatchSpec{
 			On: []batcheslib.OnQueryOrRepository{
@@ -0,0 +1,255 @@
+package sources
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A BitbucketCloudSource publishes changesets as Bitbucket Cloud pull
+// requests. Bitbucket Cloud doesn't support draft pull requests, and declined
+// pull requests can't be reopened.
+type BitbucketCloudSource struct {
+	client *bitbucketcloud.Client
+	au     auth.Authenticator
+}
+
+var _ ChangesetSource = &BitbucketCloudSource{}
+
+// NewBitbucketCloudSource returns a new BitbucketCloudSource from the given
+// external service.
+func NewBitbucketCloudSource(svc *types.ExternalService, cf *httpcli.Factory) (*BitbucketCloudSource, error) {
+	var c schema.BitbucketCloudConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+
+	if c.ApiURL == "" {
+		c.ApiURL = "https://api.bitbucket.org"
+	}
+	apiURL, err := url.Parse(c.ApiURL)
+	if err != nil {
+		return nil, err
+	}
+	apiURL = extsvc.NormalizeBaseURL(apiURL)
+
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+
+	client := bitbucketcloud.NewClient(apiURL, cli)
+	client.Username = c.Username
+	client.AppPassword = c.AppPassword
+
+	return &BitbucketCloudSource{
+		client: client,
+		au:     &auth.BasicAuth{Username: c.Username, Password: c.AppPassword},
+	}, nil
+}
+
+func (s BitbucketCloudSource) GitserverPushConfig(ctx context.Context, store *database.ExternalServiceStore, repo *types.Repo) (*protocol.PushConfig, error) {
+	return gitserverPushConfig(ctx, store, repo, s.au)
+}
+
+func (s BitbucketCloudSource) WithAuthenticator(a auth.Authenticator) (ChangesetSource, error) {
+	switch a.(type) {
+	case *auth.BasicAuth,
+		*auth.BasicAuthWithSSH:
+		break
+
+	default:
+		return nil, newUnsupportedAuthenticatorError("BitbucketCloudSource", a)
+	}
+
+	client, err := s.client.WithAuthenticator(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitbucketCloudSource{client: client, au: a}, nil
+}
+
+func (s BitbucketCloudSource) ValidateAuthenticator(ctx context.Context) error {
+	_, err := s.client.CurrentUser(ctx)
+	return err
+}
+
+// CreateChangeset opens a pull request for the given *Changeset. If an open
+// pull request for the same branches already exists, it's used instead.
+func (s BitbucketCloudSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	head, base := git.AbbreviateRef(c.HeadRef), git.AbbreviateRef(c.BaseRef)
+
+	pr, err := s.client.FindOpenPullRequest(ctx, repo, head, base)
+	if err != nil {
+		return false, errors.Wrap(err, "looking up existing pull request")
+	}
+
+	exists := pr != nil
+	if !exists {
+		pr, err = s.client.CreatePullRequest(ctx, repo, bitbucketcloud.PullRequestInput{
+			Title:             c.Title,
+			Description:       c.Body,
+			SourceBranch:      head,
+			DestinationBranch: base,
+		})
+		if err != nil {
+			return false, errors.Wrap(err, "creating pull request")
+		}
+	}
+
+	return exists, s.setChangesetMetadata(ctx, repo, pr, c)
+}
+
+// CloseChangeset declines the pull request of the given *Changeset.
+func (s BitbucketCloudSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.AnnotatedPullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	if pr.State != bitbucketcloud.PullRequestStateOpen {
+		return nil
+	}
+
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	declined, err := s.client.DeclinePullRequest(ctx, repo, pr.ID)
+	if err != nil {
+		return errors.Wrap(err, "declining pull request")
+	}
+
+	return s.setChangesetMetadata(ctx, repo, declined, c)
+}
+
+// LoadChangeset loads the pull request of the given *Changeset and its build
+// statuses.
+func (s BitbucketCloudSource) LoadChangeset(ctx context.Context, c *Changeset) error {
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+
+	id, err := strconv.ParseInt(c.ExternalID, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "parsing changeset external ID %s", c.ExternalID)
+	}
+
+	pr, err := s.client.GetPullRequest(ctx, repo, id)
+	if err != nil {
+		if bitbucketcloud.IsNotFound(err) {
+			return ChangesetNotFoundError{Changeset: c}
+		}
+		return errors.Wrapf(err, "retrieving pull request %d", id)
+	}
+
+	return s.setChangesetMetadata(ctx, repo, pr, c)
+}
+
+// ReopenChangeset opens a new pull request for the branches of the given
+// *Changeset, if its pull request was declined, since declined pull requests
+// can't be reopened on Bitbucket Cloud.
+func (s BitbucketCloudSource) ReopenChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.AnnotatedPullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	if pr.State != bitbucketcloud.PullRequestStateDeclined {
+		return nil
+	}
+
+	_, err := s.CreateChangeset(ctx, c)
+	return err
+}
+
+// UpdateChangeset updates the title, description and destination branch of
+// the pull request of the given *Changeset.
+func (s BitbucketCloudSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.AnnotatedPullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	updated, err := s.client.UpdatePullRequest(ctx, repo, pr.ID, bitbucketcloud.PullRequestInput{
+		Title:             c.Title,
+		Description:       c.Body,
+		DestinationBranch: git.AbbreviateRef(c.BaseRef),
+	})
+	if err != nil {
+		return errors.Wrap(err, "updating pull request")
+	}
+
+	return s.setChangesetMetadata(ctx, repo, updated, c)
+}
+
+// CreateComment posts a comment on the pull request of the given *Changeset.
+func (s BitbucketCloudSource) CreateComment(ctx context.Context, c *Changeset, text string) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.AnnotatedPullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	return s.client.CreatePullRequestComment(ctx, repo, pr.ID, text)
+}
+
+// MergeChangeset merges the pull request of the given *Changeset, squashing
+// its commits if squash is true.
+func (s BitbucketCloudSource) MergeChangeset(ctx context.Context, c *Changeset, squash bool) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.AnnotatedPullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	merged, err := s.client.MergePullRequest(ctx, repo, pr.ID, squash)
+	if err != nil {
+		if errors.Is(err, bitbucketcloud.ErrNotMergeable) {
+			return ChangesetNotMergeableError{ErrorMsg: err.Error()}
+		}
+		return errors.Wrap(err, "merging pull request")
+	}
+
+	return s.setChangesetMetadata(ctx, repo, merged, c)
+}
+
+// setChangesetMetadata loads the build statuses of the given pull request and
+// sets both as the metadata of the given *Changeset.
+func (s BitbucketCloudSource) setChangesetMetadata(ctx context.Context, repo *bitbucketcloud.Repo, pr *bitbucketcloud.PullRequest, c *Changeset) error {
+	statuses, err := s.client.GetPullRequestStatuses(ctx, repo, pr.ID)
+	if err != nil {
+		return errors.Wrap(err, "retrieving pull request statuses")
+	}
+
+	apr := &bitbucketcloud.AnnotatedPullRequest{
+		PullRequest: pr,
+		Statuses:    make([]*bitbucketcloud.CommitStatus, 0, len(statuses)),
+	}
+	for _, status := range statuses {
+		apr.Statuses = append(apr.Statuses, &bitbucketcloud.CommitStatus{
+			Commit: status.CommitHash(),
+			Status: *status,
+		})
+	}
+
+	if err := c.SetMetadata(apr); err != nil {
+		return errors.Wrap(err, "setting changeset metadata")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestBitbucketCloudSource_WithAuthenticator(t *testing.T) {
+	svc := &types.ExternalService{
+		Kind: extsvc.KindBitbucketCloud,
+		Config: marshalJSON(t, &schema.BitbucketCloudConnection{
+			Url:         "https://bitbucket.org",
+			Username:    "sourcegraph",
+			AppPassword: "secret",
+		}),
+	}
+
+	for name, tc := range map[string]struct {
+		au        auth.Authenticator
+		supported bool
+	}{
+		"BasicAuth":               {au: &auth.BasicAuth{}, supported: true},
+		"BasicAuthWithSSH":        {au: &auth.BasicAuthWithSSH{}, supported: true},
+		"nil":                     {au: nil},
+		"OAuthBearerToken":        {au: &auth.OAuthBearerToken{}},
+		"OAuthBearerTokenWithSSH": {au: &auth.OAuthBearerTokenWithSSH{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src, err := NewBitbucketCloudSource(svc, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			css, err := src.WithAuthenticator(tc.au)
+			if tc.supported {
+				if err != nil {
+					t.Fatalf("unexpected non-nil error: %v", err)
+				}
+				if _, ok := css.(*BitbucketCloudSource); !ok {
+					t.Fatalf("cannot coerce Source into BitbucketCloudSource")
+				}
+				return
+			}
+			if !errors.HasType(err, UnsupportedAuthenticatorError{}) {
+				t.Fatalf("unexpected error of type %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestBitbucketCloudSource_Changesets(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		pullRequest = `{"id": 7, "title": "Fix the build", "state": "%s", "source": {"branch": {"name": "fix-build"}, "commit": {"hash": "ffad59b0cbd5"}}, "destination": {"branch": {"name": "main"}}, "created_on": "2021-09-01T10:00:00.000000+00:00", "updated_on": "2021-09-01T11:00:00.000000+00:00"}`
+		statuses    = `{"values": [{"key": "build", "state": "SUCCESSFUL", "links": {"commit": {"href": "https://api.bitbucket.org/2.0/repositories/sglocal/mux/commit/ffad59b0cbd5e5b5fda3bc7ba1bcc4ddb1a4cc8a"}}}]}`
+	)
+
+	var existing bool
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /2.0/repositories/sglocal/mux/pullrequests":
+			if existing {
+				io.WriteString(w, `{"values": [`+fmt.Sprintf(pullRequest, "OPEN")+`]}`)
+			} else {
+				io.WriteString(w, `{"values": []}`)
+			}
+		case "POST /2.0/repositories/sglocal/mux/pullrequests",
+			"GET /2.0/repositories/sglocal/mux/pullrequests/7":
+			io.WriteString(w, fmt.Sprintf(pullRequest, "OPEN"))
+		case "POST /2.0/repositories/sglocal/mux/pullrequests/7/decline":
+			io.WriteString(w, fmt.Sprintf(pullRequest, "DECLINED"))
+		case "GET /2.0/repositories/sglocal/mux/pullrequests/8":
+			w.WriteHeader(http.StatusNotFound)
+		case "GET /2.0/repositories/sglocal/mux/pullrequests/7/statuses":
+			io.WriteString(w, statuses)
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	src, err := NewBitbucketCloudSource(&types.ExternalService{
+		Kind: extsvc.KindBitbucketCloud,
+		Config: marshalJSON(t, &schema.BitbucketCloudConnection{
+			Url:         "https://bitbucket.org",
+			ApiURL:      srv.URL,
+			Username:    "sourcegraph",
+			AppPassword: "secret",
+		}),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &types.Repo{
+		ExternalRepo: api.ExternalRepoSpec{ID: "{e1e75436-05e6-4c38-8543-9c36ec26fad1}", ServiceType: extsvc.TypeBitbucketCloud},
+		Metadata:     &bitbucketcloud.Repo{FullName: "sglocal/mux"},
+	}
+	newChangeset := func() *Changeset {
+		return &Changeset{
+			Title:     "Fix the build",
+			HeadRef:   "refs/heads/fix-build",
+			BaseRef:   "refs/heads/main",
+			Repo:      repo,
+			Changeset: &btypes.Changeset{},
+		}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		for _, existing = range []bool{false, true} {
+			requests = nil
+
+			cs := newChangeset()
+			exists, err := src.CreateChangeset(ctx, cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exists != existing {
+				t.Fatalf("wrong exists. want=%t, have=%t", existing, exists)
+			}
+			if existing && len(requests) != 2 || !existing && len(requests) != 3 {
+				t.Fatalf("unexpected requests %v", requests)
+			}
+
+			if have, want := cs.ExternalID, "7"; have != want {
+				t.Fatalf("wrong external ID. want=%q, have=%q", want, have)
+			}
+			if have, want := cs.ExternalBranch, "refs/heads/fix-build"; have != want {
+				t.Fatalf("wrong external branch. want=%q, have=%q", want, have)
+			}
+			pr := cs.Metadata.(*bitbucketcloud.AnnotatedPullRequest)
+			if len(pr.Statuses) != 1 || pr.Statuses[0].Commit != "ffad59b0cbd5e5b5fda3bc7ba1bcc4ddb1a4cc8a" {
+				t.Fatalf("unexpected statuses %+v", pr.Statuses)
+			}
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		cs := newChangeset()
+		if _, err := src.CreateChangeset(ctx, cs); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := src.CloseChangeset(ctx, cs); err != nil {
+			t.Fatal(err)
+		}
+		if have, want := cs.Metadata.(*bitbucketcloud.AnnotatedPullRequest).State, bitbucketcloud.PullRequestStateDeclined; have != want {
+			t.Fatalf("wrong state. want=%q, have=%q", want, have)
+		}
+	})
+
+	t.Run("load deleted", func(t *testing.T) {
+		cs := newChangeset()
+		cs.ExternalID = "8"
+
+		err := src.LoadChangeset(ctx, cs)
+		if !errors.HasType(err, ChangesetNotFoundError{}) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -37,6 +37,15 @@ type DraftChangesetSource interface {
 	UndraftChangeset(context.Context, *Changeset) error
 }
 
+// A PushPreparingChangesetSource needs to adjust how the commit of a
+// changeset is pushed to the code host, for example because the code host
+// derives changesets from magic refs instead of branches.
+type PushPreparingChangesetSource interface {
+	// PreparePush adjusts the given request to create and push the commit of
+	// the Changeset. It's called before every push of the commit.
+	PreparePush(context.Context, *Changeset, *protocol.CreateCommitFromPatchRequest) error
+}
+
 // A ChangesetSource can load the latest state of a list of Changesets.
 type ChangesetSource interface {
 	// GitserverPushConfig returns an authenticated push config used for pushing
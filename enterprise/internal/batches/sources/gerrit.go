@@ -0,0 +1,258 @@
+package sources
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A GerritSource publishes changesets as Gerrit changes.
+//
+// Gerrit doesn't create changes from branches: a change is created by pushing
+// a commit to the magic refs/for/<branch> ref, and the Change-Id trailer of the
+// commit message identifies the change further commits are added to as patch
+// sets. The head branch of a changeset is used as the topic of its change. The
+// title and body of a change are the subject and body of its commit message,
+// so they can't be updated independently of the commit.
+type GerritSource struct {
+	client *gerrit.Client
+	au     auth.Authenticator
+}
+
+var _ ChangesetSource = &GerritSource{}
+var _ DraftChangesetSource = &GerritSource{}
+var _ PushPreparingChangesetSource = &GerritSource{}
+
+// NewGerritSource returns a new GerritSource from the given external service.
+func NewGerritSource(svc *types.ExternalService, cf *httpcli.Factory) (*GerritSource, error) {
+	var c schema.GerritConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := gerrit.NewClient(&c, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GerritSource{client: client, au: client.Auth}, nil
+}
+
+func (s GerritSource) GitserverPushConfig(ctx context.Context, store *database.ExternalServiceStore, repo *types.Repo) (*protocol.PushConfig, error) {
+	return gitserverPushConfig(ctx, store, repo, s.au)
+}
+
+func (s GerritSource) WithAuthenticator(a auth.Authenticator) (ChangesetSource, error) {
+	switch a.(type) {
+	case *auth.BasicAuth,
+		*auth.BasicAuthWithSSH:
+		break
+
+	default:
+		return nil, newUnsupportedAuthenticatorError("GerritSource", a)
+	}
+
+	sc := s
+	sc.au = a
+	sc.client = sc.client.WithAuthenticator(a)
+
+	return &sc, nil
+}
+
+func (s GerritSource) ValidateAuthenticator(ctx context.Context) error {
+	_, err := s.client.AuthenticatedUsername(ctx)
+	return err
+}
+
+// PreparePush adds the Change-Id of the changeset to the commit message and
+// pushes the commit to the magic ref creating or updating the change. If the
+// base branch of an existing change changed, the change is moved to the new
+// branch first, since pushing to the new branch would create a new change.
+func (s *GerritSource) PreparePush(ctx context.Context, c *Changeset, opts *protocol.CreateCommitFromPatchRequest) error {
+	if ch, ok := c.Changeset.Metadata.(*gerrit.Change); ok {
+		if base := git.AbbreviateRef(c.BaseRef); ch.Branch != base {
+			if err := s.client.MoveChange(ctx, ch, base); err != nil {
+				return errors.Wrap(err, "moving Gerrit change")
+			}
+			if err := c.SetMetadata(ch); err != nil {
+				return errors.Wrap(err, "setting changeset metadata")
+			}
+		}
+	}
+
+	opts.CommitInfo.Message = gerrit.WithChangeID(opts.CommitInfo.Message, changeID(c))
+	opts.Push.RemoteRef = gerrit.ChangeRef(c.BaseRef, git.AbbreviateRef(c.HeadRef))
+	return nil
+}
+
+// CreateChangeset loads the change created by pushing the commit of the
+// changeset. The return value is always false, since pushing the commit is
+// what creates the change.
+func (s *GerritSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	project := c.Repo.Metadata.(*gerrit.Project)
+
+	ch, err := s.client.FindChange(ctx, project.Name, git.AbbreviateRef(c.BaseRef), changeID(c))
+	if err != nil {
+		return false, errors.Wrap(err, "retrieving the change created by the push")
+	}
+
+	if err := c.SetMetadata(ch); err != nil {
+		return false, errors.Wrap(err, "setting changeset metadata")
+	}
+	return false, nil
+}
+
+// CreateDraftChangeset loads the change created by pushing the commit of the
+// changeset and marks it as work in progress.
+func (s *GerritSource) CreateDraftChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	exists, err := s.CreateChangeset(ctx, c)
+	if err != nil {
+		return exists, err
+	}
+
+	ch := c.Changeset.Metadata.(*gerrit.Change)
+	if ch.WorkInProgress {
+		return exists, nil
+	}
+	if err := s.client.SetWorkInProgress(ctx, ch); err != nil {
+		return exists, errors.Wrap(err, "marking Gerrit change as work in progress")
+	}
+	return exists, c.SetMetadata(ch)
+}
+
+// UndraftChangeset marks the change as ready for review.
+func (s *GerritSource) UndraftChangeset(ctx context.Context, c *Changeset) error {
+	ch, ok := c.Changeset.Metadata.(*gerrit.Change)
+	if !ok {
+		return errors.New("Changeset is not a Gerrit change")
+	}
+
+	if err := s.client.SetReadyForReview(ctx, ch); err != nil {
+		return errors.Wrap(err, "marking Gerrit change as ready for review")
+	}
+	return c.SetMetadata(ch)
+}
+
+// CloseChangeset abandons the change on Gerrit.
+func (s *GerritSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	ch, ok := c.Changeset.Metadata.(*gerrit.Change)
+	if !ok {
+		return errors.New("Changeset is not a Gerrit change")
+	}
+
+	if ch.Status == gerrit.ChangeStatusAbandoned {
+		return nil
+	}
+	if err := s.client.AbandonChange(ctx, ch); err != nil {
+		return errors.Wrap(err, "abandoning Gerrit change")
+	}
+	return c.SetMetadata(ch)
+}
+
+// ReopenChangeset restores the change on Gerrit, if it's abandoned.
+func (s *GerritSource) ReopenChangeset(ctx context.Context, c *Changeset) error {
+	ch, ok := c.Changeset.Metadata.(*gerrit.Change)
+	if !ok {
+		return errors.New("Changeset is not a Gerrit change")
+	}
+
+	if ch.Status != gerrit.ChangeStatusAbandoned {
+		return nil
+	}
+	if err := s.client.RestoreChange(ctx, ch); err != nil {
+		return errors.Wrap(err, "restoring Gerrit change")
+	}
+	return c.SetMetadata(ch)
+}
+
+// LoadChangeset loads the given change from Gerrit and updates it.
+func (s *GerritSource) LoadChangeset(ctx context.Context, c *Changeset) error {
+	project := c.Repo.Metadata.(*gerrit.Project)
+
+	number, err := strconv.Atoi(c.ExternalID)
+	if err != nil {
+		return errors.Wrapf(err, "parsing changeset external ID %s", c.ExternalID)
+	}
+
+	ch, err := s.client.GetChange(ctx, project.Name, number)
+	if err != nil {
+		if errors.Is(err, gerrit.ErrChangeNotFound) {
+			return ChangesetNotFoundError{Changeset: c}
+		}
+		return errors.Wrapf(err, "retrieving change %d", number)
+	}
+
+	return c.SetMetadata(ch)
+}
+
+// UpdateChangeset moves the change to the base branch of the changeset. The
+// title and body of the change can only be updated by pushing a new commit.
+func (s *GerritSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	ch, ok := c.Changeset.Metadata.(*gerrit.Change)
+	if !ok {
+		return errors.New("Changeset is not a Gerrit change")
+	}
+
+	if base := git.AbbreviateRef(c.BaseRef); ch.Branch != base {
+		if err := s.client.MoveChange(ctx, ch, base); err != nil {
+			return errors.Wrap(err, "moving Gerrit change")
+		}
+		return c.SetMetadata(ch)
+	}
+
+	return s.LoadChangeset(ctx, c)
+}
+
+// CreateComment posts a review comment on the current patch set of the change.
+func (s *GerritSource) CreateComment(ctx context.Context, c *Changeset, text string) error {
+	ch, ok := c.Changeset.Metadata.(*gerrit.Change)
+	if !ok {
+		return errors.New("Changeset is not a Gerrit change")
+	}
+
+	return s.client.PostReviewComment(ctx, ch, text)
+}
+
+// MergeChangeset submits the change on Gerrit, if it's submittable. Since a
+// change consists of a single commit, squash is ignored.
+func (s *GerritSource) MergeChangeset(ctx context.Context, c *Changeset, squash bool) error {
+	ch, ok := c.Changeset.Metadata.(*gerrit.Change)
+	if !ok {
+		return errors.New("Changeset is not a Gerrit change")
+	}
+
+	if err := s.client.SubmitChange(ctx, ch); err != nil {
+		if errors.Is(err, gerrit.ErrNotMergeable) {
+			return ChangesetNotMergeableError{ErrorMsg: err.Error()}
+		}
+		return errors.Wrap(err, "submitting Gerrit change")
+	}
+	return c.SetMetadata(ch)
+}
+
+// changeID returns the Change-Id of the change of the given changeset. It's
+// derived from the changeset, so that every commit pushed for the changeset
+// becomes a patch set of the same change.
+func changeID(c *Changeset) string {
+	return gerrit.NewChangeID(c.Repo.ExternalRepo.ID, strconv.FormatInt(c.Changeset.ID, 10), c.HeadRef)
+}
@@ -0,0 +1,168 @@
+package sources
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestGerritSource_WithAuthenticator(t *testing.T) {
+	svc := &types.ExternalService{
+		Kind: extsvc.KindGerrit,
+		Config: marshalJSON(t, &schema.GerritConnection{
+			Url:      "https://gerrit.sgdev.org",
+			Username: "admin",
+			Password: "secret",
+		}),
+	}
+
+	for name, tc := range map[string]struct {
+		au        auth.Authenticator
+		supported bool
+	}{
+		"BasicAuth":               {au: &auth.BasicAuth{}, supported: true},
+		"BasicAuthWithSSH":        {au: &auth.BasicAuthWithSSH{}, supported: true},
+		"nil":                     {au: nil},
+		"OAuthBearerToken":        {au: &auth.OAuthBearerToken{}},
+		"OAuthBearerTokenWithSSH": {au: &auth.OAuthBearerTokenWithSSH{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src, err := NewGerritSource(svc, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			css, err := src.WithAuthenticator(tc.au)
+			if tc.supported {
+				if err != nil {
+					t.Fatalf("unexpected non-nil error: %v", err)
+				}
+				if _, ok := css.(*GerritSource); !ok {
+					t.Fatalf("cannot coerce Source into GerritSource")
+				}
+				return
+			}
+			if !errors.HasType(err, UnsupportedAuthenticatorError{}) {
+				t.Fatalf("unexpected error of type %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestGerritSource_PreparePush(t *testing.T) {
+	ctx := context.Background()
+
+	var moved bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "POST /a/changes/platform%2Fbuild~7/move":
+			moved = true
+		case "GET /a/changes/platform%2Fbuild~7":
+			io.WriteString(w, `)]}'
+{"project": "platform/build", "branch": "release", "topic": "my-batch-change", "status": "NEW", "_number": 7}`)
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	src, err := NewGerritSource(&types.ExternalService{
+		Kind: extsvc.KindGerrit,
+		Config: marshalJSON(t, &schema.GerritConnection{
+			Url:      srv.URL,
+			Username: "admin",
+			Password: "secret",
+		}),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &types.Repo{
+		ExternalRepo: api.ExternalRepoSpec{ID: "platform/build", ServiceType: extsvc.TypeGerrit},
+		Metadata:     &gerrit.Project{Name: "platform/build"},
+	}
+	newRequest := func() *protocol.CreateCommitFromPatchRequest {
+		return &protocol.CreateCommitFromPatchRequest{
+			TargetRef:  "refs/heads/my-batch-change",
+			CommitInfo: protocol.PatchCommitInfo{Message: "Fix the build"},
+			Push:       &protocol.PushConfig{},
+		}
+	}
+
+	t.Run("new change", func(t *testing.T) {
+		cs := &Changeset{
+			BaseRef:   "refs/heads/main",
+			HeadRef:   "refs/heads/my-batch-change",
+			Repo:      repo,
+			Changeset: &btypes.Changeset{ID: 42},
+		}
+
+		opts := newRequest()
+		if err := src.PreparePush(ctx, cs, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := opts.Push.RemoteRef, "refs/for/main%topic=my-batch-change"; have != want {
+			t.Fatalf("wrong remote ref. want=%q, have=%q", want, have)
+		}
+		want := "Fix the build\n\nChange-Id: " + changeID(cs) + "\n"
+		if have := opts.CommitInfo.Message; have != want {
+			t.Fatalf("wrong commit message. want=%q, have=%q", want, have)
+		}
+
+		// Pushing the changeset again has to update the same change.
+		again := newRequest()
+		if err := src.PreparePush(ctx, cs, again); err != nil {
+			t.Fatal(err)
+		}
+		if again.CommitInfo.Message != opts.CommitInfo.Message {
+			t.Fatalf("Change-Id changed between pushes: %q and %q", opts.CommitInfo.Message, again.CommitInfo.Message)
+		}
+		if moved {
+			t.Fatal("new change was moved")
+		}
+	})
+
+	t.Run("changed base branch", func(t *testing.T) {
+		ch := &btypes.Changeset{ID: 42}
+		if err := ch.SetMetadata(&gerrit.Change{Project: "platform/build", Branch: "main", Topic: "my-batch-change", Status: gerrit.ChangeStatusNew, Number: 7}); err != nil {
+			t.Fatal(err)
+		}
+		cs := &Changeset{
+			BaseRef:   "refs/heads/release",
+			HeadRef:   "refs/heads/my-batch-change",
+			Repo:      repo,
+			Changeset: ch,
+		}
+
+		opts := newRequest()
+		if err := src.PreparePush(ctx, cs, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		if !moved {
+			t.Fatal("change wasn't moved to the new base branch")
+		}
+		if have, want := cs.Changeset.Metadata.(*gerrit.Change).Branch, "release"; have != want {
+			t.Fatalf("wrong branch. want=%q, have=%q", want, have)
+		}
+		if have := opts.Push.RemoteRef; !strings.HasPrefix(have, "refs/for/release%") {
+			t.Fatalf("wrong remote ref %q", have)
+		}
+	})
+}
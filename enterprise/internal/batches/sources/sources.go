@@ -3,11 +3,13 @@ package sources
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
 
 	"github.com/cockroachdb/errors"
 
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/codehostlimit"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/store"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/database"
@@ -61,12 +63,18 @@ type Sourcer interface {
 
 type sourcer struct {
 	cf *httpcli.Factory
+
+	// limiter limits the requests of the sources to each code host.
+	limiter *codehostlimit.Limiter
 }
 
-// NewSourcer returns a new Sourcer to be used in Batch Changes.
+// NewSourcer returns a new Sourcer to be used in Batch Changes. The requests of
+// its sources are subject to the batchChanges.codeHostRequestLimits site
+// configuration.
 func NewSourcer(cf *httpcli.Factory) Sourcer {
 	return &sourcer{
-		cf,
+		cf:      cf,
+		limiter: codehostlimit.Default(),
 	}
 }
 
@@ -110,7 +118,11 @@ func (s *sourcer) loadBatchesSource(ctx context.Context, tx SourcerStore, extern
 	if err != nil {
 		return nil, errors.Wrap(err, "loading external service")
 	}
-	css, err := buildChangesetSource(tx, s.cf, extSvc)
+	cf, err := withCodeHostLimit(s.cf, s.limiter, extSvc)
+	if err != nil {
+		return nil, errors.Wrap(err, "limiting code host requests")
+	}
+	css, err := buildChangesetSource(tx, cf, extSvc)
 	if err != nil {
 		return nil, errors.Wrap(err, "building changeset source")
 	}
@@ -126,6 +138,32 @@ func (s *sourcer) loadBatchesSource(ctx context.Context, tx SourcerStore, extern
 	return css, nil
 }
 
+// withCodeHostLimit returns a copy of cf whose requests wait for the limits of
+// the code host of the given external service. A request counts against the
+// concurrency limit until its response headers are received.
+func withCodeHostLimit(cf *httpcli.Factory, limiter *codehostlimit.Limiter, extSvc *types.ExternalService) (*httpcli.Factory, error) {
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+
+	codeHostURL, err := extsvc.UniqueCodeHostIdentifier(extSvc.Kind, extSvc.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return cf.WithMiddleware(func(cli httpcli.Doer) httpcli.Doer {
+		return httpcli.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			release, err := limiter.Acquire(req.Context(), codeHostURL)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+
+			return cli.Do(req)
+		})
+	}), nil
+}
+
 func gitserverPushConfig(ctx context.Context, store *database.ExternalServiceStore, repo *types.Repo, au auth.Authenticator) (*protocol.PushConfig, error) {
 	// Empty authenticators are not allowed.
 	if au == nil {
@@ -265,6 +303,14 @@ func loadExternalService(ctx context.Context, s *database.ExternalServiceStore,
 			if cfg.Token != "" {
 				return e, nil
 			}
+		case *schema.GerritConnection:
+			if cfg.Password != "" {
+				return e, nil
+			}
+		case *schema.BitbucketCloudConnection:
+			if cfg.AppPassword != "" {
+				return e, nil
+			}
 		}
 	}
 
@@ -282,6 +328,10 @@ func buildChangesetSource(store SourcerStore, cf *httpcli.Factory, externalServi
 		return NewGitLabSource(externalService, cf)
 	case extsvc.KindBitbucketServer:
 		return NewBitbucketServerSource(externalService, cf)
+	case extsvc.KindGerrit:
+		return NewGerritSource(externalService, cf)
+	case extsvc.KindBitbucketCloud:
+		return NewBitbucketCloudSource(externalService, cf)
 	default:
 		return nil, errors.Errorf("unsupported external service type %q", extsvc.KindToType(externalService.Kind))
 	}
@@ -334,6 +384,12 @@ func setOAuthTokenAuth(u *vcs.URL, extSvcType, token string) error {
 	case extsvc.TypeBitbucketServer:
 		return errors.New("require username/token to push commits to BitbucketServer")
 
+	case extsvc.TypeGerrit:
+		return errors.New("require username/HTTP password to push commits to Gerrit")
+
+	case extsvc.TypeBitbucketCloud:
+		return errors.New("require username/app password to push commits to Bitbucket Cloud")
+
 	default:
 		panic(fmt.Sprintf("setOAuthTokenAuth: invalid external service type %q", extSvcType))
 	}
@@ -347,7 +403,7 @@ func setBasicAuth(u *vcs.URL, extSvcType, username, password string) error {
 	case extsvc.TypeGitHub, extsvc.TypeGitLab:
 		return errors.New("need token to push commits to " + extSvcType)
 
-	case extsvc.TypeBitbucketServer:
+	case extsvc.TypeBitbucketServer, extsvc.TypeBitbucketCloud, extsvc.TypeGerrit:
 		u.User = url.UserPassword(username, password)
 
 	default:
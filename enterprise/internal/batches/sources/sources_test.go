@@ -3,10 +3,14 @@ package sources
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/batches/codehostlimit"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
@@ -16,7 +20,9 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestExtractCloneURL(t *testing.T) {
@@ -540,3 +546,71 @@ func TestGitserverPushConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestWithCodeHostLimit(t *testing.T) {
+	ctx := context.Background()
+
+	limiter := codehostlimit.NewLimiter([]*schema.BatchChangeCodeHostRequestLimit{
+		{Url: "https://github.example.com", Concurrency: 1},
+	})
+
+	var requests int
+	cf := httpcli.NewFactory(nil, func(cli *http.Client) error {
+		cli.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+		return nil
+	})
+
+	doer := func(t *testing.T, config string) httpcli.Doer {
+		t.Helper()
+		limited, err := withCodeHostLimit(cf, limiter, &types.ExternalService{Kind: extsvc.KindGitHub, Config: config})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli, err := limited.Doer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cli
+	}
+	do := func(ctx context.Context, cli httpcli.Doer) error {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.example.com/", nil)
+		_, err := cli.Do(req)
+		return err
+	}
+
+	limited := doer(t, `{"url": "https://github.example.com/", "token": "123"}`)
+	unlimited := doer(t, `{"url": "https://github.com/", "token": "123"}`)
+
+	release, err := limiter.Acquire(ctx, "https://github.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := do(timeoutCtx, limited); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request to code host at its limit not blocked: %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("blocked request was sent")
+	}
+
+	if err := do(ctx, unlimited); err != nil {
+		t.Fatal(err)
+	}
+
+	release()
+	if err := do(ctx, limited); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Fatalf("wrong number of requests sent. want=2, have=%d", requests)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
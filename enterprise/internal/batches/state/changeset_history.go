@@ -53,9 +53,11 @@ var RequiredEventTypesForHistory = []btypes.ChangesetEventKind{
 	btypes.ChangesetEventKindGitHubConvertToDraft,
 	btypes.ChangesetEventKindGitHubClosed,
 	btypes.ChangesetEventKindBitbucketServerDeclined,
+	btypes.ChangesetEventKindBitbucketCloudRejected,
 	btypes.ChangesetEventKindGitLabClosed,
 	btypes.ChangesetEventKindGitHubMerged,
 	btypes.ChangesetEventKindBitbucketServerMerged,
+	btypes.ChangesetEventKindBitbucketCloudFulfilled,
 	btypes.ChangesetEventKindGitLabMerged,
 	btypes.ChangesetEventKindGitHubReopened,
 	btypes.ChangesetEventKindBitbucketServerReopened,
@@ -63,9 +65,13 @@ var RequiredEventTypesForHistory = []btypes.ChangesetEventKind{
 	btypes.ChangesetEventKindGitHubReviewed,
 	btypes.ChangesetEventKindBitbucketServerApproved,
 	btypes.ChangesetEventKindBitbucketServerReviewed,
+	btypes.ChangesetEventKindBitbucketCloudApproved,
+	btypes.ChangesetEventKindBitbucketCloudChangesRequestCreated,
 	btypes.ChangesetEventKindGitLabApproved,
 	btypes.ChangesetEventKindBitbucketServerUnapproved,
 	btypes.ChangesetEventKindBitbucketServerDismissed,
+	btypes.ChangesetEventKindBitbucketCloudUnapproved,
+	btypes.ChangesetEventKindBitbucketCloudChangesRequestRemoved,
 	btypes.ChangesetEventKindGitLabUnapproved,
 }
 
@@ -121,6 +127,7 @@ func computeHistory(ch *btypes.Changeset, ce ChangesetEvents) (changesetHistory,
 		switch e.Kind {
 		case btypes.ChangesetEventKindGitHubClosed,
 			btypes.ChangesetEventKindBitbucketServerDeclined,
+			btypes.ChangesetEventKindBitbucketCloudRejected,
 			btypes.ChangesetEventKindGitLabClosed:
 			// Merged is a final state. We can ignore everything after.
 			if currentExtState != btypes.ChangesetExternalStateMerged {
@@ -130,6 +137,7 @@ func computeHistory(ch *btypes.Changeset, ce ChangesetEvents) (changesetHistory,
 
 		case btypes.ChangesetEventKindGitHubMerged,
 			btypes.ChangesetEventKindBitbucketServerMerged,
+			btypes.ChangesetEventKindBitbucketCloudFulfilled,
 			btypes.ChangesetEventKindGitLabMerged:
 			currentExtState = btypes.ChangesetExternalStateMerged
 			pushStates(et)
@@ -175,6 +183,8 @@ func computeHistory(ch *btypes.Changeset, ce ChangesetEvents) (changesetHistory,
 		case btypes.ChangesetEventKindGitHubReviewed,
 			btypes.ChangesetEventKindBitbucketServerApproved,
 			btypes.ChangesetEventKindBitbucketServerReviewed,
+			btypes.ChangesetEventKindBitbucketCloudApproved,
+			btypes.ChangesetEventKindBitbucketCloudChangesRequestCreated,
 			btypes.ChangesetEventKindGitLabApproved:
 
 			s, err := e.ReviewState()
@@ -217,6 +227,8 @@ func computeHistory(ch *btypes.Changeset, ce ChangesetEvents) (changesetHistory,
 
 		case btypes.ChangesetEventKindBitbucketServerUnapproved,
 			btypes.ChangesetEventKindBitbucketServerDismissed,
+			btypes.ChangesetEventKindBitbucketCloudUnapproved,
+			btypes.ChangesetEventKindBitbucketCloudChangesRequestRemoved,
 			btypes.ChangesetEventKindGitLabUnapproved:
 			author := e.ReviewAuthor()
 			// If the user has been deleted, skip their reviews, as they don't count towards the final state anymore.
@@ -244,6 +256,26 @@ func computeHistory(ch *btypes.Changeset, ce ChangesetEvents) (changesetHistory,
 				}
 			}
 
+			if e.Type() == btypes.ChangesetEventKindBitbucketCloudUnapproved {
+				// A Bitbucket Cloud unapproval can only follow a previous
+				// approval by the same author.
+				lastReview, ok := lastReviewByAuthor[author]
+				if !ok || lastReview != btypes.ChangesetReviewStateApproved {
+					log15.Warn("Bitbucket Cloud unapproval not following an approval", "event", e)
+					continue
+				}
+			}
+
+			if e.Type() == btypes.ChangesetEventKindBitbucketCloudChangesRequestRemoved {
+				// A removed Bitbucket Cloud change request can only follow a
+				// previous change request by the same author.
+				lastReview, ok := lastReviewByAuthor[author]
+				if !ok || lastReview != btypes.ChangesetReviewStateChangesRequested {
+					log15.Warn("Bitbucket Cloud change request removal not following a change request", "event", e)
+					continue
+				}
+			}
+
 			// Save current review state, then remove last approval and
 			// recompute overall review state
 			oldReviewState := currentReviewState
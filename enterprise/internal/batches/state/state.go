@@ -16,7 +16,9 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
@@ -103,8 +105,14 @@ func computeCheckState(c *btypes.Changeset, events ChangesetEvents) btypes.Chang
 	case *bitbucketserver.PullRequest:
 		return computeBitbucketBuildStatus(c.UpdatedAt, m, events)
 
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return computeBitbucketCloudBuildStatus(c.UpdatedAt, m, events)
+
 	case *gitlab.MergeRequest:
 		return computeGitLabCheckState(c.UpdatedAt, m, events)
+
+	case *gerrit.Change:
+		return computeGerritCheckState(m)
 	}
 
 	return btypes.ChangesetCheckStateUnknown
@@ -197,6 +205,54 @@ func parseBitbucketBuildState(s string) btypes.ChangesetCheckState {
 	}
 }
 
+func computeBitbucketCloudBuildStatus(lastSynced time.Time, pr *bitbucketcloud.AnnotatedPullRequest, events []*btypes.ChangesetEvent) btypes.ChangesetCheckState {
+	// The pull request only contains the abbreviated hash of its head commit,
+	// while statuses reference the full hash.
+	isHead := func(commit string) bool {
+		return pr.Source.Commit.Hash != "" && strings.HasPrefix(commit, pr.Source.Commit.Hash)
+	}
+
+	stateMap := make(map[string]btypes.ChangesetCheckState)
+
+	// States from last sync
+	for _, status := range pr.Statuses {
+		if isHead(status.Commit) {
+			stateMap[status.Key()] = parseBitbucketCloudBuildState(status.Status.State)
+		}
+	}
+
+	// Add any events we've received since our last sync
+	for _, e := range events {
+		switch m := e.Metadata.(type) {
+		case *bitbucketcloud.CommitStatus:
+			if !isHead(m.Commit) || m.Status.UpdatedOn.Before(lastSynced) {
+				continue
+			}
+			stateMap[m.Key()] = parseBitbucketCloudBuildState(m.Status.State)
+		}
+	}
+
+	states := make([]btypes.ChangesetCheckState, 0, len(stateMap))
+	for _, v := range stateMap {
+		states = append(states, v)
+	}
+
+	return combineCheckStates(states)
+}
+
+func parseBitbucketCloudBuildState(s bitbucketcloud.BuildStatusState) btypes.ChangesetCheckState {
+	switch s {
+	case bitbucketcloud.BuildStatusStateFailed, bitbucketcloud.BuildStatusStateStopped:
+		return btypes.ChangesetCheckStateFailed
+	case bitbucketcloud.BuildStatusStateInProgress:
+		return btypes.ChangesetCheckStatePending
+	case bitbucketcloud.BuildStatusStateSuccessful:
+		return btypes.ChangesetCheckStatePassed
+	default:
+		return btypes.ChangesetCheckStateUnknown
+	}
+}
+
 func computeGitHubCheckState(lastSynced time.Time, pr *github.PullRequest, events []*btypes.ChangesetEvent) btypes.ChangesetCheckState {
 	// We should only consider the latest commit. This could be from a sync or a webhook that
 	// has occurred later
@@ -415,6 +471,30 @@ func parseGitLabPipelineStatus(status gitlab.PipelineStatus) btypes.ChangesetChe
 	}
 }
 
+// gerritVerifiedLabel is the label CI systems vote on in Gerrit.
+const gerritVerifiedLabel = "Verified"
+
+// gerritCodeReviewLabel is the label reviewers vote on in Gerrit.
+const gerritCodeReviewLabel = "Code-Review"
+
+func computeGerritCheckState(ch *gerrit.Change) btypes.ChangesetCheckState {
+	// Gerrit doesn't have a notion of checks, but CI systems integrated with
+	// Gerrit report their results by voting on the Verified label.
+	label, ok := ch.Labels[gerritVerifiedLabel]
+	if !ok {
+		return btypes.ChangesetCheckStateUnknown
+	}
+
+	switch {
+	case label.Rejected != nil || label.Disliked != nil:
+		return btypes.ChangesetCheckStateFailed
+	case label.Approved != nil || label.Recommended != nil:
+		return btypes.ChangesetCheckStatePassed
+	default:
+		return btypes.ChangesetCheckStatePending
+	}
+}
+
 // computeSingleChangesetExternalState of a Changeset based on the metadata.
 // It does NOT reflect the final calculated state, use `ExternalState` instead.
 func computeSingleChangesetExternalState(c *btypes.Changeset) (s btypes.ChangesetExternalState, err error) {
@@ -435,6 +515,17 @@ func computeSingleChangesetExternalState(c *btypes.Changeset) (s btypes.Changese
 		} else {
 			s = btypes.ChangesetExternalState(m.State)
 		}
+	case *bitbucketcloud.AnnotatedPullRequest:
+		switch m.State {
+		case bitbucketcloud.PullRequestStateDeclined, bitbucketcloud.PullRequestStateSuperseded:
+			s = btypes.ChangesetExternalStateClosed
+		case bitbucketcloud.PullRequestStateMerged:
+			s = btypes.ChangesetExternalStateMerged
+		case bitbucketcloud.PullRequestStateOpen:
+			s = btypes.ChangesetExternalStateOpen
+		default:
+			return "", errors.Errorf("unknown Bitbucket Cloud pull request state: %s", m.State)
+		}
 	case *gitlab.MergeRequest:
 		switch m.State {
 		case gitlab.MergeRequestStateClosed, gitlab.MergeRequestStateLocked:
@@ -450,6 +541,21 @@ func computeSingleChangesetExternalState(c *btypes.Changeset) (s btypes.Changese
 		default:
 			return "", errors.Errorf("unknown GitLab merge request state: %s", m.State)
 		}
+	case *gerrit.Change:
+		switch m.Status {
+		case gerrit.ChangeStatusAbandoned:
+			s = btypes.ChangesetExternalStateClosed
+		case gerrit.ChangeStatusMerged:
+			s = btypes.ChangesetExternalStateMerged
+		case gerrit.ChangeStatusNew:
+			if m.WorkInProgress {
+				s = btypes.ChangesetExternalStateDraft
+			} else {
+				s = btypes.ChangesetExternalStateOpen
+			}
+		default:
+			return "", errors.Errorf("unknown Gerrit change status: %s", m.Status)
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -486,6 +592,20 @@ func computeSingleChangesetReviewState(c *btypes.Changeset) (s btypes.ChangesetR
 			}
 		}
 
+	case *bitbucketcloud.AnnotatedPullRequest:
+		for _, p := range m.Participants {
+			switch p.State {
+			case bitbucketcloud.ParticipantStateChangesRequested:
+				states[btypes.ChangesetReviewStateChangesRequested] = true
+			case bitbucketcloud.ParticipantStateApproved:
+				states[btypes.ChangesetReviewStateApproved] = true
+			default:
+				if p.Role == bitbucketcloud.ParticipantRoleReviewer {
+					states[btypes.ChangesetReviewStatePending] = true
+				}
+			}
+		}
+
 	case *gitlab.MergeRequest:
 		// GitLab has an elaborate approvers workflow, but this doesn't map
 		// terribly closely to the GitHub/Bitbucket workflow: most notably,
@@ -507,6 +627,18 @@ func computeSingleChangesetReviewState(c *btypes.Changeset) (s btypes.ChangesetR
 		}
 		return btypes.ChangesetReviewStatePending, nil
 
+	case *gerrit.Change:
+		// Negative votes on the Code-Review label block a change from being
+		// submitted, so they're treated like requested changes, while only
+		// the maximum vote approves the change.
+		label := m.Labels[gerritCodeReviewLabel]
+		if label.Rejected != nil || label.Disliked != nil {
+			states[btypes.ChangesetReviewStateChangesRequested] = true
+		}
+		if label.Approved != nil {
+			states[btypes.ChangesetReviewStateApproved] = true
+		}
+
 	default:
 		return "", errors.New("unknown changeset type")
 	}
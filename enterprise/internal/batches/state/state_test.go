@@ -9,7 +9,9 @@ import (
 
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/timeutil"
@@ -276,6 +278,81 @@ func TestComputeBitbucketBuildStatus(t *testing.T) {
 	}
 }
 
+func TestComputeBitbucketCloudBuildStatus(t *testing.T) {
+	t.Parallel()
+
+	now := timeutil.Now()
+	lastSynced := now.Add(-1 * time.Minute)
+	sha := "ffad59b0cbd5e5b5fda3bc7ba1bcc4ddb1a4cc8a"
+
+	status := func(commit, key string, state bitbucketcloud.BuildStatusState, updatedOn time.Time) *bitbucketcloud.CommitStatus {
+		return &bitbucketcloud.CommitStatus{
+			Commit: commit,
+			Status: bitbucketcloud.BuildStatus{Key: key, State: state, UpdatedOn: updatedOn},
+		}
+	}
+	statusEvent := func(commit, key string, state bitbucketcloud.BuildStatusState) *btypes.ChangesetEvent {
+		return &btypes.ChangesetEvent{
+			Kind:     btypes.ChangesetEventKindBitbucketCloudCommitStatus,
+			Metadata: status(commit, key, state, now),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		statuses []*bitbucketcloud.CommitStatus
+		events   []*btypes.ChangesetEvent
+		want     btypes.ChangesetCheckState
+	}{
+		{
+			name: "no statuses",
+			want: btypes.ChangesetCheckStateUnknown,
+		},
+		{
+			name:     "synced success",
+			statuses: []*bitbucketcloud.CommitStatus{status(sha, "build", bitbucketcloud.BuildStatusStateSuccessful, lastSynced)},
+			want:     btypes.ChangesetCheckStatePassed,
+		},
+		{
+			name:     "synced stopped",
+			statuses: []*bitbucketcloud.CommitStatus{status(sha, "build", bitbucketcloud.BuildStatusStateStopped, lastSynced)},
+			want:     btypes.ChangesetCheckStateFailed,
+		},
+		{
+			name:     "status of other commit",
+			statuses: []*bitbucketcloud.CommitStatus{status("0123456789", "build", bitbucketcloud.BuildStatusStateFailed, lastSynced)},
+			want:     btypes.ChangesetCheckStateUnknown,
+		},
+		{
+			name:     "events newer than sync have precedence",
+			statuses: []*bitbucketcloud.CommitStatus{status(sha, "build", bitbucketcloud.BuildStatusStateInProgress, lastSynced)},
+			events: []*btypes.ChangesetEvent{
+				statusEvent(sha, "build", bitbucketcloud.BuildStatusStateSuccessful),
+				statusEvent(sha, "lint", bitbucketcloud.BuildStatusStateFailed),
+			},
+			want: btypes.ChangesetCheckStateFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := &bitbucketcloud.AnnotatedPullRequest{
+				PullRequest: &bitbucketcloud.PullRequest{
+					Source: bitbucketcloud.PullRequestEndpoint{
+						Commit: bitbucketcloud.PullRequestCommit{Hash: sha[:12]},
+					},
+				},
+				Statuses: tc.statuses,
+			}
+
+			have := computeBitbucketCloudBuildStatus(lastSynced, pr, tc.events)
+			if diff := cmp.Diff(tc.want, have); diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
 func TestComputeGitLabCheckState(t *testing.T) {
 	t.Parallel()
 
@@ -406,6 +483,41 @@ func TestComputeGitLabCheckState(t *testing.T) {
 	})
 }
 
+func TestComputeGerritCheckState(t *testing.T) {
+	t.Parallel()
+
+	account := &gerrit.Account{AccountID: 1000000}
+
+	for name, tc := range map[string]struct {
+		labels map[string]gerrit.Label
+		want   btypes.ChangesetCheckState
+	}{
+		"no verified label": {
+			labels: map[string]gerrit.Label{"Code-Review": {Approved: account}},
+			want:   btypes.ChangesetCheckStateUnknown,
+		},
+		"no votes": {
+			labels: map[string]gerrit.Label{"Verified": {}},
+			want:   btypes.ChangesetCheckStatePending,
+		},
+		"verified": {
+			labels: map[string]gerrit.Label{"Verified": {Approved: account}},
+			want:   btypes.ChangesetCheckStatePassed,
+		},
+		"failed": {
+			labels: map[string]gerrit.Label{"Verified": {Approved: account, Rejected: account}},
+			want:   btypes.ChangesetCheckStateFailed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			have := computeGerritCheckState(&gerrit.Change{Labels: tc.labels})
+			if have != tc.want {
+				t.Errorf("unexpected check state: have %s; want %s", have, tc.want)
+			}
+		})
+	}
+}
+
 func TestComputeReviewState(t *testing.T) {
 	t.Parallel()
 
@@ -535,6 +647,54 @@ func TestComputeReviewState(t *testing.T) {
 			},
 			want: btypes.ChangesetReviewStateChangesRequested,
 		},
+		{
+			name:      "gerrit - no votes",
+			changeset: gerritChangeset(daysAgo(10), gerrit.ChangeStatusNew, nil),
+			history:   []changesetStatesAtTime{},
+			want:      btypes.ChangesetReviewStatePending,
+		},
+		{
+			name: "gerrit - approved",
+			changeset: gerritChangeset(daysAgo(10), gerrit.ChangeStatusNew, map[string]gerrit.Label{
+				"Code-Review": {Approved: &gerrit.Account{AccountID: 1}},
+			}),
+			history: []changesetStatesAtTime{},
+			want:    btypes.ChangesetReviewStateApproved,
+		},
+		{
+			name: "gerrit - negative vote",
+			changeset: gerritChangeset(daysAgo(10), gerrit.ChangeStatusNew, map[string]gerrit.Label{
+				"Code-Review": {Approved: &gerrit.Account{AccountID: 1}, Disliked: &gerrit.Account{AccountID: 2}},
+			}),
+			history: []changesetStatesAtTime{},
+			want:    btypes.ChangesetReviewStateChangesRequested,
+		},
+		{
+			name: "bitbucketcloud - approved",
+			changeset: bitbucketCloudChangeset(daysAgo(10), bitbucketcloud.PullRequestStateOpen, []bitbucketcloud.Participant{
+				{Role: bitbucketcloud.ParticipantRoleReviewer, State: bitbucketcloud.ParticipantStateApproved},
+				{Role: bitbucketcloud.ParticipantRoleParticipant},
+			}),
+			history: []changesetStatesAtTime{},
+			want:    btypes.ChangesetReviewStateApproved,
+		},
+		{
+			name: "bitbucketcloud - changes requested",
+			changeset: bitbucketCloudChangeset(daysAgo(10), bitbucketcloud.PullRequestStateOpen, []bitbucketcloud.Participant{
+				{Role: bitbucketcloud.ParticipantRoleReviewer, State: bitbucketcloud.ParticipantStateApproved},
+				{Role: bitbucketcloud.ParticipantRoleReviewer, State: bitbucketcloud.ParticipantStateChangesRequested},
+			}),
+			history: []changesetStatesAtTime{},
+			want:    btypes.ChangesetReviewStateChangesRequested,
+		},
+		{
+			name:      "bitbucketcloud - changeset older than events",
+			changeset: bitbucketCloudChangeset(daysAgo(10), bitbucketcloud.PullRequestStateOpen, nil),
+			history: []changesetStatesAtTime{
+				{t: daysAgo(0), reviewState: btypes.ChangesetReviewStateApproved},
+			},
+			want: btypes.ChangesetReviewStateApproved,
+		},
 	}
 
 	for i, tc := range tests {
@@ -715,6 +875,44 @@ func TestComputeExternalState(t *testing.T) {
 			},
 			want: btypes.ChangesetExternalStateDraft,
 		},
+		{
+			name:      "gerrit - no events",
+			changeset: gerritChangeset(daysAgo(10), gerrit.ChangeStatusNew, nil),
+			history:   []changesetStatesAtTime{},
+			want:      btypes.ChangesetExternalStateOpen,
+		},
+		{
+			name:      "gerrit - abandoned",
+			changeset: gerritChangeset(daysAgo(10), gerrit.ChangeStatusAbandoned, nil),
+			history:   []changesetStatesAtTime{},
+			want:      btypes.ChangesetExternalStateClosed,
+		},
+		{
+			name:      "gerrit - merged",
+			changeset: gerritChangeset(daysAgo(10), gerrit.ChangeStatusMerged, nil),
+			history:   []changesetStatesAtTime{},
+			want:      btypes.ChangesetExternalStateMerged,
+		},
+		{
+			name:      "gerrit draft - no events",
+			changeset: setDraft(gerritChangeset(daysAgo(10), gerrit.ChangeStatusNew, nil)),
+			history:   []changesetStatesAtTime{},
+			want:      btypes.ChangesetExternalStateDraft,
+		},
+		{
+			name:      "bitbucketcloud - declined",
+			changeset: bitbucketCloudChangeset(daysAgo(10), bitbucketcloud.PullRequestStateDeclined, nil),
+			history:   []changesetStatesAtTime{},
+			want:      btypes.ChangesetExternalStateClosed,
+		},
+		{
+			name:      "bitbucketcloud - changeset older than events",
+			changeset: bitbucketCloudChangeset(daysAgo(10), bitbucketcloud.PullRequestStateOpen, nil),
+			history: []changesetStatesAtTime{
+				{t: daysAgo(0), externalState: btypes.ChangesetExternalStateMerged},
+			},
+			want: btypes.ChangesetExternalStateMerged,
+		},
 	}
 
 	for i, tc := range tests {
@@ -865,6 +1063,30 @@ func gitLabChangeset(updatedAt time.Time, state gitlab.MergeRequestState, notes
 	}
 }
 
+func gerritChangeset(updatedAt time.Time, status gerrit.ChangeStatus, labels map[string]gerrit.Label) *btypes.Changeset {
+	return &btypes.Changeset{
+		ExternalServiceType: extsvc.TypeGerrit,
+		UpdatedAt:           updatedAt,
+		Metadata: &gerrit.Change{
+			Status: status,
+			Labels: labels,
+		},
+	}
+}
+
+func bitbucketCloudChangeset(updatedAt time.Time, state bitbucketcloud.PullRequestState, participants []bitbucketcloud.Participant) *btypes.Changeset {
+	return &btypes.Changeset{
+		ExternalServiceType: extsvc.TypeBitbucketCloud,
+		UpdatedAt:           updatedAt,
+		Metadata: &bitbucketcloud.AnnotatedPullRequest{
+			PullRequest: &bitbucketcloud.PullRequest{
+				State:        state,
+				Participants: participants,
+			},
+		},
+	}
+}
+
 func setDeletedAt(c *btypes.Changeset, deletedAt time.Time) *btypes.Changeset {
 	c.ExternalDeletedAt = deletedAt
 	return c
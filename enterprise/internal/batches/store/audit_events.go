@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// auditEventColumns are used by the audit event related Store methods to
+// query audit events.
+var auditEventColumns = []*sqlf.Query{
+	sqlf.Sprintf("batch_changes_audit_events.id"),
+	sqlf.Sprintf("batch_changes_audit_events.actor_user_id"),
+	sqlf.Sprintf("batch_changes_audit_events.action"),
+	sqlf.Sprintf("batch_changes_audit_events.resource_type"),
+	sqlf.Sprintf("batch_changes_audit_events.resource_id"),
+	sqlf.Sprintf("batch_changes_audit_events.metadata"),
+	sqlf.Sprintf("batch_changes_audit_events.created_at"),
+}
+
+// CreateAuditEvents records the given audit events. Audit events can't be
+// updated or deleted once they are created.
+func (s *Store) CreateAuditEvents(ctx context.Context, es ...*btypes.AuditEvent) (err error) {
+	ctx, endObservation := s.operations.createAuditEvents.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("count", len(es)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	for _, e := range es {
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = s.now()
+		}
+
+		metadata := e.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		raw, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+
+		q := sqlf.Sprintf(
+			createAuditEventQueryFmtstr,
+			nullInt32Column(e.ActorUserID),
+			e.Action.ToDB(),
+			e.ResourceType.ToDB(),
+			e.ResourceID,
+			raw,
+			e.CreatedAt,
+			sqlf.Join(auditEventColumns, ", "),
+		)
+		if err := s.query(ctx, q, func(sc scanner) error { return scanAuditEvent(e, sc) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var createAuditEventQueryFmtstr = `
+-- source: enterprise/internal/batches/store/audit_events.go:CreateAuditEvents
+INSERT INTO batch_changes_audit_events (actor_user_id, action, resource_type, resource_id, metadata, created_at)
+VALUES (%s, %s, %s, %s, %s, %s)
+RETURNING %s
+`
+
+// ListAuditEventsOpts captures the query options needed for listing audit
+// events.
+type ListAuditEventsOpts struct {
+	LimitOpts
+	// Cursor, if set, only returns audit events with an ID less than or equal
+	// to it.
+	Cursor int64
+
+	ActorUserID  int32
+	Action       btypes.AuditEventAction
+	ResourceType btypes.AuditEventResourceType
+	ResourceID   int64
+}
+
+// ListAuditEvents lists the audit events matching the given options, newest
+// first.
+func (s *Store) ListAuditEvents(ctx context.Context, opts ListAuditEventsOpts) (es []*btypes.AuditEvent, next int64, err error) {
+	ctx, endObservation := s.operations.listAuditEvents.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int64("cursor", opts.Cursor),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		listAuditEventsQueryFmtstr+opts.LimitOpts.ToDB(),
+		sqlf.Join(auditEventColumns, ", "),
+		sqlf.Join(auditEventsPreds(opts.Cursor, opts.ActorUserID, opts.Action, opts.ResourceType, opts.ResourceID), "\n AND "),
+	)
+
+	es = make([]*btypes.AuditEvent, 0, opts.DBLimit())
+	err = s.query(ctx, q, func(sc scanner) error {
+		var e btypes.AuditEvent
+		if err := scanAuditEvent(&e, sc); err != nil {
+			return err
+		}
+		es = append(es, &e)
+		return nil
+	})
+
+	if opts.Limit != 0 && len(es) == opts.DBLimit() {
+		next = es[len(es)-1].ID
+		es = es[:len(es)-1]
+	}
+
+	return es, next, err
+}
+
+var listAuditEventsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/audit_events.go:ListAuditEvents
+SELECT %s FROM batch_changes_audit_events
+WHERE %s
+ORDER BY id DESC
+`
+
+// CountAuditEventsOpts captures the query options needed for counting audit
+// events.
+type CountAuditEventsOpts struct {
+	ActorUserID  int32
+	Action       btypes.AuditEventAction
+	ResourceType btypes.AuditEventResourceType
+	ResourceID   int64
+}
+
+// CountAuditEvents returns the number of audit events matching the given
+// options.
+func (s *Store) CountAuditEvents(ctx context.Context, opts CountAuditEventsOpts) (count int, err error) {
+	ctx, endObservation := s.operations.countAuditEvents.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		countAuditEventsQueryFmtstr,
+		sqlf.Join(auditEventsPreds(0, opts.ActorUserID, opts.Action, opts.ResourceType, opts.ResourceID), "\n AND "),
+	)
+	return s.queryCount(ctx, q)
+}
+
+var countAuditEventsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/audit_events.go:CountAuditEvents
+SELECT COUNT(id) FROM batch_changes_audit_events
+WHERE %s
+`
+
+func auditEventsPreds(cursor int64, actorUserID int32, action btypes.AuditEventAction, resourceType btypes.AuditEventResourceType, resourceID int64) []*sqlf.Query {
+	preds := []*sqlf.Query{sqlf.Sprintf("TRUE")}
+
+	if cursor != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_changes_audit_events.id <= %s", cursor))
+	}
+	if actorUserID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_changes_audit_events.actor_user_id = %s", actorUserID))
+	}
+	if action != "" {
+		preds = append(preds, sqlf.Sprintf("batch_changes_audit_events.action = %s", action.ToDB()))
+	}
+	if resourceType != "" {
+		preds = append(preds, sqlf.Sprintf("batch_changes_audit_events.resource_type = %s", resourceType.ToDB()))
+	}
+	if resourceID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_changes_audit_events.resource_id = %s", resourceID))
+	}
+
+	return preds
+}
+
+func scanAuditEvent(e *btypes.AuditEvent, sc scanner) error {
+	var (
+		action, resourceType string
+		metadata             json.RawMessage
+	)
+	if err := sc.Scan(
+		&e.ID,
+		&dbutil.NullInt32{N: &e.ActorUserID},
+		&action,
+		&resourceType,
+		&e.ResourceID,
+		&metadata,
+		&e.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	e.Action = btypes.AuditEventAction(strings.ToUpper(action))
+	e.ResourceType = btypes.AuditEventResourceType(strings.ToUpper(resourceType))
+	return json.Unmarshal(metadata, &e.Metadata)
+}
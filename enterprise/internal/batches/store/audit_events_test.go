@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/keegancsmith/sqlf"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+func testStoreAuditEvents(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	events := []*btypes.AuditEvent{
+		{
+			ActorUserID:  1,
+			Action:       btypes.AuditEventActionCreated,
+			ResourceType: btypes.AuditEventResourceTypeBatchSpec,
+			ResourceID:   10,
+		},
+		{
+			ActorUserID:  1,
+			Action:       btypes.AuditEventActionCreated,
+			ResourceType: btypes.AuditEventResourceTypeBatchSpecResolutionJob,
+			ResourceID:   20,
+			Metadata:     map[string]string{"batch_spec_id": "10"},
+		},
+		{
+			ActorUserID:  2,
+			Action:       btypes.AuditEventActionRetried,
+			ResourceType: btypes.AuditEventResourceTypeChangeset,
+			ResourceID:   30,
+		},
+		{
+			Action:       btypes.AuditEventActionDeleted,
+			ResourceType: btypes.AuditEventResourceTypeBatchSpec,
+			ResourceID:   10,
+		},
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		if err := s.CreateAuditEvents(ctx, events...); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range events {
+			if e.ID == 0 {
+				t.Fatal("audit event has no ID")
+			}
+			if have, want := e.CreatedAt, clock.Now(); !have.Equal(want) {
+				t.Fatalf("audit event has wrong CreatedAt. want=%s, have=%s", want, have)
+			}
+			if e.Metadata == nil {
+				t.Fatal("audit event has nil metadata")
+			}
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		have, next, err := s.ListAuditEvents(ctx, ListAuditEventsOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != 0 {
+			t.Fatalf("unexpected next cursor %d", next)
+		}
+		want := []*btypes.AuditEvent{events[3], events[2], events[1], events[0]}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatal(diff)
+		}
+
+		t.Run("Pagination", func(t *testing.T) {
+			var cursor int64
+			for i := len(events) - 1; i >= 0; i-- {
+				opts := ListAuditEventsOpts{LimitOpts: LimitOpts{Limit: 1}, Cursor: cursor}
+				have, next, err := s.ListAuditEvents(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff([]*btypes.AuditEvent{events[i]}, have); diff != "" {
+					t.Fatal(diff)
+				}
+				cursor = next
+			}
+			if cursor != 0 {
+				t.Fatalf("unexpected next cursor %d after last page", cursor)
+			}
+		})
+
+		for name, tc := range map[string]struct {
+			opts ListAuditEventsOpts
+			want []*btypes.AuditEvent
+		}{
+			"by actor": {
+				opts: ListAuditEventsOpts{ActorUserID: 1},
+				want: []*btypes.AuditEvent{events[1], events[0]},
+			},
+			"by action": {
+				opts: ListAuditEventsOpts{Action: btypes.AuditEventActionRetried},
+				want: []*btypes.AuditEvent{events[2]},
+			},
+			"by resource": {
+				opts: ListAuditEventsOpts{ResourceType: btypes.AuditEventResourceTypeBatchSpec, ResourceID: 10},
+				want: []*btypes.AuditEvent{events[3], events[0]},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				have, _, err := s.ListAuditEvents(ctx, tc.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.want, have); diff != "" {
+					t.Fatal(diff)
+				}
+
+				count, err := s.CountAuditEvents(ctx, CountAuditEventsOpts{
+					ActorUserID:  tc.opts.ActorUserID,
+					Action:       tc.opts.Action,
+					ResourceType: tc.opts.ResourceType,
+					ResourceID:   tc.opts.ResourceID,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != len(tc.want) {
+					t.Fatalf("wrong count. want=%d, have=%d", len(tc.want), count)
+				}
+			})
+		}
+	})
+
+	t.Run("Append-only", func(t *testing.T) {
+		for _, q := range []*sqlf.Query{
+			sqlf.Sprintf("UPDATE batch_changes_audit_events SET actor_user_id = 3 WHERE id = %s", events[0].ID),
+			sqlf.Sprintf("DELETE FROM batch_changes_audit_events WHERE id = %s", events[0].ID),
+		} {
+			// The failing statement aborts the transaction it runs in, so run
+			// it in a savepoint to keep the outer transaction usable.
+			tx, err := s.Transact(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Done(tx.Exec(ctx, q)); err == nil {
+				t.Fatalf("audit event was modified by %q", q.Query(sqlf.PostgresBindVar))
+			}
+		}
+
+		count, err := s.CountAuditEvents(ctx, CountAuditEventsOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(events) {
+			t.Fatalf("wrong count. want=%d, have=%d", len(events), count)
+		}
+	})
+}
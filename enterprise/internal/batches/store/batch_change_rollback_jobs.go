@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// BatchChangeRollbackJobColumns are used by the rollback job related Store
+// methods and by the rollback worker to query rollback jobs.
+var BatchChangeRollbackJobColumns = SQLColumns{
+	"batch_change_rollback_jobs.id",
+	"batch_change_rollback_jobs.batch_change_id",
+	"batch_change_rollback_jobs.user_id",
+	"batch_change_rollback_jobs.state",
+	"batch_change_rollback_jobs.failure_message",
+	"batch_change_rollback_jobs.started_at",
+	"batch_change_rollback_jobs.finished_at",
+	"batch_change_rollback_jobs.process_after",
+	"batch_change_rollback_jobs.num_resets",
+	"batch_change_rollback_jobs.num_failures",
+	"batch_change_rollback_jobs.created_at",
+	"batch_change_rollback_jobs.updated_at",
+}
+
+// CreateBatchChangeRollbackJob enqueues the given rollback job. If the batch
+// change is already being rolled back, no new job is created and the given
+// job is set to the active one instead.
+func (s *Store) CreateBatchChangeRollbackJob(ctx context.Context, job *btypes.BatchChangeRollbackJob) (err error) {
+	ctx, endObservation := s.operations.createBatchChangeRollbackJob.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchChangeID", int(job.BatchChangeID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	// Lock the batch change, so that concurrent rollbacks of it are
+	// serialized and can't both miss each other's job.
+	if err := tx.exec(ctx, sqlf.Sprintf(lockBatchChangeForRollbackQueryFmtstr, job.BatchChangeID)); err != nil {
+		return err
+	}
+
+	existing, err := tx.GetBatchChangeRollbackJob(ctx, GetBatchChangeRollbackJobOpts{BatchChangeID: job.BatchChangeID})
+	if err != nil && err != ErrNoResults {
+		return err
+	}
+	if existing != nil && existing.State != btypes.BatchChangeRollbackJobStateCompleted && existing.State != btypes.BatchChangeRollbackJobStateFailed {
+		*job = *existing
+		return nil
+	}
+
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = s.now()
+	}
+	if job.UpdatedAt.IsZero() {
+		job.UpdatedAt = job.CreatedAt
+	}
+
+	q := sqlf.Sprintf(
+		createBatchChangeRollbackJobQueryFmtstr,
+		job.BatchChangeID,
+		nullInt32Column(job.UserID),
+		btypes.BatchChangeRollbackJobStateQueued.ToDB(),
+		job.CreatedAt,
+		job.UpdatedAt,
+		sqlf.Join(BatchChangeRollbackJobColumns.ToSqlf(), ", "),
+	)
+	return tx.query(ctx, q, func(sc scanner) error { return scanBatchChangeRollbackJob(job, sc) })
+}
+
+var lockBatchChangeForRollbackQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_change_rollback_jobs.go:CreateBatchChangeRollbackJob
+SELECT 1 FROM batch_changes WHERE id = %s FOR NO KEY UPDATE
+`
+
+var createBatchChangeRollbackJobQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_change_rollback_jobs.go:CreateBatchChangeRollbackJob
+INSERT INTO batch_change_rollback_jobs (batch_change_id, user_id, state, created_at, updated_at)
+VALUES (%s, %s, %s, %s, %s)
+RETURNING %s
+`
+
+// GetBatchChangeRollbackJobOpts captures the query options needed for getting
+// a BatchChangeRollbackJob.
+type GetBatchChangeRollbackJobOpts struct {
+	ID int64
+
+	// BatchChangeID selects the most recent rollback job of the batch change.
+	BatchChangeID int64
+}
+
+// GetBatchChangeRollbackJob gets a BatchChangeRollbackJob matching the given
+// options.
+func (s *Store) GetBatchChangeRollbackJob(ctx context.Context, opts GetBatchChangeRollbackJobOpts) (job *btypes.BatchChangeRollbackJob, err error) {
+	ctx, endObservation := s.operations.getBatchChangeRollbackJob.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(opts.ID)),
+		log.Int("BatchChangeID", int(opts.BatchChangeID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	var preds []*sqlf.Query
+	if opts.ID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_change_rollback_jobs.id = %s", opts.ID))
+	}
+	if opts.BatchChangeID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_change_rollback_jobs.batch_change_id = %s", opts.BatchChangeID))
+	}
+	if len(preds) == 0 {
+		preds = append(preds, sqlf.Sprintf("TRUE"))
+	}
+
+	q := sqlf.Sprintf(
+		getBatchChangeRollbackJobQueryFmtstr,
+		sqlf.Join(BatchChangeRollbackJobColumns.ToSqlf(), ", "),
+		sqlf.Join(preds, "\n AND "),
+	)
+
+	var c btypes.BatchChangeRollbackJob
+	err = s.query(ctx, q, func(sc scanner) error { return scanBatchChangeRollbackJob(&c, sc) })
+	if err != nil {
+		return nil, err
+	}
+	if c.ID == 0 {
+		return nil, ErrNoResults
+	}
+	return &c, nil
+}
+
+var getBatchChangeRollbackJobQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_change_rollback_jobs.go:GetBatchChangeRollbackJob
+SELECT %s FROM batch_change_rollback_jobs
+WHERE %s
+ORDER BY id DESC
+LIMIT 1
+`
+
+func scanBatchChangeRollbackJob(j *btypes.BatchChangeRollbackJob, s scanner) error {
+	var failureMessage string
+	if err := s.Scan(
+		&j.ID,
+		&j.BatchChangeID,
+		&dbutil.NullInt32{N: &j.UserID},
+		&j.State,
+		&dbutil.NullString{S: &failureMessage},
+		&dbutil.NullTime{Time: &j.StartedAt},
+		&dbutil.NullTime{Time: &j.FinishedAt},
+		&dbutil.NullTime{Time: &j.ProcessAfter},
+		&j.NumResets,
+		&j.NumFailures,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	if failureMessage != "" {
+		j.FailureMessage = &failureMessage
+	}
+	j.State = btypes.BatchChangeRollbackJobState(strings.ToUpper(string(j.State)))
+	return nil
+}
+
+// ScanFirstBatchChangeRollbackJob scans the first rollback job of the given
+// rows, for use in the rollback worker store.
+func ScanFirstBatchChangeRollbackJob(rows *sql.Rows, queryErr error) (_ *btypes.BatchChangeRollbackJob, _ bool, err error) {
+	if queryErr != nil {
+		return nil, false, queryErr
+	}
+
+	var jobs []*btypes.BatchChangeRollbackJob
+	err = scanAll(rows, func(sc scanner) error {
+		var j btypes.BatchChangeRollbackJob
+		if err := scanBatchChangeRollbackJob(&j, sc); err != nil {
+			return err
+		}
+		jobs = append(jobs, &j)
+		return nil
+	})
+	if err != nil || len(jobs) == 0 {
+		return nil, false, err
+	}
+	return jobs[0], true, nil
+}
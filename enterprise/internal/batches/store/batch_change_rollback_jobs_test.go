@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/keegancsmith/sqlf"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+func testStoreBatchChangeRollbackJobs(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	job := &btypes.BatchChangeRollbackJob{BatchChangeID: 4321, UserID: 1234}
+
+	t.Run("Create", func(t *testing.T) {
+		if err := s.CreateBatchChangeRollbackJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+
+		want := &btypes.BatchChangeRollbackJob{
+			ID:            job.ID,
+			BatchChangeID: 4321,
+			UserID:        1234,
+			State:         btypes.BatchChangeRollbackJobStateQueued,
+			CreatedAt:     clock.Now(),
+			UpdatedAt:     clock.Now(),
+		}
+		if diff := cmp.Diff(want, job); diff != "" {
+			t.Fatal(diff)
+		}
+
+		t.Run("Active job exists", func(t *testing.T) {
+			again := &btypes.BatchChangeRollbackJob{BatchChangeID: 4321, UserID: 5678}
+			if err := s.CreateBatchChangeRollbackJob(ctx, again); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(job, again); diff != "" {
+				t.Fatalf("active job not returned: %s", diff)
+			}
+		})
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		for name, opts := range map[string]GetBatchChangeRollbackJobOpts{
+			"ByID":            {ID: job.ID},
+			"ByBatchChangeID": {BatchChangeID: job.BatchChangeID},
+		} {
+			t.Run(name, func(t *testing.T) {
+				have, err := s.GetBatchChangeRollbackJob(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(job, have); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		}
+
+		t.Run("NoResults", func(t *testing.T) {
+			_, have := s.GetBatchChangeRollbackJob(ctx, GetBatchChangeRollbackJobOpts{ID: 0xdeadbeef})
+			if have != ErrNoResults {
+				t.Fatalf("have err %v, want %v", have, ErrNoResults)
+			}
+		})
+	})
+
+	t.Run("Create after completion", func(t *testing.T) {
+		q := sqlf.Sprintf("UPDATE batch_change_rollback_jobs SET state = %s WHERE id = %s", btypes.BatchChangeRollbackJobStateCompleted.ToDB(), job.ID)
+		if err := s.Exec(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+
+		next := &btypes.BatchChangeRollbackJob{BatchChangeID: 4321}
+		if err := s.CreateBatchChangeRollbackJob(ctx, next); err != nil {
+			t.Fatal(err)
+		}
+		if next.ID == job.ID {
+			t.Fatal("no new job created for completed rollback")
+		}
+
+		have, err := s.GetBatchChangeRollbackJob(ctx, GetBatchChangeRollbackJobOpts{BatchChangeID: 4321})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have.ID != next.ID {
+			t.Fatalf("latest job not returned. want=%d, have=%d", next.ID, have.ID)
+		}
+	})
+}
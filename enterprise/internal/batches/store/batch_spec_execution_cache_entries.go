@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// batchSpecExecutionCacheEntryColumns are used by the batch spec execution
+// cache entry related Store methods to insert, update and query entries.
+var batchSpecExecutionCacheEntryColumns = SQLColumns{
+	"batch_spec_execution_cache_entries.id",
+	"batch_spec_execution_cache_entries.user_id",
+	"batch_spec_execution_cache_entries.workspace_key",
+	"batch_spec_execution_cache_entries.step_key",
+	"batch_spec_execution_cache_entries.result",
+	"batch_spec_execution_cache_entries.created_at",
+	"batch_spec_execution_cache_entries.last_used_at",
+}
+
+// UpsertBatchSpecExecutionCacheEntry creates the given entry, or replaces the
+// result of the existing entry of the same user, workspace and step.
+func (s *Store) UpsertBatchSpecExecutionCacheEntry(ctx context.Context, e *btypes.BatchSpecExecutionCacheEntry) (err error) {
+	ctx, endObservation := s.operations.upsertBatchSpecExecutionCacheEntry.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("userID", int(e.UserID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	result, err := json.Marshal(e.Result)
+	if err != nil {
+		return errors.Wrap(err, "marshaling step result")
+	}
+
+	now := s.now()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	e.LastUsedAt = now
+
+	q := sqlf.Sprintf(
+		upsertBatchSpecExecutionCacheEntryQueryFmtstr,
+		e.UserID,
+		e.WorkspaceKey,
+		e.StepKey,
+		result,
+		e.CreatedAt,
+		e.LastUsedAt,
+		sqlf.Join(batchSpecExecutionCacheEntryColumns.ToSqlf(), ", "),
+	)
+	return s.query(ctx, q, func(sc scanner) error { return scanBatchSpecExecutionCacheEntry(e, sc) })
+}
+
+var upsertBatchSpecExecutionCacheEntryQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_cache_entries.go:UpsertBatchSpecExecutionCacheEntry
+INSERT INTO batch_spec_execution_cache_entries (
+	user_id,
+	workspace_key,
+	step_key,
+	result,
+	created_at,
+	last_used_at
+)
+VALUES (%s, %s, %s, %s, %s, %s)
+ON CONFLICT (user_id, workspace_key, step_key) DO UPDATE SET
+	result = excluded.result,
+	last_used_at = excluded.last_used_at
+RETURNING %s
+`
+
+// ListBatchSpecExecutionCacheEntriesOpts captures the query options needed
+// for listing batch spec execution cache entries.
+type ListBatchSpecExecutionCacheEntriesOpts struct {
+	UserID       int32
+	WorkspaceKey string
+	StepKeys     []string
+}
+
+// ListBatchSpecExecutionCacheEntries lists the cache entries of the given user
+// for the given steps of the given workspace.
+func (s *Store) ListBatchSpecExecutionCacheEntries(ctx context.Context, opts ListBatchSpecExecutionCacheEntriesOpts) (es []*btypes.BatchSpecExecutionCacheEntry, err error) {
+	ctx, endObservation := s.operations.listBatchSpecExecutionCacheEntries.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("userID", int(opts.UserID)),
+		log.Int("stepKeys", len(opts.StepKeys)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		listBatchSpecExecutionCacheEntriesQueryFmtstr,
+		sqlf.Join(batchSpecExecutionCacheEntryColumns.ToSqlf(), ", "),
+		opts.UserID,
+		opts.WorkspaceKey,
+		pq.Array(opts.StepKeys),
+	)
+
+	err = s.query(ctx, q, func(sc scanner) error {
+		var e btypes.BatchSpecExecutionCacheEntry
+		if err := scanBatchSpecExecutionCacheEntry(&e, sc); err != nil {
+			return err
+		}
+		es = append(es, &e)
+		return nil
+	})
+	return es, err
+}
+
+var listBatchSpecExecutionCacheEntriesQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_cache_entries.go:ListBatchSpecExecutionCacheEntries
+SELECT %s FROM batch_spec_execution_cache_entries
+WHERE
+	user_id = %s AND
+	workspace_key = %s AND
+	step_key = ANY (%s)
+ORDER BY id ASC
+`
+
+// MarkUsedBatchSpecExecutionCacheEntries sets the last_used_at of the entries
+// with the given IDs to now, so they aren't pruned.
+func (s *Store) MarkUsedBatchSpecExecutionCacheEntries(ctx context.Context, ids []int64) (err error) {
+	ctx, endObservation := s.operations.markUsedBatchSpecExecutionCacheEntries.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("count", len(ids)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	return s.exec(ctx, sqlf.Sprintf(markUsedBatchSpecExecutionCacheEntriesQueryFmtstr, s.now(), pq.Array(ids)))
+}
+
+var markUsedBatchSpecExecutionCacheEntriesQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_cache_entries.go:MarkUsedBatchSpecExecutionCacheEntries
+UPDATE batch_spec_execution_cache_entries SET last_used_at = %s WHERE id = ANY (%s)
+`
+
+// DeleteBatchSpecExecutionCacheEntriesOpts captures the query options needed
+// for deleting batch spec execution cache entries. At least one option must
+// be set.
+type DeleteBatchSpecExecutionCacheEntriesOpts struct {
+	UserID int32
+	// WorkspaceKeys, if set, only deletes the entries of these workspaces.
+	WorkspaceKeys []string
+	// LastUsedBefore, if set, only deletes the entries that weren't used
+	// since then.
+	LastUsedBefore time.Time
+}
+
+// DeleteBatchSpecExecutionCacheEntries deletes the cache entries matching the
+// given options.
+func (s *Store) DeleteBatchSpecExecutionCacheEntries(ctx context.Context, opts DeleteBatchSpecExecutionCacheEntriesOpts) (err error) {
+	ctx, endObservation := s.operations.deleteBatchSpecExecutionCacheEntries.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("userID", int(opts.UserID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	var preds []*sqlf.Query
+	if opts.UserID != 0 {
+		preds = append(preds, sqlf.Sprintf("user_id = %s", opts.UserID))
+	}
+	if opts.WorkspaceKeys != nil {
+		preds = append(preds, sqlf.Sprintf("workspace_key = ANY (%s)", pq.Array(opts.WorkspaceKeys)))
+	}
+	if !opts.LastUsedBefore.IsZero() {
+		preds = append(preds, sqlf.Sprintf("last_used_at < %s", opts.LastUsedBefore))
+	}
+	if len(preds) == 0 {
+		return errors.New("no options given to delete batch spec execution cache entries")
+	}
+
+	return s.exec(ctx, sqlf.Sprintf(deleteBatchSpecExecutionCacheEntriesQueryFmtstr, sqlf.Join(preds, "\n AND ")))
+}
+
+var deleteBatchSpecExecutionCacheEntriesQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_cache_entries.go:DeleteBatchSpecExecutionCacheEntries
+DELETE FROM batch_spec_execution_cache_entries
+WHERE %s
+`
+
+func scanBatchSpecExecutionCacheEntry(e *btypes.BatchSpecExecutionCacheEntry, s scanner) error {
+	var result json.RawMessage
+	if err := s.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.WorkspaceKey,
+		&e.StepKey,
+		&result,
+		&e.CreatedAt,
+		&e.LastUsedAt,
+	); err != nil {
+		return err
+	}
+
+	return errors.Wrap(json.Unmarshal(result, &e.Result), "unmarshaling step result")
+}
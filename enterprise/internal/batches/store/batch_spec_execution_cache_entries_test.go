@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+)
+
+func testStoreBatchSpecExecutionCacheEntries(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	entries := make([]*btypes.BatchSpecExecutionCacheEntry, 0, 3)
+	for i := 0; i < cap(entries); i++ {
+		entries = append(entries, &btypes.BatchSpecExecutionCacheEntry{
+			UserID:       1234,
+			WorkspaceKey: "workspace-1",
+			StepKey:      "step-" + string(rune('a'+i)),
+			Result: batcheslib.AfterStepResult{
+				StepIndex: i,
+				Diff:      "diff --git a/README.md b/README.md",
+				Outputs:   map[string]interface{}{"step": float64(i)},
+			},
+		})
+	}
+	other := &btypes.BatchSpecExecutionCacheEntry{
+		UserID:       4321,
+		WorkspaceKey: "workspace-2",
+		StepKey:      "step-a",
+		Result:       batcheslib.AfterStepResult{Outputs: map[string]interface{}{}},
+	}
+
+	t.Run("Upsert", func(t *testing.T) {
+		for _, e := range append(entries, other) {
+			if err := s.UpsertBatchSpecExecutionCacheEntry(ctx, e); err != nil {
+				t.Fatal(err)
+			}
+			if e.ID == 0 {
+				t.Fatal("entry has no ID")
+			}
+			if have, want := e.LastUsedAt, clock.Now(); !have.Equal(want) {
+				t.Fatalf("entry has wrong LastUsedAt. want=%s, have=%s", want, have)
+			}
+		}
+
+		t.Run("Existing", func(t *testing.T) {
+			clock.Add(1 * time.Second)
+
+			e := *entries[2]
+			e.ID = 0
+			e.CreatedAt = time.Time{}
+			e.Result.Diff = "diff --git a/main.go b/main.go"
+			if err := s.UpsertBatchSpecExecutionCacheEntry(ctx, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.ID != entries[2].ID {
+				t.Fatalf("entry not replaced. want ID=%d, have=%d", entries[2].ID, e.ID)
+			}
+			if !e.CreatedAt.Equal(entries[2].CreatedAt) {
+				t.Fatalf("entry has wrong CreatedAt. want=%s, have=%s", entries[2].CreatedAt, e.CreatedAt)
+			}
+			entries[2] = &e
+		})
+	})
+
+	t.Run("List", func(t *testing.T) {
+		have, err := s.ListBatchSpecExecutionCacheEntries(ctx, ListBatchSpecExecutionCacheEntriesOpts{
+			UserID:       1234,
+			WorkspaceKey: "workspace-1",
+			StepKeys:     []string{"step-a", "step-c", "step-z"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*btypes.BatchSpecExecutionCacheEntry{entries[0], entries[2]}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatal(diff)
+		}
+
+		t.Run("OtherUser", func(t *testing.T) {
+			have, err := s.ListBatchSpecExecutionCacheEntries(ctx, ListBatchSpecExecutionCacheEntriesOpts{
+				UserID:       4321,
+				WorkspaceKey: "workspace-1",
+				StepKeys:     []string{"step-a"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != 0 {
+				t.Fatalf("unexpected entries of another user: %+v", have)
+			}
+		})
+	})
+
+	t.Run("MarkUsed", func(t *testing.T) {
+		clock.Add(1 * time.Second)
+
+		if err := s.MarkUsedBatchSpecExecutionCacheEntries(ctx, []int64{entries[1].ID}); err != nil {
+			t.Fatal(err)
+		}
+		have, err := s.ListBatchSpecExecutionCacheEntries(ctx, ListBatchSpecExecutionCacheEntriesOpts{
+			UserID:       1234,
+			WorkspaceKey: "workspace-1",
+			StepKeys:     []string{entries[1].StepKey},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(have) != 1 {
+			t.Fatalf("wrong number of entries. want=1, have=%d", len(have))
+		}
+		if want := clock.Now(); !have[0].LastUsedAt.Equal(want) {
+			t.Fatalf("entry has wrong LastUsedAt. want=%s, have=%s", want, have[0].LastUsedAt)
+		}
+		entries[1] = have[0]
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		t.Run("NoOptions", func(t *testing.T) {
+			if err := s.DeleteBatchSpecExecutionCacheEntries(ctx, DeleteBatchSpecExecutionCacheEntriesOpts{}); err == nil {
+				t.Fatal("no error returned for delete without options")
+			}
+		})
+
+		listAll := func(t *testing.T) []*btypes.BatchSpecExecutionCacheEntry {
+			t.Helper()
+			have, err := s.ListBatchSpecExecutionCacheEntries(ctx, ListBatchSpecExecutionCacheEntriesOpts{
+				UserID:       1234,
+				WorkspaceKey: "workspace-1",
+				StepKeys:     []string{"step-a", "step-b", "step-c"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return have
+		}
+
+		t.Run("LastUsedBefore", func(t *testing.T) {
+			// entries[0] wasn't used since it was created, the others were.
+			if err := s.DeleteBatchSpecExecutionCacheEntries(ctx, DeleteBatchSpecExecutionCacheEntriesOpts{
+				UserID:         1234,
+				LastUsedBefore: entries[2].LastUsedAt,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(entries[1:], listAll(t)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("ByWorkspaceKeys", func(t *testing.T) {
+			if err := s.DeleteBatchSpecExecutionCacheEntries(ctx, DeleteBatchSpecExecutionCacheEntriesOpts{
+				UserID:        1234,
+				WorkspaceKeys: []string{"workspace-1", "workspace-2"},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if have := listAll(t); len(have) != 0 {
+				t.Fatalf("unexpected entries after delete: %+v", have)
+			}
+
+			// The entry of the other user is left alone.
+			have, err := s.ListBatchSpecExecutionCacheEntries(ctx, ListBatchSpecExecutionCacheEntriesOpts{
+				UserID:       4321,
+				WorkspaceKey: "workspace-2",
+				StepKeys:     []string{"step-a"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]*btypes.BatchSpecExecutionCacheEntry{other}, have); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+}
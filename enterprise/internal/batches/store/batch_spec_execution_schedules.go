@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// batchSpecExecutionScheduleColumns are used by the batch spec execution
+// schedule related Store methods to insert, update and query schedules.
+var batchSpecExecutionScheduleColumns = SQLColumns{
+	"batch_spec_execution_schedules.id",
+	"batch_spec_execution_schedules.batch_change_id",
+	"batch_spec_execution_schedules.user_id",
+	"batch_spec_execution_schedules.interval_seconds",
+	"batch_spec_execution_schedules.enabled",
+	"batch_spec_execution_schedules.next_run_at",
+	"batch_spec_execution_schedules.last_run_at",
+	"batch_spec_execution_schedules.pending_batch_spec_id",
+	"batch_spec_execution_schedules.created_at",
+	"batch_spec_execution_schedules.updated_at",
+}
+
+// CreateBatchSpecExecutionSchedule creates the given schedule. A batch change
+// can only have one schedule.
+func (s *Store) CreateBatchSpecExecutionSchedule(ctx context.Context, sched *btypes.BatchSpecExecutionSchedule) (err error) {
+	ctx, endObservation := s.operations.createBatchSpecExecutionSchedule.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchChangeID", int(sched.BatchChangeID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if sched.CreatedAt.IsZero() {
+		sched.CreatedAt = s.now()
+	}
+	if sched.UpdatedAt.IsZero() {
+		sched.UpdatedAt = sched.CreatedAt
+	}
+
+	q := sqlf.Sprintf(
+		createBatchSpecExecutionScheduleQueryFmtstr,
+		sched.BatchChangeID,
+		sched.UserID,
+		int64(sched.Interval/time.Second),
+		sched.Enabled,
+		sched.NextRunAt,
+		nullTimeColumn(sched.LastRunAt),
+		nullInt64Column(sched.PendingBatchSpecID),
+		sched.CreatedAt,
+		sched.UpdatedAt,
+		sqlf.Join(batchSpecExecutionScheduleColumns.ToSqlf(), ", "),
+	)
+	return s.query(ctx, q, func(sc scanner) error { return scanBatchSpecExecutionSchedule(sched, sc) })
+}
+
+var createBatchSpecExecutionScheduleQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_schedules.go:CreateBatchSpecExecutionSchedule
+INSERT INTO batch_spec_execution_schedules (
+	batch_change_id,
+	user_id,
+	interval_seconds,
+	enabled,
+	next_run_at,
+	last_run_at,
+	pending_batch_spec_id,
+	created_at,
+	updated_at
+)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+RETURNING %s
+`
+
+// UpdateBatchSpecExecutionSchedule updates the given schedule.
+func (s *Store) UpdateBatchSpecExecutionSchedule(ctx context.Context, sched *btypes.BatchSpecExecutionSchedule) (err error) {
+	ctx, endObservation := s.operations.updateBatchSpecExecutionSchedule.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(sched.ID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	sched.UpdatedAt = s.now()
+
+	q := sqlf.Sprintf(
+		updateBatchSpecExecutionScheduleQueryFmtstr,
+		sched.UserID,
+		int64(sched.Interval/time.Second),
+		sched.Enabled,
+		sched.NextRunAt,
+		nullTimeColumn(sched.LastRunAt),
+		nullInt64Column(sched.PendingBatchSpecID),
+		sched.UpdatedAt,
+		sched.ID,
+		sqlf.Join(batchSpecExecutionScheduleColumns.ToSqlf(), ", "),
+	)
+	return s.query(ctx, q, func(sc scanner) error { return scanBatchSpecExecutionSchedule(sched, sc) })
+}
+
+var updateBatchSpecExecutionScheduleQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_schedules.go:UpdateBatchSpecExecutionSchedule
+UPDATE batch_spec_execution_schedules
+SET
+	user_id = %s,
+	interval_seconds = %s,
+	enabled = %s,
+	next_run_at = %s,
+	last_run_at = %s,
+	pending_batch_spec_id = %s,
+	updated_at = %s
+WHERE id = %s
+RETURNING %s
+`
+
+// DeleteBatchSpecExecutionSchedule deletes the schedule with the given ID.
+func (s *Store) DeleteBatchSpecExecutionSchedule(ctx context.Context, id int64) (err error) {
+	ctx, endObservation := s.operations.deleteBatchSpecExecutionSchedule.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	return s.exec(ctx, sqlf.Sprintf(deleteBatchSpecExecutionScheduleQueryFmtstr, id))
+}
+
+var deleteBatchSpecExecutionScheduleQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_schedules.go:DeleteBatchSpecExecutionSchedule
+DELETE FROM batch_spec_execution_schedules WHERE id = %s
+`
+
+// GetBatchSpecExecutionScheduleOpts captures the query options needed for
+// getting a BatchSpecExecutionSchedule.
+type GetBatchSpecExecutionScheduleOpts struct {
+	ID            int64
+	BatchChangeID int64
+}
+
+// GetBatchSpecExecutionSchedule gets a BatchSpecExecutionSchedule matching the
+// given options.
+func (s *Store) GetBatchSpecExecutionSchedule(ctx context.Context, opts GetBatchSpecExecutionScheduleOpts) (sched *btypes.BatchSpecExecutionSchedule, err error) {
+	ctx, endObservation := s.operations.getBatchSpecExecutionSchedule.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(opts.ID)),
+		log.Int("BatchChangeID", int(opts.BatchChangeID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	var preds []*sqlf.Query
+	if opts.ID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_execution_schedules.id = %s", opts.ID))
+	}
+	if opts.BatchChangeID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_execution_schedules.batch_change_id = %s", opts.BatchChangeID))
+	}
+	if len(preds) == 0 {
+		preds = append(preds, sqlf.Sprintf("TRUE"))
+	}
+
+	q := sqlf.Sprintf(
+		getBatchSpecExecutionScheduleQueryFmtstr,
+		sqlf.Join(batchSpecExecutionScheduleColumns.ToSqlf(), ", "),
+		sqlf.Join(preds, "\n AND "),
+	)
+
+	var c btypes.BatchSpecExecutionSchedule
+	err = s.query(ctx, q, func(sc scanner) error { return scanBatchSpecExecutionSchedule(&c, sc) })
+	if err != nil {
+		return nil, err
+	}
+	if c.ID == 0 {
+		return nil, ErrNoResults
+	}
+	return &c, nil
+}
+
+var getBatchSpecExecutionScheduleQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_schedules.go:GetBatchSpecExecutionSchedule
+SELECT %s FROM batch_spec_execution_schedules
+WHERE %s
+LIMIT 1
+`
+
+// ListBatchSpecExecutionSchedulesOpts captures the query options needed for
+// listing batch spec execution schedules.
+type ListBatchSpecExecutionSchedulesOpts struct {
+	LimitOpts
+	Cursor int64
+
+	// DueAt, if set, only returns enabled schedules whose next run is due at
+	// the given time.
+	DueAt time.Time
+	// OnlyPending, if true, only returns schedules whose last run created a
+	// batch spec that hasn't been executed yet.
+	OnlyPending bool
+}
+
+// ListBatchSpecExecutionSchedules lists batch spec execution schedules with
+// the given filters, ordered by ID.
+func (s *Store) ListBatchSpecExecutionSchedules(ctx context.Context, opts ListBatchSpecExecutionSchedulesOpts) (cs []*btypes.BatchSpecExecutionSchedule, next int64, err error) {
+	ctx, endObservation := s.operations.listBatchSpecExecutionSchedules.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("batch_spec_execution_schedules.id >= %s", opts.Cursor),
+	}
+	if !opts.DueAt.IsZero() {
+		preds = append(preds, sqlf.Sprintf("batch_spec_execution_schedules.enabled AND batch_spec_execution_schedules.next_run_at <= %s", opts.DueAt))
+	}
+	if opts.OnlyPending {
+		preds = append(preds, sqlf.Sprintf("batch_spec_execution_schedules.pending_batch_spec_id IS NOT NULL"))
+	}
+
+	q := sqlf.Sprintf(
+		listBatchSpecExecutionSchedulesQueryFmtstr+opts.LimitOpts.ToDB(),
+		sqlf.Join(batchSpecExecutionScheduleColumns.ToSqlf(), ", "),
+		sqlf.Join(preds, "\n AND "),
+	)
+
+	cs = make([]*btypes.BatchSpecExecutionSchedule, 0, opts.DBLimit())
+	err = s.query(ctx, q, func(sc scanner) error {
+		var c btypes.BatchSpecExecutionSchedule
+		if err := scanBatchSpecExecutionSchedule(&c, sc); err != nil {
+			return err
+		}
+		cs = append(cs, &c)
+		return nil
+	})
+
+	if opts.Limit != 0 && len(cs) == opts.DBLimit() {
+		next = cs[len(cs)-1].ID
+		cs = cs[:len(cs)-1]
+	}
+
+	return cs, next, err
+}
+
+var listBatchSpecExecutionSchedulesQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_execution_schedules.go:ListBatchSpecExecutionSchedules
+SELECT %s FROM batch_spec_execution_schedules
+WHERE %s
+ORDER BY id ASC
+`
+
+func scanBatchSpecExecutionSchedule(c *btypes.BatchSpecExecutionSchedule, s scanner) error {
+	var intervalSeconds int64
+	if err := s.Scan(
+		&c.ID,
+		&c.BatchChangeID,
+		&c.UserID,
+		&intervalSeconds,
+		&c.Enabled,
+		&c.NextRunAt,
+		&dbutil.NullTime{Time: &c.LastRunAt},
+		&dbutil.NullInt64{N: &c.PendingBatchSpecID},
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	c.Interval = time.Duration(intervalSeconds) * time.Second
+	return nil
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+func testStoreBatchSpecExecutionSchedules(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	schedules := make([]*btypes.BatchSpecExecutionSchedule, 0, 3)
+	for i := 0; i < cap(schedules); i++ {
+		schedules = append(schedules, &btypes.BatchSpecExecutionSchedule{
+			BatchChangeID: int64(i + 910),
+			UserID:        int32(i + 1234),
+			Interval:      time.Duration(i+1) * time.Hour,
+			Enabled:       true,
+			NextRunAt:     clock.Now().Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		for _, sched := range schedules {
+			if err := s.CreateBatchSpecExecutionSchedule(ctx, sched); err != nil {
+				t.Fatal(err)
+			}
+			if sched.ID == 0 {
+				t.Fatal("schedule has no ID")
+			}
+			if have, want := sched.CreatedAt, clock.Now(); !have.Equal(want) {
+				t.Fatalf("schedule has wrong CreatedAt. want=%s, have=%s", want, have)
+			}
+		}
+
+		t.Run("Duplicate batch change", func(t *testing.T) {
+			tx, err := s.Transact(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			duplicate := &btypes.BatchSpecExecutionSchedule{
+				BatchChangeID: schedules[0].BatchChangeID,
+				UserID:        1,
+				Interval:      time.Hour,
+				NextRunAt:     clock.Now(),
+			}
+			if err := tx.Done(tx.CreateBatchSpecExecutionSchedule(ctx, duplicate)); err == nil {
+				t.Fatal("second schedule for the same batch change created")
+			}
+		})
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		for name, opts := range map[string]GetBatchSpecExecutionScheduleOpts{
+			"ByID":            {ID: schedules[1].ID},
+			"ByBatchChangeID": {BatchChangeID: schedules[1].BatchChangeID},
+		} {
+			t.Run(name, func(t *testing.T) {
+				have, err := s.GetBatchSpecExecutionSchedule(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(schedules[1], have); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		}
+
+		t.Run("NoResults", func(t *testing.T) {
+			_, have := s.GetBatchSpecExecutionSchedule(ctx, GetBatchSpecExecutionScheduleOpts{ID: 0xdeadbeef})
+			if have != ErrNoResults {
+				t.Fatalf("have err %v, want %v", have, ErrNoResults)
+			}
+		})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		clock.Add(1 * time.Second)
+
+		sched := schedules[2]
+		sched.Enabled = false
+		sched.Interval = 24 * time.Hour
+		sched.LastRunAt = clock.Now()
+		if err := s.UpdateBatchSpecExecutionSchedule(ctx, sched); err != nil {
+			t.Fatal(err)
+		}
+		if have, want := sched.UpdatedAt, clock.Now(); !have.Equal(want) {
+			t.Fatalf("schedule has wrong UpdatedAt. want=%s, have=%s", want, have)
+		}
+
+		have, err := s.GetBatchSpecExecutionSchedule(ctx, GetBatchSpecExecutionScheduleOpts{ID: sched.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(sched, have); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		have, next, err := s.ListBatchSpecExecutionSchedules(ctx, ListBatchSpecExecutionSchedulesOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != 0 {
+			t.Fatalf("unexpected next cursor %d", next)
+		}
+		if diff := cmp.Diff(schedules, have); diff != "" {
+			t.Fatal(diff)
+		}
+
+		t.Run("Pagination", func(t *testing.T) {
+			var cursor int64
+			for _, want := range schedules {
+				opts := ListBatchSpecExecutionSchedulesOpts{LimitOpts: LimitOpts{Limit: 1}, Cursor: cursor}
+				have, next, err := s.ListBatchSpecExecutionSchedules(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff([]*btypes.BatchSpecExecutionSchedule{want}, have); diff != "" {
+					t.Fatal(diff)
+				}
+				cursor = next
+			}
+			if cursor != 0 {
+				t.Fatalf("unexpected next cursor %d after last page", cursor)
+			}
+		})
+
+		t.Run("Due", func(t *testing.T) {
+			// schedules[1] is due in an hour and schedules[2] is disabled.
+			have, _, err := s.ListBatchSpecExecutionSchedules(ctx, ListBatchSpecExecutionSchedulesOpts{DueAt: clock.Now()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(schedules[:1], have); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.DeleteBatchSpecExecutionSchedule(ctx, schedules[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		_, err := s.GetBatchSpecExecutionSchedule(ctx, GetBatchSpecExecutionScheduleOpts{ID: schedules[0].ID})
+		if err != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// ListBatchSpecResolutionJobOutcomesOpts captures the query options needed
+// for listing the outcomes of resolution jobs.
+type ListBatchSpecResolutionJobOutcomesOpts struct {
+	LimitOpts
+
+	// Cursor, if set, only returns outcomes with an ID greater than it.
+	// Consumers pass the ID of the last outcome they have seen to receive new
+	// outcomes only.
+	Cursor int64
+}
+
+// BatchSpecResolutionJobOutcomeVisibilityLag is how long outcomes are held
+// back after they were recorded before they are listed. IDs are assigned when
+// an outcome is inserted, not when its transaction commits, so an outcome can
+// become visible after one with a greater ID. Holding outcomes back for longer
+// than the transactions recording them take keeps a cursor from skipping them.
+const BatchSpecResolutionJobOutcomeVisibilityLag = time.Minute
+
+// ListBatchSpecResolutionJobOutcomes lists the outcomes of resolution jobs in
+// the order they were recorded, leaving out those recorded within the last
+// BatchSpecResolutionJobOutcomeVisibilityLag.
+func (s *Store) ListBatchSpecResolutionJobOutcomes(ctx context.Context, opts ListBatchSpecResolutionJobOutcomesOpts) (outcomes []*btypes.BatchSpecResolutionJobOutcome, next int64, err error) {
+	ctx, endObservation := s.operations.listBatchSpecResolutionJobOutcomes.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int64("cursor", opts.Cursor),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		listBatchSpecResolutionJobOutcomesQueryFmtstr+opts.LimitOpts.ToDB(),
+		opts.Cursor,
+		s.now().Add(-BatchSpecResolutionJobOutcomeVisibilityLag),
+	)
+
+	outcomes = make([]*btypes.BatchSpecResolutionJobOutcome, 0, opts.DBLimit())
+	err = s.query(ctx, q, func(sc scanner) error {
+		var o btypes.BatchSpecResolutionJobOutcome
+		if err := sc.Scan(
+			&o.ID,
+			&o.BatchSpecResolutionJobID,
+			&dbutil.NullInt64{N: &o.BatchSpecID},
+			&dbutil.NullInt32{N: &o.NamespaceUserID},
+			&dbutil.NullInt32{N: &o.NamespaceOrgID},
+			&o.State,
+			&o.RepositoryErrorCount,
+			&o.FinishedAt,
+		); err != nil {
+			return err
+		}
+		outcomes = append(outcomes, &o)
+		return nil
+	})
+
+	if opts.Limit != 0 && len(outcomes) == opts.DBLimit() {
+		// The cursor is exclusive, so the next page starts after the last
+		// outcome that is returned.
+		outcomes = outcomes[:len(outcomes)-1]
+		next = outcomes[len(outcomes)-1].ID
+	}
+
+	return outcomes, next, err
+}
+
+var listBatchSpecResolutionJobOutcomesQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_job_outcomes.go:ListBatchSpecResolutionJobOutcomes
+SELECT
+	id,
+	batch_spec_resolution_job_id,
+	batch_spec_id,
+	namespace_user_id,
+	namespace_org_id,
+	state,
+	repository_error_count,
+	finished_at
+FROM batch_spec_resolution_job_outcomes
+WHERE
+	id > %s
+AND
+	recorded_at < %s
+ORDER BY id ASC
+`
+
+// ListBatchSpecResolutionJobOutcomeCountsOpts captures the query options
+// needed for counting the outcomes of resolution jobs over time.
+type ListBatchSpecResolutionJobOutcomeCountsOpts struct {
+	// From and To bound the time range to count outcomes in. From is
+	// inclusive, To is exclusive.
+	From, To time.Time
+
+	// Interval is the length of the intervals outcomes are counted in. The
+	// intervals are aligned to the Unix epoch, so an interval of a day counts
+	// outcomes per UTC day.
+	Interval time.Duration
+}
+
+// ListBatchSpecResolutionJobOutcomeCounts counts the outcomes of resolution
+// jobs that finished in the given time range per interval, ordered by time.
+// Intervals without any outcomes are omitted.
+func (s *Store) ListBatchSpecResolutionJobOutcomeCounts(ctx context.Context, opts ListBatchSpecResolutionJobOutcomeCountsOpts) (cs []*btypes.BatchSpecResolutionJobOutcomeCounts, err error) {
+	ctx, endObservation := s.operations.listBatchSpecResolutionJobOutcomeCounts.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("interval", opts.Interval.String()),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if opts.Interval < time.Second {
+		return nil, errors.New("interval must be at least one second")
+	}
+	seconds := int64(opts.Interval / time.Second)
+
+	q := sqlf.Sprintf(
+		listBatchSpecResolutionJobOutcomeCountsQueryFmtstr,
+		seconds,
+		seconds,
+		opts.From,
+		opts.To,
+	)
+
+	cs = make([]*btypes.BatchSpecResolutionJobOutcomeCounts, 0)
+	err = s.query(ctx, q, func(sc scanner) error {
+		var c btypes.BatchSpecResolutionJobOutcomeCounts
+		if err := sc.Scan(&c.Time, &c.Completed, &c.Failed, &c.Namespaces); err != nil {
+			return err
+		}
+		c.Time = c.Time.UTC()
+		cs = append(cs, &c)
+		return nil
+	})
+
+	return cs, err
+}
+
+var listBatchSpecResolutionJobOutcomeCountsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_job_outcomes.go:ListBatchSpecResolutionJobOutcomeCounts
+SELECT
+	to_timestamp(floor(EXTRACT(EPOCH FROM finished_at) / %s) * %s) AS interval_start,
+	COUNT(*) FILTER (WHERE state = 'completed'),
+	COUNT(*) FILTER (WHERE state = 'failed'),
+	COUNT(DISTINCT (namespace_user_id, namespace_org_id)) FILTER (WHERE namespace_user_id IS NOT NULL OR namespace_org_id IS NOT NULL)
+FROM batch_spec_resolution_job_outcomes
+WHERE
+	finished_at >= %s
+AND
+	finished_at < %s
+GROUP BY interval_start
+ORDER BY interval_start ASC
+`
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/keegancsmith/sqlf"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+func testStoreBatchSpecResolutionJobOutcomes(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	day := time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	userSpec := &btypes.BatchSpec{UserID: 1, NamespaceUserID: 1}
+	if err := s.CreateBatchSpec(ctx, userSpec); err != nil {
+		t.Fatal(err)
+	}
+	orgSpec := &btypes.BatchSpec{UserID: 1, NamespaceOrgID: 2}
+	if err := s.CreateBatchSpec(ctx, orgSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	finish := func(batchSpecID int64, dryRun bool, state btypes.BatchSpecResolutionJobState, finishedAt time.Time) *btypes.BatchSpecResolutionJob {
+		t.Helper()
+
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID, DryRun: dryRun}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Exec(ctx, sqlf.Sprintf(
+			`UPDATE batch_spec_resolution_jobs SET state = %s, finished_at = %s WHERE id = %s`,
+			state, finishedAt, job.ID,
+		)); err != nil {
+			t.Fatal(err)
+		}
+		// Outcomes are only listed once they're older than the visibility
+		// lag.
+		if err := s.Exec(ctx, sqlf.Sprintf(
+			`UPDATE batch_spec_resolution_job_outcomes SET recorded_at = %s WHERE batch_spec_resolution_job_id = %s`,
+			finishedAt, job.ID,
+		)); err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+
+	completed := finish(userSpec.ID, false, btypes.BatchSpecResolutionJobStateCompleted, day.Add(time.Hour))
+	failed := finish(userSpec.ID, false, btypes.BatchSpecResolutionJobStateFailed, day.Add(2*time.Hour))
+	errored := finish(userSpec.ID, false, btypes.BatchSpecResolutionJobStateErrored, day.Add(3*time.Hour))
+	nextDay := finish(orgSpec.ID, false, btypes.BatchSpecResolutionJobStateCompleted, day.Add(25*time.Hour))
+
+	// Dry runs have no outcome.
+	finish(userSpec.ID, true, btypes.BatchSpecResolutionJobStateCompleted, day.Add(4*time.Hour))
+
+	// Jobs that are not finished have no outcome.
+	if err := s.CreateBatchSpecResolutionJob(ctx, &btypes.BatchSpecResolutionJob{BatchSpecID: orgSpec.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("List", func(t *testing.T) {
+		have, next, err := s.ListBatchSpecResolutionJobOutcomes(ctx, ListBatchSpecResolutionJobOutcomesOpts{LimitOpts: LimitOpts{Limit: 2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*btypes.BatchSpecResolutionJobOutcome{
+			{
+				BatchSpecResolutionJobID: completed.ID,
+				BatchSpecID:              userSpec.ID,
+				NamespaceUserID:          1,
+				State:                    btypes.BatchSpecResolutionJobStateCompleted,
+				FinishedAt:               day.Add(time.Hour),
+			},
+			{
+				BatchSpecResolutionJobID: failed.ID,
+				BatchSpecID:              userSpec.ID,
+				NamespaceUserID:          1,
+				State:                    btypes.BatchSpecResolutionJobStateFailed,
+				FinishedAt:               day.Add(2 * time.Hour),
+			},
+		}
+		if diff := cmp.Diff(want, have, cmpOutcomeOpts...); diff != "" {
+			t.Fatal(diff)
+		}
+
+		have, next, err = s.ListBatchSpecResolutionJobOutcomes(ctx, ListBatchSpecResolutionJobOutcomesOpts{Cursor: next})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != 0 {
+			t.Fatalf("unexpected next cursor %d", next)
+		}
+		if len(have) != 2 {
+			t.Fatalf("unexpected outcomes on second page: %+v", have)
+		}
+		if have[0].BatchSpecResolutionJobID != errored.ID || have[0].State != btypes.BatchSpecResolutionJobStateFailed {
+			t.Fatalf("errored job not recorded as failed: %+v", have[0])
+		}
+		if have[1].BatchSpecResolutionJobID != nextDay.ID || have[1].NamespaceOrgID != 2 {
+			t.Fatalf("unexpected outcome on second page: %+v", have[1])
+		}
+	})
+
+	t.Run("List holds back recent outcomes", func(t *testing.T) {
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: userSpec.ID}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Exec(ctx, sqlf.Sprintf(`UPDATE batch_spec_resolution_jobs SET state = 'completed', finished_at = %s WHERE id = %s`, clock.Now(), job.ID)); err != nil {
+			t.Fatal(err)
+		}
+
+		have, _, err := s.ListBatchSpecResolutionJobOutcomes(ctx, ListBatchSpecResolutionJobOutcomesOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range have {
+			if o.BatchSpecResolutionJobID == job.ID {
+				t.Fatal("recent outcome listed")
+			}
+		}
+
+		clock.Add(BatchSpecResolutionJobOutcomeVisibilityLag + time.Hour)
+		have, _, err = s.ListBatchSpecResolutionJobOutcomes(ctx, ListBatchSpecResolutionJobOutcomesOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := have[len(have)-1]; last.BatchSpecResolutionJobID != job.ID {
+			t.Fatalf("outcome not listed after the lag, last outcome is %+v", last)
+		}
+	})
+
+	t.Run("Counts", func(t *testing.T) {
+		have, err := s.ListBatchSpecResolutionJobOutcomeCounts(ctx, ListBatchSpecResolutionJobOutcomeCountsOpts{
+			From:     day,
+			To:       day.Add(48 * time.Hour),
+			Interval: 24 * time.Hour,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*btypes.BatchSpecResolutionJobOutcomeCounts{
+			{Time: day, Completed: 1, Failed: 2, Namespaces: 1},
+			{Time: day.Add(24 * time.Hour), Completed: 1, Namespaces: 1},
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatal(diff)
+		}
+
+		if _, err := s.ListBatchSpecResolutionJobOutcomeCounts(ctx, ListBatchSpecResolutionJobOutcomeCountsOpts{From: day, To: day}); err == nil {
+			t.Fatal("counting without an interval did not fail")
+		}
+	})
+
+	t.Run("Kept when job is deleted", func(t *testing.T) {
+		if _, err := s.DeleteBatchSpecResolutionJobs(ctx, DeleteBatchSpecResolutionJobsOpts{BatchSpecIDs: []int64{userSpec.ID}}); err != nil {
+			t.Fatal(err)
+		}
+		have, _, err := s.ListBatchSpecResolutionJobOutcomes(ctx, ListBatchSpecResolutionJobOutcomesOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(have) != 5 {
+			t.Fatalf("want 5 outcomes, have %d", len(have))
+		}
+	})
+}
+
+var cmpOutcomeOpts = []cmp.Option{
+	cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) }),
+	cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".ID" }, cmp.Ignore()),
+}
@@ -3,7 +3,12 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/keegancsmith/sqlf"
 	"github.com/lib/pq"
 	"github.com/opentracing/opentracing-go/log"
@@ -22,6 +27,9 @@ var batchSpecResolutionJobInsertColumns = []string{
 	"batch_spec_id",
 	"allow_unsupported",
 	"allow_ignored",
+	"labels",
+	"dry_run",
+	"priority",
 
 	"state",
 
@@ -37,6 +45,15 @@ var BatchSpecResolutionJobColums = SQLColumns{
 	"batch_spec_resolution_jobs.batch_spec_id",
 	"batch_spec_resolution_jobs.allow_unsupported",
 	"batch_spec_resolution_jobs.allow_ignored",
+	"batch_spec_resolution_jobs.labels",
+	"batch_spec_resolution_jobs.search_queries",
+	"batch_spec_resolution_jobs.progress",
+	"batch_spec_resolution_jobs.dry_run",
+	"batch_spec_resolution_jobs.dry_run_result",
+	"batch_spec_resolution_jobs.credential_warnings",
+	"batch_spec_resolution_jobs.priority",
+	"batch_spec_resolution_jobs.summary",
+	"batch_spec_resolution_jobs.repository_errors",
 
 	"batch_spec_resolution_jobs.state",
 	"batch_spec_resolution_jobs.failure_message",
@@ -52,44 +69,153 @@ var BatchSpecResolutionJobColums = SQLColumns{
 	"batch_spec_resolution_jobs.updated_at",
 }
 
+// BatchSpecResolutionMaxNumRetries sets the number of retries for batch spec
+// resolutions to 0. We don't want to retry automatically and instead wait for
+// user input
+const BatchSpecResolutionMaxNumRetries = 0
+
+// BatchSpecResolutionWorkerStoreOptions are the options of the dbworker store
+// the batch spec resolution worker dequeues jobs with. They're defined here
+// so that the store can inspect the dequeue query.
+var BatchSpecResolutionWorkerStoreOptions = dbworkerstore.Options{
+	Name:              "batch_changes_batch_spec_resolution_worker_store",
+	TableName:         "batch_spec_resolution_jobs",
+	ColumnExpressions: BatchSpecResolutionJobColums.ToSqlf(),
+	Scan:              scanFirstBatchSpecResolutionJobRecord,
+
+	// Interactive resolutions are dequeued before bulk ones, so that users
+	// waiting in the UI aren't stuck behind automation.
+	OrderByExpression: sqlf.Sprintf("batch_spec_resolution_jobs.priority DESC, batch_spec_resolution_jobs.state = 'errored', batch_spec_resolution_jobs.updated_at DESC"),
+
+	RetryAfter:    5 * time.Second,
+	MaxNumRetries: BatchSpecResolutionMaxNumRetries,
+}
+
+func scanFirstBatchSpecResolutionJobRecord(rows *sql.Rows, err error) (workerutil.Record, bool, error) {
+	return ScanFirstBatchSpecResolutionJob(rows, err)
+}
+
 // CreateBatchSpecResolutionJob creates the given batch spec resolutionjob jobs.
+//
+// If a job of the same batch spec and kind (dry run or not) is already queued
+// or processing, no new job is created. Instead, the given job is overwritten
+// with the existing one, so that the same spec is never resolved by two
+// workers concurrently.
+//
+// Creating a new job fails with a btypes.ErrBatchSpecResolutionQuotaExceeded
+// if the namespace of the batch spec used up its batchChanges.resolutionQuota.
 func (s *Store) CreateBatchSpecResolutionJob(ctx context.Context, ws ...*btypes.BatchSpecResolutionJob) (err error) {
 	ctx, endObservation := s.operations.createBatchSpecResolutionJob.With(ctx, &err, observation.Args{LogFields: []log.Field{
 		log.Int("count", len(ws)),
 	}})
 	defer endObservation(1, observation.Args{})
 
-	inserter := func(inserter *batch.Inserter) error {
-		for _, wj := range ws {
-			if wj.CreatedAt.IsZero() {
-				wj.CreatedAt = s.now()
-			}
-
-			if wj.UpdatedAt.IsZero() {
-				wj.UpdatedAt = wj.CreatedAt
-			}
-
-			state := string(wj.State)
-			if state == "" {
-				state = string(btypes.BatchSpecResolutionJobStateQueued)
-			}
-
-			if err := inserter.Insert(
-				ctx,
-				wj.BatchSpecID,
-				wj.AllowUnsupported,
-				wj.AllowIgnored,
-				state,
-				wj.CreatedAt,
-				wj.UpdatedAt,
-			); err != nil {
-				return err
-			}
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	for _, wj := range ws {
+		// Lock the batch spec, so that concurrent enqueues for it are
+		// serialized and can't both miss each other's job.
+		if err := tx.exec(ctx, sqlf.Sprintf(lockBatchSpecForResolutionQueryFmtstr, wj.BatchSpecID)); err != nil {
+			return err
 		}
 
-		return nil
+		existing, err := tx.getActiveBatchSpecResolutionJob(ctx, wj.BatchSpecID, wj.DryRun)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			*wj = *existing
+			continue
+		}
+
+		if err := tx.chargeBatchSpecResolutionQuota(ctx, wj.BatchSpecID); err != nil {
+			return err
+		}
+
+		if err := tx.insertBatchSpecResolutionJob(ctx, wj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var lockBatchSpecForResolutionQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:CreateBatchSpecResolutionJob
+SELECT 1 FROM batch_specs WHERE id = %s FOR NO KEY UPDATE
+`
+
+// getActiveBatchSpecResolutionJob returns the queued or processing job of the
+// given batch spec and kind, or nil if there is none. Errored jobs are never
+// retried, so they don't count as active.
+func (s *Store) getActiveBatchSpecResolutionJob(ctx context.Context, batchSpecID int64, dryRun bool) (*btypes.BatchSpecResolutionJob, error) {
+	q := sqlf.Sprintf(
+		getActiveBatchSpecResolutionJobQueryFmtstr,
+		sqlf.Join(BatchSpecResolutionJobColums.ToSqlf(), ", "),
+		batchSpecID,
+		dryRun,
+		btypes.BatchSpecResolutionJobStateQueued,
+		btypes.BatchSpecResolutionJobStateProcessing,
+	)
+
+	var job *btypes.BatchSpecResolutionJob
+	err := s.query(ctx, q, func(sc scanner) error {
+		job = &btypes.BatchSpecResolutionJob{}
+		return scanBatchSpecResolutionJob(job, sc)
+	})
+	return job, err
+}
+
+var getActiveBatchSpecResolutionJobQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:getActiveBatchSpecResolutionJob
+SELECT %s FROM batch_spec_resolution_jobs
+WHERE
+	batch_spec_id = %s
+AND
+	dry_run = %s
+AND
+	state IN (%s, %s)
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (s *Store) insertBatchSpecResolutionJob(ctx context.Context, wj *btypes.BatchSpecResolutionJob) error {
+	if wj.CreatedAt.IsZero() {
+		wj.CreatedAt = s.now()
+	}
+
+	if wj.UpdatedAt.IsZero() {
+		wj.UpdatedAt = wj.CreatedAt
+	}
+
+	state := string(wj.State)
+	if state == "" {
+		state = string(btypes.BatchSpecResolutionJobStateQueued)
+	}
+
+	labels, err := marshalResolutionJobLabels(wj.Labels)
+	if err != nil {
+		return err
+	}
+
+	inserter := func(inserter *batch.Inserter) error {
+		return inserter.Insert(
+			ctx,
+			wj.BatchSpecID,
+			wj.AllowUnsupported,
+			wj.AllowIgnored,
+			labels,
+			wj.DryRun,
+			wj.Priority,
+			state,
+			wj.CreatedAt,
+			wj.UpdatedAt,
+		)
 	}
-	i := -1
 	return batch.WithInserterWithReturn(
 		ctx,
 		s.Handle().DB(),
@@ -97,8 +223,7 @@ func (s *Store) CreateBatchSpecResolutionJob(ctx context.Context, ws ...*btypes.
 		batchSpecResolutionJobInsertColumns,
 		BatchSpecResolutionJobColums,
 		func(rows *sql.Rows) error {
-			i++
-			return scanBatchSpecResolutionJob(ws[i], rows)
+			return scanBatchSpecResolutionJob(wj, rows)
 		},
 		inserter,
 	)
@@ -108,6 +233,14 @@ func (s *Store) CreateBatchSpecResolutionJob(ctx context.Context, ws ...*btypes.
 type GetBatchSpecResolutionJobOpts struct {
 	ID          int64
 	BatchSpecID int64
+
+	// DryRun selects the latest dry run job of the batch spec instead of its
+	// regular job, when getting a job by BatchSpecID.
+	DryRun bool
+
+	// BatchSpecWorkspaceID selects the job that created the given
+	// workspace. Dry run jobs never create workspaces, so DryRun is ignored.
+	BatchSpecWorkspaceID int64
 }
 
 // GetBatchSpecResolutionJob gets a BatchSpecResolutionJob matching the given options.
@@ -115,6 +248,7 @@ func (s *Store) GetBatchSpecResolutionJob(ctx context.Context, opts GetBatchSpec
 	ctx, endObservation := s.operations.getBatchSpecResolutionJob.With(ctx, &err, observation.Args{LogFields: []log.Field{
 		log.Int("ID", int(opts.ID)),
 		log.Int("BatchSpecID", int(opts.BatchSpecID)),
+		log.Int("BatchSpecWorkspaceID", int(opts.BatchSpecWorkspaceID)),
 	}})
 	defer endObservation(1, observation.Args{})
 
@@ -137,12 +271,17 @@ func (s *Store) GetBatchSpecResolutionJob(ctx context.Context, opts GetBatchSpec
 var getBatchSpecResolutionJobsQueryFmtstr = `
 -- source: enterprise/internal/batches/store/batch_spec_resolution_job.go:GetBatchSpecResolutionJob
 SELECT %s FROM batch_spec_resolution_jobs
+%s
 WHERE %s
+ORDER BY batch_spec_resolution_jobs.id DESC
 LIMIT 1
 `
 
 func getBatchSpecResolutionJobQuery(opts *GetBatchSpecResolutionJobOpts) *sqlf.Query {
-	var preds []*sqlf.Query
+	var (
+		joins []*sqlf.Query
+		preds []*sqlf.Query
+	)
 
 	if opts.ID != 0 {
 		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.id = %s", opts.ID))
@@ -150,11 +289,19 @@ func getBatchSpecResolutionJobQuery(opts *GetBatchSpecResolutionJobOpts) *sqlf.Q
 
 	if opts.BatchSpecID != 0 {
 		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.batch_spec_id = %s", opts.BatchSpecID))
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.dry_run = %s", opts.DryRun))
+	}
+
+	if opts.BatchSpecWorkspaceID != 0 {
+		joins = append(joins, sqlf.Sprintf("JOIN batch_spec_workspaces ON batch_spec_workspaces.batch_spec_id = batch_spec_resolution_jobs.batch_spec_id"))
+		preds = append(preds, sqlf.Sprintf("batch_spec_workspaces.id = %s", opts.BatchSpecWorkspaceID))
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.dry_run = FALSE"))
 	}
 
 	return sqlf.Sprintf(
 		getBatchSpecResolutionJobsQueryFmtstr,
 		sqlf.Join(BatchSpecResolutionJobColums.ToSqlf(), ", "),
+		sqlf.Join(joins, "\n"),
 		sqlf.Join(preds, "\n AND "),
 	)
 }
@@ -164,6 +311,21 @@ func getBatchSpecResolutionJobQuery(opts *GetBatchSpecResolutionJobOpts) *sqlf.Q
 type ListBatchSpecResolutionJobsOpts struct {
 	State          btypes.BatchSpecResolutionJobState
 	WorkerHostname string
+
+	// Labels, if set, only returns jobs that have all of the given labels.
+	Labels map[string]string
+
+	// DryRun, if set, only returns dry run jobs if true, or only regular
+	// jobs if false.
+	DryRun *bool
+
+	// BatchSpecIDs, if set, only returns jobs of the given batch specs.
+	BatchSpecIDs []int64
+
+	// CreatorID, if set, only returns jobs of batch specs created by the
+	// given user. Use ListNamespaceBatchSpecResolutionJobs to list the jobs
+	// in a namespace.
+	CreatorID int32
 }
 
 // ListBatchSpecResolutionJobs lists batch changes with the given filters.
@@ -171,7 +333,10 @@ func (s *Store) ListBatchSpecResolutionJobs(ctx context.Context, opts ListBatchS
 	ctx, endObservation := s.operations.listBatchSpecResolutionJobs.With(ctx, &err, observation.Args{})
 	defer endObservation(1, observation.Args{})
 
-	q := listBatchSpecResolutionJobsQuery(opts)
+	q, err := listBatchSpecResolutionJobsQuery(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	cs = make([]*btypes.BatchSpecResolutionJob, 0)
 	err = s.query(ctx, q, func(sc scanner) error {
@@ -189,12 +354,14 @@ func (s *Store) ListBatchSpecResolutionJobs(ctx context.Context, opts ListBatchS
 var listBatchSpecResolutionJobsQueryFmtstr = `
 -- source: enterprise/internal/batches/store/batch_spec_resolutionjob_job.go:ListBatchSpecResolutionJobs
 SELECT %s FROM batch_spec_resolution_jobs
+%s
 WHERE %s
-ORDER BY id ASC
+ORDER BY batch_spec_resolution_jobs.id ASC
 `
 
-func listBatchSpecResolutionJobsQuery(opts ListBatchSpecResolutionJobsOpts) *sqlf.Query {
+func listBatchSpecResolutionJobsQuery(opts ListBatchSpecResolutionJobsOpts) (*sqlf.Query, error) {
 	var preds []*sqlf.Query
+	var joins []*sqlf.Query
 
 	if opts.State != "" {
 		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.state = %s", opts.State))
@@ -204,6 +371,27 @@ func listBatchSpecResolutionJobsQuery(opts ListBatchSpecResolutionJobsOpts) *sql
 		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.worker_hostname = %s", opts.WorkerHostname))
 	}
 
+	if len(opts.Labels) > 0 {
+		labels, err := marshalResolutionJobLabels(opts.Labels)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.labels @> %s", labels))
+	}
+
+	if opts.DryRun != nil {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.dry_run = %s", *opts.DryRun))
+	}
+
+	if len(opts.BatchSpecIDs) > 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.batch_spec_id = ANY (%s)", pq.Array(opts.BatchSpecIDs)))
+	}
+
+	if opts.CreatorID != 0 {
+		joins = append(joins, sqlf.Sprintf("INNER JOIN batch_specs ON batch_specs.id = batch_spec_resolution_jobs.batch_spec_id"))
+		preds = append(preds, sqlf.Sprintf("batch_specs.user_id = %s", opts.CreatorID))
+	}
+
 	if len(preds) == 0 {
 		preds = append(preds, sqlf.Sprintf("TRUE"))
 	}
@@ -211,19 +399,170 @@ func listBatchSpecResolutionJobsQuery(opts ListBatchSpecResolutionJobsOpts) *sql
 	return sqlf.Sprintf(
 		listBatchSpecResolutionJobsQueryFmtstr,
 		sqlf.Join(BatchSpecResolutionJobColums.ToSqlf(), ", "),
+		sqlf.Join(joins, "\n"),
+		sqlf.Join(preds, "\n AND "),
+	), nil
+}
+
+// ListNamespaceBatchSpecResolutionJobsOpts captures the query options needed
+// for listing the resolution jobs of all batch specs in a namespace, or of a
+// single batch spec.
+type ListNamespaceBatchSpecResolutionJobsOpts struct {
+	LimitOpts
+	Cursor int64
+
+	NamespaceUserID int32
+	NamespaceOrgID  int32
+
+	// BatchSpecID, if set, only returns the jobs of the given batch spec.
+	BatchSpecID int64
+
+	State         btypes.BatchSpecResolutionJobState
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// ListNamespaceBatchSpecResolutionJobs lists the resolution jobs of the batch
+// specs in the given namespace, newest first. Jobs of batch specs whose
+// namespace has been deleted are never returned.
+func (s *Store) ListNamespaceBatchSpecResolutionJobs(ctx context.Context, opts ListNamespaceBatchSpecResolutionJobsOpts) (cs []*btypes.BatchSpecResolutionJob, next int64, err error) {
+	ctx, endObservation := s.operations.listNamespaceBatchSpecResolutionJobs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("namespaceUserID", int(opts.NamespaceUserID)),
+		log.Int("namespaceOrgID", int(opts.NamespaceOrgID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q := listNamespaceBatchSpecResolutionJobsQuery(&opts)
+
+	cs = make([]*btypes.BatchSpecResolutionJob, 0, opts.DBLimit())
+	err = s.query(ctx, q, func(sc scanner) error {
+		var c btypes.BatchSpecResolutionJob
+		if err := scanBatchSpecResolutionJob(&c, sc); err != nil {
+			return err
+		}
+		cs = append(cs, &c)
+		return nil
+	})
+
+	if opts.Limit != 0 && len(cs) == opts.DBLimit() {
+		next = cs[len(cs)-1].ID
+		cs = cs[:len(cs)-1]
+	}
+
+	return cs, next, err
+}
+
+var listNamespaceBatchSpecResolutionJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:ListNamespaceBatchSpecResolutionJobs
+SELECT %s FROM batch_spec_resolution_jobs
+%s
+WHERE %s
+ORDER BY batch_spec_resolution_jobs.id DESC
+`
+
+func listNamespaceBatchSpecResolutionJobsQuery(opts *ListNamespaceBatchSpecResolutionJobsOpts) *sqlf.Query {
+	joins, preds := namespaceBatchSpecResolutionJobsConds(opts)
+
+	if opts.Cursor != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.id <= %s", opts.Cursor))
+	}
+
+	return sqlf.Sprintf(
+		listNamespaceBatchSpecResolutionJobsQueryFmtstr+opts.LimitOpts.ToDB(),
+		sqlf.Join(BatchSpecResolutionJobColums.ToSqlf(), ", "),
+		sqlf.Join(joins, "\n"),
+		sqlf.Join(preds, "\n AND "),
+	)
+}
+
+// CountNamespaceBatchSpecResolutionJobs returns the number of resolution jobs
+// matched by the given options. Cursor and Limit are ignored.
+func (s *Store) CountNamespaceBatchSpecResolutionJobs(ctx context.Context, opts ListNamespaceBatchSpecResolutionJobsOpts) (count int, err error) {
+	ctx, endObservation := s.operations.countNamespaceBatchSpecResolutionJobs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("namespaceUserID", int(opts.NamespaceUserID)),
+		log.Int("namespaceOrgID", int(opts.NamespaceOrgID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	joins, preds := namespaceBatchSpecResolutionJobsConds(&opts)
+	q := sqlf.Sprintf(
+		countNamespaceBatchSpecResolutionJobsQueryFmtstr,
+		sqlf.Join(joins, "\n"),
 		sqlf.Join(preds, "\n AND "),
 	)
+
+	return s.queryCount(ctx, q)
+}
+
+var countNamespaceBatchSpecResolutionJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:CountNamespaceBatchSpecResolutionJobs
+SELECT COUNT(batch_spec_resolution_jobs.id) FROM batch_spec_resolution_jobs
+%s
+WHERE %s
+`
+
+func namespaceBatchSpecResolutionJobsConds(opts *ListNamespaceBatchSpecResolutionJobsOpts) (joins, preds []*sqlf.Query) {
+	joins = []*sqlf.Query{
+		sqlf.Sprintf("INNER JOIN batch_specs ON batch_specs.id = batch_spec_resolution_jobs.batch_spec_id"),
+		sqlf.Sprintf("LEFT JOIN users namespace_user ON batch_specs.namespace_user_id = namespace_user.id"),
+		sqlf.Sprintf("LEFT JOIN orgs namespace_org ON batch_specs.namespace_org_id = namespace_org.id"),
+	}
+	preds = []*sqlf.Query{
+		sqlf.Sprintf("namespace_user.deleted_at IS NULL"),
+		sqlf.Sprintf("namespace_org.deleted_at IS NULL"),
+	}
+
+	if opts.NamespaceUserID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_specs.namespace_user_id = %s", opts.NamespaceUserID))
+	}
+
+	if opts.NamespaceOrgID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_specs.namespace_org_id = %s", opts.NamespaceOrgID))
+	}
+
+	if opts.BatchSpecID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.batch_spec_id = %s", opts.BatchSpecID))
+	}
+
+	if opts.State != "" {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.state = %s", opts.State))
+	}
+
+	if !opts.CreatedAfter.IsZero() {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.created_at >= %s", opts.CreatedAfter))
+	}
+
+	if !opts.CreatedBefore.IsZero() {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.created_at < %s", opts.CreatedBefore))
+	}
+
+	return joins, preds
 }
 
 func scanBatchSpecResolutionJob(rj *btypes.BatchSpecResolutionJob, s scanner) error {
 	var executionLogs []dbworkerstore.ExecutionLogEntry
 	var failureMessage string
+	var labels json.RawMessage
+	var progress json.RawMessage
+	var dryRunResult json.RawMessage
+	var credentialWarnings json.RawMessage
+	var summary json.RawMessage
+	var repositoryErrors json.RawMessage
 
 	if err := s.Scan(
 		&rj.ID,
 		&rj.BatchSpecID,
 		&rj.AllowUnsupported,
 		&rj.AllowIgnored,
+		&labels,
+		pq.Array(&rj.SearchQueries),
+		&progress,
+		&rj.DryRun,
+		&dryRunResult,
+		&credentialWarnings,
+		&rj.Priority,
+		&summary,
+		&repositoryErrors,
 		&rj.State,
 		&dbutil.NullString{S: &failureMessage},
 		&dbutil.NullTime{Time: &rj.StartedAt},
@@ -243,6 +582,48 @@ func scanBatchSpecResolutionJob(rj *btypes.BatchSpecResolutionJob, s scanner) er
 		rj.FailureMessage = &failureMessage
 	}
 
+	var m map[string]string
+	if err := json.Unmarshal(labels, &m); err != nil {
+		return err
+	}
+	if len(m) > 0 {
+		rj.Labels = m
+	}
+
+	if err := json.Unmarshal(progress, &rj.Progress); err != nil {
+		return err
+	}
+
+	if len(dryRunResult) > 0 {
+		rj.DryRunResult = &btypes.BatchSpecResolutionDryRunResult{}
+		if err := json.Unmarshal(dryRunResult, rj.DryRunResult); err != nil {
+			return err
+		}
+	}
+
+	var warnings []*btypes.BatchSpecResolutionCredentialWarning
+	if err := json.Unmarshal(credentialWarnings, &warnings); err != nil {
+		return err
+	}
+	if len(warnings) > 0 {
+		rj.CredentialWarnings = warnings
+	}
+
+	var repoErrs []*btypes.BatchSpecResolutionRepositoryError
+	if err := json.Unmarshal(repositoryErrors, &repoErrs); err != nil {
+		return err
+	}
+	if len(repoErrs) > 0 {
+		rj.RepositoryErrors = repoErrs
+	}
+
+	if len(summary) > 0 {
+		rj.Summary = &btypes.BatchSpecResolutionJobSummary{}
+		if err := json.Unmarshal(summary, rj.Summary); err != nil {
+			return err
+		}
+	}
+
 	for _, entry := range executionLogs {
 		rj.ExecutionLogs = append(rj.ExecutionLogs, workerutil.ExecutionLogEntry(entry))
 	}
@@ -250,6 +631,456 @@ func scanBatchSpecResolutionJob(rj *btypes.BatchSpecResolutionJob, s scanner) er
 	return nil
 }
 
+// SetBatchSpecResolutionJobSearchQueries records the repository search
+// queries the given resolution job executed.
+func (s *Store) SetBatchSpecResolutionJobSearchQueries(ctx context.Context, id int64, queries []string) (err error) {
+	ctx, endObservation := s.operations.setBatchSpecResolutionJobSearchQueries.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+		log.Int("count", len(queries)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if queries == nil {
+		queries = []string{}
+	}
+
+	q := sqlf.Sprintf(setBatchSpecResolutionJobSearchQueriesQueryFmtstr, pq.Array(queries), s.now(), id)
+	return s.exec(ctx, q)
+}
+
+var setBatchSpecResolutionJobSearchQueriesQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SetBatchSpecResolutionJobSearchQueries
+UPDATE batch_spec_resolution_jobs
+SET
+	search_queries = %s,
+	updated_at = %s
+WHERE id = %s
+`
+
+// SetBatchSpecResolutionJobProgress records how far the given resolution job
+// has progressed.
+func (s *Store) SetBatchSpecResolutionJobProgress(ctx context.Context, id int64, progress btypes.BatchSpecResolutionJobProgress) (err error) {
+	ctx, endObservation := s.operations.setBatchSpecResolutionJobProgress.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+		log.Int("percentComplete", progress.PercentComplete),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+
+	q := sqlf.Sprintf(setBatchSpecResolutionJobProgressQueryFmtstr, raw, s.now(), id)
+	return s.exec(ctx, q)
+}
+
+var setBatchSpecResolutionJobProgressQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SetBatchSpecResolutionJobProgress
+UPDATE batch_spec_resolution_jobs
+SET
+	progress = %s,
+	updated_at = %s
+WHERE id = %s
+`
+
+// SetBatchSpecResolutionJobDryRunResult stores the result of the given dry
+// run resolution job.
+func (s *Store) SetBatchSpecResolutionJobDryRunResult(ctx context.Context, id int64, result *btypes.BatchSpecResolutionDryRunResult) (err error) {
+	ctx, endObservation := s.operations.setBatchSpecResolutionJobDryRunResult.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	q := sqlf.Sprintf(setBatchSpecResolutionJobDryRunResultQueryFmtstr, raw, s.now(), id)
+	return s.exec(ctx, q)
+}
+
+var setBatchSpecResolutionJobDryRunResultQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SetBatchSpecResolutionJobDryRunResult
+UPDATE batch_spec_resolution_jobs
+SET
+	dry_run_result = %s,
+	updated_at = %s
+WHERE id = %s AND dry_run
+`
+
+// GetBatchSpecResolutionJobLogs returns the execution logs of the given
+// resolution job, starting at the given offset. Only the requested entries are
+// read, so that tailing the logs doesn't fetch the whole array every time.
+func (s *Store) GetBatchSpecResolutionJobLogs(ctx context.Context, id int64, offset int) (logs *btypes.BatchSpecResolutionJobLogs, err error) {
+	ctx, endObservation := s.operations.getBatchSpecResolutionJobLogs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+		log.Int("offset", offset),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Postgres arrays are 1-indexed.
+	q := sqlf.Sprintf(getBatchSpecResolutionJobLogsQueryFmtstr, offset+1, id)
+
+	logs = &btypes.BatchSpecResolutionJobLogs{Offset: offset}
+	err = s.query(ctx, q, func(sc scanner) error {
+		var entries []dbworkerstore.ExecutionLogEntry
+		if err := sc.Scan(&logs.State, &logs.Total, pq.Array(&entries)); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			logs.Entries = append(logs.Entries, workerutil.ExecutionLogEntry(entry))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if logs.State == "" {
+		return nil, ErrNoResults
+	}
+	return logs, nil
+}
+
+var getBatchSpecResolutionJobLogsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:GetBatchSpecResolutionJobLogs
+SELECT
+	state,
+	COALESCE(array_length(execution_logs, 1), 0),
+	execution_logs[%s:]
+FROM batch_spec_resolution_jobs
+WHERE id = %s
+`
+
+// SearchBatchSpecResolutionJobLogsOpts captures the query options needed for
+// searching the execution logs of resolution jobs.
+type SearchBatchSpecResolutionJobLogsOpts struct {
+	LimitOpts
+	Cursor int64
+
+	// BatchSpecID, if set, only searches the logs of the jobs of the given
+	// batch spec.
+	BatchSpecID int64
+	State       btypes.BatchSpecResolutionJobState
+}
+
+// SearchBatchSpecResolutionJobLogs returns the execution log entries of
+// resolution jobs whose output contains query, ignoring case. The limit and
+// cursor apply to jobs, not entries: all matching entries of a job are
+// returned together, ordered by their position in the logs, and jobs are
+// ordered newest first.
+//
+// The resolution worker writes all of the output of a job to a single entry,
+// so a match returns the complete log of the job.
+func (s *Store) SearchBatchSpecResolutionJobLogs(ctx context.Context, query string, opts SearchBatchSpecResolutionJobLogsOpts) (ms []*btypes.BatchSpecResolutionJobLogMatch, next int64, err error) {
+	ctx, endObservation := s.operations.searchBatchSpecResolutionJobLogs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchSpecID", int(opts.BatchSpecID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if query == "" {
+		return nil, 0, errors.New("empty search query")
+	}
+
+	q := searchBatchSpecResolutionJobLogsQuery(query, &opts)
+
+	ms = make([]*btypes.BatchSpecResolutionJobLogMatch, 0)
+	var jobs int
+	err = s.query(ctx, q, func(sc scanner) error {
+		var m btypes.BatchSpecResolutionJobLogMatch
+		var entry dbworkerstore.ExecutionLogEntry
+		if err := sc.Scan(&m.JobID, &m.BatchSpecID, &m.Index, &entry); err != nil {
+			return err
+		}
+		m.Entry = workerutil.ExecutionLogEntry(entry)
+
+		if len(ms) == 0 || ms[len(ms)-1].JobID != m.JobID {
+			jobs++
+		}
+		ms = append(ms, &m)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The query fetches one more job than requested to find out whether there
+	// is a next page. Drop its entries.
+	if opts.Limit != 0 && jobs == opts.DBLimit() {
+		next = ms[len(ms)-1].JobID
+		for len(ms) > 0 && ms[len(ms)-1].JobID == next {
+			ms = ms[:len(ms)-1]
+		}
+	}
+
+	return ms, next, nil
+}
+
+var searchBatchSpecResolutionJobLogsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SearchBatchSpecResolutionJobLogs
+WITH matching_jobs AS (
+	SELECT batch_spec_resolution_jobs.id
+	FROM batch_spec_resolution_jobs
+	WHERE
+		%s AND
+		EXISTS (SELECT 1 FROM unnest(batch_spec_resolution_jobs.execution_logs) AS entry WHERE entry->>'out' ILIKE %s)
+	ORDER BY batch_spec_resolution_jobs.id DESC
+	%s
+)
+SELECT
+	batch_spec_resolution_jobs.id,
+	batch_spec_resolution_jobs.batch_spec_id,
+	-- Postgres arrays are 1-indexed.
+	entries.idx - 1,
+	entries.entry
+FROM matching_jobs
+JOIN batch_spec_resolution_jobs ON batch_spec_resolution_jobs.id = matching_jobs.id
+CROSS JOIN LATERAL unnest(batch_spec_resolution_jobs.execution_logs) WITH ORDINALITY AS entries(entry, idx)
+WHERE entries.entry->>'out' ILIKE %s
+ORDER BY batch_spec_resolution_jobs.id DESC, entries.idx ASC
+`
+
+func searchBatchSpecResolutionJobLogsQuery(query string, opts *SearchBatchSpecResolutionJobLogsOpts) *sqlf.Query {
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("batch_spec_resolution_jobs.execution_logs IS NOT NULL"),
+	}
+
+	if opts.Cursor != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.id <= %s", opts.Cursor))
+	}
+
+	if opts.BatchSpecID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.batch_spec_id = %s", opts.BatchSpecID))
+	}
+
+	if opts.State != "" {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.state = %s", opts.State))
+	}
+
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+
+	return sqlf.Sprintf(
+		searchBatchSpecResolutionJobLogsQueryFmtstr,
+		sqlf.Join(preds, "\n AND "),
+		pattern,
+		sqlf.Sprintf(opts.LimitOpts.ToDB()),
+		pattern,
+	)
+}
+
+// likeEscaper escapes the characters that have a special meaning in LIKE
+// patterns, so that the query is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SetBatchSpecResolutionJobCredentialWarnings stores the credential warnings
+// of the given resolution job.
+func (s *Store) SetBatchSpecResolutionJobCredentialWarnings(ctx context.Context, id int64, warnings []*btypes.BatchSpecResolutionCredentialWarning) (err error) {
+	ctx, endObservation := s.operations.setBatchSpecResolutionJobCredentialWarnings.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+		log.Int("count", len(warnings)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if warnings == nil {
+		warnings = []*btypes.BatchSpecResolutionCredentialWarning{}
+	}
+	raw, err := json.Marshal(warnings)
+	if err != nil {
+		return err
+	}
+
+	q := sqlf.Sprintf(setBatchSpecResolutionJobCredentialWarningsQueryFmtstr, raw, s.now(), id)
+	return s.exec(ctx, q)
+}
+
+var setBatchSpecResolutionJobCredentialWarningsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SetBatchSpecResolutionJobCredentialWarnings
+UPDATE batch_spec_resolution_jobs
+SET
+	credential_warnings = %s,
+	updated_at = %s
+WHERE id = %s
+`
+
+// SetBatchSpecResolutionJobRepositoryErrors stores the per-repository errors
+// of the given resolution job, replacing those of previous attempts.
+func (s *Store) SetBatchSpecResolutionJobRepositoryErrors(ctx context.Context, id int64, repoErrs []*btypes.BatchSpecResolutionRepositoryError) (err error) {
+	ctx, endObservation := s.operations.setBatchSpecResolutionJobRepositoryErrors.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+		log.Int("count", len(repoErrs)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if repoErrs == nil {
+		repoErrs = []*btypes.BatchSpecResolutionRepositoryError{}
+	}
+	raw, err := json.Marshal(repoErrs)
+	if err != nil {
+		return err
+	}
+
+	q := sqlf.Sprintf(setBatchSpecResolutionJobRepositoryErrorsQueryFmtstr, raw, s.now(), id)
+	return s.exec(ctx, q)
+}
+
+var setBatchSpecResolutionJobRepositoryErrorsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SetBatchSpecResolutionJobRepositoryErrors
+UPDATE batch_spec_resolution_jobs
+SET
+	repository_errors = %s,
+	updated_at = %s
+WHERE id = %s
+`
+
+// SetBatchSpecResolutionJobSummary stores the summary of the given finished
+// resolution job.
+func (s *Store) SetBatchSpecResolutionJobSummary(ctx context.Context, id int64, summary *btypes.BatchSpecResolutionJobSummary) (err error) {
+	ctx, endObservation := s.operations.setBatchSpecResolutionJobSummary.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	q := sqlf.Sprintf(setBatchSpecResolutionJobSummaryQueryFmtstr, raw, s.now(), id)
+	return s.exec(ctx, q)
+}
+
+var setBatchSpecResolutionJobSummaryQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:SetBatchSpecResolutionJobSummary
+UPDATE batch_spec_resolution_jobs
+SET
+	summary = %s,
+	updated_at = %s
+WHERE id = %s
+`
+
+// DeleteBatchSpecResolutionJobsOpts captures the query options needed for
+// deleting batch spec resolution jobs. Jobs must match all of the set options,
+// and at least one option must be set.
+type DeleteBatchSpecResolutionJobsOpts struct {
+	BatchSpecIDs []int64
+	States       []btypes.BatchSpecResolutionJobState
+
+	// DryRun, if set, only deletes jobs whose dry run flag matches.
+	DryRun *bool
+
+	// CreatedBefore, if set, only deletes jobs created before this time.
+	CreatedBefore time.Time
+	// FinishedBefore, if set, only deletes jobs that finished before this
+	// time. Jobs that haven't finished are never matched.
+	FinishedBefore time.Time
+}
+
+// DeleteBatchSpecResolutionJobs deletes the resolution jobs matching the given
+// options and returns the IDs of the deleted jobs.
+func (s *Store) DeleteBatchSpecResolutionJobs(ctx context.Context, opts DeleteBatchSpecResolutionJobsOpts) (deletedIDs []int64, err error) {
+	ctx, endObservation := s.operations.deleteBatchSpecResolutionJobs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("batchSpecIDs", len(opts.BatchSpecIDs)),
+		log.Int("states", len(opts.States)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q, err := deleteBatchSpecResolutionJobsQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	err = s.query(ctx, q, func(sc scanner) error {
+		var id int64
+		if err := sc.Scan(&id); err != nil {
+			return err
+		}
+		deletedIDs = append(deletedIDs, id)
+		return nil
+	})
+	return deletedIDs, err
+}
+
+var deleteBatchSpecResolutionJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:DeleteBatchSpecResolutionJobs
+DELETE FROM batch_spec_resolution_jobs
+WHERE %s
+RETURNING id
+`
+
+func deleteBatchSpecResolutionJobsQuery(opts DeleteBatchSpecResolutionJobsOpts) (*sqlf.Query, error) {
+	var preds []*sqlf.Query
+
+	if len(opts.BatchSpecIDs) > 0 {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.batch_spec_id = ANY (%s)", pq.Array(opts.BatchSpecIDs)))
+	}
+
+	if len(opts.States) > 0 {
+		states := make([]string, 0, len(opts.States))
+		for _, state := range opts.States {
+			states = append(states, string(state))
+		}
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.state = ANY (%s)", pq.Array(states)))
+	}
+
+	if opts.DryRun != nil {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.dry_run = %s", *opts.DryRun))
+	}
+
+	if !opts.CreatedBefore.IsZero() {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.created_at < %s", opts.CreatedBefore))
+	}
+
+	if !opts.FinishedBefore.IsZero() {
+		preds = append(preds, sqlf.Sprintf("batch_spec_resolution_jobs.finished_at < %s", opts.FinishedBefore))
+	}
+
+	// Refuse to delete all jobs because of a forgotten option.
+	if len(preds) == 0 {
+		return nil, errors.New("no options given for deleting batch spec resolution jobs")
+	}
+
+	return sqlf.Sprintf(deleteBatchSpecResolutionJobsQueryFmtstr, sqlf.Join(preds, "\n AND ")), nil
+}
+
+// PurgeBatchSpecResolutionJobLogs removes the execution logs of all regular
+// resolution jobs that finished, failed or errored before the given time. The
+// jobs themselves are kept, since executing a batch spec requires its
+// resolution job.
+func (s *Store) PurgeBatchSpecResolutionJobLogs(ctx context.Context, finishedBefore time.Time) (err error) {
+	ctx, endObservation := s.operations.purgeBatchSpecResolutionJobLogs.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		purgeBatchSpecResolutionJobLogsQueryFmtstr,
+		s.now(),
+		btypes.BatchSpecResolutionJobStateCompleted,
+		btypes.BatchSpecResolutionJobStateFailed,
+		btypes.BatchSpecResolutionJobStateErrored,
+		finishedBefore,
+	)
+	return s.exec(ctx, q)
+}
+
+var purgeBatchSpecResolutionJobLogsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:PurgeBatchSpecResolutionJobLogs
+UPDATE batch_spec_resolution_jobs
+SET
+	execution_logs = NULL,
+	updated_at = %s
+WHERE
+	NOT dry_run
+AND
+	state IN (%s, %s, %s)
+AND
+	finished_at < %s
+AND
+	execution_logs IS NOT NULL
+`
+
 func ScanFirstBatchSpecResolutionJob(rows *sql.Rows, err error) (*btypes.BatchSpecResolutionJob, bool, error) {
 	jobs, err := scanBatchSpecResolutionJobs(rows, err)
 	if err != nil || len(jobs) == 0 {
@@ -274,3 +1105,133 @@ func scanBatchSpecResolutionJobs(rows *sql.Rows, queryErr error) ([]*btypes.Batc
 		return nil
 	})
 }
+
+// marshalResolutionJobLabels marshals the given labels into a JSONB object,
+// mapping nil to an empty object.
+func marshalResolutionJobLabels(labels map[string]string) ([]byte, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return json.Marshal(labels)
+}
+
+// batchSpecResolutionJobDurationStatsLookback limits the duration stats to
+// recently completed jobs, so that they reflect the current performance of
+// the instance.
+const batchSpecResolutionJobDurationStatsLookback = 30 * 24 * time.Hour
+
+// ListBatchSpecResolutionJobDurationStats returns duration percentiles of
+// recently completed resolution jobs, bucketed by the number of repositories
+// their batch spec resolved to. The buckets are ordered by ascending
+// MaxRepos, with the unbounded bucket last. Buckets without any jobs are
+// omitted.
+func (s *Store) ListBatchSpecResolutionJobDurationStats(ctx context.Context) (stats []*btypes.BatchSpecResolutionJobDurationStats, err error) {
+	ctx, endObservation := s.operations.listBatchSpecResolutionJobDurationStats.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		listBatchSpecResolutionJobDurationStatsQueryFmtstr,
+		s.now().Add(-batchSpecResolutionJobDurationStatsLookback),
+	)
+
+	err = s.query(ctx, q, func(sc scanner) error {
+		var st btypes.BatchSpecResolutionJobDurationStats
+		var p50, p90 float64
+		if err := sc.Scan(&st.MaxRepos, &st.Count, &p50, &p90); err != nil {
+			return err
+		}
+		st.P50 = time.Duration(math.Round(p50*1000)) * time.Millisecond
+		st.P90 = time.Duration(math.Round(p90*1000)) * time.Millisecond
+		stats = append(stats, &st)
+		return nil
+	})
+
+	return stats, err
+}
+
+var listBatchSpecResolutionJobDurationStatsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:ListBatchSpecResolutionJobDurationStats
+WITH durations AS (
+	SELECT
+		EXTRACT(EPOCH FROM (batch_spec_resolution_jobs.finished_at - batch_spec_resolution_jobs.started_at)) AS duration,
+		(
+			SELECT COUNT(DISTINCT batch_spec_workspaces.repo_id)
+			FROM batch_spec_workspaces
+			WHERE batch_spec_workspaces.batch_spec_id = batch_spec_resolution_jobs.batch_spec_id
+		) AS repo_count
+	FROM batch_spec_resolution_jobs
+	WHERE
+		batch_spec_resolution_jobs.state = 'completed'
+	AND
+		batch_spec_resolution_jobs.started_at IS NOT NULL
+	AND
+		batch_spec_resolution_jobs.finished_at >= %s
+), bucketed AS (
+	SELECT
+		duration,
+		CASE
+			WHEN repo_count <= 10 THEN 10
+			WHEN repo_count <= 100 THEN 100
+			WHEN repo_count <= 1000 THEN 1000
+			ELSE 0
+		END AS max_repos
+	FROM durations
+)
+SELECT
+	max_repos,
+	COUNT(*),
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY duration),
+	percentile_cont(0.9) WITHIN GROUP (ORDER BY duration)
+FROM bucketed
+GROUP BY max_repos
+ORDER BY max_repos = 0, max_repos ASC
+`
+
+// GetBatchSpecResolutionJobStats returns the number of resolution jobs per
+// state, when the oldest queued job was created and the average processing
+// time of recently completed jobs.
+func (s *Store) GetBatchSpecResolutionJobStats(ctx context.Context) (stats *btypes.BatchSpecResolutionJobStats, err error) {
+	ctx, endObservation := s.operations.getBatchSpecResolutionJobStats.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		getBatchSpecResolutionJobStatsQueryFmtstr,
+		s.now().Add(-batchSpecResolutionJobDurationStatsLookback),
+	)
+
+	stats = &btypes.BatchSpecResolutionJobStats{Counts: map[btypes.BatchSpecResolutionJobState]int{}}
+	err = s.query(ctx, q, func(sc scanner) error {
+		var (
+			state          btypes.BatchSpecResolutionJobState
+			count          int
+			oldestQueuedAt sql.NullTime
+			avgDuration    sql.NullFloat64
+		)
+		if err := sc.Scan(&state, &count, &oldestQueuedAt, &avgDuration); err != nil {
+			return err
+		}
+		stats.Counts[state] = count
+		if oldestQueuedAt.Valid {
+			stats.OldestQueuedAt = oldestQueuedAt.Time
+		}
+		if avgDuration.Valid {
+			stats.AverageProcessingTime = time.Duration(math.Round(avgDuration.Float64*1000)) * time.Millisecond
+		}
+		return nil
+	})
+
+	return stats, err
+}
+
+var getBatchSpecResolutionJobStatsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_jobs.go:GetBatchSpecResolutionJobStats
+SELECT
+	state,
+	COUNT(*),
+	MIN(created_at) FILTER (WHERE state = 'queued'),
+	AVG(EXTRACT(EPOCH FROM (finished_at - started_at))) FILTER (
+		WHERE state = 'completed' AND started_at IS NOT NULL AND finished_at >= %s
+	)
+FROM batch_spec_resolution_jobs
+GROUP BY state
+`
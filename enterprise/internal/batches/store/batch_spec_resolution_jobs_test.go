@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/keegancsmith/sqlf"
 	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
 	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
 )
 
 func testStoreBatchSpecResolutionJobs(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
@@ -24,6 +28,7 @@ func testStoreBatchSpecResolutionJobs(t *testing.T, ctx context.Context, s *Stor
 		switch i {
 		case 0:
 			job.State = btypes.BatchSpecResolutionJobStateQueued
+			job.Labels = map[string]string{"release": "R1", "pipeline": "123"}
 		case 1:
 			job.State = btypes.BatchSpecResolutionJobStateProcessing
 		case 2:
@@ -54,6 +59,77 @@ func testStoreBatchSpecResolutionJobs(t *testing.T, ctx context.Context, s *Stor
 		}
 	})
 
+	t.Run("CreateDeduplicates", func(t *testing.T) {
+		batchSpecID := int64(3000)
+		active := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID}
+		if err := s.CreateBatchSpecResolutionJob(ctx, active); err != nil {
+			t.Fatal(err)
+		}
+
+		// A second regular job for the same batch spec returns the active one.
+		duplicate := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID, AllowIgnored: true}
+		if err := s.CreateBatchSpecResolutionJob(ctx, duplicate); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(active, duplicate); diff != "" {
+			t.Fatalf("expected existing job to be returned: %s", diff)
+		}
+
+		// Dry runs are deduplicated separately from regular jobs.
+		dryRun := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID, DryRun: true}
+		if err := s.CreateBatchSpecResolutionJob(ctx, dryRun); err != nil {
+			t.Fatal(err)
+		}
+		if dryRun.ID == active.ID {
+			t.Fatal("dry run job was deduplicated against regular job")
+		}
+
+		// Once the active job is finished, a new one can be created.
+		if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = %s WHERE id = %s", btypes.BatchSpecResolutionJobStateCompleted, active.ID)); err != nil {
+			t.Fatal(err)
+		}
+		next := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID}
+		if err := s.CreateBatchSpecResolutionJob(ctx, next); err != nil {
+			t.Fatal(err)
+		}
+		if next.ID == active.ID {
+			t.Fatal("expected a new job after the previous one finished")
+		}
+
+		// Errored jobs are never retried, so they don't block a new one either.
+		if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = %s WHERE id = %s", btypes.BatchSpecResolutionJobStateErrored, next.ID)); err != nil {
+			t.Fatal(err)
+		}
+		afterError := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID}
+		if err := s.CreateBatchSpecResolutionJob(ctx, afterError); err != nil {
+			t.Fatal(err)
+		}
+		if afterError.ID == next.ID {
+			t.Fatal("expected a new job after the previous one errored")
+		}
+
+		if err := s.Exec(ctx, sqlf.Sprintf("DELETE FROM batch_spec_resolution_jobs WHERE batch_spec_id = %s", batchSpecID)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		have, err := s.GetBatchSpecResolutionJobStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &btypes.BatchSpecResolutionJobStats{
+			Counts: map[btypes.BatchSpecResolutionJobState]int{
+				btypes.BatchSpecResolutionJobStateQueued:     1,
+				btypes.BatchSpecResolutionJobStateProcessing: 1,
+			},
+			OldestQueuedAt: jobs[0].CreatedAt,
+		}
+		if diff := cmp.Diff(want, have); diff != "" {
+			t.Fatalf("invalid stats returned: %s", diff)
+		}
+	})
+
 	t.Run("Get", func(t *testing.T) {
 		t.Run("GetByID", func(t *testing.T) {
 			for i, job := range jobs {
@@ -85,6 +161,27 @@ func testStoreBatchSpecResolutionJobs(t *testing.T, ctx context.Context, s *Stor
 			}
 		})
 
+		t.Run("GetByBatchSpecWorkspaceID", func(t *testing.T) {
+			job := jobs[0]
+			ws := &btypes.BatchSpecWorkspace{BatchSpecID: job.BatchSpecID, ChangesetSpecIDs: []int64{}}
+			if err := s.CreateBatchSpecWorkspace(ctx, ws); err != nil {
+				t.Fatal(err)
+			}
+
+			have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{BatchSpecWorkspaceID: ws.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(have, job); diff != "" {
+				t.Fatal(diff)
+			}
+
+			_, err = s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{BatchSpecWorkspaceID: ws.ID + 1})
+			if err != ErrNoResults {
+				t.Fatalf("have err %v, want %v", err, ErrNoResults)
+			}
+		})
+
 		t.Run("NoResults", func(t *testing.T) {
 			opts := GetBatchSpecResolutionJobOpts{ID: 0xdeadbeef}
 
@@ -129,6 +226,28 @@ func testStoreBatchSpecResolutionJobs(t *testing.T, ctx context.Context, s *Stor
 			}
 		})
 
+		t.Run("Labels", func(t *testing.T) {
+			have, err := s.ListBatchSpecResolutionJobs(ctx, ListBatchSpecResolutionJobsOpts{
+				Labels: map[string]string{"release": "R1"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(have, jobs[:1]); diff != "" {
+				t.Fatalf("invalid batch spec resolution jobs returned: %s", diff)
+			}
+
+			have, err = s.ListBatchSpecResolutionJobs(ctx, ListBatchSpecResolutionJobsOpts{
+				Labels: map[string]string{"release": "R2"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != 0 {
+				t.Fatalf("expected no jobs, got %d", len(have))
+			}
+		})
+
 		t.Run("State", func(t *testing.T) {
 			for _, job := range jobs {
 				have, err := s.ListBatchSpecResolutionJobs(ctx, ListBatchSpecResolutionJobsOpts{
@@ -142,5 +261,671 @@ func testStoreBatchSpecResolutionJobs(t *testing.T, ctx context.Context, s *Stor
 				}
 			}
 		})
+
+		t.Run("BatchSpecIDs", func(t *testing.T) {
+			have, err := s.ListBatchSpecResolutionJobs(ctx, ListBatchSpecResolutionJobsOpts{
+				BatchSpecIDs: []int64{jobs[1].BatchSpecID, 9999},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(have, jobs[1:2]); diff != "" {
+				t.Fatalf("invalid batch spec resolution jobs returned: %s", diff)
+			}
+		})
+	})
+
+	t.Run("SetSearchQueries", func(t *testing.T) {
+		job := jobs[0]
+		queries := []string{"repo:^github\\.com/sourcegraph/ file:README count:all"}
+		if err := s.SetBatchSpecResolutionJobSearchQueries(ctx, job.ID, queries); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(have.SearchQueries, queries); diff != "" {
+			t.Fatalf("invalid search queries returned: %s", diff)
+		}
+		job.SearchQueries = queries
+	})
+
+	t.Run("SetProgress", func(t *testing.T) {
+		job := jobs[0]
+		progress := btypes.BatchSpecResolutionJobProgress{
+			ReposDiscovered:    12,
+			WorkspacesResolved: 3,
+			PercentComplete:    50,
+		}
+		if err := s.SetBatchSpecResolutionJobProgress(ctx, job.ID, progress); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(have.Progress, progress); diff != "" {
+			t.Fatalf("invalid progress returned: %s", diff)
+		}
+		job.Progress = progress
+	})
+
+	t.Run("SetSummary", func(t *testing.T) {
+		job := jobs[1]
+		summary := &btypes.BatchSpecResolutionJobSummary{
+			Version:     btypes.BatchSpecResolutionJobSummaryVersion,
+			JobID:       job.ID,
+			BatchSpecID: job.BatchSpecID,
+			State:       btypes.BatchSpecResolutionJobStateFailed,
+			Counts:      btypes.BatchSpecResolutionJobSummaryCounts{SearchQueries: 1},
+			Timings: btypes.BatchSpecResolutionJobSummaryTimings{
+				QueuedAt:   clock.Now().UTC(),
+				StartedAt:  clock.Now().UTC(),
+				FinishedAt: clock.Now().UTC(),
+			},
+			Failure: &btypes.BatchSpecResolutionJobSummaryFailure{
+				Category: btypes.BatchSpecResolutionFailureRepositorySearch,
+				Message:  "search failed",
+			},
+		}
+		if err := s.SetBatchSpecResolutionJobSummary(ctx, job.ID, summary); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(have.Summary, summary); diff != "" {
+			t.Fatalf("invalid summary returned: %s", diff)
+		}
+		job.Summary = summary
+	})
+
+	t.Run("SetRepositoryErrors", func(t *testing.T) {
+		job := jobs[1]
+		repoErrs := []*btypes.BatchSpecResolutionRepositoryError{
+			{RepoName: "github.com/sourcegraph/missing", Message: "repo not found"},
+			{RepoID: 1, RepoName: "github.com/sourcegraph/sourcegraph", Message: "checking for .batchignore file: timeout"},
+		}
+		if err := s.SetBatchSpecResolutionJobRepositoryErrors(ctx, job.ID, repoErrs); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(have.RepositoryErrors, repoErrs); diff != "" {
+			t.Fatalf("invalid repository errors returned: %s", diff)
+		}
+
+		// Setting no errors clears them.
+		if err := s.SetBatchSpecResolutionJobRepositoryErrors(ctx, job.ID, nil); err != nil {
+			t.Fatal(err)
+		}
+		have, err = s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(have.RepositoryErrors) != 0 {
+			t.Fatalf("repository errors not cleared: %+v", have.RepositoryErrors)
+		}
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		job := &btypes.BatchSpecResolutionJob{
+			BatchSpecID: jobs[0].BatchSpecID,
+			State:       btypes.BatchSpecResolutionJobStateQueued,
+			DryRun:      true,
+		}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{BatchSpecID: job.BatchSpecID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have.ID != jobs[0].ID {
+			t.Fatalf("expected regular job %d, got %d", jobs[0].ID, have.ID)
+		}
+
+		result := &btypes.BatchSpecResolutionDryRunResult{
+			Workspaces: []*btypes.BatchSpecResolutionDryRunWorkspace{
+				{RepoID: 1, Branch: "refs/heads/main", Commit: "d34db33f", Path: "a/b", FileMatches: []string{"a/b/c.go"}},
+			},
+			UnsupportedRepoIDs: []api.RepoID{2},
+			IgnoredRepoIDs:     []api.RepoID{3},
+		}
+		if err := s.SetBatchSpecResolutionJobDryRunResult(ctx, job.ID, result); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err = s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{BatchSpecID: job.BatchSpecID, DryRun: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have.ID != job.ID {
+			t.Fatalf("expected dry run job %d, got %d", job.ID, have.ID)
+		}
+		if diff := cmp.Diff(have.DryRunResult, result); diff != "" {
+			t.Fatalf("invalid dry run result returned: %s", diff)
+		}
+
+		dryRun := true
+		listed, err := s.ListBatchSpecResolutionJobs(ctx, ListBatchSpecResolutionJobsOpts{DryRun: &dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed) != 1 || listed[0].ID != job.ID {
+			t.Fatalf("expected only dry run job to be listed, got %+v", listed)
+		}
+	})
+
+	t.Run("ListNamespace", func(t *testing.T) {
+		user := ct.CreateTestUser(t, s.DB(), false)
+		orgID := int32(4711)
+
+		var userJobs, orgJobs []*btypes.BatchSpecResolutionJob
+		for i := 0; i < 4; i++ {
+			spec := &btypes.BatchSpec{UserID: user.ID}
+			if i%2 == 0 {
+				spec.NamespaceUserID = user.ID
+			} else {
+				spec.NamespaceOrgID = orgID
+			}
+			if err := s.CreateBatchSpec(ctx, spec); err != nil {
+				t.Fatal(err)
+			}
+
+			job := &btypes.BatchSpecResolutionJob{BatchSpecID: spec.ID, State: btypes.BatchSpecResolutionJobStateQueued}
+			if i >= 2 {
+				job.State = btypes.BatchSpecResolutionJobStateCompleted
+			}
+			if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			job.CreatedAt = clock.Now().Add(time.Duration(i) * time.Hour)
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET created_at = %s WHERE id = %s", job.CreatedAt, job.ID)); err != nil {
+				t.Fatal(err)
+			}
+
+			// Newest first.
+			if i%2 == 0 {
+				userJobs = append([]*btypes.BatchSpecResolutionJob{job}, userJobs...)
+			} else {
+				orgJobs = append([]*btypes.BatchSpecResolutionJob{job}, orgJobs...)
+			}
+		}
+
+		ids := func(jobs []*btypes.BatchSpecResolutionJob) []int64 {
+			ids := make([]int64, 0, len(jobs))
+			for _, j := range jobs {
+				ids = append(ids, j.ID)
+			}
+			return ids
+		}
+
+		for name, tc := range map[string]struct {
+			opts ListNamespaceBatchSpecResolutionJobsOpts
+			want []*btypes.BatchSpecResolutionJob
+		}{
+			"user": {
+				opts: ListNamespaceBatchSpecResolutionJobsOpts{NamespaceUserID: user.ID},
+				want: userJobs,
+			},
+			"org": {
+				opts: ListNamespaceBatchSpecResolutionJobsOpts{NamespaceOrgID: orgID},
+				want: orgJobs,
+			},
+			"state": {
+				opts: ListNamespaceBatchSpecResolutionJobsOpts{NamespaceOrgID: orgID, State: btypes.BatchSpecResolutionJobStateQueued},
+				want: orgJobs[1:],
+			},
+			"created after": {
+				opts: ListNamespaceBatchSpecResolutionJobsOpts{NamespaceUserID: user.ID, CreatedAfter: clock.Now().Add(time.Hour)},
+				want: userJobs[:1],
+			},
+			"created before": {
+				opts: ListNamespaceBatchSpecResolutionJobsOpts{NamespaceUserID: user.ID, CreatedBefore: clock.Now().Add(time.Hour)},
+				want: userJobs[1:],
+			},
+			"batch spec": {
+				opts: ListNamespaceBatchSpecResolutionJobsOpts{BatchSpecID: userJobs[0].BatchSpecID},
+				want: userJobs[:1],
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				have, next, err := s.ListNamespaceBatchSpecResolutionJobs(ctx, tc.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if next != 0 {
+					t.Fatalf("unexpected next cursor %d", next)
+				}
+				if diff := cmp.Diff(ids(tc.want), ids(have)); diff != "" {
+					t.Fatalf("invalid jobs returned: %s", diff)
+				}
+
+				count, err := s.CountNamespaceBatchSpecResolutionJobs(ctx, tc.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != len(tc.want) {
+					t.Fatalf("wrong count. want=%d, have=%d", len(tc.want), count)
+				}
+			})
+		}
+
+		t.Run("pagination", func(t *testing.T) {
+			opts := ListNamespaceBatchSpecResolutionJobsOpts{NamespaceUserID: user.ID, LimitOpts: LimitOpts{Limit: 1}}
+			have, next, err := s.ListNamespaceBatchSpecResolutionJobs(ctx, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(ids(userJobs[:1]), ids(have)); diff != "" {
+				t.Fatalf("invalid first page returned: %s", diff)
+			}
+			if next != userJobs[1].ID {
+				t.Fatalf("wrong next cursor. want=%d, have=%d", userJobs[1].ID, next)
+			}
+
+			opts.Cursor = next
+			have, next, err = s.ListNamespaceBatchSpecResolutionJobs(ctx, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(ids(userJobs[1:]), ids(have)); diff != "" {
+				t.Fatalf("invalid second page returned: %s", diff)
+			}
+			if next != 0 {
+				t.Fatalf("unexpected next cursor %d", next)
+			}
+		})
+
+		t.Run("list with creator filter", func(t *testing.T) {
+			for name, tc := range map[string]struct {
+				opts ListBatchSpecResolutionJobsOpts
+				want []*btypes.BatchSpecResolutionJob
+			}{
+				"creator": {
+					opts: ListBatchSpecResolutionJobsOpts{CreatorID: user.ID, State: btypes.BatchSpecResolutionJobStateCompleted},
+					want: []*btypes.BatchSpecResolutionJob{userJobs[0], orgJobs[0]},
+				},
+				"creator and batch spec": {
+					opts: ListBatchSpecResolutionJobsOpts{CreatorID: user.ID, BatchSpecIDs: []int64{orgJobs[1].BatchSpecID}},
+					want: orgJobs[1:],
+				},
+				"unknown creator": {
+					opts: ListBatchSpecResolutionJobsOpts{CreatorID: user.ID + 1000},
+					want: []*btypes.BatchSpecResolutionJob{},
+				},
+			} {
+				t.Run(name, func(t *testing.T) {
+					have, err := s.ListBatchSpecResolutionJobs(ctx, tc.opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(ids(tc.want), ids(have)); diff != "" {
+						t.Fatalf("invalid jobs returned: %s", diff)
+					}
+				})
+			}
+		})
+
+		t.Run("deleted namespace", func(t *testing.T) {
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE users SET deleted_at = NOW() WHERE id = %s", user.ID)); err != nil {
+				t.Fatal(err)
+			}
+			have, _, err := s.ListNamespaceBatchSpecResolutionJobs(ctx, ListNamespaceBatchSpecResolutionJobsOpts{NamespaceUserID: user.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != 0 {
+				t.Fatalf("expected no jobs for deleted namespace, got %d", len(have))
+			}
+		})
+	})
+
+	t.Run("ListDurationStats", func(t *testing.T) {
+		have, err := s.ListBatchSpecResolutionJobDurationStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(have) != 0 {
+			t.Fatalf("expected no stats without completed jobs, got %d", len(have))
+		}
+
+		for i, d := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second} {
+			job := &btypes.BatchSpecResolutionJob{BatchSpecID: int64(i + 1000)}
+			if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			startedAt := clock.Now().Add(-time.Hour)
+			if err := s.Exec(ctx, sqlf.Sprintf(
+				"UPDATE batch_spec_resolution_jobs SET state = 'completed', started_at = %s, finished_at = %s WHERE id = %s",
+				startedAt, startedAt.Add(d), job.ID,
+			)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		have, err = s.ListBatchSpecResolutionJobDurationStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*btypes.BatchSpecResolutionJobDurationStats{
+			{MaxRepos: 10, Count: 3, P50: 20 * time.Second, P90: 28 * time.Second},
+		}
+		if diff := cmp.Diff(have, want); diff != "" {
+			t.Fatalf("invalid stats returned: %s", diff)
+		}
+	})
+
+	t.Run("CleanUp", func(t *testing.T) {
+		retention := 24 * time.Hour
+		old := clock.Now().Add(-2 * retention)
+		recent := clock.Now().Add(-time.Hour)
+
+		batchSpecID := int64(2000)
+		create := func(dryRun bool, state btypes.BatchSpecResolutionJobState, finishedAt time.Time) *btypes.BatchSpecResolutionJob {
+			t.Helper()
+
+			batchSpecID++
+			job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID, DryRun: dryRun}
+			if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Exec(ctx, sqlf.Sprintf(
+				`UPDATE batch_spec_resolution_jobs SET state = %s, finished_at = %s, execution_logs = ARRAY['{"key": "step.1"}'::json] WHERE id = %s`,
+				state, finishedAt, job.ID,
+			)); err != nil {
+				t.Fatal(err)
+			}
+			return job
+		}
+
+		oldDryRun := create(true, btypes.BatchSpecResolutionJobStateCompleted, old)
+		oldFailedDryRun := create(true, btypes.BatchSpecResolutionJobStateFailed, old)
+		erroredDryRun := create(true, btypes.BatchSpecResolutionJobStateErrored, old)
+		recentDryRun := create(true, btypes.BatchSpecResolutionJobStateCompleted, recent)
+		processingDryRun := create(true, btypes.BatchSpecResolutionJobStateProcessing, old)
+		oldRegular := create(false, btypes.BatchSpecResolutionJobStateCompleted, old)
+		failedRegular := create(false, btypes.BatchSpecResolutionJobStateFailed, old)
+		erroredRegular := create(false, btypes.BatchSpecResolutionJobStateErrored, old)
+		queuedRegular := create(false, btypes.BatchSpecResolutionJobStateQueued, old)
+		recentRegular := create(false, btypes.BatchSpecResolutionJobStateCompleted, recent)
+
+		finishedBefore := clock.Now().Add(-retention)
+		dryRun := true
+		if _, err := s.DeleteBatchSpecResolutionJobs(ctx, DeleteBatchSpecResolutionJobsOpts{
+			DryRun: &dryRun,
+			States: []btypes.BatchSpecResolutionJobState{
+				btypes.BatchSpecResolutionJobStateCompleted,
+				btypes.BatchSpecResolutionJobStateFailed,
+				btypes.BatchSpecResolutionJobStateErrored,
+			},
+			FinishedBefore: finishedBefore,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PurgeBatchSpecResolutionJobLogs(ctx, finishedBefore); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, job := range []*btypes.BatchSpecResolutionJob{oldDryRun, oldFailedDryRun, erroredDryRun} {
+			if _, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID}); err != ErrNoResults {
+				t.Fatalf("expected job %d to be deleted, got err=%v", job.ID, err)
+			}
+		}
+
+		for job, wantLogs := range map[*btypes.BatchSpecResolutionJob]bool{
+			recentDryRun:     true,
+			processingDryRun: true,
+			oldRegular:       false,
+			failedRegular:    false,
+			erroredRegular:   false,
+			queuedRegular:    true,
+			recentRegular:    true,
+		} {
+			have, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+			if err != nil {
+				t.Fatalf("expected job %d to be kept, got err=%v", job.ID, err)
+			}
+			if hasLogs := len(have.ExecutionLogs) > 0; hasLogs != wantLogs {
+				t.Fatalf("job %d: wrong execution logs. want=%t, have=%t", job.ID, wantLogs, hasLogs)
+			}
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		create := func(batchSpecID int64, state btypes.BatchSpecResolutionJobState, createdAt time.Time) *btypes.BatchSpecResolutionJob {
+			t.Helper()
+
+			job := &btypes.BatchSpecResolutionJob{BatchSpecID: batchSpecID}
+			if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Exec(ctx, sqlf.Sprintf(
+				`UPDATE batch_spec_resolution_jobs SET state = %s, created_at = %s WHERE id = %s`,
+				state, createdAt, job.ID,
+			)); err != nil {
+				t.Fatal(err)
+			}
+			return job
+		}
+		assertDeleted := func(job *btypes.BatchSpecResolutionJob, wantDeleted bool) {
+			t.Helper()
+
+			_, err := s.GetBatchSpecResolutionJob(ctx, GetBatchSpecResolutionJobOpts{ID: job.ID})
+			if deleted := err == ErrNoResults; deleted != wantDeleted {
+				t.Fatalf("job %d: want deleted=%t, have err=%v", job.ID, wantDeleted, err)
+			}
+		}
+
+		old := clock.Now().Add(-48 * time.Hour)
+		queued := create(5001, btypes.BatchSpecResolutionJobStateQueued, clock.Now())
+		failed := create(5002, btypes.BatchSpecResolutionJobStateFailed, clock.Now())
+		oldFailed := create(5003, btypes.BatchSpecResolutionJobStateFailed, old)
+		oldCompleted := create(5004, btypes.BatchSpecResolutionJobStateCompleted, old)
+
+		if _, err := s.DeleteBatchSpecResolutionJobs(ctx, DeleteBatchSpecResolutionJobsOpts{}); err == nil {
+			t.Fatal("deleting without options did not fail")
+		}
+
+		deletedIDs, err := s.DeleteBatchSpecResolutionJobs(ctx, DeleteBatchSpecResolutionJobsOpts{BatchSpecIDs: []int64{5001}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]int64{queued.ID}, deletedIDs); diff != "" {
+			t.Fatalf("wrong deleted IDs (-want +got):\n%s", diff)
+		}
+		assertDeleted(queued, true)
+		assertDeleted(failed, false)
+
+		if _, err := s.DeleteBatchSpecResolutionJobs(ctx, DeleteBatchSpecResolutionJobsOpts{
+			States:        []btypes.BatchSpecResolutionJobState{btypes.BatchSpecResolutionJobStateFailed},
+			CreatedBefore: clock.Now().Add(-24 * time.Hour),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		assertDeleted(failed, false)
+		assertDeleted(oldFailed, true)
+		assertDeleted(oldCompleted, false)
+	})
+
+	t.Run("GetLogs", func(t *testing.T) {
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: 4000}
+		if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Exec(ctx, sqlf.Sprintf(
+			`UPDATE batch_spec_resolution_jobs SET state = 'processing', execution_logs = ARRAY['{"key": "step.0"}'::json, '{"key": "step.1"}'::json, '{"key": "step.2"}'::json] WHERE id = %s`,
+			job.ID,
+		)); err != nil {
+			t.Fatal(err)
+		}
+
+		keys := func(logs *btypes.BatchSpecResolutionJobLogs) []string {
+			keys := []string{}
+			for _, e := range logs.Entries {
+				keys = append(keys, e.Key)
+			}
+			return keys
+		}
+
+		for offset, want := range map[int][]string{
+			0: {"step.0", "step.1", "step.2"},
+			2: {"step.2"},
+			5: {},
+		} {
+			have, err := s.GetBatchSpecResolutionJobLogs(ctx, job.ID, offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, keys(have)); diff != "" {
+				t.Fatalf("offset %d: invalid entries returned: %s", offset, diff)
+			}
+			if have.Total != 3 || have.Offset != offset || have.State != btypes.BatchSpecResolutionJobStateProcessing {
+				t.Fatalf("offset %d: invalid logs returned: %+v", offset, have)
+			}
+		}
+
+		if _, err := s.GetBatchSpecResolutionJobLogs(ctx, job.ID+1000, 0); err != ErrNoResults {
+			t.Fatalf("unexpected error for unknown job: %v", err)
+		}
+	})
+
+	t.Run("SearchLogs", func(t *testing.T) {
+		// The logs are part of the query format string, so "%" is escaped.
+		jobs := make([]*btypes.BatchSpecResolutionJob, 0, 3)
+		for i, logs := range []string{
+			`ARRAY['{"key": "step.0", "out": "resolving repositories"}'::json, '{"key": "step.1", "out": "Repository NOT FOUND: github.com/a/b"}'::json]`,
+			`ARRAY['{"key": "step.0", "out": "repository not found: github.com/c/d"}'::json, '{"key": "step.1", "out": "100%% done"}'::json, '{"key": "step.2", "out": "repository not found: github.com/e/f"}'::json]`,
+			`ARRAY['{"key": "step.0", "out": "all good"}'::json]`,
+		} {
+			job := &btypes.BatchSpecResolutionJob{BatchSpecID: int64(5000 + i)}
+			if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = 'failed', execution_logs = "+logs+" WHERE id = %s", job.ID)); err != nil {
+				t.Fatal(err)
+			}
+			jobs = append(jobs, job)
+		}
+
+		type match struct {
+			JobID int64
+			Index int
+			Key   string
+		}
+		matches := func(ms []*btypes.BatchSpecResolutionJobLogMatch) []match {
+			have := []match{}
+			for _, m := range ms {
+				have = append(have, match{m.JobID, m.Index, m.Entry.Key})
+			}
+			return have
+		}
+
+		for name, tc := range map[string]struct {
+			query string
+			opts  SearchBatchSpecResolutionJobLogsOpts
+			want  []match
+		}{
+			"case insensitive": {
+				query: "repository not found",
+				want: []match{
+					{jobs[1].ID, 0, "step.0"},
+					{jobs[1].ID, 2, "step.2"},
+					{jobs[0].ID, 1, "step.1"},
+				},
+			},
+			"batch spec": {
+				query: "repository not found",
+				opts:  SearchBatchSpecResolutionJobLogsOpts{BatchSpecID: jobs[0].BatchSpecID},
+				want:  []match{{jobs[0].ID, 1, "step.1"}},
+			},
+			"literal wildcards": {
+				query: "100%",
+				want:  []match{{jobs[1].ID, 1, "step.1"}},
+			},
+			"no matches": {
+				query: "not in any log",
+				want:  []match{},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				have, next, err := s.SearchBatchSpecResolutionJobLogs(ctx, tc.query, tc.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if next != 0 {
+					t.Fatalf("unexpected next cursor %d", next)
+				}
+				if diff := cmp.Diff(tc.want, matches(have)); diff != "" {
+					t.Fatalf("invalid matches returned: %s", diff)
+				}
+			})
+		}
+
+		t.Run("pagination", func(t *testing.T) {
+			opts := SearchBatchSpecResolutionJobLogsOpts{LimitOpts: LimitOpts{Limit: 1}}
+			have, next, err := s.SearchBatchSpecResolutionJobLogs(ctx, "repository not found", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]match{{jobs[1].ID, 0, "step.0"}, {jobs[1].ID, 2, "step.2"}}, matches(have)); diff != "" {
+				t.Fatalf("invalid first page returned: %s", diff)
+			}
+			if next != jobs[0].ID {
+				t.Fatalf("wrong next cursor. want=%d, have=%d", jobs[0].ID, next)
+			}
+
+			opts.Cursor = next
+			have, next, err = s.SearchBatchSpecResolutionJobLogs(ctx, "repository not found", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]match{{jobs[0].ID, 1, "step.1"}}, matches(have)); diff != "" {
+				t.Fatalf("invalid second page returned: %s", diff)
+			}
+			if next != 0 {
+				t.Fatalf("unexpected next cursor %d", next)
+			}
+		})
+
+		t.Run("logs written by the resolution worker", func(t *testing.T) {
+			// The resolution worker writes a single entry through the worker
+			// store and keeps appending lines to it while the job runs.
+			job := &btypes.BatchSpecResolutionJob{BatchSpecID: 5100}
+			if err := s.CreateBatchSpecResolutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			workStore := dbworkerstore.New(s.Handle(), BatchSpecResolutionWorkerStoreOptions)
+			entry := workerutil.ExecutionLogEntry{Key: "resolve.workspaces", Out: "Searching for repositories: r:github.com/sourcegraph\n"}
+			entryID, err := workStore.AddExecutionLogEntry(ctx, int(job.ID), entry, dbworkerstore.ExecutionLogEntryOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry.Out += "Failed to resolve github.com/sourcegraph/private: Unauthorized\n"
+			if err := workStore.UpdateExecutionLogEntry(ctx, int(job.ID), entryID, entry, dbworkerstore.ExecutionLogEntryOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			have, _, err := s.SearchBatchSpecResolutionJobLogs(ctx, "unauthorized", SearchBatchSpecResolutionJobLogsOpts{BatchSpecID: job.BatchSpecID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]match{{job.ID, 0, "resolve.workspaces"}}, matches(have)); diff != "" {
+				t.Fatalf("invalid matches returned: %s", diff)
+			}
+			if have[0].Entry.Out != entry.Out {
+				t.Fatalf("wrong entry returned: %q", have[0].Entry.Out)
+			}
+		})
 	})
 }
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// GetBatchSpecResolutionQuotaUsageOpts captures the query options needed for
+// getting the quota usage of a namespace. Exactly one of NamespaceUserID and
+// NamespaceOrgID must be set.
+type GetBatchSpecResolutionQuotaUsageOpts struct {
+	NamespaceUserID int32
+	NamespaceOrgID  int32
+}
+
+// GetBatchSpecResolutionQuotaUsage returns how much of the
+// batchChanges.resolutionQuota the given namespace currently uses.
+func (s *Store) GetBatchSpecResolutionQuotaUsage(ctx context.Context, opts GetBatchSpecResolutionQuotaUsageOpts) (usage btypes.BatchSpecResolutionQuotaUsage, err error) {
+	ctx, endObservation := s.operations.getBatchSpecResolutionQuotaUsage.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("namespaceUserID", int(opts.NamespaceUserID)),
+		log.Int("namespaceOrgID", int(opts.NamespaceOrgID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	return s.getBatchSpecResolutionQuotaUsage(ctx, opts)
+}
+
+func (s *Store) getBatchSpecResolutionQuotaUsage(ctx context.Context, opts GetBatchSpecResolutionQuotaUsageOpts) (usage btypes.BatchSpecResolutionQuotaUsage, err error) {
+	var specPred, chargePred *sqlf.Query
+	switch {
+	case opts.NamespaceUserID != 0 && opts.NamespaceOrgID == 0:
+		specPred = sqlf.Sprintf("batch_specs.namespace_user_id = %s", opts.NamespaceUserID)
+		chargePred = sqlf.Sprintf("batch_spec_resolution_quota_charges.namespace_user_id = %s", opts.NamespaceUserID)
+	case opts.NamespaceOrgID != 0 && opts.NamespaceUserID == 0:
+		specPred = sqlf.Sprintf("batch_specs.namespace_org_id = %s", opts.NamespaceOrgID)
+		chargePred = sqlf.Sprintf("batch_spec_resolution_quota_charges.namespace_org_id = %s", opts.NamespaceOrgID)
+	default:
+		return usage, errors.New("exactly one of NamespaceUserID and NamespaceOrgID must be set")
+	}
+
+	q := sqlf.Sprintf(
+		getBatchSpecResolutionQuotaUsageQueryFmtstr,
+		btypes.BatchSpecResolutionJobStateQueued,
+		btypes.BatchSpecResolutionJobStateProcessing,
+		specPred,
+		chargePred,
+		s.now().Add(-btypes.BatchSpecResolutionQuotaWindow),
+	)
+
+	err = s.query(ctx, q, func(sc scanner) error {
+		return sc.Scan(&usage.Concurrent, &usage.LastDay)
+	})
+	return usage, err
+}
+
+// The daily usage is counted from the charges instead of the jobs, since jobs
+// are deleted when their batch spec is replaced or expires.
+var getBatchSpecResolutionQuotaUsageQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_quota.go:getBatchSpecResolutionQuotaUsage
+SELECT
+	(
+		SELECT COUNT(*)
+		FROM batch_spec_resolution_jobs
+		JOIN batch_specs ON batch_specs.id = batch_spec_resolution_jobs.batch_spec_id
+		WHERE batch_spec_resolution_jobs.state IN (%s, %s) AND %s
+	),
+	(
+		SELECT COUNT(*)
+		FROM batch_spec_resolution_quota_charges
+		WHERE %s AND batch_spec_resolution_quota_charges.created_at >= %s
+	)
+`
+
+// chargeBatchSpecResolutionQuota charges a new resolution job for the given
+// batch spec to the quota of its namespace. It returns a
+// btypes.ErrBatchSpecResolutionQuotaExceeded if the job would exceed the
+// quota. It must be called in a transaction, since it locks the namespace
+// until the transaction ends, so that concurrent enqueues can't both squeeze
+// in the last job. Jobs are charged even if the quota is unlimited, so that
+// a quota applies to the jobs of the last day once it's configured.
+func (s *Store) chargeBatchSpecResolutionQuota(ctx context.Context, batchSpecID int64) error {
+	var opts GetBatchSpecResolutionQuotaUsageOpts
+	var found bool
+	err := s.query(ctx, sqlf.Sprintf(getBatchSpecNamespaceQueryFmtstr, batchSpecID), func(sc scanner) error {
+		found = true
+		return sc.Scan(&dbutil.NullInt32{N: &opts.NamespaceUserID}, &dbutil.NullInt32{N: &opts.NamespaceOrgID})
+	})
+	if err != nil {
+		return err
+	}
+	// Without a batch spec there is no namespace to charge the job to.
+	if !found {
+		return nil
+	}
+
+	key := fmt.Sprintf("batch_spec_resolution_quota:%d:%d", opts.NamespaceUserID, opts.NamespaceOrgID)
+	if err := s.exec(ctx, sqlf.Sprintf(lockBatchSpecResolutionQuotaQueryFmtstr, key)); err != nil {
+		return err
+	}
+
+	if quota := btypes.CurrentBatchSpecResolutionQuota(); !quota.Unlimited() {
+		usage, err := s.getBatchSpecResolutionQuotaUsage(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if err := usage.Check(quota); err != nil {
+			return err
+		}
+	}
+
+	return s.exec(ctx, sqlf.Sprintf(
+		chargeBatchSpecResolutionQuotaQueryFmtstr,
+		nullInt32Column(opts.NamespaceUserID),
+		nullInt32Column(opts.NamespaceOrgID),
+		s.now(),
+		nullInt32Column(opts.NamespaceUserID),
+		nullInt32Column(opts.NamespaceOrgID),
+		s.now().Add(-btypes.BatchSpecResolutionQuotaWindow),
+	))
+}
+
+var getBatchSpecNamespaceQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_quota.go:chargeBatchSpecResolutionQuota
+SELECT namespace_user_id, namespace_org_id FROM batch_specs WHERE id = %s
+`
+
+var lockBatchSpecResolutionQuotaQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_quota.go:chargeBatchSpecResolutionQuota
+SELECT pg_advisory_xact_lock(hashtext(%s))
+`
+
+// Charges that fell out of the window are no longer needed, so they're
+// removed while the namespace is locked anyway.
+var chargeBatchSpecResolutionQuotaQueryFmtstr = `
+-- source: enterprise/internal/batches/store/batch_spec_resolution_quota.go:chargeBatchSpecResolutionQuota
+WITH charge AS (
+	INSERT INTO batch_spec_resolution_quota_charges (namespace_user_id, namespace_org_id, created_at)
+	VALUES (%s, %s, %s)
+)
+DELETE FROM batch_spec_resolution_quota_charges
+WHERE
+	namespace_user_id IS NOT DISTINCT FROM %s AND
+	namespace_org_id IS NOT DISTINCT FROM %s AND
+	created_at < %s
+`
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func testStoreBatchSpecResolutionQuota(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	user := ct.CreateTestUser(t, s.DB(), false)
+	orgID := int32(4711)
+
+	createJob := func(t *testing.T, spec *btypes.BatchSpec) (*btypes.BatchSpecResolutionJob, error) {
+		t.Helper()
+		spec.UserID = user.ID
+		if err := s.CreateBatchSpec(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+		job := &btypes.BatchSpecResolutionJob{BatchSpecID: spec.ID}
+		return job, s.CreateBatchSpecResolutionJob(ctx, job)
+	}
+	setJob := func(t *testing.T, job *btypes.BatchSpecResolutionJob, state btypes.BatchSpecResolutionJobState, createdAt time.Time) {
+		t.Helper()
+		if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = %s, created_at = %s WHERE id = %s", state, createdAt, job.ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// ageOldestCharge moves the oldest quota charge of the namespace to the
+	// given time.
+	ageOldestCharge := func(t *testing.T, opts GetBatchSpecResolutionQuotaUsageOpts, createdAt time.Time) {
+		t.Helper()
+		if err := s.Exec(ctx, sqlf.Sprintf(
+			`UPDATE batch_spec_resolution_quota_charges SET created_at = %s WHERE id = (
+				SELECT MIN(id) FROM batch_spec_resolution_quota_charges
+				WHERE namespace_user_id IS NOT DISTINCT FROM %s AND namespace_org_id IS NOT DISTINCT FROM %s
+			)`,
+			createdAt,
+			nullInt32Column(opts.NamespaceUserID),
+			nullInt32Column(opts.NamespaceOrgID),
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertUsage := func(t *testing.T, opts GetBatchSpecResolutionQuotaUsageOpts, want btypes.BatchSpecResolutionQuotaUsage) {
+		t.Helper()
+		have, err := s.GetBatchSpecResolutionQuotaUsage(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have != want {
+			t.Fatalf("wrong usage. want=%+v, have=%+v", want, have)
+		}
+	}
+	assertExceeded := func(t *testing.T, err error, limit string) {
+		t.Helper()
+		var e btypes.ErrBatchSpecResolutionQuotaExceeded
+		if !errors.As(err, &e) {
+			t.Fatalf("expected quota exceeded error, got %v", err)
+		}
+		if e.Limit != limit {
+			t.Fatalf("wrong limit exceeded. want=%q, have=%q", limit, e.Limit)
+		}
+	}
+
+	userNamespace := GetBatchSpecResolutionQuotaUsageOpts{NamespaceUserID: user.ID}
+	orgNamespace := GetBatchSpecResolutionQuotaUsageOpts{NamespaceOrgID: orgID}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			job, err := createJob(t, &btypes.BatchSpec{NamespaceOrgID: orgID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			setJob(t, job, btypes.BatchSpecResolutionJobStateCompleted, job.CreatedAt)
+		}
+		// Jobs are charged even without a quota.
+		assertUsage(t, orgNamespace, btypes.BatchSpecResolutionQuotaUsage{LastDay: 3})
+
+		for i := 0; i < 3; i++ {
+			ageOldestCharge(t, orgNamespace, clock.Now().Add(-48*time.Hour))
+		}
+		assertUsage(t, orgNamespace, btypes.BatchSpecResolutionQuotaUsage{})
+	})
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		BatchChangesResolutionQuota: &schema.BatchChangeResolutionQuota{MaxConcurrent: 2, MaxPerDay: 3},
+	}})
+	t.Cleanup(func() { conf.Mock(nil) })
+
+	var jobs []*btypes.BatchSpecResolutionJob
+
+	t.Run("Concurrent", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			job, err := createJob(t, &btypes.BatchSpec{NamespaceUserID: user.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			jobs = append(jobs, job)
+		}
+		assertUsage(t, userNamespace, btypes.BatchSpecResolutionQuotaUsage{Concurrent: 2, LastDay: 2})
+
+		_, err := createJob(t, &btypes.BatchSpec{NamespaceUserID: user.ID})
+		assertExceeded(t, err, "concurrent")
+
+		// Errored jobs are never retried, so they don't take up a slot.
+		setJob(t, jobs[1], btypes.BatchSpecResolutionJobStateErrored, jobs[1].CreatedAt)
+		assertUsage(t, userNamespace, btypes.BatchSpecResolutionQuotaUsage{Concurrent: 1, LastDay: 2})
+
+		// Other namespaces have their own quota.
+		if _, err := createJob(t, &btypes.BatchSpec{NamespaceOrgID: orgID}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Daily", func(t *testing.T) {
+		setJob(t, jobs[0], btypes.BatchSpecResolutionJobStateCompleted, jobs[0].CreatedAt)
+		job, err := createJob(t, &btypes.BatchSpec{NamespaceUserID: user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+
+		setJob(t, job, btypes.BatchSpecResolutionJobStateFailed, job.CreatedAt)
+		assertUsage(t, userNamespace, btypes.BatchSpecResolutionQuotaUsage{Concurrent: 0, LastDay: 3})
+
+		_, err = createJob(t, &btypes.BatchSpec{NamespaceUserID: user.ID})
+		assertExceeded(t, err, "daily")
+
+		// Deleting jobs, as replacing a batch spec does, doesn't give back
+		// the quota they used.
+		if err := s.Exec(ctx, sqlf.Sprintf("DELETE FROM batch_spec_resolution_jobs WHERE id = %s", jobs[0].ID)); err != nil {
+			t.Fatal(err)
+		}
+		assertUsage(t, userNamespace, btypes.BatchSpecResolutionQuotaUsage{Concurrent: 0, LastDay: 3})
+		_, err = createJob(t, &btypes.BatchSpec{NamespaceUserID: user.ID})
+		assertExceeded(t, err, "daily")
+
+		// Once a job falls out of the window, another one can be created.
+		ageOldestCharge(t, userNamespace, clock.Now().Add(-btypes.BatchSpecResolutionQuotaWindow-time.Minute))
+		spec := &btypes.BatchSpec{NamespaceUserID: user.ID}
+		job, err = createJob(t, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertUsage(t, userNamespace, btypes.BatchSpecResolutionQuotaUsage{Concurrent: 1, LastDay: 3})
+
+		t.Run("Existing job", func(t *testing.T) {
+			// The quota is used up, but enqueueing a spec that is already
+			// being resolved doesn't create a new job.
+			existing := &btypes.BatchSpecResolutionJob{BatchSpecID: spec.ID}
+			if err := s.CreateBatchSpecResolutionJob(ctx, existing); err != nil {
+				t.Fatal(err)
+			}
+			if existing.ID != job.ID {
+				t.Fatalf("expected existing job %d, got %d", job.ID, existing.ID)
+			}
+		})
+	})
+
+	t.Run("No namespace", func(t *testing.T) {
+		if _, err := s.GetBatchSpecResolutionQuotaUsage(ctx, GetBatchSpecResolutionQuotaUsageOpts{}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
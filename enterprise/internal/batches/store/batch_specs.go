@@ -24,6 +24,7 @@ var batchSpecColumns = []*sqlf.Query{
 	sqlf.Sprintf("batch_specs.namespace_user_id"),
 	sqlf.Sprintf("batch_specs.namespace_org_id"),
 	sqlf.Sprintf("batch_specs.user_id"),
+	sqlf.Sprintf("batch_specs.no_cache"),
 	sqlf.Sprintf("batch_specs.created_at"),
 	sqlf.Sprintf("batch_specs.updated_at"),
 }
@@ -37,11 +38,12 @@ var batchSpecInsertColumns = []*sqlf.Query{
 	sqlf.Sprintf("namespace_user_id"),
 	sqlf.Sprintf("namespace_org_id"),
 	sqlf.Sprintf("user_id"),
+	sqlf.Sprintf("no_cache"),
 	sqlf.Sprintf("created_at"),
 	sqlf.Sprintf("updated_at"),
 }
 
-const batchSpecInsertColsFmt = `(%s, %s, %s, %s, %s, %s, %s, %s)`
+const batchSpecInsertColsFmt = `(%s, %s, %s, %s, %s, %s, %s, %s, %s)`
 
 // CreateBatchSpec creates the given BatchSpec.
 func (s *Store) CreateBatchSpec(ctx context.Context, c *btypes.BatchSpec) (err error) {
@@ -90,6 +92,7 @@ func (s *Store) createBatchSpecQuery(c *btypes.BatchSpec) (*sqlf.Query, error) {
 		nullInt32Column(c.NamespaceUserID),
 		nullInt32Column(c.NamespaceOrgID),
 		nullInt32Column(c.UserID),
+		c.NoCache,
 		c.CreatedAt,
 		c.UpdatedAt,
 		sqlf.Join(batchSpecColumns, ", "),
@@ -137,6 +140,7 @@ func (s *Store) updateBatchSpecQuery(c *btypes.BatchSpec) (*sqlf.Query, error) {
 		nullInt32Column(c.NamespaceUserID),
 		nullInt32Column(c.NamespaceOrgID),
 		nullInt32Column(c.UserID),
+		c.NoCache,
 		c.CreatedAt,
 		c.UpdatedAt,
 		c.ID,
@@ -405,15 +409,24 @@ ON
 }
 
 // DeleteExpiredBatchSpecs deletes BatchSpecs that have not been attached
-// to a Batch change within BatchSpecTTL.
-func (s *Store) DeleteExpiredBatchSpecs(ctx context.Context) (err error) {
+// to a Batch change within BatchSpecTTL. It returns the IDs of the deleted
+// BatchSpecs.
+func (s *Store) DeleteExpiredBatchSpecs(ctx context.Context) (deletedIDs []int64, err error) {
 	ctx, endObservation := s.operations.deleteExpiredBatchSpecs.With(ctx, &err, observation.Args{})
 	defer endObservation(1, observation.Args{})
 
 	expirationTime := s.now().Add(-btypes.BatchSpecTTL)
 	q := sqlf.Sprintf(deleteExpiredBatchSpecsQueryFmtstr, expirationTime)
 
-	return s.Store.Exec(ctx, q)
+	err = s.query(ctx, q, func(sc scanner) error {
+		var id int64
+		if err := sc.Scan(&id); err != nil {
+			return err
+		}
+		deletedIDs = append(deletedIDs, id)
+		return nil
+	})
+	return deletedIDs, err
 }
 
 var deleteExpiredBatchSpecsQueryFmtstr = `
@@ -428,6 +441,7 @@ AND NOT EXISTS (
 AND NOT EXISTS (
   SELECT 1 FROM changeset_specs WHERE batch_spec_id = batch_specs.id
 )
+RETURNING id
 `
 
 func scanBatchSpec(c *btypes.BatchSpec, s scanner) error {
@@ -441,6 +455,7 @@ func scanBatchSpec(c *btypes.BatchSpec, s scanner) error {
 		&dbutil.NullInt32{N: &c.NamespaceUserID},
 		&dbutil.NullInt32{N: &c.NamespaceOrgID},
 		&dbutil.NullInt32{N: &c.UserID},
+		&c.NoCache,
 		&c.CreatedAt,
 		&c.UpdatedAt,
 	)
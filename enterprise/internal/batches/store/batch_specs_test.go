@@ -343,9 +343,13 @@ func testStoreBatchSpecs(t *testing.T, ctx context.Context, s *Store, clock ct.C
 				}
 			}
 
-			if err := s.DeleteExpiredBatchSpecs(ctx); err != nil {
+			deletedIDs, err := s.DeleteExpiredBatchSpecs(ctx)
+			if err != nil {
 				t.Fatal(err)
 			}
+			if deleted := len(deletedIDs) == 1 && deletedIDs[0] == batchSpec.ID; deleted != tc.wantDeleted {
+				t.Fatalf("tc=%+v\n\t wrong deleted IDs returned: %v", tc, deletedIDs)
+			}
 
 			haveBatchSpecs, err := s.GetBatchSpec(ctx, GetBatchSpecOpts{ID: batchSpec.ID})
 			if err != nil && err != ErrNoResults {
@@ -167,6 +167,37 @@ func getChangesetJobQuery(opts *GetChangesetJobOpts) *sqlf.Query {
 	)
 }
 
+// ExpediteChangesetJobs makes the queued changeset jobs of the given type on the
+// given changeset available to the bulk processor right away, instead of after
+// their processing delay. It's used to re-evaluate jobs waiting on the state of a
+// changeset as soon as it changes.
+func (s *Store) ExpediteChangesetJobs(ctx context.Context, changesetID int64, jobType btypes.ChangesetJobType) (err error) {
+	ctx, endObservation := s.operations.expediteChangesetJobs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("changesetID", int(changesetID)),
+		log.String("jobType", string(jobType)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	return s.Store.Exec(ctx, sqlf.Sprintf(
+		expediteChangesetJobsQueryFmtstr,
+		s.now(),
+		changesetID,
+		jobType,
+		btypes.ChangesetJobStateQueued.ToDB(),
+	))
+}
+
+var expediteChangesetJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/changeset_jobs.go:ExpediteChangesetJobs
+UPDATE changeset_jobs
+SET process_after = NULL, updated_at = %s
+WHERE
+	changeset_id = %s AND
+	job_type = %s AND
+	state = %s AND
+	process_after IS NOT NULL
+`
+
 func scanChangesetJob(c *btypes.ChangesetJob, s scanner) error {
 	var raw json.RawMessage
 	if err := s.Scan(
@@ -202,6 +233,8 @@ func scanChangesetJob(c *btypes.ChangesetJob, s scanner) error {
 		c.Payload = new(btypes.ChangesetJobClosePayload)
 	case btypes.ChangesetJobTypePublish:
 		c.Payload = new(btypes.ChangesetJobPublishPayload)
+	case btypes.ChangesetJobTypeMergeWhenChecksPass:
+		c.Payload = new(btypes.ChangesetJobMergeWhenChecksPassPayload)
 	default:
 		return errors.Errorf("unknown job type %q", c.JobType)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -107,4 +108,45 @@ func testStoreChangesetJobs(t *testing.T, ctx context.Context, s *Store, clock c
 			}
 		})
 	})
+	t.Run("Expedite", func(t *testing.T) {
+		waiting := &btypes.ChangesetJob{
+			UserID:       1234,
+			ChangesetID:  changeset.ID,
+			JobType:      btypes.ChangesetJobTypeMergeWhenChecksPass,
+			Payload:      &btypes.ChangesetJobMergeWhenChecksPassPayload{},
+			State:        btypes.ChangesetJobStateQueued,
+			ProcessAfter: clock.Now().Add(time.Hour),
+		}
+		other := &btypes.ChangesetJob{
+			UserID:       1234,
+			ChangesetID:  changeset.ID,
+			JobType:      btypes.ChangesetJobTypeComment,
+			Payload:      &btypes.ChangesetJobCommentPayload{},
+			State:        btypes.ChangesetJobStateQueued,
+			ProcessAfter: clock.Now().Add(time.Hour),
+		}
+		if err := s.CreateChangesetJob(ctx, waiting, other); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.ExpediteChangesetJobs(ctx, changeset.ID, btypes.ChangesetJobTypeMergeWhenChecksPass); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetChangesetJob(ctx, GetChangesetJobOpts{ID: waiting.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !have.ProcessAfter.IsZero() {
+			t.Fatalf("expected the job to be expedited, but process_after is %s", have.ProcessAfter)
+		}
+
+		have, err = s.GetChangesetJob(ctx, GetChangesetJobOpts{ID: other.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have.ProcessAfter.IsZero() {
+			t.Fatal("expected the job of another type not to be expedited")
+		}
+	})
 }
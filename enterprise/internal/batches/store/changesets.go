@@ -22,7 +22,9 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
@@ -64,6 +66,8 @@ var ChangesetColumns = []*sqlf.Query{
 	sqlf.Sprintf("changesets.num_failures"),
 	sqlf.Sprintf("changesets.closing"),
 	sqlf.Sprintf("changesets.syncer_error"),
+	sqlf.Sprintf("changesets.rebase_base_rev"),
+	sqlf.Sprintf("changesets.rebase_conflict"),
 }
 
 // changesetInsertColumns is the list of changeset columns that are modified in
@@ -100,6 +104,8 @@ var changesetInsertColumns = []*sqlf.Query{
 	sqlf.Sprintf("num_failures"),
 	sqlf.Sprintf("closing"),
 	sqlf.Sprintf("syncer_error"),
+	sqlf.Sprintf("rebase_base_rev"),
+	sqlf.Sprintf("rebase_conflict"),
 	// We additionally store the result of changeset.Title() in a column, so
 	// the business logic for determining it is in one place and the field is
 	// indexable for searching.
@@ -181,6 +187,8 @@ func (s *Store) changesetWriteQuery(q string, includeID bool, c *btypes.Changese
 		c.NumFailures,
 		c.Closing,
 		c.SyncErrorMessage,
+		nullStringColumn(c.RebaseBaseRev),
+		c.RebaseConflict,
 		nullStringColumn(title),
 	}
 
@@ -225,7 +233,7 @@ func (s *Store) CreateChangeset(ctx context.Context, c *btypes.Changeset) (err e
 var createChangesetQueryFmtstr = `
 -- source: enterprise/internal/batches/store.go:CreateChangeset
 INSERT INTO changesets (%s)
-VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
 RETURNING %s
 `
 
@@ -634,6 +642,51 @@ func listChangesetsQuery(opts *ListChangesetsOpts, authzConds *sqlf.Query) *sqlf
 	)
 }
 
+// ListAutoRebaseChangesets lists the changesets that may need to be rebased
+// automatically: the published and open changesets that are owned by open
+// batch changes rebasing changesets automatically, and aren't being processed
+// by the reconciler.
+func (s *Store) ListAutoRebaseChangesets(ctx context.Context) (cs btypes.Changesets, err error) {
+	ctx, endObservation := s.operations.listAutoRebaseChangesets.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		listAutoRebaseChangesetsQueryFmtstr,
+		sqlf.Join(ChangesetColumns, ", "),
+		btypes.ChangesetPublicationStatePublished,
+		btypes.ChangesetExternalStateOpen,
+		btypes.ChangesetExternalStateDraft,
+		btypes.ReconcilerStateCompleted.ToDB(),
+	)
+
+	err = s.query(ctx, q, func(sc scanner) error {
+		var c btypes.Changeset
+		if err := scanChangeset(&c, sc); err != nil {
+			return err
+		}
+		cs = append(cs, &c)
+		return nil
+	})
+	return cs, err
+}
+
+var listAutoRebaseChangesetsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/changesets.go:ListAutoRebaseChangesets
+SELECT %s FROM changesets
+INNER JOIN batch_changes ON batch_changes.id = changesets.owned_by_batch_change_id
+INNER JOIN repo ON repo.id = changesets.repo_id
+WHERE
+	batch_changes.auto_rebase AND
+	batch_changes.closed_at IS NULL AND
+	repo.deleted_at IS NULL AND
+	changesets.current_spec_id IS NOT NULL AND
+	changesets.publication_state = %s AND
+	changesets.external_state IN (%s, %s) AND
+	changesets.reconciler_state = %s AND
+	NOT changesets.closing
+ORDER BY changesets.id ASC
+`
+
 // EnqueueChangeset enqueues the given changeset by resetting all
 // worker-related columns and setting its reconciler_state column to the
 // `resetState` argument but *only if* the `currentState` matches its current
@@ -713,7 +766,7 @@ func (s *Store) UpdateChangeset(ctx context.Context, cs *btypes.Changeset) (err
 var updateChangesetQueryFmtstr = `
 -- source: enterprise/internal/batches/store_changesets.go:UpdateChangeset
 UPDATE changesets
-SET (%s) = (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+SET (%s) = (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
 WHERE id = %s
 RETURNING
   %s
@@ -1115,6 +1168,7 @@ func scanChangeset(t *btypes.Changeset, s scanner) error {
 		failureMessage      string
 		syncErrorMessage    string
 		reconcilerState     string
+		rebaseConflict      string
 	)
 	err := s.Scan(
 		&t.ID,
@@ -1149,6 +1203,8 @@ func scanChangeset(t *btypes.Changeset, s scanner) error {
 		&t.NumFailures,
 		&t.Closing,
 		&dbutil.NullString{S: &syncErrorMessage},
+		&dbutil.NullString{S: &t.RebaseBaseRev},
+		&dbutil.NullString{S: &rebaseConflict},
 	)
 	if err != nil {
 		return errors.Wrap(err, "scanning changeset")
@@ -1163,6 +1219,9 @@ func scanChangeset(t *btypes.Changeset, s scanner) error {
 	if syncErrorMessage != "" {
 		t.SyncErrorMessage = &syncErrorMessage
 	}
+	if rebaseConflict != "" {
+		t.RebaseConflict = &rebaseConflict
+	}
 	t.ReconcilerState = btypes.ReconcilerState(strings.ToUpper(reconcilerState))
 
 	switch t.ExternalServiceType {
@@ -1170,8 +1229,12 @@ func scanChangeset(t *btypes.Changeset, s scanner) error {
 		t.Metadata = new(github.PullRequest)
 	case extsvc.TypeBitbucketServer:
 		t.Metadata = new(bitbucketserver.PullRequest)
+	case extsvc.TypeBitbucketCloud:
+		t.Metadata = new(bitbucketcloud.AnnotatedPullRequest)
 	case extsvc.TypeGitLab:
 		t.Metadata = new(gitlab.MergeRequest)
+	case extsvc.TypeGerrit:
+		t.Metadata = new(gerrit.Change)
 	default:
 		return errors.New("unknown external service type")
 	}
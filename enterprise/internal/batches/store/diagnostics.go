@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/keegancsmith/sqlf"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+// QueryPlan is the result of explaining a query generated by the Store.
+type QueryPlan struct {
+	// Name identifies the query, usually by the Store method generating it.
+	Name string
+	// Plan is the query plan in Postgres' text format.
+	Plan string
+	// SeqScans lists the tables that the plan scans sequentially. With
+	// realistic data volumes these usually point at a missing index.
+	SeqScans []string
+}
+
+// ExplainBatchSpecResolutionJobQueries explains the queries used to get, list
+// and dequeue batch spec resolution jobs. It's meant to be used by developer
+// tooling, against a database holding a realistic number of jobs.
+func (s *Store) ExplainBatchSpecResolutionJobQueries(ctx context.Context) ([]*QueryPlan, error) {
+	type namedQuery struct {
+		name string
+		q    *sqlf.Query
+	}
+	queries := []namedQuery{
+		{"GetBatchSpecResolutionJob (ID)", getBatchSpecResolutionJobQuery(&GetBatchSpecResolutionJobOpts{ID: 1})},
+		{"GetBatchSpecResolutionJob (BatchSpecID)", getBatchSpecResolutionJobQuery(&GetBatchSpecResolutionJobOpts{BatchSpecID: 1})},
+		{"ListNamespaceBatchSpecResolutionJobs", listNamespaceBatchSpecResolutionJobsQuery(&ListNamespaceBatchSpecResolutionJobsOpts{NamespaceUserID: 1})},
+	}
+	for name, opts := range map[string]ListBatchSpecResolutionJobsOpts{
+		"ListBatchSpecResolutionJobs (State)":          {State: btypes.BatchSpecResolutionJobStateQueued},
+		"ListBatchSpecResolutionJobs (WorkerHostname)": {WorkerHostname: "worker"},
+		"ListBatchSpecResolutionJobs (Labels)":         {Labels: map[string]string{"key": "value"}},
+		"ListBatchSpecResolutionJobs (Creator)":        {CreatorID: 1},
+	} {
+		q, err := listBatchSpecResolutionJobsQuery(opts)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, namedQuery{name, q})
+	}
+	queries = append(queries, namedQuery{"Dequeue", dbworkerstore.DequeueCandidateQuery(BatchSpecResolutionWorkerStoreOptions, s.now())})
+
+	plans := make([]*QueryPlan, 0, len(queries))
+	for _, query := range queries {
+		plan, err := s.explain(ctx, query.name, query.q)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Name < plans[j].Name })
+	return plans, nil
+}
+
+func (s *Store) explain(ctx context.Context, name string, q *sqlf.Query) (*QueryPlan, error) {
+	lines, err := basestore.ScanStrings(s.Query(ctx, sqlf.Sprintf("EXPLAIN %s", q)))
+	if err != nil {
+		return nil, err
+	}
+
+	rawJSON, _, err := basestore.ScanFirstString(s.Query(ctx, sqlf.Sprintf("EXPLAIN (FORMAT JSON) %s", q)))
+	if err != nil {
+		return nil, err
+	}
+	seqScans, err := findSeqScans([]byte(rawJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryPlan{
+		Name:     name,
+		Plan:     strings.Join(lines, "\n"),
+		SeqScans: seqScans,
+	}, nil
+}
+
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// findSeqScans returns the sorted names of all relations that are scanned
+// sequentially in the given plan, which is the output of EXPLAIN (FORMAT JSON).
+func findSeqScans(rawJSON []byte) ([]string, error) {
+	var explained []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(rawJSON, &explained); err != nil {
+		return nil, err
+	}
+
+	tables := map[string]struct{}{}
+	var walk func(n planNode)
+	walk = func(n planNode) {
+		if n.NodeType == "Seq Scan" {
+			tables[n.RelationName] = struct{}{}
+		}
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	for _, e := range explained {
+		walk(e.Plan)
+	}
+
+	seqScans := make([]string, 0, len(tables))
+	for table := range tables {
+		seqScans = append(seqScans, table)
+	}
+	sort.Strings(seqScans)
+	return seqScans, nil
+}
+
+// SeedBatchSpecResolutionJobs inserts count synthetic batch spec resolution
+// jobs, spread over all states, and analyzes the table afterwards. It's meant
+// to be used by developer tooling within a transaction that is rolled back,
+// with foreign key constraints deferred.
+func (s *Store) SeedBatchSpecResolutionJobs(ctx context.Context, count int) error {
+	if err := s.Exec(ctx, sqlf.Sprintf(seedBatchSpecResolutionJobsQueryFmtstr, count)); err != nil {
+		return err
+	}
+	return s.Exec(ctx, sqlf.Sprintf("ANALYZE batch_spec_resolution_jobs"))
+}
+
+const seedBatchSpecResolutionJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/diagnostics.go:SeedBatchSpecResolutionJobs
+INSERT INTO batch_spec_resolution_jobs (batch_spec_id, state, worker_hostname, labels, created_at, updated_at)
+SELECT
+	-g,
+	(ARRAY['queued', 'processing', 'errored', 'failed', 'completed', 'completed', 'completed', 'completed'])[1 + mod(g, 8)],
+	'worker-' || mod(g, 10),
+	jsonb_build_object('seed', mod(g, 100)::text),
+	NOW() - (g * '1 second'::interval),
+	NOW() - (g * '1 second'::interval)
+FROM generate_series(1, %s) AS g
+`
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindSeqScans(t *testing.T) {
+	rawJSON := []byte(`[
+  {
+    "Plan": {
+      "Node Type": "Limit",
+      "Plans": [
+        {
+          "Node Type": "LockRows",
+          "Plans": [
+            {
+              "Node Type": "Sort",
+              "Plans": [
+                {
+                  "Node Type": "Seq Scan",
+                  "Relation Name": "batch_spec_resolution_jobs"
+                }
+              ]
+            }
+          ]
+        },
+        {
+          "Node Type": "Index Scan",
+          "Relation Name": "batch_specs"
+        }
+      ]
+    }
+  }
+]`)
+
+	have, err := findSeqScans(rawJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"batch_spec_resolution_jobs"}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Fatalf("unexpected seq scans (-want +have):\n%s", diff)
+	}
+}
@@ -37,6 +37,12 @@ func TestIntegration(t *testing.T) {
 		t.Run("BatchSpecWorkspaces", storeTest(db, nil, testStoreBatchSpecWorkspaces))
 		t.Run("BatchSpecWorkspaceExecutionJobs", storeTest(db, nil, testStoreBatchSpecWorkspaceExecutionJobs))
 		t.Run("BatchSpecResolutionJobs", storeTest(db, nil, testStoreBatchSpecResolutionJobs))
+		t.Run("BatchSpecResolutionJobOutcomes", storeTest(db, nil, testStoreBatchSpecResolutionJobOutcomes))
+		t.Run("BatchSpecResolutionQuota", storeTest(db, nil, testStoreBatchSpecResolutionQuota))
+		t.Run("AuditEvents", storeTest(db, nil, testStoreAuditEvents))
+		t.Run("BatchChangeRollbackJobs", storeTest(db, nil, testStoreBatchChangeRollbackJobs))
+		t.Run("BatchSpecExecutionSchedules", storeTest(db, nil, testStoreBatchSpecExecutionSchedules))
+		t.Run("BatchSpecExecutionCacheEntries", storeTest(db, nil, testStoreBatchSpecExecutionCacheEntries))
 
 		for name, key := range map[string]encryption.Key{
 			"no key":   nil,
@@ -44,6 +50,7 @@ func TestIntegration(t *testing.T) {
 		} {
 			t.Run(name, func(t *testing.T) {
 				t.Run("SiteCredentials", storeTest(db, key, testStoreSiteCredentials))
+				t.Run("OutboundWebhooks", storeTest(db, key, testStoreOutboundWebhooks))
 			})
 		}
 	})
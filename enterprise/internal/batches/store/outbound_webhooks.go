@@ -0,0 +1,488 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
+	"github.com/opentracing/opentracing-go/log"
+
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+var outboundWebhookColumns = SQLColumns{
+	"batch_changes_outbound_webhooks.id",
+	"batch_changes_outbound_webhooks.url",
+	"batch_changes_outbound_webhooks.events",
+	"batch_changes_outbound_webhooks.secret",
+	"batch_changes_outbound_webhooks.encryption_key_id",
+	"batch_changes_outbound_webhooks.created_at",
+	"batch_changes_outbound_webhooks.updated_at",
+}
+
+// CreateOutboundWebhook creates the given outbound webhook, with its secret
+// encrypted.
+func (s *Store) CreateOutboundWebhook(ctx context.Context, w *btypes.OutboundWebhook, secret string) (err error) {
+	ctx, endObservation := s.operations.createOutboundWebhook.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = s.now()
+	}
+	if w.UpdatedAt.IsZero() {
+		w.UpdatedAt = w.CreatedAt
+	}
+
+	w.Key = s.key
+	if err := w.SetSecret(ctx, secret); err != nil {
+		return err
+	}
+
+	q := sqlf.Sprintf(
+		createOutboundWebhookQueryFmtstr,
+		w.URL,
+		pq.Array(outboundWebhookEventsToStrings(w.Events)),
+		w.EncryptedSecret,
+		w.EncryptionKeyID,
+		w.CreatedAt,
+		w.UpdatedAt,
+		sqlf.Join(outboundWebhookColumns.ToSqlf(), ", "),
+	)
+	return s.query(ctx, q, func(sc scanner) error { return scanOutboundWebhook(w, sc) })
+}
+
+var createOutboundWebhookQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:CreateOutboundWebhook
+INSERT INTO batch_changes_outbound_webhooks (url, events, secret, encryption_key_id, created_at, updated_at)
+VALUES (%s, %s, %s, %s, %s, %s)
+RETURNING %s
+`
+
+// UpdateOutboundWebhook updates the URL and events of the given outbound
+// webhook. If secret is not empty, the secret is replaced too.
+func (s *Store) UpdateOutboundWebhook(ctx context.Context, w *btypes.OutboundWebhook, secret string) (err error) {
+	ctx, endObservation := s.operations.updateOutboundWebhook.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(w.ID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	w.Key = s.key
+	if secret != "" {
+		if err := w.SetSecret(ctx, secret); err != nil {
+			return err
+		}
+	}
+	w.UpdatedAt = s.now()
+
+	q := sqlf.Sprintf(
+		updateOutboundWebhookQueryFmtstr,
+		w.URL,
+		pq.Array(outboundWebhookEventsToStrings(w.Events)),
+		w.EncryptedSecret,
+		w.EncryptionKeyID,
+		w.UpdatedAt,
+		w.ID,
+		sqlf.Join(outboundWebhookColumns.ToSqlf(), ", "),
+	)
+
+	updated := &btypes.OutboundWebhook{Key: s.key}
+	if err := s.query(ctx, q, func(sc scanner) error { return scanOutboundWebhook(updated, sc) }); err != nil {
+		return err
+	}
+	if updated.ID == 0 {
+		return ErrNoResults
+	}
+	*w = *updated
+	return nil
+}
+
+var updateOutboundWebhookQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:UpdateOutboundWebhook
+UPDATE batch_changes_outbound_webhooks
+SET
+	url = %s,
+	events = %s,
+	secret = %s,
+	encryption_key_id = %s,
+	updated_at = %s
+WHERE id = %s
+RETURNING %s
+`
+
+// DeleteOutboundWebhook deletes the outbound webhook with the given ID, along
+// with its pending deliveries.
+func (s *Store) DeleteOutboundWebhook(ctx context.Context, id int64) (err error) {
+	ctx, endObservation := s.operations.deleteOutboundWebhook.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(id)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	res, err := s.ExecResult(ctx, sqlf.Sprintf(deleteOutboundWebhookQueryFmtstr, id))
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrNoResults
+	}
+	return nil
+}
+
+var deleteOutboundWebhookQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:DeleteOutboundWebhook
+DELETE FROM batch_changes_outbound_webhooks WHERE id = %s
+`
+
+// GetOutboundWebhookOpts captures the query options needed for getting an
+// OutboundWebhook.
+type GetOutboundWebhookOpts struct {
+	ID int64
+}
+
+// GetOutboundWebhook gets an outbound webhook matching the given options.
+func (s *Store) GetOutboundWebhook(ctx context.Context, opts GetOutboundWebhookOpts) (w *btypes.OutboundWebhook, err error) {
+	ctx, endObservation := s.operations.getOutboundWebhook.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(opts.ID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		getOutboundWebhookQueryFmtstr,
+		sqlf.Join(outboundWebhookColumns.ToSqlf(), ", "),
+		opts.ID,
+	)
+
+	c := btypes.OutboundWebhook{Key: s.key}
+	err = s.query(ctx, q, func(sc scanner) error { return scanOutboundWebhook(&c, sc) })
+	if err != nil {
+		return nil, err
+	}
+	if c.ID == 0 {
+		return nil, ErrNoResults
+	}
+	return &c, nil
+}
+
+var getOutboundWebhookQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:GetOutboundWebhook
+SELECT %s FROM batch_changes_outbound_webhooks
+WHERE id = %s
+LIMIT 1
+`
+
+// ListOutboundWebhooksOpts captures the query options needed for listing
+// outbound webhooks.
+type ListOutboundWebhooksOpts struct {
+	LimitOpts
+	Cursor int64
+}
+
+// ListOutboundWebhooks lists outbound webhooks with the given filters.
+func (s *Store) ListOutboundWebhooks(ctx context.Context, opts ListOutboundWebhooksOpts) (ws []*btypes.OutboundWebhook, next int64, err error) {
+	ctx, endObservation := s.operations.listOutboundWebhooks.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		listOutboundWebhooksQueryFmtstr+opts.LimitOpts.ToDB(),
+		sqlf.Join(outboundWebhookColumns.ToSqlf(), ", "),
+		opts.Cursor,
+	)
+
+	ws = make([]*btypes.OutboundWebhook, 0, opts.DBLimit())
+	err = s.query(ctx, q, func(sc scanner) error {
+		w := btypes.OutboundWebhook{Key: s.key}
+		if err := scanOutboundWebhook(&w, sc); err != nil {
+			return err
+		}
+		ws = append(ws, &w)
+		return nil
+	})
+
+	if opts.Limit != 0 && len(ws) == opts.DBLimit() {
+		next = ws[len(ws)-1].ID
+		ws = ws[:len(ws)-1]
+	}
+
+	return ws, next, err
+}
+
+var listOutboundWebhooksQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:ListOutboundWebhooks
+SELECT %s FROM batch_changes_outbound_webhooks
+WHERE id >= %s
+ORDER BY id ASC
+`
+
+func scanOutboundWebhook(w *btypes.OutboundWebhook, sc scanner) error {
+	var events []string
+	if err := sc.Scan(
+		&w.ID,
+		&w.URL,
+		pq.Array(&events),
+		&w.EncryptedSecret,
+		&w.EncryptionKeyID,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	w.Events = make([]btypes.OutboundWebhookEvent, 0, len(events))
+	for _, e := range events {
+		w.Events = append(w.Events, btypes.OutboundWebhookEvent(e))
+	}
+	return nil
+}
+
+func outboundWebhookEventsToStrings(events []btypes.OutboundWebhookEvent) []string {
+	ss := make([]string, 0, len(events))
+	for _, e := range events {
+		ss = append(ss, string(e))
+	}
+	return ss
+}
+
+// OutboundWebhookJobColumns are used by the outbound webhook job related
+// Store methods and by the delivery worker to query jobs.
+var OutboundWebhookJobColumns = SQLColumns{
+	"batch_changes_outbound_webhook_jobs.id",
+	"batch_changes_outbound_webhook_jobs.webhook_id",
+	"batch_changes_outbound_webhook_jobs.event",
+	"batch_changes_outbound_webhook_jobs.batch_spec_id",
+	"batch_changes_outbound_webhook_jobs.event_message",
+	"batch_changes_outbound_webhook_jobs.occurred_at",
+	"batch_changes_outbound_webhook_jobs.state",
+	"batch_changes_outbound_webhook_jobs.failure_message",
+	"batch_changes_outbound_webhook_jobs.started_at",
+	"batch_changes_outbound_webhook_jobs.finished_at",
+	"batch_changes_outbound_webhook_jobs.process_after",
+	"batch_changes_outbound_webhook_jobs.num_resets",
+	"batch_changes_outbound_webhook_jobs.num_failures",
+	"batch_changes_outbound_webhook_jobs.created_at",
+	"batch_changes_outbound_webhook_jobs.updated_at",
+}
+
+// EnqueueOutboundWebhookJobs enqueues a delivery to every subscribed outbound
+// webhook for each batch spec resolution and execution that finished since
+// the given time. Events are only delivered once per webhook, so calling this
+// repeatedly with overlapping times is safe. Events that occurred before a
+// webhook was created aren't delivered to it.
+//
+// Jobs are never retried, so errored jobs count as failed. Dry run
+// resolutions only preview workspaces and don't trigger events.
+//
+// An execution is finished once all of its workspace execution jobs are; it
+// failed if any of them failed or errored.
+func (s *Store) EnqueueOutboundWebhookJobs(ctx context.Context, since time.Time) (err error) {
+	ctx, endObservation := s.operations.enqueueOutboundWebhookJobs.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("since", since.String()),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	now := s.now()
+	return s.Exec(ctx, sqlf.Sprintf(
+		enqueueOutboundWebhookJobsQueryFmtstr,
+		btypes.OutboundWebhookEventResolutionCompleted,
+		btypes.OutboundWebhookEventResolutionFailed,
+		since,
+		btypes.OutboundWebhookEventExecutionFailed,
+		btypes.OutboundWebhookEventExecutionCompleted,
+		since,
+		since,
+		btypes.OutboundWebhookJobStateQueued.ToDB(),
+		now,
+		now,
+	))
+}
+
+var enqueueOutboundWebhookJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:EnqueueOutboundWebhookJobs
+WITH events AS (
+	SELECT
+		CASE WHEN j.state = 'completed' THEN %s ELSE %s END AS event,
+		j.batch_spec_id,
+		j.failure_message AS event_message,
+		j.finished_at AS occurred_at
+	FROM batch_spec_resolution_jobs j
+	WHERE
+		j.state IN ('completed', 'failed', 'errored') AND
+		NOT j.dry_run AND
+		j.finished_at >= %s
+	UNION ALL
+	SELECT
+		CASE WHEN bool_or(j.state IN ('failed', 'errored')) THEN %s ELSE %s END AS event,
+		ws.batch_spec_id,
+		NULL AS event_message,
+		MAX(j.finished_at) AS occurred_at
+	FROM batch_spec_workspace_execution_jobs j
+	JOIN batch_spec_workspaces ws ON ws.id = j.batch_spec_workspace_id
+	WHERE ws.batch_spec_id IN (
+		SELECT ws2.batch_spec_id
+		FROM batch_spec_workspace_execution_jobs j2
+		JOIN batch_spec_workspaces ws2 ON ws2.id = j2.batch_spec_workspace_id
+		WHERE j2.finished_at >= %s
+	)
+	GROUP BY ws.batch_spec_id
+	HAVING
+		bool_and(j.state IN ('completed', 'failed', 'errored')) AND
+		MAX(j.finished_at) >= %s
+)
+INSERT INTO batch_changes_outbound_webhook_jobs (webhook_id, event, batch_spec_id, event_message, occurred_at, state, created_at, updated_at)
+SELECT w.id, e.event, e.batch_spec_id, e.event_message, e.occurred_at, %s, %s, %s
+FROM events e
+JOIN batch_changes_outbound_webhooks w ON
+	e.occurred_at >= w.created_at AND
+	(w.events = '{}' OR e.event = ANY (w.events))
+ON CONFLICT (webhook_id, batch_spec_id, event) DO NOTHING
+`
+
+// GetOutboundWebhookJobOpts captures the query options needed for getting an
+// OutboundWebhookJob.
+type GetOutboundWebhookJobOpts struct {
+	ID int64
+}
+
+// GetOutboundWebhookJob gets an outbound webhook job matching the given
+// options.
+func (s *Store) GetOutboundWebhookJob(ctx context.Context, opts GetOutboundWebhookJobOpts) (job *btypes.OutboundWebhookJob, err error) {
+	ctx, endObservation := s.operations.getOutboundWebhookJob.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("ID", int(opts.ID)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	q := sqlf.Sprintf(
+		getOutboundWebhookJobQueryFmtstr,
+		sqlf.Join(OutboundWebhookJobColumns.ToSqlf(), ", "),
+		opts.ID,
+	)
+
+	var j btypes.OutboundWebhookJob
+	err = s.query(ctx, q, func(sc scanner) error { return scanOutboundWebhookJob(&j, sc) })
+	if err != nil {
+		return nil, err
+	}
+	if j.ID == 0 {
+		return nil, ErrNoResults
+	}
+	return &j, nil
+}
+
+var getOutboundWebhookJobQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:GetOutboundWebhookJob
+SELECT %s FROM batch_changes_outbound_webhook_jobs
+WHERE id = %s
+LIMIT 1
+`
+
+// ListOutboundWebhookJobsOpts captures the query options needed for listing
+// outbound webhook jobs.
+type ListOutboundWebhookJobsOpts struct {
+	LimitOpts
+	Cursor int64
+
+	WebhookID   int64
+	BatchSpecID int64
+}
+
+// ListOutboundWebhookJobs lists outbound webhook jobs with the given filters.
+func (s *Store) ListOutboundWebhookJobs(ctx context.Context, opts ListOutboundWebhookJobsOpts) (js []*btypes.OutboundWebhookJob, next int64, err error) {
+	ctx, endObservation := s.operations.listOutboundWebhookJobs.With(ctx, &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("batch_changes_outbound_webhook_jobs.id >= %s", opts.Cursor),
+	}
+	if opts.WebhookID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_changes_outbound_webhook_jobs.webhook_id = %s", opts.WebhookID))
+	}
+	if opts.BatchSpecID != 0 {
+		preds = append(preds, sqlf.Sprintf("batch_changes_outbound_webhook_jobs.batch_spec_id = %s", opts.BatchSpecID))
+	}
+
+	q := sqlf.Sprintf(
+		listOutboundWebhookJobsQueryFmtstr+opts.LimitOpts.ToDB(),
+		sqlf.Join(OutboundWebhookJobColumns.ToSqlf(), ", "),
+		sqlf.Join(preds, "\n AND "),
+	)
+
+	js = make([]*btypes.OutboundWebhookJob, 0, opts.DBLimit())
+	err = s.query(ctx, q, func(sc scanner) error {
+		var j btypes.OutboundWebhookJob
+		if err := scanOutboundWebhookJob(&j, sc); err != nil {
+			return err
+		}
+		js = append(js, &j)
+		return nil
+	})
+
+	if opts.Limit != 0 && len(js) == opts.DBLimit() {
+		next = js[len(js)-1].ID
+		js = js[:len(js)-1]
+	}
+
+	return js, next, err
+}
+
+var listOutboundWebhookJobsQueryFmtstr = `
+-- source: enterprise/internal/batches/store/outbound_webhooks.go:ListOutboundWebhookJobs
+SELECT %s FROM batch_changes_outbound_webhook_jobs
+WHERE %s
+ORDER BY batch_changes_outbound_webhook_jobs.id ASC
+`
+
+func scanOutboundWebhookJob(j *btypes.OutboundWebhookJob, s scanner) error {
+	var failureMessage string
+	if err := s.Scan(
+		&j.ID,
+		&j.WebhookID,
+		&j.Event,
+		&j.BatchSpecID,
+		&dbutil.NullString{S: &j.EventMessage},
+		&j.OccurredAt,
+		&j.State,
+		&dbutil.NullString{S: &failureMessage},
+		&dbutil.NullTime{Time: &j.StartedAt},
+		&dbutil.NullTime{Time: &j.FinishedAt},
+		&dbutil.NullTime{Time: &j.ProcessAfter},
+		&j.NumResets,
+		&j.NumFailures,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	if failureMessage != "" {
+		j.FailureMessage = &failureMessage
+	}
+	j.State = btypes.OutboundWebhookJobState(strings.ToUpper(string(j.State)))
+	return nil
+}
+
+// ScanFirstOutboundWebhookJob scans the first outbound webhook job of the
+// given rows, for use in the delivery worker store.
+func ScanFirstOutboundWebhookJob(rows *sql.Rows, queryErr error) (_ *btypes.OutboundWebhookJob, _ bool, err error) {
+	if queryErr != nil {
+		return nil, false, queryErr
+	}
+
+	var jobs []*btypes.OutboundWebhookJob
+	err = scanAll(rows, func(sc scanner) error {
+		var j btypes.OutboundWebhookJob
+		if err := scanOutboundWebhookJob(&j, sc); err != nil {
+			return err
+		}
+		jobs = append(jobs, &j)
+		return nil
+	})
+	if err != nil || len(jobs) == 0 {
+		return nil, false, err
+	}
+	return jobs[0], true, nil
+}
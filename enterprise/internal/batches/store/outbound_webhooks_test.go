@@ -0,0 +1,252 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/keegancsmith/sqlf"
+
+	ct "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/testing"
+	btypes "github.com/sourcegraph/sourcegraph/enterprise/internal/batches/types"
+)
+
+func testStoreOutboundWebhooks(t *testing.T, ctx context.Context, s *Store, clock ct.Clock) {
+	webhooks := []*btypes.OutboundWebhook{
+		{URL: "https://ci.example.com/hooks/1"},
+		{URL: "https://ci.example.com/hooks/2", Events: []btypes.OutboundWebhookEvent{btypes.OutboundWebhookEventExecutionFailed}},
+	}
+	// The webhooks don't compare their keys.
+	opts := cmpopts.IgnoreFields(btypes.OutboundWebhook{}, "Key")
+
+	t.Run("Create", func(t *testing.T) {
+		for i, w := range webhooks {
+			if err := s.CreateOutboundWebhook(ctx, w, "secret"); err != nil {
+				t.Fatal(err)
+			}
+			if w.ID == 0 {
+				t.Fatalf("webhook %d has no ID", i)
+			}
+			if have, want := w.CreatedAt, clock.Now(); !have.Equal(want) {
+				t.Fatalf("webhook has wrong CreatedAt. want=%s, have=%s", want, have)
+			}
+			if s.key != nil && string(w.EncryptedSecret) == "secret" {
+				t.Fatal("secret is not encrypted")
+			}
+			secret, err := w.Secret(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if secret != "secret" {
+				t.Fatalf("webhook has wrong secret: %q", secret)
+			}
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		have, err := s.GetOutboundWebhook(ctx, GetOutboundWebhookOpts{ID: webhooks[1].ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(webhooks[1], have, opts); diff != "" {
+			t.Fatal(diff)
+		}
+
+		t.Run("NoResults", func(t *testing.T) {
+			_, have := s.GetOutboundWebhook(ctx, GetOutboundWebhookOpts{ID: 0xdeadbeef})
+			if have != ErrNoResults {
+				t.Fatalf("have err %v, want %v", have, ErrNoResults)
+			}
+		})
+	})
+
+	t.Run("List", func(t *testing.T) {
+		have, next, err := s.ListOutboundWebhooks(ctx, ListOutboundWebhooksOpts{LimitOpts: LimitOpts{Limit: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(webhooks[:1], have, opts); diff != "" {
+			t.Fatal(diff)
+		}
+		if next != webhooks[1].ID {
+			t.Fatalf("wrong next cursor. want=%d, have=%d", webhooks[1].ID, next)
+		}
+
+		have, next, err = s.ListOutboundWebhooks(ctx, ListOutboundWebhooksOpts{Cursor: next})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(webhooks[1:], have, opts); diff != "" {
+			t.Fatal(diff)
+		}
+		if next != 0 {
+			t.Fatalf("unexpected next cursor %d", next)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		clock.Add(1 * time.Second)
+
+		w := webhooks[0]
+		w.URL = "https://ci.example.com/hooks/updated"
+		encryptedSecret := w.EncryptedSecret
+		if err := s.UpdateOutboundWebhook(ctx, w, ""); err != nil {
+			t.Fatal(err)
+		}
+		if have, want := w.UpdatedAt, clock.Now(); !have.Equal(want) {
+			t.Fatalf("webhook has wrong UpdatedAt. want=%s, have=%s", want, have)
+		}
+		if string(w.EncryptedSecret) != string(encryptedSecret) {
+			t.Fatal("secret changed, but none was given")
+		}
+
+		if err := s.UpdateOutboundWebhook(ctx, w, "new secret"); err != nil {
+			t.Fatal(err)
+		}
+		secret, err := w.Secret(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret != "new secret" {
+			t.Fatalf("webhook has wrong secret: %q", secret)
+		}
+
+		t.Run("NoResults", func(t *testing.T) {
+			if err := s.UpdateOutboundWebhook(ctx, &btypes.OutboundWebhook{ID: 0xdeadbeef}, ""); err != ErrNoResults {
+				t.Fatalf("have err %v, want %v", err, ErrNoResults)
+			}
+		})
+	})
+
+	t.Run("EnqueueOutboundWebhookJobs", func(t *testing.T) {
+		clock.Add(1 * time.Minute)
+		finishedAt := clock.Now()
+
+		// A resolution that completed, one that's still running, one that
+		// errored and a completed dry run, which doesn't trigger events.
+		resolutionJobs := []*btypes.BatchSpecResolutionJob{
+			{BatchSpecID: 7001, State: btypes.BatchSpecResolutionJobStateQueued},
+			{BatchSpecID: 7002, State: btypes.BatchSpecResolutionJobStateQueued},
+			{BatchSpecID: 7004, State: btypes.BatchSpecResolutionJobStateQueued},
+			{BatchSpecID: 7005, State: btypes.BatchSpecResolutionJobStateQueued, DryRun: true},
+		}
+		if err := s.CreateBatchSpecResolutionJob(ctx, resolutionJobs...); err != nil {
+			t.Fatal(err)
+		}
+		for i, state := range map[int]string{0: "completed", 2: "errored", 3: "completed"} {
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_resolution_jobs SET state = %s, finished_at = %s WHERE id = %s", state, finishedAt, resolutionJobs[i].ID)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// An execution with a completed and a failed workspace, and one with
+		// a completed and an errored workspace.
+		workspaces := []*btypes.BatchSpecWorkspace{
+			{BatchSpecID: 7003, RepoID: 1},
+			{BatchSpecID: 7003, RepoID: 2},
+			{BatchSpecID: 7006, RepoID: 1},
+			{BatchSpecID: 7006, RepoID: 2},
+		}
+		if err := s.CreateBatchSpecWorkspace(ctx, workspaces...); err != nil {
+			t.Fatal(err)
+		}
+		for i, ws := range workspaces {
+			job := &btypes.BatchSpecWorkspaceExecutionJob{BatchSpecWorkspaceID: ws.ID}
+			if err := s.CreateBatchSpecWorkspaceExecutionJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			state := []string{"completed", "failed", "completed", "errored"}[i]
+			if err := s.Exec(ctx, sqlf.Sprintf("UPDATE batch_spec_workspace_execution_jobs SET state = %s, finished_at = %s WHERE id = %s", state, finishedAt, job.ID)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Enqueueing twice doesn't deliver events twice.
+		for i := 0; i < 2; i++ {
+			if err := s.EnqueueOutboundWebhookJobs(ctx, finishedAt.Add(-time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		have, _, err := s.ListOutboundWebhookJobs(ctx, ListOutboundWebhookJobsOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		type delivery struct {
+			WebhookID   int64
+			Event       btypes.OutboundWebhookEvent
+			BatchSpecID int64
+		}
+		deliveries := make([]delivery, 0, len(have))
+		for _, j := range have {
+			if j.State != btypes.OutboundWebhookJobStateQueued {
+				t.Fatalf("job has wrong state %q", j.State)
+			}
+			if !j.OccurredAt.Equal(finishedAt) {
+				t.Fatalf("job has wrong OccurredAt. want=%s, have=%s", finishedAt, j.OccurredAt)
+			}
+			deliveries = append(deliveries, delivery{j.WebhookID, j.Event, j.BatchSpecID})
+		}
+		want := []delivery{
+			{webhooks[0].ID, btypes.OutboundWebhookEventResolutionCompleted, 7001},
+			{webhooks[0].ID, btypes.OutboundWebhookEventExecutionFailed, 7003},
+			{webhooks[0].ID, btypes.OutboundWebhookEventResolutionFailed, 7004},
+			{webhooks[0].ID, btypes.OutboundWebhookEventExecutionFailed, 7006},
+			{webhooks[1].ID, btypes.OutboundWebhookEventExecutionFailed, 7003},
+			{webhooks[1].ID, btypes.OutboundWebhookEventExecutionFailed, 7006},
+		}
+		less := func(a, b delivery) bool {
+			if a.WebhookID != b.WebhookID {
+				return a.WebhookID < b.WebhookID
+			}
+			return a.BatchSpecID < b.BatchSpecID
+		}
+		if diff := cmp.Diff(want, deliveries, cmpopts.SortSlices(less)); diff != "" {
+			t.Fatal(diff)
+		}
+
+		t.Run("GetOutboundWebhookJob", func(t *testing.T) {
+			job, err := s.GetOutboundWebhookJob(ctx, GetOutboundWebhookJobOpts{ID: have[0].ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(have[0], job); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("ListOutboundWebhookJobs by webhook", func(t *testing.T) {
+			jobs, _, err := s.ListOutboundWebhookJobs(ctx, ListOutboundWebhookJobsOpts{WebhookID: webhooks[1].ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(jobs) != 2 || jobs[0].Event != btypes.OutboundWebhookEventExecutionFailed || jobs[1].Event != btypes.OutboundWebhookEventExecutionFailed {
+				t.Fatalf("unexpected jobs: %+v", jobs)
+			}
+		})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.DeleteOutboundWebhook(ctx, webhooks[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetOutboundWebhook(ctx, GetOutboundWebhookOpts{ID: webhooks[0].ID}); err != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+
+		// Its deliveries are deleted too.
+		jobs, _, err := s.ListOutboundWebhookJobs(ctx, ListOutboundWebhookJobsOpts{WebhookID: webhooks[0].ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != 0 {
+			t.Fatalf("unexpected jobs: %+v", jobs)
+		}
+
+		if err := s.DeleteOutboundWebhook(ctx, webhooks[0].ID); err != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+	})
+}
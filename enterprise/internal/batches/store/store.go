@@ -140,14 +140,24 @@ func (s *Store) UserCredentials() *database.UserCredentialsStore {
 }
 
 func (s *Store) query(ctx context.Context, q *sqlf.Query, sc scanFunc) error {
+	var rowsScanned int
+	finishTrace := traceSQL(ctx, q)
+	defer func() { finishTrace(rowsScanned) }()
+
 	rows, err := s.Store.Query(ctx, q)
 	if err != nil {
 		return err
 	}
-	return scanAll(rows, sc)
+	return scanAll(rows, func(row scanner) error {
+		rowsScanned++
+		return sc(row)
+	})
 }
 
 func (s *Store) queryCount(ctx context.Context, q *sqlf.Query) (int, error) {
+	finishTrace := traceSQL(ctx, q)
+	defer finishTrace(1)
+
 	count, ok, err := basestore.ScanFirstInt(s.Query(ctx, q))
 	if err != nil || !ok {
 		return count, err
@@ -155,6 +165,13 @@ func (s *Store) queryCount(ctx context.Context, q *sqlf.Query) (int, error) {
 	return count, nil
 }
 
+func (s *Store) exec(ctx context.Context, q *sqlf.Query) error {
+	finishTrace := traceSQL(ctx, q)
+	defer finishTrace(0)
+
+	return s.Store.Exec(ctx, q)
+}
+
 type operations struct {
 	createBatchChange      *observation.Operation
 	updateBatchChange      *observation.Operation
@@ -189,8 +206,9 @@ type operations struct {
 	countChangesetEvents  *observation.Operation
 	upsertChangesetEvents *observation.Operation
 
-	createChangesetJob *observation.Operation
-	getChangesetJob    *observation.Operation
+	createChangesetJob    *observation.Operation
+	getChangesetJob       *observation.Operation
+	expediteChangesetJobs *observation.Operation
 
 	createChangesetSpec                      *observation.Operation
 	updateChangesetSpec                      *observation.Operation
@@ -208,6 +226,7 @@ type operations struct {
 	getChangeset                      *observation.Operation
 	listChangesetSyncData             *observation.Operation
 	listChangesets                    *observation.Operation
+	listAutoRebaseChangesets          *observation.Operation
 	enqueueChangeset                  *observation.Operation
 	updateChangeset                   *observation.Operation
 	updateChangesetBatchChanges       *observation.Operation
@@ -243,6 +262,52 @@ type operations struct {
 	createBatchSpecResolutionJob *observation.Operation
 	getBatchSpecResolutionJob    *observation.Operation
 	listBatchSpecResolutionJobs  *observation.Operation
+
+	listBatchSpecResolutionJobDurationStats     *observation.Operation
+	setBatchSpecResolutionJobSearchQueries      *observation.Operation
+	setBatchSpecResolutionJobProgress           *observation.Operation
+	setBatchSpecResolutionJobDryRunResult       *observation.Operation
+	setBatchSpecResolutionJobCredentialWarnings *observation.Operation
+	setBatchSpecResolutionJobRepositoryErrors   *observation.Operation
+	setBatchSpecResolutionJobSummary            *observation.Operation
+	getBatchSpecResolutionJobLogs               *observation.Operation
+	searchBatchSpecResolutionJobLogs            *observation.Operation
+	listNamespaceBatchSpecResolutionJobs        *observation.Operation
+	countNamespaceBatchSpecResolutionJobs       *observation.Operation
+	getBatchSpecResolutionJobStats              *observation.Operation
+	getBatchSpecResolutionQuotaUsage            *observation.Operation
+	deleteBatchSpecResolutionJobs               *observation.Operation
+	purgeBatchSpecResolutionJobLogs             *observation.Operation
+
+	listBatchSpecResolutionJobOutcomes      *observation.Operation
+	listBatchSpecResolutionJobOutcomeCounts *observation.Operation
+
+	createAuditEvents *observation.Operation
+	listAuditEvents   *observation.Operation
+	countAuditEvents  *observation.Operation
+
+	createBatchChangeRollbackJob *observation.Operation
+	getBatchChangeRollbackJob    *observation.Operation
+
+	createBatchSpecExecutionSchedule *observation.Operation
+	updateBatchSpecExecutionSchedule *observation.Operation
+	deleteBatchSpecExecutionSchedule *observation.Operation
+	getBatchSpecExecutionSchedule    *observation.Operation
+	listBatchSpecExecutionSchedules  *observation.Operation
+
+	upsertBatchSpecExecutionCacheEntry     *observation.Operation
+	listBatchSpecExecutionCacheEntries     *observation.Operation
+	markUsedBatchSpecExecutionCacheEntries *observation.Operation
+	deleteBatchSpecExecutionCacheEntries   *observation.Operation
+
+	createOutboundWebhook      *observation.Operation
+	updateOutboundWebhook      *observation.Operation
+	deleteOutboundWebhook      *observation.Operation
+	getOutboundWebhook         *observation.Operation
+	listOutboundWebhooks       *observation.Operation
+	enqueueOutboundWebhookJobs *observation.Operation
+	getOutboundWebhookJob      *observation.Operation
+	listOutboundWebhookJobs    *observation.Operation
 }
 
 var (
@@ -309,8 +374,9 @@ func newOperations(observationContext *observation.Context) *operations {
 			countChangesetEvents:  op("CountChangesetEvents"),
 			upsertChangesetEvents: op("UpsertChangesetEvents"),
 
-			createChangesetJob: op("CreateChangesetJob"),
-			getChangesetJob:    op("GetChangesetJob"),
+			createChangesetJob:    op("CreateChangesetJob"),
+			getChangesetJob:       op("GetChangesetJob"),
+			expediteChangesetJobs: op("ExpediteChangesetJobs"),
 
 			createChangesetSpec:                      op("CreateChangesetSpec"),
 			updateChangesetSpec:                      op("UpdateChangesetSpec"),
@@ -328,6 +394,7 @@ func newOperations(observationContext *observation.Context) *operations {
 			getChangeset:                      op("GetChangeset"),
 			listChangesetSyncData:             op("ListChangesetSyncData"),
 			listChangesets:                    op("ListChangesets"),
+			listAutoRebaseChangesets:          op("ListAutoRebaseChangesets"),
 			enqueueChangeset:                  op("EnqueueChangeset"),
 			updateChangeset:                   op("UpdateChangeset"),
 			updateChangesetBatchChanges:       op("UpdateChangesetBatchChanges"),
@@ -363,6 +430,52 @@ func newOperations(observationContext *observation.Context) *operations {
 			createBatchSpecResolutionJob: op("CreateBatchSpecResolutionJob"),
 			getBatchSpecResolutionJob:    op("GetBatchSpecResolutionJob"),
 			listBatchSpecResolutionJobs:  op("ListBatchSpecResolutionJobs"),
+
+			listBatchSpecResolutionJobDurationStats:     op("ListBatchSpecResolutionJobDurationStats"),
+			setBatchSpecResolutionJobSearchQueries:      op("SetBatchSpecResolutionJobSearchQueries"),
+			setBatchSpecResolutionJobProgress:           op("SetBatchSpecResolutionJobProgress"),
+			setBatchSpecResolutionJobDryRunResult:       op("SetBatchSpecResolutionJobDryRunResult"),
+			setBatchSpecResolutionJobCredentialWarnings: op("SetBatchSpecResolutionJobCredentialWarnings"),
+			setBatchSpecResolutionJobRepositoryErrors:   op("SetBatchSpecResolutionJobRepositoryErrors"),
+			setBatchSpecResolutionJobSummary:            op("SetBatchSpecResolutionJobSummary"),
+			getBatchSpecResolutionJobLogs:               op("GetBatchSpecResolutionJobLogs"),
+			searchBatchSpecResolutionJobLogs:            op("SearchBatchSpecResolutionJobLogs"),
+			listNamespaceBatchSpecResolutionJobs:        op("ListNamespaceBatchSpecResolutionJobs"),
+			countNamespaceBatchSpecResolutionJobs:       op("CountNamespaceBatchSpecResolutionJobs"),
+			getBatchSpecResolutionJobStats:              op("GetBatchSpecResolutionJobStats"),
+			getBatchSpecResolutionQuotaUsage:            op("GetBatchSpecResolutionQuotaUsage"),
+			deleteBatchSpecResolutionJobs:               op("DeleteBatchSpecResolutionJobs"),
+			purgeBatchSpecResolutionJobLogs:             op("PurgeBatchSpecResolutionJobLogs"),
+
+			listBatchSpecResolutionJobOutcomes:      op("ListBatchSpecResolutionJobOutcomes"),
+			listBatchSpecResolutionJobOutcomeCounts: op("ListBatchSpecResolutionJobOutcomeCounts"),
+
+			createAuditEvents: op("CreateAuditEvents"),
+			listAuditEvents:   op("ListAuditEvents"),
+			countAuditEvents:  op("CountAuditEvents"),
+
+			createBatchChangeRollbackJob: op("CreateBatchChangeRollbackJob"),
+			getBatchChangeRollbackJob:    op("GetBatchChangeRollbackJob"),
+
+			createBatchSpecExecutionSchedule: op("CreateBatchSpecExecutionSchedule"),
+			updateBatchSpecExecutionSchedule: op("UpdateBatchSpecExecutionSchedule"),
+			deleteBatchSpecExecutionSchedule: op("DeleteBatchSpecExecutionSchedule"),
+			getBatchSpecExecutionSchedule:    op("GetBatchSpecExecutionSchedule"),
+			listBatchSpecExecutionSchedules:  op("ListBatchSpecExecutionSchedules"),
+
+			upsertBatchSpecExecutionCacheEntry:     op("UpsertBatchSpecExecutionCacheEntry"),
+			listBatchSpecExecutionCacheEntries:     op("ListBatchSpecExecutionCacheEntries"),
+			markUsedBatchSpecExecutionCacheEntries: op("MarkUsedBatchSpecExecutionCacheEntries"),
+			deleteBatchSpecExecutionCacheEntries:   op("DeleteBatchSpecExecutionCacheEntries"),
+
+			createOutboundWebhook:      op("CreateOutboundWebhook"),
+			updateOutboundWebhook:      op("UpdateOutboundWebhook"),
+			deleteOutboundWebhook:      op("DeleteOutboundWebhook"),
+			getOutboundWebhook:         op("GetOutboundWebhook"),
+			listOutboundWebhooks:       op("ListOutboundWebhooks"),
+			enqueueOutboundWebhookJobs: op("EnqueueOutboundWebhookJobs"),
+			getOutboundWebhookJob:      op("GetOutboundWebhookJob"),
+			listOutboundWebhookJobs:    op("ListOutboundWebhookJobs"),
 		}
 	})
 
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// maxTracedStatementLength caps the length of the SQL statements recorded on
+// traces, so that huge generated queries don't blow up the span size.
+const maxTracedStatementLength = 2048
+
+var statementWhitespace = lazyregexp.New(`\s+`)
+
+// traceSQL records the given query on the trace of the current operation, if
+// there is one. The returned function must be called once the query is done
+// with the number of rows that were scanned, to record them together with the
+// duration of the query.
+//
+// Only the statement with its bind variables is recorded, never the argument
+// values, as those can contain credentials or the contents of batch specs. The
+// fields are flat, dot-separated and primitive, so they map directly to
+// columns in Honeycomb.
+func traceSQL(ctx context.Context, q *sqlf.Query) func(rowsScanned int) {
+	tr := trace.TraceFromContext(ctx)
+	if tr == nil {
+		return func(int) {}
+	}
+
+	start := time.Now()
+	return func(rowsScanned int) {
+		tr.LogFields(
+			log.String("db.system", "postgresql"),
+			log.String("db.statement", redactedStatement(q)),
+			log.Int("db.args", len(q.Args())),
+			log.Int("db.rows_scanned", rowsScanned),
+			log.Float64("db.duration_ms", float64(time.Since(start))/float64(time.Millisecond)),
+		)
+	}
+}
+
+// redactedStatement returns the statement of the given query on a single line
+// and without its arguments.
+func redactedStatement(q *sqlf.Query) string {
+	stmt := strings.TrimSpace(statementWhitespace.ReplaceAllString(q.Query(sqlf.PostgresBindVar), " "))
+	if len(stmt) > maxTracedStatementLength {
+		stmt = stmt[:maxTracedStatementLength] + "..."
+	}
+	return stmt
+}
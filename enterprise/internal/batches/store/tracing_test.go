@@ -0,0 +1,29 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keegancsmith/sqlf"
+)
+
+func TestRedactedStatement(t *testing.T) {
+	q := sqlf.Sprintf(`
+-- source: test
+SELECT id FROM batch_spec_resolution_jobs
+WHERE
+	state = %s
+AND
+	labels @> %s
+`, "queued", `{"secret":"value"}`)
+
+	want := "-- source: test SELECT id FROM batch_spec_resolution_jobs WHERE state = $1 AND labels @> $2"
+	if have := redactedStatement(q); have != want {
+		t.Fatalf("wrong statement\nwant: %q\nhave: %q", want, have)
+	}
+
+	long := sqlf.Sprintf("SELECT " + strings.Repeat("x", 2*maxTracedStatementLength))
+	if have := redactedStatement(long); len(have) != maxTracedStatementLength+len("...") {
+		t.Fatalf("statement not truncated: length %d", len(have))
+	}
+}
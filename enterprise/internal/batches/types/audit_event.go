@@ -0,0 +1,92 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// AuditEventAction defines the possible state changes recorded by an
+// AuditEvent.
+type AuditEventAction string
+
+// AuditEventAction constants.
+const (
+	AuditEventActionCreated  AuditEventAction = "CREATED"
+	AuditEventActionRetried  AuditEventAction = "RETRIED"
+	AuditEventActionCanceled AuditEventAction = "CANCELED"
+	AuditEventActionDeleted  AuditEventAction = "DELETED"
+	AuditEventActionClosed   AuditEventAction = "CLOSED"
+	// AuditEventActionRolledBack records that a rollback of a batch change
+	// was requested. The rollback itself is recorded as the events of the
+	// changes it makes.
+	AuditEventActionRolledBack AuditEventAction = "ROLLED_BACK"
+)
+
+// Valid returns true if the given AuditEventAction is valid.
+func (a AuditEventAction) Valid() bool {
+	switch a {
+	case AuditEventActionCreated,
+		AuditEventActionRetried,
+		AuditEventActionCanceled,
+		AuditEventActionDeleted,
+		AuditEventActionClosed,
+		AuditEventActionRolledBack:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToDB returns the database representation of the action.
+func (a AuditEventAction) ToDB() string { return strings.ToLower(string(a)) }
+
+// AuditEventResourceType defines the kinds of resources whose state changes
+// are recorded by AuditEvents.
+type AuditEventResourceType string
+
+// AuditEventResourceType constants.
+const (
+	AuditEventResourceTypeBatchChange            AuditEventResourceType = "BATCH_CHANGE"
+	AuditEventResourceTypeBatchSpec              AuditEventResourceType = "BATCH_SPEC"
+	AuditEventResourceTypeBatchSpecResolutionJob AuditEventResourceType = "BATCH_SPEC_RESOLUTION_JOB"
+	AuditEventResourceTypeChangeset              AuditEventResourceType = "CHANGESET"
+)
+
+// Valid returns true if the given AuditEventResourceType is valid.
+func (t AuditEventResourceType) Valid() bool {
+	switch t {
+	case AuditEventResourceTypeBatchChange,
+		AuditEventResourceTypeBatchSpec,
+		AuditEventResourceTypeBatchSpecResolutionJob,
+		AuditEventResourceTypeChangeset:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToDB returns the database representation of the resource type.
+func (t AuditEventResourceType) ToDB() string { return strings.ToLower(string(t)) }
+
+// AuditEvent records that a user changed the state of a batch changes
+// resource. Audit events are append-only and are kept when the resource is
+// deleted.
+type AuditEvent struct {
+	ID int64
+
+	// ActorUserID is the user that made the change. It is zero if the change
+	// was made by Sourcegraph itself.
+	ActorUserID int32
+
+	Action       AuditEventAction
+	ResourceType AuditEventResourceType
+	// ResourceID is the database ID of the resource, which may no longer
+	// exist.
+	ResourceID int64
+
+	// Metadata holds additional details about the change, such as the batch
+	// spec a resolution job belongs to.
+	Metadata map[string]string
+
+	CreatedAt time.Time
+}
@@ -28,6 +28,10 @@ type BatchChange struct {
 
 	ClosedAt time.Time
 
+	// AutoRebase is true if the changesets of the batch change are rebased
+	// automatically when their base branch moves.
+	AutoRebase bool
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
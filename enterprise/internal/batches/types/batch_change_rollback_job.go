@@ -0,0 +1,64 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// BatchChangeRollbackJobState defines the possible states of a
+// BatchChangeRollbackJob.
+type BatchChangeRollbackJobState string
+
+// BatchChangeRollbackJobState constants.
+const (
+	BatchChangeRollbackJobStateQueued     BatchChangeRollbackJobState = "QUEUED"
+	BatchChangeRollbackJobStateProcessing BatchChangeRollbackJobState = "PROCESSING"
+	BatchChangeRollbackJobStateErrored    BatchChangeRollbackJobState = "ERRORED"
+	BatchChangeRollbackJobStateFailed     BatchChangeRollbackJobState = "FAILED"
+	BatchChangeRollbackJobStateCompleted  BatchChangeRollbackJobState = "COMPLETED"
+)
+
+// Valid returns true if the given BatchChangeRollbackJobState is valid.
+func (s BatchChangeRollbackJobState) Valid() bool {
+	switch s {
+	case BatchChangeRollbackJobStateQueued,
+		BatchChangeRollbackJobStateProcessing,
+		BatchChangeRollbackJobStateErrored,
+		BatchChangeRollbackJobStateFailed,
+		BatchChangeRollbackJobStateCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToDB returns the database representation of the worker state. That's
+// needed because we want to use UPPERCASE in the application and GraphQL layer,
+// but need to use lowercase in the database to make it work with workerutil.Worker.
+func (s BatchChangeRollbackJobState) ToDB() string { return strings.ToLower(string(s)) }
+
+// BatchChangeRollbackJob rolls back an applied batch change: changesets that
+// are yet to be published are no longer published, published changesets are
+// closed and the batch change itself is closed.
+type BatchChangeRollbackJob struct {
+	ID            int64
+	BatchChangeID int64
+	// UserID is the user that requested the rollback.
+	UserID int32
+
+	State          BatchChangeRollbackJobState
+	FailureMessage *string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	ProcessAfter   time.Time
+	NumResets      int64
+	NumFailures    int64
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecordID implements the workerutil.Record interface.
+func (j *BatchChangeRollbackJob) RecordID() int {
+	return int(j.ID)
+}
@@ -33,6 +33,10 @@ type BatchSpec struct {
 
 	UserID int32
 
+	// NoCache is true if the execution of the batch spec doesn't reuse cached
+	// step results. The results of its steps are cached nonetheless.
+	NoCache bool
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
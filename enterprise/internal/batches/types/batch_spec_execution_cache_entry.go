@@ -0,0 +1,101 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+)
+
+// BatchSpecExecutionCacheEntry is the cached result of executing the steps of
+// a workspace up to and including one step. A later execution of the same
+// steps in a workspace with the same content can skip them and continue from
+// the cached result.
+type BatchSpecExecutionCacheEntry struct {
+	ID int64
+	// UserID is the user that executed the steps. Cached results are only
+	// reused for executions of that user.
+	UserID int32
+
+	// WorkspaceKey is the key of the content of the workspace, see
+	// ExecutionCacheWorkspaceKey.
+	WorkspaceKey string
+	// StepKey is the key of the steps up to and including the cached one, see
+	// ExecutionCacheStepKeys.
+	StepKey string
+
+	Result batcheslib.AfterStepResult
+
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// executionCacheWorkspace holds everything the content of a workspace, and the
+// values available to the templates of its steps, are derived from.
+type executionCacheWorkspace struct {
+	RepoID             api.RepoID
+	RepoName           string
+	Branch             string
+	Commit             string
+	Path               string
+	OnlyFetchWorkspace bool
+	SearchResultPaths  []string
+
+	BatchChangeName        string
+	BatchChangeDescription string
+}
+
+// ExecutionCacheWorkspaceKey returns the hex-encoded SHA-256 hash of the
+// content of the given workspace of the given batch spec. Commits are content
+// addressed, so two workspaces have the same key if they are in the same
+// repository, commit and path, and their steps are templated with the same
+// values.
+func ExecutionCacheWorkspaceKey(spec *BatchSpec, ws *BatchSpecWorkspace, repoName string) (string, error) {
+	key := executionCacheWorkspace{
+		RepoID:             ws.RepoID,
+		RepoName:           repoName,
+		Branch:             ws.Branch,
+		Commit:             ws.Commit,
+		Path:               ws.Path,
+		OnlyFetchWorkspace: ws.OnlyFetchWorkspace,
+		SearchResultPaths:  append([]string{}, ws.FileMatches...),
+	}
+	sort.Strings(key.SearchResultPaths)
+	if spec.Spec != nil {
+		key.BatchChangeName = spec.Spec.Name
+		key.BatchChangeDescription = spec.Spec.Description
+	}
+
+	return hashJSON(key)
+}
+
+// ExecutionCacheStepKeys returns the keys of the results of the given steps.
+// The key of a step is the hash of the digests of its container image, command,
+// environment, files, outputs and condition, and of those of all the steps
+// before it, since its result depends on theirs.
+func ExecutionCacheStepKeys(steps []batcheslib.Step) ([]string, error) {
+	keys := make([]string, 0, len(steps))
+	h := sha256.New()
+	for _, step := range steps {
+		digest, err := hashJSON(step)
+		if err != nil {
+			return nil, err
+		}
+		h.Write([]byte(digest))
+		keys = append(keys, hex.EncodeToString(h.Sum(nil)))
+	}
+	return keys, nil
+}
+
+func hashJSON(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(raw)
+	return hex.EncodeToString(h[:]), nil
+}
@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// BatchSpecExecutionSchedule periodically re-runs the batch spec of a batch
+// change: every Interval, a new batch spec is created from the raw spec the
+// batch change was last applied with, its workspaces are resolved and, once
+// resolution completed, it is executed.
+type BatchSpecExecutionSchedule struct {
+	ID            int64
+	BatchChangeID int64
+	// UserID is the user whose permissions the scheduled runs use.
+	UserID int32
+
+	Interval time.Duration
+	Enabled  bool
+
+	NextRunAt time.Time
+	LastRunAt time.Time
+
+	// PendingBatchSpecID is the batch spec created by the last run, as long
+	// as it waits for its workspaces to be resolved before it is executed.
+	PendingBatchSpecID int64
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
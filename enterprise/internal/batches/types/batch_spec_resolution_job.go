@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil"
 )
 
@@ -36,6 +37,20 @@ func (s BatchSpecResolutionJobState) Valid() bool {
 // ToGraphQL returns the GraphQL representation of the worker state.
 func (s BatchSpecResolutionJobState) ToGraphQL() string { return strings.ToUpper(string(s)) }
 
+// BatchSpecResolutionJobPriority defines the order in which queued batch spec
+// resolution jobs are dequeued. Jobs of a higher priority are dequeued first.
+type BatchSpecResolutionJobPriority int32
+
+// BatchSpecResolutionJobPriority constants.
+const (
+	// BatchSpecResolutionJobPriorityBulk is the default priority, used for
+	// resolutions triggered by the CLI or other automation.
+	BatchSpecResolutionJobPriorityBulk BatchSpecResolutionJobPriority = 0
+	// BatchSpecResolutionJobPriorityInteractive is used for resolutions a
+	// user is waiting for in the UI.
+	BatchSpecResolutionJobPriorityInteractive BatchSpecResolutionJobPriority = 10
+)
+
 type BatchSpecResolutionJob struct {
 	ID int64
 
@@ -43,6 +58,41 @@ type BatchSpecResolutionJob struct {
 	AllowUnsupported bool
 	AllowIgnored     bool
 
+	// Labels are arbitrary key/value pairs set at creation time, e.g. to tag
+	// a resolution with the ID of the pipeline run that triggered it.
+	Labels map[string]string
+
+	// DryRun jobs only preview which repositories and workspaces the batch
+	// spec targets. Instead of creating BatchSpecWorkspaces, the worker
+	// stores the preview in DryRunResult.
+	DryRun       bool
+	DryRunResult *BatchSpecResolutionDryRunResult
+
+	// SearchQueries are the repository search queries the resolution
+	// executed to discover workspaces, exactly as they were sent to the
+	// search API (i.e. after defaults were applied).
+	SearchQueries []string
+
+	// Progress is updated periodically by the worker while the resolution
+	// is processing.
+	Progress BatchSpecResolutionJobProgress
+
+	// CredentialWarnings lists the code hosts of the resolved workspaces for
+	// which no credential to publish changesets is configured.
+	CredentialWarnings []*BatchSpecResolutionCredentialWarning
+
+	// RepositoryErrors lists the repositories that could not be resolved or
+	// inspected, when the resolution failed only for some of them.
+	RepositoryErrors []*BatchSpecResolutionRepositoryError
+
+	// Priority is set at creation time and determines how early the job is
+	// dequeued relative to other queued jobs.
+	Priority BatchSpecResolutionJobPriority
+
+	// Summary is generated by the worker once the job has finished. It is
+	// nil until then.
+	Summary *BatchSpecResolutionJobSummary
+
 	// workerutil fields
 	State           BatchSpecResolutionJobState
 	FailureMessage  *string
@@ -63,3 +113,277 @@ type BatchSpecResolutionJob struct {
 func (j *BatchSpecResolutionJob) RecordID() int {
 	return int(j.ID)
 }
+
+// BatchSpecResolutionDryRunResult is the preview of the workspaces a batch
+// spec targets, as produced by a dry run resolution.
+type BatchSpecResolutionDryRunResult struct {
+	Workspaces []*BatchSpecResolutionDryRunWorkspace `json:"workspaces"`
+
+	// UnsupportedRepoIDs and IgnoredRepoIDs are the repositories that were
+	// matched but are unsupported or contain a .batchignore file.
+	UnsupportedRepoIDs []api.RepoID `json:"unsupportedRepoIDs"`
+	IgnoredRepoIDs     []api.RepoID `json:"ignoredRepoIDs"`
+}
+
+// BatchSpecResolutionDryRunWorkspace is a workspace that a batch spec targets.
+type BatchSpecResolutionDryRunWorkspace struct {
+	RepoID             api.RepoID `json:"repoID"`
+	Branch             string     `json:"branch"`
+	Commit             string     `json:"commit"`
+	Path               string     `json:"path"`
+	FileMatches        []string   `json:"fileMatches"`
+	OnlyFetchWorkspace bool       `json:"onlyFetchWorkspace"`
+}
+
+// BatchSpecResolutionCredentialWarning warns that neither the creator of the
+// batch spec nor the site has a credential configured for a code host of the
+// resolved workspaces, so publishing changesets on it would fail.
+type BatchSpecResolutionCredentialWarning struct {
+	ExternalServiceType string `json:"externalServiceType"`
+	ExternalServiceID   string `json:"externalServiceID"`
+	// RepoCount is the number of resolved repositories on the code host.
+	RepoCount int `json:"repoCount"`
+}
+
+// BatchSpecResolutionRepositoryError records why a single repository could
+// not be resolved.
+type BatchSpecResolutionRepositoryError struct {
+	// RepoID is not set if the repository could not be found.
+	RepoID   api.RepoID `json:"repoID,omitempty"`
+	RepoName string     `json:"repoName"`
+	Message  string     `json:"message"`
+}
+
+// BatchSpecResolutionJobSummaryVersion is the version of the format of
+// BatchSpecResolutionJobSummary. It must be incremented on every change that
+// isn't backwards compatible, since the summary is consumed by external
+// systems.
+const BatchSpecResolutionJobSummaryVersion = 1
+
+// BatchSpecResolutionJobSummary is a machine-readable summary of a finished
+// resolution job, for consumption by external systems that orchestrate batch
+// changes without using the GraphQL API.
+type BatchSpecResolutionJobSummary struct {
+	Version     int                         `json:"version"`
+	JobID       int64                       `json:"jobID"`
+	BatchSpecID int64                       `json:"batchSpecID"`
+	DryRun      bool                        `json:"dryRun"`
+	State       BatchSpecResolutionJobState `json:"state"`
+
+	Counts  BatchSpecResolutionJobSummaryCounts  `json:"counts"`
+	Timings BatchSpecResolutionJobSummaryTimings `json:"timings"`
+
+	// Failure is set if the job did not complete successfully.
+	Failure *BatchSpecResolutionJobSummaryFailure `json:"failure"`
+}
+
+// BatchSpecResolutionJobSummaryCounts holds the counts of a
+// BatchSpecResolutionJobSummary.
+type BatchSpecResolutionJobSummaryCounts struct {
+	SearchQueries int `json:"searchQueries"`
+	Repositories  int `json:"repositories"`
+	Workspaces    int `json:"workspaces"`
+}
+
+// BatchSpecResolutionJobSummaryTimings holds the timings of a
+// BatchSpecResolutionJobSummary.
+type BatchSpecResolutionJobSummaryTimings struct {
+	QueuedAt     time.Time `json:"queuedAt"`
+	StartedAt    time.Time `json:"startedAt"`
+	FinishedAt   time.Time `json:"finishedAt"`
+	QueuedMs     int64     `json:"queuedMs"`
+	ProcessingMs int64     `json:"processingMs"`
+}
+
+// BatchSpecResolutionFailureCategory classifies why a resolution job failed.
+type BatchSpecResolutionFailureCategory string
+
+// BatchSpecResolutionFailureCategory constants.
+const (
+	// BatchSpecResolutionFailureInvalidSpec means the batch spec could not
+	// be parsed.
+	BatchSpecResolutionFailureInvalidSpec BatchSpecResolutionFailureCategory = "INVALID_SPEC"
+	// BatchSpecResolutionFailureRepositorySearch means resolving the
+	// repositories and workspaces the batch spec targets failed.
+	BatchSpecResolutionFailureRepositorySearch BatchSpecResolutionFailureCategory = "REPOSITORY_SEARCH"
+	// BatchSpecResolutionFailureTimeout means the resolution took too long.
+	BatchSpecResolutionFailureTimeout BatchSpecResolutionFailureCategory = "TIMEOUT"
+	// BatchSpecResolutionFailureInternal covers all other failures.
+	BatchSpecResolutionFailureInternal BatchSpecResolutionFailureCategory = "INTERNAL"
+)
+
+// BatchSpecResolutionJobSummaryFailure describes why a resolution job failed.
+type BatchSpecResolutionJobSummaryFailure struct {
+	Category BatchSpecResolutionFailureCategory `json:"category"`
+	Message  string                             `json:"message"`
+}
+
+// BatchSpecResolutionJobLogs is a window of the execution logs of a
+// resolution job, starting at Offset.
+type BatchSpecResolutionJobLogs struct {
+	State   BatchSpecResolutionJobState
+	Offset  int
+	Entries []workerutil.ExecutionLogEntry
+	// Total is the number of log entries the job has overall.
+	Total int
+}
+
+// BatchSpecResolutionJobLogMatch is an execution log entry of a resolution job
+// that matched a log search.
+type BatchSpecResolutionJobLogMatch struct {
+	JobID       int64
+	BatchSpecID int64
+	// Index is the position of the entry in the execution logs of the job.
+	Index int
+	Entry workerutil.ExecutionLogEntry
+}
+
+// BatchSpecResolutionJobProgress describes how far a batch spec resolution
+// has progressed.
+type BatchSpecResolutionJobProgress struct {
+	// ReposDiscovered is the number of repositories matched by the "on"
+	// definitions of the batch spec that have been resolved so far.
+	ReposDiscovered int `json:"reposDiscovered"`
+	// WorkspacesResolved is the number of workspaces found in those
+	// repositories.
+	WorkspacesResolved int `json:"workspacesResolved"`
+	// PercentComplete is an estimate of how much of the resolution is done,
+	// between 0 and 100.
+	PercentComplete int `json:"percentComplete"`
+}
+
+// BatchSpecResolutionJobDurationStats holds duration percentiles of completed
+// resolution jobs, bucketed by the number of repositories their batch spec
+// resolved to.
+type BatchSpecResolutionJobDurationStats struct {
+	// MaxRepos is the inclusive upper bound of the bucket. Zero means the
+	// bucket is unbounded.
+	MaxRepos int
+	Count    int
+	P50      time.Duration
+	P90      time.Duration
+}
+
+// BatchSpecResolutionJobOutcome records that a resolution job that isn't a dry
+// run reached a final state. Outcomes are kept when the job or its batch spec
+// are deleted.
+type BatchSpecResolutionJobOutcome struct {
+	ID                       int64
+	BatchSpecResolutionJobID int64
+	BatchSpecID              int64
+
+	NamespaceUserID int32
+	NamespaceOrgID  int32
+
+	// State is either BatchSpecResolutionJobStateCompleted or
+	// BatchSpecResolutionJobStateFailed. Errored jobs are never retried, so
+	// they are recorded as failed.
+	State                BatchSpecResolutionJobState
+	RepositoryErrorCount int
+	FinishedAt           time.Time
+}
+
+// BatchSpecResolutionJobOutcomeCounts holds the number of resolution jobs that
+// finished in the interval starting at Time.
+type BatchSpecResolutionJobOutcomeCounts struct {
+	Time      time.Time
+	Completed int
+	Failed    int
+
+	// Namespaces is the number of distinct namespaces that resolved batch
+	// specs in the interval.
+	Namespaces int
+}
+
+// BatchSpecResolutionJobStats describes the current state of the queue of
+// resolution jobs.
+type BatchSpecResolutionJobStats struct {
+	// Counts holds the number of jobs in each state. States without any jobs
+	// are omitted.
+	Counts map[BatchSpecResolutionJobState]int
+	// OldestQueuedAt is when the oldest queued job was created. It is zero
+	// if no job is queued.
+	OldestQueuedAt time.Time
+	// AverageProcessingTime is the average duration of recently completed
+	// jobs. It is zero if no job completed recently.
+	AverageProcessingTime time.Duration
+}
+
+// EstimateTimeRemaining estimates how much longer a resolution job that has
+// been processing for elapsed and reported percentComplete as its progress
+// will take to complete.
+//
+// The total duration of the job is estimated from the given historical stats,
+// which must be ordered by ascending MaxRepos with the unbounded bucket last,
+// and from the rate of the job's progress so far. The further the job has
+// progressed, the more the rate of its progress is trusted over the history.
+// If repoCount is negative the number of repositories is considered unknown and
+// the stats of all buckets are consulted.
+//
+// It returns false if no estimate can be made, because the job reported no
+// progress and there is either no history or the job has already been running
+// for longer than the 90th percentile.
+func EstimateTimeRemaining(stats []*BatchSpecResolutionJobDurationStats, repoCount int, elapsed time.Duration, percentComplete int) (time.Duration, bool) {
+	if percentComplete >= 100 {
+		return 0, true
+	}
+
+	historical, ok := estimateTotalDuration(stats, repoCount, elapsed)
+	if percentComplete <= 0 {
+		if !ok {
+			return 0, false
+		}
+		return historical - elapsed, true
+	}
+
+	progressed := float64(percentComplete) / 100
+	total := float64(elapsed) / progressed
+	if ok {
+		total = progressed*total + (1-progressed)*float64(historical)
+	}
+	return time.Duration(total) - elapsed, true
+}
+
+// estimateTotalDuration estimates the total duration of a resolution job that
+// has been processing for elapsed from the historical stats, as described by
+// EstimateTimeRemaining. It returns false if there is no history or the job
+// has already been running for longer than the 90th percentile.
+func estimateTotalDuration(stats []*BatchSpecResolutionJobDurationStats, repoCount int, elapsed time.Duration) (time.Duration, bool) {
+	var candidates []*BatchSpecResolutionJobDurationStats
+	if repoCount >= 0 {
+		for _, s := range stats {
+			if s.MaxRepos == 0 || repoCount <= s.MaxRepos {
+				if s.Count > 0 {
+					candidates = []*BatchSpecResolutionJobDurationStats{s}
+				}
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = stats
+	}
+
+	// Weight each bucket's percentiles by the number of jobs it contains.
+	var count int
+	var p50, p90 float64
+	for _, s := range candidates {
+		count += s.Count
+		p50 += float64(s.Count) * float64(s.P50)
+		p90 += float64(s.Count) * float64(s.P90)
+	}
+	if count == 0 {
+		return 0, false
+	}
+	p50, p90 = p50/float64(count), p90/float64(count)
+
+	if float64(elapsed) >= p90 {
+		return 0, false
+	}
+	if float64(elapsed) >= p50 {
+		// We're past the median, so use the 90th percentile as a
+		// pessimistic estimate.
+		return time.Duration(p90), true
+	}
+	return time.Duration(p50), true
+}
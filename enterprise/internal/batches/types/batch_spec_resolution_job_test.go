@@ -0,0 +1,117 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateTimeRemaining(t *testing.T) {
+	t.Parallel()
+
+	stats := []*BatchSpecResolutionJobDurationStats{
+		{MaxRepos: 10, Count: 10, P50: 10 * time.Second, P90: 20 * time.Second},
+		{MaxRepos: 100, Count: 30, P50: 60 * time.Second, P90: 120 * time.Second},
+		{MaxRepos: 0, Count: 0, P50: 0, P90: 0},
+	}
+
+	tests := map[string]struct {
+		stats     []*BatchSpecResolutionJobDurationStats
+		repoCount int
+		elapsed   time.Duration
+		percent   int
+
+		wantRemaining time.Duration
+		wantOK        bool
+	}{
+		"no history": {
+			stats:     nil,
+			repoCount: 5,
+			elapsed:   time.Second,
+		},
+		"small bucket before median": {
+			stats:         stats,
+			repoCount:     5,
+			elapsed:       4 * time.Second,
+			wantRemaining: 6 * time.Second,
+			wantOK:        true,
+		},
+		"small bucket past median": {
+			stats:         stats,
+			repoCount:     5,
+			elapsed:       15 * time.Second,
+			wantRemaining: 5 * time.Second,
+			wantOK:        true,
+		},
+		"past 90th percentile": {
+			stats:     stats,
+			repoCount: 5,
+			elapsed:   30 * time.Second,
+		},
+		"larger bucket": {
+			stats:         stats,
+			repoCount:     50,
+			elapsed:       30 * time.Second,
+			wantRemaining: 30 * time.Second,
+			wantOK:        true,
+		},
+		"unknown repo count uses weighted average": {
+			stats:     stats,
+			repoCount: -1,
+			elapsed:   0,
+			// (10*10s + 30*60s) / 40
+			wantRemaining: 47500 * time.Millisecond,
+			wantOK:        true,
+		},
+		"empty matching bucket falls back to all buckets": {
+			stats:         stats,
+			repoCount:     5000,
+			elapsed:       0,
+			wantRemaining: 47500 * time.Millisecond,
+			wantOK:        true,
+		},
+		"progress without history": {
+			stats:         nil,
+			repoCount:     5,
+			elapsed:       10 * time.Second,
+			percent:       25,
+			wantRemaining: 30 * time.Second,
+			wantOK:        true,
+		},
+		"progress weighted with history": {
+			stats:     stats,
+			repoCount: 5,
+			elapsed:   4 * time.Second,
+			percent:   50,
+			// 0.5*8s from the progress + 0.5*10s from the median
+			wantRemaining: 5 * time.Second,
+			wantOK:        true,
+		},
+		"progress past 90th percentile": {
+			stats:         stats,
+			repoCount:     5,
+			elapsed:       30 * time.Second,
+			percent:       75,
+			wantRemaining: 10 * time.Second,
+			wantOK:        true,
+		},
+		"complete": {
+			stats:     stats,
+			repoCount: 5,
+			elapsed:   30 * time.Second,
+			percent:   100,
+			wantOK:    true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			remaining, ok := EstimateTimeRemaining(tc.stats, tc.repoCount, tc.elapsed, tc.percent)
+			if ok != tc.wantOK {
+				t.Fatalf("wrong ok. want=%t, have=%t", tc.wantOK, ok)
+			}
+			if remaining != tc.wantRemaining {
+				t.Fatalf("wrong remaining. want=%s, have=%s", tc.wantRemaining, remaining)
+			}
+		})
+	}
+}
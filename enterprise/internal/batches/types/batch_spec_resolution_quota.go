@@ -0,0 +1,72 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// BatchSpecResolutionQuotaWindow is the window in which the resolutions
+// counted against BatchSpecResolutionQuota.MaxPerDay were created.
+const BatchSpecResolutionQuotaWindow = 24 * time.Hour
+
+// BatchSpecResolutionQuota limits the resolution jobs of the batch specs in a
+// namespace. Zero values mean unlimited.
+type BatchSpecResolutionQuota struct {
+	MaxConcurrent int
+	MaxPerDay     int
+}
+
+// CurrentBatchSpecResolutionQuota returns the quota configured with
+// batchChanges.resolutionQuota in the site configuration.
+func CurrentBatchSpecResolutionQuota() BatchSpecResolutionQuota {
+	c := conf.Get().BatchChangesResolutionQuota
+	if c == nil {
+		return BatchSpecResolutionQuota{}
+	}
+	return BatchSpecResolutionQuota{MaxConcurrent: c.MaxConcurrent, MaxPerDay: c.MaxPerDay}
+}
+
+// Unlimited returns true if the quota doesn't limit anything.
+func (q BatchSpecResolutionQuota) Unlimited() bool {
+	return q.MaxConcurrent <= 0 && q.MaxPerDay <= 0
+}
+
+// BatchSpecResolutionQuotaUsage is the part of the BatchSpecResolutionQuota a
+// namespace currently uses.
+type BatchSpecResolutionQuotaUsage struct {
+	// Concurrent is the number of queued or processing resolution jobs.
+	// Errored jobs are never retried, so they don't count.
+	Concurrent int
+	// LastDay is the number of resolution jobs created within
+	// BatchSpecResolutionQuotaWindow, including those that were deleted since.
+	LastDay int
+}
+
+// Check returns an ErrBatchSpecResolutionQuotaExceeded if another resolution
+// job would exceed the given quota.
+func (u BatchSpecResolutionQuotaUsage) Check(q BatchSpecResolutionQuota) error {
+	if q.MaxConcurrent > 0 && u.Concurrent >= q.MaxConcurrent {
+		return ErrBatchSpecResolutionQuotaExceeded{Limit: "concurrent", Max: q.MaxConcurrent}
+	}
+	if q.MaxPerDay > 0 && u.LastDay >= q.MaxPerDay {
+		return ErrBatchSpecResolutionQuotaExceeded{Limit: "daily", Max: q.MaxPerDay}
+	}
+	return nil
+}
+
+// ErrBatchSpecResolutionQuotaExceeded is returned when a resolution job can't
+// be created, because the namespace of its batch spec used up its quota.
+type ErrBatchSpecResolutionQuotaExceeded struct {
+	// Limit is the exceeded limit, either "concurrent" or "daily".
+	Limit string
+	Max   int
+}
+
+func (e ErrBatchSpecResolutionQuotaExceeded) Error() string {
+	if e.Limit == "daily" {
+		return fmt.Sprintf("batch spec resolution quota exceeded: at most %d resolutions can be started per day in this namespace", e.Max)
+	}
+	return fmt.Sprintf("batch spec resolution quota exceeded: at most %d resolutions can run at the same time in this namespace", e.Max)
+}
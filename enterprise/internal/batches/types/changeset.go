@@ -11,7 +11,9 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/timeutil"
@@ -284,6 +286,14 @@ type Changeset struct {
 	// Closing is set to true (along with the ReocncilerState) when the
 	// reconciler should close the changeset.
 	Closing bool
+
+	// RebaseBaseRev is the commit of the base branch the last automatic rebase
+	// of the changeset was attempted onto. It's empty if the changeset hasn't
+	// been rebased since the commit of its current spec was pushed.
+	RebaseBaseRev string
+	// RebaseConflict is set when the last automatic rebase of the changeset
+	// failed because its diff doesn't apply on the base branch anymore.
+	RebaseConflict *string
 }
 
 // RecordID is needed to implement the workerutil.Record interface.
@@ -373,12 +383,28 @@ func (c *Changeset) SetMetadata(meta interface{}) error {
 		c.ExternalServiceType = extsvc.TypeBitbucketServer
 		c.ExternalBranch = git.EnsureRefPrefix(pr.FromRef.ID)
 		c.ExternalUpdatedAt = unixMilliToTime(int64(pr.UpdatedDate))
+	case *bitbucketcloud.AnnotatedPullRequest:
+		c.Metadata = pr
+		c.ExternalID = strconv.FormatInt(pr.ID, 10)
+		c.ExternalServiceType = extsvc.TypeBitbucketCloud
+		c.ExternalBranch = git.EnsureRefPrefix(pr.Source.Branch.Name)
+		c.ExternalUpdatedAt = pr.UpdatedOn
 	case *gitlab.MergeRequest:
 		c.Metadata = pr
 		c.ExternalID = strconv.FormatInt(int64(pr.IID), 10)
 		c.ExternalServiceType = extsvc.TypeGitLab
 		c.ExternalBranch = git.EnsureRefPrefix(pr.SourceBranch)
 		c.ExternalUpdatedAt = pr.UpdatedAt.Time
+	case *gerrit.Change:
+		c.Metadata = pr
+		c.ExternalID = strconv.Itoa(pr.Number)
+		c.ExternalServiceType = extsvc.TypeGerrit
+		// Changes don't have a head branch, but the changesets we publish use
+		// their branch as the topic of the change.
+		if pr.Topic != "" {
+			c.ExternalBranch = git.EnsureRefPrefix(pr.Topic)
+		}
+		c.ExternalUpdatedAt = pr.Updated.Time
 	default:
 		return errors.New("unknown changeset type")
 	}
@@ -402,8 +428,12 @@ func (c *Changeset) Title() (string, error) {
 		return m.Title, nil
 	case *bitbucketserver.PullRequest:
 		return m.Title, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return m.Title, nil
 	case *gitlab.MergeRequest:
 		return m.Title, nil
+	case *gerrit.Change:
+		return m.Subject, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -419,8 +449,12 @@ func (c *Changeset) AuthorName() (string, error) {
 			return "", nil
 		}
 		return m.Author.User.Name, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return m.Author.Nickname, nil
 	case *gitlab.MergeRequest:
 		return m.Author.Username, nil
+	case *gerrit.Change:
+		return m.Owner.Username, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -442,8 +476,13 @@ func (c *Changeset) AuthorEmail() (string, error) {
 			return "", nil
 		}
 		return m.Author.User.EmailAddress, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		// Bitbucket Cloud doesn't expose the email addresses of accounts.
+		return "", nil
 	case *gitlab.MergeRequest:
 		return m.Author.Email, nil
+	case *gerrit.Change:
+		return m.Owner.Email, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -458,8 +497,12 @@ func (c *Changeset) ExternalCreatedAt() time.Time {
 		return m.CreatedAt
 	case *bitbucketserver.PullRequest:
 		return unixMilliToTime(int64(m.CreatedDate))
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return m.CreatedOn
 	case *gitlab.MergeRequest:
 		return m.CreatedAt.Time
+	case *gerrit.Change:
+		return m.Created.Time
 	default:
 		return time.Time{}
 	}
@@ -472,8 +515,12 @@ func (c *Changeset) Body() (string, error) {
 		return m.Body, nil
 	case *bitbucketserver.PullRequest:
 		return m.Description, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return m.Description, nil
 	case *gitlab.MergeRequest:
 		return m.Description, nil
+	case *gerrit.Change:
+		return m.Body(), nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -509,8 +556,12 @@ func (c *Changeset) URL() (s string, err error) {
 		}
 		selfLink := m.Links.Self[0]
 		return selfLink.Href, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return m.Links.HTML.Href, nil
 	case *gitlab.MergeRequest:
 		return m.WebURL, nil
+	case *gerrit.Change:
+		return m.URL, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -600,6 +651,22 @@ func (c *Changeset) Events() (events []*ChangesetEvent, err error) {
 			}
 		}
 
+	case *bitbucketcloud.AnnotatedPullRequest:
+		events = make([]*ChangesetEvent, 0, len(m.Statuses))
+
+		for _, s := range m.Statuses {
+			var kind ChangesetEventKind
+			if kind, err = ChangesetEventKindFor(s); err != nil {
+				return
+			}
+			appendEvent(&ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         s.Key(),
+				Kind:        kind,
+				Metadata:    s,
+			})
+		}
+
 	case *gitlab.MergeRequest:
 		events = make([]*ChangesetEvent, 0, len(m.Notes)+len(m.ResourceStateEvents)+len(m.Pipelines))
 		var kind ChangesetEventKind
@@ -656,8 +723,14 @@ func (c *Changeset) HeadRefOid() (string, error) {
 		return m.HeadRefOid, nil
 	case *bitbucketserver.PullRequest:
 		return "", nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		// The API only returns abbreviated commit hashes, which gitserver
+		// resolves like any other revision.
+		return m.Source.Commit.Hash, nil
 	case *gitlab.MergeRequest:
 		return m.DiffRefs.HeadSHA, nil
+	case *gerrit.Change:
+		return m.CurrentRevision, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -671,8 +744,12 @@ func (c *Changeset) HeadRef() (string, error) {
 		return "refs/heads/" + m.HeadRefName, nil
 	case *bitbucketserver.PullRequest:
 		return m.FromRef.ID, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return "refs/heads/" + m.Source.Branch.Name, nil
 	case *gitlab.MergeRequest:
 		return "refs/heads/" + m.SourceBranch, nil
+	case *gerrit.Change:
+		return "refs/heads/" + m.Topic, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -687,8 +764,16 @@ func (c *Changeset) BaseRefOid() (string, error) {
 		return m.BaseRefOid, nil
 	case *bitbucketserver.PullRequest:
 		return "", nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return m.Destination.Commit.Hash, nil
 	case *gitlab.MergeRequest:
 		return m.DiffRefs.BaseSHA, nil
+	case *gerrit.Change:
+		// Changes consist of a single commit, so the base is its parent.
+		if commit := m.CurrentCommit(); commit != nil && len(commit.Parents) > 0 {
+			return commit.Parents[0].Commit, nil
+		}
+		return "", nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -702,8 +787,12 @@ func (c *Changeset) BaseRef() (string, error) {
 		return "refs/heads/" + m.BaseRefName, nil
 	case *bitbucketserver.PullRequest:
 		return m.ToRef.ID, nil
+	case *bitbucketcloud.AnnotatedPullRequest:
+		return "refs/heads/" + m.Destination.Branch.Name, nil
 	case *gitlab.MergeRequest:
 		return "refs/heads/" + m.TargetBranch, nil
+	case *gerrit.Change:
+		return "refs/heads/" + m.Branch, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -964,6 +1053,20 @@ func ChangesetEventKindFor(e interface{}) (ChangesetEventKind, error) {
 		return ChangesetEventKind("bitbucketserver:participant_status:" + strings.ToLower(string(e.Action))), nil
 	case *bitbucketserver.CommitStatus:
 		return ChangesetEventKindBitbucketServerCommitStatus, nil
+	case *bitbucketcloud.PullRequestApprovedEvent:
+		return ChangesetEventKindBitbucketCloudApproved, nil
+	case *bitbucketcloud.PullRequestUnapprovedEvent:
+		return ChangesetEventKindBitbucketCloudUnapproved, nil
+	case *bitbucketcloud.PullRequestChangesRequestCreatedEvent:
+		return ChangesetEventKindBitbucketCloudChangesRequestCreated, nil
+	case *bitbucketcloud.PullRequestChangesRequestRemovedEvent:
+		return ChangesetEventKindBitbucketCloudChangesRequestRemoved, nil
+	case *bitbucketcloud.PullRequestFulfilledEvent:
+		return ChangesetEventKindBitbucketCloudFulfilled, nil
+	case *bitbucketcloud.PullRequestRejectedEvent:
+		return ChangesetEventKindBitbucketCloudRejected, nil
+	case *bitbucketcloud.CommitStatus:
+		return ChangesetEventKindBitbucketCloudCommitStatus, nil
 	case *gitlab.Pipeline:
 		return ChangesetEventKindGitLabPipeline, nil
 	case *gitlab.ReviewApprovedEvent:
@@ -999,6 +1102,23 @@ func NewChangesetEventMetadata(k ChangesetEventKind) (interface{}, error) {
 		default:
 			return new(bitbucketserver.Activity), nil
 		}
+	case strings.HasPrefix(string(k), "bitbucketcloud"):
+		switch k {
+		case ChangesetEventKindBitbucketCloudApproved:
+			return new(bitbucketcloud.PullRequestApprovedEvent), nil
+		case ChangesetEventKindBitbucketCloudUnapproved:
+			return new(bitbucketcloud.PullRequestUnapprovedEvent), nil
+		case ChangesetEventKindBitbucketCloudChangesRequestCreated:
+			return new(bitbucketcloud.PullRequestChangesRequestCreatedEvent), nil
+		case ChangesetEventKindBitbucketCloudChangesRequestRemoved:
+			return new(bitbucketcloud.PullRequestChangesRequestRemovedEvent), nil
+		case ChangesetEventKindBitbucketCloudFulfilled:
+			return new(bitbucketcloud.PullRequestFulfilledEvent), nil
+		case ChangesetEventKindBitbucketCloudRejected:
+			return new(bitbucketcloud.PullRequestRejectedEvent), nil
+		case ChangesetEventKindBitbucketCloudCommitStatus:
+			return new(bitbucketcloud.CommitStatus), nil
+		}
 	case strings.HasPrefix(string(k), "github"):
 		switch k {
 		case ChangesetEventKindGitHubAssigned:
@@ -9,6 +9,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/inconshreveable/log15"
 
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
@@ -69,6 +70,14 @@ const (
 	// clearly convey that it only occurs when a request for changes has been dismissed.
 	ChangesetEventKindBitbucketServerDismissed ChangesetEventKind = "bitbucketserver:participant_status:unapproved"
 
+	ChangesetEventKindBitbucketCloudApproved              ChangesetEventKind = "bitbucketcloud:pullrequest:approved"
+	ChangesetEventKindBitbucketCloudUnapproved            ChangesetEventKind = "bitbucketcloud:pullrequest:unapproved"
+	ChangesetEventKindBitbucketCloudChangesRequestCreated ChangesetEventKind = "bitbucketcloud:pullrequest:changes_request_created"
+	ChangesetEventKindBitbucketCloudChangesRequestRemoved ChangesetEventKind = "bitbucketcloud:pullrequest:changes_request_removed"
+	ChangesetEventKindBitbucketCloudFulfilled             ChangesetEventKind = "bitbucketcloud:pullrequest:fulfilled"
+	ChangesetEventKindBitbucketCloudRejected              ChangesetEventKind = "bitbucketcloud:pullrequest:rejected"
+	ChangesetEventKindBitbucketCloudCommitStatus          ChangesetEventKind = "bitbucketcloud:commit_status"
+
 	ChangesetEventKindGitLabApproved             ChangesetEventKind = "gitlab:approved"
 	ChangesetEventKindGitLabClosed               ChangesetEventKind = "gitlab:closed"
 	ChangesetEventKindGitLabMerged               ChangesetEventKind = "gitlab:merged"
@@ -115,6 +124,18 @@ func (e *ChangesetEvent) ReviewAuthor() string {
 	case *bitbucketserver.ParticipantStatusEvent:
 		return meta.User.Name
 
+	case *bitbucketcloud.PullRequestApprovedEvent:
+		return meta.Approval.User.UUID
+
+	case *bitbucketcloud.PullRequestUnapprovedEvent:
+		return meta.Approval.User.UUID
+
+	case *bitbucketcloud.PullRequestChangesRequestCreatedEvent:
+		return meta.ChangesRequest.User.UUID
+
+	case *bitbucketcloud.PullRequestChangesRequestRemovedEvent:
+		return meta.ChangesRequest.User.UUID
+
 	case *gitlab.ReviewApprovedEvent:
 		return meta.Author.Username
 
@@ -130,9 +151,13 @@ func (e *ChangesetEvent) ReviewAuthor() string {
 func (e *ChangesetEvent) ReviewState() (ChangesetReviewState, error) {
 	switch e.Kind {
 	case ChangesetEventKindBitbucketServerApproved,
+		ChangesetEventKindBitbucketCloudApproved,
 		ChangesetEventKindGitLabApproved:
 		return ChangesetReviewStateApproved, nil
 
+	case ChangesetEventKindBitbucketCloudChangesRequestCreated:
+		return ChangesetReviewStateChangesRequested, nil
+
 	// BitbucketServer's "REVIEWED" activity is created when someone clicks
 	// the "Needs work" button in the UI, which is why we map it to "Changes Requested"
 	case ChangesetEventKindBitbucketServerReviewed:
@@ -155,6 +180,8 @@ func (e *ChangesetEvent) ReviewState() (ChangesetReviewState, error) {
 	case ChangesetEventKindGitHubReviewDismissed,
 		ChangesetEventKindBitbucketServerUnapproved,
 		ChangesetEventKindBitbucketServerDismissed,
+		ChangesetEventKindBitbucketCloudUnapproved,
+		ChangesetEventKindBitbucketCloudChangesRequestRemoved,
 		ChangesetEventKindGitLabUnapproved:
 		return ChangesetReviewStateDismissed, nil
 
@@ -221,6 +248,20 @@ func (e *ChangesetEvent) Timestamp() time.Time {
 		t = unixMilliToTime(int64(ev.CreatedDate))
 	case *bitbucketserver.CommitStatus:
 		t = unixMilliToTime(ev.Status.DateAdded)
+	case *bitbucketcloud.PullRequestApprovedEvent:
+		t = ev.Approval.Date
+	case *bitbucketcloud.PullRequestUnapprovedEvent:
+		t = ev.Approval.Date
+	case *bitbucketcloud.PullRequestChangesRequestCreatedEvent:
+		t = ev.ChangesRequest.Date
+	case *bitbucketcloud.PullRequestChangesRequestRemovedEvent:
+		t = ev.ChangesRequest.Date
+	case *bitbucketcloud.PullRequestFulfilledEvent:
+		t = ev.PullRequest.UpdatedOn
+	case *bitbucketcloud.PullRequestRejectedEvent:
+		t = ev.PullRequest.UpdatedOn
+	case *bitbucketcloud.CommitStatus:
+		t = ev.Status.UpdatedOn
 	case *gitlab.ReviewApprovedEvent:
 		t = ev.CreatedAt.Time
 	case *gitlab.ReviewUnapprovedEvent:
@@ -602,6 +643,41 @@ func (e *ChangesetEvent) Update(o *ChangesetEvent) error {
 		// We always get the full event, so safe to replace it
 		*e = *o
 
+	case *bitbucketcloud.PullRequestApprovedEvent:
+		o := o.Metadata.(*bitbucketcloud.PullRequestApprovedEvent)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.PullRequestUnapprovedEvent:
+		o := o.Metadata.(*bitbucketcloud.PullRequestUnapprovedEvent)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.PullRequestChangesRequestCreatedEvent:
+		o := o.Metadata.(*bitbucketcloud.PullRequestChangesRequestCreatedEvent)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.PullRequestChangesRequestRemovedEvent:
+		o := o.Metadata.(*bitbucketcloud.PullRequestChangesRequestRemovedEvent)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.PullRequestFulfilledEvent:
+		o := o.Metadata.(*bitbucketcloud.PullRequestFulfilledEvent)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.PullRequestRejectedEvent:
+		o := o.Metadata.(*bitbucketcloud.PullRequestRejectedEvent)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.CommitStatus:
+		o := o.Metadata.(*bitbucketcloud.CommitStatus)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
 	case *github.CheckRun:
 		o := o.Metadata.(*github.CheckRun)
 		if e.Status == "" {
@@ -47,6 +47,8 @@ var (
 	ChangesetJobTypeMerge     ChangesetJobType = "merge"
 	ChangesetJobTypeClose     ChangesetJobType = "close"
 	ChangesetJobTypePublish   ChangesetJobType = "publish"
+
+	ChangesetJobTypeMergeWhenChecksPass ChangesetJobType = "merge_when_checks_pass"
 )
 
 type ChangesetJobCommentPayload struct {
@@ -67,6 +69,10 @@ type ChangesetJobPublishPayload struct {
 	Draft bool `json:"draft"`
 }
 
+type ChangesetJobMergeWhenChecksPassPayload struct {
+	Squash bool `json:"squash,omitempty"`
+}
+
 // ChangesetJob describes a one-time action to be taken on a changeset.
 type ChangesetJob struct {
 	ID int64
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/encryption"
+)
+
+// OutboundWebhookEvent is an event that outbound webhooks are notified of.
+type OutboundWebhookEvent string
+
+// OutboundWebhookEvent constants.
+const (
+	OutboundWebhookEventResolutionCompleted OutboundWebhookEvent = "batch_spec_resolution.completed"
+	OutboundWebhookEventResolutionFailed    OutboundWebhookEvent = "batch_spec_resolution.failed"
+	OutboundWebhookEventExecutionCompleted  OutboundWebhookEvent = "batch_spec_execution.completed"
+	OutboundWebhookEventExecutionFailed     OutboundWebhookEvent = "batch_spec_execution.failed"
+)
+
+// Valid returns true if the given OutboundWebhookEvent is valid.
+func (e OutboundWebhookEvent) Valid() bool {
+	switch e {
+	case OutboundWebhookEventResolutionCompleted,
+		OutboundWebhookEventResolutionFailed,
+		OutboundWebhookEventExecutionCompleted,
+		OutboundWebhookEventExecutionFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// OutboundWebhook is an HTTP endpoint that is notified when batch spec
+// resolutions and executions finish, so that external systems don't have to
+// poll for their state.
+type OutboundWebhook struct {
+	ID  int64
+	URL string
+	// Events are the events the webhook is notified of. If empty, it's
+	// notified of all events.
+	Events []OutboundWebhookEvent
+
+	EncryptedSecret []byte
+	EncryptionKeyID string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Key encryption.Key
+}
+
+// Subscribed returns true if the webhook is notified of the given event.
+func (w *OutboundWebhook) Subscribed(event OutboundWebhookEvent) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Secret decrypts the secret used to sign the deliveries to the webhook.
+func (w *OutboundWebhook) Secret(ctx context.Context) (string, error) {
+	if w.EncryptionKeyID == "" {
+		return string(w.EncryptedSecret), nil
+	}
+	if w.Key == nil {
+		return "", errors.New("webhook secret is encrypted, but no key is available to decrypt it")
+	}
+
+	secret, err := w.Key.Decrypt(ctx, w.EncryptedSecret)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypting secret")
+	}
+	return secret.Secret(), nil
+}
+
+// SetSecret encrypts and sets the secret used to sign the deliveries to the
+// webhook.
+func (w *OutboundWebhook) SetSecret(ctx context.Context, secret string) error {
+	id, err := keyID(ctx, w.Key)
+	if err != nil {
+		return err
+	}
+
+	encrypted := []byte(secret)
+	if w.Key != nil {
+		encrypted, err = w.Key.Encrypt(ctx, []byte(secret))
+		if err != nil {
+			return errors.Wrap(err, "encrypting secret")
+		}
+	}
+
+	w.EncryptedSecret = encrypted
+	w.EncryptionKeyID = id
+	return nil
+}
+
+// OutboundWebhookJobState defines the possible states of an
+// OutboundWebhookJob.
+type OutboundWebhookJobState string
+
+// OutboundWebhookJobState constants.
+const (
+	OutboundWebhookJobStateQueued     OutboundWebhookJobState = "QUEUED"
+	OutboundWebhookJobStateProcessing OutboundWebhookJobState = "PROCESSING"
+	OutboundWebhookJobStateErrored    OutboundWebhookJobState = "ERRORED"
+	OutboundWebhookJobStateFailed     OutboundWebhookJobState = "FAILED"
+	OutboundWebhookJobStateCompleted  OutboundWebhookJobState = "COMPLETED"
+)
+
+// ToDB returns the database representation of the worker state. That's
+// needed because we want to use UPPERCASE in the application and GraphQL layer,
+// but need to use lowercase in the database to make it work with workerutil.Worker.
+func (s OutboundWebhookJobState) ToDB() string { return strings.ToLower(string(s)) }
+
+// OutboundWebhookJob delivers an event about a batch spec to an outbound
+// webhook.
+type OutboundWebhookJob struct {
+	ID          int64
+	WebhookID   int64
+	Event       OutboundWebhookEvent
+	BatchSpecID int64
+	// EventMessage is the failure message of the resolution the event is
+	// about, if any.
+	EventMessage string
+	OccurredAt   time.Time
+
+	State          OutboundWebhookJobState
+	FailureMessage *string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	ProcessAfter   time.Time
+	NumResets      int64
+	NumFailures    int64
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecordID implements the workerutil.Record interface.
+func (j *OutboundWebhookJob) RecordID() int {
+	return int(j.ID)
+}
@@ -77,6 +77,21 @@ func (s *authzStore) GrantPendingPermissions(ctx context.Context, args *database
 			})
 		}
 
+		// Email addresses that the user no longer has, but that are attributed to them.
+		attributions, err := database.GlobalUserEmails.ListAttributionsByUser(ctx, args.UserID)
+		if err != nil {
+			return errors.Wrap(err, "list email attributions")
+		}
+		for i := range attributions {
+			perms = append(perms, &authz.UserPendingPermissions{
+				ServiceType: authz.SourcegraphServiceType,
+				ServiceID:   authz.SourcegraphServiceID,
+				BindID:      attributions[i].Email,
+				Perm:        args.Perm,
+				Type:        args.Type,
+			})
+		}
+
 	case "username":
 		user, err := database.GlobalUsers.GetByID(ctx, args.UserID)
 		if err != nil {
@@ -0,0 +1,188 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// NewEvaluator returns a background goroutine which will periodically evaluate the alerts defined
+// on insight series against the data points recorded since they were last evaluated, and notify
+// the users that defined them when their condition starts to hold.
+func NewEvaluator(ctx context.Context, alertStore store.SeriesAlertStore, insightsStore store.Interface, observationContext *observation.Context) goroutine.BackgroundRoutine {
+	metrics := metrics.NewOperationMetrics(
+		observationContext.Registerer,
+		"insights_alert_evaluator",
+		metrics.WithCountHelp("Total number of insights alert evaluator executions"),
+	)
+	operation := observationContext.Operation(observation.Op{
+		Name:    "Alerts.Evaluator.Run",
+		Metrics: metrics,
+	})
+
+	e := &evaluator{
+		alertStore:    alertStore,
+		insightsStore: insightsStore,
+		notify:        notify,
+	}
+	return goroutine.NewPeriodicGoroutineWithMetrics(ctx, 5*time.Minute, goroutine.NewHandlerWithErrorMessage(
+		"insights_alert_evaluator",
+		e.Handler,
+	), operation)
+}
+
+// Notification describes an alert whose condition started to hold for a data point of its series.
+type Notification struct {
+	Alert types.InsightSeriesAlert
+	Time  time.Time
+	Value float64
+	// Previous is the value of the data point before it, if there is one.
+	Previous *float64
+}
+
+// Message returns a human readable description of the notification.
+func (n Notification) Message() string {
+	label := n.Alert.Label
+	if label == "" {
+		label = n.Alert.SeriesID
+	}
+	at := n.Time.UTC().Format("2006-01-02 15:04 MST")
+	switch n.Alert.Condition {
+	case types.AlertConditionIncrease, types.AlertConditionDecrease:
+		var previous float64
+		if n.Previous != nil {
+			previous = *n.Previous
+		}
+		change := "an increase"
+		if n.Alert.Condition == types.AlertConditionDecrease {
+			change = "a decrease"
+		}
+		return fmt.Sprintf("Code insights alert %q: the value changed from %v to %v at %s, %s of at least %v%%.", label, previous, n.Value, at, change, n.Alert.Threshold)
+	default:
+		return fmt.Sprintf("Code insights alert %q: the value %v at %s is %s the threshold of %v.", label, n.Value, at, n.Alert.Condition, n.Alert.Threshold)
+	}
+}
+
+type evaluator struct {
+	// Required fields used for mocking in tests.
+	alertStore    store.SeriesAlertStore
+	insightsStore store.Interface
+	notify        func(ctx context.Context, n Notification) error
+}
+
+func (e *evaluator) Handler(ctx context.Context) error {
+	alerts, err := e.alertStore.GetAlerts(ctx, store.GetAlertsArgs{})
+	if err != nil {
+		return errors.Wrap(err, "GetAlerts")
+	}
+
+	var errs error
+	for _, alert := range alerts {
+		if err := e.evaluate(ctx, alert); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "alert %d", alert.ID))
+		}
+	}
+	return errs
+}
+
+// evaluate evaluates the given alert against the data points of its series that are newer than the
+// last point it was evaluated for. An alert that was never evaluated is only evaluated against the
+// latest data point, so creating an alert does not notify about the history of the series.
+func (e *evaluator) evaluate(ctx context.Context, alert types.InsightSeriesAlert) error {
+	// 🚨 SECURITY: The data points are read as the user that defined the alert, so that the
+	// notifications only include the values of the repositories that user can see. 🚨
+	ctx = actor.WithActor(ctx, actor.FromUser(alert.UserID))
+
+	points, err := e.insightsStore.SeriesRollups(ctx, store.SeriesRollupsOpts{
+		SeriesID: alert.SeriesID,
+		// The last evaluated point is the previous point of the first new one.
+		From: alert.LastEvaluatedAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "SeriesRollups")
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	first := 0
+	if alert.LastEvaluatedAt == nil {
+		first = len(points) - 1
+	} else {
+		for first < len(points) && !points[first].Time.After(*alert.LastEvaluatedAt) {
+			first++
+		}
+	}
+	if first < 0 || first >= len(points) {
+		return nil // no new data points.
+	}
+
+	triggered := alert.Triggered
+	var fired *Notification
+	for i := first; i < len(points); i++ {
+		var previous *float64
+		if i > 0 {
+			previous = &points[i-1].Value
+		}
+		holds := conditionHolds(alert.Condition, alert.Threshold, points[i].Value, previous)
+		if holds && !triggered {
+			fired = &Notification{Alert: alert, Time: points[i].Time, Value: points[i].Value, Previous: previous}
+		}
+		triggered = holds
+	}
+
+	var notifyErr error
+	if fired != nil {
+		if notifyErr = e.notify(ctx, *fired); notifyErr != nil {
+			// The state is updated regardless, so that notifications that were delivered are not
+			// sent again.
+			log15.Error("insights: failed to send alert notification", "alert_id", alert.ID, "error", notifyErr)
+		}
+	}
+	if err := e.alertStore.UpdateAlertState(ctx, alert.ID, triggered, points[len(points)-1].Time); err != nil {
+		return errors.Wrap(err, "UpdateAlertState")
+	}
+	return notifyErr
+}
+
+// conditionHolds reports whether the given condition holds for a data point with the given value,
+// and the given previous value, if any. Trend conditions never hold for the first data point.
+func conditionHolds(condition string, threshold, value float64, previous *float64) bool {
+	switch condition {
+	case types.AlertConditionAbove:
+		return value > threshold
+	case types.AlertConditionBelow:
+		return value < threshold
+	case types.AlertConditionIncrease:
+		return previous != nil && changePercent(*previous, value) >= threshold
+	case types.AlertConditionDecrease:
+		return previous != nil && -changePercent(*previous, value) >= threshold
+	}
+	return false
+}
+
+// changePercent returns the change in percent from previous to value. Any change from zero is
+// infinite.
+func changePercent(previous, value float64) float64 {
+	if previous == 0 {
+		switch {
+		case value > 0:
+			return math.Inf(1)
+		case value < 0:
+			return math.Inf(-1)
+		}
+		return 0
+	}
+	return (value - previous) / math.Abs(previous) * 100
+}
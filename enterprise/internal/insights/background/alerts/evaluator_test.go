@@ -0,0 +1,132 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hexops/autogold"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+// fakeAlertStore is an in-memory store.SeriesAlertStore.
+type fakeAlertStore struct {
+	store.SeriesAlertStore
+	alerts []types.InsightSeriesAlert
+}
+
+func (s *fakeAlertStore) GetAlerts(ctx context.Context, args store.GetAlertsArgs) ([]types.InsightSeriesAlert, error) {
+	return append([]types.InsightSeriesAlert(nil), s.alerts...), nil
+}
+
+func (s *fakeAlertStore) UpdateAlertState(ctx context.Context, id int, triggered bool, evaluatedAt time.Time) error {
+	for i := range s.alerts {
+		if s.alerts[i].ID == id {
+			s.alerts[i].Triggered = triggered
+			s.alerts[i].LastEvaluatedAt = &evaluatedAt
+		}
+	}
+	return nil
+}
+
+func TestConditionHolds(t *testing.T) {
+	ten, zero := 10.0, 0.0
+	for _, tc := range []struct {
+		condition string
+		threshold float64
+		value     float64
+		previous  *float64
+		want      bool
+	}{
+		{types.AlertConditionAbove, 10, 11, nil, true},
+		{types.AlertConditionAbove, 10, 10, nil, false},
+		{types.AlertConditionBelow, 10, 9, nil, true},
+		{types.AlertConditionBelow, 10, 10, nil, false},
+		{types.AlertConditionIncrease, 50, 15, &ten, true},
+		{types.AlertConditionIncrease, 50, 14, &ten, false},
+		{types.AlertConditionIncrease, 50, 15, nil, false},
+		{types.AlertConditionIncrease, 50, 1, &zero, true},
+		{types.AlertConditionDecrease, 50, 5, &ten, true},
+		{types.AlertConditionDecrease, 50, 6, &ten, false},
+		{"unknown", 0, 1, nil, false},
+	} {
+		if got := conditionHolds(tc.condition, tc.threshold, tc.value, tc.previous); got != tc.want {
+			t.Errorf("conditionHolds(%q, %v, %v, %v) = %v, want %v", tc.condition, tc.threshold, tc.value, tc.previous, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	var values []float64
+	insightsStore := store.NewMockInterface()
+	insightsStore.SeriesRollupsFunc.SetDefaultHook(func(ctx context.Context, opts store.SeriesRollupsOpts) ([]store.SeriesPoint, error) {
+		if a := actor.FromContext(ctx); a.UID != 42 {
+			t.Errorf("unexpected actor %v", a)
+		}
+		var points []store.SeriesPoint
+		for i, v := range values {
+			if opts.From != nil && day(i+1).Before(*opts.From) {
+				continue
+			}
+			points = append([]store.SeriesPoint{{SeriesID: opts.SeriesID, Time: day(i + 1), Value: v}}, points...)
+		}
+		return points, nil
+	})
+
+	alertStore := &fakeAlertStore{alerts: []types.InsightSeriesAlert{
+		{ID: 1, SeriesID: "series1", UserID: 42, Label: "threshold", Condition: types.AlertConditionAbove, Threshold: 10},
+		{ID: 2, SeriesID: "series1", UserID: 42, Label: "trend", Condition: types.AlertConditionIncrease, Threshold: 100},
+	}}
+
+	var notifications []string
+	e := &evaluator{
+		alertStore:    alertStore,
+		insightsStore: insightsStore,
+		notify: func(ctx context.Context, n Notification) error {
+			notifications = append(notifications, n.Message())
+			return nil
+		},
+	}
+	evaluate := func(t *testing.T, newValues ...float64) []string {
+		t.Helper()
+		values = append(values, newValues...)
+		notifications = nil
+		if err := e.Handler(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return notifications
+	}
+
+	t.Run("first evaluation only considers the latest point", func(t *testing.T) {
+		got := evaluate(t, 20, 10, 11)
+		autogold.Want("first", []string{`Code insights alert "threshold": the value 11 at 2021-01-03 00:00 UTC is above the threshold of 10.`}).Equal(t, got)
+	})
+
+	t.Run("no new points", func(t *testing.T) {
+		autogold.Want("no new points", []string(nil)).Equal(t, evaluate(t))
+	})
+
+	t.Run("still triggered", func(t *testing.T) {
+		autogold.Want("still triggered", []string(nil)).Equal(t, evaluate(t, 12))
+	})
+
+	t.Run("triggered again", func(t *testing.T) {
+		got := evaluate(t, 4, 5)
+		autogold.Want("reset", []string(nil)).Equal(t, got)
+		got = evaluate(t, 30)
+		autogold.Want("triggered again", []string{
+			`Code insights alert "threshold": the value 30 at 2021-01-07 00:00 UTC is above the threshold of 10.`,
+			`Code insights alert "trend": the value changed from 5 to 30 at 2021-01-07 00:00 UTC, an increase of at least 100%.`,
+		}).Equal(t, got)
+	})
+
+	if a := alertStore.alerts[0]; !a.Triggered || !a.LastEvaluatedAt.Equal(day(7)) {
+		t.Errorf("unexpected alert state: %+v", a)
+	}
+}
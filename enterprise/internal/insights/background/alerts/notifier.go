@@ -0,0 +1,174 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/slack"
+	"github.com/sourcegraph/sourcegraph/internal/txemail"
+	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+)
+
+// notify sends the given notification on all the channels configured on its alert: an email to the
+// user that defined it, a JSON payload posted to its webhook and a message posted to its Slack
+// webhook.
+func notify(ctx context.Context, n Notification) error {
+	insightsURL, err := getInsightsURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	if n.Alert.NotifyEmail {
+		if err := sendEmail(ctx, n, insightsURL); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if n.Alert.WebhookURL != "" {
+		if err := postWebhook(ctx, n, insightsURL); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if n.Alert.SlackWebhookURL != "" {
+		if err := slack.New(n.Alert.SlackWebhookURL).Post(ctx, &slack.Payload{
+			Text: n.Message() + " <" + insightsURL + "|View insights>",
+		}); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+func getInsightsURL(ctx context.Context) (string, error) {
+	externalURLStr, err := api.InternalClient.ExternalURL(ctx)
+	if err != nil {
+		return "", errors.Errorf("failed to get ExternalURL: %w", err)
+	}
+	externalURL, err := url.Parse(externalURLStr)
+	if err != nil {
+		return "", errors.Errorf("failed to parse ExternalURL: %w", err)
+	}
+	return externalURL.ResolveReference(&url.URL{Path: "insights"}).String(), nil
+}
+
+var alertEmailTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `[Code insights alert] {{.Label}}`,
+	Text: `
+{{.Message}}
+
+View insights on Sourcegraph: {{.InsightsURL}}
+
+__
+You are receiving this notification because you defined an alert on a code insight.
+`,
+	HTML: `
+<!DOCTYPE html>
+<html>
+  <body>
+    <p style="font-size: 16px; line-height: 24px">
+      {{.Message}}
+    </p>
+    <p style="font-size: 16px; line-height: 24px">
+      <a href="{{.InsightsURL}}">View insights on Sourcegraph</a>
+    </p>
+    <br />
+    __
+    <p style="font-size: 14px; line-height: 24px">
+      You are receiving this notification because you defined an alert on a code insight.
+    </p>
+  </body>
+</html>
+`,
+})
+
+func sendEmail(ctx context.Context, n Notification, insightsURL string) error {
+	email, err := api.InternalClient.UserEmailsGetEmail(ctx, n.Alert.UserID)
+	if err != nil {
+		return errors.Errorf("InternalClient.UserEmailsGetEmail for userID=%d: %w", n.Alert.UserID, err)
+	}
+	if email == nil {
+		return errors.Errorf("unable to send email to user ID %d with unknown email address", n.Alert.UserID)
+	}
+
+	label := n.Alert.Label
+	if label == "" {
+		label = n.Alert.SeriesID
+	}
+	if err := api.InternalClient.SendEmail(ctx, txtypes.Message{
+		To:       []string{*email},
+		Template: alertEmailTemplates,
+		Data: struct {
+			Label       string
+			Message     string
+			InsightsURL string
+		}{
+			Label:       label,
+			Message:     n.Message(),
+			InsightsURL: insightsURL,
+		},
+	}); err != nil {
+		return errors.Errorf("InternalClient.SendEmail to email=%q userID=%d: %w", *email, n.Alert.UserID, err)
+	}
+	return nil
+}
+
+// webhookPayload is the JSON payload posted to the webhook of an alert.
+type webhookPayload struct {
+	AlertID       int      `json:"alertId"`
+	SeriesID      string   `json:"seriesId"`
+	Label         string   `json:"label"`
+	Condition     string   `json:"condition"`
+	Threshold     float64  `json:"threshold"`
+	Time          string   `json:"time"`
+	Value         float64  `json:"value"`
+	PreviousValue *float64 `json:"previousValue,omitempty"`
+	Message       string   `json:"message"`
+	URL           string   `json:"url"`
+}
+
+func postWebhook(ctx context.Context, n Notification, insightsURL string) error {
+	payload, err := json.Marshal(webhookPayload{
+		AlertID:       n.Alert.ID,
+		SeriesID:      n.Alert.SeriesID,
+		Label:         n.Alert.Label,
+		Condition:     n.Alert.Condition,
+		Threshold:     n.Alert.Threshold,
+		Time:          n.Time.UTC().Format(time.RFC3339),
+		Value:         n.Value,
+		PreviousValue: n.Previous,
+		Message:       n.Message(),
+		URL:           insightsURL,
+	})
+	if err != nil {
+		return errors.Wrap(err, "webhook: marshal json")
+	}
+	req, err := http.NewRequest("POST", n.Alert.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "webhook: create post request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	resp, err := httpcli.ExternalDoer.Do(req.WithContext(timeoutCtx))
+	if err != nil {
+		return errors.Wrap(err, "webhook: http request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("webhook: failed with %d %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
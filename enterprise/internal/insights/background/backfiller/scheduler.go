@@ -0,0 +1,136 @@
+package backfiller
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	itypes "github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// RepoStore is a subset of the API exposed by the database.Repos() store (only the subset used by
+// the scheduler.)
+type RepoStore interface {
+	GetByName(ctx context.Context, name api.RepoName) (*itypes.Repo, error)
+}
+
+// NewScheduler returns a background goroutine which will periodically find the insight series
+// that have not been backfilled yet, and enqueue one backfiller job per series and repository.
+// Series scoped to a team only get jobs for the repositories the team owns. It does nothing unless
+// the backfiller is enabled with the site setting insights.backfiller.
+func NewScheduler(ctx context.Context, workerBaseStore *basestore.Store, dataSeriesStore store.DataSeriesStore, teamStore store.TeamStore, allReposIterator func(ctx context.Context, each func(repoName string) error) error, observationContext *observation.Context) goroutine.BackgroundRoutine {
+	metrics := metrics.NewOperationMetrics(
+		observationContext.Registerer,
+		"insights_backfiller_scheduler",
+		metrics.WithCountHelp("Total number of insights backfiller scheduler executions"),
+	)
+	operation := observationContext.Operation(observation.Op{
+		Name:    "Backfiller.Scheduler.Run",
+		Metrics: metrics,
+	})
+
+	s := &scheduler{
+		dataSeriesStore:  dataSeriesStore,
+		teamStore:        teamStore,
+		repoStore:        database.Repos(workerBaseStore.Handle().DB()),
+		allReposIterator: allReposIterator,
+		enabled:          Enabled,
+		enqueueJob: func(ctx context.Context, job *Job) error {
+			return EnqueueJob(ctx, workerBaseStore, job)
+		},
+	}
+	return goroutine.NewPeriodicGoroutineWithMetrics(ctx, 5*time.Minute, goroutine.NewHandlerWithErrorMessage(
+		"insights_backfiller_scheduler",
+		s.Handler,
+	), operation)
+}
+
+type scheduler struct {
+	// Required fields used for mocking in tests.
+	dataSeriesStore  store.DataSeriesStore
+	teamStore        store.TeamStore
+	repoStore        RepoStore
+	allReposIterator func(ctx context.Context, each func(repoName string) error) error
+	enabled          func() bool
+	enqueueJob       func(ctx context.Context, job *Job) error
+}
+
+func (s *scheduler) Handler(ctx context.Context) error {
+	if !s.enabled() {
+		return nil
+	}
+
+	series, err := s.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{BackfillIncomplete: true, ExcludePaused: true})
+	if err != nil {
+		return errors.Wrap(err, "GetDataSeries")
+	}
+	if len(series) == 0 {
+		return nil
+	}
+
+	// The repositories of teams are looked up once per run, so that changes of ownership apply to
+	// the next run.
+	teams := store.NewTeamRepositories(s.teamStore)
+	err = s.allReposIterator(ctx, func(repoName string) error {
+		repo, err := s.repoStore.GetByName(ctx, api.RepoName(repoName))
+		if err != nil {
+			if errors.HasType(err, &database.RepoNotFoundErr{}) {
+				return nil // the repository was deleted since it was listed.
+			}
+			return err
+		}
+		for _, series := range series {
+			if series.GenerationMethod == types.GenerationMethodLanguageStats {
+				// Language statistics are computed from the contents of the repositories by the
+				// queryrunner, which only records them going forward.
+				continue
+			}
+			if series.Team != "" {
+				owns, err := teams.Owns(ctx, series.Team, repoName)
+				if err != nil {
+					return errors.Wrap(err, "GetTeamRepositories")
+				}
+				if !owns {
+					continue
+				}
+			}
+			if err := s.enqueueJob(ctx, &Job{
+				SeriesID: series.SeriesID,
+				RepoID:   repo.ID,
+				RepoName: string(repo.Name),
+				State:    "queued",
+			}); err != nil {
+				return errors.Wrap(err, "EnqueueJob")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Every repository has a job for the series now, so they won't be scheduled again.
+	s.markScheduled(ctx, series)
+	return nil
+}
+
+func (s *scheduler) markScheduled(ctx context.Context, scheduled []types.InsightSeries) {
+	for _, series := range scheduled {
+		if _, err := s.dataSeriesStore.StampBackfill(ctx, series); err != nil {
+			// The jobs are only enqueued once, so the series is scheduled again next time.
+			log15.Error("insights: failed to mark series backfill scheduled", "series_id", series.SeriesID, "error", err)
+			continue
+		}
+		log15.Info("insights: backfill scheduled", "series_id", series.SeriesID)
+	}
+}
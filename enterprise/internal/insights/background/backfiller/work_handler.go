@@ -0,0 +1,191 @@
+package backfiller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/compression"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/insights/priority"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git/gitapi"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+)
+
+var _ workerutil.Handler = &workHandler{}
+
+// workHandler implements the dbworker.Handler interface by sampling the history of a repository
+// for the backfill of a series: for every sampled point in time it finds the most recent commit
+// of the repository, and enqueues a query runner job to search the repository at that commit.
+// Sampled points that share a commit are searched once, and sampled points before the first
+// commit of the repository are recorded as zero without searching.
+type workHandler struct {
+	// Required fields used for mocking in tests.
+	insightsStore         store.Interface
+	dataSeriesStore       store.DataSeriesStore
+	enqueueQueryRunnerJob func(ctx context.Context, job *queryrunner.Job) error
+	gitFirstEverCommit    func(ctx context.Context, repoName api.RepoName) (*gitapi.Commit, error)
+	gitFindRecentCommit   func(ctx context.Context, repoName api.RepoName, revision string, target time.Time) ([]*gitapi.Commit, error)
+	updateProgress        func(ctx context.Context, jobID, framesTotal, framesDone int) error
+
+	// limiter limits the rate of gitserver requests.
+	limiter *rate.Limiter
+
+	// frames is the number of points in time to sample, frameLength the duration between them.
+	frames      func() int
+	frameLength func() time.Duration
+}
+
+func (h *workHandler) Handle(ctx context.Context, record workerutil.Record) (err error) {
+	defer func() {
+		if err != nil {
+			log15.Error("insights.backfiller.workHandler", "error", err)
+		}
+	}()
+
+	job := record.(*Job)
+	series, err := h.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{SeriesID: job.SeriesID})
+	if err != nil {
+		return errors.Wrap(err, "GetDataSeries")
+	}
+	if len(series) == 0 {
+		return nil // the series was deleted, nothing to backfill.
+	}
+
+	executions, err := h.sample(ctx, job, series[0])
+	if err != nil {
+		return err
+	}
+
+	var total int
+	for _, execution := range executions {
+		total += execution.RecordCount()
+	}
+	if err := h.updateProgress(ctx, job.ID, total, job.FramesDone); err != nil {
+		return err
+	}
+
+	var recorded int
+	for _, execution := range executions {
+		recorded += execution.RecordCount()
+		if recorded <= job.FramesDone {
+			continue // done by a previous attempt of the job.
+		}
+		if err := h.backfill(ctx, job, series[0], execution); err != nil {
+			return err
+		}
+		if err := h.updateProgress(ctx, job.ID, total, recorded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sample returns the query executions needed to backfill the series in the repository of the job,
+// oldest first, walking the history of the branch or revision the series pins in the repository.
+// Executions without a revision are before the first commit of the repository.
+func (h *workHandler) sample(ctx context.Context, job *Job, series types.InsightSeries) ([]*compression.QueryExecution, error) {
+	// TODO(insights): the repo: filter of the series would need to be combined with ours, like
+	// the historical enqueuer we don't support those queries.
+	if strings.Contains(series.Query, "repo:") {
+		return nil, nil
+	}
+
+	repoName := api.RepoName(job.RepoName)
+	if err := h.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	firstCommit, err := h.gitFirstEverCommit(ctx, repoName)
+	if err != nil {
+		if errors.HasType(err, &gitserver.RevisionNotFoundError{}) || vcs.IsRepoNotExist(err) {
+			// The repository may not be cloned yet, in which case retrying the job later helps.
+			return nil, errors.Wrap(err, "repository not available yet")
+		}
+		if strings.Contains(err.Error(), `failed (output: "usage: git rev-list [OPTION] <commit-id>...`) {
+			return nil, nil // repository is empty
+		}
+		return nil, errors.Wrap(err, "FirstEverCommit")
+	}
+
+	var (
+		executions []*compression.QueryExecution
+		prev       *compression.QueryExecution
+	)
+	for _, t := range sampleTimes(series.CreatedAt, h.frames(), h.frameLength()) {
+		if t.Before(firstCommit.Author.Date) {
+			executions = append(executions, &compression.QueryExecution{RecordingTime: t})
+			continue
+		}
+
+		if err := h.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		commits, err := h.gitFindRecentCommit(ctx, repoName, series.RevisionFor(job.RepoName), t)
+		if err != nil {
+			return nil, errors.Wrap(err, "FindNearestCommit")
+		}
+		if len(commits) == 0 || commits[0].Committer == nil {
+			continue
+		}
+		revision := string(commits[0].ID)
+
+		// The repository had no commits since the previous sample, so the result of its search
+		// is shared.
+		if prev != nil && prev.Revision == revision {
+			prev.SharedRecordings = append(prev.SharedRecordings, t)
+			continue
+		}
+		prev = &compression.QueryExecution{Revision: revision, RecordingTime: t}
+		executions = append(executions, prev)
+	}
+	return executions, nil
+}
+
+// backfill records or enqueues the search of the given execution.
+func (h *workHandler) backfill(ctx context.Context, job *Job, series types.InsightSeries, execution *compression.QueryExecution) error {
+	if execution.Revision == "" {
+		// The repository had no commits yet, so there are no results. Series of captured values
+		// have no value to record a zero for.
+		if series.GenerationMethod == types.GenerationMethodSearchCompute {
+			return nil
+		}
+		args := execution.ToRecording(series.SeriesID, job.RepoName, job.RepoID, 0)
+		return errors.Wrap(h.insightsStore.RecordSeriesPoints(ctx, args), "RecordSeriesPoints zero value")
+	}
+
+	query := historicalQuery(series.Query, job.RepoName, execution.Revision)
+	queueJob := execution.ToQueueJob(series.SeriesID, query, priority.Unindexed, priority.FromTimeInterval(execution.RecordingTime, series.CreatedAt))
+	queueJob.PatternType = series.PatternType
+	return errors.Wrap(h.enqueueQueryRunnerJob(ctx, queueJob), "enqueueing query runner job")
+}
+
+// sampleTimes returns n points in time, oldest first, that are length apart and end length
+// before the day of end.
+func sampleTimes(end time.Time, n int, length time.Duration) []time.Time {
+	end = end.UTC().Truncate(24 * time.Hour)
+	times := make([]time.Time, 0, n)
+	for i := n; i > 0; i-- {
+		times = append(times, end.Add(-time.Duration(i)*length))
+	}
+	return times
+}
+
+// historicalQuery returns the query that searches the given repository at the given revision,
+// counting all results like the queries of the insight enqueuer.
+func historicalQuery(query, repoName, revision string) string {
+	if !strings.Contains(query, "count:") {
+		query += " count:all"
+	}
+	return fmt.Sprintf("%s repo:^%s$@%s", query, regexp.QuoteMeta(repoName), revision)
+}
@@ -0,0 +1,109 @@
+package backfiller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hexops/autogold"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git/gitapi"
+)
+
+func TestSampleTimes(t *testing.T) {
+	end := time.Date(2021, 1, 1, 13, 30, 0, 0, time.UTC)
+	var got []string
+	for _, t := range sampleTimes(end, 3, 7*24*time.Hour) {
+		got = append(got, t.Format(time.RFC3339))
+	}
+	autogold.Want("samples", []string{
+		"2020-12-11T00:00:00Z",
+		"2020-12-18T00:00:00Z",
+		"2020-12-25T00:00:00Z",
+	}).Equal(t, got)
+}
+
+func TestHistoricalQuery(t *testing.T) {
+	autogold.Want("without count", "errorf count:all repo:^github\\.com/gorilla/mux$@abc").Equal(t, historicalQuery("errorf", "github.com/gorilla/mux", "abc"))
+	autogold.Want("with count", "errorf count:10 repo:^github\\.com/gorilla/mux$@abc").Equal(t, historicalQuery("errorf count:10", "github.com/gorilla/mux", "abc"))
+}
+
+func TestWorkHandler(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2020, 12, d, 0, 0, 0, 0, time.UTC) }
+
+	dataSeriesStore := store.NewMockDataSeriesStore()
+	dataSeriesStore.GetDataSeriesFunc.SetDefaultReturn([]types.InsightSeries{
+		{SeriesID: "series1", Query: "errorf", CreatedAt: createdAt},
+	}, nil)
+
+	var operations []string
+	insightsStore := store.NewMockInterface()
+	insightsStore.RecordSeriesPointsFunc.SetDefaultHook(func(ctx context.Context, points []store.RecordSeriesPointArgs) error {
+		for _, p := range points {
+			operations = append(operations, fmt.Sprintf("recordSeriesPoint(point=%v, repoName=%v)", p.Point.String(), *p.RepoName))
+		}
+		return nil
+	})
+
+	h := &workHandler{
+		insightsStore:   insightsStore,
+		dataSeriesStore: dataSeriesStore,
+		enqueueQueryRunnerJob: func(ctx context.Context, job *queryrunner.Job) error {
+			operations = append(operations, fmt.Sprintf("enqueueQueryRunnerJob(recordTime=%s, dependentFrames=%d, query=%q)", job.RecordTime.Format(time.RFC3339), len(job.DependentFrames), job.SearchQuery))
+			return nil
+		},
+		gitFirstEverCommit: func(ctx context.Context, repoName api.RepoName) (*gitapi.Commit, error) {
+			return &gitapi.Commit{Author: gitapi.Signature{Date: day(10)}}, nil
+		},
+		gitFindRecentCommit: func(ctx context.Context, repoName api.RepoName, revision string, target time.Time) ([]*gitapi.Commit, error) {
+			id := "abc"
+			if target.After(day(20)) {
+				id = "def"
+			}
+			return []*gitapi.Commit{{ID: api.CommitID(id), Committer: &gitapi.Signature{}}}, nil
+		},
+		updateProgress: func(ctx context.Context, jobID, framesTotal, framesDone int) error {
+			operations = append(operations, fmt.Sprintf("updateProgress(total=%d, done=%d)", framesTotal, framesDone))
+			return nil
+		},
+		limiter:     rate.NewLimiter(rate.Inf, 1),
+		frames:      func() int { return 4 },
+		frameLength: func() time.Duration { return 7 * 24 * time.Hour },
+	}
+
+	t.Run("new job", func(t *testing.T) {
+		operations = nil
+		if err := h.Handle(ctx, &Job{ID: 1, SeriesID: "series1", RepoID: 1, RepoName: "github.com/gorilla/mux"}); err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("new job", []string{
+			"updateProgress(total=4, done=0)",
+			"recordSeriesPoint(point=SeriesPoint{Time: \"2020-12-04 00:00:00 +0000 UTC\", Value: 0, Metadata: }, repoName=github.com/gorilla/mux)",
+			"updateProgress(total=4, done=1)",
+			`enqueueQueryRunnerJob(recordTime=2020-12-11T00:00:00Z, dependentFrames=1, query="errorf count:all repo:^github\\.com/gorilla/mux$@abc")`,
+			"updateProgress(total=4, done=3)",
+			`enqueueQueryRunnerJob(recordTime=2020-12-25T00:00:00Z, dependentFrames=0, query="errorf count:all repo:^github\\.com/gorilla/mux$@def")`,
+			"updateProgress(total=4, done=4)",
+		}).Equal(t, operations)
+	})
+
+	t.Run("retried job", func(t *testing.T) {
+		operations = nil
+		if err := h.Handle(ctx, &Job{ID: 1, SeriesID: "series1", RepoID: 1, RepoName: "github.com/gorilla/mux", FramesDone: 3}); err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("retried job", []string{
+			"updateProgress(total=4, done=3)",
+			`enqueueQueryRunnerJob(recordTime=2020-12-25T00:00:00Z, dependentFrames=0, query="errorf count:all repo:^github\\.com/gorilla/mux$@def")`,
+			"updateProgress(total=4, done=4)",
+		}).Equal(t, operations)
+	})
+}
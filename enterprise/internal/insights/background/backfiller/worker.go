@@ -0,0 +1,320 @@
+package backfiller
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
+	"github.com/xhit/go-str2duration/v2"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git/gitapi"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+// This file contains all the methods required to:
+//
+// 1. Create the backfiller worker
+// 2. Enqueue jobs for the backfiller to execute.
+// 3. Serialize jobs for the backfiller into the DB.
+// 4. Report the progress of the backfill of a series.
+//
+
+// NewWorker returns a worker that samples the history of repositories for the backfill of new
+// insight series, and enqueues query runner jobs to search the repositories at the sampled
+// commits. The requests made to gitserver are rate limited by the site setting
+// insights.backfiller.gitserverRateLimit.
+func NewWorker(ctx context.Context, workerStore dbworkerstore.Store, insightsStore *store.Store, metrics workerutil.WorkerMetrics, observationContext *observation.Context) *workerutil.Worker {
+	options := workerutil.WorkerOptions{
+		Name:              "insights_backfiller_worker",
+		NumHandlers:       1,
+		Interval:          5 * time.Second,
+		HeartbeatInterval: 15 * time.Second,
+		Metrics:           metrics,
+	}
+
+	limiter := rate.NewLimiter(gitserverRateLimit(), 1)
+	go conf.Watch(func() {
+		val := gitserverRateLimit()
+		log15.Info(fmt.Sprintf("Updating insights/backfiller gitserver rate limit value=%v", val))
+		limiter.SetLimit(val)
+	})
+
+	workerBaseStore := basestore.NewWithDB(workerStore.Handle().DB(), sql.TxOptions{})
+	enqueuer := queryrunner.NewEnqueuer(workerBaseStore, observationContext)
+
+	return dbworker.NewWorker(ctx, workerStore, &workHandler{
+		insightsStore:   insightsStore,
+		dataSeriesStore: store.NewInsightStore(insightsStore.Handle().DB()),
+		limiter:         limiter,
+		enqueueQueryRunnerJob: func(ctx context.Context, job *queryrunner.Job) error {
+			_, err := enqueuer.Enqueue(ctx, job)
+			return err
+		},
+		gitFirstEverCommit: git.FirstEverCommit,
+		gitFindRecentCommit: func(ctx context.Context, repoName api.RepoName, revision string, target time.Time) ([]*gitapi.Commit, error) {
+			return git.Commits(ctx, repoName, git.CommitsOptions{Range: revision, N: 1, Before: target.Format(time.RFC3339), DateOrder: true})
+		},
+		updateProgress: func(ctx context.Context, jobID, framesTotal, framesDone int) error {
+			return updateProgress(ctx, workerBaseStore, jobID, framesTotal, framesDone)
+		},
+		frames:      frames,
+		frameLength: frameLength,
+	}, options)
+}
+
+// NewResetter returns a resetter that will reset pending backfiller jobs if they take too long to
+// complete.
+func NewResetter(ctx context.Context, workerStore dbworkerstore.Store, metrics dbworker.ResetterMetrics) *dbworker.Resetter {
+	options := dbworker.ResetterOptions{
+		Name:     "insights_backfiller_worker_resetter",
+		Interval: 1 * time.Minute,
+		Metrics:  metrics,
+	}
+	return dbworker.NewResetter(workerStore, options)
+}
+
+// CreateDBWorkerStore creates the dbworker store for the backfiller worker.
+//
+// See internal/workerutil/dbworker for more information about dbworkers.
+func CreateDBWorkerStore(s *basestore.Store, observationContext *observation.Context) dbworkerstore.Store {
+	return dbworkerstore.NewWithMetrics(s.Handle(), dbworkerstore.Options{
+		Name:              "insights_backfill_jobs_store",
+		TableName:         "insights_backfill_jobs",
+		ColumnExpressions: jobsColumns,
+		Scan:              scanJobs,
+		StalledMaxAge:     5 * time.Minute,
+		RetryAfter:        30 * time.Minute,
+		MaxNumRetries:     10,
+		MaxNumResets:      10,
+		OrderByExpression: sqlf.Sprintf("id"),
+	}, observationContext)
+}
+
+// Defaults of the insights.backfiller site configuration.
+const (
+	defaultFrames             = 52
+	defaultFrameLength        = 7 * 24 * time.Hour
+	defaultGitserverRateLimit = rate.Limit(5.0)
+)
+
+// Enabled reports whether new insight series are backfilled with the backfiller.
+func Enabled() bool {
+	c := conf.Get().InsightsBackfiller
+	return c != nil && c.Enabled
+}
+
+func frames() int {
+	if c := conf.Get().InsightsBackfiller; c != nil && c.Frames > 0 {
+		return c.Frames
+	}
+	return defaultFrames
+}
+
+func frameLength() time.Duration {
+	c := conf.Get().InsightsBackfiller
+	if c == nil || c.FrameLength == "" {
+		return defaultFrameLength
+	}
+	parsed, err := str2duration.ParseDuration(c.FrameLength)
+	if err != nil || parsed <= 0 {
+		log15.Error("insights: failed to parse site config insights.backfiller.frameLength", "value", c.FrameLength, "error", err)
+		return defaultFrameLength
+	}
+	return parsed
+}
+
+func gitserverRateLimit() rate.Limit {
+	if c := conf.Get().InsightsBackfiller; c != nil && c.GitserverRateLimit != nil {
+		return rate.Limit(*c.GitserverRateLimit)
+	}
+	return defaultGitserverRateLimit
+}
+
+// EnqueueJob enqueues a backfiller job for the given series and repository, unless one was
+// enqueued already.
+func EnqueueJob(ctx context.Context, workerBaseStore *basestore.Store, job *Job) error {
+	return workerBaseStore.Exec(ctx, sqlf.Sprintf(
+		enqueueJobFmtStr,
+		job.SeriesID,
+		job.RepoID,
+		job.RepoName,
+		job.State,
+	))
+}
+
+const enqueueJobFmtStr = `
+-- source: enterprise/internal/insights/background/backfiller/worker.go:EnqueueJob
+INSERT INTO insights_backfill_jobs (
+	series_id,
+	repo_id,
+	repo_name,
+	state
+) VALUES (%s, %s, %s, %s)
+ON CONFLICT (series_id, repo_id) DO NOTHING
+`
+
+// updateProgress records the number of historical data points sampled for the job and the number
+// of them that have been enqueued or recorded.
+func updateProgress(ctx context.Context, workerBaseStore *basestore.Store, jobID, framesTotal, framesDone int) error {
+	return workerBaseStore.Exec(ctx, sqlf.Sprintf(updateProgressFmtStr, framesTotal, framesDone, jobID))
+}
+
+const updateProgressFmtStr = `
+-- source: enterprise/internal/insights/background/backfiller/worker.go:updateProgress
+UPDATE insights_backfill_jobs SET frames_total = %s, frames_done = %s WHERE id = %s
+`
+
+// Progress describes the progress of the backfill of a series.
+type Progress struct {
+	// The number of repositories the backfill covers, and how many of them are done or failed.
+	TotalRepositories, CompletedRepositories, FailedRepositories int
+
+	// The number of historical data points sampled so far, and how many of them have been
+	// enqueued for search or recorded.
+	TotalFrames, CompletedFrames int
+}
+
+// QueryProgress queries the progress of the backfill of the specified series. It returns nil if
+// the series was not backfilled by the backfiller.
+func QueryProgress(ctx context.Context, workerBaseStore *basestore.Store, seriesID string) (_ *Progress, err error) {
+	rows, err := workerBaseStore.Query(ctx, sqlf.Sprintf(queryProgressFmtStr, seriesID))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = basestore.CloseRows(rows, err) }()
+
+	var p Progress
+	for rows.Next() {
+		if err := rows.Scan(&p.TotalRepositories, &p.CompletedRepositories, &p.FailedRepositories, &p.TotalFrames, &p.CompletedFrames); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if p.TotalRepositories == 0 {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+const queryProgressFmtStr = `
+-- source: enterprise/internal/insights/background/backfiller/worker.go:QueryProgress
+SELECT
+	COUNT(*),
+	COUNT(*) FILTER (WHERE state = 'completed'),
+	COUNT(*) FILTER (WHERE state = 'failed'),
+	COALESCE(SUM(frames_total), 0),
+	COALESCE(SUM(frames_done), 0)
+FROM insights_backfill_jobs
+WHERE series_id = %s
+`
+
+// Job represents a single job for the backfiller worker to perform: sampling the history of a
+// repository for a series. When enqueued, it is stored in the insights_backfill_jobs table - then
+// the worker dequeues it by reading it from that table.
+//
+// See internal/workerutil/dbworker for more information about dbworkers.
+type Job struct {
+	// Backfiller fields.
+	SeriesID    string
+	RepoID      api.RepoID
+	RepoName    string
+	FramesTotal int
+	FramesDone  int // Frames that are done are skipped when the job is retried.
+
+	// Standard/required dbworker fields. If enqueuing a job, these may all be zero values except State.
+	ID             int
+	State          string // If enqueing a job, set to "queued"
+	FailureMessage *string
+	StartedAt      *time.Time
+	FinishedAt     *time.Time
+	ProcessAfter   *time.Time
+	NumResets      int32
+	NumFailures    int32
+	ExecutionLogs  []workerutil.ExecutionLogEntry
+}
+
+// Implements the internal/workerutil.Record interface, used by the work handler to locate the job
+// once executing (see work_handler.go:Handle).
+func (j *Job) RecordID() int {
+	return j.ID
+}
+
+func scanJobs(rows *sql.Rows, err error) (workerutil.Record, bool, error) {
+	records, err := doScanJobs(rows, err)
+	if err != nil || len(records) == 0 {
+		return &Job{}, false, err
+	}
+	return records[0], true, nil
+}
+
+func doScanJobs(rows *sql.Rows, err error) (_ []*Job, scanErr error) {
+	if err != nil {
+		return nil, err
+	}
+	defer func() { scanErr = basestore.CloseRows(rows, scanErr) }()
+
+	var jobs []*Job
+	for rows.Next() {
+		j := &Job{}
+		if err := rows.Scan(
+			// Backfiller fields.
+			&j.SeriesID,
+			&j.RepoID,
+			&j.RepoName,
+			&j.FramesTotal,
+			&j.FramesDone,
+
+			// Standard/required dbworker fields.
+			&j.ID,
+			&j.State,
+			&j.FailureMessage,
+			&j.StartedAt,
+			&j.FinishedAt,
+			&j.ProcessAfter,
+			&j.NumResets,
+			&j.NumFailures,
+			pq.Array(&j.ExecutionLogs),
+		); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "scanning backfill jobs")
+	}
+	return jobs, nil
+}
+
+var jobsColumns = []*sqlf.Query{
+	sqlf.Sprintf("insights_backfill_jobs.series_id"),
+	sqlf.Sprintf("insights_backfill_jobs.repo_id"),
+	sqlf.Sprintf("insights_backfill_jobs.repo_name"),
+	sqlf.Sprintf("insights_backfill_jobs.frames_total"),
+	sqlf.Sprintf("insights_backfill_jobs.frames_done"),
+	sqlf.Sprintf("id"),
+	sqlf.Sprintf("state"),
+	sqlf.Sprintf("failure_message"),
+	sqlf.Sprintf("started_at"),
+	sqlf.Sprintf("finished_at"),
+	sqlf.Sprintf("process_after"),
+	sqlf.Sprintf("num_resets"),
+	sqlf.Sprintf("num_failures"),
+	sqlf.Sprintf("execution_logs"),
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/discovery"
 
@@ -14,9 +15,15 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/alerts"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/backfiller"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/retention"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbcache"
 	"github.com/sourcegraph/sourcegraph/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/internal/observation"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
@@ -45,6 +52,8 @@ func GetBackgroundJobs(ctx context.Context, mainAppDB *sql.DB, insightsDB *sql.D
 	insightsMetadataStore := store.NewInsightStore(insightsDB)
 
 	workerStore := queryrunner.CreateDBWorkerStore(workerBaseStore, observationContext)
+	backfillerWorkerMetrics, backfillerResetterMetrics := newWorkerMetrics(observationContext, "insights_backfill_queue")
+	backfillerWorkerStore := backfiller.CreateDBWorkerStore(workerBaseStore, observationContext)
 
 	// Start background goroutines for all of our workers.
 	routines := []goroutine.BackgroundRoutine{
@@ -53,7 +62,7 @@ func GetBackgroundJobs(ctx context.Context, mainAppDB *sql.DB, insightsDB *sql.D
 
 		// Register the query-runner worker and resetter, which executes search queries and records
 		// results to TimescaleDB.
-		queryrunner.NewWorker(ctx, workerStore, insightsStore, queryRunnerWorkerMetrics),
+		queryrunner.NewWorker(ctx, workerStore, insightsStore, queryrunner.InternalAuthenticator{}, queryRunnerWorkerMetrics, observationContext),
 		queryrunner.NewResetter(ctx, workerStore, queryRunnerResetterMetrics),
 		// disabling the cleaner job while we debug mismatched results from historical insights
 		queryrunner.NewCleaner(ctx, workerBaseStore, observationContext),
@@ -68,9 +77,37 @@ func GetBackgroundJobs(ctx context.Context, mainAppDB *sql.DB, insightsDB *sql.D
 	// work to fill them - if not disabled.
 	disableHistorical, _ := strconv.ParseBool(os.Getenv("DISABLE_CODE_INSIGHTS_HISTORICAL"))
 	if !disableHistorical {
-		routines = append(routines, newInsightHistoricalEnqueuer(ctx, workerBaseStore, insightsMetadataStore, insightsStore, observationContext))
+		routines = append(routines, newInsightHistoricalEnqueuer(ctx, workerBaseStore, insightsMetadataStore, insightsMetadataStore, insightsStore, observationContext))
 	}
 
+	// Register the backfiller, which populates the historical data of new series when enabled with
+	// the site setting insights.backfiller (instead of the historical enqueuer).
+	repoStore := database.Repos(mainAppDB)
+	backfillReposIterator := discovery.NewAllReposIterator(
+		dbcache.NewIndexableReposLister(repoStore),
+		repoStore,
+		time.Now,
+		envvar.SourcegraphDotComMode(),
+		15*time.Minute,
+		&prometheus.CounterOpts{
+			Namespace: "src",
+			Name:      "insights_backfiller_repositories_scheduled",
+			Help:      "Counter of the number of repositories for which insights backfiller jobs were scheduled.",
+		})
+	routines = append(routines,
+		backfiller.NewScheduler(ctx, workerBaseStore, insightsMetadataStore, insightsMetadataStore, backfillReposIterator.ForEach, observationContext),
+		backfiller.NewWorker(ctx, backfillerWorkerStore, insightsStore, backfillerWorkerMetrics, observationContext),
+		backfiller.NewResetter(ctx, backfillerWorkerStore, backfillerResetterMetrics),
+	)
+
+	// Register the alert evaluator, which notifies users when the alerts they defined on series
+	// are triggered by newly recorded data points.
+	routines = append(routines, alerts.NewEvaluator(ctx, insightsMetadataStore, insightsStore, observationContext))
+
+	// Register the retention compactor, which downsamples and deletes old data points according to
+	// the site setting insights.retention.
+	routines = append(routines, retention.NewCompactor(ctx, insightsStore, insightsMetadataStore, observationContext))
+
 	routines = append(routines, discovery.NewMigrateSettingInsightsJob(ctx, mainAppDB, insightsDB))
 
 	return routines
@@ -14,9 +14,11 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/inconshreveable/log15"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/xhit/go-str2duration/v2"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/backfiller"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/compression"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/discovery"
@@ -67,7 +69,7 @@ import (
 // insights across all user settings, and determine for which dates they do not have data and attempt
 // to backfill them by enqueueing work for executing searches with `before:` and `after:` filter
 // ranges.
-func newInsightHistoricalEnqueuer(ctx context.Context, workerBaseStore *basestore.Store, dataSeriesStore store.DataSeriesStore, insightsStore *store.Store, observationContext *observation.Context) goroutine.BackgroundRoutine {
+func newInsightHistoricalEnqueuer(ctx context.Context, workerBaseStore *basestore.Store, dataSeriesStore store.DataSeriesStore, teamStore store.TeamStore, insightsStore *store.Store, observationContext *observation.Context) goroutine.BackgroundRoutine {
 	metrics := metrics.NewOperationMetrics(
 		observationContext.Registerer,
 		"insights_historical_enqueuer",
@@ -125,19 +127,22 @@ func newInsightHistoricalEnqueuer(ctx context.Context, workerBaseStore *basestor
 
 	maxTime := time.Now().Add(-time.Duration(framesToBackfill()) * frameLength())
 
+	enqueuer := queryrunner.NewEnqueuer(workerBaseStore, observationContext)
+
 	historicalEnqueuer := &historicalEnqueuer{
 		now:             time.Now,
 		insightsStore:   insightsStore,
 		repoStore:       database.Repos(workerBaseStore.Handle().DB()),
 		dataSeriesStore: dataSeriesStore,
+		teamStore:       teamStore,
 		limiter:         limiter,
 		enqueueQueryRunnerJob: func(ctx context.Context, job *queryrunner.Job) error {
-			_, err := queryrunner.EnqueueJob(ctx, workerBaseStore, job)
+			_, err := enqueuer.Enqueue(ctx, job)
 			return err
 		},
 		gitFirstEverCommit: (&cachedGitFirstEverCommit{impl: git.FirstEverCommit}).gitFirstEverCommit,
-		gitFindRecentCommit: func(ctx context.Context, repoName api.RepoName, target time.Time) ([]*gitapi.Commit, error) {
-			return git.Commits(ctx, repoName, git.CommitsOptions{N: 1, Before: target.Format(time.RFC3339), DateOrder: true})
+		gitFindRecentCommit: func(ctx context.Context, repoName api.RepoName, revision string, target time.Time) ([]*gitapi.Commit, error) {
+			return git.Commits(ctx, repoName, git.CommitsOptions{Range: revision, N: 1, Before: target.Format(time.RFC3339), DateOrder: true})
 		},
 
 		// Fill e.g. the last 52 weeks of data, recording 1 point per week.
@@ -147,6 +152,8 @@ func newInsightHistoricalEnqueuer(ctx context.Context, workerBaseStore *basestor
 		frameFilter: compression.NewHistoricalFilter(true, maxTime, insightsStore.Handle().DB()),
 
 		allReposIterator: iterator.ForEach,
+
+		backfillerEnabled: backfiller.Enabled,
 	}
 
 	// We use a periodic goroutine here just for metrics tracking. We specify 5s here so it runs as
@@ -194,9 +201,11 @@ type RepoStore interface {
 //   * For every repository on Sourcegraph (a subset on Sourcegraph.com):
 //     * Build a list of time frames that we should consider
 //	   * Check the commit index to see if any timeframes can be discarded (if they didn't change)
+//	   * Reuse the result of the previous timeframe if the repository had no commits in between
 //     * For each frame:
 //       * Find the oldest commit in the repository.
 //         * For every unique search insight series (i.e. search query):
+//           * If the series is scoped to a team that doesn't own the repository, skip it.
 //           * Consider yielding/sleeping.
 //           * If the series has data for this timeframe+repo already, nothing to do.
 //           * If the timeframe we're generating data for is before the oldest commit in the repo, record a zero value.
@@ -218,10 +227,11 @@ type historicalEnqueuer struct {
 	now                   func() time.Time
 	insightsStore         store.Interface
 	dataSeriesStore       store.DataSeriesStore
+	teamStore             store.TeamStore
 	repoStore             RepoStore
 	enqueueQueryRunnerJob func(ctx context.Context, job *queryrunner.Job) error
 	gitFirstEverCommit    func(ctx context.Context, repoName api.RepoName) (*gitapi.Commit, error)
-	gitFindRecentCommit   func(ctx context.Context, repoName api.RepoName, target time.Time) ([]*gitapi.Commit, error)
+	gitFindRecentCommit   func(ctx context.Context, repoName api.RepoName, revision string, target time.Time) ([]*gitapi.Commit, error)
 	frameFilter           compression.DataFrameFilter
 
 	// framesToBackfill describes the number of historical timeframes to backfill data for.
@@ -233,11 +243,19 @@ type historicalEnqueuer struct {
 	// The iterator to use for walking over all repositories on Sourcegraph.
 	allReposIterator func(ctx context.Context, each func(repoName string) error) error
 	limiter          *rate.Limiter
+
+	// backfillerEnabled reports whether new series are backfilled by the backfiller instead, in
+	// which case the historical enqueuer does nothing.
+	backfillerEnabled func() bool
 }
 
 func (h *historicalEnqueuer) Handler(ctx context.Context) error {
+	if h.backfillerEnabled() {
+		return nil
+	}
+
 	// Discover all insights on the instance.
-	foundInsights, err := h.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{BackfillIncomplete: true})
+	foundInsights, err := h.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{BackfillIncomplete: true, ExcludePaused: true})
 	if err != nil {
 		return errors.Wrap(err, "Discover")
 	}
@@ -256,10 +274,18 @@ func (h *historicalEnqueuer) Handler(ctx context.Context) error {
 		if _, exists := uniqueSeries[seriesID]; exists {
 			continue
 		}
+		if series.GenerationMethod == itypes.GenerationMethodLanguageStats {
+			// Language statistics are computed from the contents of the repositories by the
+			// queryrunner, which only records them going forward.
+			continue
+		}
 		uniqueSeries[seriesID] = series
 		sortedSeriesIDs = append(sortedSeriesIDs, seriesID)
 	}
-	if err := h.buildFrames(ctx, uniqueSeries, sortedSeriesIDs); err != nil {
+	// The repositories of teams are looked up once per run, so that changes of ownership apply to
+	// the next run.
+	teams := store.NewTeamRepositories(h.teamStore)
+	if err := h.buildFrames(ctx, uniqueSeries, sortedSeriesIDs, teams); err != nil {
 		return multierror.Append(multi, err)
 	}
 	if err == nil {
@@ -289,17 +315,17 @@ func (h *historicalEnqueuer) markInsightsComplete(ctx context.Context, completed
 // It is only called if there is at least one insights series defined.
 //
 // It will return instantly if there are no unique series.
-func (h *historicalEnqueuer) buildFrames(ctx context.Context, uniqueSeries map[string]itypes.InsightSeries, sortedSeriesIDs []string) error {
+func (h *historicalEnqueuer) buildFrames(ctx context.Context, uniqueSeries map[string]itypes.InsightSeries, sortedSeriesIDs []string, teams *store.TeamRepositories) error {
 	if len(uniqueSeries) == 0 {
 		return nil // nothing to do.
 	}
 	var multi error
 
-	hardErr := h.allReposIterator(ctx, h.buildForRepo(ctx, uniqueSeries, sortedSeriesIDs, multi))
+	hardErr := h.allReposIterator(ctx, h.buildForRepo(ctx, uniqueSeries, sortedSeriesIDs, teams, multi))
 	return hardErr
 }
 
-func (h *historicalEnqueuer) buildForRepo(ctx context.Context, uniqueSeries map[string]itypes.InsightSeries, sortedSeriesIDs []string, softErr error) func(repoName string) error {
+func (h *historicalEnqueuer) buildForRepo(ctx context.Context, uniqueSeries map[string]itypes.InsightSeries, sortedSeriesIDs []string, teams *store.TeamRepositories, softErr error) func(repoName string) error {
 	return func(repoName string) error {
 		// Lookup the repository (we need its database ID)
 		repo, err := h.repoStore.GetByName(ctx, api.RepoName(repoName))
@@ -326,14 +352,40 @@ func (h *historicalEnqueuer) buildForRepo(ctx context.Context, uniqueSeries map[
 			return nil
 		}
 
+		// The revision to search at a point in time only depends on the repository and the branch
+		// or revision that is searched, so the revisions are shared by all series on the same one.
+		revisions := map[string]map[time.Time]string{}
+
 		// For every series that we want to potentially gather historical data for, try.
 		for _, seriesID := range sortedSeriesIDs {
 			series := uniqueSeries[seriesID]
 
+			if series.Team != "" {
+				owns, err := teams.Owns(ctx, series.Team, repoName)
+				if err != nil {
+					softErr = multierror.Append(softErr, errors.Wrap(err, "GetTeamRepositories"))
+					continue
+				}
+				if !owns {
+					continue
+				}
+			}
+
 			frames := FirstOfMonthFrames(12, series.CreatedAt.Truncate(time.Hour*24))
 
 			log15.Debug("insights: starting frames", "repo_id", repo.ID, "series_id", series.SeriesID, "frames", frames)
-			plan := h.frameFilter.FilterFrames(ctx, frames, repo.ID)
+			seriesRevision := series.RevisionFor(repoName)
+			var plan compression.BackfillPlan
+			if seriesRevision == "" {
+				plan = h.frameFilter.FilterFrames(ctx, frames, repo.ID)
+			} else {
+				// The commit index only knows the commits of the default branch.
+				plan = (&compression.NoopFilter{}).FilterFrames(ctx, frames, repo.ID)
+			}
+			if revisions[seriesRevision] == nil {
+				revisions[seriesRevision] = map[time.Time]string{}
+			}
+			plan = h.reuseUnchangedFrames(ctx, repo, seriesID, seriesRevision, firstHEADCommit, plan, revisions[seriesRevision])
 			log15.Debug("insights: sampling historical data frames", "repo_id", repo.ID, "series_id", series.SeriesID, "frames", frames)
 
 			for i := len(plan.Executions) - 1; i >= 0; i-- {
@@ -344,19 +396,12 @@ func (h *historicalEnqueuer) buildForRepo(ctx context.Context, uniqueSeries map[
 					return err
 				}
 
-				to := queryExecution.RecordingTime.Add(time.Hour * 24)
 				// If we already have data for this frame+repo+series, then there's nothing to do.
-				var numDataPoints int
-				numDataPoints, err = h.insightsStore.CountData(ctx, store.CountDataOpts{
-					From:     &queryExecution.RecordingTime,
-					To:       &to,
-					SeriesID: &seriesID,
-					RepoID:   &repo.ID,
-				})
+				hasData, err := h.hasData(ctx, queryExecution, seriesID, repo.ID)
 				if err != nil {
 					softErr = multierror.Append(softErr, err)
 					// In this case we will assume the point does not exist and query for it anyway.
-				} else if numDataPoints > 0 {
+				} else if hasData {
 					continue
 				}
 
@@ -382,6 +427,112 @@ func (h *historicalEnqueuer) buildForRepo(ctx context.Context, uniqueSeries map[
 	}
 }
 
+// hasData reports whether data has been recorded for every recording time of the execution.
+func (h *historicalEnqueuer) hasData(ctx context.Context, execution *compression.QueryExecution, seriesID string, repoID api.RepoID) (bool, error) {
+	times := append([]time.Time{execution.RecordingTime}, execution.SharedRecordings...)
+	for _, from := range times {
+		from := from
+		to := from.Add(time.Hour * 24)
+		numDataPoints, err := h.insightsStore.CountData(ctx, store.CountDataOpts{
+			From:     &from,
+			To:       &to,
+			SeriesID: &seriesID,
+			RepoID:   &repoID,
+		})
+		if err != nil {
+			return false, err
+		}
+		if numDataPoints == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+var historicalFramesReused = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Name:      "insights_historical_frames_reused_total",
+	Help:      "Number of historical frames that reuse the search result of the previous frame, because the repository had no commits in between.",
+})
+
+// reuseUnchangedFrames merges every execution of the plan into the previous one if the
+// repository had no commits in between, which is the case if both would search the same
+// revision. The recording times of a merged execution become shared recordings of the previous
+// one, so that the repository is searched once and the result recorded for all of them. This
+// avoids searching dormant repositories over and over again during backfills.
+//
+// Executions that already have data for the series are dropped from the plan before their
+// revision is looked up, so that already backfilled frames don't cost a gitserver request each.
+//
+// Revisions are looked up from gitserver on the given branch or revision (the default branch if
+// empty) unless the plan already knows them, and cached in revisions.
+func (h *historicalEnqueuer) reuseUnchangedFrames(ctx context.Context, repo *types.Repo, seriesID, seriesRevision string, firstHEADCommit *gitapi.Commit, plan compression.BackfillPlan, revisions map[time.Time]string) compression.BackfillPlan {
+	executions := make([]*compression.QueryExecution, 0, len(plan.Executions))
+	var prev *compression.QueryExecution
+	for i, execution := range plan.Executions {
+		// Frames before the first commit record a zero value without searching anyway.
+		if execution.RecordingTime.Before(firstHEADCommit.Author.Date) {
+			executions = append(executions, execution)
+			prev = nil
+			continue
+		}
+
+		// If we already have data for this frame+repo+series, then there's nothing to do. The
+		// previous execution can still be reused by later frames: if they search the same
+		// revision, the repository had no commits in between at all.
+		hasData, err := h.hasData(ctx, execution, seriesID, repo.ID)
+		if err != nil {
+			// Assume the point does not exist, buildForRepo checks again.
+			log15.Debug("insights: unable to check for existing data", "repo_id", repo.ID, "series_id", seriesID, "for_time", execution.RecordingTime, "error", err)
+		} else if hasData {
+			continue
+		}
+
+		if execution.Revision == "" {
+			revision, err := h.revisionAt(ctx, repo.Name, seriesRevision, execution.RecordingTime, revisions)
+			if err != nil {
+				// Leave the remaining executions alone, buildSeries handles the error.
+				log15.Debug("insights: unable to find revision to reuse frames", "repo_id", repo.ID, "for_time", execution.RecordingTime, "error", err)
+				plan.Executions = append(executions, plan.Executions[i:]...)
+				return plan
+			}
+			execution.Revision = revision
+		}
+
+		if prev != nil && execution.Revision != "" && execution.Revision == prev.Revision {
+			log15.Debug("insights: reusing previous frame for unchanged repository", "repo_id", repo.ID, "for_time", execution.RecordingTime, "reused_time", prev.RecordingTime, "rev", execution.Revision)
+			prev.SharedRecordings = append(prev.SharedRecordings, execution.RecordingTime)
+			prev.SharedRecordings = append(prev.SharedRecordings, execution.SharedRecordings...)
+			historicalFramesReused.Add(float64(execution.RecordCount()))
+			continue
+		}
+
+		executions = append(executions, execution)
+		prev = execution
+	}
+	plan.Executions = executions
+	return plan
+}
+
+// revisionAt returns the most recent commit in the history of the given branch or revision (the
+// default branch if empty) at the given time, or an empty string if there is none.
+func (h *historicalEnqueuer) revisionAt(ctx context.Context, repoName api.RepoName, seriesRevision string, at time.Time, revisions map[time.Time]string) (string, error) {
+	if revision, ok := revisions[at]; ok {
+		return revision, nil
+	}
+
+	recentCommits, err := h.gitFindRecentCommit(ctx, repoName, seriesRevision, at)
+	if err != nil {
+		return "", err
+	}
+	var revision string
+	if len(recentCommits) > 0 && recentCommits[0].Committer != nil {
+		revision = string(recentCommits[0].ID)
+	}
+	revisions[at] = revision
+	return revision, nil
+}
+
 // buildSeriesContext describes context/parameters for a call to buildSeries()
 type buildSeriesContext struct {
 	// The timeframe we're building historical data for.
@@ -484,7 +635,7 @@ func (h *historicalEnqueuer) buildSeries(ctx context.Context, bctx *buildSeriesC
 	if len(bctx.execution.Revision) > 0 {
 		revision = bctx.execution.Revision
 	} else {
-		recentCommits, err := h.gitFindRecentCommit(ctx, bctx.repo.Name, bctx.execution.RecordingTime)
+		recentCommits, err := h.gitFindRecentCommit(ctx, bctx.repo.Name, bctx.series.RevisionFor(string(bctx.repo.Name)), bctx.execution.RecordingTime)
 		if err != nil {
 			if errors.HasType(err, &gitserver.RevisionNotFoundError{}) || vcs.IsRepoNotExist(err) {
 				return // no error - repo may not be cloned yet (or not even pushed to code host yet)
@@ -513,6 +664,7 @@ func (h *historicalEnqueuer) buildSeries(ctx context.Context, bctx *buildSeriesC
 	query = fmt.Sprintf("%s repo:^%s$@%s", query, regexp.QuoteMeta(repoName), revision)
 
 	job := bctx.execution.ToQueueJob(bctx.seriesID, query, priority.Unindexed, priority.FromTimeInterval(bctx.execution.RecordingTime, bctx.series.CreatedAt))
+	job.PatternType = bctx.series.PatternType
 	hardErr = h.enqueueQueryRunnerJob(ctx, job)
 	return
 }
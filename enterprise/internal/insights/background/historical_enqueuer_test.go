@@ -26,6 +26,7 @@ type testParams struct {
 	frames                int
 	recordSleepOperations bool
 	haveData              bool
+	unchangedRepos        bool
 }
 
 type testResults struct {
@@ -101,6 +102,10 @@ func testHistoricalEnqueuer(t *testing.T, p *testParams) *testResults {
 	})
 
 	enqueueQueryRunnerJob := func(ctx context.Context, job *queryrunner.Job) error {
+		if len(job.DependentFrames) > 0 {
+			r.operations = append(r.operations, fmt.Sprintf(`enqueueQueryRunnerJob("%s", "%s", dependentFrames=%d)`, job.RecordTime.Format(time.RFC3339), job.SearchQuery, len(job.DependentFrames)))
+			return nil
+		}
 		r.operations = append(r.operations, fmt.Sprintf(`enqueueQueryRunnerJob("%s", "%s")`, job.RecordTime.Format(time.RFC3339), job.SearchQuery))
 		return nil
 	}
@@ -124,7 +129,14 @@ func testHistoricalEnqueuer(t *testing.T, p *testParams) *testResults {
 		return &gitapi.Commit{Committer: &gitapi.Signature{Date: yearsAgo}}, nil
 	}
 
-	gitFindRecentCommit := func(ctx context.Context, repoName api.RepoName, target time.Time) ([]*gitapi.Commit, error) {
+	gitFindRecentCommit := func(ctx context.Context, repoName api.RepoName, revision string, target time.Time) ([]*gitapi.Commit, error) {
+		if p.haveData {
+			t.Errorf("unexpected revision lookup for frame %s that already has data", target.Format(time.RFC3339))
+		}
+		if p.unchangedRepos {
+			// The same commit is the most recent one at any point in time.
+			return []*gitapi.Commit{{ID: "d34db33f", Committer: &gitapi.Signature{Date: clock().Add(-3 * 365 * 24 * time.Hour)}}}, nil
+		}
 		nearby := target.Add(-2 * 24 * time.Hour)
 		return []*gitapi.Commit{{Committer: &gitapi.Signature{Date: nearby}}}, nil
 	}
@@ -144,6 +156,7 @@ func testHistoricalEnqueuer(t *testing.T, p *testParams) *testResults {
 		framesToBackfill:      func() int { return p.frames },
 		frameLength:           func() time.Duration { return 7 * 24 * time.Hour },
 		dataSeriesStore:       dataSeriesStore,
+		backfillerEnabled:     func() bool { return false },
 	}
 
 	// If we do an iteration without any insights or repos, we should expect no sleep calls to be made.
@@ -248,6 +261,25 @@ func Test_historicalEnqueuer(t *testing.T) {
 			recordSleepOperations: true,
 		}))
 	})
+
+	// Test that when a repository had no commits during the backfilled timeframes, it is only
+	// searched once per series and the result is reused for all the other timeframes.
+	t.Run("unchanged_repos", func(t *testing.T) {
+		want := autogold.Want("unchanged_repos", &testResults{
+			allReposIteratorCalls: 1, reposGetByName: 1,
+			operations: []string{
+				`enqueueQueryRunnerJob("2020-02-01T00:00:00Z", "query1 count:all repo:^repo/0$@d34db33f", dependentFrames=11)`,
+				`enqueueQueryRunnerJob("2020-02-01T00:00:00Z", "query2 count:all repo:^repo/0$@d34db33f", dependentFrames=11)`,
+			},
+		})
+		want.Equal(t, testHistoricalEnqueuer(t, &testParams{
+			settings:              testRealGlobalSettings,
+			numRepos:              1,
+			frames:                2,
+			recordSleepOperations: true,
+			unchangedRepos:        true,
+		}))
+	})
 }
 
 func TestDayOfMonthFrames(t *testing.T) {
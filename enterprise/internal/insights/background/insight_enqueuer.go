@@ -43,11 +43,12 @@ func newInsightEnqueuer(ctx context.Context, workerBaseStore *basestore.Store, i
 	//
 	// See also https://github.com/sourcegraph/sourcegraph/pull/17227#issuecomment-779515187 for some very rough
 	// data retention / scale concerns.
+	enqueuer := queryrunner.NewEnqueuer(workerBaseStore, observationContext)
 	return goroutine.NewPeriodicGoroutineWithMetrics(ctx, 1*time.Hour, goroutine.NewHandlerWithErrorMessage(
 		"insights_enqueuer",
 		func(ctx context.Context) error {
 			queryRunnerEnqueueJob := func(ctx context.Context, job *queryrunner.Job) error {
-				_, err := queryrunner.EnqueueJob(ctx, workerBaseStore, job)
+				_, err := enqueuer.Enqueue(ctx, job)
 				return err
 			}
 			now := time.Now
@@ -67,7 +68,7 @@ func discoverAndEnqueueInsights(
 
 	log15.Info("enqueuing indexed insight recordings")
 	// this job will do the work of both recording (permanent) queries, and snapshot (ephemeral) queries. We want to try both, so if either has a soft-failure we will attempt both.
-	recordingSeries, err := insightStore.GetDataSeries(ctx, store.GetDataSeriesArgs{NextRecordingBefore: now()})
+	recordingSeries, err := insightStore.GetDataSeries(ctx, store.GetDataSeriesArgs{NextRecordingBefore: now(), ExcludePaused: true})
 	if err != nil {
 		return errors.Wrap(err, "indexed insight recorder: unable to fetch series for recordings")
 	}
@@ -77,7 +78,7 @@ func discoverAndEnqueueInsights(
 	}
 
 	log15.Info("enqueuing indexed insight snapshots")
-	snapshotSeries, err := insightStore.GetDataSeries(ctx, store.GetDataSeriesArgs{NextSnapshotBefore: now()})
+	snapshotSeries, err := insightStore.GetDataSeries(ctx, store.GetDataSeriesArgs{NextSnapshotBefore: now(), ExcludePaused: true})
 	if err != nil {
 		return errors.Wrap(err, "indexed insight recorder: unable to fetch series for snapshots")
 	}
@@ -114,6 +115,7 @@ func enqueue(ctx context.Context, dataSeries []types.InsightSeries, mode store.P
 			Priority:    int(priority.High),
 			Cost:        int(priority.Indexed),
 			PersistMode: string(mode),
+			PatternType: series.PatternType,
 		})
 		if err != nil {
 			multi = multierror.Append(multi, errors.Wrapf(err, "failed to enqueue insight series_id: %s", seriesID))
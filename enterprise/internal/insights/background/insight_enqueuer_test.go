@@ -96,6 +96,7 @@ func Test_discoverAndEnqueueInsights(t *testing.T) {
 			Query:                 "query2",
 			NextRecordingAfter:    now.Add(1 * time.Hour),
 			RecordingIntervalDays: 1,
+			PatternType:           types.PatternTypeStructural,
 		},
 	}, nil)
 
@@ -116,6 +117,7 @@ func Test_discoverAndEnqueueInsights(t *testing.T) {
     "Cost": 500,
     "Priority": 10,
     "PersistMode": "record",
+    "PatternType": "",
     "DependentFrames": null,
     "ID": 0,
     "State": "queued",
@@ -134,6 +136,7 @@ func Test_discoverAndEnqueueInsights(t *testing.T) {
     "Cost": 500,
     "Priority": 10,
     "PersistMode": "record",
+    "PatternType": "structural",
     "DependentFrames": null,
     "ID": 0,
     "State": "queued",
@@ -152,6 +155,7 @@ func Test_discoverAndEnqueueInsights(t *testing.T) {
     "Cost": 500,
     "Priority": 10,
     "PersistMode": "snapshot",
+    "PatternType": "",
     "DependentFrames": null,
     "ID": 0,
     "State": "queued",
@@ -170,6 +174,7 @@ func Test_discoverAndEnqueueInsights(t *testing.T) {
     "Cost": 500,
     "Priority": 10,
     "PersistMode": "snapshot",
+    "PatternType": "structural",
     "DependentFrames": null,
     "ID": 0,
     "State": "queued",
@@ -0,0 +1,68 @@
+package queryrunner
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+)
+
+// Authenticator decides which Sourcegraph API the query runner sends the search queries of a job
+// to, and which credentials it sends along.
+//
+// The background routines use InternalAuthenticator. Other implementations can, for example, send
+// all queries to the external API with the token of a service account.
+//
+// Jobs don't record who owns them: insight series are shared between the users that can see
+// them, so a job only identifies its series. Implementations therefore can't pick credentials per
+// user or per tenant.
+type Authenticator interface {
+	// Credentials returns the credentials to execute the search queries of the given job with.
+	// It is called for every search request, so implementations that rotate tokens only need to
+	// return the current one.
+	Credentials(ctx context.Context, job *Job) (*Credentials, error)
+
+	// Rejected is called when the API refused the given credentials, so that implementations can
+	// drop or refresh cached credentials before the job is retried.
+	Rejected(ctx context.Context, job *Job, credentials *Credentials)
+}
+
+// Credentials describe where and how the query runner executes search queries.
+type Credentials struct {
+	// URL is the base URL of the API, such as https://sourcegraph.example.com/.api. Search
+	// queries are sent to its streaming search endpoint, /search/stream, and compute queries to
+	// its GraphQL endpoint, /graphql.
+	URL string
+
+	// Token, if not empty, is sent as the access token of every request.
+	Token string
+
+	// Doer sends the requests. If nil, httpcli.ExternalDoer is used.
+	Doer httpcli.Doer
+}
+
+// errCredentialsRejected is returned by search when the API refused the credentials.
+var errCredentialsRejected = errors.New("search API rejected the credentials")
+
+// InternalAuthenticator executes all search queries against the internal API of the frontend,
+// without any credentials.
+//
+// 🚨 SECURITY: The internal API doesn't enforce repository permissions, so search results
+// include every repository on the instance.
+type InternalAuthenticator struct{}
+
+var _ Authenticator = InternalAuthenticator{}
+
+func (InternalAuthenticator) Credentials(ctx context.Context, job *Job) (*Credentials, error) {
+	u, err := url.Parse(api.InternalClient.URL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/.internal"
+	return &Credentials{URL: u.String(), Doer: httpcli.InternalDoer}, nil
+}
+
+func (InternalAuthenticator) Rejected(ctx context.Context, job *Job, credentials *Credentials) {}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/keegancsmith/sqlf"
 
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/internal/metrics"
@@ -18,6 +19,9 @@ import (
 // This is particularly important because the historical enqueuer can produce e.g.
 // num_series*num_repos*num_timeframes jobs (example: 20*40,000*6 in an average case) which
 // can quickly add up to be millions of jobs left in a "completed" state in the DB.
+//
+// It also removes the query cache entries that are older than the insights.query.cache.ttl site
+// setting.
 func NewCleaner(ctx context.Context, workerBaseStore *basestore.Store, observationContext *observation.Context) goroutine.BackgroundRoutine {
 	metrics := metrics.NewOperationMetrics(
 		observationContext.Registerer,
@@ -34,8 +38,11 @@ func NewCleaner(ctx context.Context, workerBaseStore *basestore.Store, observati
 		"insights_query_runner_cleaner",
 		func(ctx context.Context) error {
 			// TODO(slimsag): future: recording the number of jobs cleaned up in a metric would be nice.
-			_, err := cleanJobs(ctx, workerBaseStore)
-			return err
+			if _, err := cleanJobs(ctx, workerBaseStore); err != nil {
+				return err
+			}
+			ttl := queryCacheOptionsFromConfig(conf.Get().InsightsQueryCache).ttl
+			return cleanQueryCache(ctx, workerBaseStore, time.Now().Add(-ttl))
 		},
 	), operation)
 }
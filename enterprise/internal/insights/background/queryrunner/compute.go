@@ -0,0 +1,131 @@
+package queryrunner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gqlclient"
+)
+
+// This file contains all the methods required to execute compute queries using our GraphQL API
+// and count the values they capture.
+
+const gqlComputeQuery = `query InsightsCompute($query: String!) {
+	compute(query: $query) {
+		__typename
+		... on ComputeMatchContext {
+			repository {
+				id
+				name
+			}
+			matches {
+				value
+				environment {
+					variable
+					value
+				}
+			}
+		}
+	}
+}`
+
+type gqlComputeVars struct {
+	Query string `json:"query"`
+}
+
+// computeResult is a result of the compute endpoint. Only match contexts are decoded, other
+// results have no repository and are ignored.
+type computeResult struct {
+	Typename   string `json:"__typename"`
+	Repository struct {
+		ID   graphql.ID
+		Name string
+	}
+	Matches []struct {
+		Value       string
+		Environment []struct {
+			Variable string
+			Value    string
+		}
+	}
+}
+
+// computeResults are the results of a compute query, aggregated per repository and captured value.
+type computeResults struct {
+	// valuesPerRepo is the number of times each value was captured in each repository, and
+	// repoNames the names of the repositories.
+	valuesPerRepo map[api.RepoID]map[string]float64
+	repoNames     map[api.RepoID]string
+}
+
+// compute executes the given compute query with the given credentials, and counts the number of
+// times each value is captured in each repository. The values are those of the capture groups of
+// the query's regular expression, or the whole match if the expression has no capture group.
+//
+// Like search, errors that may go away when the query is retried are wrapped in a
+// transientSearchError.
+func compute(ctx context.Context, credentials *Credentials, query string) (*computeResults, error) {
+	client := &gqlclient.Client{
+		URL:       strings.TrimSuffix(credentials.URL, "/") + "/graphql",
+		Token:     credentials.Token,
+		UserAgent: searchUserAgent,
+		Doer:      credentials.Doer,
+	}
+	var data struct {
+		Compute []computeResult
+	}
+	err := client.Do(ctx, gqlclient.Request{
+		Name:      "InsightsCompute",
+		Query:     gqlComputeQuery,
+		Variables: gqlComputeVars{Query: query},
+	}, &data)
+	if err != nil {
+		if errors.Is(err, gqlclient.ErrUnauthorized) {
+			return nil, errors.Wrap(errCredentialsRejected, err.Error())
+		}
+		if gqlclient.IsTransient(err) {
+			return nil, &transientSearchError{errors.Wrap(err, "compute")}
+		}
+		return nil, errors.Wrap(err, "compute")
+	}
+	return aggregateComputeResults(data.Compute)
+}
+
+func aggregateComputeResults(results []computeResult) (*computeResults, error) {
+	aggregated := &computeResults{
+		valuesPerRepo: map[api.RepoID]map[string]float64{},
+		repoNames:     map[api.RepoID]string{},
+	}
+	for _, result := range results {
+		if result.Typename != "ComputeMatchContext" || len(result.Matches) == 0 {
+			continue
+		}
+
+		var repoID api.RepoID
+		if err := relay.UnmarshalSpec(result.Repository.ID, &repoID); err != nil {
+			return nil, errors.Wrapf(err, "invalid repository ID %q", result.Repository.ID)
+		}
+		aggregated.repoNames[repoID] = result.Repository.Name
+
+		values, ok := aggregated.valuesPerRepo[repoID]
+		if !ok {
+			values = map[string]float64{}
+			aggregated.valuesPerRepo[repoID] = values
+		}
+		for _, match := range result.Matches {
+			if len(match.Environment) == 0 {
+				values[match.Value]++
+				continue
+			}
+			for _, entry := range match.Environment {
+				values[entry.Value]++
+			}
+		}
+	}
+	return aggregated, nil
+}
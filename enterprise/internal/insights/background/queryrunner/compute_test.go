@@ -0,0 +1,57 @@
+package queryrunner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestCompute(t *testing.T) {
+	var gotPath, gotAuthorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuthorization = r.Header.Get("Authorization")
+		// Repository IDs are the relay IDs of repositories 1 and 2.
+		fmt.Fprint(w, `{"data":{"compute":[
+	{"__typename":"ComputeMatchContext","repository":{"id":"UmVwb3NpdG9yeTox","name":"a"},"matches":[
+		{"value":"go 1.17","environment":[{"variable":"1","value":"1.17"}]}
+	]},
+	{"__typename":"ComputeMatchContext","repository":{"id":"UmVwb3NpdG9yeToy","name":"b"},"matches":[
+		{"value":"go 1.16","environment":[{"variable":"1","value":"1.16"}]},
+		{"value":"go 1.17","environment":[{"variable":"1","value":"1.17"}]},
+		{"value":"go 1.17","environment":[]}
+	]},
+	{"__typename":"ComputeText","value":"ignored"}
+]}}`)
+	}))
+	defer srv.Close()
+
+	credentials := &Credentials{URL: srv.URL + "/.api", Token: "t", Doer: srv.Client()}
+	res, err := compute(context.Background(), credentials, `go\s(\d\.\d+) file:go.mod`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := gotPath, "/.api/graphql"; have != want {
+		t.Fatalf("wrong path. want=%q, have=%q", want, have)
+	}
+	if have, want := gotAuthorization, "token t"; have != want {
+		t.Fatalf("wrong authorization header. want=%q, have=%q", want, have)
+	}
+
+	wantValues := map[api.RepoID]map[string]float64{
+		1: {"1.17": 1},
+		2: {"1.16": 1, "1.17": 1, "go 1.17": 1},
+	}
+	if diff := cmp.Diff(wantValues, res.valuesPerRepo); diff != "" {
+		t.Errorf("unexpected captured values (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[api.RepoID]string{1: "a", 2: "b"}, res.repoNames); diff != "" {
+		t.Errorf("unexpected repository names (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,130 @@
+package queryrunner
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/inventory"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// handleLanguageStats computes the number of lines of code per language in each repository of a
+// language-stats series, and records one point per language in each repository. The statistics
+// are computed from an archive of the repository fetched from gitserver rather than by searching
+// it, which is much faster and exact for whole repositories.
+//
+// 🚨 SECURITY: Like search results, the statistics of every repository are recorded, and the
+// repository permissions are enforced when the points are read.
+func (r *workHandler) handleLanguageStats(ctx context.Context, job *Job, series *types.InsightSeries) error {
+	recordTime := time.Now()
+	if job.RecordTime != nil {
+		recordTime = *job.RecordTime
+	}
+
+	repoStore := database.Repos(r.baseWorkerStore.Handle().DB())
+	results := &computeResults{
+		valuesPerRepo: map[api.RepoID]map[string]float64{},
+		repoNames:     map[api.RepoID]string{},
+	}
+	for _, repoName := range series.Repositories {
+		repo, err := repoStore.GetByName(ctx, api.RepoName(repoName))
+		if err != nil {
+			if errors.HasType(err, &database.RepoNotFoundErr{}) {
+				// Like a search for a repository that doesn't exist, there is nothing to record.
+				log15.Warn("insights: repository of language statistics not found", "series_id", series.SeriesID, "repo", repoName)
+				continue
+			}
+			return err
+		}
+
+		stats, err := r.languageStats(ctx, repo.Name, series.RevisionFor(repoName))
+		if err != nil {
+			return err
+		}
+		results.valuesPerRepo[repo.ID] = stats
+		results.repoNames[repo.ID] = repoName
+	}
+	return r.recordCapturedResults(ctx, job, series, recordTime, results)
+}
+
+// languageStats returns the number of lines of code per language in the repository at the given
+// revision, or at its default branch if the revision is empty.
+func (r *workHandler) languageStats(ctx context.Context, repoName api.RepoName, revision string) (_ map[string]float64, err error) {
+	ctx, endObservation := r.operations.languageStats.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("repo", string(repoName)),
+		log.String("revision", revision),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	if revision == "" {
+		revision = "HEAD"
+	}
+	commitID, err := git.ResolveRevision(ctx, repoName, revision, git.ResolveRevisionOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "ResolveRevision")
+	}
+	archive, err := gitserver.DefaultClient.Archive(ctx, repoName, gitserver.ArchiveOptions{Treeish: string(commitID), Format: "tar"})
+	if err != nil {
+		return nil, errors.Wrap(err, "Archive")
+	}
+	defer archive.Close()
+	return languageStatsFromArchive(ctx, archive)
+}
+
+// languageStatsFromArchive returns the number of lines of code per language of the files in the
+// given tar archive. Languages are detected the same way as for the language statistics of
+// repositories, including skipping vendored files.
+func languageStatsFromArchive(ctx context.Context, archive io.Reader) (map[string]float64, error) {
+	tr := tar.NewReader(archive)
+	invCtx := inventory.Context{
+		// Files are read as they are listed in the archive, so the reader of the current entry is
+		// the reader of the file.
+		NewFileReader: func(ctx context.Context, path string) (io.ReadCloser, error) {
+			return io.NopCloser(tr), nil
+		},
+	}
+
+	stats := map[string]float64{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading archive")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		inv, err := invCtx.Entries(ctx, archiveFileInfo{FileInfo: header.FileInfo(), path: header.Name})
+		if err != nil {
+			return nil, err
+		}
+		for _, lang := range inv.Languages {
+			stats[lang.Name] += float64(lang.TotalLines)
+		}
+	}
+	return stats, nil
+}
+
+// archiveFileInfo is the fs.FileInfo of a file in an archive. Its name is the path of the file,
+// which the language detection needs to recognize e.g. vendored files.
+type archiveFileInfo struct {
+	fs.FileInfo
+	path string
+}
+
+func (fi archiveFileInfo) Name() string { return fi.path }
@@ -0,0 +1,45 @@
+package queryrunner
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLanguageStatsFromArchive(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct {
+		name     string
+		contents string
+	}{
+		{"main.go", "package main\n\nfunc main() {}\n"},
+		{"cmd/tool/tool.go", "package tool\n\nvar x = 1"},
+		// Vendored files are not counted.
+		{"vendor/github.com/foo/foo.go", "package foo\n"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.contents)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "cmd/", Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := languageStatsFromArchive(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]float64{"Go": 6}, stats); diff != "" {
+		t.Errorf("unexpected language statistics (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,57 @@
+package queryrunner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+type operations struct {
+	enqueue *observation.Operation
+	dequeue *observation.Operation
+	search  *observation.Operation
+	compute *observation.Operation
+	record  *observation.Operation
+
+	languageStats *observation.Operation
+}
+
+var (
+	operationsOnce sync.Once
+	sharedOps      *operations
+)
+
+// newOperations returns the operations used to trace and measure each phase of a query runner
+// job. The enqueuers and the worker share a single set of operations so that the underlying
+// metrics are only registered once.
+func newOperations(observationContext *observation.Context) *operations {
+	operationsOnce.Do(func() {
+		m := metrics.NewOperationMetrics(
+			observationContext.Registerer,
+			"insights_query_runner",
+			metrics.WithLabels("op"),
+			metrics.WithCountHelp("Total number of method invocations."),
+		)
+
+		op := func(name string) *observation.Operation {
+			return observationContext.Operation(observation.Op{
+				Name:              fmt.Sprintf("insights.queryrunner.%s", name),
+				MetricLabelValues: []string{name},
+				Metrics:           m,
+			})
+		}
+
+		sharedOps = &operations{
+			enqueue: op("Enqueue"),
+			dequeue: op("Dequeue"),
+			search:  op("Search"),
+			compute: op("Compute"),
+			record:  op("Record"),
+
+			languageStats: op("LanguageStats"),
+		}
+	})
+	return sharedOps
+}
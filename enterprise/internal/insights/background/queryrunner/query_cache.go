@@ -0,0 +1,207 @@
+package queryrunner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/keegancsmith/sqlf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/xhit/go-str2duration/v2"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// This file contains the cache of search results that is shared by all series: series whose
+// queries have the same normal form (see store.NormalizeSeriesQuery) share the results of a
+// search for data points recorded in the same time bucket.
+
+var (
+	queryCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_insights_query_cache_hits_total",
+		Help: "Total number of code insights search queries whose results were read from the query cache.",
+	})
+	queryCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_insights_query_cache_misses_total",
+		Help: "Total number of code insights search queries that were not found in the query cache.",
+	})
+)
+
+// Defaults of the insights.query.cache site configuration.
+const (
+	defaultQueryCacheBucket = time.Hour
+	defaultQueryCacheTTL    = 24 * time.Hour
+)
+
+type queryCacheOptions struct {
+	enabled bool
+	bucket  time.Duration
+	ttl     time.Duration
+}
+
+// queryCacheOptionsFromConfig returns the cache options of the given site configuration, using
+// the defaults for anything that is unset or invalid.
+func queryCacheOptionsFromConfig(c *schema.InsightsQueryCache) queryCacheOptions {
+	opts := queryCacheOptions{
+		enabled: true,
+		bucket:  defaultQueryCacheBucket,
+		ttl:     defaultQueryCacheTTL,
+	}
+	if c == nil {
+		return opts
+	}
+
+	if c.Enabled != nil {
+		opts.enabled = *c.Enabled
+	}
+	parseDuration := func(name, value string, d *time.Duration) {
+		if value == "" {
+			return
+		}
+		v, err := str2duration.ParseDuration(value)
+		if err != nil || v <= 0 {
+			log15.Warn("insights.queryrunner: invalid insights.query.cache duration, using the default", "field", name, "value", value, "default", *d)
+			return
+		}
+		*d = v
+	}
+	parseDuration("bucket", c.Bucket, &opts.bucket)
+	parseDuration("ttl", c.Ttl, &opts.ttl)
+	return opts
+}
+
+// queryCacheKey returns the key of the results of the given query in the cache. Queries that can
+// not be parsed are keyed by their exact text.
+func queryCacheKey(query, patternType string) string {
+	normalized, err := store.NormalizeSeriesQuery(query, patternType)
+	if err != nil {
+		normalized = query
+	}
+	h := sha256.Sum256([]byte(patternType + "\x00" + normalized))
+	return hex.EncodeToString(h[:])
+}
+
+// cachedSearchResults is the serialized form of the searchResults stored in the cache. Only
+// complete results are cached, see isCacheable.
+type cachedSearchResults struct {
+	MatchesPerRepo map[api.RepoID]int
+	RepoNames      map[api.RepoID]string
+	Samples        []cachedSample
+}
+
+type cachedSample struct {
+	RepoID     api.RepoID
+	RepoName   string
+	Path       string
+	LineNumber *int32  `json:",omitempty"`
+	Preview    *string `json:",omitempty"`
+}
+
+// isCacheable reports whether the given results can be shared with other series. Results with an
+// alert, or that are incomplete for any reason, are specific to the search that produced them.
+func isCacheable(results *searchResults) bool {
+	return results.alert == nil && len(results.skipped) == 0 && !results.budgetExceeded
+}
+
+// getCachedSearchResults returns the cached results of the query with the given key in the given
+// time bucket, if there are any that were cached after the given time. The results have the
+// samples that were retained when they were cached, which may be fewer than sampleLimit.
+func getCachedSearchResults(ctx context.Context, workerBaseStore *basestore.Store, key string, bucket, after time.Time, sampleLimit int) (*searchResults, bool, error) {
+	payload, ok, err := basestore.ScanFirstString(workerBaseStore.Query(ctx, sqlf.Sprintf(getCachedSearchResultsFmtStr, key, bucket.UTC(), after)))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	var cached cachedSearchResults
+	if err := json.Unmarshal([]byte(payload), &cached); err != nil {
+		return nil, false, errors.Wrap(err, "decoding cached search results")
+	}
+	results := newSearchResults(sampleLimit)
+	for repoID, count := range cached.MatchesPerRepo {
+		results.matchesPerRepo[repoID] = count
+	}
+	for repoID, name := range cached.RepoNames {
+		results.repoNames[repoID] = name
+	}
+	for _, s := range cached.Samples {
+		if len(results.samples) >= sampleLimit {
+			break
+		}
+		results.samples = append(results.samples, sample{
+			repoID:     s.RepoID,
+			repoName:   s.RepoName,
+			path:       s.Path,
+			lineNumber: s.LineNumber,
+			preview:    s.Preview,
+		})
+	}
+	return results, true, nil
+}
+
+const getCachedSearchResultsFmtStr = `
+-- source: enterprise/internal/insights/background/queryrunner/query_cache.go:getCachedSearchResults
+SELECT results FROM insights_query_cache WHERE query_key = %s AND bucket = %s AND created_at > %s
+`
+
+// putCachedSearchResults caches the results of the query with the given key in the given time
+// bucket, unless results were cached already. seriesKey is the key of the query of the series the
+// search was run for, see InvalidateQueryCache.
+func putCachedSearchResults(ctx context.Context, workerBaseStore *basestore.Store, key, seriesKey string, bucket time.Time, results *searchResults) error {
+	cached := cachedSearchResults{
+		MatchesPerRepo: results.matchesPerRepo,
+		RepoNames:      results.repoNames,
+		Samples:        make([]cachedSample, 0, len(results.samples)),
+	}
+	for _, s := range results.samples {
+		cached.Samples = append(cached.Samples, cachedSample{
+			RepoID:     s.repoID,
+			RepoName:   s.repoName,
+			Path:       s.path,
+			LineNumber: s.lineNumber,
+			Preview:    s.preview,
+		})
+	}
+	payload, err := json.Marshal(cached)
+	if err != nil {
+		return errors.Wrap(err, "encoding search results")
+	}
+	return workerBaseStore.Exec(ctx, sqlf.Sprintf(putCachedSearchResultsFmtStr, key, bucket.UTC(), seriesKey, payload))
+}
+
+const putCachedSearchResultsFmtStr = `
+-- source: enterprise/internal/insights/background/queryrunner/query_cache.go:putCachedSearchResults
+INSERT INTO insights_query_cache (query_key, bucket, series_query_key, results)
+VALUES (%s, %s, %s, %s)
+ON CONFLICT (query_key, bucket) DO NOTHING
+`
+
+// InvalidateQueryCache removes the cached results of the given series query, and of all the
+// queries derived from it (e.g. the historical queries of the series), so the next recordings of
+// series with that query run their searches again.
+func InvalidateQueryCache(ctx context.Context, workerBaseStore *basestore.Store, query, patternType string) error {
+	key := queryCacheKey(query, patternType)
+	return workerBaseStore.Exec(ctx, sqlf.Sprintf(invalidateQueryCacheFmtStr, key, key))
+}
+
+const invalidateQueryCacheFmtStr = `
+-- source: enterprise/internal/insights/background/queryrunner/query_cache.go:InvalidateQueryCache
+DELETE FROM insights_query_cache WHERE series_query_key = %s OR query_key = %s
+`
+
+// cleanQueryCache removes the cached results that were cached before the given time.
+func cleanQueryCache(ctx context.Context, workerBaseStore *basestore.Store, before time.Time) error {
+	return workerBaseStore.Exec(ctx, sqlf.Sprintf(cleanQueryCacheFmtStr, before))
+}
+
+const cleanQueryCacheFmtStr = `
+-- source: enterprise/internal/insights/background/queryrunner/query_cache.go:cleanQueryCache
+DELETE FROM insights_query_cache WHERE created_at <= %s
+`
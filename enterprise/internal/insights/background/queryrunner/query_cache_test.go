@@ -0,0 +1,124 @@
+package queryrunner
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestQueryCacheKey(t *testing.T) {
+	if queryCacheKey("errorf repo:^github\\.com/gorilla/mux$", "literal") != queryCacheKey("repo:^github\\.com/gorilla/mux$ errorf", "literal") {
+		t.Error("expected queries that only differ in the order of their parameters to have the same key")
+	}
+	if queryCacheKey("errorf", "literal") == queryCacheKey("errorf", "regexp") {
+		t.Error("expected queries with different pattern types to have different keys")
+	}
+	if queryCacheKey("errorf", "literal") == queryCacheKey("errorf count:all", "literal") {
+		t.Error("expected different queries to have different keys")
+	}
+}
+
+func TestQueryCacheOptionsFromConfig(t *testing.T) {
+	disabled := false
+	for _, tc := range []struct {
+		name string
+		c    *schema.InsightsQueryCache
+		want queryCacheOptions
+	}{
+		{
+			name: "defaults",
+			want: queryCacheOptions{enabled: true, bucket: time.Hour, ttl: 24 * time.Hour},
+		},
+		{
+			name: "disabled",
+			c:    &schema.InsightsQueryCache{Enabled: &disabled},
+			want: queryCacheOptions{enabled: false, bucket: time.Hour, ttl: 24 * time.Hour},
+		},
+		{
+			name: "durations",
+			c:    &schema.InsightsQueryCache{Bucket: "6h", Ttl: "7d"},
+			want: queryCacheOptions{enabled: true, bucket: 6 * time.Hour, ttl: 7 * 24 * time.Hour},
+		},
+		{
+			name: "invalid durations",
+			c:    &schema.InsightsQueryCache{Bucket: "soon", Ttl: "-1h"},
+			want: queryCacheOptions{enabled: true, bucket: time.Hour, ttl: 24 * time.Hour},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := queryCacheOptionsFromConfig(tc.c); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := actor.WithInternalActor(context.Background())
+	workerBaseStore := basestore.NewWithDB(dbtesting.GetDB(t), sql.TxOptions{})
+
+	bucket := time.Date(2021, time.September, 10, 10, 0, 0, 0, time.UTC)
+	seriesKey := queryCacheKey("errorf", "literal")
+	key := queryCacheKey("errorf repo:^github\\.com/gorilla/mux$@abc", "literal")
+
+	results := newSearchResults(10)
+	results.matchesPerRepo[1] = 3
+	results.repoNames[1] = "github.com/gorilla/mux"
+	results.samples = []sample{{repoID: 1, repoName: "github.com/gorilla/mux", path: "mux.go"}}
+	if err := putCachedSearchResults(ctx, workerBaseStore, key, seriesKey, bucket, results); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(t *testing.T, bucket time.Time) *searchResults {
+		t.Helper()
+		cached, ok, err := getCachedSearchResults(ctx, workerBaseStore, key, bucket, time.Now().Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			return nil
+		}
+		return cached
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		cached := get(t, bucket)
+		if cached == nil {
+			t.Fatal("expected cached results")
+		}
+		if diff := cmp.Diff(map[api.RepoID]int{1: 3}, cached.matchesPerRepo); diff != "" {
+			t.Errorf("unexpected matches (-want +got):\n%s", diff)
+		}
+		if len(cached.samples) != 1 || cached.samples[0].path != "mux.go" {
+			t.Errorf("unexpected samples: %+v", cached.samples)
+		}
+	})
+
+	t.Run("other bucket", func(t *testing.T) {
+		if cached := get(t, bucket.Add(time.Hour)); cached != nil {
+			t.Errorf("unexpected cached results for other bucket: %+v", cached)
+		}
+	})
+
+	t.Run("invalidated", func(t *testing.T) {
+		if err := InvalidateQueryCache(ctx, workerBaseStore, "errorf", "literal"); err != nil {
+			t.Fatal(err)
+		}
+		if cached := get(t, bucket); cached != nil {
+			t.Errorf("unexpected cached results after invalidation: %+v", cached)
+		}
+	})
+}
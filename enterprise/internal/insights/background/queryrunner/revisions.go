@@ -0,0 +1,121 @@
+package queryrunner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// revisionQueries returns the queries that are run instead of the query of the job, so that the
+// repositories the series pins a branch or revision for are searched at it: the query of the job
+// excluding those repositories, and one query per pinned repository at its revision. It returns
+// nil if the query of the job is run as is.
+//
+// Historical jobs already search a single repository at a commit on the pinned branch, so only
+// live jobs are split up.
+func revisionQueries(job *Job, series *types.InsightSeries) []string {
+	if job.RecordTime != nil || len(series.RepositoryRevisions) == 0 {
+		return nil
+	}
+
+	repos := make([]string, 0, len(series.RepositoryRevisions))
+	for repo := range series.RepositoryRevisions {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	others := job.SearchQuery
+	queries := make([]string, 0, len(repos)+1)
+	for _, repo := range repos {
+		others = fmt.Sprintf("%s -repo:^%s$", others, regexp.QuoteMeta(repo))
+		queries = append(queries, fmt.Sprintf("%s repo:^%s$@%s", job.SearchQuery, regexp.QuoteMeta(repo), series.RepositoryRevisions[repo]))
+	}
+	return append([]string{others}, queries...)
+}
+
+// searchRevisions runs each of the given queries in place of the query of the job, and merges their
+// results.
+func (r *workHandler) searchRevisions(ctx context.Context, job *Job, series *types.InsightSeries, queries []string, recordTime time.Time, sampleLimit int, timeBudget time.Duration) (*searchResults, error) {
+	results := make([]*searchResults, 0, len(queries))
+	for _, query := range queries {
+		queryJob := *job
+		queryJob.SearchQuery = query
+		res, err := r.cachedSearch(ctx, &queryJob, series, recordTime, sampleLimit, timeBudget)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return mergeSearchResults(results, sampleLimit), nil
+}
+
+// computeRevisions is like searchRevisions, but for compute queries.
+func (r *workHandler) computeRevisions(ctx context.Context, job *Job, queries []string) (*computeResults, error) {
+	results := make([]*computeResults, 0, len(queries))
+	for _, query := range queries {
+		queryJob := *job
+		queryJob.SearchQuery = query
+		res, err := r.compute(ctx, &queryJob)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return mergeComputeResults(results), nil
+}
+
+// mergeSearchResults merges the results of searches of disjoint sets of repositories. An alert that
+// no repository matched is only kept if none of the searches had a different alert, as it is
+// expected when a pinned repository doesn't exist or everything else is excluded.
+func mergeSearchResults(results []*searchResults, sampleLimit int) *searchResults {
+	merged := newSearchResults(sampleLimit)
+	for _, res := range results {
+		for repoID, matches := range res.matchesPerRepo {
+			merged.matchesPerRepo[repoID] += matches
+		}
+		for repoID, name := range res.repoNames {
+			merged.repoNames[repoID] = name
+		}
+		for _, s := range res.samples {
+			if len(merged.samples) >= sampleLimit {
+				break
+			}
+			merged.samples = append(merged.samples, s)
+		}
+		if res.alert != nil && (merged.alert == nil || merged.alert.Title == noRepositoriesAlertTitle) {
+			merged.alert = res.alert
+		}
+		merged.skipped = append(merged.skipped, res.skipped...)
+		merged.budgetExceeded = merged.budgetExceeded || res.budgetExceeded
+	}
+	return merged
+}
+
+// mergeComputeResults merges the results of compute queries of disjoint sets of repositories.
+func mergeComputeResults(results []*computeResults) *computeResults {
+	merged := &computeResults{
+		valuesPerRepo: map[api.RepoID]map[string]float64{},
+		repoNames:     map[api.RepoID]string{},
+	}
+	for _, res := range results {
+		for repoID, values := range res.valuesPerRepo {
+			mergedValues, ok := merged.valuesPerRepo[repoID]
+			if !ok {
+				mergedValues = map[string]float64{}
+				merged.valuesPerRepo[repoID] = mergedValues
+			}
+			for value, count := range values {
+				mergedValues[value] += count
+			}
+		}
+		for repoID, name := range res.repoNames {
+			merged.repoNames[repoID] = name
+		}
+	}
+	return merged
+}
@@ -0,0 +1,100 @@
+package queryrunner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	streamhttp "github.com/sourcegraph/sourcegraph/internal/search/streaming/http"
+)
+
+func TestRevisionQueries(t *testing.T) {
+	series := &types.InsightSeries{RepositoryRevisions: map[string]string{
+		"github.com/golang/go":           "release-branch.go1.17",
+		"github.com/sourcegraph/src-cli": "3.33",
+	}}
+
+	got := revisionQueries(&Job{SearchQuery: "fmt.Errorf count:99999"}, series)
+	want := []string{
+		`fmt.Errorf count:99999 -repo:^github\.com/golang/go$ -repo:^github\.com/sourcegraph/src-cli$`,
+		`fmt.Errorf count:99999 repo:^github\.com/golang/go$@release-branch.go1.17`,
+		`fmt.Errorf count:99999 repo:^github\.com/sourcegraph/src-cli$@3.33`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected queries (-want +got):\n%s", diff)
+	}
+
+	recordTime := time.Date(2021, time.September, 15, 0, 0, 0, 0, time.UTC)
+	if got := revisionQueries(&Job{SearchQuery: "fmt.Errorf", RecordTime: &recordTime}, series); got != nil {
+		t.Errorf("expected historical jobs not to be split up, got %q", got)
+	}
+	if got := revisionQueries(&Job{SearchQuery: "fmt.Errorf"}, &types.InsightSeries{}); got != nil {
+		t.Errorf("expected jobs of series without revisions not to be split up, got %q", got)
+	}
+}
+
+func TestMergeSearchResults(t *testing.T) {
+	others := newSearchResults(2)
+	others.matchesPerRepo[1] = 3
+	others.repoNames[1] = "a"
+	others.samples = []sample{{repoID: 1, path: "a.go"}, {repoID: 1, path: "b.go"}}
+
+	pinned := newSearchResults(2)
+	pinned.matchesPerRepo[2] = 5
+	pinned.repoNames[2] = "b"
+	pinned.samples = []sample{{repoID: 2, path: "c.go"}}
+	pinned.budgetExceeded = true
+
+	missing := newSearchResults(2)
+	missing.alert = &streamhttp.EventAlert{Title: noRepositoriesAlertTitle}
+
+	merged := mergeSearchResults([]*searchResults{others, missing, pinned}, 2)
+	if diff := cmp.Diff(map[api.RepoID]int{1: 3, 2: 5}, merged.matchesPerRepo); diff != "" {
+		t.Errorf("unexpected matches (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[api.RepoID]string{1: "a", 2: "b"}, merged.repoNames); diff != "" {
+		t.Errorf("unexpected repository names (-want +got):\n%s", diff)
+	}
+	if len(merged.samples) != 2 {
+		t.Errorf("expected the samples to be limited to 2, got %d", len(merged.samples))
+	}
+	if !merged.budgetExceeded {
+		t.Error("expected the merged results to have exceeded the time budget")
+	}
+	if merged.alert == nil || merged.alert.Title != noRepositoriesAlertTitle {
+		t.Errorf("unexpected alert %+v", merged.alert)
+	}
+
+	failed := newSearchResults(2)
+	failed.alert = &streamhttp.EventAlert{Title: "Some repositories could not be searched"}
+	merged = mergeSearchResults([]*searchResults{missing, failed}, 2)
+	if merged.alert == nil || merged.alert.Title != "Some repositories could not be searched" {
+		t.Errorf("expected the alert of the failed search to be kept, got %+v", merged.alert)
+	}
+}
+
+func TestMergeComputeResults(t *testing.T) {
+	merged := mergeComputeResults([]*computeResults{
+		{
+			valuesPerRepo: map[api.RepoID]map[string]float64{1: {"1.17": 2}},
+			repoNames:     map[api.RepoID]string{1: "a"},
+		},
+		{
+			valuesPerRepo: map[api.RepoID]map[string]float64{2: {"1.16": 1}},
+			repoNames:     map[api.RepoID]string{2: "b"},
+		},
+	})
+	want := map[api.RepoID]map[string]float64{
+		1: {"1.17": 2},
+		2: {"1.16": 1},
+	}
+	if diff := cmp.Diff(want, merged.valuesPerRepo); diff != "" {
+		t.Errorf("unexpected captured values (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[api.RepoID]string{1: "a", 2: "b"}, merged.repoNames); diff != "" {
+		t.Errorf("unexpected repository names (-want +got):\n%s", diff)
+	}
+}
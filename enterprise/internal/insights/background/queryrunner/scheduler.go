@@ -0,0 +1,77 @@
+package queryrunner
+
+import (
+	"context"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var schedulerThrottled = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "src_insights_search_scheduler_throttled_total",
+	Help: "Total number of times the code insights query runner did not dequeue a job because the maximum number of concurrent searches was reached.",
+})
+
+// defaultMaxConcurrentSearches is the default of insights.query.scheduler.maxConcurrentSearches.
+const defaultMaxConcurrentSearches = 10
+
+type schedulerOptions struct {
+	// maxConcurrentSearches is the maximum number of jobs processed at the same time across all
+	// worker nodes, or 0 if unlimited.
+	maxConcurrentSearches int
+}
+
+// schedulerOptionsFromConfig returns the scheduler options of the given site configuration, using
+// the defaults for anything that is unset or invalid.
+func schedulerOptionsFromConfig(c *schema.InsightsQueryScheduler) schedulerOptions {
+	opts := schedulerOptions{
+		maxConcurrentSearches: defaultMaxConcurrentSearches,
+	}
+	if c == nil {
+		return opts
+	}
+
+	if c.MaxConcurrentSearches != nil && *c.MaxConcurrentSearches >= 0 {
+		opts.maxConcurrentSearches = *c.MaxConcurrentSearches
+	}
+	return opts
+}
+
+var _ workerutil.WithPreDequeue = &workHandler{}
+
+// PreDequeue bounds the number of insight searches running at the same time across all worker
+// nodes, so that a large number of queued jobs (e.g. while new series are backfilled) cannot
+// starve the interactive searches of users. A job is only dequeued while fewer jobs than the
+// configured maximum are being processed.
+func (r *workHandler) PreDequeue(ctx context.Context) (bool, interface{}, error) {
+	opts := schedulerOptionsFromConfig(conf.Get().InsightsQueryScheduler)
+	if opts.maxConcurrentSearches <= 0 {
+		return true, nil, nil
+	}
+
+	processing, err := countProcessingJobs(ctx, r.baseWorkerStore)
+	if err != nil {
+		return false, nil, err
+	}
+	if processing >= opts.maxConcurrentSearches {
+		schedulerThrottled.Inc()
+		return false, nil, nil
+	}
+	return true, nil, nil
+}
+
+func countProcessingJobs(ctx context.Context, workerBaseStore *basestore.Store) (int, error) {
+	count, _, err := basestore.ScanFirstInt(workerBaseStore.Query(ctx, sqlf.Sprintf(countProcessingJobsFmtStr)))
+	return count, err
+}
+
+const countProcessingJobsFmtStr = `
+-- source: enterprise/internal/insights/background/queryrunner/scheduler.go:countProcessingJobs
+SELECT COUNT(*) FROM insights_query_runner_jobs WHERE state = 'processing'
+`
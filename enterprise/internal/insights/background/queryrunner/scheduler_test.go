@@ -0,0 +1,28 @@
+package queryrunner
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSchedulerOptionsFromConfig(t *testing.T) {
+	zero, four, negative := 0, 4, -1
+	for _, tc := range []struct {
+		name   string
+		config *schema.InsightsQueryScheduler
+		want   schedulerOptions
+	}{
+		{"unset", nil, schedulerOptions{maxConcurrentSearches: 10}},
+		{"empty", &schema.InsightsQueryScheduler{}, schedulerOptions{maxConcurrentSearches: 10}},
+		{"unlimited", &schema.InsightsQueryScheduler{MaxConcurrentSearches: &zero}, schedulerOptions{}},
+		{"invalid", &schema.InsightsQueryScheduler{MaxConcurrentSearches: &negative}, schedulerOptions{maxConcurrentSearches: 10}},
+		{"set", &schema.InsightsQueryScheduler{MaxConcurrentSearches: &four}, schedulerOptions{maxConcurrentSearches: 4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := schedulerOptionsFromConfig(tc.config); got != tc.want {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
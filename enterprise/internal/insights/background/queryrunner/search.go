@@ -0,0 +1,116 @@
+package queryrunner
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	streamapi "github.com/sourcegraph/sourcegraph/internal/search/streaming/api"
+	streamhttp "github.com/sourcegraph/sourcegraph/internal/search/streaming/http"
+)
+
+// This file contains all the methods required to execute Sourcegraph searches using our streaming
+// search API and aggregate the results as they arrive.
+
+const searchUserAgent = "Code Insights query runner"
+
+// search executes the given search query with the given credentials and pattern type (literal if
+// empty). The matches are counted per repository as they are streamed, so the results of huge queries are never held in memory. Up to
+// sampleLimit example matches are retained, see searchResults.addSamples.
+//
+// If timeBudget is positive and the search takes longer than that, the search is stopped and the
+// results aggregated so far are returned with budgetExceeded set.
+//
+// Errors that may go away when the search is retried, such as network errors and server errors,
+// are wrapped in a transientSearchError.
+func search(ctx context.Context, credentials *Credentials, query, patternType string, sampleLimit int, timeBudget time.Duration) (*searchResults, error) {
+	req, err := streamhttp.NewRequest(credentials.URL, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing streaming search request")
+	}
+	if patternType != "" {
+		q := req.URL.Query()
+		q.Set("t", patternType)
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Header.Set("User-Agent", searchUserAgent)
+	if credentials.Token != "" {
+		req.Header.Set("Authorization", "token "+credentials.Token)
+	}
+
+	searchCtx := ctx
+	if timeBudget > 0 {
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, timeBudget)
+		defer cancel()
+	}
+	results := newSearchResults(sampleLimit)
+	budgetExceeded := func() bool {
+		if ctx.Err() == nil && errors.Is(searchCtx.Err(), context.DeadlineExceeded) {
+			results.budgetExceeded = true
+			return true
+		}
+		return false
+	}
+
+	doer := credentials.Doer
+	if doer == nil {
+		doer = httpcli.ExternalDoer
+	}
+	resp, err := doer.Do(req.WithContext(searchCtx))
+	if err != nil {
+		if budgetExceeded() {
+			return results, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Wrap(err, "Get")
+		}
+		return nil, &transientSearchError{errors.Wrap(err, "Get")}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errors.Wrapf(errCredentialsRejected, "status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := errors.Errorf("streaming search: unexpected status %d: %s", resp.StatusCode, body)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, &transientSearchError{err}
+		}
+		return nil, err
+	}
+
+	var searchErr error
+	dec := streamhttp.FrontendStreamDecoder{
+		OnProgress: func(p *streamapi.Progress) {
+			// Every progress event describes everything that was skipped so far.
+			results.skipped = p.Skipped
+		},
+		OnMatches: results.addMatches,
+		OnAlert: func(alert *streamhttp.EventAlert) {
+			results.alert = alert
+		},
+		OnError: func(e *streamhttp.EventError) {
+			searchErr = errors.Errorf("streaming search: error: %s", e.Message)
+		},
+	}
+	if err := dec.ReadAll(resp.Body); err != nil {
+		if budgetExceeded() {
+			return results, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Wrap(err, "reading search results")
+		}
+		return nil, &transientSearchError{errors.Wrap(err, "reading search results")}
+	}
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	return results, nil
+}
@@ -0,0 +1,184 @@
+package queryrunner
+
+import (
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	streamapi "github.com/sourcegraph/sourcegraph/internal/search/streaming/api"
+	streamhttp "github.com/sourcegraph/sourcegraph/internal/search/streaming/http"
+)
+
+// searchResults are the results of a search, aggregated as they are streamed.
+type searchResults struct {
+	// matchesPerRepo is the number of matches in each repository, and repoNames the names of
+	// the repositories.
+	matchesPerRepo map[api.RepoID]int
+	repoNames      map[api.RepoID]string
+
+	// samples are example matches, see addSamples.
+	samples     []sample
+	sampleLimit int
+
+	// alert is the alert of the search, if any.
+	alert *streamhttp.EventAlert
+
+	// skipped describes what the search skipped, e.g. because it hit a limit or repositories
+	// were still cloning.
+	skipped []streamapi.Skipped
+
+	// budgetExceeded is true if the search was stopped because it exceeded its time budget, in
+	// which case the match counts are lower bounds.
+	budgetExceeded bool
+}
+
+func newSearchResults(sampleLimit int) *searchResults {
+	return &searchResults{
+		matchesPerRepo: map[api.RepoID]int{},
+		repoNames:      map[api.RepoID]string{},
+		sampleLimit:    sampleLimit,
+	}
+}
+
+// limitHit reports whether the search didn't search everything because it hit a limit, in which
+// case the match counts are lower bounds.
+func (r *searchResults) limitHit() bool {
+	for _, s := range r.skipped {
+		switch s.Reason {
+		case streamapi.DocumentMatchLimit, streamapi.ShardMatchLimit, streamapi.RepositoryLimit, streamapi.DisplayLimit:
+			return true
+		}
+	}
+	return false
+}
+
+// Dirty query reasons of data points whose value is a lower bound, because the search didn't
+// search everything.
+const (
+	limitHitReason           = "limit hit"
+	timeBudgetExceededReason = "time budget exceeded"
+	reposCloningReason       = "repositories cloning"
+	reposMissingReason       = "repositories missing"
+	reposTimedOutReason      = "repositories timed out"
+)
+
+// IsIncompleteDataReason reports whether the given dirty query reason means that the data point
+// it was recorded for was computed from partial data.
+func IsIncompleteDataReason(reason string) bool {
+	switch reason {
+	case limitHitReason, timeBudgetExceededReason, reposCloningReason, reposMissingReason, reposTimedOutReason:
+		return true
+	}
+	return false
+}
+
+// incompleteReasons returns the reasons why the match counts of the search results are lower
+// bounds, if any.
+func (r *searchResults) incompleteReasons() []string {
+	var reasons []string
+	if r.limitHit() {
+		reasons = append(reasons, limitHitReason)
+	}
+	if r.budgetExceeded {
+		reasons = append(reasons, timeBudgetExceededReason)
+	}
+	for _, s := range r.skipped {
+		switch s.Reason {
+		case streamapi.RepositoryCloning:
+			reasons = append(reasons, reposCloningReason)
+		case streamapi.RepositoryMissing:
+			reasons = append(reasons, reposMissingReason)
+		case streamapi.ShardTimeout:
+			reasons = append(reasons, reposTimedOutReason)
+		}
+	}
+	return reasons
+}
+
+func (r *searchResults) addMatches(matches []streamhttp.EventMatch) {
+	for _, match := range matches {
+		repoID, repoName, count := matchCount(match)
+		if repoName == "" {
+			continue
+		}
+		r.repoNames[repoID] = repoName
+		r.matchesPerRepo[repoID] += count
+	}
+	r.addSamples(matches)
+}
+
+// matchCount returns the repository of the match and the number of matches it represents, counted
+// in the same way as the match count of the search.
+func matchCount(match streamhttp.EventMatch) (api.RepoID, string, int) {
+	switch m := match.(type) {
+	case *streamhttp.EventContentMatch:
+		count := 0
+		for _, lm := range m.LineMatches {
+			count += len(lm.OffsetAndLengths)
+		}
+		if count == 0 {
+			count = 1
+		}
+		return api.RepoID(m.RepositoryID), m.Repository, count
+	case *streamhttp.EventPathMatch:
+		return api.RepoID(m.RepositoryID), m.Repository, 1
+	case *streamhttp.EventSymbolMatch:
+		count := len(m.Symbols)
+		if count == 0 {
+			count = 1
+		}
+		return api.RepoID(m.RepositoryID), m.Repository, count
+	case *streamhttp.EventCommitMatch:
+		// A commit matched by its message or diff content has one range per match, a commit
+		// matched by other fields such as its author has none.
+		count := len(m.Ranges)
+		if count == 0 {
+			count = 1
+		}
+		return api.RepoID(m.RepositoryID), m.Repository, count
+	case *streamhttp.EventRepoMatch:
+		return api.RepoID(m.RepositoryID), m.Repository, 1
+	}
+	return 0, "", 0
+}
+
+// sample is an example match, as recorded by addSamples.
+type sample struct {
+	repoID     api.RepoID
+	repoName   string
+	path       string
+	lineNumber *int32
+	preview    *string
+}
+
+// addSamples retains example matches until there are sampleLimit of them. Only file matches are
+// sampled, as the other match types have no file or line to show; files matched by path or by
+// symbols are sampled without a line.
+func (r *searchResults) addSamples(matches []streamhttp.EventMatch) {
+	for _, match := range matches {
+		if len(r.samples) >= r.sampleLimit {
+			return
+		}
+		switch m := match.(type) {
+		case *streamhttp.EventContentMatch:
+			if len(m.LineMatches) == 0 {
+				r.samples = append(r.samples, sample{repoID: api.RepoID(m.RepositoryID), repoName: m.Repository, path: m.Path})
+				continue
+			}
+			for i := range m.LineMatches {
+				if len(r.samples) >= r.sampleLimit {
+					return
+				}
+				lm := m.LineMatches[i]
+				r.samples = append(r.samples, sample{
+					repoID:     api.RepoID(m.RepositoryID),
+					repoName:   m.Repository,
+					path:       m.Path,
+					lineNumber: &lm.LineNumber,
+					preview:    &lm.Line,
+				})
+			}
+		case *streamhttp.EventPathMatch:
+			r.samples = append(r.samples, sample{repoID: api.RepoID(m.RepositoryID), repoName: m.Repository, path: m.Path})
+		case *streamhttp.EventSymbolMatch:
+			r.samples = append(r.samples, sample{repoID: api.RepoID(m.RepositoryID), repoName: m.Repository, path: m.Path})
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package queryrunner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/rehttp"
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var (
+	searchRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_insights_search_retries_total",
+		Help: "Total number of code insights search queries that were retried after a transient error.",
+	})
+	searchCircuitRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_insights_search_circuit_rejections_total",
+		Help: "Total number of code insights search queries that failed right away because the circuit breaker was open.",
+	})
+	searchCircuitOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_insights_search_circuit_open",
+		Help: "Whether the circuit breaker of code insights search queries is open (1) or closed (0).",
+	})
+)
+
+// errSearchCircuitOpen is returned instead of running a search while the circuit breaker is open.
+var errSearchCircuitOpen = errors.New("search circuit breaker is open: too many consecutive search failures")
+
+// transientSearchError wraps errors of a search that may succeed when retried, such as a network
+// error, a timeout or a server error.
+type transientSearchError struct {
+	err error
+}
+
+func (e *transientSearchError) Error() string { return e.err.Error() }
+func (e *transientSearchError) Unwrap() error { return e.err }
+
+func isTransientSearchError(err error) bool {
+	var e *transientSearchError
+	return errors.As(err, &e)
+}
+
+// Defaults of the insights.query.retry site configuration.
+const (
+	defaultSearchMaxAttempts             = 3
+	defaultSearchBackoffBase             = time.Second
+	defaultSearchBackoffMax              = 30 * time.Second
+	defaultSearchCircuitBreakerThreshold = 10
+	defaultSearchCircuitBreakerCooldown  = time.Minute
+)
+
+type searchRetryOptions struct {
+	maxAttempts             int
+	backoffBase             time.Duration
+	backoffMax              time.Duration
+	circuitBreakerThreshold int
+	circuitBreakerCooldown  time.Duration
+}
+
+// searchRetryOptionsFromConfig returns the retry options of the given site configuration, using
+// the defaults for anything that is unset or invalid.
+func searchRetryOptionsFromConfig(c *schema.InsightsQueryRetry) searchRetryOptions {
+	opts := searchRetryOptions{
+		maxAttempts:             defaultSearchMaxAttempts,
+		backoffBase:             defaultSearchBackoffBase,
+		backoffMax:              defaultSearchBackoffMax,
+		circuitBreakerThreshold: defaultSearchCircuitBreakerThreshold,
+		circuitBreakerCooldown:  defaultSearchCircuitBreakerCooldown,
+	}
+	if c == nil {
+		return opts
+	}
+
+	if c.MaxAttempts > 0 {
+		opts.maxAttempts = c.MaxAttempts
+	}
+	if c.CircuitBreakerThreshold != nil && *c.CircuitBreakerThreshold >= 0 {
+		opts.circuitBreakerThreshold = *c.CircuitBreakerThreshold
+	}
+	parseDuration := func(name, value string, d *time.Duration) {
+		if value == "" {
+			return
+		}
+		v, err := time.ParseDuration(value)
+		if err != nil || v <= 0 {
+			log15.Warn("insights.queryrunner: invalid insights.query.retry duration, using the default", "field", name, "value", value, "default", *d)
+			return
+		}
+		*d = v
+	}
+	parseDuration("backoffBase", c.BackoffBase, &opts.backoffBase)
+	parseDuration("backoffMax", c.BackoffMax, &opts.backoffMax)
+	parseDuration("circuitBreakerCooldown", c.CircuitBreakerCooldown, &opts.circuitBreakerCooldown)
+	if opts.backoffMax < opts.backoffBase {
+		opts.backoffMax = opts.backoffBase
+	}
+	return opts
+}
+
+// searchRetrier retries searches that fail with a transient error, with exponential backoff and
+// jitter between attempts. It also acts as a circuit breaker: after too many consecutive failed
+// attempts, searches fail right away for a cooldown period instead of piling up more load on a
+// search backend that is unhealthy. The failed jobs are then retried by the worker later on.
+type searchRetrier struct {
+	options func() searchRetryOptions
+	now     func() time.Time
+	sleep   func(context.Context, time.Duration) error
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+}
+
+func newSearchRetrier() *searchRetrier {
+	return &searchRetrier{
+		options: func() searchRetryOptions {
+			return searchRetryOptionsFromConfig(conf.Get().InsightsQueryRetry)
+		},
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+// do calls search until it succeeds, fails with an error that is not transient, or the maximum
+// number of attempts is reached. search is either a search or a compute query.
+func (r *searchRetrier) do(ctx context.Context, search func(context.Context) error) error {
+	opts := r.options()
+	delay := httpcli.ExpJitterDelay(opts.backoffBase, opts.backoffMax)
+
+	for attempt := 0; ; attempt++ {
+		if err := r.allow(opts); err != nil {
+			return err
+		}
+
+		err := search(ctx)
+		if open := r.record(opts, err); open || err == nil || !isTransientSearchError(err) || ctx.Err() != nil || attempt+1 >= opts.maxAttempts {
+			return err
+		}
+
+		wait := delay(rehttp.Attempt{Index: attempt})
+		log15.Warn("insights.queryrunner: retrying search after transient error", "attempt", attempt+1, "wait", wait, "error", err)
+		searchRetries.Inc()
+		if err := r.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// allow returns errSearchCircuitOpen if the circuit breaker is open.
+func (r *searchRetrier) allow(opts searchRetryOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if opts.circuitBreakerThreshold > 0 && r.now().Before(r.openUntil) {
+		searchCircuitRejections.Inc()
+		return errSearchCircuitOpen
+	}
+	searchCircuitOpen.Set(0)
+	return nil
+}
+
+// record updates the circuit breaker with the outcome of a search attempt and reports whether the
+// circuit is now open. Only transient errors count as failures, as other errors such as invalid
+// queries say nothing about the health of the search backend. Once the threshold was reached,
+// every further failure opens the circuit again until a search succeeds.
+func (r *searchRetrier) record(opts searchRetryOptions, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		r.consecutiveFailures = 0
+		return false
+	}
+	if !isTransientSearchError(err) {
+		return false
+	}
+
+	r.consecutiveFailures++
+	if opts.circuitBreakerThreshold > 0 && r.consecutiveFailures >= opts.circuitBreakerThreshold {
+		r.openUntil = r.now().Add(opts.circuitBreakerCooldown)
+		searchCircuitOpen.Set(1)
+		log15.Warn("insights.queryrunner: opening search circuit breaker", "consecutiveFailures", r.consecutiveFailures, "cooldown", opts.circuitBreakerCooldown)
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+package queryrunner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+func newTestSearchRetrier(opts searchRetryOptions) (*searchRetrier, *time.Time) {
+	now := time.Now()
+	return &searchRetrier{
+		options: func() searchRetryOptions { return opts },
+		now:     func() time.Time { return now },
+		sleep:   func(context.Context, time.Duration) error { return nil },
+	}, &now
+}
+
+func TestSearchRetrierRetriesTransientErrors(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "event: matches\ndata: [{\"type\":\"repo\",\"repositoryID\":1,\"repository\":\"r\"}]\n\nevent: done\ndata: {}\n\n")
+	}))
+	defer srv.Close()
+
+	retrier, _ := newTestSearchRetrier(searchRetryOptionsFromConfig(nil))
+	credentials := &Credentials{URL: srv.URL, Doer: srv.Client()}
+	var res *searchResults
+	err := retrier.do(context.Background(), func(ctx context.Context) (err error) {
+		res, err = search(ctx, credentials, "foo", "", 0, 0)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := res.matchesPerRepo[1], 1; have != want {
+		t.Fatalf("wrong match count. want=%d, have=%d", want, have)
+	}
+	if have, want := requests, 2; have != want {
+		t.Fatalf("wrong number of requests. want=%d, have=%d", want, have)
+	}
+}
+
+func TestSearchRetrierDoesNotRetryOtherErrors(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	retrier, _ := newTestSearchRetrier(searchRetryOptionsFromConfig(nil))
+	credentials := &Credentials{URL: srv.URL, Doer: srv.Client()}
+	err := retrier.do(context.Background(), func(ctx context.Context) error {
+		_, err := search(ctx, credentials, "foo", "", 0, 0)
+		return err
+	})
+	if err == nil || isTransientSearchError(err) {
+		t.Fatalf("want non-transient error, have %v", err)
+	}
+	if have, want := requests, 1; have != want {
+		t.Fatalf("wrong number of requests. want=%d, have=%d", want, have)
+	}
+}
+
+func TestSearchRetrierCircuitBreaker(t *testing.T) {
+	retrier, now := newTestSearchRetrier(searchRetryOptions{
+		maxAttempts:             2,
+		circuitBreakerThreshold: 3,
+		circuitBreakerCooldown:  time.Minute,
+	})
+
+	var attempts int
+	fail := func(context.Context) error {
+		attempts++
+		return &transientSearchError{errors.New("unavailable")}
+	}
+	succeed := func(context.Context) error {
+		attempts++
+		return nil
+	}
+
+	// Two failed attempts of the first search, one of the second opens the circuit.
+	for i := 0; i < 2; i++ {
+		if err := retrier.do(context.Background(), fail); !isTransientSearchError(err) {
+			t.Fatalf("want transient error, have %v", err)
+		}
+	}
+	if have, want := attempts, 3; have != want {
+		t.Fatalf("wrong number of attempts. want=%d, have=%d", want, have)
+	}
+	if err := retrier.do(context.Background(), succeed); err != errSearchCircuitOpen {
+		t.Fatalf("want errSearchCircuitOpen, have %v", err)
+	}
+	if have, want := attempts, 3; have != want {
+		t.Fatalf("search attempted while the circuit was open. want=%d attempts, have=%d", want, have)
+	}
+
+	// After the cooldown, a single failure opens the circuit again, without retrying.
+	*now = now.Add(2 * time.Minute)
+	if err := retrier.do(context.Background(), fail); !isTransientSearchError(err) {
+		t.Fatalf("want transient error, have %v", err)
+	}
+	if err := retrier.do(context.Background(), succeed); err != errSearchCircuitOpen {
+		t.Fatalf("want errSearchCircuitOpen, have %v", err)
+	}
+	if have, want := attempts, 4; have != want {
+		t.Fatalf("wrong number of attempts. want=%d, have=%d", want, have)
+	}
+
+	// A success closes the circuit.
+	*now = now.Add(2 * time.Minute)
+	if err := retrier.do(context.Background(), succeed); err != nil {
+		t.Fatal(err)
+	}
+	if err := retrier.do(context.Background(), fail); !isTransientSearchError(err) {
+		t.Fatalf("want transient error, have %v", err)
+	}
+	if have, want := attempts, 7; have != want {
+		t.Fatalf("wrong number of attempts. want=%d, have=%d", want, have)
+	}
+}
@@ -0,0 +1,156 @@
+package queryrunner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestSearchCredentials(t *testing.T) {
+	var gotAuthorization, gotPath, gotQuery, gotPatternType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query().Get("q")
+		gotPatternType = r.URL.Query().Get("t")
+
+		if gotAuthorization != "token valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "event: matches\ndata: [{\"type\":\"repo\",\"repositoryID\":1,\"repository\":\"r\"}]\n\nevent: done\ndata: {}\n\n")
+	}))
+	defer srv.Close()
+
+	credentials := &Credentials{URL: srv.URL + "/.api", Token: "valid", Doer: srv.Client()}
+	res, err := search(context.Background(), credentials, "foo", "structural", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := res.matchesPerRepo[1], 1; have != want {
+		t.Fatalf("wrong match count. want=%d, have=%d", want, have)
+	}
+	if have, want := gotPath, "/.api/search/stream"; have != want {
+		t.Fatalf("wrong path. want=%q, have=%q", want, have)
+	}
+	if have, want := gotQuery, "foo"; have != want {
+		t.Fatalf("wrong query. want=%q, have=%q", want, have)
+	}
+	if have, want := gotPatternType, "structural"; have != want {
+		t.Fatalf("wrong pattern type. want=%q, have=%q", want, have)
+	}
+
+	credentials.Token = "expired"
+	if _, err := search(context.Background(), credentials, "foo", "", 0, 0); !errors.Is(err, errCredentialsRejected) {
+		t.Fatalf("want errCredentialsRejected, have %v", err)
+	}
+	if have, want := gotAuthorization, "token expired"; have != want {
+		t.Fatalf("wrong authorization header. want=%q, have=%q", want, have)
+	}
+}
+
+func TestSearchAggregatesResults(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `event: matches
+data: [{"type":"content","repositoryID":1,"repository":"a","path":"f.go","lineMatches":[{"line":"foo foo","lineNumber":3,"offsetAndLengths":[[0,3],[4,3]]}]},{"type":"path","repositoryID":2,"repository":"b","path":"foo.go"}]
+
+event: matches
+data: [{"type":"symbol","repositoryID":1,"repository":"a","path":"g.go","symbols":[{"name":"foo"},{"name":"Foo"}]},{"type":"commit","repositoryID":2,"repository":"b","ranges":[[1,0,3]]},{"type":"repo","repositoryID":3,"repository":"foo"}]
+
+event: progress
+data: {"done":true,"matchCount":8,"skipped":[{"reason":"shard-match-limit","title":"result limit hit"}]}
+
+event: done
+data: {}
+
+`)
+	}))
+	defer srv.Close()
+
+	credentials := &Credentials{URL: srv.URL, Doer: srv.Client()}
+	res, err := search(context.Background(), credentials, "foo", "", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[api.RepoID]int{1: 4, 2: 2, 3: 1}, res.matchesPerRepo); diff != "" {
+		t.Errorf("unexpected match counts (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[api.RepoID]string{1: "a", 2: "b", 3: "foo"}, res.repoNames); diff != "" {
+		t.Errorf("unexpected repository names (-want +got):\n%s", diff)
+	}
+	if len(res.samples) != 2 || res.samples[0].path != "f.go" || *res.samples[0].lineNumber != 3 || res.samples[1].path != "foo.go" {
+		t.Errorf("unexpected samples %+v", res.samples)
+	}
+	if diff := cmp.Diff([]string{limitHitReason}, res.incompleteReasons()); diff != "" {
+		t.Errorf("unexpected incomplete reasons (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchTimeBudget(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: matches\ndata: [{\"type\":\"repo\",\"repositoryID\":1,\"repository\":\"r\"}]\n\n")
+		w.(http.Flusher).Flush()
+		// Keep streaming until the client gives up.
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	credentials := &Credentials{URL: srv.URL, Doer: srv.Client()}
+	res, err := search(context.Background(), credentials, "foo", "", 0, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.budgetExceeded {
+		t.Fatal("want budget to be exceeded")
+	}
+	if have, want := res.matchesPerRepo[1], 1; have != want {
+		t.Fatalf("want the matches found before the budget was exceeded. want=%d, have=%d", want, have)
+	}
+	if diff := cmp.Diff([]string{timeBudgetExceededReason}, res.incompleteReasons()); diff != "" {
+		t.Errorf("unexpected incomplete reasons (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchIncompleteRepositories(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `event: progress
+data: {"done":true,"matchCount":0,"skipped":[{"reason":"repository-cloning","title":"2 cloning"},{"reason":"repository-missing","title":"1 missing"},{"reason":"shard-timeout","title":"1 timed out"},{"reason":"repository-fork","title":"3 forks"}]}
+
+event: done
+data: {}
+
+`)
+	}))
+	defer srv.Close()
+
+	credentials := &Credentials{URL: srv.URL, Doer: srv.Client()}
+	res, err := search(context.Background(), credentials, "foo", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{reposCloningReason, reposMissingReason, reposTimedOutReason}
+	if diff := cmp.Diff(want, res.incompleteReasons()); diff != "" {
+		t.Errorf("unexpected incomplete reasons (-want +got):\n%s", diff)
+	}
+	for _, reason := range want {
+		if !IsIncompleteDataReason(reason) {
+			t.Errorf("expected %q to be an incomplete data reason", reason)
+		}
+	}
+	if IsIncompleteDataReason(revisionUnavailableReason) {
+		t.Errorf("expected %q not to be an incomplete data reason", revisionUnavailableReason)
+	}
+}
@@ -0,0 +1,44 @@
+package queryrunner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+)
+
+// teamJob returns the job restricted to the repositories owned by the team of the series, which
+// are looked up when the job runs, or nil if the team owns no repositories. It returns the job as
+// is if the series isn't scoped to a team.
+//
+// Historical jobs already search a single repository, which the enqueuers only enqueue jobs for
+// if the team owns it, so only live jobs are restricted.
+func (r *workHandler) teamJob(ctx context.Context, job *Job, series *types.InsightSeries) (*Job, error) {
+	if series.Team == "" || job.RecordTime != nil {
+		return job, nil
+	}
+
+	repoNames, err := r.metadadataStore.GetTeamRepositories(ctx, series.Team)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetTeamRepositories")
+	}
+	if len(repoNames) == 0 {
+		return nil, nil
+	}
+	teamJob := *job
+	teamJob.SearchQuery = teamQuery(job.SearchQuery, repoNames)
+	return &teamJob, nil
+}
+
+// teamQuery returns the query restricted to the given repositories.
+func teamQuery(query string, repoNames []string) string {
+	patterns := make([]string, 0, len(repoNames))
+	for _, repoName := range repoNames {
+		patterns = append(patterns, regexp.QuoteMeta(repoName))
+	}
+	return fmt.Sprintf("%s repo:^(%s)$", query, strings.Join(patterns, "|"))
+}
@@ -0,0 +1,13 @@
+package queryrunner
+
+import (
+	"testing"
+)
+
+func TestTeamQuery(t *testing.T) {
+	got := teamQuery("fmt.Errorf count:all", []string{"github.com/golang/go", "github.com/sourcegraph/src-cli"})
+	want := `fmt.Errorf count:all repo:^(github\.com/golang/go|github\.com/sourcegraph/src-cli)$`
+	if got != want {
+		t.Errorf("got query %q, want %q", got, want)
+	}
+}
@@ -2,7 +2,6 @@ package queryrunner
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
@@ -17,11 +16,12 @@ import (
 	"golang.org/x/time/rate"
 
 	"github.com/cockroachdb/errors"
-	"github.com/graph-gophers/graphql-go"
 	"github.com/inconshreveable/log15"
+	"github.com/opentracing/opentracing-go/log"
 
-	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
 	"github.com/sourcegraph/sourcegraph/internal/workerutil"
 )
 
@@ -34,6 +34,9 @@ type workHandler struct {
 	insightsStore   *store.Store
 	metadadataStore *store.InsightStore
 	limiter         *rate.Limiter
+	authenticator   Authenticator
+	retrier         *searchRetrier
+	operations      *operations
 
 	mu          sync.RWMutex
 	seriesCache map[string]*types.InsightSeries
@@ -80,7 +83,7 @@ func (r *workHandler) Handle(ctx context.Context, record workerutil.Record) (err
 	if err != nil {
 		return err
 	}
-	job, err := dequeueJob(ctx, r.baseWorkerStore, record.RecordID())
+	job, err := r.dequeueJob(ctx, record.RecordID())
 	if err != nil {
 		return err
 	}
@@ -92,24 +95,59 @@ func (r *workHandler) Handle(ctx context.Context, record workerutil.Record) (err
 		return err
 	}
 
+	// Language-stats series don't have a search query, their statistics are computed from the
+	// contents of the repositories.
+	if series.GenerationMethod == types.GenerationMethodLanguageStats {
+		return r.handleLanguageStats(ctx, job, series)
+	}
+
+	// Series queries are validated when the series is created, but series created before that
+	// validation existed may still contain invalid queries. Those would fail on every retry, so
+	// fail the job right away instead.
+	if err := store.ValidateSeriesQuery(job.SearchQuery, job.PatternType); err != nil {
+		return err
+	}
+
+	job, err = r.teamJob(ctx, job, series)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		// The team owns no repositories, so there are no results to record.
+		log15.Warn("insights: team of series owns no repositories", "seriesID", series.SeriesID, "team", series.Team)
+		return nil
+	}
+
+	if series.GenerationMethod == types.GenerationMethodSearchCompute {
+		return r.handleCompute(ctx, job, series)
+	}
+
 	// Actually perform the search query.
 	//
-	// 🚨 SECURITY: The request is performed without authentication, we get back results from every
-	// repository on Sourcegraph - so we must be careful to only record insightful information that
-	// is OK to expose to every user on Sourcegraph (e.g. total result counts are fine, exposing
-	// that a repository exists may or may not be fine, exposing individual results is definitely
-	// not, etc.)
-	var results *gqlSearchResponse
-	results, err = search(ctx, job.SearchQuery)
+	// 🚨 SECURITY: With the InternalAuthenticator the request is performed without
+	// authentication, we get back results from every repository on Sourcegraph - so we must be
+	// careful to only record insightful information that is OK to expose to every user on
+	// Sourcegraph (e.g. total result counts are fine, exposing that a repository exists may or may
+	// not be fine, exposing individual results is definitely not, etc.)
+	recordTime := time.Now()
+	if job.RecordTime != nil {
+		recordTime = *job.RecordTime
+	}
+
+	sampleLimit := conf.Get().InsightsQuerySamples
+	timeBudget := time.Duration(conf.Get().InsightsQueryTimeBudget) * time.Second
+	var results *searchResults
+	if queries := revisionQueries(job, series); queries != nil {
+		results, err = r.searchRevisions(ctx, job, series, queries, recordTime, sampleLimit, timeBudget)
+	} else {
+		results, err = r.cachedSearch(ctx, job, series, recordTime, sampleLimit, timeBudget)
+	}
 	if err != nil {
 		return err
 	}
 
-	if len(results.Errors) > 0 {
-		return errors.Errorf("GraphQL errors: %v", results.Errors)
-	}
-	if alert := results.Data.Search.Results.Alert; alert != nil {
-		if alert.Title == "No repositories satisfied your repo: filter" {
+	if alert := results.alert; alert != nil {
+		if alert.Title == noRepositoriesAlertTitle {
 			// We got zero results and no repositories matched. This could be for a few reasons:
 			//
 			// 1. The repo hasn't been cloned by Sourcegraph yet.
@@ -121,31 +159,42 @@ func (r *workHandler) Handle(ctx context.Context, record workerutil.Record) (err
 			//
 			// In any case, this is not a problem - we want to record that we got zero results in
 			// general.
+		} else if job.RecordTime != nil && isRevisionUnavailableAlert(alert.Title) {
+			// This is a historical search for a revision that no longer exists in the repository,
+			// e.g. because its history was rewritten by a force-push or the repository was
+			// migrated. Retrying won't help, so rather than failing the job (and leaving a hole in
+			// the backfill that is retried forever) we record why the point is missing and move on.
+			log15.Warn("insights query issue", "problem", revisionUnavailableReason, "query", job.SearchQuery)
+			dq := types.DirtyQuery{
+				Query:   job.SearchQuery,
+				ForTime: *job.RecordTime,
+				Reason:  revisionUnavailableReason,
+			}
+			if err := r.metadadataStore.InsertDirtyQuery(ctx, series, &dq); err != nil {
+				return errors.Wrap(err, "failed to write dirty query record")
+			}
+			return nil
 		} else {
 			// Maybe the user's search query is actually wrong.
 			return errors.Errorf("insights query issue: alert: %v query=%q", alert, job.SearchQuery)
 		}
 	}
-	if results.Data.Search.Results.LimitHit {
-		log15.Error("insights query issue", "problem", "limit hit", "query", job.SearchQuery)
-		dq := types.DirtyQuery{
-			Query:   job.SearchQuery,
-			ForTime: *job.RecordTime,
-			Reason:  "limit hit",
-		}
-		if err := r.metadadataStore.InsertDirtyQuery(ctx, series, &dq); err != nil {
-			return errors.Wrap(err, "failed to write dirty query record")
+	// Points computed from partial data are flagged with dirty queries, so that they can be
+	// annotated when they are displayed. The dependent frames of the job are computed from the
+	// same search, so they are flagged too.
+	for _, reason := range results.incompleteReasons() {
+		log15.Error("insights query issue", "problem", reason, "query", job.SearchQuery)
+		for _, t := range append([]time.Time{recordTime}, job.DependentFrames...) {
+			dq := types.DirtyQuery{
+				Query:   job.SearchQuery,
+				ForTime: t,
+				Reason:  reason,
+			}
+			if err := r.metadadataStore.InsertDirtyQuery(ctx, series, &dq); err != nil {
+				return errors.Wrap(err, "failed to write dirty query record")
+			}
 		}
 	}
-	if cloning := len(results.Data.Search.Results.Cloning); cloning > 0 {
-		log15.Error("insights query issue", "cloning_repos", cloning, "query", job.SearchQuery)
-	}
-	if missing := len(results.Data.Search.Results.Missing); missing > 0 {
-		log15.Error("insights query issue", "missing_repos", missing, "query", job.SearchQuery)
-	}
-	if timedout := len(results.Data.Search.Results.Timedout); timedout > 0 {
-		log15.Error("insights query issue", "timedout_repos", timedout, "query", job.SearchQuery)
-	}
 
 	// 🚨 SECURITY: The request is performed without authentication, we get back results from every
 	// repository on Sourcegraph - so we must be careful to only record insightful information that
@@ -153,23 +202,195 @@ func (r *workHandler) Handle(ctx context.Context, record workerutil.Record) (err
 	// that a repository exists may just barely be fine, exposing individual results is definitely
 	// not, etc.) OR record only data that we later restrict to only users who have access to those
 	// repositories.
+	if err := r.recordResults(ctx, job, series, recordTime, results); err != nil {
+		return err
+	}
+
+	// Snapshots are replaced continuously, so only recorded points retain samples.
+	if sampleLimit > 0 && job.PersistMode == string(store.RecordMode) {
+		// The points have been recorded, so failing the job now would only record them again on
+		// retry. Samples are a nice-to-have, so we log and move on instead.
+		if err := r.recordSamples(ctx, job, recordTime, results.samples, sampleLimit); err != nil {
+			log15.Warn("insights: failed to record samples", "seriesID", job.SeriesID, "error", err)
+		}
+	}
+	return nil
+}
+
+// handleCompute executes the compute query of a job of a series that records one point per
+// captured value, and records the number of times each value was captured in each repository.
+//
+// 🚨 SECURITY: Like search results, the captured values of every repository are recorded, and the
+// repository permissions are enforced when the points are read.
+func (r *workHandler) handleCompute(ctx context.Context, job *Job, series *types.InsightSeries) error {
+	var (
+		results *computeResults
+		err     error
+	)
+	if queries := revisionQueries(job, series); queries != nil {
+		results, err = r.computeRevisions(ctx, job, queries)
+	} else {
+		results, err = r.compute(ctx, job)
+	}
+	if err != nil {
+		return err
+	}
+
 	recordTime := time.Now()
 	if job.RecordTime != nil {
 		recordTime = *job.RecordTime
 	}
+	return r.recordCapturedResults(ctx, job, series, recordTime, results)
+}
 
-	// Figure out how many matches we got for every unique repository returned in the search
-	// results.
-	matchesPerRepo := make(map[string]int, len(results.Data.Search.Results.Results)*4)
-	repoNames := make(map[string]string, len(matchesPerRepo))
-	for _, result := range results.Data.Search.Results.Results {
-		decoded, err := decodeResult(result)
+// recordCapturedResults records the captured values we got, one data point per-repository and
+// per-value.
+func (r *workHandler) recordCapturedResults(ctx context.Context, job *Job, series *types.InsightSeries, recordTime time.Time, results *computeResults) (err error) {
+	ctx, endObservation := r.operations.record.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("seriesID", job.SeriesID),
+		log.Int("numRepos", len(results.valuesPerRepo)),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	tx, err := r.insightsStore.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if job.PersistMode == string(store.SnapshotMode) {
+		if err := tx.DeleteSnapshots(ctx, series); err != nil {
+			return err
+		}
+	}
+
+	for repoID, values := range results.valuesPerRepo {
+		for _, t := range append([]time.Time{recordTime}, job.DependentFrames...) {
+			if recordErr := tx.RecordCapturedSeriesPoints(ctx, store.RecordCapturedSeriesPointsArgs{
+				SeriesID:    job.SeriesID,
+				Time:        t,
+				RepoName:    results.repoNames[repoID],
+				RepoID:      repoID,
+				Values:      values,
+				PersistMode: store.PersistMode(job.PersistMode),
+			}); recordErr != nil {
+				err = multierror.Append(err, errors.Wrap(recordErr, "RecordCapturedSeriesPoints"))
+			}
+		}
+	}
+	return err
+}
+
+// noRepositoriesAlertTitle is the title of the search alert returned when no repository matches
+// the repo: filters of a query.
+const noRepositoriesAlertTitle = "No repositories satisfied your repo: filter"
+
+// revisionUnavailableReason is the dirty query reason recorded for historical data points that
+// could not be computed because the revision they were to be computed at no longer exists.
+const revisionUnavailableReason = "revision unavailable"
+
+// isRevisionUnavailableAlert reports whether a search alert with the given title indicates that
+// the repository matched by the query does not contain the searched revision. Historical queries
+// search exactly one repo@revision, so for them this alert means the revision is gone.
+func isRevisionUnavailableAlert(title string) bool {
+	return title == "Some repositories could not be searched"
+}
+
+func (r *workHandler) dequeueJob(ctx context.Context, recordID int) (_ *Job, err error) {
+	ctx, endObservation := r.operations.dequeue.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.Int("recordID", recordID),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	return dequeueJob(ctx, r.baseWorkerStore, recordID)
+}
+
+func (r *workHandler) search(ctx context.Context, job *Job, sampleLimit int, timeBudget time.Duration) (_ *searchResults, err error) {
+	ctx, endObservation := r.operations.search.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("query", job.SearchQuery),
+		log.String("patternType", job.PatternType),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	var results *searchResults
+	err = r.retrier.do(ctx, func(ctx context.Context) error {
+		credentials, err := r.authenticator.Credentials(ctx, job)
 		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf(`for query "%s"`, job.SearchQuery))
+			return errors.Wrap(err, "getting search credentials")
+		}
+
+		results, err = search(ctx, credentials, job.SearchQuery, job.PatternType, sampleLimit, timeBudget)
+		if errors.Is(err, errCredentialsRejected) {
+			// The job is retried, give the authenticator a chance to rotate the credentials first.
+			r.authenticator.Rejected(ctx, job, credentials)
+		}
+		return err
+	})
+	return results, err
+}
+
+// cachedSearch returns the results of the search of the job from the query cache if the results of
+// an equivalent query in the same time bucket are cached, and otherwise performs the search and
+// caches its results. Failing to read or write the cache doesn't fail the job.
+func (r *workHandler) cachedSearch(ctx context.Context, job *Job, series *types.InsightSeries, recordTime time.Time, sampleLimit int, timeBudget time.Duration) (*searchResults, error) {
+	opts := queryCacheOptionsFromConfig(conf.Get().InsightsQueryCache)
+	if !opts.enabled {
+		return r.search(ctx, job, sampleLimit, timeBudget)
+	}
+
+	key := queryCacheKey(job.SearchQuery, job.PatternType)
+	bucket := recordTime.Truncate(opts.bucket)
+	cached, ok, err := getCachedSearchResults(ctx, r.baseWorkerStore, key, bucket, time.Now().Add(-opts.ttl), sampleLimit)
+	if err != nil {
+		log15.Warn("insights: failed to read the query cache", "query", job.SearchQuery, "error", err)
+	} else if ok {
+		queryCacheHits.Inc()
+		return cached, nil
+	}
+	queryCacheMisses.Inc()
+
+	results, err := r.search(ctx, job, sampleLimit, timeBudget)
+	if err != nil {
+		return nil, err
+	}
+	if isCacheable(results) {
+		seriesKey := queryCacheKey(series.Query, series.PatternType)
+		if err := putCachedSearchResults(ctx, r.baseWorkerStore, key, seriesKey, bucket, results); err != nil {
+			log15.Warn("insights: failed to write the query cache", "query", job.SearchQuery, "error", err)
 		}
-		repoNames[decoded.repoID()] = decoded.repoName()
-		matchesPerRepo[decoded.repoID()] = matchesPerRepo[decoded.repoID()] + decoded.matchCount()
 	}
+	return results, nil
+}
+
+func (r *workHandler) compute(ctx context.Context, job *Job) (_ *computeResults, err error) {
+	ctx, endObservation := r.operations.compute.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("query", job.SearchQuery),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	var results *computeResults
+	err = r.retrier.do(ctx, func(ctx context.Context) error {
+		credentials, err := r.authenticator.Credentials(ctx, job)
+		if err != nil {
+			return errors.Wrap(err, "getting search credentials")
+		}
+
+		results, err = compute(ctx, credentials, job.SearchQuery)
+		if errors.Is(err, errCredentialsRejected) {
+			r.authenticator.Rejected(ctx, job, credentials)
+		}
+		return err
+	})
+	return results, err
+}
+
+// recordResults records the number of results we got, one data point per-repository.
+func (r *workHandler) recordResults(ctx context.Context, job *Job, series *types.InsightSeries, recordTime time.Time, results *searchResults) (err error) {
+	ctx, endObservation := r.operations.record.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("seriesID", job.SeriesID),
+		log.Int("numRepos", len(results.matchesPerRepo)),
+	}})
+	defer endObservation(1, observation.Args{})
 
 	tx, err := r.insightsStore.Transact(ctx)
 	if err != nil {
@@ -185,21 +406,8 @@ func (r *workHandler) Handle(ctx context.Context, record workerutil.Record) (err
 		}
 	}
 
-	// Record the number of results we got, one data point per-repository.
-	for graphQLRepoID, matchCount := range matchesPerRepo {
-		dbRepoID, idErr := graphqlbackend.UnmarshalRepositoryID(graphql.ID(graphQLRepoID))
-		if idErr != nil {
-			err = multierror.Append(err, errors.Wrap(idErr, "UnmarshalRepositoryID"))
-			continue
-		}
-		repoName := repoNames[graphQLRepoID]
-		if len(repoName) == 0 {
-			// this really should never happen, expect if for some reason the gql response is broken
-			err = multierror.Append(err, errors.Newf("MissingRepositoryName for repo_id: %v", string(dbRepoID)))
-			continue
-		}
-
-		args := ToRecording(job, float64(matchCount), recordTime, repoName, dbRepoID)
+	for repoID, matchCount := range results.matchesPerRepo {
+		args := ToRecording(job, float64(matchCount), recordTime, results.repoNames[repoID], repoID)
 		if recordErr := tx.RecordSeriesPoints(ctx, args); recordErr != nil {
 			err = multierror.Append(err, errors.Wrap(recordErr, "RecordSeriesPoints"))
 		}
@@ -207,6 +415,38 @@ func (r *workHandler) Handle(ctx context.Context, record workerutil.Record) (err
 	return err
 }
 
+// recordSamples records up to limit example matches from the search results for the data points
+// recorded by the job. The permissions of the samples are enforced when they are read.
+func (r *workHandler) recordSamples(ctx context.Context, job *Job, recordTime time.Time, sampled []sample, limit int) error {
+	if len(sampled) == 0 {
+		return nil
+	}
+
+	samples := make([]store.SeriesPointSample, 0, len(sampled))
+	for _, s := range sampled {
+		samples = append(samples, store.SeriesPointSample{
+			RepoID:     s.repoID,
+			RepoName:   s.repoName,
+			Path:       s.path,
+			LineNumber: s.lineNumber,
+			Preview:    s.preview,
+		})
+	}
+
+	// The dependent frames share the value of the job's data point, and thus its samples.
+	for _, t := range append([]time.Time{recordTime}, job.DependentFrames...) {
+		if err := r.insightsStore.RecordSeriesPointSamples(ctx, store.RecordSeriesPointSamplesArgs{
+			SeriesID: job.SeriesID,
+			Time:     t,
+			Samples:  samples,
+			Limit:    limit,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ToRecording(record *Job, value float64, recordTime time.Time, repoName string, repoID api.RepoID) []store.RecordSeriesPointArgs {
 	args := make([]store.RecordSeriesPointArgs, 0, len(record.DependentFrames)+1)
 	base := store.RecordSeriesPointArgs{
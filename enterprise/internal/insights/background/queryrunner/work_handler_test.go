@@ -0,0 +1,20 @@
+package queryrunner
+
+import "testing"
+
+func TestIsRevisionUnavailableAlert(t *testing.T) {
+	for title, want := range map[string]bool{
+		// Returned for queries searching a repo@revision that doesn't exist.
+		"Some repositories could not be searched": true,
+
+		"":                       false,
+		noRepositoriesAlertTitle: false,
+		"No repositories found":  false,
+		"Search timed out":       false,
+		"some repositories could not be searched": false,
+	} {
+		if have := isRevisionUnavailableAlert(title); have != want {
+			t.Errorf("isRevisionUnavailableAlert(%q) = %t, want %t", title, have, want)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/opentracing/opentracing-go/log"
+
 	"github.com/sourcegraph/sourcegraph/internal/observation"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,8 +40,9 @@ import (
 //
 
 // NewWorker returns a worker that will execute search queries and insert information about the
-// results into the code insights database.
-func NewWorker(ctx context.Context, workerStore dbworkerstore.Store, insightsStore *store.Store, metrics workerutil.WorkerMetrics) *workerutil.Worker {
+// results into the code insights database. The authenticator decides which API the queries are
+// executed against, and with which credentials.
+func NewWorker(ctx context.Context, workerStore dbworkerstore.Store, insightsStore *store.Store, authenticator Authenticator, metrics workerutil.WorkerMetrics, observationContext *observation.Context) *workerutil.Worker {
 	numHandlers := conf.Get().InsightsQueryWorkerConcurrency
 	if numHandlers <= 0 {
 		numHandlers = 1
@@ -82,8 +85,11 @@ func NewWorker(ctx context.Context, workerStore dbworkerstore.Store, insightsSto
 		baseWorkerStore: basestore.NewWithDB(workerStore.Handle().DB(), sql.TxOptions{}),
 		insightsStore:   insightsStore,
 		limiter:         limiter,
+		authenticator:   authenticator,
+		retrier:         newSearchRetrier(),
 		metadadataStore: store.NewInsightStore(insightsStore.Handle().DB()),
 		seriesCache:     sharedCache,
+		operations:      newOperations(observationContext),
 	}, options)
 }
 
@@ -125,11 +131,13 @@ func CreateDBWorkerStore(s *basestore.Store, observationContext *observation.Con
 
 		// If you change this, be sure to adjust the interval that work is enqueued in
 		// enterprise/internal/insights/background:newInsightEnqueuer.
-		StalledMaxAge:     60 * time.Second,
-		RetryAfter:        30 * time.Minute,
-		MaxNumRetries:     100,
-		MaxNumResets:      10,
-		OrderByExpression: sqlf.Sprintf("priority, id"),
+		StalledMaxAge: 60 * time.Second,
+		RetryAfter:    30 * time.Minute,
+		MaxNumRetries: 100,
+		MaxNumResets:  10,
+
+		// Within a priority, jobs are dequeued round-robin across series. See EnqueueJob.
+		OrderByExpression: sqlf.Sprintf("priority, series_turn, id"),
 	}, observationContext)
 }
 
@@ -179,8 +187,41 @@ const insertJobDependencies = `
 -- source: enterprise/internal/insights/background/queryrunner/worker.go:insertDependencies
 INSERT INTO insights_query_runner_jobs_dependencies (job_id, recording_time) VALUES %s;`
 
-// EnqueueJob enqueues a job for the query runner worker to execute later.
+// Enqueuer enqueues jobs for the query runner worker, tracing and recording metrics for each
+// enqueue operation.
+type Enqueuer struct {
+	workerBaseStore *basestore.Store
+	operations      *operations
+}
+
+// NewEnqueuer returns an Enqueuer that writes jobs to the given worker store.
+func NewEnqueuer(workerBaseStore *basestore.Store, observationContext *observation.Context) *Enqueuer {
+	return &Enqueuer{
+		workerBaseStore: workerBaseStore,
+		operations:      newOperations(observationContext),
+	}
+}
+
+// Enqueue enqueues a job for the query runner worker to execute later.
+func (e *Enqueuer) Enqueue(ctx context.Context, job *Job) (id int, err error) {
+	ctx, endObservation := e.operations.enqueue.With(ctx, &err, observation.Args{LogFields: []log.Field{
+		log.String("seriesID", job.SeriesID),
+		log.String("persistMode", job.PersistMode),
+		log.Int("priority", job.Priority),
+	}})
+	defer endObservation(1, observation.Args{})
+
+	return EnqueueJob(ctx, e.workerBaseStore, job)
+}
+
+// EnqueueJob enqueues a job for the query runner worker to execute later. Jobs without a pattern
+// type are literal.
 func EnqueueJob(ctx context.Context, workerBaseStore *basestore.Store, job *Job) (id int, err error) {
+	patternType := job.PatternType
+	if patternType == "" {
+		patternType = types.PatternTypeLiteral
+	}
+
 	tx, err := workerBaseStore.Transact(ctx)
 	if err != nil {
 		return 0, err
@@ -199,6 +240,8 @@ func EnqueueJob(ctx context.Context, workerBaseStore *basestore.Store, job *Job)
 			job.Cost,
 			job.Priority,
 			job.PersistMode,
+			patternType,
+			job.SeriesID,
 		),
 	))
 	if err != nil {
@@ -221,8 +264,18 @@ INSERT INTO insights_query_runner_jobs (
 	process_after,
 	cost,
 	priority,
-	persist_mode
-) VALUES (%s, %s, %s, %s, %s, %s, %s, %s)
+	persist_mode,
+	pattern_type,
+	series_turn
+) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, (
+	-- Jobs of a series take consecutive turns, starting at the current turn of the queue, so that
+	-- the jobs of series that enqueue many jobs at once (e.g. backfills) are interleaved with the
+	-- jobs of the other series instead of starving them.
+	SELECT GREATEST(
+		COALESCE((SELECT MIN(series_turn) FROM insights_query_runner_jobs WHERE state = 'queued'), 0),
+		COALESCE((SELECT MAX(series_turn) + 1 FROM insights_query_runner_jobs WHERE state = 'queued' AND series_id = %s), 0)
+	)
+))
 RETURNING id
 `
 
@@ -264,6 +317,7 @@ SELECT
 	cost,
 	priority,
 	persist_mode,
+	pattern_type,
 	id,
 	state,
 	failure_message,
@@ -326,6 +380,7 @@ type Job struct {
 	Cost        int
 	Priority    int
 	PersistMode string
+	PatternType string // The pattern type (literal, regexp or structural) of SearchQuery.
 
 	DependentFrames []time.Time // This field isn't part of the job table, but maps to a table one-many on this job.
 
@@ -373,6 +428,7 @@ func doScanJobs(rows *sql.Rows, err error) ([]*Job, error) {
 			&j.Cost,
 			&j.Priority,
 			&j.PersistMode,
+			&j.PatternType,
 
 			// Standard/required dbworker fields.
 			&j.ID,
@@ -406,6 +462,7 @@ var jobsColumns = []*sqlf.Query{
 	sqlf.Sprintf("insights_query_runner_jobs.cost"),
 	sqlf.Sprintf("insights_query_runner_jobs.priority"),
 	sqlf.Sprintf("insights_query_runner_jobs.persist_mode"),
+	sqlf.Sprintf("insights_query_runner_jobs.pattern_type"),
 	sqlf.Sprintf("id"),
 	sqlf.Sprintf("state"),
 	sqlf.Sprintf("failure_message"),
@@ -10,6 +10,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
 
 	"github.com/hexops/autogold"
+	"github.com/keegancsmith/sqlf"
 
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
@@ -62,6 +63,7 @@ func TestJobQueue(t *testing.T) {
 	autogold.Want("2", &Job{
 		SeriesID: "job 1", SearchQuery: "our search 1",
 		PersistMode:     "record",
+		PatternType:     "literal",
 		DependentFrames: []time.Time{},
 		ID:              1,
 	}).Equal(t, firstJob)
@@ -72,6 +74,7 @@ func TestJobQueue(t *testing.T) {
 		DependentFrames: []time.Time{},
 		ID:              2,
 		PersistMode:     "record",
+		PatternType:     "literal",
 	}).Equal(t, secondJob)
 	autogold.Want("5", "<nil>").Equal(t, fmt.Sprint(err))
 }
@@ -102,6 +105,7 @@ func TestJobQueueDependencies(t *testing.T) {
 		autogold.Want("1", &Job{
 			SeriesID: "job 1", SearchQuery: "our search 1",
 			PersistMode:     "record",
+			PatternType:     "literal",
 			DependentFrames: []time.Time{},
 			ID:              1,
 		}).Equal(t, got)
@@ -126,3 +130,37 @@ func TestJobQueueDependencies(t *testing.T) {
 		autogold.Equal(t, got, autogold.ExportedOnly())
 	})
 }
+
+// TestJobQueueRoundRobin tests that the jobs of a series that enqueues many jobs at once are
+// interleaved with the jobs of the other series.
+func TestJobQueueRoundRobin(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := actor.WithInternalActor(context.Background())
+
+	mainAppDB := dbtesting.GetDB(t)
+	workerBaseStore := basestore.NewWithDB(mainAppDB, sql.TxOptions{})
+
+	for _, seriesID := range []string{"backfill", "backfill", "backfill", "other"} {
+		if _, err := EnqueueJob(ctx, workerBaseStore, &Job{
+			SeriesID:    seriesID,
+			SearchQuery: "our search",
+			State:       "queued",
+			PersistMode: string(store.RecordMode),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := workerBaseStore.Query(ctx, sqlf.Sprintf(`SELECT series_id FROM insights_query_runner_jobs ORDER BY priority, series_turn, id`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := basestore.ScanStrings(rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	autogold.Want("dequeue order", []string{"backfill", "other", "backfill", "backfill"}).Equal(t, order)
+}
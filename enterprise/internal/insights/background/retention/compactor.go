@@ -0,0 +1,160 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var downsampledPoints = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "src_insights_retention_downsampled_points_total",
+	Help: "Total number of points in time of code insight series deleted by downsampling.",
+})
+
+// Policy downsamples the data points older than OlderThan to Resolution.
+type Policy struct {
+	OlderThan  time.Duration
+	Resolution string
+}
+
+// Options are the retention options of insight data points.
+type Options struct {
+	Policies []Policy
+
+	// DeleteAfter is the age after which data points are deleted, or 0 to keep them forever.
+	DeleteAfter time.Duration
+}
+
+// OptionsFromConfig returns the retention options of the given site configuration. Invalid
+// policies are ignored.
+func OptionsFromConfig(c *schema.InsightsRetention) Options {
+	var opts Options
+	if c == nil {
+		return opts
+	}
+
+	const day = 24 * time.Hour
+	for _, p := range c.Policies {
+		if p == nil || p.OlderThanDays <= 0 || !store.IsResolution(p.Resolution) {
+			log15.Warn("insights.retention: ignoring invalid policy", "policy", p)
+			continue
+		}
+		opts.Policies = append(opts.Policies, Policy{
+			OlderThan:  time.Duration(p.OlderThanDays) * day,
+			Resolution: p.Resolution,
+		})
+	}
+	if c.DeleteAfterDays > 0 {
+		opts.DeleteAfter = time.Duration(c.DeleteAfterDays) * day
+	}
+	return opts
+}
+
+// ResolutionFor returns the resolution that data points from the given time up to now are
+// queried at, so that a chart which includes downsampled data points shows all its data points at
+// the same resolution. It is the coarsest resolution of the policies that apply to data points at
+// the given time, or the empty string if none apply.
+func (o Options) ResolutionFor(from, now time.Time) string {
+	var resolution string
+	for _, p := range o.Policies {
+		if from.Before(now.Add(-p.OlderThan)) {
+			resolution = store.CoarserResolution(resolution, p.Resolution)
+		}
+	}
+	return resolution
+}
+
+// ResolutionFor returns the resolution that data points from the given time up to now are
+// queried at with the current site configuration. See Options.ResolutionFor.
+func ResolutionFor(from time.Time) string {
+	return OptionsFromConfig(conf.Get().InsightsRetention).ResolutionFor(from, time.Now())
+}
+
+// pointsStore is the subset of the store used to compact the data points of series.
+type pointsStore interface {
+	DownsampleSeries(ctx context.Context, args store.DownsampleSeriesArgs) (int, error)
+	DeleteSeriesPointsBefore(ctx context.Context, seriesID string, before time.Time) error
+}
+
+// NewCompactor returns a background goroutine which will periodically downsample and delete the
+// data points of all series according to the insights.retention site configuration.
+func NewCompactor(ctx context.Context, insightsStore *store.Store, dataSeriesStore store.DataSeriesStore, observationContext *observation.Context) goroutine.BackgroundRoutine {
+	metrics := metrics.NewOperationMetrics(
+		observationContext.Registerer,
+		"insights_retention_compactor",
+		metrics.WithCountHelp("Total number of insights retention compactor executions"),
+	)
+	operation := observationContext.Operation(observation.Op{
+		Name:    "Retention.Compactor.Run",
+		Metrics: metrics,
+	})
+
+	c := &compactor{
+		pointsStore:     insightsStore,
+		dataSeriesStore: dataSeriesStore,
+		options: func() Options {
+			return OptionsFromConfig(conf.Get().InsightsRetention)
+		},
+		now: time.Now,
+	}
+	return goroutine.NewPeriodicGoroutineWithMetrics(ctx, time.Hour, goroutine.NewHandlerWithErrorMessage(
+		"insights_retention_compactor",
+		c.Handler,
+	), operation)
+}
+
+type compactor struct {
+	// Required fields used for mocking in tests.
+	pointsStore     pointsStore
+	dataSeriesStore store.DataSeriesStore
+	options         func() Options
+	now             func() time.Time
+}
+
+func (c *compactor) Handler(ctx context.Context) error {
+	opts := c.options()
+	if len(opts.Policies) == 0 && opts.DeleteAfter == 0 {
+		return nil
+	}
+
+	series, err := c.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{})
+	if err != nil {
+		return errors.Wrap(err, "GetDataSeries")
+	}
+
+	now := c.now()
+	var errs error
+	for _, s := range series {
+		if opts.DeleteAfter > 0 {
+			if err := c.pointsStore.DeleteSeriesPointsBefore(ctx, s.SeriesID, now.Add(-opts.DeleteAfter)); err != nil {
+				errs = multierror.Append(errs, errors.Wrapf(err, "series %q", s.SeriesID))
+				continue
+			}
+		}
+		for _, p := range opts.Policies {
+			n, err := c.pointsStore.DownsampleSeries(ctx, store.DownsampleSeriesArgs{
+				SeriesID:   s.SeriesID,
+				Before:     now.Add(-p.OlderThan),
+				Resolution: p.Resolution,
+			})
+			if err != nil {
+				errs = multierror.Append(errs, errors.Wrapf(err, "series %q", s.SeriesID))
+				break
+			}
+			downsampledPoints.Add(float64(n))
+		}
+	}
+	return errs
+}
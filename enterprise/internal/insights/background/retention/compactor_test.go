@@ -0,0 +1,108 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hexops/autogold"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// fakePointsStore records the calls made to it.
+type fakePointsStore struct {
+	calls []string
+}
+
+func (s *fakePointsStore) DownsampleSeries(ctx context.Context, args store.DownsampleSeriesArgs) (int, error) {
+	s.calls = append(s.calls, fmt.Sprintf("downsample %s before %s to %s", args.SeriesID, args.Before.Format("2006-01-02"), args.Resolution))
+	return 1, nil
+}
+
+func (s *fakePointsStore) DeleteSeriesPointsBefore(ctx context.Context, seriesID string, before time.Time) error {
+	s.calls = append(s.calls, fmt.Sprintf("delete %s before %s", seriesID, before.Format("2006-01-02")))
+	return nil
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts := OptionsFromConfig(&schema.InsightsRetention{
+		DeleteAfterDays: 1000,
+		Policies: []*schema.InsightsRetentionPolicy{
+			{OlderThanDays: 90, Resolution: "week"},
+			{OlderThanDays: 0, Resolution: "day"},
+			{OlderThanDays: 30, Resolution: "hour"},
+			{OlderThanDays: 730, Resolution: "month"},
+		},
+	})
+	autogold.Want("options", Options{
+		Policies: []Policy{
+			{OlderThan: 90 * 24 * time.Hour, Resolution: "week"},
+			{OlderThan: 730 * 24 * time.Hour, Resolution: "month"},
+		},
+		DeleteAfter: 1000 * 24 * time.Hour,
+	}).Equal(t, opts)
+
+	autogold.Want("unset", Options{}).Equal(t, OptionsFromConfig(nil))
+}
+
+func TestResolutionFor(t *testing.T) {
+	now := time.Date(2021, time.September, 15, 0, 0, 0, 0, time.UTC)
+	opts := Options{Policies: []Policy{
+		{OlderThan: 730 * 24 * time.Hour, Resolution: store.ResolutionMonth},
+		{OlderThan: 90 * 24 * time.Hour, Resolution: store.ResolutionWeek},
+	}}
+	for _, tc := range []struct {
+		from time.Time
+		want string
+	}{
+		{now.AddDate(0, 0, -30), ""},
+		{now.AddDate(-1, 0, 0), store.ResolutionWeek},
+		{now.AddDate(-3, 0, 0), store.ResolutionMonth},
+	} {
+		if got := opts.ResolutionFor(tc.from, now); got != tc.want {
+			t.Errorf("ResolutionFor(%s) = %q, want %q", tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestCompactor(t *testing.T) {
+	now := time.Date(2021, time.September, 15, 0, 0, 0, 0, time.UTC)
+	dataSeriesStore := store.NewMockDataSeriesStore()
+	dataSeriesStore.GetDataSeriesFunc.SetDefaultReturn([]types.InsightSeries{{SeriesID: "one"}, {SeriesID: "two"}}, nil)
+
+	pointsStore := &fakePointsStore{}
+	options := Options{}
+	c := &compactor{
+		pointsStore:     pointsStore,
+		dataSeriesStore: dataSeriesStore,
+		options:         func() Options { return options },
+		now:             func() time.Time { return now },
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		if err := c.Handler(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("disabled", []string(nil)).Equal(t, pointsStore.calls)
+	})
+
+	t.Run("policies", func(t *testing.T) {
+		options = Options{
+			Policies:    []Policy{{OlderThan: 90 * 24 * time.Hour, Resolution: store.ResolutionWeek}},
+			DeleteAfter: 365 * 24 * time.Hour,
+		}
+		if err := c.Handler(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("policies", []string{
+			"delete one before 2020-09-15",
+			"downsample one before 2021-06-17 to week",
+			"delete two before 2020-09-15",
+			"downsample two before 2021-06-17 to week",
+		}).Equal(t, pointsStore.calls)
+	})
+}
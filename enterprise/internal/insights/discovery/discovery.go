@@ -79,8 +79,13 @@ func convertFromBackendInsight(backendInsights []*schema.Insight) []insights.Sea
 		temp.Description = backendInsight.Description
 		for _, series := range backendInsight.Series {
 			temp.Series = append(temp.Series, insights.TimeSeries{
-				Name:  series.Label,
-				Query: series.Search,
+				Name:                       series.Label,
+				Query:                      series.Search,
+				PatternType:                series.PatternType,
+				GeneratedFromCaptureGroups: series.GeneratedFromCaptureGroups,
+				RepositoryRevisions:        series.RepositoryRevisions,
+				LanguageStatsRepositories:  series.LanguageStatsRepositories,
+				Team:                       series.Team,
 			})
 		}
 		temp.ID = backendInsight.Id
@@ -198,10 +203,20 @@ func migrateSeries(ctx context.Context, insightStore *store.InsightStore, from i
 		temp := types.InsightSeries{
 			SeriesID:              Encode(timeSeries),
 			Query:                 timeSeries.Query,
+			PatternType:           timeSeries.PatternType,
+			RepositoryRevisions:   timeSeries.RepositoryRevisions,
+			Team:                  timeSeries.Team,
 			RecordingIntervalDays: 1,
 			NextRecordingAfter:    insights.NextRecording(time.Now()),
 			NextSnapshotAfter:     insights.NextSnapshot(time.Now()),
 		}
+		if timeSeries.GeneratedFromCaptureGroups {
+			temp.GenerationMethod = types.GenerationMethodSearchCompute
+		}
+		if len(timeSeries.LanguageStatsRepositories) > 0 {
+			temp.GenerationMethod = types.GenerationMethodLanguageStats
+			temp.Repositories = timeSeries.LanguageStatsRepositories
+		}
 		var series types.InsightSeries
 		// first check if this data series already exists (somebody already created an insight of this query), in which case we just need to attach the view to this data series
 		existing, err := tx.GetDataSeries(ctx, store.GetDataSeriesArgs{SeriesID: Encode(timeSeries)})
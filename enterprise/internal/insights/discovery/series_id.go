@@ -3,9 +3,12 @@ package discovery
 import (
 	"crypto/sha256"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
 	"github.com/sourcegraph/sourcegraph/internal/insights"
 
 	"github.com/sourcegraph/sourcegraph/schema"
@@ -14,14 +17,20 @@ import (
 // EncodeSeriesID hashes the hashes the input series to return a string which uniquely identifies
 // the data series being described. It is possible the same series is described in multiple user's
 // settings, e.g. if multiple users declare an insight with the same search query - in which case
-// we have an opportunity to deduplicate them.
+// we have an opportunity to deduplicate them. Series of language statistics have no query, and are
+// identified by their repositories instead.
 //
 // Note that since the series ID hash is stored in the database, it must remain stable or else past
-// data will not be queryable.
+// data will not be queryable. The pattern type is only part of the hash if it isn't literal, the
+// generation method only if it isn't a plain search, the repository revisions only if there are
+// any, and the team only if there is one, so the IDs of series that existed before those were
+// supported are unchanged.
 func EncodeSeriesID(series *schema.InsightSeries) (string, error) {
 	switch {
+	case len(series.LanguageStatsRepositories) > 0:
+		return fmt.Sprintf("l:%s", languageStatsSeriesHash(series.LanguageStatsRepositories, series.RepositoryRevisions)), nil
 	case series.Search != "":
-		return fmt.Sprintf("s:%s", sha256String(series.Search)), nil
+		return fmt.Sprintf("s:%s", searchSeriesHash(series.Search, series.PatternType, series.GeneratedFromCaptureGroups, series.RepositoryRevisions, series.Team)), nil
 	case series.Webhook != "":
 		return fmt.Sprintf("w:%s", sha256String(series.Webhook)), nil
 	default:
@@ -30,7 +39,49 @@ func EncodeSeriesID(series *schema.InsightSeries) (string, error) {
 }
 
 func Encode(series insights.TimeSeries) string {
-	return fmt.Sprintf("s:%s", sha256String(series.Query))
+	if len(series.LanguageStatsRepositories) > 0 {
+		return fmt.Sprintf("l:%s", languageStatsSeriesHash(series.LanguageStatsRepositories, series.RepositoryRevisions))
+	}
+	return fmt.Sprintf("s:%s", searchSeriesHash(series.Query, series.PatternType, series.GeneratedFromCaptureGroups, series.RepositoryRevisions, series.Team))
+}
+
+// searchSeriesHash hashes the query, pattern type, generation method, repository revisions and team
+// of a search series, see EncodeSeriesID.
+func searchSeriesHash(query, patternType string, captureGroups bool, revisions map[string]string, team string) string {
+	if patternType != "" && patternType != types.PatternTypeLiteral {
+		query = patternType + ":" + query
+	}
+	if captureGroups {
+		query = types.GenerationMethodSearchCompute + ":" + query
+	}
+	if len(revisions) > 0 {
+		query = "revisions(" + strings.Join(sortedRevisions(revisions), ",") + "):" + query
+	}
+	if team != "" {
+		query = "team(" + team + "):" + query
+	}
+	return sha256String(query)
+}
+
+// languageStatsSeriesHash hashes the repositories of a series of language statistics, and the
+// revisions they are analyzed at.
+func languageStatsSeriesHash(repos []string, revisions map[string]string) string {
+	sorted := append([]string(nil), repos...)
+	sort.Strings(sorted)
+	s := strings.Join(sorted, ",")
+	if len(revisions) > 0 {
+		s = "revisions(" + strings.Join(sortedRevisions(revisions), ",") + "):" + s
+	}
+	return sha256String(s)
+}
+
+func sortedRevisions(revisions map[string]string) []string {
+	pins := make([]string, 0, len(revisions))
+	for repo, rev := range revisions {
+		pins = append(pins, repo+"@"+rev)
+	}
+	sort.Strings(pins)
+	return pins
 }
 
 func sha256String(s string) string {
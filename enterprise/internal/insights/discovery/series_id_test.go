@@ -21,6 +21,51 @@ func TestEncodeSeriesID(t *testing.T) {
 				"<nil>",
 			}),
 		},
+		{
+			input: &schema.InsightSeries{Search: "fmt.Errorf repo:github.com/golang/go", PatternType: "literal"},
+			want: autogold.Want("literal_search", [2]interface{}{
+				"s:6CB26B840C8EEBFB03DDB44A23FFBD4D7AD864B47D9AA1E975E69FCF0EE2A67E",
+				"<nil>",
+			}),
+		},
+		{
+			input: &schema.InsightSeries{Search: "fmt.Sprintf(:[args])", PatternType: "structural"},
+			want: autogold.Want("structural_search", [2]interface{}{
+				"s:EA5208483BAA8D8DFFE0666374D9505769F1B1697C593382575425F296D1AE1A",
+				"<nil>",
+			}),
+		},
+		{
+			input: &schema.InsightSeries{Search: `go\s(\d\.\d+) file:go.mod`, GeneratedFromCaptureGroups: true},
+			want: autogold.Want("capture_groups_search", [2]interface{}{
+				"s:DBEA40BAADE3784C18E8E8901626393FB6F3898A93150F06919770A4D8A17BF3",
+				"<nil>",
+			}),
+		},
+		{
+			input: &schema.InsightSeries{
+				Search:              "fmt.Errorf",
+				RepositoryRevisions: map[string]string{"github.com/golang/go": "release-branch.go1.17"},
+			},
+			want: autogold.Want("repository_revisions_search", [2]interface{}{
+				"s:DA7206295532C3B4C3E37E87AE3B1C03F0F71CC8AF36D2D42D77659AFFB0BC14",
+				"<nil>",
+			}),
+		},
+		{
+			input: &schema.InsightSeries{Search: "fmt.Errorf", Team: "platform"},
+			want: autogold.Want("team_search", [2]interface{}{
+				"s:D4AB82159DCC3635915941FD2EF84A7A90D99A2364AD180723E5ABF86B1DA4D8",
+				"<nil>",
+			}),
+		},
+		{
+			input: &schema.InsightSeries{LanguageStatsRepositories: []string{"github.com/sourcegraph/sourcegraph", "github.com/golang/go"}},
+			want: autogold.Want("language_stats", [2]interface{}{
+				"l:67A01F55106071C7B2D28A7DECFBE883A222F51A202E2EC5E9E79328AC502DB2",
+				"<nil>",
+			}),
+		},
 		{
 			input: &schema.InsightSeries{Webhook: "https://example.com/getData?foo=bar"},
 			want: autogold.Want("basic_webhook", [2]interface{}{
@@ -30,7 +75,7 @@ func TestEncodeSeriesID(t *testing.T) {
 		},
 		{
 			input: &schema.InsightSeries{},
-			want:  autogold.Want("invalid", [2]interface{}{"", "invalid series &{Label: RepositoriesList:[] Search: Webhook:}"}),
+			want:  autogold.Want("invalid", [2]interface{}{"", "invalid series &{GeneratedFromCaptureGroups:false Label: LanguageStatsRepositories:[] PatternType: RepositoriesList:[] RepositoryRevisions:map[] Search: Team: Webhook:}"}),
 		},
 	}
 	for _, tc := range testCases {
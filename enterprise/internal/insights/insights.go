@@ -50,6 +50,7 @@ func Init(ctx context.Context, postgres dbutil.DB, outOfBandMigrationRunner *oob
 		return err
 	}
 	enterpriseServices.InsightsResolver = resolvers.New(timescale, postgres)
+	enterpriseServices.InsightsExportHandler = resolvers.NewExportHandler(timescale, postgres)
 	return nil
 }
 
@@ -0,0 +1,246 @@
+package resolvers
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+const insightSeriesAlertKind = "InsightSeriesAlert"
+
+func marshalInsightSeriesAlertID(id int) graphql.ID {
+	return relay.MarshalID(insightSeriesAlertKind, id)
+}
+
+func unmarshalInsightSeriesAlertID(id graphql.ID) (alertID int, err error) {
+	if kind := relay.UnmarshalKind(id); kind != insightSeriesAlertKind {
+		return 0, errors.Errorf("expected graphql ID to have kind %q; got %q", insightSeriesAlertKind, kind)
+	}
+	err = relay.UnmarshalSpec(id, &alertID)
+	return
+}
+
+func (r *Resolver) CreateInsightSeriesAlert(ctx context.Context, args *graphqlbackend.CreateInsightSeriesAlertArgs) (graphqlbackend.InsightSeriesAlertResolver, error) {
+	// 🚨 SECURITY: Alerts notify the user that created them of the values of a series, so they may
+	// only be created by signed in users on the series of insights they can see.
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, errors.New("must be signed in to create insight series alerts")
+	}
+	if err := r.checkCanSeeSeries(ctx, args.SeriesID); err != nil {
+		return nil, err
+	}
+
+	series, err := r.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{SeriesID: args.SeriesID})
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, store.ErrSeriesNotFound
+	}
+	if types.RecordsCapturedValues(series[0].GenerationMethod) {
+		return nil, errors.New("alerts are not supported on series of captured values")
+	}
+
+	alert, err := alertFromArgs(args.Label, args.Condition, args.Threshold, args.NotifyEmail, args.WebhookURL, args.SlackWebhookURL)
+	if err != nil {
+		return nil, err
+	}
+	alert.SeriesID = args.SeriesID
+	alert.UserID = a.UID
+
+	created, err := r.alertStore.CreateAlert(ctx, alert)
+	if err != nil {
+		return nil, err
+	}
+	return &insightSeriesAlertResolver{alert: created}, nil
+}
+
+func (r *Resolver) UpdateInsightSeriesAlert(ctx context.Context, args *graphqlbackend.UpdateInsightSeriesAlertArgs) (graphqlbackend.InsightSeriesAlertResolver, error) {
+	existing, err := r.alertForOwner(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	alert, err := alertFromArgs(args.Label, args.Condition, args.Threshold, args.NotifyEmail, args.WebhookURL, args.SlackWebhookURL)
+	if err != nil {
+		return nil, err
+	}
+	alert.ID = existing.ID
+
+	updated, err := r.alertStore.UpdateAlert(ctx, alert)
+	if err != nil {
+		return nil, err
+	}
+	return &insightSeriesAlertResolver{alert: updated}, nil
+}
+
+func (r *Resolver) DeleteInsightSeriesAlert(ctx context.Context, args *graphqlbackend.DeleteInsightSeriesAlertArgs) (*graphqlbackend.EmptyResponse, error) {
+	alert, err := r.alertForOwner(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.alertStore.DeleteAlert(ctx, alert.ID); err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+// alertForOwner returns the alert with the given ID if the current user created it or is a site
+// admin.
+func (r *Resolver) alertForOwner(ctx context.Context, id graphql.ID) (types.InsightSeriesAlert, error) {
+	alertID, err := unmarshalInsightSeriesAlertID(id)
+	if err != nil {
+		return types.InsightSeriesAlert{}, err
+	}
+	alerts, err := r.alertStore.GetAlerts(ctx, store.GetAlertsArgs{ID: alertID})
+	if err != nil {
+		return types.InsightSeriesAlert{}, err
+	}
+	if len(alerts) == 0 {
+		return types.InsightSeriesAlert{}, store.ErrAlertNotFound
+	}
+
+	// 🚨 SECURITY: Only the user that created an alert and site admins may change it.
+	if a := actor.FromContext(ctx); !a.IsAuthenticated() || a.UID != alerts[0].UserID {
+		if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.workerBaseStore.Handle().DB()); err != nil {
+			return types.InsightSeriesAlert{}, store.ErrAlertNotFound
+		}
+	}
+	return alerts[0], nil
+}
+
+// checkCanSeeSeries returns an error unless the series with the given series ID belongs to an
+// insight that the current user can see.
+func (r *Resolver) checkCanSeeSeries(ctx context.Context, seriesID string) error {
+	insights, err := (&insightConnectionResolver{
+		insightsStore:        r.insightsStore,
+		workerBaseStore:      r.workerBaseStore,
+		insightMetadataStore: r.insightMetadataStore,
+		orgStore:             database.Orgs(r.workerBaseStore.Handle().DB()),
+	}).Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, insight := range insights {
+		for _, series := range insight.Series() {
+			if series.SeriesID() == seriesID {
+				return nil
+			}
+		}
+	}
+	return store.ErrSeriesNotFound
+}
+
+// alertFromArgs returns the definition of an alert with the given GraphQL arguments, validating
+// them.
+func alertFromArgs(label *string, condition string, threshold float64, notifyEmail bool, webhookURL, slackWebhookURL *string) (types.InsightSeriesAlert, error) {
+	alert := types.InsightSeriesAlert{
+		Condition:   strings.ToLower(condition),
+		Threshold:   threshold,
+		NotifyEmail: notifyEmail,
+	}
+	if label != nil {
+		alert.Label = *label
+	}
+	switch alert.Condition {
+	case types.AlertConditionAbove, types.AlertConditionBelow:
+	case types.AlertConditionIncrease, types.AlertConditionDecrease:
+		if threshold < 0 {
+			return alert, errors.New("the threshold of a trend condition must be a positive percentage")
+		}
+	default:
+		return alert, errors.Errorf("unknown alert condition %q", condition)
+	}
+
+	for _, u := range []struct {
+		arg   *string
+		field *string
+	}{
+		{webhookURL, &alert.WebhookURL},
+		{slackWebhookURL, &alert.SlackWebhookURL},
+	} {
+		if u.arg == nil || *u.arg == "" {
+			continue
+		}
+		parsed, err := url.Parse(*u.arg)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return alert, errors.Errorf("invalid webhook URL %q", *u.arg)
+		}
+		*u.field = *u.arg
+	}
+	if !alert.NotifyEmail && alert.WebhookURL == "" && alert.SlackWebhookURL == "" {
+		return alert, errors.New("an alert must notify by email, webhook or Slack")
+	}
+	return alert, nil
+}
+
+// Alerts returns the alerts that the current user defined on the series.
+func (r *insightSeriesResolver) Alerts(ctx context.Context) ([]graphqlbackend.InsightSeriesAlertResolver, error) {
+	uid := actor.FromContext(ctx).UID
+	if uid == 0 || r.alertStore == nil {
+		return []graphqlbackend.InsightSeriesAlertResolver{}, nil
+	}
+	alerts, err := r.alertStore.GetAlerts(ctx, store.GetAlertsArgs{SeriesID: r.series.SeriesID, UserID: uid})
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.InsightSeriesAlertResolver, 0, len(alerts))
+	for _, alert := range alerts {
+		resolvers = append(resolvers, &insightSeriesAlertResolver{alert: alert})
+	}
+	return resolvers, nil
+}
+
+var _ graphqlbackend.InsightSeriesAlertResolver = &insightSeriesAlertResolver{}
+
+type insightSeriesAlertResolver struct {
+	alert types.InsightSeriesAlert
+}
+
+func (r *insightSeriesAlertResolver) ID() graphql.ID { return marshalInsightSeriesAlertID(r.alert.ID) }
+
+func (r *insightSeriesAlertResolver) SeriesID() string { return r.alert.SeriesID }
+
+func (r *insightSeriesAlertResolver) Label() string { return r.alert.Label }
+
+func (r *insightSeriesAlertResolver) Condition() string { return strings.ToUpper(r.alert.Condition) }
+
+func (r *insightSeriesAlertResolver) Threshold() float64 { return r.alert.Threshold }
+
+func (r *insightSeriesAlertResolver) NotifyEmail() bool { return r.alert.NotifyEmail }
+
+func (r *insightSeriesAlertResolver) WebhookURL() *string {
+	if r.alert.WebhookURL == "" {
+		return nil
+	}
+	return &r.alert.WebhookURL
+}
+
+func (r *insightSeriesAlertResolver) SlackWebhookURL() *string {
+	if r.alert.SlackWebhookURL == "" {
+		return nil
+	}
+	return &r.alert.SlackWebhookURL
+}
+
+func (r *insightSeriesAlertResolver) Triggered() bool { return r.alert.Triggered }
+
+func (r *insightSeriesAlertResolver) LastTriggeredAt() *graphqlbackend.DateTime {
+	return graphqlbackend.DateTimeOrNil(r.alert.LastTriggeredAt)
+}
+
+func (r *insightSeriesAlertResolver) CreatedAt() graphqlbackend.DateTime {
+	return graphqlbackend.DateTime{Time: r.alert.CreatedAt}
+}
@@ -0,0 +1,49 @@
+package resolvers
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+)
+
+func TestAlertFromArgs(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	t.Run("valid", func(t *testing.T) {
+		alert, err := alertFromArgs(str("too many TODOs"), "INCREASE", 50, true, str("https://example.com/hook"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := types.InsightSeriesAlert{
+			Label:       "too many TODOs",
+			Condition:   types.AlertConditionIncrease,
+			Threshold:   50,
+			NotifyEmail: true,
+			WebhookURL:  "https://example.com/hook",
+		}
+		if alert != want {
+			t.Errorf("got %+v, want %+v", alert, want)
+		}
+	})
+
+	for _, tc := range []struct {
+		name            string
+		condition       string
+		threshold       float64
+		notifyEmail     bool
+		webhookURL      *string
+		slackWebhookURL *string
+	}{
+		{name: "unknown condition", condition: "SIDEWAYS", notifyEmail: true},
+		{name: "negative trend", condition: "DECREASE", threshold: -10, notifyEmail: true},
+		{name: "no channel", condition: "ABOVE"},
+		{name: "invalid webhook", condition: "ABOVE", webhookURL: str("file:///etc/passwd")},
+		{name: "relative slack webhook", condition: "BELOW", slackWebhookURL: str("/services/abc")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := alertFromArgs(nil, tc.condition, tc.threshold, tc.notifyEmail, tc.webhookURL, tc.slackWebhookURL); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package resolvers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+const insightsDashboardKind = "InsightsDashboard"
+
+func marshalInsightsDashboardID(id int) graphql.ID {
+	return relay.MarshalID(insightsDashboardKind, id)
+}
+
+func unmarshalInsightsDashboardID(id graphql.ID) (dashboardID int, err error) {
+	if kind := relay.UnmarshalKind(id); kind != insightsDashboardKind {
+		return 0, errors.Errorf("expected graphql ID to have kind %q; got %q", insightsDashboardKind, kind)
+	}
+	err = relay.UnmarshalSpec(id, &dashboardID)
+	return
+}
+
+func (r *Resolver) InsightsDashboards(ctx context.Context, args *graphqlbackend.InsightsDashboardsArgs) (graphqlbackend.InsightsDashboardConnectionResolver, error) {
+	var ids []int
+	if args != nil && args.Ids != nil {
+		ids = make([]int, 0, len(*args.Ids))
+		for _, id := range *args.Ids {
+			dashboardID, err := unmarshalInsightsDashboardID(id)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, dashboardID)
+		}
+	}
+	return &dashboardConnectionResolver{resolver: r, ids: ids}, nil
+}
+
+func (r *Resolver) CreateInsightsDashboard(ctx context.Context, args *graphqlbackend.CreateInsightsDashboardArgs) (graphqlbackend.InsightsDashboardResolver, error) {
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, errors.New("must be signed in to create insights dashboards")
+	}
+
+	grants := []store.DashboardGrant{store.UserDashboardGrant(int(a.UID))}
+	if args.Grants != nil {
+		more, err := grantsFromArgs(*args.Grants)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.checkCanGrant(ctx, more); err != nil {
+			return nil, err
+		}
+		grants = append(grants, more...)
+	}
+
+	dashboard := types.Dashboard{Title: args.Title, CreatedBy: &a.UID}
+	if args.InsightIds != nil {
+		dashboard.InsightIDs = *args.InsightIds
+	}
+	created, err := r.dashboardStore.CreateDashboard(ctx, dashboard, grants)
+	if err != nil {
+		return nil, err
+	}
+	return &dashboardResolver{resolver: r, dashboard: created}, nil
+}
+
+func (r *Resolver) ShareInsightsDashboard(ctx context.Context, args *graphqlbackend.ShareInsightsDashboardArgs) (*graphqlbackend.EmptyResponse, error) {
+	dashboardID, grants, err := r.dashboardGrantsForManager(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.dashboardStore.AddDashboardGrants(ctx, dashboardID, grants); err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+func (r *Resolver) UnshareInsightsDashboard(ctx context.Context, args *graphqlbackend.ShareInsightsDashboardArgs) (*graphqlbackend.EmptyResponse, error) {
+	dashboardID, grants, err := r.dashboardGrantsForManager(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.dashboardStore.RemoveDashboardGrants(ctx, dashboardID, grants); err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+// dashboardGrantsForManager returns the ID of the dashboard and the grants of the given arguments if
+// the current user may manage the grants of the dashboard.
+func (r *Resolver) dashboardGrantsForManager(ctx context.Context, args *graphqlbackend.ShareInsightsDashboardArgs) (int, []store.DashboardGrant, error) {
+	dashboardID, err := unmarshalInsightsDashboardID(args.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	grants, err := grantsFromArgs(args.Grants)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// 🚨 SECURITY: Only users that a dashboard is granted to, directly or through one of their orgs,
+	// may manage its grants. A global grant is not enough, as anyone could then unshare it.
+	userIDs, orgIDs, err := getUserPermissions(ctx, r.orgStore())
+	if err != nil {
+		return 0, nil, err
+	}
+	existing, err := r.dashboardStore.GetDashboardGrants(ctx, store.DashboardQueryArgs{ID: []int{dashboardID}, UserID: userIDs, OrgID: orgIDs})
+	if err != nil {
+		return 0, nil, err
+	}
+	if !isGrantedDirectly(existing, userIDs, orgIDs) {
+		return 0, nil, store.ErrDashboardNotFound
+	}
+	if err := r.checkCanGrant(ctx, grants); err != nil {
+		return 0, nil, err
+	}
+	return dashboardID, grants, nil
+}
+
+// checkCanGrant returns an error unless the current user may grant (or revoke) the given grants: only
+// members of an org may grant a dashboard to it, and only site admins may grant a dashboard globally.
+func (r *Resolver) checkCanGrant(ctx context.Context, grants []store.DashboardGrant) error {
+	_, orgIDs, err := getUserPermissions(ctx, r.orgStore())
+	if err != nil {
+		return err
+	}
+	member := make(map[int]bool, len(orgIDs))
+	for _, id := range orgIDs {
+		member[id] = true
+	}
+
+	for _, grant := range grants {
+		if grant.OrgID != nil && !member[*grant.OrgID] {
+			if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.workerBaseStore.Handle().DB()); err != nil {
+				return errors.New("must be a member of an organization to grant it a dashboard")
+			}
+		}
+		if grant.Global != nil && *grant.Global {
+			if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.workerBaseStore.Handle().DB()); err != nil {
+				return errors.New("must be a site admin to grant a dashboard to everyone")
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) orgStore() *database.OrgStore {
+	return database.Orgs(r.workerBaseStore.Handle().DB())
+}
+
+// isGrantedDirectly reports whether one of the given grants is a grant to one of the given users or
+// orgs.
+func isGrantedDirectly(grants []store.DashboardGrant, userIDs, orgIDs []int) bool {
+	users := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		users[id] = true
+	}
+	orgs := make(map[int]bool, len(orgIDs))
+	for _, id := range orgIDs {
+		orgs[id] = true
+	}
+	for _, grant := range grants {
+		if (grant.UserID != nil && users[*grant.UserID]) || (grant.OrgID != nil && orgs[*grant.OrgID]) {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsFromArgs returns the dashboard grants of the given GraphQL input.
+func grantsFromArgs(args graphqlbackend.InsightsPermissionGrantsInput) ([]store.DashboardGrant, error) {
+	var grants []store.DashboardGrant
+	if args.Users != nil {
+		for _, id := range *args.Users {
+			userID, err := graphqlbackend.UnmarshalUserID(id)
+			if err != nil {
+				return nil, err
+			}
+			grants = append(grants, store.UserDashboardGrant(int(userID)))
+		}
+	}
+	if args.Organizations != nil {
+		for _, id := range *args.Organizations {
+			orgID, err := graphqlbackend.UnmarshalOrgID(id)
+			if err != nil {
+				return nil, err
+			}
+			grants = append(grants, store.OrgDashboardGrant(int(orgID)))
+		}
+	}
+	if args.Global != nil && *args.Global {
+		grants = append(grants, store.GlobalDashboardGrant())
+	}
+	return grants, nil
+}
+
+var _ graphqlbackend.InsightsDashboardConnectionResolver = &dashboardConnectionResolver{}
+
+type dashboardConnectionResolver struct {
+	resolver *Resolver
+
+	// arguments from query
+	ids []int
+
+	// cache results because they are used by multiple fields
+	once       sync.Once
+	dashboards []types.Dashboard
+	err        error
+}
+
+func (r *dashboardConnectionResolver) compute(ctx context.Context) ([]types.Dashboard, error) {
+	r.once.Do(func() {
+		userIDs, orgIDs, err := getUserPermissions(ctx, r.resolver.orgStore())
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.dashboards, r.err = r.resolver.dashboardStore.GetDashboards(ctx, store.DashboardQueryArgs{ID: r.ids, UserID: userIDs, OrgID: orgIDs})
+	})
+	return r.dashboards, r.err
+}
+
+func (r *dashboardConnectionResolver) Nodes(ctx context.Context) ([]graphqlbackend.InsightsDashboardResolver, error) {
+	dashboards, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.InsightsDashboardResolver, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		resolvers = append(resolvers, &dashboardResolver{resolver: r.resolver, dashboard: dashboard})
+	}
+	return resolvers, nil
+}
+
+func (r *dashboardConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	dashboards, err := r.compute(ctx)
+	return int32(len(dashboards)), err
+}
+
+func (r *dashboardConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	_, err := r.compute(ctx)
+	return graphqlutil.HasNextPage(false), err
+}
+
+var _ graphqlbackend.InsightsDashboardResolver = &dashboardResolver{}
+
+type dashboardResolver struct {
+	resolver  *Resolver
+	dashboard types.Dashboard
+}
+
+func (r *dashboardResolver) ID() graphql.ID { return marshalInsightsDashboardID(r.dashboard.ID) }
+
+func (r *dashboardResolver) Title() string { return r.dashboard.Title }
+
+func (r *dashboardResolver) Views() graphqlbackend.InsightConnectionResolver {
+	// 🚨 SECURITY: The insight connection resolver only returns insights that the current user may
+	// see, which may not be all the insights on the dashboard.
+	return &insightConnectionResolver{
+		insightsStore:        r.resolver.insightsStore,
+		workerBaseStore:      r.resolver.workerBaseStore,
+		insightMetadataStore: r.resolver.insightMetadataStore,
+		alertStore:           r.resolver.alertStore,
+		dashboardID:          r.dashboard.ID,
+		orgStore:             r.resolver.orgStore(),
+	}
+}
+
+func (r *dashboardResolver) Grants(ctx context.Context) (graphqlbackend.InsightsPermissionGrantsResolver, error) {
+	userIDs, orgIDs, err := getUserPermissions(ctx, r.resolver.orgStore())
+	if err != nil {
+		return nil, err
+	}
+	grants, err := r.resolver.dashboardStore.GetDashboardGrants(ctx, store.DashboardQueryArgs{ID: []int{r.dashboard.ID}, UserID: userIDs, OrgID: orgIDs})
+	if err != nil {
+		return nil, err
+	}
+	return &permissionGrantsResolver{grants: grants}, nil
+}
+
+var _ graphqlbackend.InsightsPermissionGrantsResolver = &permissionGrantsResolver{}
+
+type permissionGrantsResolver struct {
+	grants []store.DashboardGrant
+}
+
+func (r *permissionGrantsResolver) Users() []graphql.ID {
+	ids := []graphql.ID{}
+	for _, grant := range r.grants {
+		if grant.UserID != nil {
+			ids = append(ids, graphqlbackend.MarshalUserID(int32(*grant.UserID)))
+		}
+	}
+	return ids
+}
+
+func (r *permissionGrantsResolver) Organizations() []graphql.ID {
+	ids := []graphql.ID{}
+	for _, grant := range r.grants {
+		if grant.OrgID != nil {
+			ids = append(ids, graphqlbackend.MarshalOrgID(int32(*grant.OrgID)))
+		}
+	}
+	return ids
+}
+
+func (r *permissionGrantsResolver) Global() bool {
+	for _, grant := range r.grants {
+		if grant.Global != nil && *grant.Global {
+			return true
+		}
+	}
+	return false
+}
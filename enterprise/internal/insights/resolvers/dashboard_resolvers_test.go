@@ -0,0 +1,55 @@
+package resolvers
+
+import (
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/hexops/autogold"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+)
+
+func TestGrantsFromArgs(t *testing.T) {
+	global := true
+	grants, err := grantsFromArgs(graphqlbackend.InsightsPermissionGrantsInput{
+		Users:         &[]graphql.ID{graphqlbackend.MarshalUserID(1)},
+		Organizations: &[]graphql.ID{graphqlbackend.MarshalOrgID(5)},
+		Global:        &global,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	autogold.Want("grants", []store.DashboardGrant{
+		store.UserDashboardGrant(1),
+		store.OrgDashboardGrant(5),
+		store.GlobalDashboardGrant(),
+	}).Equal(t, grants)
+
+	if _, err := grantsFromArgs(graphqlbackend.InsightsPermissionGrantsInput{
+		Users: &[]graphql.ID{graphqlbackend.MarshalOrgID(5)},
+	}); err == nil {
+		t.Error("expected an error for an org ID given as a user")
+	}
+}
+
+func TestIsGrantedDirectly(t *testing.T) {
+	grants := []store.DashboardGrant{store.UserDashboardGrant(1), store.OrgDashboardGrant(5), store.GlobalDashboardGrant()}
+	for _, tc := range []struct {
+		name    string
+		userIDs []int
+		orgIDs  []int
+		want    bool
+	}{
+		{name: "user", userIDs: []int{1}, want: true},
+		{name: "org member", userIDs: []int{2}, orgIDs: []int{5}, want: true},
+		{name: "global only", userIDs: []int{2}, orgIDs: []int{6}, want: false},
+		{name: "anonymous", want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isGrantedDirectly(grants, tc.userIDs, tc.orgIDs); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -26,9 +26,11 @@ type insightConnectionResolver struct {
 	workerBaseStore      *basestore.Store
 	orgStore             *database.OrgStore
 	insightMetadataStore store.InsightMetadataStore
+	alertStore           store.SeriesAlertStore
 
 	// arguments from query
-	ids []string
+	ids         []string
+	dashboardID int
 
 	// cache results because they are used by multiple fields
 	once     sync.Once
@@ -49,6 +51,7 @@ func (r *insightConnectionResolver) Nodes(ctx context.Context) ([]graphqlbackend
 			workerBaseStore: r.workerBaseStore,
 			insight:         insight,
 			metadataStore:   r.insightMetadataStore,
+			alertStore:      r.alertStore,
 		})
 	}
 	return resolvers, nil
@@ -72,23 +75,12 @@ func (r *insightConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.
 
 func (r *insightConnectionResolver) compute(ctx context.Context) ([]types.Insight, int64, error) {
 	r.once.Do(func() {
-		args := store.InsightQueryArgs{UniqueIDs: r.ids}
-		uid := actor.FromContext(ctx).UID
-		if uid != 0 {
-			// 🚨 SECURITY
-			// only add users / orgs if the user is non-anonymous. This will restrict anonymous users to only see
-			// insights with a global grant.
-			args.UserID = []int{int(uid)}
-			orgs, err := r.orgStore.GetByUserID(ctx, uid)
-			if err != nil {
-				r.err = err
-				return
-			}
-			orgIDs := make([]int, 0, len(orgs))
-			for _, org := range orgs {
-				orgIDs = append(orgIDs, int(org.ID))
-			}
-			args.OrgID = orgIDs
+		args := store.InsightQueryArgs{UniqueIDs: r.ids, DashboardID: r.dashboardID}
+		var err error
+		args.UserID, args.OrgID, err = getUserPermissions(ctx, r.orgStore)
+		if err != nil {
+			r.err = err
+			return
 		}
 
 		mapped, err := r.insightMetadataStore.GetMapped(ctx, args)
@@ -110,6 +102,26 @@ func (r *insightConnectionResolver) compute(ctx context.Context) ([]types.Insigh
 	return r.insights, r.next, r.err
 }
 
+// getUserPermissions returns the user and org IDs that grants of the current user are checked against.
+func getUserPermissions(ctx context.Context, orgStore *database.OrgStore) (userIDs []int, orgIDs []int, err error) {
+	uid := actor.FromContext(ctx).UID
+	if uid == 0 {
+		// 🚨 SECURITY
+		// only add users / orgs if the user is non-anonymous. This will restrict anonymous users to only see
+		// insights and dashboards with a global grant.
+		return nil, nil, nil
+	}
+	orgs, err := orgStore.GetByUserID(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	orgIDs = make([]int, 0, len(orgs))
+	for _, org := range orgs {
+		orgIDs = append(orgIDs, int(org.ID))
+	}
+	return []int{int(uid)}, orgIDs, nil
+}
+
 // InsightResolver is also defined here as it is covered by the same tests.
 
 var _ graphqlbackend.InsightResolver = &insightResolver{}
@@ -118,6 +130,7 @@ type insightResolver struct {
 	insightsStore   store.Interface
 	workerBaseStore *basestore.Store
 	metadataStore   store.InsightMetadataStore
+	alertStore      store.SeriesAlertStore
 	insight         types.Insight
 }
 
@@ -138,6 +151,7 @@ func (r *insightResolver) Series() []graphqlbackend.InsightSeriesResolver {
 			workerBaseStore: r.workerBaseStore,
 			series:          series,
 			metadataStore:   r.metadataStore,
+			alertStore:      r.alertStore,
 		})
 	}
 	return resolvers
@@ -0,0 +1,232 @@
+package resolvers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/mux"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/timeutil"
+)
+
+// The formats that insights can be exported in.
+const (
+	exportFormatCSV  = "csv"
+	exportFormatJSON = "json"
+)
+
+// exportRow is a single row of an exported insight: the value of a series in a repository at a
+// point in time.
+type exportRow struct {
+	SeriesID    string  `json:"seriesId"`
+	SeriesLabel string  `json:"seriesLabel"`
+	Query       string  `json:"query"`
+	Time        string  `json:"time"`
+	Repository  string  `json:"repository"`
+	Capture     *string `json:"capture,omitempty"`
+	Value       float64 `json:"value"`
+}
+
+// exportEncoder writes the rows of an exported insight to the underlying writer as they are
+// produced.
+type exportEncoder interface {
+	writeRow(row exportRow) error
+	close() error
+}
+
+func newExportEncoder(w io.Writer, format string) (exportEncoder, error) {
+	switch format {
+	case exportFormatCSV:
+		enc := &csvExportEncoder{w: csv.NewWriter(w)}
+		return enc, enc.w.Write([]string{"series_id", "series_label", "query", "time", "repository", "capture", "value"})
+	case exportFormatJSON:
+		return &jsonExportEncoder{w: w}, nil
+	}
+	return nil, errors.Errorf("unknown export format %q", format)
+}
+
+type csvExportEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvExportEncoder) writeRow(row exportRow) error {
+	var capture string
+	if row.Capture != nil {
+		capture = *row.Capture
+	}
+	return e.w.Write([]string{
+		row.SeriesID,
+		row.SeriesLabel,
+		row.Query,
+		row.Time,
+		row.Repository,
+		capture,
+		strconv.FormatFloat(row.Value, 'f', -1, 64),
+	})
+}
+
+func (e *csvExportEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonExportEncoder writes the rows as a JSON array, one element at a time.
+type jsonExportEncoder struct {
+	w    io.Writer
+	rows int
+}
+
+func (e *jsonExportEncoder) writeRow(row exportRow) error {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.rows == 0 {
+		sep = "[\n"
+	}
+	e.rows++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonExportEncoder) close() error {
+	end := "\n]\n"
+	if e.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// exportInsight writes the value of every repository at every point in time of all the series of
+// the given insight to w, in the given format. The rows are written as they are read from the
+// store, so exporting a large insight doesn't hold all its data in memory.
+func exportInsight(ctx context.Context, w io.Writer, insightsStore store.Interface, insight types.Insight, format string) error {
+	enc, err := newExportEncoder(w, format)
+	if err != nil {
+		return err
+	}
+	for _, series := range insight.Series {
+		err := insightsStore.ExportSeriesPoints(ctx, store.ExportSeriesPointsOpts{
+			SeriesID: series.SeriesID,
+			Captured: types.RecordsCapturedValues(series.GenerationMethod),
+		}, func(point store.RepoSeriesPoint, t time.Time, capture *string) error {
+			return enc.writeRow(exportRow{
+				SeriesID:    series.SeriesID,
+				SeriesLabel: series.Label,
+				Query:       series.Query,
+				Time:        t.UTC().Format(time.RFC3339),
+				Repository:  point.RepoName,
+				Capture:     capture,
+				Value:       point.Value,
+			})
+		})
+		if err != nil {
+			return errors.Wrapf(err, "exporting series %q", series.SeriesID)
+		}
+	}
+	return enc.close()
+}
+
+// parseExportFormat returns the export format with the given name, which defaults to CSV.
+func parseExportFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", exportFormatCSV:
+		return exportFormatCSV, nil
+	case exportFormatJSON:
+		return exportFormatJSON, nil
+	}
+	return "", errors.Errorf("unknown export format %q, expected csv or json", format)
+}
+
+// ExportData returns the data of the insight, encoded in the given format. Large insights should
+// rather be exported with the HTTP endpoint served by NewExportHandler, which streams the data.
+func (r *insightResolver) ExportData(ctx context.Context, args *graphqlbackend.InsightExportDataArgs) (string, error) {
+	format, err := parseExportFormat(args.Format)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := exportInsight(ctx, &buf, r.insightsStore, r.insight, format); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// NewExportHandler returns an HTTP handler that exports the data of the insight with the ID given
+// by the "id" route variable. The data is encoded in the format given by the "format" query
+// parameter, either "csv" (the default) or "json", and streamed as it is read from the database.
+func NewExportHandler(timescale, postgres dbutil.DB) http.Handler {
+	resolver := newWithClock(timescale, postgres, timeutil.Now)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		id := mux.Vars(req)["id"]
+
+		format, err := parseExportFormat(req.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// 🚨 SECURITY: The insight connection resolver only returns insights that the current user
+		// may see, and the store only exports the values of the repositories they can access.
+		insights, _, err := (&insightConnectionResolver{
+			insightsStore:        resolver.insightsStore,
+			workerBaseStore:      resolver.workerBaseStore,
+			insightMetadataStore: resolver.insightMetadataStore,
+			ids:                  []string{id},
+			orgStore:             database.Orgs(resolver.workerBaseStore.Handle().DB()),
+		}).compute(ctx)
+		if err != nil {
+			log15.Error("failed to load insight for export", "id", id, "err", err)
+			http.Error(w, "failed to load insight", http.StatusInternalServerError)
+			return
+		}
+		if len(insights) == 0 {
+			http.Error(w, "insight not found", http.StatusNotFound)
+			return
+		}
+
+		contentType := "text/csv; charset=utf-8"
+		if format == exportFormatJSON {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+exportFileName(id)+"."+format+`"`)
+
+		// Once rows were written, the status can't be changed anymore, so errors only truncate
+		// the response.
+		if err := exportInsight(ctx, w, resolver.insightsStore, insights[0], format); err != nil {
+			log15.Error("failed to export insight", "id", id, "err", err)
+		}
+	})
+}
+
+// exportFileName returns the name of the file an insight with the given ID is exported to,
+// without its extension.
+func exportFileName(id string) string {
+	return "insight-" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, id)
+}
@@ -0,0 +1,98 @@
+package resolvers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hexops/autogold"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+)
+
+func TestExportInsight(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	capture := "1.17"
+
+	insightsStore := store.NewMockInterface()
+	insightsStore.ExportSeriesPointsFunc.SetDefaultHook(func(ctx context.Context, opts store.ExportSeriesPointsOpts, fn func(store.RepoSeriesPoint, time.Time, *string) error) error {
+		switch opts.SeriesID {
+		case "todos":
+			if opts.Captured {
+				t.Errorf("unexpected captured export of series %q", opts.SeriesID)
+			}
+			for _, p := range []struct {
+				time  time.Time
+				repo  string
+				value float64
+			}{
+				{day(1), "github.com/a/a", 3},
+				{day(1), "github.com/b/b", 1},
+				{day(2), "github.com/a/a", 4},
+			} {
+				if err := fn(store.RepoSeriesPoint{RepoName: p.repo, Value: p.value}, p.time, nil); err != nil {
+					return err
+				}
+			}
+		case "versions":
+			if !opts.Captured {
+				t.Errorf("expected captured export of series %q", opts.SeriesID)
+			}
+			return fn(store.RepoSeriesPoint{RepoName: "github.com/a/a", Value: 1}, day(2), &capture)
+		}
+		return nil
+	})
+
+	insight := types.Insight{
+		UniqueID: "insight",
+		Series: []types.InsightViewSeries{
+			{SeriesID: "todos", Label: "TODOs", Query: "TODO"},
+			{SeriesID: "versions", Label: "Go, versions", Query: `go\s(\d+\.\d+)`, GenerationMethod: types.GenerationMethodSearchCompute},
+			{SeriesID: "empty", Label: "Empty", Query: "nothing"},
+		},
+	}
+	export := func(t *testing.T, format string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := exportInsight(ctx, &buf, insightsStore, insight, format); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		autogold.Want("csv", `series_id,series_label,query,time,repository,capture,value
+todos,TODOs,TODO,2021-01-01T00:00:00Z,github.com/a/a,,3
+todos,TODOs,TODO,2021-01-01T00:00:00Z,github.com/b/b,,1
+todos,TODOs,TODO,2021-01-02T00:00:00Z,github.com/a/a,,4
+versions,"Go, versions",go\s(\d+\.\d+),2021-01-02T00:00:00Z,github.com/a/a,1.17,1
+`).Equal(t, export(t, exportFormatCSV))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		autogold.Want("json", `[
+{"seriesId":"todos","seriesLabel":"TODOs","query":"TODO","time":"2021-01-01T00:00:00Z","repository":"github.com/a/a","value":3},
+{"seriesId":"todos","seriesLabel":"TODOs","query":"TODO","time":"2021-01-01T00:00:00Z","repository":"github.com/b/b","value":1},
+{"seriesId":"todos","seriesLabel":"TODOs","query":"TODO","time":"2021-01-02T00:00:00Z","repository":"github.com/a/a","value":4},
+{"seriesId":"versions","seriesLabel":"Go, versions","query":"go\\s(\\d+\\.\\d+)","time":"2021-01-02T00:00:00Z","repository":"github.com/a/a","capture":"1.17","value":1}
+]
+`).Equal(t, export(t, exportFormatJSON))
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := exportInsight(ctx, &buf, insightsStore, types.Insight{}, exportFormatJSON); err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("empty json", "[]\n").Equal(t, buf.String())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := parseExportFormat("xml"); err == nil {
+			t.Error("expected an error for an unknown format")
+		}
+	})
+}
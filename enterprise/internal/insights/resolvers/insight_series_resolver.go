@@ -2,12 +2,15 @@ package resolvers
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/backfiller"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/retention"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 )
@@ -19,10 +22,24 @@ type insightSeriesResolver struct {
 	workerBaseStore *basestore.Store
 	series          types.InsightViewSeries
 	metadataStore   store.InsightMetadataStore
+	alertStore      store.SeriesAlertStore
 }
 
+func (r *insightSeriesResolver) SeriesID() string { return r.series.SeriesID }
+
 func (r *insightSeriesResolver) Label() string { return r.series.Label }
 
+func (r *insightSeriesResolver) PausedAt() *graphqlbackend.DateTime {
+	return graphqlbackend.DateTimeOrNil(r.series.PausedAt)
+}
+
+func (r *insightSeriesResolver) PauseReason() *string {
+	if r.series.PausedAt == nil || r.series.PauseReason == "" {
+		return nil
+	}
+	return &r.series.PauseReason
+}
+
 func (r *insightSeriesResolver) Points(ctx context.Context, args *graphqlbackend.InsightsPointsArgs) ([]graphqlbackend.InsightsDataPointResolver, error) {
 	var opts store.SeriesPointsOpts
 
@@ -46,19 +63,67 @@ func (r *insightSeriesResolver) Points(ctx context.Context, args *graphqlbackend
 	if args.ExcludeRepoRegex != nil {
 		opts.ExcludeRepoRegex = *args.ExcludeRepoRegex
 	}
+	// Charts that include downsampled data points are shown at the same resolution throughout.
+	opts.Resolution = retention.ResolutionFor(*opts.From)
 	// TODO(slimsag): future: Pass through opts.Limit
 
-	points, err := r.insightsStore.SeriesPoints(ctx, opts)
+	var (
+		points []store.SeriesPoint
+		err    error
+	)
+	if types.RecordsCapturedValues(r.series.GenerationMethod) {
+		// Series of captured values have one point per value, which are not rolled up.
+		points, err = r.insightsStore.CapturedSeriesPoints(ctx, opts)
+	} else if opts.IncludeRepoRegex == "" && opts.ExcludeRepoRegex == "" {
+		// Without repository filters, the aggregated points can be read from the rollups, which
+		// is much cheaper than aggregating the raw data points.
+		points, err = r.insightsStore.SeriesRollups(ctx, store.SeriesRollupsOpts{
+			SeriesID:   seriesID,
+			From:       opts.From,
+			To:         opts.To,
+			Resolution: opts.Resolution,
+		})
+	} else {
+		points, err = r.insightsStore.SeriesPoints(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	incomplete, err := r.incompleteReasons(ctx)
 	if err != nil {
 		return nil, err
 	}
 	resolvers := make([]graphqlbackend.InsightsDataPointResolver, 0, len(points))
 	for _, point := range points {
-		resolvers = append(resolvers, insightsDataPointResolver{point})
+		resolvers = append(resolvers, insightsDataPointResolver{
+			p:                 point,
+			insightsStore:     r.insightsStore,
+			incompleteReasons: incomplete[point.Time.UnixNano()],
+		})
 	}
 	return resolvers, nil
 }
 
+// incompleteReasons returns the reasons why data points of the series were computed from partial
+// data, keyed by the time of the data points in nanoseconds.
+func (r *insightSeriesResolver) incompleteReasons(ctx context.Context) (map[int64][]string, error) {
+	dirty, err := r.metadataStore.GetDirtyQueriesAggregated(ctx, r.series.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	reasons := map[int64][]string{}
+	for _, dqa := range dirty {
+		if queryrunner.IsIncompleteDataReason(dqa.Reason) {
+			key := dqa.ForTime.UnixNano()
+			reasons[key] = append(reasons[key], dqa.Reason)
+		}
+	}
+	for _, rs := range reasons {
+		sort.Strings(rs)
+	}
+	return reasons, nil
+}
+
 func (r *insightSeriesResolver) Status(ctx context.Context) (graphqlbackend.InsightStatusResolver, error) {
 	seriesID := r.series.SeriesID
 
@@ -74,6 +139,11 @@ func (r *insightSeriesResolver) Status(ctx context.Context) (graphqlbackend.Insi
 		return nil, err
 	}
 
+	backfillProgress, err := backfiller.QueryProgress(ctx, r.workerBaseStore, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
 	return insightStatusResolver{
 		totalPoints: int32(totalPoints),
 
@@ -83,6 +153,7 @@ func (r *insightSeriesResolver) Status(ctx context.Context) (graphqlbackend.Insi
 		completedJobs:    int32(status.Completed),
 		failedJobs:       int32(status.Failed),
 		backfillQueuedAt: r.series.BackfillQueuedAt,
+		backfillProgress: backfillProgress,
 	}, nil
 }
 
@@ -100,7 +171,11 @@ func (r *insightSeriesResolver) DirtyMetadata(ctx context.Context) ([]graphqlbac
 
 var _ graphqlbackend.InsightsDataPointResolver = insightsDataPointResolver{}
 
-type insightsDataPointResolver struct{ p store.SeriesPoint }
+type insightsDataPointResolver struct {
+	p                 store.SeriesPoint
+	insightsStore     store.Interface
+	incompleteReasons []string
+}
 
 func (i insightsDataPointResolver) DateTime() graphqlbackend.DateTime {
 	return graphqlbackend.DateTime{Time: i.p.Time}
@@ -108,9 +183,68 @@ func (i insightsDataPointResolver) DateTime() graphqlbackend.DateTime {
 
 func (i insightsDataPointResolver) Value() float64 { return i.p.Value }
 
+func (i insightsDataPointResolver) Capture() *string { return i.p.Capture }
+
+func (i insightsDataPointResolver) IncompleteReasons() []string {
+	if i.incompleteReasons == nil {
+		return []string{}
+	}
+	return i.incompleteReasons
+}
+
+func (i insightsDataPointResolver) Samples(ctx context.Context, args *graphqlbackend.InsightDataPointSamplesArgs) ([]graphqlbackend.InsightDataPointSampleResolver, error) {
+	samples, err := i.insightsStore.SeriesPointSamples(ctx, store.SeriesPointSamplesOpts{
+		SeriesID: i.p.SeriesID,
+		Time:     i.p.Time,
+		Limit:    int(args.First),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.InsightDataPointSampleResolver, 0, len(samples))
+	for _, sample := range samples {
+		resolvers = append(resolvers, insightDataPointSampleResolver{sample})
+	}
+	return resolvers, nil
+}
+
+func (i insightsDataPointResolver) Repositories(ctx context.Context, args *graphqlbackend.InsightDataPointRepositoriesArgs) ([]graphqlbackend.InsightRepositoryDataPointResolver, error) {
+	points, err := i.insightsStore.RepoSeriesPoints(ctx, store.RepoSeriesPointsOpts{
+		SeriesID: i.p.SeriesID,
+		Time:     i.p.Time,
+		Capture:  i.p.Capture,
+		Limit:    int(args.First),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]graphqlbackend.InsightRepositoryDataPointResolver, 0, len(points))
+	for _, point := range points {
+		resolvers = append(resolvers, insightRepositoryDataPointResolver{point})
+	}
+	return resolvers, nil
+}
+
+var _ graphqlbackend.InsightDataPointSampleResolver = insightDataPointSampleResolver{}
+
+type insightDataPointSampleResolver struct{ s store.SeriesPointSample }
+
+func (i insightDataPointSampleResolver) RepositoryName() string { return i.s.RepoName }
+func (i insightDataPointSampleResolver) Path() string           { return i.s.Path }
+func (i insightDataPointSampleResolver) LineNumber() *int32     { return i.s.LineNumber }
+func (i insightDataPointSampleResolver) Preview() *string       { return i.s.Preview }
+
+var _ graphqlbackend.InsightRepositoryDataPointResolver = insightRepositoryDataPointResolver{}
+
+type insightRepositoryDataPointResolver struct{ p store.RepoSeriesPoint }
+
+func (i insightRepositoryDataPointResolver) RepositoryName() string { return i.p.RepoName }
+func (i insightRepositoryDataPointResolver) Value() float64         { return i.p.Value }
+
 type insightStatusResolver struct {
 	totalPoints, pendingJobs, completedJobs, failedJobs int32
 	backfillQueuedAt                                    *time.Time
+	backfillProgress                                    *backfiller.Progress
 }
 
 func (i insightStatusResolver) TotalPoints() int32   { return i.totalPoints }
@@ -120,3 +254,34 @@ func (i insightStatusResolver) FailedJobs() int32    { return i.failedJobs }
 func (i insightStatusResolver) BackfillQueuedAt() *graphqlbackend.DateTime {
 	return graphqlbackend.DateTimeOrNil(i.backfillQueuedAt)
 }
+
+func (i insightStatusResolver) BackfillProgress() graphqlbackend.InsightBackfillProgressResolver {
+	if i.backfillProgress == nil {
+		return nil
+	}
+	return insightBackfillProgressResolver{*i.backfillProgress}
+}
+
+var _ graphqlbackend.InsightBackfillProgressResolver = insightBackfillProgressResolver{}
+
+type insightBackfillProgressResolver struct{ p backfiller.Progress }
+
+func (i insightBackfillProgressResolver) TotalRepositories() int32 {
+	return int32(i.p.TotalRepositories)
+}
+
+func (i insightBackfillProgressResolver) CompletedRepositories() int32 {
+	return int32(i.p.CompletedRepositories)
+}
+
+func (i insightBackfillProgressResolver) FailedRepositories() int32 {
+	return int32(i.p.FailedRepositories)
+}
+
+func (i insightBackfillProgressResolver) TotalFrames() int32 {
+	return int32(i.p.TotalFrames)
+}
+
+func (i insightBackfillProgressResolver) CompletedFrames() int32 {
+	return int32(i.p.CompletedFrames)
+}
@@ -105,22 +105,75 @@ func TestResolver_InsightSeries(t *testing.T) {
 		// Mock the store and confirm args got passed through as expected.
 		args.From.Time, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
 		args.To.Time, _ = time.Parse(time.RFC3339, "2006-01-03T15:04:05Z")
+		mockedPoints := []store.SeriesPoint{
+			{Time: args.From.Time, Value: 1},
+			{Time: args.From.Time, Value: 2},
+			{Time: args.From.Time, Value: 3},
+		}
+		mock.SeriesRollupsFunc.SetDefaultHook(func(ctx context.Context, opts store.SeriesRollupsOpts) ([]store.SeriesPoint, error) {
+			json, err := json.Marshal(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			autogold.Want("insights[0][0].Points store rollups opts", `{"SeriesID":"1234567","From":"2006-01-02T15:04:05Z","To":"2006-01-03T15:04:05Z"}`).Equal(t, string(json))
+			return mockedPoints, nil
+		})
 		mock.SeriesPointsFunc.SetDefaultHook(func(ctx context.Context, opts store.SeriesPointsOpts) ([]store.SeriesPoint, error) {
 			json, err := json.Marshal(opts)
 			if err != nil {
 				t.Fatal(err)
 			}
-			autogold.Want("insights[0][0].Points store opts", `{"SeriesID":"1234567","RepoID":null,"Excluded":null,"Included":null,"IncludeRepoRegex":"","ExcludeRepoRegex":"","From":"2006-01-02T15:04:05Z","To":"2006-01-03T15:04:05Z","Limit":0}`).Equal(t, string(json))
-			return []store.SeriesPoint{
-				{Time: args.From.Time, Value: 1},
-				{Time: args.From.Time, Value: 2},
-				{Time: args.From.Time, Value: 3},
-			}, nil
+			autogold.Want("insights[0][0].Points store opts", `{"SeriesID":"1234567","RepoID":null,"Excluded":null,"Included":null,"IncludeRepoRegex":"github.com/sourcegraph/.*","ExcludeRepoRegex":"","From":"2006-01-02T15:04:05Z","To":"2006-01-03T15:04:05Z","Limit":0}`).Equal(t, string(json))
+			return mockedPoints, nil
 		})
 		points, err = insights[0][0].Points(ctx, args)
 		if err != nil {
 			t.Fatal(err)
 		}
 		autogold.Want("insights[0][0].Points mocked", "[{p:{SeriesID: Time:{wall:0 ext:63271811045 loc:<nil>} Value:1 Metadata:[]}} {p:{SeriesID: Time:{wall:0 ext:63271811045 loc:<nil>} Value:2 Metadata:[]}} {p:{SeriesID: Time:{wall:0 ext:63271811045 loc:<nil>} Value:3 Metadata:[]}}]").Equal(t, fmt.Sprintf("%+v", points))
+
+		// With repository filters, the raw data points need to be aggregated.
+		includeRepoRegex := "github.com/sourcegraph/.*"
+		args.IncludeRepoRegex = &includeRepoRegex
+		points, err = insights[0][0].Points(ctx, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("insights[0][0].Points filtered mocked", "[{p:{SeriesID: Time:{wall:0 ext:63271811045 loc:<nil>} Value:1 Metadata:[]}} {p:{SeriesID: Time:{wall:0 ext:63271811045 loc:<nil>} Value:2 Metadata:[]}} {p:{SeriesID: Time:{wall:0 ext:63271811045 loc:<nil>} Value:3 Metadata:[]}}]").Equal(t, fmt.Sprintf("%+v", points))
 	})
 }
+
+func TestInsightSeriesResolver_IncompleteReasons(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	insightsStore := store.NewMockInterface()
+	insightsStore.SeriesRollupsFunc.SetDefaultReturn([]store.SeriesPoint{
+		{SeriesID: "series1", Time: day(2), Value: 3},
+		{SeriesID: "series1", Time: day(1), Value: 2},
+	}, nil)
+	metadataStore := store.NewMockInsightMetadataStore()
+	metadataStore.GetDirtyQueriesAggregatedFunc.SetDefaultReturn([]*types.DirtyQueryAggregate{
+		{Count: 1, ForTime: day(1), Reason: "repositories missing"},
+		{Count: 2, ForTime: day(1), Reason: "repositories cloning"},
+		{Count: 1, ForTime: day(1), Reason: "series paused: too expensive"},
+	}, nil)
+
+	r := &insightSeriesResolver{
+		insightsStore: insightsStore,
+		metadataStore: metadataStore,
+		series:        types.InsightViewSeries{SeriesID: "series1"},
+	}
+	points, err := r.Points(ctx, &graphqlbackend.InsightsPointsArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range points {
+		got = append(got, fmt.Sprintf("%s: %v", p.DateTime().Time.Format("2006-01-02"), p.IncompleteReasons()))
+	}
+	autogold.Want("incomplete reasons", []string{
+		"2021-01-02: []",
+		"2021-01-01: [repositories cloning repositories missing]",
+	}).Equal(t, got)
+}
@@ -3,13 +3,17 @@ package resolvers
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/internal/database"
 
 	"github.com/cockroachdb/errors"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/background/queryrunner"
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
@@ -23,6 +27,10 @@ type Resolver struct {
 	insightsStore        store.Interface
 	workerBaseStore      *basestore.Store
 	insightMetadataStore store.InsightMetadataStore
+	dataSeriesStore      store.DataSeriesStore
+	alertStore           store.SeriesAlertStore
+	dashboardStore       store.DashboardStore
+	teamStore            store.TeamStore
 }
 
 // New returns a new Resolver whose store uses the given Timescale and Postgres DBs.
@@ -33,10 +41,15 @@ func New(timescale, postgres dbutil.DB) graphqlbackend.InsightsResolver {
 // newWithClock returns a new Resolver whose store uses the given Timescale and Postgres DBs, and the given
 // clock for timestamps.
 func newWithClock(timescale, postgres dbutil.DB, clock func() time.Time) *Resolver {
+	insightStore := store.NewInsightStore(timescale)
 	return &Resolver{
 		insightsStore:        store.NewWithClock(timescale, store.NewInsightPermissionStore(postgres), clock),
 		workerBaseStore:      basestore.NewWithDB(postgres, sql.TxOptions{}),
-		insightMetadataStore: store.NewInsightStore(timescale),
+		insightMetadataStore: insightStore,
+		dataSeriesStore:      insightStore,
+		alertStore:           insightStore,
+		dashboardStore:       insightStore,
+		teamStore:            insightStore,
 	}
 }
 
@@ -52,11 +65,132 @@ func (r *Resolver) Insights(ctx context.Context, args *graphqlbackend.InsightsAr
 		insightsStore:        r.insightsStore,
 		workerBaseStore:      r.workerBaseStore,
 		insightMetadataStore: r.insightMetadataStore,
+		alertStore:           r.alertStore,
 		ids:                  idList,
 		orgStore:             database.Orgs(r.workerBaseStore.Handle().DB()),
 	}, nil
 }
 
+func (r *Resolver) PauseInsightSeries(ctx context.Context, args *graphqlbackend.PauseInsightSeriesArgs) (*graphqlbackend.EmptyResponse, error) {
+	// 🚨 SECURITY: Series are shared between all insights with the same query, so only site admins may
+	// pause them.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.workerBaseStore.Handle().DB()); err != nil {
+		return nil, err
+	}
+
+	var reason string
+	if args.Reason != nil {
+		reason = *args.Reason
+	}
+	if err := r.dataSeriesStore.PauseSeries(ctx, args.SeriesID, reason); err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+func (r *Resolver) ResumeInsightSeries(ctx context.Context, args *graphqlbackend.ResumeInsightSeriesArgs) (*graphqlbackend.EmptyResponse, error) {
+	// 🚨 SECURITY: Series are shared between all insights with the same query, so only site admins may
+	// resume them.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.workerBaseStore.Handle().DB()); err != nil {
+		return nil, err
+	}
+
+	if err := r.dataSeriesStore.ResumeSeries(ctx, args.SeriesID); err != nil {
+		return nil, err
+	}
+
+	// The series may have been paused because its results were wrong, so the cached results of
+	// its query are not reused once it records again.
+	series, err := r.dataSeriesStore.GetDataSeries(ctx, store.GetDataSeriesArgs{SeriesID: args.SeriesID})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range series {
+		if err := queryrunner.InvalidateQueryCache(ctx, r.workerBaseStore, s.Query, s.PatternType); err != nil {
+			return nil, errors.Wrap(err, "InvalidateQueryCache")
+		}
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+func (r *Resolver) SetInsightsTeamRepositories(ctx context.Context, args *graphqlbackend.SetInsightsTeamRepositoriesArgs) (*graphqlbackend.EmptyResponse, error) {
+	// 🚨 SECURITY: The repositories of a team decide what series scoped to the team search, for all
+	// insights with those series, so only site admins may set them.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.workerBaseStore.Handle().DB()); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(args.Team) == "" {
+		return nil, errors.New("team must not be empty")
+	}
+	if err := r.teamStore.SetTeamRepositories(ctx, args.Team, args.Repositories); err != nil {
+		return nil, err
+	}
+	return &graphqlbackend.EmptyResponse{}, nil
+}
+
+// InsightBackfillProgress sends the insight when the subscription starts and whenever the backfill
+// status of one of its series changes.
+func (r *Resolver) InsightBackfillProgress(ctx context.Context, args *graphqlbackend.InsightBackfillProgressArgs) (<-chan graphqlbackend.InsightResolver, error) {
+	// 🚨 SECURITY: The insight connection resolver only returns insights that the current user may
+	// see.
+	insight := func(ctx context.Context) (graphqlbackend.InsightResolver, error) {
+		nodes, err := (&insightConnectionResolver{
+			insightsStore:        r.insightsStore,
+			workerBaseStore:      r.workerBaseStore,
+			insightMetadataStore: r.insightMetadataStore,
+			alertStore:           r.alertStore,
+			ids:                  []string{args.ID},
+			orgStore:             database.Orgs(r.workerBaseStore.Handle().DB()),
+		}).Nodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 0 {
+			return nil, errors.Errorf("insight not found: %q", args.ID)
+		}
+		return nodes[0], nil
+	}
+	latest, err := insight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan graphqlbackend.InsightResolver)
+	go func() {
+		defer close(c)
+		graphqlbackend.PollSubscription(ctx, func(ctx context.Context) (string, error) {
+			i, err := insight(ctx)
+			if err != nil {
+				return "", err
+			}
+			latest = i
+
+			var fingerprint strings.Builder
+			for _, series := range i.Series() {
+				status, err := series.Status(ctx)
+				if err != nil {
+					return "", err
+				}
+				var queuedAt string
+				if t := status.BackfillQueuedAt(); t != nil {
+					queuedAt = t.Time.String()
+				}
+				fmt.Fprintf(&fingerprint, "%s:%d:%d:%d:%d:%s ", series.SeriesID(), status.TotalPoints(), status.PendingJobs(), status.CompletedJobs(), status.FailedJobs(), queuedAt)
+			}
+			return fingerprint.String(), nil
+		}, func() bool {
+			select {
+			case c <- latest:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return c, nil
+}
+
 type disabledResolver struct {
 	reason string
 }
@@ -68,3 +202,47 @@ func NewDisabledResolver(reason string) graphqlbackend.InsightsResolver {
 func (r *disabledResolver) Insights(ctx context.Context, args *graphqlbackend.InsightsArgs) (graphqlbackend.InsightConnectionResolver, error) {
 	return nil, errors.New(r.reason)
 }
+
+func (r *disabledResolver) InsightsDashboards(ctx context.Context, args *graphqlbackend.InsightsDashboardsArgs) (graphqlbackend.InsightsDashboardConnectionResolver, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) PauseInsightSeries(ctx context.Context, args *graphqlbackend.PauseInsightSeriesArgs) (*graphqlbackend.EmptyResponse, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) ResumeInsightSeries(ctx context.Context, args *graphqlbackend.ResumeInsightSeriesArgs) (*graphqlbackend.EmptyResponse, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) SetInsightsTeamRepositories(ctx context.Context, args *graphqlbackend.SetInsightsTeamRepositoriesArgs) (*graphqlbackend.EmptyResponse, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) CreateInsightSeriesAlert(ctx context.Context, args *graphqlbackend.CreateInsightSeriesAlertArgs) (graphqlbackend.InsightSeriesAlertResolver, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) UpdateInsightSeriesAlert(ctx context.Context, args *graphqlbackend.UpdateInsightSeriesAlertArgs) (graphqlbackend.InsightSeriesAlertResolver, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) DeleteInsightSeriesAlert(ctx context.Context, args *graphqlbackend.DeleteInsightSeriesAlertArgs) (*graphqlbackend.EmptyResponse, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) CreateInsightsDashboard(ctx context.Context, args *graphqlbackend.CreateInsightsDashboardArgs) (graphqlbackend.InsightsDashboardResolver, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) ShareInsightsDashboard(ctx context.Context, args *graphqlbackend.ShareInsightsDashboardArgs) (*graphqlbackend.EmptyResponse, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) UnshareInsightsDashboard(ctx context.Context, args *graphqlbackend.ShareInsightsDashboardArgs) (*graphqlbackend.EmptyResponse, error) {
+	return nil, errors.New(r.reason)
+}
+
+func (r *disabledResolver) InsightBackfillProgress(ctx context.Context, args *graphqlbackend.InsightBackfillProgressArgs) (<-chan graphqlbackend.InsightResolver, error) {
+	return nil, errors.New(r.reason)
+}
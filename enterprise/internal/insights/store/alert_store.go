@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// SeriesAlertStore is the subset of the API of the InsightStore that manages alerts on insight
+// series.
+type SeriesAlertStore interface {
+	GetAlerts(ctx context.Context, args GetAlertsArgs) ([]types.InsightSeriesAlert, error)
+	CreateAlert(ctx context.Context, alert types.InsightSeriesAlert) (types.InsightSeriesAlert, error)
+	UpdateAlert(ctx context.Context, alert types.InsightSeriesAlert) (types.InsightSeriesAlert, error)
+	DeleteAlert(ctx context.Context, id int) error
+	UpdateAlertState(ctx context.Context, id int, triggered bool, evaluatedAt time.Time) error
+}
+
+var _ SeriesAlertStore = &InsightStore{}
+
+// ErrAlertNotFound is returned when updating an alert that does not exist.
+var ErrAlertNotFound = errors.New("insight series alert not found")
+
+// GetAlertsArgs contains query predicates for fetching alerts. Any provided values will be included
+// as query arguments.
+type GetAlertsArgs struct {
+	ID       int
+	UserID   int32
+	SeriesID string
+}
+
+// GetAlerts returns all matching alerts, oldest first.
+func (s *InsightStore) GetAlerts(ctx context.Context, args GetAlertsArgs) ([]types.InsightSeriesAlert, error) {
+	preds := []*sqlf.Query{sqlf.Sprintf("TRUE")}
+	if args.ID != 0 {
+		preds = append(preds, sqlf.Sprintf("id = %s", args.ID))
+	}
+	if args.UserID != 0 {
+		preds = append(preds, sqlf.Sprintf("user_id = %s", args.UserID))
+	}
+	if args.SeriesID != "" {
+		preds = append(preds, sqlf.Sprintf("series_id = %s", args.SeriesID))
+	}
+	return scanAlerts(s.Query(ctx, sqlf.Sprintf(getAlertsSql, sqlf.Join(preds, "\n AND"))))
+}
+
+// CreateAlert creates the given alert, which is not triggered until it is evaluated.
+func (s *InsightStore) CreateAlert(ctx context.Context, alert types.InsightSeriesAlert) (types.InsightSeriesAlert, error) {
+	alerts, err := scanAlerts(s.Query(ctx, sqlf.Sprintf(createAlertSql,
+		alert.SeriesID,
+		alert.UserID,
+		alert.Label,
+		alert.Condition,
+		alert.Threshold,
+		alert.NotifyEmail,
+		dbutil.NewNullString(alert.WebhookURL),
+		dbutil.NewNullString(alert.SlackWebhookURL),
+		s.Now(),
+	)))
+	if err != nil {
+		return types.InsightSeriesAlert{}, err
+	}
+	if len(alerts) == 0 {
+		return types.InsightSeriesAlert{}, errors.New("failed to insert insight series alert")
+	}
+	return alerts[0], nil
+}
+
+// UpdateAlert updates the definition of the given alert. Changing the condition or threshold of an
+// alert resets its state, so it notifies again if its condition holds for the next data point.
+func (s *InsightStore) UpdateAlert(ctx context.Context, alert types.InsightSeriesAlert) (types.InsightSeriesAlert, error) {
+	alerts, err := scanAlerts(s.Query(ctx, sqlf.Sprintf(updateAlertSql,
+		alert.Condition,
+		alert.Threshold,
+		alert.Label,
+		alert.Condition,
+		alert.Threshold,
+		alert.NotifyEmail,
+		dbutil.NewNullString(alert.WebhookURL),
+		dbutil.NewNullString(alert.SlackWebhookURL),
+		alert.ID,
+	)))
+	if err != nil {
+		return types.InsightSeriesAlert{}, err
+	}
+	if len(alerts) == 0 {
+		return types.InsightSeriesAlert{}, ErrAlertNotFound
+	}
+	return alerts[0], nil
+}
+
+// DeleteAlert deletes the alert with the given ID. Deleting an alert that does not exist is a no-op.
+func (s *InsightStore) DeleteAlert(ctx context.Context, id int) error {
+	return s.Exec(ctx, sqlf.Sprintf(deleteAlertSql, id))
+}
+
+// UpdateAlertState records the result of evaluating the alert with the given ID for the data point
+// at the given time.
+func (s *InsightStore) UpdateAlertState(ctx context.Context, id int, triggered bool, evaluatedAt time.Time) error {
+	return s.Exec(ctx, sqlf.Sprintf(updateAlertStateSql, triggered, evaluatedAt, triggered, s.Now(), id))
+}
+
+func scanAlerts(rows *sql.Rows, queryErr error) (_ []types.InsightSeriesAlert, err error) {
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer func() { err = basestore.CloseRows(rows, err) }()
+
+	results := make([]types.InsightSeriesAlert, 0)
+	for rows.Next() {
+		var temp types.InsightSeriesAlert
+		if err := rows.Scan(
+			&temp.ID,
+			&temp.SeriesID,
+			&temp.UserID,
+			&temp.Label,
+			&temp.Condition,
+			&temp.Threshold,
+			&temp.NotifyEmail,
+			&dbutil.NullString{S: &temp.WebhookURL},
+			&dbutil.NullString{S: &temp.SlackWebhookURL},
+			&temp.Triggered,
+			&temp.LastEvaluatedAt,
+			&temp.LastTriggeredAt,
+			&temp.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, temp)
+	}
+	return results, nil
+}
+
+const alertColumns = `id, series_id, user_id, label, condition, threshold, notify_email, webhook_url, slack_webhook_url,
+triggered, last_evaluated_at, last_triggered_at, created_at`
+
+const getAlertsSql = `
+-- source: enterprise/internal/insights/store/alert_store.go:GetAlerts
+SELECT ` + alertColumns + ` FROM insight_series_alerts
+WHERE %s
+ORDER BY id
+`
+
+const createAlertSql = `
+-- source: enterprise/internal/insights/store/alert_store.go:CreateAlert
+INSERT INTO insight_series_alerts (series_id, user_id, label, condition, threshold, notify_email, webhook_url, slack_webhook_url, created_at)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+RETURNING ` + alertColumns
+
+const updateAlertSql = `
+-- source: enterprise/internal/insights/store/alert_store.go:UpdateAlert
+UPDATE insight_series_alerts
+SET triggered = CASE WHEN condition <> %s OR threshold <> %s THEN FALSE ELSE triggered END,
+	label = %s, condition = %s, threshold = %s, notify_email = %s, webhook_url = %s, slack_webhook_url = %s
+WHERE id = %s
+RETURNING ` + alertColumns
+
+const deleteAlertSql = `
+-- source: enterprise/internal/insights/store/alert_store.go:DeleteAlert
+DELETE FROM insight_series_alerts WHERE id = %s
+`
+
+const updateAlertStateSql = `
+-- source: enterprise/internal/insights/store/alert_store.go:UpdateAlertState
+UPDATE insight_series_alerts
+SET triggered = %s, last_evaluated_at = %s, last_triggered_at = CASE WHEN %s AND NOT triggered THEN %s ELSE last_triggered_at END
+WHERE id = %s
+`
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	insightsdbtesting "github.com/sourcegraph/sourcegraph/enterprise/internal/insights/dbtesting"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+)
+
+func TestInsightStore_Alerts(t *testing.T) {
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	now := time.Now().Round(0).Truncate(time.Microsecond)
+	ctx := context.Background()
+
+	store := NewInsightStore(timescale)
+	store.Now = func() time.Time {
+		return now
+	}
+
+	created, err := store.CreateAlert(ctx, types.InsightSeriesAlert{
+		SeriesID:    "series-1",
+		UserID:      1,
+		Label:       "too many TODOs",
+		Condition:   types.AlertConditionAbove,
+		Threshold:   100,
+		NotifyEmail: true,
+		WebhookURL:  "https://example.com/hook",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateAlert(ctx, types.InsightSeriesAlert{
+		SeriesID:  "series-2",
+		UserID:    2,
+		Condition: types.AlertConditionIncrease,
+		Threshold: 10,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := types.InsightSeriesAlert{
+		ID:          created.ID,
+		SeriesID:    "series-1",
+		UserID:      1,
+		Label:       "too many TODOs",
+		Condition:   types.AlertConditionAbove,
+		Threshold:   100,
+		NotifyEmail: true,
+		WebhookURL:  "https://example.com/hook",
+		CreatedAt:   now,
+	}
+	if diff := cmp.Diff(want, created); diff != "" {
+		t.Errorf("unexpected created alert (-want +got):\n%s", diff)
+	}
+
+	t.Run("get", func(t *testing.T) {
+		for _, args := range []GetAlertsArgs{{ID: created.ID}, {UserID: 1}, {SeriesID: "series-1"}} {
+			got, err := store.GetAlerts(ctx, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]types.InsightSeriesAlert{want}, got); diff != "" {
+				t.Errorf("unexpected alerts for %+v (-want +got):\n%s", args, diff)
+			}
+		}
+		all, err := store.GetAlerts(ctx, GetAlertsArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 {
+			t.Errorf("unexpected number of alerts: %d", len(all))
+		}
+	})
+
+	t.Run("state", func(t *testing.T) {
+		evaluatedAt := now.Add(-time.Hour)
+		if err := store.UpdateAlertState(ctx, created.ID, true, evaluatedAt); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetAlerts(ctx, GetAlertsArgs{ID: created.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || !got[0].Triggered || got[0].LastTriggeredAt == nil || !got[0].LastTriggeredAt.Equal(now) || !got[0].LastEvaluatedAt.Equal(evaluatedAt) {
+			t.Fatalf("alert not triggered: %+v", got)
+		}
+
+		// Updating the definition without changing the condition keeps the state.
+		update := got[0]
+		update.Label = "way too many TODOs"
+		updated, err := store.UpdateAlert(ctx, update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.Triggered || updated.Label != "way too many TODOs" {
+			t.Errorf("unexpected updated alert: %+v", updated)
+		}
+
+		update.Threshold = 200
+		updated, err = store.UpdateAlert(ctx, update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Triggered || updated.Threshold != 200 {
+			t.Errorf("expected alert with a new threshold to be reset: %+v", updated)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := store.DeleteAlert(ctx, created.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetAlerts(ctx, GetAlertsArgs{ID: created.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("unexpected alerts after delete: %+v", got)
+		}
+		if _, err := store.UpdateAlert(ctx, created); err != ErrAlertNotFound {
+			t.Errorf("unexpected error updating deleted alert: %v", err)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+)
+
+// DashboardStore is the subset of the API of the InsightStore that manages dashboards of insights.
+type DashboardStore interface {
+	GetDashboards(ctx context.Context, args DashboardQueryArgs) ([]types.Dashboard, error)
+	GetDashboardGrants(ctx context.Context, args DashboardQueryArgs) ([]DashboardGrant, error)
+	CreateDashboard(ctx context.Context, dashboard types.Dashboard, grants []DashboardGrant) (types.Dashboard, error)
+	AddDashboardGrants(ctx context.Context, dashboardID int, grants []DashboardGrant) error
+	RemoveDashboardGrants(ctx context.Context, dashboardID int, grants []DashboardGrant) error
+}
+
+var _ DashboardStore = &InsightStore{}
+
+// ErrDashboardNotFound is returned when a dashboard does not exist or is not granted to the current
+// user.
+var ErrDashboardNotFound = errors.New("insights dashboard not found")
+
+// DashboardQueryArgs contains query predicates for fetching viewable dashboards. Only dashboards
+// granted to one of the given users or orgs, or granted globally, are matched.
+type DashboardQueryArgs struct {
+	ID     []int
+	UserID []int
+	OrgID  []int
+}
+
+// DashboardGrant is a permission grant of a dashboard to a single principal.
+type DashboardGrant struct {
+	// DashboardID is the ID of the granted dashboard. It is only set on grants returned by
+	// GetDashboardGrants.
+	DashboardID int
+
+	UserID *int
+	OrgID  *int
+	Global *bool
+}
+
+func (g DashboardGrant) toQuery(dashboardID int) *sqlf.Query {
+	// dashboard_id, org_id, user_id, global
+	valuesFmt := "(%s, %s, %s, %s)"
+	return sqlf.Sprintf(valuesFmt, dashboardID, g.OrgID, g.UserID, g.Global)
+}
+
+// principalQuery returns the condition matching the grant rows of the principal of the grant.
+func (g DashboardGrant) principalQuery() *sqlf.Query {
+	switch {
+	case g.UserID != nil:
+		return sqlf.Sprintf("user_id = %s", *g.UserID)
+	case g.OrgID != nil:
+		return sqlf.Sprintf("org_id = %s", *g.OrgID)
+	case g.Global != nil && *g.Global:
+		return sqlf.Sprintf("global IS TRUE")
+	}
+	return sqlf.Sprintf("FALSE")
+}
+
+func UserDashboardGrant(userID int) DashboardGrant {
+	return DashboardGrant{UserID: &userID}
+}
+
+func OrgDashboardGrant(orgID int) DashboardGrant {
+	return DashboardGrant{OrgID: &orgID}
+}
+
+func GlobalDashboardGrant() DashboardGrant {
+	b := true
+	return DashboardGrant{Global: &b}
+}
+
+// dashboardPermissionsQuery generates the SQL condition selecting the dashboards db that are granted
+// to the users or orgs of the given arguments, or granted globally.
+func dashboardPermissionsQuery(args DashboardQueryArgs) *sqlf.Query {
+	permsPreds := make([]*sqlf.Query, 0, 3)
+	if len(args.OrgID) > 0 {
+		permsPreds = append(permsPreds, sqlf.Sprintf("dg.org_id IN (%s)", joinInts(args.OrgID)))
+	}
+	if len(args.UserID) > 0 {
+		permsPreds = append(permsPreds, sqlf.Sprintf("dg.user_id IN (%s)", joinInts(args.UserID)))
+	}
+	permsPreds = append(permsPreds, sqlf.Sprintf("dg.global IS TRUE"))
+
+	return sqlf.Sprintf(dashboardPermissionsFmtstr, sqlf.Join(permsPreds, "OR"))
+}
+
+const dashboardPermissionsFmtstr = `
+EXISTS (SELECT 1 FROM dashboard_grants dg WHERE dg.dashboard_id = db.id AND (%s))
+`
+
+func joinInts(ids []int) *sqlf.Query {
+	elems := make([]*sqlf.Query, 0, len(ids))
+	for _, id := range ids {
+		elems = append(elems, sqlf.Sprintf("%s", id))
+	}
+	return sqlf.Join(elems, ",")
+}
+
+func dashboardPreds(args DashboardQueryArgs) []*sqlf.Query {
+	preds := []*sqlf.Query{dashboardPermissionsQuery(args)}
+	if len(args.ID) > 0 {
+		preds = append(preds, sqlf.Sprintf("db.id IN (%s)", joinInts(args.ID)))
+	}
+	return preds
+}
+
+// GetDashboards returns all matching dashboards that are granted to the given users or orgs, or
+// granted globally.
+func (s *InsightStore) GetDashboards(ctx context.Context, args DashboardQueryArgs) ([]types.Dashboard, error) {
+	return scanDashboards(s.Query(ctx, sqlf.Sprintf(getDashboardsSql, sqlf.Join(dashboardPreds(args), "\n AND"))))
+}
+
+func scanDashboards(rows *sql.Rows, queryErr error) (_ []types.Dashboard, err error) {
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer func() { err = basestore.CloseRows(rows, err) }()
+
+	results := make([]types.Dashboard, 0)
+	for rows.Next() {
+		var temp types.Dashboard
+		if err := rows.Scan(
+			&temp.ID,
+			&temp.Title,
+			&temp.CreatedAt,
+			&temp.CreatedBy,
+			pq.Array(&temp.InsightIDs),
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, temp)
+	}
+	return results, nil
+}
+
+// GetDashboardGrants returns the grants of all matching dashboards that are granted to the given
+// users or orgs, or granted globally, so that the grants of a dashboard can only be listed by
+// those it is granted to.
+func (s *InsightStore) GetDashboardGrants(ctx context.Context, args DashboardQueryArgs) (_ []DashboardGrant, err error) {
+	q := sqlf.Sprintf(getDashboardGrantsSql, sqlf.Join(dashboardPreds(args), "\n AND"))
+	rows, err := s.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = basestore.CloseRows(rows, err) }()
+
+	results := make([]DashboardGrant, 0)
+	for rows.Next() {
+		var temp DashboardGrant
+		if err := rows.Scan(&temp.DashboardID, &temp.UserID, &temp.OrgID, &temp.Global); err != nil {
+			return nil, err
+		}
+		results = append(results, temp)
+	}
+	return results, nil
+}
+
+// CreateDashboard creates the given dashboard with the insight views of the given unique IDs and
+// the given grants. Unique IDs of insight views that do not exist are ignored.
+func (s *InsightStore) CreateDashboard(ctx context.Context, dashboard types.Dashboard, grants []DashboardGrant) (_ types.Dashboard, err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return types.Dashboard{}, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	row := tx.QueryRow(ctx, sqlf.Sprintf(createDashboardSql, dashboard.Title, s.Now(), dashboard.CreatedBy))
+	if err := row.Scan(&dashboard.ID, &dashboard.CreatedAt); err != nil {
+		return types.Dashboard{}, errors.Wrap(err, "failed to insert dashboard")
+	}
+	if len(dashboard.InsightIDs) > 0 {
+		if err := tx.Exec(ctx, sqlf.Sprintf(attachViewsToDashboardSql, dashboard.ID, pq.Array(dashboard.InsightIDs))); err != nil {
+			return types.Dashboard{}, errors.Wrap(err, "failed to attach insight views to dashboard")
+		}
+	}
+	if err := tx.AddDashboardGrants(ctx, dashboard.ID, grants); err != nil {
+		return types.Dashboard{}, errors.Wrap(err, "failed to attach dashboard grants")
+	}
+
+	// The dashboard is read back without checking its grants, as it may not be granted to anyone yet.
+	dashboards, err := scanDashboards(tx.Query(ctx, sqlf.Sprintf(getDashboardsSql, sqlf.Sprintf("db.id = %s", dashboard.ID))))
+	if err != nil {
+		return types.Dashboard{}, err
+	}
+	if len(dashboards) == 0 {
+		return types.Dashboard{}, ErrDashboardNotFound
+	}
+	return dashboards[0], nil
+}
+
+// AddDashboardGrants grants the dashboard with the given ID to the principals of the given grants.
+// Granting a dashboard to a principal that it is already granted to is a no-op.
+func (s *InsightStore) AddDashboardGrants(ctx context.Context, dashboardID int, grants []DashboardGrant) error {
+	if dashboardID == 0 {
+		return errors.New("unable to grant dashboard permissions invalid dashboard id")
+	} else if len(grants) == 0 {
+		return nil
+	}
+
+	values := make([]*sqlf.Query, 0, len(grants))
+	for _, grant := range grants {
+		values = append(values, grant.toQuery(dashboardID))
+	}
+	return s.Exec(ctx, sqlf.Sprintf(addDashboardGrantsSql, sqlf.Join(values, ",\n")))
+}
+
+// RemoveDashboardGrants revokes the given grants of the dashboard with the given ID. Revoking a grant
+// that does not exist is a no-op.
+func (s *InsightStore) RemoveDashboardGrants(ctx context.Context, dashboardID int, grants []DashboardGrant) error {
+	if len(grants) == 0 {
+		return nil
+	}
+
+	principals := make([]*sqlf.Query, 0, len(grants))
+	for _, grant := range grants {
+		principals = append(principals, grant.principalQuery())
+	}
+	return s.Exec(ctx, sqlf.Sprintf(removeDashboardGrantsSql, dashboardID, sqlf.Join(principals, "OR")))
+}
+
+const getDashboardsSql = `
+-- source: enterprise/internal/insights/store/dashboard_store.go:GetDashboards
+SELECT db.id, db.title, db.created_at, db.created_by,
+       ARRAY(SELECT iv.unique_id
+             FROM dashboard_insight_view div
+                      JOIN insight_view iv ON div.insight_view_id = iv.id
+             WHERE div.dashboard_id = db.id
+             ORDER BY div.id) AS insight_ids
+FROM dashboard db
+WHERE %s
+ORDER BY db.id
+`
+
+const getDashboardGrantsSql = `
+-- source: enterprise/internal/insights/store/dashboard_store.go:GetDashboardGrants
+SELECT g.dashboard_id, g.user_id, g.org_id, g.global
+FROM dashboard_grants g
+         JOIN dashboard db ON g.dashboard_id = db.id
+WHERE %s
+ORDER BY g.dashboard_id, g.id
+`
+
+const createDashboardSql = `
+-- source: enterprise/internal/insights/store/dashboard_store.go:CreateDashboard
+INSERT INTO dashboard (title, created_at, created_by)
+VALUES (%s, %s, %s)
+RETURNING id, created_at;
+`
+
+const attachViewsToDashboardSql = `
+-- source: enterprise/internal/insights/store/dashboard_store.go:CreateDashboard
+INSERT INTO dashboard_insight_view (dashboard_id, insight_view_id)
+SELECT %s, id FROM insight_view WHERE unique_id = ANY(%s)
+ON CONFLICT DO NOTHING;
+`
+
+const addDashboardGrantsSql = `
+-- source: enterprise/internal/insights/store/dashboard_store.go:AddDashboardGrants
+INSERT INTO dashboard_grants (dashboard_id, org_id, user_id, global)
+VALUES %s
+ON CONFLICT DO NOTHING;
+`
+
+const removeDashboardGrantsSql = `
+-- source: enterprise/internal/insights/store/dashboard_store.go:RemoveDashboardGrants
+DELETE FROM dashboard_grants
+WHERE dashboard_id = %s AND (%s);
+`
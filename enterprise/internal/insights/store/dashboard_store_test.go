@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hexops/autogold"
+
+	insightsdbtesting "github.com/sourcegraph/sourcegraph/enterprise/internal/insights/dbtesting"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+)
+
+func TestInsightStore_Dashboards(t *testing.T) {
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	now := time.Date(2021, 9, 15, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	store := NewInsightStore(timescale)
+	store.Now = func() time.Time {
+		return now
+	}
+
+	_, err := timescale.Exec(`INSERT INTO insight_view (title, description, unique_id)
+									VALUES ('test title', 'test description', 'unique-1'),
+									       ('test title 2', 'test description 2', 'unique-2')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := int32(1)
+	private, err := store.CreateDashboard(ctx, types.Dashboard{
+		Title:      "private",
+		CreatedBy:  &userID,
+		InsightIDs: []string{"unique-2", "unique-1", "missing"},
+	}, []DashboardGrant{UserDashboardGrant(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.Dashboard{
+		ID:         private.ID,
+		Title:      "private",
+		CreatedAt:  now,
+		CreatedBy:  &userID,
+		InsightIDs: []string{"unique-2", "unique-1"},
+	}
+	if diff := cmp.Diff(want, private); diff != "" {
+		t.Errorf("unexpected created dashboard (-want +got):\n%s", diff)
+	}
+
+	org, err := store.CreateDashboard(ctx, types.Dashboard{Title: "org"}, []DashboardGrant{OrgDashboardGrant(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	global, err := store.CreateDashboard(ctx, types.Dashboard{Title: "global"}, []DashboardGrant{GlobalDashboardGrant()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := func(t *testing.T, args DashboardQueryArgs) []string {
+		t.Helper()
+		dashboards, err := store.GetDashboards(ctx, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, d := range dashboards {
+			titles = append(titles, d.Title)
+		}
+		return titles
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		autogold.Want("anonymous", []string{"global"}).Equal(t, titles(t, DashboardQueryArgs{}))
+	})
+	t.Run("user", func(t *testing.T) {
+		autogold.Want("user", []string{"private", "global"}).Equal(t, titles(t, DashboardQueryArgs{UserID: []int{1}}))
+	})
+	t.Run("org member", func(t *testing.T) {
+		autogold.Want("org member", []string{"org", "global"}).Equal(t, titles(t, DashboardQueryArgs{UserID: []int{2}, OrgID: []int{5}}))
+	})
+	t.Run("by id without grant", func(t *testing.T) {
+		autogold.Want("by id without grant", []string(nil)).Equal(t, titles(t, DashboardQueryArgs{ID: []int{private.ID}, UserID: []int{2}}))
+	})
+
+	t.Run("share and unshare", func(t *testing.T) {
+		// Sharing twice is a no-op.
+		for i := 0; i < 2; i++ {
+			if err := store.AddDashboardGrants(ctx, private.ID, []DashboardGrant{UserDashboardGrant(2), OrgDashboardGrant(5)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		autogold.Want("shared with user", []string{"private", "global"}).Equal(t, titles(t, DashboardQueryArgs{UserID: []int{2}}))
+
+		grants, err := store.GetDashboardGrants(ctx, DashboardQueryArgs{ID: []int{private.ID}, UserID: []int{2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("grants", 3).Equal(t, len(grants))
+
+		if err := store.RemoveDashboardGrants(ctx, private.ID, []DashboardGrant{UserDashboardGrant(2), OrgDashboardGrant(5)}); err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("unshared", []string{"global"}).Equal(t, titles(t, DashboardQueryArgs{UserID: []int{2}}))
+
+		// The grants of a dashboard are only listed for the principals it is granted to.
+		grants, err = store.GetDashboardGrants(ctx, DashboardQueryArgs{ID: []int{private.ID, org.ID, global.ID}, UserID: []int{2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("grants after unshare", 1).Equal(t, len(grants))
+	})
+
+	t.Run("insights on dashboard", func(t *testing.T) {
+		_, err = timescale.Exec(`INSERT INTO insight_view_grants (insight_view_id, global) VALUES (1, true), (2, true)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = timescale.Exec(`INSERT INTO insight_series (series_id, query, created_at, oldest_historical_at, last_recorded_at,
+                            next_recording_after, last_snapshot_at, next_snapshot_after, recording_interval_days)
+                            VALUES ('series-id-1', 'query-1', $1, $1, $1, $1, $1, $1, 5);`, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = timescale.Exec(`INSERT INTO insight_view_series (insight_view_id, insight_series_id, label, stroke)
+									VALUES (1, 1, 'label1', 'color1'), (2, 1, 'label2', 'color2');`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		insights, err := store.Get(ctx, InsightQueryArgs{DashboardID: private.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("insights on private dashboard", 2).Equal(t, len(insights))
+
+		insights, err = store.Get(ctx, InsightQueryArgs{DashboardID: global.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		autogold.Want("insights on global dashboard", 0).Equal(t, len(insights))
+	})
+}
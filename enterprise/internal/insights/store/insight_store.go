@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/internal/insights"
@@ -12,6 +14,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
 
 	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
 
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
@@ -49,6 +52,8 @@ type InsightQueryArgs struct {
 	UniqueID  string
 	UserID    []int
 	OrgID     []int
+	// DashboardID filters for the insight views on the dashboard with the given ID.
+	DashboardID int
 }
 
 // Get returns all matching viewable insight series.
@@ -65,6 +70,9 @@ func (s *InsightStore) Get(ctx context.Context, args InsightQueryArgs) ([]types.
 	if len(args.UniqueID) > 0 {
 		preds = append(preds, sqlf.Sprintf("iv.unique_id = %s", args.UniqueID))
 	}
+	if args.DashboardID != 0 {
+		preds = append(preds, sqlf.Sprintf("iv.id IN (SELECT insight_view_id FROM dashboard_insight_view WHERE dashboard_id = %s)", args.DashboardID))
+	}
 	preds = append(preds, viewPermissionsQuery(args))
 
 	if len(preds) == 0 {
@@ -210,6 +218,8 @@ type GetDataSeriesArgs struct {
 	Deleted             bool
 	BackfillIncomplete  bool
 	SeriesID            string
+	// ExcludePaused will filter out series for which recording is currently paused.
+	ExcludePaused bool
 }
 
 func (s *InsightStore) GetDataSeries(ctx context.Context, args GetDataSeriesArgs) ([]types.InsightSeries, error) {
@@ -235,6 +245,9 @@ func (s *InsightStore) GetDataSeries(ctx context.Context, args GetDataSeriesArgs
 	if len(args.SeriesID) > 0 {
 		preds = append(preds, sqlf.Sprintf("series_id = %s", args.SeriesID))
 	}
+	if args.ExcludePaused {
+		preds = append(preds, sqlf.Sprintf("paused_at IS NULL"))
+	}
 
 	q := sqlf.Sprintf(getInsightDataSeriesSql, sqlf.Join(preds, "\n AND"))
 	return scanDataSeries(s.Query(ctx, q))
@@ -249,6 +262,7 @@ func scanDataSeries(rows *sql.Rows, queryErr error) (_ []types.InsightSeries, er
 	results := make([]types.InsightSeries, 0)
 	for rows.Next() {
 		var temp types.InsightSeries
+		var revisions dbutil.NullJSONRawMessage
 		if err := rows.Scan(
 			&temp.ID,
 			&temp.SeriesID,
@@ -260,9 +274,21 @@ func scanDataSeries(rows *sql.Rows, queryErr error) (_ []types.InsightSeries, er
 			&temp.RecordingIntervalDays,
 			&temp.LastSnapshotAt,
 			&temp.NextSnapshotAfter,
+			&temp.PausedAt,
+			&dbutil.NullString{S: &temp.PauseReason},
+			&temp.PatternType,
+			&temp.GenerationMethod,
+			&revisions,
+			pq.Array(&temp.Repositories),
+			&dbutil.NullString{S: &temp.Team},
 		); err != nil {
 			return []types.InsightSeries{}, err
 		}
+		if revisions.Raw != nil {
+			if err := json.Unmarshal(revisions.Raw, &temp.RepositoryRevisions); err != nil {
+				return []types.InsightSeries{}, errors.Wrap(err, "unmarshalling repository revisions")
+			}
+		}
 		results = append(results, temp)
 	}
 	return results, nil
@@ -293,6 +319,9 @@ func scanInsightViewSeries(rows *sql.Rows, queryErr error) (_ []types.InsightVie
 			&temp.RecordingIntervalDays,
 			&temp.LastSnapshotAt,
 			&temp.NextSnapshotAfter,
+			&temp.PausedAt,
+			&dbutil.NullString{S: &temp.PauseReason},
+			&temp.GenerationMethod,
 		); err != nil {
 			return []types.InsightViewSeries{}, err
 		}
@@ -387,7 +416,41 @@ delete from insight_view where %s;
 `
 
 // CreateSeries will create a new insight data series. This series must be uniquely identified by the series ID.
+// Series without a generation method are search series, and series without a pattern type are literal, except for
+// search-compute series which are always regexp. Series with a query that can not be parsed with their pattern type
+// are rejected with an *InvalidSeriesQueryError. Repository revisions are rejected unless they can be used
+// in a repo: filter. Language-stats series have no query, but must have repositories, and can't be scoped to a team.
 func (s *InsightStore) CreateSeries(ctx context.Context, series types.InsightSeries) (types.InsightSeries, error) {
+	switch series.GenerationMethod {
+	case "":
+		series.GenerationMethod = types.GenerationMethodSearch
+	case types.GenerationMethodSearch:
+	case types.GenerationMethodSearchCompute:
+		// The compute endpoint matches the query as a regular expression.
+		if series.PatternType == "" {
+			series.PatternType = types.PatternTypeRegexp
+		}
+		if series.PatternType != types.PatternTypeRegexp {
+			return types.InsightSeries{}, &InvalidSeriesQueryError{Query: series.Query, Err: errors.Errorf("%s series must use the %s pattern type", series.GenerationMethod, types.PatternTypeRegexp)}
+		}
+	case types.GenerationMethodLanguageStats:
+		if len(series.Repositories) == 0 {
+			return types.InsightSeries{}, errors.Errorf("%s series must have repositories", series.GenerationMethod)
+		}
+		if series.Team != "" {
+			return types.InsightSeries{}, errors.Errorf("%s series can't be scoped to a team", series.GenerationMethod)
+		}
+	default:
+		return types.InsightSeries{}, errors.Errorf("unknown insight series generation method %q", series.GenerationMethod)
+	}
+	if series.PatternType == "" {
+		series.PatternType = types.PatternTypeLiteral
+	}
+	if series.GenerationMethod != types.GenerationMethodLanguageStats {
+		if err := ValidateSeriesQuery(series.Query, series.PatternType); err != nil {
+			return types.InsightSeries{}, err
+		}
+	}
 	if series.CreatedAt.IsZero() {
 		series.CreatedAt = s.Now()
 	}
@@ -397,6 +460,20 @@ func (s *InsightStore) CreateSeries(ctx context.Context, series types.InsightSer
 	if series.NextSnapshotAfter.IsZero() {
 		series.NextSnapshotAfter = s.Now()
 	}
+	for repoName, revision := range series.RepositoryRevisions {
+		// The revisions are injected into search queries, so they must be a single token.
+		if repoName == "" || revision == "" || strings.HasPrefix(revision, "-") || strings.ContainsAny(repoName+revision, " \t\n:") {
+			return types.InsightSeries{}, errors.Errorf("invalid revision %q of repository %q", revision, repoName)
+		}
+	}
+	var revisions dbutil.NullString
+	if len(series.RepositoryRevisions) > 0 {
+		marshalled, err := json.Marshal(series.RepositoryRevisions)
+		if err != nil {
+			return types.InsightSeries{}, err
+		}
+		revisions = dbutil.NewNullString(string(marshalled))
+	}
 	if series.OldestHistoricalAt.IsZero() {
 		// TODO(insights): this value should probably somewhere more discoverable / obvious than here
 		series.OldestHistoricalAt = s.Now().Add(-time.Hour * 24 * 7 * 26)
@@ -411,6 +488,11 @@ func (s *InsightStore) CreateSeries(ctx context.Context, series types.InsightSer
 		series.RecordingIntervalDays,
 		series.LastSnapshotAt,
 		series.NextSnapshotAfter,
+		series.PatternType,
+		series.GenerationMethod,
+		revisions,
+		pq.Array(series.Repositories),
+		dbutil.NewNullString(series.Team),
 	))
 	var id int
 	err := row.Scan(&id)
@@ -426,6 +508,8 @@ type DataSeriesStore interface {
 	StampRecording(ctx context.Context, series types.InsightSeries) (types.InsightSeries, error)
 	StampSnapshot(ctx context.Context, series types.InsightSeries) (types.InsightSeries, error)
 	StampBackfill(ctx context.Context, series types.InsightSeries) (types.InsightSeries, error)
+	PauseSeries(ctx context.Context, seriesID string, reason string) error
+	ResumeSeries(ctx context.Context, seriesID string) error
 }
 
 type InsightMetadataStore interface {
@@ -468,6 +552,75 @@ func (s *InsightStore) StampBackfill(ctx context.Context, series types.InsightSe
 	return series, nil
 }
 
+// ErrSeriesNotFound is returned when pausing or resuming a series that does not exist.
+var ErrSeriesNotFound = errors.New("insight series not found")
+
+// PauseSeries pauses recording for the series with the given series ID. While paused, the series is not
+// scheduled for recordings, snapshots or backfills. Pausing an already paused series updates the reason.
+func (s *InsightStore) PauseSeries(ctx context.Context, seriesID string, reason string) error {
+	ids, err := basestore.ScanInts(s.Query(ctx, sqlf.Sprintf(pauseSeriesSql, s.Now(), reason, seriesID)))
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrSeriesNotFound
+	}
+	return nil
+}
+
+// ResumeSeries resumes recording for the series with the given series ID. The gap in the data caused by
+// the pause is recorded as a dirty query with the pause reason, so that it shows up in the series' dirty
+// metadata. Resuming a series that is not paused is a no-op.
+func (s *InsightStore) ResumeSeries(ctx context.Context, seriesID string) (err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Store.Done(err) }()
+
+	series, err := scanDataSeries(tx.Query(ctx, sqlf.Sprintf(getInsightDataSeriesSql+"FOR UPDATE", sqlf.Sprintf("series_id = %s AND deleted_at IS NULL", seriesID))))
+	if err != nil {
+		return err
+	}
+	if len(series) == 0 {
+		return ErrSeriesNotFound
+	}
+	paused := series[0]
+	if paused.PausedAt == nil {
+		return nil
+	}
+
+	reason := "series paused"
+	if paused.PauseReason != "" {
+		reason += ": " + paused.PauseReason
+	}
+	if err := tx.InsertDirtyQuery(ctx, &paused, &types.DirtyQuery{
+		Query:   paused.Query,
+		Reason:  reason,
+		ForTime: *paused.PausedAt,
+	}); err != nil {
+		return errors.Wrap(err, "recording pause gap")
+	}
+	return tx.Exec(ctx, sqlf.Sprintf(resumeSeriesSql, paused.ID))
+}
+
+const pauseSeriesSql = `
+-- source: enterprise/internal/insights/store/insight_store.go:PauseSeries
+UPDATE insight_series
+SET paused_at = COALESCE(paused_at, %s),
+    pause_reason = %s
+WHERE series_id = %s AND deleted_at IS NULL
+RETURNING id;
+`
+
+const resumeSeriesSql = `
+-- source: enterprise/internal/insights/store/insight_store.go:ResumeSeries
+UPDATE insight_series
+SET paused_at = NULL,
+    pause_reason = NULL
+WHERE id = %s;
+`
+
 const stampBackfillSql = `
 -- source: enterprise/internal/insights/store/insight_store.go:StampRecording
 UPDATE insight_series
@@ -506,15 +659,17 @@ returning id;`
 const createInsightSeriesSql = `
 -- source: enterprise/internal/insights/store/insight_store.go:CreateSeries
 INSERT INTO insight_series (series_id, query, created_at, oldest_historical_at, last_recorded_at,
-                            next_recording_after, recording_interval_days, last_snapshot_at, next_snapshot_after)
-VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+                            next_recording_after, recording_interval_days, last_snapshot_at, next_snapshot_after, pattern_type, generation_method,
+                            repository_revisions, repositories, team)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
 RETURNING id;`
 
 const getInsightByViewSql = `
 -- source: enterprise/internal/insights/store/insight_store.go:Get
 SELECT iv.unique_id, iv.title, iv.description, ivs.label, ivs.stroke,
 i.series_id, i.query, i.created_at, i.oldest_historical_at, i.last_recorded_at,
-i.next_recording_after, i.backfill_queued_at, i.recording_interval_days, i.last_snapshot_at, i.next_snapshot_after
+i.next_recording_after, i.backfill_queued_at, i.recording_interval_days, i.last_snapshot_at, i.next_snapshot_after,
+i.paused_at, i.pause_reason, i.generation_method
 FROM insight_view iv
          JOIN insight_view_series ivs ON iv.id = ivs.insight_view_id
          JOIN insight_series i ON ivs.insight_series_id = i.id
@@ -525,6 +680,6 @@ ORDER BY iv.unique_id, i.series_id
 
 const getInsightDataSeriesSql = `
 -- source: enterprise/internal/insights/store/insight_store.go:GetDataSeries
-select id, series_id, query, created_at, oldest_historical_at, last_recorded_at, next_recording_after, recording_interval_days, last_snapshot_at, next_snapshot_after from insight_series
+select id, series_id, query, created_at, oldest_historical_at, last_recorded_at, next_recording_after, recording_interval_days, last_snapshot_at, next_snapshot_after, paused_at, pause_reason, pattern_type, generation_method, repository_revisions, repositories, team from insight_series
 WHERE %s
 `
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/hexops/autogold"
 
 	"github.com/inconshreveable/log15"
@@ -75,6 +76,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 5,
 				Label:                 "label1",
 				Stroke:                "color1",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 			{
 				UniqueID:              "unique-1",
@@ -91,6 +93,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 6,
 				Label:                 "label2",
 				Stroke:                "color2",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 			{
 				UniqueID:              "unique-2",
@@ -107,6 +110,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 6,
 				Label:                 "second-label-2",
 				Stroke:                "second-color-2",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 		}
 
@@ -139,6 +143,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 5,
 				Label:                 "label1",
 				Stroke:                "color1",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 			{
 				UniqueID:              "unique-1",
@@ -155,6 +160,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 6,
 				Label:                 "label2",
 				Stroke:                "color2",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 		}
 
@@ -186,6 +192,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 5,
 				Label:                 "label1",
 				Stroke:                "color1",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 			{
 				UniqueID:              "unique-1",
@@ -202,6 +209,7 @@ func TestGet(t *testing.T) {
 				RecordingIntervalDays: 6,
 				Label:                 "label2",
 				Stroke:                "color2",
+				GenerationMethod:      types.GenerationMethodSearch,
 			},
 		}
 
@@ -252,6 +260,8 @@ func TestCreateSeries(t *testing.T) {
 			NextSnapshotAfter:     now,
 			RecordingIntervalDays: 4,
 			CreatedAt:             now,
+			PatternType:           types.PatternTypeLiteral,
+			GenerationMethod:      types.GenerationMethodSearch,
 		}
 
 		log15.Info("values", "want", want, "got", got)
@@ -260,6 +270,167 @@ func TestCreateSeries(t *testing.T) {
 			t.Errorf("unexpected result from create insight series (want/got): %s", diff)
 		}
 	})
+
+	t.Run("test create series with invalid query", func(t *testing.T) {
+		series := types.InsightSeries{
+			SeriesID: "unique-invalid",
+			Query:    "repo:foo count:notanumber",
+		}
+
+		_, err := store.CreateSeries(ctx, series)
+		var invalidErr *InvalidSeriesQueryError
+		if !errors.As(err, &invalidErr) {
+			t.Fatalf("expected InvalidSeriesQueryError, got %v", err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{SeriesID: "unique-invalid"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected invalid series not to be created, got %v", got)
+		}
+	})
+
+	t.Run("test create series with pattern type", func(t *testing.T) {
+		_, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:    "unique-structural",
+			Query:       "fmt.Sprintf(:[args])",
+			PatternType: types.PatternTypeStructural,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{SeriesID: "unique-structural"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].PatternType != types.PatternTypeStructural {
+			t.Errorf("expected structural series, got %v", got)
+		}
+
+		_, err = store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:    "unique-glob",
+			Query:       "foo",
+			PatternType: "glob",
+		})
+		var invalidErr *InvalidSeriesQueryError
+		if !errors.As(err, &invalidErr) {
+			t.Fatalf("expected InvalidSeriesQueryError for unknown pattern type, got %v", err)
+		}
+	})
+
+	t.Run("test create search-compute series", func(t *testing.T) {
+		got, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:         "unique-compute",
+			Query:            `file:go\.mod$ go\s*(\d\.\d+)`,
+			GenerationMethod: types.GenerationMethodSearchCompute,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.PatternType != types.PatternTypeRegexp {
+			t.Errorf("expected search-compute series to default to the regexp pattern type, got %q", got.PatternType)
+		}
+
+		_, err = store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:         "unique-compute-literal",
+			Query:            "go 1.17",
+			PatternType:      types.PatternTypeLiteral,
+			GenerationMethod: types.GenerationMethodSearchCompute,
+		})
+		var invalidErr *InvalidSeriesQueryError
+		if !errors.As(err, &invalidErr) {
+			t.Fatalf("expected InvalidSeriesQueryError for literal search-compute series, got %v", err)
+		}
+	})
+
+	t.Run("test create series with repository revisions", func(t *testing.T) {
+		revisions := map[string]string{"github.com/sourcegraph/sourcegraph": "3.33"}
+		if _, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:            "unique-revisions",
+			Query:               "TODO",
+			RepositoryRevisions: revisions,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{SeriesID: "unique-revisions"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("expected one series, got %v", got)
+		}
+		if diff := cmp.Diff(revisions, got[0].RepositoryRevisions); diff != "" {
+			t.Errorf("unexpected repository revisions (-want +got):\n%s", diff)
+		}
+
+		if _, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:            "unique-invalid-revision",
+			Query:               "TODO",
+			RepositoryRevisions: map[string]string{"github.com/sourcegraph/sourcegraph": "main count:1"},
+		}); err == nil {
+			t.Error("expected an error for a revision that is not a single token")
+		}
+	})
+
+	t.Run("test create language stats series", func(t *testing.T) {
+		repos := []string{"github.com/sourcegraph/sourcegraph", "github.com/golang/go"}
+		if _, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:         "unique-language-stats",
+			GenerationMethod: types.GenerationMethodLanguageStats,
+			Repositories:     repos,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{SeriesID: "unique-language-stats"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("expected one series, got %v", got)
+		}
+		if diff := cmp.Diff(repos, got[0].Repositories); diff != "" {
+			t.Errorf("unexpected repositories (-want +got):\n%s", diff)
+		}
+
+		if _, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:         "unique-language-stats-without-repositories",
+			GenerationMethod: types.GenerationMethodLanguageStats,
+		}); err == nil {
+			t.Error("expected an error for a language stats series without repositories")
+		}
+	})
+
+	t.Run("test create series scoped to a team", func(t *testing.T) {
+		if _, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID: "unique-team",
+			Query:    "TODO",
+			Team:     "platform",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{SeriesID: "unique-team"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Team != "platform" {
+			t.Fatalf("expected one series of team platform, got %v", got)
+		}
+
+		if _, err := store.CreateSeries(ctx, types.InsightSeries{
+			SeriesID:         "unique-language-stats-team",
+			GenerationMethod: types.GenerationMethodLanguageStats,
+			Repositories:     []string{"github.com/sourcegraph/sourcegraph"},
+			Team:             "platform",
+		}); err == nil {
+			t.Error("expected an error for a language stats series scoped to a team")
+		}
+	})
 }
 
 func TestCreateView(t *testing.T) {
@@ -554,6 +725,7 @@ func TestAttachSeriesView(t *testing.T) {
 			RecordingIntervalDays: series.RecordingIntervalDays,
 			Label:                 "my label",
 			Stroke:                "my stroke",
+			GenerationMethod:      types.GenerationMethodSearch,
 		}}
 
 		if diff := cmp.Diff(want, got); diff != "" {
@@ -709,6 +881,102 @@ func TestInsightStore_StampBackfill(t *testing.T) {
 	})
 }
 
+func TestInsightStore_PauseResumeSeries(t *testing.T) {
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	now := time.Now().Round(0).Truncate(time.Microsecond)
+	ctx := context.Background()
+
+	store := NewInsightStore(timescale)
+	store.Now = func() time.Time {
+		return now
+	}
+
+	created, err := store.CreateSeries(ctx, types.InsightSeries{
+		SeriesID:              "unique-1",
+		Query:                 "query-1",
+		RecordingIntervalDays: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unknown series", func(t *testing.T) {
+		if err := store.PauseSeries(ctx, "unknown", ""); err != ErrSeriesNotFound {
+			t.Errorf("unexpected error pausing unknown series: %v", err)
+		}
+		if err := store.ResumeSeries(ctx, "unknown"); err != ErrSeriesNotFound {
+			t.Errorf("unexpected error resuming unknown series: %v", err)
+		}
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		if err := store.PauseSeries(ctx, created.SeriesID, "too expensive"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{SeriesID: created.SeriesID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].PausedAt == nil || !got[0].PausedAt.Equal(now) || got[0].PauseReason != "too expensive" {
+			t.Fatalf("series not paused: %+v", got)
+		}
+
+		got, err = store.GetDataSeries(ctx, GetDataSeriesArgs{ExcludePaused: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("unexpected paused series returned: %+v", got)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		if err := store.ResumeSeries(ctx, created.SeriesID); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetDataSeries(ctx, GetDataSeriesArgs{ExcludePaused: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].PausedAt != nil || got[0].PauseReason != "" {
+			t.Fatalf("series not resumed: %+v", got)
+		}
+
+		dirty, err := store.GetDirtyQueries(ctx, &created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirty) != 1 {
+			t.Fatalf("unexpected number of dirty queries: %d", len(dirty))
+		}
+		want := []*types.DirtyQuery{{
+			ID:      dirty[0].ID,
+			Query:   created.Query,
+			Reason:  "series paused: too expensive",
+			ForTime: now,
+			DirtyAt: now,
+		}}
+		if diff := cmp.Diff(want, dirty); diff != "" {
+			t.Errorf("mismatched gap dirty queries (want/got): %v", diff)
+		}
+
+		// Resuming again is a no-op.
+		if err := store.ResumeSeries(ctx, created.SeriesID); err != nil {
+			t.Fatal(err)
+		}
+		dirty, err = store.GetDirtyQueries(ctx, &created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirty) != 1 {
+			t.Errorf("unexpected number of dirty queries: %d", len(dirty))
+		}
+	})
+}
+
 func TestDirtyQueries(t *testing.T) {
 	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
 	defer cleanup()
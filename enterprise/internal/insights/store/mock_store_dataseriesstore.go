@@ -17,6 +17,12 @@ type MockDataSeriesStore struct {
 	// GetDataSeriesFunc is an instance of a mock function object
 	// controlling the behavior of the method GetDataSeries.
 	GetDataSeriesFunc *DataSeriesStoreGetDataSeriesFunc
+	// PauseSeriesFunc is an instance of a mock function object controlling
+	// the behavior of the method PauseSeries.
+	PauseSeriesFunc *DataSeriesStorePauseSeriesFunc
+	// ResumeSeriesFunc is an instance of a mock function object
+	// controlling the behavior of the method ResumeSeries.
+	ResumeSeriesFunc *DataSeriesStoreResumeSeriesFunc
 	// StampBackfillFunc is an instance of a mock function object
 	// controlling the behavior of the method StampBackfill.
 	StampBackfillFunc *DataSeriesStoreStampBackfillFunc
@@ -38,6 +44,16 @@ func NewMockDataSeriesStore() *MockDataSeriesStore {
 				return nil, nil
 			},
 		},
+		PauseSeriesFunc: &DataSeriesStorePauseSeriesFunc{
+			defaultHook: func(context.Context, string, string) error {
+				return nil
+			},
+		},
+		ResumeSeriesFunc: &DataSeriesStoreResumeSeriesFunc{
+			defaultHook: func(context.Context, string) error {
+				return nil
+			},
+		},
 		StampBackfillFunc: &DataSeriesStoreStampBackfillFunc{
 			defaultHook: func(context.Context, types.InsightSeries) (types.InsightSeries, error) {
 				return types.InsightSeries{}, nil
@@ -64,6 +80,12 @@ func NewMockDataSeriesStoreFrom(i DataSeriesStore) *MockDataSeriesStore {
 		GetDataSeriesFunc: &DataSeriesStoreGetDataSeriesFunc{
 			defaultHook: i.GetDataSeries,
 		},
+		PauseSeriesFunc: &DataSeriesStorePauseSeriesFunc{
+			defaultHook: i.PauseSeries,
+		},
+		ResumeSeriesFunc: &DataSeriesStoreResumeSeriesFunc{
+			defaultHook: i.ResumeSeries,
+		},
 		StampBackfillFunc: &DataSeriesStoreStampBackfillFunc{
 			defaultHook: i.StampBackfill,
 		},
@@ -186,6 +208,221 @@ func (c DataSeriesStoreGetDataSeriesFuncCall) Results() []interface{} {
 	return []interface{}{c.Result0, c.Result1}
 }
 
+// DataSeriesStorePauseSeriesFunc describes the behavior when the
+// PauseSeries method of the parent MockDataSeriesStore instance is invoked.
+type DataSeriesStorePauseSeriesFunc struct {
+	defaultHook func(context.Context, string, string) error
+	hooks       []func(context.Context, string, string) error
+	history     []DataSeriesStorePauseSeriesFuncCall
+	mutex       sync.Mutex
+}
+
+// PauseSeries delegates to the next hook function in the queue and stores
+// the parameter and result values of this invocation.
+func (m *MockDataSeriesStore) PauseSeries(v0 context.Context, v1 string, v2 string) error {
+	r0 := m.PauseSeriesFunc.nextHook()(v0, v1, v2)
+	m.PauseSeriesFunc.appendCall(DataSeriesStorePauseSeriesFuncCall{v0, v1, v2, r0})
+	return r0
+}
+
+// SetDefaultHook sets function that is called when the PauseSeries method
+// of the parent MockDataSeriesStore instance is invoked and the hook queue
+// is empty.
+func (f *DataSeriesStorePauseSeriesFunc) SetDefaultHook(hook func(context.Context, string, string) error) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// PauseSeries method of the parent MockDataSeriesStore instance invokes
+// the hook at the front of the queue and discards it. After the queue is
+// empty, the default hook function is invoked for any future action.
+func (f *DataSeriesStorePauseSeriesFunc) PushHook(hook func(context.Context, string, string) error) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *DataSeriesStorePauseSeriesFunc) SetDefaultReturn(r0 error) {
+	f.SetDefaultHook(func(context.Context, string, string) error {
+		return r0
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *DataSeriesStorePauseSeriesFunc) PushReturn(r0 error) {
+	f.PushHook(func(context.Context, string, string) error {
+		return r0
+	})
+}
+
+func (f *DataSeriesStorePauseSeriesFunc) nextHook() func(context.Context, string, string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *DataSeriesStorePauseSeriesFunc) appendCall(r0 DataSeriesStorePauseSeriesFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of DataSeriesStorePauseSeriesFuncCall
+// objects describing the invocations of this function.
+func (f *DataSeriesStorePauseSeriesFunc) History() []DataSeriesStorePauseSeriesFuncCall {
+	f.mutex.Lock()
+	history := make([]DataSeriesStorePauseSeriesFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// DataSeriesStorePauseSeriesFuncCall is an object that describes an
+// invocation of method PauseSeries on an instance of MockDataSeriesStore.
+type DataSeriesStorePauseSeriesFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method
+	// invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method
+	// invocation.
+	Arg1 string
+	// Arg2 is the value of the 3rd argument passed to this method
+	// invocation.
+	Arg2 string
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c DataSeriesStorePauseSeriesFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1, c.Arg2}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c DataSeriesStorePauseSeriesFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0}
+}
+
+// DataSeriesStoreResumeSeriesFunc describes the behavior when the
+// ResumeSeries method of the parent MockDataSeriesStore instance is invoked.
+type DataSeriesStoreResumeSeriesFunc struct {
+	defaultHook func(context.Context, string) error
+	hooks       []func(context.Context, string) error
+	history     []DataSeriesStoreResumeSeriesFuncCall
+	mutex       sync.Mutex
+}
+
+// ResumeSeries delegates to the next hook function in the queue and stores
+// the parameter and result values of this invocation.
+func (m *MockDataSeriesStore) ResumeSeries(v0 context.Context, v1 string) error {
+	r0 := m.ResumeSeriesFunc.nextHook()(v0, v1)
+	m.ResumeSeriesFunc.appendCall(DataSeriesStoreResumeSeriesFuncCall{v0, v1, r0})
+	return r0
+}
+
+// SetDefaultHook sets function that is called when the ResumeSeries method
+// of the parent MockDataSeriesStore instance is invoked and the hook queue
+// is empty.
+func (f *DataSeriesStoreResumeSeriesFunc) SetDefaultHook(hook func(context.Context, string) error) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// ResumeSeries method of the parent MockDataSeriesStore instance invokes
+// the hook at the front of the queue and discards it. After the queue is
+// empty, the default hook function is invoked for any future action.
+func (f *DataSeriesStoreResumeSeriesFunc) PushHook(hook func(context.Context, string) error) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *DataSeriesStoreResumeSeriesFunc) SetDefaultReturn(r0 error) {
+	f.SetDefaultHook(func(context.Context, string) error {
+		return r0
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *DataSeriesStoreResumeSeriesFunc) PushReturn(r0 error) {
+	f.PushHook(func(context.Context, string) error {
+		return r0
+	})
+}
+
+func (f *DataSeriesStoreResumeSeriesFunc) nextHook() func(context.Context, string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *DataSeriesStoreResumeSeriesFunc) appendCall(r0 DataSeriesStoreResumeSeriesFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of DataSeriesStoreResumeSeriesFuncCall
+// objects describing the invocations of this function.
+func (f *DataSeriesStoreResumeSeriesFunc) History() []DataSeriesStoreResumeSeriesFuncCall {
+	f.mutex.Lock()
+	history := make([]DataSeriesStoreResumeSeriesFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// DataSeriesStoreResumeSeriesFuncCall is an object that describes an
+// invocation of method ResumeSeries on an instance of MockDataSeriesStore.
+type DataSeriesStoreResumeSeriesFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method
+	// invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method
+	// invocation.
+	Arg1 string
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c DataSeriesStoreResumeSeriesFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c DataSeriesStoreResumeSeriesFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0}
+}
+
 // DataSeriesStoreStampBackfillFunc describes the behavior when the
 // StampBackfill method of the parent MockDataSeriesStore instance is
 // invoked.
@@ -5,6 +5,7 @@ package store
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // MockInterface is a mock implementation of the Interface interface (from
@@ -12,29 +13,54 @@ import (
 // github.com/sourcegraph/sourcegraph/enterprise/internal/insights/store)
 // used for unit testing.
 type MockInterface struct {
+	// CapturedSeriesPointsFunc is an instance of a mock function object
+	// controlling the behavior of the method CapturedSeriesPoints.
+	CapturedSeriesPointsFunc *InterfaceCapturedSeriesPointsFunc
 	// CountDataFunc is an instance of a mock function object controlling
 	// the behavior of the method CountData.
 	CountDataFunc *InterfaceCountDataFunc
+	// ExportSeriesPointsFunc is an instance of a mock function object
+	// controlling the behavior of the method ExportSeriesPoints.
+	ExportSeriesPointsFunc *InterfaceExportSeriesPointsFunc
 	// RecordSeriesPointFunc is an instance of a mock function object
 	// controlling the behavior of the method RecordSeriesPoint.
 	RecordSeriesPointFunc *InterfaceRecordSeriesPointFunc
 	// RecordSeriesPointsFunc is an instance of a mock function object
 	// controlling the behavior of the method RecordSeriesPoints.
 	RecordSeriesPointsFunc *InterfaceRecordSeriesPointsFunc
+	// RepoSeriesPointsFunc is an instance of a mock function object
+	// controlling the behavior of the method RepoSeriesPoints.
+	RepoSeriesPointsFunc *InterfaceRepoSeriesPointsFunc
+	// SeriesPointSamplesFunc is an instance of a mock function object
+	// controlling the behavior of the method SeriesPointSamples.
+	SeriesPointSamplesFunc *InterfaceSeriesPointSamplesFunc
 	// SeriesPointsFunc is an instance of a mock function object controlling
 	// the behavior of the method SeriesPoints.
 	SeriesPointsFunc *InterfaceSeriesPointsFunc
+	// SeriesRollupsFunc is an instance of a mock function object
+	// controlling the behavior of the method SeriesRollups.
+	SeriesRollupsFunc *InterfaceSeriesRollupsFunc
 }
 
 // NewMockInterface creates a new mock of the Interface interface. All
 // methods return zero values for all results, unless overwritten.
 func NewMockInterface() *MockInterface {
 	return &MockInterface{
+		CapturedSeriesPointsFunc: &InterfaceCapturedSeriesPointsFunc{
+			defaultHook: func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error) {
+				return nil, nil
+			},
+		},
 		CountDataFunc: &InterfaceCountDataFunc{
 			defaultHook: func(context.Context, CountDataOpts) (int, error) {
 				return 0, nil
 			},
 		},
+		ExportSeriesPointsFunc: &InterfaceExportSeriesPointsFunc{
+			defaultHook: func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error {
+				return nil
+			},
+		},
 		RecordSeriesPointFunc: &InterfaceRecordSeriesPointFunc{
 			defaultHook: func(context.Context, RecordSeriesPointArgs) error {
 				return nil
@@ -45,11 +71,26 @@ func NewMockInterface() *MockInterface {
 				return nil
 			},
 		},
+		RepoSeriesPointsFunc: &InterfaceRepoSeriesPointsFunc{
+			defaultHook: func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error) {
+				return nil, nil
+			},
+		},
+		SeriesPointSamplesFunc: &InterfaceSeriesPointSamplesFunc{
+			defaultHook: func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error) {
+				return nil, nil
+			},
+		},
 		SeriesPointsFunc: &InterfaceSeriesPointsFunc{
 			defaultHook: func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error) {
 				return nil, nil
 			},
 		},
+		SeriesRollupsFunc: &InterfaceSeriesRollupsFunc{
+			defaultHook: func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error) {
+				return nil, nil
+			},
+		},
 	}
 }
 
@@ -57,21 +98,145 @@ func NewMockInterface() *MockInterface {
 // All methods delegate to the given implementation, unless overwritten.
 func NewMockInterfaceFrom(i Interface) *MockInterface {
 	return &MockInterface{
+		CapturedSeriesPointsFunc: &InterfaceCapturedSeriesPointsFunc{
+			defaultHook: i.CapturedSeriesPoints,
+		},
 		CountDataFunc: &InterfaceCountDataFunc{
 			defaultHook: i.CountData,
 		},
+		ExportSeriesPointsFunc: &InterfaceExportSeriesPointsFunc{
+			defaultHook: i.ExportSeriesPoints,
+		},
 		RecordSeriesPointFunc: &InterfaceRecordSeriesPointFunc{
 			defaultHook: i.RecordSeriesPoint,
 		},
 		RecordSeriesPointsFunc: &InterfaceRecordSeriesPointsFunc{
 			defaultHook: i.RecordSeriesPoints,
 		},
+		RepoSeriesPointsFunc: &InterfaceRepoSeriesPointsFunc{
+			defaultHook: i.RepoSeriesPoints,
+		},
+		SeriesPointSamplesFunc: &InterfaceSeriesPointSamplesFunc{
+			defaultHook: i.SeriesPointSamples,
+		},
 		SeriesPointsFunc: &InterfaceSeriesPointsFunc{
 			defaultHook: i.SeriesPoints,
 		},
+		SeriesRollupsFunc: &InterfaceSeriesRollupsFunc{
+			defaultHook: i.SeriesRollups,
+		},
 	}
 }
 
+// InterfaceCapturedSeriesPointsFunc describes the behavior when the
+// CapturedSeriesPoints method of the parent MockInterface instance is
+// invoked.
+type InterfaceCapturedSeriesPointsFunc struct {
+	defaultHook func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error)
+	hooks       []func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error)
+	history     []InterfaceCapturedSeriesPointsFuncCall
+	mutex       sync.Mutex
+}
+
+// CapturedSeriesPoints delegates to the next hook function in the queue and
+// stores the parameter and result values of this invocation.
+func (m *MockInterface) CapturedSeriesPoints(v0 context.Context, v1 SeriesPointsOpts) ([]SeriesPoint, error) {
+	r0, r1 := m.CapturedSeriesPointsFunc.nextHook()(v0, v1)
+	m.CapturedSeriesPointsFunc.appendCall(InterfaceCapturedSeriesPointsFuncCall{v0, v1, r0, r1})
+	return r0, r1
+}
+
+// SetDefaultHook sets function that is called when the CapturedSeriesPoints
+// method of the parent MockInterface instance is invoked and the hook queue
+// is empty.
+func (f *InterfaceCapturedSeriesPointsFunc) SetDefaultHook(hook func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error)) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// CapturedSeriesPoints method of the parent MockInterface instance invokes
+// the hook at the front of the queue and discards it. After the queue is
+// empty, the default hook function is invoked for any future action.
+func (f *InterfaceCapturedSeriesPointsFunc) PushHook(hook func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error)) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *InterfaceCapturedSeriesPointsFunc) SetDefaultReturn(r0 []SeriesPoint, r1 error) {
+	f.SetDefaultHook(func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error) {
+		return r0, r1
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *InterfaceCapturedSeriesPointsFunc) PushReturn(r0 []SeriesPoint, r1 error) {
+	f.PushHook(func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error) {
+		return r0, r1
+	})
+}
+
+func (f *InterfaceCapturedSeriesPointsFunc) nextHook() func(context.Context, SeriesPointsOpts) ([]SeriesPoint, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *InterfaceCapturedSeriesPointsFunc) appendCall(r0 InterfaceCapturedSeriesPointsFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of InterfaceCapturedSeriesPointsFuncCall
+// objects describing the invocations of this function.
+func (f *InterfaceCapturedSeriesPointsFunc) History() []InterfaceCapturedSeriesPointsFuncCall {
+	f.mutex.Lock()
+	history := make([]InterfaceCapturedSeriesPointsFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// InterfaceCapturedSeriesPointsFuncCall is an object that describes an
+// invocation of method CapturedSeriesPoints on an instance of
+// MockInterface.
+type InterfaceCapturedSeriesPointsFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method invocation.
+	Arg1 SeriesPointsOpts
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 []SeriesPoint
+	// Result1 is the value of the 2nd result returned from this method
+	// invocation.
+	Result1 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c InterfaceCapturedSeriesPointsFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c InterfaceCapturedSeriesPointsFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0, c.Result1}
+}
+
 // InterfaceCountDataFunc describes the behavior when the CountData method
 // of the parent MockInterface instance is invoked.
 type InterfaceCountDataFunc struct {
@@ -180,6 +345,116 @@ func (c InterfaceCountDataFuncCall) Results() []interface{} {
 	return []interface{}{c.Result0, c.Result1}
 }
 
+// InterfaceExportSeriesPointsFunc describes the behavior when the
+// ExportSeriesPoints method of the parent MockInterface instance is
+// invoked.
+type InterfaceExportSeriesPointsFunc struct {
+	defaultHook func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error
+	hooks       []func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error
+	history     []InterfaceExportSeriesPointsFuncCall
+	mutex       sync.Mutex
+}
+
+// ExportSeriesPoints delegates to the next hook function in the queue and
+// stores the parameter and result values of this invocation.
+func (m *MockInterface) ExportSeriesPoints(v0 context.Context, v1 ExportSeriesPointsOpts, v2 func(RepoSeriesPoint, time.Time, *string) error) error {
+	r0 := m.ExportSeriesPointsFunc.nextHook()(v0, v1, v2)
+	m.ExportSeriesPointsFunc.appendCall(InterfaceExportSeriesPointsFuncCall{v0, v1, v2, r0})
+	return r0
+}
+
+// SetDefaultHook sets function that is called when the ExportSeriesPoints
+// method of the parent MockInterface instance is invoked and the hook queue
+// is empty.
+func (f *InterfaceExportSeriesPointsFunc) SetDefaultHook(hook func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// ExportSeriesPoints method of the parent MockInterface instance invokes
+// the hook at the front of the queue and discards it. After the queue is
+// empty, the default hook function is invoked for any future action.
+func (f *InterfaceExportSeriesPointsFunc) PushHook(hook func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *InterfaceExportSeriesPointsFunc) SetDefaultReturn(r0 error) {
+	f.SetDefaultHook(func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error {
+		return r0
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *InterfaceExportSeriesPointsFunc) PushReturn(r0 error) {
+	f.PushHook(func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error {
+		return r0
+	})
+}
+
+func (f *InterfaceExportSeriesPointsFunc) nextHook() func(context.Context, ExportSeriesPointsOpts, func(RepoSeriesPoint, time.Time, *string) error) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *InterfaceExportSeriesPointsFunc) appendCall(r0 InterfaceExportSeriesPointsFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of InterfaceExportSeriesPointsFuncCall objects
+// describing the invocations of this function.
+func (f *InterfaceExportSeriesPointsFunc) History() []InterfaceExportSeriesPointsFuncCall {
+	f.mutex.Lock()
+	history := make([]InterfaceExportSeriesPointsFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// InterfaceExportSeriesPointsFuncCall is an object that describes an
+// invocation of method ExportSeriesPoints on an instance of MockInterface.
+type InterfaceExportSeriesPointsFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method
+	// invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method
+	// invocation.
+	Arg1 ExportSeriesPointsOpts
+	// Arg2 is the value of the 3rd argument passed to this method
+	// invocation.
+	Arg2 func(RepoSeriesPoint, time.Time, *string) error
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c InterfaceExportSeriesPointsFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1, c.Arg2}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c InterfaceExportSeriesPointsFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0}
+}
+
 // InterfaceRecordSeriesPointFunc describes the behavior when the
 // RecordSeriesPoint method of the parent MockInterface instance is invoked.
 type InterfaceRecordSeriesPointFunc struct {
@@ -393,6 +668,226 @@ func (c InterfaceRecordSeriesPointsFuncCall) Results() []interface{} {
 	return []interface{}{c.Result0}
 }
 
+// InterfaceRepoSeriesPointsFunc describes the behavior when the
+// RepoSeriesPoints method of the parent MockInterface instance is
+// invoked.
+type InterfaceRepoSeriesPointsFunc struct {
+	defaultHook func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error)
+	hooks       []func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error)
+	history     []InterfaceRepoSeriesPointsFuncCall
+	mutex       sync.Mutex
+}
+
+// RepoSeriesPoints delegates to the next hook function in the queue and
+// stores the parameter and result values of this invocation.
+func (m *MockInterface) RepoSeriesPoints(v0 context.Context, v1 RepoSeriesPointsOpts) ([]RepoSeriesPoint, error) {
+	r0, r1 := m.RepoSeriesPointsFunc.nextHook()(v0, v1)
+	m.RepoSeriesPointsFunc.appendCall(InterfaceRepoSeriesPointsFuncCall{v0, v1, r0, r1})
+	return r0, r1
+}
+
+// SetDefaultHook sets function that is called when the RepoSeriesPoints
+// method of the parent MockInterface instance is invoked and the hook queue
+// is empty.
+func (f *InterfaceRepoSeriesPointsFunc) SetDefaultHook(hook func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error)) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// RepoSeriesPoints method of the parent MockInterface instance invokes the
+// hook at the front of the queue and discards it. After the queue is empty, the
+// default hook function is invoked for any future action.
+func (f *InterfaceRepoSeriesPointsFunc) PushHook(hook func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error)) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *InterfaceRepoSeriesPointsFunc) SetDefaultReturn(r0 []RepoSeriesPoint, r1 error) {
+	f.SetDefaultHook(func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error) {
+		return r0, r1
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *InterfaceRepoSeriesPointsFunc) PushReturn(r0 []RepoSeriesPoint, r1 error) {
+	f.PushHook(func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error) {
+		return r0, r1
+	})
+}
+
+func (f *InterfaceRepoSeriesPointsFunc) nextHook() func(context.Context, RepoSeriesPointsOpts) ([]RepoSeriesPoint, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *InterfaceRepoSeriesPointsFunc) appendCall(r0 InterfaceRepoSeriesPointsFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of InterfaceRepoSeriesPointsFuncCall objects
+// describing the invocations of this function.
+func (f *InterfaceRepoSeriesPointsFunc) History() []InterfaceRepoSeriesPointsFuncCall {
+	f.mutex.Lock()
+	history := make([]InterfaceRepoSeriesPointsFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// InterfaceRepoSeriesPointsFuncCall is an object that describes an
+// invocation of method RepoSeriesPoints on an instance of MockInterface.
+type InterfaceRepoSeriesPointsFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method
+	// invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method
+	// invocation.
+	Arg1 RepoSeriesPointsOpts
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 []RepoSeriesPoint
+	// Result1 is the value of the 2nd result returned from this method
+	// invocation.
+	Result1 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c InterfaceRepoSeriesPointsFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c InterfaceRepoSeriesPointsFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0, c.Result1}
+}
+
+// InterfaceSeriesPointSamplesFunc describes the behavior when the
+// SeriesPointSamples method of the parent MockInterface instance is
+// invoked.
+type InterfaceSeriesPointSamplesFunc struct {
+	defaultHook func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error)
+	hooks       []func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error)
+	history     []InterfaceSeriesPointSamplesFuncCall
+	mutex       sync.Mutex
+}
+
+// SeriesPointSamples delegates to the next hook function in the queue and
+// stores the parameter and result values of this invocation.
+func (m *MockInterface) SeriesPointSamples(v0 context.Context, v1 SeriesPointSamplesOpts) ([]SeriesPointSample, error) {
+	r0, r1 := m.SeriesPointSamplesFunc.nextHook()(v0, v1)
+	m.SeriesPointSamplesFunc.appendCall(InterfaceSeriesPointSamplesFuncCall{v0, v1, r0, r1})
+	return r0, r1
+}
+
+// SetDefaultHook sets function that is called when the SeriesPointSamples
+// method of the parent MockInterface instance is invoked and the hook queue
+// is empty.
+func (f *InterfaceSeriesPointSamplesFunc) SetDefaultHook(hook func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error)) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// SeriesPointSamples method of the parent MockInterface instance invokes the
+// hook at the front of the queue and discards it. After the queue is empty, the
+// default hook function is invoked for any future action.
+func (f *InterfaceSeriesPointSamplesFunc) PushHook(hook func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error)) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *InterfaceSeriesPointSamplesFunc) SetDefaultReturn(r0 []SeriesPointSample, r1 error) {
+	f.SetDefaultHook(func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error) {
+		return r0, r1
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *InterfaceSeriesPointSamplesFunc) PushReturn(r0 []SeriesPointSample, r1 error) {
+	f.PushHook(func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error) {
+		return r0, r1
+	})
+}
+
+func (f *InterfaceSeriesPointSamplesFunc) nextHook() func(context.Context, SeriesPointSamplesOpts) ([]SeriesPointSample, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *InterfaceSeriesPointSamplesFunc) appendCall(r0 InterfaceSeriesPointSamplesFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of InterfaceSeriesPointSamplesFuncCall objects
+// describing the invocations of this function.
+func (f *InterfaceSeriesPointSamplesFunc) History() []InterfaceSeriesPointSamplesFuncCall {
+	f.mutex.Lock()
+	history := make([]InterfaceSeriesPointSamplesFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// InterfaceSeriesPointSamplesFuncCall is an object that describes an
+// invocation of method SeriesPointSamples on an instance of MockInterface.
+type InterfaceSeriesPointSamplesFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method
+	// invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method
+	// invocation.
+	Arg1 SeriesPointSamplesOpts
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 []SeriesPointSample
+	// Result1 is the value of the 2nd result returned from this method
+	// invocation.
+	Result1 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c InterfaceSeriesPointSamplesFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c InterfaceSeriesPointSamplesFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0, c.Result1}
+}
+
 // InterfaceSeriesPointsFunc describes the behavior when the SeriesPoints
 // method of the parent MockInterface instance is invoked.
 type InterfaceSeriesPointsFunc struct {
@@ -501,3 +996,112 @@ func (c InterfaceSeriesPointsFuncCall) Args() []interface{} {
 func (c InterfaceSeriesPointsFuncCall) Results() []interface{} {
 	return []interface{}{c.Result0, c.Result1}
 }
+
+// InterfaceSeriesRollupsFunc describes the behavior when the SeriesRollups
+// method of the parent MockInterface instance is invoked.
+type InterfaceSeriesRollupsFunc struct {
+	defaultHook func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error)
+	hooks       []func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error)
+	history     []InterfaceSeriesRollupsFuncCall
+	mutex       sync.Mutex
+}
+
+// SeriesRollups delegates to the next hook function in the queue and stores
+// the parameter and result values of this invocation.
+func (m *MockInterface) SeriesRollups(v0 context.Context, v1 SeriesRollupsOpts) ([]SeriesPoint, error) {
+	r0, r1 := m.SeriesRollupsFunc.nextHook()(v0, v1)
+	m.SeriesRollupsFunc.appendCall(InterfaceSeriesRollupsFuncCall{v0, v1, r0, r1})
+	return r0, r1
+}
+
+// SetDefaultHook sets function that is called when the SeriesRollups method
+// of the parent MockInterface instance is invoked and the hook queue is
+// empty.
+func (f *InterfaceSeriesRollupsFunc) SetDefaultHook(hook func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error)) {
+	f.defaultHook = hook
+}
+
+// PushHook adds a function to the end of hook queue. Each invocation of the
+// SeriesRollups method of the parent MockInterface instance invokes the hook
+// at the front of the queue and discards it. After the queue is empty, the
+// default hook function is invoked for any future action.
+func (f *InterfaceSeriesRollupsFunc) PushHook(hook func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error)) {
+	f.mutex.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mutex.Unlock()
+}
+
+// SetDefaultReturn calls SetDefaultDefaultHook with a function that returns
+// the given values.
+func (f *InterfaceSeriesRollupsFunc) SetDefaultReturn(r0 []SeriesPoint, r1 error) {
+	f.SetDefaultHook(func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error) {
+		return r0, r1
+	})
+}
+
+// PushReturn calls PushDefaultHook with a function that returns the given
+// values.
+func (f *InterfaceSeriesRollupsFunc) PushReturn(r0 []SeriesPoint, r1 error) {
+	f.PushHook(func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error) {
+		return r0, r1
+	})
+}
+
+func (f *InterfaceSeriesRollupsFunc) nextHook() func(context.Context, SeriesRollupsOpts) ([]SeriesPoint, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.hooks) == 0 {
+		return f.defaultHook
+	}
+
+	hook := f.hooks[0]
+	f.hooks = f.hooks[1:]
+	return hook
+}
+
+func (f *InterfaceSeriesRollupsFunc) appendCall(r0 InterfaceSeriesRollupsFuncCall) {
+	f.mutex.Lock()
+	f.history = append(f.history, r0)
+	f.mutex.Unlock()
+}
+
+// History returns a sequence of InterfaceSeriesRollupsFuncCall objects
+// describing the invocations of this function.
+func (f *InterfaceSeriesRollupsFunc) History() []InterfaceSeriesRollupsFuncCall {
+	f.mutex.Lock()
+	history := make([]InterfaceSeriesRollupsFuncCall, len(f.history))
+	copy(history, f.history)
+	f.mutex.Unlock()
+
+	return history
+}
+
+// InterfaceSeriesRollupsFuncCall is an object that describes an invocation
+// of method SeriesRollups on an instance of MockInterface.
+type InterfaceSeriesRollupsFuncCall struct {
+	// Arg0 is the value of the 1st argument passed to this method
+	// invocation.
+	Arg0 context.Context
+	// Arg1 is the value of the 2nd argument passed to this method
+	// invocation.
+	Arg1 SeriesRollupsOpts
+	// Result0 is the value of the 1st result returned from this method
+	// invocation.
+	Result0 []SeriesPoint
+	// Result1 is the value of the 2nd result returned from this method
+	// invocation.
+	Result1 error
+}
+
+// Args returns an interface slice containing the arguments of this
+// invocation.
+func (c InterfaceSeriesRollupsFuncCall) Args() []interface{} {
+	return []interface{}{c.Arg0, c.Arg1}
+}
+
+// Results returns an interface slice containing the results of this
+// invocation.
+func (c InterfaceSeriesRollupsFuncCall) Results() []interface{} {
+	return []interface{}{c.Result0, c.Result1}
+}
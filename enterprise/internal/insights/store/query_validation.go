@@ -0,0 +1,67 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/insights/types"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// InvalidSeriesQueryError is returned when the search query of a series can
+// not be parsed. Such a query will never succeed, so it's reported as
+// non-retryable to the query runner.
+type InvalidSeriesQueryError struct {
+	Query string
+	Err   error
+}
+
+func (e *InvalidSeriesQueryError) Error() string {
+	return fmt.Sprintf("invalid insight series query %q: %s", e.Query, e.Err)
+}
+
+func (e *InvalidSeriesQueryError) Unwrap() error { return e.Err }
+
+func (e *InvalidSeriesQueryError) NonRetryable() bool { return true }
+
+// ValidateSeriesQuery returns an *InvalidSeriesQueryError if the given query
+// is not a valid search query for the given pattern type. An empty pattern
+// type means literal, the default of series.
+func ValidateSeriesQuery(q, patternType string) error {
+	searchType, err := seriesSearchType(patternType)
+	if err != nil {
+		return &InvalidSeriesQueryError{Query: q, Err: err}
+	}
+	if _, err := query.Pipeline(query.Init(q, searchType)); err != nil {
+		return &InvalidSeriesQueryError{Query: q, Err: err}
+	}
+	return nil
+}
+
+// NormalizeSeriesQuery returns a canonical form of the given query, so that queries which only
+// differ in whitespace or in the order of their parameters have the same normal form. Like
+// ValidateSeriesQuery, it returns an *InvalidSeriesQueryError if the query can not be parsed.
+func NormalizeSeriesQuery(q, patternType string) (string, error) {
+	searchType, err := seriesSearchType(patternType)
+	if err != nil {
+		return "", &InvalidSeriesQueryError{Query: q, Err: err}
+	}
+	nodes, err := query.Parse(q, searchType)
+	if err != nil {
+		return "", &InvalidSeriesQueryError{Query: q, Err: err}
+	}
+	return query.StringHuman(nodes), nil
+}
+
+func seriesSearchType(patternType string) (query.SearchType, error) {
+	switch patternType {
+	case "", types.PatternTypeLiteral:
+		return query.SearchTypeLiteral, nil
+	case types.PatternTypeRegexp:
+		return query.SearchTypeRegex, nil
+	case types.PatternTypeStructural:
+		return query.SearchTypeStructural, nil
+	}
+	return 0, errors.Errorf("unknown pattern type %q", patternType)
+}
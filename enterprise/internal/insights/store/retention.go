@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+)
+
+// The resolutions that data points can be downsampled to.
+const (
+	ResolutionDay   = "day"
+	ResolutionWeek  = "week"
+	ResolutionMonth = "month"
+)
+
+// resolutionOrder orders the resolutions from the finest to the coarsest.
+var resolutionOrder = map[string]int{
+	ResolutionDay:   1,
+	ResolutionWeek:  2,
+	ResolutionMonth: 3,
+}
+
+// IsResolution reports whether the given string is a valid resolution.
+func IsResolution(resolution string) bool {
+	_, ok := resolutionOrder[resolution]
+	return ok
+}
+
+// CoarserResolution returns the coarsest of the given resolutions. An empty resolution is the
+// finest.
+func CoarserResolution(a, b string) string {
+	if resolutionOrder[b] > resolutionOrder[a] {
+		return b
+	}
+	return a
+}
+
+// TruncateToResolution returns the start of the day, week or month of the given time in UTC, in
+// the same way as date_trunc in Postgres: weeks start on Monday. Any other resolution returns the
+// time unchanged.
+func TruncateToResolution(t time.Time, resolution string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch resolution {
+	case ResolutionDay:
+		return day
+	case ResolutionWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case ResolutionMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// downsamplePoints keeps only the latest point of every series and captured value in every day,
+// week or month, in the same way as DownsampleSeries. The order of the points is kept.
+func downsamplePoints(points []SeriesPoint, resolution string) []SeriesPoint {
+	if !IsResolution(resolution) {
+		return points
+	}
+
+	type bucket struct {
+		seriesID string
+		capture  string
+		start    int64
+	}
+	latest := map[bucket]int{}
+	for i, point := range points {
+		b := bucket{seriesID: point.SeriesID, start: TruncateToResolution(point.Time, resolution).Unix()}
+		if point.Capture != nil {
+			b.capture = *point.Capture
+		}
+		if j, ok := latest[b]; !ok || point.Time.After(points[j].Time) {
+			latest[b] = i
+		}
+	}
+
+	keep := make([]int, 0, len(latest))
+	for _, i := range latest {
+		keep = append(keep, i)
+	}
+	sort.Ints(keep)
+	downsampled := make([]SeriesPoint, 0, len(keep))
+	for _, i := range keep {
+		downsampled = append(downsampled, points[i])
+	}
+	return downsampled
+}
+
+// seriesPointsTables are the tables that hold the data points of a series, or data derived from
+// them, keyed by series ID and time.
+var seriesPointsTables = []string{
+	recordingTable,
+	snapshotsTable,
+	"series_points_captured",
+	"series_points_rollups",
+	"series_points_repos",
+	"series_points_samples",
+}
+
+// DownsampleSeriesArgs describes arguments for the DownsampleSeries method.
+type DownsampleSeriesArgs struct {
+	// SeriesID is the unique series ID to downsample.
+	SeriesID string
+
+	// Before is the time before which data points are downsampled. Only days, weeks or months
+	// that end before this time are downsampled, so that the point kept for a day, week or month
+	// doesn't change as new points are recorded.
+	Before time.Time
+
+	// Resolution is the resolution to downsample to: ResolutionDay, ResolutionWeek or
+	// ResolutionMonth.
+	Resolution string
+}
+
+// DownsampleSeries deletes the data points of the given series that are older than args.Before,
+// except for the latest point of every day, week or month. All the data recorded for a deleted
+// point in time is deleted, including its rollups and samples, so the remaining points stay
+// consistent. It returns the number of points in time that were deleted.
+func (s *Store) DownsampleSeries(ctx context.Context, args DownsampleSeriesArgs) (_ int, err error) {
+	if !IsResolution(args.Resolution) {
+		return 0, errors.Errorf("invalid resolution %q", args.Resolution)
+	}
+	before := TruncateToResolution(args.Before, args.Resolution)
+
+	tx, err := s.Store.Transact(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	times, err := basestore.ScanTimes(tx.Query(ctx, sqlf.Sprintf(
+		downsampledTimesFmtstr,
+		args.Resolution,
+		args.SeriesID, before,
+		args.SeriesID, before,
+		args.SeriesID, before,
+	)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list downsampled points of series_id: %s", args.SeriesID)
+	}
+	if len(times) == 0 {
+		return 0, nil
+	}
+
+	for _, table := range seriesPointsTables {
+		if err := tx.Exec(ctx, sqlf.Sprintf(deletePointsAtFmtstr, sqlf.Sprintf(table), args.SeriesID, pq.Array(times))); err != nil {
+			return 0, errors.Wrapf(err, "failed to delete downsampled points of series_id %s from %s", args.SeriesID, table)
+		}
+	}
+	return len(times), nil
+}
+
+const downsampledTimesFmtstr = `
+-- source: enterprise/internal/insights/store/retention.go:DownsampleSeries
+SELECT time FROM (
+	SELECT time, ROW_NUMBER() OVER (PARTITION BY date_trunc(%s, time AT TIME ZONE 'UTC') ORDER BY time DESC) AS rank
+	FROM (
+		SELECT time FROM series_points WHERE series_id = %s AND time < %s
+		UNION
+		SELECT time FROM series_points_snapshots WHERE series_id = %s AND time < %s
+		UNION
+		SELECT time FROM series_points_captured WHERE series_id = %s AND time < %s
+	) t
+) ranked
+WHERE rank > 1
+`
+
+const deletePointsAtFmtstr = `
+-- source: enterprise/internal/insights/store/retention.go:DownsampleSeries
+DELETE FROM %s WHERE series_id = %s AND time = ANY(%s::timestamptz[])
+`
+
+// DeleteSeriesPointsBefore deletes all the data recorded for the given series before the given
+// time, including rollups and samples.
+func (s *Store) DeleteSeriesPointsBefore(ctx context.Context, seriesID string, before time.Time) (err error) {
+	tx, err := s.Store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	for _, table := range seriesPointsTables {
+		if err := tx.Exec(ctx, sqlf.Sprintf(deletePointsBeforeFmtstr, sqlf.Sprintf(table), seriesID, before)); err != nil {
+			return errors.Wrapf(err, "failed to delete expired points of series_id %s from %s", seriesID, table)
+		}
+	}
+	return nil
+}
+
+const deletePointsBeforeFmtstr = `
+-- source: enterprise/internal/insights/store/retention.go:DeleteSeriesPointsBefore
+DELETE FROM %s WHERE series_id = %s AND time < %s
+`
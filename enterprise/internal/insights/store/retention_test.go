@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hexops/autogold"
+
+	insightsdbtesting "github.com/sourcegraph/sourcegraph/enterprise/internal/insights/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/timeutil"
+)
+
+func TestTruncateToResolution(t *testing.T) {
+	// A Wednesday.
+	tm := time.Date(2021, time.September, 15, 10, 30, 0, 0, time.UTC)
+	for resolution, want := range map[string]time.Time{
+		ResolutionDay:   time.Date(2021, time.September, 15, 0, 0, 0, 0, time.UTC),
+		ResolutionWeek:  time.Date(2021, time.September, 13, 0, 0, 0, 0, time.UTC),
+		ResolutionMonth: time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC),
+		"":              tm,
+	} {
+		if got := TruncateToResolution(tm, resolution); !got.Equal(want) {
+			t.Errorf("TruncateToResolution(%q) = %s, want %s", resolution, got, want)
+		}
+	}
+
+	// Sundays belong to the week that started on the previous Monday.
+	sunday := time.Date(2021, time.September, 19, 23, 0, 0, 0, time.UTC)
+	autogold.Want("sunday", "2021-09-13T00:00:00Z").Equal(t, TruncateToResolution(sunday, ResolutionWeek).Format(time.RFC3339))
+}
+
+func TestDownsamplePoints(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, time.September, d, 0, 0, 0, 0, time.UTC) }
+	capture := "a"
+	points := []SeriesPoint{
+		{SeriesID: "one", Time: day(14), Value: 4},
+		{SeriesID: "one", Time: day(13), Value: 3},
+		{SeriesID: "one", Time: day(12), Value: 2},
+		{SeriesID: "one", Time: day(6), Value: 1},
+		{SeriesID: "one", Time: day(13), Value: 5, Capture: &capture},
+		{SeriesID: "two", Time: day(13), Value: 6},
+	}
+
+	var got []float64
+	for _, p := range downsamplePoints(points, ResolutionWeek) {
+		got = append(got, p.Value)
+	}
+	autogold.Want("weekly", []float64{4, 2, 5, 6}).Equal(t, got)
+
+	autogold.Want("no resolution", 6).Equal(t, len(downsamplePoints(points, "")))
+}
+
+func TestDownsampleSeries(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	store := NewWithClock(timescale, unauthorizedRepos(nil), timeutil.Now)
+
+	optionalString := func(v string) *string { return &v }
+	optionalRepoID := func(v api.RepoID) *api.RepoID { return &v }
+	day := func(d int) time.Time { return time.Date(2021, time.September, d, 12, 0, 0, 0, time.UTC) }
+
+	// Points every day from Monday, September 6th to Wednesday, September 15th.
+	for d := 6; d <= 15; d++ {
+		if err := store.RecordSeriesPoint(ctx, RecordSeriesPointArgs{
+			SeriesID:    "one",
+			Point:       SeriesPoint{Time: day(d), Value: float64(d)},
+			RepoName:    optionalString("repo1"),
+			RepoID:      optionalRepoID(1),
+			PersistMode: RecordMode,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := store.DownsampleSeries(ctx, DownsampleSeriesArgs{SeriesID: "one", Before: day(15), Resolution: ResolutionWeek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the week of September 6th has ended before September 15th, so the points of the week
+	// that is still in progress are kept.
+	autogold.Want("removed", 6).Equal(t, removed)
+
+	points, err := store.SeriesRollups(ctx, SeriesRollupsOpts{SeriesID: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range points {
+		got = append(got, p.Time.UTC().Format("2006-01-02"))
+	}
+	autogold.Want("remaining", []string{"2021-09-15", "2021-09-14", "2021-09-13", "2021-09-12"}).Equal(t, got)
+
+	if err := store.DeleteSeriesPointsBefore(ctx, "one", day(14)); err != nil {
+		t.Fatal(err)
+	}
+	count, err := store.CountData(ctx, CountDataOpts{SeriesID: optionalString("one")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	autogold.Want("count after delete", 2).Equal(t, count)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
@@ -23,6 +24,11 @@ import (
 // for actual API usage.
 type Interface interface {
 	SeriesPoints(ctx context.Context, opts SeriesPointsOpts) ([]SeriesPoint, error)
+	SeriesRollups(ctx context.Context, opts SeriesRollupsOpts) ([]SeriesPoint, error)
+	CapturedSeriesPoints(ctx context.Context, opts SeriesPointsOpts) ([]SeriesPoint, error)
+	SeriesPointSamples(ctx context.Context, opts SeriesPointSamplesOpts) ([]SeriesPointSample, error)
+	RepoSeriesPoints(ctx context.Context, opts RepoSeriesPointsOpts) ([]RepoSeriesPoint, error)
+	ExportSeriesPoints(ctx context.Context, opts ExportSeriesPointsOpts, fn func(RepoSeriesPoint, time.Time, *string) error) error
 	RecordSeriesPoint(ctx context.Context, v RecordSeriesPointArgs) error
 	RecordSeriesPoints(ctx context.Context, pts []RecordSeriesPointArgs) error
 	CountData(ctx context.Context, opts CountDataOpts) (int, error)
@@ -87,6 +93,10 @@ type SeriesPoint struct {
 	Time     time.Time
 	Value    float64
 	Metadata []byte
+
+	// Capture is the captured value that the point counts the matches of, for series that record
+	// one point per captured value. See CapturedSeriesPoints.
+	Capture *string
 }
 
 func (s *SeriesPoint) String() string {
@@ -115,6 +125,11 @@ type SeriesPointsOpts struct {
 
 	// Limit is the number of data points to query, if non-zero.
 	Limit int
+
+	// Resolution, if set to ResolutionDay, ResolutionWeek or ResolutionMonth, downsamples the
+	// data points to keep only the latest point of every day, week or month. See
+	// DownsampleSeries.
+	Resolution string
 }
 
 // SeriesPoints queries data points over time for a specific insights' series.
@@ -153,7 +168,7 @@ func (s *Store) SeriesPoints(ctx context.Context, opts SeriesPointsOpts) ([]Seri
 		points = append(points, point)
 		return nil
 	})
-	return points, err
+	return downsamplePoints(points, opts.Resolution), err
 }
 
 // Note: the inner query could return duplicate points on its own if we merely did a SUM(value) over
@@ -188,6 +203,19 @@ ORDER BY sub.series_id, sub.interval_time DESC
 // 3. Searches may not complete at the same exact time, so even in a perfect world if the interval
 //    should be 12h it may be off by a minute or so.
 func seriesPointsQuery(opts SeriesPointsOpts) *sqlf.Query {
+	limitClause := ""
+	if opts.Limit > 0 {
+		limitClause = fmt.Sprintf("LIMIT %d", opts.Limit)
+	}
+	return sqlf.Sprintf(
+		fullVectorSeriesAggregation+limitClause,
+		sqlf.Join(seriesPointsPreds(opts), "\n AND "),
+	)
+}
+
+// seriesPointsPreds returns the conditions that the points of a series must satisfy to be included
+// in the results of a query with the given options. The repository name is available as rn.name.
+func seriesPointsPreds(opts SeriesPointsOpts) []*sqlf.Query {
 	preds := []*sqlf.Query{}
 
 	if opts.SeriesID != nil {
@@ -202,10 +230,6 @@ func seriesPointsQuery(opts SeriesPointsOpts) *sqlf.Query {
 	if opts.To != nil {
 		preds = append(preds, sqlf.Sprintf("time <= %s", *opts.To))
 	}
-	limitClause := ""
-	if opts.Limit > 0 {
-		limitClause = fmt.Sprintf("LIMIT %d", opts.Limit)
-	}
 	if len(opts.Included) > 0 {
 		s := fmt.Sprintf("repo_id = any(%v)", values(opts.Included))
 		preds = append(preds, sqlf.Sprintf(s))
@@ -224,12 +248,491 @@ func seriesPointsQuery(opts SeriesPointsOpts) *sqlf.Query {
 	if len(preds) == 0 {
 		preds = append(preds, sqlf.Sprintf("TRUE"))
 	}
-	return sqlf.Sprintf(
-		fullVectorSeriesAggregation+limitClause,
-		sqlf.Join(preds, "\n AND "),
-	)
+	return preds
+}
+
+// CapturedSeriesPoints queries data points over time for a series that records one point per
+// captured value. There is one point for every captured value at every point in time, aggregated
+// across repositories in the same way as SeriesPoints.
+func (s *Store) CapturedSeriesPoints(ctx context.Context, opts SeriesPointsOpts) ([]SeriesPoint, error) {
+	// 🚨 SECURITY: Captured values are aggregated across repositories in the same way as the
+	// points of SeriesPoints, so they must be filtered by the repo permissions of the current user
+	// in the same way. 🚨
+	denylist, err := s.permStore.GetUnauthorizedRepoIDs(ctx)
+	if err != nil {
+		return []SeriesPoint{}, err
+	}
+	opts.Excluded = append(opts.Excluded, denylist...)
+
+	limitClause := ""
+	if opts.Limit > 0 {
+		limitClause = fmt.Sprintf("LIMIT %d", opts.Limit)
+	}
+	q := sqlf.Sprintf(capturedSeriesPointsFmtstr+limitClause, sqlf.Join(seriesPointsPreds(opts), "\n AND "))
+
+	points := make([]SeriesPoint, 0, opts.Limit)
+	err = s.query(ctx, q, func(sc scanner) error {
+		var (
+			point   SeriesPoint
+			capture string
+		)
+		if err := sc.Scan(&point.SeriesID, &point.Time, &capture, &point.Value); err != nil {
+			return err
+		}
+		point.Capture = &capture
+		points = append(points, point)
+		return nil
+	})
+	return downsamplePoints(points, opts.Resolution), err
+}
+
+const capturedSeriesPointsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:CapturedSeriesPoints
+SELECT sub.series_id, sub.time, sub.capture, SUM(sub.value) AS value FROM (
+	SELECT spc.series_id, spc.time, spc.capture, spc.repo_name_id, MAX(spc.value) AS value
+	FROM series_points_captured spc
+	JOIN repo_names rn ON spc.repo_name_id = rn.id
+	WHERE %s
+	GROUP BY spc.series_id, spc.time, spc.capture, spc.repo_name_id
+) sub
+GROUP BY sub.series_id, sub.time, sub.capture
+ORDER BY sub.series_id, sub.time DESC, sub.capture
+`
+
+// RecordCapturedSeriesPointsArgs describes arguments for the RecordCapturedSeriesPoints method.
+type RecordCapturedSeriesPointsArgs struct {
+	// SeriesID is the unique series ID the points belong to.
+	SeriesID string
+
+	// Time is the time of the points.
+	Time time.Time
+
+	// Repository name and DB ID that the points were recorded for.
+	RepoName string
+	RepoID   api.RepoID
+
+	// Values are the number of matches in the repository per captured value.
+	Values map[string]float64
+
+	PersistMode PersistMode
+}
+
+// RecordCapturedSeriesPoints records one data point per captured value for a series that records
+// one point per captured value. See CapturedSeriesPoints.
+func (s *Store) RecordCapturedSeriesPoints(ctx context.Context, args RecordCapturedSeriesPointsArgs) (err error) {
+	var snapshot bool
+	switch args.PersistMode {
+	case RecordMode:
+	case SnapshotMode:
+		snapshot = true
+	default:
+		return errors.Newf("unsupported insights series point persist mode: %v", args.PersistMode)
+	}
+	if len(args.Values) == 0 {
+		return nil
+	}
+
+	tx, err := s.Store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	repoNameID, ok, err := basestore.ScanFirstInt(tx.Query(ctx, sqlf.Sprintf(upsertRepoNameFmtStr, args.RepoName, args.RepoName)))
+	if err != nil {
+		return errors.Wrap(err, "upserting repo name ID")
+	}
+	if !ok {
+		return errors.Wrap(err, "repo name ID not found (this should never happen)")
+	}
+
+	for capture, value := range args.Values {
+		if err := tx.Exec(ctx, sqlf.Sprintf(
+			recordCapturedSeriesPointFmtstr,
+			args.SeriesID,   // series_id
+			args.Time.UTC(), // time
+			capture,         // capture
+			value,           // value
+			args.RepoID,     // repo_id
+			repoNameID,      // repo_name_id
+			snapshot,        // snapshot
+		)); err != nil {
+			return errors.Wrap(err, "recording captured value")
+		}
+	}
+	return nil
+}
+
+const recordCapturedSeriesPointFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:RecordCapturedSeriesPoints
+INSERT INTO series_points_captured (series_id, time, capture, value, repo_id, repo_name_id, snapshot)
+VALUES (%s, %s, %s, %s, %s, %s, %s)
+`
+
+// SeriesRollupsOpts describes options for querying the rollups of an insights' series.
+type SeriesRollupsOpts struct {
+	// SeriesID is the unique series ID to query.
+	SeriesID string
+
+	// Time ranges to query from/to, if non-nil, in UTC.
+	From, To *time.Time
+
+	// Resolution downsamples the data points in the same way as SeriesPointsOpts.Resolution.
+	Resolution string
+}
+
+// SeriesRollups queries data points over time for a specific insights' series from the rollups that
+// are maintained as points are recorded. The result is the same as that of SeriesPoints without
+// any repository filters, but does not require aggregating the raw data points.
+func (s *Store) SeriesRollups(ctx context.Context, opts SeriesRollupsOpts) ([]SeriesPoint, error) {
+	// 🚨 SECURITY: Rollups are aggregated across all repositories, so they can only be used if the
+	// current user can see every repository. Otherwise we fall back to aggregating the raw data
+	// points, which enforces repo permissions. 🚨
+	denylist, err := s.permStore.GetUnauthorizedRepoIDs(ctx)
+	if err != nil {
+		return []SeriesPoint{}, err
+	}
+	if len(denylist) > 0 {
+		return s.SeriesPoints(ctx, SeriesPointsOpts{SeriesID: &opts.SeriesID, From: opts.From, To: opts.To, Resolution: opts.Resolution})
+	}
+
+	preds := []*sqlf.Query{sqlf.Sprintf("series_id = %s", opts.SeriesID)}
+	if opts.From != nil {
+		preds = append(preds, sqlf.Sprintf("time >= %s", *opts.From))
+	}
+	if opts.To != nil {
+		preds = append(preds, sqlf.Sprintf("time <= %s", *opts.To))
+	}
+
+	points := []SeriesPoint{}
+	err = s.query(ctx, sqlf.Sprintf(seriesRollupsFmtstr, sqlf.Join(preds, "\n AND ")), func(sc scanner) error {
+		var point SeriesPoint
+		if err := sc.Scan(&point.SeriesID, &point.Time, &point.Value); err != nil {
+			return err
+		}
+		points = append(points, point)
+		return nil
+	})
+	return downsamplePoints(points, opts.Resolution), err
+}
+
+const seriesRollupsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:SeriesRollups
+SELECT series_id, time, value FROM series_points_rollups
+WHERE %s
+ORDER BY time DESC
+`
+
+// RepoSeriesPoint is the value of a data point of a series in a single repository.
+type RepoSeriesPoint struct {
+	RepoID   api.RepoID
+	RepoName string
+	Value    float64
+}
+
+// RepoSeriesPointsOpts describes options for querying the values of a data point per repository.
+type RepoSeriesPointsOpts struct {
+	// SeriesID is the unique series ID to query.
+	SeriesID string
+
+	// Time is the time of the data point to query.
+	Time time.Time
+
+	// Capture is the captured value of the data point to query, for series that record one
+	// point per captured value. See CapturedSeriesPoints.
+	Capture *string
+
+	// Limit is the number of repositories to query, if non-zero.
+	Limit int
+}
+
+// RepoSeriesPoints queries the repositories that contribute to the data point of the given series
+// at the given time, and their values, largest first. Repositories with a zero value and
+// repositories the current user cannot access are omitted.
+func (s *Store) RepoSeriesPoints(ctx context.Context, opts RepoSeriesPointsOpts) ([]RepoSeriesPoint, error) {
+	// 🚨 SECURITY: The values of individual repositories reveal which repositories exist and
+	// what they contain, so they must be filtered by the repo permissions of the current user,
+	// the same way as SeriesPoints. 🚨
+	denylist, err := s.permStore.GetUnauthorizedRepoIDs(ctx)
+	if err != nil {
+		return []RepoSeriesPoint{}, err
+	}
+
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("sp.series_id = %s", opts.SeriesID),
+		sqlf.Sprintf("sp.time = %s", opts.Time.UTC()),
+		sqlf.Sprintf("sp.value > 0"),
+	}
+	if len(denylist) > 0 {
+		preds = append(preds, sqlf.Sprintf(fmt.Sprintf("sp.repo_id != all(%v)", values(denylist))))
+	}
+	limitClause := sqlf.Sprintf("")
+	if opts.Limit > 0 {
+		limitClause = sqlf.Sprintf("LIMIT %s", opts.Limit)
+	}
+
+	var q *sqlf.Query
+	if opts.Capture != nil {
+		// Captured values are not rolled up per repository, so they are aggregated here in the
+		// same way as CapturedSeriesPoints does.
+		preds = append(preds, sqlf.Sprintf("sp.capture = %s", *opts.Capture))
+		q = sqlf.Sprintf(capturedRepoSeriesPointsFmtstr, sqlf.Join(preds, "\n AND "), limitClause)
+	} else {
+		q = sqlf.Sprintf(repoSeriesPointsFmtstr, sqlf.Join(preds, "\n AND "), limitClause)
+	}
+
+	points := []RepoSeriesPoint{}
+	err = s.query(ctx, q, func(sc scanner) error {
+		var point RepoSeriesPoint
+		if err := sc.Scan(&point.RepoID, &point.RepoName, &point.Value); err != nil {
+			return err
+		}
+		points = append(points, point)
+		return nil
+	})
+	return points, err
 }
 
+const repoSeriesPointsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:RepoSeriesPoints
+SELECT sp.repo_id, rn.name, sp.value
+FROM series_points_repos sp
+JOIN repo_names rn ON sp.repo_name_id = rn.id
+WHERE %s
+ORDER BY sp.value DESC, rn.name
+%s
+`
+
+const capturedRepoSeriesPointsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:RepoSeriesPoints
+SELECT MAX(sp.repo_id), rn.name, MAX(sp.value) AS value
+FROM series_points_captured sp
+JOIN repo_names rn ON sp.repo_name_id = rn.id
+WHERE %s
+GROUP BY rn.name
+ORDER BY value DESC, rn.name
+%s
+`
+
+// ExportSeriesPointsOpts describes options for exporting the data points of a series.
+type ExportSeriesPointsOpts struct {
+	// SeriesID is the unique series ID to export.
+	SeriesID string
+
+	// Captured is whether the series records one point per captured value. See
+	// CapturedSeriesPoints.
+	Captured bool
+}
+
+// ExportSeriesPoints calls fn with the value of every repository at every point in time of the
+// given series, oldest first. The points are read as they are streamed from the database, so
+// series with a long history don't have to be held in memory at once. Repositories the current
+// user cannot access are omitted.
+func (s *Store) ExportSeriesPoints(ctx context.Context, opts ExportSeriesPointsOpts, fn func(RepoSeriesPoint, time.Time, *string) error) error {
+	// 🚨 SECURITY: The values of individual repositories reveal which repositories exist and
+	// what they contain, so they must be filtered by the repo permissions of the current user,
+	// the same way as RepoSeriesPoints. 🚨
+	denylist, err := s.permStore.GetUnauthorizedRepoIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	preds := []*sqlf.Query{sqlf.Sprintf("sp.series_id = %s", opts.SeriesID)}
+	if len(denylist) > 0 {
+		preds = append(preds, sqlf.Sprintf(fmt.Sprintf("sp.repo_id != all(%v)", values(denylist))))
+	}
+
+	var q *sqlf.Query
+	if opts.Captured {
+		q = sqlf.Sprintf(exportCapturedSeriesPointsFmtstr, sqlf.Join(preds, "\n AND "))
+	} else {
+		q = sqlf.Sprintf(exportSeriesPointsFmtstr, sqlf.Join(preds, "\n AND "))
+	}
+
+	return s.query(ctx, q, func(sc scanner) error {
+		var (
+			point   RepoSeriesPoint
+			t       time.Time
+			capture *string
+		)
+		if err := sc.Scan(&t, &capture, &point.RepoID, &point.RepoName, &point.Value); err != nil {
+			return err
+		}
+		return fn(point, t, capture)
+	})
+}
+
+const exportSeriesPointsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:ExportSeriesPoints
+SELECT sp.time, NULL::text, sp.repo_id, rn.name, sp.value
+FROM series_points_repos sp
+JOIN repo_names rn ON sp.repo_name_id = rn.id
+WHERE %s
+ORDER BY sp.time, rn.name
+`
+
+const exportCapturedSeriesPointsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:ExportSeriesPoints
+SELECT sp.time, sp.capture, MAX(sp.repo_id), rn.name, MAX(sp.value)
+FROM series_points_captured sp
+JOIN repo_names rn ON sp.repo_name_id = rn.id
+WHERE %s
+GROUP BY sp.time, sp.capture, rn.name
+ORDER BY sp.time, sp.capture, rn.name
+`
+
+// maxSamplePreviewLength is the maximum length in bytes of the line previews stored with samples.
+const maxSamplePreviewLength = 256
+
+// SeriesPointSample is an example match behind a data point of a series.
+type SeriesPointSample struct {
+	RepoID   api.RepoID
+	RepoName string
+	Path     string
+
+	// LineNumber is the zero-based line number of the match, or nil if the whole file matched.
+	LineNumber *int32
+	Preview    *string
+}
+
+// RecordSeriesPointSamplesArgs describes arguments for the RecordSeriesPointSamples method.
+type RecordSeriesPointSamplesArgs struct {
+	// SeriesID is the unique series ID the samples belong to.
+	SeriesID string
+
+	// Time is the time of the data point the samples belong to.
+	Time time.Time
+
+	Samples []SeriesPointSample
+
+	// Limit is the maximum number of samples retained for the data point, across all calls.
+	// Samples beyond it are dropped.
+	Limit int
+}
+
+// RecordSeriesPointSamples records example matches for the data point of the given series at the
+// given time, until the data point has Limit samples. Concurrent calls for the same data point may
+// exceed the limit by the number of samples they record.
+func (s *Store) RecordSeriesPointSamples(ctx context.Context, args RecordSeriesPointSamplesArgs) (err error) {
+	if args.Limit <= 0 || len(args.Samples) == 0 {
+		return nil
+	}
+
+	tx, err := s.Store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	for _, sample := range args.Samples {
+		repoNameID, ok, err := basestore.ScanFirstInt(tx.Query(ctx, sqlf.Sprintf(upsertRepoNameFmtStr, sample.RepoName, sample.RepoName)))
+		if err != nil {
+			return errors.Wrap(err, "upserting repo name ID")
+		}
+		if !ok {
+			return errors.Wrap(err, "repo name ID not found (this should never happen)")
+		}
+
+		preview := sample.Preview
+		if preview != nil && len(*preview) > maxSamplePreviewLength {
+			truncated := strings.ToValidUTF8((*preview)[:maxSamplePreviewLength], "")
+			preview = &truncated
+		}
+
+		_, inserted, err := basestore.ScanFirstInt(tx.Query(ctx, sqlf.Sprintf(
+			recordSeriesPointSampleFmtstr,
+			args.SeriesID,     // series_id
+			args.Time.UTC(),   // time
+			sample.RepoID,     // repo_id
+			repoNameID,        // repo_name_id
+			sample.Path,       // path
+			sample.LineNumber, // line_number
+			preview,           // preview
+			args.SeriesID,     // existing.series_id
+			args.Time.UTC(),   // existing.time
+			args.Limit,        // limit
+		)))
+		if err != nil {
+			return errors.Wrap(err, "recording sample")
+		}
+		if !inserted {
+			// The data point has all the samples it can take.
+			return nil
+		}
+	}
+	return nil
+}
+
+const recordSeriesPointSampleFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:RecordSeriesPointSamples
+INSERT INTO series_points_samples (series_id, time, repo_id, repo_name_id, path, line_number, preview)
+SELECT %s, %s, %s, %s, %s, %s, %s
+WHERE (SELECT COUNT(*) FROM series_points_samples existing WHERE existing.series_id = %s AND existing.time = %s) < %s
+RETURNING 1
+`
+
+// SeriesPointSamplesOpts describes options for querying the samples of a data point.
+type SeriesPointSamplesOpts struct {
+	// SeriesID is the unique series ID to query.
+	SeriesID string
+
+	// Time is the time of the data point to query.
+	Time time.Time
+
+	// Limit is the number of samples to query, if non-zero.
+	Limit int
+}
+
+// SeriesPointSamples queries the example matches recorded for the data point of the given series
+// at the given time. Samples in repositories the current user cannot access are omitted.
+func (s *Store) SeriesPointSamples(ctx context.Context, opts SeriesPointSamplesOpts) ([]SeriesPointSample, error) {
+	// 🚨 SECURITY: Samples expose the contents of repositories, so they must be filtered by the
+	// repo permissions of the current user, the same way as SeriesPoints. 🚨
+	denylist, err := s.permStore.GetUnauthorizedRepoIDs(ctx)
+	if err != nil {
+		return []SeriesPointSample{}, err
+	}
+
+	preds := []*sqlf.Query{
+		sqlf.Sprintf("sps.series_id = %s", opts.SeriesID),
+		sqlf.Sprintf("sps.time = %s", opts.Time.UTC()),
+	}
+	if len(denylist) > 0 {
+		preds = append(preds, sqlf.Sprintf(fmt.Sprintf("sps.repo_id != all(%v)", values(denylist))))
+	}
+	limitClause := sqlf.Sprintf("")
+	if opts.Limit > 0 {
+		limitClause = sqlf.Sprintf("LIMIT %s", opts.Limit)
+	}
+
+	samples := []SeriesPointSample{}
+	err = s.query(ctx, sqlf.Sprintf(seriesPointSamplesFmtstr, sqlf.Join(preds, "\n AND "), limitClause), func(sc scanner) error {
+		var sample SeriesPointSample
+		if err := sc.Scan(
+			&sample.RepoID,
+			&sample.RepoName,
+			&sample.Path,
+			&sample.LineNumber,
+			&sample.Preview,
+		); err != nil {
+			return err
+		}
+		samples = append(samples, sample)
+		return nil
+	})
+	return samples, err
+}
+
+const seriesPointSamplesFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:SeriesPointSamples
+SELECT sps.repo_id, rn.name, sps.path, sps.line_number, sps.preview
+FROM series_points_samples sps
+JOIN repo_names rn ON sps.repo_name_id = rn.id
+WHERE %s
+ORDER BY rn.name, sps.path, sps.line_number
+%s
+`
+
 //values constructs a SQL values statement out of an array of repository ids
 func values(ids []api.RepoID) string {
 	if len(ids) == 0 {
@@ -273,7 +776,7 @@ func (s *Store) CountData(ctx context.Context, opts CountDataOpts) (int, error)
 }
 
 const countDataFmtstr = `
-SELECT COUNT(*) FROM series_points WHERE %s
+SELECT (SELECT COUNT(*) FROM series_points WHERE %s) + (SELECT COUNT(*) FROM series_points_captured WHERE %s)
 `
 
 func countDataQuery(opts CountDataOpts) *sqlf.Query {
@@ -296,20 +799,98 @@ func countDataQuery(opts CountDataOpts) *sqlf.Query {
 	return sqlf.Sprintf(
 		countDataFmtstr,
 		sqlf.Join(preds, "\n AND "),
+		sqlf.Join(preds, "\n AND "),
 	)
 }
 
-func (s *Store) DeleteSnapshots(ctx context.Context, series *types.InsightSeries) error {
-	err := s.Exec(ctx, sqlf.Sprintf(deleteSnapshotsSql, sqlf.Sprintf(snapshotsTable), series.SeriesID))
+func (s *Store) DeleteSnapshots(ctx context.Context, series *types.InsightSeries) (err error) {
+	tx, err := s.Store.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	times, err := basestore.ScanTimes(tx.Query(ctx, sqlf.Sprintf(deleteSnapshotsSql, sqlf.Sprintf(snapshotsTable), series.SeriesID)))
 	if err != nil {
 		return errors.Wrapf(err, "failed to delete insights snapshots for series_id: %s", series.SeriesID)
 	}
+	if err := tx.Exec(ctx, sqlf.Sprintf(deleteCapturedSnapshotsSql, series.SeriesID)); err != nil {
+		return errors.Wrapf(err, "failed to delete insights captured snapshots for series_id: %s", series.SeriesID)
+	}
+
+	// The rollups at the times of the deleted snapshots, both aggregated and per repository,
+	// include the deleted values, so they need to be recomputed from the remaining points.
+	if len(times) > 0 {
+		if err := tx.Exec(ctx, sqlf.Sprintf(refreshRollupsFmtstr, series.SeriesID, pq.Array(times), series.SeriesID, pq.Array(times))); err != nil {
+			return errors.Wrapf(err, "failed to refresh insights rollups for series_id: %s", series.SeriesID)
+		}
+		if err := tx.Exec(ctx, sqlf.Sprintf(refreshRepoRollupsFmtstr, series.SeriesID, pq.Array(times), series.SeriesID, pq.Array(times))); err != nil {
+			return errors.Wrapf(err, "failed to refresh insights repository rollups for series_id: %s", series.SeriesID)
+		}
+	}
 	return nil
 }
 
 const deleteSnapshotsSql = `
 -- source: enterprise/internal/insights/store/store.go:DeleteSnapshots
-delete from %s where series_id = %s;
+with deleted as (delete from %s where series_id = %s returning time)
+select distinct time from deleted;
+`
+
+const deleteCapturedSnapshotsSql = `
+-- source: enterprise/internal/insights/store/store.go:DeleteSnapshots
+DELETE FROM series_points_captured WHERE series_id = %s AND snapshot;
+`
+
+// refreshRollupsFmtstr recomputes the rollups of a series at the given times from the recorded
+// points, removing the rollups at times for which no points remain.
+const refreshRollupsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:DeleteSnapshots
+WITH aggregated AS (
+	SELECT sub.series_id, sub.time, SUM(sub.value) AS value FROM (
+		SELECT sp.series_id, sp.time, sp.repo_name_id, MAX(sp.value) AS value
+		FROM (
+			SELECT series_id, time, repo_name_id, value FROM series_points
+			UNION ALL
+			SELECT series_id, time, repo_name_id, value FROM series_points_snapshots
+		) sp
+		WHERE sp.series_id = %s AND sp.time = ANY(%s::timestamptz[]) AND sp.repo_name_id IS NOT NULL
+		GROUP BY sp.series_id, sp.time, sp.repo_name_id
+	) sub
+	GROUP BY sub.series_id, sub.time
+),
+deleted AS (
+	DELETE FROM series_points_rollups r
+	WHERE r.series_id = %s AND r.time = ANY(%s::timestamptz[])
+	AND NOT EXISTS (SELECT 1 FROM aggregated a WHERE a.time = r.time)
+)
+INSERT INTO series_points_rollups (series_id, time, value, updated_at)
+SELECT series_id, time, value, now() FROM aggregated
+ON CONFLICT (series_id, time) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+`
+
+// refreshRepoRollupsFmtstr recomputes the per-repository rollups of a series at the given times
+// from the recorded points, removing the rollups for which no points remain.
+const refreshRepoRollupsFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:DeleteSnapshots
+WITH aggregated AS (
+	SELECT sp.series_id, sp.time, sp.repo_name_id, MAX(sp.repo_id) AS repo_id, MAX(sp.value) AS value
+	FROM (
+		SELECT series_id, time, repo_name_id, repo_id, value FROM series_points
+		UNION ALL
+		SELECT series_id, time, repo_name_id, repo_id, value FROM series_points_snapshots
+	) sp
+	WHERE sp.series_id = %s AND sp.time = ANY(%s::timestamptz[]) AND sp.repo_name_id IS NOT NULL AND sp.repo_id IS NOT NULL
+	GROUP BY sp.series_id, sp.time, sp.repo_name_id
+),
+deleted AS (
+	DELETE FROM series_points_repos r
+	WHERE r.series_id = %s AND r.time = ANY(%s::timestamptz[])
+	AND NOT EXISTS (SELECT 1 FROM aggregated a WHERE a.time = r.time AND a.repo_name_id = r.repo_name_id)
+)
+INSERT INTO series_points_repos (series_id, time, repo_name_id, repo_id, value, updated_at)
+SELECT series_id, time, repo_name_id, repo_id, value, now() FROM aggregated
+ON CONFLICT (series_id, time, repo_name_id) DO UPDATE SET repo_id = EXCLUDED.repo_id, value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
 `
 
 type PersistMode string
@@ -413,6 +994,43 @@ func (s *Store) RecordSeriesPoint(ctx context.Context, v RecordSeriesPointArgs)
 		repoNameID,         // repo_name_id
 		repoNameID,         // original_repo_name_id
 	)
+
+	// Points without a repository are not part of the aggregated series, see SeriesPoints.
+	if repoNameID != nil {
+		// Incrementally update the rollup. A repository contributes its maximum value at a point
+		// in time, so the rollup only grows by the amount this point exceeds that maximum by.
+		// This has to happen before the point is inserted, so the previous maximum is observed.
+		if err := txStore.Exec(ctx, sqlf.Sprintf(
+			updateRollupFmtstr,
+			v.SeriesID,         // series_id
+			v.Point.Time.UTC(), // time
+			v.Point.Value,      // value, if there is no previous maximum
+			v.Point.Value,      // value, if there is a previous maximum
+			v.SeriesID,         // series_points.series_id
+			v.RepoID,           // series_points.repo_id
+			v.Point.Time.UTC(), // series_points.time
+			repoNameID,         // series_points.repo_name_id
+			v.SeriesID,         // series_points_snapshots.series_id
+			v.RepoID,           // series_points_snapshots.repo_id
+			v.Point.Time.UTC(), // series_points_snapshots.time
+			repoNameID,         // series_points_snapshots.repo_name_id
+		)); err != nil {
+			return errors.Wrap(err, "updating rollup")
+		}
+
+		// The value of the repository at a point in time is the maximum value recorded for it.
+		if err := txStore.Exec(ctx, sqlf.Sprintf(
+			updateRepoRollupFmtstr,
+			v.SeriesID,         // series_id
+			v.Point.Time.UTC(), // time
+			repoNameID,         // repo_name_id
+			v.RepoID,           // repo_id
+			v.Point.Value,      // value
+		)); err != nil {
+			return errors.Wrap(err, "updating repository rollup")
+		}
+	}
+
 	// Insert the actual data point.
 	return txStore.Exec(ctx, q)
 }
@@ -473,6 +1091,27 @@ INSERT INTO %s (
 VALUES (%s, %s, %s, %s, %s, %s, %s);
 `
 
+const updateRollupFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:RecordSeriesPoint
+INSERT INTO series_points_rollups AS r (series_id, time, value)
+SELECT %s, %s, CASE WHEN COUNT(*) = 0 THEN %s ELSE GREATEST(%s - MAX(previous.value), 0) END
+FROM (
+	SELECT value FROM series_points
+	WHERE series_id = %s AND repo_id = %s AND time = %s AND repo_name_id = %s
+	UNION ALL
+	SELECT value FROM series_points_snapshots
+	WHERE series_id = %s AND repo_id = %s AND time = %s AND repo_name_id = %s
+) previous
+ON CONFLICT (series_id, time) DO UPDATE SET value = r.value + EXCLUDED.value, updated_at = now()
+`
+
+const updateRepoRollupFmtstr = `
+-- source: enterprise/internal/insights/store/store.go:RecordSeriesPoint
+INSERT INTO series_points_repos AS r (series_id, time, repo_name_id, repo_id, value)
+VALUES (%s, %s, %s, %s, %s)
+ON CONFLICT (series_id, time, repo_name_id) DO UPDATE SET value = GREATEST(r.value, EXCLUDED.value), updated_at = now()
+`
+
 func (s *Store) query(ctx context.Context, q *sqlf.Query, sc scanFunc) error {
 	rows, err := s.Store.Query(ctx, q)
 	if err != nil {
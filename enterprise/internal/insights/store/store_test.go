@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -534,6 +535,271 @@ func TestDeleteSnapshots(t *testing.T) {
 	autogold.Equal(t, points, autogold.ExportedOnly())
 }
 
+func TestSeriesRollups(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	clock := timeutil.Now
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	postgres := dbtest.NewDB(t, "")
+	permStore := NewInsightPermissionStore(postgres)
+	store := NewWithClock(timescale, permStore, clock)
+
+	optionalString := func(v string) *string { return &v }
+	optionalRepoID := func(v api.RepoID) *api.RepoID { return &v }
+
+	current := time.Date(2021, time.September, 10, 10, 0, 0, 0, time.UTC)
+	seriesID := "one"
+
+	record := func(repoID api.RepoID, repoName string, at time.Time, value float64, mode PersistMode) {
+		t.Helper()
+		if err := store.RecordSeriesPoint(ctx, RecordSeriesPointArgs{
+			SeriesID:    seriesID,
+			Point:       SeriesPoint{Time: at, Value: value},
+			RepoName:    optionalString(repoName),
+			RepoID:      optionalRepoID(repoID),
+			PersistMode: mode,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compare := func(t *testing.T) {
+		t.Helper()
+		want, err := store.SeriesPoints(ctx, SeriesPointsOpts{SeriesID: &seriesID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.SeriesRollups(ctx, SeriesRollupsOpts{SeriesID: seriesID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected rollups (-want +got):\n%s", diff)
+		}
+	}
+
+	record(1, "repo1", current, 1, RecordMode)
+	record(2, "repo2", current, 2, RecordMode)
+	// A duplicate point for the same repository only counts with its maximum value.
+	record(1, "repo1", current, 3, RecordMode)
+	record(2, "repo2", current, 1, RecordMode)
+	record(1, "repo1", current.Add(time.Hour), 0, RecordMode)
+	record(2, "repo2", current.Add(2*time.Hour), 5, SnapshotMode)
+
+	t.Run("recorded points", func(t *testing.T) {
+		compare(t)
+		autogold.Want("SeriesRollups.len", int(3)).Equal(t, len(mustSeriesRollups(t, store, seriesID)))
+	})
+
+	t.Run("deleted snapshots", func(t *testing.T) {
+		if err := store.DeleteSnapshots(ctx, &types.InsightSeries{SeriesID: seriesID}); err != nil {
+			t.Fatal(err)
+		}
+		compare(t)
+		autogold.Want("SeriesRollups(2).len", int(2)).Equal(t, len(mustSeriesRollups(t, store, seriesID)))
+	})
+}
+
+func mustSeriesRollups(t *testing.T, store *Store, seriesID string) []SeriesPoint {
+	t.Helper()
+	points, err := store.SeriesRollups(context.Background(), SeriesRollupsOpts{SeriesID: seriesID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return points
+}
+
+// unauthorizedRepos is an InsightPermissionStore denying access to the given repositories.
+type unauthorizedRepos []api.RepoID
+
+func (u unauthorizedRepos) GetUnauthorizedRepoIDs(context.Context) ([]api.RepoID, error) {
+	return u, nil
+}
+
+func TestSeriesPointSamples(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	store := NewWithClock(timescale, unauthorizedRepos{2}, timeutil.Now)
+
+	optionalInt32 := func(v int32) *int32 { return &v }
+	optionalString := func(v string) *string { return &v }
+
+	current := time.Date(2021, time.September, 10, 10, 0, 0, 0, time.UTC)
+	record := func(limit int, samples ...SeriesPointSample) {
+		t.Helper()
+		if err := store.RecordSeriesPointSamples(ctx, RecordSeriesPointSamplesArgs{
+			SeriesID: "one",
+			Time:     current,
+			Samples:  samples,
+			Limit:    limit,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record(3,
+		SeriesPointSample{RepoID: 1, RepoName: "repo1", Path: "a.go", LineNumber: optionalInt32(4), Preview: optionalString("foo()")},
+		SeriesPointSample{RepoID: 2, RepoName: "repo2", Path: "b.go", LineNumber: optionalInt32(0), Preview: optionalString("foo()")},
+	)
+	// Only one more sample fits into the limit.
+	record(3,
+		SeriesPointSample{RepoID: 1, RepoName: "repo1", Path: "c.go"},
+		SeriesPointSample{RepoID: 1, RepoName: "repo1", Path: "d.go"},
+	)
+
+	samples, err := store.SeriesPointSamples(ctx, SeriesPointSamplesOpts{SeriesID: "one", Time: current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sample in repo2 is omitted, as the user cannot access it.
+	want := []SeriesPointSample{
+		{RepoID: 1, RepoName: "repo1", Path: "a.go", LineNumber: optionalInt32(4), Preview: optionalString("foo()")},
+		{RepoID: 1, RepoName: "repo1", Path: "c.go"},
+	}
+	if diff := cmp.Diff(want, samples); diff != "" {
+		t.Errorf("unexpected samples (-want +got):\n%s", diff)
+	}
+
+	samples, err = store.SeriesPointSamples(ctx, SeriesPointSamplesOpts{SeriesID: "one", Time: current.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 0 {
+		t.Errorf("unexpected samples for other data point: %v", samples)
+	}
+}
+
+func TestRepoSeriesPoints(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	store := NewWithClock(timescale, unauthorizedRepos{3}, timeutil.Now)
+
+	optionalString := func(v string) *string { return &v }
+	optionalRepoID := func(v api.RepoID) *api.RepoID { return &v }
+
+	current := time.Date(2021, time.September, 10, 10, 0, 0, 0, time.UTC)
+	seriesID := "one"
+	record := func(repoID api.RepoID, repoName string, value float64, mode PersistMode) {
+		t.Helper()
+		if err := store.RecordSeriesPoint(ctx, RecordSeriesPointArgs{
+			SeriesID:    seriesID,
+			Point:       SeriesPoint{Time: current, Value: value},
+			RepoName:    optionalString(repoName),
+			RepoID:      optionalRepoID(repoID),
+			PersistMode: mode,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repoSeriesPoints := func(t *testing.T, limit int) []RepoSeriesPoint {
+		t.Helper()
+		points, err := store.RepoSeriesPoints(ctx, RepoSeriesPointsOpts{SeriesID: seriesID, Time: current, Limit: limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return points
+	}
+
+	record(1, "repo1", 2, RecordMode)
+	// A duplicate point for the same repository only counts with its maximum value.
+	record(1, "repo1", 1, RecordMode)
+	record(2, "repo2", 5, RecordMode)
+	record(3, "repo3", 7, RecordMode)
+	record(4, "repo4", 0, RecordMode)
+	record(1, "repo1", 9, SnapshotMode)
+
+	t.Run("recorded points", func(t *testing.T) {
+		// repo3 is omitted as the user cannot access it, and repo4 as it has no results.
+		want := []RepoSeriesPoint{
+			{RepoID: 1, RepoName: "repo1", Value: 9},
+			{RepoID: 2, RepoName: "repo2", Value: 5},
+		}
+		if diff := cmp.Diff(want, repoSeriesPoints(t, 0)); diff != "" {
+			t.Errorf("unexpected points (-want +got):\n%s", diff)
+		}
+		autogold.Want("RepoSeriesPoints(limit).len", int(1)).Equal(t, len(repoSeriesPoints(t, 1)))
+	})
+
+	t.Run("deleted snapshots", func(t *testing.T) {
+		if err := store.DeleteSnapshots(ctx, &types.InsightSeries{SeriesID: seriesID}); err != nil {
+			t.Fatal(err)
+		}
+		want := []RepoSeriesPoint{
+			{RepoID: 2, RepoName: "repo2", Value: 5},
+			{RepoID: 1, RepoName: "repo1", Value: 2},
+		}
+		if diff := cmp.Diff(want, repoSeriesPoints(t, 0)); diff != "" {
+			t.Errorf("unexpected points (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestExportSeriesPoints(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	store := NewWithClock(timescale, unauthorizedRepos{3}, timeutil.Now)
+
+	optionalString := func(v string) *string { return &v }
+	optionalRepoID := func(v api.RepoID) *api.RepoID { return &v }
+
+	first := time.Date(2021, time.September, 10, 10, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	for _, p := range []struct {
+		time     time.Time
+		repoID   api.RepoID
+		repoName string
+		value    float64
+	}{
+		{second, 1, "repo1", 4},
+		{first, 2, "repo2", 5},
+		{first, 1, "repo1", 2},
+		{first, 3, "repo3", 7},
+	} {
+		if err := store.RecordSeriesPoint(ctx, RecordSeriesPointArgs{
+			SeriesID:    "one",
+			Point:       SeriesPoint{Time: p.time, Value: p.value},
+			RepoName:    optionalString(p.repoName),
+			RepoID:      optionalRepoID(p.repoID),
+			PersistMode: RecordMode,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	if err := store.ExportSeriesPoints(ctx, ExportSeriesPointsOpts{SeriesID: "one"}, func(point RepoSeriesPoint, t time.Time, capture *string) error {
+		got = append(got, fmt.Sprintf("%s %s %v %v", t.UTC().Format(time.RFC3339), point.RepoName, point.Value, capture))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// repo3 is omitted as the user cannot access it.
+	autogold.Want("exported points", []string{
+		"2021-09-10T10:00:00Z repo1 2 <nil>",
+		"2021-09-10T10:00:00Z repo2 5 <nil>",
+		"2021-09-11T10:00:00Z repo1 4 <nil>",
+	}).Equal(t, got)
+}
+
 func TestValues(t *testing.T) {
 	ids := []api.RepoID{1, 2, 3, 4, 5, 6}
 	got := values(ids)
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+)
+
+// TeamStore is the subset of the API of the InsightStore that manages the repositories owned by
+// teams, which insight series can be scoped to.
+type TeamStore interface {
+	GetTeamRepositories(ctx context.Context, team string) ([]string, error)
+	SetTeamRepositories(ctx context.Context, team string, repoNames []string) error
+}
+
+var _ TeamStore = &InsightStore{}
+
+// GetTeamRepositories returns the names of the repositories owned by the given team, sorted.
+func (s *InsightStore) GetTeamRepositories(ctx context.Context, team string) ([]string, error) {
+	return basestore.ScanStrings(s.Query(ctx, sqlf.Sprintf(getTeamRepositoriesSql, team)))
+}
+
+// SetTeamRepositories replaces the repositories owned by the given team.
+func (s *InsightStore) SetTeamRepositories(ctx context.Context, team string, repoNames []string) (err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.Exec(ctx, sqlf.Sprintf(deleteTeamRepositoriesSql, team)); err != nil {
+		return err
+	}
+	if len(repoNames) == 0 {
+		return nil
+	}
+	values := make([]*sqlf.Query, 0, len(repoNames))
+	for _, repoName := range repoNames {
+		values = append(values, sqlf.Sprintf("(%s, %s)", team, repoName))
+	}
+	return tx.Exec(ctx, sqlf.Sprintf(insertTeamRepositoriesSql, sqlf.Join(values, ",")))
+}
+
+// TeamRepositories looks up the repositories owned by teams, looking up each team only once. It
+// is meant to be used for a single run of a background routine or job, so that changes of
+// ownership are picked up by the next one. It is not safe for concurrent use.
+type TeamRepositories struct {
+	store TeamStore
+	teams map[string][]string
+}
+
+// NewTeamRepositories returns a TeamRepositories that looks up the repositories of teams in the
+// given store.
+func NewTeamRepositories(store TeamStore) *TeamRepositories {
+	return &TeamRepositories{store: store, teams: map[string][]string{}}
+}
+
+// Get returns the names of the repositories owned by the given team, sorted.
+func (t *TeamRepositories) Get(ctx context.Context, team string) ([]string, error) {
+	if repoNames, ok := t.teams[team]; ok {
+		return repoNames, nil
+	}
+	repoNames, err := t.store.GetTeamRepositories(ctx, team)
+	if err != nil {
+		return nil, err
+	}
+	t.teams[team] = repoNames
+	return repoNames, nil
+}
+
+// Owns reports whether the given team owns the repository with the given name.
+func (t *TeamRepositories) Owns(ctx context.Context, team, repoName string) (bool, error) {
+	repoNames, err := t.Get(ctx, team)
+	if err != nil {
+		return false, err
+	}
+	i := sort.SearchStrings(repoNames, repoName)
+	return i < len(repoNames) && repoNames[i] == repoName, nil
+}
+
+const getTeamRepositoriesSql = `
+-- source: enterprise/internal/insights/store/team_store.go:GetTeamRepositories
+SELECT repo_name FROM insight_team_repositories WHERE team = %s ORDER BY repo_name COLLATE "C";
+`
+
+const deleteTeamRepositoriesSql = `
+-- source: enterprise/internal/insights/store/team_store.go:SetTeamRepositories
+DELETE FROM insight_team_repositories WHERE team = %s;
+`
+
+const insertTeamRepositoriesSql = `
+-- source: enterprise/internal/insights/store/team_store.go:SetTeamRepositories
+INSERT INTO insight_team_repositories (team, repo_name) VALUES %s ON CONFLICT DO NOTHING;
+`
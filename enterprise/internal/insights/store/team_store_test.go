@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	insightsdbtesting "github.com/sourcegraph/sourcegraph/enterprise/internal/insights/dbtesting"
+)
+
+func TestInsightStore_TeamRepositories(t *testing.T) {
+	timescale, cleanup := insightsdbtesting.TimescaleDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	store := NewInsightStore(timescale)
+
+	if err := store.SetTeamRepositories(ctx, "platform", []string{"github.com/sourcegraph/zoekt", "github.com/sourcegraph/sourcegraph"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetTeamRepositories(ctx, "search", []string{"github.com/sourcegraph/zoekt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	teams := NewTeamRepositories(store)
+	got, err := teams.Get(ctx, "platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"github.com/sourcegraph/sourcegraph", "github.com/sourcegraph/zoekt"}, got); diff != "" {
+		t.Errorf("unexpected repositories (-want +got):\n%s", diff)
+	}
+
+	// Replacing the repositories of a team doesn't change those of other teams, nor those already
+	// looked up.
+	if err := store.SetTeamRepositories(ctx, "platform", []string{"github.com/sourcegraph/src-cli"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		team, repoName string
+		want           bool
+	}{
+		{"platform", "github.com/sourcegraph/sourcegraph", true},
+		{"platform", "github.com/sourcegraph/src-cli", false},
+		{"search", "github.com/sourcegraph/zoekt", true},
+		{"search", "github.com/sourcegraph/sourcegraph", false},
+		{"unknown", "github.com/sourcegraph/zoekt", false},
+	} {
+		owns, err := teams.Owns(ctx, tc.team, tc.repoName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owns != tc.want {
+			t.Errorf("team %q owns %q: got %v, want %v", tc.team, tc.repoName, owns, tc.want)
+		}
+	}
+
+	got, err = store.GetTeamRepositories(ctx, "platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"github.com/sourcegraph/src-cli"}, got); diff != "" {
+		t.Errorf("unexpected replaced repositories (-want +got):\n%s", diff)
+	}
+}
@@ -19,6 +19,9 @@ type InsightViewSeries struct {
 	NextSnapshotAfter     time.Time
 	BackfillQueuedAt      *time.Time
 	RecordingIntervalDays int
+	PausedAt              *time.Time
+	PauseReason           string
+	GenerationMethod      string
 	Label                 string
 	Stroke                string
 }
@@ -58,6 +61,88 @@ type InsightSeries struct {
 	NextSnapshotAfter     time.Time
 	BackfillQueuedAt      time.Time
 	RecordingIntervalDays int
+	PausedAt              *time.Time
+	PauseReason           string
+	PatternType           string
+	GenerationMethod      string
+	// RepositoryRevisions maps the names of repositories to the revision they are searched at,
+	// instead of their default branch.
+	RepositoryRevisions map[string]string
+	// Repositories are the names of the repositories that the language statistics of a
+	// language-stats series are computed for.
+	Repositories []string
+	// Team is the team whose repositories the series is restricted to, or the empty string for
+	// all repositories. The repositories of the team are looked up when the series is computed.
+	Team string
+}
+
+// RevisionFor returns the revision that the given repository is searched at for the series, or
+// the empty string for its default branch.
+func (s InsightSeries) RevisionFor(repoName string) string {
+	return s.RepositoryRevisions[repoName]
+}
+
+// Pattern types that the search query of an insight series can be executed with.
+const (
+	PatternTypeLiteral    = "literal"
+	PatternTypeRegexp     = "regexp"
+	PatternTypeStructural = "structural"
+)
+
+// Generation methods of the data points of an insight series. Search series count the matches of
+// their search query, search-compute series count the matches of their query per value captured
+// by its capture group, recording one point per captured value. Language-stats series count the
+// lines of code per language in each of their repositories, computed from the contents of the
+// repositories instead of searching them, recording one point per language.
+const (
+	GenerationMethodSearch        = "search"
+	GenerationMethodSearchCompute = "search-compute"
+	GenerationMethodLanguageStats = "language-stats"
+)
+
+// RecordsCapturedValues reports whether series with the given generation method record one point
+// per captured value (e.g. per language) in each repository, rather than one point per repository.
+func RecordsCapturedValues(generationMethod string) bool {
+	return generationMethod == GenerationMethodSearchCompute || generationMethod == GenerationMethodLanguageStats
+}
+
+// InsightSeriesAlert is an alert defined by a user on an insight series, which notifies them when
+// the data points of the series meet its condition.
+type InsightSeriesAlert struct {
+	ID              int
+	SeriesID        string
+	UserID          int32
+	Label           string
+	Condition       string
+	Threshold       float64
+	NotifyEmail     bool
+	WebhookURL      string
+	SlackWebhookURL string
+	Triggered       bool
+	LastEvaluatedAt *time.Time
+	LastTriggeredAt *time.Time
+	CreatedAt       time.Time
+}
+
+// Conditions of insight series alerts. Threshold conditions compare the value of a data point with
+// the threshold of the alert, trend conditions compare the change in percent of the value of a
+// data point from the previous data point with it.
+const (
+	AlertConditionAbove    = "above"
+	AlertConditionBelow    = "below"
+	AlertConditionIncrease = "increase"
+	AlertConditionDecrease = "decrease"
+)
+
+// Dashboard is a named collection of insight views. Users see the dashboards that are granted to
+// them, to one of their organizations or to everyone.
+type Dashboard struct {
+	ID        int
+	Title     string
+	CreatedAt time.Time
+	CreatedBy *int32
+	// InsightIDs are the unique IDs of the insight views on the dashboard.
+	InsightIDs []string
 }
 
 type DirtyQuery struct {
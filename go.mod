@@ -71,6 +71,7 @@ require (
 	github.com/gorilla/schema v1.2.0
 	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.1
+	github.com/gorilla/websocket v1.4.2
 	github.com/goware/urlx v0.3.1
 	github.com/grafana-tools/sdk v0.0.0-20210921191058-888ef9d18611
 	github.com/graph-gophers/graphql-go v0.0.0-20201113091052-beb923fada29
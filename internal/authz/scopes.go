@@ -1,13 +1,50 @@
 package authz
 
+import "context"
+
 const (
 	// Access token scopes.
 	ScopeUserAll       = "user:all"        // Full control of all resources accessible to the user account.
 	ScopeSiteAdminSudo = "site-admin:sudo" // Ability to perform any action as any other user.
+
+	// Fine-grained access token scopes. Tokens that have these scopes instead of ScopeUserAll can
+	// only use the corresponding APIs.
+	ScopeReadSearch   = "read:search"   // Ability to run searches.
+	ScopeWriteBatches = "write:batches" // Ability to use the batch changes API.
+	ScopeAdmin        = "admin"         // Ability to perform site admin actions (site admins only).
 )
 
 // AllScopes is a list of all known access token scopes.
 var AllScopes = []string{
 	ScopeUserAll,
 	ScopeSiteAdminSudo,
+	ScopeReadSearch,
+	ScopeWriteBatches,
+	ScopeAdmin,
+}
+
+// HasScope reports whether scopes contains scope.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type accessTokenScopesKey struct{}
+
+// WithAccessTokenScopes returns a context for a request that is authenticated with an access
+// token that only has the given fine-grained scopes (and not ScopeUserAll).
+func WithAccessTokenScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, accessTokenScopesKey{}, scopes)
+}
+
+// AccessTokenScopesFromContext returns the scopes of the access token of the request if the
+// request is restricted to them. If restricted is false, the request isn't restricted by access
+// token scopes.
+func AccessTokenScopesFromContext(ctx context.Context) (scopes []string, restricted bool) {
+	scopes, restricted = ctx.Value(accessTokenScopesKey{}).([]string)
+	return scopes, restricted
 }
@@ -113,12 +113,34 @@ func AccessTokensAllow() AccessTokAllow {
 	}
 }
 
+type PersistedQueriesMode string
+
+const (
+	PersistedQueriesEnabled       PersistedQueriesMode = "enabled"
+	PersistedQueriesAllowlistOnly PersistedQueriesMode = "allowlist-only"
+	PersistedQueriesDisabled      PersistedQueriesMode = "disabled"
+)
+
+// PersistedQueries returns whether persisted GraphQL queries are enabled, disabled, or the only
+// queries that users other than site admins may run.
+func PersistedQueries() PersistedQueriesMode {
+	switch mode := PersistedQueriesMode(Get().ApiPersistedQueries); mode {
+	case "":
+		return PersistedQueriesEnabled
+	case PersistedQueriesEnabled, PersistedQueriesAllowlistOnly, PersistedQueriesDisabled:
+		return mode
+	default:
+		// Fail closed for unknown values.
+		return PersistedQueriesAllowlistOnly
+	}
+}
+
 // EmailVerificationRequired returns whether users must verify an email address before they
 // can perform most actions on this site.
 //
 // It's false for sites that do not have an email sending API key set up.
 func EmailVerificationRequired() bool {
-	return Get().EmailSmtp != nil
+	return CanSendEmail()
 }
 
 // CanSendEmail returns whether the site can send emails (e.g., to reset a password or
@@ -126,7 +148,57 @@ func EmailVerificationRequired() bool {
 //
 // It's false for sites that do not have an email sending API key set up.
 func CanSendEmail() bool {
-	return Get().EmailSmtp != nil
+	c := Get()
+	switch EmailProviderType() {
+	case "ses":
+		return c.EmailProvider.Ses != nil
+	case "sendgrid":
+		return c.EmailProvider.Sendgrid != nil
+	default:
+		return c.EmailSmtp != nil
+	}
+}
+
+// EmailProviderType returns the service used to send emails (see the email.provider site
+// configuration property). It defaults to "smtp", which uses the email.smtp server.
+func EmailProviderType() string {
+	if p := Get().EmailProvider; p != nil && p.Type != "" {
+		return p.Type
+	}
+	return "smtp"
+}
+
+// AccountChangeNotificationChannels returns the channels users are notified on about
+// security-relevant changes to their account (see the notifications.accountChanges site
+// configuration property). It defaults to email only.
+func AccountChangeNotificationChannels() []string {
+	if cfg := Get().NotificationsAccountChanges; cfg != nil && len(cfg.Channels) > 0 {
+		return cfg.Channels
+	}
+	return []string{"email"}
+}
+
+// CanNotifyOfAccountChanges returns whether users can be notified about security-relevant
+// changes to their account on at least one channel.
+func CanNotifyOfAccountChanges() bool {
+	cfg := Get().NotificationsAccountChanges
+	for _, channel := range AccountChangeNotificationChannels() {
+		switch channel {
+		case "email":
+			if CanSendEmail() {
+				return true
+			}
+		case "slack":
+			if cfg != nil && cfg.Slack != nil && cfg.Slack.BotToken != "" {
+				return true
+			}
+		case "webhook":
+			if cfg != nil && cfg.Webhook != nil && cfg.Webhook.Url != "" {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Deploy type constants. Any changes here should be reflected in the DeployType type declared in web/src/globals.d.ts:
@@ -238,6 +310,19 @@ func BatchChangesRestrictedToAdmins() bool {
 	return false
 }
 
+const defaultBatchChangesResolutionJobRetention = 30 * 24 * time.Hour
+
+// BatchChangesResolutionJobRetention returns how long finished batch spec
+// resolution jobs are kept around before being cleaned up. If not set, it
+// returns the default value.
+func BatchChangesResolutionJobRetention() time.Duration {
+	days := Get().BatchChangesResolutionJobRetentionDays
+	if days < 1 {
+		return defaultBatchChangesResolutionJobRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 func CodeIntelAutoIndexingEnabled() bool {
 	if enabled := Get().CodeIntelAutoIndexingEnabled; enabled != nil {
 		return *enabled
@@ -345,6 +430,40 @@ func AuthPasswordResetLinkExpiry() int {
 	return val
 }
 
+// AuthPrimaryEmailChangeSessionRevocation returns when the sessions and access tokens of a user
+// are revoked after their primary email address changes: "never", "unrecognized" or "always". If
+// not set, it returns "never".
+func AuthPrimaryEmailChangeSessionRevocation() string {
+	val := Get().AuthPrimaryEmailChangeSessionRevocation
+	if val == "" {
+		return "never"
+	}
+	return val
+}
+
+// By default, a verification email can be resent once per minute.
+const defaultEmailVerificationResendCooldown = time.Minute
+
+// EmailVerificationResendCooldown returns how long users must wait before another verification
+// email can be sent for the same email address. If not set, it returns the default value.
+func EmailVerificationResendCooldown() time.Duration {
+	cfg := Get().EmailVerificationResend
+	if cfg == nil || cfg.CooldownSeconds <= 0 {
+		return defaultEmailVerificationResendCooldown
+	}
+	return time.Duration(cfg.CooldownSeconds) * time.Second
+}
+
+// EmailVerificationResendMaxPerDay returns the number of verification emails that can be sent at
+// most for an email address within 24 hours, or 0 if there is no limit.
+func EmailVerificationResendMaxPerDay() int {
+	cfg := Get().EmailVerificationResend
+	if cfg == nil || cfg.MaxPerDay < 0 {
+		return 0
+	}
+	return cfg.MaxPerDay
+}
+
 type ExternalServiceMode int
 
 const (
@@ -134,6 +134,35 @@ func TestGitLongCommandTimeout(t *testing.T) {
 	}
 }
 
+func TestBatchChangesResolutionJobRetention(t *testing.T) {
+	tests := []struct {
+		name string
+		sc   *Unified
+		want time.Duration
+	}{{
+		name: "Resolution job retention has a default value if null",
+		sc:   &Unified{},
+		want: defaultBatchChangesResolutionJobRetention,
+	}, {
+		name: "Resolution job retention has a default value if negative",
+		sc:   &Unified{SiteConfiguration: schema.SiteConfiguration{BatchChangesResolutionJobRetentionDays: -1}},
+		want: defaultBatchChangesResolutionJobRetention,
+	}, {
+		name: "Resolution job retention can be customized",
+		sc:   &Unified{SiteConfiguration: schema.SiteConfiguration{BatchChangesResolutionJobRetentionDays: 7}},
+		want: 7 * 24 * time.Hour,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Mock(test.sc)
+			if got, want := BatchChangesResolutionJobRetention(), test.want; got != want {
+				t.Fatalf("BatchChangesResolutionJobRetention() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestGitMaxCodehostRequestsPerSecond(t *testing.T) {
 	tests := []struct {
 		name string
@@ -244,3 +273,99 @@ func boolPtr(b bool) *bool {
 func intPtr(i int) *int {
 	return &i
 }
+
+func TestCanNotifyOfAccountChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		sc   *Unified
+		want bool
+	}{{
+		name: "email by default, but SMTP is not configured",
+		sc:   &Unified{},
+		want: false,
+	}, {
+		name: "email by default",
+		sc:   &Unified{SiteConfiguration: schema.SiteConfiguration{EmailSmtp: &schema.SMTPServerConfig{}}},
+		want: true,
+	}, {
+		name: "slack without a bot token",
+		sc: &Unified{SiteConfiguration: schema.SiteConfiguration{
+			EmailSmtp: &schema.SMTPServerConfig{},
+			NotificationsAccountChanges: &schema.AccountChangeNotifications{
+				Channels: []string{"slack"},
+			},
+		}},
+		want: false,
+	}, {
+		name: "slack",
+		sc: &Unified{SiteConfiguration: schema.SiteConfiguration{
+			NotificationsAccountChanges: &schema.AccountChangeNotifications{
+				Channels: []string{"slack"},
+				Slack:    &schema.AccountChangeNotificationsSlack{BotToken: "xoxb-123"},
+			},
+		}},
+		want: true,
+	}, {
+		name: "webhook",
+		sc: &Unified{SiteConfiguration: schema.SiteConfiguration{
+			NotificationsAccountChanges: &schema.AccountChangeNotifications{
+				Channels: []string{"email", "webhook"},
+				Webhook:  &schema.AccountChangeNotificationsWebhook{Url: "https://example.com/hook"},
+			},
+		}},
+		want: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Mock(test.sc)
+			if got, want := CanNotifyOfAccountChanges(), test.want; got != want {
+				t.Fatalf("CanNotifyOfAccountChanges() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCanSendEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		sc   *Unified
+		want bool
+	}{{
+		name: "nothing configured",
+		sc:   &Unified{},
+		want: false,
+	}, {
+		name: "SMTP by default",
+		sc:   &Unified{SiteConfiguration: schema.SiteConfiguration{EmailSmtp: &schema.SMTPServerConfig{}}},
+		want: true,
+	}, {
+		name: "SES without its configuration",
+		sc: &Unified{SiteConfiguration: schema.SiteConfiguration{
+			EmailSmtp:     &schema.SMTPServerConfig{},
+			EmailProvider: &schema.EmailProvider{Type: "ses"},
+		}},
+		want: false,
+	}, {
+		name: "SES",
+		sc: &Unified{SiteConfiguration: schema.SiteConfiguration{
+			EmailProvider: &schema.EmailProvider{Type: "ses", Ses: &schema.SESEmailProvider{Region: "us-east-1"}},
+		}},
+		want: true,
+	}, {
+		name: "SendGrid",
+		sc: &Unified{SiteConfiguration: schema.SiteConfiguration{
+			EmailProvider: &schema.EmailProvider{Type: "sendgrid", Sendgrid: &schema.SendGridEmailProvider{ApiKey: "SG.x"}},
+		}},
+		want: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Mock(test.sc)
+			if got, want := CanSendEmail(), test.want; got != want {
+				t.Fatalf("CanSendEmail() = %v, want %v", got, want)
+			}
+		})
+	}
+}
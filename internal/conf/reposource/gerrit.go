@@ -0,0 +1,41 @@
+package reposource
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type Gerrit struct {
+	*schema.GerritConnection
+}
+
+var _ RepoSource = Gerrit{}
+
+func (c Gerrit) CloneURLToRepoName(cloneURL string) (repoName api.RepoName, err error) {
+	parsedCloneURL, baseURL, match, err := parseURLs(cloneURL, c.Url)
+	if err != nil {
+		return "", err
+	}
+	if !match {
+		return "", nil
+	}
+
+	// Strip the path of the Gerrit instance and the /a/ prefix of
+	// authenticated clone URLs from the path to get the project name.
+	name := strings.TrimPrefix(parsedCloneURL.Path, strings.TrimSuffix(baseURL.Path, "/"))
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "/"), "a/")
+	return GerritRepoName(c.RepositoryPathPattern, baseURL.Hostname(), strings.TrimSuffix(name, ".git")), nil
+}
+
+func GerritRepoName(repositoryPathPattern, host, name string) api.RepoName {
+	if repositoryPathPattern == "" {
+		repositoryPathPattern = "{host}/{name}"
+	}
+
+	return api.RepoName(strings.NewReplacer(
+		"{host}", host,
+		"{name}", name,
+	).Replace(repositoryPathPattern))
+}
@@ -53,6 +53,23 @@ func validateCustom(cfg Unified) (problems Problems) {
 		}
 	}
 
+	if p := cfg.EmailProvider; p != nil {
+		switch {
+		case p.Type == "smtp" && cfg.EmailSmtp == nil:
+			invalid(NewSiteProblem(`must set email.smtp because email.provider type is "smtp"`))
+		case p.Type == "ses" && p.Ses == nil:
+			invalid(NewSiteProblem(`must set email.provider ses because email.provider type is "ses"`))
+		case p.Type == "sendgrid" && p.Sendgrid == nil:
+			invalid(NewSiteProblem(`must set email.provider sendgrid because email.provider type is "sendgrid"`))
+		}
+		if p.Ses != nil && (p.Ses.AccessKeyID == "") != (p.Ses.SecretAccessKey == "") {
+			invalid(NewSiteProblem(`must set both or neither of email.provider ses accessKeyID and secretAccessKey`))
+		}
+		if p.Type != "smtp" && cfg.EmailAddress == "" {
+			invalid(NewSiteProblem(`should set email.address because email.provider is set`))
+		}
+	}
+
 	// Prevent usage of non-root externalURLs until we add their support:
 	// https://github.com/sourcegraph/sourcegraph/issues/7884
 	if cfg.ExternalURL != "" {
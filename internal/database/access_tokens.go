@@ -26,12 +26,16 @@ type AccessToken struct {
 	CreatorUserID int32
 	CreatedAt     time.Time
 	LastUsedAt    *time.Time
+	ExpiresAt     *time.Time // nil if the token never expires
 }
 
 // ErrAccessTokenNotFound occurs when a database operation expects a specific access token to exist
 // but it does not exist.
 var ErrAccessTokenNotFound = errors.New("access token not found")
 
+// ErrAccessTokenExpired occurs when an expired access token is rotated.
+var ErrAccessTokenExpired = errors.New("access token expired")
+
 // AccessTokenStore implements autocert.Cache
 type AccessTokenStore struct {
 	*basestore.Store
@@ -69,11 +73,13 @@ func (s *AccessTokenStore) Transact(ctx context.Context) (*AccessTokenStore, err
 // space; also bcrypt is slow and would add noticeable latency to each request that supplied a
 // token.
 //
+// If expiresAt is non-nil, the token is rejected after that time.
+//
 // 🚨 SECURITY: The caller must ensure that the actor is permitted to create tokens for the
 // specified user (i.e., that the actor is either the user or a site admin).
-func (s *AccessTokenStore) Create(ctx context.Context, subjectUserID int32, scopes []string, note string, creatorUserID int32) (id int64, token string, err error) {
+func (s *AccessTokenStore) Create(ctx context.Context, subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (id int64, token string, err error) {
 	if Mocks.AccessTokens.Create != nil {
-		return Mocks.AccessTokens.Create(subjectUserID, scopes, note, creatorUserID)
+		return Mocks.AccessTokens.Create(subjectUserID, scopes, note, creatorUserID, expiresAt)
 	}
 
 	var b [20]byte
@@ -99,20 +105,20 @@ creator_user AS (
   SELECT id FROM users WHERE id=$5 AND deleted_at IS NULL FOR UPDATE
 ),
 insert_values AS (
-  SELECT subject_user.id AS subject_user_id, $2::text[] AS scopes, $3::bytea AS value_sha256, $4::text AS note, creator_user.id AS creator_user_id
+  SELECT subject_user.id AS subject_user_id, $2::text[] AS scopes, $3::bytea AS value_sha256, $4::text AS note, creator_user.id AS creator_user_id, $6::timestamp with time zone AS expires_at
   FROM subject_user, creator_user
 )
-INSERT INTO access_tokens(subject_user_id, scopes, value_sha256, note, creator_user_id) SELECT * FROM insert_values RETURNING id
+INSERT INTO access_tokens(subject_user_id, scopes, value_sha256, note, creator_user_id, expires_at) SELECT * FROM insert_values RETURNING id
 `,
-		subjectUserID, pq.Array(scopes), toSHA256Bytes(b[:]), note, creatorUserID,
+		subjectUserID, pq.Array(scopes), toSHA256Bytes(b[:]), note, creatorUserID, expiresAt,
 	).Scan(&id); err != nil {
 		return 0, "", err
 	}
 	return id, token, nil
 }
 
-// Lookup looks up the access token. If it's valid and contains the required scope, it returns the
-// subject's user ID. Otherwise ErrAccessTokenNotFound is returned.
+// Lookup looks up the access token. If it's valid (i.e., not deleted or expired) and contains the
+// required scope, it returns the subject's user ID. Otherwise ErrAccessTokenNotFound is returned.
 //
 // Calling Lookup also updates the access token's last-used-at date.
 //
@@ -141,6 +147,7 @@ WHERE t.id IN (
 	JOIN users subject_user ON t2.subject_user_id=subject_user.id AND subject_user.deleted_at IS NULL
 	JOIN users creator_user ON t2.creator_user_id=creator_user.id AND creator_user.deleted_at IS NULL
 	WHERE t2.value_sha256=$1 AND t2.deleted_at IS NULL AND
+	(t2.expires_at IS NULL OR t2.expires_at > now()) AND
 	$2 = ANY (t2.scopes)
 )
 RETURNING t.subject_user_id
@@ -155,6 +162,81 @@ RETURNING t.subject_user_id
 	return subjectUserID, nil
 }
 
+// LookupWithScopes is like Lookup, except that it returns the scopes of the access token instead
+// of requiring a scope. The caller must restrict the request to the returned scopes.
+//
+// Calling LookupWithScopes also updates the access token's last-used-at date.
+func (s *AccessTokenStore) LookupWithScopes(ctx context.Context, tokenHexEncoded string) (subjectUserID int32, scopes []string, err error) {
+	if Mocks.AccessTokens.LookupWithScopes != nil {
+		return Mocks.AccessTokens.LookupWithScopes(tokenHexEncoded)
+	}
+
+	token, err := hex.DecodeString(tokenHexEncoded)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "AccessTokens.LookupWithScopes")
+	}
+
+	if err := s.Handle().DB().QueryRowContext(ctx,
+		// Ensure that subject and creator users still exist.
+		`
+UPDATE access_tokens t SET last_used_at=now()
+WHERE t.id IN (
+	SELECT t2.id FROM access_tokens t2
+	JOIN users subject_user ON t2.subject_user_id=subject_user.id AND subject_user.deleted_at IS NULL
+	JOIN users creator_user ON t2.creator_user_id=creator_user.id AND creator_user.deleted_at IS NULL
+	WHERE t2.value_sha256=$1 AND t2.deleted_at IS NULL AND
+	(t2.expires_at IS NULL OR t2.expires_at > now())
+)
+RETURNING t.subject_user_id, t.scopes
+`,
+		toSHA256Bytes(token),
+	).Scan(&subjectUserID, pq.Array(&scopes)); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, ErrAccessTokenNotFound
+		}
+		return 0, nil, err
+	}
+	return subjectUserID, scopes, nil
+}
+
+// Rotate replaces the access token with a new one that has the same subject user, scopes and note,
+// and returns the new token's ID and secret value. If the old token expires, the new one expires
+// after the same lifetime, counted from now. The old token is deleted. Expired tokens can't be
+// rotated: ErrAccessTokenExpired is returned.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is permitted to create tokens for the subject
+// user of the token, because the actor obtains the new secret value.
+func (s *AccessTokenStore) Rotate(ctx context.Context, id int64, creatorUserID int32) (newID int64, token string, err error) {
+	if Mocks.AccessTokens.Rotate != nil {
+		return Mocks.AccessTokens.Rotate(id, creatorUserID)
+	}
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	old, err := tx.GetByID(ctx, id)
+	if err != nil {
+		return 0, "", err
+	}
+	// 🚨 SECURITY: Otherwise a leaked token that expired could be revived by rotating it.
+	if old.ExpiresAt != nil && !old.ExpiresAt.After(time.Now()) {
+		return 0, "", ErrAccessTokenExpired
+	}
+	if err := tx.delete(ctx, sqlf.Sprintf("id=%d", id)); err != nil {
+		return 0, "", err
+	}
+
+	var expiresAt *time.Time
+	if old.ExpiresAt != nil {
+		t := time.Now().Add(old.ExpiresAt.Sub(old.CreatedAt))
+		expiresAt = &t
+	}
+	return tx.Create(ctx, old.SubjectUserID, old.Scopes, old.Note, creatorUserID, expiresAt)
+}
+
 // GetByID retrieves the access token (if any) given its ID.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is permitted to view this access token.
@@ -221,7 +303,7 @@ func (s *AccessTokenStore) List(ctx context.Context, opt AccessTokensListOptions
 
 func (s *AccessTokenStore) list(ctx context.Context, conds []*sqlf.Query, limitOffset *LimitOffset) ([]*AccessToken, error) {
 	q := sqlf.Sprintf(`
-SELECT id, subject_user_id, scopes, note, creator_user_id, created_at, last_used_at FROM access_tokens
+SELECT id, subject_user_id, scopes, note, creator_user_id, created_at, last_used_at, expires_at FROM access_tokens
 WHERE (%s)
 ORDER BY now() - created_at < interval '5 minutes' DESC, -- show recently created tokens first
 last_used_at DESC NULLS FIRST, -- ensure newly created tokens show first
@@ -240,7 +322,7 @@ created_at DESC
 	var results []*AccessToken
 	for rows.Next() {
 		var t AccessToken
-		if err := rows.Scan(&t.ID, &t.SubjectUserID, pq.Array(&t.Scopes), &t.Note, &t.CreatorUserID, &t.CreatedAt, &t.LastUsedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.SubjectUserID, pq.Array(&t.Scopes), &t.Note, &t.CreatorUserID, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt); err != nil {
 			return nil, err
 		}
 		results = append(results, &t)
@@ -285,6 +367,15 @@ func (s *AccessTokenStore) DeleteByToken(ctx context.Context, tokenHexEncoded st
 	return s.delete(ctx, sqlf.Sprintf("value_sha256=%s", toSHA256Bytes(token)))
 }
 
+// DeleteBySubjectUser deletes all access tokens that authenticate as the given user. It does not
+// return an error if the user has no access tokens.
+func (s *AccessTokenStore) DeleteBySubjectUser(ctx context.Context, subjectUserID int32) error {
+	if Mocks.AccessTokens.DeleteBySubjectUser != nil {
+		return Mocks.AccessTokens.DeleteBySubjectUser(subjectUserID)
+	}
+	return s.Exec(ctx, sqlf.Sprintf("UPDATE access_tokens SET deleted_at=now() WHERE subject_user_id=%d AND deleted_at IS NULL", subjectUserID))
+}
+
 func (s *AccessTokenStore) delete(ctx context.Context, cond *sqlf.Query) error {
 	conds := []*sqlf.Query{cond, sqlf.Sprintf("deleted_at IS NULL")}
 	q := sqlf.Sprintf("UPDATE access_tokens SET deleted_at=now() WHERE (%s)", sqlf.Join(conds, ") AND ("))
@@ -309,8 +400,11 @@ func toSHA256Bytes(input []byte) []byte {
 }
 
 type MockAccessTokens struct {
-	Create     func(subjectUserID int32, scopes []string, note string, creatorUserID int32) (id int64, token string, err error)
-	DeleteByID func(id int64, subjectUserID int32) error
-	Lookup     func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error)
-	GetByID    func(id int64) (*AccessToken, error)
+	Create              func(subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (id int64, token string, err error)
+	DeleteByID          func(id int64, subjectUserID int32) error
+	DeleteBySubjectUser func(subjectUserID int32) error
+	Lookup              func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error)
+	LookupWithScopes    func(tokenHexEncoded string) (subjectUserID int32, scopes []string, err error)
+	Rotate              func(id int64, creatorUserID int32) (newID int64, token string, err error)
+	GetByID             func(id int64) (*AccessToken, error)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
 )
@@ -35,7 +36,7 @@ func TestAccessTokens_Create(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tid0, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID)
+	tid0, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -110,11 +111,11 @@ func TestAccessTokens_List(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, _, err = AccessTokens(db).Create(ctx, subject1.ID, []string{"a", "b"}, "n0", subject1.ID)
+	_, _, err = AccessTokens(db).Create(ctx, subject1.ID, []string{"a", "b"}, "n0", subject1.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, _, err = AccessTokens(db).Create(ctx, subject1.ID, []string{"a", "b"}, "n1", subject1.ID)
+	_, _, err = AccessTokens(db).Create(ctx, subject1.ID, []string{"a", "b"}, "n1", subject1.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,7 +191,7 @@ func TestAccessTokens_Lookup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tid0, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID)
+	tid0, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -259,7 +260,7 @@ func TestAccessTokens_Lookup_deletedUser(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID)
+		_, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -270,7 +271,7 @@ func TestAccessTokens_Lookup_deletedUser(t *testing.T) {
 			t.Fatal("Lookup: want error looking up token for deleted subject user")
 		}
 
-		if _, _, err := AccessTokens(db).Create(ctx, subject.ID, nil, "n0", creator.ID); err == nil {
+		if _, _, err := AccessTokens(db).Create(ctx, subject.ID, nil, "n0", creator.ID, nil); err == nil {
 			t.Fatal("Create: want error creating token for deleted subject user")
 		}
 	})
@@ -295,7 +296,7 @@ func TestAccessTokens_Lookup_deletedUser(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID)
+		_, tv0, err := AccessTokens(db).Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -306,8 +307,133 @@ func TestAccessTokens_Lookup_deletedUser(t *testing.T) {
 			t.Fatal("Lookup: want error looking up token for deleted creator user")
 		}
 
-		if _, _, err := AccessTokens(db).Create(ctx, subject.ID, nil, "n0", creator.ID); err == nil {
+		if _, _, err := AccessTokens(db).Create(ctx, subject.ID, nil, "n0", creator.ID, nil); err == nil {
 			t.Fatal("Create: want error creating token for deleted creator user")
 		}
 	})
 }
+
+func TestAccessTokens_DeleteBySubjectUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	var userIDs []int32
+	for _, username := range []string{"u1", "u2"} {
+		u, err := Users(db).Create(ctx, NewUser{
+			Email:                 username + "@example.com",
+			Username:              username,
+			Password:              "p",
+			EmailVerificationCode: "c",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, u.ID)
+	}
+	subject, other := userIDs[0], userIDs[1]
+
+	var subjectTokens []string
+	for _, note := range []string{"n0", "n1"} {
+		_, tv, err := AccessTokens(db).Create(ctx, subject, []string{"a"}, note, subject, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subjectTokens = append(subjectTokens, tv)
+	}
+	_, otherToken, err := AccessTokens(db).Create(ctx, other, []string{"a"}, "n2", other, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AccessTokens(db).DeleteBySubjectUser(ctx, subject); err != nil {
+		t.Fatal(err)
+	}
+	for _, tv := range subjectTokens {
+		if _, err := AccessTokens(db).Lookup(ctx, tv, "a"); err == nil {
+			t.Error("want deleted token to be invalid")
+		}
+	}
+	if _, err := AccessTokens(db).Lookup(ctx, otherToken, "a"); err != nil {
+		t.Errorf("want token of other user to remain valid, got %v", err)
+	}
+
+	// Deleting again, when there are no tokens left, is not an error.
+	if err := AccessTokens(db).DeleteBySubjectUser(ctx, subject); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 🚨 SECURITY: This tests that expired and rotated access tokens are rejected.
+func TestAccessTokens_ExpiryAndRotate(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Username: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	expiredID, expiredToken, err := AccessTokens(db).Create(ctx, user.ID, []string{"a"}, "expired", user.ID, &expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AccessTokens(db).Lookup(ctx, expiredToken, "a"); err != ErrAccessTokenNotFound {
+		t.Fatalf("Lookup: got err %v, want %v", err, ErrAccessTokenNotFound)
+	}
+	if _, _, err := AccessTokens(db).LookupWithScopes(ctx, expiredToken); err != ErrAccessTokenNotFound {
+		t.Fatalf("LookupWithScopes: got err %v, want %v", err, ErrAccessTokenNotFound)
+	}
+	if _, _, err := AccessTokens(db).Rotate(ctx, expiredID, user.ID); err != ErrAccessTokenExpired {
+		t.Fatalf("Rotate: got err %v, want %v", err, ErrAccessTokenExpired)
+	}
+	if _, err := AccessTokens(db).GetByID(ctx, expiredID); err != nil {
+		t.Fatalf("GetByID: got err %v, want the expired token to be kept", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	id, token, err := AccessTokens(db).Create(ctx, user.ID, []string{"a", "b"}, "n", user.ID, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subjectUserID, scopes, err := AccessTokens(db).LookupWithScopes(ctx, token); err != nil {
+		t.Fatal(err)
+	} else if subjectUserID != user.ID || !reflect.DeepEqual(scopes, []string{"a", "b"}) {
+		t.Fatalf("LookupWithScopes: got %d, %q", subjectUserID, scopes)
+	}
+
+	newID, newToken, err := AccessTokens(db).Rotate(ctx, id, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newID == id || newToken == token {
+		t.Fatal("Rotate: want a new token")
+	}
+	if _, err := AccessTokens(db).Lookup(ctx, token, "a"); err != ErrAccessTokenNotFound {
+		t.Fatalf("Lookup: got err %v, want the rotated token to be rejected", err)
+	}
+	if _, err := AccessTokens(db).Lookup(ctx, newToken, "b"); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := AccessTokens(db).GetByID(ctx, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Note != "n" || !reflect.DeepEqual(rotated.Scopes, []string{"a", "b"}) || rotated.ExpiresAt == nil {
+		t.Fatalf("unexpected rotated token %+v", rotated)
+	}
+	if lifetime := rotated.ExpiresAt.Sub(rotated.CreatedAt); lifetime < 59*time.Minute || lifetime > 61*time.Minute {
+		t.Fatalf("got lifetime %s, want about an hour", lifetime)
+	}
+	if _, _, err := AccessTokens(db).Rotate(ctx, id, user.ID); err != ErrAccessTokenNotFound {
+		t.Fatalf("Rotate: got err %v, want the deleted token to be rejected", err)
+	}
+}
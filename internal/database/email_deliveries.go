@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// The statuses of an email delivery, as reported by the email provider.
+const (
+	EmailDeliverySent        = "sent"
+	EmailDeliveryDelivered   = "delivered"
+	EmailDeliverySoftBounced = "soft_bounced"
+	EmailDeliveryBounced     = "bounced"
+	EmailDeliveryComplained  = "complained"
+)
+
+// EmailDelivery is an email sent to a user, such as a verification email, and what the email
+// provider reported about its delivery.
+type EmailDelivery struct {
+	ID           int64
+	MessageID    string // the Message-ID header, without angle brackets
+	UserID       int32
+	Email        string
+	Kind         string // e.g. "verification" or "notification"
+	Status       string
+	StatusReason string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type EmailDeliveryStore struct {
+	*basestore.Store
+}
+
+// EmailDeliveries instantiates and returns a new EmailDeliveryStore.
+func EmailDeliveries(db dbutil.DB) *EmailDeliveryStore {
+	return &EmailDeliveryStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// EmailDeliveriesWith instantiates and returns a new EmailDeliveryStore using the other store
+// handle.
+func EmailDeliveriesWith(other basestore.ShareableStore) *EmailDeliveryStore {
+	return &EmailDeliveryStore{Store: basestore.NewWithHandle(other.Handle())}
+}
+
+// normalizeMessageID strips the angle brackets and surrounding whitespace of a Message-ID header.
+func normalizeMessageID(messageID string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(messageID), "<"), ">")
+}
+
+// Create records that an email was sent.
+func (s *EmailDeliveryStore) Create(ctx context.Context, d *EmailDelivery) error {
+	if Mocks.EmailDeliveries.Create != nil {
+		return Mocks.EmailDeliveries.Create(ctx, d)
+	}
+
+	d.MessageID = normalizeMessageID(d.MessageID)
+	if d.Status == "" {
+		d.Status = EmailDeliverySent
+	}
+	return s.QueryRow(ctx, sqlf.Sprintf(`
+INSERT INTO email_deliveries (message_id, user_id, email, kind, status)
+VALUES (%s, NULLIF(%s, 0), %s, %s, %s)
+RETURNING id, created_at, updated_at
+`, d.MessageID, d.UserID, d.Email, d.Kind, d.Status)).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+}
+
+// UpdateStatus records the status that the email provider reported for an email sent to the
+// address. The email is looked up by its message ID. If the provider didn't report the message
+// ID or it is unknown, the most recent email sent to the address is updated instead. It returns
+// false if no email was sent to the address.
+func (s *EmailDeliveryStore) UpdateStatus(ctx context.Context, messageID, email, status, reason string) (bool, error) {
+	if Mocks.EmailDeliveries.UpdateStatus != nil {
+		return Mocks.EmailDeliveries.UpdateStatus(ctx, messageID, email, status, reason)
+	}
+
+	res, err := s.ExecResult(ctx, sqlf.Sprintf(`
+UPDATE email_deliveries
+SET status = %s, status_reason = NULLIF(%s, ''), updated_at = now()
+WHERE id = (
+	SELECT id FROM email_deliveries
+	WHERE email = %s
+	ORDER BY message_id = %s DESC, created_at DESC
+	LIMIT 1
+)
+`, status, reason, email, normalizeMessageID(messageID)))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListByUser returns the emails sent to the user, most recent first.
+func (s *EmailDeliveryStore) ListByUser(ctx context.Context, userID int32, limitOffset *LimitOffset) ([]*EmailDelivery, error) {
+	rows, err := s.Query(ctx, sqlf.Sprintf(`
+SELECT id, message_id, COALESCE(user_id, 0), email, kind, status, COALESCE(status_reason, ''), created_at, updated_at
+FROM email_deliveries
+WHERE user_id = %s
+ORDER BY created_at DESC, id DESC
+%s
+`, userID, limitOffset.SQL()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*EmailDelivery
+	for rows.Next() {
+		var d EmailDelivery
+		if err := rows.Scan(&d.ID, &d.MessageID, &d.UserID, &d.Email, &d.Kind, &d.Status, &d.StatusReason, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,8 @@
+package database
+
+import "context"
+
+type MockEmailDeliveries struct {
+	Create       func(ctx context.Context, d *EmailDelivery) error
+	UpdateStatus func(ctx context.Context, messageID, email, status, reason string) (bool, error)
+}
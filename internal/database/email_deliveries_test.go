@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+)
+
+func TestEmailDeliveries(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Email: "alice@example.com", Username: "alice", EmailVerificationCode: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := EmailDeliveries(db)
+
+	first := &EmailDelivery{MessageID: "<1@example.com>", UserID: user.ID, Email: "alice@example.com", Kind: "verification"}
+	if err := store.Create(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if first.MessageID != "1@example.com" || first.Status != EmailDeliverySent {
+		t.Fatalf("unexpected delivery %+v", first)
+	}
+	second := &EmailDelivery{MessageID: "<2@example.com>", UserID: user.ID, Email: "alice@example.com", Kind: "notification"}
+	if err := store.Create(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Events are matched by message ID, or else to the most recent email to the address.
+	if ok, err := store.UpdateStatus(ctx, "<1@example.com>", "alice@example.com", EmailDeliveryDelivered, ""); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the delivery to be updated", ok, err)
+	}
+	if ok, err := store.UpdateStatus(ctx, "", "alice@example.com", EmailDeliveryBounced, "550 no such user"); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the delivery to be updated", ok, err)
+	}
+	if ok, err := store.UpdateStatus(ctx, "", "bob@example.com", EmailDeliveryBounced, ""); err != nil || ok {
+		t.Fatalf("got %v, %v, want no delivery to be updated", ok, err)
+	}
+
+	deliveries, err := store.ListByUser(ctx, user.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2", len(deliveries))
+	}
+	if d := deliveries[0]; d.ID != second.ID || d.Status != EmailDeliveryBounced || d.StatusReason != "550 no such user" {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if d := deliveries[1]; d.ID != first.ID || d.Status != EmailDeliveryDelivered || d.StatusReason != "" {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+
+	// Bouncing addresses are marked until they are verified again.
+	if n, err := UserEmails(db).MarkBounced(ctx, "alice@example.com", "550 no such user"); err != nil || n != 1 {
+		t.Fatalf("got %d, %v, want 1 user email marked", n, err)
+	}
+	emails, err := UserEmails(db).ListByUser(ctx, UserEmailsListOptions{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].BouncedAt == nil || emails[0].BounceReason == nil || *emails[0].BounceReason != "550 no such user" {
+		t.Fatalf("want email to be bouncing, got %+v", emails)
+	}
+	if ok, err := UserEmails(db).Verify(ctx, user.ID, "alice@example.com", "c"); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the email to be verified", ok, err)
+	}
+	emails, err = UserEmails(db).ListByUser(ctx, UserEmailsListOptions{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].BouncedAt != nil || emails[0].BounceReason != nil {
+		t.Fatalf("want email to no longer be bouncing, got %+v", emails)
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// EmailDomainPolicyOverride allows or denies email addresses of a domain and its subdomains,
+// taking precedence over the email.domainPolicy site configuration.
+type EmailDomainPolicyOverride struct {
+	Domain    string
+	Allow     bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type emailDomainPolicyOverrideNotFoundError struct {
+	domain string
+}
+
+func (e emailDomainPolicyOverrideNotFoundError) Error() string {
+	return fmt.Sprintf("email domain policy override not found: %s", e.domain)
+}
+
+func (emailDomainPolicyOverrideNotFoundError) NotFound() bool {
+	return true
+}
+
+type EmailDomainPolicyOverridesStore struct {
+	*basestore.Store
+}
+
+func EmailDomainPolicyOverrides(db dbutil.DB) *EmailDomainPolicyOverridesStore {
+	return &EmailDomainPolicyOverridesStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// List returns all overrides, ordered by domain.
+func (s *EmailDomainPolicyOverridesStore) List(ctx context.Context) ([]*EmailDomainPolicyOverride, error) {
+	if Mocks.EmailDomainPolicyOverrides.List != nil {
+		return Mocks.EmailDomainPolicyOverrides.List(ctx)
+	}
+
+	rows, err := s.Query(ctx, sqlf.Sprintf(`
+SELECT domain, allow, created_at, updated_at
+FROM email_domain_policy_overrides
+ORDER BY domain
+`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*EmailDomainPolicyOverride
+	for rows.Next() {
+		var o EmailDomainPolicyOverride
+		if err := rows.Scan(&o.Domain, &o.Allow, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, &o)
+	}
+	return overrides, rows.Err()
+}
+
+// Upsert creates the override for the domain, or replaces the existing one.
+func (s *EmailDomainPolicyOverridesStore) Upsert(ctx context.Context, domain string, allow bool) (*EmailDomainPolicyOverride, error) {
+	if Mocks.EmailDomainPolicyOverrides.Upsert != nil {
+		return Mocks.EmailDomainPolicyOverrides.Upsert(ctx, domain, allow)
+	}
+
+	o := EmailDomainPolicyOverride{Domain: domain, Allow: allow}
+	err := s.QueryRow(ctx, sqlf.Sprintf(`
+INSERT INTO email_domain_policy_overrides (domain, allow)
+VALUES (%s, %s)
+ON CONFLICT (domain) DO UPDATE SET
+	allow = EXCLUDED.allow,
+	updated_at = now()
+RETURNING created_at, updated_at
+`, domain, allow)).Scan(&o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Delete removes the override for the domain.
+func (s *EmailDomainPolicyOverridesStore) Delete(ctx context.Context, domain string) error {
+	if Mocks.EmailDomainPolicyOverrides.Delete != nil {
+		return Mocks.EmailDomainPolicyOverrides.Delete(ctx, domain)
+	}
+
+	res, err := s.ExecResult(ctx, sqlf.Sprintf("DELETE FROM email_domain_policy_overrides WHERE domain = %s", domain))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return emailDomainPolicyOverrideNotFoundError{domain: domain}
+	}
+	return nil
+}
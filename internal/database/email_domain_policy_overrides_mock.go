@@ -0,0 +1,9 @@
+package database
+
+import "context"
+
+type MockEmailDomainPolicyOverrides struct {
+	List   func(ctx context.Context) ([]*EmailDomainPolicyOverride, error)
+	Upsert func(ctx context.Context, domain string, allow bool) (*EmailDomainPolicyOverride, error)
+	Delete func(ctx context.Context, domain string) error
+}
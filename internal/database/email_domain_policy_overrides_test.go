@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestEmailDomainPolicyOverrides(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	store := EmailDomainPolicyOverrides(dbtest.NewDB(t, ""))
+	ctx := context.Background()
+
+	if _, err := store.Upsert(ctx, "mailinator.com", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Upsert(ctx, "example.com", false); err != nil {
+		t.Fatal(err)
+	}
+	// Domains are case-insensitive, so this replaces the override above.
+	updated, err := store.Upsert(ctx, "Example.COM", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Allow || updated.UpdatedAt.Before(updated.CreatedAt) {
+		t.Fatalf("unexpected override after update: %+v", updated)
+	}
+
+	overrides, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides) != 2 {
+		t.Fatalf("got %d overrides, want 2", len(overrides))
+	}
+	if o := overrides[0]; o.Domain != "example.com" || !o.Allow {
+		t.Errorf("got first override %+v, want allowed example.com", o)
+	}
+	if o := overrides[1]; o.Domain != "mailinator.com" || o.Allow {
+		t.Errorf("got second override %+v, want denied mailinator.com", o)
+	}
+
+	if err := store.Delete(ctx, "MAILINATOR.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "mailinator.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+	if overrides, err := store.List(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(overrides) != 1 {
+		t.Fatalf("got %d overrides after delete, want 1", len(overrides))
+	}
+}
@@ -86,6 +86,10 @@ type Event struct {
 }
 
 func (l *EventLogStore) Insert(ctx context.Context, e *Event) error {
+	if Mocks.EventLogs.Insert != nil {
+		return Mocks.EventLogs.Insert(ctx, e)
+	}
+
 	// 🚨 SECURITY: It is important to sanitize event URL before being stored to the
 	// database to help guarantee no malicious data at rest.
 	e.URL = SanitizeEventURL(e.URL)
@@ -155,10 +159,12 @@ type EventLogsListOptions struct {
 	*LimitOffset
 
 	EventName *string
+
+	// EventNames, if set, restricts the events to those with one of the given names.
+	EventNames []string
 }
 
-// ListAll gets all event logs in descending order of timestamp.
-func (l *EventLogStore) ListAll(ctx context.Context, opt EventLogsListOptions) ([]*types.Event, error) {
+func (opt EventLogsListOptions) sqlConditions() []*sqlf.Query {
 	conds := []*sqlf.Query{sqlf.Sprintf("TRUE")}
 	if opt.UserID != 0 {
 		conds = append(conds, sqlf.Sprintf("user_id = %d", opt.UserID))
@@ -166,7 +172,28 @@ func (l *EventLogStore) ListAll(ctx context.Context, opt EventLogsListOptions) (
 	if opt.EventName != nil {
 		conds = append(conds, sqlf.Sprintf("name = %s", opt.EventName))
 	}
-	return l.getBySQL(ctx, sqlf.Sprintf("WHERE %s ORDER BY timestamp DESC %s", sqlf.Join(conds, "AND"), opt.LimitOffset.SQL()))
+	if opt.EventNames != nil {
+		conds = append(conds, sqlf.Sprintf("name = ANY(%s)", pq.Array(opt.EventNames)))
+	}
+	return conds
+}
+
+// ListAll gets all event logs in descending order of timestamp.
+func (l *EventLogStore) ListAll(ctx context.Context, opt EventLogsListOptions) ([]*types.Event, error) {
+	if Mocks.EventLogs.ListAll != nil {
+		return Mocks.EventLogs.ListAll(ctx, opt)
+	}
+
+	return l.getBySQL(ctx, sqlf.Sprintf("WHERE %s ORDER BY timestamp DESC %s", sqlf.Join(opt.sqlConditions(), "AND"), opt.LimitOffset.SQL()))
+}
+
+// CountAll counts the event logs matching the options, ignoring pagination.
+func (l *EventLogStore) CountAll(ctx context.Context, opt EventLogsListOptions) (int, error) {
+	if Mocks.EventLogs.CountAll != nil {
+		return Mocks.EventLogs.CountAll(ctx, opt)
+	}
+
+	return l.countBySQL(ctx, sqlf.Sprintf("WHERE %s", sqlf.Join(opt.sqlConditions(), "AND")))
 }
 
 // LatestPing returns the most recently recorded ping event.
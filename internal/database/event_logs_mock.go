@@ -7,5 +7,8 @@ import (
 )
 
 type MockEventLogs struct {
+	Insert     func(ctx context.Context, e *Event) error
 	LatestPing func(ctx context.Context) (*types.Event, error)
+	ListAll    func(ctx context.Context, opt EventLogsListOptions) ([]*types.Event, error)
+	CountAll   func(ctx context.Context, opt EventLogsListOptions) (int, error)
 }
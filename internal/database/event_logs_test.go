@@ -952,6 +952,24 @@ func TestEventLogs_ListAll(t *testing.T) {
 	if diff := cmp.Diff(want, len(have)); diff != "" {
 		t.Error(diff)
 	}
+
+	opt := EventLogsListOptions{UserID: 2, EventNames: []string{"codeintel", "SearchResultsQueried"}}
+	have, err = EventLogs(db).ListAll(ctx, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(2, len(have)); diff != "" {
+		t.Error(diff)
+	}
+
+	opt.LimitOffset = &LimitOffset{Limit: 1}
+	count, err := EventLogs(db).CountAll(ctx, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(2, count); diff != "" {
+		t.Error(diff)
+	}
 }
 
 func TestEventLogs_LatestPing(t *testing.T) {
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// GraphQLPersistedQuery is a GraphQL query that a client registered to run it by its hash.
+type GraphQLPersistedQuery struct {
+	Hash          string // hex-encoded SHA-256 hash of the query
+	Query         string
+	CreatorUserID *int32 // nil if registered by an anonymous user
+	CreatedAt     time.Time
+}
+
+// graphQLPersistedQueryNotFoundError occurs when no query with the hash was registered.
+type graphQLPersistedQueryNotFoundError struct {
+	hash string
+}
+
+func (e graphQLPersistedQueryNotFoundError) Error() string {
+	return fmt.Sprintf("persisted GraphQL query %q not found", e.hash)
+}
+
+func (e graphQLPersistedQueryNotFoundError) NotFound() bool { return true }
+
+type GraphQLPersistedQueryStore struct {
+	*basestore.Store
+}
+
+// GraphQLPersistedQueries instantiates and returns a new GraphQLPersistedQueryStore.
+func GraphQLPersistedQueries(db dbutil.DB) *GraphQLPersistedQueryStore {
+	return &GraphQLPersistedQueryStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// GraphQLPersistedQueriesWith instantiates and returns a new GraphQLPersistedQueryStore using the
+// other store handle.
+func GraphQLPersistedQueriesWith(other basestore.ShareableStore) *GraphQLPersistedQueryStore {
+	return &GraphQLPersistedQueryStore{Store: basestore.NewWithHandle(other.Handle())}
+}
+
+// Create registers the query with its hash. Registering a query again does nothing, so the creator
+// of a query is the user who registered it first.
+func (s *GraphQLPersistedQueryStore) Create(ctx context.Context, hash, query string, creatorUserID *int32) error {
+	if Mocks.GraphQLPersistedQueries.Create != nil {
+		return Mocks.GraphQLPersistedQueries.Create(ctx, hash, query, creatorUserID)
+	}
+
+	return s.Exec(ctx, sqlf.Sprintf(`
+INSERT INTO graphql_persisted_queries (hash, query, creator_user_id)
+VALUES (%s, %s, %s)
+ON CONFLICT (hash) DO NOTHING
+`, hash, query, creatorUserID))
+}
+
+// GetByHash returns the query with the hash. It returns an error that satisfies
+// errcode.IsNotFound if no query with the hash was registered.
+func (s *GraphQLPersistedQueryStore) GetByHash(ctx context.Context, hash string) (*GraphQLPersistedQuery, error) {
+	if Mocks.GraphQLPersistedQueries.GetByHash != nil {
+		return Mocks.GraphQLPersistedQueries.GetByHash(ctx, hash)
+	}
+
+	var q GraphQLPersistedQuery
+	err := s.QueryRow(ctx, sqlf.Sprintf(`
+SELECT hash, query, creator_user_id, created_at
+FROM graphql_persisted_queries
+WHERE hash = %s
+`, hash)).Scan(&q.Hash, &q.Query, &q.CreatorUserID, &q.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, graphQLPersistedQueryNotFoundError{hash: hash}
+	} else if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
@@ -0,0 +1,8 @@
+package database
+
+import "context"
+
+type MockGraphQLPersistedQueries struct {
+	Create    func(ctx context.Context, hash, query string, creatorUserID *int32) error
+	GetByHash func(ctx context.Context, hash string) (*GraphQLPersistedQuery, error)
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := GraphQLPersistedQueries(db)
+
+	if _, err := store.GetByHash(ctx, "h1"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+
+	if err := store.Create(ctx, "h1", "query { a }", &user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(ctx, "h2", "query { b }", nil); err != nil {
+		t.Fatal(err)
+	}
+	// Registering a query again keeps the first registration.
+	if err := store.Create(ctx, "h1", "query { a }", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := store.GetByHash(ctx, "h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Query != "query { a }" || q.CreatorUserID == nil || *q.CreatorUserID != user.ID {
+		t.Fatalf("unexpected persisted query %+v", q)
+	}
+	q, err = store.GetByHash(ctx, "h2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Query != "query { b }" || q.CreatorUserID != nil {
+		t.Fatalf("unexpected persisted query %+v", q)
+	}
+}
@@ -10,6 +10,7 @@ type MockStores struct {
 	Namespaces      MockNamespaces
 	Orgs            MockOrgs
 	OrgMembers      MockOrgMembers
+	OrgDomains      MockOrgDomains
 	SavedSearches   MockSavedSearches
 	Settings        MockSettings
 	Users           MockUsers
@@ -31,4 +32,12 @@ type MockStores struct {
 	EventLogs MockEventLogs
 
 	TemporarySettings MockTemporarySettings
+
+	EmailDomainPolicyOverrides MockEmailDomainPolicyOverrides
+
+	EmailDeliveries MockEmailDeliveries
+
+	UserTOTPs MockUserTOTPs
+
+	GraphQLPersistedQueries MockGraphQLPersistedQueries
 }
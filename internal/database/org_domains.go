@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jackc/pgconn"
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+// OrgDomain is an email domain of an organization. Once the organization proves control of the
+// domain, users who verify an email address of the domain join the organization.
+type OrgDomain struct {
+	ID                int32
+	OrgID             int32
+	Domain            string
+	VerificationToken string
+	VerifiedAt        *time.Time
+	LastCheckedAt     *time.Time
+	CreatedAt         time.Time
+}
+
+type orgDomainNotFoundError struct {
+	args []interface{}
+}
+
+func (e orgDomainNotFoundError) Error() string {
+	return fmt.Sprintf("organization domain not found: %v", e.args)
+}
+
+func (orgDomainNotFoundError) NotFound() bool {
+	return true
+}
+
+type OrgDomainStore struct {
+	*basestore.Store
+}
+
+// OrgDomains instantiates and returns a new OrgDomainStore.
+func OrgDomains(db dbutil.DB) *OrgDomainStore {
+	return &OrgDomainStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// OrgDomainsWith instantiates and returns a new OrgDomainStore using the other store handle.
+func OrgDomainsWith(other basestore.ShareableStore) *OrgDomainStore {
+	return &OrgDomainStore{Store: basestore.NewWithHandle(other.Handle())}
+}
+
+// Create adds the domain to the organization, unverified.
+func (s *OrgDomainStore) Create(ctx context.Context, orgID int32, domain, verificationToken string) (*OrgDomain, error) {
+	if Mocks.OrgDomains.Create != nil {
+		return Mocks.OrgDomains.Create(ctx, orgID, domain, verificationToken)
+	}
+
+	d := OrgDomain{OrgID: orgID, Domain: domain, VerificationToken: verificationToken}
+	if err := s.QueryRow(ctx, sqlf.Sprintf(`
+INSERT INTO org_domains (org_id, domain, verification_token)
+VALUES (%s, %s, %s)
+RETURNING id, created_at
+`, orgID, domain, verificationToken)).Scan(&d.ID, &d.CreatedAt); err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.ConstraintName == "org_domains_org_id_domain_key" {
+			return nil, errors.New("domain was already added to the organization")
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Get returns the domain of the organization.
+func (s *OrgDomainStore) Get(ctx context.Context, orgID int32, domain string) (*OrgDomain, error) {
+	if Mocks.OrgDomains.Get != nil {
+		return Mocks.OrgDomains.Get(ctx, orgID, domain)
+	}
+
+	domains, err := s.list(ctx, sqlf.Sprintf("org_id = %s AND domain = %s", orgID, domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, orgDomainNotFoundError{[]interface{}{orgID, domain}}
+	}
+	return domains[0], nil
+}
+
+// ListByOrg returns the domains of the organization, ordered by domain.
+func (s *OrgDomainStore) ListByOrg(ctx context.Context, orgID int32) ([]*OrgDomain, error) {
+	if Mocks.OrgDomains.ListByOrg != nil {
+		return Mocks.OrgDomains.ListByOrg(ctx, orgID)
+	}
+	return s.list(ctx, sqlf.Sprintf("org_id = %s", orgID), nil)
+}
+
+// ListDueForCheck returns up to limit unverified domains that were added after addedAfter and
+// weren't checked after checkedBefore, least recently checked first.
+func (s *OrgDomainStore) ListDueForCheck(ctx context.Context, addedAfter, checkedBefore time.Time, limit int) ([]*OrgDomain, error) {
+	return s.list(ctx, sqlf.Sprintf(
+		"verified_at IS NULL AND created_at > %s AND (last_checked_at IS NULL OR last_checked_at < %s)",
+		addedAfter, checkedBefore,
+	), &LimitOffset{Limit: limit})
+}
+
+// GetVerifiedForEmail returns the verified domain of the email address, if an organization that
+// isn't deleted verified it. Subdomains don't match.
+func (s *OrgDomainStore) GetVerifiedForEmail(ctx context.Context, email string) (*OrgDomain, error) {
+	if Mocks.OrgDomains.GetVerifiedForEmail != nil {
+		return Mocks.OrgDomains.GetVerifiedForEmail(ctx, email)
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return nil, orgDomainNotFoundError{[]interface{}{email}}
+	}
+	domains, err := s.list(ctx, sqlf.Sprintf(
+		"domain = %s AND verified_at IS NOT NULL AND EXISTS (SELECT 1 FROM orgs WHERE orgs.id = org_id AND orgs.deleted_at IS NULL)",
+		email[at+1:],
+	), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, orgDomainNotFoundError{[]interface{}{email}}
+	}
+	return domains[0], nil
+}
+
+// MarkChecked records that the DNS TXT records of the domain were checked, and marks the domain
+// as verified if verified is true. It fails if another organization verified the domain already.
+func (s *OrgDomainStore) MarkChecked(ctx context.Context, id int32, verified bool) error {
+	if Mocks.OrgDomains.MarkChecked != nil {
+		return Mocks.OrgDomains.MarkChecked(ctx, id, verified)
+	}
+
+	q := sqlf.Sprintf("UPDATE org_domains SET last_checked_at = now() WHERE id = %s", id)
+	if verified {
+		q = sqlf.Sprintf("UPDATE org_domains SET last_checked_at = now(), verified_at = COALESCE(verified_at, now()) WHERE id = %s", id)
+	}
+	res, err := s.ExecResult(ctx, q)
+	if err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.ConstraintName == "org_domains_domain_verified_idx" {
+			return errors.New("domain is already verified by another organization")
+		}
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return orgDomainNotFoundError{[]interface{}{id}}
+	}
+	return nil
+}
+
+// Delete removes the domain from the organization. Users who joined the organization because of
+// the domain remain members.
+func (s *OrgDomainStore) Delete(ctx context.Context, orgID int32, domain string) error {
+	if Mocks.OrgDomains.Delete != nil {
+		return Mocks.OrgDomains.Delete(ctx, orgID, domain)
+	}
+
+	res, err := s.ExecResult(ctx, sqlf.Sprintf("DELETE FROM org_domains WHERE org_id = %s AND domain = %s", orgID, domain))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return orgDomainNotFoundError{[]interface{}{orgID, domain}}
+	}
+	return nil
+}
+
+// JoinOrgsForEmail adds the user as a member of the organization that verified the domain of the
+// email address, if any. It returns the IDs of the organizations the user was added to.
+//
+// 🚨 SECURITY: The caller must ensure that the user has verified ownership of the email address.
+func (s *OrgDomainStore) JoinOrgsForEmail(ctx context.Context, userID int32, email string) ([]int32, error) {
+	d, err := s.GetVerifiedForEmail(ctx, email)
+	if err != nil {
+		if errcode.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return basestore.ScanInt32s(s.Query(ctx, sqlf.Sprintf(
+		"INSERT INTO org_members (org_id, user_id) VALUES (%s, %s) ON CONFLICT (org_id, user_id) DO NOTHING RETURNING org_id",
+		d.OrgID, userID,
+	)))
+}
+
+func (s *OrgDomainStore) list(ctx context.Context, cond *sqlf.Query, limitOffset *LimitOffset) ([]*OrgDomain, error) {
+	rows, err := s.Query(ctx, sqlf.Sprintf(`
+SELECT id, org_id, domain, verification_token, verified_at, last_checked_at, created_at
+FROM org_domains
+WHERE %s
+ORDER BY domain, id
+%s
+`, cond, limitOffset.SQL()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []*OrgDomain
+	for rows.Next() {
+		var d OrgDomain
+		if err := rows.Scan(&d.ID, &d.OrgID, &d.Domain, &d.VerificationToken, &d.VerifiedAt, &d.LastCheckedAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		domains = append(domains, &d)
+	}
+	return domains, rows.Err()
+}
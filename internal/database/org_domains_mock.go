@@ -0,0 +1,12 @@
+package database
+
+import "context"
+
+type MockOrgDomains struct {
+	Create              func(ctx context.Context, orgID int32, domain, verificationToken string) (*OrgDomain, error)
+	Get                 func(ctx context.Context, orgID int32, domain string) (*OrgDomain, error)
+	ListByOrg           func(ctx context.Context, orgID int32) ([]*OrgDomain, error)
+	GetVerifiedForEmail func(ctx context.Context, email string) (*OrgDomain, error)
+	MarkChecked         func(ctx context.Context, id int32, verified bool) error
+	Delete              func(ctx context.Context, orgID int32, domain string) error
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestOrgDomains(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	org, err := Orgs(db).Create(ctx, "acme", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherOrg, err := Orgs(db).Create(ctx, "other", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := OrgDomains(db)
+
+	d, err := store.Create(ctx, org.ID, "example.com", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create(ctx, org.ID, "Example.COM", "token2"); err == nil {
+		t.Fatal("want error adding the same domain twice")
+	}
+	otherD, err := store.Create(ctx, otherOrg.ID, "example.com", "token3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.GetVerifiedForEmail(ctx, "alice@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found for an unverified domain", err)
+	}
+	if err := store.MarkChecked(ctx, d.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkChecked(ctx, otherD.ID, true); err == nil {
+		t.Fatal("want error verifying a domain that another organization verified")
+	}
+	if got, err := store.GetVerifiedForEmail(ctx, "alice@EXAMPLE.com"); err != nil {
+		t.Fatal(err)
+	} else if got.OrgID != org.ID || got.VerifiedAt == nil || got.LastCheckedAt == nil {
+		t.Fatalf("unexpected domain %+v", got)
+	}
+	if _, err := store.GetVerifiedForEmail(ctx, "alice@eu.example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found for a subdomain", err)
+	}
+
+	// Users join the organization when they verify an address of the domain.
+	user, err := Users(db).Create(ctx, NewUser{Email: "alice@example.com", Username: "alice", EmailVerificationCode: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, org.ID, user.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found before verifying", err)
+	}
+	if err := UserEmails(db).SetVerified(ctx, user.ID, "alice@example.com", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, org.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, otherOrg.ID, user.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found for the organization that didn't verify the domain", err)
+	}
+
+	if err := store.Delete(ctx, org.ID, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, org.ID, "example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+	if domains, err := store.ListByOrg(ctx, otherOrg.ID); err != nil {
+		t.Fatal(err)
+	} else if len(domains) != 1 || domains[0].VerifiedAt != nil {
+		t.Fatalf("unexpected domains %+v", domains)
+	}
+}
@@ -18,8 +18,9 @@ import (
 type OrgInvitation struct {
 	ID              int64
 	OrgID           int32
-	SenderUserID    int32 // the sender of the invitation
-	RecipientUserID int32 // the recipient of the invitation
+	SenderUserID    int32  // the sender of the invitation
+	RecipientUserID int32  // the recipient of the invitation (0 if the invitation was sent to an email address that has not been claimed yet)
+	RecipientEmail  string // the email address the invitation was sent to, if it was not sent to an existing user
 	CreatedAt       time.Time
 	NotifiedAt      *time.Time
 	RespondedAt     *time.Time
@@ -92,6 +93,29 @@ func (s *OrgInvitationStore) Create(ctx context.Context, orgID, senderUserID, re
 	return t, nil
 }
 
+// CreateForEmail creates an invitation to join the org for the given email address. It is used
+// when the recipient does not have an account yet: the invitation is accepted on the recipient's
+// behalf once a user verifies the address (see AcceptPendingForEmail).
+func (s *OrgInvitationStore) CreateForEmail(ctx context.Context, orgID, senderUserID int32, email string) (*OrgInvitation, error) {
+	t := &OrgInvitation{
+		OrgID:          orgID,
+		SenderUserID:   senderUserID,
+		RecipientEmail: email,
+	}
+	if err := s.Handle().DB().QueryRowContext(
+		ctx,
+		"INSERT INTO org_invitations(org_id, sender_user_id, recipient_email) VALUES($1, $2, $3) RETURNING id, created_at",
+		orgID, senderUserID, email,
+	).Scan(&t.ID, &t.CreatedAt); err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.ConstraintName == "org_invitations_email_singleflight" {
+			return nil, errors.New("email address was already invited to organization (and has not responded yet)")
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
 // GetByID retrieves the org invitation (if any) given its ID.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is permitted to view this org invitation.
@@ -116,7 +140,8 @@ func (s *OrgInvitationStore) GetByID(ctx context.Context, id int64) (*OrgInvitat
 // 🚨 SECURITY: The caller must ensure that the actor is permitted to view this org invitation.
 func (s *OrgInvitationStore) GetPending(ctx context.Context, orgID, recipientUserID int32) (*OrgInvitation, error) {
 	results, err := s.list(ctx, []*sqlf.Query{
-		sqlf.Sprintf("org_id=%d AND recipient_user_id=%d AND responded_at IS NULL AND revoked_at IS NULL", orgID, recipientUserID),
+		sqlf.Sprintf("org_id=%d AND responded_at IS NULL AND revoked_at IS NULL", orgID),
+		invitationRecipientCond(recipientUserID),
 	}, nil)
 	if err != nil {
 		return nil, err
@@ -129,8 +154,9 @@ func (s *OrgInvitationStore) GetPending(ctx context.Context, orgID, recipientUse
 
 // OrgInvitationsListOptions contains options for listing org invitations.
 type OrgInvitationsListOptions struct {
-	OrgID           int32 // only list org invitations for this org
-	RecipientUserID int32 // only list org invitations with this user as the recipient
+	OrgID           int32  // only list org invitations for this org
+	RecipientUserID int32  // only list org invitations with this user as the recipient
+	RecipientEmail  string // only list org invitations sent to this email address
 	*LimitOffset
 }
 
@@ -142,6 +168,9 @@ func (o OrgInvitationsListOptions) sqlConditions() []*sqlf.Query {
 	if o.RecipientUserID != 0 {
 		conds = append(conds, sqlf.Sprintf("recipient_user_id=%d", o.RecipientUserID))
 	}
+	if o.RecipientEmail != "" {
+		conds = append(conds, sqlf.Sprintf("recipient_email=%s", o.RecipientEmail))
+	}
 	if len(conds) == 0 {
 		conds = append(conds, sqlf.Sprintf("TRUE"))
 	}
@@ -158,7 +187,7 @@ func (s *OrgInvitationStore) List(ctx context.Context, opt OrgInvitationsListOpt
 
 func (s *OrgInvitationStore) list(ctx context.Context, conds []*sqlf.Query, limitOffset *LimitOffset) ([]*OrgInvitation, error) {
 	q := sqlf.Sprintf(`
-SELECT id, org_id, sender_user_id, recipient_user_id, created_at, notified_at, responded_at, response_type, revoked_at FROM org_invitations
+SELECT id, org_id, sender_user_id, recipient_user_id, recipient_email, created_at, notified_at, responded_at, response_type, revoked_at FROM org_invitations
 WHERE (%s) AND deleted_at IS NULL
 ORDER BY id ASC
 %s`,
@@ -175,7 +204,7 @@ ORDER BY id ASC
 	var results []*OrgInvitation
 	for rows.Next() {
 		var t OrgInvitation
-		if err := rows.Scan(&t.ID, &t.OrgID, &t.SenderUserID, &t.RecipientUserID, &t.CreatedAt, &t.NotifiedAt, &t.RespondedAt, &t.ResponseType, &t.RevokedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.OrgID, &t.SenderUserID, &dbutil.NullInt32{N: &t.RecipientUserID}, &dbutil.NullString{S: &t.RecipientEmail}, &t.CreatedAt, &t.NotifiedAt, &t.RespondedAt, &t.ResponseType, &t.RevokedAt); err != nil {
 			return nil, err
 		}
 		results = append(results, &t)
@@ -212,11 +241,29 @@ func (s *OrgInvitationStore) UpdateEmailSentTimestamp(ctx context.Context, id in
 	return nil
 }
 
+// invitationRecipientCond matches the invitations the given user is the recipient of: those sent
+// to the user, and those sent to an email address the user has verified.
+func invitationRecipientCond(recipientUserID int32) *sqlf.Query {
+	return sqlf.Sprintf(`(
+	recipient_user_id=%d OR (
+		recipient_user_id IS NULL AND
+		recipient_email IN (SELECT email FROM user_emails WHERE user_id=%d AND verified_at IS NOT NULL AND deleted_at IS NULL)
+	)
+)`, recipientUserID, recipientUserID)
+}
+
 // Respond sets the recipient's response to the org invitation and returns the organization's ID to
 // which the recipient was invited. If the recipient user ID given is incorrect, an
 // OrgInvitationNotFoundError error is returned.
+//
+// Invitations sent to an email address the recipient has verified can be responded to as well,
+// which records the recipient on them.
 func (s *OrgInvitationStore) Respond(ctx context.Context, id int64, recipientUserID int32, accept bool) (orgID int32, err error) {
-	if err := s.Handle().DB().QueryRowContext(ctx, "UPDATE org_invitations SET responded_at=now(), response_type=$3 WHERE id=$1 AND recipient_user_id=$2 AND responded_at IS NULL AND revoked_at IS NULL AND deleted_at IS NULL RETURNING org_id", id, recipientUserID, accept).Scan(&orgID); err == sql.ErrNoRows {
+	q := sqlf.Sprintf(
+		"UPDATE org_invitations SET recipient_user_id=%d, responded_at=now(), response_type=%s WHERE id=%d AND %s AND responded_at IS NULL AND revoked_at IS NULL AND deleted_at IS NULL RETURNING org_id",
+		recipientUserID, accept, id, invitationRecipientCond(recipientUserID),
+	)
+	if err := s.Handle().DB().QueryRowContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...).Scan(&orgID); err == sql.ErrNoRows {
 		return 0, OrgInvitationNotFoundError{[]interface{}{fmt.Sprintf("id %d recipient %d", id, recipientUserID)}}
 	} else if err != nil {
 		return 0, err
@@ -224,6 +271,53 @@ func (s *OrgInvitationStore) Respond(ctx context.Context, id int64, recipientUse
 	return orgID, nil
 }
 
+// AcceptPendingForEmail accepts all pending invitations that were sent to the given email address
+// on behalf of the user, and adds the user as a member of each of the invitations' organizations.
+// It returns the IDs of the organizations the user was added to.
+//
+// It is called when the user verifies the email address, and should be called in the same
+// transaction as the verification so that the user is never left with a verified address but
+// without the memberships they were invited to (or vice versa).
+//
+// 🚨 SECURITY: The caller must ensure that the user has verified ownership of the email address.
+func (s *OrgInvitationStore) AcceptPendingForEmail(ctx context.Context, userID int32, email string) (orgIDs []int32, err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	orgIDs, err = basestore.ScanInt32s(tx.Query(ctx, sqlf.Sprintf(acceptPendingForEmailQuery, userID, email)))
+	if err != nil {
+		return nil, err
+	}
+	for _, orgID := range orgIDs {
+		if err := tx.Exec(ctx, sqlf.Sprintf(
+			"INSERT INTO org_members (org_id, user_id) VALUES (%s, %s) ON CONFLICT (org_id, user_id) DO NOTHING",
+			orgID, userID,
+		)); err != nil {
+			return nil, err
+		}
+	}
+	return orgIDs, nil
+}
+
+const acceptPendingForEmailQuery = `
+-- source: internal/database/org_invitations.go:AcceptPendingForEmail
+UPDATE org_invitations
+SET
+	recipient_user_id = %s,
+	responded_at = now(),
+	response_type = true
+WHERE
+	recipient_email = %s AND
+	recipient_user_id IS NULL AND
+	responded_at IS NULL AND
+	revoked_at IS NULL AND
+	deleted_at IS NULL
+RETURNING org_id
+`
+
 // Revoke marks an org invitation as revoked. The recipient is forbidden from responding to it after
 // it has been revoked.
 func (s *OrgInvitationStore) Revoke(ctx context.Context, id int64) error {
@@ -195,3 +195,159 @@ func TestOrgInvitations(t *testing.T) {
 		}
 	})
 }
+
+func TestOrgInvitations_AcceptPendingForEmail(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	sender, err := Users(db).Create(ctx, NewUser{
+		Email:           "a1@example.com",
+		Username:        "u1",
+		Password:        "p1",
+		EmailIsVerified: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	org1, err := Orgs(db).Create(ctx, "o1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	org2, err := Orgs(db).Create(ctx, "o2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oi1, err := OrgInvitations(db).CreateForEmail(ctx, org1.ID, sender.ID, "invitee@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OrgInvitations(db).CreateForEmail(ctx, org1.ID, sender.ID, "INVITEE@example.com"); err == nil {
+		t.Fatal("want error creating duplicate pending invitation for email")
+	}
+
+	t.Run("not accepted before the email is verified", func(t *testing.T) {
+		recipient, err := Users(db).Create(ctx, NewUser{
+			Email:                 "invitee@example.com",
+			Username:              "u2",
+			Password:              "p2",
+			EmailVerificationCode: "c2",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, org1.ID, recipient.ID); err == nil {
+			t.Fatal("user is a member before verifying their email")
+		}
+
+		if verified, err := UserEmails(db).Verify(ctx, recipient.ID, "invitee@example.com", "c2"); err != nil {
+			t.Fatal(err)
+		} else if !verified {
+			t.Fatal("email was not verified")
+		}
+
+		if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, org1.ID, recipient.ID); err != nil {
+			t.Fatalf("user is not a member after verifying their email: %s", err)
+		}
+		oi, err := OrgInvitations(db).GetByID(ctx, oi1.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oi.RecipientUserID != recipient.ID {
+			t.Errorf("got recipient user ID %d, want %d", oi.RecipientUserID, recipient.ID)
+		}
+		if oi.RecipientEmail != "invitee@example.com" {
+			t.Errorf("got recipient email %q, want %q", oi.RecipientEmail, "invitee@example.com")
+		}
+		if oi.ResponseType == nil || !*oi.ResponseType || oi.Pending() {
+			t.Errorf("invitation was not accepted: %+v", oi)
+		}
+	})
+
+	t.Run("accepted on creating a user with a verified email", func(t *testing.T) {
+		oi2, err := OrgInvitations(db).CreateForEmail(ctx, org2.ID, sender.ID, "other@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recipient, err := Users(db).Create(ctx, NewUser{
+			Email:           "other@example.com",
+			Username:        "u3",
+			Password:        "p3",
+			EmailIsVerified: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, org2.ID, recipient.ID); err != nil {
+			t.Fatalf("user is not a member after signing up: %s", err)
+		}
+		oi, err := OrgInvitations(db).GetByID(ctx, oi2.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oi.RecipientUserID != recipient.ID || oi.Pending() {
+			t.Errorf("invitation was not accepted: %+v", oi)
+		}
+	})
+
+	t.Run("revoked invitations are not accepted", func(t *testing.T) {
+		oi3, err := OrgInvitations(db).CreateForEmail(ctx, org2.ID, sender.ID, "revoked@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := OrgInvitations(db).Revoke(ctx, oi3.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		recipient, err := Users(db).Create(ctx, NewUser{
+			Email:           "revoked@example.com",
+			Username:        "u4",
+			Password:        "p4",
+			EmailIsVerified: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OrgMembers(db).GetByOrgIDAndUserID(ctx, org2.ID, recipient.ID); err == nil {
+			t.Fatal("user is a member despite the invitation being revoked")
+		}
+	})
+	t.Run("existing users respond themselves", func(t *testing.T) {
+		// sender already verified a1@example.com, so the invitation isn't accepted on their
+		// behalf, but they can respond to it.
+		oi4, err := OrgInvitations(db).CreateForEmail(ctx, org2.ID, sender.ID, "a1@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OrgInvitations(db).Respond(ctx, oi4.ID, 12345 /* invalid user */, true); !errcode.IsNotFound(err) {
+			t.Errorf("got err %v, want errcode.IsNotFound", err)
+		}
+		if pending, err := OrgInvitations(db).GetPending(ctx, org2.ID, sender.ID); err != nil {
+			t.Fatal(err)
+		} else if pending.ID != oi4.ID {
+			t.Errorf("got pending invitation %d, want %d", pending.ID, oi4.ID)
+		}
+
+		if orgID, err := OrgInvitations(db).Respond(ctx, oi4.ID, sender.ID, true); err != nil {
+			t.Fatal(err)
+		} else if orgID != org2.ID {
+			t.Errorf("got org ID %d, want %d", orgID, org2.ID)
+		}
+		oi, err := OrgInvitations(db).GetByID(ctx, oi4.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oi.RecipientUserID != sender.ID || oi.Pending() {
+			t.Errorf("invitation was not responded to by the user: %+v", oi)
+		}
+	})
+}
@@ -36,7 +36,13 @@ const (
 	SecurityEventNamPasswordRandomized     SecurityEventName = "PasswordRandomized"
 	SecurityEventNamePasswordChanged       SecurityEventName = "PasswordChanged"
 
-	SecurityEventNameEmailVerified SecurityEventName = "EmailVerified"
+	SecurityEventNameAccountRecoveryRequested SecurityEventName = "AccountRecoveryRequested"
+	SecurityEventNameRecoveryEmailChanged     SecurityEventName = "RecoveryEmailChanged"
+
+	SecurityEventNameEmailVerified       SecurityEventName = "EmailVerified"
+	SecurityEventNamePrimaryEmailChanged SecurityEventName = "PrimaryEmailChanged"
+
+	SecurityEventNameSessionsRevoked SecurityEventName = "SessionsRevoked"
 
 	SecurityEventNameRoleChangeDenied  SecurityEventName = "RoleChangeDenied"
 	SecurityEventNameRoleChangeGranted SecurityEventName = "RoleChangeGranted"
@@ -5,12 +5,15 @@ import (
 	"crypto/subtle"
 	"database/sql"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
 
+	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbconn"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
@@ -26,6 +29,15 @@ type UserEmail struct {
 	VerifiedAt             *time.Time
 	LastVerificationSentAt *time.Time
 	Primary                bool
+	Recovery               bool
+
+	VerificationRemindersSent       int
+	VerificationRemindersOptedOutAt *time.Time
+
+	// BouncedAt is when the email provider last reported that emails to this address bounce
+	// permanently or were marked as spam. It is cleared when the address is verified again.
+	BouncedAt    *time.Time
+	BounceReason *string
 }
 
 // NeedsVerificationCoolDown returns true if the verification cooled down time is behind current time.
@@ -48,6 +60,22 @@ func (err userEmailNotFoundError) NotFound() bool {
 	return true
 }
 
+// AfterUserEmailsChange (if set) is a hook called after the email addresses of a user were added,
+// removed, restored, verified, unverified or made primary by any means (e.g., both via
+// UserEmails.Verify and via UserEmails.CompleteChangeRequest), and after the user was deleted.
+var AfterUserEmailsChange func(userID int32)
+
+// afterUserEmailsChange runs the AfterUserEmailsChange hook for the given users unless *err is
+// non-nil. It must be deferred before the transaction is done, so that it runs after the commit.
+func afterUserEmailsChange(err *error, userIDs ...int32) {
+	if *err != nil || AfterUserEmailsChange == nil {
+		return
+	}
+	for _, id := range userIDs {
+		AfterUserEmailsChange(id)
+	}
+}
+
 // UserEmailsStore provides access to the `user_emails` table.
 type UserEmailsStore struct {
 	*basestore.Store
@@ -95,7 +123,7 @@ func (s *UserEmailsStore) GetInitialSiteAdminEmail(ctx context.Context) (email s
 		return "", err
 	}
 	s.ensureStore()
-	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email FROM user_emails JOIN users ON user_emails.user_id=users.id WHERE users.site_admin AND users.deleted_at IS NULL ORDER BY users.id ASC LIMIT 1").Scan(&email); err != nil {
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email FROM user_emails JOIN users ON user_emails.user_id=users.id WHERE users.site_admin AND users.deleted_at IS NULL AND user_emails.deleted_at IS NULL ORDER BY users.id ASC LIMIT 1").Scan(&email); err != nil {
 		return "", errors.New("initial site admin email not found")
 	}
 	return email, nil
@@ -108,7 +136,7 @@ func (s *UserEmailsStore) GetPrimaryEmail(ctx context.Context, id int32) (email
 		return Mocks.UserEmails.GetPrimaryEmail(ctx, id)
 	}
 	s.ensureStore()
-	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email, verified_at IS NOT NULL AS verified FROM user_emails WHERE user_id=$1 AND is_primary",
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email, verified_at IS NOT NULL AS verified FROM user_emails WHERE user_id=$1 AND is_primary AND deleted_at IS NULL",
 		id,
 	).Scan(&email, &verified); err != nil {
 		return "", false, userEmailNotFoundError{[]interface{}{fmt.Sprintf("id %d", id)}}
@@ -118,8 +146,9 @@ func (s *UserEmailsStore) GetPrimaryEmail(ctx context.Context, id int32) (email
 
 // SetPrimaryEmail sets the primary email for a user.
 // The address must be verified.
-// All other addresses for the user will be set as not primary.
-func (s *UserEmailsStore) SetPrimaryEmail(ctx context.Context, userID int32, email string) error {
+// All other addresses for the user will be set as not primary. If the address was the user's
+// recovery address, it no longer is.
+func (s *UserEmailsStore) SetPrimaryEmail(ctx context.Context, userID int32, email string) (err error) {
 	if Mocks.UserEmails.SetPrimaryEmail != nil {
 		return Mocks.UserEmails.SetPrimaryEmail(ctx, userID, email)
 	}
@@ -128,11 +157,12 @@ func (s *UserEmailsStore) SetPrimaryEmail(ctx context.Context, userID int32, ema
 	if err != nil {
 		return err
 	}
+	defer afterUserEmailsChange(&err, userID)
 	defer func() { err = tx.Done(err) }()
 
 	// Get the email. It needs to exist and be verified.
 	var verified bool
-	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT verified_at IS NOT NULL AS verified FROM user_emails WHERE user_id=$1 AND email=$2",
+	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT verified_at IS NOT NULL AS verified FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL",
 		userID, email,
 	).Scan(&verified); err != nil {
 		return err
@@ -150,13 +180,91 @@ func (s *UserEmailsStore) SetPrimaryEmail(ctx context.Context, userID int32, ema
 	}
 
 	// Set selected as primary
-	if _, err := tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET is_primary = true WHERE user_id=$1 AND email=$2", userID, email); err != nil {
+	if _, err := tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET is_primary = true, is_recovery = false WHERE user_id=$1 AND email=$2", userID, email); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// GetRecoveryEmail returns the verified address that account recovery emails are sent to for the
+// user. It returns an error satisfying errcode.IsNotFound if the user has no recovery address.
+func (s *UserEmailsStore) GetRecoveryEmail(ctx context.Context, userID int32) (string, error) {
+	if Mocks.UserEmails.GetRecoveryEmail != nil {
+		return Mocks.UserEmails.GetRecoveryEmail(ctx, userID)
+	}
+	s.ensureStore()
+	var email string
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email FROM user_emails WHERE user_id=$1 AND is_recovery AND verified_at IS NOT NULL AND deleted_at IS NULL",
+		userID,
+	).Scan(&email); err != nil {
+		if err == sql.ErrNoRows {
+			return "", userEmailNotFoundError{[]interface{}{fmt.Sprintf("recovery email of user %d", userID)}}
+		}
+		return "", err
+	}
+	return email, nil
+}
+
+// SetRecoveryEmail sets the address that account recovery emails are sent to for the user. The
+// address must be verified and must not be the user's primary address. Any other recovery address
+// of the user is unset.
+func (s *UserEmailsStore) SetRecoveryEmail(ctx context.Context, userID int32, email string) (err error) {
+	if Mocks.UserEmails.SetRecoveryEmail != nil {
+		return Mocks.UserEmails.SetRecoveryEmail(ctx, userID, email)
+	}
+	s.ensureStore()
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	var verified, primary bool
+	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT verified_at IS NOT NULL, is_primary FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL FOR UPDATE",
+		userID, email,
+	).Scan(&verified, &primary); err != nil {
+		if err == sql.ErrNoRows {
+			return userEmailNotFoundError{[]interface{}{fmt.Sprintf("userID %d email %q", userID, email)}}
+		}
+		return err
+	}
+	if !verified {
+		return errors.New("recovery email must be verified")
+	}
+	if primary {
+		return errors.New("recovery email must not be the primary email")
+	}
+
+	// As with primary addresses, unset the old recovery address first so that we don't violate
+	// our index.
+	if _, err := tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET is_recovery = false WHERE user_id=$1 AND is_recovery", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET is_recovery = true WHERE user_id=$1 AND email=$2", userID, email); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClearRecoveryEmail unsets the recovery address of the user, if any. It returns whether the user
+// had a recovery address.
+func (s *UserEmailsStore) ClearRecoveryEmail(ctx context.Context, userID int32) (bool, error) {
+	if Mocks.UserEmails.ClearRecoveryEmail != nil {
+		return Mocks.UserEmails.ClearRecoveryEmail(ctx, userID)
+	}
+	s.ensureStore()
+	res, err := s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET is_recovery = false WHERE user_id=$1 AND is_recovery", userID)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows > 0, nil
+}
+
 // Get gets information about the user's associated email address.
 func (s *UserEmailsStore) Get(ctx context.Context, userID int32, email string) (emailCanonicalCase string, verified bool, err error) {
 	if Mocks.UserEmails.Get != nil {
@@ -164,7 +272,7 @@ func (s *UserEmailsStore) Get(ctx context.Context, userID int32, email string) (
 	}
 	s.ensureStore()
 
-	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email, verified_at IS NOT NULL AS verified FROM user_emails WHERE user_id=$1 AND email=$2",
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email, verified_at IS NOT NULL AS verified FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL",
 		userID, email,
 	).Scan(&emailCanonicalCase, &verified); err != nil {
 		return "", false, userEmailNotFoundError{[]interface{}{fmt.Sprintf("userID %d email %q", userID, email)}}
@@ -172,26 +280,49 @@ func (s *UserEmailsStore) Get(ctx context.Context, userID int32, email string) (
 	return emailCanonicalCase, verified, nil
 }
 
-// Add adds new user email. When added, it is always unverified.
-func (s *UserEmailsStore) Add(ctx context.Context, userID int32, email string, verificationCode *string) error {
+// Add adds new user email. When added, it is always unverified. If the user removed the address
+// before, the removed address can no longer be restored.
+func (s *UserEmailsStore) Add(ctx context.Context, userID int32, email string, verificationCode *string) (err error) {
+	if Mocks.UserEmails.Add != nil {
+		return Mocks.UserEmails.Add(ctx, userID, email, verificationCode)
+	}
 	s.ensureStore()
-	_, err := s.Handle().DB().ExecContext(ctx, "INSERT INTO user_emails(user_id, email, verification_code) VALUES($1, $2, $3)", userID, email, verificationCode)
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer afterUserEmailsChange(&err, userID)
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.purgeRemoved(ctx, userID, email); err != nil {
+		return err
+	}
+	_, err = tx.Handle().DB().ExecContext(ctx, "INSERT INTO user_emails(user_id, email, verification_code) VALUES($1, $2, $3)", userID, email, verificationCode)
 	return err
 }
 
+// UserEmailRestoreWindow is how long a removed user email can be restored. Removed user emails are
+// deleted after that (see PurgeRemoved).
+const UserEmailRestoreWindow = 30 * 24 * time.Hour
+
 // Remove removes a user email. It returns an error if there is no such email associated with the user or the email
-// is the user's primary address
-func (s *UserEmailsStore) Remove(ctx context.Context, userID int32, email string) error {
+// is the user's primary address.
+//
+// The address is soft-deleted and can be restored with Restore for UserEmailRestoreWindow. It is
+// no longer verified by the user in the meantime, so another user may verify it. A removed address
+// is never a recovery address, and its verification code is discarded.
+func (s *UserEmailsStore) Remove(ctx context.Context, userID int32, email string) (err error) {
 	s.ensureStore()
 	tx, err := s.Transact(ctx)
 	if err != nil {
 		return err
 	}
+	defer afterUserEmailsChange(&err, userID)
 	defer func() { err = tx.Done(err) }()
 
-	// Get the email. It needs to exist and be verified.
+	// Get the email. It needs to exist and not be the primary address.
 	var isPrimary bool
-	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT is_primary FROM user_emails WHERE user_id=$1 AND email=$2",
+	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT is_primary FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL FOR UPDATE",
 		userID, email,
 	).Scan(&isPrimary); err != nil {
 		return errors.Errorf("fetching email address: %w", err)
@@ -200,23 +331,84 @@ func (s *UserEmailsStore) Remove(ctx context.Context, userID int32, email string
 		return errors.New("can't delete primary email address")
 	}
 
-	_, err = tx.Handle().DB().ExecContext(ctx, "DELETE FROM user_emails WHERE user_id=$1 AND email=$2", userID, email)
+	_, err = tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET deleted_at=now(), is_recovery=false, verification_code=null WHERE user_id=$1 AND email=$2", userID, email)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Restore restores a user email that was removed less than UserEmailRestoreWindow ago, with the
+// verification status it had when it was removed. It returns an error satisfying
+// errcode.IsNotFound if there is no such removed email, and an error if the address was verified
+// by another user in the meantime.
+func (s *UserEmailsStore) Restore(ctx context.Context, userID int32, email string) (err error) {
+	if Mocks.UserEmails.Restore != nil {
+		return Mocks.UserEmails.Restore(ctx, userID, email)
+	}
+	s.ensureStore()
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer afterUserEmailsChange(&err, userID)
+	defer func() { err = tx.Done(err) }()
+
+	var verified bool
+	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT verified_at IS NOT NULL FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at > $3 FOR UPDATE",
+		userID, email, time.Now().Add(-UserEmailRestoreWindow),
+	).Scan(&verified); err != nil {
+		if err == sql.ErrNoRows {
+			return userEmailNotFoundError{[]interface{}{fmt.Sprintf("removed userID %d email %q", userID, email)}}
+		}
+		return err
+	}
+	if verified {
+		var takenByOtherUser bool
+		if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM user_emails WHERE email=$1 AND user_id<>$2 AND verified_at IS NOT NULL AND deleted_at IS NULL)",
+			email, userID,
+		).Scan(&takenByOtherUser); err != nil {
+			return err
+		}
+		if takenByOtherUser {
+			return errors.New("the email address was verified by another user since it was removed")
+		}
+	}
+
+	_, err = tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET deleted_at=null WHERE user_id=$1 AND email=$2", userID, email)
+	return err
+}
+
+// PurgeRemoved deletes the user emails that were removed UserEmailRestoreWindow ago or earlier.
+// It returns the number of deleted user emails.
+func (s *UserEmailsStore) PurgeRemoved(ctx context.Context) (int64, error) {
+	s.ensureStore()
+	res, err := s.Handle().DB().ExecContext(ctx, "DELETE FROM user_emails WHERE deleted_at <= $1", time.Now().Add(-UserEmailRestoreWindow))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// purgeRemoved deletes the user's email address if it was removed, so that the address can be
+// added again.
+func (s *UserEmailsStore) purgeRemoved(ctx context.Context, userID int32, email string) error {
+	_, err := s.Handle().DB().ExecContext(ctx, "DELETE FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NOT NULL", userID, email)
+	return err
+}
+
 // Verify verifies the user's email address given the email verification code. If the code is not
 // correct (not the one originally used when creating the user or adding the user email), then it
 // returns false.
-func (s *UserEmailsStore) Verify(ctx context.Context, userID int32, email, code string) (bool, error) {
+//
+// Any pending org invitations sent to the email address are accepted in the same transaction.
+func (s *UserEmailsStore) Verify(ctx context.Context, userID int32, email, code string) (_ bool, err error) {
 	if Mocks.UserEmails.Verify != nil {
 		return Mocks.UserEmails.Verify(ctx, userID, email, code)
 	}
 	s.ensureStore()
 	var dbCode sql.NullString
-	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT verification_code FROM user_emails WHERE user_id=$1 AND email=$2", userID, email).Scan(&dbCode); err != nil {
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT verification_code FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL", userID, email).Scan(&dbCode); err != nil {
 		return false, err
 	}
 	if !dbCode.Valid {
@@ -226,29 +418,70 @@ func (s *UserEmailsStore) Verify(ctx context.Context, userID int32, email, code
 	if len(dbCode.String) != len(code) || subtle.ConstantTimeCompare([]byte(dbCode.String), []byte(code)) != 1 {
 		return false, nil
 	}
-	if _, err := s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_code=null, verified_at=now() WHERE user_id=$1 AND email=$2", userID, email); err != nil {
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer afterUserEmailsChange(&err, userID)
+	defer func() { err = tx.Done(err) }()
+
+	// Only consume the code if it is still set, so that a code can't be used twice by concurrent
+	// requests.
+	res, err := tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_code=null, verified_at=now(), bounced_at=null, bounce_reason=null WHERE user_id=$1 AND email=$2 AND verification_code=$3 AND deleted_at IS NULL", userID, email, code)
+	if err != nil {
+		return false, err
+	}
+	if nrows, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if nrows == 0 {
+		return false, nil
+	}
+	if err := joinOrgsForVerifiedEmail(ctx, tx, userID, email); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
+// MarkBounced records that emails to the address bounce permanently or were marked as spam, for
+// every user that has the address. It returns the number of user emails that were marked.
+func (s *UserEmailsStore) MarkBounced(ctx context.Context, email, reason string) (int, error) {
+	if Mocks.UserEmails.MarkBounced != nil {
+		return Mocks.UserEmails.MarkBounced(ctx, email, reason)
+	}
+	s.ensureStore()
+	res, err := s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET bounced_at=now(), bounce_reason=$2 WHERE email=$1 AND deleted_at IS NULL", email, reason)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
 // SetVerified bypasses the normal email verification code process and manually sets the verified
-// status for an email.
-func (s *UserEmailsStore) SetVerified(ctx context.Context, userID int32, email string, verified bool) error {
+// status for an email. When an email is marked as verified, any pending org invitations sent to it
+// are accepted in the same transaction.
+func (s *UserEmailsStore) SetVerified(ctx context.Context, userID int32, email string, verified bool) (err error) {
 	if Mocks.UserEmails.SetVerified != nil {
 		return Mocks.UserEmails.SetVerified(ctx, userID, email, verified)
 	}
 	s.ensureStore()
 
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer afterUserEmailsChange(&err, userID)
+	defer func() { err = tx.Done(err) }()
+
 	var res sql.Result
-	var err error
 	if verified {
 		// Mark as verified.
-		res, err = s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_code=null, verified_at=now() WHERE user_id=$1 AND email=$2", userID, email)
+		res, err = tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_code=null, verified_at=now() WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL", userID, email)
 	} else {
-		// Mark as unverified.
-		res, err = s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_code=null, verified_at=null WHERE user_id=$1 AND email=$2", userID, email)
+		// Mark as unverified. Unverified addresses can't be recovery addresses.
+		res, err = tx.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_code=null, verified_at=null, is_recovery=false WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL", userID, email)
 	}
 	if err != nil {
 		return err
@@ -260,16 +493,111 @@ func (s *UserEmailsStore) SetVerified(ctx context.Context, userID int32, email s
 	if nrows == 0 {
 		return errors.New("user email not found")
 	}
+	if verified {
+		if err := joinOrgsForVerifiedEmail(ctx, tx, userID, email); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// SetVerifiedBulk is like SetVerified, but sets the verification status of all email addresses
+// of the given users at once. It returns the number of email addresses whose status changed.
+//
+// Addresses that another user already verified are left unverified, since an address can only
+// be verified by one user. For the same reason, if several of the given users share an
+// unverified address, only the user with the lowest ID gets it verified.
+func (s *UserEmailsStore) SetVerifiedBulk(ctx context.Context, userIDs []int32, verified bool) (_ int, err error) {
+	if Mocks.UserEmails.SetVerifiedBulk != nil {
+		return Mocks.UserEmails.SetVerifiedBulk(ctx, userIDs, verified)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+	s.ensureStore()
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer afterUserEmailsChange(&err, userIDs...)
+	defer func() { err = tx.Done(err) }()
+
+	var q *sqlf.Query
+	if verified {
+		q = sqlf.Sprintf(`
+UPDATE user_emails SET verification_code=null, verified_at=now()
+FROM (
+	SELECT DISTINCT ON (candidate.email) candidate.user_id, candidate.email
+	FROM user_emails candidate
+	WHERE
+		candidate.user_id = ANY(%s)
+		AND candidate.verified_at IS NULL
+		AND candidate.deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM user_emails other
+			WHERE other.email = candidate.email AND other.user_id <> candidate.user_id AND other.verified_at IS NOT NULL AND other.deleted_at IS NULL
+		)
+	ORDER BY candidate.email, candidate.user_id
+) chosen
+WHERE user_emails.user_id = chosen.user_id AND user_emails.email = chosen.email
+RETURNING user_emails.user_id, user_emails.email
+`, pq.Array(userIDs))
+	} else {
+		q = sqlf.Sprintf(`
+UPDATE user_emails SET verification_code=null, verified_at=null, is_recovery=false
+WHERE user_id = ANY(%s) AND verified_at IS NOT NULL AND deleted_at IS NULL
+RETURNING user_id, email
+`, pq.Array(userIDs))
+	}
+
+	rows, err := tx.Query(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	type userEmail struct {
+		userID int32
+		email  string
+	}
+	var changed []userEmail
+	for rows.Next() {
+		var e userEmail
+		if err := rows.Scan(&e.userID, &e.email); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		changed = append(changed, e)
+	}
+	if err := basestore.CloseRows(rows, rows.Err()); err != nil {
+		return 0, err
+	}
+
+	if verified {
+		for _, e := range changed {
+			if err := joinOrgsForVerifiedEmail(ctx, tx, e.userID, e.email); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(changed), nil
+}
+
 // SetLastVerification sets the "last_verification_sent_at" column to now() and updates the verification code for given email of the user.
+// It also records the verification attempt, attributed to the actor in ctx, in user_email_verification_attempts.
 func (s *UserEmailsStore) SetLastVerification(ctx context.Context, userID int32, email, code string) error {
 	if Mocks.UserEmails.SetLastVerification != nil {
 		return Mocks.UserEmails.SetLastVerification(ctx, userID, email, code)
 	}
 	s.ensureStore()
-	res, err := s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET last_verification_sent_at=now(), verification_code = $3 WHERE user_id=$1 AND email=$2", userID, email, code)
+	res, err := s.Handle().DB().ExecContext(ctx, `
+WITH updated AS (
+	UPDATE user_emails SET last_verification_sent_at=now(), verification_code = $3 WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL
+	RETURNING user_id, email
+)
+INSERT INTO user_email_verification_attempts(user_id, email, actor_user_id)
+SELECT user_id, email, NULLIF($4::integer, 0) FROM updated`,
+		userID, email, code, actor.FromContext(ctx).UID,
+	)
 	if err != nil {
 		return err
 	}
@@ -291,7 +619,7 @@ func (s *UserEmailsStore) GetLatestVerificationSentEmail(ctx context.Context, em
 	}
 
 	q := sqlf.Sprintf(`
-WHERE email=%s AND last_verification_sent_at IS NOT NULL
+WHERE email=%s AND last_verification_sent_at IS NOT NULL AND deleted_at IS NULL
 ORDER BY last_verification_sent_at DESC
 LIMIT 1
 `, email)
@@ -319,10 +647,445 @@ func (s *UserEmailsStore) GetVerifiedEmails(ctx context.Context, emails ...strin
 	for i := range emails {
 		items[i] = sqlf.Sprintf("%s", emails[i])
 	}
-	q := sqlf.Sprintf("WHERE email IN (%s) AND verified_at IS NOT NULL", sqlf.Join(items, ","))
+	q := sqlf.Sprintf("WHERE email IN (%s) AND verified_at IS NOT NULL AND deleted_at IS NULL", sqlf.Join(items, ","))
 	return s.getBySQL(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
 }
 
+// joinOrgsForVerifiedEmail adds the user to the organizations that verifying the email address
+// entitles them to: those that invited the address, and the one that verified its domain. It is
+// called in the same transaction that verifies the address.
+func joinOrgsForVerifiedEmail(ctx context.Context, tx basestore.ShareableStore, userID int32, email string) error {
+	if _, err := OrgInvitationsWith(tx).AcceptPendingForEmail(ctx, userID, email); err != nil {
+		return err
+	}
+	_, err := OrgDomainsWith(tx).JoinOrgsForEmail(ctx, userID, email)
+	return err
+}
+
+// VerificationReminderOptions specifies which unverified email addresses are due for a reminder
+// to verify them.
+type VerificationReminderOptions struct {
+	// MaxReminders is the number of reminders that are sent at most per email address.
+	MaxReminders int
+	// SentBefore excludes email addresses that were sent a verification email or a reminder at or
+	// after this time.
+	SentBefore time.Time
+}
+
+// verificationReminderDueConds returns the conditions an email address must meet to be due for a
+// verification reminder.
+func verificationReminderDueConds(opts VerificationReminderOptions) []*sqlf.Query {
+	return []*sqlf.Query{
+		sqlf.Sprintf("user_emails.verified_at IS NULL"),
+		sqlf.Sprintf("user_emails.deleted_at IS NULL"),
+		sqlf.Sprintf("user_emails.verification_code IS NOT NULL"),
+		sqlf.Sprintf("user_emails.verification_reminders_opted_out_at IS NULL"),
+		sqlf.Sprintf("user_emails.bounced_at IS NULL"),
+		sqlf.Sprintf("user_emails.verification_reminders_sent < %s", opts.MaxReminders),
+		sqlf.Sprintf("COALESCE(user_emails.last_verification_sent_at, user_emails.created_at) < %s", opts.SentBefore),
+	}
+}
+
+// ListDueForVerificationReminder returns up to limit unverified email addresses of non-deleted
+// users that are due for a verification reminder, least recently reminded first.
+func (s *UserEmailsStore) ListDueForVerificationReminder(ctx context.Context, opts VerificationReminderOptions, limit int) ([]*UserEmail, error) {
+	conds := append(verificationReminderDueConds(opts), sqlf.Sprintf("users.deleted_at IS NULL"))
+	q := sqlf.Sprintf(`
+JOIN users ON users.id = user_emails.user_id
+WHERE %s
+ORDER BY COALESCE(user_emails.last_verification_sent_at, user_emails.created_at) ASC
+LIMIT %s
+`, sqlf.Join(conds, "AND"), limit)
+	return s.getBySQL(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+}
+
+// ClaimVerificationReminder records that a verification reminder is sent to the email address, if
+// it is still due for one. Claiming before sending ensures that concurrent senders never remind
+// twice. It returns the token that opts out of further reminders, which is the given token unless
+// the email address already has one, and false if the reminder must not be sent.
+func (s *UserEmailsStore) ClaimVerificationReminder(ctx context.Context, userID int32, email, token string, opts VerificationReminderOptions) (_ string, claimed bool, err error) {
+	s.ensureStore()
+	conds := append(verificationReminderDueConds(opts),
+		sqlf.Sprintf("user_emails.user_id = %s", userID),
+		sqlf.Sprintf("user_emails.email = %s", email),
+	)
+	q := sqlf.Sprintf(`
+UPDATE user_emails
+SET
+	verification_reminders_sent = verification_reminders_sent + 1,
+	last_verification_sent_at = now(),
+	verification_reminders_token = COALESCE(verification_reminders_token, %s)
+WHERE %s
+RETURNING verification_reminders_token
+`, token, sqlf.Join(conds, "AND"))
+	var claimedToken string
+	if err := s.Handle().DB().QueryRowContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...).Scan(&claimedToken); err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return claimedToken, true, nil
+}
+
+// OptOutOfVerificationReminders stops verification reminders for the user's email address, given
+// the token included in the reminders. It returns false if the token is not correct.
+func (s *UserEmailsStore) OptOutOfVerificationReminders(ctx context.Context, userID int32, email, token string) (bool, error) {
+	if Mocks.UserEmails.OptOutOfVerificationReminders != nil {
+		return Mocks.UserEmails.OptOutOfVerificationReminders(ctx, userID, email, token)
+	}
+	s.ensureStore()
+	var dbToken sql.NullString
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT verification_reminders_token FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL", userID, email).Scan(&dbToken); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	// 🚨 SECURITY: Use constant-time comparisons to avoid leaking the token via timing attack.
+	if !dbToken.Valid || len(dbToken.String) != len(token) || subtle.ConstantTimeCompare([]byte(dbToken.String), []byte(token)) != 1 {
+		return false, nil
+	}
+
+	_, err := s.Handle().DB().ExecContext(ctx, "UPDATE user_emails SET verification_reminders_opted_out_at=now() WHERE user_id=$1 AND email=$2 AND verification_reminders_opted_out_at IS NULL", userID, email)
+	return err == nil, err
+}
+
+// UserEmailChangeRequest represents a row in the `user_email_change_requests` table: a pending
+// change of a user's primary email address to an address that isn't confirmed yet.
+type UserEmailChangeRequest struct {
+	UserID    int32
+	Email     string
+	Secret    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// userEmailChangeRequestNotFoundError is the error that is returned when a user has no pending
+// email change.
+type userEmailChangeRequestNotFoundError struct {
+	userID int32
+}
+
+func (err userEmailChangeRequestNotFoundError) Error() string {
+	return fmt.Sprintf("email change request not found: user %d", err.userID)
+}
+
+func (err userEmailChangeRequestNotFoundError) NotFound() bool {
+	return true
+}
+
+// CreateChangeRequest records a pending change of the user's primary email address. A user has at
+// most one pending change, so an earlier request is replaced.
+func (s *UserEmailsStore) CreateChangeRequest(ctx context.Context, req *UserEmailChangeRequest) error {
+	if Mocks.UserEmails.CreateChangeRequest != nil {
+		return Mocks.UserEmails.CreateChangeRequest(ctx, req)
+	}
+	s.ensureStore()
+	return s.Handle().DB().QueryRowContext(ctx, `
+INSERT INTO user_email_change_requests(user_id, email, secret, expires_at)
+VALUES($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE SET
+	email = excluded.email,
+	secret = excluded.secret,
+	expires_at = excluded.expires_at,
+	created_at = now()
+RETURNING created_at`,
+		req.UserID, req.Email, req.Secret, req.ExpiresAt,
+	).Scan(&req.CreatedAt)
+}
+
+// GetChangeRequest returns the pending email change of the user. The request may have expired.
+func (s *UserEmailsStore) GetChangeRequest(ctx context.Context, userID int32) (*UserEmailChangeRequest, error) {
+	if Mocks.UserEmails.GetChangeRequest != nil {
+		return Mocks.UserEmails.GetChangeRequest(ctx, userID)
+	}
+	s.ensureStore()
+	req := UserEmailChangeRequest{UserID: userID}
+	if err := s.Handle().DB().QueryRowContext(ctx, "SELECT email, secret, expires_at, created_at FROM user_email_change_requests WHERE user_id=$1", userID).Scan(
+		&req.Email, &req.Secret, &req.ExpiresAt, &req.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, userEmailChangeRequestNotFoundError{userID}
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// CompleteChangeRequest finalizes the pending email change of the user to the given address: the
+// address is added to the user if necessary, marked as verified and set as the primary address,
+// and the request is deleted. The caller must have checked that the user confirmed the change.
+//
+// Any pending org invitations sent to the email address are accepted in the same transaction.
+func (s *UserEmailsStore) CompleteChangeRequest(ctx context.Context, userID int32, email string) (err error) {
+	if Mocks.UserEmails.CompleteChangeRequest != nil {
+		return Mocks.UserEmails.CompleteChangeRequest(ctx, userID, email)
+	}
+	s.ensureStore()
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer afterUserEmailsChange(&err, userID)
+	defer func() { err = tx.Done(err) }()
+
+	// Deleting the request first ensures that it's only ever completed once.
+	res, err := tx.Handle().DB().ExecContext(ctx, "DELETE FROM user_email_change_requests WHERE user_id=$1 AND email=$2", userID, email)
+	if err != nil {
+		return err
+	}
+	if nrows, err := res.RowsAffected(); err != nil {
+		return err
+	} else if nrows == 0 {
+		return userEmailChangeRequestNotFoundError{userID}
+	}
+
+	if err := tx.purgeRemoved(ctx, userID, email); err != nil {
+		return err
+	}
+	if _, err := tx.Handle().DB().ExecContext(ctx, "INSERT INTO user_emails(user_id, email) VALUES($1, $2) ON CONFLICT ON CONSTRAINT user_emails_no_duplicates_per_user DO NOTHING", userID, email); err != nil {
+		return err
+	}
+	if err := tx.SetVerified(ctx, userID, email, true); err != nil {
+		return err
+	}
+	return tx.SetPrimaryEmail(ctx, userID, email)
+}
+
+// UserEmailAttribution represents a row in the `user_email_attributions` table: an email address
+// that a user no longer has, whose commit authorship and permissions are attributed to another
+// email address of the user.
+type UserEmailAttribution struct {
+	Email     string
+	UserID    int32
+	ToEmail   string
+	CreatedAt time.Time
+}
+
+// TransferAttribution attributes the email address from to the user's verified email address to,
+// so that commits authored with from resolve to the user and repository permissions bound to from
+// are granted to the user, even after from is removed from the user. Attributions that were
+// transferred to from before are re-pointed to to.
+//
+// The user must have verified from, possibly before removing it, or had it attributed to them
+// already, and no other user may have it verified.
+func (s *UserEmailsStore) TransferAttribution(ctx context.Context, userID int32, from, to string) (err error) {
+	if Mocks.UserEmails.TransferAttribution != nil {
+		return Mocks.UserEmails.TransferAttribution(ctx, userID, from, to)
+	}
+	if strings.EqualFold(from, to) {
+		return errors.New("can't transfer the attribution of an email address to itself")
+	}
+	s.ensureStore()
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	var toVerified bool
+	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT verified_at IS NOT NULL FROM user_emails WHERE user_id=$1 AND email=$2 AND deleted_at IS NULL FOR UPDATE",
+		userID, to,
+	).Scan(&toVerified); err != nil {
+		if err == sql.ErrNoRows {
+			return userEmailNotFoundError{[]interface{}{fmt.Sprintf("userID %d email %q", userID, to)}}
+		}
+		return err
+	}
+	if !toVerified {
+		return errors.New("attribution can only be transferred to a verified email address")
+	}
+
+	// 🚨 SECURITY: Only the attribution of email addresses the user proved to own can be
+	// transferred. Otherwise, users could claim the repository permissions of any email address.
+	var owned bool
+	if err := tx.Handle().DB().QueryRowContext(ctx, `
+SELECT
+	EXISTS (SELECT 1 FROM user_emails WHERE user_id=$1 AND email=$2 AND verified_at IS NOT NULL)
+	OR EXISTS (SELECT 1 FROM user_email_attributions WHERE user_id=$1 AND email=$2)`,
+		userID, from,
+	).Scan(&owned); err != nil {
+		return err
+	}
+	if !owned {
+		return errors.New("attribution can only be transferred from an email address the user verified")
+	}
+	var takenByOtherUser bool
+	if err := tx.Handle().DB().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM user_emails WHERE email=$1 AND user_id<>$2 AND verified_at IS NOT NULL AND deleted_at IS NULL)",
+		from, userID,
+	).Scan(&takenByOtherUser); err != nil {
+		return err
+	}
+	if takenByOtherUser {
+		return errors.New("the email address is verified by another user")
+	}
+
+	if _, err := tx.Handle().DB().ExecContext(ctx, `
+INSERT INTO user_email_attributions(email, user_id, to_email)
+VALUES($1, $2, $3)
+ON CONFLICT (email) DO UPDATE SET
+	user_id = excluded.user_id,
+	to_email = excluded.to_email,
+	created_at = now()`,
+		from, userID, to,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Handle().DB().ExecContext(ctx, "UPDATE user_email_attributions SET to_email=$3 WHERE user_id=$1 AND to_email=$2", userID, from, to); err != nil {
+		return err
+	}
+	// The user has the target address, so it needs no attribution of its own.
+	if _, err := tx.Handle().DB().ExecContext(ctx, "DELETE FROM user_email_attributions WHERE user_id=$1 AND email=$2", userID, to); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAttributions returns the attributions of the given email addresses that apply: the user
+// still has the target address verified, and no other user verified the email address since.
+func (s *UserEmailsStore) GetAttributions(ctx context.Context, emails ...string) ([]*UserEmailAttribution, error) {
+	if Mocks.UserEmails.GetAttributions != nil {
+		return Mocks.UserEmails.GetAttributions(ctx, emails...)
+	}
+	if len(emails) == 0 {
+		return []*UserEmailAttribution{}, nil
+	}
+
+	items := make([]*sqlf.Query, len(emails))
+	for i := range emails {
+		items[i] = sqlf.Sprintf("%s", emails[i])
+	}
+	return s.listAttributions(ctx, sqlf.Sprintf("a.email IN (%s)", sqlf.Join(items, ",")))
+}
+
+// ListAttributionsByUser returns the attributions to the user that apply, as GetAttributions.
+func (s *UserEmailsStore) ListAttributionsByUser(ctx context.Context, userID int32) ([]*UserEmailAttribution, error) {
+	if Mocks.UserEmails.ListAttributionsByUser != nil {
+		return Mocks.UserEmails.ListAttributionsByUser(ctx, userID)
+	}
+	return s.listAttributions(ctx, sqlf.Sprintf("a.user_id = %s", userID))
+}
+
+func (s *UserEmailsStore) listAttributions(ctx context.Context, cond *sqlf.Query) ([]*UserEmailAttribution, error) {
+	s.ensureStore()
+	q := sqlf.Sprintf(`
+SELECT a.email, a.user_id, a.to_email, a.created_at
+FROM user_email_attributions a
+JOIN users ON users.id = a.user_id AND users.deleted_at IS NULL
+JOIN user_emails target ON
+	target.user_id = a.user_id
+	AND target.email = a.to_email
+	AND target.verified_at IS NOT NULL
+	AND target.deleted_at IS NULL
+WHERE
+	%s
+	AND NOT EXISTS (
+		SELECT 1 FROM user_emails other
+		WHERE other.email = a.email AND other.user_id <> a.user_id AND other.verified_at IS NOT NULL AND other.deleted_at IS NULL
+	)
+ORDER BY a.email ASC`, cond)
+	rows, err := s.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attributions []*UserEmailAttribution
+	for rows.Next() {
+		var a UserEmailAttribution
+		if err := rows.Scan(&a.Email, &a.UserID, &a.ToEmail, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attributions = append(attributions, &a)
+	}
+	return attributions, rows.Err()
+}
+
+// UserEmailVerificationAttempt represents a row in the `user_email_verification_attempts` table:
+// a verification email sent to a user email address.
+type UserEmailVerificationAttempt struct {
+	ID     int64
+	UserID int32
+	Email  string
+	// ActorUserID is the user who requested the verification email, or 0 if it was requested
+	// anonymously or the user was deleted.
+	ActorUserID int32
+	CreatedAt   time.Time
+}
+
+// VerificationAttemptsListOptions specifies the options for listing and counting verification
+// attempts.
+type VerificationAttemptsListOptions struct {
+	// UserID, if set, only includes attempts for email addresses of the user.
+	UserID int32
+	// Email, if set, only includes attempts for the email address.
+	Email string
+	// Since, if set, only includes attempts made at or after this time.
+	Since time.Time
+
+	*LimitOffset
+}
+
+func (o VerificationAttemptsListOptions) sqlConditions() []*sqlf.Query {
+	conds := []*sqlf.Query{sqlf.Sprintf("TRUE")}
+	if o.UserID != 0 {
+		conds = append(conds, sqlf.Sprintf("user_id = %s", o.UserID))
+	}
+	if o.Email != "" {
+		conds = append(conds, sqlf.Sprintf("email = %s", o.Email))
+	}
+	if !o.Since.IsZero() {
+		conds = append(conds, sqlf.Sprintf("created_at >= %s", o.Since))
+	}
+	return conds
+}
+
+// ListVerificationAttempts returns the verification attempts matching the options, most recent
+// first.
+func (s *UserEmailsStore) ListVerificationAttempts(ctx context.Context, opts VerificationAttemptsListOptions) ([]*UserEmailVerificationAttempt, error) {
+	if Mocks.UserEmails.ListVerificationAttempts != nil {
+		return Mocks.UserEmails.ListVerificationAttempts(ctx, opts)
+	}
+	s.ensureStore()
+
+	q := sqlf.Sprintf(`
+SELECT id, user_id, email, COALESCE(actor_user_id, 0), created_at
+FROM user_email_verification_attempts
+WHERE %s
+ORDER BY created_at DESC, id DESC
+%s`, sqlf.Join(opts.sqlConditions(), "AND"), opts.LimitOffset.SQL())
+	rows, err := s.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*UserEmailVerificationAttempt
+	for rows.Next() {
+		var a UserEmailVerificationAttempt
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Email, &a.ActorUserID, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &a)
+	}
+	return attempts, rows.Err()
+}
+
+// CountVerificationAttempts returns the number of verification attempts matching the options,
+// ignoring LimitOffset.
+func (s *UserEmailsStore) CountVerificationAttempts(ctx context.Context, opts VerificationAttemptsListOptions) (int, error) {
+	if Mocks.UserEmails.CountVerificationAttempts != nil {
+		return Mocks.UserEmails.CountVerificationAttempts(ctx, opts)
+	}
+	s.ensureStore()
+
+	q := sqlf.Sprintf("SELECT COUNT(*) FROM user_email_verification_attempts WHERE %s", sqlf.Join(opts.sqlConditions(), "AND"))
+	var count int
+	err := s.QueryRow(ctx, q).Scan(&count)
+	return count, err
+}
+
 // UserEmailsListOptions specifies the options for listing user emails.
 type UserEmailsListOptions struct {
 	// UserID specifies the id of the user for listing emails.
@@ -339,6 +1102,7 @@ func (s *UserEmailsStore) ListByUser(ctx context.Context, opt UserEmailsListOpti
 
 	conds := []*sqlf.Query{
 		sqlf.Sprintf("user_id=%s", opt.UserID),
+		sqlf.Sprintf("deleted_at IS NULL"),
 	}
 	if opt.OnlyVerified {
 		conds = append(conds, sqlf.Sprintf("verified_at IS NOT NULL"))
@@ -353,7 +1117,9 @@ func (s *UserEmailsStore) getBySQL(ctx context.Context, query string, args ...in
 	s.ensureStore()
 	rows, err := s.Handle().DB().QueryContext(ctx,
 		`SELECT user_emails.user_id, user_emails.email, user_emails.created_at, user_emails.verification_code,
-				user_emails.verified_at, user_emails.last_verification_sent_at, user_emails.is_primary FROM user_emails `+query, args...)
+				user_emails.verified_at, user_emails.last_verification_sent_at, user_emails.is_primary, user_emails.is_recovery,
+				user_emails.verification_reminders_sent, user_emails.verification_reminders_opted_out_at,
+				user_emails.bounced_at, user_emails.bounce_reason FROM user_emails `+query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -362,7 +1128,7 @@ func (s *UserEmailsStore) getBySQL(ctx context.Context, query string, args ...in
 	defer rows.Close()
 	for rows.Next() {
 		var v UserEmail
-		err := rows.Scan(&v.UserID, &v.Email, &v.CreatedAt, &v.VerificationCode, &v.VerifiedAt, &v.LastVerificationSentAt, &v.Primary)
+		err := rows.Scan(&v.UserID, &v.Email, &v.CreatedAt, &v.VerificationCode, &v.VerifiedAt, &v.LastVerificationSentAt, &v.Primary, &v.Recovery, &v.VerificationRemindersSent, &v.VerificationRemindersOptedOutAt, &v.BouncedAt, &v.BounceReason)
 		if err != nil {
 			return nil, err
 		}
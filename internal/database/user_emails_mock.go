@@ -8,10 +8,26 @@ type MockUserEmails struct {
 	GetPrimaryEmail                func(ctx context.Context, id int32) (email string, verified bool, err error)
 	Get                            func(userID int32, email string) (emailCanonicalCase string, verified bool, err error)
 	SetPrimaryEmail                func(ctx context.Context, userID int32, email string) error
+	GetRecoveryEmail               func(ctx context.Context, userID int32) (string, error)
+	SetRecoveryEmail               func(ctx context.Context, userID int32, email string) error
+	ClearRecoveryEmail             func(ctx context.Context, userID int32) (bool, error)
+	Add                            func(ctx context.Context, userID int32, email string, verificationCode *string) error
+	Restore                        func(ctx context.Context, userID int32, email string) error
 	SetVerified                    func(ctx context.Context, userID int32, email string, verified bool) error
+	SetVerifiedBulk                func(ctx context.Context, userIDs []int32, verified bool) (int, error)
 	SetLastVerification            func(ctx context.Context, userID int32, email, code string) error
 	GetLatestVerificationSentEmail func(ctx context.Context, email string) (*UserEmail, error)
 	GetVerifiedEmails              func(ctx context.Context, emails ...string) ([]*UserEmail, error)
 	ListByUser                     func(ctx context.Context, opt UserEmailsListOptions) ([]*UserEmail, error)
 	Verify                         func(ctx context.Context, userID int32, email, code string) (bool, error)
+	MarkBounced                    func(ctx context.Context, email, reason string) (int, error)
+	OptOutOfVerificationReminders  func(ctx context.Context, userID int32, email, token string) (bool, error)
+	CreateChangeRequest            func(ctx context.Context, req *UserEmailChangeRequest) error
+	GetChangeRequest               func(ctx context.Context, userID int32) (*UserEmailChangeRequest, error)
+	CompleteChangeRequest          func(ctx context.Context, userID int32, email string) error
+	TransferAttribution            func(ctx context.Context, userID int32, from, to string) error
+	GetAttributions                func(ctx context.Context, emails ...string) ([]*UserEmailAttribution, error)
+	ListAttributionsByUser         func(ctx context.Context, userID int32) ([]*UserEmailAttribution, error)
+	ListVerificationAttempts       func(ctx context.Context, opts VerificationAttemptsListOptions) ([]*UserEmailVerificationAttempt, error)
+	CountVerificationAttempts      func(ctx context.Context, opts VerificationAttemptsListOptions) (int, error)
 }
@@ -2,12 +2,14 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -197,6 +199,77 @@ func TestUserEmails_SetPrimary(t *testing.T) {
 	}
 }
 
+func TestUserEmails_AfterUserEmailsChange(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	// Not parallel, since the hook is global.
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	var changed []int32
+	AfterUserEmailsChange = func(userID int32) { changed = append(changed, userID) }
+	t.Cleanup(func() { AfterUserEmailsChange = nil })
+
+	user, err := Users(db).Create(ctx, NewUser{
+		Email:                 "a@example.com",
+		Username:              "u",
+		Password:              "pw",
+		EmailVerificationCode: "c",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		change  func() error
+		wantIDs []int32
+	}{
+		{
+			name:    "add",
+			change:  func() error { return UserEmails(db).Add(ctx, user.ID, "b@example.com", nil) },
+			wantIDs: []int32{user.ID},
+		},
+		{
+			name:    "verify",
+			change:  func() error { return UserEmails(db).SetVerified(ctx, user.ID, "b@example.com", true) },
+			wantIDs: []int32{user.ID},
+		},
+		{
+			name:    "set primary",
+			change:  func() error { return UserEmails(db).SetPrimaryEmail(ctx, user.ID, "b@example.com") },
+			wantIDs: []int32{user.ID},
+		},
+		{
+			name:    "failed set primary",
+			change:  func() error { return UserEmails(db).SetPrimaryEmail(ctx, user.ID, "a@example.com") },
+			wantIDs: nil,
+		},
+		{
+			name:    "remove",
+			change:  func() error { return UserEmails(db).Remove(ctx, user.ID, "a@example.com") },
+			wantIDs: []int32{user.ID},
+		},
+		{
+			name:    "delete user",
+			change:  func() error { return Users(db).Delete(ctx, user.ID) },
+			wantIDs: []int32{user.ID},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			changed = nil
+			err := tc.change()
+			if tc.wantIDs != nil && err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantIDs, changed); diff != "" {
+				t.Fatalf("unexpected hook calls (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestUserEmails_ListByUser(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -572,3 +645,600 @@ func TestUserEmails_GetVerifiedEmails(t *testing.T) {
 		t.Errorf("got %s, but want %q", emails[0].Email, "alice@example.com")
 	}
 }
+
+func TestUserEmails_VerificationReminders(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	for _, newUser := range []NewUser{
+		{
+			Email:           "alice@example.com",
+			Username:        "alice",
+			EmailIsVerified: true,
+		},
+		{
+			Email:                 "bob@example.com",
+			Username:              "bob",
+			EmailVerificationCode: "c",
+		},
+	} {
+		if _, err := Users(db).Create(ctx, newUser); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := VerificationReminderOptions{MaxReminders: 2, SentBefore: time.Now().Add(time.Hour)}
+	listDue := func() []string {
+		t.Helper()
+		emails, err := UserEmails(db).ListDueForVerificationReminder(ctx, opts, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var addrs []string
+		for _, e := range emails {
+			addrs = append(addrs, e.Email)
+		}
+		return addrs
+	}
+
+	// Only the unverified address is due.
+	if diff := cmp.Diff([]string{"bob@example.com"}, listDue()); diff != "" {
+		t.Fatalf("unexpected due emails (-want +got):\n%s", diff)
+	}
+	bob, err := Users(db).GetByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first claim sets the token, later claims keep it.
+	token, claimed, err := UserEmails(db).ClaimVerificationReminder(ctx, bob.ID, "bob@example.com", "first", opts)
+	if err != nil {
+		t.Fatal(err)
+	} else if !claimed || token != "first" {
+		t.Fatalf("got token %q, claimed %t; want %q, true", token, claimed, "first")
+	}
+	token, claimed, err = UserEmails(db).ClaimVerificationReminder(ctx, bob.ID, "bob@example.com", "second", opts)
+	if err != nil {
+		t.Fatal(err)
+	} else if !claimed || token != "first" {
+		t.Fatalf("got token %q, claimed %t; want %q, true", token, claimed, "first")
+	}
+
+	// MaxReminders is reached.
+	if due := listDue(); len(due) != 0 {
+		t.Fatalf("want no due emails, got %v", due)
+	}
+	if _, claimed, err := UserEmails(db).ClaimVerificationReminder(ctx, bob.ID, "bob@example.com", "third", opts); err != nil {
+		t.Fatal(err)
+	} else if claimed {
+		t.Fatal("claimed reminder beyond MaxReminders")
+	}
+
+	// Opting out requires the correct token.
+	opts.MaxReminders = 10
+	if ok, err := UserEmails(db).OptOutOfVerificationReminders(ctx, bob.ID, "bob@example.com", "second"); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("opted out with wrong token")
+	}
+	if diff := cmp.Diff([]string{"bob@example.com"}, listDue()); diff != "" {
+		t.Fatalf("unexpected due emails (-want +got):\n%s", diff)
+	}
+	if ok, err := UserEmails(db).OptOutOfVerificationReminders(ctx, bob.ID, "bob@example.com", "first"); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("could not opt out with correct token")
+	}
+	if due := listDue(); len(due) != 0 {
+		t.Fatalf("want no due emails after opting out, got %v", due)
+	}
+}
+
+func TestUserEmails_ChangeRequests(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{
+		Email:           "a@example.com",
+		Username:        "u",
+		Password:        "pw",
+		EmailIsVerified: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UserEmails(db).GetChangeRequest(ctx, user.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+
+	// A later request replaces an earlier one.
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	for _, email := range []string{"b@example.com", "c@example.com"} {
+		req := &UserEmailChangeRequest{UserID: user.ID, Email: email, Secret: "s-" + email, ExpiresAt: expiresAt}
+		if err := UserEmails(db).CreateChangeRequest(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if req.CreatedAt.IsZero() {
+			t.Fatal("CreatedAt is not set")
+		}
+	}
+	req, err := UserEmails(db).GetChangeRequest(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Email != "c@example.com" || req.Secret != "s-c@example.com" || !req.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected change request %+v", req)
+	}
+
+	// Only the pending address can be completed.
+	if err := UserEmails(db).CompleteChangeRequest(ctx, user.ID, "b@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+	if err := UserEmails(db).CompleteChangeRequest(ctx, user.ID, "c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	email, verified, err := UserEmails(db).GetPrimaryEmail(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email != "c@example.com" || !verified {
+		t.Fatalf("got primary email %q (verified %t), want verified c@example.com", email, verified)
+	}
+	if _, err := UserEmails(db).GetChangeRequest(ctx, user.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+
+	// The request is deleted, so it can't be completed again.
+	if err := UserEmails(db).CompleteChangeRequest(ctx, user.ID, "c@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+}
+
+func TestUserEmails_SetVerifiedBulk(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	var userIDs []int32
+	for _, newUser := range []NewUser{
+		{Email: "a@example.com", Username: "a", EmailVerificationCode: "c"},
+		{Email: "b@example.com", Username: "b", EmailVerificationCode: "c"},
+		{Email: "c@example.com", Username: "c", EmailIsVerified: true},
+	} {
+		user, err := Users(db).Create(ctx, newUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	// c@example.com is verified by user c, so user a can't verify it too.
+	if err := UserEmails(db).Add(ctx, userIDs[0], "c@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	checkVerified := func(t *testing.T, userID int32, email string, want bool) {
+		t.Helper()
+		_, verified, err := UserEmails(db).Get(ctx, userID, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verified != want {
+			t.Errorf("%s of user %d: got verified %t, want %t", email, userID, verified, want)
+		}
+	}
+
+	n, err := UserEmails(db).SetVerifiedBulk(ctx, userIDs[:2], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d changed emails, want 2", n)
+	}
+	checkVerified(t, userIDs[0], "a@example.com", true)
+	checkVerified(t, userIDs[0], "c@example.com", false)
+	checkVerified(t, userIDs[1], "b@example.com", true)
+
+	n, err = UserEmails(db).SetVerifiedBulk(ctx, userIDs[1:], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d changed emails, want 2", n)
+	}
+	checkVerified(t, userIDs[0], "a@example.com", true)
+	checkVerified(t, userIDs[1], "b@example.com", false)
+	checkVerified(t, userIDs[2], "c@example.com", false)
+
+	t.Run("shared address", func(t *testing.T) {
+		// Both users have the same unverified address, but only one of
+		// them can verify it.
+		for _, userID := range userIDs[1:] {
+			if err := UserEmails(db).Add(ctx, userID, "shared@example.com", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		n, err := UserEmails(db).SetVerifiedBulk(ctx, userIDs[1:], true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Errorf("got %d changed emails, want 3", n)
+		}
+		checkVerified(t, userIDs[1], "b@example.com", true)
+		checkVerified(t, userIDs[1], "shared@example.com", true)
+		checkVerified(t, userIDs[2], "c@example.com", true)
+		checkVerified(t, userIDs[2], "shared@example.com", false)
+	})
+}
+
+func TestUserEmails_VerificationAttempts(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	alice, err := Users(db).Create(ctx, NewUser{Email: "a@example.com", Username: "a", EmailVerificationCode: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := Users(db).Create(ctx, NewUser{Email: "admin@example.com", Username: "admin", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).Add(ctx, alice.ID, "a2@example.com", strptr("c")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Anonymous, by the user themselves and by a site admin.
+	for _, test := range []struct {
+		actorID int32
+		email   string
+	}{
+		{0, "a@example.com"},
+		{alice.ID, "a@example.com"},
+		{admin.ID, "a2@example.com"},
+	} {
+		ctx := actor.WithActor(ctx, &actor.Actor{UID: test.actorID})
+		if err := UserEmails(db).SetLastVerification(ctx, alice.ID, test.email, "c2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// No attempt is recorded for unknown email addresses.
+	if err := UserEmails(db).SetLastVerification(ctx, alice.ID, "unknown@example.com", "c2"); err == nil {
+		t.Fatal("want error for unknown email address")
+	}
+
+	attempts, err := UserEmails(db).ListVerificationAttempts(ctx, VerificationAttemptsListOptions{UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, a := range attempts {
+		if a.UserID != alice.ID || a.CreatedAt.IsZero() {
+			t.Fatalf("unexpected attempt %+v", a)
+		}
+		have = append(have, fmt.Sprintf("%s by %d", a.Email, a.ActorUserID))
+	}
+	want := []string{
+		fmt.Sprintf("a2@example.com by %d", admin.ID),
+		fmt.Sprintf("a@example.com by %d", alice.ID),
+		"a@example.com by 0",
+	}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Fatalf("unexpected attempts (-want +have):\n%s", diff)
+	}
+
+	for _, test := range []struct {
+		opts VerificationAttemptsListOptions
+		want int
+	}{
+		{VerificationAttemptsListOptions{}, 3},
+		{VerificationAttemptsListOptions{UserID: admin.ID}, 0},
+		{VerificationAttemptsListOptions{UserID: alice.ID, Email: "A@example.com"}, 2},
+		{VerificationAttemptsListOptions{Since: time.Now().Add(time.Hour)}, 0},
+		{VerificationAttemptsListOptions{LimitOffset: &LimitOffset{Limit: 1}}, 3},
+	} {
+		count, err := UserEmails(db).CountVerificationAttempts(ctx, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != test.want {
+			t.Errorf("opts %+v: got count %d, want %d", test.opts, count, test.want)
+		}
+	}
+}
+
+func TestUserEmails_RecoveryEmail(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Email: "a@example.com", Username: "u", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).Add(ctx, user.ID, "b@example.com", strptr("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).Add(ctx, user.ID, "c@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).SetVerified(ctx, user.ID, "c@example.com", true); err != nil {
+		t.Fatal(err)
+	}
+
+	checkRecoveryEmail := func(t *testing.T, want string) {
+		t.Helper()
+		email, err := UserEmails(db).GetRecoveryEmail(ctx, user.ID)
+		if want == "" {
+			if !errcode.IsNotFound(err) {
+				t.Fatalf("got recovery email %q, err %v, want not found", email, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if email != want {
+			t.Fatalf("got recovery email %q, want %q", email, want)
+		}
+	}
+
+	checkRecoveryEmail(t, "")
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "a@example.com"); err == nil {
+		t.Fatal("want error for the primary email")
+	}
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "b@example.com"); err == nil {
+		t.Fatal("want error for an unverified email")
+	}
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "unknown@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkRecoveryEmail(t, "c@example.com")
+
+	// Setting another recovery email replaces the old one.
+	if err := UserEmails(db).SetVerified(ctx, user.ID, "b@example.com", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkRecoveryEmail(t, "b@example.com")
+	emails, err := UserEmails(db).ListByUser(ctx, UserEmailsListOptions{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range emails {
+		if e.Recovery != (e.Email == "b@example.com") {
+			t.Errorf("email %q: got recovery %t", e.Email, e.Recovery)
+		}
+	}
+
+	// Unverifying the recovery email unsets it.
+	if err := UserEmails(db).SetVerified(ctx, user.ID, "b@example.com", false); err != nil {
+		t.Fatal(err)
+	}
+	checkRecoveryEmail(t, "")
+
+	// Making the recovery email primary unsets it.
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).SetPrimaryEmail(ctx, user.ID, "c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkRecoveryEmail(t, "")
+
+	if err := UserEmails(db).SetRecoveryEmail(ctx, user.ID, "a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if cleared, err := UserEmails(db).ClearRecoveryEmail(ctx, user.ID); err != nil || !cleared {
+		t.Fatalf("got (%t, %v), want (true, nil)", cleared, err)
+	}
+	if cleared, err := UserEmails(db).ClearRecoveryEmail(ctx, user.ID); err != nil || cleared {
+		t.Fatalf("got (%t, %v), want (false, nil)", cleared, err)
+	}
+	checkRecoveryEmail(t, "")
+}
+
+func TestUserEmails_Restore(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Email: "a@example.com", Username: "u", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Users(db).Create(ctx, NewUser{Email: "other@example.com", Username: "other", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"b@example.com", "c@example.com"} {
+		if err := UserEmails(db).Add(ctx, user.ID, email, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := UserEmails(db).SetVerified(ctx, user.ID, email, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := UserEmails(db).Remove(ctx, user.ID, email); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Removed emails are hidden.
+	if _, _, err := UserEmails(db).Get(ctx, user.ID, "b@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+	if _, err := Users(db).GetByVerifiedEmail(ctx, "b@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+	if err := UserEmails(db).Remove(ctx, user.ID, "b@example.com"); err == nil {
+		t.Fatal("got err == nil for Remove on removed email")
+	}
+
+	// Restoring keeps the verification status.
+	if err := UserEmails(db).Restore(ctx, user.ID, "b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, verified, err := UserEmails(db).Get(ctx, user.ID, "b@example.com"); err != nil {
+		t.Fatal(err)
+	} else if !verified {
+		t.Fatal("want restored email to be verified")
+	}
+	if err := UserEmails(db).Restore(ctx, user.ID, "b@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found for a restored email", err)
+	}
+
+	// A removed email can be verified by another user, and then can't be restored.
+	if err := UserEmails(db).Add(ctx, other.ID, "c@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).SetVerified(ctx, other.ID, "c@example.com", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).Restore(ctx, user.ID, "c@example.com"); err == nil || errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want error for an email verified by another user", err)
+	}
+
+	// Removed emails can't be restored after the restore window, and are then purged.
+	if err := UserEmails(db).Remove(ctx, user.ID, "b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE user_emails SET deleted_at = deleted_at - interval '31 days' WHERE user_id=$1 AND email=$2", user.ID, "b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).Restore(ctx, user.ID, "b@example.com"); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found for an email removed too long ago", err)
+	}
+	if n, err := UserEmails(db).PurgeRemoved(ctx); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("got %d purged emails, want 1", n)
+	}
+
+	// Adding a removed email again replaces it.
+	if err := UserEmails(db).Add(ctx, user.ID, "c@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, verified, err := UserEmails(db).Get(ctx, user.ID, "c@example.com"); err != nil {
+		t.Fatal(err)
+	} else if verified {
+		t.Fatal("want re-added email to be unverified")
+	}
+}
+
+func TestUserEmails_TransferAttribution(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Email: "a@example.com", Username: "u", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Users(db).Create(ctx, NewUser{Email: "other@example.com", Username: "other", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"b@example.com", "c@example.com"} {
+		if err := UserEmails(db).Add(ctx, user.ID, email, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := UserEmails(db).SetVerified(ctx, user.ID, email, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := UserEmails(db).Add(ctx, user.ID, "unverified@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	checkAttributions := func(t *testing.T, want map[string]string) {
+		t.Helper()
+		attributions, err := UserEmails(db).ListAttributionsByUser(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string, len(attributions))
+		for _, a := range attributions {
+			got[a.Email] = a.ToEmail
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("unexpected attributions (-want +got):\n%s", diff)
+		}
+	}
+
+	for _, test := range []struct{ from, to string }{
+		{"b@example.com", "B@example.com"},
+		{"b@example.com", "unverified@example.com"},
+		{"b@example.com", "unknown@example.com"},
+		{"unverified@example.com", "a@example.com"},
+		{"other@example.com", "a@example.com"},
+	} {
+		if err := UserEmails(db).TransferAttribution(ctx, user.ID, test.from, test.to); err == nil {
+			t.Fatalf("want error transferring %q to %q", test.from, test.to)
+		}
+	}
+	checkAttributions(t, map[string]string{})
+
+	// A removed email address can be attributed to another address of the user.
+	if err := UserEmails(db).Remove(ctx, user.ID, "b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).TransferAttribution(ctx, user.ID, "b@example.com", "c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkAttributions(t, map[string]string{"b@example.com": "c@example.com"})
+
+	// Transferring the target re-points earlier attributions.
+	if err := UserEmails(db).Remove(ctx, user.ID, "c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkAttributions(t, map[string]string{})
+	if err := UserEmails(db).TransferAttribution(ctx, user.ID, "c@example.com", "a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkAttributions(t, map[string]string{"b@example.com": "a@example.com", "c@example.com": "a@example.com"})
+
+	// Attributions don't apply once another user verifies the email address.
+	if err := UserEmails(db).Add(ctx, other.ID, "b@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserEmails(db).SetVerified(ctx, other.ID, "b@example.com", true); err != nil {
+		t.Fatal(err)
+	}
+	if attributions, err := UserEmails(db).GetAttributions(ctx, "b@example.com", "c@example.com"); err != nil {
+		t.Fatal(err)
+	} else if len(attributions) != 1 || attributions[0].Email != "c@example.com" || attributions[0].UserID != user.ID {
+		t.Fatalf("unexpected attributions %+v", attributions)
+	}
+}
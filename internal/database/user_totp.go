@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/encryption"
+	"github.com/sourcegraph/sourcegraph/internal/encryption/keyring"
+)
+
+// UserTOTP is the TOTP secret of a user who enabled or is enrolling in two-factor authentication.
+type UserTOTP struct {
+	UserID       int32
+	Secret       string     // base32-encoded
+	EnabledAt    *time.Time // nil while the enrollment is pending
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// userTOTPNotFoundError occurs when a user has no TOTP secret.
+type userTOTPNotFoundError struct {
+	userID int32
+}
+
+func (e userTOTPNotFoundError) Error() string {
+	return fmt.Sprintf("user %d has no TOTP secret", e.userID)
+}
+
+func (e userTOTPNotFoundError) NotFound() bool { return true }
+
+// ErrTOTPAlreadyEnabled occurs when a user who already enabled two-factor authentication enrolls
+// again.
+var ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+type UserTOTPStore struct {
+	*basestore.Store
+	key encryption.Key
+}
+
+// UserTOTPs instantiates and returns a new UserTOTPStore.
+func UserTOTPs(db dbutil.DB) *UserTOTPStore {
+	return &UserTOTPStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// UserTOTPsWith instantiates and returns a new UserTOTPStore using the other store handle.
+func UserTOTPsWith(other basestore.ShareableStore) *UserTOTPStore {
+	return &UserTOTPStore{Store: basestore.NewWithHandle(other.Handle())}
+}
+
+// WithEncryptionKey sets the encryption key of the TOTP secrets.
+func (s *UserTOTPStore) WithEncryptionKey(key encryption.Key) *UserTOTPStore {
+	return &UserTOTPStore{Store: s.Store, key: key}
+}
+
+func (s *UserTOTPStore) Transact(ctx context.Context) (*UserTOTPStore, error) {
+	txBase, err := s.Store.Transact(ctx)
+	return &UserTOTPStore{Store: txBase, key: s.key}, err
+}
+
+// TOTP secrets are credentials of user accounts, like the auth data of external accounts, so they
+// are encrypted with the same key.
+func (s *UserTOTPStore) getEncryptionKey() encryption.Key {
+	if s.key != nil {
+		return s.key
+	}
+	return keyring.Default().UserExternalAccountKey
+}
+
+// CreatePending starts the enrollment of the user with the secret, replacing the secret of a
+// previous enrollment that wasn't confirmed. It returns ErrTOTPAlreadyEnabled if the user already
+// enabled two-factor authentication.
+func (s *UserTOTPStore) CreatePending(ctx context.Context, userID int32, secret string) error {
+	if Mocks.UserTOTPs.CreatePending != nil {
+		return Mocks.UserTOTPs.CreatePending(ctx, userID, secret)
+	}
+
+	encrypted, keyID, err := MaybeEncrypt(ctx, s.getEncryptionKey(), secret)
+	if err != nil {
+		return err
+	}
+	res, err := s.ExecResult(ctx, sqlf.Sprintf(`
+INSERT INTO user_totp_secrets (user_id, secret, encryption_key_id)
+VALUES (%s, %s, %s)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = excluded.secret, encryption_key_id = excluded.encryption_key_id, last_used_step = 0, created_at = now()
+WHERE user_totp_secrets.enabled_at IS NULL
+`, userID, encrypted, keyID))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// GetByUserID returns the TOTP secret of the user, whether or not the enrollment was confirmed.
+func (s *UserTOTPStore) GetByUserID(ctx context.Context, userID int32) (*UserTOTP, error) {
+	if Mocks.UserTOTPs.GetByUserID != nil {
+		return Mocks.UserTOTPs.GetByUserID(ctx, userID)
+	}
+
+	var t UserTOTP
+	var keyID string
+	err := s.QueryRow(ctx, sqlf.Sprintf(`
+SELECT user_id, secret, encryption_key_id, enabled_at, last_used_step, created_at
+FROM user_totp_secrets
+WHERE user_id = %s
+`, userID)).Scan(&t.UserID, &t.Secret, &keyID, &t.EnabledAt, &t.LastUsedStep, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, userTOTPNotFoundError{userID: userID}
+	} else if err != nil {
+		return nil, err
+	}
+	if t.Secret, err = MaybeDecrypt(ctx, s.getEncryptionKey(), t.Secret, keyID); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Enable confirms the enrollment of the user with a code of the given time step, and replaces the
+// user's recovery codes with the given hashes.
+func (s *UserTOTPStore) Enable(ctx context.Context, userID int32, step int64, recoveryCodeHashes []string) (err error) {
+	if Mocks.UserTOTPs.Enable != nil {
+		return Mocks.UserTOTPs.Enable(ctx, userID, step, recoveryCodeHashes)
+	}
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	res, err := tx.ExecResult(ctx, sqlf.Sprintf(`
+UPDATE user_totp_secrets SET enabled_at = now(), last_used_step = %s
+WHERE user_id = %s AND enabled_at IS NULL
+`, step, userID))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return tx.replaceRecoveryCodes(ctx, userID, recoveryCodeHashes)
+}
+
+// UseStep records that the user signed in with a code of the given time step. It returns false if
+// a code of the same or a later step was used before, which prevents replaying a code.
+func (s *UserTOTPStore) UseStep(ctx context.Context, userID int32, step int64) (bool, error) {
+	if Mocks.UserTOTPs.UseStep != nil {
+		return Mocks.UserTOTPs.UseStep(ctx, userID, step)
+	}
+
+	res, err := s.ExecResult(ctx, sqlf.Sprintf(`
+UPDATE user_totp_secrets SET last_used_step = %s
+WHERE user_id = %s AND enabled_at IS NOT NULL AND last_used_step < %s
+`, step, userID, step))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UseRecoveryCode consumes the unused recovery code of the user with the given hash. It returns
+// false if there is none.
+func (s *UserTOTPStore) UseRecoveryCode(ctx context.Context, userID int32, codeHash string) (bool, error) {
+	if Mocks.UserTOTPs.UseRecoveryCode != nil {
+		return Mocks.UserTOTPs.UseRecoveryCode(ctx, userID, codeHash)
+	}
+
+	res, err := s.ExecResult(ctx, sqlf.Sprintf(`
+UPDATE user_totp_recovery_codes SET used_at = now()
+WHERE user_id = %s AND code_hash = %s AND used_at IS NULL
+`, userID, codeHash))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of the user with the given hashes.
+func (s *UserTOTPStore) ReplaceRecoveryCodes(ctx context.Context, userID int32, codeHashes []string) (err error) {
+	if Mocks.UserTOTPs.ReplaceRecoveryCodes != nil {
+		return Mocks.UserTOTPs.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+	}
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+	return tx.replaceRecoveryCodes(ctx, userID, codeHashes)
+}
+
+func (s *UserTOTPStore) replaceRecoveryCodes(ctx context.Context, userID int32, codeHashes []string) error {
+	if err := s.Exec(ctx, sqlf.Sprintf("DELETE FROM user_totp_recovery_codes WHERE user_id = %s", userID)); err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+	values := make([]*sqlf.Query, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		values = append(values, sqlf.Sprintf("(%s, %s)", userID, h))
+	}
+	return s.Exec(ctx, sqlf.Sprintf("INSERT INTO user_totp_recovery_codes (user_id, code_hash) VALUES %s", sqlf.Join(values, ",")))
+}
+
+// CountUnusedRecoveryCodes returns the number of recovery codes that the user can still use.
+func (s *UserTOTPStore) CountUnusedRecoveryCodes(ctx context.Context, userID int32) (int, error) {
+	count, _, err := basestore.ScanFirstInt(s.Query(ctx, sqlf.Sprintf(
+		"SELECT COUNT(*) FROM user_totp_recovery_codes WHERE user_id = %s AND used_at IS NULL", userID)))
+	return count, err
+}
+
+// Delete disables two-factor authentication for the user, deleting the TOTP secret and recovery
+// codes. It returns false if the user had no TOTP secret.
+func (s *UserTOTPStore) Delete(ctx context.Context, userID int32) (_ bool, err error) {
+	if Mocks.UserTOTPs.Delete != nil {
+		return Mocks.UserTOTPs.Delete(ctx, userID)
+	}
+
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.Exec(ctx, sqlf.Sprintf("DELETE FROM user_totp_recovery_codes WHERE user_id = %s", userID)); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecResult(ctx, sqlf.Sprintf("DELETE FROM user_totp_secrets WHERE user_id = %s", userID))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
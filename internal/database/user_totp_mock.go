@@ -0,0 +1,13 @@
+package database
+
+import "context"
+
+type MockUserTOTPs struct {
+	CreatePending        func(ctx context.Context, userID int32, secret string) error
+	GetByUserID          func(ctx context.Context, userID int32) (*UserTOTP, error)
+	Enable               func(ctx context.Context, userID int32, step int64, recoveryCodeHashes []string) error
+	UseStep              func(ctx context.Context, userID int32, step int64) (bool, error)
+	UseRecoveryCode      func(ctx context.Context, userID int32, codeHash string) (bool, error)
+	ReplaceRecoveryCodes func(ctx context.Context, userID int32, codeHashes []string) error
+	Delete               func(ctx context.Context, userID int32) (bool, error)
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	et "github.com/sourcegraph/sourcegraph/internal/encryption/testing"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestUserTOTPs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	user, err := Users(db).Create(ctx, NewUser{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := UserTOTPs(db).WithEncryptionKey(et.TestKey{})
+
+	if _, err := store.GetByUserID(ctx, user.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+
+	// A pending enrollment can be restarted with a new secret.
+	if err := store.CreatePending(ctx, user.ID, "OLDSECRET"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreatePending(ctx, user.ID, "SECRET"); err != nil {
+		t.Fatal(err)
+	}
+	totp, err := store.GetByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totp.Secret != "SECRET" || totp.EnabledAt != nil {
+		t.Fatalf("unexpected TOTP %+v", totp)
+	}
+	if ok, err := store.UseStep(ctx, user.ID, 10); err != nil || ok {
+		t.Fatalf("got %v, %v, want codes to be rejected while the enrollment is pending", ok, err)
+	}
+
+	if err := store.Enable(ctx, user.ID, 10, []string{"hash1", "hash2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreatePending(ctx, user.ID, "NEWSECRET"); err != ErrTOTPAlreadyEnabled {
+		t.Fatalf("got err %v, want %v", err, ErrTOTPAlreadyEnabled)
+	}
+	if totp, err := store.GetByUserID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	} else if totp.Secret != "SECRET" || totp.EnabledAt == nil || totp.LastUsedStep != 10 {
+		t.Fatalf("unexpected TOTP %+v", totp)
+	}
+
+	// Codes can't be replayed.
+	if ok, err := store.UseStep(ctx, user.ID, 10); err != nil || ok {
+		t.Fatalf("got %v, %v, want the code of the enrollment to be rejected", ok, err)
+	}
+	if ok, err := store.UseStep(ctx, user.ID, 11); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the code to be accepted", ok, err)
+	}
+
+	// Recovery codes can only be used once.
+	if ok, err := store.UseRecoveryCode(ctx, user.ID, "hash1"); err != nil || !ok {
+		t.Fatalf("got %v, %v, want the recovery code to be accepted", ok, err)
+	}
+	if ok, err := store.UseRecoveryCode(ctx, user.ID, "hash1"); err != nil || ok {
+		t.Fatalf("got %v, %v, want the used recovery code to be rejected", ok, err)
+	}
+	if n, err := store.CountUnusedRecoveryCodes(ctx, user.ID); err != nil || n != 1 {
+		t.Fatalf("got %d, %v, want 1 unused recovery code", n, err)
+	}
+	if err := store.ReplaceRecoveryCodes(ctx, user.ID, []string{"hash3", "hash4", "hash5"}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.UseRecoveryCode(ctx, user.ID, "hash2"); err != nil || ok {
+		t.Fatalf("got %v, %v, want the replaced recovery code to be rejected", ok, err)
+	}
+	if n, err := store.CountUnusedRecoveryCodes(ctx, user.ID); err != nil || n != 3 {
+		t.Fatalf("got %d, %v, want 3 unused recovery codes", n, err)
+	}
+
+	if ok, err := store.Delete(ctx, user.ID); err != nil || !ok {
+		t.Fatalf("got %v, %v, want TOTP to be deleted", ok, err)
+	}
+	if _, err := store.GetByUserID(ctx, user.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got err %v, want not found", err)
+	}
+	if n, err := store.CountUnusedRecoveryCodes(ctx, user.ID); err != nil || n != 0 {
+		t.Fatalf("got %d, %v, want no recovery codes", n, err)
+	}
+}
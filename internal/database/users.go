@@ -149,6 +149,9 @@ type NewUser struct {
 	Password    string
 	AvatarURL   string // the new user's avatar URL, if known
 
+	// Tags are the initial tags of the new user, such as TagServiceAccount.
+	Tags []string `json:"-"` // forbid this field being set by JSON, just in case
+
 	// EmailVerificationCode, if given, causes the new user's email address to be unverified until
 	// they perform the email verification process and provied this code.
 	EmailVerificationCode string `json:"-"` // forbid this field being set by JSON, just in case
@@ -289,11 +292,16 @@ func (u *UserStore) create(ctx context.Context, info NewUser) (newUser *types.Us
 		}
 	}
 
+	tags := info.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
 	var siteAdmin bool
 	err = u.QueryRow(
 		ctx,
-		sqlf.Sprintf("INSERT INTO users(username, display_name, avatar_url, created_at, updated_at, passwd, invalidated_sessions_at, site_admin) VALUES(%s, %s, %s, %s, %s, %s, %s, %s AND NOT EXISTS(SELECT * FROM users)) RETURNING id, site_admin",
-			info.Username, info.DisplayName, avatarURL, createdAt, updatedAt, passwd, invalidatedSessionsAt, !alreadyInitialized)).Scan(&id, &siteAdmin)
+		sqlf.Sprintf("INSERT INTO users(username, display_name, avatar_url, created_at, updated_at, passwd, invalidated_sessions_at, site_admin, tags) VALUES(%s, %s, %s, %s, %s, %s, %s, %s AND NOT EXISTS(SELECT * FROM users), %s) RETURNING id, site_admin",
+			info.Username, info.DisplayName, avatarURL, createdAt, updatedAt, passwd, invalidatedSessionsAt, !alreadyInitialized, pq.Array(tags))).Scan(&id, &siteAdmin)
 	if err != nil {
 		var e *pgconn.PgError
 		if errors.As(err, &e) {
@@ -319,7 +327,7 @@ func (u *UserStore) create(ctx context.Context, info NewUser) (newUser *types.Us
 	if info.Email != "" {
 		// We don't allow adding a new user with an email address that has already been
 		// verified by another user.
-		exists, _, err := basestore.ScanFirstBool(u.Query(ctx, sqlf.Sprintf("SELECT TRUE WHERE EXISTS (SELECT FROM user_emails where email = %s AND verified_at IS NOT NULL)", info.Email)))
+		exists, _, err := basestore.ScanFirstBool(u.Query(ctx, sqlf.Sprintf("SELECT TRUE WHERE EXISTS (SELECT FROM user_emails where email = %s AND verified_at IS NOT NULL AND deleted_at IS NULL)", info.Email)))
 		if err != nil {
 			return nil, err
 		}
@@ -340,6 +348,14 @@ func (u *UserStore) create(ctx context.Context, info NewUser) (newUser *types.Us
 			}
 			return nil, err
 		}
+
+		// The email address is already verified, so the user can claim any org invitations
+		// that were sent to it before they signed up, and join the org that verified its domain.
+		if info.EmailIsVerified {
+			if err := joinOrgsForVerifiedEmail(ctx, u, id, info.Email); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	user := &types.User{
@@ -351,6 +367,7 @@ func (u *UserStore) create(ctx context.Context, info NewUser) (newUser *types.Us
 		UpdatedAt:             updatedAt,
 		SiteAdmin:             siteAdmin,
 		BuiltinAuth:           info.Password != "",
+		Tags:                  info.Tags,
 		InvalidatedSessionsAt: invalidatedSessionsAt,
 	}
 	{
@@ -501,6 +518,7 @@ func (u *UserStore) Delete(ctx context.Context, id int32) (err error) {
 	if err != nil {
 		return err
 	}
+	defer afterUserEmailsChange(&err, id)
 	defer func() { err = tx.Done(err) }()
 
 	res, err := tx.ExecResult(ctx, sqlf.Sprintf("UPDATE users SET deleted_at=now() WHERE id=%s AND deleted_at IS NULL", id))
@@ -552,6 +570,7 @@ func (u *UserStore) HardDelete(ctx context.Context, id int32) (err error) {
 	if err != nil {
 		return err
 	}
+	defer afterUserEmailsChange(&err, id)
 	defer func() { err = tx.Done(err) }()
 
 	if err := tx.Exec(ctx, sqlf.Sprintf("DELETE FROM names WHERE user_id=%s", id)); err != nil {
@@ -696,7 +715,7 @@ func (u *UserStore) GetByVerifiedEmail(ctx context.Context, email string) (*type
 	if Mocks.Users.GetByVerifiedEmail != nil {
 		return Mocks.Users.GetByVerifiedEmail(ctx, email)
 	}
-	return u.getOneBySQL(ctx, sqlf.Sprintf("WHERE id=(SELECT user_id FROM user_emails WHERE email=%s AND verified_at IS NOT NULL) AND deleted_at IS NULL LIMIT 1", email))
+	return u.getOneBySQL(ctx, sqlf.Sprintf("WHERE id=(SELECT user_id FROM user_emails WHERE email=%s AND verified_at IS NOT NULL AND deleted_at IS NULL) AND deleted_at IS NULL LIMIT 1", email))
 }
 
 func (u *UserStore) GetByUsername(ctx context.Context, username string) (*types.User, error) {
@@ -1168,6 +1187,12 @@ const (
 	// TagAllowUserExternalServicePublic if set on a user, allows them to add
 	// public code through external services they own.
 	TagAllowUserExternalServicePublic = "AllowUserExternalServicePublic"
+	// TagServiceAccount if set on a user, marks them as a service account (bot
+	// user). Service accounts are exempt from email verification and account
+	// change notifications, and only site admins can edit their email addresses.
+	// Site admins also create their access tokens, which may be restricted to a
+	// single API.
+	TagServiceAccount = "ServiceAccount"
 )
 
 // SetTag adds (present=true) or removes (present=false) a tag from the given user's set of tags. An
@@ -1,6 +1,7 @@
 package bitbucketcloud
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	"golang.org/x/time/rate"
 
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/metrics"
 	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
@@ -86,6 +88,39 @@ func NewClient(apiURL *url.URL, httpClient httpcli.Doer) *Client {
 	}
 }
 
+// WithAuthenticator returns a new Client that uses the same configuration,
+// HTTPClient, and RateLimiter as the current Client, except authenticated with
+// the username and app password of the given authenticator. Only
+// auth.BasicAuth and auth.BasicAuthWithSSH are supported.
+func (c *Client) WithAuthenticator(a auth.Authenticator) (*Client, error) {
+	var ba *auth.BasicAuth
+	switch a := a.(type) {
+	case *auth.BasicAuth:
+		ba = a
+	case *auth.BasicAuthWithSSH:
+		ba = &a.BasicAuth
+	default:
+		return nil, errors.Errorf("authenticator type unsupported for Bitbucket Cloud clients: %T", a)
+	}
+
+	return &Client{
+		httpClient:  c.httpClient,
+		URL:         c.URL,
+		Username:    ba.Username,
+		AppPassword: ba.Password,
+		RateLimit:   c.RateLimit,
+	}, nil
+}
+
+// CurrentUser returns the user the client is authenticated as.
+func (c *Client) CurrentUser(ctx context.Context) (*Account, error) {
+	var user Account
+	if err := c.send(ctx, "GET", "/2.0/user", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // Repos returns a list of repositories that are fetched and populated based on given account
 // name and pagination criteria. If the account requested is a team, results will be filtered
 // down to the ones that the app password's user has access to.
@@ -144,6 +179,25 @@ func (c *Client) reqPage(ctx context.Context, url string, results interface{}) (
 	return &next, nil
 }
 
+func (c *Client) send(ctx context.Context, method, path string, qry url.Values, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		bs, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bs)
+	}
+
+	u := url.URL{Path: path, RawQuery: qry.Encode()}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, req, result)
+}
+
 func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
 	req.URL = c.URL.ResolveReference(req.URL)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
@@ -277,3 +331,9 @@ func (e *httpError) Unauthorized() bool {
 func (e *httpError) NotFound() bool {
 	return e.StatusCode == http.StatusNotFound
 }
+
+// IsNotFound reports whether err is a Bitbucket Cloud API not found error.
+func IsNotFound(err error) bool {
+	var e *httpError
+	return errors.As(err, &e) && e.NotFound()
+}
@@ -0,0 +1,120 @@
+package bitbucketcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	eventTypeHeader = "X-Event-Key"
+)
+
+// WebhookEventType returns the type of the webhook event sent with the given
+// request.
+func WebhookEventType(r *http.Request) string {
+	return r.Header.Get(eventTypeHeader)
+}
+
+// ParseWebhookEvent parses the payload of a webhook event of the given type.
+func ParseWebhookEvent(eventType string, payload []byte) (e interface{}, err error) {
+	switch eventType {
+	case "pullrequest:approved":
+		e = &PullRequestApprovedEvent{}
+	case "pullrequest:unapproved":
+		e = &PullRequestUnapprovedEvent{}
+	case "pullrequest:changes_request_created":
+		e = &PullRequestChangesRequestCreatedEvent{}
+	case "pullrequest:changes_request_removed":
+		e = &PullRequestChangesRequestRemovedEvent{}
+	case "pullrequest:fulfilled":
+		e = &PullRequestFulfilledEvent{}
+	case "pullrequest:rejected":
+		e = &PullRequestRejectedEvent{}
+	case "repo:commit_status_created", "repo:commit_status_updated":
+		e = &RepoCommitStatusEvent{}
+	default:
+		return nil, errors.Errorf("unknown webhook event type: %q", eventType)
+	}
+	return e, json.Unmarshal(payload, e)
+}
+
+// RepoEvent contains the fields sent with every webhook event.
+type RepoEvent struct {
+	Actor      Account `json:"actor"`
+	Repository Repo    `json:"repository"`
+}
+
+// PullRequestEvent contains the fields sent with every pull request webhook
+// event.
+type PullRequestEvent struct {
+	RepoEvent
+	PullRequest PullRequest `json:"pullrequest"`
+}
+
+// Approval is an approval or change request of a pull request.
+type Approval struct {
+	Date time.Time `json:"date"`
+	User Account   `json:"user"`
+}
+
+func (a *Approval) key() string {
+	return fmt.Sprintf("%s:%s", a.User.UUID, a.Date.UTC().Format(time.RFC3339Nano))
+}
+
+type PullRequestApprovedEvent struct {
+	PullRequestEvent
+	Approval Approval `json:"approval"`
+}
+
+func (e *PullRequestApprovedEvent) Key() string { return e.Approval.key() }
+
+type PullRequestUnapprovedEvent struct {
+	PullRequestEvent
+	Approval Approval `json:"approval"`
+}
+
+func (e *PullRequestUnapprovedEvent) Key() string { return e.Approval.key() }
+
+type PullRequestChangesRequestCreatedEvent struct {
+	PullRequestEvent
+	ChangesRequest Approval `json:"changes_request"`
+}
+
+func (e *PullRequestChangesRequestCreatedEvent) Key() string { return e.ChangesRequest.key() }
+
+type PullRequestChangesRequestRemovedEvent struct {
+	PullRequestEvent
+	ChangesRequest Approval `json:"changes_request"`
+}
+
+func (e *PullRequestChangesRequestRemovedEvent) Key() string { return e.ChangesRequest.key() }
+
+// PullRequestFulfilledEvent is sent when a pull request is merged.
+type PullRequestFulfilledEvent struct {
+	PullRequestEvent
+}
+
+func (e *PullRequestFulfilledEvent) Key() string {
+	return fmt.Sprintf("%d:%s", e.PullRequest.ID, e.PullRequest.UpdatedOn.UTC().Format(time.RFC3339Nano))
+}
+
+// PullRequestRejectedEvent is sent when a pull request is declined.
+type PullRequestRejectedEvent struct {
+	PullRequestEvent
+}
+
+func (e *PullRequestRejectedEvent) Key() string {
+	return fmt.Sprintf("%d:%s", e.PullRequest.ID, e.PullRequest.UpdatedOn.UTC().Format(time.RFC3339Nano))
+}
+
+// RepoCommitStatusEvent is sent when a build status of a commit is created or
+// updated. It doesn't reference the pull requests of the commit, only the
+// branch the build ran for, if the reporter set it.
+type RepoCommitStatusEvent struct {
+	RepoEvent
+	CommitStatus BuildStatus `json:"commit_status"`
+}
@@ -0,0 +1,54 @@
+package bitbucketcloud
+
+import (
+	"testing"
+)
+
+func TestParseWebhookEvent(t *testing.T) {
+	t.Run("approved", func(t *testing.T) {
+		e, err := ParseWebhookEvent("pullrequest:approved", []byte(`{
+			"repository": {"uuid": "{e1e75436-05e6-4c38-8543-9c36ec26fad1}", "full_name": "sglocal/mux"},
+			"pullrequest": {"id": 7, "state": "OPEN"},
+			"approval": {"date": "2021-09-01T10:00:00.000000+00:00", "user": {"uuid": "{d301aafa-d676-4ee0-88be-962be7417567}"}}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ev, ok := e.(*PullRequestApprovedEvent)
+		if !ok {
+			t.Fatalf("unexpected event type %T", e)
+		}
+		if ev.Repository.UUID != "{e1e75436-05e6-4c38-8543-9c36ec26fad1}" || ev.PullRequest.ID != 7 {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+		if have, want := ev.Key(), "{d301aafa-d676-4ee0-88be-962be7417567}:2021-09-01T10:00:00Z"; have != want {
+			t.Fatalf("wrong key. want=%q, have=%q", want, have)
+		}
+	})
+
+	t.Run("commit status", func(t *testing.T) {
+		for _, typ := range []string{"repo:commit_status_created", "repo:commit_status_updated"} {
+			e, err := ParseWebhookEvent(typ, []byte(`{
+				"commit_status": {"key": "build", "state": "INPROGRESS", "refname": "fix-build"}
+			}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ev, ok := e.(*RepoCommitStatusEvent)
+			if !ok {
+				t.Fatalf("unexpected event type %T", e)
+			}
+			if ev.CommitStatus.RefName != "fix-build" || ev.CommitStatus.State != BuildStatusStateInProgress {
+				t.Fatalf("unexpected event %+v", ev)
+			}
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := ParseWebhookEvent("repo:push", []byte(`{}`)); err == nil {
+			t.Fatal("unexpected nil error")
+		}
+	})
+}
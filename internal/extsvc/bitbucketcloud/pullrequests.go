@@ -0,0 +1,306 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrNotMergeable is returned by MergePullRequest when Bitbucket Cloud refuses
+// to merge the pull request, for example because of merge conflicts or unmet
+// merge checks.
+var ErrNotMergeable = errors.New("pull request cannot be merged")
+
+// PullRequestState is the state of a pull request.
+type PullRequestState string
+
+const (
+	PullRequestStateOpen       PullRequestState = "OPEN"
+	PullRequestStateMerged     PullRequestState = "MERGED"
+	PullRequestStateDeclined   PullRequestState = "DECLINED"
+	PullRequestStateSuperseded PullRequestState = "SUPERSEDED"
+)
+
+// PullRequest is a pull request on Bitbucket Cloud.
+type PullRequest struct {
+	ID                int64               `json:"id"`
+	Title             string              `json:"title"`
+	Description       string              `json:"description"`
+	State             PullRequestState    `json:"state"`
+	Author            Account             `json:"author"`
+	Source            PullRequestEndpoint `json:"source"`
+	Destination       PullRequestEndpoint `json:"destination"`
+	MergeCommit       *PullRequestCommit  `json:"merge_commit"`
+	Participants      []Participant       `json:"participants"`
+	CloseSourceBranch bool                `json:"close_source_branch"`
+	CreatedOn         time.Time           `json:"created_on"`
+	UpdatedOn         time.Time           `json:"updated_on"`
+	Links             PullRequestLinks    `json:"links"`
+}
+
+// PullRequestEndpoint is the source or destination of a pull request.
+type PullRequestEndpoint struct {
+	Repo   PullRequestRepo   `json:"repository"`
+	Branch PullRequestBranch `json:"branch"`
+	Commit PullRequestCommit `json:"commit"`
+}
+
+type PullRequestRepo struct {
+	FullName string `json:"full_name"`
+	Name     string `json:"name"`
+	UUID     string `json:"uuid"`
+}
+
+type PullRequestBranch struct {
+	Name string `json:"name"`
+}
+
+// PullRequestCommit is a commit referenced by a pull request. The hash is
+// abbreviated in most API responses.
+type PullRequestCommit struct {
+	Hash string `json:"hash"`
+}
+
+type PullRequestLinks struct {
+	HTML Link `json:"html"`
+}
+
+// Account is a user or team on Bitbucket Cloud.
+type Account struct {
+	AccountID   string `json:"account_id"`
+	UUID        string `json:"uuid"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+// ParticipantRole is the role of a participant of a pull request.
+type ParticipantRole string
+
+const (
+	ParticipantRoleParticipant ParticipantRole = "PARTICIPANT"
+	ParticipantRoleReviewer    ParticipantRole = "REVIEWER"
+)
+
+// ParticipantState is the review given by a participant of a pull request.
+// Participants that didn't review the pull request have an empty state.
+type ParticipantState string
+
+const (
+	ParticipantStateApproved         ParticipantState = "approved"
+	ParticipantStateChangesRequested ParticipantState = "changes_requested"
+)
+
+// Participant is a user that reviewed or commented on a pull request.
+type Participant struct {
+	User     Account          `json:"user"`
+	Role     ParticipantRole  `json:"role"`
+	Approved bool             `json:"approved"`
+	State    ParticipantState `json:"state"`
+}
+
+// BuildStatusState is the state of a build status.
+type BuildStatusState string
+
+const (
+	BuildStatusStateSuccessful BuildStatusState = "SUCCESSFUL"
+	BuildStatusStateFailed     BuildStatusState = "FAILED"
+	BuildStatusStateInProgress BuildStatusState = "INPROGRESS"
+	BuildStatusStateStopped    BuildStatusState = "STOPPED"
+)
+
+// BuildStatus is a build status reported for a commit.
+type BuildStatus struct {
+	Key         string           `json:"key"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	State       BuildStatusState `json:"state"`
+	URL         string           `json:"url"`
+	RefName     string           `json:"refname"`
+	CreatedOn   time.Time        `json:"created_on"`
+	UpdatedOn   time.Time        `json:"updated_on"`
+	Links       BuildStatusLinks `json:"links"`
+}
+
+type BuildStatusLinks struct {
+	Commit Link `json:"commit"`
+}
+
+// CommitHash returns the full hash of the commit the status was reported for.
+func (s *BuildStatus) CommitHash() string {
+	return path.Base(s.Links.Commit.Href)
+}
+
+// CommitStatus is the build status of a commit, as stored in changeset
+// events.
+type CommitStatus struct {
+	Commit string      `json:"commit"`
+	Status BuildStatus `json:"status"`
+}
+
+func (s *CommitStatus) Key() string {
+	return fmt.Sprintf("%s:%s", s.Commit, s.Status.Key)
+}
+
+// AnnotatedPullRequest is a pull request together with the statuses of its
+// head commit, which aren't part of the pull request returned by the API.
+type AnnotatedPullRequest struct {
+	*PullRequest
+	Statuses []*CommitStatus `json:"statuses"`
+}
+
+// PullRequestInput is the input for creating and updating pull requests.
+type PullRequestInput struct {
+	Title             string
+	Description       string
+	SourceBranch      string
+	DestinationBranch string
+}
+
+func (input *PullRequestInput) MarshalJSON() ([]byte, error) {
+	type branch struct {
+		Name string `json:"name"`
+	}
+	type endpoint struct {
+		Branch branch `json:"branch"`
+	}
+	type pullRequest struct {
+		Title       string    `json:"title"`
+		Description string    `json:"description"`
+		Source      *endpoint `json:"source,omitempty"`
+		Destination *endpoint `json:"destination,omitempty"`
+	}
+
+	pr := pullRequest{Title: input.Title, Description: input.Description}
+	if input.SourceBranch != "" {
+		pr.Source = &endpoint{Branch: branch{Name: input.SourceBranch}}
+	}
+	if input.DestinationBranch != "" {
+		pr.Destination = &endpoint{Branch: branch{Name: input.DestinationBranch}}
+	}
+	return json.Marshal(pr)
+}
+
+// CreatePullRequest opens a pull request from the source branch to the
+// destination branch of the given input in the given repository.
+func (c *Client) CreatePullRequest(ctx context.Context, repo *Repo, input PullRequestInput) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.send(ctx, "POST", pullRequestsPath(repo), nil, &input, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// FindOpenPullRequest returns the open pull request from the source branch to
+// the destination branch in the given repository. If there is none, nil is
+// returned.
+func (c *Client) FindOpenPullRequest(ctx context.Context, repo *Repo, source, destination string) (*PullRequest, error) {
+	qry := url.Values{"q": []string{fmt.Sprintf(
+		`source.branch.name = %q AND destination.branch.name = %q AND state = %q`,
+		source, destination, PullRequestStateOpen,
+	)}}
+
+	var prs []*PullRequest
+	if _, err := c.page(ctx, pullRequestsPath(repo), qry, &PageToken{Pagelen: 1}, &prs); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return prs[0], nil
+}
+
+// GetPullRequest returns the pull request with the given ID.
+func (c *Client) GetPullRequest(ctx context.Context, repo *Repo, id int64) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.send(ctx, "GET", pullRequestPath(repo, id), nil, nil, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// UpdatePullRequest updates the title, description and destination branch of
+// the pull request with the given ID.
+func (c *Client) UpdatePullRequest(ctx context.Context, repo *Repo, id int64, input PullRequestInput) (*PullRequest, error) {
+	// The source branch of a pull request can't be changed.
+	input.SourceBranch = ""
+
+	var pr PullRequest
+	if err := c.send(ctx, "PUT", pullRequestPath(repo, id), nil, &input, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// DeclinePullRequest declines the pull request with the given ID.
+func (c *Client) DeclinePullRequest(ctx context.Context, repo *Repo, id int64) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.send(ctx, "POST", pullRequestPath(repo, id)+"/decline", nil, nil, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// MergePullRequest merges the pull request with the given ID, squashing its
+// commits if squash is true. If Bitbucket Cloud refuses to merge the pull
+// request, an error wrapping ErrNotMergeable is returned.
+func (c *Client) MergePullRequest(ctx context.Context, repo *Repo, id int64, squash bool) (*PullRequest, error) {
+	strategy := "merge_commit"
+	if squash {
+		strategy = "squash"
+	}
+
+	var pr PullRequest
+	err := c.send(ctx, "POST", pullRequestPath(repo, id)+"/merge", nil, map[string]string{"merge_strategy": strategy}, &pr)
+	if err != nil {
+		var e *httpError
+		if errors.As(err, &e) && (e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusConflict) {
+			return nil, errors.Wrap(ErrNotMergeable, strings.TrimSpace(string(e.Body)))
+		}
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// CreatePullRequestComment posts a comment with the given Markdown text on the
+// pull request with the given ID.
+func (c *Client) CreatePullRequestComment(ctx context.Context, repo *Repo, id int64, text string) error {
+	payload := map[string]interface{}{
+		"content": map[string]string{"raw": text},
+	}
+	return c.send(ctx, "POST", pullRequestPath(repo, id)+"/comments", nil, payload, nil)
+}
+
+// GetPullRequestStatuses returns all build statuses reported for the commits
+// of the pull request with the given ID.
+func (c *Client) GetPullRequestStatuses(ctx context.Context, repo *Repo, id int64) ([]*BuildStatus, error) {
+	var all []*BuildStatus
+
+	var page []*BuildStatus
+	next, err := c.page(ctx, pullRequestPath(repo, id)+"/statuses", nil, &PageToken{Pagelen: 100}, &page)
+	for {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if !next.HasMore() {
+			return all, nil
+		}
+		page = nil
+		next, err = c.reqPage(ctx, next.Next, &page)
+	}
+}
+
+func pullRequestsPath(repo *Repo) string {
+	return fmt.Sprintf("/2.0/repositories/%s/pullrequests", repo.FullName)
+}
+
+func pullRequestPath(repo *Repo, id int64) string {
+	return fmt.Sprintf("%s/%d", pullRequestsPath(repo), id)
+}
@@ -0,0 +1,179 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newPullRequestTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(u, http.DefaultClient)
+	cli.Username = "sourcegraph"
+	cli.AppPassword = "secret"
+	return cli
+}
+
+func TestClient_CreatePullRequest(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+
+	cli := newPullRequestTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.Method+" "+r.URL.Path, "POST /2.0/repositories/sglocal/mux/pullrequests"; have != want {
+			t.Fatalf("unexpected request. want=%q, have=%q", want, have)
+		}
+		if user, pass, _ := r.BasicAuth(); user != "sourcegraph" || pass != "secret" {
+			t.Fatalf("unexpected credentials %q:%q", user, pass)
+		}
+
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{
+			"title":       "Fix the build",
+			"description": "It's broken",
+			"source":      map[string]interface{}{"branch": map[string]interface{}{"name": "fix-build"}},
+			"destination": map[string]interface{}{"branch": map[string]interface{}{"name": "main"}},
+		}
+		if diff := cmp.Diff(want, payload); diff != "" {
+			t.Fatalf("unexpected payload (-want +have):\n%s", diff)
+		}
+
+		io.WriteString(w, `{"id": 7, "title": "Fix the build", "state": "OPEN", "source": {"branch": {"name": "fix-build"}, "commit": {"hash": "ffad59b0cbd5"}}}`)
+	})
+
+	pr, err := cli.CreatePullRequest(context.Background(), repo, PullRequestInput{
+		Title:             "Fix the build",
+		Description:       "It's broken",
+		SourceBranch:      "fix-build",
+		DestinationBranch: "main",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.ID != 7 || pr.State != PullRequestStateOpen || pr.Source.Commit.Hash != "ffad59b0cbd5" {
+		t.Fatalf("unexpected pull request %+v", pr)
+	}
+}
+
+func TestClient_FindOpenPullRequest(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+
+	for name, tc := range map[string]struct {
+		response string
+		wantID   int64
+	}{
+		"found":     {response: `{"values": [{"id": 7}]}`, wantID: 7},
+		"not found": {response: `{"values": []}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cli := newPullRequestTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				want := `source.branch.name = "fix-build" AND destination.branch.name = "main" AND state = "OPEN"`
+				if have := r.URL.Query().Get("q"); have != want {
+					t.Fatalf("unexpected query. want=%q, have=%q", want, have)
+				}
+				io.WriteString(w, tc.response)
+			})
+
+			pr, err := cli.FindOpenPullRequest(context.Background(), repo, "fix-build", "main")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.wantID == 0 {
+				if pr != nil {
+					t.Fatalf("unexpected pull request %+v", pr)
+				}
+				return
+			}
+			if pr == nil || pr.ID != tc.wantID {
+				t.Fatalf("unexpected pull request %+v", pr)
+			}
+		})
+	}
+}
+
+func TestClient_MergePullRequest(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+
+	t.Run("squash", func(t *testing.T) {
+		cli := newPullRequestTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if have, want := r.URL.Path, "/2.0/repositories/sglocal/mux/pullrequests/7/merge"; have != want {
+				t.Fatalf("unexpected path. want=%q, have=%q", want, have)
+			}
+			var payload map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatal(err)
+			}
+			if have, want := payload["merge_strategy"], "squash"; have != want {
+				t.Fatalf("unexpected merge strategy. want=%q, have=%q", want, have)
+			}
+			io.WriteString(w, `{"id": 7, "state": "MERGED"}`)
+		})
+
+		pr, err := cli.MergePullRequest(context.Background(), repo, 7, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pr.State != PullRequestStateMerged {
+			t.Fatalf("unexpected state %q", pr.State)
+		}
+	})
+
+	t.Run("not mergeable", func(t *testing.T) {
+		cli := newPullRequestTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"type": "error", "error": {"message": "You can't merge until you resolve all merge conflicts."}}`)
+		})
+
+		_, err := cli.MergePullRequest(context.Background(), repo, 7, false)
+		if !errors.Is(err, ErrNotMergeable) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestClient_GetPullRequestStatuses(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+
+	var srvURL string
+	cli := newPullRequestTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			io.WriteString(w, `{"values": [{"key": "lint", "state": "FAILED"}]}`)
+			return
+		}
+		io.WriteString(w, `{"values": [{"key": "build", "state": "SUCCESSFUL", "links": {"commit": {"href": "https://api.bitbucket.org/2.0/repositories/sglocal/mux/commit/ffad59b0cbd5e5b5fda3bc7ba1bcc4ddb1a4cc8a"}}}], "next": "`+srvURL+`/2.0/repositories/sglocal/mux/pullrequests/7/statuses?page=2"}`)
+	})
+	srvURL = cli.URL.String()
+
+	statuses, err := cli.GetPullRequestStatuses(context.Background(), repo, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 {
+		t.Fatalf("wrong number of statuses: %d", len(statuses))
+	}
+	if have, want := statuses[0].CommitHash(), "ffad59b0cbd5e5b5fda3bc7ba1bcc4ddb1a4cc8a"; have != want {
+		t.Fatalf("wrong commit hash. want=%q, have=%q", want, have)
+	}
+	if have, want := statuses[1].State, BuildStatusStateFailed; have != want {
+		t.Fatalf("wrong state. want=%q, have=%q", want, have)
+	}
+}
@@ -0,0 +1,47 @@
+package gerrit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// changeIDTrailer is the trailer of commit messages that identifies the change
+// a commit belongs to.
+const changeIDTrailer = "Change-Id: "
+
+// NewChangeID returns a Change-Id derived from the given parts, so that the
+// commits pushed for the same parts end up as patch sets of the same change.
+func NewChangeID(parts ...string) string {
+	h := sha1.New()
+	h.Write([]byte(strings.Join(parts, "\x00")))
+	return "I" + hex.EncodeToString(h.Sum(nil))
+}
+
+// WithChangeID appends a Change-Id trailer with the given Change-Id to the
+// given commit message, unless the message already has one.
+func WithChangeID(message, changeID string) string {
+	message = strings.TrimRight(message, "\n")
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, changeIDTrailer) {
+			return message + "\n"
+		}
+	}
+
+	separator := "\n\n"
+	if message == "" {
+		separator = ""
+	}
+	return message + separator + changeIDTrailer + changeID + "\n"
+}
+
+// ChangeRef returns the ref commits are pushed to in order to create changes
+// targeting the given branch, or to push new patch sets to them. If topic is
+// non-empty, the topic of the changes is set to it.
+func ChangeRef(branch, topic string) string {
+	ref := "refs/for/" + strings.TrimPrefix(branch, "refs/heads/")
+	if topic != "" {
+		ref += "%topic=" + topic
+	}
+	return ref
+}
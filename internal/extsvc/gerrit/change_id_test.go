@@ -0,0 +1,55 @@
+package gerrit
+
+import "testing"
+
+func TestWithChangeID(t *testing.T) {
+	for name, tc := range map[string]struct {
+		message string
+		want    string
+	}{
+		"subject only": {
+			message: "Fix the build",
+			want:    "Fix the build\n\nChange-Id: Iabc\n",
+		},
+		"with body": {
+			message: "Fix the build\n\nThe build was broken.\n",
+			want:    "Fix the build\n\nThe build was broken.\n\nChange-Id: Iabc\n",
+		},
+		"existing Change-Id": {
+			message: "Fix the build\n\nChange-Id: Idef\n",
+			want:    "Fix the build\n\nChange-Id: Idef\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if have := WithChangeID(tc.message, "Iabc"); have != tc.want {
+				t.Fatalf("wrong message. want=%q, have=%q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestNewChangeID(t *testing.T) {
+	id := NewChangeID("platform/build", "refs/heads/my-branch")
+	if len(id) != 41 || id[0] != 'I' {
+		t.Fatalf("invalid Change-Id %q", id)
+	}
+	if other := NewChangeID("platform/build", "refs/heads/other-branch"); other == id {
+		t.Fatal("expected different Change-Ids for different branches")
+	}
+	if again := NewChangeID("platform/build", "refs/heads/my-branch"); again != id {
+		t.Fatalf("expected the same Change-Id, got %q and %q", id, again)
+	}
+}
+
+func TestChangeBody(t *testing.T) {
+	ch := &Change{
+		Subject:         "Fix the build",
+		CurrentRevision: "abc",
+		Revisions: map[string]Revision{
+			"abc": {Commit: &Commit{Message: "Fix the build\n\nThe build was broken.\n\nChange-Id: Iabc\n"}},
+		},
+	}
+	if have, want := ch.Body(), "The build was broken."; have != want {
+		t.Fatalf("wrong body. want=%q, have=%q", want, have)
+	}
+}
@@ -0,0 +1,388 @@
+package gerrit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var requestCounter = metrics.NewRequestMeter("gerrit_requests_count", "Total number of requests sent to the Gerrit API.")
+
+// These fields define the self-imposed Gerrit rate limit, since Gerrit doesn't
+// report rate limits in HTTP response headers.
+//
+// See https://godoc.org/golang.org/x/time/rate#Limiter for an explanation of these fields.
+const (
+	rateLimitRequestsPerSecond = 2 // 120/min or 7200/hr
+	rateLimitMaxBurstRequests  = 500
+)
+
+// xssiPrefix is prepended by Gerrit to all JSON responses to prevent XSSI
+// attacks. It has to be stripped before decoding the response.
+const xssiPrefix = ")]}'"
+
+// changeOptions are the additional fields requested for changes. See
+// https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#query-options.
+var changeOptions = []string{"CURRENT_REVISION", "CURRENT_COMMIT", "LABELS", "DETAILED_ACCOUNTS", "SUBMITTABLE"}
+
+// ErrChangeNotFound is returned when a change can't be found.
+var ErrChangeNotFound = errors.New("change not found")
+
+// ErrNotMergeable is returned by SubmitChange when the change can't be
+// submitted, for example because it's missing approvals.
+var ErrNotMergeable = errors.New("change cannot be submitted")
+
+// Client accesses a Gerrit instance via its REST API.
+type Client struct {
+	// HTTP Client used to communicate with the API
+	httpClient httpcli.Doer
+
+	// URL is the base URL of the Gerrit instance.
+	URL *url.URL
+
+	// Auth is the authentication method used when accessing the server. Gerrit
+	// expects the username and HTTP password of a user, so only auth.BasicAuth
+	// and auth.BasicAuthWithSSH are supported.
+	Auth auth.Authenticator
+
+	// RateLimit is the self-imposed rate limiter (since Gerrit does not have a concept
+	// of rate limiting in HTTP response headers).
+	RateLimit *rate.Limiter
+}
+
+// NewClient returns an authenticated Gerrit API client with the provided
+// configuration. If a nil httpClient is provided, httpcli.ExternalDoer will be
+// used.
+func NewClient(config *schema.GerritConnection, httpClient httpcli.Doer) (*Client, error) {
+	u, err := url.Parse(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	u = extsvc.NormalizeBaseURL(u)
+
+	if httpClient == nil {
+		httpClient = httpcli.ExternalDoer
+	}
+	httpClient = requestCounter.Doer(httpClient, categorize)
+
+	// Normally our registry will return a default infinite limiter when nothing has been
+	// synced from config. However, we always want to ensure there is at least some form of rate
+	// limiting for Gerrit.
+	defaultLimiter := rate.NewLimiter(rateLimitRequestsPerSecond, rateLimitMaxBurstRequests)
+	l := ratelimit.DefaultRegistry.GetOrSet(u.String(), defaultLimiter)
+
+	return &Client{
+		httpClient: httpClient,
+		URL:        u,
+		Auth:       &auth.BasicAuth{Username: config.Username, Password: config.Password},
+		RateLimit:  l,
+	}, nil
+}
+
+// WithAuthenticator returns a new Client that uses the same configuration,
+// HTTPClient, and RateLimiter as the current Client, except authenticated user
+// with the given authenticator instance.
+func (c *Client) WithAuthenticator(a auth.Authenticator) *Client {
+	return &Client{
+		httpClient: c.httpClient,
+		URL:        c.URL,
+		RateLimit:  c.RateLimit,
+		Auth:       a,
+	}
+}
+
+// AuthenticatedUsername returns the username of the account the client is
+// authenticated as.
+func (c *Client) AuthenticatedUsername(ctx context.Context) (string, error) {
+	var account Account
+	if err := c.send(ctx, "GET", "a/accounts/self", nil, nil, &account); err != nil {
+		return "", err
+	}
+	return account.Username, nil
+}
+
+// ListProjects returns the active projects on the Gerrit instance, sorted by
+// name. At most limit projects are returned, starting after the given number of
+// projects. The returned boolean is true if there are more projects to list.
+func (c *Client) ListProjects(ctx context.Context, limit, skip int) ([]*Project, bool, error) {
+	qry := url.Values{
+		"n": []string{strconv.Itoa(limit)},
+		"S": []string{strconv.Itoa(skip)},
+		"s": []string{"ACTIVE"},
+	}
+
+	var byName map[string]*Project
+	if err := c.send(ctx, "GET", "a/projects/", qry, nil, &byName); err != nil {
+		return nil, false, err
+	}
+
+	projects := make([]*Project, 0, len(byName))
+	more := false
+	for name, p := range byName {
+		// Projects are keyed by name, and the name is omitted from the values.
+		p.Name = name
+		more = more || p.MoreProjects
+		p.MoreProjects = false
+		projects = append(projects, p)
+	}
+	sortProjects(projects)
+
+	return projects, more, nil
+}
+
+// GetProject returns the project with the given name.
+func (c *Client) GetProject(ctx context.Context, name string) (*Project, error) {
+	var p Project
+	if err := c.send(ctx, "GET", "a/projects/"+url.PathEscape(name), nil, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetChange returns the change with the given number in the given project. If
+// the change doesn't exist, ErrChangeNotFound is returned.
+func (c *Client) GetChange(ctx context.Context, project string, number int) (*Change, error) {
+	var ch Change
+	if err := c.send(ctx, "GET", changePath(project, number), changeQuery(), nil, &ch); err != nil {
+		if IsNotFound(err) {
+			return nil, ErrChangeNotFound
+		}
+		return nil, err
+	}
+	c.setURL(&ch)
+	return &ch, nil
+}
+
+// FindChange returns the change with the given Change-Id targeting the given
+// branch of the given project. If no such change exists, ErrChangeNotFound is
+// returned.
+func (c *Client) FindChange(ctx context.Context, project, branch, changeID string) (*Change, error) {
+	qry := changeQuery()
+	qry.Set("q", fmt.Sprintf("project:%q branch:%q change:%s", project, branch, changeID))
+
+	var changes []*Change
+	if err := c.send(ctx, "GET", "a/changes/", qry, nil, &changes); err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, ErrChangeNotFound
+	}
+	c.setURL(changes[0])
+	return changes[0], nil
+}
+
+// AbandonChange abandons the given change and updates it in place.
+func (c *Client) AbandonChange(ctx context.Context, ch *Change) error {
+	return c.changeAction(ctx, ch, "abandon", nil)
+}
+
+// RestoreChange restores the given abandoned change and updates it in place.
+func (c *Client) RestoreChange(ctx context.Context, ch *Change) error {
+	return c.changeAction(ctx, ch, "restore", nil)
+}
+
+// SetWorkInProgress marks the given change as work in progress and updates it
+// in place.
+func (c *Client) SetWorkInProgress(ctx context.Context, ch *Change) error {
+	return c.changeAction(ctx, ch, "wip", nil)
+}
+
+// SetReadyForReview marks the given change as ready for review and updates it
+// in place.
+func (c *Client) SetReadyForReview(ctx context.Context, ch *Change) error {
+	return c.changeAction(ctx, ch, "ready", nil)
+}
+
+// MoveChange moves the given change to the given destination branch and
+// updates it in place.
+func (c *Client) MoveChange(ctx context.Context, ch *Change, branch string) error {
+	return c.changeAction(ctx, ch, "move", map[string]string{"destination_branch": branch})
+}
+
+// SubmitChange submits the given change and updates it in place. If the change
+// can't be submitted, an error wrapping ErrNotMergeable is returned.
+func (c *Client) SubmitChange(ctx context.Context, ch *Change) error {
+	err := c.changeAction(ctx, ch, "submit", nil)
+	var e *httpError
+	if errors.As(err, &e) && e.StatusCode == http.StatusConflict {
+		return errors.Wrap(ErrNotMergeable, string(e.Body))
+	}
+	return err
+}
+
+// PostReviewComment posts the given message as a review comment on the current
+// revision of the given change.
+func (c *Client) PostReviewComment(ctx context.Context, ch *Change, message string) error {
+	path := changePath(ch.Project, ch.Number) + "/revisions/current/review"
+	return c.send(ctx, "POST", path, nil, map[string]string{"message": message}, nil)
+}
+
+// changeAction runs the given action on the change and then reloads the
+// change, since the responses of actions don't include the fields requested
+// by changeOptions.
+func (c *Client) changeAction(ctx context.Context, ch *Change, action string, input interface{}) error {
+	if err := c.send(ctx, "POST", changePath(ch.Project, ch.Number)+"/"+action, nil, input, nil); err != nil {
+		return err
+	}
+
+	updated, err := c.GetChange(ctx, ch.Project, ch.Number)
+	if err != nil {
+		return err
+	}
+	*ch = *updated
+	return nil
+}
+
+// setURL sets the URL of the change in the web UI, which has the form
+// <base URL>/c/<project>/+/<number>.
+func (c *Client) setURL(ch *Change) {
+	u := *c.URL
+	u.Path += "c/" + ch.Project + "/+/" + strconv.Itoa(ch.Number)
+	ch.URL = u.String()
+}
+
+func changePath(project string, number int) string {
+	return "a/changes/" + url.PathEscape(project) + "~" + strconv.Itoa(number)
+}
+
+func changeQuery() url.Values {
+	return url.Values{"o": changeOptions}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, qry url.Values, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		bs, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bs)
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = qry.Encode()
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, req, result)
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+
+	req, ht := nethttp.TraceRequest(ot.GetTracer(ctx),
+		req.WithContext(ctx),
+		nethttp.OperationName("Gerrit"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	if err := c.Auth.Authenticate(req); err != nil {
+		return err
+	}
+
+	startWait := time.Now()
+	if err := c.RateLimit.Wait(ctx); err != nil {
+		return err
+	}
+
+	if d := time.Since(startWait); d > 200*time.Millisecond {
+		log15.Warn("Gerrit self-enforced API rate limit: request delayed longer than expected due to rate limit", "delay", d)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return errors.WithStack(&httpError{
+			URL:        req.URL,
+			StatusCode: resp.StatusCode,
+			Body:       bs,
+		})
+	}
+
+	if result != nil {
+		return json.Unmarshal(bytes.TrimPrefix(bs, []byte(xssiPrefix)), result)
+	}
+
+	return nil
+}
+
+// categorize returns a category for an API URL. Used by metrics.
+func categorize(u *url.URL) string {
+	// API paths look like this:
+	//
+	// 	a/projects/...
+	// 	a/changes/...
+	// 	a/accounts/...
+	//
+	// The path might also be prefixed with the path of the Gerrit instance, so
+	// we use the component following the "a" component.
+	parts := strings.Split(u.Path, "/")
+	for i, p := range parts {
+		if p == "a" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+	Body       []byte
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("Gerrit API HTTP error: code=%d url=%q body=%q", e.StatusCode, e.URL, e.Body)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsNotFound reports whether err is a Gerrit API not found error.
+func IsNotFound(err error) bool {
+	var e *httpError
+	return errors.As(err, &e) && e.NotFound()
+}
@@ -0,0 +1,182 @@
+package gerrit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/auth"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// newTestClient returns a client talking to a fake Gerrit instance served by
+// the given handler, which has to serve the API below /gerrit/.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	cli, err := NewClient(&schema.GerritConnection{
+		Url:      srv.URL + "/gerrit",
+		Username: "admin",
+		Password: "secret",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+// writeJSON writes the given value like Gerrit does, prefixed to prevent XSSI.
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	bs, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, xssiPrefix+"\n")
+	w.Write(bs)
+}
+
+func TestClient_ListProjects(t *testing.T) {
+	cli := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gerrit/a/projects/" {
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+		if have, want := r.URL.Query().Get("S"), "2"; have != want {
+			t.Fatalf("wrong skip. want=%q, have=%q", want, have)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"platform/tools": map[string]interface{}{"id": "platform%2Ftools", "state": "ACTIVE", "_more_projects": true},
+			"platform/build": map[string]interface{}{"id": "platform%2Fbuild", "state": "ACTIVE"},
+		})
+	})
+
+	projects, more, err := cli.ListProjects(context.Background(), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !more {
+		t.Fatal("expected more projects")
+	}
+
+	want := []*Project{
+		{ID: "platform%2Fbuild", Name: "platform/build", State: "ACTIVE"},
+		{ID: "platform%2Ftools", Name: "platform/tools", State: "ACTIVE"},
+	}
+	if diff := cmp.Diff(want, projects); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestClient_GetChange(t *testing.T) {
+	cli := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/gerrit/a/changes/platform%2Fbuild~42":
+			writeJSON(t, w, map[string]interface{}{
+				"id":               "platform%2Fbuild~main~Iabc",
+				"project":          "platform/build",
+				"branch":           "main",
+				"change_id":        "Iabc",
+				"subject":          "Fix the build",
+				"status":           "NEW",
+				"created":          "2021-09-28 10:31:52.000000000",
+				"updated":          "2021-09-28 12:00:00.000000000",
+				"_number":          42,
+				"work_in_progress": true,
+				"labels": map[string]interface{}{
+					"Code-Review": map[string]interface{}{"approved": map[string]interface{}{"_account_id": 1000000}},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ch, err := cli.GetChange(context.Background(), "platform/build", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Change{
+		ID:             "platform%2Fbuild~main~Iabc",
+		Project:        "platform/build",
+		Branch:         "main",
+		ChangeID:       "Iabc",
+		Subject:        "Fix the build",
+		Status:         ChangeStatusNew,
+		Created:        Timestamp{time.Date(2021, 9, 28, 10, 31, 52, 0, time.UTC)},
+		Updated:        Timestamp{time.Date(2021, 9, 28, 12, 0, 0, 0, time.UTC)},
+		Number:         42,
+		WorkInProgress: true,
+		Labels: map[string]Label{
+			"Code-Review": {Approved: &Account{AccountID: 1000000}},
+		},
+		URL: cli.URL.String() + "c/platform/build/+/42",
+	}
+	compareTimestamps := cmp.Comparer(func(a, b Timestamp) bool { return a.Equal(b.Time) })
+	if diff := cmp.Diff(want, ch, compareTimestamps); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// The metadata of changesets is stored as JSON, so timestamps have to
+	// survive a roundtrip.
+	bs, err := json.Marshal(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundtripped Change
+	if err := json.Unmarshal(bs, &roundtripped); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, &roundtripped, compareTimestamps); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if _, err := cli.GetChange(context.Background(), "platform/build", 43); err != ErrChangeNotFound {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestClient_SubmitChange(t *testing.T) {
+	cli := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.EscapedPath() != "/gerrit/a/changes/platform%2Fbuild~42/submit" {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, "change 42: needs Code-Review")
+	})
+
+	err := cli.SubmitChange(context.Background(), &Change{Project: "platform/build", Number: 42})
+	if !errors.Is(err, ErrNotMergeable) {
+		t.Fatalf("expected ErrNotMergeable, got %v", err)
+	}
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	cli := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected request")
+	})
+	cli = cli.WithAuthenticator(&auth.BasicAuth{Username: "admin", Password: "wrong"})
+
+	_, err := cli.AuthenticatedUsername(context.Background())
+	var e *httpError
+	if !errors.As(err, &e) || !e.Unauthorized() {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package gerrit
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Project is a Gerrit project, which is backed by a Git repository. See
+// https://gerrit-review.googlesource.com/Documentation/rest-api-projects.html#project-info.
+type Project struct {
+	// ID is the URL encoded name of the project.
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Parent      string `json:"parent,omitempty"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state,omitempty"`
+
+	// MoreProjects is set on the last project of a page of projects if there
+	// are more projects to list.
+	MoreProjects bool `json:"_more_projects,omitempty"`
+}
+
+func sortProjects(ps []*Project) {
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+}
+
+// ChangeStatus is the status of a change.
+type ChangeStatus string
+
+const (
+	ChangeStatusNew       ChangeStatus = "NEW"
+	ChangeStatusMerged    ChangeStatus = "MERGED"
+	ChangeStatusAbandoned ChangeStatus = "ABANDONED"
+)
+
+// Change is a Gerrit change, the equivalent of a pull request. See
+// https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#change-info.
+type Change struct {
+	ID             string       `json:"id"`
+	Project        string       `json:"project"`
+	Branch         string       `json:"branch"`
+	Topic          string       `json:"topic,omitempty"`
+	ChangeID       string       `json:"change_id"`
+	Subject        string       `json:"subject"`
+	Status         ChangeStatus `json:"status"`
+	Created        Timestamp    `json:"created"`
+	Updated        Timestamp    `json:"updated"`
+	WorkInProgress bool         `json:"work_in_progress,omitempty"`
+	Submittable    bool         `json:"submittable,omitempty"`
+	Number         int          `json:"_number"`
+	Owner          Account      `json:"owner"`
+
+	// Labels maps the names of the labels of the change, such as "Code-Review"
+	// and "Verified", to their votes.
+	Labels map[string]Label `json:"labels,omitempty"`
+
+	// CurrentRevision is the commit ID of the current patch set of the change.
+	CurrentRevision string              `json:"current_revision,omitempty"`
+	Revisions       map[string]Revision `json:"revisions,omitempty"`
+
+	// URL is the URL of the change in the Gerrit web UI. It's not part of the
+	// Gerrit API and set by the Client.
+	URL string `json:"url,omitempty"`
+}
+
+// CurrentCommit returns the commit of the current patch set of the change, if
+// it was requested.
+func (c *Change) CurrentCommit() *Commit {
+	if r, ok := c.Revisions[c.CurrentRevision]; ok {
+		return r.Commit
+	}
+	return nil
+}
+
+// Message returns the commit message of the current patch set of the change,
+// falling back to the subject of the change.
+func (c *Change) Message() string {
+	if commit := c.CurrentCommit(); commit != nil && commit.Message != "" {
+		return commit.Message
+	}
+	return c.Subject
+}
+
+// Body returns the commit message of the current patch set of the change
+// without its subject line and Change-Id trailer.
+func (c *Change) Body() string {
+	msg := c.Message()
+	i := strings.Index(msg, "\n")
+	if i < 0 {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSpace(msg[i+1:]), "\n")
+	if n := len(lines); n > 0 && strings.HasPrefix(lines[n-1], changeIDTrailer) {
+		lines = lines[:n-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Account is a Gerrit user account.
+type Account struct {
+	AccountID int    `json:"_account_id"`
+	Name      string `json:"name,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Label is a review label of a change, summarizing the votes on it. See
+// https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#label-info.
+type Label struct {
+	// Approved is set to one of the users who voted the maximum value.
+	Approved *Account `json:"approved,omitempty"`
+	// Rejected is set to one of the users who voted the minimum value.
+	Rejected *Account `json:"rejected,omitempty"`
+	// Recommended is set to one of the users who voted a positive value
+	// below the maximum.
+	Recommended *Account `json:"recommended,omitempty"`
+	// Disliked is set to one of the users who voted a negative value above
+	// the minimum.
+	Disliked *Account `json:"disliked,omitempty"`
+	// Blocking is true if the label blocks the change from being submitted.
+	Blocking bool `json:"blocking,omitempty"`
+	// Optional is true if the label isn't required to submit the change.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Revision is a patch set of a change.
+type Revision struct {
+	Number int     `json:"_number"`
+	Ref    string  `json:"ref"`
+	Commit *Commit `json:"commit,omitempty"`
+}
+
+// Commit is the commit of a patch set.
+type Commit struct {
+	// Commit is only set on the parents of a commit.
+	Commit  string    `json:"commit,omitempty"`
+	Parents []*Commit `json:"parents,omitempty"`
+	Author  Person    `json:"author"`
+	Subject string    `json:"subject"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Person is the author or committer of a commit.
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// timestampLayout is the layout of timestamps in the Gerrit API, which are
+// always in UTC.
+const timestampLayout = "2006-01-02 15:04:05.000000000"
+
+// Timestamp is a timestamp in the format used by the Gerrit API.
+type Timestamp struct {
+	time.Time
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.UTC().Format(timestampLayout) + `"`), nil
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	parsed, err := time.Parse(timestampLayout, s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
@@ -108,6 +108,18 @@ func TestExtractRateLimitConfig(t *testing.T) {
 				IsDefault:   true,
 			},
 		},
+		{
+			name:        "Gerrit default",
+			config:      `{"url": "https://example.com/"}`,
+			kind:        KindGerrit,
+			displayName: "Gerrit 1",
+			want: RateLimitConfig{
+				BaseURL:     "https://example.com/",
+				DisplayName: "Gerrit 1",
+				Limit:       2.0,
+				IsDefault:   true,
+			},
+		},
 		{
 			name:        "GitLab non-default",
 			config:      `{"url": "https://example.com/", "rateLimit": {"enabled": true, "requestsPerHour": 3600}}`,
@@ -304,6 +316,12 @@ func TestUniqueCodeHostIdentifier(t *testing.T) {
 			want:   "https://bitbucket.org/",
 		},
 
+		{
+			kind:   KindGerrit,
+			config: `{"url": "https://gerrit.sgdev.org"}`,
+			want:   "https://gerrit.sgdev.org/",
+		},
+
 		{
 			kind:   KindGitolite,
 			config: `{"host": "ssh://git@gitolite.example.com:2222/"}`,
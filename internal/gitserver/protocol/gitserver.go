@@ -280,6 +280,12 @@ type PushConfig struct {
 	// Passphrase is the passphrase to decrypt the private key. It is required
 	// when passing PrivateKey.
 	Passphrase string
+
+	// RemoteRef is the ref on the remote the commit is pushed to, if it differs
+	// from the TargetRef of the request. Code hosts such as Gerrit expect
+	// commits to be pushed to a special ref instead of a branch. The commit is
+	// still available at TargetRef in the local repository.
+	RemoteRef string
 }
 
 // CreateCommitFromPatchResponse is the response type returned after creating
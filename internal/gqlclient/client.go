@@ -0,0 +1,181 @@
+// Package gqlclient is a client of the Sourcegraph GraphQL API for internal callers, such as
+// background workers that query the API on behalf of users or of the instance.
+package gqlclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+// Request is a GraphQL request.
+type Request struct {
+	// Name is the name of the request. It is sent as the query string of the request URL, which
+	// is used to keep track of the source and type of GraphQL requests.
+	Name string
+
+	// Query is the GraphQL query, and Variables its variables.
+	Query     string
+	Variables interface{}
+}
+
+// Client executes GraphQL requests.
+type Client struct {
+	// URL is the URL of the GraphQL endpoint, e.g. https://sourcegraph.example.com/.api/graphql.
+	URL string
+
+	// Token, if set, is the access token that requests are authenticated with.
+	Token string
+
+	// UserAgent, if set, is the User-Agent header of requests.
+	UserAgent string
+
+	// Doer performs the HTTP requests. It defaults to httpcli.ExternalDoer.
+	Doer httpcli.Doer
+}
+
+// NewInternal returns a client of the internal GraphQL endpoint of the frontend, which does not
+// require authentication.
+func NewInternal(userAgent string) (*Client, error) {
+	u, err := url.Parse(api.InternalClient.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing frontend URL")
+	}
+	u.Path = "/.internal/graphql"
+	return &Client{URL: u.String(), UserAgent: userAgent, Doer: httpcli.InternalDoer}, nil
+}
+
+// ErrUnauthorized is returned when the GraphQL API rejected the credentials of a request.
+var ErrUnauthorized = errors.New("GraphQL API rejected the credentials")
+
+// TransientError wraps errors of requests that may succeed when retried, such as network errors,
+// timeouts and server errors.
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// IsTransient reports whether the request that returned err may succeed when retried.
+func IsTransient(err error) bool {
+	var e *TransientError
+	return errors.As(err, &e)
+}
+
+// QueryError is returned when the GraphQL API returned errors for a request, e.g. because its query
+// is invalid. Retrying the request won't help.
+type QueryError struct {
+	Errors []ResponseError
+}
+
+// ResponseError is an error in the response of a GraphQL request.
+type ResponseError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *QueryError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Message)
+	}
+	return "graphql: errors: " + strings.Join(messages, "; ")
+}
+
+type graphQLQuery struct {
+	Query     string      `json:"query"`
+	Variables interface{} `json:"variables"`
+}
+
+// Do executes the given request, and decodes the data of its response into data, which should be
+// a pointer to a struct with the fields selected by the query.
+//
+// Errors that may go away when the request is retried are wrapped in a *TransientError, requests
+// whose credentials were rejected return an error wrapping ErrUnauthorized, and requests that the
+// GraphQL API returned errors for return a *QueryError.
+func (c *Client) Do(ctx context.Context, r Request, data interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(graphQLQuery{Query: r.Query, Variables: r.Variables}); err != nil {
+		return errors.Wrap(err, "Encode")
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return errors.Wrap(err, "constructing GraphQL API URL")
+	}
+	u.RawQuery = r.Name
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return errors.Wrap(err, "Post")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	req, ht := nethttp.TraceRequest(ot.GetTracer(ctx), req.WithContext(ctx),
+		nethttp.OperationName("GraphQL: "+r.Name),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	doer := c.Doer
+	if doer == nil {
+		doer = httpcli.ExternalDoer
+	}
+	resp, err := doer.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrap(err, "Post")
+		}
+		return &TransientError{errors.Wrap(err, "Post")}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return errors.Wrapf(ErrUnauthorized, "status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := errors.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return &TransientError{err}
+		}
+		return err
+	}
+
+	var res struct {
+		Data   json.RawMessage
+		Errors []ResponseError
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrap(err, "Decode")
+		}
+		return &TransientError{errors.Wrap(err, "Decode")}
+	}
+	if len(res.Errors) > 0 {
+		return &QueryError{Errors: res.Errors}
+	}
+	if data != nil && len(res.Data) > 0 {
+		if err := json.Unmarshal(res.Data, data); err != nil {
+			return errors.Wrap(err, "Decode data")
+		}
+	}
+	return nil
+}
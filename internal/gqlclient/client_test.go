@@ -0,0 +1,102 @@
+package gqlclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestClientDo(t *testing.T) {
+	var (
+		gotQuery         string
+		gotAuthorization string
+		gotUserAgent     string
+		gotBody          graphQLQuery
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotAuthorization = r.Header.Get("Authorization")
+		gotUserAgent = r.Header.Get("User-Agent")
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `{"data":{"currentUser":{"username":"alice"}}}`)
+	}))
+	defer srv.Close()
+
+	client := &Client{URL: srv.URL + "/.api/graphql", Token: "t", UserAgent: "test", Doer: srv.Client()}
+	var data struct {
+		CurrentUser struct {
+			Username string
+		}
+	}
+	err := client.Do(context.Background(), Request{
+		Name:      "CurrentUser",
+		Query:     `query CurrentUser($x: Int) { currentUser { username } }`,
+		Variables: map[string]int{"x": 1},
+	}, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.CurrentUser.Username != "alice" {
+		t.Errorf("unexpected data %+v", data)
+	}
+	if gotQuery != "CurrentUser" || gotAuthorization != "token t" || gotUserAgent != "test" {
+		t.Errorf("unexpected request: query=%q authorization=%q user-agent=%q", gotQuery, gotAuthorization, gotUserAgent)
+	}
+	if gotBody.Query == "" || gotBody.Variables == nil {
+		t.Errorf("unexpected request body %+v", gotBody)
+	}
+}
+
+func TestClientDoErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		status        int
+		body          string
+		unauthorized  bool
+		transient     bool
+		queryErrorMsg string
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, unauthorized: true},
+		{name: "forbidden", status: http.StatusForbidden, unauthorized: true},
+		{name: "server error", status: http.StatusBadGateway, transient: true},
+		{name: "rate limited", status: http.StatusTooManyRequests, transient: true},
+		{name: "bad request", status: http.StatusBadRequest},
+		{name: "truncated response", status: http.StatusOK, body: `{"data":`, transient: true},
+		{name: "query error", status: http.StatusOK, body: `{"errors":[{"message":"a"},{"message":"b"}]}`, queryErrorMsg: "graphql: errors: a; b"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer srv.Close()
+
+			client := &Client{URL: srv.URL, Doer: srv.Client()}
+			err := client.Do(context.Background(), Request{Name: "Test", Query: "query Test { site { id } }"}, nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := errors.Is(err, ErrUnauthorized); got != tc.unauthorized {
+				t.Errorf("errors.Is(err, ErrUnauthorized) = %v, want %v (err: %v)", got, tc.unauthorized, err)
+			}
+			if got := IsTransient(err); got != tc.transient {
+				t.Errorf("IsTransient(err) = %v, want %v (err: %v)", got, tc.transient, err)
+			}
+			var queryErr *QueryError
+			if errors.As(err, &queryErr) {
+				if err.Error() != tc.queryErrorMsg {
+					t.Errorf("unexpected query error %q, want %q", err.Error(), tc.queryErrorMsg)
+				}
+			} else if tc.queryErrorMsg != "" {
+				t.Errorf("expected a query error, got %v", err)
+			}
+		})
+	}
+}
@@ -201,6 +201,15 @@ func NewFactory(stack Middleware, common ...Opt) *Factory {
 	return &Factory{stack: stack, common: common}
 }
 
+// WithMiddleware returns a copy of the Factory whose Doers are additionally
+// wrapped by the given Middlewares, on top of its own middleware stack.
+func (f Factory) WithMiddleware(mws ...Middleware) *Factory {
+	if f.stack != nil {
+		mws = append([]Middleware{f.stack}, mws...)
+	}
+	return &Factory{stack: NewMiddleware(mws...), common: f.common}
+}
+
 //
 // Common Middleware
 //
@@ -82,6 +82,56 @@ func TestHeadersMiddleware(t *testing.T) {
 	}
 }
 
+func TestFactoryWithMiddleware(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(cli Doer) Doer {
+			return DoerFunc(func(r *http.Request) (*http.Response, error) {
+				calls = append(calls, name)
+				return cli.Do(r)
+			})
+		}
+	}
+	bottom := func(cli *http.Client) error {
+		cli.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			calls = append(calls, "transport")
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+		return nil
+	}
+
+	base := NewFactory(record("stack"), bottom)
+	cli, err := base.WithMiddleware(record("added")).Doer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://dev/null", nil)
+	if _, err := cli.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"added", "stack", "transport"}, calls); diff != "" {
+		t.Fatalf("unexpected calls (-want +got):\n%s", diff)
+	}
+
+	// The original Factory is unchanged.
+	calls = nil
+	cli, err = base.Doer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"stack", "transport"}, calls); diff != "" {
+		t.Fatalf("unexpected calls (-want +got):\n%s", diff)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
 func TestContextErrorMiddleware(t *testing.T) {
 	cancelled, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -237,9 +237,25 @@ func (i IntegratedInsights) Insights(perms permissionAssociations) []SearchInsig
 }
 
 type TimeSeries struct {
-	Name   string
-	Stroke string
-	Query  string
+	Name        string
+	Stroke      string
+	Query       string
+	PatternType string
+
+	// GeneratedFromCaptureGroups is true if the series shows one series per value captured by the
+	// capture groups of its query, rather than the number of results.
+	GeneratedFromCaptureGroups bool
+
+	// LanguageStatsRepositories are the repositories that the series shows the number of lines of
+	// code per language of, if it is a series of language statistics rather than a search.
+	LanguageStatsRepositories []string
+
+	// RepositoryRevisions maps repository names to the branch or revision the series is computed
+	// on in that repository, instead of the default branch.
+	RepositoryRevisions map[string]string
+
+	// Team is the team whose repositories the series is restricted to, if any.
+	Team string
 }
 
 type Interval struct {
@@ -0,0 +1,173 @@
+package repos
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// gerritProjectsPerPage is the number of projects requested per page when
+// listing all projects of a Gerrit instance.
+const gerritProjectsPerPage = 100
+
+// A GerritSource yields repositories from a single Gerrit connection configured
+// in Sourcegraph via the external services configuration.
+type GerritSource struct {
+	svc     *types.ExternalService
+	config  *schema.GerritConnection
+	exclude excludeFunc
+	client  *gerrit.Client
+}
+
+// NewGerritSource returns a new GerritSource from the given external service.
+func NewGerritSource(svc *types.ExternalService, cf *httpcli.Factory) (*GerritSource, error) {
+	var c schema.GerritConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+	return newGerritSource(svc, &c, cf)
+}
+
+func newGerritSource(svc *types.ExternalService, c *schema.GerritConnection, cf *httpcli.Factory) (*GerritSource, error) {
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+
+	var eb excludeBuilder
+	for _, r := range c.Exclude {
+		eb.Exact(r.Name)
+		eb.Pattern(r.Pattern)
+	}
+	exclude, err := eb.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := gerrit.NewClient(c, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GerritSource{
+		svc:     svc,
+		config:  c,
+		exclude: exclude,
+		client:  client,
+	}, nil
+}
+
+// ListRepos returns all Gerrit projects accessible to the configured user, or
+// the configured projects if any.
+func (s GerritSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	if len(s.config.Projects) > 0 {
+		for _, name := range s.config.Projects {
+			p, err := s.client.GetProject(ctx, name)
+			if err != nil {
+				results <- SourceResult{Source: s, Err: errors.Wrapf(err, "gerrit.project: item=%q", name)}
+				continue
+			}
+			if !s.exclude(p.Name) {
+				results <- SourceResult{Source: s, Repo: s.makeRepo(p)}
+			}
+		}
+		return
+	}
+
+	for skip := 0; ; skip += gerritProjectsPerPage {
+		projects, more, err := s.client.ListProjects(ctx, gerritProjectsPerPage, skip)
+		if err != nil {
+			results <- SourceResult{Source: s, Err: errors.Wrapf(err, "gerrit.projects: skip=%d", skip)}
+			return
+		}
+
+		for _, p := range projects {
+			if !s.exclude(p.Name) {
+				results <- SourceResult{Source: s, Repo: s.makeRepo(p)}
+			}
+		}
+
+		if !more {
+			return
+		}
+	}
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s GerritSource) ExternalServices() types.ExternalServices {
+	return types.ExternalServices{s.svc}
+}
+
+func (s GerritSource) makeRepo(p *gerrit.Project) *types.Repo {
+	host := s.client.URL
+
+	urn := s.svc.URN()
+	return &types.Repo{
+		Name: reposource.GerritRepoName(
+			s.config.RepositoryPathPattern,
+			host.Hostname(),
+			p.Name,
+		),
+		URI: string(reposource.GerritRepoName(
+			"",
+			host.Hostname(),
+			p.Name,
+		)),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          p.Name,
+			ServiceType: extsvc.TypeGerrit,
+			ServiceID:   host.String(),
+		},
+		Description: p.Description,
+		Sources: map[string]*types.SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: s.remoteURL(p),
+			},
+		},
+		Metadata: p,
+	}
+}
+
+// remoteURL returns the project's Git remote URL
+//
+// note: this doesn't contain credentials, if you need to get an authenticated
+// clone url use repos.CloneURL
+func (s GerritSource) remoteURL(p *gerrit.Project) string {
+	u, err := gerritProjectURL(s.config.Url, p, false)
+	if err != nil {
+		// This should never happen, since the client was created from the URL.
+		panic(errors.Errorf("malformed Gerrit config, invalid URL: %q, error: %s", s.config.Url, err))
+	}
+	return u.String()
+}
+
+// gerritProjectURL returns the URL of the Git repository of the given project
+// on the Gerrit instance with the given URL. Gerrit serves authenticated Git
+// requests below the /a/ path.
+func gerritProjectURL(baseURL string, p *gerrit.Project, authenticated bool) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u = extsvc.NormalizeBaseURL(u)
+	if authenticated {
+		u.Path += "a/"
+	}
+	u.Path += p.Name
+	return u, nil
+}
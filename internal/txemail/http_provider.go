@@ -0,0 +1,32 @@
+package txemail
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// apiResponseError returns the error for an unsuccessful response from the API of an email
+// service provider. Throttled requests and server errors are temporary.
+func apiResponseError(service string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	err := errors.Errorf("%s API responded with status %d: %s", service, resp.StatusCode, bytes.TrimSpace(body))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return temporary(err, retryAfter(resp.Header))
+	}
+	return err
+}
+
+// retryAfter returns the duration in the Retry-After header, or 0 if there is none. Only the
+// delay-seconds form is supported.
+func retryAfter(h http.Header) time.Duration {
+	seconds, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,127 @@
+package txemail
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jordan-wright/email"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+)
+
+// Provider delivers rendered emails with an email service, such as an SMTP server or the API of
+// an email service provider.
+type Provider interface {
+	// Send delivers the email. Errors that may go away by retrying, such as throttled requests or
+	// an unavailable server, are wrapped with temporary.
+	Send(ctx context.Context, m *email.Email) error
+
+	// Backoff returns how long to wait before retrying after the given number of failed attempts
+	// (starting at 1), unless the service said how long to wait.
+	Backoff(attempt int) time.Duration
+}
+
+// defaultMaxAttempts is the number of attempts to send an email if email.provider doesn't set
+// maxAttempts.
+const defaultMaxAttempts = 3
+
+// maxBackoff caps how long to wait between attempts, since emails are often sent while handling
+// a request.
+const maxBackoff = 10 * time.Second
+
+// newProvider returns the provider configured in the email.provider site configuration property,
+// or the SMTP server in email.smtp by default.
+func newProvider(ctx context.Context, c *conf.Unified) (Provider, error) {
+	switch typ := conf.EmailProviderType(); typ {
+	case "smtp":
+		if c.EmailSmtp == nil {
+			return nil, errors.New("no SMTP server configured (in email.smtp)")
+		}
+		return &smtpProvider{config: *c.EmailSmtp}, nil
+	case "ses":
+		if c.EmailProvider.Ses == nil {
+			return nil, errors.New("no Amazon SES configuration (in email.provider)")
+		}
+		return newSESProvider(ctx, *c.EmailProvider.Ses)
+	case "sendgrid":
+		if c.EmailProvider.Sendgrid == nil {
+			return nil, errors.New("no SendGrid configuration (in email.provider)")
+		}
+		return &sendGridProvider{apiKey: c.EmailProvider.Sendgrid.ApiKey, baseURL: sendGridBaseURL, doer: emailHTTPClient}, nil
+	default:
+		return nil, errors.Errorf("unknown email provider %q (in email.provider)", typ)
+	}
+}
+
+// maxAttempts returns the number of attempts to send an email.
+func maxAttempts(c *conf.Unified) int {
+	if c.EmailProvider != nil && c.EmailProvider.MaxAttempts > 0 {
+		return c.EmailProvider.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+// sendWithRetry sends the email with the provider, retrying temporary errors up to the given
+// number of attempts.
+func sendWithRetry(ctx context.Context, p Provider, m *email.Email, attempts int) error {
+	for attempt := 1; ; attempt++ {
+		err := p.Send(ctx, m)
+
+		var tempErr *temporaryError
+		if err == nil || attempt >= attempts || !errors.As(err, &tempErr) {
+			return err
+		}
+
+		wait := tempErr.retryAfter
+		if wait <= 0 {
+			wait = p.Backoff(attempt)
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// temporaryError is an error that may go away by retrying.
+type temporaryError struct {
+	err error
+	// retryAfter is how long the service asked to wait before retrying, if it did.
+	retryAfter time.Duration
+}
+
+func (e *temporaryError) Error() string { return e.err.Error() }
+func (e *temporaryError) Unwrap() error { return e.err }
+
+// temporary marks err as worth retrying, optionally after the given duration.
+func temporary(err error, retryAfter time.Duration) error {
+	return &temporaryError{err: err, retryAfter: retryAfter}
+}
+
+// exponentialBackoff returns base doubled for every failed attempt after the first.
+func exponentialBackoff(base time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+// emailHTTPClient sends requests to the APIs of email service providers. Unlike
+// httpcli.ExternalDoer it doesn't retry requests itself, since sendWithRetry retries according to
+// the backoff of the provider.
+var emailHTTPClient, _ = httpcli.NewFactory(
+	httpcli.NewMiddleware(httpcli.ContextErrorMiddleware),
+	httpcli.NewTimeoutOpt(30*time.Second),
+	httpcli.ExternalTransportOpt,
+	httpcli.TracedTransportOpt,
+).Doer()
@@ -0,0 +1,172 @@
+package txemail
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jordan-wright/email"
+)
+
+type fakeProvider struct {
+	errs  []error
+	sends int
+}
+
+func (p *fakeProvider) Send(context.Context, *email.Email) error {
+	p.sends++
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func (p *fakeProvider) Backoff(int) time.Duration { return time.Millisecond }
+
+func TestSendWithRetry(t *testing.T) {
+	throttled := temporary(errors.New("throttled"), 0)
+	rejected := errors.New("rejected")
+
+	tests := []struct {
+		name      string
+		errs      []error
+		attempts  int
+		wantErr   error
+		wantSends int
+	}{
+		{name: "success", attempts: 3, wantSends: 1},
+		{name: "temporary error", errs: []error{throttled}, attempts: 3, wantSends: 2},
+		{name: "too many temporary errors", errs: []error{throttled, throttled, throttled}, attempts: 3, wantErr: throttled, wantSends: 3},
+		{name: "permanent error", errs: []error{rejected}, attempts: 3, wantErr: rejected, wantSends: 1},
+		{name: "no retries", errs: []error{throttled}, attempts: 1, wantErr: throttled, wantSends: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &fakeProvider{errs: test.errs}
+			if err := sendWithRetry(context.Background(), p, &email.Email{}, test.attempts); err != test.wantErr {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if p.sends != test.wantSends {
+				t.Fatalf("got %d sends, want %d", p.sends, test.wantSends)
+			}
+		})
+	}
+}
+
+func TestClassifySMTPError(t *testing.T) {
+	var tempErr *temporaryError
+	if err := classifySMTPError(&textproto.Error{Code: 451, Msg: "greylisted"}); !errors.As(err, &tempErr) {
+		t.Errorf("want 451 reply to be temporary, got %v", err)
+	}
+	if err := classifySMTPError(&textproto.Error{Code: 550, Msg: "no such user"}); errors.As(err, &tempErr) {
+		t.Errorf("want 550 reply to be permanent, got %v", err)
+	}
+}
+
+func TestSendGridProvider(t *testing.T) {
+	var gotAuth string
+	var got sendGridMail
+	status := http.StatusAccepted
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/mail/send" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	p := &sendGridProvider{apiKey: "SG.x", baseURL: ts.URL, doer: http.DefaultClient}
+	m := &email.Email{
+		From:    "Sourcegraph <noreply@sourcegraph.com>",
+		To:      []string{"alice@example.com"},
+		Subject: "Hello",
+		Text:    []byte("text"),
+		HTML:    []byte("<p>html</p>"),
+		Headers: textproto.MIMEHeader{"Message-Id": []string{"1"}},
+	}
+	if err := p.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer SG.x" {
+		t.Errorf("got Authorization %q", gotAuth)
+	}
+	want := sendGridMail{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: "alice@example.com"}}}},
+		From:             sendGridAddress{Email: "noreply@sourcegraph.com", Name: "Sourcegraph"},
+		Subject:          "Hello",
+		Content:          []sendGridContent{{Type: "text/plain", Value: "text"}, {Type: "text/html", Value: "<p>html</p>"}},
+		Headers:          map[string]string{"Message-Id": "1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected request (-want +got):\n%s", diff)
+	}
+
+	status = http.StatusTooManyRequests
+	var tempErr *temporaryError
+	if err := p.Send(context.Background(), m); !errors.As(err, &tempErr) || tempErr.retryAfter != time.Second {
+		t.Fatalf("want temporary error with Retry-After, got %v", err)
+	}
+	status = http.StatusBadRequest
+	if err := p.Send(context.Background(), m); err == nil || errors.As(err, &tempErr) {
+		t.Fatalf("want permanent error, got %v", err)
+	}
+}
+
+func TestSESProvider(t *testing.T) {
+	var gotAuth string
+	var got sesSendEmailRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/email/outbound-emails" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(`{"MessageId":"1"}`))
+	}))
+	defer ts.Close()
+
+	p := &sesProvider{
+		region:      "us-east-1",
+		credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		baseURL:     ts.URL,
+		doer:        http.DefaultClient,
+	}
+	m := &email.Email{
+		From:    "noreply@sourcegraph.com",
+		To:      []string{"alice@example.com"},
+		Subject: "Hello",
+		Text:    []byte("text"),
+		Headers: make(textproto.MIMEHeader),
+	}
+	if err := p.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(gotAuth, "/us-east-1/ses/aws4_request") {
+		t.Errorf("unexpected Authorization %q", gotAuth)
+	}
+	if got.FromEmailAddress != "noreply@sourcegraph.com" || !cmp.Equal(got.Destination.ToAddresses, []string{"alice@example.com"}) {
+		t.Errorf("unexpected request %+v", got)
+	}
+	if !strings.Contains(string(got.Content.Raw.Data), "Subject: Hello") {
+		t.Errorf("unexpected raw message %q", got.Content.Raw.Data)
+	}
+}
@@ -0,0 +1,140 @@
+package txemail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jordan-wright/email"
+
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+)
+
+const sendGridBaseURL = "https://api.sendgrid.com"
+
+// sendGridProvider sends emails with the mail send endpoint of the SendGrid v3 API.
+type sendGridProvider struct {
+	apiKey  string
+	baseURL string
+	doer    httpcli.Doer
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+func (p *sendGridProvider) Send(ctx context.Context, m *email.Email) error {
+	payload, err := newSendGridMail(m)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.doer.Do(req)
+	if err != nil {
+		return temporary(errors.Wrap(err, "sending SendGrid request"), 0)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		err := apiResponseError("SendGrid", resp)
+		// SendGrid tells when the rate limit resets instead of sending Retry-After.
+		var tempErr *temporaryError
+		if errors.As(err, &tempErr) && tempErr.retryAfter == 0 {
+			if reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); reset > 0 {
+				tempErr.retryAfter = time.Until(time.Unix(reset, 0))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (p *sendGridProvider) Backoff(attempt int) time.Duration {
+	return exponentialBackoff(500*time.Millisecond, attempt)
+}
+
+// newSendGridMail converts the email to the request body of the mail send endpoint.
+func newSendGridMail(m *email.Email) (*sendGridMail, error) {
+	parseAddress := func(s string) (sendGridAddress, error) {
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return sendGridAddress{}, errors.Wrapf(err, "invalid email address %q", s)
+		}
+		return sendGridAddress{Email: addr.Address, Name: addr.Name}, nil
+	}
+
+	var payload sendGridMail
+	var err error
+	if payload.From, err = parseAddress(m.From); err != nil {
+		return nil, err
+	}
+	to := make([]sendGridAddress, 0, len(m.To))
+	for _, s := range m.To {
+		addr, err := parseAddress(s)
+		if err != nil {
+			return nil, err
+		}
+		to = append(to, addr)
+	}
+	payload.Personalizations = []sendGridPersonalization{{To: to}}
+	if len(m.ReplyTo) > 0 {
+		addr, err := parseAddress(m.ReplyTo[0])
+		if err != nil {
+			return nil, err
+		}
+		payload.ReplyTo = &addr
+	}
+
+	payload.Subject = m.Subject
+	// SendGrid requires the plain text content to come first.
+	if len(m.Text) > 0 {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/plain", Value: string(m.Text)})
+	}
+	if len(m.HTML) > 0 {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: string(m.HTML)})
+	}
+
+	for k, v := range m.Headers {
+		if len(v) > 0 {
+			if payload.Headers == nil {
+				payload.Headers = make(map[string]string, len(m.Headers))
+			}
+			payload.Headers[k] = v[0]
+		}
+	}
+	return &payload, nil
+}
@@ -0,0 +1,108 @@
+package txemail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/cockroachdb/errors"
+	"github.com/jordan-wright/email"
+
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// sesProvider sends emails with the SendEmail operation of the Amazon SES v2 API. Requests are
+// signed with AWS Signature Version 4.
+type sesProvider struct {
+	region      string
+	credentials aws.CredentialsProvider
+	baseURL     string
+	doer        httpcli.Doer
+}
+
+func newSESProvider(ctx context.Context, c schema.SESEmailProvider) (*sesProvider, error) {
+	var creds aws.CredentialsProvider
+	if c.AccessKeyID != "" {
+		creds = credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, "")
+	} else {
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.Region))
+		if err != nil {
+			return nil, errors.Wrap(err, "loading AWS config for SES")
+		}
+		creds = cfg.Credentials
+	}
+	return &sesProvider{
+		region:      c.Region,
+		credentials: creds,
+		baseURL:     fmt.Sprintf("https://email.%s.amazonaws.com", c.Region),
+		doer:        emailHTTPClient,
+	}, nil
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Raw struct {
+			Data []byte `json:"Data"` // base64-encoded by encoding/json
+		} `json:"Raw"`
+	} `json:"Content"`
+}
+
+func (p *sesProvider) Send(ctx context.Context, m *email.Email) error {
+	// The raw message keeps all headers (such as Message-ID and References) as they are.
+	raw, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	var payload sesSendEmailRequest
+	payload.FromEmailAddress = m.From
+	payload.Destination.ToAddresses = m.To
+	payload.Content.Raw.Data = raw
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	creds, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return errors.Wrap(err, "retrieving AWS credentials for SES")
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), "ses", p.region, time.Now()); err != nil {
+		return errors.Wrap(err, "signing SES request")
+	}
+
+	resp, err := p.doer.Do(req)
+	if err != nil {
+		return temporary(errors.Wrap(err, "sending SES request"), 0)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return apiResponseError("Amazon SES", resp)
+	}
+	return nil
+}
+
+// Backoff follows the exponential backoff that AWS recommends for throttled requests.
+func (p *sesProvider) Backoff(attempt int) time.Duration {
+	return exponentialBackoff(500*time.Millisecond, attempt)
+}
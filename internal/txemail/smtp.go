@@ -0,0 +1,80 @@
+package txemail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jordan-wright/email"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// smtpProvider sends emails with the SMTP server configured in email.smtp.
+type smtpProvider struct {
+	config schema.SMTPServerConfig
+}
+
+func (p *smtpProvider) Send(ctx context.Context, m *email.Email) error {
+	// Disable Mandrill features, because they make the emails look sketchy.
+	if p.config.Host == "smtp.mandrillapp.com" {
+		// Disable click tracking ("noclicks" could be any string; the docs say that anything will disable click tracking except
+		// those defined at
+		// https://mandrill.zendesk.com/hc/en-us/articles/205582117-How-to-Use-SMTP-Headers-to-Customize-Your-Messages#enable-open-and-click-tracking).
+		m.Headers["X-MC-Track"] = []string{"noclicks"}
+
+		m.Headers["X-MC-AutoText"] = []string{"false"}
+		m.Headers["X-MC-AutoHTML"] = []string{"false"}
+		m.Headers["X-MC-ViewContentLink"] = []string{"false"}
+	}
+
+	var smtpAuth smtp.Auth
+	switch p.config.Authentication {
+	case "none": // nothing to do
+	case "PLAIN":
+		smtpAuth = smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host)
+	case "CRAM-MD5":
+		smtpAuth = smtp.CRAMMD5Auth(p.config.Username, p.config.Password)
+	default:
+		return errors.Errorf("invalid SMTP authentication type %q", p.config.Authentication)
+	}
+
+	addr := net.JoinHostPort(p.config.Host, strconv.Itoa(p.config.Port))
+	var err error
+	if p.config.NoVerifyTLS {
+		err = m.SendWithStartTLS(addr, smtpAuth, &tls.Config{
+			InsecureSkipVerify: true,
+		})
+	} else {
+		err = m.Send(addr, smtpAuth)
+	}
+	return classifySMTPError(err)
+}
+
+// Backoff is longer than for the APIs, since SMTP servers reply with transient errors when they
+// greylist the sender.
+func (p *smtpProvider) Backoff(attempt int) time.Duration {
+	return exponentialBackoff(2*time.Second, attempt)
+}
+
+// classifySMTPError marks transient negative replies (4xx, RFC 5321 section 4.2.1) and network
+// errors as temporary.
+func classifySMTPError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 400 && protoErr.Code < 500 {
+		return temporary(err, 0)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return temporary(err, 0)
+	}
+	return err
+}
@@ -3,17 +3,17 @@ package txemail
 
 import (
 	"context"
-	"crypto/tls"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"net"
-	"net/smtp"
 	"net/textproto"
-	"strconv"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/jordan-wright/email"
 
 	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
 )
 
@@ -29,6 +29,20 @@ type Message struct {
 	Data     interface{}       // template data
 }
 
+// NewMessageID returns a new unique value for the "Message-ID" header, with which the delivery
+// reports of the email provider can be matched to the email that was sent.
+func NewMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	domain := "sourcegraph"
+	if i := strings.LastIndex(conf.Get().EmailAddress, "@"); i >= 0 {
+		domain = strings.TrimSuffix(conf.Get().EmailAddress[i+1:], ">")
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
 // render returns the rendered message contents without sending email.
 func render(message Message) (*email.Email, error) {
 	m := email.Email{
@@ -81,57 +95,58 @@ func Send(ctx context.Context, message Message) error {
 		return nil
 	}
 
-	conf := conf.Get()
-	if conf.EmailAddress == "" {
+	err := send(ctx, message)
+	recordDelivery(err)
+	return err
+}
+
+func send(ctx context.Context, message Message) error {
+	c := conf.Get()
+	if c.EmailAddress == "" {
 		return errors.New("no \"From\" email address configured (in email.address)")
 	}
-	if conf.EmailSmtp == nil {
-		return errors.New("no SMTP server configured (in email.smtp)")
+	p, err := newProvider(ctx, c)
+	if err != nil {
+		return err
 	}
 
 	m, err := render(message)
 	if err != nil {
 		return err
 	}
-	m.From = conf.EmailAddress
-
-	// Disable Mandrill features, because they make the emails look sketchy.
-	if conf.EmailSmtp.Host == "smtp.mandrillapp.com" {
-		// Disable click tracking ("noclicks" could be any string; the docs say that anything will disable click tracking except
-		// those defined at
-		// https://mandrill.zendesk.com/hc/en-us/articles/205582117-How-to-Use-SMTP-Headers-to-Customize-Your-Messages#enable-open-and-click-tracking).
-		m.Headers["X-MC-Track"] = []string{"noclicks"}
-
-		m.Headers["X-MC-AutoText"] = []string{"false"}
-		m.Headers["X-MC-AutoHTML"] = []string{"false"}
-		m.Headers["X-MC-ViewContentLink"] = []string{"false"}
-	}
-
-	var smtpAuth smtp.Auth
-	switch conf.EmailSmtp.Authentication {
-	case "none": // nothing to do
-	case "PLAIN":
-		smtpAuth = smtp.PlainAuth("", conf.EmailSmtp.Username, conf.EmailSmtp.Password, conf.EmailSmtp.Host)
-	case "CRAM-MD5":
-		smtpAuth = smtp.CRAMMD5Auth(conf.EmailSmtp.Username, conf.EmailSmtp.Password)
-	default:
-		return errors.Errorf("invalid SMTP authentication type %q", conf.EmailSmtp.Authentication)
-	}
-
-	if conf.EmailSmtp.NoVerifyTLS {
-		return m.SendWithStartTLS(
-			net.JoinHostPort(conf.EmailSmtp.Host, strconv.Itoa(conf.EmailSmtp.Port)),
-			smtpAuth,
-			&tls.Config{
-				InsecureSkipVerify: true,
-			},
-		)
-	}
-
-	return m.Send(
-		net.JoinHostPort(conf.EmailSmtp.Host, strconv.Itoa(conf.EmailSmtp.Port)),
-		smtpAuth,
-	)
+	m.From = c.EmailAddress
+
+	return sendWithRetry(ctx, p, m, maxAttempts(c))
+}
+
+// deliveryStatus holds the error of the most recent attempt to send an email. It is kept in Redis,
+// so that all instances report the same deliverability regardless of which one sent the email.
+var deliveryStatus = rcache.New("txemail_delivery")
+
+const lastDeliveryErrorKey = "last_error"
+
+// recordDelivery records the outcome of the most recent attempt to send an email.
+func recordDelivery(err error) {
+	if err == nil {
+		deliveryStatus.Delete(lastDeliveryErrorKey)
+		return
+	}
+	deliveryStatus.Set(lastDeliveryErrorKey, []byte(err.Error()))
+}
+
+// CheckDeliverable returns a non-nil error if emails cannot currently be delivered, either
+// because no email provider is configured or because the most recent attempt to send an email,
+// by any instance, failed. It is best-effort: if Redis is unavailable, only the configuration is
+// checked.
+func CheckDeliverable() error {
+	if !conf.CanSendEmail() {
+		return errors.New("no email provider configured (in email.smtp or email.provider)")
+	}
+
+	if lastErr, ok := deliveryStatus.Get(lastDeliveryErrorKey); ok {
+		return errors.Newf("last attempt to send an email failed: %s", lastErr)
+	}
+	return nil
 }
 
 // MockSend is used in tests to mock the Send func.
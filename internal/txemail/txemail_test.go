@@ -4,10 +4,14 @@ import (
 	"net/textproto"
 	"testing"
 
+	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
 	"github.com/jordan-wright/email"
 
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/txemail/txtypes"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestRender(t *testing.T) {
@@ -59,3 +63,30 @@ func TestRender(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(want, got))
 	}
 }
+
+func TestCheckDeliverable(t *testing.T) {
+	rcache.SetupForTest(t)
+
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+	if err := CheckDeliverable(); err == nil {
+		t.Fatal("expected error when no email provider is configured")
+	}
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		EmailSmtp: &schema.SMTPServerConfig{Host: "smtp.example.com", Port: 587},
+	}})
+	if err := CheckDeliverable(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	recordDelivery(errors.New("connection refused"))
+	if err := CheckDeliverable(); err == nil {
+		t.Fatal("expected error after a failed delivery")
+	}
+
+	recordDelivery(nil)
+	if err := CheckDeliverable(); err != nil {
+		t.Fatalf("unexpected error after a successful delivery: %s", err)
+	}
+}
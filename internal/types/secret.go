@@ -53,6 +53,8 @@ func (e *ExternalService) RedactConfigSecrets() (string, error) {
 		newCfg, err = redactField(e.Config, fields...)
 	case *schema.BitbucketCloudConnection:
 		newCfg, err = redactField(e.Config, []string{"appPassword"})
+	case *schema.GerritConnection:
+		newCfg, err = redactField(e.Config, []string{"password"})
 	case *schema.AWSCodeCommitConnection:
 		newCfg, err = redactField(e.Config, []string{"secretAccessKey"}, []string{"gitCredentials", "password"})
 	case *schema.PhabricatorConnection:
@@ -129,6 +131,8 @@ func (e *ExternalService) UnredactConfig(old *ExternalService) error {
 		unredacted, err = unredactField(old.Config, e.Config, &cfg, fields...)
 	case *schema.BitbucketCloudConnection:
 		unredacted, err = unredactField(old.Config, e.Config, &cfg, jsonStringField{[]string{"appPassword"}, &cfg.AppPassword})
+	case *schema.GerritConnection:
+		unredacted, err = unredactField(old.Config, e.Config, &cfg, jsonStringField{[]string{"password"}, &cfg.Password})
 	case *schema.AWSCodeCommitConnection:
 		unredacted, err = unredactField(old.Config,
 			e.Config,
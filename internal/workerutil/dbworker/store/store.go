@@ -276,19 +276,6 @@ func newStore(handle *basestore.TransactableHandle, options Options, observation
 		options.clock = glock.NewRealClock()
 	}
 
-	alternateColumnNames := map[string]string{}
-	for _, column := range columns {
-		alternateColumnNames[column.name] = column.name
-	}
-	for k, v := range options.AlternateColumnNames {
-		alternateColumnNames[k] = v
-	}
-
-	var replacements []string
-	for k, v := range alternateColumnNames {
-		replacements = append(replacements, fmt.Sprintf("{%s}", k), v)
-	}
-
 	modifiedColumnExpressionMatches := matchModifiedColumnExpressions(options.ViewName, options.ColumnExpressions)
 
 	for i, expression := range options.ColumnExpressions {
@@ -312,7 +299,7 @@ func newStore(handle *basestore.TransactableHandle, options Options, observation
 	return &store{
 		Store:                           basestore.NewWithHandle(handle),
 		options:                         options,
-		columnReplacer:                  strings.NewReplacer(replacements...),
+		columnReplacer:                  newColumnReplacer(options),
 		modifiedColumnExpressionMatches: modifiedColumnExpressionMatches,
 		operations:                      newOperations(options.Name, observationContext),
 	}
@@ -407,7 +394,6 @@ func (s *store) Dequeue(ctx context.Context, workerHostname string, conditions [
 	}
 
 	now := s.now()
-	retryAfter := int(s.options.RetryAfter / time.Second)
 
 	var (
 		processingExpr     = sqlf.Sprintf("%s", "processing")
@@ -430,14 +416,7 @@ func (s *store) Dequeue(ctx context.Context, workerHostname string, conditions [
 
 	record, exists, err := s.options.Scan(s.Query(ctx, s.formatQuery(
 		dequeueQuery,
-		quote(s.options.ViewName),
-		now,
-		retryAfter,
-		now,
-		retryAfter,
-		s.options.MaxNumRetries,
-		makeConditionSuffix(conditions),
-		s.options.OrderByExpression,
+		s.dequeueCandidateQuery(now, conditions),
 		quote(s.options.TableName),
 		sqlf.Join(s.makeDequeueUpdateStatements(updatedColumns), ", "),
 		sqlf.Join(s.makeDequeueSelectExpressions(updatedColumns), ", "),
@@ -457,23 +436,7 @@ func (s *store) Dequeue(ctx context.Context, workerHostname string, conditions [
 const dequeueQuery = `
 -- source: internal/workerutil/store.go:Dequeue
 WITH candidate AS (
-	SELECT {id} FROM %s
-	WHERE
-		(
-			(
-				{state} = 'queued' AND
-				({process_after} IS NULL OR {process_after} <= %s)
-			) OR (
-				%s > 0 AND
-				{state} = 'errored' AND
-				%s - {finished_at} > (%s * '1 second'::interval) AND
-				{num_failures} < %s
-			)
-		)
-		%s
-	ORDER BY %s
-	FOR UPDATE SKIP LOCKED
-	LIMIT 1
+	%s
 ),
 updated_record AS (
 	UPDATE
@@ -491,6 +454,56 @@ WHERE
 	{id} IN (SELECT {id} FROM candidate)
 `
 
+// dequeueCandidateQuery returns the query selecting the ID of the record that
+// Dequeue updates and returns.
+func (s *store) dequeueCandidateQuery(now time.Time, conditions []*sqlf.Query) *sqlf.Query {
+	viewName := s.options.ViewName
+	if viewName == "" {
+		viewName = s.options.TableName
+	}
+	retryAfter := int(s.options.RetryAfter / time.Second)
+
+	return s.formatQuery(
+		dequeueCandidateQuery,
+		quote(viewName),
+		now,
+		retryAfter,
+		now,
+		retryAfter,
+		s.options.MaxNumRetries,
+		makeConditionSuffix(conditions),
+		s.options.OrderByExpression,
+	)
+}
+
+const dequeueCandidateQuery = `
+SELECT {id} FROM %s
+WHERE
+	(
+		(
+			{state} = 'queued' AND
+			({process_after} IS NULL OR {process_after} <= %s)
+		) OR (
+			%s > 0 AND
+			{state} = 'errored' AND
+			%s - {finished_at} > (%s * '1 second'::interval) AND
+			{num_failures} < %s
+		)
+	)
+	%s
+ORDER BY %s
+FOR UPDATE SKIP LOCKED
+LIMIT 1
+`
+
+// DequeueCandidateQuery returns the query a store with the given options uses
+// to select the record to dequeue at the given time. It's meant to be used by
+// tooling that inspects the dequeue query, such as to explain its plan.
+func DequeueCandidateQuery(options Options, now time.Time) *sqlf.Query {
+	s := &store{options: options, columnReplacer: newColumnReplacer(options)}
+	return s.dequeueCandidateQuery(now, nil)
+}
+
 // makeDequeueSelectExpressions constructs the ordered set of SQL expressions that are returned
 // from the dequeue query. This method returns a copy of the configured column expressions slice
 // where expressions referencing one of the column updated by dequeue are replaced by the updated
@@ -867,6 +880,24 @@ WHERE {id} IN (SELECT {id} FROM stalled)
 RETURNING {id}, {last_heartbeat_at}
 `
 
+// newColumnReplacer returns a replacer that replaces the {column} placeholders
+// in queries with the names of the columns in the table of the given options.
+func newColumnReplacer(options Options) *strings.Replacer {
+	alternateColumnNames := map[string]string{}
+	for _, column := range columns {
+		alternateColumnNames[column.name] = column.name
+	}
+	for k, v := range options.AlternateColumnNames {
+		alternateColumnNames[k] = v
+	}
+
+	var replacements []string
+	for k, v := range alternateColumnNames {
+		replacements = append(replacements, fmt.Sprintf("{%s}", k), v)
+	}
+	return strings.NewReplacer(replacements...)
+}
+
 func (s *store) formatQuery(query string, args ...interface{}) *sqlf.Query {
 	return sqlf.Sprintf(s.columnReplacer.Replace(query), args...)
 }
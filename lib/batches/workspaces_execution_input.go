@@ -12,6 +12,24 @@ type Workspace struct {
 	OnlyFetchWorkspace bool            `json:"onlyFetchWorkspace"`
 	Steps              []Step          `json:"steps"`
 	SearchResultPaths  []string        `json:"searchResultPaths"`
+
+	// CachedStepResultFound is true if CachedStepResult holds the result of a
+	// previous execution of the steps up to and including
+	// CachedStepResult.StepIndex. Execution continues after that step.
+	CachedStepResultFound bool            `json:"cachedStepResultFound"`
+	CachedStepResult      AfterStepResult `json:"cachedStepResult,omitempty"`
+}
+
+// AfterStepResult is the state of a workspace after a step was executed.
+type AfterStepResult struct {
+	// StepIndex is the index of the step in the steps of the workspace,
+	// starting at 0.
+	StepIndex int `json:"stepIndex"`
+	// Diff is the diff of the changes made to the workspace by the step and
+	// all steps before it.
+	Diff string `json:"diff"`
+	// Outputs are the outputs set by the step and all steps before it.
+	Outputs map[string]interface{} `json:"outputs"`
 }
 
 type WorkspaceRepo struct {
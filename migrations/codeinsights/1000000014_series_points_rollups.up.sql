@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS series_points_rollups
+(
+    series_id  text                     NOT NULL,
+    time       timestamp with time zone NOT NULL,
+    value      double precision         NOT NULL,
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (series_id, time)
+);
+
+COMMENT ON TABLE series_points_rollups IS 'Aggregated value of each series at each point in time across all repositories. Maintained incrementally as points are recorded, so that series can be loaded without aggregating the raw data points.';
+COMMENT ON COLUMN series_points_rollups.value IS 'Sum over all repositories of the maximum value recorded for the repository at this time, across both series_points and series_points_snapshots.';
+
+-- Backfill the rollups from the points recorded so far.
+INSERT INTO series_points_rollups (series_id, time, value)
+SELECT sub.series_id, sub.time, SUM(sub.value)
+FROM (
+    SELECT sp.series_id, sp.time, sp.repo_name_id, MAX(sp.value) AS value
+    FROM (
+        SELECT series_id, time, repo_name_id, value FROM series_points
+        UNION ALL
+        SELECT series_id, time, repo_name_id, value FROM series_points_snapshots
+    ) sp
+    WHERE sp.repo_name_id IS NOT NULL
+    GROUP BY sp.series_id, sp.time, sp.repo_name_id
+) sub
+GROUP BY sub.series_id, sub.time;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE insight_series DROP COLUMN IF EXISTS paused_at;
+ALTER TABLE insight_series DROP COLUMN IF EXISTS pause_reason;
+
+COMMIT;
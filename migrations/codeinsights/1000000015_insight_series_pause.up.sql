@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS paused_at TIMESTAMP;
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS pause_reason TEXT;
+
+COMMENT ON COLUMN insight_series.paused_at IS 'Timestamp that recording for this series was paused. Paused series are not scheduled for recordings, snapshots or backfills until they are resumed.';
+COMMENT ON COLUMN insight_series.pause_reason IS 'The reason given for pausing this series, recorded as the reason for the gap in the data once the series is resumed.';
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS series_points_samples
+(
+    series_id    text                     NOT NULL,
+    time         timestamp with time zone NOT NULL,
+    repo_id      integer                  NOT NULL,
+    repo_name_id integer                  NOT NULL REFERENCES repo_names (id),
+    path         text                     NOT NULL,
+    line_number  integer,
+    preview      text
+);
+
+CREATE INDEX IF NOT EXISTS series_points_samples_series_id_time_idx ON series_points_samples (series_id, time);
+
+COMMENT ON TABLE series_points_samples IS 'A small, bounded sample of the matches behind the data points of a series, so that example matches can be shown for a data point. Samples are filtered by repository permissions when they are read.';
+COMMENT ON COLUMN series_points_samples.line_number IS 'Zero-based line number of the match, or null if the whole file matched (e.g. for type:path searches).';
+COMMENT ON COLUMN series_points_samples.preview IS 'Truncated content of the matched line, if any.';
+
+COMMIT;
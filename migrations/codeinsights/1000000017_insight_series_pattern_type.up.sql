@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS pattern_type TEXT NOT NULL DEFAULT 'literal';
+
+COMMENT ON COLUMN insight_series.pattern_type IS 'The pattern type (literal, regexp or structural) that the search query of this series is executed with.';
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS series_points_captured;
+ALTER TABLE insight_series DROP COLUMN IF EXISTS generation_method;
+
+COMMIT;
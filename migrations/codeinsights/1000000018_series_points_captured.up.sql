@@ -0,0 +1,25 @@
+BEGIN;
+
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS generation_method TEXT NOT NULL DEFAULT 'search';
+
+COMMENT ON COLUMN insight_series.generation_method IS 'How the data points of this series are generated: search counts the matches of the search query, search-compute counts the matches of the query per value captured by its capture group.';
+
+CREATE TABLE IF NOT EXISTS series_points_captured
+(
+    series_id    text                     NOT NULL,
+    time         timestamp with time zone NOT NULL,
+    capture      text                     NOT NULL,
+    value        double precision         NOT NULL,
+    repo_id      integer                  NOT NULL,
+    repo_name_id integer                  NOT NULL REFERENCES repo_names (id),
+    snapshot     boolean                  NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS series_points_captured_series_id_time_idx ON series_points_captured (series_id, time);
+
+COMMENT ON TABLE series_points_captured IS 'Data points of series that record one point per captured value (generation method search-compute), per repository.';
+COMMENT ON COLUMN series_points_captured.capture IS 'The value captured by the capture group of the query.';
+COMMENT ON COLUMN series_points_captured.value IS 'The number of matches in the repository with this captured value.';
+COMMENT ON COLUMN series_points_captured.snapshot IS 'Whether this is a snapshot point, which is replaced by the next snapshot, rather than a recorded point.';
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS series_points_repos;
+
+COMMIT;
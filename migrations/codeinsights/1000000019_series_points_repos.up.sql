@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS series_points_repos
+(
+    series_id    text                     NOT NULL,
+    time         timestamp with time zone NOT NULL,
+    repo_name_id integer                  NOT NULL REFERENCES repo_names (id),
+    repo_id      integer                  NOT NULL,
+    value        double precision         NOT NULL,
+    updated_at   timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (series_id, time, repo_name_id)
+);
+
+COMMENT ON TABLE series_points_repos IS 'Value of each series at each point in time per repository. Maintained incrementally as points are recorded, so that the repositories contributing to a data point can be listed without aggregating the raw data points.';
+COMMENT ON COLUMN series_points_repos.value IS 'Maximum value recorded for the repository at this time, across both series_points and series_points_snapshots.';
+
+-- Backfill the per-repository values from the points recorded so far.
+INSERT INTO series_points_repos (series_id, time, repo_name_id, repo_id, value)
+SELECT sp.series_id, sp.time, sp.repo_name_id, MAX(sp.repo_id), MAX(sp.value)
+FROM (
+    SELECT series_id, time, repo_name_id, repo_id, value FROM series_points
+    UNION ALL
+    SELECT series_id, time, repo_name_id, repo_id, value FROM series_points_snapshots
+) sp
+WHERE sp.repo_name_id IS NOT NULL AND sp.repo_id IS NOT NULL
+GROUP BY sp.series_id, sp.time, sp.repo_name_id;
+
+COMMIT;
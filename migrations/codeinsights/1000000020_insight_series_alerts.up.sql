@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS insight_series_alerts
+(
+    id                serial PRIMARY KEY,
+    series_id         text                     NOT NULL,
+    user_id           integer                  NOT NULL,
+    label             text                     NOT NULL DEFAULT '',
+    condition         text                     NOT NULL,
+    threshold         double precision         NOT NULL,
+    notify_email      boolean                  NOT NULL DEFAULT FALSE,
+    webhook_url       text,
+    slack_webhook_url text,
+    triggered         boolean                  NOT NULL DEFAULT FALSE,
+    last_evaluated_at timestamp with time zone,
+    last_triggered_at timestamp with time zone,
+    created_at        timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS insight_series_alerts_series_id_idx ON insight_series_alerts (series_id);
+CREATE INDEX IF NOT EXISTS insight_series_alerts_user_id_idx ON insight_series_alerts (user_id);
+
+COMMENT ON TABLE insight_series_alerts IS 'Alerts defined by users on insight series, which notify them when the data points of the series meet a condition.';
+COMMENT ON COLUMN insight_series_alerts.user_id IS 'The user that created the alert, who is notified by email and whose repository permissions apply when the alert is evaluated. References the users table of the main app database.';
+COMMENT ON COLUMN insight_series_alerts.condition IS 'One of above or below (the value of a data point compared to the threshold), or increase or decrease (the change in percent of a data point from the previous one compared to the threshold).';
+COMMENT ON COLUMN insight_series_alerts.triggered IS 'Whether the condition held for the last evaluated data point. Notifications are only sent when an alert becomes triggered.';
+COMMENT ON COLUMN insight_series_alerts.last_evaluated_at IS 'Time of the last data point the alert was evaluated for.';
+
+COMMIT;
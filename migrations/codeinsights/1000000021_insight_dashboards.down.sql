@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS dashboard_grants;
+DROP TABLE IF EXISTS dashboard_insight_view;
+DROP TABLE IF EXISTS dashboard;
+
+COMMIT;
@@ -0,0 +1,67 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS dashboard
+(
+    id         SERIAL
+        CONSTRAINT dashboard_pk
+            PRIMARY KEY,
+    title      TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INTEGER
+);
+
+COMMENT ON TABLE dashboard IS 'Metadata for dashboards of insights.';
+COMMENT ON COLUMN dashboard.title IS 'Title of the dashboard.';
+COMMENT ON COLUMN dashboard.created_by IS 'User ID of the user that created the dashboard. References the users table of the main app database.';
+
+CREATE TABLE IF NOT EXISTS dashboard_insight_view
+(
+    id              SERIAL
+        CONSTRAINT dashboard_insight_view_pk
+            PRIMARY KEY,
+    dashboard_id    INTEGER NOT NULL
+        CONSTRAINT dashboard_insight_view_dashboard_id_fk
+            REFERENCES dashboard
+            ON DELETE CASCADE,
+    insight_view_id INTEGER NOT NULL
+        CONSTRAINT dashboard_insight_view_insight_view_id_fk
+            REFERENCES insight_view
+            ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS dashboard_insight_view_dashboard_id_insight_view_id_idx
+    ON dashboard_insight_view (dashboard_id, insight_view_id);
+
+CREATE TABLE IF NOT EXISTS dashboard_grants
+(
+    id           SERIAL
+        CONSTRAINT dashboard_grants_pk
+            PRIMARY KEY,
+    dashboard_id INTEGER NOT NULL
+        CONSTRAINT dashboard_grants_dashboard_id_fk
+            REFERENCES dashboard
+            ON DELETE CASCADE, -- These grants only have meaning in the context of a parent dashboard.
+    user_id      INTEGER,
+    org_id       INTEGER,
+    global       BOOLEAN
+);
+
+COMMENT ON TABLE dashboard_grants IS 'Permission grants for dashboards. Each row should represent a unique principal (user, org, etc).';
+COMMENT ON COLUMN dashboard_grants.user_id IS 'User ID that that receives this grant.';
+COMMENT ON COLUMN dashboard_grants.org_id IS 'Org ID that that receives this grant.';
+COMMENT ON COLUMN dashboard_grants.global IS 'Grant that does not belong to any specific principal and is granted to all users.';
+
+CREATE INDEX IF NOT EXISTS dashboard_grants_dashboard_id_index
+    ON dashboard_grants (dashboard_id);
+
+-- Each principal may only be granted a dashboard once, so that sharing is idempotent.
+CREATE UNIQUE INDEX IF NOT EXISTS dashboard_grants_user_id_idx
+    ON dashboard_grants (user_id, dashboard_id) WHERE user_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS dashboard_grants_org_id_idx
+    ON dashboard_grants (org_id, dashboard_id) WHERE org_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS dashboard_grants_global_idx
+    ON dashboard_grants (dashboard_id) WHERE global IS TRUE;
+
+COMMIT;
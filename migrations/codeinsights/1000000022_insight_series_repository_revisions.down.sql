@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE insight_series DROP COLUMN IF EXISTS repository_revisions;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS repository_revisions JSONB;
+
+COMMENT ON COLUMN insight_series.repository_revisions IS 'An object mapping repository names to the revision (e.g. a release branch) that the repository is searched at for this series, instead of its default branch.';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS repositories TEXT[];
+
+COMMENT ON COLUMN insight_series.repositories IS 'The names of the repositories that the language statistics of a language-stats series are computed for.';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE insight_series DROP COLUMN IF EXISTS team;
+
+DROP TABLE IF EXISTS insight_team_repositories;
+
+COMMIT;
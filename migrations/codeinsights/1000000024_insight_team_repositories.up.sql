@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS insight_team_repositories (
+    team TEXT NOT NULL,
+    repo_name TEXT NOT NULL,
+    PRIMARY KEY (team, repo_name)
+);
+
+COMMENT ON TABLE insight_team_repositories IS 'The repositories owned by each team, which insight series scoped to a team are restricted to.';
+
+ALTER TABLE insight_series ADD COLUMN IF NOT EXISTS team TEXT;
+
+COMMENT ON COLUMN insight_series.team IS 'The team whose repositories (in insight_team_repositories) this series is restricted to, if any.';
+
+COMMIT;
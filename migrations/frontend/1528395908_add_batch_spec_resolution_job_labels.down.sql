@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS batch_spec_resolution_jobs_labels;
+
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs DROP COLUMN IF EXISTS labels;
+
+COMMIT;
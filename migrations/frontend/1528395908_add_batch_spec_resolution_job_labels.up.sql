@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS batch_spec_resolution_jobs_labels ON batch_spec_resolution_jobs USING gin (labels);
+
+COMMIT;
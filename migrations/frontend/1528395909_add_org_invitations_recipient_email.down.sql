@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Invitations that were never claimed by a user cannot be represented
+-- without the recipient_email column.
+DELETE FROM org_invitations WHERE recipient_user_id IS NULL;
+
+DROP INDEX IF EXISTS org_invitations_recipient_email;
+DROP INDEX IF EXISTS org_invitations_email_singleflight;
+
+ALTER TABLE IF EXISTS org_invitations DROP CONSTRAINT IF EXISTS check_recipient_defined;
+ALTER TABLE IF EXISTS org_invitations ALTER COLUMN recipient_user_id SET NOT NULL;
+ALTER TABLE IF EXISTS org_invitations DROP COLUMN IF EXISTS recipient_email;
+
+COMMIT;
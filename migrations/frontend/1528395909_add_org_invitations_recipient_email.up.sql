@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS org_invitations ADD COLUMN IF NOT EXISTS recipient_email citext;
+ALTER TABLE IF EXISTS org_invitations ALTER COLUMN recipient_user_id DROP NOT NULL;
+ALTER TABLE IF EXISTS org_invitations ADD CONSTRAINT check_recipient_defined CHECK (recipient_user_id IS NOT NULL OR recipient_email IS NOT NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS org_invitations_email_singleflight ON org_invitations (org_id, recipient_email) WHERE responded_at IS NULL AND revoked_at IS NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS org_invitations_recipient_email ON org_invitations (recipient_email) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs DROP COLUMN IF EXISTS dry_run_result;
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs DROP COLUMN IF EXISTS dry_run;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs ADD COLUMN IF NOT EXISTS dry_run_result JSONB;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs DROP COLUMN IF EXISTS credential_warnings;
+
+COMMIT;
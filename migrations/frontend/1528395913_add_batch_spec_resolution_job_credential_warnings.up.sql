@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS batch_spec_resolution_jobs ADD COLUMN IF NOT EXISTS credential_warnings JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS user_emails
+    DROP COLUMN IF EXISTS verification_reminders_sent,
+    DROP COLUMN IF EXISTS verification_reminders_token,
+    DROP COLUMN IF EXISTS verification_reminders_opted_out_at;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS user_emails
+    ADD COLUMN IF NOT EXISTS verification_reminders_sent integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS verification_reminders_token text,
+    ADD COLUMN IF NOT EXISTS verification_reminders_opted_out_at timestamp with time zone;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS trig_record_batch_spec_resolution_job_outcome ON batch_spec_resolution_jobs;
+DROP FUNCTION IF EXISTS record_batch_spec_resolution_job_outcome();
+DROP TABLE IF EXISTS batch_spec_resolution_job_outcomes;
+
+COMMIT;
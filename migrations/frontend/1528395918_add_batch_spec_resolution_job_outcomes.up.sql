@@ -0,0 +1,47 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_spec_resolution_job_outcomes (
+    id bigserial PRIMARY KEY,
+    batch_spec_resolution_job_id bigint NOT NULL,
+    batch_spec_id integer,
+    namespace_user_id integer,
+    namespace_org_id integer,
+    state text NOT NULL,
+    repository_error_count integer NOT NULL DEFAULT 0,
+    finished_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS batch_spec_resolution_job_outcomes_finished_at ON batch_spec_resolution_job_outcomes (finished_at);
+
+COMMENT ON TABLE batch_spec_resolution_job_outcomes IS 'Append-only record of the final state of every batch spec resolution job. Rows are not deleted with the job or batch spec, so that the history of resolutions can be charted.';
+
+CREATE OR REPLACE FUNCTION record_batch_spec_resolution_job_outcome() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+    ns_user_id integer;
+    ns_org_id integer;
+BEGIN
+    IF NEW.state IN ('completed', 'failed') AND OLD.state IS DISTINCT FROM NEW.state THEN
+        SELECT namespace_user_id, namespace_org_id INTO ns_user_id, ns_org_id FROM batch_specs WHERE id = NEW.batch_spec_id;
+
+        INSERT INTO batch_spec_resolution_job_outcomes
+            (batch_spec_resolution_job_id, batch_spec_id, namespace_user_id, namespace_org_id, state, repository_error_count, finished_at)
+        VALUES
+            (NEW.id, NEW.batch_spec_id, ns_user_id, ns_org_id, NEW.state, jsonb_array_length(NEW.repository_errors), COALESCE(NEW.finished_at, NOW()));
+    END IF;
+    RETURN NULL;
+END $$;
+
+DROP TRIGGER IF EXISTS trig_record_batch_spec_resolution_job_outcome ON batch_spec_resolution_jobs;
+CREATE TRIGGER trig_record_batch_spec_resolution_job_outcome AFTER UPDATE OF state ON batch_spec_resolution_jobs FOR EACH ROW EXECUTE PROCEDURE record_batch_spec_resolution_job_outcome();
+
+-- Backfill the outcomes of the jobs that have finished so far.
+INSERT INTO batch_spec_resolution_job_outcomes
+    (batch_spec_resolution_job_id, batch_spec_id, namespace_user_id, namespace_org_id, state, repository_error_count, finished_at)
+SELECT
+    jobs.id, jobs.batch_spec_id, batch_specs.namespace_user_id, batch_specs.namespace_org_id, jobs.state,
+    jsonb_array_length(jobs.repository_errors), COALESCE(jobs.finished_at, jobs.updated_at)
+FROM batch_spec_resolution_jobs jobs
+LEFT JOIN batch_specs ON batch_specs.id = jobs.batch_spec_id
+WHERE jobs.state IN ('completed', 'failed');
+
+COMMIT;
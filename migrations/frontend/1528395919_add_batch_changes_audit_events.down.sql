@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS trig_batch_changes_audit_events_append_only ON batch_changes_audit_events;
+DROP FUNCTION IF EXISTS batch_changes_audit_events_append_only();
+DROP TABLE IF EXISTS batch_changes_audit_events;
+
+COMMIT;
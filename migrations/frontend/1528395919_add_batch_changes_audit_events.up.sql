@@ -0,0 +1,27 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_changes_audit_events (
+    id bigserial PRIMARY KEY,
+    actor_user_id integer,
+    action text NOT NULL,
+    resource_type text NOT NULL,
+    resource_id bigint NOT NULL,
+    metadata jsonb NOT NULL DEFAULT '{}'::jsonb,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS batch_changes_audit_events_actor_user_id ON batch_changes_audit_events (actor_user_id);
+CREATE INDEX IF NOT EXISTS batch_changes_audit_events_resource ON batch_changes_audit_events (resource_type, resource_id);
+
+COMMENT ON TABLE batch_changes_audit_events IS 'Append-only log of the state changes users made to batch specs, resolution jobs and changesets.';
+COMMENT ON COLUMN batch_changes_audit_events.actor_user_id IS 'The user that made the change, or NULL for changes made by Sourcegraph itself. Not a foreign key, so that events outlive the user.';
+
+CREATE OR REPLACE FUNCTION batch_changes_audit_events_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'batch_changes_audit_events is append-only';
+END $$;
+
+DROP TRIGGER IF EXISTS trig_batch_changes_audit_events_append_only ON batch_changes_audit_events;
+CREATE TRIGGER trig_batch_changes_audit_events_append_only BEFORE UPDATE OR DELETE ON batch_changes_audit_events FOR EACH ROW EXECUTE PROCEDURE batch_changes_audit_events_append_only();
+
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_change_rollback_jobs (
+    id bigserial PRIMARY KEY,
+    batch_change_id integer NOT NULL REFERENCES batch_changes(id) ON DELETE CASCADE DEFERRABLE,
+    user_id integer REFERENCES users(id) ON DELETE SET NULL DEFERRABLE,
+
+    state text DEFAULT 'queued',
+    failure_message text,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    process_after timestamp with time zone,
+    num_resets integer NOT NULL DEFAULT 0,
+    num_failures integer NOT NULL DEFAULT 0,
+    execution_logs json[],
+    worker_hostname text NOT NULL DEFAULT '',
+    last_heartbeat_at timestamp with time zone,
+
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS batch_change_rollback_jobs_state_idx ON batch_change_rollback_jobs (state);
+CREATE UNIQUE INDEX IF NOT EXISTS batch_change_rollback_jobs_active_batch_change_id ON batch_change_rollback_jobs (batch_change_id) WHERE state IN ('queued', 'processing', 'errored');
+
+COMMIT;
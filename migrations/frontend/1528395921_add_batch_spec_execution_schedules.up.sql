@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_spec_execution_schedules (
+    id bigserial PRIMARY KEY,
+    batch_change_id integer NOT NULL REFERENCES batch_changes(id) ON DELETE CASCADE DEFERRABLE,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+
+    interval_seconds integer NOT NULL CHECK (interval_seconds > 0),
+    enabled boolean NOT NULL DEFAULT TRUE,
+    next_run_at timestamp with time zone NOT NULL,
+    last_run_at timestamp with time zone,
+    pending_batch_spec_id integer REFERENCES batch_specs(id) ON DELETE SET NULL DEFERRABLE,
+
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS batch_spec_execution_schedules_batch_change_id ON batch_spec_execution_schedules (batch_change_id);
+CREATE INDEX IF NOT EXISTS batch_spec_execution_schedules_next_run_at ON batch_spec_execution_schedules (next_run_at) WHERE enabled;
+
+COMMENT ON TABLE batch_spec_execution_schedules IS 'Schedules that periodically re-resolve and re-execute the batch spec of a batch change.';
+COMMENT ON COLUMN batch_spec_execution_schedules.user_id IS 'The user whose permissions the scheduled runs use.';
+COMMENT ON COLUMN batch_spec_execution_schedules.pending_batch_spec_id IS 'The batch spec created by the last run that is waiting for its workspaces to be resolved before it is executed.';
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS batch_changes_outbound_webhook_jobs;
+DROP TABLE IF EXISTS batch_changes_outbound_webhooks;
+
+COMMIT;
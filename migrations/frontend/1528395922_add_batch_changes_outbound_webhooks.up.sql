@@ -0,0 +1,46 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_changes_outbound_webhooks (
+    id bigserial PRIMARY KEY,
+    url text NOT NULL,
+    secret bytea NOT NULL,
+    encryption_key_id text NOT NULL DEFAULT '',
+    events text[] NOT NULL DEFAULT '{}',
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE batch_changes_outbound_webhooks IS 'Webhooks that are notified when batch spec resolutions and executions finish.';
+COMMENT ON COLUMN batch_changes_outbound_webhooks.secret IS 'The secret used to sign deliveries, encrypted if encryption_key_id is set.';
+COMMENT ON COLUMN batch_changes_outbound_webhooks.events IS 'The events the webhook is notified of. Empty means all events.';
+
+CREATE TABLE IF NOT EXISTS batch_changes_outbound_webhook_jobs (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL REFERENCES batch_changes_outbound_webhooks(id) ON DELETE CASCADE DEFERRABLE,
+    event text NOT NULL,
+    batch_spec_id integer NOT NULL REFERENCES batch_specs(id) ON DELETE CASCADE DEFERRABLE,
+    event_message text,
+    occurred_at timestamp with time zone NOT NULL,
+
+    state text DEFAULT 'queued',
+    failure_message text,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    process_after timestamp with time zone,
+    num_resets integer NOT NULL DEFAULT 0,
+    num_failures integer NOT NULL DEFAULT 0,
+    execution_logs json[],
+    worker_hostname text NOT NULL DEFAULT '',
+    last_heartbeat_at timestamp with time zone,
+
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE batch_changes_outbound_webhook_jobs IS 'Deliveries of events to batch_changes_outbound_webhooks.';
+COMMENT ON COLUMN batch_changes_outbound_webhook_jobs.event_message IS 'The failure message of the resolution the event is about, if any.';
+
+CREATE INDEX IF NOT EXISTS batch_changes_outbound_webhook_jobs_state_idx ON batch_changes_outbound_webhook_jobs (state);
+CREATE UNIQUE INDEX IF NOT EXISTS batch_changes_outbound_webhook_jobs_unique_event ON batch_changes_outbound_webhook_jobs (webhook_id, batch_spec_id, event);
+
+COMMIT;
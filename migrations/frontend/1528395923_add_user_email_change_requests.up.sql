@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_email_change_requests (
+    user_id integer PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    email citext NOT NULL,
+    secret text NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE user_email_change_requests IS 'Pending changes of the primary email address of users, which are finalized once the new address is confirmed.';
+COMMENT ON COLUMN user_email_change_requests.secret IS 'The key that signs the confirmation token sent to the new address.';
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS email_domain_policy_overrides (
+    domain citext PRIMARY KEY,
+    allow boolean NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE email_domain_policy_overrides IS 'Email domains that site admins explicitly allowed or denied, taking precedence over the email.domainPolicy site configuration.';
+COMMENT ON COLUMN email_domain_policy_overrides.domain IS 'The domain, which also matches its subdomains.';
+
+COMMIT;
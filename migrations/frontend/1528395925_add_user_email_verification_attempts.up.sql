@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_email_verification_attempts (
+    id bigserial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    email citext NOT NULL,
+    actor_user_id integer REFERENCES users(id) ON DELETE SET NULL DEFERRABLE,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS user_email_verification_attempts_user_id_email_created_at_idx ON user_email_verification_attempts (user_id, email, created_at);
+
+COMMENT ON TABLE user_email_verification_attempts IS 'Every verification email sent to a user email address, for rate limiting resends and for audit.';
+COMMENT ON COLUMN user_email_verification_attempts.actor_user_id IS 'The user who requested the verification email, which is NULL if the user was deleted or for anonymous requests such as signups.';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE user_emails DROP CONSTRAINT IF EXISTS user_emails_recovery_verified_not_primary;
+DROP INDEX IF EXISTS user_emails_user_id_is_recovery_idx;
+ALTER TABLE user_emails DROP COLUMN IF EXISTS is_recovery;
+
+COMMIT;
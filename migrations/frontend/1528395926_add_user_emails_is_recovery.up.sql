@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE user_emails ADD COLUMN IF NOT EXISTS is_recovery boolean NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_emails_user_id_is_recovery_idx ON user_emails (user_id, is_recovery) WHERE is_recovery = true;
+
+ALTER TABLE user_emails ADD CONSTRAINT user_emails_recovery_verified_not_primary CHECK (NOT is_recovery OR (verified_at IS NOT NULL AND NOT is_primary));
+
+COMMENT ON COLUMN user_emails.is_recovery IS 'Whether account recovery emails are sent to this address. It must be verified and can''t be the primary address.';
+
+COMMIT;
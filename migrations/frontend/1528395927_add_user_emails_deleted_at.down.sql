@@ -0,0 +1,10 @@
+BEGIN;
+
+DELETE FROM user_emails WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS user_emails_deleted_at_idx;
+ALTER TABLE user_emails DROP CONSTRAINT IF EXISTS user_emails_unique_verified_email;
+ALTER TABLE user_emails ADD CONSTRAINT user_emails_unique_verified_email EXCLUDE USING btree (email WITH =) WHERE (verified_at IS NOT NULL);
+ALTER TABLE user_emails DROP COLUMN IF EXISTS deleted_at;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+ALTER TABLE user_emails ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone;
+
+-- Removed email addresses can be verified by other users.
+ALTER TABLE user_emails DROP CONSTRAINT IF EXISTS user_emails_unique_verified_email;
+ALTER TABLE user_emails ADD CONSTRAINT user_emails_unique_verified_email EXCLUDE USING btree (email WITH =) WHERE (verified_at IS NOT NULL AND deleted_at IS NULL);
+
+CREATE INDEX IF NOT EXISTS user_emails_deleted_at_idx ON user_emails (deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON COLUMN user_emails.deleted_at IS 'When the email address was removed from the user. Removed email addresses can be restored by site admins for 30 days, after which they are deleted.';
+
+COMMIT;
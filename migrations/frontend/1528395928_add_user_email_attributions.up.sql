@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_email_attributions (
+    email citext PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    to_email citext NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS user_email_attributions_user_id_to_email_idx ON user_email_attributions (user_id, to_email);
+
+COMMENT ON TABLE user_email_attributions IS 'Email addresses that a user no longer has, whose commit authorship and permissions are attributed to another email address of the user.';
+COMMENT ON COLUMN user_email_attributions.to_email IS 'The email address of the user that the email address is attributed to. The attribution only applies while the user has this email address verified.';
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS org_domains (
+    id serial PRIMARY KEY,
+    org_id integer NOT NULL REFERENCES orgs(id) ON DELETE CASCADE DEFERRABLE,
+    domain citext NOT NULL,
+    verification_token text NOT NULL,
+    verified_at timestamp with time zone,
+    last_checked_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT org_domains_org_id_domain_key UNIQUE (org_id, domain)
+);
+
+-- A domain can only be verified by one organization.
+CREATE UNIQUE INDEX IF NOT EXISTS org_domains_domain_verified_idx ON org_domains (domain) WHERE verified_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS org_domains_unverified_last_checked_at_idx ON org_domains (last_checked_at) WHERE verified_at IS NULL;
+
+COMMENT ON TABLE org_domains IS 'Email domains of organizations. Once an organization proves control of a domain, email addresses of the domain added by users are verified automatically and their users join the organization.';
+COMMENT ON COLUMN org_domains.verification_token IS 'The token that must be published in a DNS TXT record of the domain to prove control of it.';
+COMMENT ON COLUMN org_domains.last_checked_at IS 'When the DNS TXT records of the domain were last checked for the verification token.';
+
+COMMIT;
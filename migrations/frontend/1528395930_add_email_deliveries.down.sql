@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS email_deliveries;
+
+ALTER TABLE user_emails DROP COLUMN IF EXISTS bounced_at;
+ALTER TABLE user_emails DROP COLUMN IF EXISTS bounce_reason;
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+ALTER TABLE user_emails ADD COLUMN IF NOT EXISTS bounced_at timestamp with time zone;
+ALTER TABLE user_emails ADD COLUMN IF NOT EXISTS bounce_reason text;
+
+COMMENT ON COLUMN user_emails.bounced_at IS 'When the email provider last reported a permanent bounce or a complaint for the address. Cleared when the address is verified again.';
+
+CREATE TABLE IF NOT EXISTS email_deliveries (
+    id bigserial PRIMARY KEY,
+    message_id text NOT NULL,
+    user_id integer REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    email citext NOT NULL,
+    kind text NOT NULL,
+    status text NOT NULL DEFAULT 'sent',
+    status_reason text,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS email_deliveries_message_id_idx ON email_deliveries (message_id);
+CREATE INDEX IF NOT EXISTS email_deliveries_email_created_at_idx ON email_deliveries (email, created_at);
+CREATE INDEX IF NOT EXISTS email_deliveries_user_id_idx ON email_deliveries (user_id);
+
+COMMENT ON TABLE email_deliveries IS 'Emails sent to users, such as verification emails and account change notifications, and what the email provider reported about their delivery.';
+COMMENT ON COLUMN email_deliveries.message_id IS 'The Message-ID header of the email, without angle brackets.';
+COMMENT ON COLUMN email_deliveries.kind IS 'What the email was sent for, e.g. verification or notification.';
+COMMENT ON COLUMN email_deliveries.status IS 'One of sent, delivered, soft_bounced, bounced and complained.';
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_totp_secrets (
+    user_id integer PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    secret text NOT NULL,
+    encryption_key_id text NOT NULL DEFAULT '',
+    enabled_at timestamp with time zone,
+    last_used_step bigint NOT NULL DEFAULT 0,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE user_totp_secrets IS 'The TOTP secrets of users who enabled or are enrolling in two-factor authentication.';
+COMMENT ON COLUMN user_totp_secrets.enabled_at IS 'When the user confirmed the enrollment with a valid code. Null while the enrollment is pending.';
+COMMENT ON COLUMN user_totp_secrets.last_used_step IS 'The time step of the last accepted code, so that a code can''t be used twice.';
+
+CREATE TABLE IF NOT EXISTS user_totp_recovery_codes (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    code_hash text NOT NULL,
+    used_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS user_totp_recovery_codes_user_id_idx ON user_totp_recovery_codes (user_id);
+
+COMMENT ON TABLE user_totp_recovery_codes IS 'One-time codes that users can sign in with instead of a TOTP code, e.g. when they lost their device.';
+COMMENT ON COLUMN user_totp_recovery_codes.code_hash IS 'The SHA-256 hash of the recovery code.';
+
+COMMIT;
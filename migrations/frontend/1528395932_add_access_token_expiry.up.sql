@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS expires_at timestamp with time zone;
+
+COMMENT ON COLUMN access_tokens.expires_at IS 'When the access token expires. Expired tokens are rejected. NULL means the token never expires.';
+
+COMMIT;
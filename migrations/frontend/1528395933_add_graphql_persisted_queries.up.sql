@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS graphql_persisted_queries (
+    hash text PRIMARY KEY,
+    query text NOT NULL,
+    creator_user_id integer REFERENCES users(id) ON DELETE SET NULL DEFERRABLE,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE graphql_persisted_queries IS 'GraphQL queries that clients registered to run them by their hash.';
+COMMENT ON COLUMN graphql_persisted_queries.hash IS 'The hex-encoded SHA-256 hash of the query.';
+COMMENT ON COLUMN graphql_persisted_queries.creator_user_id IS 'The user who registered the query. Null if it was registered by an anonymous user.';
+
+COMMIT;
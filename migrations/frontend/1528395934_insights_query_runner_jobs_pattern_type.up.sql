@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE insights_query_runner_jobs ADD COLUMN IF NOT EXISTS pattern_type TEXT NOT NULL DEFAULT 'literal';
+
+COMMENT ON COLUMN insights_query_runner_jobs.pattern_type IS 'The pattern type (literal, regexp or structural) that the search query is executed with.';
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS insights_backfill_jobs (
+    id SERIAL PRIMARY KEY,
+    series_id TEXT NOT NULL,
+    repo_id INTEGER NOT NULL,
+    repo_name TEXT NOT NULL,
+    frames_total INTEGER NOT NULL DEFAULT 0,
+    frames_done INTEGER NOT NULL DEFAULT 0,
+    state TEXT DEFAULT 'queued',
+    failure_message TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    process_after TIMESTAMP WITH TIME ZONE,
+    num_resets INTEGER NOT NULL DEFAULT 0,
+    num_failures INTEGER NOT NULL DEFAULT 0,
+    execution_logs JSON[],
+    worker_hostname TEXT NOT NULL DEFAULT '',
+    last_heartbeat_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS insights_backfill_jobs_series_id_repo_id_idx ON insights_backfill_jobs (series_id, repo_id);
+CREATE INDEX IF NOT EXISTS insights_backfill_jobs_state_idx ON insights_backfill_jobs (state);
+
+COMMENT ON TABLE insights_backfill_jobs IS 'Jobs of the code insights backfiller, one per series and repository, which sample the history of the repository and enqueue the searches of the historical data points of the series.';
+COMMENT ON COLUMN insights_backfill_jobs.frames_total IS 'The number of historical data points sampled for the series in the repository.';
+COMMENT ON COLUMN insights_backfill_jobs.frames_done IS 'The number of sampled historical data points that have been enqueued for search or recorded.';
+
+COMMIT;
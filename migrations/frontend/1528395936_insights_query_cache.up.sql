@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS insights_query_cache (
+    query_key TEXT NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    series_query_key TEXT NOT NULL,
+    results JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (query_key, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS insights_query_cache_series_query_key_idx ON insights_query_cache (series_query_key);
+CREATE INDEX IF NOT EXISTS insights_query_cache_created_at_idx ON insights_query_cache (created_at);
+
+COMMENT ON TABLE insights_query_cache IS 'Results of code insights search queries, shared by all series whose queries have the same normal form.';
+COMMENT ON COLUMN insights_query_cache.query_key IS 'The hex-encoded SHA-256 hash of the pattern type and the normalized search query.';
+COMMENT ON COLUMN insights_query_cache.bucket IS 'The start of the time bucket of the data points the results are shared by.';
+COMMENT ON COLUMN insights_query_cache.series_query_key IS 'The query_key of the query of the series the search was run for, used to invalidate the results derived from a series query.';
+
+COMMIT;
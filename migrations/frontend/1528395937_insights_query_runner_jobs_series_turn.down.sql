@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS insights_query_runner_jobs_queued_turn;
+DROP INDEX IF EXISTS insights_query_runner_jobs_queued_series_turn;
+DROP INDEX IF EXISTS insights_query_runner_jobs_processable_priority_turn_id;
+CREATE INDEX IF NOT EXISTS insights_query_runner_jobs_processable_priority_id ON insights_query_runner_jobs (priority, id) WHERE state = 'queued' OR state = 'errored';
+
+ALTER TABLE insights_query_runner_jobs DROP COLUMN IF EXISTS series_turn;
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+ALTER TABLE insights_query_runner_jobs ADD COLUMN IF NOT EXISTS series_turn INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN insights_query_runner_jobs.series_turn IS 'The round in which the job is dequeued, in a round-robin across series: the jobs of a series are assigned consecutive turns, starting at the lowest turn of the queued jobs.';
+
+-- Jobs are dequeued in the order of (priority, series_turn, id).
+DROP INDEX IF EXISTS insights_query_runner_jobs_processable_priority_id;
+CREATE INDEX IF NOT EXISTS insights_query_runner_jobs_processable_priority_turn_id ON insights_query_runner_jobs (priority, series_turn, id) WHERE state = 'queued' OR state = 'errored';
+
+-- Used to assign the turns of enqueued jobs.
+CREATE INDEX IF NOT EXISTS insights_query_runner_jobs_queued_series_turn ON insights_query_runner_jobs (series_id, series_turn) WHERE state = 'queued';
+CREATE INDEX IF NOT EXISTS insights_query_runner_jobs_queued_turn ON insights_query_runner_jobs (series_turn) WHERE state = 'queued';
+
+COMMIT;
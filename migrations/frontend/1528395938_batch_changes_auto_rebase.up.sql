@@ -0,0 +1,35 @@
+BEGIN;
+
+ALTER TABLE batch_changes ADD COLUMN IF NOT EXISTS auto_rebase boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN batch_changes.auto_rebase IS 'Whether the changesets of the batch change are rebased automatically when their base branch moves.';
+
+-- Note that we have to regenerate the reconciler_changesets view, as the SELECT
+-- c.* in the view definition isn't refreshed when the fields change within the
+-- changesets table.
+DROP VIEW IF EXISTS
+    reconciler_changesets;
+
+ALTER TABLE changesets ADD COLUMN IF NOT EXISTS rebase_base_rev text;
+ALTER TABLE changesets ADD COLUMN IF NOT EXISTS rebase_conflict text;
+
+COMMENT ON COLUMN changesets.rebase_base_rev IS 'The commit of the base branch the last automatic rebase of the changeset was attempted onto. NULL if the changeset has not been rebased since its changeset spec was pushed.';
+COMMENT ON COLUMN changesets.rebase_conflict IS 'The conflict that prevented the last automatic rebase of the changeset, if any.';
+
+CREATE VIEW reconciler_changesets AS
+    SELECT c.* FROM changesets c
+    INNER JOIN repo r on r.id = c.repo_id
+    WHERE
+        r.deleted_at IS NULL AND
+        EXISTS (
+            SELECT 1 FROM batch_changes
+            LEFT JOIN users namespace_user ON batch_changes.namespace_user_id = namespace_user.id
+            LEFT JOIN orgs namespace_org ON batch_changes.namespace_org_id = namespace_org.id
+            WHERE
+                c.batch_change_ids ? batch_changes.id::text AND
+                namespace_user.deleted_at IS NULL AND
+                namespace_org.deleted_at IS NULL
+        )
+;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE batch_specs DROP COLUMN IF EXISTS no_cache;
+
+DROP TABLE IF EXISTS batch_spec_execution_cache_entries;
+
+COMMIT;
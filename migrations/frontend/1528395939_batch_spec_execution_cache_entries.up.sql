@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_spec_execution_cache_entries (
+    id bigserial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+
+    workspace_key text NOT NULL,
+    step_key text NOT NULL,
+    result jsonb NOT NULL,
+
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    last_used_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS batch_spec_execution_cache_entries_user_id_workspace_key_step_key ON batch_spec_execution_cache_entries (user_id, workspace_key, step_key);
+CREATE INDEX IF NOT EXISTS batch_spec_execution_cache_entries_last_used_at ON batch_spec_execution_cache_entries (last_used_at);
+
+COMMENT ON TABLE batch_spec_execution_cache_entries IS 'Results of the steps of batch spec workspace executions, reused by later executions of the same steps in workspaces with the same content.';
+COMMENT ON COLUMN batch_spec_execution_cache_entries.user_id IS 'The user that executed the steps. Results are only reused for executions of that user.';
+COMMENT ON COLUMN batch_spec_execution_cache_entries.workspace_key IS 'The hex-encoded SHA-256 hash of the repository, commit and path of the workspace and of the values its steps are templated with.';
+COMMENT ON COLUMN batch_spec_execution_cache_entries.step_key IS 'The hex-encoded SHA-256 hash of the digests of the cached step and all steps before it.';
+COMMENT ON COLUMN batch_spec_execution_cache_entries.result IS 'The diff and outputs of the workspace after the cached step.';
+
+ALTER TABLE batch_specs ADD COLUMN IF NOT EXISTS no_cache boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN batch_specs.no_cache IS 'Whether the execution of the batch spec skips cached step results. Its results are still cached.';
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE OR REPLACE FUNCTION record_batch_spec_resolution_job_outcome() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+    ns_user_id integer;
+    ns_org_id integer;
+BEGIN
+    IF NEW.state IN ('completed', 'failed') AND OLD.state IS DISTINCT FROM NEW.state THEN
+        SELECT namespace_user_id, namespace_org_id INTO ns_user_id, ns_org_id FROM batch_specs WHERE id = NEW.batch_spec_id;
+
+        INSERT INTO batch_spec_resolution_job_outcomes
+            (batch_spec_resolution_job_id, batch_spec_id, namespace_user_id, namespace_org_id, state, repository_error_count, finished_at)
+        VALUES
+            (NEW.id, NEW.batch_spec_id, ns_user_id, ns_org_id, NEW.state, jsonb_array_length(NEW.repository_errors), COALESCE(NEW.finished_at, NOW()));
+    END IF;
+    RETURN NULL;
+END $$;
+
+DROP INDEX IF EXISTS batch_spec_resolution_job_outcomes_recorded_at;
+ALTER TABLE batch_spec_resolution_job_outcomes DROP COLUMN IF EXISTS recorded_at;
+
+COMMIT;
@@ -0,0 +1,41 @@
+BEGIN;
+
+-- recorded_at is when the outcome was inserted. Consumers paginate on it with
+-- a lag, since IDs can become visible out of order.
+ALTER TABLE batch_spec_resolution_job_outcomes ADD COLUMN IF NOT EXISTS recorded_at timestamp with time zone NOT NULL DEFAULT clock_timestamp();
+CREATE INDEX IF NOT EXISTS batch_spec_resolution_job_outcomes_recorded_at ON batch_spec_resolution_job_outcomes (recorded_at);
+
+-- Errored jobs are never retried, so they are recorded as failed. Dry runs
+-- only preview workspaces and are not recorded.
+CREATE OR REPLACE FUNCTION record_batch_spec_resolution_job_outcome() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+    ns_user_id integer;
+    ns_org_id integer;
+BEGIN
+    IF NEW.state IN ('completed', 'failed', 'errored') AND OLD.state IS DISTINCT FROM NEW.state AND NOT NEW.dry_run THEN
+        SELECT namespace_user_id, namespace_org_id INTO ns_user_id, ns_org_id FROM batch_specs WHERE id = NEW.batch_spec_id;
+
+        INSERT INTO batch_spec_resolution_job_outcomes
+            (batch_spec_resolution_job_id, batch_spec_id, namespace_user_id, namespace_org_id, state, repository_error_count, finished_at)
+        VALUES
+            (NEW.id, NEW.batch_spec_id, ns_user_id, ns_org_id, CASE WHEN NEW.state = 'errored' THEN 'failed' ELSE NEW.state END, jsonb_array_length(NEW.repository_errors), COALESCE(NEW.finished_at, NOW()));
+    END IF;
+    RETURN NULL;
+END $$;
+
+-- Remove the outcomes of dry runs that are still known, and backfill the
+-- outcomes of errored jobs.
+DELETE FROM batch_spec_resolution_job_outcomes outcomes
+USING batch_spec_resolution_jobs jobs
+WHERE jobs.id = outcomes.batch_spec_resolution_job_id AND jobs.dry_run;
+
+INSERT INTO batch_spec_resolution_job_outcomes
+    (batch_spec_resolution_job_id, batch_spec_id, namespace_user_id, namespace_org_id, state, repository_error_count, finished_at)
+SELECT
+    jobs.id, jobs.batch_spec_id, batch_specs.namespace_user_id, batch_specs.namespace_org_id, 'failed',
+    jsonb_array_length(jobs.repository_errors), COALESCE(jobs.finished_at, jobs.updated_at)
+FROM batch_spec_resolution_jobs jobs
+LEFT JOIN batch_specs ON batch_specs.id = jobs.batch_spec_id
+WHERE jobs.state = 'errored' AND NOT jobs.dry_run;
+
+COMMIT;
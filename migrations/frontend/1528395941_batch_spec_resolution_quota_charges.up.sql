@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batch_spec_resolution_quota_charges (
+    id bigserial PRIMARY KEY,
+    namespace_user_id integer,
+    namespace_org_id integer,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS batch_spec_resolution_quota_charges_namespace_user_id ON batch_spec_resolution_quota_charges (namespace_user_id, created_at) WHERE namespace_user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS batch_spec_resolution_quota_charges_namespace_org_id ON batch_spec_resolution_quota_charges (namespace_org_id, created_at) WHERE namespace_org_id IS NOT NULL;
+
+COMMENT ON TABLE batch_spec_resolution_quota_charges IS 'Append-only record of the batch spec resolution jobs created per namespace, which the daily resolution quota is counted from. Rows are not deleted with the job or batch spec, so that replacing a batch spec does not reset the quota.';
+
+-- Charge the jobs of the last day, so that the quota keeps applying to them.
+INSERT INTO batch_spec_resolution_quota_charges (namespace_user_id, namespace_org_id, created_at)
+SELECT batch_specs.namespace_user_id, batch_specs.namespace_org_id, jobs.created_at
+FROM batch_spec_resolution_jobs jobs
+JOIN batch_specs ON batch_specs.id = jobs.batch_spec_id
+WHERE jobs.created_at >= now() - interval '1 day';
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+COMMENT ON TABLE org_domains IS 'Email domains of organizations. Once an organization proves control of a domain, email addresses of the domain added by users are verified automatically and their users join the organization.';
+
+COMMIT;
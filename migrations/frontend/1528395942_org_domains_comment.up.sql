@@ -0,0 +1,5 @@
+BEGIN;
+
+COMMENT ON TABLE org_domains IS 'Email domains of organizations. Once an organization proves control of a domain, users who verify an email address of the domain join the organization.';
+
+COMMIT;
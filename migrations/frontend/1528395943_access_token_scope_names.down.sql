@@ -0,0 +1,7 @@
+BEGIN;
+
+UPDATE access_tokens
+SET scopes = array_replace(array_replace(scopes, 'read:search', 'search'), 'write:batches', 'batch-changes')
+WHERE scopes && ARRAY['read:search', 'write:batches']::text[];
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- The "search" and "batch-changes" scopes of service account tokens were renamed when they became
+-- fine-grained scopes that any token may have.
+UPDATE access_tokens
+SET scopes = array_replace(array_replace(scopes, 'search', 'read:search'), 'batch-changes', 'write:batches')
+WHERE scopes && ARRAY['search', 'batch-changes']::text[];
+
+COMMIT;
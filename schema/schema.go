@@ -53,6 +53,30 @@ type AWSKMSEncryptionKey struct {
 	Region          string `json:"region,omitempty"`
 	Type            string `json:"type"`
 }
+
+// AccountChangeNotifications description: Configures how users are notified about security-relevant changes to their account, such as a changed password or a new access token. If not set, users are notified by email (if email.smtp is configured).
+type AccountChangeNotifications struct {
+	// Channels description: The channels users are notified on. A user is notified on every listed channel that is available for them.
+	Channels []string `json:"channels,omitempty"`
+	// Slack description: Notifies users with a direct message in Slack. Only users who have signed in with Slack (through an OpenID Connect auth provider with the issuer https://slack.com) can be notified this way.
+	Slack *AccountChangeNotificationsSlack `json:"slack,omitempty"`
+	// Webhook description: Notifies an external service by sending a POST request with a JSON payload describing the change.
+	Webhook *AccountChangeNotificationsWebhook `json:"webhook,omitempty"`
+}
+
+// AccountChangeNotificationsSlack description: Notifies users with a direct message in Slack. Only users who have signed in with Slack (through an OpenID Connect auth provider with the issuer https://slack.com) can be notified this way.
+type AccountChangeNotificationsSlack struct {
+	// BotToken description: A Slack bot token with the chat:write scope.
+	BotToken string `json:"botToken"`
+}
+
+// AccountChangeNotificationsWebhook description: Notifies an external service by sending a POST request with a JSON payload describing the change.
+type AccountChangeNotificationsWebhook struct {
+	// Secret description: If set, requests include an X-Sourcegraph-Signature header with the hex-encoded HMAC-SHA256 of the request body, keyed with this secret.
+	Secret string `json:"secret,omitempty"`
+	// Url description: The URL the notifications are sent to.
+	Url string `json:"url"`
+}
 type AdditionalProperties struct {
 	// Format description: The expected format of the output. If set, the output is being parsed in that format before being stored in the var. If not set, 'text' is assumed to the format.
 	Format string `json:"format,omitempty"`
@@ -64,6 +88,10 @@ type AdditionalProperties struct {
 type ApiRatelimit struct {
 	// Enabled description: Whether API rate limiting is enabled
 	Enabled bool `json:"enabled"`
+	// MaxCost description: The maximum estimated cost (the number of fields the query may return) of a single GraphQL query. More expensive queries are rejected. Queries whose cost can't be estimated are rejected too. Applies even if rate limiting is disabled. The default is no maximum.
+	MaxCost int `json:"maxCost,omitempty"`
+	// MaxDepth description: The maximum depth of a single GraphQL query. Deeper queries are rejected. Queries whose cost can't be estimated are rejected too. Applies even if rate limiting is disabled. The default is no maximum.
+	MaxDepth int `json:"maxDepth,omitempty"`
 	// Overrides description: An array of rate limit overrides
 	Overrides []*Overrides `json:"overrides,omitempty"`
 	// PerIP description: Limit granted per IP per hour, only applied to anonymous users
@@ -137,6 +165,11 @@ func (v *AuthProviders) UnmarshalJSON(data []byte) error {
 	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"builtin", "saml", "openidconnect", "http-header", "github", "gitlab"})
 }
 
+// AuthTwoFactor description: Settings for two-factor authentication with a time-based one-time password (TOTP) app. Users who enable it must enter a code from the app (or a recovery code) when they sign in with a username and password. It does not apply to sign-ins through external authentication providers, which should enforce their own second factor.
+type AuthTwoFactor struct {
+	// RequireForSiteAdmins description: Requires site admins to enable two-factor authentication. Site admins who haven't enabled it can still sign in, but can't perform site admin actions until they enable it.
+	RequireForSiteAdmins bool `json:"requireForSiteAdmins,omitempty"`
+}
 type BackendInsight struct {
 	// Description description: The description of this insight
 	Description string          `json:"description,omitempty"`
@@ -154,6 +187,22 @@ type BackendInsightSeries struct {
 	// Stroke description: The color of the line for the series.
 	Stroke string `json:"stroke,omitempty"`
 }
+type BatchChangeCodeHostRequestLimit struct {
+	// Concurrency description: The maximum number of concurrent requests to the code host. 0 means unlimited.
+	Concurrency int `json:"concurrency,omitempty"`
+	// RequestsPerSecond description: The maximum number of requests per second to the code host. 0 means unlimited.
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+	// Url description: The URL of the code host, as used in the url field of its code host connection. For example: https://github.com/.
+	Url string `json:"url"`
+}
+
+// BatchChangeResolutionQuota description: Limits the batch spec workspace resolutions per namespace (user or organization), so that a single namespace can't use up the resolution capacity of the instance. Resolutions beyond the quota are rejected.
+type BatchChangeResolutionQuota struct {
+	// MaxConcurrent description: The maximum number of queued or running resolutions of the batch specs in a namespace. 0 means unlimited.
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+	// MaxPerDay description: The maximum number of resolutions of the batch specs in a namespace that can be started within 24 hours. 0 means unlimited.
+	MaxPerDay int `json:"maxPerDay,omitempty"`
+}
 type BatchChangeRolloutWindow struct {
 	// Days description: Day(s) the window applies to. If omitted, this rule applies to all days of the week.
 	Days []string `json:"days,omitempty"`
@@ -217,6 +266,8 @@ type BitbucketCloudConnection struct {
 	Url string `json:"url"`
 	// Username description: The username to use when authenticating to the Bitbucket Cloud. Also set the corresponding "appPassword" field.
 	Username string `json:"username"`
+	// Webhooks description: An array of webhook configurations. Bitbucket Cloud webhooks can't be signed, so the secret has to be included in the URL of the webhook as the value of the "secret" query parameter.
+	Webhooks []*BitbucketCloudWebhook `json:"webhooks,omitempty"`
 }
 
 // BitbucketCloudRateLimit description: Rate limit applied when making background API requests to Bitbucket Cloud.
@@ -226,6 +277,10 @@ type BitbucketCloudRateLimit struct {
 	// RequestsPerHour description: Requests per hour permitted. This is an average, calculated per second. Internally, the burst limit is set to 500, which implies that for a requests per hour limit as low as 1, users will continue to be able to send a maximum of 500 requests immediately, provided that the complexity cost of each request is 1.
 	RequestsPerHour float64 `json:"requestsPerHour"`
 }
+type BitbucketCloudWebhook struct {
+	// Secret description: The secret used to authenticate incoming webhook requests
+	Secret string `json:"secret"`
+}
 
 // BitbucketServerAuthorization description: If non-null, enforces Bitbucket Server repository permissions.
 type BitbucketServerAuthorization struct {
@@ -426,6 +481,52 @@ type Dotcom struct {
 	SlackLicenseExpirationWebhook string `json:"slackLicenseExpirationWebhook,omitempty"`
 }
 
+// EmailDomainPolicy description: Restricts the domains of email addresses that users can sign up with or add to their account. Domains also match their subdomains. Site admins can override the policy for individual domains, and such overrides take precedence over this setting. The email addresses of service accounts are exempt.
+type EmailDomainPolicy struct {
+	// Allow description: Domains that email addresses must belong to. If empty, all domains that are not denied are allowed.
+	Allow []string `json:"allow,omitempty"`
+	// Deny description: Domains that email addresses must not belong to, such as disposable email providers. Takes precedence over allow.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// EmailProvider description: The service used to send transactional emails. If not set, emails are sent with the SMTP server configured in email.smtp.
+type EmailProvider struct {
+	// MaxAttempts description: The number of attempts to send an email before giving up. Attempts that fail with a temporary error, such as a throttled request or an unavailable server, are retried with exponential backoff.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Sendgrid description: Sends emails with the SendGrid v3 API. Required if type is "sendgrid".
+	Sendgrid *SendGridEmailProvider `json:"sendgrid,omitempty"`
+	// Ses description: Sends emails with the Amazon SES API. Required if type is "ses".
+	Ses *SESEmailProvider `json:"ses,omitempty"`
+	// Type description: The service used to send emails: the SMTP server configured in email.smtp, or the Amazon SES or SendGrid API.
+	Type string `json:"type"`
+	// WebhookSecret description: Enables the endpoint that receives bounce and complaint notifications from the email provider, at /.api/email-webhooks/ses (for an Amazon SNS topic) or /.api/email-webhooks/sendgrid (for the SendGrid Event Webhook). The secret must be passed in the secret query parameter of the endpoint URL. Addresses that bounce permanently or mark emails as spam are marked as bouncing.
+	WebhookSecret string `json:"webhookSecret,omitempty"`
+}
+
+// EmailRoleAddresses description: Rejects role and shared mailbox addresses (such as admin@, noreply@ or support@) when users add email addresses to their account. Such addresses don't identify a single person, which breaks permission syncing that matches users to code host accounts by email. The email addresses of service accounts are exempt.
+type EmailRoleAddresses struct {
+	// Patterns description: Regular expressions matched case-insensitively against the whole local part (before the @, ignoring any +suffix) of an email address. Defaults to a list of common role addresses.
+	Patterns []string `json:"patterns,omitempty"`
+	// Reject description: Whether to reject role addresses.
+	Reject bool `json:"reject,omitempty"`
+}
+
+// EmailVerificationReminders description: Reminds users to verify their email addresses by sending reminder emails with a verification link at regular intervals. Every reminder contains a link to opt out of further reminders. Only used if email.smtp is configured.
+type EmailVerificationReminders struct {
+	// IntervalHours description: The number of hours after the last verification email or reminder before the next reminder is sent.
+	IntervalHours int `json:"intervalHours,omitempty"`
+	// MaxReminders description: The number of reminders sent at most per unverified email address. 0 disables reminders.
+	MaxReminders int `json:"maxReminders,omitempty"`
+}
+
+// EmailVerificationResend description: Limits how often verification emails can be resent for an email address. Every verification email that is sent is recorded, and site admins can review them.
+type EmailVerificationResend struct {
+	// CooldownSeconds description: The number of seconds users must wait before another verification email can be sent for the same email address.
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"`
+	// MaxPerDay description: The number of verification emails that can be sent at most for an email address within 24 hours. 0 means unlimited.
+	MaxPerDay int `json:"maxPerDay,omitempty"`
+}
+
 // EncryptionKey description: Config for a key
 type EncryptionKey struct {
 	Cloudkms *CloudKMSEncryptionKey
@@ -501,6 +602,12 @@ type ExcludedBitbucketServerRepo struct {
 	// Pattern description: Regular expression which matches against the name of a Bitbucket Server repo.
 	Pattern string `json:"pattern,omitempty"`
 }
+type ExcludedGerritProject struct {
+	// Name description: The name of a Gerrit project ("platform/build") to exclude from mirroring.
+	Name string `json:"name,omitempty"`
+	// Pattern description: Regular expression which matches against the name of a Gerrit project to exclude from mirroring.
+	Pattern string `json:"pattern,omitempty"`
+}
 type ExcludedGitHubRepo struct {
 	// Archived description: If set to true, archived repositories will be excluded.
 	Archived bool `json:"archived,omitempty"`
@@ -591,6 +698,40 @@ type ExternalIdentity struct {
 	Type           string `json:"type"`
 }
 
+// GerritConnection description: Configuration for a connection to Gerrit.
+type GerritConnection struct {
+	// Exclude description: A list of projects to never mirror from this Gerrit instance. Takes precedence over "projects" configuration.
+	//
+	// Supports excluding by exact name ({"name": "my-project"}) or by regular expression ({"pattern": "^archived/.*"}).
+	Exclude []*ExcludedGerritProject `json:"exclude,omitempty"`
+	// Password description: The HTTP password to use when authenticating to the Gerrit instance, as generated in the "HTTP Credentials" section of the user's settings on Gerrit. Also set the corresponding "username" field.
+	Password string `json:"password"`
+	// Projects description: An array of names of Gerrit projects to mirror on Sourcegraph. If empty, all projects visible to the configured user are mirrored.
+	Projects []string `json:"projects,omitempty"`
+	// RateLimit description: Rate limit applied when making background API requests to Gerrit.
+	RateLimit *GerritRateLimit `json:"rateLimit,omitempty"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for a Gerrit project.
+	//
+	//  - "{host}" is replaced with the Gerrit URL's host (such as gerrit.example.com), and "{name}" is replaced with the Gerrit project's name (such as "platform/build").
+	//
+	// For example, if your Gerrit is https://gerrit.example.com and your Sourcegraph is https://src.example.com, then a repositoryPathPattern of "{host}/{name}" would mean that the Gerrit project platform/build is available on Sourcegraph at https://src.example.com/gerrit.example.com/platform/build.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+	// Url description: URL of the Gerrit instance, such as https://gerrit.example.com.
+	Url string `json:"url"`
+	// Username description: The username to use when authenticating to the Gerrit instance. Also set the corresponding "password" field.
+	Username string `json:"username"`
+}
+
+// GerritRateLimit description: Rate limit applied when making background API requests to Gerrit.
+type GerritRateLimit struct {
+	// Enabled description: true if rate limiting is enabled.
+	Enabled bool `json:"enabled"`
+	// RequestsPerHour description: Requests per hour permitted. This is an average, calculated per second. Internally, the burst limit is set to 500, which implies that for a requests per hour limit as low as 1, users will continue to be able to send a maximum of 500 requests immediately, provided that the complexity cost of each request is 1.
+	RequestsPerHour float64 `json:"requestsPerHour"`
+}
+
 // GitCommitAuthor description: The author of the Git commit.
 type GitCommitAuthor struct {
 	// Email description: The Git commit author email.
@@ -893,16 +1034,84 @@ type InsightFilters struct {
 	Repositories      []string `json:"repositories,omitempty"`
 }
 type InsightSeries struct {
+	// GeneratedFromCaptureGroups description: Show one series per value captured by the capture groups of the regular expression of the search query (e.g. one series per Go version found in go.mod files), instead of the number of results.
+	GeneratedFromCaptureGroups bool `json:"generatedFromCaptureGroups,omitempty"`
 	// Label description: The label to use for the series in the graph.
 	Label string `json:"label"`
+	// LanguageStatsRepositories description: Show one series per language with the number of lines of code in that language in the given repositories, computed from the contents of the repositories instead of search results. Series with language statistics have no search query.
+	LanguageStatsRepositories []string `json:"languageStatsRepositories,omitempty"`
+	// PatternType description: The pattern type that the search query of the series is executed with.
+	PatternType string `json:"patternType,omitempty"`
 	// RepositoriesList description: Performs a search query and shows the number of results returned.
 	RepositoriesList []interface{} `json:"repositoriesList,omitempty"`
+	// RepositoryRevisions description: The revision (e.g. a release branch) that each of the given repositories is searched at, instead of its default branch. Keys are repository names.
+	RepositoryRevisions map[string]string `json:"repositoryRevisions,omitempty"`
 	// Search description: Performs a search query and shows the number of results returned.
 	Search string `json:"search,omitempty"`
+	// Team description: Only search the repositories owned by the given team. The repositories of a team are looked up each time the series is computed, so changes of ownership apply to the data points recorded afterwards.
+	Team string `json:"team,omitempty"`
 	// Webhook description: (not yet supported) Fetch data from a webhook URL.
 	Webhook string `json:"webhook,omitempty"`
 }
 
+// InsightsBackfiller description: The backfiller of code insights, which populates the historical data of new insight series by sampling past commits of every repository and searching the repository at each of them.
+type InsightsBackfiller struct {
+	// Enabled description: Backfill new insight series with the backfiller, instead of the historical enqueuer.
+	Enabled bool `json:"enabled,omitempty"`
+	// FrameLength description: Duration between two sampled historical data points.
+	FrameLength string `json:"frameLength,omitempty"`
+	// Frames description: Number of historical data points sampled for each repository, going back from the creation of the series.
+	Frames int `json:"frames,omitempty"`
+	// GitserverRateLimit description: Maximum number of gitserver requests per second that the backfiller makes to look up the commits to sample.
+	GitserverRateLimit *float64 `json:"gitserverRateLimit,omitempty"`
+}
+
+// InsightsQueryCache description: Cache of code insight search results, shared by all series. Series whose queries only differ in whitespace or in the order of their parameters share cached results for data points recorded in the same time bucket, instead of running the same search again.
+type InsightsQueryCache struct {
+	// Bucket description: Length of the time buckets of cached results. Data points recorded in the same bucket share the results of the same search.
+	Bucket string `json:"bucket,omitempty"`
+	// Enabled description: Whether search results are cached.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Ttl description: How long cached results are kept.
+	Ttl string `json:"ttl,omitempty"`
+}
+
+// InsightsQueryRetry description: Retries of code insight search queries that fail with a transient error, such as a server error or a timeout.
+type InsightsQueryRetry struct {
+	// BackoffBase description: Base delay before retrying a search query. The delay grows exponentially with each attempt, with random jitter.
+	BackoffBase string `json:"backoffBase,omitempty"`
+	// BackoffMax description: Maximum delay before retrying a search query.
+	BackoffMax string `json:"backoffMax,omitempty"`
+	// CircuitBreakerCooldown description: How long searches fail right away once the circuit breaker opened.
+	CircuitBreakerCooldown string `json:"circuitBreakerCooldown,omitempty"`
+	// CircuitBreakerThreshold description: Number of consecutive failed search attempts after which further searches fail right away until the cooldown has passed, to not overload an unhealthy search backend. Set to 0 to disable the circuit breaker.
+	CircuitBreakerThreshold *int `json:"circuitBreakerThreshold,omitempty"`
+	// MaxAttempts description: Maximum number of attempts of a search query, including the first one.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+}
+
+// InsightsQueryScheduler description: Scheduling of code insight searches, which bounds how many of them run at the same time so that they do not starve interactive searches. Queued searches are run in a round-robin order across insight series, so that series with many queued searches (e.g. while they are backfilled) do not delay the others.
+type InsightsQueryScheduler struct {
+	// MaxConcurrentSearches description: Maximum number of code insight searches running at the same time, across all worker nodes. Set to 0 to not limit the number of searches beyond insights.query.worker.concurrency.
+	MaxConcurrentSearches *int `json:"maxConcurrentSearches,omitempty"`
+}
+
+// InsightsRetention description: Retention of code insight data points. Data points older than the age of a policy are downsampled to the resolution of the policy, keeping the latest data point of every day, week or month. Charts that include downsampled data points are displayed at the same resolution.
+type InsightsRetention struct {
+	// DeleteAfterDays description: Age in days after which data points are deleted. Set to 0 to keep data points forever.
+	DeleteAfterDays int `json:"deleteAfterDays,omitempty"`
+	// Policies description: Downsampling policies. When several policies apply to a data point, the one with the coarsest resolution wins.
+	Policies []*InsightsRetentionPolicy `json:"policies,omitempty"`
+}
+
+// InsightsRetentionPolicy description: A downsampling policy of code insight data points.
+type InsightsRetentionPolicy struct {
+	// OlderThanDays description: Age in days after which data points are downsampled.
+	OlderThanDays int `json:"olderThanDays"`
+	// Resolution description: Resolution that data points are downsampled to.
+	Resolution string `json:"resolution"`
+}
+
 // JVMPackagesConnection description: Configuration for a connection to a JVM packages repository.
 type JVMPackagesConnection struct {
 	// Maven description: Configuration for resolving from Maven repositories.
@@ -1257,6 +1466,16 @@ type SAMLAuthProvider struct {
 	Type         string `json:"type"`
 }
 
+// SESEmailProvider description: Sends emails with the Amazon SES API. Required if type is "ses".
+type SESEmailProvider struct {
+	// AccessKeyID description: The AWS access key ID. If not set, credentials are loaded from the environment, shared credentials file or instance role, like for other AWS clients.
+	AccessKeyID string `json:"accessKeyID,omitempty"`
+	// Region description: The AWS region of the SES API, such as us-east-1.
+	Region string `json:"region"`
+	// SecretAccessKey description: The AWS secret access key. Required if accessKeyID is set.
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+}
+
 // SMTPServerConfig description: The SMTP server used to send transactional emails (such as email verifications, reset-password emails, and notifications).
 type SMTPServerConfig struct {
 	// Authentication description: The type of authentication to use for the SMTP server.
@@ -1307,6 +1526,12 @@ type SearchScope struct {
 	Value string `json:"value"`
 }
 
+// SendGridEmailProvider description: Sends emails with the SendGrid v3 API. Required if type is "sendgrid".
+type SendGridEmailProvider struct {
+	// ApiKey description: A SendGrid API key with the Mail Send permission.
+	ApiKey string `json:"apiKey"`
+}
+
 // Sentry description: Configuration for Sentry
 type Sentry struct {
 	// BackendDSN description: Sentry Data Source Name (DSN) for backend errors. Per the Sentry docs (https://docs.sentry.io/quickstart/#about-the-dsn), it should match the following pattern: '{PROTOCOL}://{PUBLIC_KEY}@{HOST}/{PATH}{PROJECT_ID}'.
@@ -1436,6 +1661,8 @@ type SettingsExperimentalFeatures struct {
 
 // SiteConfiguration description: Configuration for a Sourcegraph site.
 type SiteConfiguration struct {
+	// ApiPersistedQueries description: Controls persisted GraphQL queries, which clients register once and then run by sending only the SHA-256 hash of the query (using the persisted query protocol of Apollo). The default is "enabled". Use "allowlist-only" to only allow users other than site admins to run queries that were registered before; in this mode, only site admins may register queries. Use "disabled" to reject requests that use persisted queries.
+	ApiPersistedQueries string `json:"api.persistedQueries,omitempty"`
 	// ApiRatelimit description: Configuration for API rate limiting
 	ApiRatelimit *ApiRatelimit `json:"api.ratelimit,omitempty"`
 	// AuthAccessTokens description: Settings for access tokens, which enable external tools to access the Sourcegraph API with the privileges of the user.
@@ -1446,6 +1673,12 @@ type SiteConfiguration struct {
 	AuthMinPasswordLength int `json:"auth.minPasswordLength,omitempty"`
 	// AuthPasswordResetLinkExpiry description: The duration (in seconds) that a password reset link is considered valid.
 	AuthPasswordResetLinkExpiry int `json:"auth.passwordResetLinkExpiry,omitempty"`
+	// AuthPrimaryEmailChangeSessionRevocation description: Whether to sign a user out everywhere (revoking all of their sessions and access tokens) when the primary email address of their account changes. This protects against account takeover by swapping the email address that password resets are sent to.
+	//
+	// - "never": never revoke sessions and access tokens.
+	// - "unrecognized": revoke them if the change was not made by the user themselves from a signed-in browser session, e.g. if it was made with an access token or by a site admin.
+	// - "always": always revoke them, including the session that made the change.
+	AuthPrimaryEmailChangeSessionRevocation string `json:"auth.primaryEmailChangeSessionRevocation,omitempty"`
 	// AuthProviders description: The authentication providers to use for identifying and signing in users. See instructions below for configuring SAML, OpenID Connect (including Google Workspace), and HTTP authentication proxies. Multiple authentication providers are supported (by specifying multiple elements in this array).
 	AuthProviders []AuthProviders `json:"auth.providers,omitempty"`
 	// AuthPublic description: WARNING: This option has been removed as of 3.8.
@@ -1464,12 +1697,22 @@ type SiteConfiguration struct {
 	//   ```
 	//
 	AuthSessionExpiry string `json:"auth.sessionExpiry,omitempty"`
+	// AuthTwoFactor description: Settings for two-factor authentication with a time-based one-time password (TOTP) app. Users who enable it must enter a code from the app (or a recovery code) when they sign in with a username and password. It does not apply to sign-ins through external authentication providers, which should enforce their own second factor.
+	AuthTwoFactor *AuthTwoFactor `json:"auth.twoFactor,omitempty"`
 	// AuthUserOrgMap description: Ensure that matching users are members of the specified orgs (auto-joining users to the orgs if they are not already a member). Provide a JSON object of the form `{"*": ["org1", "org2"]}`, where org1 and org2 are orgs that all users are automatically joined to. Currently the only supported key is `"*"`.
 	AuthUserOrgMap map[string][]string `json:"auth.userOrgMap,omitempty"`
 	// AuthzEnforceForSiteAdmins description: When true, site admins will only be able to see private code they have access to via our authz system.
 	AuthzEnforceForSiteAdmins bool `json:"authz.enforceForSiteAdmins,omitempty"`
+	// BatchChangesArchiveSigningKey description: The key used to sign and verify batch spec archives, which move batch specs between Sourcegraph instances. Instances that exchange archives must use the same key. Exporting and importing batch specs is disabled if unset.
+	BatchChangesArchiveSigningKey string `json:"batchChanges.archiveSigningKey,omitempty"`
+	// BatchChangesCodeHostRequestLimits description: Limits the requests that Batch Changes makes to the API of a code host, for example to publish, sync and close changesets, so that large batch changes don't overload it. Code hosts without an entry are not limited.
+	BatchChangesCodeHostRequestLimits []*BatchChangeCodeHostRequestLimit `json:"batchChanges.codeHostRequestLimits,omitempty"`
 	// BatchChangesEnabled description: Enables/disables the Batch Changes feature.
 	BatchChangesEnabled *bool `json:"batchChanges.enabled,omitempty"`
+	// BatchChangesResolutionJobRetentionDays description: The number of days finished batch spec workspace resolution jobs are kept. Older dry run jobs are deleted, and the execution logs of older regular jobs are removed. The default is 30 days.
+	BatchChangesResolutionJobRetentionDays int `json:"batchChanges.resolutionJobRetentionDays,omitempty"`
+	// BatchChangesResolutionQuota description: Limits the batch spec workspace resolutions per namespace (user or organization), so that a single namespace can't use up the resolution capacity of the instance. Resolutions beyond the quota are rejected.
+	BatchChangesResolutionQuota *BatchChangeResolutionQuota `json:"batchChanges.resolutionQuota,omitempty"`
 	// BatchChangesRestrictToAdmins description: When enabled, only site admins can create and apply batch changes.
 	BatchChangesRestrictToAdmins *bool `json:"batchChanges.restrictToAdmins,omitempty"`
 	// BatchChangesRolloutWindows description: Specifies specific windows, which can have associated rate limits, to be used when publishing changesets. All days and times are handled in UTC.
@@ -1504,8 +1747,20 @@ type SiteConfiguration struct {
 	Dotcom *Dotcom `json:"dotcom,omitempty"`
 	// EmailAddress description: The "from" address for emails sent by this server.
 	EmailAddress string `json:"email.address,omitempty"`
+	// EmailDomainPolicy description: Restricts the domains of email addresses that users can sign up with or add to their account. Domains also match their subdomains. Site admins can override the policy for individual domains, and such overrides take precedence over this setting. The email addresses of service accounts are exempt.
+	EmailDomainPolicy *EmailDomainPolicy `json:"email.domainPolicy,omitempty"`
+	// EmailProvider description: The service used to send transactional emails. If not set, emails are sent with the SMTP server configured in email.smtp.
+	EmailProvider *EmailProvider `json:"email.provider,omitempty"`
+	// EmailRoleAddresses description: Rejects role and shared mailbox addresses (such as admin@, noreply@ or support@) when users add email addresses to their account. Such addresses don't identify a single person, which breaks permission syncing that matches users to code host accounts by email. The email addresses of service accounts are exempt.
+	EmailRoleAddresses *EmailRoleAddresses `json:"email.roleAddresses,omitempty"`
 	// EmailSmtp description: The SMTP server used to send transactional emails (such as email verifications, reset-password emails, and notifications).
 	EmailSmtp *SMTPServerConfig `json:"email.smtp,omitempty"`
+	// EmailVerificationLinkSignIn description: Makes the link in verification emails also sign the user in, if they aren't signed in already, so that they don't have to sign in after verifying their email address. The link signs in only once and only within 24 hours of being sent. Site admins and users who enabled two-factor authentication always have to sign in.
+	EmailVerificationLinkSignIn bool `json:"email.verificationLinkSignIn,omitempty"`
+	// EmailVerificationReminders description: Reminds users to verify their email addresses by sending reminder emails with a verification link at regular intervals. Every reminder contains a link to opt out of further reminders. Only used if email.smtp is configured.
+	EmailVerificationReminders *EmailVerificationReminders `json:"email.verificationReminders,omitempty"`
+	// EmailVerificationResend description: Limits how often verification emails can be resent for an email address. Every verification email that is sent is recorded, and site admins can review them.
+	EmailVerificationResend *EmailVerificationResend `json:"email.verificationResend,omitempty"`
 	// EncryptionKeys description: Configuration for encryption keys used to encrypt data at rest in the database.
 	EncryptionKeys *EncryptionKeys `json:"encryption.keys,omitempty"`
 	// ExperimentalFeatures description: Experimental features to enable or disable. Features that are now enabled by default are marked as deprecated.
@@ -1538,6 +1793,8 @@ type SiteConfiguration struct {
 	HtmlHeadBottom string `json:"htmlHeadBottom,omitempty"`
 	// HtmlHeadTop description: HTML to inject at the top of the `<head>` element on each page, for analytics scripts
 	HtmlHeadTop string `json:"htmlHeadTop,omitempty"`
+	// InsightsBackfiller description: The backfiller of code insights, which populates the historical data of new insight series by sampling past commits of every repository and searching the repository at each of them.
+	InsightsBackfiller *InsightsBackfiller `json:"insights.backfiller,omitempty"`
 	// InsightsCommitIndexerInterval description: The interval (in minutes) at which the insights commit indexer will check for new commits.
 	InsightsCommitIndexerInterval int `json:"insights.commit.indexer.interval,omitempty"`
 	// InsightsHistoricalFrameLength description: (debug) duration of historical insights timeframes, one point per repository will be recorded in each timeframe.
@@ -1548,10 +1805,22 @@ type SiteConfiguration struct {
 	InsightsHistoricalSpeedFactor *float64 `json:"insights.historical.speedFactor,omitempty"`
 	// InsightsHistoricalWorkerRateLimit description: Maximum number of historical Code Insights data frames that may be analyzed per second.
 	InsightsHistoricalWorkerRateLimit *float64 `json:"insights.historical.worker.rateLimit,omitempty"`
+	// InsightsQueryCache description: Cache of code insight search results, shared by all series. Series whose queries only differ in whitespace or in the order of their parameters share cached results for data points recorded in the same time bucket, instead of running the same search again.
+	InsightsQueryCache *InsightsQueryCache `json:"insights.query.cache,omitempty"`
+	// InsightsQuerySamples description: Maximum number of example matches (repository, file and line) retained for each data point of a code insight, so that they can be shown for the data point. Samples are only retained for recorded data points, and are filtered by repository permissions when read. Set to 0 to retain no samples.
+	InsightsQuerySamples int `json:"insights.query.samples,omitempty"`
+	// InsightsQueryRetry description: Retries of code insight search queries that fail with a transient error, such as a server error or a timeout.
+	InsightsQueryRetry *InsightsQueryRetry `json:"insights.query.retry,omitempty"`
+	// InsightsQueryScheduler description: Scheduling of code insight searches, which bounds how many of them run at the same time so that they do not starve interactive searches. Queued searches are run in a round-robin order across insight series, so that series with many queued searches (e.g. while they are backfilled) do not delay the others.
+	InsightsQueryScheduler *InsightsQueryScheduler `json:"insights.query.scheduler,omitempty"`
+	// InsightsQueryTimeBudget description: Maximum number of seconds that the search query of a code insight series may run for a single data point. When the time budget is exceeded, the matches found so far are recorded and the data point is flagged as incomplete. Set to 0 to not limit the time beyond the search timeout.
+	InsightsQueryTimeBudget int `json:"insights.query.timeBudget,omitempty"`
 	// InsightsQueryWorkerConcurrency description: Number of concurrent executions of a code insight query on a worker node
 	InsightsQueryWorkerConcurrency int `json:"insights.query.worker.concurrency,omitempty"`
 	// InsightsQueryWorkerRateLimit description: Maximum number of Code Insights queries initiated per second on a worker node.
 	InsightsQueryWorkerRateLimit *float64 `json:"insights.query.worker.rateLimit,omitempty"`
+	// InsightsRetention description: Retention of code insight data points. Data points older than the age of a policy are downsampled to the resolution of the policy, keeping the latest data point of every day, week or month. Charts that include downsampled data points are displayed at the same resolution.
+	InsightsRetention *InsightsRetention `json:"insights.retention,omitempty"`
 	// LicenseKey description: The license key associated with a Sourcegraph product subscription, which is necessary to activate Sourcegraph Enterprise functionality. To obtain this value, contact Sourcegraph to purchase a subscription. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
 	LicenseKey string `json:"licenseKey,omitempty"`
 	// Log description: Configuration for logging and alerting, including to external services.
@@ -1560,6 +1829,8 @@ type SiteConfiguration struct {
 	LsifEnforceAuth bool `json:"lsifEnforceAuth,omitempty"`
 	// MaxReposToSearch description: DEPRECATED: Configure maxRepos in search.limits. The maximum number of repositories to search across. The user is prompted to narrow their query if exceeded. Any value less than or equal to zero means unlimited.
 	MaxReposToSearch int `json:"maxReposToSearch,omitempty"`
+	// NotificationsAccountChanges description: Configures how users are notified about security-relevant changes to their account, such as a changed password or a new access token. If not set, users are notified by email (if email.smtp is configured).
+	NotificationsAccountChanges *AccountChangeNotifications `json:"notifications.accountChanges,omitempty"`
 	// ObservabilityAlerts description: Configure notifications for Sourcegraph's built-in alerts.
 	ObservabilityAlerts []*ObservabilityAlerts `json:"observability.alerts,omitempty"`
 	// ObservabilityLogSlowGraphQLRequests description: (debug) logs all GraphQL requests slower than the specified number of milliseconds.
@@ -1580,6 +1851,8 @@ type SiteConfiguration struct {
 	RepoConcurrentExternalServiceSyncers int `json:"repoConcurrentExternalServiceSyncers,omitempty"`
 	// RepoListUpdateInterval description: Interval (in minutes) for checking code hosts (such as GitHub, Gitolite, etc.) for new repositories.
 	RepoListUpdateInterval int `json:"repoListUpdateInterval,omitempty"`
+	// ScimAuthToken description: The bearer token that identity providers use to authenticate to the SCIM 2.0 API at /.api/scim/v2, which provisions and deprovisions users and synchronizes their email addresses. The SCIM API is disabled if this is not set. Use a long random string.
+	ScimAuthToken string `json:"scim.authToken,omitempty"`
 	// SearchIndexEnabled description: Whether indexed search is enabled. If unset Sourcegraph detects the environment to decide if indexed search is enabled. Indexed search is RAM heavy, and is disabled by default in the single docker image. All other environments will have it enabled by default. The size of all your repository working copies is the amount of additional RAM required.
 	SearchIndexEnabled *bool `json:"search.index.enabled,omitempty"`
 	// SearchIndexSymbolsEnabled description: Whether indexed symbol search is enabled. This is contingent on the indexed search configuration, and is true by default for instances with indexed search enabled. Enabling this will cause every repository to re-index, which is a time consuming (several hours) operation. Additionally, it requires more storage and ram to accommodate the added symbols information in the search index.